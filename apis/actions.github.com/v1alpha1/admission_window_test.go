@@ -0,0 +1,93 @@
+package v1alpha1_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmissionWindowsOpen(t *testing.T) {
+	windows := []v1alpha1.AdmissionWindow{
+		{DailyWindow: v1alpha1.DailyWindow{
+			Days:     []v1alpha1.WindowDay{"Monday", "Tuesday"},
+			Start:    "09:00",
+			End:      "12:00",
+			TimeZone: "Europe/Berlin",
+		}},
+		{DailyWindow: v1alpha1.DailyWindow{
+			Days:  []v1alpha1.WindowDay{"Saturday"},
+			Start: "22:00",
+			End:   "02:00",
+		}},
+	}
+
+	// 2024-05-06 is a Monday, and Europe/Berlin is UTC+2
+	monday := func(hour, min int) time.Time {
+		return time.Date(2024, 5, 6, hour, min, 0, 0, time.UTC)
+	}
+
+	t.Run("without windows", func(t *testing.T) {
+		open, next, err := v1alpha1.AdmissionWindowsOpen(nil, monday(5, 0))
+		require.NoError(t, err)
+		assert.True(t, open)
+		assert.True(t, next.IsZero())
+	})
+
+	t.Run("before a window", func(t *testing.T) {
+		open, next, err := v1alpha1.AdmissionWindowsOpen(windows, monday(5, 0))
+		require.NoError(t, err)
+		assert.False(t, open)
+		assert.WithinDuration(t, monday(7, 0), next, 0)
+	})
+
+	t.Run("in a window of the time zone", func(t *testing.T) {
+		open, next, err := v1alpha1.AdmissionWindowsOpen(windows, monday(7, 0))
+		require.NoError(t, err)
+		assert.True(t, open)
+		assert.WithinDuration(t, monday(10, 0), next, 0)
+	})
+
+	t.Run("in a window started on the previous day", func(t *testing.T) {
+		open, next, err := v1alpha1.AdmissionWindowsOpen(windows, monday(1, 0).AddDate(0, 0, -1))
+		require.NoError(t, err)
+		assert.True(t, open)
+		assert.WithinDuration(t, monday(2, 0).AddDate(0, 0, -1), next, 0)
+	})
+
+	t.Run("after the last window of the week", func(t *testing.T) {
+		open, next, err := v1alpha1.AdmissionWindowsOpen(windows, monday(11, 0).AddDate(0, 0, 1))
+		require.NoError(t, err)
+		assert.False(t, open)
+		assert.WithinDuration(t, monday(22, 0).AddDate(0, 0, 5), next, 0)
+	})
+
+	t.Run("invalid time zone", func(t *testing.T) {
+		_, _, err := v1alpha1.AdmissionWindowsOpen([]v1alpha1.AdmissionWindow{{DailyWindow: v1alpha1.DailyWindow{Start: "08:00", End: "09:00", TimeZone: "Nowhere/Nowhere"}}}, monday(7, 0))
+		assert.ErrorContains(t, err, "invalid admission window 0: invalid time zone")
+	})
+}
+
+func TestDailyWindowActive(t *testing.T) {
+	// 2024-05-06 is a Monday
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 5, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	overnight := v1alpha1.DailyWindow{Days: []v1alpha1.WindowDay{"Friday"}, Start: "22:00", End: "06:00"}
+
+	active, next, err := overnight.Active(at(11, 2))
+	require.NoError(t, err)
+	assert.True(t, active, "a window started on the previous day is still active")
+	assert.WithinDuration(t, at(11, 6), next, 0)
+
+	active, next, err = overnight.Active(at(11, 6))
+	require.NoError(t, err)
+	assert.False(t, active)
+	assert.WithinDuration(t, at(17, 22), next, 0, "the next window starts on Friday")
+
+	_, _, err = v1alpha1.DailyWindow{Start: "8am", End: "09:00"}.Active(at(6, 7))
+	assert.ErrorContains(t, err, "invalid start")
+}
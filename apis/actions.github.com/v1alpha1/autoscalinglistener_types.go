@@ -63,6 +63,24 @@ type AutoscalingListenerSpec struct {
 
 	// +optional
 	Template *corev1.PodTemplateSpec `json:"template,omitempty"`
+
+	// AllowedRepositories are the full names, owner/name, of the repositories whose jobs the listener acquires. Defaults to all the repositories.
+	// +optional
+	AllowedRepositories []string `json:"allowedRepositories,omitempty"`
+
+	// AdmissionWindows are the recurring periods during which the listener acquires jobs. Jobs are acquired at any time when empty.
+	// +optional
+	AdmissionWindows []AdmissionWindow `json:"admissionWindows,omitempty"`
+
+	// OutsideAdmissionWindows is what the listener does with the jobs available outside of admissionWindows.
+	// +optional
+	// +kubebuilder:validation:Enum=Leave;Hold
+	OutsideAdmissionWindows string `json:"outsideAdmissionWindows,omitempty"`
+
+	// Shards is the number of listener pods the job acquisition is partitioned between. Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Shards int `json:"shards,omitempty"`
 }
 
 // AutoscalingListenerStatus defines the observed state of AutoscalingListener
@@ -17,16 +17,19 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/actions/actions-runner-controller/hash"
 	"golang.org/x/net/http/httpproxy"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
@@ -59,12 +62,37 @@ type AutoscalingRunnerSetSpec struct {
 	// Required
 	GitHubConfigSecret string `json:"githubConfigSecret,omitempty"`
 
+	// RunnerGitHubConfigSecret is the secret used to generate the JIT configuration of runners and to remove them,
+	// so that githubConfigSecret, used by the listener to acquire jobs and by the controller to manage the scale set,
+	// can have more privileges than the credentials runners are registered with.
+	// Defaults to githubConfigSecret.
+	// +optional
+	RunnerGitHubConfigSecret string `json:"runnerGitHubConfigSecret,omitempty"`
+
 	// +optional
 	RunnerGroup string `json:"runnerGroup,omitempty"`
 
+	// RunnerGroupRepositories are the names of the repositories of the organization whose jobs run on the scale set.
+	// When RunnerGroup is restricted to selected repositories, the runner scale set isn't created
+	// until the runner group allows all of them.
+	// +optional
+	RunnerGroupRepositories []string `json:"runnerGroupRepositories,omitempty"`
+
+	// AllowedRepositories are the names, or owner/name, of the repositories of the organization whose jobs run on the scale set.
+	// Unlike RunnerGroupRepositories, it's enforced: the runner scale set isn't created until RunnerGroup, or the default
+	// runner group, allows all of them and none other, and the listener doesn't acquire the jobs of the other repositories.
+	// It can only be set on the scale sets of organizations.
+	// +optional
+	AllowedRepositories []string `json:"allowedRepositories,omitempty"`
+
 	// +optional
 	RunnerScaleSetName string `json:"runnerScaleSetName,omitempty"`
 
+	// RunnerScaleSetLabels are labels of the runner scale set besides its name, which jobs can request in runs-on instead of its name.
+	// They're set on the AutoscalingRunnerSets of job templates.
+	// +optional
+	RunnerScaleSetLabels []string `json:"runnerScaleSetLabels,omitempty"`
+
 	// +optional
 	Proxy *ProxyConfig `json:"proxy,omitempty"`
 
@@ -84,11 +112,491 @@ type AutoscalingRunnerSetSpec struct {
 	// +optional
 	// +kubebuilder:validation:Minimum:=0
 	MinRunners *int `json:"minRunners,omitempty"`
+
+	// MinRunnersSchedule are the recurring periods during which minRunners is overridden,
+	// like to keep warm runners during business hours and none overnight.
+	// The largest minRunners of the windows active at a time applies, up to maxRunners.
+	// +optional
+	MinRunnersSchedule []MinRunnersWindow `json:"minRunnersSchedule,omitempty"`
+
+	// +optional
+	EgressPolicy *EgressPolicyConfig `json:"egressPolicy,omitempty"`
+
+	// +optional
+	JobQueueLatencySLO *JobQueueLatencySLO `json:"jobQueueLatencySLO,omitempty"`
+
+	// ContainerHooks are the versions of the runner container hooks the runners use in the kubernetes container mode,
+	// instead of the hooks bundled in the runner image.
+	// New runners are spread between the versions by weight, so that a new version can be rolled out gradually.
+	// Changing them doesn't recreate the runners, which keep the version they were created with.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	ContainerHooks []ContainerHooks `json:"containerHooks,omitempty"`
+
+	// JobTemplates are the pod templates of the runners of the jobs requesting their labels, like gpu or xlarge.
+	// Each of them gets its own runner scale set, named after the one of this AutoscalingRunnerSet and the job template,
+	// with its labels and its own listener, so that its jobs only ever run on the runners of its pod template.
+	// The AutoscalingRunnerSets of the job templates are created and owned by this one.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	JobTemplates []JobTemplate `json:"jobTemplates,omitempty"`
+
+	// WorkVolume is the volume mounted at the work directory of the runner container, /home/runner/_work,
+	// where jobs check out repositories and write build outputs.
+	// It replaces the source of the "work" volume of the pod template when there's one, like in the dind and kubernetes
+	// container modes, and is added otherwise. It applies to the pod templates of jobTemplates too.
+	// +optional
+	WorkVolume *WorkVolumeSource `json:"workVolume,omitempty"`
+
+	// Canary rolls a new image of the runner container out to a percentage of the new runners first.
+	// The image is promoted to all the runners once enough of the jobs of the canary runners completed
+	// without too many failing, or rolled back otherwise. Other changes of the runner spec roll out right away.
+	// +optional
+	Canary *CanaryRollout `json:"canary,omitempty"`
+
+	// DriftDetection periodically compares the runner scale set on GitHub with this spec,
+	// to detect changes made out-of-band, like in the GitHub UI.
+	// +optional
+	DriftDetection *DriftDetection `json:"driftDetection,omitempty"`
+
+	// Placeholders keeps low-priority placeholder pods shaped like the runner pods while demand is anticipated,
+	// so that the cluster autoscaler provisions nodes in advance and the runner pods preempting them are scheduled right away.
+	// +optional
+	Placeholders *Placeholders `json:"placeholders,omitempty"`
+
+	// WarmPool keeps warm pods that pulled the images of the runner pod template on their nodes, without registering runners.
+	// A new runner takes the place of a warm pod on its node, so that it starts without waiting for a node or pulling images.
+	// +optional
+	WarmPool *WarmPool `json:"warmPool,omitempty"`
+
+	// FailureRetention keeps the evidence of the runner pods that fail, which are otherwise deleted right away.
+	// +optional
+	FailureRetention *FailureRetention `json:"failureRetention,omitempty"`
+
+	// ConnectivityProbe runs a Job with the pod template of the runners before the listener of a new runner spec is created,
+	// to verify that runner pods can resolve and reach GitHub through their proxy and with the CAs they trust.
+	// Its result is reported in the GitHubReachable condition, and no runner is created until it succeeds.
+	// +optional
+	ConnectivityProbe *ConnectivityProbe `json:"connectivityProbe,omitempty"`
+
+	// AdmissionWindows are the recurring periods during which the listener acquires jobs,
+	// like the change windows of a scale set whose runners have production credentials.
+	// Outside of them, the available jobs are handled according to outsideAdmissionWindows.
+	// Jobs are acquired at any time when empty.
+	// +optional
+	AdmissionWindows []AdmissionWindow `json:"admissionWindows,omitempty"`
+
+	// OutsideAdmissionWindows is what the listener does with the jobs available outside of admissionWindows.
+	// Leave doesn't acquire them, leaving them to the other scale sets that can run them.
+	// Hold doesn't acquire them either, but acquires the ones still available once the next window opens.
+	// Defaults to Leave.
+	// +optional
+	// +kubebuilder:validation:Enum=Leave;Hold
+	OutsideAdmissionWindows string `json:"outsideAdmissionWindows,omitempty"`
+
+	// ListenerShards is the number of listener pods the job acquisition of the scale set is partitioned between,
+	// for scale sets with thousands of concurrent jobs whose single message loop can't acquire them fast enough.
+	// The first pod owns the message session and scales the runners, the others acquire their partition of the jobs
+	// by polling the acquirable jobs with the session. Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=16
+	ListenerShards *int `json:"listenerShards,omitempty"`
+}
+
+// DailyWindow is a period of the day recurring on some days of the week.
+type DailyWindow struct {
+	// Days are the days of the week the window starts on. Defaults to every day.
+	// +optional
+	Days []WindowDay `json:"days,omitempty"`
+
+	// Start is the time of the day the window starts at, like "08:00".
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End is the time of the day the window ends at, like "18:00".
+	// A window ending at or before its start ends on the next day.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+
+	// TimeZone is the IANA time zone of start and end, like "Europe/Berlin". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type WindowDay string
+
+// Active returns true when the window is active at now,
+// and the next time after now the window starts or ends.
+func (w DailyWindow) Active(now time.Time) (bool, time.Time, error) {
+	loc := time.UTC
+	if w.TimeZone != "" {
+		l, err := time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("invalid time zone: %v", err)
+		}
+		loc = l
+	}
+
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid start: %v", err)
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid end: %v", err)
+	}
+
+	var (
+		active     bool
+		nextChange time.Time
+	)
+
+	next := func(t time.Time) {
+		if t.After(now) && (nextChange.IsZero() || t.Before(nextChange)) {
+			nextChange = t
+		}
+	}
+
+	local := now.In(loc)
+
+	// A window started on the previous day may still be active, and the next one may start up to a week later
+	for d := -1; d <= 7; d++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+d, 0, 0, 0, 0, loc)
+		if !w.startsOn(day.Weekday()) {
+			continue
+		}
+
+		s := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		e := time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+		if !e.After(s) {
+			e = e.AddDate(0, 0, 1)
+		}
+
+		if !now.Before(s) && now.Before(e) {
+			active = true
+		}
+
+		next(s)
+		next(e)
+	}
+
+	return active, nextChange, nil
+}
+
+func (w DailyWindow) startsOn(weekday time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, d := range w.Days {
+		if string(d) == weekday.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// EarliestChange returns the earlier of the next changes a and b, either of which is the zero time when there is none.
+func EarliestChange(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// MinRunnersWindow is a daily period during which minRunners is overridden.
+type MinRunnersWindow struct {
+	DailyWindow `json:",inline"`
+
+	// MinRunners is the minRunners of the scale set during the window.
+	// +kubebuilder:validation:Minimum:=0
+	MinRunners int `json:"minRunners"`
+}
+
+// AdmissionWindow is a daily period during which the listener acquires jobs.
+type AdmissionWindow struct {
+	DailyWindow `json:",inline"`
+}
+
+const (
+	OutsideAdmissionWindowsLeave = "Leave"
+	OutsideAdmissionWindowsHold  = "Hold"
+)
+
+// AdmissionWindowsOpen returns true when any of the windows is open at now, or when there is no window,
+// and the next time any of the windows opens or closes, or the zero time when there is no window.
+func AdmissionWindowsOpen(windows []AdmissionWindow, now time.Time) (bool, time.Time, error) {
+	if len(windows) == 0 {
+		return true, time.Time{}, nil
+	}
+
+	var (
+		open       bool
+		nextChange time.Time
+	)
+	for i, w := range windows {
+		active, next, err := w.Active(now)
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("invalid admission window %d: %v", i, err)
+		}
+
+		open = open || active
+		nextChange = EarliestChange(nextChange, next)
+	}
+
+	return open, nextChange, nil
+}
+
+// ConnectivityProbe configures the connectivity probe of the scale set.
+type ConnectivityProbe struct {
+	// Endpoints are the URLs that must be resolvable and reachable from the runner pods.
+	// Any HTTP response counts as reachable.
+	// Defaults to the GitHub URL and the GitHub API URL of githubConfigUrl, plus the Actions service when it's github.com.
+	// +optional
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Command replaces the probe run in the runner container, like to resolve the endpoints with a specific tool or resolver.
+	// The endpoints are passed space-separated in the CONNECTIVITY_PROBE_ENDPOINTS environment variable,
+	// and the command must exit with a non-zero code when any of them is unreachable.
+	// Defaults to resolving each endpoint with getent and connecting to it with curl.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// TimeoutSeconds is how long the probe retries the endpoints before failing. Defaults to 120.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// WarmPool configures the warm pods of the scale set.
+type WarmPool struct {
+	// Replicas is the number of warm pods kept, which are replaced as runners take their place.
+	// +kubebuilder:validation:Minimum:=0
+	Replicas int `json:"replicas"`
+}
+
+// FailureRetention configures what is kept of the failed runner pods of the scale set for post-mortem.
+type FailureRetention struct {
+	// KeepFailedPods is the number of failed pods of the scale set kept instead of being deleted.
+	// The last pod of a runner that failed too many times is kept, and the oldest kept pods are deleted first.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	KeepFailedPods int `json:"keepFailedPods,omitempty"`
+
+	// TTL is how long the failed pods are kept. Defaults to 24h.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// LogLines is the number of the last lines of the logs of the runner container captured
+	// in the lastFailure status of the EphemeralRunner when its pod fails. Defaults to 0, capturing none.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=500
+	LogLines int64 `json:"logLines,omitempty"`
+}
+
+// Placeholders configures the placeholder pods of the scale set.
+// The number of placeholder pods is the largest of replicas and the replicas of the active windows,
+// plus perPendingRunner for each pending runner, up to maxRunners minus the current runners.
+type Placeholders struct {
+	// PriorityClassName is the priority class of the placeholder pods.
+	// Its value must be lower than the priority of the runner pods, like a negative value,
+	// for the runner pods to preempt the placeholder pods.
+	PriorityClassName string `json:"priorityClassName"`
+
+	// Image is the image of the placeholder pods, which must run until they are preempted.
+	// Defaults to registry.k8s.io/pause.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Replicas is the number of placeholder pods kept at any time.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	Replicas *int `json:"replicas,omitempty"`
+
+	// PerPendingRunner is the number of placeholder pods added per pending runner.
+	// Runners waiting for a node mean that the job queue grows faster than nodes are provisioned,
+	// so that nodes are provisioned for the jobs expected to follow.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	PerPendingRunner *int `json:"perPendingRunner,omitempty"`
+
+	// Windows are the recurring periods of anticipated demand, like the start of the workday,
+	// during which more placeholder pods are kept.
+	// +optional
+	Windows []PlaceholderWindow `json:"windows,omitempty"`
+}
+
+// PlaceholderWindow is a daily period during which placeholder pods are kept.
+type PlaceholderWindow struct {
+	DailyWindow `json:",inline"`
+
+	// Replicas is the number of placeholder pods kept during the window.
+	// +kubebuilder:validation:Minimum:=0
+	Replicas int `json:"replicas"`
+}
+
+// DriftDetection configures the detection of changes made to the runner scale set out-of-band.
+type DriftDetection struct {
+	// Action is what the controller does when the runner scale set drifted from the spec.
+	// Warn sets the ScaleSetDrifted condition, and Reconcile also reverts the runner scale set to the spec.
+	// +kubebuilder:validation:Enum=Warn;Reconcile
+	Action string `json:"action"`
+
+	// Interval is the interval between two comparisons. Defaults to 10m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+const (
+	DriftDetectionActionWarn      = "Warn"
+	DriftDetectionActionReconcile = "Reconcile"
+)
+
+// JobTemplate is the pod template of the runners of the jobs requesting its labels.
+type JobTemplate struct {
+	// Name identifies the job template. It's appended to the names of the AutoscalingRunnerSet and the runner scale set
+	// of the job template.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`
+	Name string `json:"name"`
+
+	// Labels are the labels of the runner scale set of the job template besides its name,
+	// which its jobs request in runs-on, like runs-on: gpu.
+	// +kubebuilder:validation:MinItems=1
+	Labels []string `json:"labels"`
+
+	// MaxRunners is the maxRunners of the runner scale set of the job template. Defaults to the maxRunners of this one.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	MaxRunners *int `json:"maxRunners,omitempty"`
+
+	// MinRunners is the minRunners of the runner scale set of the job template. Defaults to 0.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	MinRunners *int `json:"minRunners,omitempty"`
+
+	// Template is the pod template of the runners of the job template, instead of the template of this one.
+	// Its schema isn't part of the CRD, to keep the CRD within the size limit of etcd.
+	// It's validated as a pod template by the admission webhook, and by the API server
+	// when the AutoscalingRunnerSet of the job template is created.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Template corev1.PodTemplateSpec `json:"template"`
+}
+
+// JobTemplateName returns the name of the AutoscalingRunnerSet of the job template.
+func (ars *AutoscalingRunnerSet) JobTemplateName(template *JobTemplate) string {
+	return ars.Name + "-" + template.Name
+}
+
+// ContainerHooks is a version of the runner container hooks, copied from an image into the runner pods.
+type ContainerHooks struct {
+	// Name identifies the version, like "v0.6.1". Runners are labeled with the name of the version they use.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`
+	Name string `json:"name"`
+
+	// Image is the image the hooks are copied from, like a runner image bundling the hooks.
+	// It must contain the cp command.
+	Image string `json:"image"`
+
+	// Path is the directory of the image containing the index.js of the hooks. Defaults to /home/runner/k8s.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// MinRunnerVersion is the oldest version of the runner the hooks are compatible with, like "2.317.0".
+	// The hooks aren't used while the tag of the runner image is an older version.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^v?[0-9]+(\.[0-9]+){0,2}$`
+	MinRunnerVersion string `json:"minRunnerVersion,omitempty"`
+
+	// Weight is the share of the new runners using this version, relative to the weights of the other versions.
+	// Defaults to 1. A weight of 0 stops new runners from using this version.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Weight *int `json:"weight,omitempty"`
+}
+
+// CanaryRollout configures the canary of the runner image updates of a scale set.
+type CanaryRollout struct {
+	// Percentage is the share of the new runners using the new image while it is a canary.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Percentage int `json:"percentage"`
+
+	// Jobs is the number of jobs of the canary runners that must complete before the image is promoted. Defaults to 10.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Jobs *int `json:"jobs,omitempty"`
+
+	// MaxFailedJobsPercentage is the share of the completed jobs of the canary runners that may fail
+	// without the image being rolled back. Defaults to 10.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxFailedJobsPercentage *int `json:"maxFailedJobsPercentage,omitempty"`
+}
+
+// The phases of the canary of a runner image.
+const (
+	CanaryPhaseProgressing = "Progressing"
+	CanaryPhasePromoted    = "Promoted"
+	CanaryPhaseRolledBack  = "RolledBack"
+)
+
+// JobQueueLatencySLO is a service level objective on the time jobs wait for a runner,
+// like 95% of the jobs assigned a runner within 60 seconds.
+// The controller measures it from the queue and runner assignment times of the jobs reported by the listener,
+// and sets the Degraded condition when the SLO is burning its error budget too fast.
+type JobQueueLatencySLO struct {
+	// Threshold is the time from being queued to being assigned a runner a job must not exceed.
+	Threshold metav1.Duration `json:"threshold"`
+
+	// Objective is the percentage of jobs that must be assigned a runner within the threshold, like "95" or "99.5".
+	// +kubebuilder:validation:Pattern=`^[0-9]{1,2}(\.[0-9]+)?$`
+	Objective string `json:"objective"`
+
+	// Window is the rolling window the jobs are measured over. Defaults to 1h.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// BurnRateThreshold is the rate the error budget is consumed at above which the SLO is burning, like "2".
+	// A burn rate of 1 consumes exactly the error budget over the window. Defaults to "1".
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	BurnRateThreshold string `json:"burnRateThreshold,omitempty"`
+
+	// MaxRunnersWhileBurning raises maxRunners to this number while the SLO is burning.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	MaxRunnersWhileBurning *int `json:"maxRunnersWhileBurning,omitempty"`
+}
+
+// EgressPolicyConfig configures the egress policy resource the controller manages for the runners of the scale set,
+// so that the traffic of the runners comes from predictable IPs, which can be allowed by the firewalls of internal systems.
+type EgressPolicyConfig struct {
+	// Template is the manifest of the egress policy resource of an egress gateway solution,
+	// like a CiliumEgressGatewayPolicy of Cilium or a StaticGatewayConfiguration of the AKS static egress gateway.
+	// The controller sets its name, and its namespace unless the resource is cluster-scoped.
+	// The runner pods have the actions.github.com/scale-set-name and actions.github.com/scale-set-namespace labels to select them with.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:EmbeddedResource
+	Template runtime.RawExtension `json:"template"`
 }
 
 type GitHubServerTLSConfig struct {
 	// Required
 	CertificateFrom *TLSCertificateSource `json:"certificateFrom,omitempty"`
+
+	// ClientCertificateFrom is the client certificate presented to GitHub, like when GHES is fronted by a proxy that enforces mTLS.
+	// +optional
+	ClientCertificateFrom *TLSClientCertificateSource `json:"clientCertificateFrom,omitempty"`
 }
 
 func (c *GitHubServerTLSConfig) ToCertPool(keyFetcher func(name, key string) ([]byte, error)) (*x509.CertPool, error) {
@@ -128,6 +636,36 @@ type TLSCertificateSource struct {
 	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
 }
 
+// ToClientCertificate returns the client certificate presented to GitHub, or nil when there's none.
+func (c *GitHubServerTLSConfig) ToClientCertificate(secretFetcher func(string) (*corev1.Secret, error)) (*tls.Certificate, error) {
+	if c.ClientCertificateFrom == nil {
+		return nil, nil
+	}
+
+	if c.ClientCertificateFrom.SecretRef == nil || c.ClientCertificateFrom.SecretRef.Name == "" {
+		return nil, fmt.Errorf("secretRef not specified")
+	}
+
+	name := c.ClientCertificateFrom.SecretRef.Name
+	secret, err := secretFetcher(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %q: %w", name, err)
+	}
+
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the client certificate in secret %q: %w", name, err)
+	}
+
+	return &cert, nil
+}
+
+type TLSClientCertificateSource struct {
+	// SecretRef is the kubernetes.io/tls secret with the certificate in the tls.crt key and its private key in the tls.key key.
+	// Required
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
 type ProxyConfig struct {
 	// +optional
 	HTTP *ProxyServerConfig `json:"http,omitempty"`
@@ -248,14 +786,199 @@ type AutoscalingRunnerSetStatus struct {
 	RunningEphemeralRunners int `json:"runningEphemeralRunners"`
 	// +optional
 	FailedEphemeralRunners int `json:"failedEphemeralRunners"`
+
+	// RunnerImage is the image of the runner container of the latest EphemeralRunnerSet.
+	// A runner image build pipeline can wait for it to match the image it rolled out.
+	// +optional
+	RunnerImage string `json:"runnerImage,omitempty"`
+
+	// EgressPolicyRef refers to the egress policy resource created from spec.egressPolicy.
+	// +optional
+	EgressPolicyRef *corev1.ObjectReference `json:"egressPolicyRef,omitempty"`
+
+	// JobQueueLatencySLO is the measurement of spec.jobQueueLatencySLO.
+	// +optional
+	JobQueueLatencySLO *JobQueueLatencySLOStatus `json:"jobQueueLatencySLO,omitempty"`
+
+	// LastDriftCheckTime is the last time the runner scale set on GitHub was compared with spec.driftDetection.
+	// +optional
+	LastDriftCheckTime *metav1.Time `json:"lastDriftCheckTime,omitempty"`
+
+	// Placeholders is the state of the placeholder pods of spec.placeholders.
+	// +optional
+	Placeholders *PlaceholdersStatus `json:"placeholders,omitempty"`
+
+	// WarmPool is the state of the warm pods of spec.warmPool.
+	// +optional
+	WarmPool *WarmPoolStatus `json:"warmPool,omitempty"`
+
+	// Canary is the state of the canary of the latest runner image, when spec.canary is set.
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// CanaryStatus is the state of the canary of a runner image.
+type CanaryStatus struct {
+	// Image is the runner image of the canary.
+	Image string `json:"image"`
+
+	// SpecHash is the runner spec hash the image was rolled out with.
+	SpecHash string `json:"specHash"`
+
+	// +kubebuilder:validation:Enum=Progressing;Promoted;RolledBack
+	Phase string `json:"phase"`
+
+	// CompletedJobs is the number of jobs of the canary runners that completed.
+	// +optional
+	CompletedJobs int `json:"completedJobs"`
+
+	// FailedJobs is the number of the completed jobs of the canary runners that failed.
+	// +optional
+	FailedJobs int `json:"failedJobs"`
+}
+
+// PlaceholdersStatus is the state of the placeholder pods of a scale set.
+type PlaceholdersStatus struct {
+	// DeploymentName is the name of the deployment of the placeholder pods.
+	DeploymentName string `json:"deploymentName"`
+
+	// Replicas is the number of placeholder pods requested.
+	Replicas int `json:"replicas"`
+}
+
+// WarmPoolStatus is the state of the warm pods of a scale set.
+type WarmPoolStatus struct {
+	// DeploymentName is the name of the deployment of the warm pods.
+	DeploymentName string `json:"deploymentName"`
+
+	// Replicas is the number of warm pods requested.
+	Replicas int `json:"replicas"`
+}
+
+// AutoscalingRunnerSetConditionDegraded is true while the scale set doesn't meet its JobQueueLatencySLO.
+const AutoscalingRunnerSetConditionDegraded = "Degraded"
+
+// AutoscalingRunnerSetConditionContainerHooksCompatible is false while some of the ContainerHooks
+// are not compatible with the version of the runner image, and are not used.
+const AutoscalingRunnerSetConditionContainerHooksCompatible = "ContainerHooksCompatible"
+
+// AutoscalingRunnerSetConditionRunnerGroupPermitted is false while the runner scale set can't be created in spec.runnerGroup,
+// because the credentials can't administer the runner group or the runner group doesn't allow spec.runnerGroupRepositories.
+const AutoscalingRunnerSetConditionRunnerGroupPermitted = "RunnerGroupPermitted"
+
+// AutoscalingRunnerSetConditionScaleSetDrifted is true while the runner scale set on GitHub
+// doesn't match the spec, when spec.driftDetection is set.
+const AutoscalingRunnerSetConditionScaleSetDrifted = "ScaleSetDrifted"
+
+// AutoscalingRunnerSetConditionAdmissionWindowOpen is true while the listener acquires jobs,
+// and false outside of spec.admissionWindows.
+const AutoscalingRunnerSetConditionAdmissionWindowOpen = "AdmissionWindowOpen"
+
+// AutoscalingRunnerSetConditionPaused is true while the AnnotationKeyPaused annotation pauses the scale set.
+const AutoscalingRunnerSetConditionPaused = "Paused"
+
+// AutoscalingRunnerSetConditionGitHubReachable is true once the connectivity probe of spec.connectivityProbe
+// succeeded for the latest runner spec, and false while it runs or after it failed.
+const AutoscalingRunnerSetConditionGitHubReachable = "GitHubReachable"
+
+type JobQueueLatencySLOStatus struct {
+	// Buckets count the jobs assigned a runner over consecutive intervals of the window, oldest first.
+	// +optional
+	Buckets []JobQueueLatencyBucket `json:"buckets,omitempty"`
+
+	// LastRunnerAssignTime is the latest runner assignment time of the jobs counted,
+	// so that each job is counted once.
+	// +optional
+	LastRunnerAssignTime metav1.Time `json:"lastRunnerAssignTime,omitempty"`
+
+	// Compliance is the percentage of jobs assigned a runner within the threshold over the window.
+	// +optional
+	Compliance string `json:"compliance,omitempty"`
+
+	// BurnRate is the rate the error budget is consumed at over the window.
+	// +optional
+	BurnRate string `json:"burnRate,omitempty"`
+}
+
+type JobQueueLatencyBucket struct {
+	StartTime metav1.Time `json:"startTime"`
+
+	// Jobs is the number of jobs assigned a runner in the interval.
+	Jobs int `json:"jobs"`
+
+	// SlowJobs is the number of those jobs that waited longer than the threshold.
+	SlowJobs int `json:"slowJobs"`
 }
 
 func (ars *AutoscalingRunnerSet) ListenerSpecHash() string {
 	arsSpec := ars.Spec.DeepCopy()
+	// The container hooks are rolled out to the runner set without recreating the listener
+	arsSpec.ContainerHooks = nil
+	// Drift detection, the runner group preflight, the warm pool and the failure retention only involve the controller
+	arsSpec.DriftDetection = nil
+	arsSpec.RunnerGroupRepositories = nil
+	arsSpec.WarmPool = nil
+	arsSpec.FailureRetention = nil
+	// The canary only changes how the runner spec is rolled out
+	arsSpec.Canary = nil
+	// The placeholder pods are managed by the controller, and the job templates get listeners of their own
+	arsSpec.Placeholders = nil
+	arsSpec.JobTemplates = nil
+	// The minRunners of the listener derived from the minRunnersSchedule is compared on its own,
+	// and the windows are applied to the EphemeralRunnerSet
+	arsSpec.MinRunnersSchedule = nil
 	spec := arsSpec
 	return hash.ComputeTemplateHash(&spec)
 }
 
+// AnnotationKeyRunnerImage is the annotation that a runner image build pipeline can set on an AutoscalingRunnerSet
+// to roll out a new image of the runner container, without modifying the spec that is usually owned by Helm or a GitOps tool.
+// Its value takes precedence over the image of the runner container in spec.template.
+const AnnotationKeyRunnerImage = "actions.github.com/runner-image"
+
+// AnnotationKeyPaused is the annotation that pauses an AutoscalingRunnerSet when set to "true", for maintenance windows and incident response:
+// its listener stops acquiring jobs and no runner is created, while the runners running jobs finish them.
+const AnnotationKeyPaused = "actions.github.com/paused"
+
+// Paused returns whether the AutoscalingRunnerSet is paused by the AnnotationKeyPaused annotation.
+func (ars *AutoscalingRunnerSet) Paused() bool {
+	return ars.Annotations[AnnotationKeyPaused] == "true"
+}
+
+// runnerContainerName must be kept in sync with the controller's EphemeralRunnerContainerName.
+const runnerContainerName = "runner"
+
+// RunnerTemplate returns the pod template of the runners, with the runner image overridden
+// by the AnnotationKeyRunnerImage annotation if any.
+func (ars *AutoscalingRunnerSet) RunnerTemplate() corev1.PodTemplateSpec {
+	image := ars.Annotations[AnnotationKeyRunnerImage]
+	if image == "" {
+		return ars.Spec.Template
+	}
+
+	template := *ars.Spec.Template.DeepCopy()
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == runnerContainerName {
+			template.Spec.Containers[i].Image = image
+		}
+	}
+
+	return template
+}
+
+// RunnerGitHubConfigSecret returns the name of the secret used to register and remove runners.
+func (ars *AutoscalingRunnerSet) RunnerGitHubConfigSecret() string {
+	if ars.Spec.RunnerGitHubConfigSecret != "" {
+		return ars.Spec.RunnerGitHubConfigSecret
+	}
+	return ars.Spec.GitHubConfigSecret
+}
+
 func (ars *AutoscalingRunnerSet) RunnerSetSpecHash() string {
 	type runnerSetSpec struct {
 		GitHubConfigUrl    string
@@ -268,14 +991,23 @@ func (ars *AutoscalingRunnerSet) RunnerSetSpecHash() string {
 	}
 	spec := &runnerSetSpec{
 		GitHubConfigUrl:    ars.Spec.GitHubConfigUrl,
-		GitHubConfigSecret: ars.Spec.GitHubConfigSecret,
+		GitHubConfigSecret: ars.RunnerGitHubConfigSecret(),
 		RunnerGroup:        ars.Spec.RunnerGroup,
 		RunnerScaleSetName: ars.Spec.RunnerScaleSetName,
 		Proxy:              ars.Spec.Proxy,
 		GitHubServerTLS:    ars.Spec.GitHubServerTLS,
-		Template:           ars.Spec.Template,
+		Template:           ars.RunnerTemplate(),
 	}
-	return hash.ComputeTemplateHash(&spec)
+	if ars.Spec.WorkVolume == nil {
+		return hash.ComputeTemplateHash(&spec)
+	}
+
+	// The work volume is only hashed when set, to keep the hash of the runner sets that don't use it unchanged,
+	// so that upgrading the controller doesn't recreate their runners.
+	return hash.ComputeTemplateHash(&struct {
+		Spec       *runnerSetSpec
+		WorkVolume *WorkVolumeSource
+	}{spec, ars.Spec.WorkVolume})
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,28 @@
+package v1alpha1_test
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenerSpecHash(t *testing.T) {
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl:    "https://github.com/org/repo",
+			GitHubConfigSecret: "secret",
+		},
+	}
+	hash := ars.ListenerSpecHash()
+
+	replicas := 2
+	ars.Spec.Placeholders = &v1alpha1.Placeholders{PriorityClassName: "placeholder", Replicas: &replicas}
+	assert.Equal(t, hash, ars.ListenerSpecHash(), "the placeholders don't involve the listener")
+
+	ars.Spec.JobTemplates = []v1alpha1.JobTemplate{{Name: "gpu", Labels: []string{"gpu"}}}
+	assert.Equal(t, hash, ars.ListenerSpecHash(), "the job templates get listeners of their own")
+
+	ars.Spec.GitHubConfigSecret = "other-secret"
+	assert.NotEqual(t, hash, ars.ListenerSpecHash())
+}
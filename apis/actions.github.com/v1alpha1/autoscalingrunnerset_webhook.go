@@ -0,0 +1,202 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/pkg/githuburl"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// AutoscalingRunnerSetWebhook validates the GitHub config URL of AutoscalingRunnerSets,
+// so that a malformed URL is rejected on admission instead of failing the creation of the runner scale set with a 404.
+// It also validates their job templates, whose pod templates aren't part of the CRD schema.
+//
+// It's served at /validate-actions-github-com-v1alpha1-autoscalingrunnerset.
+// There's no kubebuilder marker for it, as the generated webhook configuration is deployed with the legacy controller,
+// which doesn't serve it. The gha-runner-scale-set-controller chart deploys its own configuration when admissionWebhook.enabled is set.
+// +kubebuilder:object:generate=false
+type AutoscalingRunnerSetWebhook struct{}
+
+func (w *AutoscalingRunnerSetWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&AutoscalingRunnerSet{}).
+		WithValidator(w).
+		Complete()
+}
+
+var _ webhook.CustomValidator = &AutoscalingRunnerSetWebhook{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *AutoscalingRunnerSetWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ars, ok := obj.(*AutoscalingRunnerSet)
+	if !ok {
+		return nil, fmt.Errorf("expected an AutoscalingRunnerSet but got a %T", obj)
+	}
+
+	return nil, ars.Validate()
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type.
+// The URL, the job templates and the work volume are validated only when they change, so that AutoscalingRunnerSets created
+// before the webhook can still be updated.
+func (w *AutoscalingRunnerSetWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	ars, ok := newObj.(*AutoscalingRunnerSet)
+	if !ok {
+		return nil, fmt.Errorf("expected an AutoscalingRunnerSet but got a %T", newObj)
+	}
+
+	old, ok := oldObj.(*AutoscalingRunnerSet)
+	if ok && old.Spec.GitHubConfigUrl == ars.Spec.GitHubConfigUrl && equality.Semantic.DeepEqual(old.Spec.JobTemplates, ars.Spec.JobTemplates) && equality.Semantic.DeepEqual(old.Spec.WorkVolume, ars.Spec.WorkVolume) {
+		return nil, nil
+	}
+
+	return nil, ars.Validate()
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *AutoscalingRunnerSetWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Validate validates the GitHub config URL, the job templates and the work volume of the AutoscalingRunnerSet.
+func (ars *AutoscalingRunnerSet) Validate() error {
+	var errList field.ErrorList
+
+	if _, err := githuburl.Parse(ars.Spec.GitHubConfigUrl); err != nil {
+		errList = append(errList, field.Invalid(field.NewPath("spec", "githubConfigUrl"), ars.Spec.GitHubConfigUrl, err.Error()))
+	}
+
+	errList = append(errList, ars.validateJobTemplates(field.NewPath("spec", "jobTemplates"))...)
+
+	if v := ars.Spec.WorkVolume; v != nil {
+		sources := 0
+		for _, set := range []bool{v.EmptyDir != nil, v.Ephemeral != nil, v.HostPath != nil} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			errList = append(errList, field.Invalid(field.NewPath("spec", "workVolume"), sources, "exactly one of emptyDir, ephemeral and hostPath must be set"))
+		}
+	}
+
+	if len(errList) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("AutoscalingRunnerSet").GroupKind(), ars.Name, errList)
+	}
+
+	return nil
+}
+
+// maxAutoscalingRunnerSetNameLength is the length of the longest AutoscalingRunnerSet name the gha-runner-scale-set chart accepts,
+// for the names of the resources of the scale set derived from it to fit.
+const maxAutoscalingRunnerSetNameLength = 45
+
+// validateJobTemplates validates the job templates, which have their own runner scale sets and AutoscalingRunnerSets,
+// and their pod templates, which were already decoded as pod templates.
+func (ars *AutoscalingRunnerSet) validateJobTemplates(path *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	labels := make(map[string]string)
+	for i := range ars.Spec.JobTemplates {
+		t := &ars.Spec.JobTemplates[i]
+		p := path.Index(i)
+
+		if name := ars.JobTemplateName(t); len(name) > maxAutoscalingRunnerSetNameLength || len(validation.IsDNS1035Label(name)) > 0 {
+			errList = append(errList, field.Invalid(p.Child("name"), t.Name, fmt.Sprintf("the name of its AutoscalingRunnerSet, %q, must be a lowercase RFC 1035 label of at most %d characters", name, maxAutoscalingRunnerSetNameLength)))
+		}
+
+		if len(t.Labels) == 0 {
+			errList = append(errList, field.Required(p.Child("labels"), "the jobs of the job template are routed to it by its labels"))
+		}
+		key := labelSetKey(t.Labels)
+		if other, ok := labels[key]; ok {
+			errList = append(errList, field.Invalid(p.Child("labels"), t.Labels, fmt.Sprintf("the job template %q has the same labels", other)))
+		}
+		labels[key] = t.Name
+
+		if t.MinRunners != nil && t.MaxRunners != nil && *t.MinRunners > *t.MaxRunners {
+			errList = append(errList, field.Invalid(p.Child("minRunners"), *t.MinRunners, "it must not be greater than maxRunners"))
+		}
+
+		errList = append(errList, validateRunnerPodTemplate(&t.Template, p.Child("template"))...)
+	}
+
+	return errList
+}
+
+// validateRunnerPodTemplate validates the pod template of runners: its labels and annotations,
+// and its containers, which must include the runner container.
+func validateRunnerPodTemplate(template *corev1.PodTemplateSpec, path *field.Path) field.ErrorList {
+	errList := metav1validation.ValidateLabels(template.Labels, path.Child("metadata", "labels"))
+	errList = append(errList, apivalidation.ValidateAnnotations(template.Annotations, path.Child("metadata", "annotations"))...)
+
+	containersPath := path.Child("spec", "containers")
+	if len(template.Spec.Containers) == 0 {
+		return append(errList, field.Required(containersPath, "the pod template must have the runner container"))
+	}
+
+	names := make(map[string]bool)
+	for i, container := range append(slices.Clone(template.Spec.InitContainers), template.Spec.Containers...) {
+		p := path.Child("spec", "initContainers").Index(i)
+		if i >= len(template.Spec.InitContainers) {
+			p = containersPath.Index(i - len(template.Spec.InitContainers))
+		}
+
+		for _, msg := range validation.IsDNS1123Label(container.Name) {
+			errList = append(errList, field.Invalid(p.Child("name"), container.Name, msg))
+		}
+		if names[container.Name] {
+			errList = append(errList, field.Duplicate(p.Child("name"), container.Name))
+		}
+		names[container.Name] = true
+
+		if container.Image == "" {
+			errList = append(errList, field.Required(p.Child("image"), ""))
+		}
+	}
+
+	if !slices.ContainsFunc(template.Spec.Containers, func(c corev1.Container) bool { return c.Name == runnerContainerName }) {
+		errList = append(errList, field.Required(containersPath, fmt.Sprintf("the pod template must have the runner container, named %q", runnerContainerName)))
+	}
+
+	return errList
+}
+
+// labelSetKey returns the labels lowercased, sorted and comma-separated, as GitHub matches them case-insensitively and in any order.
+func labelSetKey(labels []string) string {
+	key := make([]string, 0, len(labels))
+	for _, label := range labels {
+		key = append(key, strings.ToLower(label))
+	}
+	slices.Sort(key)
+	return strings.Join(key, ",")
+}
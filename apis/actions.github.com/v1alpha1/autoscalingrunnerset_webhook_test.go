@@ -0,0 +1,109 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAutoscalingRunnerSetWebhook_Validate(t *testing.T) {
+	w := &v1alpha1.AutoscalingRunnerSetWebhook{}
+
+	newARS := func(url string) *v1alpha1.AutoscalingRunnerSet {
+		return &v1alpha1.AutoscalingRunnerSet{
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{GitHubConfigUrl: url},
+		}
+	}
+
+	_, err := w.ValidateCreate(context.Background(), newARS("https://github.com/my-org/my-repo/"))
+	require.NoError(t, err)
+
+	_, err = w.ValidateCreate(context.Background(), newARS("https://api.github.com/repos/my-org/my-repo"))
+	require.ErrorContains(t, err, `spec.githubConfigUrl`)
+	require.ErrorContains(t, err, `Did you mean "https://github.com/my-org/my-repo"?`)
+
+	// An AutoscalingRunnerSet created before the webhook can be updated without fixing its URL
+	_, err = w.ValidateUpdate(context.Background(), newARS("github.com/my-org"), newARS("github.com/my-org"))
+	require.NoError(t, err)
+
+	_, err = w.ValidateUpdate(context.Background(), newARS("https://github.com/my-org"), newARS("github.com/my-org"))
+	require.ErrorContains(t, err, `it must start with http:// or https://. Did you mean "https://github.com/my-org"?`)
+}
+
+func TestAutoscalingRunnerSetWebhook_ValidateWorkVolume(t *testing.T) {
+	w := &v1alpha1.AutoscalingRunnerSetWebhook{}
+
+	newARS := func(workVolume *v1alpha1.WorkVolumeSource) *v1alpha1.AutoscalingRunnerSet {
+		return &v1alpha1.AutoscalingRunnerSet{
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{GitHubConfigUrl: "https://github.com/my-org", WorkVolume: workVolume},
+		}
+	}
+
+	_, err := w.ValidateCreate(context.Background(), newARS(&v1alpha1.WorkVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}))
+	require.NoError(t, err)
+
+	// Like "emptyDir: null", which passes the minProperties of the CRD
+	_, err = w.ValidateCreate(context.Background(), newARS(&v1alpha1.WorkVolumeSource{}))
+	require.ErrorContains(t, err, "spec.workVolume")
+
+	_, err = w.ValidateUpdate(context.Background(), newARS(nil), newARS(&v1alpha1.WorkVolumeSource{
+		EmptyDir: &corev1.EmptyDirVolumeSource{},
+		HostPath: &corev1.HostPathVolumeSource{Path: "/var/lib/runner-work"},
+	}))
+	require.ErrorContains(t, err, "exactly one of emptyDir, ephemeral and hostPath must be set")
+}
+
+func TestAutoscalingRunnerSetWebhook_ValidateJobTemplates(t *testing.T) {
+	w := &v1alpha1.AutoscalingRunnerSetWebhook{}
+
+	runnerTemplate := func(containers ...corev1.Container) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: containers}}
+	}
+	newARS := func(templates ...v1alpha1.JobTemplate) *v1alpha1.AutoscalingRunnerSet {
+		return &v1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "arc-runners"},
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{
+				GitHubConfigUrl: "https://github.com/my-org",
+				JobTemplates:    templates,
+			},
+		}
+	}
+	gpu := v1alpha1.JobTemplate{
+		Name:     "gpu",
+		Labels:   []string{"gpu"},
+		Template: runnerTemplate(corev1.Container{Name: "runner", Image: "ghcr.io/actions/actions-runner:latest"}),
+	}
+
+	_, err := w.ValidateCreate(context.Background(), newARS(gpu))
+	require.NoError(t, err)
+
+	noRunner := gpu
+	noRunner.Template = runnerTemplate(corev1.Container{Name: "build", Image: "ghcr.io/actions/actions-runner:latest"})
+	_, err = w.ValidateCreate(context.Background(), newARS(noRunner))
+	require.ErrorContains(t, err, `spec.jobTemplates[0].template.spec.containers: Required value: the pod template must have the runner container, named "runner"`)
+
+	noImage := gpu
+	noImage.Template = runnerTemplate(corev1.Container{Name: "runner"}, corev1.Container{Name: "runner", Image: "docker:dind"})
+	_, err = w.ValidateCreate(context.Background(), newARS(noImage))
+	require.ErrorContains(t, err, `spec.jobTemplates[0].template.spec.containers[0].image: Required value`)
+	require.ErrorContains(t, err, `spec.jobTemplates[0].template.spec.containers[1].name: Duplicate value: "runner"`)
+
+	sameLabels := gpu
+	sameLabels.Name = "gpu-2"
+	sameLabels.Labels = []string{"GPU"}
+	_, err = w.ValidateCreate(context.Background(), newARS(gpu, sameLabels))
+	require.ErrorContains(t, err, `spec.jobTemplates[1].labels: Invalid value: []string{"GPU"}: the job template "gpu" has the same labels`)
+
+	uppercase := gpu
+	uppercase.Name = "GPU"
+	_, err = w.ValidateCreate(context.Background(), newARS(uppercase))
+	require.ErrorContains(t, err, `spec.jobTemplates[0].name`)
+
+	// The job templates of an AutoscalingRunnerSet are validated when they change
+	_, err = w.ValidateUpdate(context.Background(), newARS(gpu), newARS(noRunner))
+	require.ErrorContains(t, err, `spec.jobTemplates[0].template.spec.containers`)
+}
@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 //+kubebuilder:object:root=true
@@ -71,6 +72,34 @@ type EphemeralRunnerSpec struct {
 
 	// +required
 	corev1.PodTemplateSpec `json:",inline"`
+
+	// WorkVolume is the volume mounted at the work directory of the runner container, /home/runner/_work,
+	// where jobs check out repositories and write build outputs.
+	// It replaces the source of the "work" volume of the pod template when there's one, like in the dind and kubernetes
+	// container modes, and is added otherwise.
+	// +optional
+	WorkVolume *WorkVolumeSource `json:"workVolume,omitempty"`
+}
+
+// WorkVolumeSource is the source of the work volume of the runner. Exactly one of the sources must be set.
+// +kubebuilder:validation:MinProperties=1
+// +kubebuilder:validation:MaxProperties=1
+type WorkVolumeSource struct {
+	// EmptyDir is a node-local volume deleted along with the runner pod.
+	// When sizeLimit is set, the runner container requests as much ephemeral storage, so that the scheduler only places
+	// the pod on a node with enough disk, and the kubelet evicts the runner pod alone when the jobs exceed it.
+	// +optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+
+	// Ephemeral is a generic ephemeral volume, provisioned per runner pod by a storage class,
+	// whose size is enforced by the volume itself.
+	// +optional
+	Ephemeral *corev1.EphemeralVolumeSource `json:"ephemeral,omitempty"`
+
+	// HostPath is a directory of the node, kept across runner pods to reuse e.g. checkouts and caches.
+	// Runner pods on the same node share it, so it's only suitable for trusted workflows.
+	// +optional
+	HostPath *corev1.HostPathVolumeSource `json:"hostPath,omitempty"`
 }
 
 // EphemeralRunnerStatus defines the observed state of EphemeralRunner
@@ -107,6 +136,21 @@ type EphemeralRunnerStatus struct {
 	// +optional
 	Failures map[string]bool `json:"failures,omitempty"`
 
+	// PodUID is the UID of the last pod created for the runner.
+	// It tells a pod deleted out of band, e.g. by a user or a node drain, from a pod deleted by the controller.
+	// +optional
+	PodUID types.UID `json:"podUID,omitempty"`
+
+	// LostPods are the UIDs of the pods of the runner deleted out of band.
+	// Unlike Failures, they don't count towards the pod failures the runner tolerates.
+	// +optional
+	LostPods map[string]bool `json:"lostPods,omitempty"`
+
+	// LastFailure is the diagnostic captured from the last pod of the runner that failed,
+	// when the scale set has a failureRetention.
+	// +optional
+	LastFailure *EphemeralRunnerFailure `json:"lastFailure,omitempty"`
+
 	// +optional
 	JobRequestId int64 `json:"jobRequestId,omitempty"`
 
@@ -121,6 +165,44 @@ type EphemeralRunnerStatus struct {
 
 	// +optional
 	JobDisplayName string `json:"jobDisplayName,omitempty"`
+
+	// JobQueueTime is the time the job was queued. JobScaleSetAssignTime and JobRunnerAssignTime are the times
+	// the job was assigned to the scale set and to the runner, from the JobAssigned and JobStarted messages.
+	// The JobQueueLatencySLO of the scale set is measured between the latter two, which leaves out the time the job
+	// waited for anything but a runner of the scale set, like an environment approval or a concurrency group.
+	// +optional
+	JobQueueTime *metav1.Time `json:"jobQueueTime,omitempty"`
+	// +optional
+	JobScaleSetAssignTime *metav1.Time `json:"jobScaleSetAssignTime,omitempty"`
+	// +optional
+	JobRunnerAssignTime *metav1.Time `json:"jobRunnerAssignTime,omitempty"`
+}
+
+// EphemeralRunnerFailure is the diagnostic captured from a failed pod of an EphemeralRunner.
+type EphemeralRunnerFailure struct {
+	// PodUID is the UID of the failed pod.
+	PodUID types.UID `json:"podUID"`
+
+	// Time is when the failure was observed.
+	Time metav1.Time `json:"time"`
+
+	// Reason and Message are the ones of the status of the failed pod.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ExitCode is the exit code of the runner container, when it terminated.
+	// +optional
+	ExitCode int32 `json:"exitCode,omitempty"`
+
+	// Logs are the last lines of the logs of the runner container.
+	// +optional
+	Logs string `json:"logs,omitempty"`
+
+	// Retained is true when the failed pod is kept instead of being deleted.
+	// +optional
+	Retained bool `json:"retained,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -27,7 +27,40 @@ type EphemeralRunnerSetSpec struct {
 	// PatchID is the unique identifier for the patch issued by the listener app
 	PatchID int `json:"patchID"`
 
+	// MaxReplicas caps Replicas. The AutoscalingRunnerSet controller sets it to the maxRunners of a scale set
+	// with a JobQueueLatencySLO, whose listener scales up to maxRunnersWhileBurning, so that maxRunners is raised
+	// and lowered with the burning state of the SLO without recreating the listener.
+	// +optional
+	MaxReplicas *int `json:"maxReplicas,omitempty"`
+
+	// AdditionalReplicas is added to Replicas before MaxReplicas caps it. The AutoscalingRunnerSet controller sets it
+	// to the difference between the minRunners of the windows of a minRunnersSchedule active now and the minRunners of the listener,
+	// which is the lowest of the schedule, so that minRunners changes as the windows start and end without recreating the listener.
+	// +optional
+	AdditionalReplicas int `json:"additionalReplicas,omitempty"`
+
 	EphemeralRunnerSpec EphemeralRunnerSpec `json:"ephemeralRunnerSpec,omitempty"`
+
+	// ContainerHooks are the versions of the runner container hooks new EphemeralRunners are spread between.
+	// +optional
+	ContainerHooks []ContainerHooks `json:"containerHooks,omitempty"`
+
+	// Canary is the runner image a percentage of the new EphemeralRunners use while it is a canary.
+	// +optional
+	Canary *EphemeralRunnerSetCanary `json:"canary,omitempty"`
+}
+
+// EphemeralRunnerSetCanary is the canary of a runner image in an EphemeralRunnerSet.
+type EphemeralRunnerSetCanary struct {
+	// Image is the image of the runner container of the canary EphemeralRunners.
+	Image string `json:"image"`
+
+	// Percentage is the share of the new EphemeralRunners that are canary EphemeralRunners.
+	Percentage int `json:"percentage"`
+
+	// SpecHash is the runner spec hash of the AutoscalingRunnerSet the image comes from.
+	// The canary EphemeralRunners are labeled with it.
+	SpecHash string `json:"specHash"`
 }
 
 // EphemeralRunnerSetStatus defines the observed state of EphemeralRunnerSet
@@ -43,6 +76,22 @@ type EphemeralRunnerSetStatus struct {
 	RunningEphemeralRunners int `json:"runningEphemeralRunners"`
 	// +optional
 	FailedEphemeralRunners int `json:"failedEphemeralRunners"`
+
+	// Canary counts the EphemeralRunners created since the canary in the spec started.
+	// +optional
+	Canary *EphemeralRunnerSetCanaryStatus `json:"canary,omitempty"`
+}
+
+// EphemeralRunnerSetCanaryStatus counts the EphemeralRunners created since the canary of a runner image started.
+type EphemeralRunnerSetCanaryStatus struct {
+	// SpecHash is the runner spec hash of the canary.
+	SpecHash string `json:"specHash"`
+
+	// CreatedRunners is the number of EphemeralRunners created since the canary started.
+	CreatedRunners int `json:"createdRunners"`
+
+	// CanaryRunners is the number of the created EphemeralRunners that are canary EphemeralRunners.
+	CanaryRunners int `json:"canaryRunners"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The modes of generating the jobs of a LoadTest.
+const (
+	LoadTestModeDispatch   = "Dispatch"
+	LoadTestModeSimulation = "Simulation"
+)
+
+// The phases of a LoadTest.
+const (
+	LoadTestPhaseRunning   = "Running"
+	LoadTestPhaseCompleted = "Completed"
+	LoadTestPhaseFailed    = "Failed"
+)
+
+// LoadTestSpec defines the desired state of LoadTest
+type LoadTestSpec struct {
+	// AutoscalingRunnerSetName is the name of the scale set, in the namespace of the LoadTest, whose scaling is measured.
+	// Required
+	AutoscalingRunnerSetName string `json:"autoscalingRunnerSetName,omitempty"`
+
+	// Mode is how the jobs are generated.
+	// Dispatch creates workflow_dispatch runs of the workflow, which reach the scale set through GitHub like any job,
+	// and measures the time from the jobs being queued to a runner being assigned to them.
+	// Simulation scales a runner set of the scale set up as its listener would for acquired jobs, without involving GitHub,
+	// and measures the time from each simulated job to a runner pod running for it. Defaults to Dispatch.
+	// +optional
+	// +kubebuilder:validation:Enum=Dispatch;Simulation
+	Mode string `json:"mode,omitempty"`
+
+	// Workflow is the workflow dispatched in the Dispatch mode.
+	// +optional
+	Workflow *LoadTestWorkflow `json:"workflow,omitempty"`
+
+	// JobsPerMinute is the rate the jobs are generated at.
+	// Required
+	// +kubebuilder:validation:Minimum=1
+	JobsPerMinute int `json:"jobsPerMinute,omitempty"`
+
+	// Jobs is the number of jobs generated.
+	// Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=2000
+	Jobs int `json:"jobs,omitempty"`
+
+	// Timeout is how long the scaling of the jobs is waited for after the last one is generated,
+	// before the load test fails. Defaults to 10m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// LoadTestWorkflow is the workflow dispatched by a LoadTest.
+type LoadTestWorkflow struct {
+	// Repository is the repository of the workflow, as owner/name, or as name for the repositories of the organization of the scale set.
+	// Defaults to the repository of the scale set.
+	// +optional
+	Repository string `json:"repository,omitempty"`
+
+	// Workflow is the file name or the ID of the workflow. It must be triggered by workflow_dispatch and run on the scale set.
+	// Required
+	Workflow string `json:"workflow,omitempty"`
+
+	// Ref is the branch or the tag the workflow is dispatched on. Defaults to main.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Inputs are the inputs of the dispatched workflow runs.
+	// +optional
+	Inputs map[string]string `json:"inputs,omitempty"`
+}
+
+// LoadTestStatus defines the observed state of LoadTest
+type LoadTestStatus struct {
+	// +optional
+	// +kubebuilder:validation:Enum=Running;Completed;Failed
+	Phase string `json:"phase,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// GeneratedJobs is the number of jobs generated so far.
+	// +optional
+	GeneratedJobs int `json:"generatedJobs,omitempty"`
+
+	// ScalingLatency are the percentiles of the scaling latency of the jobs observed so far.
+	// +optional
+	ScalingLatency *LoadTestLatency `json:"scalingLatency,omitempty"`
+
+	// Samples are the jobs whose scaling was observed.
+	// +optional
+	Samples []LoadTestSample `json:"samples,omitempty"`
+}
+
+// LoadTestLatency are percentiles of the scaling latency of the jobs of a LoadTest.
+type LoadTestLatency struct {
+	P50 metav1.Duration `json:"p50"`
+	P90 metav1.Duration `json:"p90"`
+	P99 metav1.Duration `json:"p99"`
+	Max metav1.Duration `json:"max"`
+}
+
+// LoadTestSample is a job of a LoadTest whose scaling was observed.
+type LoadTestSample struct {
+	// Name identifies the job: the ID of its runner request in the Dispatch mode, or the name of its EphemeralRunner in the Simulation mode.
+	Name string `json:"name"`
+
+	// GeneratedTime is when the job was queued in the Dispatch mode, or when it was simulated in the Simulation mode.
+	GeneratedTime metav1.Time `json:"generatedTime"`
+
+	// ScaledTime is when a runner was assigned to the job in the Dispatch mode, or when its runner pod started in the Simulation mode.
+	ScaledTime metav1.Time `json:"scaledTime"`
+}
+
+// Latency returns the scaling latency of the job.
+func (s *LoadTestSample) Latency() time.Duration {
+	return s.ScaledTime.Sub(s.GeneratedTime.Time)
+}
+
+// LoadTestMode returns the mode the jobs of the load test are generated in.
+func (lt *LoadTest) LoadTestMode() string {
+	if lt.Spec.Mode != "" {
+		return lt.Spec.Mode
+	}
+	return LoadTestModeDispatch
+}
+
+// JobInterval returns the interval the jobs of the load test are generated at.
+func (lt *LoadTest) JobInterval() time.Duration {
+	return time.Minute / time.Duration(lt.Spec.JobsPerMinute)
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:JSONPath=".spec.autoscalingRunnerSetName",name=Scale Set,type=string
+//+kubebuilder:printcolumn:JSONPath=".spec.mode",name=Mode,type=string
+//+kubebuilder:printcolumn:JSONPath=".status.phase",name=Phase,type=string
+//+kubebuilder:printcolumn:JSONPath=".status.generatedJobs",name=Generated,type=integer
+//+kubebuilder:printcolumn:JSONPath=".status.scalingLatency.p90",name=P90,type=string
+
+// LoadTest is the Schema for the loadtests API
+type LoadTest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LoadTestSpec   `json:"spec,omitempty"`
+	Status LoadTestStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// LoadTestList contains a list of LoadTest
+type LoadTestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LoadTest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LoadTest{}, &LoadTestList{})
+}
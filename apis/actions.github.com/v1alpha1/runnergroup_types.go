@@ -0,0 +1,152 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The policies of what happens to the runner group on GitHub when its RunnerGroup is deleted.
+const (
+	RunnerGroupDeletionPolicyDelete = "Delete"
+	RunnerGroupDeletionPolicyOrphan = "Orphan"
+)
+
+// RunnerGroupConditionSynced tells whether the runner group on GitHub matches the spec of the RunnerGroup.
+const RunnerGroupConditionSynced = "Synced"
+
+// RunnerGroupSpec defines the desired state of RunnerGroup
+type RunnerGroupSpec struct {
+	// GitHubConfigUrl is the URL of the organization or the enterprise the runner group belongs to.
+	// Required
+	GitHubConfigUrl string `json:"githubConfigUrl,omitempty"`
+
+	// Required
+	GitHubConfigSecret string `json:"githubConfigSecret,omitempty"`
+
+	// Name is the name of the runner group on GitHub. Defaults to the name of the RunnerGroup.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Adopt tells whether an existing runner group with the same name is managed by the RunnerGroup.
+	// Otherwise, the RunnerGroup isn't synced while a runner group it didn't create has its name.
+	// +optional
+	Adopt bool `json:"adopt,omitempty"`
+
+	// Visibility tells the repositories, or the organizations for an enterprise, that can use the runner group:
+	// all of them, the selected ones, or only the private ones. Defaults to all.
+	// The runner groups of enterprises can't be restricted to selected organizations.
+	// +optional
+	// +kubebuilder:validation:Enum=all;selected;private
+	Visibility string `json:"visibility,omitempty"`
+
+	// SelectedRepositories are the names of the repositories of the organization that can use the runner group
+	// when its visibility is selected.
+	// +optional
+	SelectedRepositories []string `json:"selectedRepositories,omitempty"`
+
+	// AllowsPublicRepositories tells whether public repositories can use the runner group.
+	// +optional
+	AllowsPublicRepositories bool `json:"allowsPublicRepositories,omitempty"`
+
+	// DeletionPolicy tells whether the runner group is deleted from GitHub when the RunnerGroup is deleted,
+	// or left as it is. Defaults to Delete for the runner groups the RunnerGroup created, and to Orphan for the adopted ones.
+	// +optional
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// +optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+
+	// +optional
+	GitHubServerTLS *GitHubServerTLSConfig `json:"githubServerTLS,omitempty"`
+}
+
+// RunnerGroupStatus defines the observed state of RunnerGroup
+type RunnerGroupStatus struct {
+	// ID is the ID of the runner group on GitHub.
+	// +optional
+	ID int64 `json:"id,omitempty"`
+
+	// Adopted tells whether the runner group existed before the RunnerGroup, rather than being created by it.
+	// +optional
+	Adopted bool `json:"adopted,omitempty"`
+
+	// LastSyncTime is when the runner group on GitHub was last found to match the spec.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// RunnerGroupName returns the name of the runner group on GitHub.
+func (rg *RunnerGroup) RunnerGroupName() string {
+	if rg.Spec.Name != "" {
+		return rg.Spec.Name
+	}
+	return rg.Name
+}
+
+// RunnerGroupDeletionPolicy returns the policy of what happens to the runner group on GitHub when the RunnerGroup is deleted.
+func (rg *RunnerGroup) RunnerGroupDeletionPolicy() string {
+	if rg.Spec.DeletionPolicy != "" {
+		return rg.Spec.DeletionPolicy
+	}
+	if rg.Status.Adopted {
+		return RunnerGroupDeletionPolicyOrphan
+	}
+	return RunnerGroupDeletionPolicyDelete
+}
+
+// RunnerGroupVisibility returns the visibility of the runner group on GitHub.
+func (rg *RunnerGroup) RunnerGroupVisibility() string {
+	if rg.Spec.Visibility != "" {
+		return rg.Spec.Visibility
+	}
+	return "all"
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:JSONPath=".spec.githubConfigUrl",name=GitHub Configure URL,type=string
+//+kubebuilder:printcolumn:JSONPath=".status.id",name=ID,type=integer
+//+kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type==\"Synced\")].status",name=Synced,type=string
+
+// RunnerGroup is the Schema for the runnergroups API
+type RunnerGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RunnerGroupSpec   `json:"spec,omitempty"`
+	Status RunnerGroupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RunnerGroupList contains a list of RunnerGroup
+type RunnerGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RunnerGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RunnerGroup{}, &RunnerGroupList{})
+}
@@ -103,3 +103,79 @@ func TestGitHubServerTLSConfig_ToCertPool(t *testing.T) {
 		assert.True(t, serverSuccessfullyCalled)
 	})
 }
+
+func TestGitHubServerTLSConfig_ToClientCertificate(t *testing.T) {
+	certsFolder := filepath.Join(
+		"../../../",
+		"github",
+		"actions",
+		"testdata",
+	)
+
+	t.Run("returns nil if ClientCertificateFrom not specified", func(t *testing.T) {
+		c := &v1alpha1.GitHubServerTLSConfig{}
+
+		cert, err := c.ToClientCertificate(nil)
+		require.NoError(t, err)
+		assert.Nil(t, cert)
+	})
+
+	t.Run("returns an error if ClientCertificateFrom.SecretRef not specified", func(t *testing.T) {
+		c := &v1alpha1.GitHubServerTLSConfig{
+			ClientCertificateFrom: &v1alpha1.TLSClientCertificateSource{},
+		}
+
+		cert, err := c.ToClientCertificate(nil)
+		assert.Nil(t, cert)
+
+		require.Error(t, err)
+		assert.Equal(t, err.Error(), "secretRef not specified")
+	})
+
+	t.Run("returns the certificate of the secret", func(t *testing.T) {
+		c := &v1alpha1.GitHubServerTLSConfig{
+			ClientCertificateFrom: &v1alpha1.TLSClientCertificateSource{
+				SecretRef: &v1.LocalObjectReference{Name: "client-cert"},
+			},
+		}
+
+		crt, err := os.ReadFile(filepath.Join(certsFolder, "server.crt"))
+		require.NoError(t, err)
+		key, err := os.ReadFile(filepath.Join(certsFolder, "server.key"))
+		require.NoError(t, err)
+
+		fetcher := func(name string) (*v1.Secret, error) {
+			assert.Equal(t, "client-cert", name)
+			return &v1.Secret{
+				Data: map[string][]byte{
+					v1.TLSCertKey:       crt,
+					v1.TLSPrivateKeyKey: key,
+				},
+			}, nil
+		}
+
+		cert, err := c.ToClientCertificate(fetcher)
+		require.NoError(t, err)
+		require.NotNil(t, cert)
+		assert.NotEmpty(t, cert.Certificate)
+	})
+
+	t.Run("returns an error if the secret has no key", func(t *testing.T) {
+		c := &v1alpha1.GitHubServerTLSConfig{
+			ClientCertificateFrom: &v1alpha1.TLSClientCertificateSource{
+				SecretRef: &v1.LocalObjectReference{Name: "client-cert"},
+			},
+		}
+
+		crt, err := os.ReadFile(filepath.Join(certsFolder, "server.crt"))
+		require.NoError(t, err)
+
+		fetcher := func(name string) (*v1.Secret, error) {
+			return &v1.Secret{Data: map[string][]byte{v1.TLSCertKey: crt}}, nil
+		}
+
+		cert, err := c.ToClientCertificate(fetcher)
+		assert.Nil(t, cert)
+		assert.Error(t, err)
+	})
+}
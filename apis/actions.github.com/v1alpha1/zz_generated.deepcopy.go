@@ -22,9 +22,26 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionWindow) DeepCopyInto(out *AdmissionWindow) {
+	*out = *in
+	in.DailyWindow.DeepCopyInto(&out.DailyWindow)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionWindow.
+func (in *AdmissionWindow) DeepCopy() *AdmissionWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AutoscalingListener) DeepCopyInto(out *AutoscalingListener) {
 	*out = *in
@@ -107,6 +124,18 @@ func (in *AutoscalingListenerSpec) DeepCopyInto(out *AutoscalingListenerSpec) {
 		*out = new(v1.PodTemplateSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AllowedRepositories != nil {
+		in, out := &in.AllowedRepositories, &out.AllowedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdmissionWindows != nil {
+		in, out := &in.AdmissionWindows, &out.AdmissionWindows
+		*out = make([]AdmissionWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingListenerSpec.
@@ -140,7 +169,7 @@ func (in *AutoscalingRunnerSet) DeepCopyInto(out *AutoscalingRunnerSet) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingRunnerSet.
@@ -196,6 +225,21 @@ func (in *AutoscalingRunnerSetList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AutoscalingRunnerSetSpec) DeepCopyInto(out *AutoscalingRunnerSetSpec) {
 	*out = *in
+	if in.RunnerGroupRepositories != nil {
+		in, out := &in.RunnerGroupRepositories, &out.RunnerGroupRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedRepositories != nil {
+		in, out := &in.AllowedRepositories, &out.AllowedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RunnerScaleSetLabels != nil {
+		in, out := &in.RunnerScaleSetLabels, &out.RunnerScaleSetLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Proxy != nil {
 		in, out := &in.Proxy, &out.Proxy
 		*out = new(ProxyConfig)
@@ -222,6 +266,84 @@ func (in *AutoscalingRunnerSetSpec) DeepCopyInto(out *AutoscalingRunnerSetSpec)
 		*out = new(int)
 		**out = **in
 	}
+	if in.MinRunnersSchedule != nil {
+		in, out := &in.MinRunnersSchedule, &out.MinRunnersSchedule
+		*out = make([]MinRunnersWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EgressPolicy != nil {
+		in, out := &in.EgressPolicy, &out.EgressPolicy
+		*out = new(EgressPolicyConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JobQueueLatencySLO != nil {
+		in, out := &in.JobQueueLatencySLO, &out.JobQueueLatencySLO
+		*out = new(JobQueueLatencySLO)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerHooks != nil {
+		in, out := &in.ContainerHooks, &out.ContainerHooks
+		*out = make([]ContainerHooks, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.JobTemplates != nil {
+		in, out := &in.JobTemplates, &out.JobTemplates
+		*out = make([]JobTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WorkVolume != nil {
+		in, out := &in.WorkVolume, &out.WorkVolume
+		*out = new(WorkVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(CanaryRollout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DriftDetection != nil {
+		in, out := &in.DriftDetection, &out.DriftDetection
+		*out = new(DriftDetection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Placeholders != nil {
+		in, out := &in.Placeholders, &out.Placeholders
+		*out = new(Placeholders)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WarmPool != nil {
+		in, out := &in.WarmPool, &out.WarmPool
+		*out = new(WarmPool)
+		**out = **in
+	}
+	if in.FailureRetention != nil {
+		in, out := &in.FailureRetention, &out.FailureRetention
+		*out = new(FailureRetention)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectivityProbe != nil {
+		in, out := &in.ConnectivityProbe, &out.ConnectivityProbe
+		*out = new(ConnectivityProbe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdmissionWindows != nil {
+		in, out := &in.AdmissionWindows, &out.AdmissionWindows
+		*out = make([]AdmissionWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ListenerShards != nil {
+		in, out := &in.ListenerShards, &out.ListenerShards
+		*out = new(int)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingRunnerSetSpec.
@@ -237,6 +359,42 @@ func (in *AutoscalingRunnerSetSpec) DeepCopy() *AutoscalingRunnerSetSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AutoscalingRunnerSetStatus) DeepCopyInto(out *AutoscalingRunnerSetStatus) {
 	*out = *in
+	if in.EgressPolicyRef != nil {
+		in, out := &in.EgressPolicyRef, &out.EgressPolicyRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+	if in.JobQueueLatencySLO != nil {
+		in, out := &in.JobQueueLatencySLO, &out.JobQueueLatencySLO
+		*out = new(JobQueueLatencySLOStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastDriftCheckTime != nil {
+		in, out := &in.LastDriftCheckTime, &out.LastDriftCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Placeholders != nil {
+		in, out := &in.Placeholders, &out.Placeholders
+		*out = new(PlaceholdersStatus)
+		**out = **in
+	}
+	if in.WarmPool != nil {
+		in, out := &in.WarmPool, &out.WarmPool
+		*out = new(WarmPoolStatus)
+		**out = **in
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(CanaryStatus)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingRunnerSetStatus.
@@ -249,6 +407,152 @@ func (in *AutoscalingRunnerSetStatus) DeepCopy() *AutoscalingRunnerSetStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryRollout) DeepCopyInto(out *CanaryRollout) {
+	*out = *in
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxFailedJobsPercentage != nil {
+		in, out := &in.MaxFailedJobsPercentage, &out.MaxFailedJobsPercentage
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryRollout.
+func (in *CanaryRollout) DeepCopy() *CanaryRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryStatus) DeepCopyInto(out *CanaryStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryStatus.
+func (in *CanaryStatus) DeepCopy() *CanaryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectivityProbe) DeepCopyInto(out *ConnectivityProbe) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectivityProbe.
+func (in *ConnectivityProbe) DeepCopy() *ConnectivityProbe {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectivityProbe)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerHooks) DeepCopyInto(out *ContainerHooks) {
+	*out = *in
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerHooks.
+func (in *ContainerHooks) DeepCopy() *ContainerHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DailyWindow) DeepCopyInto(out *DailyWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]WindowDay, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DailyWindow.
+func (in *DailyWindow) DeepCopy() *DailyWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(DailyWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftDetection) DeepCopyInto(out *DriftDetection) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftDetection.
+func (in *DriftDetection) DeepCopy() *DriftDetection {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftDetection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressPolicyConfig) DeepCopyInto(out *EgressPolicyConfig) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressPolicyConfig.
+func (in *EgressPolicyConfig) DeepCopy() *EgressPolicyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressPolicyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EphemeralRunner) DeepCopyInto(out *EphemeralRunner) {
 	*out = *in
@@ -276,6 +580,22 @@ func (in *EphemeralRunner) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EphemeralRunnerFailure) DeepCopyInto(out *EphemeralRunnerFailure) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerFailure.
+func (in *EphemeralRunnerFailure) DeepCopy() *EphemeralRunnerFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(EphemeralRunnerFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EphemeralRunnerList) DeepCopyInto(out *EphemeralRunnerList) {
 	*out = *in
@@ -314,7 +634,7 @@ func (in *EphemeralRunnerSet) DeepCopyInto(out *EphemeralRunnerSet) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerSet.
@@ -335,6 +655,36 @@ func (in *EphemeralRunnerSet) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EphemeralRunnerSetCanary) DeepCopyInto(out *EphemeralRunnerSetCanary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerSetCanary.
+func (in *EphemeralRunnerSetCanary) DeepCopy() *EphemeralRunnerSetCanary {
+	if in == nil {
+		return nil
+	}
+	out := new(EphemeralRunnerSetCanary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EphemeralRunnerSetCanaryStatus) DeepCopyInto(out *EphemeralRunnerSetCanaryStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerSetCanaryStatus.
+func (in *EphemeralRunnerSetCanaryStatus) DeepCopy() *EphemeralRunnerSetCanaryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EphemeralRunnerSetCanaryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EphemeralRunnerSetList) DeepCopyInto(out *EphemeralRunnerSetList) {
 	*out = *in
@@ -370,7 +720,24 @@ func (in *EphemeralRunnerSetList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EphemeralRunnerSetSpec) DeepCopyInto(out *EphemeralRunnerSetSpec) {
 	*out = *in
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int)
+		**out = **in
+	}
 	in.EphemeralRunnerSpec.DeepCopyInto(&out.EphemeralRunnerSpec)
+	if in.ContainerHooks != nil {
+		in, out := &in.ContainerHooks, &out.ContainerHooks
+		*out = make([]ContainerHooks, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(EphemeralRunnerSetCanary)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerSetSpec.
@@ -386,6 +753,11 @@ func (in *EphemeralRunnerSetSpec) DeepCopy() *EphemeralRunnerSetSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EphemeralRunnerSetStatus) DeepCopyInto(out *EphemeralRunnerSetStatus) {
 	*out = *in
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(EphemeralRunnerSetCanaryStatus)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerSetStatus.
@@ -412,6 +784,11 @@ func (in *EphemeralRunnerSpec) DeepCopyInto(out *EphemeralRunnerSpec) {
 		(*in).DeepCopyInto(*out)
 	}
 	in.PodTemplateSpec.DeepCopyInto(&out.PodTemplateSpec)
+	if in.WorkVolume != nil {
+		in, out := &in.WorkVolume, &out.WorkVolume
+		*out = new(WorkVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerSpec.
@@ -434,6 +811,30 @@ func (in *EphemeralRunnerStatus) DeepCopyInto(out *EphemeralRunnerStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.LostPods != nil {
+		in, out := &in.LostPods, &out.LostPods
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = new(EphemeralRunnerFailure)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JobQueueTime != nil {
+		in, out := &in.JobQueueTime, &out.JobQueueTime
+		*out = (*in).DeepCopy()
+	}
+	if in.JobScaleSetAssignTime != nil {
+		in, out := &in.JobScaleSetAssignTime, &out.JobScaleSetAssignTime
+		*out = (*in).DeepCopy()
+	}
+	if in.JobRunnerAssignTime != nil {
+		in, out := &in.JobRunnerAssignTime, &out.JobRunnerAssignTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerStatus.
@@ -446,6 +847,26 @@ func (in *EphemeralRunnerStatus) DeepCopy() *EphemeralRunnerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureRetention) DeepCopyInto(out *FailureRetention) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureRetention.
+func (in *FailureRetention) DeepCopy() *FailureRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubServerTLSConfig) DeepCopyInto(out *GitHubServerTLSConfig) {
 	*out = *in
@@ -454,6 +875,11 @@ func (in *GitHubServerTLSConfig) DeepCopyInto(out *GitHubServerTLSConfig) {
 		*out = new(TLSCertificateSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ClientCertificateFrom != nil {
+		in, out := &in.ClientCertificateFrom, &out.ClientCertificateFrom
+		*out = new(TLSClientCertificateSource)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubServerTLSConfig.
@@ -467,10 +893,362 @@ func (in *GitHubServerTLSConfig) DeepCopy() *GitHubServerTLSConfig {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxyConfig) DeepCopyInto(out *ProxyConfig) {
+func (in *JobQueueLatencyBucket) DeepCopyInto(out *JobQueueLatencyBucket) {
 	*out = *in
-	if in.HTTP != nil {
-		in, out := &in.HTTP, &out.HTTP
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobQueueLatencyBucket.
+func (in *JobQueueLatencyBucket) DeepCopy() *JobQueueLatencyBucket {
+	if in == nil {
+		return nil
+	}
+	out := new(JobQueueLatencyBucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobQueueLatencySLO) DeepCopyInto(out *JobQueueLatencySLO) {
+	*out = *in
+	out.Threshold = in.Threshold
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxRunnersWhileBurning != nil {
+		in, out := &in.MaxRunnersWhileBurning, &out.MaxRunnersWhileBurning
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobQueueLatencySLO.
+func (in *JobQueueLatencySLO) DeepCopy() *JobQueueLatencySLO {
+	if in == nil {
+		return nil
+	}
+	out := new(JobQueueLatencySLO)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobQueueLatencySLOStatus) DeepCopyInto(out *JobQueueLatencySLOStatus) {
+	*out = *in
+	if in.Buckets != nil {
+		in, out := &in.Buckets, &out.Buckets
+		*out = make([]JobQueueLatencyBucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.LastRunnerAssignTime.DeepCopyInto(&out.LastRunnerAssignTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobQueueLatencySLOStatus.
+func (in *JobQueueLatencySLOStatus) DeepCopy() *JobQueueLatencySLOStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JobQueueLatencySLOStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobTemplate) DeepCopyInto(out *JobTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxRunners != nil {
+		in, out := &in.MaxRunners, &out.MaxRunners
+		*out = new(int)
+		**out = **in
+	}
+	if in.MinRunners != nil {
+		in, out := &in.MinRunners, &out.MinRunners
+		*out = new(int)
+		**out = **in
+	}
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobTemplate.
+func (in *JobTemplate) DeepCopy() *JobTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(JobTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadTest) DeepCopyInto(out *LoadTest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadTest.
+func (in *LoadTest) DeepCopy() *LoadTest {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadTest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LoadTest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadTestLatency) DeepCopyInto(out *LoadTestLatency) {
+	*out = *in
+	out.P50 = in.P50
+	out.P90 = in.P90
+	out.P99 = in.P99
+	out.Max = in.Max
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadTestLatency.
+func (in *LoadTestLatency) DeepCopy() *LoadTestLatency {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadTestLatency)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadTestList) DeepCopyInto(out *LoadTestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LoadTest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadTestList.
+func (in *LoadTestList) DeepCopy() *LoadTestList {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadTestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LoadTestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadTestSample) DeepCopyInto(out *LoadTestSample) {
+	*out = *in
+	in.GeneratedTime.DeepCopyInto(&out.GeneratedTime)
+	in.ScaledTime.DeepCopyInto(&out.ScaledTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadTestSample.
+func (in *LoadTestSample) DeepCopy() *LoadTestSample {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadTestSample)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadTestSpec) DeepCopyInto(out *LoadTestSpec) {
+	*out = *in
+	if in.Workflow != nil {
+		in, out := &in.Workflow, &out.Workflow
+		*out = new(LoadTestWorkflow)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadTestSpec.
+func (in *LoadTestSpec) DeepCopy() *LoadTestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadTestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadTestStatus) DeepCopyInto(out *LoadTestStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ScalingLatency != nil {
+		in, out := &in.ScalingLatency, &out.ScalingLatency
+		*out = new(LoadTestLatency)
+		**out = **in
+	}
+	if in.Samples != nil {
+		in, out := &in.Samples, &out.Samples
+		*out = make([]LoadTestSample, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadTestStatus.
+func (in *LoadTestStatus) DeepCopy() *LoadTestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadTestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadTestWorkflow) DeepCopyInto(out *LoadTestWorkflow) {
+	*out = *in
+	if in.Inputs != nil {
+		in, out := &in.Inputs, &out.Inputs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadTestWorkflow.
+func (in *LoadTestWorkflow) DeepCopy() *LoadTestWorkflow {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadTestWorkflow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MinRunnersWindow) DeepCopyInto(out *MinRunnersWindow) {
+	*out = *in
+	in.DailyWindow.DeepCopyInto(&out.DailyWindow)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinRunnersWindow.
+func (in *MinRunnersWindow) DeepCopy() *MinRunnersWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MinRunnersWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaceholderWindow) DeepCopyInto(out *PlaceholderWindow) {
+	*out = *in
+	in.DailyWindow.DeepCopyInto(&out.DailyWindow)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaceholderWindow.
+func (in *PlaceholderWindow) DeepCopy() *PlaceholderWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaceholderWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Placeholders) DeepCopyInto(out *Placeholders) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.PerPendingRunner != nil {
+		in, out := &in.PerPendingRunner, &out.PerPendingRunner
+		*out = new(int)
+		**out = **in
+	}
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]PlaceholderWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Placeholders.
+func (in *Placeholders) DeepCopy() *Placeholders {
+	if in == nil {
+		return nil
+	}
+	out := new(Placeholders)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaceholdersStatus) DeepCopyInto(out *PlaceholdersStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaceholdersStatus.
+func (in *PlaceholdersStatus) DeepCopy() *PlaceholdersStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaceholdersStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyConfig) DeepCopyInto(out *ProxyConfig) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
 		*out = new(ProxyServerConfig)
 		**out = **in
 	}
@@ -511,6 +1289,121 @@ func (in *ProxyServerConfig) DeepCopy() *ProxyServerConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerGroup) DeepCopyInto(out *RunnerGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerGroup.
+func (in *RunnerGroup) DeepCopy() *RunnerGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerGroupList) DeepCopyInto(out *RunnerGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RunnerGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerGroupList.
+func (in *RunnerGroupList) DeepCopy() *RunnerGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerGroupSpec) DeepCopyInto(out *RunnerGroupSpec) {
+	*out = *in
+	if in.SelectedRepositories != nil {
+		in, out := &in.SelectedRepositories, &out.SelectedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitHubServerTLS != nil {
+		in, out := &in.GitHubServerTLS, &out.GitHubServerTLS
+		*out = new(GitHubServerTLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerGroupSpec.
+func (in *RunnerGroupSpec) DeepCopy() *RunnerGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerGroupStatus) DeepCopyInto(out *RunnerGroupStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerGroupStatus.
+func (in *RunnerGroupStatus) DeepCopy() *RunnerGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TLSCertificateSource) DeepCopyInto(out *TLSCertificateSource) {
 	*out = *in
@@ -530,3 +1423,83 @@ func (in *TLSCertificateSource) DeepCopy() *TLSCertificateSource {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSClientCertificateSource) DeepCopyInto(out *TLSClientCertificateSource) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSClientCertificateSource.
+func (in *TLSClientCertificateSource) DeepCopy() *TLSClientCertificateSource {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSClientCertificateSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WarmPool) DeepCopyInto(out *WarmPool) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WarmPool.
+func (in *WarmPool) DeepCopy() *WarmPool {
+	if in == nil {
+		return nil
+	}
+	out := new(WarmPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WarmPoolStatus) DeepCopyInto(out *WarmPoolStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WarmPoolStatus.
+func (in *WarmPoolStatus) DeepCopy() *WarmPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WarmPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkVolumeSource) DeepCopyInto(out *WorkVolumeSource) {
+	*out = *in
+	if in.EmptyDir != nil {
+		in, out := &in.EmptyDir, &out.EmptyDir
+		*out = new(v1.EmptyDirVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ephemeral != nil {
+		in, out := &in.Ephemeral, &out.Ephemeral
+		*out = new(v1.EphemeralVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostPath != nil {
+		in, out := &in.HostPath, &out.HostPath
+		*out = new(v1.HostPathVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkVolumeSource.
+func (in *WorkVolumeSource) DeepCopy() *WorkVolumeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkVolumeSource)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,6 +26,13 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// ScaleTargetRef is the reference to scaled resource like RunnerDeployment
 	ScaleTargetRef ScaleTargetRef `json:"scaleTargetRef,omitempty"`
 
+	// ScaleTargets splits the desired replicas across multiple RunnerDeployments,
+	// like a cheaper spot-backed one and an on-demand fallback.
+	// When set, ScaleTargetRef must refer to one of them, and its runner configuration is used
+	// to compute the metrics and to match webhook events.
+	// +optional
+	ScaleTargets []WeightedScaleTargetRef `json:"scaleTargets,omitempty"`
+
 	// MinReplicas is the minimum number of replicas the deployment is allowed to scale
 	// +optional
 	MinReplicas *int `json:"minReplicas,omitempty"`
@@ -38,6 +46,54 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// +optional
 	ScaleDownDelaySecondsAfterScaleUp *int `json:"scaleDownDelaySecondsAfterScaleOut,omitempty"`
 
+	// ScaleDownStabilizationSeconds is the length of the window over which the past desired replicas are considered on scale down.
+	// Like the stabilization window of HorizontalPodAutoscaler, the HRA scales down only to the highest number of replicas
+	// it computed within the window, so that a metric dipping for a while doesn't remove runners that are needed again soon after.
+	// The computed replicas are recorded in status.desiredReplicasHistory.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ScaleDownStabilizationSeconds *int `json:"scaleDownStabilizationSeconds,omitempty"`
+
+	// MaxScaleUpReplicasPerSync is the maximum number of replicas added per sync period of the controller,
+	// so that a burst of webhook events scales the runners up in steps rather than all at once.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxScaleUpReplicasPerSync *int `json:"maxScaleUpReplicasPerSync,omitempty"`
+
+	// MaxScaleDownReplicasPerSync is the maximum number of replicas removed per sync period of the controller.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxScaleDownReplicasPerSync *int `json:"maxScaleDownReplicasPerSync,omitempty"`
+
+	// FallbackReplicas is the number of replicas suggested when computing the metrics fails, for example due to
+	// the GitHub API rate limit or an outage. When unset, the desired replicas are kept as is until the metrics recover.
+	// The secondary metric, if any, is tried before falling back to it.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	FallbackReplicas *int `json:"fallbackReplicas,omitempty"`
+
+	// DryRun makes the HRA compute the desired replicas and record them in status and metrics without scaling the scale target,
+	// so that metrics and thresholds can be tuned safely in production.
+	// The scaling operation the HRA would have made is recorded in status.dryRun.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// CostBudget caps the replicas so that the spend of the runners stays within a monthly budget.
+	// The replica-hours accumulated in the current month are tracked in status.costBudget.
+	// +optional
+	CostBudget *CostBudget `json:"costBudget,omitempty"`
+
+	// Federation divides a maximum number of replicas between the HRAs of the same federation
+	// in multiple ARC installations, in proportion to the replicas each of them demands.
+	// The installations exchange their demands through the federation store configured on the controller.
+	// +optional
+	Federation *Federation `json:"federation,omitempty"`
+
+	// MaxReplicasSaturation configures when the HRA reports that it's saturated, that is, the metrics have demanded
+	// more replicas than maxReplicas for a while, in the MaxReplicasSaturated condition.
+	// +optional
+	MaxReplicasSaturation *MaxReplicasSaturation `json:"maxReplicasSaturation,omitempty"`
+
 	// Metrics is the collection of various metric targets to calculate desired number of runners
 	// +optional
 	Metrics []MetricSpec `json:"metrics,omitempty"`
@@ -55,6 +111,12 @@ type HorizontalRunnerAutoscalerSpec struct {
 
 	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 
+	// RepositoryBudgets caps the capacity reservations attributed to each repository,
+	// so that a single noisy repository cannot consume the entire maxReplicas of an organization or enterprise runner pool.
+	// Reservations beyond the budget of their repository don't add replicas until earlier ones of the same repository are released.
+	// +optional
+	RepositoryBudgets []RepositoryBudget `json:"repositoryBudgets,omitempty"`
+
 	// ScheduledOverrides is the list of ScheduledOverride.
 	// It can be used to override a few fields of HorizontalRunnerAutoscalerSpec on schedule.
 	// The earlier a scheduled override is, the higher it is prioritized.
@@ -65,10 +127,63 @@ type HorizontalRunnerAutoscalerSpec struct {
 	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
 }
 
+// CostBudget is the budget of the runners of an HRA for a calendar month, in UTC.
+// Both amounts are decimal numbers in the same currency, like "0.35" and "1500".
+type CostBudget struct {
+	// ReplicaHourlyCost is the cost of running a single replica for an hour.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	ReplicaHourlyCost string `json:"replicaHourlyCost"`
+
+	// MonthlyCap is the maximum cost of the replicas in a calendar month.
+	// Once the replica-hours accumulated in the month would cost more, the HRA doesn't scale above
+	// the replicas the rest of the budget can pay for until the next sync, nor below minReplicas.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	MonthlyCap string `json:"monthlyCap"`
+}
+
+type MaxReplicasSaturation struct {
+	// Duration is how long the metrics must demand more replicas than maxReplicas for the HRA to be saturated.
+	// Defaults to 15m.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// SuggestMaxReplicas makes the HRA suggest a new maxReplicas while it's saturated,
+	// which is the highest number of replicas the metrics demanded since it started being capped.
+	// +optional
+	SuggestMaxReplicas bool `json:"suggestMaxReplicas,omitempty"`
+}
+
+// Federation is a group of HRAs, usually in different clusters, sharing a maximum number of replicas.
+type Federation struct {
+	// Name identifies the federation. It must be the same for all the HRAs of the federation.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[-_a-zA-Z0-9]+$`
+	Name string `json:"name"`
+
+	// MaxReplicas is the maximum number of replicas of all the HRAs of the federation.
+	// While the HRAs demand more, each of them gets a share proportional to its demand,
+	// without going below its own minReplicas.
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas int `json:"maxReplicas"`
+}
+
 type ScaleUpTrigger struct {
 	GitHubEvent *GitHubEventScaleUpTriggerSpec `json:"githubEvent,omitempty"`
 	Amount      int                            `json:"amount,omitempty"`
-	Duration    metav1.Duration                `json:"duration,omitempty"`
+
+	// AmountExpression is a CEL expression evaluated against the webhook payload to compute the amount
+	// of replicas added by each event, like `size(workflow_job.labels)`, or whether the event triggers a scale
+	// at all, like `workflow_job.labels.exists(l, l == 'xlarge')`.
+	// The top-level fields of the payload, like `action`, `workflow_job` and `repository`, are available as variables,
+	// along with `event`, the type of the event, and `payload`, the whole payload.
+	// An integer result is the amount, and a boolean one adds a single replica when true.
+	// The event is ignored when the result is zero or false.
+	// The expression isn't evaluated against the completion of a job, which releases the capacity reservations
+	// its queued event added instead.
+	// +optional
+	AmountExpression string `json:"amountExpression,omitempty"`
+
+	Duration metav1.Duration `json:"duration,omitempty"`
 }
 
 type GitHubEventScaleUpTriggerSpec struct {
@@ -76,6 +191,21 @@ type GitHubEventScaleUpTriggerSpec struct {
 	PullRequest *PullRequestSpec `json:"pullRequest,omitempty"`
 	Push        *PushSpec        `json:"push,omitempty"`
 	WorkflowJob *WorkflowJobSpec `json:"workflowJob,omitempty"`
+
+	// CheckSuite scales on the check_suite events of GitHub Actions, for the GitHub Enterprise Server versions
+	// and the webhooks that don't receive workflow_job events.
+	// +optional
+	CheckSuite *CheckSuiteSpec `json:"checkSuite,omitempty"`
+
+	// WorkflowRun scales on workflow_run events, for the GitHub Enterprise Server versions
+	// and the webhooks that don't receive workflow_job events.
+	// +optional
+	WorkflowRun *WorkflowRunSpec `json:"workflowRun,omitempty"`
+
+	// Deployment scales on the deployment_protection_rule and deployment events of the deployments to environments,
+	// so that the runners of a gated deployment are up by the time its approval completes.
+	// +optional
+	Deployment *DeploymentSpec `json:"deployment,omitempty"`
 }
 
 // https://docs.github.com/en/actions/reference/events-that-trigger-workflows#check_run
@@ -97,6 +227,70 @@ type CheckRunSpec struct {
 
 // https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_job
 type WorkflowJobSpec struct {
+	// Workflows is a list of GitHub Actions glob patterns.
+	// Any workflow_job event whose workflow name matches one of patterns in the list can trigger autoscaling.
+	// Defaults to all the workflows.
+	// +optional
+	Workflows []string `json:"workflows,omitempty"`
+
+	// WorkflowPaths is a list of GitHub Actions glob patterns, like `.github/workflows/build-*.yml`.
+	// Any workflow_job event whose workflow file path matches one of patterns in the list can trigger autoscaling.
+	// The path isn't part of the event, so it's looked up from the workflow run with the GitHub API,
+	// which requires the github webhook server to be configured with GitHub credentials.
+	// Defaults to all the workflow files.
+	// +optional
+	WorkflowPaths []string `json:"workflowPaths,omitempty"`
+}
+
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#check_suite
+//
+// A check suite of GitHub Actions adds capacity reservations when it's requested,
+// which are removed when it's completed. The check suites of other GitHub Apps are ignored.
+type CheckSuiteSpec struct {
+	// Types is a list of the actions of the check_suite events that add capacity reservations,
+	// out of requested and rerequested. Defaults to both.
+	// +optional
+	Types []string `json:"types,omitempty"`
+
+	// Branches is a list of GitHub Actions glob patterns.
+	// Any check_suite event whose head branch matches one of patterns in the list can trigger autoscaling.
+	// Defaults to all the branches.
+	// +optional
+	Branches []string `json:"branches,omitempty"`
+}
+
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#workflow_run
+//
+// A workflow run adds capacity reservations when it's requested, renews them when it starts,
+// and removes them when it's completed.
+type WorkflowRunSpec struct {
+	// Types is a list of the actions of the workflow_run events that add capacity reservations,
+	// out of requested and in_progress. Defaults to requested.
+	// +optional
+	Types []string `json:"types,omitempty"`
+
+	// Branches is a list of GitHub Actions glob patterns.
+	// Any workflow_run event whose head branch matches one of patterns in the list can trigger autoscaling.
+	// Defaults to all the branches.
+	// +optional
+	Branches []string `json:"branches,omitempty"`
+}
+
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#deployment_protection_rule
+//
+// A deployment adds capacity reservations when its deployment protection rules are requested, or when it's created,
+// renews them when its deployment_status becomes in_progress, and removes them when it becomes success, failure, error, or inactive.
+type DeploymentSpec struct {
+	// Events is a list of the events that add capacity reservations, out of deployment_protection_rule and deployment.
+	// A deployment adds capacity reservations only once even when both of its events are received. Defaults to both.
+	// +optional
+	Events []string `json:"events,omitempty"`
+
+	// Environments is a list of GitHub Actions glob patterns.
+	// Any deployment whose environment matches one of patterns in the list can trigger autoscaling.
+	// Defaults to all the environments.
+	// +optional
+	Environments []string `json:"environments,omitempty"`
 }
 
 // https://docs.github.com/en/actions/reference/events-that-trigger-workflows#pull_request
@@ -119,6 +313,28 @@ type CapacityReservation struct {
 
 	// +optional
 	EffectiveTime metav1.Time `json:"effectiveTime,omitempty"`
+
+	// Repository is the full name of the repository, like OWNER/REPO, whose webhook event added this reservation.
+	// +optional
+	Repository string `json:"repository,omitempty"`
+
+	// JobID is the ID of the workflow job whose queued event added this reservation.
+	// The reservation is held from the time the job starts until it completes, so that a job that waited long for a runner
+	// or runs longer than the duration of its scale up trigger doesn't lose it mid-build. A started job whose completion
+	// is never received releases it after a safety cap configured on the webhook server, which defaults to 24 hours.
+	// +optional
+	JobID int64 `json:"jobID,omitempty"`
+}
+
+// RepositoryBudget is the maximum number of replicas reserved for the workflow jobs of a repository.
+type RepositoryBudget struct {
+	// Repository is the name of the repository, either REPO or OWNER/REPO.
+	// "*" sets the budget of every repository not listed explicitly.
+	Repository string `json:"repository"`
+
+	// MaxReplicas is the maximum number of capacity reservations of the repository that add replicas at a time.
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas int `json:"maxReplicas"`
 }
 
 type ScaleTargetRef struct {
@@ -131,9 +347,33 @@ type ScaleTargetRef struct {
 	Name string `json:"name,omitempty"`
 }
 
+// WeightedScaleTargetRef is a scale target that receives a part of the desired replicas.
+// Targets are filled in ascending order of Priority, and targets sharing the same priority
+// split the replicas in proportion to their weights.
+type WeightedScaleTargetRef struct {
+	ScaleTargetRef `json:",inline"`
+
+	// Priority is the order in which the target is filled. Lower values are filled first.
+	// +optional
+	Priority int `json:"priority,omitempty"`
+
+	// Weight is the relative share of the replicas the target receives among the targets of the same priority.
+	// Defaults to 1. The target is never scaled up when set to 0.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Weight *int `json:"weight,omitempty"`
+
+	// MaxReplicas is the maximum number of replicas the target receives.
+	// The remaining replicas overflow to the targets of the next priority.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas *int `json:"maxReplicas,omitempty"`
+}
+
 type MetricSpec struct {
 	// Type is the type of metric to be used for autoscaling.
-	// It can be TotalNumberOfQueuedAndInProgressWorkflowRuns or PercentageRunnersBusy.
+	// It can be TotalNumberOfQueuedAndInProgressWorkflowRuns, TotalNumberOfQueuedAndInProgressWorkflowJobs, PercentageRunnersBusy,
+	// or OldestQueuedWorkflowJobAge.
 	Type string `json:"type,omitempty"`
 
 	// RepositoryNames is the list of repository names to be used for calculating the metric.
@@ -170,6 +410,18 @@ type MetricSpec struct {
 	// You can only specify either ScaleDownFactor or ScaleDownAdjustment.
 	// +optional
 	ScaleDownAdjustment int `json:"scaleDownAdjustment,omitempty"`
+
+	// SmoothingHalfLife enables the exponentially-weighted moving average of the percentage of busy runners,
+	// compared to the thresholds instead of the latest sample, so that short jobs don't make the runners oscillate.
+	// A sample weighs half as much after each half-life.
+	// Only applies to PercentageRunnersBusy.
+	// +optional
+	SmoothingHalfLife *metav1.Duration `json:"smoothingHalfLife,omitempty"`
+
+	// QueuedJobAgeThreshold is how long a queued workflow job can wait for a runner before OldestQueuedWorkflowJobAge scales up.
+	// Only applies to OldestQueuedWorkflowJobAge. Defaults to 2m.
+	// +optional
+	QueuedJobAgeThreshold *metav1.Duration `json:"queuedJobAgeThreshold,omitempty"`
 }
 
 // ScheduledOverride can be used to override a few fields of HorizontalRunnerAutoscalerSpec on schedule.
@@ -190,6 +442,30 @@ type ScheduledOverride struct {
 
 	// +optional
 	RecurrenceRule RecurrenceRule `json:"recurrenceRule,omitempty"`
+
+	// ExclusionCalendar is the set of dates, like public holidays and company shutdown weeks,
+	// on which a recurrence of this override is skipped.
+	// +optional
+	ExclusionCalendar *ExclusionCalendar `json:"exclusionCalendar,omitempty"`
+}
+
+// ExclusionCalendar is a set of dates on which a scheduled override doesn't take effect.
+// A recurrence is skipped when the date it starts on is excluded.
+type ExclusionCalendar struct {
+	// Dates is the list of excluded dates in the YYYY-MM-DD format.
+	// A range of dates can be specified as YYYY-MM-DD/YYYY-MM-DD, where both ends are inclusive.
+	// +optional
+	Dates []string `json:"dates,omitempty"`
+
+	// ICalSecretKeyRef refers to a key of a Secret in the HRA's namespace that contains an iCalendar (RFC 5545) document.
+	// The dates covered by each VEVENT of the document are excluded. Recurring events, with an RRULE or RDATE, are rejected.
+	// +optional
+	ICalSecretKeyRef *corev1.SecretKeySelector `json:"icalSecretKeyRef,omitempty"`
+
+	// TimeZone is the IANA time zone name used to determine the date a recurrence starts on, like "America/New_York".
+	// Defaults to the X-WR-TIMEZONE of the iCalendar document, if any, and to UTC otherwise.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 type RecurrenceRule struct {
@@ -221,6 +497,35 @@ type HorizontalRunnerAutoscalerStatus struct {
 	// +nullable
 	LastSuccessfulScaleOutTime *metav1.Time `json:"lastSuccessfulScaleOutTime,omitempty"`
 
+	// DesiredReplicasSource is the type of the metric that suggested the desired replicas,
+	// or FallbackReplicas when the metrics failed and spec.fallbackReplicas was used.
+	// +optional
+	DesiredReplicasSource string `json:"desiredReplicasSource,omitempty"`
+
+	// LastScaleTime is the last time the desired replicas changed, in either direction.
+	// +optional
+	// +nullable
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+
+	// LastScaleReason explains the desired replicas computed at the last evaluation,
+	// like the metric they were computed from and the limits applied to them.
+	// +optional
+	LastScaleReason string `json:"lastScaleReason,omitempty"`
+
+	// LastEvaluationTime is the last time the controller computed the desired replicas.
+	// +optional
+	// +nullable
+	LastEvaluationTime *metav1.Time `json:"lastEvaluationTime,omitempty"`
+
+	// NextEvaluationTime is the time the controller is expected to compute the desired replicas next,
+	// unless a change to the HRA or its scale target triggers an earlier evaluation.
+	// +optional
+	// +nullable
+	NextEvaluationTime *metav1.Time `json:"nextEvaluationTime,omitempty"`
+
+	// CacheEntries is no longer set. The controller keeps the metric cache in memory, and clears the entries set by older versions.
+	//
+	// Deprecated: The metric cache isn't part of the status anymore.
 	// +optional
 	CacheEntries []CacheEntry `json:"cacheEntries,omitempty"`
 
@@ -228,6 +533,116 @@ type HorizontalRunnerAutoscalerStatus struct {
 	// for observability.
 	// +optional
 	ScheduledOverridesSummary *string `json:"scheduledOverridesSummary,omitempty"`
+
+	// DesiredReplicasHistory is the list of the desired replicas computed within the scale down stabilization window,
+	// before the stabilization is applied. Oldest first.
+	// +optional
+	DesiredReplicasHistory []DesiredReplicasRecord `json:"desiredReplicasHistory,omitempty"`
+
+	// RepositoryUsage is the number of capacity reservations per repository with a budget.
+	// +optional
+	RepositoryUsage []RepositoryUsage `json:"repositoryUsage,omitempty"`
+
+	// CostBudget is the usage of spec.costBudget in the current month.
+	// +optional
+	CostBudget *CostBudgetStatus `json:"costBudget,omitempty"`
+
+	// DryRun is the scaling operation the HRA would have made on the scale target, when spec.dryRun is true.
+	// +optional
+	DryRun *DryRunStatus `json:"dryRun,omitempty"`
+
+	// Federation is the share of spec.federation.maxReplicas of this HRA at the last sync.
+	// +optional
+	Federation *FederationStatus `json:"federation,omitempty"`
+
+	// MaxReplicasSaturation is set while the metrics demand more replicas than maxReplicas.
+	// +optional
+	MaxReplicasSaturation *MaxReplicasSaturationStatus `json:"maxReplicasSaturation,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// HorizontalRunnerAutoscalerConditionMaxReplicasSaturated is true when the metrics have demanded more replicas
+// than maxReplicas for longer than spec.maxReplicasSaturation.duration, so that jobs wait for runners because of the cap.
+const HorizontalRunnerAutoscalerConditionMaxReplicasSaturated = "MaxReplicasSaturated"
+
+type MaxReplicasSaturationStatus struct {
+	// Since is when the metrics started demanding more replicas than maxReplicas.
+	Since metav1.Time `json:"since"`
+
+	// PeakDemandReplicas is the highest number of replicas the metrics demanded since Since.
+	PeakDemandReplicas int `json:"peakDemandReplicas"`
+
+	// SuggestedMaxReplicas is the maxReplicas that would have met the demand since Since,
+	// when spec.maxReplicasSaturation.suggestMaxReplicas is true and the HRA is saturated.
+	// +optional
+	SuggestedMaxReplicas *int `json:"suggestedMaxReplicas,omitempty"`
+}
+
+type FederationStatus struct {
+	// Demand is the number of replicas this HRA demanded from the federation.
+	Demand int `json:"demand"`
+
+	// TotalDemand is the number of replicas all the live members of the federation demanded.
+	TotalDemand int `json:"totalDemand"`
+
+	// Members is the number of live members of the federation, including this HRA.
+	Members int `json:"members"`
+
+	// Share is the maximum number of replicas of this HRA.
+	Share int `json:"share"`
+}
+
+type DryRunStatus struct {
+	// Time is when the operation was first computed.
+	Time metav1.Time `json:"time"`
+
+	// CurrentReplicas is the number of replicas of the scale target, which the dry run leaves as is.
+	CurrentReplicas int `json:"currentReplicas"`
+
+	// DesiredReplicas is the number of replicas the scale target would have been scaled to.
+	DesiredReplicas int `json:"desiredReplicas"`
+
+	// Reasons explains how the desired replicas were computed, like the metric that suggested them
+	// and the limits applied to them.
+	// +optional
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+type CostBudgetStatus struct {
+	// PeriodStart is the start of the calendar month, in UTC, the replica-hours are accumulated for.
+	PeriodStart metav1.Time `json:"periodStart"`
+
+	// LastAccountedTime is the last time the desired replicas were accounted into ReplicaHours.
+	LastAccountedTime metav1.Time `json:"lastAccountedTime"`
+
+	// ReplicaHours is the decimal number of replica-hours accumulated since PeriodStart.
+	ReplicaHours string `json:"replicaHours"`
+
+	// Throttled is true when the budget kept the desired replicas below the computed ones at the last sync.
+	// +optional
+	Throttled bool `json:"throttled,omitempty"`
+}
+
+type DesiredReplicasRecord struct {
+	Time     metav1.Time `json:"time"`
+	Replicas int         `json:"replicas"`
+}
+
+type RepositoryUsage struct {
+	Repository string `json:"repository"`
+
+	// Reserved is the number of replicas reserved for the repository within its budget.
+	Reserved int `json:"reserved"`
+
+	// Pending is the number of replicas requested by the repository beyond its budget.
+	// +optional
+	Pending int `json:"pending,omitempty"`
+
+	MaxReplicas int `json:"maxReplicas"`
 }
 
 const CacheEntryKeyDesiredReplicas = "desiredReplicas"
@@ -245,6 +660,10 @@ type CacheEntry struct {
 // +kubebuilder:printcolumn:JSONPath=".spec.maxReplicas",name=Max,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.desiredReplicas",name=Desired,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.scheduledOverridesSummary",name=Schedule,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.dryRun",name=DryRun,type=boolean,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.lastEvaluationTime",name=Last Evaluation,type=date,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.nextEvaluationTime",name=Next Evaluation,type=string,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.lastScaleReason",name=Reason,type=string,priority=1
 
 // HorizontalRunnerAutoscaler is the Schema for the horizontalrunnerautoscaler API
 type HorizontalRunnerAutoscaler struct {
@@ -0,0 +1,323 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// DefaultScaleUpTriggerDuration is the default duration of the capacity reservation added by a scale up trigger.
+// The reservation is released after the duration in case GitHub somehow stopped sending us the "completed" workflow_job event.
+const DefaultScaleUpTriggerDuration = 10 * time.Minute
+
+// log is for logging in this package.
+var horizontalRunnerAutoscalerLog = logf.Log.WithName("horizontalrunnerautoscaler-resource")
+
+// HorizontalRunnerAutoscalerWebhook defaults and validates the durations of the scale up triggers
+// according to the controller configuration.
+// A misconfigured duration either results in runaway scaling due to capacity reservations that live too long,
+// or ineffective scaling due to ones that expire before the job is even scheduled.
+// +kubebuilder:object:generate=false
+type HorizontalRunnerAutoscalerWebhook struct {
+	// DefaultScaleUpTriggerDuration is set to the scale up triggers that omit the duration.
+	DefaultScaleUpTriggerDuration time.Duration
+
+	// MinScaleUpTriggerDuration and MaxScaleUpTriggerDuration bound the duration of scale up triggers.
+	// Zero means unbounded.
+	MinScaleUpTriggerDuration time.Duration
+	MaxScaleUpTriggerDuration time.Duration
+}
+
+func (w *HorizontalRunnerAutoscalerWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&HorizontalRunnerAutoscaler{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-actions-summerwind-dev-v1alpha1-horizontalrunnerautoscaler,verbs=create;update,mutating=true,failurePolicy=fail,groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,versions=v1alpha1,name=mutate.horizontalrunnerautoscaler.actions.summerwind.dev,sideEffects=None,admissionReviewVersions=v1beta1
+
+var _ webhook.CustomDefaulter = &HorizontalRunnerAutoscalerWebhook{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type
+func (w *HorizontalRunnerAutoscalerWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	hra, ok := obj.(*HorizontalRunnerAutoscaler)
+	if !ok {
+		return fmt.Errorf("expected a HorizontalRunnerAutoscaler but got a %T", obj)
+	}
+
+	if w.DefaultScaleUpTriggerDuration <= 0 {
+		return nil
+	}
+
+	for i := range hra.Spec.ScaleUpTriggers {
+		t := &hra.Spec.ScaleUpTriggers[i]
+
+		if t.Duration.Duration == 0 {
+			t.Duration = metav1.Duration{Duration: w.DefaultScaleUpTriggerDuration}
+		}
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-actions-summerwind-dev-v1alpha1-horizontalrunnerautoscaler,verbs=create;update,mutating=false,failurePolicy=fail,groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,versions=v1alpha1,name=validate.horizontalrunnerautoscaler.actions.summerwind.dev,sideEffects=None,admissionReviewVersions=v1beta1
+
+var _ webhook.CustomValidator = &HorizontalRunnerAutoscalerWebhook{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *HorizontalRunnerAutoscalerWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	hra, ok := obj.(*HorizontalRunnerAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a HorizontalRunnerAutoscaler but got a %T", obj)
+	}
+
+	horizontalRunnerAutoscalerLog.Info("validate resource to be created", "name", hra.Name)
+	return nil, w.Validate(hra)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *HorizontalRunnerAutoscalerWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	hra, ok := newObj.(*HorizontalRunnerAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a HorizontalRunnerAutoscaler but got a %T", newObj)
+	}
+
+	horizontalRunnerAutoscalerLog.Info("validate resource to be updated", "name", hra.Name)
+	return nil, w.Validate(hra)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *HorizontalRunnerAutoscalerWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Validate validates the amount expressions, workflow, type and branch filters and durations of the scale up triggers, the weighted scale targets,
+// the repository budgets, the cost budget, and the metric smoothing.
+func (w *HorizontalRunnerAutoscalerWebhook) Validate(hra *HorizontalRunnerAutoscaler) error {
+	errList := validateScaleTargets(hra.Spec)
+	errList = append(errList, validateRepositoryBudgets(hra.Spec)...)
+	errList = append(errList, validateCostBudget(hra.Spec)...)
+
+	for i, t := range hra.Spec.ScaleUpTriggers {
+		if t.AmountExpression != "" {
+			if _, err := CompileScaleUpTriggerAmountExpression(t.AmountExpression); err != nil {
+				path := field.NewPath("spec", "scaleUpTriggers").Index(i).Child("amountExpression")
+				errList = append(errList, field.Invalid(path, t.AmountExpression, err.Error()))
+			}
+		}
+
+		if t.GitHubEvent != nil && t.GitHubEvent.WorkflowJob != nil {
+			path := field.NewPath("spec", "scaleUpTriggers").Index(i).Child("githubEvent", "workflowJob")
+			for j, w := range t.GitHubEvent.WorkflowJob.Workflows {
+				if w == "" {
+					errList = append(errList, field.Invalid(path.Child("workflows").Index(j), w, "pattern must not be empty"))
+				}
+			}
+			for j, p := range t.GitHubEvent.WorkflowJob.WorkflowPaths {
+				if p == "" {
+					errList = append(errList, field.Invalid(path.Child("workflowPaths").Index(j), p, "pattern must not be empty"))
+				}
+			}
+		}
+
+		if t.GitHubEvent != nil && t.GitHubEvent.CheckSuite != nil {
+			path := field.NewPath("spec", "scaleUpTriggers").Index(i).Child("githubEvent", "checkSuite")
+			errList = append(errList, validateEventFilters(path, t.GitHubEvent.CheckSuite.Types, t.GitHubEvent.CheckSuite.Branches, "requested", "rerequested")...)
+		}
+
+		if t.GitHubEvent != nil && t.GitHubEvent.WorkflowRun != nil {
+			path := field.NewPath("spec", "scaleUpTriggers").Index(i).Child("githubEvent", "workflowRun")
+			errList = append(errList, validateEventFilters(path, t.GitHubEvent.WorkflowRun.Types, t.GitHubEvent.WorkflowRun.Branches, "requested", "in_progress")...)
+		}
+
+		if t.GitHubEvent != nil && t.GitHubEvent.Deployment != nil {
+			path := field.NewPath("spec", "scaleUpTriggers").Index(i).Child("githubEvent", "deployment")
+			errList = append(errList, validateSupportedValues(path.Child("events"), t.GitHubEvent.Deployment.Events, "deployment_protection_rule", "deployment")...)
+			errList = append(errList, validatePatterns(path.Child("environments"), t.GitHubEvent.Deployment.Environments)...)
+		}
+
+		path := field.NewPath("spec", "scaleUpTriggers").Index(i).Child("duration")
+		d := t.Duration.Duration
+
+		switch {
+		case d < 0:
+			errList = append(errList, field.Invalid(path, t.Duration.String(), "duration must not be negative"))
+		case d == 0:
+			// Defaulted by the mutating webhook, or by the webhook-based autoscaler when the mutating webhook is disabled.
+		case w.MinScaleUpTriggerDuration > 0 && d < w.MinScaleUpTriggerDuration:
+			errList = append(errList, field.Invalid(path, t.Duration.String(), fmt.Sprintf("duration must be at least %s", w.MinScaleUpTriggerDuration)))
+		case w.MaxScaleUpTriggerDuration > 0 && d > w.MaxScaleUpTriggerDuration:
+			errList = append(errList, field.Invalid(path, t.Duration.String(), fmt.Sprintf("duration must be at most %s", w.MaxScaleUpTriggerDuration)))
+		}
+	}
+
+	for i, m := range hra.Spec.Metrics {
+		if m.SmoothingHalfLife != nil && m.SmoothingHalfLife.Duration < 0 {
+			path := field.NewPath("spec", "metrics").Index(i).Child("smoothingHalfLife")
+			errList = append(errList, field.Invalid(path, m.SmoothingHalfLife.String(), "smoothingHalfLife must not be negative"))
+		}
+
+		if m.QueuedJobAgeThreshold != nil && m.QueuedJobAgeThreshold.Duration < 0 {
+			path := field.NewPath("spec", "metrics").Index(i).Child("queuedJobAgeThreshold")
+			errList = append(errList, field.Invalid(path, m.QueuedJobAgeThreshold.String(), "queuedJobAgeThreshold must not be negative"))
+		}
+	}
+
+	if len(errList) > 0 {
+		return apierrors.NewInvalid(hra.GroupVersionKind().GroupKind(), hra.Name, errList)
+	}
+
+	return nil
+}
+
+func validateScaleTargets(spec HorizontalRunnerAutoscalerSpec) field.ErrorList {
+	if len(spec.ScaleTargets) == 0 {
+		return nil
+	}
+
+	var errList field.ErrorList
+
+	path := field.NewPath("spec", "scaleTargets")
+	names := map[string]struct{}{}
+
+	for i, t := range spec.ScaleTargets {
+		p := path.Index(i)
+
+		if t.Kind != "" && t.Kind != "RunnerDeployment" {
+			errList = append(errList, field.NotSupported(p.Child("kind"), t.Kind, []string{"RunnerDeployment"}))
+		}
+
+		if t.Name == "" {
+			errList = append(errList, field.Required(p.Child("name"), "name is required"))
+		} else if _, dup := names[t.Name]; dup {
+			errList = append(errList, field.Duplicate(p.Child("name"), t.Name))
+		}
+
+		names[t.Name] = struct{}{}
+
+		if t.Weight != nil && *t.Weight < 0 {
+			errList = append(errList, field.Invalid(p.Child("weight"), *t.Weight, "weight must not be negative"))
+		}
+
+		if t.MaxReplicas != nil && *t.MaxReplicas < 0 {
+			errList = append(errList, field.Invalid(p.Child("maxReplicas"), *t.MaxReplicas, "maxReplicas must not be negative"))
+		}
+	}
+
+	if _, ok := names[spec.ScaleTargetRef.Name]; !ok {
+		errList = append(errList, field.Invalid(field.NewPath("spec", "scaleTargetRef", "name"), spec.ScaleTargetRef.Name, "scaleTargetRef must refer to one of scaleTargets"))
+	}
+
+	if spec.ScaleTargetRef.Kind != "" && spec.ScaleTargetRef.Kind != "RunnerDeployment" {
+		errList = append(errList, field.NotSupported(field.NewPath("spec", "scaleTargetRef", "kind"), spec.ScaleTargetRef.Kind, []string{"RunnerDeployment"}))
+	}
+
+	return errList
+}
+
+func validateRepositoryBudgets(spec HorizontalRunnerAutoscalerSpec) field.ErrorList {
+	var errList field.ErrorList
+
+	path := field.NewPath("spec", "repositoryBudgets")
+	repos := map[string]struct{}{}
+
+	for i, b := range spec.RepositoryBudgets {
+		p := path.Index(i)
+
+		if b.Repository == "" {
+			errList = append(errList, field.Required(p.Child("repository"), "repository is required"))
+		} else if _, dup := repos[b.Repository]; dup {
+			errList = append(errList, field.Duplicate(p.Child("repository"), b.Repository))
+		}
+
+		repos[b.Repository] = struct{}{}
+
+		if b.MaxReplicas < 0 {
+			errList = append(errList, field.Invalid(p.Child("maxReplicas"), b.MaxReplicas, "maxReplicas must not be negative"))
+		}
+	}
+
+	return errList
+}
+
+func validateCostBudget(spec HorizontalRunnerAutoscalerSpec) field.ErrorList {
+	if spec.CostBudget == nil {
+		return nil
+	}
+
+	var errList field.ErrorList
+
+	path := field.NewPath("spec", "costBudget")
+
+	if cost, err := strconv.ParseFloat(spec.CostBudget.ReplicaHourlyCost, 64); err != nil || cost <= 0 {
+		errList = append(errList, field.Invalid(path.Child("replicaHourlyCost"), spec.CostBudget.ReplicaHourlyCost, "replicaHourlyCost must be a positive decimal number"))
+	}
+
+	if limit, err := strconv.ParseFloat(spec.CostBudget.MonthlyCap, 64); err != nil || limit < 0 {
+		errList = append(errList, field.Invalid(path.Child("monthlyCap"), spec.CostBudget.MonthlyCap, "monthlyCap must be a decimal number"))
+	}
+
+	return errList
+}
+
+// validateEventFilters validates the types and branches of a check_suite or workflow_run scale up trigger.
+func validateEventFilters(path *field.Path, types, branches []string, supportedTypes ...string) field.ErrorList {
+	errList := validateSupportedValues(path.Child("types"), types, supportedTypes...)
+
+	return append(errList, validatePatterns(path.Child("branches"), branches)...)
+}
+
+// validateSupportedValues validates that every value of a scale up trigger filter is one of the supported ones.
+func validateSupportedValues(path *field.Path, values []string, supported ...string) field.ErrorList {
+	var errList field.ErrorList
+
+	for j, v := range values {
+		if !slices.Contains(supported, v) {
+			errList = append(errList, field.NotSupported(path.Index(j), v, supported))
+		}
+	}
+
+	return errList
+}
+
+// validatePatterns validates the glob patterns of a scale up trigger filter.
+func validatePatterns(path *field.Path, patterns []string) field.ErrorList {
+	var errList field.ErrorList
+
+	for j, p := range patterns {
+		if p == "" {
+			errList = append(errList, field.Invalid(path.Index(j), p, "pattern must not be empty"))
+		}
+	}
+
+	return errList
+}
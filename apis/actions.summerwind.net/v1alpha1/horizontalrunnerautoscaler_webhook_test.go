@@ -0,0 +1,309 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newHRAWithTriggerDurations(durations ...time.Duration) *v1alpha1.HorizontalRunnerAutoscaler {
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "example"},
+	}
+	for _, d := range durations {
+		hra.Spec.ScaleUpTriggers = append(hra.Spec.ScaleUpTriggers, v1alpha1.ScaleUpTrigger{
+			Amount:   1,
+			Duration: metav1.Duration{Duration: d},
+		})
+	}
+	return hra
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_Default(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{DefaultScaleUpTriggerDuration: 15 * time.Minute}
+
+	hra := newHRAWithTriggerDurations(0, 5*time.Minute)
+	require.NoError(t, w.Default(context.Background(), hra))
+
+	assert.Equal(t, 15*time.Minute, hra.Spec.ScaleUpTriggers[0].Duration.Duration)
+	assert.Equal(t, 5*time.Minute, hra.Spec.ScaleUpTriggers[1].Duration.Duration)
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_Validate(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{
+		MinScaleUpTriggerDuration: time.Minute,
+		MaxScaleUpTriggerDuration: time.Hour,
+	}
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+		wantErr  string
+	}{
+		{name: "omitted", duration: 0},
+		{name: "within bounds", duration: 30 * time.Minute},
+		{name: "negative", duration: -time.Minute, wantErr: "duration must not be negative"},
+		{name: "too short", duration: 30 * time.Second, wantErr: "duration must be at least 1m0s"},
+		{name: "too long", duration: 2 * time.Hour, wantErr: "duration must be at most 1h0m0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := w.ValidateCreate(context.Background(), newHRAWithTriggerDurations(tt.duration))
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "spec.scaleUpTriggers[0].duration")
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("unbounded", func(t *testing.T) {
+		_, err := (&v1alpha1.HorizontalRunnerAutoscalerWebhook{}).ValidateCreate(context.Background(), newHRAWithTriggerDurations(24*time.Hour))
+		require.NoError(t, err)
+	})
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_ValidateScaleTargets(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{}
+
+	target := func(kind, name string, weight int) v1alpha1.WeightedScaleTargetRef {
+		return v1alpha1.WeightedScaleTargetRef{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{Kind: kind, Name: name},
+			Weight:         &weight,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		targets []v1alpha1.WeightedScaleTargetRef
+		wantErr string
+	}{
+		{name: "valid", ref: "spot", targets: []v1alpha1.WeightedScaleTargetRef{target("", "spot", 1), target("RunnerDeployment", "on-demand", 0)}},
+		{name: "ref not in targets", ref: "other", targets: []v1alpha1.WeightedScaleTargetRef{target("", "spot", 1)}, wantErr: "spec.scaleTargetRef.name"},
+		{name: "duplicate", ref: "spot", targets: []v1alpha1.WeightedScaleTargetRef{target("", "spot", 1), target("", "spot", 1)}, wantErr: "spec.scaleTargets[1].name: Duplicate value"},
+		{name: "runnerset", ref: "spot", targets: []v1alpha1.WeightedScaleTargetRef{target("", "spot", 1), target("RunnerSet", "set", 1)}, wantErr: "spec.scaleTargets[1].kind: Unsupported value"},
+		{name: "negative weight", ref: "spot", targets: []v1alpha1.WeightedScaleTargetRef{target("", "spot", -1)}, wantErr: "weight must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hra := newHRAWithTriggerDurations()
+			hra.Spec.ScaleTargetRef.Name = tt.ref
+			hra.Spec.ScaleTargets = tt.targets
+
+			_, err := w.ValidateCreate(context.Background(), hra)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_ValidateRepositoryBudgets(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{}
+
+	tests := []struct {
+		name    string
+		budgets []v1alpha1.RepositoryBudget
+		wantErr string
+	}{
+		{name: "valid", budgets: []v1alpha1.RepositoryBudget{{Repository: "*", MaxReplicas: 2}, {Repository: "org/monorepo", MaxReplicas: 5}}},
+		{name: "duplicate", budgets: []v1alpha1.RepositoryBudget{{Repository: "monorepo", MaxReplicas: 1}, {Repository: "monorepo", MaxReplicas: 2}}, wantErr: "spec.repositoryBudgets[1].repository: Duplicate value"},
+		{name: "missing repository", budgets: []v1alpha1.RepositoryBudget{{MaxReplicas: 1}}, wantErr: "spec.repositoryBudgets[0].repository: Required value"},
+		{name: "negative", budgets: []v1alpha1.RepositoryBudget{{Repository: "monorepo", MaxReplicas: -1}}, wantErr: "maxReplicas must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hra := newHRAWithTriggerDurations()
+			hra.Spec.RepositoryBudgets = tt.budgets
+
+			_, err := w.ValidateCreate(context.Background(), hra)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_ValidateSmoothingHalfLife(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{}
+
+	hra := newHRAWithTriggerDurations()
+	hra.Spec.Metrics = []v1alpha1.MetricSpec{
+		{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy, SmoothingHalfLife: &metav1.Duration{Duration: 5 * time.Minute}},
+	}
+
+	_, err := w.ValidateCreate(context.Background(), hra)
+	require.NoError(t, err)
+
+	hra.Spec.Metrics[0].SmoothingHalfLife = &metav1.Duration{Duration: -time.Minute}
+
+	_, err = w.ValidateCreate(context.Background(), hra)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "spec.metrics[0].smoothingHalfLife")
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_ValidateQueuedJobAgeThreshold(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{}
+
+	hra := newHRAWithTriggerDurations()
+	hra.Spec.Metrics = []v1alpha1.MetricSpec{
+		{Type: v1alpha1.AutoscalingMetricTypeOldestQueuedWorkflowJobAge, QueuedJobAgeThreshold: &metav1.Duration{Duration: 2 * time.Minute}},
+	}
+
+	_, err := w.ValidateCreate(context.Background(), hra)
+	require.NoError(t, err)
+
+	hra.Spec.Metrics[0].QueuedJobAgeThreshold = &metav1.Duration{Duration: -time.Minute}
+
+	_, err = w.ValidateCreate(context.Background(), hra)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "spec.metrics[0].queuedJobAgeThreshold")
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_ValidateCostBudget(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{}
+
+	tests := []struct {
+		name    string
+		budget  *v1alpha1.CostBudget
+		wantErr string
+	}{
+		{name: "valid", budget: &v1alpha1.CostBudget{ReplicaHourlyCost: "0.35", MonthlyCap: "1500"}},
+		{name: "zero cost", budget: &v1alpha1.CostBudget{ReplicaHourlyCost: "0", MonthlyCap: "1500"}, wantErr: "spec.costBudget.replicaHourlyCost"},
+		{name: "malformed cap", budget: &v1alpha1.CostBudget{ReplicaHourlyCost: "0.35", MonthlyCap: "$1500"}, wantErr: "spec.costBudget.monthlyCap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hra := newHRAWithTriggerDurations()
+			hra.Spec.CostBudget = tt.budget
+
+			_, err := w.ValidateCreate(context.Background(), hra)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_ValidateAmountExpression(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{}
+
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr string
+	}{
+		{name: "int", expr: "size(workflow_job.labels)"},
+		{name: "bool", expr: "workflow_job.labels.exists(l, l == 'xlarge')"},
+		{name: "syntax error", expr: "size(workflow_job.labels", wantErr: "spec.scaleUpTriggers[0].amountExpression"},
+		{name: "undeclared variable", expr: "job.labels.size()", wantErr: "undeclared reference to 'job'"},
+		{name: "string", expr: "event + 'x'", wantErr: "expression must evaluate to an int or a bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hra := newHRAWithTriggerDurations(0)
+			hra.Spec.ScaleUpTriggers[0].AmountExpression = tt.expr
+
+			_, err := w.ValidateCreate(context.Background(), hra)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_ValidateWorkflowJobFilters(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{}
+
+	hra := newHRAWithTriggerDurations(0)
+	hra.Spec.ScaleUpTriggers[0].GitHubEvent = &v1alpha1.GitHubEventScaleUpTriggerSpec{
+		WorkflowJob: &v1alpha1.WorkflowJobSpec{
+			Workflows:     []string{"Build *"},
+			WorkflowPaths: []string{".github/workflows/build-*.yml"},
+		},
+	}
+
+	_, err := w.ValidateCreate(context.Background(), hra)
+	require.NoError(t, err)
+
+	hra.Spec.ScaleUpTriggers[0].GitHubEvent.WorkflowJob.WorkflowPaths = append(hra.Spec.ScaleUpTriggers[0].GitHubEvent.WorkflowJob.WorkflowPaths, "")
+
+	_, err = w.ValidateCreate(context.Background(), hra)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "spec.scaleUpTriggers[0].githubEvent.workflowJob.workflowPaths[1]")
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_ValidateCheckSuiteAndWorkflowRunFilters(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{}
+
+	hra := newHRAWithTriggerDurations(0)
+	hra.Spec.ScaleUpTriggers[0].GitHubEvent = &v1alpha1.GitHubEventScaleUpTriggerSpec{
+		CheckSuite: &v1alpha1.CheckSuiteSpec{
+			Types:    []string{"requested", "rerequested"},
+			Branches: []string{"main", "release/*"},
+		},
+		WorkflowRun: &v1alpha1.WorkflowRunSpec{
+			Types:    []string{"requested", "in_progress"},
+			Branches: []string{"main"},
+		},
+	}
+
+	_, err := w.ValidateCreate(context.Background(), hra)
+	require.NoError(t, err)
+
+	hra.Spec.ScaleUpTriggers[0].GitHubEvent.CheckSuite.Types = []string{"completed"}
+	hra.Spec.ScaleUpTriggers[0].GitHubEvent.WorkflowRun.Branches = []string{""}
+
+	_, err = w.ValidateCreate(context.Background(), hra)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "spec.scaleUpTriggers[0].githubEvent.checkSuite.types[0]")
+	assert.Contains(t, err.Error(), "spec.scaleUpTriggers[0].githubEvent.workflowRun.branches[0]")
+}
+
+func TestHorizontalRunnerAutoscalerWebhook_ValidateDeploymentFilters(t *testing.T) {
+	w := &v1alpha1.HorizontalRunnerAutoscalerWebhook{}
+
+	hra := newHRAWithTriggerDurations(0)
+	hra.Spec.ScaleUpTriggers[0].GitHubEvent = &v1alpha1.GitHubEventScaleUpTriggerSpec{
+		Deployment: &v1alpha1.DeploymentSpec{
+			Events:       []string{"deployment_protection_rule"},
+			Environments: []string{"production", "staging-*"},
+		},
+	}
+
+	_, err := w.ValidateCreate(context.Background(), hra)
+	require.NoError(t, err)
+
+	hra.Spec.ScaleUpTriggers[0].GitHubEvent.Deployment.Events = []string{"deployment_status"}
+	hra.Spec.ScaleUpTriggers[0].GitHubEvent.Deployment.Environments = []string{""}
+
+	_, err = w.ValidateCreate(context.Background(), hra)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "spec.scaleUpTriggers[0].githubEvent.deployment.events[0]")
+	assert.Contains(t, err.Error(), "spec.scaleUpTriggers[0].githubEvent.deployment.environments[0]")
+}
@@ -19,7 +19,10 @@ package v1alpha1
 import (
 	"errors"
 	"fmt"
+	"path"
+	"strings"
 
+	"github.com/actions/actions-runner-controller/pkg/githuburl"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
@@ -52,15 +55,51 @@ type RunnerConfig struct {
 	// +optional
 	Group string `json:"group,omitempty"`
 
+	// AllowedRepositories restricts an organization runner to the jobs of the listed repositories of the organization.
+	// ARC restricts the runner group to the repositories before registering the runners, creating the group when it's missing,
+	// and the github webhook server doesn't scale the runners for the jobs of the other repositories.
+	// It requires a group other than the default one, which is dedicated to the runners.
+	// +optional
+	AllowedRepositories []string `json:"allowedRepositories,omitempty"`
+
 	// +optional
 	Ephemeral *bool `json:"ephemeral,omitempty"`
 
+	// IdleTimeout is how long a non-ephemeral runner can stay without running a job before it's unregistered and deleted.
+	// The pool doesn't replace the runners deleted this way until its desired replicas increase,
+	// so that the runners are removed one by one as they become idle, even when the replicas are kept up by minReplicas or schedules.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+
+	// SlotsPerPod is the number of runners hosted by each runner pod, in containers sharing the pod's image, volumes and dockerd.
+	// Each slot registers itself as a distinct runner named after the pod, suffixed with "-slot-<n>" except for the first slot,
+	// and works in its own subdirectory of the work directory. A pod is scaled down only once all of its slots are idle.
+	// Experimental. Requires non-ephemeral runners. Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=16
+	SlotsPerPod *int `json:"slotsPerPod,omitempty"`
+
+	// JITConfig registers each runner with a just-in-time configuration generated via the GitHub API once its pod is created,
+	// instead of a registration token shared by the runners. The configuration is mounted into the runner container from a secret owned by the pod,
+	// and the runner ID is known before the runner starts, so that the runner can be unregistered at any time without waiting for its registration.
+	// The runners get the self-hosted label and the configured labels, but not the labels of their OS and architecture.
+	// Requires ephemeral runners.
+	// +optional
+	JITConfig *bool `json:"jitConfig,omitempty"`
+
 	// +optional
 	Image string `json:"image"`
 
 	// +optional
 	WorkDir string `json:"workDir,omitempty"`
 
+	// WorkVolume is the volume mounted at the work directory of the runner, where jobs check out repositories and write build outputs.
+	// Defaults to the work directory within the /runner emptyDir volume, or to a "work" emptyDir volume when dockerd runs in a sidecar.
+	// Not supported with containerMode "kubernetes", which uses workVolumeClaimTemplate instead.
+	// +optional
+	WorkVolume *WorkVolumeSource `json:"workVolume,omitempty"`
+
 	// +optional
 	DockerdWithinRunnerContainer *bool `json:"dockerdWithinRunnerContainer,omitempty"`
 	// +optional
@@ -76,12 +115,103 @@ type RunnerConfig struct {
 	// +optional
 	VolumeStorageMedium *string `json:"volumeStorageMedium,omitempty"`
 
+	// ShmSize is the size of the memory-backed /dev/shm of the runner container, for jobs that need more shared memory
+	// than the 64Mi provided by the container runtime, like browser tests and ML frameworks.
+	// It counts towards the memory usage of the runner container.
+	// +optional
+	ShmSize *resource.Quantity `json:"shmSize,omitempty"`
+
 	// +optional
 	ContainerMode string `json:"containerMode,omitempty"`
 
+	// Spread is a preset to spread the runner pods of the same RunnerDeployment or RunnerSet
+	// across nodes ("node") or zones and nodes ("zone"), so that a single node failure doesn't kill many concurrent jobs.
+	// It expands to best-effort topologySpreadConstraints, and is ignored when topologySpreadConstraints are set explicitly.
+	// Defaults to "none".
+	// +optional
+	// +kubebuilder:validation:Enum=node;zone;none
+	Spread string `json:"spread,omitempty"`
+
+	// RunnerVersion is the version of actions/runner to download and run on startup,
+	// instead of the one bundled in the runner image.
+	// The release is downloaded from the runner artifact mirror when the controller is configured with one,
+	// and from GitHub releases otherwise.
+	// +optional
+	RunnerVersion string `json:"runnerVersion,omitempty"`
+
+	// ContainerHooksVersion is the version of actions/runner-container-hooks to download and use on startup,
+	// instead of the one bundled in the runner image. Only used when containerMode is "kubernetes".
+	// +optional
+	ContainerHooksVersion string `json:"containerHooksVersion,omitempty"`
+
+	// NetworkCheck injects an init container that verifies DNS resolution and reachability of the GitHub and Actions endpoints
+	// before the runner starts registering, and a readiness gate that reports the result as the
+	// actions.summerwind.dev/network-ready pod condition.
+	// +optional
+	NetworkCheck *NetworkCheckSpec `json:"networkCheck,omitempty"`
+
 	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
 }
 
+// NetworkCheckSpec configures the network readiness check of the runner pod.
+type NetworkCheckSpec struct {
+	// Endpoints are the URLs that must be resolvable and reachable from the runner pod.
+	// Any HTTP response counts as reachable.
+	// Defaults to the GitHub URL, plus the API and the Actions service endpoints when the GitHub URL is github.com.
+	// +optional
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// TimeoutSeconds is how long the check retries the endpoints before failing the runner pod.
+	// Defaults to 120.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// WorkVolumeSource is the source of the work volume of the runner. Exactly one of the sources must be set.
+type WorkVolumeSource struct {
+	// EmptyDir is a node-local volume deleted along with the runner pod.
+	// When sizeLimit is set, the runner container requests as much ephemeral storage, so that the scheduler only places
+	// the pod on a node with enough disk, and the kubelet evicts the runner pod alone when the jobs exceed it.
+	// +optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+
+	// Ephemeral is a generic ephemeral volume, provisioned per runner pod by a storage class,
+	// whose size is enforced by the volume itself.
+	// +optional
+	Ephemeral *corev1.EphemeralVolumeSource `json:"ephemeral,omitempty"`
+
+	// HostPath is a directory of the node, kept across runner pods to reuse e.g. checkouts and caches.
+	// Runner pods on the same node share it, so it's only suitable for trusted workflows.
+	// +optional
+	HostPath *corev1.HostPathVolumeSource `json:"hostPath,omitempty"`
+}
+
+func (s *WorkVolumeSource) validate() error {
+	var n int
+	if s.EmptyDir != nil {
+		n++
+	}
+	if s.Ephemeral != nil {
+		n++
+		if s.Ephemeral.VolumeClaimTemplate == nil {
+			return errors.New("ephemeral.volumeClaimTemplate must be specified")
+		}
+	}
+	if s.HostPath != nil {
+		n++
+		if s.HostPath.Path == "" {
+			return errors.New("hostPath.path must be specified")
+		}
+	}
+
+	if n != 1 {
+		return errors.New("exactly one of emptyDir, ephemeral, and hostPath must be specified")
+	}
+
+	return nil
+}
+
 type GitHubAPICredentialsFrom struct {
 	SecretRef SecretReference `json:"secretRef,omitempty"`
 }
@@ -180,6 +310,13 @@ type RunnerPodSpec struct {
 
 	// +optional
 	WorkVolumeClaimTemplate *WorkVolumeClaimTemplate `json:"workVolumeClaimTemplate,omitempty"`
+
+	// HostNetwork runs the runner pod in the network namespace of the node, for jobs that need raw network access.
+	// The DNS policy defaults to ClusterFirstWithHostNet, so that cluster services remain resolvable.
+	// Docker must be disabled, as dockerd would manage the bridge and iptables rules of the node,
+	// and network sysctls can't be set, as they would apply to the node.
+	// +optional
+	HostNetwork bool `json:"hostNetwork,omitempty"`
 }
 
 func (rs *RunnerSpec) Validate(rootPath *field.Path) field.ErrorList {
@@ -193,11 +330,48 @@ func (rs *RunnerSpec) Validate(rootPath *field.Path) field.ErrorList {
 		errList = append(errList, field.Invalid(rootPath.Child("repository"), rs.Repository, err.Error()))
 	}
 
+	errList = append(errList, rs.validateScopeNames(rootPath)...)
+
+	err = rs.validateAllowedRepositories()
+	if err != nil {
+		errList = append(errList, field.Invalid(rootPath.Child("allowedRepositories"), rs.AllowedRepositories, err.Error()))
+	}
+
 	err = rs.validateWorkVolumeClaimTemplate()
 	if err != nil {
 		errList = append(errList, field.Invalid(rootPath.Child("workVolumeClaimTemplate"), rs.WorkVolumeClaimTemplate, err.Error()))
 	}
 
+	err = rs.validateWorkVolume()
+	if err != nil {
+		errList = append(errList, field.Invalid(rootPath.Child("workVolume"), rs.WorkVolume, err.Error()))
+	}
+
+	err = rs.validateHostNetwork()
+	if err != nil {
+		errList = append(errList, field.Invalid(rootPath.Child("hostNetwork"), rs.HostNetwork, err.Error()))
+	}
+
+	err = rs.validateShmSize()
+	if err != nil {
+		errList = append(errList, field.Invalid(rootPath.Child("shmSize"), rs.ShmSize, err.Error()))
+	}
+
+	err = rs.validateIdleTimeout()
+	if err != nil {
+		errList = append(errList, field.Invalid(rootPath.Child("idleTimeout"), rs.IdleTimeout, err.Error()))
+	}
+
+	err = rs.validateSlotsPerPod()
+	if err != nil {
+		errList = append(errList, field.Invalid(rootPath.Child("slotsPerPod"), rs.SlotsPerPod, err.Error()))
+	}
+
+	err = rs.validateJITConfig()
+	if err != nil {
+		errList = append(errList, field.Invalid(rootPath.Child("jitConfig"), rs.JITConfig, err.Error()))
+	}
+
 	return errList
 }
 
@@ -224,6 +398,77 @@ func (rs *RunnerSpec) validateRepository() error {
 	return nil
 }
 
+// validateScopeNames validates the names of the enterprise, organization and repository,
+// so that a URL or a malformed name is rejected here rather than resulting in 404s from the GitHub API on registration.
+func (rs *RunnerSpec) validateScopeNames(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	for _, f := range []struct {
+		name  string
+		scope githuburl.Scope
+		value string
+	}{
+		{"enterprise", githuburl.ScopeEnterprise, rs.Enterprise},
+		{"organization", githuburl.ScopeOrganization, rs.Organization},
+		{"repository", githuburl.ScopeRepository, rs.Repository},
+	} {
+		if f.value == "" {
+			continue
+		}
+
+		if err := githuburl.ValidateNameField(f.scope, f.value); err != nil {
+			errList = append(errList, field.Invalid(rootPath.Child(f.name), f.value, err.Error()))
+		}
+	}
+
+	return errList
+}
+
+// NormalizeScope trims the names of the enterprise, organization and repository,
+// and replaces the URLs pasted into them with the names they point to.
+func (rc *RunnerConfig) NormalizeScope() {
+	if rc.Enterprise != "" {
+		rc.Enterprise = githuburl.NormalizeName(githuburl.ScopeEnterprise, rc.Enterprise)
+	}
+	if rc.Organization != "" {
+		rc.Organization = githuburl.NormalizeName(githuburl.ScopeOrganization, rc.Organization)
+	}
+	if rc.Repository != "" {
+		rc.Repository = githuburl.NormalizeName(githuburl.ScopeRepository, rc.Repository)
+	}
+}
+
+func (rs *RunnerSpec) validateAllowedRepositories() error {
+	if len(rs.AllowedRepositories) == 0 {
+		return nil
+	}
+
+	if rs.Organization == "" {
+		return errors.New("allowedRepositories can be used only with organization runners")
+	}
+
+	if rs.Group == "" || strings.EqualFold(rs.Group, "Default") {
+		return errors.New("allowedRepositories requires a group other than the default one, which ARC restricts to the repositories")
+	}
+
+	for _, r := range rs.AllowedRepositories {
+		owner, name, found := strings.Cut(r, "/")
+		if !found {
+			owner, name = rs.Organization, r
+		}
+
+		if name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("%q must be the name of a repository of the organization", r)
+		}
+
+		if !strings.EqualFold(owner, rs.Organization) {
+			return fmt.Errorf("%q isn't a repository of organization %s", r, rs.Organization)
+		}
+	}
+
+	return nil
+}
+
 func (rs *RunnerSpec) validateWorkVolumeClaimTemplate() error {
 	if rs.ContainerMode != "kubernetes" {
 		return nil
@@ -236,6 +481,126 @@ func (rs *RunnerSpec) validateWorkVolumeClaimTemplate() error {
 	return rs.WorkVolumeClaimTemplate.validate()
 }
 
+func (rs *RunnerSpec) validateWorkVolume() error {
+	if rs.WorkVolume == nil {
+		return nil
+	}
+
+	if rs.ContainerMode == "kubernetes" {
+		return errors.New("workVolume can't be used with containerMode: kubernetes, use workVolumeClaimTemplate instead")
+	}
+
+	return rs.WorkVolume.validate()
+}
+
+func (rs *RunnerSpec) validateHostNetwork() error {
+	if !rs.HostNetwork {
+		return nil
+	}
+
+	if rs.ContainerMode != "kubernetes" && (rs.DockerEnabled == nil || *rs.DockerEnabled) {
+		return errors.New("hostNetwork requires dockerEnabled: false, as dockerd would manage the network of the node")
+	}
+
+	if rs.SecurityContext != nil {
+		for _, s := range rs.SecurityContext.Sysctls {
+			if strings.HasPrefix(s.Name, "net.") {
+				return fmt.Errorf("sysctl %q can't be set with hostNetwork, as it would apply to the node", s.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (rs *RunnerSpec) validateShmSize() error {
+	if rs.ShmSize == nil {
+		return nil
+	}
+
+	if rs.ShmSize.Sign() <= 0 {
+		return errors.New("shmSize must be greater than zero")
+	}
+
+	for _, m := range rs.VolumeMounts {
+		if path.Clean(m.MountPath) == "/dev/shm" {
+			return fmt.Errorf("shmSize can't be used along with the volume mount %q at /dev/shm", m.Name)
+		}
+	}
+
+	return nil
+}
+
+func (rs *RunnerSpec) validateIdleTimeout() error {
+	if rs.IdleTimeout == nil {
+		return nil
+	}
+
+	if rs.IdleTimeout.Duration <= 0 {
+		return errors.New("idleTimeout must be greater than zero")
+	}
+
+	if rs.Ephemeral == nil || *rs.Ephemeral {
+		return errors.New("idleTimeout can be used only with non-ephemeral runners, as ephemeral runners are deleted after their jobs")
+	}
+
+	return nil
+}
+
+func (rs *RunnerSpec) validateSlotsPerPod() error {
+	if rs.SlotsPerPod == nil {
+		return nil
+	}
+
+	if *rs.SlotsPerPod < 1 {
+		return errors.New("slotsPerPod must be greater than zero")
+	}
+
+	if *rs.SlotsPerPod == 1 {
+		return nil
+	}
+
+	if rs.Ephemeral == nil || *rs.Ephemeral {
+		return errors.New("slotsPerPod greater than 1 can be used only with non-ephemeral runners, as an ephemeral runner pod is replaced once any of its runners completes a job")
+	}
+
+	if rs.ContainerMode == "kubernetes" {
+		return errors.New("slotsPerPod greater than 1 can't be used along with containerMode kubernetes")
+	}
+
+	if rs.DockerdWithinRunnerContainer != nil && *rs.DockerdWithinRunnerContainer {
+		return errors.New("slotsPerPod greater than 1 can't be used along with dockerdWithinRunnerContainer, as the slots share the dockerd of the pod")
+	}
+
+	return nil
+}
+
+func (rs *RunnerSpec) validateJITConfig() error {
+	if rs.JITConfig == nil || !*rs.JITConfig {
+		return nil
+	}
+
+	if rs.Ephemeral != nil && !*rs.Ephemeral {
+		return errors.New("jitConfig can be used only with ephemeral runners, as a just-in-time configuration registers the runner for a single job")
+	}
+
+	return nil
+}
+
+// ValidateWarmStandby validates that warm standby runners, which register only once promoted,
+// are not combined with a just-in-time configuration that registers each runner as soon as its pod is created.
+func (rs *RunnerSpec) ValidateWarmStandby(warmStandby *int) error {
+	if warmStandby == nil || *warmStandby == 0 {
+		return nil
+	}
+
+	if rs.JITConfig != nil && *rs.JITConfig {
+		return errors.New("warmStandby can't be used along with jitConfig, as a just-in-time configuration registers the runner before it is promoted")
+	}
+
+	return nil
+}
+
 // RunnerStatus defines the observed state of Runner
 type RunnerStatus struct {
 	// Turns true only if the runner pod is ready.
@@ -0,0 +1,383 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestRunnerSpecValidate_HostNetworkAndShmSize(t *testing.T) {
+	dockerDisabled := false
+	zero := resource.MustParse("0")
+	size := resource.MustParse("1Gi")
+
+	tests := []struct {
+		name    string
+		spec    RunnerSpec
+		wantErr bool
+	}{
+		{
+			name: "hostNetwork without docker",
+			spec: RunnerSpec{
+				RunnerConfig:  RunnerConfig{DockerEnabled: &dockerDisabled},
+				RunnerPodSpec: RunnerPodSpec{HostNetwork: true},
+			},
+		},
+		{
+			name: "hostNetwork in kubernetes container mode",
+			spec: RunnerSpec{
+				RunnerConfig: RunnerConfig{ContainerMode: "kubernetes"},
+				RunnerPodSpec: RunnerPodSpec{
+					HostNetwork:             true,
+					WorkVolumeClaimTemplate: &WorkVolumeClaimTemplate{AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}},
+				},
+			},
+		},
+		{
+			name:    "hostNetwork with docker",
+			spec:    RunnerSpec{RunnerPodSpec: RunnerPodSpec{HostNetwork: true}},
+			wantErr: true,
+		},
+		{
+			name: "hostNetwork with network sysctls",
+			spec: RunnerSpec{
+				RunnerConfig: RunnerConfig{DockerEnabled: &dockerDisabled},
+				RunnerPodSpec: RunnerPodSpec{
+					HostNetwork:     true,
+					SecurityContext: &corev1.PodSecurityContext{Sysctls: []corev1.Sysctl{{Name: "net.ipv4.ip_local_port_range", Value: "1024 65535"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sysctls without hostNetwork",
+			spec: RunnerSpec{
+				RunnerPodSpec: RunnerPodSpec{
+					SecurityContext: &corev1.PodSecurityContext{Sysctls: []corev1.Sysctl{{Name: "net.ipv4.ip_local_port_range", Value: "1024 65535"}}},
+				},
+			},
+		},
+		{
+			name: "shmSize",
+			spec: RunnerSpec{RunnerConfig: RunnerConfig{ShmSize: &size}},
+		},
+		{
+			name:    "zero shmSize",
+			spec:    RunnerSpec{RunnerConfig: RunnerConfig{ShmSize: &zero}},
+			wantErr: true,
+		},
+		{
+			name: "shmSize with a /dev/shm volume mount",
+			spec: RunnerSpec{
+				RunnerConfig:  RunnerConfig{ShmSize: &size},
+				RunnerPodSpec: RunnerPodSpec{VolumeMounts: []corev1.VolumeMount{{Name: "shm", MountPath: "/dev/shm"}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.spec.Repository = "test/valid"
+			errs := tt.spec.Validate(field.NewPath("spec"))
+			if tt.wantErr {
+				require.NotEmpty(t, errs)
+			} else {
+				require.Empty(t, errs)
+			}
+		})
+	}
+}
+
+func TestRunnerSpecValidate_AllowedRepositories(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RunnerConfig
+		wantErr bool
+	}{
+		{
+			name:   "repository names",
+			config: RunnerConfig{Organization: "example", Group: "linux", AllowedRepositories: []string{"api", "Example/web"}},
+		},
+		{
+			name:    "repository runners",
+			config:  RunnerConfig{Repository: "example/api", AllowedRepositories: []string{"api"}},
+			wantErr: true,
+		},
+		{
+			name:    "no group",
+			config:  RunnerConfig{Organization: "example", AllowedRepositories: []string{"api"}},
+			wantErr: true,
+		},
+		{
+			name:    "default group",
+			config:  RunnerConfig{Organization: "example", Group: "default", AllowedRepositories: []string{"api"}},
+			wantErr: true,
+		},
+		{
+			name:    "repository of another organization",
+			config:  RunnerConfig{Organization: "example", Group: "linux", AllowedRepositories: []string{"other/api"}},
+			wantErr: true,
+		},
+		{
+			name:    "empty repository name",
+			config:  RunnerConfig{Organization: "example", Group: "linux", AllowedRepositories: []string{"example/"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := RunnerSpec{RunnerConfig: tt.config}
+			errs := spec.Validate(field.NewPath("spec"))
+			if tt.wantErr {
+				require.NotEmpty(t, errs)
+			} else {
+				require.Empty(t, errs)
+			}
+		})
+	}
+}
+
+func TestRunnerSpecValidate_IdleTimeout(t *testing.T) {
+	ephemeral := true
+	persistent := false
+
+	tests := []struct {
+		name    string
+		config  RunnerConfig
+		wantErr bool
+	}{
+		{
+			name:   "persistent runners",
+			config: RunnerConfig{Ephemeral: &persistent, IdleTimeout: &metav1.Duration{Duration: 30 * time.Minute}},
+		},
+		{
+			name:    "ephemeral runners",
+			config:  RunnerConfig{Ephemeral: &ephemeral, IdleTimeout: &metav1.Duration{Duration: 30 * time.Minute}},
+			wantErr: true,
+		},
+		{
+			name:    "runners ephemeral by default",
+			config:  RunnerConfig{IdleTimeout: &metav1.Duration{Duration: 30 * time.Minute}},
+			wantErr: true,
+		},
+		{
+			name:    "zero idle timeout",
+			config:  RunnerConfig{Ephemeral: &persistent, IdleTimeout: &metav1.Duration{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := RunnerSpec{RunnerConfig: tt.config}
+			spec.Repository = "test/valid"
+			errs := spec.Validate(field.NewPath("spec"))
+			if tt.wantErr {
+				require.NotEmpty(t, errs)
+			} else {
+				require.Empty(t, errs)
+			}
+		})
+	}
+}
+
+func TestRunnerSpecValidate_SlotsPerPod(t *testing.T) {
+	ephemeral := true
+	persistent := false
+	one, four, zero := 1, 4, 0
+
+	tests := []struct {
+		name    string
+		config  RunnerConfig
+		wantErr bool
+	}{
+		{
+			name:   "persistent runners",
+			config: RunnerConfig{Ephemeral: &persistent, SlotsPerPod: &four},
+		},
+		{
+			name:   "single slot of ephemeral runners",
+			config: RunnerConfig{Ephemeral: &ephemeral, SlotsPerPod: &one},
+		},
+		{
+			name:    "ephemeral runners",
+			config:  RunnerConfig{Ephemeral: &ephemeral, SlotsPerPod: &four},
+			wantErr: true,
+		},
+		{
+			name:    "runners ephemeral by default",
+			config:  RunnerConfig{SlotsPerPod: &four},
+			wantErr: true,
+		},
+		{
+			name:    "dockerd within runner container",
+			config:  RunnerConfig{Ephemeral: &persistent, DockerdWithinRunnerContainer: &ephemeral, SlotsPerPod: &four},
+			wantErr: true,
+		},
+		{
+			name:    "zero slots",
+			config:  RunnerConfig{Ephemeral: &persistent, SlotsPerPod: &zero},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := RunnerSpec{RunnerConfig: tt.config}
+			spec.Repository = "test/valid"
+			errs := spec.Validate(field.NewPath("spec"))
+			if tt.wantErr {
+				require.NotEmpty(t, errs)
+			} else {
+				require.Empty(t, errs)
+			}
+		})
+	}
+}
+
+func TestRunnerSpecValidate_JITConfig(t *testing.T) {
+	enabled, disabled := true, false
+
+	tests := []struct {
+		name    string
+		config  RunnerConfig
+		wantErr bool
+	}{
+		{
+			name:   "runners ephemeral by default",
+			config: RunnerConfig{JITConfig: &enabled},
+		},
+		{
+			name:   "ephemeral runners",
+			config: RunnerConfig{JITConfig: &enabled, Ephemeral: &enabled},
+		},
+		{
+			name:   "disabled for persistent runners",
+			config: RunnerConfig{JITConfig: &disabled, Ephemeral: &disabled},
+		},
+		{
+			name:    "persistent runners",
+			config:  RunnerConfig{JITConfig: &enabled, Ephemeral: &disabled},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := RunnerSpec{RunnerConfig: tt.config}
+			spec.Repository = "test/valid"
+			errs := spec.Validate(field.NewPath("spec"))
+			if tt.wantErr {
+				require.NotEmpty(t, errs)
+			} else {
+				require.Empty(t, errs)
+			}
+		})
+	}
+}
+
+func TestRunnerSpecValidateWarmStandby(t *testing.T) {
+	enabled, disabled := true, false
+	zero, two := 0, 2
+
+	tests := []struct {
+		name        string
+		config      RunnerConfig
+		warmStandby *int
+		wantErr     bool
+	}{
+		{
+			name:   "no warm standby",
+			config: RunnerConfig{JITConfig: &enabled},
+		},
+		{
+			name:        "zero warm standby",
+			config:      RunnerConfig{JITConfig: &enabled},
+			warmStandby: &zero,
+		},
+		{
+			name:        "registration tokens",
+			config:      RunnerConfig{JITConfig: &disabled},
+			warmStandby: &two,
+		},
+		{
+			name:        "jit config",
+			config:      RunnerConfig{JITConfig: &enabled},
+			warmStandby: &two,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := RunnerSpec{RunnerConfig: tt.config}
+			err := spec.ValidateWarmStandby(tt.warmStandby)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRunnerSpecValidate_ScopeNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RunnerConfig
+		want    RunnerConfig
+		wantErr string
+	}{
+		{
+			name:   "repository URL",
+			config: RunnerConfig{Repository: "https://github.com/my-org/my-repo/"},
+			want:   RunnerConfig{Repository: "my-org/my-repo"},
+		},
+		{
+			name:   "GHES organization URL",
+			config: RunnerConfig{Organization: "https://ghes.example.com/my-org"},
+			want:   RunnerConfig{Organization: "my-org"},
+		},
+		{
+			name:   "enterprise URL",
+			config: RunnerConfig{Enterprise: "https://github.com/enterprises/my-enterprise"},
+			want:   RunnerConfig{Enterprise: "my-enterprise"},
+		},
+		{
+			name:    "organization URL in the repository field",
+			config:  RunnerConfig{Repository: "https://github.com/my-org"},
+			want:    RunnerConfig{Repository: "https://github.com/my-org"},
+			wantErr: `Set organization to "my-org" instead`,
+		},
+		{
+			name:    "repository without owner",
+			config:  RunnerConfig{Repository: "my-repo"},
+			want:    RunnerConfig{Repository: "my-repo"},
+			wantErr: "must be in the OWNER/REPO format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := &RunnerDeployment{}
+			rd.Spec.Template.Spec.RunnerConfig = tt.config
+			rd.Default()
+
+			require.Equal(t, tt.want, rd.Spec.Template.Spec.RunnerConfig)
+
+			err := rd.Validate()
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -41,7 +41,7 @@ var _ webhook.Defaulter = &Runner{}
 
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *Runner) Default() {
-	// Nothing to do.
+	r.Spec.RunnerConfig.NormalizeScope()
 }
 
 // +kubebuilder:webhook:path=/validate-actions-summerwind-dev-v1alpha1-runner,verbs=create;update,mutating=false,failurePolicy=fail,groups=actions.summerwind.dev,resources=runners,versions=v1alpha1,name=validate.runner.actions.summerwind.dev,sideEffects=None,admissionReviewVersions=v1beta1
@@ -23,6 +23,8 @@ import (
 const (
 	AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns = "TotalNumberOfQueuedAndInProgressWorkflowRuns"
 	AutoscalingMetricTypePercentageRunnersBusy                        = "PercentageRunnersBusy"
+	AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowJobs = "TotalNumberOfQueuedAndInProgressWorkflowJobs"
+	AutoscalingMetricTypeOldestQueuedWorkflowJobAge                   = "OldestQueuedWorkflowJobAge"
 )
 
 // RunnerDeploymentSpec defines the desired state of RunnerDeployment
@@ -39,6 +41,15 @@ type RunnerDeploymentSpec struct {
 	// +nullable
 	EffectiveTime *metav1.Time `json:"effectiveTime"`
 
+	// WarmStandby is the number of runner pods created in addition to Replicas and kept paused before registering to GitHub.
+	// A standby runner pod has its images pulled and its containers started, and is promoted to a registered runner
+	// as soon as Replicas increases, which greatly reduces the latency of the first job after scaling from zero.
+	// The value is inherited to RunnerReplicaSet(s).
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	WarmStandby *int `json:"warmStandby,omitempty"`
+
 	// +optional
 	// +nullable
 	Selector *metav1.LabelSelector `json:"selector"`
@@ -72,6 +83,19 @@ type RunnerDeploymentStatus struct {
 	// Replicas is the total number of replicas
 	// +optional
 	Replicas *int `json:"replicas"`
+
+	// RunnerImage is the runner image of the newest runner replica set.
+	// It is empty when the default runner image is used.
+	// A runner image build pipeline can wait for it to match the image it rolled out,
+	// and for updatedReplicas to match replicas.
+	// +optional
+	RunnerImage string `json:"runnerImage,omitempty"`
+
+	// OverlappingRunnerPools lists the other RunnerDeployments and RunnerSets, formatted as "<kind>/<namespace>/<name>",
+	// that register runners with the same labels to the same enterprise, organization or repository and runner group.
+	// GitHub can route a job to any of the overlapping pools, so this should usually be empty.
+	// +optional
+	OverlappingRunnerPools []string `json:"overlappingRunnerPools,omitempty"`
 }
 
 // +kubebuilder:object:root=true
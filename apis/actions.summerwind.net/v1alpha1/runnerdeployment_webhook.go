@@ -41,7 +41,7 @@ var _ webhook.Defaulter = &RunnerDeployment{}
 
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *RunnerDeployment) Default() {
-	// Nothing to do.
+	r.Spec.Template.Spec.RunnerConfig.NormalizeScope()
 }
 
 // +kubebuilder:webhook:path=/validate-actions-summerwind-dev-v1alpha1-runnerdeployment,verbs=create;update,mutating=false,failurePolicy=fail,groups=actions.summerwind.dev,resources=runnerdeployments,versions=v1alpha1,name=validate.runnerdeployment.actions.summerwind.dev,sideEffects=None,admissionReviewVersions=v1beta1
@@ -69,6 +69,10 @@ func (r *RunnerDeployment) ValidateDelete() (admission.Warnings, error) {
 func (r *RunnerDeployment) Validate() error {
 	errList := r.Spec.Template.Spec.Validate(field.NewPath("spec", "template", "spec"))
 
+	if err := r.Spec.Template.Spec.ValidateWarmStandby(r.Spec.WarmStandby); err != nil {
+		errList = append(errList, field.Invalid(field.NewPath("spec", "warmStandby"), r.Spec.WarmStandby, err.Error()))
+	}
+
 	if len(errList) > 0 {
 		return apierrors.NewInvalid(r.GroupVersionKind().GroupKind(), r.Name, errList)
 	}
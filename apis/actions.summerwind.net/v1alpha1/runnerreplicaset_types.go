@@ -35,6 +35,13 @@ type RunnerReplicaSetSpec struct {
 	// +nullable
 	EffectiveTime *metav1.Time `json:"effectiveTime"`
 
+	// WarmStandby is the number of runners created in addition to Replicas and kept paused before registering to GitHub,
+	// so that they can be promoted to registered runners as soon as Replicas increases.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	WarmStandby *int `json:"warmStandby,omitempty"`
+
 	// +optional
 	// +nullable
 	Selector *metav1.LabelSelector `json:"selector"`
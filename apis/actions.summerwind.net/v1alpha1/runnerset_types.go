@@ -69,6 +69,12 @@ type RunnerSetStatus struct {
 	// Replicas is the total number of replicas
 	// +optional
 	Replicas *int `json:"replicas"`
+
+	// OverlappingRunnerPools lists the other RunnerDeployments and RunnerSets, formatted as "<kind>/<namespace>/<name>",
+	// that register runners with the same labels to the same enterprise, organization or repository and runner group.
+	// GitHub can route a job to any of the overlapping pools, so this should usually be empty.
+	// +optional
+	OverlappingRunnerPools []string `json:"overlappingRunnerPools,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,119 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/google/cel-go/cel"
+)
+
+// amountExpressionCostLimit bounds the cost of evaluating an amountExpression,
+// so that a costly expression can't stall the webhook server.
+const amountExpressionCostLimit = 100000
+
+// amountExpressionPayloadFields are the top-level fields of webhook payloads declared as variables of amountExpressions.
+// A field missing from the payload of an event is null.
+var amountExpressionPayloadFields = []string{
+	"action",
+	"check_run",
+	"check_suite",
+	"deployment",
+	"deployment_status",
+	"enterprise",
+	"environment",
+	"installation",
+	"organization",
+	"pull_request",
+	"repository",
+	"sender",
+	"workflow_job",
+	"workflow_run",
+}
+
+// ScaleUpTriggerAmountProgram is a compiled amountExpression.
+// +kubebuilder:object:generate=false
+type ScaleUpTriggerAmountProgram struct {
+	program cel.Program
+}
+
+// CompileScaleUpTriggerAmountExpression compiles the amountExpression of a scale up trigger.
+func CompileScaleUpTriggerAmountExpression(expr string) (*ScaleUpTriggerAmountProgram, error) {
+	opts := []cel.EnvOption{
+		cel.Variable("event", cel.StringType),
+		cel.Variable("payload", cel.DynType),
+	}
+	for _, f := range amountExpressionPayloadFields {
+		opts = append(opts, cel.Variable(f, cel.DynType))
+	}
+
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+
+	if t := ast.OutputType(); !isAmountExpressionOutputType(t) {
+		return nil, fmt.Errorf("expression must evaluate to an int or a bool, but evaluates to %s", t)
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(amountExpressionCostLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScaleUpTriggerAmountProgram{program: program}, nil
+}
+
+// isAmountExpressionOutputType returns true when the type-checked result of an amountExpression can be an amount.
+// Expressions like `workflow_job.run_attempt` are dyn, as the payload isn't typed, so they are checked on evaluation.
+func isAmountExpressionOutputType(t *cel.Type) bool {
+	for _, amountType := range []*cel.Type{cel.IntType, cel.UintType, cel.BoolType} {
+		if amountType.IsAssignableType(t) {
+			return true
+		}
+	}
+	return t.String() == cel.DynType.String()
+}
+
+// Eval evaluates the expression against the payload of a webhook event of the given type, and returns the amount.
+func (p *ScaleUpTriggerAmountProgram) Eval(event string, payload map[string]interface{}) (int, error) {
+	vars := map[string]interface{}{
+		"event":   event,
+		"payload": payload,
+	}
+	for _, f := range amountExpressionPayloadFields {
+		vars[f] = payload[f]
+	}
+
+	out, _, err := p.program.Eval(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := out.Value().(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("expression evaluated to a negative amount: %d", v)
+		}
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float64:
+		// Numbers of the payload are decoded as doubles
+		if v < 0 || v != math.Trunc(v) {
+			return 0, fmt.Errorf("expression evaluated to an invalid amount: %v", v)
+		}
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("expression must evaluate to an int or a bool, but evaluated to %T", v)
+	}
+}
@@ -0,0 +1,52 @@
+package v1alpha1_test
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaleUpTriggerAmountProgram_Eval(t *testing.T) {
+	payload := map[string]interface{}{
+		"action": "queued",
+		"workflow_job": map[string]interface{}{
+			"labels":      []interface{}{"self-hosted", "xlarge"},
+			"run_attempt": float64(2),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    int
+		wantErr string
+	}{
+		{name: "int", expr: "size(workflow_job.labels)", want: 2},
+		{name: "true", expr: "workflow_job.labels.exists(l, l == 'xlarge')", want: 1},
+		{name: "false", expr: "workflow_job.labels.exists(l, l == 'gpu')", want: 0},
+		{name: "event", expr: "event == 'workflow_job' && action == 'queued' ? 3 : 0", want: 3},
+		{name: "missing field", expr: "pull_request == null", want: 1},
+		{name: "whole payload", expr: "has(payload.workflow_job)", want: 1},
+		{name: "payload number", expr: "workflow_job.run_attempt", want: 2},
+		{name: "negative", expr: "-1", wantErr: "negative amount"},
+		{name: "dyn string", expr: "action", wantErr: "must evaluate to an int or a bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v1alpha1.CompileScaleUpTriggerAmountExpression(tt.expr)
+			require.NoError(t, err)
+
+			got, err := p.Eval("workflow_job", payload)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
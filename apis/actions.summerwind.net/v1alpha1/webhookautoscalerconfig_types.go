@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// WebhookDeduplicationPolicyNone applies every delivery.
+	WebhookDeduplicationPolicyNone = "None"
+	// WebhookDeduplicationPolicyDeliveryID ignores a delivery with the same X-GitHub-Delivery as a delivery applied within the window,
+	// like a delivery redelivered from the GitHub UI or API.
+	WebhookDeduplicationPolicyDeliveryID = "DeliveryID"
+	// WebhookDeduplicationPolicyWorkflowJob ignores a workflow_job event with the same job ID and action as an event applied within the window,
+	// like the same event sent by both a repository and an organization webhook.
+	WebhookDeduplicationPolicyWorkflowJob = "WorkflowJob"
+
+	// DefaultWebhookSecretTokenKey is the key of the webhook secret token in the secret referenced by secretTokenSecretRef.
+	DefaultWebhookSecretTokenKey = "github_webhook_secret_token"
+	// DefaultWebhookNextSecretTokenKey is the key of the next webhook secret token in the secret referenced by nextSecretTokenSecretRef.
+	DefaultWebhookNextSecretTokenKey = "github_webhook_secret_token_next"
+)
+
+// WebhookAutoscalerConfigSpec defines how the github webhook server handles the deliveries sent to a path.
+type WebhookAutoscalerConfigSpec struct {
+	// Path is the HTTP path of the webhook server the GitHub webhook delivers to.
+	// It must be under "/<namespace>/", and defaults to "/<namespace>/<name>".
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// SecretTokenSecretRef references the secret token of the GitHub webhook in a secret in the same namespace.
+	// The key defaults to "github_webhook_secret_token".
+	SecretTokenSecretRef *SecretKeyReference `json:"secretTokenSecretRef"`
+
+	// NextSecretTokenSecretRef references the secret token the GitHub webhook is being rotated to, in a secret in the same namespace.
+	// Deliveries signed with either of the two tokens are accepted while the GitHub webhook is updated.
+	// The key defaults to "github_webhook_secret_token_next".
+	// +optional
+	NextSecretTokenSecretRef *SecretKeyReference `json:"nextSecretTokenSecretRef,omitempty"`
+
+	// GitHubAPICredentialsFrom references the secret containing the GitHub API credentials used to discover runner groups
+	// visible to repositories and to look up workflow paths, in the same format as the controller's secret.
+	// +optional
+	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
+
+	// HorizontalRunnerAutoscalerSelector selects the HorizontalRunnerAutoscalers in the same namespace the deliveries scale.
+	// All of them are selected when omitted.
+	// +optional
+	HorizontalRunnerAutoscalerSelector *metav1.LabelSelector `json:"horizontalRunnerAutoscalerSelector,omitempty"`
+
+	// DefaultScaleUpTriggerDuration is the duration of the capacity reservation added by a scale up trigger that omits it.
+	// Defaults to the --default-scale-up-trigger-duration of the webhook server.
+	// +optional
+	DefaultScaleUpTriggerDuration *metav1.Duration `json:"defaultScaleUpTriggerDuration,omitempty"`
+
+	// +optional
+	Deduplication *WebhookDeduplication `json:"deduplication,omitempty"`
+
+	// Routes route the workflow_job events to the HorizontalRunnerAutoscalers by the repository and the labels of the job,
+	// instead of by the repository, the organization or the enterprise and the labels of the runners of the HorizontalRunnerAutoscalers.
+	// An event is routed by the first route that matches it. The events no route matches are handled as if there were no routes.
+	// +optional
+	Routes []WebhookRoute `json:"routes,omitempty"`
+}
+
+// WebhookRoute routes the workflow_job events it matches to the HorizontalRunnerAutoscalers it selects.
+type WebhookRoute struct {
+	// Repositories is a list of GitHub Actions glob patterns, like `my-org/frontend-*`.
+	// Any workflow_job event whose repository full name matches one of patterns in the list is matched.
+	// Defaults to all the repositories.
+	// +optional
+	Repositories []string `json:"repositories,omitempty"`
+
+	// Labels is a list of GitHub Actions glob patterns, like `gpu-*`.
+	// Any workflow_job event that has a label matching each of patterns in the list is matched. Labels are compared case-insensitively.
+	// Defaults to all the labels.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// HorizontalRunnerAutoscalerSelector selects the HorizontalRunnerAutoscalers in the same namespace the matched events scale,
+	// among the ones selected by spec.horizontalRunnerAutoscalerSelector.
+	// The event scales the first of them by name whose workflowJob scale up trigger matches the workflow of the job.
+	HorizontalRunnerAutoscalerSelector *metav1.LabelSelector `json:"horizontalRunnerAutoscalerSelector"`
+
+	// RunnerGroup restricts the selected HorizontalRunnerAutoscalers to the ones whose runners are registered to the runner group.
+	// +optional
+	RunnerGroup string `json:"runnerGroup,omitempty"`
+}
+
+type SecretKeyReference struct {
+	Name string `json:"name"`
+
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// WebhookDeduplication configures how deliveries already applied are ignored.
+type WebhookDeduplication struct {
+	// Policy is one of None, DeliveryID and WorkflowJob. Defaults to None.
+	// +optional
+	// +kubebuilder:validation:Enum=None;DeliveryID;WorkflowJob
+	Policy string `json:"policy,omitempty"`
+
+	// Window is how long an applied delivery is remembered. Defaults to 10m.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+}
+
+type WebhookAutoscalerConfigStatus struct {
+	// ObservedGeneration is the generation of the spec the webhook server has loaded.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Path is the HTTP path the webhook server serves this config on.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Ready is true once the webhook server serves this config.
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+
+	// Message explains why the config isn't ready.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".status.path",name=Path,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.ready",name=Ready,type=boolean
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// WebhookAutoscalerConfig is the Schema for the webhookautoscalerconfigs API
+type WebhookAutoscalerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WebhookAutoscalerConfigSpec   `json:"spec,omitempty"`
+	Status WebhookAutoscalerConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WebhookAutoscalerConfigList contains a list of WebhookAutoscalerConfig
+type WebhookAutoscalerConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WebhookAutoscalerConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WebhookAutoscalerConfig{}, &WebhookAutoscalerConfigList{})
+}
@@ -21,8 +21,8 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -89,6 +89,180 @@ func (in *CheckRunSpec) DeepCopy() *CheckRunSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckSuiteSpec) DeepCopyInto(out *CheckSuiteSpec) {
+	*out = *in
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckSuiteSpec.
+func (in *CheckSuiteSpec) DeepCopy() *CheckSuiteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckSuiteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostBudget) DeepCopyInto(out *CostBudget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostBudget.
+func (in *CostBudget) DeepCopy() *CostBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(CostBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostBudgetStatus) DeepCopyInto(out *CostBudgetStatus) {
+	*out = *in
+	in.PeriodStart.DeepCopyInto(&out.PeriodStart)
+	in.LastAccountedTime.DeepCopyInto(&out.LastAccountedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostBudgetStatus.
+func (in *CostBudgetStatus) DeepCopy() *CostBudgetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CostBudgetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentSpec) DeepCopyInto(out *DeploymentSpec) {
+	*out = *in
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentSpec.
+func (in *DeploymentSpec) DeepCopy() *DeploymentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DesiredReplicasRecord) DeepCopyInto(out *DesiredReplicasRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DesiredReplicasRecord.
+func (in *DesiredReplicasRecord) DeepCopy() *DesiredReplicasRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(DesiredReplicasRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunStatus) DeepCopyInto(out *DryRunStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunStatus.
+func (in *DryRunStatus) DeepCopy() *DryRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExclusionCalendar) DeepCopyInto(out *ExclusionCalendar) {
+	*out = *in
+	if in.Dates != nil {
+		in, out := &in.Dates, &out.Dates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ICalSecretKeyRef != nil {
+		in, out := &in.ICalSecretKeyRef, &out.ICalSecretKeyRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExclusionCalendar.
+func (in *ExclusionCalendar) DeepCopy() *ExclusionCalendar {
+	if in == nil {
+		return nil
+	}
+	out := new(ExclusionCalendar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Federation) DeepCopyInto(out *Federation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Federation.
+func (in *Federation) DeepCopy() *Federation {
+	if in == nil {
+		return nil
+	}
+	out := new(Federation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationStatus) DeepCopyInto(out *FederationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationStatus.
+func (in *FederationStatus) DeepCopy() *FederationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubAPICredentialsFrom) DeepCopyInto(out *GitHubAPICredentialsFrom) {
 	*out = *in
@@ -126,7 +300,22 @@ func (in *GitHubEventScaleUpTriggerSpec) DeepCopyInto(out *GitHubEventScaleUpTri
 	if in.WorkflowJob != nil {
 		in, out := &in.WorkflowJob, &out.WorkflowJob
 		*out = new(WorkflowJobSpec)
-		**out = **in
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CheckSuite != nil {
+		in, out := &in.CheckSuite, &out.CheckSuite
+		*out = new(CheckSuiteSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WorkflowRun != nil {
+		in, out := &in.WorkflowRun, &out.WorkflowRun
+		*out = new(WorkflowRunSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deployment != nil {
+		in, out := &in.Deployment, &out.Deployment
+		*out = new(DeploymentSpec)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -203,6 +392,13 @@ func (in *HorizontalRunnerAutoscalerList) DeepCopyObject() runtime.Object {
 func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAutoscalerSpec) {
 	*out = *in
 	out.ScaleTargetRef = in.ScaleTargetRef
+	if in.ScaleTargets != nil {
+		in, out := &in.ScaleTargets, &out.ScaleTargets
+		*out = make([]WeightedScaleTargetRef, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.MinReplicas != nil {
 		in, out := &in.MinReplicas, &out.MinReplicas
 		*out = new(int)
@@ -218,6 +414,41 @@ func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAuto
 		*out = new(int)
 		**out = **in
 	}
+	if in.ScaleDownStabilizationSeconds != nil {
+		in, out := &in.ScaleDownStabilizationSeconds, &out.ScaleDownStabilizationSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxScaleUpReplicasPerSync != nil {
+		in, out := &in.MaxScaleUpReplicasPerSync, &out.MaxScaleUpReplicasPerSync
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxScaleDownReplicasPerSync != nil {
+		in, out := &in.MaxScaleDownReplicasPerSync, &out.MaxScaleDownReplicasPerSync
+		*out = new(int)
+		**out = **in
+	}
+	if in.FallbackReplicas != nil {
+		in, out := &in.FallbackReplicas, &out.FallbackReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.CostBudget != nil {
+		in, out := &in.CostBudget, &out.CostBudget
+		*out = new(CostBudget)
+		**out = **in
+	}
+	if in.Federation != nil {
+		in, out := &in.Federation, &out.Federation
+		*out = new(Federation)
+		**out = **in
+	}
+	if in.MaxReplicasSaturation != nil {
+		in, out := &in.MaxReplicasSaturation, &out.MaxReplicasSaturation
+		*out = new(MaxReplicasSaturation)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Metrics != nil {
 		in, out := &in.Metrics, &out.Metrics
 		*out = make([]MetricSpec, len(*in))
@@ -239,6 +470,11 @@ func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAuto
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RepositoryBudgets != nil {
+		in, out := &in.RepositoryBudgets, &out.RepositoryBudgets
+		*out = make([]RepositoryBudget, len(*in))
+		copy(*out, *in)
+	}
 	if in.ScheduledOverrides != nil {
 		in, out := &in.ScheduledOverrides, &out.ScheduledOverrides
 		*out = make([]ScheduledOverride, len(*in))
@@ -275,6 +511,18 @@ func (in *HorizontalRunnerAutoscalerStatus) DeepCopyInto(out *HorizontalRunnerAu
 		in, out := &in.LastSuccessfulScaleOutTime, &out.LastSuccessfulScaleOutTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastEvaluationTime != nil {
+		in, out := &in.LastEvaluationTime, &out.LastEvaluationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextEvaluationTime != nil {
+		in, out := &in.NextEvaluationTime, &out.NextEvaluationTime
+		*out = (*in).DeepCopy()
+	}
 	if in.CacheEntries != nil {
 		in, out := &in.CacheEntries, &out.CacheEntries
 		*out = make([]CacheEntry, len(*in))
@@ -287,6 +535,45 @@ func (in *HorizontalRunnerAutoscalerStatus) DeepCopyInto(out *HorizontalRunnerAu
 		*out = new(string)
 		**out = **in
 	}
+	if in.DesiredReplicasHistory != nil {
+		in, out := &in.DesiredReplicasHistory, &out.DesiredReplicasHistory
+		*out = make([]DesiredReplicasRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RepositoryUsage != nil {
+		in, out := &in.RepositoryUsage, &out.RepositoryUsage
+		*out = make([]RepositoryUsage, len(*in))
+		copy(*out, *in)
+	}
+	if in.CostBudget != nil {
+		in, out := &in.CostBudget, &out.CostBudget
+		*out = new(CostBudgetStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(DryRunStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Federation != nil {
+		in, out := &in.Federation, &out.Federation
+		*out = new(FederationStatus)
+		**out = **in
+	}
+	if in.MaxReplicasSaturation != nil {
+		in, out := &in.MaxReplicasSaturation, &out.MaxReplicasSaturation
+		*out = new(MaxReplicasSaturationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerStatus.
@@ -299,6 +586,47 @@ func (in *HorizontalRunnerAutoscalerStatus) DeepCopy() *HorizontalRunnerAutoscal
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaxReplicasSaturation) DeepCopyInto(out *MaxReplicasSaturation) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaxReplicasSaturation.
+func (in *MaxReplicasSaturation) DeepCopy() *MaxReplicasSaturation {
+	if in == nil {
+		return nil
+	}
+	out := new(MaxReplicasSaturation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaxReplicasSaturationStatus) DeepCopyInto(out *MaxReplicasSaturationStatus) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+	if in.SuggestedMaxReplicas != nil {
+		in, out := &in.SuggestedMaxReplicas, &out.SuggestedMaxReplicas
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaxReplicasSaturationStatus.
+func (in *MaxReplicasSaturationStatus) DeepCopy() *MaxReplicasSaturationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaxReplicasSaturationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricSpec) DeepCopyInto(out *MetricSpec) {
 	*out = *in
@@ -307,6 +635,16 @@ func (in *MetricSpec) DeepCopyInto(out *MetricSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.SmoothingHalfLife != nil {
+		in, out := &in.SmoothingHalfLife, &out.SmoothingHalfLife
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.QueuedJobAgeThreshold != nil {
+		in, out := &in.QueuedJobAgeThreshold, &out.QueuedJobAgeThreshold
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricSpec.
@@ -319,6 +657,31 @@ func (in *MetricSpec) DeepCopy() *MetricSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkCheckSpec) DeepCopyInto(out *NetworkCheckSpec) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkCheckSpec.
+func (in *NetworkCheckSpec) DeepCopy() *NetworkCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PullRequestSpec) DeepCopyInto(out *PullRequestSpec) {
 	*out = *in
@@ -375,6 +738,36 @@ func (in *RecurrenceRule) DeepCopy() *RecurrenceRule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryBudget) DeepCopyInto(out *RepositoryBudget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryBudget.
+func (in *RepositoryBudget) DeepCopy() *RepositoryBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryUsage) DeepCopyInto(out *RepositoryUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryUsage.
+func (in *RepositoryUsage) DeepCopy() *RepositoryUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Runner) DeepCopyInto(out *Runner) {
 	*out = *in
@@ -410,11 +803,36 @@ func (in *RunnerConfig) DeepCopyInto(out *RunnerConfig) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.AllowedRepositories != nil {
+		in, out := &in.AllowedRepositories, &out.AllowedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Ephemeral != nil {
 		in, out := &in.Ephemeral, &out.Ephemeral
 		*out = new(bool)
 		**out = **in
 	}
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.SlotsPerPod != nil {
+		in, out := &in.SlotsPerPod, &out.SlotsPerPod
+		*out = new(int)
+		**out = **in
+	}
+	if in.JITConfig != nil {
+		in, out := &in.JITConfig, &out.JITConfig
+		*out = new(bool)
+		**out = **in
+	}
+	if in.WorkVolume != nil {
+		in, out := &in.WorkVolume, &out.WorkVolume
+		*out = new(WorkVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.DockerdWithinRunnerContainer != nil {
 		in, out := &in.DockerdWithinRunnerContainer, &out.DockerdWithinRunnerContainer
 		*out = new(bool)
@@ -450,6 +868,16 @@ func (in *RunnerConfig) DeepCopyInto(out *RunnerConfig) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.ShmSize != nil {
+		in, out := &in.ShmSize, &out.ShmSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.NetworkCheck != nil {
+		in, out := &in.NetworkCheck, &out.NetworkCheck
+		*out = new(NetworkCheckSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.GitHubAPICredentialsFrom != nil {
 		in, out := &in.GitHubAPICredentialsFrom, &out.GitHubAPICredentialsFrom
 		*out = new(GitHubAPICredentialsFrom)
@@ -538,9 +966,14 @@ func (in *RunnerDeploymentSpec) DeepCopyInto(out *RunnerDeploymentSpec) {
 		in, out := &in.EffectiveTime, &out.EffectiveTime
 		*out = (*in).DeepCopy()
 	}
+	if in.WarmStandby != nil {
+		in, out := &in.WarmStandby, &out.WarmStandby
+		*out = new(int)
+		**out = **in
+	}
 	if in.Selector != nil {
 		in, out := &in.Selector, &out.Selector
-		*out = new(metav1.LabelSelector)
+		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
 	in.Template.DeepCopyInto(&out.Template)
@@ -584,6 +1017,11 @@ func (in *RunnerDeploymentStatus) DeepCopyInto(out *RunnerDeploymentStatus) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.OverlappingRunnerPools != nil {
+		in, out := &in.OverlappingRunnerPools, &out.OverlappingRunnerPools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentStatus.
@@ -634,35 +1072,35 @@ func (in *RunnerPodSpec) DeepCopyInto(out *RunnerPodSpec) {
 	in.DockerdContainerResources.DeepCopyInto(&out.DockerdContainerResources)
 	if in.DockerVolumeMounts != nil {
 		in, out := &in.DockerVolumeMounts, &out.DockerVolumeMounts
-		*out = make([]v1.VolumeMount, len(*in))
+		*out = make([]corev1.VolumeMount, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.DockerEnv != nil {
 		in, out := &in.DockerEnv, &out.DockerEnv
-		*out = make([]v1.EnvVar, len(*in))
+		*out = make([]corev1.EnvVar, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.Containers != nil {
 		in, out := &in.Containers, &out.Containers
-		*out = make([]v1.Container, len(*in))
+		*out = make([]corev1.Container, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
-		*out = make([]v1.EnvVar, len(*in))
+		*out = make([]corev1.EnvVar, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.EnvFrom != nil {
 		in, out := &in.EnvFrom, &out.EnvFrom
-		*out = make([]v1.EnvFromSource, len(*in))
+		*out = make([]corev1.EnvFromSource, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -670,14 +1108,14 @@ func (in *RunnerPodSpec) DeepCopyInto(out *RunnerPodSpec) {
 	in.Resources.DeepCopyInto(&out.Resources)
 	if in.VolumeMounts != nil {
 		in, out := &in.VolumeMounts, &out.VolumeMounts
-		*out = make([]v1.VolumeMount, len(*in))
+		*out = make([]corev1.VolumeMount, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
-		*out = make([]v1.Volume, len(*in))
+		*out = make([]corev1.Volume, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -689,7 +1127,7 @@ func (in *RunnerPodSpec) DeepCopyInto(out *RunnerPodSpec) {
 	}
 	if in.InitContainers != nil {
 		in, out := &in.InitContainers, &out.InitContainers
-		*out = make([]v1.Container, len(*in))
+		*out = make([]corev1.Container, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -708,29 +1146,29 @@ func (in *RunnerPodSpec) DeepCopyInto(out *RunnerPodSpec) {
 	}
 	if in.SidecarContainers != nil {
 		in, out := &in.SidecarContainers, &out.SidecarContainers
-		*out = make([]v1.Container, len(*in))
+		*out = make([]corev1.Container, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.SecurityContext != nil {
 		in, out := &in.SecurityContext, &out.SecurityContext
-		*out = new(v1.PodSecurityContext)
+		*out = new(corev1.PodSecurityContext)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.ImagePullSecrets != nil {
 		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
-		*out = make([]v1.LocalObjectReference, len(*in))
+		*out = make([]corev1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
+		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]v1.Toleration, len(*in))
+		*out = make([]corev1.Toleration, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -742,21 +1180,21 @@ func (in *RunnerPodSpec) DeepCopyInto(out *RunnerPodSpec) {
 	}
 	if in.EphemeralContainers != nil {
 		in, out := &in.EphemeralContainers, &out.EphemeralContainers
-		*out = make([]v1.EphemeralContainer, len(*in))
+		*out = make([]corev1.EphemeralContainer, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.HostAliases != nil {
 		in, out := &in.HostAliases, &out.HostAliases
-		*out = make([]v1.HostAlias, len(*in))
+		*out = make([]corev1.HostAlias, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.TopologySpreadConstraints != nil {
 		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
-		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -768,7 +1206,7 @@ func (in *RunnerPodSpec) DeepCopyInto(out *RunnerPodSpec) {
 	}
 	if in.DnsConfig != nil {
 		in, out := &in.DnsConfig, &out.DnsConfig
-		*out = new(v1.PodDNSConfig)
+		*out = new(corev1.PodDNSConfig)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.WorkVolumeClaimTemplate != nil {
@@ -859,9 +1297,14 @@ func (in *RunnerReplicaSetSpec) DeepCopyInto(out *RunnerReplicaSetSpec) {
 		in, out := &in.EffectiveTime, &out.EffectiveTime
 		*out = (*in).DeepCopy()
 	}
+	if in.WarmStandby != nil {
+		in, out := &in.WarmStandby, &out.WarmStandby
+		*out = new(int)
+		**out = **in
+	}
 	if in.Selector != nil {
 		in, out := &in.Selector, &out.Selector
-		*out = new(metav1.LabelSelector)
+		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
 	in.Template.DeepCopyInto(&out.Template)
@@ -1020,6 +1463,11 @@ func (in *RunnerSetStatus) DeepCopyInto(out *RunnerSetStatus) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.OverlappingRunnerPools != nil {
+		in, out := &in.OverlappingRunnerPools, &out.OverlappingRunnerPools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerSetStatus.
@@ -1159,6 +1607,11 @@ func (in *ScheduledOverride) DeepCopyInto(out *ScheduledOverride) {
 		**out = **in
 	}
 	in.RecurrenceRule.DeepCopyInto(&out.RecurrenceRule)
+	if in.ExclusionCalendar != nil {
+		in, out := &in.ExclusionCalendar, &out.ExclusionCalendar
+		*out = new(ExclusionCalendar)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduledOverride.
@@ -1171,6 +1624,21 @@ func (in *ScheduledOverride) DeepCopy() *ScheduledOverride {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretReference) DeepCopyInto(out *SecretReference) {
 	*out = *in
@@ -1186,12 +1654,214 @@ func (in *SecretReference) DeepCopy() *SecretReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookAutoscalerConfig) DeepCopyInto(out *WebhookAutoscalerConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookAutoscalerConfig.
+func (in *WebhookAutoscalerConfig) DeepCopy() *WebhookAutoscalerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookAutoscalerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WebhookAutoscalerConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookAutoscalerConfigList) DeepCopyInto(out *WebhookAutoscalerConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]WebhookAutoscalerConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookAutoscalerConfigList.
+func (in *WebhookAutoscalerConfigList) DeepCopy() *WebhookAutoscalerConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookAutoscalerConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WebhookAutoscalerConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookAutoscalerConfigSpec) DeepCopyInto(out *WebhookAutoscalerConfigSpec) {
+	*out = *in
+	if in.SecretTokenSecretRef != nil {
+		in, out := &in.SecretTokenSecretRef, &out.SecretTokenSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.NextSecretTokenSecretRef != nil {
+		in, out := &in.NextSecretTokenSecretRef, &out.NextSecretTokenSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.GitHubAPICredentialsFrom != nil {
+		in, out := &in.GitHubAPICredentialsFrom, &out.GitHubAPICredentialsFrom
+		*out = new(GitHubAPICredentialsFrom)
+		**out = **in
+	}
+	if in.HorizontalRunnerAutoscalerSelector != nil {
+		in, out := &in.HorizontalRunnerAutoscalerSelector, &out.HorizontalRunnerAutoscalerSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultScaleUpTriggerDuration != nil {
+		in, out := &in.DefaultScaleUpTriggerDuration, &out.DefaultScaleUpTriggerDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Deduplication != nil {
+		in, out := &in.Deduplication, &out.Deduplication
+		*out = new(WebhookDeduplication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]WebhookRoute, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookAutoscalerConfigSpec.
+func (in *WebhookAutoscalerConfigSpec) DeepCopy() *WebhookAutoscalerConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookAutoscalerConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookAutoscalerConfigStatus) DeepCopyInto(out *WebhookAutoscalerConfigStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookAutoscalerConfigStatus.
+func (in *WebhookAutoscalerConfigStatus) DeepCopy() *WebhookAutoscalerConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookAutoscalerConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookDeduplication) DeepCopyInto(out *WebhookDeduplication) {
+	*out = *in
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookDeduplication.
+func (in *WebhookDeduplication) DeepCopy() *WebhookDeduplication {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookDeduplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookRoute) DeepCopyInto(out *WebhookRoute) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HorizontalRunnerAutoscalerSelector != nil {
+		in, out := &in.HorizontalRunnerAutoscalerSelector, &out.HorizontalRunnerAutoscalerSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookRoute.
+func (in *WebhookRoute) DeepCopy() *WebhookRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WeightedScaleTargetRef) DeepCopyInto(out *WeightedScaleTargetRef) {
+	*out = *in
+	out.ScaleTargetRef = in.ScaleTargetRef
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WeightedScaleTargetRef.
+func (in *WeightedScaleTargetRef) DeepCopy() *WeightedScaleTargetRef {
+	if in == nil {
+		return nil
+	}
+	out := new(WeightedScaleTargetRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkVolumeClaimTemplate) DeepCopyInto(out *WorkVolumeClaimTemplate) {
 	*out = *in
 	if in.AccessModes != nil {
 		in, out := &in.AccessModes, &out.AccessModes
-		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		*out = make([]corev1.PersistentVolumeAccessMode, len(*in))
 		copy(*out, *in)
 	}
 	in.Resources.DeepCopyInto(&out.Resources)
@@ -1207,9 +1877,49 @@ func (in *WorkVolumeClaimTemplate) DeepCopy() *WorkVolumeClaimTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkVolumeSource) DeepCopyInto(out *WorkVolumeSource) {
+	*out = *in
+	if in.EmptyDir != nil {
+		in, out := &in.EmptyDir, &out.EmptyDir
+		*out = new(corev1.EmptyDirVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ephemeral != nil {
+		in, out := &in.Ephemeral, &out.Ephemeral
+		*out = new(corev1.EphemeralVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostPath != nil {
+		in, out := &in.HostPath, &out.HostPath
+		*out = new(corev1.HostPathVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkVolumeSource.
+func (in *WorkVolumeSource) DeepCopy() *WorkVolumeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkVolumeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkflowJobSpec) DeepCopyInto(out *WorkflowJobSpec) {
 	*out = *in
+	if in.Workflows != nil {
+		in, out := &in.Workflows, &out.Workflows
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.WorkflowPaths != nil {
+		in, out := &in.WorkflowPaths, &out.WorkflowPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkflowJobSpec.
@@ -1222,6 +1932,31 @@ func (in *WorkflowJobSpec) DeepCopy() *WorkflowJobSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkflowRunSpec) DeepCopyInto(out *WorkflowRunSpec) {
+	*out = *in
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkflowRunSpec.
+func (in *WorkflowRunSpec) DeepCopy() *WorkflowRunSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkflowRunSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkflowStatus) DeepCopyInto(out *WorkflowStatus) {
 	*out = *in
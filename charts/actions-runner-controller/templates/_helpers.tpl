@@ -108,6 +108,10 @@ Create the name of the service account to use
 {{- include "actions-runner-controller.fullname" . }}-selfsigned-issuer
 {{- end }}
 
+{{- define "actions-runner-controller.externalMetricsServiceName" -}}
+{{- include "actions-runner-controller.fullname" . | trunc 46 }}-external-metrics
+{{- end }}
+
 {{- define "actions-runner-controller.servingCertName" -}}
 {{- include "actions-runner-controller.fullname" . }}-serving-cert
 {{- end }}
@@ -115,3 +119,45 @@ Create the name of the service account to use
 {{- define "actions-runner-controller.pdbName" -}}
 {{- include "actions-runner-controller.fullname" . | trunc 59 }}-pdb
 {{- end }}
+
+{{- define "actions-runner-controller.githubTLSEnv" -}}
+{{- with .Values.githubTLS }}
+{{- if .caBundleSecretName }}
+- name: GITHUB_TLS_CA_CERT
+  valueFrom:
+    secretKeyRef:
+      key: ca.crt
+      name: {{ .caBundleSecretName }}
+{{- end }}
+{{- if .clientCertSecretName }}
+- name: GITHUB_TLS_CLIENT_CERT
+  valueFrom:
+    secretKeyRef:
+      key: tls.crt
+      name: {{ .clientCertSecretName }}
+- name: GITHUB_TLS_CLIENT_KEY
+  valueFrom:
+    secretKeyRef:
+      key: tls.key
+      name: {{ .clientCertSecretName }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+{{- define "actions-runner-controller.githubAPIProxyEnv" -}}
+{{- if .Values.githubAPIProxyURL }}
+{{- if .Values.githubTLS }}
+{{- fail "githubTLS can't be used with githubAPIProxyURL, as the proxy doesn't apply it to its requests to GitHub" }}
+{{- end }}
+{{- if not .Values.githubAPIProxyCASecretName }}
+{{- fail "githubAPIProxyCASecretName is required with githubAPIProxyURL, as the certificate of the proxy is pinned to its CA" }}
+{{- end }}
+- name: GITHUB_API_PROXY_URL
+  value: {{ .Values.githubAPIProxyURL }}
+- name: GITHUB_API_PROXY_CA_CERT
+  valueFrom:
+    secretKeyRef:
+      key: ca.crt
+      name: {{ .Values.githubAPIProxyCASecretName }}
+{{- end }}
+{{- end }}
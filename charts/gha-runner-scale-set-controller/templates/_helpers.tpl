@@ -126,3 +126,29 @@ Create the name of the service account to use
 {{- end }}
 {{- $names | join ","}}
 {{- end }}
+
+{{- define "gha-runner-scale-set-controller.githubAPIProxyName" -}}
+{{- include "gha-runner-scale-set-controller.fullname" . }}-github-api-proxy
+{{- end }}
+
+{{- define "gha-runner-scale-set-controller.githubAPIProxySelectorLabels" -}}
+app.kubernetes.io/name: {{ include "gha-runner-scale-set-controller.name" . }}-github-api-proxy
+app.kubernetes.io/namespace: {{ .Release.Namespace }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end }}
+
+{{- define "gha-runner-scale-set-controller.githubAPIProxyCertSecretName" -}}
+{{- default (printf "%s-cert" (include "gha-runner-scale-set-controller.githubAPIProxyName" .)) .Values.githubAPIProxy.certificateSecretName }}
+{{- end }}
+
+{{- define "gha-runner-scale-set-controller.githubAPIProxyURL" -}}
+https://{{ include "gha-runner-scale-set-controller.githubAPIProxyName" . }}.{{ .Release.Namespace }}.svc:{{ default 8443 .Values.githubAPIProxy.port }}
+{{- end }}
+
+{{- define "gha-runner-scale-set-controller.requestCoordinatorServiceName" -}}
+{{- include "gha-runner-scale-set-controller.fullname" . }}-request-coordinator
+{{- end }}
+
+{{- define "gha-runner-scale-set-controller.requestCoordinatorURL" -}}
+http://{{ include "gha-runner-scale-set-controller.requestCoordinatorServiceName" . }}.{{ .Release.Namespace }}.svc:{{ default 8084 .Values.flags.actionsRequestCoordinatorPort }}
+{{- end }}
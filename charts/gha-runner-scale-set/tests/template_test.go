@@ -1624,7 +1624,8 @@ func TestTemplate_CreateManagerRole(t *testing.T) {
 	assert.Equal(t, namespaceName, managerRole.Namespace, "namespace should match the namespace of the Helm release")
 	assert.Equal(t, "test-runners-gha-rs-manager", managerRole.Name)
 	assert.Equal(t, "actions.github.com/cleanup-protection", managerRole.Finalizers[0])
-	assert.Equal(t, 6, len(managerRole.Rules))
+	assert.Equal(t, 7, len(managerRole.Rules))
+	assert.Equal(t, "deployments", managerRole.Rules[6].Resources[0])
 
 	var ars v1alpha1.AutoscalingRunnerSet
 	helm.UnmarshalK8SYaml(t, output, &ars)
@@ -1660,8 +1661,8 @@ func TestTemplate_CreateManagerRole_UseConfigMaps(t *testing.T) {
 	assert.Equal(t, namespaceName, managerRole.Namespace, "namespace should match the namespace of the Helm release")
 	assert.Equal(t, "test-runners-gha-rs-manager", managerRole.Name)
 	assert.Equal(t, "actions.github.com/cleanup-protection", managerRole.Finalizers[0])
-	assert.Equal(t, 7, len(managerRole.Rules))
-	assert.Equal(t, "configmaps", managerRole.Rules[6].Resources[0])
+	assert.Equal(t, 8, len(managerRole.Rules))
+	assert.Equal(t, "configmaps", managerRole.Rules[7].Resources[0])
 }
 
 func TestTemplate_CreateManagerRoleBinding(t *testing.T) {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/config"
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/listener"
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/metrics"
@@ -34,6 +35,8 @@ type Listener interface {
 //go:generate mockery --name Worker --output ./mocks --outpkg mocks --case underscore
 type Worker interface {
 	HandleJobStarted(ctx context.Context, jobInfo *actions.JobStarted) error
+	HandleJobAssigned(ctx context.Context, jobInfo *actions.JobAssigned) error
+	HandleJobCompleted(ctx context.Context, jobInfo *actions.JobCompleted) error
 	HandleDesiredRunnerCount(ctx context.Context, count int, jobsCompleted int) (int, error)
 }
 
@@ -67,6 +70,7 @@ func New(config config.Config) (*App, error) {
 			Enterprise:        ghConfig.Enterprise,
 			Organization:      ghConfig.Organization,
 			Repository:        ghConfig.Repository,
+			RunnerGroup:       config.RunnerGroup,
 			ServerAddr:        config.MetricsAddr,
 			ServerEndpoint:    config.MetricsEndpoint,
 		})
@@ -78,6 +82,7 @@ func New(config config.Config) (*App, error) {
 			EphemeralRunnerSetName:      config.EphemeralRunnerSetName,
 			MaxRunners:                  config.MaxRunners,
 			MinRunners:                  config.MinRunners,
+			SessionSecretName:           config.SessionSecretName,
 		},
 		worker.WithLogger(app.logger.WithName("worker")),
 	)
@@ -86,6 +91,11 @@ func New(config config.Config) (*App, error) {
 	}
 	app.worker = worker
 
+	var handoff listener.SessionHandoff
+	if config.SessionSecretName != "" {
+		handoff = worker
+	}
+
 	listener, err := listener.New(listener.Config{
 		Client:     actionsClient,
 		ScaleSetID: app.config.RunnerScaleSetId,
@@ -93,6 +103,14 @@ func New(config config.Config) (*App, error) {
 		MaxRunners: app.config.MaxRunners,
 		Logger:     app.logger.WithName("listener"),
 		Metrics:    app.metrics,
+
+		AllowedRepositories:         app.config.AllowedRepositories,
+		AdmissionWindows:            app.config.AdmissionWindows,
+		HoldOutsideAdmissionWindows: app.config.OutsideAdmissionWindows == v1alpha1.OutsideAdmissionWindowsHold,
+		ShardIndex:                  app.config.ShardIndex,
+		ShardCount:                  app.config.ShardCount,
+		Sessions:                    worker,
+		Handoff:                     handoff,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new listener: %w", err)
@@ -1,16 +1,21 @@
 package config
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/build"
 	"github.com/actions/actions-runner-controller/github/actions"
 	"github.com/actions/actions-runner-controller/logging"
+	"github.com/actions/actions-runner-controller/pkg/githubapiproxy"
 	"github.com/go-logr/logr"
 	"golang.org/x/net/http/httpproxy"
 )
@@ -27,11 +32,37 @@ type Config struct {
 	MinRunners                  int    `json:"minRunners"`
 	RunnerScaleSetId            int    `json:"runnerScaleSetId"`
 	RunnerScaleSetName          string `json:"runnerScaleSetName"`
+	RunnerGroup                 string `json:"runnerGroup,omitempty"`
 	ServerRootCA                string `json:"serverRootCA"`
-	LogLevel                    string `json:"logLevel"`
-	LogFormat                   string `json:"logFormat"`
-	MetricsAddr                 string `json:"metricsAddr"`
-	MetricsEndpoint             string `json:"metricsEndpoint"`
+	// ServerClientCertificate and ServerClientKey are the PEM client certificate presented to GitHub and its private key, if any.
+	ServerClientCertificate string `json:"serverClientCertificate,omitempty"`
+	ServerClientKey         string `json:"serverClientKey,omitempty"`
+	LogLevel                string `json:"logLevel"`
+	LogFormat               string `json:"logFormat"`
+	MetricsAddr             string `json:"metricsAddr"`
+	MetricsEndpoint         string `json:"metricsEndpoint"`
+	// AdminTokenPath is the path of the Actions service admin token shared by the controller.
+	// The listener mints its own token when it's missing or about to expire.
+	AdminTokenPath string `json:"adminTokenPath,omitempty"`
+	// GitHubAPIProxyURL is the https URL of the GitHub API proxy shared with the controller, if any.
+	GitHubAPIProxyURL string `json:"gitHubAPIProxyURL,omitempty"`
+	// GitHubAPIProxyCACert is the PEM bundle of the CAs the certificate of the GitHub API proxy is pinned to.
+	GitHubAPIProxyCACert string `json:"gitHubAPIProxyCACert,omitempty"`
+	// RequestCoordinatorURL is the URL of the request coordinator of the controller the listener paces its requests with, if any.
+	RequestCoordinatorURL string `json:"requestCoordinatorURL,omitempty"`
+	// AllowedRepositories are the full names, owner/name, of the repositories whose jobs the listener acquires. Defaults to all the repositories.
+	AllowedRepositories []string `json:"allowedRepositories,omitempty"`
+	// AdmissionWindows are the recurring periods during which the listener acquires jobs. Defaults to any time.
+	AdmissionWindows []v1alpha1.AdmissionWindow `json:"admissionWindows,omitempty"`
+	// OutsideAdmissionWindows is what the listener does with the jobs available outside of AdmissionWindows, Leave or Hold.
+	OutsideAdmissionWindows string `json:"outsideAdmissionWindows,omitempty"`
+	// ShardIndex is the partition of the jobs acquired by the listener, out of ShardCount.
+	// The listener of the first shard owns the message session. Defaults to a single shard.
+	ShardIndex int `json:"shardIndex,omitempty"`
+	ShardCount int `json:"shardCount,omitempty"`
+	// SessionSecretName is the secret, in the namespace of the ephemeral runner set, the message session is persisted in,
+	// for the replacement listener to resume it on restarts. The session is deleted on exit when empty.
+	SessionSecretName string `json:"sessionSecretName,omitempty"`
 }
 
 func Read(path string) (Config, error) {
@@ -82,6 +113,45 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("only one GitHub auth method supported at a time. Have both PAT and App auth: token length: '%d', appId: '%d', installationId: '%d', private key length: '%d", len(c.Token), c.AppID, c.AppInstallationID, len(c.AppPrivateKey))
 	}
 
+	if c.GitHubAPIProxyURL != "" {
+		proxyURL, err := url.Parse(c.GitHubAPIProxyURL)
+		if err != nil {
+			return fmt.Errorf("GitHubAPIProxyURL '%s' is invalid: %w", c.GitHubAPIProxyURL, err)
+		}
+		if err := githubapiproxy.ValidateURL(proxyURL); err != nil {
+			return fmt.Errorf("GitHubAPIProxyURL is invalid: %w", err)
+		}
+		if _, err := githubapiproxy.CertPool([]byte(c.GitHubAPIProxyCACert)); err != nil {
+			return fmt.Errorf("GitHubAPIProxyCACert is invalid: %w", err)
+		}
+	}
+
+	if c.RequestCoordinatorURL != "" {
+		if _, err := url.Parse(c.RequestCoordinatorURL); err != nil {
+			return fmt.Errorf("RequestCoordinatorURL '%s' is invalid: %w", c.RequestCoordinatorURL, err)
+		}
+	}
+
+	if _, _, err := v1alpha1.AdmissionWindowsOpen(c.AdmissionWindows, time.Now()); err != nil {
+		return fmt.Errorf("AdmissionWindows are invalid: %w", err)
+	}
+
+	switch c.OutsideAdmissionWindows {
+	case "", v1alpha1.OutsideAdmissionWindowsLeave, v1alpha1.OutsideAdmissionWindowsHold:
+	default:
+		return fmt.Errorf("OutsideAdmissionWindows '%s' is invalid: it must be %s or %s", c.OutsideAdmissionWindows, v1alpha1.OutsideAdmissionWindowsLeave, v1alpha1.OutsideAdmissionWindowsHold)
+	}
+
+	for _, r := range c.AllowedRepositories {
+		if owner, name, ok := strings.Cut(r, "/"); !ok || owner == "" || name == "" {
+			return fmt.Errorf("AllowedRepositories '%s' is invalid: it must be the full name of a repository, owner/name", r)
+		}
+	}
+
+	if c.ShardCount < 0 || c.ShardIndex < 0 || c.ShardIndex >= max(c.ShardCount, 1) {
+		return fmt.Errorf("ShardIndex '%d' is invalid: it must be between 0 and ShardCount '%d'", c.ShardIndex, c.ShardCount)
+	}
+
 	return nil
 }
 
@@ -121,6 +191,10 @@ func (c *Config) ActionsClient(logger logr.Logger, clientOptions ...actions.Clie
 		actions.WithLogger(logger),
 	}, clientOptions...)
 
+	if c.AdminTokenPath != "" {
+		options = append(options, actions.WithAdminTokenSource(actions.AdminTokenFromFile(c.AdminTokenPath)))
+	}
+
 	if c.ServerRootCA != "" {
 		systemPool, err := x509.SystemCertPool()
 		if err != nil {
@@ -135,6 +209,42 @@ func (c *Config) ActionsClient(logger logr.Logger, clientOptions ...actions.Clie
 		options = append(options, actions.WithRootCAs(pool))
 	}
 
+	if c.ServerClientCertificate != "" {
+		cert, err := tls.X509KeyPair([]byte(c.ServerClientCertificate), []byte(c.ServerClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+
+		options = append(options, actions.WithClientCertificate(cert))
+	}
+
+	if c.GitHubAPIProxyURL != "" {
+		proxyURL, err := url.Parse(c.GitHubAPIProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GitHub API proxy URL: %w", err)
+		}
+		caCerts, err := githubapiproxy.CertPool([]byte(c.GitHubAPIProxyCACert))
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, actions.WithGitHubAPIProxy(proxyURL, caCerts))
+	}
+
+	if c.RequestCoordinatorURL != "" {
+		// The controller identifies the scale set by the namespace and name of its AutoscalingRunnerSet
+		coordinator, err := actions.NewRemoteRequestCoordinator(
+			c.RequestCoordinatorURL,
+			c.EphemeralRunnerSetNamespace+"/"+c.RunnerScaleSetName,
+			logger.WithName("request-coordinator"),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse request coordinator URL: %w", err)
+		}
+
+		options = append(options, actions.WithRequestCoordinator(coordinator))
+	}
+
 	proxyFunc := httpproxy.FromEnvironment().ProxyFunc()
 	options = append(options, actions.WithProxy(func(req *http.Request) (*url.URL, error) {
 		return proxyFunc(req.URL)
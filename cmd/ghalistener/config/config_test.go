@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -90,3 +91,47 @@ func TestConfigValidationConfigUrl(t *testing.T) {
 
 	assert.ErrorContains(t, err, "GitHubConfigUrl is not provided", "Expected error about missing ConfigureUrl")
 }
+
+func TestConfigValidationAdmissionWindows(t *testing.T) {
+	config := &Config{
+		ConfigureUrl:                "github.com/some_org/some_repo",
+		EphemeralRunnerSetNamespace: "namespace",
+		EphemeralRunnerSetName:      "deployment",
+		RunnerScaleSetId:            1,
+		Token:                       "token",
+		AdmissionWindows:            []v1alpha1.AdmissionWindow{{DailyWindow: v1alpha1.DailyWindow{Start: "09:00", End: "17:00", TimeZone: "Nowhere/Nowhere"}}},
+	}
+	err := config.Validate()
+	assert.ErrorContains(t, err, "AdmissionWindows are invalid: invalid admission window 0: invalid time zone")
+
+	config.AdmissionWindows[0].TimeZone = "Europe/Berlin"
+	config.OutsideAdmissionWindows = "Queue"
+	err = config.Validate()
+	assert.ErrorContains(t, err, "OutsideAdmissionWindows 'Queue' is invalid: it must be Leave or Hold")
+
+	config.OutsideAdmissionWindows = v1alpha1.OutsideAdmissionWindowsHold
+	assert.NoError(t, config.Validate())
+}
+
+func TestConfigValidationShards(t *testing.T) {
+	config := &Config{
+		ConfigureUrl:                "github.com/some_org/some_repo",
+		EphemeralRunnerSetNamespace: "namespace",
+		EphemeralRunnerSetName:      "deployment",
+		RunnerScaleSetId:            1,
+		Token:                       "asdf",
+		ShardIndex:                  3,
+		ShardCount:                  3,
+	}
+
+	err := config.Validate()
+	assert.ErrorContains(t, err, "ShardIndex '3' is invalid: it must be between 0 and ShardCount '3'")
+
+	config.ShardIndex = 1
+	config.ShardCount = 0
+	err = config.Validate()
+	assert.ErrorContains(t, err, "ShardIndex '1' is invalid")
+
+	config.ShardCount = 3
+	assert.NoError(t, config.Validate())
+}
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/metrics"
 	"github.com/actions/actions-runner-controller/github/actions"
 	"github.com/go-logr/logr"
@@ -17,6 +19,12 @@ import (
 
 const (
 	sessionCreationMaxRetries = 10
+
+	// shardPollInterval is how often the listeners of the shards other than the first one poll the acquirable jobs.
+	shardPollInterval = 5 * time.Second
+	// shardTakeoverDelay is how long a job of another shard stays acquirable before the listener of the first shard acquires it,
+	// so that the jobs of a shard whose listener is down are still acquired.
+	shardTakeoverDelay = time.Minute
 )
 
 // message types
@@ -38,6 +46,30 @@ type Client interface {
 	DeleteMessageSession(ctx context.Context, runnerScaleSetId int, sessionId *uuid.UUID) error
 }
 
+// SessionStore shares the ID of the message session of the scale set between the shards of the listener.
+//
+//go:generate mockery --name SessionStore --output ./mocks --outpkg mocks --case underscore
+type SessionStore interface {
+	PublishSessionID(ctx context.Context, sessionID uuid.UUID) error
+	// SessionID returns the ID of the shared message session, or nil when none is shared yet.
+	SessionID(ctx context.Context) (*uuid.UUID, error)
+}
+
+// SessionHandoff persists the message session of the listener, for the listener replacing it on restarts to resume the session
+// instead of creating another one, which would drop the messages not received yet and pause the scaling.
+type SessionHandoff interface {
+	SaveSession(ctx context.Context, state *SessionState) error
+	// LoadSession returns the persisted message session, or nil when none is persisted.
+	LoadSession(ctx context.Context) (*SessionState, error)
+}
+
+// SessionState is the message session handed off between the listeners of the scale set.
+type SessionState struct {
+	ScaleSetID    int
+	SessionID     uuid.UUID
+	LastMessageID int64
+}
+
 type Config struct {
 	Client     Client
 	ScaleSetID int
@@ -45,6 +77,26 @@ type Config struct {
 	MaxRunners int
 	Logger     logr.Logger
 	Metrics    metrics.Publisher
+	// AllowedRepositories are the full names, owner/name, of the repositories whose jobs are acquired.
+	// The jobs of the other repositories are left unacquired. Defaults to all the repositories.
+	AllowedRepositories []string
+	// AdmissionWindows are the recurring periods during which jobs are acquired.
+	// The jobs available outside of them are left unacquired. Jobs are acquired at any time when empty.
+	AdmissionWindows []v1alpha1.AdmissionWindow
+	// HoldOutsideAdmissionWindows acquires the jobs left unacquired outside of AdmissionWindows
+	// that are still available once the next window opens.
+	HoldOutsideAdmissionWindows bool
+	// ShardIndex and ShardCount partition the job acquisition between the listeners of the scale set by request ID.
+	// The listener of the first shard creates the message session, shares it through Sessions and scales the runners.
+	// The listeners of the other shards poll the acquirable jobs of their partition with the shared session.
+	// Defaults to a single shard.
+	ShardIndex int
+	ShardCount int
+	// Sessions shares the message session between the shards. Required with more than one shard.
+	Sessions SessionStore
+	// Handoff, when set, persists the message session, which is left open on exit for the replacement listener to resume.
+	// The session is deleted on exit otherwise.
+	Handoff SessionHandoff
 }
 
 func (c *Config) Validate() error {
@@ -63,6 +115,15 @@ func (c *Config) Validate() error {
 	if c.MaxRunners > 0 && c.MinRunners > c.MaxRunners {
 		return errors.New("minRunners must be less than or equal to maxRunners")
 	}
+	if _, _, err := v1alpha1.AdmissionWindowsOpen(c.AdmissionWindows, time.Now()); err != nil {
+		return fmt.Errorf("admissionWindows are invalid: %w", err)
+	}
+	if c.ShardCount < 0 || c.ShardIndex < 0 || c.ShardIndex >= max(c.ShardCount, 1) {
+		return errors.New("shardIndex must be greater than or equal to 0 and less than shardCount")
+	}
+	if c.ShardCount > 1 && c.Sessions == nil {
+		return errors.New("sessions is required with more than one shard")
+	}
 	return nil
 }
 
@@ -74,14 +135,26 @@ type Listener struct {
 	client     Client            // The client used to interact with the scale set.
 	metrics    metrics.Publisher // The publisher used to publish metrics.
 
+	allowedRepositories map[string]bool            // The lowercased full names of the repositories whose jobs are acquired, or nil for all.
+	admissionWindows    []v1alpha1.AdmissionWindow // The periods during which jobs are acquired, or nil for any time.
+	holdJobs            bool                       // Whether the jobs left outside of the admission windows are acquired once a window opens.
+	shardIndex          int                        // The partition of the jobs acquired by the listener.
+	shardCount          int                        // The number of partitions of the jobs, or 0 for a single shard.
+	sessions            SessionStore               // The store sharing the message session between the shards.
+	handoff             SessionHandoff             // The store handing the message session off to the replacement listener, or nil.
+
 	// internal fields
-	logger   logr.Logger // The logger used for logging.
-	hostname string      // The hostname of the listener.
+	logger   logr.Logger      // The logger used for logging.
+	hostname string           // The hostname of the listener.
+	now      func() time.Time // The clock the admission windows are evaluated with.
 
 	// updated fields
 	lastMessageID int64                          // The ID of the last processed message.
 	maxCapacity   int                            // The maximum number of runners that can be created.
 	session       *actions.RunnerScaleSetSession // The session for managing the runner scale set.
+	jobsHeld      bool                           // Whether jobs were held outside of the admission windows.
+	lastTakeover  time.Time                      // When the jobs of the other shards were last checked for a takeover.
+	jobsFirstSeen map[int64]time.Time            // When the acquirable jobs of the other shards were first seen.
 }
 
 func New(config Config) (*Listener, error) {
@@ -95,12 +168,27 @@ func New(config Config) (*Listener, error) {
 		logger:      config.Logger,
 		metrics:     metrics.Discard,
 		maxCapacity: config.MaxRunners,
+		now:         time.Now,
+
+		admissionWindows: config.AdmissionWindows,
+		holdJobs:         config.HoldOutsideAdmissionWindows,
+		shardIndex:       config.ShardIndex,
+		shardCount:       config.ShardCount,
+		sessions:         config.Sessions,
+		handoff:          config.Handoff,
 	}
 
 	if config.Metrics != nil {
 		listener.metrics = config.Metrics
 	}
 
+	if len(config.AllowedRepositories) > 0 {
+		listener.allowedRepositories = make(map[string]bool, len(config.AllowedRepositories))
+		for _, r := range config.AllowedRepositories {
+			listener.allowedRepositories[strings.ToLower(r)] = true
+		}
+	}
+
 	listener.metrics.PublishStatic(config.MinRunners, config.MaxRunners)
 
 	hostname, err := os.Hostname()
@@ -115,7 +203,9 @@ func New(config Config) (*Listener, error) {
 
 //go:generate mockery --name Handler --output ./mocks --outpkg mocks --case underscore
 type Handler interface {
+	HandleJobAssigned(ctx context.Context, jobInfo *actions.JobAssigned) error
 	HandleJobStarted(ctx context.Context, jobInfo *actions.JobStarted) error
+	HandleJobCompleted(ctx context.Context, jobInfo *actions.JobCompleted) error
 	HandleDesiredRunnerCount(ctx context.Context, count, jobsCompleted int) (int, error)
 }
 
@@ -124,17 +214,40 @@ type Handler interface {
 // The initial message contains the current statistics and acquirable jobs, if any.
 // The handler is responsible for handling the initial message and subsequent messages.
 // If an error occurs during any step, Listen returns an error.
+// The listeners of the shards other than the first one only acquire the jobs of their partition, leaving the handler unused.
 func (l *Listener) Listen(ctx context.Context, handler Handler) error {
-	if err := l.createSession(ctx); err != nil {
-		return fmt.Errorf("createSession failed: %w", err)
+	if l.shardIndex > 0 {
+		return l.listenShard(ctx)
+	}
+
+	if !l.resumeSession(ctx) {
+		if err := l.createSession(ctx); err != nil {
+			return fmt.Errorf("createSession failed: %w", err)
+		}
+		l.saveSession(ctx)
 	}
 
 	defer func() {
+		if l.handoff != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			l.saveSession(ctx)
+			l.logger.Info("Leaving the message session open for the replacement listener", "sessionId", l.session.SessionId.String())
+			return
+		}
+
 		if err := l.deleteMessageSession(); err != nil {
 			l.logger.Error(err, "failed to delete message session")
 		}
 	}()
 
+	if l.shardCount > 1 {
+		if err := l.sessions.PublishSessionID(ctx, *l.session.SessionId); err != nil {
+			return fmt.Errorf("failed to share message session with the shards: %w", err)
+		}
+	}
+
 	initialMessage := &actions.RunnerScaleSetMessage{
 		MessageId:   0,
 		MessageType: "RunnerScaleSetJobMessages",
@@ -160,6 +273,14 @@ func (l *Listener) Listen(ctx context.Context, handler Handler) error {
 		default:
 		}
 
+		if err := l.acquireHeldJobs(ctx); err != nil {
+			return fmt.Errorf("failed to acquire held jobs: %w", err)
+		}
+
+		if err := l.acquireOrphanedJobs(ctx); err != nil {
+			return fmt.Errorf("failed to acquire the jobs of the other shards: %w", err)
+		}
+
 		msg, err := l.getMessage(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to get message: %w", err)
@@ -188,8 +309,8 @@ func (l *Listener) handleMessage(ctx context.Context, handler Handler, msg *acti
 	}
 	l.metrics.PublishStatistics(parsedMsg.statistics)
 
-	if len(parsedMsg.jobsAvailable) > 0 {
-		acquiredJobIDs, err := l.acquireAvailableJobs(ctx, parsedMsg.jobsAvailable)
+	if jobsAvailable := l.ownedJobs(parsedMsg.jobsAvailable); len(jobsAvailable) > 0 {
+		acquiredJobIDs, err := l.acquireAvailableJobs(ctx, jobsAvailable)
 		if err != nil {
 			return fmt.Errorf("failed to acquire jobs: %w", err)
 		}
@@ -206,6 +327,19 @@ func (l *Listener) handleMessage(ctx context.Context, handler Handler, msg *acti
 	if err := l.deleteLastMessage(ctx); err != nil {
 		return fmt.Errorf("failed to delete message: %w", err)
 	}
+	l.saveSession(ctx)
+
+	for _, jobAssigned := range parsedMsg.jobsAssigned {
+		if err := handler.HandleJobAssigned(ctx, jobAssigned); err != nil {
+			return fmt.Errorf("failed to handle job assigned: %w", err)
+		}
+	}
+
+	for _, jobCompleted := range parsedMsg.jobsCompleted {
+		if err := handler.HandleJobCompleted(ctx, jobCompleted); err != nil {
+			return fmt.Errorf("failed to handle job completed: %w", err)
+		}
+	}
 
 	for _, jobStarted := range parsedMsg.jobsStarted {
 		if err := handler.HandleJobStarted(ctx, jobStarted); err != nil {
@@ -267,9 +401,60 @@ func (l *Listener) createSession(ctx context.Context) error {
 	return nil
 }
 
+// resumeSession resumes the message session handed off by the previous listener of the scale set, and returns whether it did.
+// A handed off session that can't be resumed is deleted, so that it doesn't conflict with the one created instead.
+func (l *Listener) resumeSession(ctx context.Context) bool {
+	if l.handoff == nil {
+		return false
+	}
+
+	state, err := l.handoff.LoadSession(ctx)
+	if err != nil {
+		l.logger.Error(err, "Failed to load the handed off message session, creating a new one")
+		return false
+	}
+	if state == nil || state.ScaleSetID != l.scaleSetID {
+		return false
+	}
+
+	session, err := l.client.RefreshMessageSession(ctx, l.scaleSetID, &state.SessionID)
+	if err != nil || session.Statistics == nil {
+		l.logger.Info("Unable to resume the handed off message session, creating a new one", "sessionId", state.SessionID.String(), "error", fmt.Sprint(err))
+		if err := l.client.DeleteMessageSession(ctx, l.scaleSetID, &state.SessionID); err != nil {
+			l.logger.Info("Unable to delete the handed off message session", "sessionId", state.SessionID.String(), "error", err.Error())
+		}
+		return false
+	}
+	l.metrics.PublishSessionRefresh()
+
+	l.logger.Info("Resumed the message session handed off by the previous listener", "sessionId", state.SessionID.String(), "lastMessageId", state.LastMessageID)
+	l.session = session
+	l.lastMessageID = state.LastMessageID
+	return true
+}
+
+// saveSession persists the message session and the last message processed with it, for the replacement listener to resume.
+// Failures are only logged, as they only cost the replacement listener a new session.
+func (l *Listener) saveSession(ctx context.Context) {
+	if l.handoff == nil {
+		return
+	}
+
+	state := &SessionState{
+		ScaleSetID:    l.scaleSetID,
+		SessionID:     *l.session.SessionId,
+		LastMessageID: l.lastMessageID,
+	}
+	if err := l.handoff.SaveSession(ctx, state); err != nil {
+		l.logger.Error(err, "Failed to persist the message session for its handoff")
+	}
+}
+
 func (l *Listener) getMessage(ctx context.Context) (*actions.RunnerScaleSetMessage, error) {
 	l.logger.Info("Getting next message", "lastMessageID", l.lastMessageID)
+	start := time.Now()
 	msg, err := l.client.GetMessage(ctx, l.session.MessageQueueUrl, l.session.MessageQueueAccessToken, l.lastMessageID, l.maxCapacity)
+	l.metrics.PublishMessagePollDuration(time.Since(start))
 	if err == nil { // if NO error
 		return msg, nil
 	}
@@ -285,7 +470,9 @@ func (l *Listener) getMessage(ctx context.Context) (*actions.RunnerScaleSetMessa
 
 	l.logger.Info("Getting next message", "lastMessageID", l.lastMessageID)
 
+	start = time.Now()
 	msg, err = l.client.GetMessage(ctx, l.session.MessageQueueUrl, l.session.MessageQueueAccessToken, l.lastMessageID, l.maxCapacity)
+	l.metrics.PublishMessagePollDuration(time.Since(start))
 	if err != nil { // if NO error
 		return nil, fmt.Errorf("failed to get next message after message session refresh: %w", err)
 	}
@@ -321,6 +508,7 @@ type parsedMessage struct {
 	statistics    *actions.RunnerScaleSetStatistic
 	jobsStarted   []*actions.JobStarted
 	jobsAvailable []*actions.JobAvailable
+	jobsAssigned  []*actions.JobAssigned
 	jobsCompleted []*actions.JobCompleted
 }
 
@@ -371,6 +559,7 @@ func (l *Listener) parseMessage(ctx context.Context, msg *actions.RunnerScaleSet
 			}
 
 			l.logger.Info("Job assigned message received", "jobId", jobAssigned.RunnerRequestId)
+			parsedMsg.jobsAssigned = append(parsedMsg.jobsAssigned, &jobAssigned)
 
 		case messageTypeJobStarted:
 			var jobStarted actions.JobStarted
@@ -398,11 +587,43 @@ func (l *Listener) parseMessage(ctx context.Context, msg *actions.RunnerScaleSet
 }
 
 func (l *Listener) acquireAvailableJobs(ctx context.Context, jobsAvailable []*actions.JobAvailable) ([]int64, error) {
+	open, next, err := v1alpha1.AdmissionWindowsOpen(l.admissionWindows, l.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate admission windows: %w", err)
+	}
+	if !open {
+		ids := make([]int64, 0, len(jobsAvailable))
+		for _, job := range jobsAvailable {
+			ids = append(ids, job.RunnerRequestId)
+		}
+
+		if l.holdJobs {
+			l.logger.Info("Holding jobs until the next admission window opens", "count", len(ids), "requestIds", fmt.Sprint(ids), "nextWindow", next)
+			l.jobsHeld = true
+		} else {
+			l.logger.Info("Leaving jobs available outside of the admission windows to other scale sets", "count", len(ids), "requestIds", fmt.Sprint(ids), "nextWindow", next)
+		}
+		return nil, nil
+	}
+
 	ids := make([]int64, 0, len(jobsAvailable))
 	for _, job := range jobsAvailable {
+		// The same name can be the one of repositories of other organizations, in the scale sets of enterprises
+		if l.allowedRepositories != nil && !l.allowedRepositories[strings.ToLower(job.OwnerName+"/"+job.RepositoryName)] {
+			l.logger.Info(
+				"Refusing to acquire job of a repository that isn't allowed",
+				"requestId", job.RunnerRequestId,
+				"repository", job.OwnerName+"/"+job.RepositoryName,
+			)
+			continue
+		}
 		ids = append(ids, job.RunnerRequestId)
 	}
 
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
 	l.logger.Info("Acquiring jobs", "count", len(ids), "requestIds", fmt.Sprint(ids))
 
 	idsAcquired, err := l.client.AcquireJobs(ctx, l.scaleSetID, l.session.MessageQueueAccessToken, ids)
@@ -427,12 +648,213 @@ func (l *Listener) acquireAvailableJobs(ctx context.Context, jobsAvailable []*ac
 	return idsAcquired, nil
 }
 
+// acquireHeldJobs acquires the jobs held outside of the admission windows that are still available, once a window opens.
+// The jobs are acquired between two messages, so up to the long poll of the message queue after the window opens.
+func (l *Listener) acquireHeldJobs(ctx context.Context) error {
+	if !l.jobsHeld {
+		return nil
+	}
+
+	open, _, err := v1alpha1.AdmissionWindowsOpen(l.admissionWindows, l.now())
+	if err != nil {
+		return fmt.Errorf("failed to evaluate admission windows: %w", err)
+	}
+	if !open {
+		return nil
+	}
+
+	acquirableJobs, err := l.client.GetAcquirableJobs(ctx, l.scaleSetID)
+	if err != nil {
+		return fmt.Errorf("failed to get acquirable jobs: %w", err)
+	}
+	l.jobsHeld = false
+
+	jobs := l.ownedJobs(jobsAvailable(acquirableJobs))
+	if len(jobs) == 0 {
+		l.logger.Info("Admission window opened with no held job still available")
+		return nil
+	}
+
+	acquiredJobIDs, err := l.acquireAvailableJobs(ctx, jobs)
+	if err != nil {
+		return err
+	}
+
+	l.logger.Info("Held jobs are acquired", "count", len(acquiredJobIDs), "requestIds", fmt.Sprint(acquiredJobIDs))
+	return nil
+}
+
+// ownedJobs returns the jobs of the partition of the listener.
+func (l *Listener) ownedJobs(jobs []*actions.JobAvailable) []*actions.JobAvailable {
+	if l.shardCount <= 1 {
+		return jobs
+	}
+
+	owned := make([]*actions.JobAvailable, 0, len(jobs))
+	for _, job := range jobs {
+		if l.ownsJob(job.RunnerRequestId) {
+			owned = append(owned, job)
+		}
+	}
+	return owned
+}
+
+func (l *Listener) ownsJob(requestID int64) bool {
+	return l.shardCount <= 1 || requestID%int64(l.shardCount) == int64(l.shardIndex)
+}
+
+// listenShard acquires the jobs of the partition of the shard until the context is cancelled,
+// by polling the acquirable jobs with the message session shared by the listener of the first shard.
+func (l *Listener) listenShard(ctx context.Context) error {
+	l.logger.Info("Acquiring the jobs of the shard", "shard", l.shardIndex, "shards", l.shardCount)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(shardPollInterval):
+		}
+
+		if l.session == nil {
+			if err := l.joinSession(ctx); err != nil {
+				return err
+			}
+			if l.session == nil {
+				continue
+			}
+		}
+
+		if err := l.acquireShardJobs(ctx); err != nil {
+			// The shared session may have been replaced by a restarted listener of the first shard
+			l.logger.Error(err, "Failed to acquire the jobs of the shard, joining the message session again")
+			l.session = nil
+		}
+	}
+}
+
+// joinSession refreshes the message session shared by the listener of the first shard, to get an access token of its own.
+// The session is left unset when none is shared yet, or when the shared one can't be refreshed.
+func (l *Listener) joinSession(ctx context.Context) error {
+	sessionID, err := l.sessions.SessionID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the shared message session: %w", err)
+	}
+	if sessionID == nil {
+		l.logger.Info("Waiting for the listener of the first shard to share its message session")
+		return nil
+	}
+
+	session, err := l.client.RefreshMessageSession(ctx, l.scaleSetID, sessionID)
+	if err != nil {
+		l.logger.Info("Unable to join the shared message session, will try again", "sessionId", sessionID.String(), "error", err.Error())
+		return nil
+	}
+	l.metrics.PublishSessionRefresh()
+
+	l.logger.Info("Joined the shared message session", "sessionId", sessionID.String())
+	l.session = session
+	return nil
+}
+
+// acquireShardJobs acquires the acquirable jobs of the partition of the shard.
+func (l *Listener) acquireShardJobs(ctx context.Context) error {
+	acquirableJobs, err := l.client.GetAcquirableJobs(ctx, l.scaleSetID)
+	if err != nil {
+		return fmt.Errorf("failed to get acquirable jobs: %w", err)
+	}
+
+	jobs := l.ownedJobs(jobsAvailable(acquirableJobs))
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	acquiredJobIDs, err := l.acquireAvailableJobs(ctx, jobs)
+	if err != nil {
+		return err
+	}
+
+	l.logger.Info("Jobs are acquired", "count", len(acquiredJobIDs), "requestIds", fmt.Sprint(acquiredJobIDs))
+	return nil
+}
+
+// acquireOrphanedJobs acquires the jobs of the other shards that stayed acquirable for shardTakeoverDelay,
+// so that the jobs of a shard whose listener is down aren't left unacquired.
+// The acquirable jobs are checked between two messages, at most every shardTakeoverDelay/2.
+func (l *Listener) acquireOrphanedJobs(ctx context.Context) error {
+	if l.shardCount <= 1 {
+		return nil
+	}
+
+	now := l.now()
+	if now.Sub(l.lastTakeover) < shardTakeoverDelay/2 {
+		return nil
+	}
+	l.lastTakeover = now
+
+	acquirableJobs, err := l.client.GetAcquirableJobs(ctx, l.scaleSetID)
+	if err != nil {
+		return fmt.Errorf("failed to get acquirable jobs: %w", err)
+	}
+
+	firstSeen := make(map[int64]time.Time)
+	var orphanedJobs []*actions.JobAvailable
+	for _, job := range jobsAvailable(acquirableJobs) {
+		if l.ownsJob(job.RunnerRequestId) {
+			continue
+		}
+
+		seen, ok := l.jobsFirstSeen[job.RunnerRequestId]
+		if !ok {
+			seen = now
+		}
+		firstSeen[job.RunnerRequestId] = seen
+
+		if now.Sub(seen) >= shardTakeoverDelay {
+			orphanedJobs = append(orphanedJobs, job)
+		}
+	}
+	l.jobsFirstSeen = firstSeen
+
+	if len(orphanedJobs) == 0 {
+		return nil
+	}
+
+	l.logger.Info("Taking over the jobs left unacquired by their shard", "count", len(orphanedJobs))
+	acquiredJobIDs, err := l.acquireAvailableJobs(ctx, orphanedJobs)
+	if err != nil {
+		return err
+	}
+
+	l.logger.Info("Jobs of the other shards are acquired", "count", len(acquiredJobIDs), "requestIds", fmt.Sprint(acquiredJobIDs))
+	return nil
+}
+
+// jobsAvailable converts the acquirable jobs to the available jobs of the messages.
+func jobsAvailable(acquirableJobs *actions.AcquirableJobList) []*actions.JobAvailable {
+	jobs := make([]*actions.JobAvailable, 0, len(acquirableJobs.Jobs))
+	for _, job := range acquirableJobs.Jobs {
+		jobs = append(jobs, &actions.JobAvailable{
+			AcquireJobUrl: job.AcquireJobUrl,
+			JobMessageBase: actions.JobMessageBase{
+				RunnerRequestId: job.RunnerRequestId,
+				RepositoryName:  job.RepositoryName,
+				OwnerName:       job.OwnerName,
+				JobWorkflowRef:  job.JobWorkflowRef,
+				EventName:       job.EventName,
+				RequestLabels:   job.RequestLabels,
+			},
+		})
+	}
+	return jobs
+}
+
 func (l *Listener) refreshSession(ctx context.Context) error {
 	l.logger.Info("Message queue token is expired during GetNextMessage, refreshing...")
 	session, err := l.client.RefreshMessageSession(ctx, l.session.RunnerScaleSet.Id, l.session.SessionId)
 	if err != nil {
 		return fmt.Errorf("refresh message session failed. %w", err)
 	}
+	l.metrics.PublishSessionRefresh()
 
 	l.session = session
 	return nil
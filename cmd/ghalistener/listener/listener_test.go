@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	listenermocks "github.com/actions/actions-runner-controller/cmd/ghalistener/listener/mocks"
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/metrics"
 	"github.com/actions/actions-runner-controller/github/actions"
@@ -828,6 +829,350 @@ func TestListener_acquireAvailableJobs(t *testing.T) {
 		assert.NotNil(t, err)
 		assert.Nil(t, got)
 	})
+
+	t.Run("RefusesJobsOfRepositoriesNotAllowed", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		config := Config{
+			ScaleSetID:          1,
+			Metrics:             metrics.Discard,
+			AllowedRepositories: []string{"example/API", "example/web"},
+		}
+
+		client := listenermocks.NewClient(t)
+
+		client.On("AcquireJobs", ctx, mock.Anything, mock.Anything, []int64{1, 3}).Return([]int64{1, 3}, nil).Once()
+
+		config.Client = client
+
+		l, err := New(config)
+		require.Nil(t, err)
+
+		uuid := uuid.New()
+		l.session = &actions.RunnerScaleSetSession{
+			SessionId:               &uuid,
+			OwnerName:               "example",
+			RunnerScaleSet:          &actions.RunnerScaleSet{},
+			MessageQueueUrl:         "https://example.com",
+			MessageQueueAccessToken: "1234567890",
+			Statistics:              &actions.RunnerScaleSetStatistic{},
+		}
+
+		availableJobs := []*actions.JobAvailable{
+			{
+				JobMessageBase: actions.JobMessageBase{
+					RunnerRequestId: 1,
+					OwnerName:       "example",
+					RepositoryName:  "api",
+				},
+			},
+			{
+				JobMessageBase: actions.JobMessageBase{
+					RunnerRequestId: 2,
+					OwnerName:       "example",
+					RepositoryName:  "other",
+				},
+			},
+			{
+				JobMessageBase: actions.JobMessageBase{
+					RunnerRequestId: 3,
+					OwnerName:       "example",
+					RepositoryName:  "Web",
+				},
+			},
+			{
+				// A repository of the same name in another organization of the enterprise
+				JobMessageBase: actions.JobMessageBase{
+					RunnerRequestId: 4,
+					OwnerName:       "other-org",
+					RepositoryName:  "api",
+				},
+			},
+		}
+		acquiredJobIDs, err := l.acquireAvailableJobs(ctx, availableJobs)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 3}, acquiredJobIDs)
+
+		acquiredJobIDs, err = l.acquireAvailableJobs(ctx, []*actions.JobAvailable{availableJobs[1], availableJobs[3]})
+		assert.NoError(t, err)
+		assert.Empty(t, acquiredJobIDs)
+	})
+
+	t.Run("LeavesJobsOutsideAdmissionWindows", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		config := Config{
+			ScaleSetID:       1,
+			Metrics:          metrics.Discard,
+			AdmissionWindows: []v1alpha1.AdmissionWindow{{DailyWindow: v1alpha1.DailyWindow{Start: "09:00", End: "17:00"}}},
+		}
+
+		client := listenermocks.NewClient(t)
+		config.Client = client
+
+		l, err := New(config)
+		require.Nil(t, err)
+		l.now = func() time.Time { return time.Date(2024, 5, 6, 18, 0, 0, 0, time.UTC) }
+
+		availableJobs := []*actions.JobAvailable{
+			{
+				JobMessageBase: actions.JobMessageBase{
+					RunnerRequestId: 1,
+				},
+			},
+		}
+		acquiredJobIDs, err := l.acquireAvailableJobs(ctx, availableJobs)
+		assert.NoError(t, err)
+		assert.Empty(t, acquiredJobIDs)
+		assert.False(t, l.jobsHeld)
+
+		// AcquireJobs isn't expected, and no held job is acquired later on
+		require.NoError(t, l.acquireHeldJobs(ctx))
+	})
+}
+
+func TestListener_acquireHeldJobs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	config := Config{
+		ScaleSetID:                  1,
+		Metrics:                     metrics.Discard,
+		AllowedRepositories:         []string{"example/api"},
+		AdmissionWindows:            []v1alpha1.AdmissionWindow{{DailyWindow: v1alpha1.DailyWindow{Start: "09:00", End: "17:00"}}},
+		HoldOutsideAdmissionWindows: true,
+	}
+
+	client := listenermocks.NewClient(t)
+	config.Client = client
+
+	l, err := New(config)
+	require.Nil(t, err)
+
+	now := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	uuid := uuid.New()
+	l.session = &actions.RunnerScaleSetSession{
+		SessionId:               &uuid,
+		RunnerScaleSet:          &actions.RunnerScaleSet{},
+		MessageQueueUrl:         "https://example.com",
+		MessageQueueAccessToken: "1234567890",
+		Statistics:              &actions.RunnerScaleSetStatistic{},
+	}
+
+	availableJobs := []*actions.JobAvailable{
+		{
+			JobMessageBase: actions.JobMessageBase{
+				RunnerRequestId: 1,
+				OwnerName:       "example",
+				RepositoryName:  "api",
+			},
+		},
+	}
+	acquiredJobIDs, err := l.acquireAvailableJobs(ctx, availableJobs)
+	assert.NoError(t, err)
+	assert.Empty(t, acquiredJobIDs)
+	assert.True(t, l.jobsHeld)
+
+	// The jobs are held until the window opens
+	require.NoError(t, l.acquireHeldJobs(ctx))
+	assert.True(t, l.jobsHeld)
+
+	now = time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+
+	client.On("GetAcquirableJobs", ctx, 1).Return(&actions.AcquirableJobList{
+		Count: 2,
+		Jobs: []actions.AcquirableJob{
+			{RunnerRequestId: 1, OwnerName: "example", RepositoryName: "api"},
+			{RunnerRequestId: 2, OwnerName: "example", RepositoryName: "other"},
+		},
+	}, nil).Once()
+	client.On("AcquireJobs", ctx, 1, "1234567890", []int64{1}).Return([]int64{1}, nil).Once()
+
+	require.NoError(t, l.acquireHeldJobs(ctx))
+	assert.False(t, l.jobsHeld)
+}
+
+func TestListener_shards(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	acquirableJobs := &actions.AcquirableJobList{
+		Count: 4,
+		Jobs: []actions.AcquirableJob{
+			{RunnerRequestId: 3},
+			{RunnerRequestId: 4},
+			{RunnerRequestId: 5},
+			{RunnerRequestId: 6},
+		},
+	}
+
+	newListener := func(t *testing.T, shardIndex int) (*Listener, *listenermocks.Client, *listenermocks.SessionStore) {
+		client := listenermocks.NewClient(t)
+		sessions := listenermocks.NewSessionStore(t)
+		l, err := New(Config{
+			Client:     client,
+			ScaleSetID: 1,
+			Metrics:    metrics.Discard,
+			ShardIndex: shardIndex,
+			ShardCount: 3,
+			Sessions:   sessions,
+		})
+		require.NoError(t, err)
+		return l, client, sessions
+	}
+
+	t.Run("RequiresSessions", func(t *testing.T) {
+		t.Parallel()
+		_, err := New(Config{Client: listenermocks.NewClient(t), ScaleSetID: 1, ShardCount: 2})
+		assert.ErrorContains(t, err, "sessions is required")
+	})
+
+	t.Run("JoinsSharedSession", func(t *testing.T) {
+		t.Parallel()
+		l, client, sessions := newListener(t, 1)
+
+		sessions.On("SessionID", ctx).Return(nil, nil).Once()
+		require.NoError(t, l.joinSession(ctx))
+		assert.Nil(t, l.session)
+
+		sessionID := uuid.New()
+		session := &actions.RunnerScaleSetSession{SessionId: &sessionID, MessageQueueAccessToken: "token"}
+		sessions.On("SessionID", ctx).Return(&sessionID, nil).Once()
+		client.On("RefreshMessageSession", ctx, 1, &sessionID).Return(session, nil).Once()
+		require.NoError(t, l.joinSession(ctx))
+		assert.Equal(t, session, l.session)
+	})
+
+	t.Run("AcquiresJobsOfShard", func(t *testing.T) {
+		t.Parallel()
+		l, client, _ := newListener(t, 1)
+		l.session = &actions.RunnerScaleSetSession{MessageQueueAccessToken: "token"}
+
+		client.On("GetAcquirableJobs", ctx, 1).Return(acquirableJobs, nil).Once()
+		client.On("AcquireJobs", ctx, 1, "token", []int64{4}).Return([]int64{4}, nil).Once()
+		require.NoError(t, l.acquireShardJobs(ctx))
+	})
+
+	t.Run("TakesOverOrphanedJobs", func(t *testing.T) {
+		t.Parallel()
+		l, client, _ := newListener(t, 0)
+		l.session = &actions.RunnerScaleSetSession{MessageQueueAccessToken: "token"}
+
+		now := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+		l.now = func() time.Time { return now }
+
+		client.On("GetAcquirableJobs", ctx, 1).Return(acquirableJobs, nil).Once()
+		require.NoError(t, l.acquireOrphanedJobs(ctx))
+
+		// Not checked again before half of the takeover delay
+		now = now.Add(shardTakeoverDelay / 4)
+		require.NoError(t, l.acquireOrphanedJobs(ctx))
+
+		now = now.Add(shardTakeoverDelay)
+		client.On("GetAcquirableJobs", ctx, 1).Return(acquirableJobs, nil).Once()
+		client.On("AcquireJobs", ctx, 1, "token", []int64{4, 5}).Return([]int64{4, 5}, nil).Once()
+		require.NoError(t, l.acquireOrphanedJobs(ctx))
+	})
+}
+
+// memoryHandoff keeps the handed off message session in memory.
+type memoryHandoff struct {
+	state *SessionState
+	saves int
+}
+
+func (h *memoryHandoff) SaveSession(ctx context.Context, state *SessionState) error {
+	saved := *state
+	h.state = &saved
+	h.saves++
+	return nil
+}
+
+func (h *memoryHandoff) LoadSession(ctx context.Context) (*SessionState, error) {
+	return h.state, nil
+}
+
+func TestListener_sessionHandoff(t *testing.T) {
+	t.Parallel()
+
+	newListener := func(t *testing.T, handoff *memoryHandoff) (*Listener, *listenermocks.Client) {
+		client := listenermocks.NewClient(t)
+		l, err := New(Config{
+			Client:     client,
+			ScaleSetID: 1,
+			Metrics:    metrics.Discard,
+			Handoff:    handoff,
+		})
+		require.NoError(t, err)
+		return l, client
+	}
+
+	t.Run("ResumesHandedOffSession", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		sessionID := uuid.New()
+		handoff := &memoryHandoff{state: &SessionState{ScaleSetID: 1, SessionID: sessionID, LastMessageID: 41}}
+		l, client := newListener(t, handoff)
+
+		session := &actions.RunnerScaleSetSession{
+			SessionId:               &sessionID,
+			RunnerScaleSet:          &actions.RunnerScaleSet{Id: 1},
+			MessageQueueUrl:         "https://example.com",
+			MessageQueueAccessToken: "token",
+			Statistics:              &actions.RunnerScaleSetStatistic{},
+		}
+		client.On("RefreshMessageSession", ctx, 1, &sessionID).Return(session, nil).Once()
+		client.On("GetMessage", ctx, "https://example.com", "token", int64(41), 0).
+			Return(&actions.RunnerScaleSetMessage{MessageId: 42, MessageType: "RunnerScaleSetJobMessages", Statistics: &actions.RunnerScaleSetStatistic{}}, nil).
+			Run(func(mock.Arguments) { cancel() }).
+			Once()
+		client.On("DeleteMessage", mock.Anything, "https://example.com", "token", int64(42)).Return(nil).Once()
+
+		handler := listenermocks.NewHandler(t)
+		handler.On("HandleDesiredRunnerCount", mock.Anything, 0, 0).Return(0, nil).Twice()
+
+		err := l.Listen(ctx, handler)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// The session is left open for the next listener, with the last message processed
+		client.AssertNotCalled(t, "DeleteMessageSession", mock.Anything, mock.Anything, mock.Anything)
+		assert.Equal(t, &SessionState{ScaleSetID: 1, SessionID: sessionID, LastMessageID: 42}, handoff.state)
+	})
+
+	t.Run("CreatesSessionWhenHandedOffOneIsGone", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		staleID := uuid.New()
+		handoff := &memoryHandoff{state: &SessionState{ScaleSetID: 1, SessionID: staleID, LastMessageID: 7}}
+		l, client := newListener(t, handoff)
+
+		newID := uuid.New()
+		session := &actions.RunnerScaleSetSession{SessionId: &newID, RunnerScaleSet: &actions.RunnerScaleSet{Id: 1}, Statistics: &actions.RunnerScaleSetStatistic{}}
+		client.On("RefreshMessageSession", ctx, 1, &staleID).Return(nil, assert.AnError).Once()
+		client.On("DeleteMessageSession", ctx, 1, &staleID).Return(assert.AnError).Once()
+		client.On("CreateMessageSession", ctx, 1, mock.Anything).Return(session, nil).Once()
+
+		require.False(t, l.resumeSession(ctx))
+		require.NoError(t, l.createSession(ctx))
+		l.saveSession(ctx)
+
+		assert.Equal(t, int64(0), l.lastMessageID)
+		assert.Equal(t, &SessionState{ScaleSetID: 1, SessionID: newID}, handoff.state)
+	})
+
+	t.Run("IgnoresSessionOfAnotherScaleSet", func(t *testing.T) {
+		t.Parallel()
+		handoff := &memoryHandoff{state: &SessionState{ScaleSetID: 2, SessionID: uuid.New()}}
+		l, _ := newListener(t, handoff)
+
+		assert.False(t, l.resumeSession(context.Background()))
+	})
 }
 
 func TestListener_parseMessage(t *testing.T) {
@@ -964,6 +1309,7 @@ func TestListener_parseMessage(t *testing.T) {
 
 		assert.Equal(t, msg.Statistics, parsedMsg.statistics)
 		assert.Equal(t, jobsAvailable, parsedMsg.jobsAvailable)
+		assert.Equal(t, jobsAssigned, parsedMsg.jobsAssigned)
 		assert.Equal(t, jobsStarted, parsedMsg.jobsStarted)
 		assert.Equal(t, jobsCompleted, parsedMsg.jobsCompleted)
 	})
@@ -178,6 +178,8 @@ func TestHandleMessageMetrics(t *testing.T) {
 
 	handler := listenermocks.NewHandler(t)
 	handler.On("HandleJobStarted", mock.Anything, jobsStarted[0]).Return(nil).Once()
+	handler.On("HandleJobCompleted", mock.Anything, jobsCompleted[0]).Return(nil).Once()
+	handler.On("HandleJobCompleted", mock.Anything, jobsCompleted[1]).Return(nil).Once()
 	handler.On("HandleDesiredRunnerCount", mock.Anything, mock.Anything, 2).Return(desiredResult, nil).Once()
 
 	client := listenermocks.NewClient(t)
@@ -203,3 +205,46 @@ func TestHandleMessageMetrics(t *testing.T) {
 	err = l.handleMessage(context.Background(), handler, msg)
 	require.NoError(t, err)
 }
+
+func TestGetMessageMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	metrics := metricsmocks.NewPublisher(t)
+	metrics.On("PublishStatic", 0, 10).Once()
+	metrics.On("PublishMessagePollDuration", mock.AnythingOfType("time.Duration")).Twice()
+	metrics.On("PublishSessionRefresh").Once()
+
+	uuid := uuid.New()
+	session := &actions.RunnerScaleSetSession{
+		SessionId:               &uuid,
+		RunnerScaleSet:          &actions.RunnerScaleSet{},
+		MessageQueueUrl:         "https://example.com",
+		MessageQueueAccessToken: "1234567890",
+	}
+
+	want := &actions.RunnerScaleSetMessage{
+		MessageId: 1,
+	}
+
+	client := listenermocks.NewClient(t)
+	client.On("GetMessage", ctx, mock.Anything, mock.Anything, mock.Anything, 10).Return(nil, &actions.MessageQueueTokenExpiredError{}).Once()
+	client.On("RefreshMessageSession", ctx, mock.Anything, mock.Anything).Return(session, nil).Once()
+	client.On("GetMessage", ctx, mock.Anything, mock.Anything, mock.Anything, 10).Return(want, nil).Once()
+
+	config := Config{
+		Client:     client,
+		ScaleSetID: 1,
+		Metrics:    metrics,
+		MaxRunners: 10,
+	}
+
+	l, err := New(config)
+	require.NoError(t, err)
+	l.session = session
+
+	got, err := l.getMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
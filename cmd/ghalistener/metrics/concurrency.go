@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// concurrencySummaryInterval is how often the job concurrency summary is logged,
+// and how long the peak concurrency is tracked for before it's reset.
+const concurrencySummaryInterval = time.Hour
+
+var (
+	concurrencyLabels = []string{
+		labelKeyRunnerScaleSetName,
+		labelKeyRunnerScaleSetNamespace,
+		labelKeyEnterprise,
+		labelKeyOrganization,
+		labelKeyRunnerGroup,
+	}
+
+	concurrentJobs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: githubScaleSetSubsystem,
+			Name:      "concurrent_jobs",
+			Help:      "Number of jobs running concurrently on the scale set per organization and runner group.",
+		},
+		concurrencyLabels,
+	)
+
+	concurrentJobsPeak = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: githubScaleSetSubsystem,
+			Name:      "concurrent_jobs_peak",
+			Help:      "Peak number of jobs running concurrently on the scale set per organization and runner group within the current summary period.",
+		},
+		concurrencyLabels,
+	)
+)
+
+// jobConcurrency tracks the number of jobs running concurrently per organization, for license and capacity planning.
+// It is an approximation after a listener restart, as the jobs started before the restart are never counted.
+type jobConcurrency struct {
+	mu sync.Mutex
+
+	baseLabels  baseLabels
+	runnerGroup string
+
+	// running maps the runner request ID of each running job to its organization
+	running map[int64]string
+	current map[string]int
+	peak    map[string]int
+}
+
+func newJobConcurrency(b baseLabels, runnerGroup string) *jobConcurrency {
+	return &jobConcurrency{
+		baseLabels:  b,
+		runnerGroup: runnerGroup,
+		running:     map[int64]string{},
+		current:     map[string]int{},
+		peak:        map[string]int{},
+	}
+}
+
+func (c *jobConcurrency) labels(organization string) prometheus.Labels {
+	return prometheus.Labels{
+		labelKeyRunnerScaleSetName:      c.baseLabels.scaleSetName,
+		labelKeyRunnerScaleSetNamespace: c.baseLabels.scaleSetNamespace,
+		labelKeyEnterprise:              c.baseLabels.enterprise,
+		labelKeyOrganization:            organization,
+		labelKeyRunnerGroup:             c.runnerGroup,
+	}
+}
+
+func (c *jobConcurrency) started(msg *actions.JobMessageBase) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.running[msg.RunnerRequestId]; ok {
+		return
+	}
+
+	org := msg.OwnerName
+	c.running[msg.RunnerRequestId] = org
+	c.current[org]++
+
+	if c.current[org] > c.peak[org] {
+		c.peak[org] = c.current[org]
+	}
+
+	c.publish(org)
+}
+
+func (c *jobConcurrency) completed(msg *actions.JobMessageBase) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Jobs that are cancelled before they start, or that started before the listener, were never counted
+	org, ok := c.running[msg.RunnerRequestId]
+	if !ok {
+		return
+	}
+
+	delete(c.running, msg.RunnerRequestId)
+	c.current[org]--
+
+	c.publish(org)
+}
+
+func (c *jobConcurrency) publish(org string) {
+	l := c.labels(org)
+	concurrentJobs.With(l).Set(float64(c.current[org]))
+	concurrentJobsPeak.With(l).Set(float64(c.peak[org]))
+}
+
+// summarize logs the current and peak concurrency per organization, and starts a new summary period.
+func (c *jobConcurrency) summarize(logger logr.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	orgs := make([]string, 0, len(c.peak))
+	for org := range c.peak {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+
+	for _, org := range orgs {
+		logger.Info(
+			"Job concurrency summary",
+			"organization", org,
+			"runnerGroup", c.runnerGroup,
+			"current", c.current[org],
+			"peak", c.peak[org],
+			"period", concurrencySummaryInterval.String(),
+		)
+
+		c.peak[org] = c.current[org]
+
+		if c.current[org] == 0 {
+			// Stop reporting organizations that no longer run jobs
+			concurrentJobs.Delete(c.labels(org))
+			concurrentJobsPeak.Delete(c.labels(org))
+			delete(c.current, org)
+			delete(c.peak, org)
+
+			continue
+		}
+
+		c.publish(org)
+	}
+}
+
+func (c *jobConcurrency) runSummaries(ctx context.Context, logger logr.Logger) {
+	ticker := time.NewTicker(concurrencySummaryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.summarize(logger)
+		}
+	}
+}
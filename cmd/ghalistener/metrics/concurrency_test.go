@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobConcurrency(t *testing.T) {
+	c := newJobConcurrency(baseLabels{scaleSetName: "arc", scaleSetNamespace: "arc-runners"}, "Default")
+
+	job := func(id int64, org string) *actions.JobMessageBase {
+		return &actions.JobMessageBase{RunnerRequestId: id, OwnerName: org}
+	}
+
+	c.started(job(1, "org-a"))
+	c.started(job(2, "org-a"))
+	c.started(job(2, "org-a")) // duplicate
+	c.started(job(3, "org-b"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(concurrentJobs.With(c.labels("org-a"))))
+	assert.Equal(t, 1.0, testutil.ToFloat64(concurrentJobs.With(c.labels("org-b"))))
+
+	c.completed(job(1, "org-a"))
+	c.completed(job(4, "org-a")) // never started
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(concurrentJobs.With(c.labels("org-a"))))
+	assert.Equal(t, 2.0, testutil.ToFloat64(concurrentJobsPeak.With(c.labels("org-a"))))
+
+	c.completed(job(3, "org-b"))
+	c.summarize(logr.Discard())
+
+	// The peak is reset to the current concurrency for the next period
+	assert.Equal(t, 1.0, testutil.ToFloat64(concurrentJobsPeak.With(c.labels("org-a"))))
+	assert.Equal(t, 1, c.peak["org-a"])
+
+	// Organizations without running jobs are no longer reported
+	_, ok := c.peak["org-b"]
+	assert.False(t, ok)
+	assert.Equal(t, 1, testutil.CollectAndCount(concurrentJobs))
+}
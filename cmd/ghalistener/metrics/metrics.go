@@ -20,6 +20,7 @@ const (
 	labelKeyJobName                 = "job_name"
 	labelKeyEventName               = "event_name"
 	labelKeyJobResult               = "job_result"
+	labelKeyRunnerGroup             = "runner_group"
 )
 
 const githubScaleSetSubsystem = "gha"
@@ -58,6 +59,24 @@ var (
 		scaleSetLabels,
 	)
 
+	availableJobs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: githubScaleSetSubsystem,
+			Name:      "available_jobs",
+			Help:      "Number of jobs available to this scale set, not acquired yet.",
+		},
+		scaleSetLabels,
+	)
+
+	acquiredJobs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: githubScaleSetSubsystem,
+			Name:      "acquired_jobs",
+			Help:      "Number of jobs acquired by this scale set, not assigned to a runner yet.",
+		},
+		scaleSetLabels,
+	)
+
 	runningJobs = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Subsystem: githubScaleSetSubsystem,
@@ -158,8 +177,29 @@ var (
 		},
 		jobExecutionDurationLabels,
 	)
+
+	messagePollDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: githubScaleSetSubsystem,
+			Name:      "message_poll_duration_seconds",
+			Help:      "Time spent polling the message queue for the next message, which is long-polled for up to 50 seconds (in seconds).",
+			Buckets:   messagePollBuckets,
+		},
+		scaleSetLabels,
+	)
+
+	sessionRefreshesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: githubScaleSetSubsystem,
+			Name:      "session_refreshes_total",
+			Help:      "Total number of refreshes of the message session, after the message queue token expired.",
+		},
+		scaleSetLabels,
+	)
 )
 
+var messagePollBuckets = []float64{0.1, 0.5, 1, 2, 5, 10, 20, 30, 40, 50, 55, 60, 90}
+
 var runtimeBuckets []float64 = []float64{
 	0.01,
 	0.05,
@@ -254,6 +294,8 @@ type Publisher interface {
 	PublishJobStarted(msg *actions.JobStarted)
 	PublishJobCompleted(msg *actions.JobCompleted)
 	PublishDesiredRunners(count int)
+	PublishMessagePollDuration(d time.Duration)
+	PublishSessionRefresh()
 }
 
 //go:generate mockery --name ServerPublisher --output ./mocks --outpkg mocks --case underscore
@@ -272,7 +314,8 @@ var Discard Publisher = &discard{}
 type exporter struct {
 	logger logr.Logger
 	baseLabels
-	srv *http.Server
+	concurrency *jobConcurrency
+	srv         *http.Server
 }
 
 type ExporterConfig struct {
@@ -281,6 +324,7 @@ type ExporterConfig struct {
 	Enterprise        string
 	Organization      string
 	Repository        string
+	RunnerGroup       string
 	ServerAddr        string
 	ServerEndpoint    string
 	Logger            logr.Logger
@@ -290,6 +334,8 @@ func NewExporter(config ExporterConfig) ServerPublisher {
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(
 		assignedJobs,
+		availableJobs,
+		acquiredJobs,
 		runningJobs,
 		registeredRunners,
 		busyRunners,
@@ -301,6 +347,10 @@ func NewExporter(config ExporterConfig) ServerPublisher {
 		completedJobsTotal,
 		jobStartupDurationSeconds,
 		jobExecutionDurationSeconds,
+		messagePollDurationSeconds,
+		sessionRefreshesTotal,
+		concurrentJobs,
+		concurrentJobsPeak,
 	)
 
 	mux := http.NewServeMux()
@@ -309,15 +359,18 @@ func NewExporter(config ExporterConfig) ServerPublisher {
 		promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}),
 	)
 
+	b := baseLabels{
+		scaleSetName:      config.ScaleSetName,
+		scaleSetNamespace: config.ScaleSetNamespace,
+		enterprise:        config.Enterprise,
+		organization:      config.Organization,
+		repository:        config.Repository,
+	}
+
 	return &exporter{
-		logger: config.Logger.WithName("metrics"),
-		baseLabels: baseLabels{
-			scaleSetName:      config.ScaleSetName,
-			scaleSetNamespace: config.ScaleSetNamespace,
-			enterprise:        config.Enterprise,
-			organization:      config.Organization,
-			repository:        config.Repository,
-		},
+		logger:      config.Logger.WithName("metrics"),
+		baseLabels:  b,
+		concurrency: newJobConcurrency(b, config.RunnerGroup),
 		srv: &http.Server{
 			Addr:    config.ServerAddr,
 			Handler: mux,
@@ -327,6 +380,7 @@ func NewExporter(config ExporterConfig) ServerPublisher {
 
 func (e *exporter) ListenAndServe(ctx context.Context) error {
 	e.logger.Info("starting metrics server", "addr", e.srv.Addr)
+	go e.concurrency.runSummaries(ctx, e.logger)
 	go func() {
 		<-ctx.Done()
 		e.logger.Info("stopping metrics server", "err", ctx.Err())
@@ -346,6 +400,8 @@ func (m *exporter) PublishStatic(min, max int) {
 func (e *exporter) PublishStatistics(stats *actions.RunnerScaleSetStatistic) {
 	l := e.scaleSetLabels()
 
+	availableJobs.With(l).Set(float64(stats.TotalAvailableJobs))
+	acquiredJobs.With(l).Set(float64(stats.TotalAcquiredJobs))
 	assignedJobs.With(l).Set(float64(stats.TotalAssignedJobs))
 	runningJobs.With(l).Set(float64(stats.TotalRunningJobs))
 	registeredRunners.With(l).Set(float64(stats.TotalRegisteredRunners))
@@ -356,6 +412,7 @@ func (e *exporter) PublishStatistics(stats *actions.RunnerScaleSetStatistic) {
 func (e *exporter) PublishJobStarted(msg *actions.JobStarted) {
 	l := e.startedJobLabels(msg)
 	startedJobsTotal.With(l).Inc()
+	e.concurrency.started(&msg.JobMessageBase)
 
 	startupDuration := msg.JobMessageBase.RunnerAssignTime.Unix() - msg.JobMessageBase.ScaleSetAssignTime.Unix()
 	jobStartupDurationSeconds.With(l).Observe(float64(startupDuration))
@@ -364,6 +421,7 @@ func (e *exporter) PublishJobStarted(msg *actions.JobStarted) {
 func (e *exporter) PublishJobCompleted(msg *actions.JobCompleted) {
 	l := e.completedJobLabels(msg)
 	completedJobsTotal.With(l).Inc()
+	e.concurrency.completed(&msg.JobMessageBase)
 
 	executionDuration := msg.JobMessageBase.FinishTime.Unix() - msg.JobMessageBase.RunnerAssignTime.Unix()
 	jobExecutionDurationSeconds.With(l).Observe(float64(executionDuration))
@@ -373,6 +431,14 @@ func (m *exporter) PublishDesiredRunners(count int) {
 	desiredRunners.With(m.scaleSetLabels()).Set(float64(count))
 }
 
+func (e *exporter) PublishMessagePollDuration(d time.Duration) {
+	messagePollDurationSeconds.With(e.scaleSetLabels()).Observe(d.Seconds())
+}
+
+func (e *exporter) PublishSessionRefresh() {
+	sessionRefreshesTotal.With(e.scaleSetLabels()).Inc()
+}
+
 type discard struct{}
 
 func (*discard) PublishStatic(int, int)                             {}
@@ -380,3 +446,5 @@ func (*discard) PublishStatistics(*actions.RunnerScaleSetStatistic) {}
 func (*discard) PublishJobStarted(*actions.JobStarted)              {}
 func (*discard) PublishJobCompleted(*actions.JobCompleted)          {}
 func (*discard) PublishDesiredRunners(int)                          {}
+func (*discard) PublishMessagePollDuration(time.Duration)           {}
+func (*discard) PublishSessionRefresh()                             {}
@@ -6,6 +6,8 @@ import (
 	actions "github.com/actions/actions-runner-controller/github/actions"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Publisher is an autogenerated mock type for the Publisher type
@@ -28,6 +30,16 @@ func (_m *Publisher) PublishJobStarted(msg *actions.JobStarted) {
 	_m.Called(msg)
 }
 
+// PublishMessagePollDuration provides a mock function with given fields: d
+func (_m *Publisher) PublishMessagePollDuration(d time.Duration) {
+	_m.Called(d)
+}
+
+// PublishSessionRefresh provides a mock function with given fields:
+func (_m *Publisher) PublishSessionRefresh() {
+	_m.Called()
+}
+
 // PublishStatic provides a mock function with given fields: min, max
 func (_m *Publisher) PublishStatic(min int, max int) {
 	_m.Called(min, max)
@@ -8,6 +8,8 @@ import (
 	actions "github.com/actions/actions-runner-controller/github/actions"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // ServerPublisher is an autogenerated mock type for the ServerPublisher type
@@ -44,6 +46,16 @@ func (_m *ServerPublisher) PublishJobStarted(msg *actions.JobStarted) {
 	_m.Called(msg)
 }
 
+// PublishMessagePollDuration provides a mock function with given fields: d
+func (_m *ServerPublisher) PublishMessagePollDuration(d time.Duration) {
+	_m.Called(d)
+}
+
+// PublishSessionRefresh provides a mock function with given fields:
+func (_m *ServerPublisher) PublishSessionRefresh() {
+	_m.Called()
+}
+
 // PublishStatic provides a mock function with given fields: min, max
 func (_m *ServerPublisher) PublishStatic(min int, max int) {
 	_m.Called(min, max)
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/listener"
@@ -11,7 +14,9 @@ import (
 	"github.com/actions/actions-runner-controller/logging"
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/go-logr/logr"
+	"github.com/google/uuid"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -19,6 +24,28 @@ import (
 
 const workerName = "kubernetesworker"
 
+// annotationKeyListenerSessionID is the annotation of the ephemeral runner set holding the ID of the message session
+// the listener of the first shard shares with the other shards.
+const annotationKeyListenerSessionID = "actions.github.com/listener-session-id"
+
+// The annotations of the ephemeral runner set the jobs of its canary runners are counted in, for the controller
+// to promote or roll back the canary runner image. They must be kept in sync with the ones of the controller.
+const (
+	annotationKeyCanarySpecHash      = "actions.github.com/canary-spec-hash"
+	annotationKeyCanaryCompletedJobs = "actions.github.com/canary-completed-jobs"
+	annotationKeyCanaryFailedJobs    = "actions.github.com/canary-failed-jobs"
+)
+
+// canaryRunnerNameInfix follows the name of the ephemeral runner set in the names of its canary runners.
+const canaryRunnerNameInfix = "-canary-"
+
+// The keys of the session secret the message session is handed off through.
+const (
+	sessionSecretKeyScaleSetID    = "scaleSetId"
+	sessionSecretKeySessionID     = "sessionId"
+	sessionSecretKeyLastMessageID = "lastMessageId"
+)
+
 type Option func(*Worker)
 
 func WithLogger(logger logr.Logger) Option {
@@ -33,6 +60,8 @@ type Config struct {
 	EphemeralRunnerSetName      string
 	MaxRunners                  int
 	MinRunners                  int
+	// SessionSecretName is the secret, in the namespace of the ephemeral runner set, the message session is handed off through.
+	SessionSecretName string
 }
 
 // The Worker's role is to process the messages it receives from the listener.
@@ -43,9 +72,15 @@ type Worker struct {
 	lastPatch int
 	patchSeq  int
 	logger    *logr.Logger
+	// jobsAssignedAt are the times the jobs that haven't started yet were assigned to the scale set, by job request ID.
+	jobsAssignedAt map[int64]time.Time
 }
 
-var _ listener.Handler = (*Worker)(nil)
+var (
+	_ listener.Handler        = (*Worker)(nil)
+	_ listener.SessionStore   = (*Worker)(nil)
+	_ listener.SessionHandoff = (*Worker)(nil)
+)
 
 func New(config Config, options ...Option) (*Worker, error) {
 	w := &Worker{
@@ -90,12 +125,30 @@ func (w *Worker) applyDefaults() error {
 	return nil
 }
 
+// HandleJobAssigned records the time the job was assigned to the scale set, until it starts.
+func (w *Worker) HandleJobAssigned(ctx context.Context, jobInfo *actions.JobAssigned) error {
+	// The job queue latency SLO of the scale set is measured from the assignment of the job to the scale set
+	if w.jobsAssignedAt == nil {
+		w.jobsAssignedAt = make(map[int64]time.Time)
+	}
+	assignedAt := jobInfo.ScaleSetAssignTime
+	if assignedAt.IsZero() {
+		assignedAt = time.Now()
+	}
+	w.jobsAssignedAt[jobInfo.RunnerRequestId] = assignedAt
+
+	return nil
+}
+
 // HandleJobStarted updates the job information for the ephemeral runner when a job is started.
 // It takes a context and a jobInfo parameter which contains the details of the started job.
 // This update marks the ephemeral runner so that the controller would have more context
 // about the ephemeral runner that should not be deleted when scaling down.
 // It returns an error if there is any issue with updating the job information.
 func (w *Worker) HandleJobStarted(ctx context.Context, jobInfo *actions.JobStarted) error {
+	scaleSetAssignTime, runnerAssignTime := w.jobAssignTimes(jobInfo, time.Now())
+	delete(w.jobsAssignedAt, jobInfo.RunnerRequestId)
+
 	w.logger.Info("Updating job info for the runner",
 		"runnerName", jobInfo.RunnerName,
 		"ownerName", jobInfo.OwnerName,
@@ -110,17 +163,23 @@ func (w *Worker) HandleJobStarted(ctx context.Context, jobInfo *actions.JobStart
 		return fmt.Errorf("failed to marshal empty ephemeral runner: %w", err)
 	}
 
-	patch, err := json.Marshal(
-		&v1alpha1.EphemeralRunner{
-			Status: v1alpha1.EphemeralRunnerStatus{
-				JobRequestId:      jobInfo.RunnerRequestId,
-				JobRepositoryName: fmt.Sprintf("%s/%s", jobInfo.OwnerName, jobInfo.RepositoryName),
-				WorkflowRunId:     jobInfo.WorkflowRunId,
-				JobWorkflowRef:    jobInfo.JobWorkflowRef,
-				JobDisplayName:    jobInfo.JobDisplayName,
-			},
-		},
-	)
+	status := v1alpha1.EphemeralRunnerStatus{
+		JobRequestId:      jobInfo.RunnerRequestId,
+		JobRepositoryName: fmt.Sprintf("%s/%s", jobInfo.OwnerName, jobInfo.RepositoryName),
+		WorkflowRunId:     jobInfo.WorkflowRunId,
+		JobWorkflowRef:    jobInfo.JobWorkflowRef,
+		JobDisplayName:    jobInfo.JobDisplayName,
+	}
+	if !jobInfo.QueueTime.IsZero() {
+		status.JobQueueTime = &metav1.Time{Time: jobInfo.QueueTime}
+	}
+	// The controller measures the job queue latency SLO of the scale set from these
+	if !scaleSetAssignTime.IsZero() {
+		status.JobScaleSetAssignTime = &metav1.Time{Time: scaleSetAssignTime}
+		status.JobRunnerAssignTime = &metav1.Time{Time: runnerAssignTime}
+	}
+
+	patch, err := json.Marshal(&v1alpha1.EphemeralRunner{Status: status})
 	if err != nil {
 		return fmt.Errorf("failed to marshal ephemeral runner patch: %w", err)
 	}
@@ -156,6 +215,109 @@ func (w *Worker) HandleJobStarted(ctx context.Context, jobInfo *actions.JobStart
 	return nil
 }
 
+// jobAssignTimes returns the times the started job was assigned to the scale set and to its runner.
+// The assignment to the scale set is taken from the JobAssigned message of the job, and from the JobStarted message
+// when the listener didn't receive the former, like after it restarted. It's zero when neither has it.
+// The assignment to the runner defaults to now when the JobStarted message doesn't have it.
+func (w *Worker) jobAssignTimes(jobInfo *actions.JobStarted, now time.Time) (time.Time, time.Time) {
+	scaleSetAssignTime, ok := w.jobsAssignedAt[jobInfo.RunnerRequestId]
+	if !ok {
+		scaleSetAssignTime = jobInfo.ScaleSetAssignTime
+	}
+
+	runnerAssignTime := jobInfo.RunnerAssignTime
+	if runnerAssignTime.IsZero() {
+		runnerAssignTime = now
+	}
+
+	return scaleSetAssignTime, runnerAssignTime
+}
+
+// HandleJobCompleted counts the completed and the failed jobs of the canary runners of the ephemeral runner set
+// in its annotations. The jobs of the other runners are ignored.
+func (w *Worker) HandleJobCompleted(ctx context.Context, jobInfo *actions.JobCompleted) error {
+	// The job may have been cancelled before it started
+	delete(w.jobsAssignedAt, jobInfo.RunnerRequestId)
+
+	if !strings.HasPrefix(jobInfo.RunnerName, w.config.EphemeralRunnerSetName+canaryRunnerNameInfix) {
+		return nil
+	}
+
+	ephemeralRunnerSet := &v1alpha1.EphemeralRunnerSet{}
+	err := w.clientset.RESTClient().
+		Get().
+		Prefix("apis", v1alpha1.GroupVersion.Group, v1alpha1.GroupVersion.Version).
+		Namespace(w.config.EphemeralRunnerSetNamespace).
+		Resource("ephemeralrunnersets").
+		Name(w.config.EphemeralRunnerSetName).
+		Do(ctx).
+		Into(ephemeralRunnerSet)
+	if err != nil {
+		return fmt.Errorf("could not get ephemeral runner set: %w", err)
+	}
+
+	annotations := canaryJobAnnotations(ephemeralRunnerSet, jobInfo)
+	if annotations == nil {
+		w.logger.Info("Ephemeral runner set has no canary, skipping counting the job of the canary runner", "runnerName", jobInfo.RunnerName)
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal ephemeral runner set patch: %w", err)
+	}
+
+	err = w.clientset.RESTClient().
+		Patch(types.MergePatchType).
+		Prefix("apis", v1alpha1.GroupVersion.Group, v1alpha1.GroupVersion.Version).
+		Namespace(w.config.EphemeralRunnerSetNamespace).
+		Resource("ephemeralrunnersets").
+		Name(w.config.EphemeralRunnerSetName).
+		Body(patch).
+		Do(ctx).
+		Error()
+	if err != nil {
+		return fmt.Errorf("could not annotate ephemeral runner set with the canary jobs: %w", err)
+	}
+
+	w.logger.Info("Counted the job of the canary runner",
+		"runnerName", jobInfo.RunnerName,
+		"result", jobInfo.Result,
+		"completedJobs", annotations[annotationKeyCanaryCompletedJobs],
+		"failedJobs", annotations[annotationKeyCanaryFailedJobs])
+	return nil
+}
+
+// canaryJobAnnotations returns the annotations of the ephemeral runner set counting the completed job of a canary runner,
+// or nil when the ephemeral runner set has no canary. The counts restart when the canary changes.
+func canaryJobAnnotations(ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet, jobInfo *actions.JobCompleted) map[string]string {
+	canary := ephemeralRunnerSet.Spec.Canary
+	if canary == nil {
+		return nil
+	}
+
+	var completed, failed int
+	if ephemeralRunnerSet.Annotations[annotationKeyCanarySpecHash] == canary.SpecHash {
+		completed, _ = strconv.Atoi(ephemeralRunnerSet.Annotations[annotationKeyCanaryCompletedJobs])
+		failed, _ = strconv.Atoi(ephemeralRunnerSet.Annotations[annotationKeyCanaryFailedJobs])
+	}
+
+	completed++
+	if strings.EqualFold(jobInfo.Result, "failed") {
+		failed++
+	}
+
+	return map[string]string{
+		annotationKeyCanarySpecHash:      canary.SpecHash,
+		annotationKeyCanaryCompletedJobs: strconv.Itoa(completed),
+		annotationKeyCanaryFailedJobs:    strconv.Itoa(failed),
+	}
+}
+
 // HandleDesiredRunnerCount handles the desired runner count by scaling the ephemeral runner set.
 // The function calculates the target runner count based on the minimum and maximum runner count configuration.
 // If the target runner count is the same as the last patched count, it skips patching and returns nil.
@@ -255,3 +417,117 @@ func (w *Worker) setDesiredWorkerState(count, jobsCompleted int) int {
 
 	return desiredPatchID
 }
+
+// PublishSessionID annotates the ephemeral runner set with the ID of the message session,
+// for the listeners of the other shards to acquire jobs with.
+func (w *Worker) PublishSessionID(ctx context.Context, sessionID uuid.UUID) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{
+				annotationKeyListenerSessionID: sessionID.String(),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal ephemeral runner set patch: %w", err)
+	}
+
+	err = w.clientset.RESTClient().
+		Patch(types.MergePatchType).
+		Prefix("apis", v1alpha1.GroupVersion.Group, v1alpha1.GroupVersion.Version).
+		Namespace(w.config.EphemeralRunnerSetNamespace).
+		Resource("ephemeralrunnersets").
+		Name(w.config.EphemeralRunnerSetName).
+		Body(patch).
+		Do(ctx).
+		Error()
+	if err != nil {
+		return fmt.Errorf("could not annotate ephemeral runner set with the message session: %w", err)
+	}
+
+	w.logger.Info("Shared the message session with the other shards", "sessionId", sessionID.String())
+	return nil
+}
+
+// SessionID returns the ID of the message session annotated on the ephemeral runner set by the listener of the first shard,
+// or nil when there is none.
+func (w *Worker) SessionID(ctx context.Context) (*uuid.UUID, error) {
+	ephemeralRunnerSet := &v1alpha1.EphemeralRunnerSet{}
+	err := w.clientset.RESTClient().
+		Get().
+		Prefix("apis", v1alpha1.GroupVersion.Group, v1alpha1.GroupVersion.Version).
+		Namespace(w.config.EphemeralRunnerSetNamespace).
+		Resource("ephemeralrunnersets").
+		Name(w.config.EphemeralRunnerSetName).
+		Do(ctx).
+		Into(ephemeralRunnerSet)
+	if err != nil {
+		return nil, fmt.Errorf("could not get ephemeral runner set: %w", err)
+	}
+
+	value, ok := ephemeralRunnerSet.Annotations[annotationKeyListenerSessionID]
+	if !ok {
+		return nil, nil
+	}
+
+	sessionID, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message session ID %q: %w", value, err)
+	}
+	return &sessionID, nil
+}
+
+// SaveSession persists the message session in the session secret, for the replacement listener to resume it.
+func (w *Worker) SaveSession(ctx context.Context, state *listener.SessionState) error {
+	secrets := w.clientset.CoreV1().Secrets(w.config.EphemeralRunnerSetNamespace)
+	secret, err := secrets.Get(ctx, w.config.SessionSecretName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not get session secret: %w", err)
+	}
+
+	secret.Data = map[string][]byte{
+		sessionSecretKeyScaleSetID:    []byte(strconv.Itoa(state.ScaleSetID)),
+		sessionSecretKeySessionID:     []byte(state.SessionID.String()),
+		sessionSecretKeyLastMessageID: []byte(strconv.FormatInt(state.LastMessageID, 10)),
+	}
+	if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update session secret: %w", err)
+	}
+	return nil
+}
+
+// LoadSession returns the message session persisted in the session secret by the previous listener, or nil when there is none.
+func (w *Worker) LoadSession(ctx context.Context) (*listener.SessionState, error) {
+	secret, err := w.clientset.CoreV1().Secrets(w.config.EphemeralRunnerSetNamespace).Get(ctx, w.config.SessionSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get session secret: %w", err)
+	}
+
+	return parseSessionState(secret.Data)
+}
+
+// parseSessionState parses the message session persisted in the data of the session secret, or returns nil when there is none.
+func parseSessionState(data map[string][]byte) (*listener.SessionState, error) {
+	if len(data[sessionSecretKeySessionID]) == 0 {
+		return nil, nil
+	}
+
+	scaleSetID, err := strconv.Atoi(string(data[sessionSecretKeyScaleSetID]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid scale set ID in session secret: %w", err)
+	}
+	sessionID, err := uuid.Parse(string(data[sessionSecretKeySessionID]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid session ID in session secret: %w", err)
+	}
+	lastMessageID, err := strconv.ParseInt(string(data[sessionSecretKeyLastMessageID]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid last message ID in session secret: %w", err)
+	}
+
+	return &listener.SessionState{
+		ScaleSetID:    scaleSetID,
+		SessionID:     sessionID,
+		LastMessageID: lastMessageID,
+	}, nil
+}
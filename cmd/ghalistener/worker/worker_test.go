@@ -1,11 +1,19 @@
 package worker
 
 import (
+	"context"
 	"math"
 	"testing"
+	"time"
 
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/cmd/ghalistener/listener"
+	"github.com/actions/actions-runner-controller/github/actions"
 	"github.com/go-logr/logr"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestSetDesiredWorkerState_MinMaxDefaults(t *testing.T) {
@@ -324,3 +332,98 @@ func TestSetDesiredWorkerState_MinMaxSet(t *testing.T) {
 		assert.Equal(t, 2, w.patchSeq)
 	})
 }
+
+func TestParseSessionState(t *testing.T) {
+	state, err := parseSessionState(nil)
+	require.NoError(t, err)
+	assert.Nil(t, state, "the session secret is empty until the first listener saves its session")
+
+	sessionID := uuid.New()
+	state, err = parseSessionState(map[string][]byte{
+		sessionSecretKeyScaleSetID:    []byte("3"),
+		sessionSecretKeySessionID:     []byte(sessionID.String()),
+		sessionSecretKeyLastMessageID: []byte("42"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &listener.SessionState{ScaleSetID: 3, SessionID: sessionID, LastMessageID: 42}, state)
+
+	_, err = parseSessionState(map[string][]byte{
+		sessionSecretKeyScaleSetID: []byte("3"),
+		sessionSecretKeySessionID:  []byte("not-a-uuid"),
+	})
+	assert.Error(t, err)
+}
+
+func TestCanaryJobAnnotations(t *testing.T) {
+	ephemeralRunnerSet := &v1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				annotationKeyCanarySpecHash:      "abc",
+				annotationKeyCanaryCompletedJobs: "3",
+				annotationKeyCanaryFailedJobs:    "1",
+			},
+		},
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			Canary: &v1alpha1.EphemeralRunnerSetCanary{Image: "runner:new", Percentage: 10, SpecHash: "abc"},
+		},
+	}
+
+	assert.Equal(t, map[string]string{
+		annotationKeyCanarySpecHash:      "abc",
+		annotationKeyCanaryCompletedJobs: "4",
+		annotationKeyCanaryFailedJobs:    "2",
+	}, canaryJobAnnotations(ephemeralRunnerSet, &actions.JobCompleted{Result: "failed"}))
+
+	assert.Equal(t, map[string]string{
+		annotationKeyCanarySpecHash:      "abc",
+		annotationKeyCanaryCompletedJobs: "4",
+		annotationKeyCanaryFailedJobs:    "1",
+	}, canaryJobAnnotations(ephemeralRunnerSet, &actions.JobCompleted{Result: "canceled"}))
+
+	ephemeralRunnerSet.Spec.Canary.SpecHash = "def"
+	assert.Equal(t, map[string]string{
+		annotationKeyCanarySpecHash:      "def",
+		annotationKeyCanaryCompletedJobs: "1",
+		annotationKeyCanaryFailedJobs:    "0",
+	}, canaryJobAnnotations(ephemeralRunnerSet, &actions.JobCompleted{Result: "succeeded"}), "the counts restart with a new canary")
+
+	ephemeralRunnerSet.Spec.Canary = nil
+	assert.Nil(t, canaryJobAnnotations(ephemeralRunnerSet, &actions.JobCompleted{Result: "failed"}))
+}
+
+func TestJobAssignTimes(t *testing.T) {
+	ctx := context.Background()
+	logger := logr.Discard()
+	w := &Worker{logger: &logger}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assignedAt := now.Add(-3 * time.Minute)
+	startedAt := now.Add(-time.Minute)
+
+	started := func(id int64, base actions.JobMessageBase) *actions.JobStarted {
+		base.RunnerRequestId = id
+		return &actions.JobStarted{JobMessageBase: base}
+	}
+
+	// The job was queued long before it was assigned to the scale set, like while waiting for an environment approval
+	require.NoError(t, w.HandleJobAssigned(ctx, &actions.JobAssigned{JobMessageBase: actions.JobMessageBase{
+		RunnerRequestId:    1,
+		QueueTime:          now.Add(-time.Hour),
+		ScaleSetAssignTime: assignedAt,
+	}}))
+
+	scaleSetAssignTime, runnerAssignTime := w.jobAssignTimes(started(1, actions.JobMessageBase{RunnerAssignTime: startedAt}), now)
+	assert.Equal(t, assignedAt, scaleSetAssignTime)
+	assert.Equal(t, startedAt, runnerAssignTime)
+
+	// The JobAssigned message wasn't received, like when the listener restarted in between
+	scaleSetAssignTime, runnerAssignTime = w.jobAssignTimes(started(2, actions.JobMessageBase{ScaleSetAssignTime: assignedAt}), now)
+	assert.Equal(t, assignedAt, scaleSetAssignTime)
+	assert.Equal(t, now, runnerAssignTime)
+
+	scaleSetAssignTime, _ = w.jobAssignTimes(started(3, actions.JobMessageBase{}), now)
+	assert.True(t, scaleSetAssignTime.IsZero())
+
+	require.NoError(t, w.HandleJobCompleted(ctx, &actions.JobCompleted{JobMessageBase: actions.JobMessageBase{RunnerRequestId: 1}, Result: "canceled"}))
+	assert.Empty(t, w.jobsAssignedAt)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/actions/actions-runner-controller/logging"
+	"github.com/actions/actions-runner-controller/pkg/githubapiproxy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func main() {
+	var (
+		addr        string
+		metricsAddr string
+		upstreams   string
+		cacheSize   int
+
+		tlsCertFile string
+		tlsKeyFile  string
+
+		logLevel  string
+		logFormat string
+	)
+
+	flag.StringVar(&addr, "addr", githubapiproxy.DefaultAddr, "The address the proxy binds to.")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "", "The path of the PEM certificate the proxy is served with. The proxy only serves HTTPS, as it's sent the credentials of its clients.")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", "", "The path of the PEM private key of --tls-cert-file.")
+	flag.StringVar(&metricsAddr, "metrics-addr", ":8081", "The address the metric endpoint binds to. Set to empty to disable.")
+	flag.StringVar(&upstreams, "upstreams", githubapiproxy.DefaultUpstream, "Comma-separated base URLs of the GitHub APIs the proxy forwards to, like https://api.github.com,https://ghes.example.com. Requests to any other host are rejected.")
+	flag.IntVar(&cacheSize, "cache-size", githubapiproxy.DefaultCacheSize, "The maximum number of responses cached for revalidation. Set to a negative value to disable caching.")
+	flag.StringVar(&logLevel, "log-level", logging.LogLevelDebug, `The verbosity of the logging. Valid values are "debug", "info", "warn", "error". Defaults to "debug".`)
+	flag.StringVar(&logFormat, "log-format", "text", `The log format. Valid options are "text" and "json". Defaults to "text"`)
+	flag.Parse()
+
+	logger, err := logging.NewLogger(logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: creating logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	if tlsCertFile == "" || tlsKeyFile == "" {
+		logger.Error(errors.New("--tls-cert-file and --tls-key-file are required"), "invalid TLS configuration")
+		os.Exit(1)
+	}
+
+	proxy, err := githubapiproxy.New(githubapiproxy.Options{
+		Upstreams: strings.Split(upstreams, ","),
+		CacheSize: cacheSize,
+		Log:       logger.WithName("githubapiproxy"),
+	})
+	if err != nil {
+		logger.Error(err, "unable to create the proxy")
+		os.Exit(1)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(githubapiproxy.Collectors()...)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	serve := func(name string, srv *http.Server, listenAndServe func() error) {
+		wg.Add(1)
+		go func() {
+			defer cancel()
+			defer wg.Done()
+
+			go func() {
+				<-ctx.Done()
+
+				shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer shutdownCancel()
+
+				srv.Shutdown(shutdownCtx)
+			}()
+
+			logger.Info("Starting "+name, "addr", srv.Addr)
+
+			if err := listenAndServe(); err != nil {
+				if !errors.Is(err, http.ErrServerClosed) {
+					logger.Error(err, "problem running "+name)
+				}
+			}
+		}()
+	}
+
+	if metricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorHandling: promhttp.HTTPErrorOnError,
+		}))
+
+		srv := &http.Server{
+			Addr:    metricsAddr,
+			Handler: metricsMux,
+		}
+		serve("metrics server", srv, srv.ListenAndServe)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", proxy)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serve("GitHub API proxy", srv, func() error {
+		return srv.ListenAndServeTLS(tlsCertFile, tlsKeyFile)
+	})
+
+	wg.Wait()
+}
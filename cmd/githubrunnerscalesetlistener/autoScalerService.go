@@ -17,6 +17,8 @@ type ScaleSettings struct {
 	ResourceName string
 	MinRunners   int
 	MaxRunners   int
+	// AllowedRepositories are the full names, owner/name, of the repositories whose jobs are acquired. Defaults to all the repositories.
+	AllowedRepositories []string
 }
 
 type Service struct {
@@ -148,6 +150,16 @@ func (s *Service) processMessage(message *actions.RunnerScaleSetMessage) error {
 				"RequestId",
 				jobAvailable.RunnerRequestId,
 			)
+			if !s.repositoryAllowed(jobAvailable.OwnerName, jobAvailable.RepositoryName) {
+				s.logger.Info(
+					"refusing to acquire job of a repository that isn't allowed.",
+					"RequestId",
+					jobAvailable.RunnerRequestId,
+					"Repository",
+					jobAvailable.OwnerName+"/"+jobAvailable.RepositoryName,
+				)
+				continue
+			}
 			availableJobs = append(availableJobs, jobAvailable.RunnerRequestId)
 		case "JobAssigned":
 			var jobAssigned actions.JobAssigned
@@ -244,3 +256,18 @@ func (s *Service) updateJobInfoForRunner(jobInfo actions.JobStarted) {
 		s.logger.Error(err, "could not update ephemeral runner with job info", "runnerName", jobInfo.RunnerName, "requestId", jobInfo.RunnerRequestId)
 	}
 }
+
+// repositoryAllowed returns true when the jobs of the repository of the owner can be acquired.
+func (s *Service) repositoryAllowed(owner, repository string) bool {
+	if len(s.settings.AllowedRepositories) == 0 {
+		return true
+	}
+
+	for _, r := range s.settings.AllowedRepositories {
+		if strings.EqualFold(r, owner+"/"+repository) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 )
 
 type Config struct {
@@ -18,11 +21,38 @@ type Config struct {
 	MinRunners                  int    `json:"minRunners"`
 	RunnerScaleSetId            int    `json:"runnerScaleSetId"`
 	RunnerScaleSetName          string `json:"runnerScaleSetName"`
+	RunnerGroup                 string `json:"runnerGroup,omitempty"`
 	ServerRootCA                string `json:"serverRootCA"`
-	LogLevel                    string `json:"logLevel"`
-	LogFormat                   string `json:"logFormat"`
-	MetricsAddr                 string `json:"metricsAddr"`
-	MetricsEndpoint             string `json:"metricsEndpoint"`
+	// ServerClientCertificate and ServerClientKey are the PEM client certificate presented to GitHub and its private key, if any.
+	ServerClientCertificate string `json:"serverClientCertificate,omitempty"`
+	ServerClientKey         string `json:"serverClientKey,omitempty"`
+	LogLevel                string `json:"logLevel"`
+	LogFormat               string `json:"logFormat"`
+	MetricsAddr             string `json:"metricsAddr"`
+	MetricsEndpoint         string `json:"metricsEndpoint"`
+	// AdminTokenPath is the path of the Actions service admin token shared by the controller.
+	// The listener mints its own token when it's missing or about to expire.
+	AdminTokenPath string `json:"adminTokenPath,omitempty"`
+	// GitHubAPIProxyURL is the https URL of the GitHub API proxy shared with the controller, if any.
+	GitHubAPIProxyURL string `json:"gitHubAPIProxyURL,omitempty"`
+	// GitHubAPIProxyCACert is the PEM bundle of the CAs the certificate of the GitHub API proxy is pinned to.
+	GitHubAPIProxyCACert string `json:"gitHubAPIProxyCACert,omitempty"`
+	// RequestCoordinatorURL is the URL of the request coordinator of the controller the listener paces its requests with, if any.
+	RequestCoordinatorURL string `json:"requestCoordinatorURL,omitempty"`
+	// AllowedRepositories are the full names, owner/name, of the repositories whose jobs the listener acquires. Defaults to all the repositories.
+	AllowedRepositories []string `json:"allowedRepositories,omitempty"`
+	// AdmissionWindows are the recurring periods during which the listener acquires jobs. Defaults to any time.
+	// They're enforced by ghalistener only.
+	AdmissionWindows []v1alpha1.AdmissionWindow `json:"admissionWindows,omitempty"`
+	// OutsideAdmissionWindows is what the listener does with the jobs available outside of AdmissionWindows, Leave or Hold.
+	OutsideAdmissionWindows string `json:"outsideAdmissionWindows,omitempty"`
+	// ShardIndex is the partition of the jobs acquired by the listener, out of ShardCount.
+	// Sharding is supported by ghalistener only.
+	ShardIndex int `json:"shardIndex,omitempty"`
+	ShardCount int `json:"shardCount,omitempty"`
+	// SessionSecretName is the secret, in the namespace of the ephemeral runner set, the listener hands its message session off through.
+	// The session handoff is supported by ghalistener only.
+	SessionSecretName string `json:"sessionSecretName,omitempty"`
 }
 
 func Read(path string) (Config, error) {
@@ -72,5 +102,11 @@ func (c *Config) validate() error {
 		return fmt.Errorf("only one GitHub auth method supported at a time. Have both PAT and App auth: token length: '%d', appId: '%d', installationId: '%d', private key length: '%d", len(c.Token), c.AppID, c.AppInstallationID, len(c.AppPrivateKey))
 	}
 
+	for _, r := range c.AllowedRepositories {
+		if owner, name, ok := strings.Cut(r, "/"); !ok || owner == "" || name == "" {
+			return fmt.Errorf("AllowedRepositories '%s' is invalid: it must be the full name of a repository, owner/name", r)
+		}
+	}
+
 	return nil
 }
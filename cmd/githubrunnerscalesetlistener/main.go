@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"net/http"
@@ -31,6 +32,7 @@ import (
 	"github.com/actions/actions-runner-controller/cmd/githubrunnerscalesetlistener/config"
 	"github.com/actions/actions-runner-controller/github/actions"
 	"github.com/actions/actions-runner-controller/logging"
+	"github.com/actions/actions-runner-controller/pkg/githubapiproxy"
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -201,6 +203,8 @@ func run(ctx context.Context, rc config.Config, logger logr.Logger, opts runOpti
 		ResourceName: rc.EphemeralRunnerSetName,
 		MaxRunners:   rc.MaxRunners,
 		MinRunners:   rc.MinRunners,
+
+		AllowedRepositories: rc.AllowedRepositories,
 	}
 
 	service, err := NewService(ctx, autoScalerClient, kubeManager, scaleSettings, opts.serviceOptions...)
@@ -230,11 +234,50 @@ func newActionsClientFromConfig(config config.Config, creds *actions.ActionsAuth
 		options = append(options, actions.WithRootCAs(pool))
 	}
 
+	if config.ServerClientCertificate != "" {
+		cert, err := tls.X509KeyPair([]byte(config.ServerClientCertificate), []byte(config.ServerClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+
+		options = append(options, actions.WithClientCertificate(cert))
+	}
+
 	proxyFunc := httpproxy.FromEnvironment().ProxyFunc()
 	options = append(options, actions.WithProxy(func(req *http.Request) (*url.URL, error) {
 		return proxyFunc(req.URL)
 	}))
 
+	if config.AdminTokenPath != "" {
+		options = append(options, actions.WithAdminTokenSource(actions.AdminTokenFromFile(config.AdminTokenPath)))
+	}
+
+	if config.GitHubAPIProxyURL != "" {
+		proxyURL, err := url.Parse(config.GitHubAPIProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GitHub API proxy URL: %w", err)
+		}
+		caCerts, err := githubapiproxy.CertPool([]byte(config.GitHubAPIProxyCACert))
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, actions.WithGitHubAPIProxy(proxyURL, caCerts))
+	}
+
+	if config.RequestCoordinatorURL != "" {
+		coordinator, err := actions.NewRemoteRequestCoordinator(
+			config.RequestCoordinatorURL,
+			config.EphemeralRunnerSetNamespace+"/"+config.RunnerScaleSetName,
+			logr.Discard(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse request coordinator URL: %w", err)
+		}
+
+		options = append(options, actions.WithRequestCoordinator(coordinator))
+	}
+
 	return actions.NewClient(config.ConfigureUrl, creds, options...)
 }
 
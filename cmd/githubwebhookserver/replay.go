@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	actionssummerwindnet "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net"
+)
+
+const replayUsage = `Usage: github-webhook-server replay (--delivery-id <id> --hook-id <id> (--repository <owner/name> | --organization <org>) | --event <type> --payload-file <path>) [flags]
+
+Makes a running webhook server re-process a webhook delivery, to recover from missed deliveries or to test
+scale triggers with recorded payloads. The delivery is either fetched from GitHub by its ID, using the GitHub API
+credentials of the server, or read from a payload file. The server must be started with --replay-token.
+
+Flags:
+`
+
+// replay sends a delivery to the replay endpoint of a running webhook server.
+func replay(args []string) error {
+	var (
+		server      string
+		token       string
+		path        string
+		timeout     time.Duration
+		payloadFile string
+
+		req actionssummerwindnet.WebhookReplayRequest
+	)
+
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), replayUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&server, "server", "http://localhost:8080", "The URL of the metrics server of the webhook server.")
+	fs.StringVar(&token, "token", "", "The --replay-token of the webhook server. Defaults to $"+webhookReplayTokenEnvName+".")
+	fs.StringVar(&path, "path", "/", "The path of the webhook server the delivery was sent to, which selects the WebhookAutoscalerConfig it's handled with.")
+	fs.DurationVar(&timeout, "timeout", time.Minute, "The timeout of the replay.")
+	fs.Int64Var(&req.DeliveryID, "delivery-id", 0, "The ID of the delivery to fetch from GitHub, as listed in the Recent Deliveries of the webhook.")
+	fs.Int64Var(&req.HookID, "hook-id", 0, "The ID of the webhook the delivery was sent by.")
+	fs.StringVar(&req.Repository, "repository", "", "The owner/name of the repository of the webhook.")
+	fs.StringVar(&req.Organization, "organization", "", "The organization of the webhook.")
+	fs.StringVar(&req.Event, "event", "", `The type of the event of the payload file, like "workflow_job".`)
+	fs.StringVar(&payloadFile, "payload-file", "", `The file of the payload to replay, or "-" for stdin.`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if token == "" {
+		token = os.Getenv(webhookReplayTokenEnvName)
+	}
+
+	if token == "" {
+		return fmt.Errorf("--token or %s is required", webhookReplayTokenEnvName)
+	}
+
+	if (req.DeliveryID == 0) == (payloadFile == "") {
+		fs.Usage()
+		return errors.New("exactly one of --delivery-id and --payload-file is required")
+	}
+
+	if payloadFile != "" {
+		if req.Event == "" {
+			return errors.New("--event is required along with --payload-file")
+		}
+
+		var (
+			payload []byte
+			err     error
+		)
+		if payloadFile == "-" {
+			payload, err = io.ReadAll(os.Stdin)
+		} else {
+			payload, err = os.ReadFile(payloadFile)
+		}
+		if err != nil {
+			return fmt.Errorf("reading payload: %w", err)
+		}
+
+		if !json.Valid(payload) {
+			return fmt.Errorf("payload in %s isn't valid JSON", payloadFile)
+		}
+
+		req.Payload = payload
+	}
+
+	req.Path = path
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(server, "/")+actionssummerwindnet.ReplayPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("sending replay request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("replay failed with %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var res actionssummerwindnet.WebhookReplayResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("decoding replay response: %w", err)
+	}
+
+	msg := res.Message
+	if msg == "" {
+		msg = "ignored"
+	}
+
+	if res.GUID != "" {
+		fmt.Printf("Replayed %s delivery %s: %s\n", res.Event, res.GUID, msg)
+	} else {
+		fmt.Printf("Replayed %s payload: %s\n", res.Event, msg)
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	actionssummerwindnet "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const explainScaleUsage = `Usage: kubectl arc explain-scale [hra/]<name> [flags]
+
+Shows how the actions-runner-controller computed the latest desired replicas of a HorizontalRunnerAutoscaler:
+the inputs of its metrics, the thresholds and factors applied to them, and every adjustment made afterwards,
+like capacity reservations, scheduled overrides and the clamping to minReplicas and maxReplicas.
+
+The explanation is read from the metrics server of the leader controller pod through a port-forward,
+so it's available only for the decisions made since the leader started.
+
+Flags:
+`
+
+func explainScale(args []string) error {
+	var (
+		namespace  string
+		output     string
+		kubeconfig string
+
+		controllerNamespace string
+		leaderElectionID    string
+		pod                 string
+		metricsPort         int
+	)
+
+	fs := flag.NewFlagSet("explain-scale", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), explainScaleUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&namespace, "namespace", "", "The namespace of the HorizontalRunnerAutoscaler. Defaults to the namespace of the current context.")
+	fs.StringVar(&output, "o", "", `The output format. Either empty for a human-readable explanation or "json".`)
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "The path to the kubeconfig. Defaults to $KUBECONFIG or ~/.kube/config.")
+	fs.StringVar(&controllerNamespace, "controller-namespace", "actions-runner-system", "The namespace of the actions-runner-controller.")
+	fs.StringVar(&leaderElectionID, "leader-election-id", "actions-runner-controller", "The leader election id of the actions-runner-controller, which is the name of its lease.")
+	fs.StringVar(&pod, "pod", "", "The name of the controller pod to read the explanation from. Defaults to the current leader.")
+	fs.IntVar(&metricsPort, "metrics-port", 8080, "The port the metrics server of the controller listens on within the pod. It's 8080 when the kube-rbac-proxy is enabled, and metrics.port otherwise.")
+
+	// Accept the flags after the name too, as kubectl does
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+	if name == "" && fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "hra/"), "horizontalrunnerautoscaler/")
+	if name == "" || strings.Contains(name, "/") {
+		fs.Usage()
+		return fmt.Errorf("the name of a HorizontalRunnerAutoscaler is required, like hra/<name>")
+	}
+
+	if output != "" && output != "json" {
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("loading kubeconfig: %w", err)
+	}
+
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return fmt.Errorf("loading namespace from kubeconfig: %w", err)
+		}
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("creating clientset: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if pod == "" {
+		// Only the leader reconciles the HRAs, so it's the only pod having the explanations
+		lease, err := clientset.CoordinationV1().Leases(controllerNamespace).Get(ctx, leaderElectionID, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("getting leader election lease %s/%s: %w", controllerNamespace, leaderElectionID, err)
+		}
+
+		// The holder identity is "<pod name>_<uuid>"
+		if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+			return fmt.Errorf("leader election lease %s/%s has no holder", controllerNamespace, leaderElectionID)
+		}
+		pod, _, _ = strings.Cut(*lease.Spec.HolderIdentity, "_")
+	}
+
+	localPort, stop, err := portForward(config, clientset, controllerNamespace, pod, metricsPort)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	q := url.Values{}
+	q.Set("namespace", namespace)
+	q.Set("name", name)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d%s?%s", localPort, actionssummerwindnet.ScaleExplanationPath, q.Encode()), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting explanation from pod %s/%s: %w", controllerNamespace, pod, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("reading explanation: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("requesting explanation from pod %s/%s: %s: %s", controllerNamespace, pod, res.Status, strings.TrimSpace(string(body)))
+	}
+
+	if output == "json" {
+		_, err := os.Stdout.Write(body)
+		return err
+	}
+
+	var e actionssummerwindnet.ScaleExplanation
+	if err := json.Unmarshal(body, &e); err != nil {
+		return fmt.Errorf("parsing explanation: %w", err)
+	}
+
+	printScaleExplanation(os.Stdout, e)
+
+	return nil
+}
+
+// portForward forwards a random local port to the port of the pod, and returns the local port and the function to stop forwarding.
+func portForward(config *rest.Config, clientset *kubernetes.Clientset, namespace, pod string, port int) (int, func(), error) {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return 0, nil, fmt.Errorf("creating port-forward transport: %w", err)
+	}
+
+	u := clientset.CoreV1().RESTClient().Post().Resource("pods").Namespace(namespace).Name(pod).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, u)
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)}, stopCh, readyCh, io.Discard, os.Stderr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("port-forwarding to pod %s/%s: %w", namespace, pod, err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return 0, nil, fmt.Errorf("port-forwarding to pod %s/%s: %w", namespace, pod, err)
+	}
+
+	ports, err := fw.GetPorts()
+	if err != nil || len(ports) == 0 {
+		close(stopCh)
+		return 0, nil, fmt.Errorf("port-forwarding to pod %s/%s: no local port: %v", namespace, pod, err)
+	}
+
+	return int(ports[0].Local), func() { close(stopCh) }, nil
+}
+
+func printScaleExplanation(out io.Writer, e actionssummerwindnet.ScaleExplanation) {
+	fmt.Fprintf(out, "HorizontalRunnerAutoscaler: %s/%s\n", e.Namespace, e.Name)
+	fmt.Fprintf(out, "Computed at: %s\n", e.Time.Format(time.RFC3339))
+	fmt.Fprintf(out, "Scale target: %s/%s (%d replicas)\n", e.ScaleTargetKind, e.ScaleTargetName, e.CurrentReplicas)
+	if e.ScheduledOverride != "" {
+		fmt.Fprintf(out, "Min replicas: %d (scheduled override: %s)\n", e.MinReplicas, e.ScheduledOverride)
+	} else {
+		fmt.Fprintf(out, "Min replicas: %d\n", e.MinReplicas)
+	}
+	if e.MaxReplicas != nil {
+		fmt.Fprintf(out, "Max replicas: %d\n", *e.MaxReplicas)
+	}
+
+	if len(e.Metrics) > 0 {
+		fmt.Fprintln(out, "\nMetrics:")
+
+		for _, m := range e.Metrics {
+			switch {
+			case m.Error != "":
+				fmt.Fprintf(out, "  %s: failed: %s\n", m.Type, m.Error)
+			case m.SuggestedReplicas != nil:
+				fmt.Fprintf(out, "  %s: suggested %d replicas\n", m.Type, *m.SuggestedReplicas)
+			default:
+				fmt.Fprintf(out, "  %s\n", m.Type)
+			}
+
+			keys := make([]string, 0, len(m.Inputs))
+			for k := range m.Inputs {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			for _, k := range keys {
+				fmt.Fprintf(tw, "    %s:\t%v\n", k, m.Inputs[k])
+			}
+			tw.Flush()
+		}
+	}
+
+	if len(e.Steps) > 0 {
+		fmt.Fprintln(out, "\nSteps:")
+
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "  STEP\tREPLICAS\tDETAIL")
+		for _, s := range e.Steps {
+			fmt.Fprintf(tw, "  %s\t%d\t%s\n", s.Name, s.Replicas, s.Detail)
+		}
+		tw.Flush()
+	}
+
+	fmt.Fprintln(out)
+
+	if e.Error != "" {
+		fmt.Fprintf(out, "Error: %s\n", e.Error)
+		return
+	}
+
+	if e.DryRun {
+		fmt.Fprintf(out, "Desired replicas: %d (dry run, not applied)\n", e.DesiredReplicas)
+	} else {
+		fmt.Fprintf(out, "Desired replicas: %d\n", e.DesiredReplicas)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	actionsgithubcom "github.com/actions/actions-runner-controller/controllers/actions.github.com"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const logsUsage = `Usage: kubectl arc logs (--job <id> | --run <id>) [flags]
+
+Streams the logs of the runners of a workflow job or run in a namespace. The runners are looked up by the
+job request ID or the workflow run ID the listener assigned to them, and the logs of all of them are multiplexed,
+each line prefixed with the name of the runner.
+
+The logs are read from the API server with your own credentials, so you must be allowed to list the
+ephemeralrunners and to get the pods/log of the namespace.
+
+Flags:
+`
+
+func logs(args []string) error {
+	var (
+		job, run   int64
+		namespace  string
+		follow     bool
+		kubeconfig string
+	)
+
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), logsUsage)
+		fs.PrintDefaults()
+	}
+	fs.Int64Var(&job, "job", 0, "The job request ID of the workflow job.")
+	fs.Int64Var(&run, "run", 0, "The ID of the workflow run, to stream the logs of the runners of all its jobs.")
+	fs.StringVar(&namespace, "namespace", "", "The namespace of the runners. Defaults to the namespace of the current context.")
+	fs.BoolVar(&follow, "follow", false, "Keep streaming the logs until the runners exit.")
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "The path to the kubeconfig. Defaults to $KUBECONFIG or ~/.kube/config.")
+	fs.Parse(args)
+
+	if (job == 0) == (run == 0) {
+		fs.Usage()
+		return fmt.Errorf("exactly one of --job and --run is required")
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("loading kubeconfig: %w", err)
+	}
+
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return fmt.Errorf("loading namespace from kubeconfig: %w", err)
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return err
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("creating client: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("creating clientset: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	l := &runnerLogs{Client: c, Clientset: clientset}
+
+	runners, err := l.findRunners(ctx, namespace, job, run)
+	if err != nil {
+		return fmt.Errorf("listing runners in namespace %s: %w", namespace, err)
+	}
+
+	if len(runners) == 0 {
+		return fmt.Errorf("no runner found in namespace %s. The runner may not have been assigned the job yet, or already be deleted", namespace)
+	}
+
+	l.stream(ctx, os.Stdout, runners, follow)
+
+	return nil
+}
+
+// runnerLogs streams the logs of the runner containers of EphemeralRunners.
+type runnerLogs struct {
+	client.Client
+	Clientset kubernetes.Interface
+}
+
+// findRunners returns the runners of the namespace running the workflow job of the job request ID,
+// or the jobs of the workflow run of the run ID.
+// The IDs are in the status of the runners, which can't be selected by the API server.
+func (l *runnerLogs) findRunners(ctx context.Context, namespace string, job, run int64) ([]v1alpha1.EphemeralRunner, error) {
+	var list v1alpha1.EphemeralRunnerList
+	if err := l.List(ctx, &list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	var runners []v1alpha1.EphemeralRunner
+	for _, runner := range list.Items {
+		if (job != 0 && runner.Status.JobRequestId == job) || (run != 0 && runner.Status.WorkflowRunId == run) {
+			runners = append(runners, runner)
+		}
+	}
+
+	sort.Slice(runners, func(i, j int) bool {
+		return runners[i].Name < runners[j].Name
+	})
+
+	return runners, nil
+}
+
+// stream multiplexes the logs of the runners into out. The errors of a runner are written as its lines.
+func (l *runnerLogs) stream(ctx context.Context, out io.Writer, runners []v1alpha1.EphemeralRunner, follow bool) {
+	w := &lineWriter{w: out}
+
+	var wg sync.WaitGroup
+	for _, runner := range runners {
+		runner := runner
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := l.streamLogs(ctx, w, runner, follow); err != nil && ctx.Err() == nil {
+				w.writeLine(runner.Name, fmt.Sprintf("error streaming logs: %v", err))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// streamLogs copies the logs of the runner container of the runner's pod, which has the same name as the runner.
+func (l *runnerLogs) streamLogs(ctx context.Context, out *lineWriter, runner v1alpha1.EphemeralRunner, follow bool) error {
+	req := l.Clientset.CoreV1().Pods(runner.Namespace).GetLogs(runner.Name, &corev1.PodLogOptions{
+		Container: actionsgithubcom.EphemeralRunnerContainerName,
+		Follow:    follow,
+	})
+
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		out.writeLine(runner.Name, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// lineWriter serializes the lines written by the streams of multiple runners,
+// so that lines of different runners are never interleaved.
+type lineWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lineWriter) writeLine(runner, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(l.w, "[%s] %s\n", runner, line)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunnerLogs(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	runner := func(name, namespace string, jobRequestId, workflowRunId int64) *v1alpha1.EphemeralRunner {
+		return &v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status: v1alpha1.EphemeralRunnerStatus{
+				JobRequestId:  jobRequestId,
+				WorkflowRunId: workflowRunId,
+			},
+		}
+	}
+
+	pod := func(name string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"}}
+	}
+
+	l := &runnerLogs{
+		Client: crfake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(
+				runner("runner-b", "test-ns", 2, 100),
+				runner("runner-a", "test-ns", 1, 100),
+				runner("runner-c", "test-ns", 3, 200),
+				runner("runner-idle", "test-ns", 0, 0),
+				runner("runner-other", "other-ns", 1, 100),
+			).
+			Build(),
+		Clientset: k8sfake.NewSimpleClientset(pod("runner-a"), pod("runner-b"), pod("runner-c")),
+	}
+
+	t.Run("job", func(t *testing.T) {
+		runners, err := l.findRunners(ctx, "test-ns", 2, 0)
+		require.NoError(t, err)
+		require.Len(t, runners, 1)
+
+		var out bytes.Buffer
+		l.stream(ctx, &out, runners, false)
+		// The fake clientset returns "fake logs" for any pod
+		assert.Equal(t, "[runner-b] fake logs\n", out.String())
+	})
+
+	t.Run("run multiplexes the runners of all its jobs in the namespace", func(t *testing.T) {
+		runners, err := l.findRunners(ctx, "test-ns", 0, 100)
+		require.NoError(t, err)
+		require.Len(t, runners, 2)
+		assert.Equal(t, "runner-a", runners[0].Name)
+		assert.Equal(t, "runner-b", runners[1].Name)
+
+		var out bytes.Buffer
+		l.stream(ctx, &out, runners, true)
+		assert.Contains(t, out.String(), "[runner-a] fake logs\n")
+		assert.Contains(t, out.String(), "[runner-b] fake logs\n")
+	})
+
+	t.Run("no runner", func(t *testing.T) {
+		runners, err := l.findRunners(ctx, "test-ns", 4, 0)
+		require.NoError(t, err)
+		assert.Empty(t, runners)
+	})
+}
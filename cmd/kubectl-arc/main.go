@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-arc is a kubectl plugin for actions-runner-controller.
+// Put it in your PATH and run `kubectl arc logs --job <id>` to stream the logs of the runner of a workflow job,
+// or `kubectl arc explain-scale hra/<name>` to see how the latest desired replicas of a HorizontalRunnerAutoscaler were computed.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	_ "k8s.io/client-go/plugin/pkg/client/auth/exec"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+)
+
+const usage = `Usage:
+  kubectl arc logs (--job <id> | --run <id>) [flags]
+  kubectl arc explain-scale [hra/]<name> [flags]
+
+Run "kubectl arc <command> --help" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch os.Args[1] {
+	case "logs":
+		err = logs(os.Args[2:])
+	case "explain-scale":
+		err = explainScale(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
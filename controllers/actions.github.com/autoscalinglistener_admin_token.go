@@ -0,0 +1,173 @@
+package actionsgithubcom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// listenerAdminTokenMinValidity is how long the admin token shared with a listener must remain valid.
+	// The controller refreshes the token this long before it expires, so that the listener picks up
+	// the new token from its mounted secret instead of minting one itself.
+	listenerAdminTokenMinValidity = 10 * time.Minute
+
+	// listenerAdminTokenRetryInterval is how soon sharing the admin token is retried after a failure.
+	listenerAdminTokenRetryInterval = time.Minute
+
+	listenerAdminTokenKey        = "token.json"
+	listenerAdminTokenVolumeName = "listener-admin-token"
+	listenerAdminTokenMountPath  = "/etc/gha-listener-admin-token"
+)
+
+// reconcileAdminTokenSecret keeps the Actions service admin token of the scale set in a secret mounted by the listener,
+// and returns when it needs to be refreshed.
+func (r *AutoscalingListenerReconciler) reconcileAdminTokenSecret(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, autoscalingListener *v1alpha1.AutoscalingListener, configSecret *corev1.Secret, logger logr.Logger) (time.Duration, error) {
+	actionsClient, err := r.actionsClientFor(ctx, autoscalingRunnerSet, configSecret)
+	if err != nil {
+		return 0, err
+	}
+
+	provider, ok := actionsClient.(actions.AdminTokenProvider)
+	if !ok {
+		return 0, nil
+	}
+
+	token, err := provider.AdminToken(ctx, listenerAdminTokenMinValidity)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get admin token: %v", err)
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode admin token: %v", err)
+	}
+
+	requeueAfter := time.Until(token.ExpiresAt) - listenerAdminTokenMinValidity
+	if requeueAfter < listenerAdminTokenRetryInterval {
+		requeueAfter = listenerAdminTokenRetryInterval
+	}
+
+	secret := new(corev1.Secret)
+	err = r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: scaleSetListenerAdminTokenName(autoscalingListener)}, secret)
+	switch {
+	case kerrors.IsNotFound(err):
+		newSecret := r.ResourceBuilder.newScaleSetListenerAdminTokenSecret(autoscalingListener, data)
+		if err := ctrl.SetControllerReference(autoscalingListener, newSecret, r.Scheme); err != nil {
+			return 0, fmt.Errorf("failed to set controller reference: %v", err)
+		}
+
+		logger.Info("Creating listener admin token secret", "namespace", newSecret.Namespace, "name", newSecret.Name)
+		if err := r.Create(ctx, newSecret); err != nil {
+			return 0, fmt.Errorf("failed to create listener admin token secret: %v", err)
+		}
+	case err != nil:
+		return 0, fmt.Errorf("failed to get listener admin token secret: %v", err)
+	case !bytes.Equal(secret.Data[listenerAdminTokenKey], data):
+		updated := secret.DeepCopy()
+		updated.Data = map[string][]byte{listenerAdminTokenKey: data}
+
+		logger.Info("Updating listener admin token secret", "namespace", secret.Namespace, "name", secret.Name, "expiresAt", token.ExpiresAt)
+		if err := r.Update(ctx, updated); err != nil {
+			return 0, fmt.Errorf("failed to update listener admin token secret: %v", err)
+		}
+	}
+
+	return requeueAfter, nil
+}
+
+func (r *AutoscalingListenerReconciler) actionsClientFor(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, configSecret *corev1.Secret) (actions.ActionsService, error) {
+	opts, err := r.actionsClientOptionsFor(ctx, autoscalingRunnerSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get actions client options: %w", err)
+	}
+
+	return r.ActionsClient.GetClientFromSecret(
+		ctx,
+		autoscalingRunnerSet.Spec.GitHubConfigUrl,
+		autoscalingRunnerSet.Namespace,
+		configSecret.Data,
+		opts...,
+	)
+}
+
+func (r *AutoscalingListenerReconciler) actionsClientOptionsFor(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) ([]actions.ClientOption, error) {
+	var options []actions.ClientOption
+
+	if autoscalingRunnerSet.Spec.Proxy != nil {
+		proxyFunc, err := autoscalingRunnerSet.Spec.Proxy.ProxyFunc(func(s string) (*corev1.Secret, error) {
+			var secret corev1.Secret
+			err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingRunnerSet.Namespace, Name: s}, &secret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get proxy secret %s: %w", s, err)
+			}
+
+			return &secret, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get proxy func: %w", err)
+		}
+
+		options = append(options, actions.WithProxy(proxyFunc))
+	}
+
+	tlsConfig := autoscalingRunnerSet.Spec.GitHubServerTLS
+	if tlsConfig != nil {
+		pool, err := tlsConfig.ToCertPool(func(name, key string) ([]byte, error) {
+			var configmap corev1.ConfigMap
+			err := r.Get(
+				ctx,
+				types.NamespacedName{
+					Namespace: autoscalingRunnerSet.Namespace,
+					Name:      name,
+				},
+				&configmap,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get configmap %s: %w", name, err)
+			}
+
+			return []byte(configmap.Data[key]), nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tls config: %w", err)
+		}
+
+		options = append(options, actions.WithRootCAs(pool))
+
+		cert, err := tlsConfig.ToClientCertificate(func(name string) (*corev1.Secret, error) {
+			var secret corev1.Secret
+			err := r.Get(
+				ctx,
+				types.NamespacedName{
+					Namespace: autoscalingRunnerSet.Namespace,
+					Name:      name,
+				},
+				&secret,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+			}
+
+			return &secret, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tls client certificate: %w", err)
+		}
+		if cert != nil {
+			options = append(options, actions.WithClientCertificate(*cert))
+		}
+	}
+
+	return options, nil
+}
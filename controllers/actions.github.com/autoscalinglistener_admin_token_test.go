@@ -0,0 +1,151 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/actions/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type adminTokenClient struct {
+	actions.ActionsService
+	token       actions.ActionsServiceAdminToken
+	minValidity time.Duration
+}
+
+func (c *adminTokenClient) AdminToken(_ context.Context, minValidity time.Duration) (*actions.ActionsServiceAdminToken, error) {
+	c.minValidity = minValidity
+	token := c.token
+	return &token, nil
+}
+
+func TestReconcileAdminTokenSecret(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-scale-set", Namespace: "test-ns"},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl:    "https://github.com/owner/repo",
+			GitHubConfigSecret: "github-config",
+		},
+	}
+	listener := &v1alpha1.AutoscalingListener{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-listener", Namespace: "arc-system", UID: "listener-uid"},
+	}
+	configSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-config", Namespace: "test-ns"},
+		Data:       map[string][]byte{"github_token": []byte("token")},
+	}
+
+	newReconciler := func(actionsClient actions.ActionsService) *AutoscalingListenerReconciler {
+		return &AutoscalingListenerReconciler{
+			Client:        crfake.NewClientBuilder().WithScheme(scheme).WithObjects(listener).Build(),
+			Scheme:        scheme,
+			ActionsClient: fake.NewMultiClient(fake.WithDefaultClient(actionsClient, nil)),
+		}
+	}
+
+	getSharedToken := func(t *testing.T, r *AutoscalingListenerReconciler) actions.ActionsServiceAdminToken {
+		var secret corev1.Secret
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: scaleSetListenerAdminTokenName(listener)}, &secret))
+		require.Len(t, secret.OwnerReferences, 1)
+		assert.Equal(t, listener.Name, secret.OwnerReferences[0].Name)
+
+		var token actions.ActionsServiceAdminToken
+		require.NoError(t, json.Unmarshal(secret.Data[listenerAdminTokenKey], &token))
+		return token
+	}
+
+	t.Run("shares the token and refreshes it ahead of its expiry", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		actionsClient := &adminTokenClient{token: actions.ActionsServiceAdminToken{
+			ActionsServiceURL: "https://pipelines.actions.githubusercontent.com/abc",
+			Token:             "first-token",
+			ExpiresAt:         expiresAt,
+		}}
+		r := newReconciler(actionsClient)
+
+		requeueAfter, err := r.reconcileAdminTokenSecret(ctx, ars, listener, configSecret, logr.Discard())
+		require.NoError(t, err)
+		assert.Equal(t, listenerAdminTokenMinValidity, actionsClient.minValidity)
+		assert.InDelta(t, float64(50*time.Minute), float64(requeueAfter), float64(time.Minute))
+		assert.Equal(t, "first-token", getSharedToken(t, r).Token)
+
+		actionsClient.token.Token = "second-token"
+		actionsClient.token.ExpiresAt = expiresAt.Add(time.Hour)
+
+		_, err = r.reconcileAdminTokenSecret(ctx, ars, listener, configSecret, logr.Discard())
+		require.NoError(t, err)
+		token := getSharedToken(t, r)
+		assert.Equal(t, "second-token", token.Token)
+		assert.True(t, token.ExpiresAt.Equal(expiresAt.Add(time.Hour)))
+	})
+
+	t.Run("retries soon when the token is short lived", func(t *testing.T) {
+		r := newReconciler(&adminTokenClient{token: actions.ActionsServiceAdminToken{
+			ActionsServiceURL: "https://pipelines.actions.githubusercontent.com/abc",
+			Token:             "token",
+			ExpiresAt:         time.Now().Add(5 * time.Minute),
+		}})
+
+		requeueAfter, err := r.reconcileAdminTokenSecret(ctx, ars, listener, configSecret, logr.Discard())
+		require.NoError(t, err)
+		assert.Equal(t, listenerAdminTokenRetryInterval, requeueAfter)
+	})
+
+	t.Run("clients unable to share their token are skipped", func(t *testing.T) {
+		r := newReconciler(fake.NewFakeClient())
+
+		requeueAfter, err := r.reconcileAdminTokenSecret(ctx, ars, listener, configSecret, logr.Discard())
+		require.NoError(t, err)
+		assert.Zero(t, requeueAfter)
+
+		var secret corev1.Secret
+		err = r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: scaleSetListenerAdminTokenName(listener)}, &secret)
+		assert.True(t, kerrors.IsNotFound(err))
+	})
+}
+
+func TestListenerPodMountsSharedAdminToken(t *testing.T) {
+	listener := &v1alpha1.AutoscalingListener{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-listener", Namespace: "arc-system"},
+	}
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test-sa"}}
+	b := ResourceBuilder{}
+
+	pod, err := b.newScaleSetListenerPod(listener, 0, &corev1.Secret{}, serviceAccount, &corev1.Secret{}, nil, true)
+	require.NoError(t, err)
+
+	var volume *corev1.Volume
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == listenerAdminTokenVolumeName {
+			volume = &pod.Spec.Volumes[i]
+		}
+	}
+	require.NotNil(t, volume)
+	assert.Equal(t, scaleSetListenerAdminTokenName(listener), volume.Secret.SecretName)
+	assert.True(t, *volume.Secret.Optional, "the listener must start before the token is shared")
+	assert.Contains(t, pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: listenerAdminTokenVolumeName, MountPath: listenerAdminTokenMountPath, ReadOnly: true})
+
+	config, err := b.newScaleSetListenerConfig(listener, 0, &corev1.Secret{}, nil, listenerServerTLS{}, true)
+	require.NoError(t, err)
+	assert.Contains(t, string(config.Data["config.json"]), `"adminTokenPath":"/etc/gha-listener-admin-token/token.json"`)
+}
@@ -19,6 +19,7 @@ package actionsgithubcom
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -35,6 +36,7 @@ import (
 	"github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
 	"github.com/actions/actions-runner-controller/github/actions"
 	hash "github.com/actions/actions-runner-controller/hash"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -55,6 +57,10 @@ type AutoscalingListenerReconciler struct {
 	ListenerMetricsAddr     string
 	ListenerMetricsEndpoint string
 
+	// ActionsClient, when set, is used to acquire the Actions service admin token of each scale set
+	// and share it with its listener through a secret, so that listener restarts don't mint new tokens.
+	ActionsClient actions.MultiClient
+
 	ResourceBuilder
 }
 
@@ -139,9 +145,9 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// Create a mirror secret in the same namespace as the AutoscalingListener
 	mirrorSecret := new(corev1.Secret)
-	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: scaleSetListenerSecretMirrorName(autoscalingListener)}, mirrorSecret); err != nil {
+	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: r.scaleSetListenerSecretMirrorName(autoscalingListener)}, mirrorSecret); err != nil {
 		if !kerrors.IsNotFound(err) {
-			log.Error(err, "Unable to get listener secret mirror", "namespace", autoscalingListener.Namespace, "name", scaleSetListenerSecretMirrorName(autoscalingListener))
+			log.Error(err, "Unable to get listener secret mirror", "namespace", autoscalingListener.Namespace, "name", r.scaleSetListenerSecretMirrorName(autoscalingListener))
 			return ctrl.Result{}, err
 		}
 
@@ -160,9 +166,9 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// Make sure the runner scale set listener service account is created for the listener pod in the controller namespace
 	serviceAccount := new(corev1.ServiceAccount)
-	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: scaleSetListenerServiceAccountName(autoscalingListener)}, serviceAccount); err != nil {
+	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: r.scaleSetListenerServiceAccountName(autoscalingListener)}, serviceAccount); err != nil {
 		if !kerrors.IsNotFound(err) {
-			log.Error(err, "Unable to get listener service accounts", "namespace", autoscalingListener.Namespace, "name", scaleSetListenerServiceAccountName(autoscalingListener))
+			log.Error(err, "Unable to get listener service accounts", "namespace", autoscalingListener.Namespace, "name", r.scaleSetListenerServiceAccountName(autoscalingListener))
 			return ctrl.Result{}, err
 		}
 
@@ -175,9 +181,9 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// Make sure the runner scale set listener role is created in the AutoscalingRunnerSet namespace
 	listenerRole := new(rbacv1.Role)
-	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: scaleSetListenerRoleName(autoscalingListener)}, listenerRole); err != nil {
+	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: r.scaleSetListenerRoleName(autoscalingListener)}, listenerRole); err != nil {
 		if !kerrors.IsNotFound(err) {
-			log.Error(err, "Unable to get listener role", "namespace", autoscalingListener.Spec.AutoscalingRunnerSetNamespace, "name", scaleSetListenerRoleName(autoscalingListener))
+			log.Error(err, "Unable to get listener role", "namespace", autoscalingListener.Spec.AutoscalingRunnerSetNamespace, "name", r.scaleSetListenerRoleName(autoscalingListener))
 			return ctrl.Result{}, err
 		}
 
@@ -188,7 +194,7 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// Make sure the listener role has the up-to-date rules
 	existingRuleHash := listenerRole.Labels["role-policy-rules-hash"]
-	desiredRules := rulesForListenerRole([]string{autoscalingListener.Spec.EphemeralRunnerSetName})
+	desiredRules := rulesForListenerRole([]string{autoscalingListener.Spec.EphemeralRunnerSetName}, r.scaleSetListenerSessionSecretName(autoscalingListener))
 	desiredRulesHash := hash.ComputeTemplateHash(&desiredRules)
 	if existingRuleHash != desiredRulesHash {
 		log.Info("Updating the listener role with the up-to-date rules")
@@ -197,9 +203,9 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// Make sure the runner scale set listener role binding is created
 	listenerRoleBinding := new(rbacv1.RoleBinding)
-	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: scaleSetListenerRoleName(autoscalingListener)}, listenerRoleBinding); err != nil {
+	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: r.scaleSetListenerRoleName(autoscalingListener)}, listenerRoleBinding); err != nil {
 		if !kerrors.IsNotFound(err) {
-			log.Error(err, "Unable to get listener role binding", "namespace", autoscalingListener.Spec.AutoscalingRunnerSetNamespace, "name", scaleSetListenerRoleName(autoscalingListener))
+			log.Error(err, "Unable to get listener role binding", "namespace", autoscalingListener.Spec.AutoscalingRunnerSetNamespace, "name", r.scaleSetListenerRoleName(autoscalingListener))
 			return ctrl.Result{}, err
 		}
 
@@ -208,12 +214,24 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 		return r.createRoleBindingForListener(ctx, autoscalingListener, listenerRole, serviceAccount, log)
 	}
 
+	// Make sure the secret the listener hands its message session off through is created in the AutoscalingRunnerSet namespace
+	sessionSecret := new(corev1.Secret)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: r.scaleSetListenerSessionSecretName(autoscalingListener)}, sessionSecret); err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Error(err, "Unable to get listener session secret", "namespace", autoscalingListener.Spec.AutoscalingRunnerSetNamespace, "name", r.scaleSetListenerSessionSecretName(autoscalingListener))
+			return ctrl.Result{}, err
+		}
+
+		log.Info("Creating a session secret for the listener pod")
+		return r.createSessionSecretForListener(ctx, &autoscalingRunnerSet, autoscalingListener, log)
+	}
+
 	// Create a secret containing proxy config if specified
 	if autoscalingListener.Spec.Proxy != nil {
 		proxySecret := new(corev1.Secret)
-		if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: proxyListenerSecretName(autoscalingListener)}, proxySecret); err != nil {
+		if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: r.proxyListenerSecretName(autoscalingListener)}, proxySecret); err != nil {
 			if !kerrors.IsNotFound(err) {
-				log.Error(err, "Unable to get listener proxy secret", "namespace", autoscalingListener.Namespace, "name", proxyListenerSecretName(autoscalingListener))
+				log.Error(err, "Unable to get listener proxy secret", "namespace", autoscalingListener.Namespace, "name", r.proxyListenerSecretName(autoscalingListener))
 				return ctrl.Result{}, err
 			}
 
@@ -225,6 +243,24 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// TODO: make sure the role binding has the up-to-date role and service account
 
+	var requeueAfter time.Duration
+	if r.ActionsClient != nil {
+		var err error
+		requeueAfter, err = r.reconcileAdminTokenSecret(ctx, &autoscalingRunnerSet, autoscalingListener, secret, log)
+		if err != nil {
+			// The listener mints its own token until the shared one is available
+			log.Error(err, "Unable to share the admin token with the listener")
+			requeueAfter = listenerAdminTokenRetryInterval
+		}
+	}
+
+	if autoscalingListener.Spec.Shards > 1 {
+		changed, result, err := r.reconcileListenerShards(ctx, &autoscalingRunnerSet, autoscalingListener, serviceAccount, mirrorSecret, log)
+		if changed || err != nil {
+			return result, err
+		}
+	}
+
 	listenerPod := new(corev1.Pod)
 	if err := r.Get(ctx, client.ObjectKey{Namespace: autoscalingListener.Namespace, Name: autoscalingListener.Name}, listenerPod); err != nil {
 		if !kerrors.IsNotFound(err) {
@@ -239,14 +275,14 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 		// Create a listener pod in the controller namespace
 		log.Info("Creating a listener pod")
-		return r.createListenerPod(ctx, &autoscalingRunnerSet, autoscalingListener, serviceAccount, mirrorSecret, log)
+		return r.createListenerPod(ctx, &autoscalingRunnerSet, autoscalingListener, 0, serviceAccount, mirrorSecret, log)
 	}
 
 	cs := listenerContainerStatus(listenerPod)
 	switch {
 	case cs == nil:
 		log.Info("Listener pod is not ready", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
-		return ctrl.Result{}, nil
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	case cs.State.Terminated != nil:
 		log.Info("Listener pod is terminated", "namespace", listenerPod.Namespace, "name", listenerPod.Name, "reason", cs.State.Terminated.Reason, "message", cs.State.Terminated.Message)
 
@@ -261,21 +297,25 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 				return ctrl.Result{}, err
 			}
 		}
-		return ctrl.Result{}, nil
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	case cs.State.Running != nil:
 		if err := r.publishRunningListener(autoscalingListener, true); err != nil {
 			log.Error(err, "Unable to publish running listener", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
 			// stop reconciling. We should never get to this point but if we do,
 			// listener won't be able to start up, and the crash from the pod should
 			// notify the reconciler again.
-			return ctrl.Result{}, nil
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
 		}
-		return ctrl.Result{}, nil
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *AutoscalingListenerReconciler) cleanupResources(ctx context.Context, autoscalingListener *v1alpha1.AutoscalingListener, logger logr.Logger) (done bool, err error) {
+	if done, err := r.cleanupListenerShards(ctx, autoscalingListener, logger); !done || err != nil {
+		return false, err
+	}
+
 	logger.Info("Cleaning up the listener pod")
 	listenerPod := new(corev1.Pod)
 	err = r.Get(ctx, types.NamespacedName{Name: autoscalingListener.Name, Namespace: autoscalingListener.Namespace}, listenerPod)
@@ -314,7 +354,7 @@ func (r *AutoscalingListenerReconciler) cleanupResources(ctx context.Context, au
 	if autoscalingListener.Spec.Proxy != nil {
 		logger.Info("Cleaning up the listener proxy secret")
 		proxySecret := new(corev1.Secret)
-		err = r.Get(ctx, types.NamespacedName{Name: proxyListenerSecretName(autoscalingListener), Namespace: autoscalingListener.Namespace}, proxySecret)
+		err = r.Get(ctx, types.NamespacedName{Name: r.proxyListenerSecretName(autoscalingListener), Namespace: autoscalingListener.Namespace}, proxySecret)
 		switch {
 		case err == nil:
 			if proxySecret.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -331,7 +371,7 @@ func (r *AutoscalingListenerReconciler) cleanupResources(ctx context.Context, au
 	}
 
 	listenerRoleBinding := new(rbacv1.RoleBinding)
-	err = r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: scaleSetListenerRoleName(autoscalingListener)}, listenerRoleBinding)
+	err = r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: r.scaleSetListenerRoleName(autoscalingListener)}, listenerRoleBinding)
 	switch {
 	case err == nil:
 		if listenerRoleBinding.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -347,7 +387,7 @@ func (r *AutoscalingListenerReconciler) cleanupResources(ctx context.Context, au
 	logger.Info("Listener role binding is deleted")
 
 	listenerRole := new(rbacv1.Role)
-	err = r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: scaleSetListenerRoleName(autoscalingListener)}, listenerRole)
+	err = r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: r.scaleSetListenerRoleName(autoscalingListener)}, listenerRole)
 	switch {
 	case err == nil:
 		if listenerRole.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -364,7 +404,7 @@ func (r *AutoscalingListenerReconciler) cleanupResources(ctx context.Context, au
 
 	logger.Info("Cleaning up the listener service account")
 	listenerSa := new(corev1.ServiceAccount)
-	err = r.Get(ctx, types.NamespacedName{Name: scaleSetListenerServiceAccountName(autoscalingListener), Namespace: autoscalingListener.Namespace}, listenerSa)
+	err = r.Get(ctx, types.NamespacedName{Name: r.scaleSetListenerServiceAccountName(autoscalingListener), Namespace: autoscalingListener.Namespace}, listenerSa)
 	switch {
 	case err == nil:
 		if listenerSa.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -379,6 +419,26 @@ func (r *AutoscalingListenerReconciler) cleanupResources(ctx context.Context, au
 	}
 	logger.Info("Listener service account is deleted")
 
+	// The objects created under a previous naming policy aren't found by their current names above
+	for _, kind := range []struct {
+		list      client.ObjectList
+		namespace string
+	}{
+		{&corev1.SecretList{}, autoscalingListener.Namespace},
+		{&corev1.ServiceAccountList{}, autoscalingListener.Namespace},
+		{&rbacv1.RoleBindingList{}, autoscalingListener.Spec.AutoscalingRunnerSetNamespace},
+		{&rbacv1.RoleList{}, autoscalingListener.Spec.AutoscalingRunnerSetNamespace},
+	} {
+		deleted, err := deleteObjectsNotNamed(ctx, r.Client, kind.list, nil, nil, client.InNamespace(kind.namespace), client.MatchingLabels(listenerOwnershipLabels(autoscalingListener)))
+		if err != nil {
+			return false, fmt.Errorf("failed to delete listener objects created under other names: %v", err)
+		}
+		if deleted {
+			logger.Info("Deleting the listener objects created under other names")
+			return false, nil
+		}
+	}
+
 	return true, nil
 }
 
@@ -399,14 +459,14 @@ func (r *AutoscalingListenerReconciler) createServiceAccountForListener(ctx cont
 	return ctrl.Result{}, nil
 }
 
-func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, autoscalingListener *v1alpha1.AutoscalingListener, serviceAccount *corev1.ServiceAccount, secret *corev1.Secret, logger logr.Logger) (ctrl.Result, error) {
+func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, autoscalingListener *v1alpha1.AutoscalingListener, shard int, serviceAccount *corev1.ServiceAccount, secret *corev1.Secret, logger logr.Logger) (ctrl.Result, error) {
 	var envs []corev1.EnvVar
 	if autoscalingListener.Spec.Proxy != nil {
 		httpURL := corev1.EnvVar{
 			Name: "http_proxy",
 			ValueFrom: &corev1.EnvVarSource{
 				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{Name: proxyListenerSecretName(autoscalingListener)},
+					LocalObjectReference: corev1.LocalObjectReference{Name: r.proxyListenerSecretName(autoscalingListener)},
 					Key:                  "http_proxy",
 				},
 			},
@@ -419,7 +479,7 @@ func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, a
 			Name: "https_proxy",
 			ValueFrom: &corev1.EnvVarSource{
 				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{Name: proxyListenerSecretName(autoscalingListener)},
+					LocalObjectReference: corev1.LocalObjectReference{Name: r.proxyListenerSecretName(autoscalingListener)},
 					Key:                  "https_proxy",
 				},
 			},
@@ -432,7 +492,7 @@ func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, a
 			Name: "no_proxy",
 			ValueFrom: &corev1.EnvVarSource{
 				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{Name: proxyListenerSecretName(autoscalingListener)},
+					LocalObjectReference: corev1.LocalObjectReference{Name: r.proxyListenerSecretName(autoscalingListener)},
 					Key:                  "no_proxy",
 				},
 			},
@@ -442,13 +502,18 @@ func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, a
 		}
 	}
 
-	cert := ""
+	var serverTLS listenerServerTLS
 	if autoscalingListener.Spec.GitHubServerTLS != nil {
 		var err error
-		cert, err = r.certificate(ctx, autoscalingRunnerSet, autoscalingListener)
+		serverTLS.rootCA, err = r.certificate(ctx, autoscalingRunnerSet, autoscalingListener)
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("failed to create certificate env var for listener: %v", err)
 		}
+
+		serverTLS.clientCert, serverTLS.clientKey, err = r.clientCertificate(ctx, autoscalingRunnerSet, autoscalingListener)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to get client certificate for listener: %v", err)
+		}
 	}
 
 	var metricsConfig *listenerMetricsServerConfig
@@ -460,15 +525,15 @@ func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, a
 	}
 
 	var podConfig corev1.Secret
-	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: scaleSetListenerConfigName(autoscalingListener)}, &podConfig); err != nil {
+	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: scaleSetListenerShardConfigName(autoscalingListener, shard)}, &podConfig); err != nil {
 		if !kerrors.IsNotFound(err) {
-			logger.Error(err, "Unable to get listener config secret", "namespace", autoscalingListener.Namespace, "name", scaleSetListenerConfigName(autoscalingListener))
+			logger.Error(err, "Unable to get listener config secret", "namespace", autoscalingListener.Namespace, "name", scaleSetListenerShardConfigName(autoscalingListener, shard))
 			return ctrl.Result{Requeue: true}, err
 		}
 
 		logger.Info("Creating listener config secret")
 
-		podConfig, err := r.ResourceBuilder.newScaleSetListenerConfig(autoscalingListener, secret, metricsConfig, cert)
+		podConfig, err := r.ResourceBuilder.newScaleSetListenerConfig(autoscalingListener, shard, secret, metricsConfig, serverTLS, r.ActionsClient != nil)
 		if err != nil {
 			logger.Error(err, "Failed to build listener config secret")
 			return ctrl.Result{}, err
@@ -487,7 +552,7 @@ func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, a
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	newPod, err := r.ResourceBuilder.newScaleSetListenerPod(autoscalingListener, &podConfig, serviceAccount, secret, metricsConfig, envs...)
+	newPod, err := r.ResourceBuilder.newScaleSetListenerPod(autoscalingListener, shard, &podConfig, serviceAccount, secret, metricsConfig, r.ActionsClient != nil, envs...)
 	if err != nil {
 		logger.Error(err, "Failed to build listener pod")
 		return ctrl.Result{}, err
@@ -546,6 +611,38 @@ func (r *AutoscalingListenerReconciler) certificate(ctx context.Context, autosca
 	return certificate, nil
 }
 
+// clientCertificate returns the PEM certificate and private key the listener presents to GitHub, if any.
+func (r *AutoscalingListenerReconciler) clientCertificate(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, autoscalingListener *v1alpha1.AutoscalingListener) (string, string, error) {
+	source := autoscalingListener.Spec.GitHubServerTLS.ClientCertificateFrom
+	if source == nil {
+		return "", "", nil
+	}
+
+	if source.SecretRef == nil || source.SecretRef.Name == "" {
+		return "", "", fmt.Errorf("githubServerTLS.clientCertificateFrom.secretRef is not specified")
+	}
+
+	var secret corev1.Secret
+	err := r.Get(
+		ctx,
+		types.NamespacedName{
+			Namespace: autoscalingRunnerSet.Namespace,
+			Name:      source.SecretRef.Name,
+		},
+		&secret,
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get secret %s: %w", source.SecretRef.Name, err)
+	}
+
+	cert, key := secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if len(cert) == 0 || len(key) == 0 {
+		return "", "", fmt.Errorf("keys %s and %s are required in secret %s", corev1.TLSCertKey, corev1.TLSPrivateKeyKey, source.SecretRef.Name)
+	}
+
+	return string(cert), string(key), nil
+}
+
 func (r *AutoscalingListenerReconciler) createSecretsForListener(ctx context.Context, autoscalingListener *v1alpha1.AutoscalingListener, secret *corev1.Secret, logger logr.Logger) (ctrl.Result, error) {
 	newListenerSecret := r.ResourceBuilder.newScaleSetListenerSecretMirror(autoscalingListener, secret)
 
@@ -563,6 +660,25 @@ func (r *AutoscalingListenerReconciler) createSecretsForListener(ctx context.Con
 	return ctrl.Result{Requeue: true}, nil
 }
 
+// createSessionSecretForListener creates the secret the listener hands its message session off through, owned by the scale set,
+// so that the session survives the listener pod restarts and the AutoscalingListener recreations.
+func (r *AutoscalingListenerReconciler) createSessionSecretForListener(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, autoscalingListener *v1alpha1.AutoscalingListener, logger logr.Logger) (ctrl.Result, error) {
+	newSessionSecret := r.ResourceBuilder.newScaleSetListenerSessionSecret(autoscalingRunnerSet, autoscalingListener)
+	if err := ctrl.SetControllerReference(autoscalingRunnerSet, newSessionSecret, r.Scheme); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create listener session secret: %w", err)
+	}
+
+	logger.Info("Creating listener session secret", "namespace", newSessionSecret.Namespace, "name", newSessionSecret.Name)
+	if err := r.Create(ctx, newSessionSecret); err != nil {
+		logger.Error(err, "Unable to create listener session secret", "namespace", newSessionSecret.Namespace, "name", newSessionSecret.Name)
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Created listener session secret", "namespace", newSessionSecret.Namespace, "name", newSessionSecret.Name)
+
+	return ctrl.Result{Requeue: true}, nil
+}
+
 func (r *AutoscalingListenerReconciler) createProxySecret(ctx context.Context, autoscalingListener *v1alpha1.AutoscalingListener, logger logr.Logger) (ctrl.Result, error) {
 	data, err := autoscalingListener.Spec.Proxy.ToSecretData(func(s string) (*corev1.Secret, error) {
 		var secret corev1.Secret
@@ -578,12 +694,14 @@ func (r *AutoscalingListenerReconciler) createProxySecret(ctx context.Context, a
 
 	newProxySecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      proxyListenerSecretName(autoscalingListener),
+			Name:      r.proxyListenerSecretName(autoscalingListener),
 			Namespace: autoscalingListener.Namespace,
-			Labels: map[string]string{
+			Labels: r.NamingPolicy.applyRequiredLabels(map[string]string{
 				LabelKeyGitHubScaleSetNamespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace,
 				LabelKeyGitHubScaleSetName:      autoscalingListener.Spec.AutoscalingRunnerSetName,
-			},
+				labelKeyListenerNamespace:       autoscalingListener.Namespace,
+				labelKeyListenerName:            autoscalingListener.Name,
+			}),
 		},
 		Data: data,
 	}
@@ -730,7 +848,7 @@ func (r *AutoscalingListenerReconciler) SetupWithManager(mgr ctrl.Manager) error
 		Watches(&rbacv1.Role{}, handler.EnqueueRequestsFromMapFunc(labelBasedWatchFunc)).
 		Watches(&rbacv1.RoleBinding{}, handler.EnqueueRequestsFromMapFunc(labelBasedWatchFunc)).
 		WithEventFilter(predicate.ResourceVersionChangedPredicate{}).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap("autoscalinglistener", mgr.GetCache(), &v1alpha1.AutoscalingListener{}, r))
 }
 
 func listenerContainerStatus(pod *corev1.Pod) *corev1.ContainerStatus {
@@ -138,7 +138,7 @@ var _ = Describe("Test AutoScalingListener controller", func() {
 			mirrorSecret := new(corev1.Secret)
 			Eventually(
 				func() (string, error) {
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerSecretMirrorName(autoscalingListener), Namespace: autoscalingListener.Namespace}, mirrorSecret)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerSecretMirrorName(autoscalingListener), Namespace: autoscalingListener.Namespace}, mirrorSecret)
 					if err != nil {
 						return "", err
 					}
@@ -151,20 +151,20 @@ var _ = Describe("Test AutoScalingListener controller", func() {
 			serviceAccount := new(corev1.ServiceAccount)
 			Eventually(
 				func() (string, error) {
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerServiceAccountName(autoscalingListener), Namespace: autoscalingListener.Namespace}, serviceAccount)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerServiceAccountName(autoscalingListener), Namespace: autoscalingListener.Namespace}, serviceAccount)
 					if err != nil {
 						return "", err
 					}
 					return serviceAccount.Name, nil
 				},
 				autoscalingListenerTestTimeout,
-				autoscalingListenerTestInterval).Should(BeEquivalentTo(scaleSetListenerServiceAccountName(autoscalingListener)), "Service account should be created")
+				autoscalingListenerTestInterval).Should(BeEquivalentTo(new(ResourceBuilder).scaleSetListenerServiceAccountName(autoscalingListener)), "Service account should be created")
 
 			// Check if role is created
 			role := new(rbacv1.Role)
 			Eventually(
 				func() ([]rbacv1.PolicyRule, error) {
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, role)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, role)
 					if err != nil {
 						return nil, err
 					}
@@ -172,13 +172,13 @@ var _ = Describe("Test AutoScalingListener controller", func() {
 					return role.Rules, nil
 				},
 				autoscalingListenerTestTimeout,
-				autoscalingListenerTestInterval).Should(BeEquivalentTo(rulesForListenerRole([]string{autoscalingListener.Spec.EphemeralRunnerSetName})), "Role should be created")
+				autoscalingListenerTestInterval).Should(BeEquivalentTo(rulesForListenerRole([]string{autoscalingListener.Spec.EphemeralRunnerSetName}, new(ResourceBuilder).scaleSetListenerSessionSecretName(autoscalingListener))), "Role should be created")
 
 			// Check if rolebinding is created
 			roleBinding := new(rbacv1.RoleBinding)
 			Eventually(
 				func() (string, error) {
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, roleBinding)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, roleBinding)
 					if err != nil {
 						return "", err
 					}
@@ -186,7 +186,7 @@ var _ = Describe("Test AutoScalingListener controller", func() {
 					return roleBinding.RoleRef.Name, nil
 				},
 				autoscalingListenerTestTimeout,
-				autoscalingListenerTestInterval).Should(BeEquivalentTo(scaleSetListenerRoleName(autoscalingListener)), "Rolebinding should be created")
+				autoscalingListenerTestInterval).Should(BeEquivalentTo(new(ResourceBuilder).scaleSetListenerRoleName(autoscalingListener)), "Rolebinding should be created")
 
 			// Check if pod is created
 			pod := new(corev1.Pod)
@@ -248,7 +248,7 @@ var _ = Describe("Test AutoScalingListener controller", func() {
 			Eventually(
 				func() bool {
 					roleBinding := new(rbacv1.RoleBinding)
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, roleBinding)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, roleBinding)
 					return kerrors.IsNotFound(err)
 				},
 				autoscalingListenerTestTimeout,
@@ -259,7 +259,7 @@ var _ = Describe("Test AutoScalingListener controller", func() {
 			Eventually(
 				func() bool {
 					role := new(rbacv1.Role)
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, role)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, role)
 					return kerrors.IsNotFound(err)
 				},
 				autoscalingListenerTestTimeout,
@@ -340,7 +340,7 @@ var _ = Describe("Test AutoScalingListener controller", func() {
 			role := new(rbacv1.Role)
 			Eventually(
 				func() ([]rbacv1.PolicyRule, error) {
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, role)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerRoleName(autoscalingListener), Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace}, role)
 					if err != nil {
 						return nil, err
 					}
@@ -348,7 +348,7 @@ var _ = Describe("Test AutoScalingListener controller", func() {
 					return role.Rules, nil
 				},
 				autoscalingListenerTestTimeout,
-				autoscalingListenerTestInterval).Should(BeEquivalentTo(rulesForListenerRole([]string{updated.Spec.EphemeralRunnerSetName})), "Role should be updated")
+				autoscalingListenerTestInterval).Should(BeEquivalentTo(rulesForListenerRole([]string{updated.Spec.EphemeralRunnerSetName}, new(ResourceBuilder).scaleSetListenerSessionSecretName(updated))), "Role should be updated")
 		})
 
 		It("It should re-create pod whenever listener container is terminated", func() {
@@ -439,7 +439,7 @@ var _ = Describe("Test AutoScalingListener controller", func() {
 			mirrorSecret := new(corev1.Secret)
 			Eventually(
 				func() (map[string][]byte, error) {
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerSecretMirrorName(autoscalingListener), Namespace: autoscalingListener.Namespace}, mirrorSecret)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerSecretMirrorName(autoscalingListener), Namespace: autoscalingListener.Namespace}, mirrorSecret)
 					if err != nil {
 						return nil, err
 					}
@@ -829,7 +829,7 @@ var _ = Describe("Test AutoScalingListener controller with proxy", func() {
 			func(g Gomega) {
 				err := k8sClient.Get(
 					ctx,
-					types.NamespacedName{Name: proxyListenerSecretName(autoscalingListener), Namespace: autoscalingNS.Name},
+					types.NamespacedName{Name: new(ResourceBuilder).proxyListenerSecretName(autoscalingListener), Namespace: autoscalingNS.Name},
 					&proxySecret,
 				)
 				g.Expect(err).NotTo(HaveOccurred(), "failed to get secret")
@@ -863,7 +863,7 @@ var _ = Describe("Test AutoScalingListener controller with proxy", func() {
 					Name: "http_proxy",
 					ValueFrom: &corev1.EnvVarSource{
 						SecretKeyRef: &corev1.SecretKeySelector{
-							LocalObjectReference: corev1.LocalObjectReference{Name: proxyListenerSecretName(autoscalingListener)},
+							LocalObjectReference: corev1.LocalObjectReference{Name: new(ResourceBuilder).proxyListenerSecretName(autoscalingListener)},
 							Key:                  "http_proxy",
 						},
 					},
@@ -873,7 +873,7 @@ var _ = Describe("Test AutoScalingListener controller with proxy", func() {
 					Name: "https_proxy",
 					ValueFrom: &corev1.EnvVarSource{
 						SecretKeyRef: &corev1.SecretKeySelector{
-							LocalObjectReference: corev1.LocalObjectReference{Name: proxyListenerSecretName(autoscalingListener)},
+							LocalObjectReference: corev1.LocalObjectReference{Name: new(ResourceBuilder).proxyListenerSecretName(autoscalingListener)},
 							Key:                  "https_proxy",
 						},
 					},
@@ -883,7 +883,7 @@ var _ = Describe("Test AutoScalingListener controller with proxy", func() {
 					Name: "no_proxy",
 					ValueFrom: &corev1.EnvVarSource{
 						SecretKeyRef: &corev1.SecretKeySelector{
-							LocalObjectReference: corev1.LocalObjectReference{Name: proxyListenerSecretName(autoscalingListener)},
+							LocalObjectReference: corev1.LocalObjectReference{Name: new(ResourceBuilder).proxyListenerSecretName(autoscalingListener)},
 							Key:                  "no_proxy",
 						},
 					},
@@ -901,7 +901,7 @@ var _ = Describe("Test AutoScalingListener controller with proxy", func() {
 				var proxySecret corev1.Secret
 				err := k8sClient.Get(
 					ctx,
-					types.NamespacedName{Name: proxyListenerSecretName(autoscalingListener), Namespace: autoscalingNS.Name},
+					types.NamespacedName{Name: new(ResourceBuilder).proxyListenerSecretName(autoscalingListener), Namespace: autoscalingNS.Name},
 					&proxySecret,
 				)
 				g.Expect(kerrors.IsNotFound(err)).To(BeTrue())
@@ -1049,7 +1049,7 @@ var _ = Describe("Test AutoScalingListener controller with template modification
 				var proxySecret corev1.Secret
 				err := k8sClient.Get(
 					ctx,
-					types.NamespacedName{Name: proxyListenerSecretName(autoscalingListener), Namespace: autoscalingNS.Name},
+					types.NamespacedName{Name: new(ResourceBuilder).proxyListenerSecretName(autoscalingListener), Namespace: autoscalingNS.Name},
 					&proxySecret,
 				)
 				g.Expect(kerrors.IsNotFound(err)).To(BeTrue())
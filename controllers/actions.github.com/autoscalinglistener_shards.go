@@ -0,0 +1,90 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileListenerShards makes sure a pod runs for each shard of the listener besides the first one,
+// which is the listener pod. The shard pods acquire their partition of the jobs with the message session of the listener pod.
+// It returns true when it created or deleted a shard pod, along with the result to return from the reconciliation.
+func (r *AutoscalingListenerReconciler) reconcileListenerShards(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, autoscalingListener *v1alpha1.AutoscalingListener, serviceAccount *corev1.ServiceAccount, secret *corev1.Secret, logger logr.Logger) (bool, ctrl.Result, error) {
+	for shard := 1; shard < autoscalingListener.Spec.Shards; shard++ {
+		name := scaleSetListenerShardPodName(autoscalingListener, shard)
+
+		pod := new(corev1.Pod)
+		if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: name}, pod); err != nil {
+			if !kerrors.IsNotFound(err) {
+				logger.Error(err, "Unable to get listener shard pod", "namespace", autoscalingListener.Namespace, "name", name)
+				return true, ctrl.Result{}, err
+			}
+
+			logger.Info("Creating a listener shard pod", "shard", shard)
+			result, err := r.createListenerPod(ctx, autoscalingRunnerSet, autoscalingListener, shard, serviceAccount, secret, logger)
+			return true, result, err
+		}
+
+		cs := listenerContainerStatus(pod)
+		if cs == nil || cs.State.Terminated == nil {
+			continue
+		}
+
+		logger.Info("Listener shard pod is terminated", "namespace", pod.Namespace, "name", pod.Name, "reason", cs.State.Terminated.Reason, "message", cs.State.Terminated.Message)
+		if !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		logger.Info("Deleting the listener shard pod", "namespace", pod.Namespace, "name", pod.Name)
+		if err := r.Delete(ctx, pod); err != nil && !kerrors.IsNotFound(err) {
+			logger.Error(err, "Unable to delete the listener shard pod", "namespace", pod.Namespace, "name", pod.Name)
+			return true, ctrl.Result{}, err
+		}
+		return true, ctrl.Result{}, nil
+	}
+
+	return false, ctrl.Result{}, nil
+}
+
+// cleanupListenerShards deletes the pods and config secrets of the shards of the listener besides the first one.
+func (r *AutoscalingListenerReconciler) cleanupListenerShards(ctx context.Context, autoscalingListener *v1alpha1.AutoscalingListener, logger logr.Logger) (done bool, err error) {
+	for shard := 1; shard < autoscalingListener.Spec.Shards; shard++ {
+		pod := new(corev1.Pod)
+		err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: scaleSetListenerShardPodName(autoscalingListener, shard)}, pod)
+		switch {
+		case err == nil:
+			if pod.ObjectMeta.DeletionTimestamp.IsZero() {
+				logger.Info("Deleting the listener shard pod", "shard", shard)
+				if err := r.Delete(ctx, pod); err != nil {
+					return false, fmt.Errorf("failed to delete listener shard pod: %v", err)
+				}
+			}
+			return false, nil
+		case !kerrors.IsNotFound(err):
+			return false, fmt.Errorf("failed to get listener shard pod: %v", err)
+		}
+
+		var secret corev1.Secret
+		err = r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: scaleSetListenerShardConfigName(autoscalingListener, shard)}, &secret)
+		switch {
+		case err == nil:
+			if secret.ObjectMeta.DeletionTimestamp.IsZero() {
+				logger.Info("Deleting the listener shard config secret", "shard", shard)
+				if err := r.Delete(ctx, &secret); err != nil {
+					return false, fmt.Errorf("failed to delete listener shard config secret: %v", err)
+				}
+			}
+			return false, nil
+		case !kerrors.IsNotFound(err):
+			return false, fmt.Errorf("failed to get listener shard config secret: %v", err)
+		}
+	}
+
+	return true, nil
+}
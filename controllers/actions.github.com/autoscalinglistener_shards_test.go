@@ -0,0 +1,119 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	listenerconfig "github.com/actions/actions-runner-controller/cmd/githubrunnerscalesetlistener/config"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileListenerShards(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-scale-set", Namespace: "test-ns"},
+	}
+	listener := &v1alpha1.AutoscalingListener{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-listener", Namespace: "arc-system", UID: "listener-uid"},
+		Spec: v1alpha1.AutoscalingListenerSpec{
+			GitHubConfigUrl:               "https://github.com/owner/repo",
+			AutoscalingRunnerSetNamespace: "test-ns",
+			EphemeralRunnerSetName:        "test-runner-set",
+			RunnerScaleSetId:              1,
+			Shards:                        3,
+		},
+	}
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test-listener-sa", Namespace: "arc-system"}}
+	secret := &corev1.Secret{Data: map[string][]byte{"github_token": []byte("token")}}
+
+	r := &AutoscalingListenerReconciler{
+		Client: crfake.NewClientBuilder().WithScheme(scheme).WithObjects(listener).Build(),
+		Scheme: scheme,
+	}
+
+	reconcileUntilDone := func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			changed, _, err := r.reconcileListenerShards(ctx, ars, listener, serviceAccount, secret, logr.Discard())
+			require.NoError(t, err)
+			if !changed {
+				return
+			}
+		}
+		t.Fatal("shards kept changing")
+	}
+
+	reconcileUntilDone(t)
+
+	var primary corev1.Pod
+	err := r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: listener.Name}, &primary)
+	assert.True(t, kerrors.IsNotFound(err), "the listener pod is not a shard pod")
+
+	for shard := 1; shard < 3; shard++ {
+		var pod corev1.Pod
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: scaleSetListenerShardPodName(listener, shard)}, &pod))
+		assert.Equal(t, scaleSetListenerShardConfigName(listener, shard), pod.Spec.Volumes[0].Secret.SecretName)
+
+		var podConfig corev1.Secret
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: scaleSetListenerShardConfigName(listener, shard)}, &podConfig))
+
+		var config listenerconfig.Config
+		require.NoError(t, json.Unmarshal(podConfig.Data["config.json"], &config))
+		assert.Equal(t, shard, config.ShardIndex)
+		assert.Equal(t, 3, config.ShardCount)
+	}
+
+	t.Run("terminated shard pods are replaced", func(t *testing.T) {
+		var pod corev1.Pod
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: scaleSetListenerShardPodName(listener, 2)}, &pod))
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  autoscalingListenerContainerName,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error"}},
+		}}
+		require.NoError(t, r.Status().Update(ctx, &pod))
+
+		changed, _, err := r.reconcileListenerShards(ctx, ars, listener, serviceAccount, secret, logr.Discard())
+		require.NoError(t, err)
+		assert.True(t, changed)
+
+		err = r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: pod.Name}, &pod)
+		assert.True(t, kerrors.IsNotFound(err), "the terminated shard pod should be deleted")
+
+		reconcileUntilDone(t)
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: pod.Name}, &pod))
+		assert.Empty(t, pod.Status.ContainerStatuses)
+	})
+
+	t.Run("cleanup deletes the shard pods and configs", func(t *testing.T) {
+		for i := 0; ; i++ {
+			require.Less(t, i, 10, "cleanup kept going")
+			done, err := r.cleanupListenerShards(ctx, listener, logr.Discard())
+			require.NoError(t, err)
+			if done {
+				break
+			}
+		}
+
+		for shard := 1; shard < 3; shard++ {
+			err := r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: scaleSetListenerShardPodName(listener, shard)}, &corev1.Pod{})
+			assert.True(t, kerrors.IsNotFound(err))
+			err = r.Get(ctx, client.ObjectKey{Namespace: listener.Namespace, Name: scaleSetListenerShardConfigName(listener, shard)}, &corev1.Secret{})
+			assert.True(t, kerrors.IsNotFound(err))
+		}
+	})
+}
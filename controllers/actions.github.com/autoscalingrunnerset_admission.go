@@ -0,0 +1,82 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	reasonWithinAdmissionWindow   = "WithinAdmissionWindow"
+	reasonOutsideAdmissionWindows = "OutsideAdmissionWindows"
+	reasonInvalidAdmissionWindows = "InvalidAdmissionWindows"
+)
+
+// reconcileAdmissionWindows sets the AdmissionWindowOpen condition from spec.admissionWindows, which the listener acquires jobs during,
+// and removes it when the scale set has no window.
+// It returns the delay until the next window opens or closes, or zero when there is no window.
+func (r *AutoscalingRunnerSetReconciler) reconcileAdmissionWindows(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) (time.Duration, error) {
+	if len(autoscalingRunnerSet.Spec.AdmissionWindows) == 0 {
+		if meta.FindStatusCondition(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionAdmissionWindowOpen) == nil {
+			return 0, nil
+		}
+
+		return 0, patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			meta.RemoveStatusCondition(&obj.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionAdmissionWindowOpen)
+		})
+	}
+
+	now := time.Now()
+	condition, nextChange := admissionWindowCondition(autoscalingRunnerSet, now)
+
+	current := meta.FindStatusCondition(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionAdmissionWindowOpen)
+	if current == nil || current.Status != condition.Status || current.Reason != condition.Reason || current.Message != condition.Message || current.ObservedGeneration != condition.ObservedGeneration {
+		logger.Info("Updating the admission window status", "open", condition.Status, "reason", condition.Reason)
+		if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			meta.SetStatusCondition(&obj.Status.Conditions, condition)
+		}); err != nil {
+			return 0, fmt.Errorf("failed to update admission window status: %w", err)
+		}
+	}
+
+	if nextChange.IsZero() {
+		return 0, nil
+	}
+	return nextChange.Sub(now), nil
+}
+
+// admissionWindowCondition returns the AdmissionWindowOpen condition of the scale set at now,
+// and the next time any of its admission windows opens or closes.
+func admissionWindowCondition(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, now time.Time) (metav1.Condition, time.Time) {
+	condition := metav1.Condition{
+		Type:               v1alpha1.AutoscalingRunnerSetConditionAdmissionWindowOpen,
+		ObservedGeneration: autoscalingRunnerSet.Generation,
+	}
+
+	open, nextChange, err := v1alpha1.AdmissionWindowsOpen(autoscalingRunnerSet.Spec.AdmissionWindows, now)
+	switch {
+	case err != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonInvalidAdmissionWindows
+		condition.Message = err.Error()
+	case open:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonWithinAdmissionWindow
+		condition.Message = fmt.Sprintf("Jobs are acquired until %s", nextChange.UTC().Format(time.RFC3339))
+	case autoscalingRunnerSet.Spec.OutsideAdmissionWindows == v1alpha1.OutsideAdmissionWindowsHold:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonOutsideAdmissionWindows
+		condition.Message = fmt.Sprintf("Jobs are held until the next admission window opens at %s", nextChange.UTC().Format(time.RFC3339))
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonOutsideAdmissionWindows
+		condition.Message = fmt.Sprintf("Jobs are left to other scale sets until the next admission window opens at %s", nextChange.UTC().Format(time.RFC3339))
+	}
+
+	return condition, nextChange
+}
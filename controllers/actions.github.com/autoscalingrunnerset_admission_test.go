@@ -0,0 +1,57 @@
+package actionsgithubcom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAdmissionWindowCondition(t *testing.T) {
+	newARS := func(outside string, windows ...v1alpha1.AdmissionWindow) *v1alpha1.AutoscalingRunnerSet {
+		return &v1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Generation: 3},
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{
+				AdmissionWindows:        windows,
+				OutsideAdmissionWindows: outside,
+			},
+		}
+	}
+
+	window := v1alpha1.AdmissionWindow{DailyWindow: v1alpha1.DailyWindow{Start: "09:00", End: "17:00"}}
+	at := func(hour int) time.Time {
+		return time.Date(2024, 5, 6, hour, 0, 0, 0, time.UTC)
+	}
+
+	t.Run("within a window", func(t *testing.T) {
+		condition, next := admissionWindowCondition(newARS("", window), at(10))
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, reasonWithinAdmissionWindow, condition.Reason)
+		assert.Equal(t, "Jobs are acquired until 2024-05-06T17:00:00Z", condition.Message)
+		assert.Equal(t, int64(3), condition.ObservedGeneration)
+		assert.WithinDuration(t, at(17), next, 0)
+	})
+
+	t.Run("outside of the windows", func(t *testing.T) {
+		condition, next := admissionWindowCondition(newARS("", window), at(18))
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonOutsideAdmissionWindows, condition.Reason)
+		assert.Equal(t, "Jobs are left to other scale sets until the next admission window opens at 2024-05-07T09:00:00Z", condition.Message)
+		assert.WithinDuration(t, at(9).AddDate(0, 0, 1), next, 0)
+	})
+
+	t.Run("holding jobs outside of the windows", func(t *testing.T) {
+		condition, _ := admissionWindowCondition(newARS(v1alpha1.OutsideAdmissionWindowsHold, window), at(18))
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, "Jobs are held until the next admission window opens at 2024-05-07T09:00:00Z", condition.Message)
+	})
+
+	t.Run("invalid windows", func(t *testing.T) {
+		condition, next := admissionWindowCondition(newARS("", v1alpha1.AdmissionWindow{DailyWindow: v1alpha1.DailyWindow{Start: "09:00", End: "17:00", TimeZone: "Nowhere/Nowhere"}}), at(10))
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonInvalidAdmissionWindows, condition.Reason)
+		assert.True(t, next.IsZero())
+	})
+}
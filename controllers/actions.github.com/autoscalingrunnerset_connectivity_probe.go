@@ -0,0 +1,224 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultConnectivityProbeTimeoutSeconds = 120
+
+	// connectivityProbeStartupSeconds is how long the probe pod can take to be scheduled and started,
+	// on top of the timeout of the probe, before the Job fails.
+	connectivityProbeStartupSeconds = 600
+
+	// connectivityProbeRetryInterval is how long after a failed probe it's run again, as the failure may be transient.
+	connectivityProbeRetryInterval = 5 * time.Minute
+
+	// labelKeyConnectivityProbe tells the probe pods apart from the runner pods, whose labels they otherwise share.
+	labelKeyConnectivityProbe = "actions.github.com/connectivity-probe"
+
+	envVarConnectivityProbeEndpoints = "CONNECTIVITY_PROBE_ENDPOINTS"
+
+	reasonConnectivityProbeRunning   = "ProbeRunning"
+	reasonConnectivityProbeSucceeded = "ProbeSucceeded"
+	reasonConnectivityProbeFailed    = "ProbeFailed"
+)
+
+// connectivityProbeScript resolves and connects to each endpoint until all of them succeed or the timeout elapses.
+// The resolution is left to the proxy when there is one, and the CA bundle the runner adds to the trusted CAs is trusted as well.
+// The failure is written to the termination log so that it ends up in the message of the GitHubReachable condition.
+const connectivityProbeScript = `deadline=$(( $(date +%s) + CONNECTIVITY_PROBE_TIMEOUT_SECONDS ))
+cacert=""
+if [ -n "$NODE_EXTRA_CA_CERTS" ] && [ -f "$NODE_EXTRA_CA_CERTS" ]; then
+  cacert=/tmp/connectivity-probe-ca.crt
+  cat /etc/ssl/certs/ca-certificates.crt "$NODE_EXTRA_CA_CERTS" > "$cacert" 2>/dev/null || cp "$NODE_EXTRA_CA_CERTS" "$cacert"
+fi
+for endpoint in $CONNECTIVITY_PROBE_ENDPOINTS; do
+  host=$(echo "$endpoint" | sed -E 's#^[a-z]+://([^/:]+).*#\1#')
+  until err=$( [ -n "$https_proxy$http_proxy" ] || getent hosts "$host" >/dev/null || echo "DNS resolution of $host failed") && [ -z "$err" ] \
+    && err=$(curl -sS -o /dev/null --connect-timeout 5 --max-time 10 ${cacert:+--cacert "$cacert"} "$endpoint" 2>&1) ; do
+    if [ "$(date +%s)" -ge "$deadline" ]; then
+      echo "$endpoint is unreachable: $err" | tee /dev/termination-log
+      exit 1
+    fi
+    sleep 5
+  done
+  echo "$endpoint is reachable"
+done
+`
+
+// connectivityProbeEndpoints returns the endpoints probed by default for the GitHub config URL:
+// the GitHub URL and the GitHub API URL, plus the Actions service when it's github.com.
+func connectivityProbeEndpoints(githubConfigURL string) []string {
+	config, err := actions.ParseGitHubConfigFromURL(githubConfigURL)
+	if err != nil {
+		return []string{githubConfigURL}
+	}
+
+	endpoints := []string{
+		fmt.Sprintf("%s://%s/", config.ConfigURL.Scheme, config.ConfigURL.Host),
+		config.GitHubAPIURL("/").String(),
+	}
+
+	if strings.EqualFold(config.ConfigURL.Host, "github.com") || strings.EqualFold(config.ConfigURL.Host, "www.github.com") {
+		endpoints = append(endpoints, "https://pipelines.actions.githubusercontent.com/")
+	}
+
+	return endpoints
+}
+
+// reconcileConnectivityProbe runs the connectivity probe of spec.connectivityProbe for the runner spec of the latest ephemeral runner set,
+// and reports its result in the GitHubReachable condition.
+// It returns true once the probe succeeded, or when the scale set has no probe, and otherwise the delay after which a failed probe is run again.
+func (r *AutoscalingRunnerSetReconciler) reconcileConnectivityProbe(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, latestRunnerSet *v1alpha1.EphemeralRunnerSet, logger logr.Logger) (bool, time.Duration, error) {
+	if autoscalingRunnerSet.Spec.ConnectivityProbe == nil {
+		if meta.FindStatusCondition(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionGitHubReachable) == nil {
+			return true, 0, nil
+		}
+
+		logger.Info("Deleting connectivity probe")
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: autoscalingRunnerSet.Namespace, Name: r.scaleSetConnectivityProbeName(autoscalingRunnerSet)}}
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !kerrors.IsNotFound(err) {
+			return false, 0, fmt.Errorf("failed to delete connectivity probe job: %v", err)
+		}
+
+		return true, 0, patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			meta.RemoveStatusCondition(&obj.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionGitHubReachable)
+		})
+	}
+
+	desired := r.ResourceBuilder.newConnectivityProbeJob(autoscalingRunnerSet, latestRunnerSet)
+	if err := ctrl.SetControllerReference(autoscalingRunnerSet, desired, r.Scheme); err != nil {
+		return false, 0, fmt.Errorf("failed to set controller reference on connectivity probe job: %v", err)
+	}
+
+	running := metav1.Condition{
+		Type:    v1alpha1.AutoscalingRunnerSetConditionGitHubReachable,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonConnectivityProbeRunning,
+		Message: fmt.Sprintf("Probing the connectivity of the runner pods of %s", latestRunnerSet.Name),
+	}
+
+	job := new(batchv1.Job)
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), job)
+	switch {
+	case kerrors.IsNotFound(err):
+		logger.Info("Creating connectivity probe", "name", desired.Name, "ephemeralRunnerSetName", latestRunnerSet.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			return false, 0, fmt.Errorf("failed to create connectivity probe job: %v", err)
+		}
+		return false, 0, r.setConnectivityProbeCondition(ctx, autoscalingRunnerSet, running)
+	case err != nil:
+		return false, 0, fmt.Errorf("failed to get connectivity probe job: %v", err)
+	case !job.DeletionTimestamp.IsZero():
+		// The deletion of the job triggers another reconciliation
+		return false, 0, nil
+	case job.Annotations[annotationKeyValuesHash] != desired.Annotations[annotationKeyValuesHash]:
+		logger.Info("Connectivity probe is out of date. Deleting it so that it is recreated", "name", job.Name)
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !kerrors.IsNotFound(err) {
+			return false, 0, fmt.Errorf("failed to delete connectivity probe job: %v", err)
+		}
+		return false, 0, r.setConnectivityProbeCondition(ctx, autoscalingRunnerSet, running)
+	}
+
+	finished, jobCondition := jobFinishedCondition(job)
+	if !finished {
+		return false, 0, r.setConnectivityProbeCondition(ctx, autoscalingRunnerSet, running)
+	}
+
+	if jobCondition.Type == batchv1.JobComplete {
+		return true, 0, r.setConnectivityProbeCondition(ctx, autoscalingRunnerSet, metav1.Condition{
+			Type:    v1alpha1.AutoscalingRunnerSetConditionGitHubReachable,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonConnectivityProbeSucceeded,
+			Message: fmt.Sprintf("The runner pods of %s can reach GitHub", latestRunnerSet.Name),
+		})
+	}
+
+	message, err := r.connectivityProbeFailure(ctx, job, jobCondition)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if err := r.setConnectivityProbeCondition(ctx, autoscalingRunnerSet, metav1.Condition{
+		Type:    v1alpha1.AutoscalingRunnerSetConditionGitHubReachable,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonConnectivityProbeFailed,
+		Message: message,
+	}); err != nil {
+		return false, 0, err
+	}
+
+	retryAfter := time.Until(jobCondition.LastTransitionTime.Add(connectivityProbeRetryInterval))
+	if retryAfter > 0 {
+		return false, retryAfter, nil
+	}
+
+	logger.Info("Running the failed connectivity probe again", "name", job.Name)
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !kerrors.IsNotFound(err) {
+		return false, 0, fmt.Errorf("failed to delete connectivity probe job: %v", err)
+	}
+	return false, 0, nil
+}
+
+// jobFinishedCondition returns the Complete or Failed condition of the job, and false while the job is running.
+func jobFinishedCondition(job *batchv1.Job) (bool, batchv1.JobCondition) {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true, c
+		}
+	}
+	return false, batchv1.JobCondition{}
+}
+
+// connectivityProbeFailure returns why the connectivity probe failed: the termination message of the probe,
+// or the reason of the Failed condition of the job when the probe didn't run, like when the pod couldn't be scheduled in time.
+func (r *AutoscalingRunnerSetReconciler) connectivityProbeFailure(ctx context.Context, job *batchv1.Job, failed batchv1.JobCondition) (string, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", fmt.Errorf("failed to list connectivity probe pods: %v", err)
+	}
+
+	for _, pod := range pods.Items {
+		for _, s := range pod.Status.ContainerStatuses {
+			if s.Name == EphemeralRunnerContainerName && s.State.Terminated != nil && s.State.Terminated.ExitCode != 0 {
+				if message := strings.TrimSpace(s.State.Terminated.Message); message != "" {
+					return message, nil
+				}
+			}
+		}
+	}
+
+	if failed.Message != "" {
+		return fmt.Sprintf("The connectivity probe failed: %s", failed.Message), nil
+	}
+	return fmt.Sprintf("The connectivity probe failed: %s", failed.Reason), nil
+}
+
+// setConnectivityProbeCondition updates the GitHubReachable condition, unless it's already up to date.
+func (r *AutoscalingRunnerSetReconciler) setConnectivityProbeCondition(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, condition metav1.Condition) error {
+	condition.ObservedGeneration = autoscalingRunnerSet.Generation
+
+	current := meta.FindStatusCondition(autoscalingRunnerSet.Status.Conditions, condition.Type)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason && current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+
+	return patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		meta.SetStatusCondition(&obj.Status.Conditions, condition)
+	})
+}
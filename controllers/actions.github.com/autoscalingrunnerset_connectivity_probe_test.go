@@ -0,0 +1,286 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConnectivityProbeEndpoints(t *testing.T) {
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{
+			url:  "https://github.com/owner/repo",
+			want: []string{"https://github.com/", "https://api.github.com/", "https://pipelines.actions.githubusercontent.com/"},
+		},
+		{
+			url:  "https://my-tenant.ghe.com/enterprises/acme",
+			want: []string{"https://my-tenant.ghe.com/", "https://api.my-tenant.ghe.com/"},
+		},
+		{
+			url:  "https://ghes.example.com/org",
+			want: []string{"https://ghes.example.com/", "https://ghes.example.com/api/v3/"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			assert.Equal(t, tc.want, connectivityProbeEndpoints(tc.url))
+		})
+	}
+}
+
+func newConnectivityProbeTestObjects() (*v1alpha1.AutoscalingRunnerSet, *v1alpha1.EphemeralRunnerSet) {
+	timeout := int32(30)
+
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "arc-runners",
+			Namespace:  "arc-runners",
+			Generation: 2,
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl:    "https://github.com/owner/repo",
+			GitHubConfigSecret: "github-config",
+			ConnectivityProbe:  &v1alpha1.ConnectivityProbe{TimeoutSeconds: &timeout},
+		},
+	}
+
+	ers := &v1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "arc-runners-abcde",
+			Namespace: "arc-runners",
+			Labels: map[string]string{
+				LabelKeyKubernetesComponent: "runner-set",
+				LabelKeyGitHubScaleSetName:  "arc-runners",
+			},
+		},
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			EphemeralRunnerSpec: v1alpha1.EphemeralRunnerSpec{
+				GitHubConfigUrl: "https://github.com/owner/repo",
+				Proxy: &v1alpha1.ProxyConfig{
+					HTTPS: &v1alpha1.ProxyServerConfig{Url: "http://proxy.example.com:3128"},
+				},
+				PodTemplateSpec: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"egress": "gateway"}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: EphemeralRunnerContainerName, Image: "runner", Command: []string{"/home/runner/run.sh"}},
+							{Name: "dind", Image: "docker:dind"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return ars, ers
+}
+
+func TestNewConnectivityProbeJob(t *testing.T) {
+	ars, ers := newConnectivityProbeTestObjects()
+
+	var b ResourceBuilder
+	job := b.newConnectivityProbeJob(ars, ers)
+
+	assert.Equal(t, b.scaleSetConnectivityProbeName(ars), job.Name)
+	assert.Equal(t, "arc-runners", job.Namespace)
+	assert.Equal(t, int64(30+connectivityProbeStartupSeconds), *job.Spec.ActiveDeadlineSeconds)
+	assert.Equal(t, int32(0), *job.Spec.BackoffLimit)
+
+	podLabels := job.Spec.Template.Labels
+	assert.Equal(t, "runner", podLabels[LabelKeyKubernetesComponent], "the probe pod shares the egress of the runner pods")
+	assert.Equal(t, "arc-runners", podLabels[LabelKeyGitHubScaleSetName])
+	assert.Equal(t, "gateway", podLabels["egress"])
+	assert.Equal(t, "true", podLabels[labelKeyConnectivityProbe])
+
+	spec := job.Spec.Template.Spec
+	assert.Equal(t, corev1.RestartPolicyNever, spec.RestartPolicy)
+	require.Len(t, spec.Containers, 1, "the sidecars of the runner are dropped")
+
+	c := spec.Containers[0]
+	assert.Equal(t, "runner", c.Image)
+	assert.Equal(t, "sh", c.Command[0])
+	assert.Contains(t, c.Command[2], "deadline=$(( $(date +%s) + 30 ))")
+
+	envs := map[string]corev1.EnvVar{}
+	for _, e := range c.Env {
+		envs[e.Name] = e
+	}
+	assert.Equal(t, "https://github.com/ https://api.github.com/ https://pipelines.actions.githubusercontent.com/", envs[envVarConnectivityProbeEndpoints].Value)
+	require.Contains(t, envs, EnvVarHTTPSProxy)
+	assert.Equal(t, proxyEphemeralRunnerSetSecretName(ers), envs[EnvVarHTTPSProxy].ValueFrom.SecretKeyRef.Name)
+	assert.NotContains(t, envs, EnvVarHTTPProxy)
+
+	t.Run("custom command", func(t *testing.T) {
+		ars.Spec.ConnectivityProbe.Command = []string{"/probe", "--resolver", "10.0.0.10"}
+		ars.Spec.ConnectivityProbe.Endpoints = []string{"https://ghes.example.com/"}
+
+		job := b.newConnectivityProbeJob(ars, ers)
+		c := job.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, []string{"/probe", "--resolver", "10.0.0.10"}, c.Command)
+		assert.Contains(t, c.Env, corev1.EnvVar{Name: envVarConnectivityProbeEndpoints, Value: "https://ghes.example.com/"})
+	})
+
+	t.Run("a new runner set is probed again", func(t *testing.T) {
+		other := ers.DeepCopy()
+		other.Name = "arc-runners-fghij"
+
+		assert.NotEqual(t, b.newConnectivityProbeJob(ars, ers).Annotations[annotationKeyValuesHash], b.newConnectivityProbeJob(ars, other).Annotations[annotationKeyValuesHash])
+	})
+}
+
+func TestReconcileConnectivityProbe(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newReconciler := func(ars *v1alpha1.AutoscalingRunnerSet, objs ...client.Object) *AutoscalingRunnerSetReconciler {
+		return &AutoscalingRunnerSetReconciler{
+			Client: crfake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objs, ars)...).WithStatusSubresource(ars).Build(),
+			Scheme: scheme,
+		}
+	}
+
+	reconcile := func(t *testing.T, r *AutoscalingRunnerSetReconciler, ars *v1alpha1.AutoscalingRunnerSet, ers *v1alpha1.EphemeralRunnerSet) (bool, time.Duration, *metav1.Condition) {
+		t.Helper()
+
+		current := new(v1alpha1.AutoscalingRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), current))
+
+		reachable, requeueAfter, err := r.reconcileConnectivityProbe(ctx, current, ers, logr.Discard())
+		require.NoError(t, err)
+
+		updated := new(v1alpha1.AutoscalingRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), updated))
+		return reachable, requeueAfter, meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionGitHubReachable)
+	}
+
+	getJob := func(t *testing.T, r *AutoscalingRunnerSetReconciler, ars *v1alpha1.AutoscalingRunnerSet) *batchv1.Job {
+		t.Helper()
+
+		job := new(batchv1.Job)
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: ars.Namespace, Name: r.scaleSetConnectivityProbeName(ars)}, job))
+		return job
+	}
+
+	finishJob := func(t *testing.T, r *AutoscalingRunnerSetReconciler, job *batchv1.Job, conditionType batchv1.JobConditionType, finishedAt time.Time) {
+		t.Helper()
+
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: conditionType, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", LastTransitionTime: metav1.NewTime(finishedAt)},
+		}
+		require.NoError(t, r.Status().Update(ctx, job))
+	}
+
+	t.Run("succeeds", func(t *testing.T) {
+		ars, ers := newConnectivityProbeTestObjects()
+		r := newReconciler(ars)
+
+		reachable, _, cond := reconcile(t, r, ars, ers)
+		assert.False(t, reachable)
+		require.NotNil(t, cond)
+		assert.Equal(t, metav1.ConditionFalse, cond.Status)
+		assert.Equal(t, reasonConnectivityProbeRunning, cond.Reason)
+		assert.Equal(t, int64(2), cond.ObservedGeneration)
+
+		job := getJob(t, r, ars)
+		require.Len(t, job.OwnerReferences, 1)
+		assert.Equal(t, ars.Name, job.OwnerReferences[0].Name)
+
+		reachable, _, _ = reconcile(t, r, ars, ers)
+		assert.False(t, reachable, "the probe is still running")
+
+		finishJob(t, r, job, batchv1.JobComplete, time.Now())
+
+		reachable, _, cond = reconcile(t, r, ars, ers)
+		assert.True(t, reachable)
+		assert.Equal(t, metav1.ConditionTrue, cond.Status)
+		assert.Equal(t, reasonConnectivityProbeSucceeded, cond.Reason)
+	})
+
+	t.Run("reports the failure and runs the probe again", func(t *testing.T) {
+		ars, ers := newConnectivityProbeTestObjects()
+		r := newReconciler(ars)
+
+		reconcile(t, r, ars, ers)
+		job := getJob(t, r, ars)
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: job.Name + "-xyz", Namespace: job.Namespace, Labels: map[string]string{"job-name": job.Name}},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name: EphemeralRunnerContainerName,
+						State: corev1.ContainerState{
+							Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: "https://api.github.com/ is unreachable: SSL certificate problem\n"},
+						},
+					},
+				},
+			},
+		}
+		require.NoError(t, r.Create(ctx, pod))
+		finishJob(t, r, job, batchv1.JobFailed, time.Now().Add(-time.Minute))
+
+		reachable, requeueAfter, cond := reconcile(t, r, ars, ers)
+		assert.False(t, reachable)
+		assert.InDelta(t, float64(connectivityProbeRetryInterval-time.Minute), float64(requeueAfter), float64(5*time.Second))
+		assert.Equal(t, reasonConnectivityProbeFailed, cond.Reason)
+		assert.Equal(t, "https://api.github.com/ is unreachable: SSL certificate problem", cond.Message)
+
+		job = getJob(t, r, ars)
+		finishJob(t, r, job, batchv1.JobFailed, time.Now().Add(-connectivityProbeRetryInterval))
+
+		reachable, _, _ = reconcile(t, r, ars, ers)
+		assert.False(t, reachable)
+		err := r.Get(ctx, client.ObjectKeyFromObject(job), new(batchv1.Job))
+		assert.True(t, client.IgnoreNotFound(err) == nil && err != nil, "the failed probe is deleted to be run again")
+	})
+
+	t.Run("probes a new runner set again", func(t *testing.T) {
+		ars, ers := newConnectivityProbeTestObjects()
+		r := newReconciler(ars)
+
+		reconcile(t, r, ars, ers)
+		finishJob(t, r, getJob(t, r, ars), batchv1.JobComplete, time.Now())
+		reachable, _, _ := reconcile(t, r, ars, ers)
+		require.True(t, reachable)
+
+		newRunnerSet := ers.DeepCopy()
+		newRunnerSet.Name = "arc-runners-fghij"
+
+		reachable, _, cond := reconcile(t, r, ars, newRunnerSet)
+		assert.False(t, reachable)
+		assert.Equal(t, reasonConnectivityProbeRunning, cond.Reason)
+	})
+
+	t.Run("removes the condition without a probe", func(t *testing.T) {
+		ars, ers := newConnectivityProbeTestObjects()
+		ars.Spec.ConnectivityProbe = nil
+		ars.Status.Conditions = []metav1.Condition{
+			{Type: v1alpha1.AutoscalingRunnerSetConditionGitHubReachable, Status: metav1.ConditionTrue, Reason: reasonConnectivityProbeSucceeded, LastTransitionTime: metav1.Now()},
+		}
+		r := newReconciler(ars)
+
+		reachable, _, cond := reconcile(t, r, ars, ers)
+		assert.True(t, reachable)
+		assert.Nil(t, cond)
+	})
+}
@@ -26,7 +26,9 @@ import (
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/build"
 	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -79,6 +81,8 @@ type AutoscalingRunnerSetReconciler struct {
 	DefaultRunnerScaleSetListenerImagePullSecrets []string
 	UpdateStrategy                                UpdateStrategy
 	ActionsClient                                 actions.MultiClient
+	// EgressPolicyKinds are the kinds of egress policy resources the scale sets can create. None are allowed when empty.
+	EgressPolicyKinds []EgressPolicyKind
 	ResourceBuilder
 }
 
@@ -89,6 +93,8 @@ type AutoscalingRunnerSetReconciler struct {
 // +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunnersets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalinglisteners,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalinglisteners/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
 
 // Reconcile a AutoscalingRunnerSet resource to meet its desired spec.
 func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -99,13 +105,25 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	ctx = withScaleSet(ctx, autoscalingRunnerSet)
+
 	if !autoscalingRunnerSet.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(autoscalingRunnerSet, autoscalingRunnerSetFinalizerName) {
 			return ctrl.Result{}, nil
 		}
 
 		log.Info("Deleting resources")
-		done, err := r.cleanupListener(ctx, autoscalingRunnerSet, log)
+		done, err := r.cleanupJobTemplates(ctx, autoscalingRunnerSet, log)
+		if err != nil {
+			log.Error(err, "Failed to clean up the AutoscalingRunnerSets of the job templates")
+			return ctrl.Result{}, err
+		}
+		if !done {
+			log.Info("Waiting for the AutoscalingRunnerSets of the job templates to be deleted")
+			return ctrl.Result{}, nil
+		}
+
+		done, err = r.cleanupListener(ctx, autoscalingRunnerSet, log)
 		if err != nil {
 			log.Error(err, "Failed to clean up listener")
 			return ctrl.Result{}, err
@@ -133,6 +151,14 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 			return ctrl.Result{}, err
 		}
 
+		// Namespaced egress policies are garbage collected, but cluster-scoped ones can't be owned by the autoscaling runner set
+		if ref := autoscalingRunnerSet.Status.EgressPolicyRef; ref != nil {
+			if err := r.deleteEgressPolicy(ctx, ref, log); err != nil {
+				log.Error(err, "Failed to delete egress policy")
+				return ctrl.Result{}, err
+			}
+		}
+
 		if err := r.removeFinalizersFromDependentResources(ctx, autoscalingRunnerSet, log); err != nil {
 			log.Error(err, "Failed to remove finalizers on dependent resources")
 			return ctrl.Result{}, err
@@ -207,6 +233,30 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 		return r.updateRunnerScaleSetName(ctx, autoscalingRunnerSet, log)
 	}
 
+	// Make sure the labels of the runner scale set are up to date
+	if runnerScaleSetLabelsChanged(autoscalingRunnerSet) {
+		log.Info("AutoScalingRunnerSet runner scale set labels changed. Updating the runner scale set.")
+		return r.updateRunnerScaleSetLabels(ctx, autoscalingRunnerSet, log)
+	}
+
+	// Make sure the objects created under a previous naming policy don't linger under their previous names
+	if err := r.renameObjects(ctx, autoscalingRunnerSet, log); err != nil {
+		log.Error(err, "Failed to rename objects after a naming policy change")
+		return ctrl.Result{}, err
+	}
+
+	// The egress policy must be in place before runners are created, for their traffic to come from the expected IPs
+	if err := r.reconcileEgressPolicy(ctx, autoscalingRunnerSet, log); err != nil {
+		log.Error(err, "Failed to reconcile egress policy")
+		return ctrl.Result{}, err
+	}
+
+	// The job templates have their own runner scale sets, which don't wait for the runners of this one
+	if err := r.reconcileJobTemplates(ctx, autoscalingRunnerSet, log); err != nil {
+		log.Error(err, "Failed to reconcile the AutoscalingRunnerSets of the job templates")
+		return ctrl.Result{}, err
+	}
+
 	secret := new(corev1.Secret)
 	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingRunnerSet.Namespace, Name: autoscalingRunnerSet.Spec.GitHubConfigSecret}, secret); err != nil {
 		log.Error(err, "Failed to find GitHub config secret.",
@@ -215,6 +265,16 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, err
 	}
 
+	if autoscalingRunnerSet.Spec.RunnerGitHubConfigSecret != "" {
+		runnerSecret := new(corev1.Secret)
+		if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingRunnerSet.Namespace, Name: autoscalingRunnerSet.Spec.RunnerGitHubConfigSecret}, runnerSecret); err != nil {
+			log.Error(err, "Failed to find runner GitHub config secret.",
+				"namespace", autoscalingRunnerSet.Namespace,
+				"name", autoscalingRunnerSet.Spec.RunnerGitHubConfigSecret)
+			return ctrl.Result{}, err
+		}
+	}
+
 	existingRunnerSets, err := r.listEphemeralRunnerSets(ctx, autoscalingRunnerSet)
 	if err != nil {
 		log.Error(err, "Failed to list existing ephemeral runner sets")
@@ -232,22 +292,27 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 	}
 
 	// Make sure the AutoscalingListener is up and running in the controller namespace
-	listener := new(v1alpha1.AutoscalingListener)
-	listenerFound := true
-	if err := r.Get(ctx, client.ObjectKey{Namespace: r.ControllerNamespace, Name: scaleSetListenerName(autoscalingRunnerSet)}, listener); err != nil {
-		if !kerrors.IsNotFound(err) {
-			log.Error(err, "Failed to get AutoscalingListener resource")
-			return ctrl.Result{}, err
-		}
-
-		listenerFound = false
+	listener, err := r.getListener(ctx, autoscalingRunnerSet)
+	if err != nil {
+		log.Error(err, "Failed to get AutoscalingListener resource")
+		return ctrl.Result{}, err
+	}
+	listenerFound := listener != nil
+	if !listenerFound {
+		listener = new(v1alpha1.AutoscalingListener)
 		log.Info("AutoscalingListener does not exist.")
 	}
 
 	// Our listener pod is out of date, so we need to delete it to get a new recreate.
 	listenerValuesHashChanged := listener.Annotations[annotationKeyValuesHash] != autoscalingRunnerSet.Annotations[annotationKeyValuesHash]
 	listenerSpecHashChanged := listener.Annotations[annotationKeyRunnerSpecHash] != autoscalingRunnerSet.ListenerSpecHash()
-	if listenerFound && (listenerValuesHashChanged || listenerSpecHashChanged) {
+	// maxRunners of the listener is raised to the maxRunnersWhileBurning of the job queue latency SLO
+	listenerMaxRunnersChanged := listener.Spec.MaxRunners != listenerMaxRunners(autoscalingRunnerSet)
+	// minRunners of the listener is the lowest of the minRunnersSchedule, which the EphemeralRunnerSet raises while a window is active
+	listenerMinRunnersChanged := listener.Spec.MinRunners != listenerMinRunners(autoscalingRunnerSet)
+	// The listener and the objects it owns are renamed when the naming policy changes
+	listenerNamingPolicyChanged := listener.Annotations[annotationKeyNamingPolicyHash] != r.NamingPolicy.Hash() || listener.Name != r.scaleSetListenerName(autoscalingRunnerSet)
+	if listenerFound && (listenerValuesHashChanged || listenerSpecHashChanged || listenerMaxRunnersChanged || listenerMinRunnersChanged || listenerNamingPolicyChanged) {
 		log.Info("RunnerScaleSetListener is out of date. Deleting it so that it is recreated", "name", listener.Name)
 		if err := r.Delete(ctx, listener); err != nil {
 			if kerrors.IsNotFound(err) {
@@ -261,7 +326,24 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, nil
 	}
 
-	if latestRunnerSet.Annotations[annotationKeyRunnerSpecHash] != autoscalingRunnerSet.RunnerSetSpecHash() {
+	// The listener of a paused scale set is deleted, so that it stops acquiring jobs
+	if listenerFound && autoscalingRunnerSet.Paused() {
+		log.Info("AutoscalingRunnerSet is paused. Deleting the listener", "name", listener.Name)
+		if err := r.Delete(ctx, listener); err != nil && !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete AutoscalingListener resource of paused AutoscalingRunnerSet")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// The latest runner set is kept while a new runner image is rolled out to a canary of its runners
+	canarying, err := r.reconcileCanary(ctx, autoscalingRunnerSet, latestRunnerSet, log)
+	if err != nil {
+		log.Error(err, "Failed to reconcile canary")
+		return ctrl.Result{}, err
+	}
+
+	if !canarying && latestRunnerSet.Annotations[annotationKeyRunnerSpecHash] != autoscalingRunnerSet.RunnerSetSpecHash() {
 		if r.drainingJobs(&latestRunnerSet.Status) {
 			log.Info("Latest runner set spec hash does not match the current autoscaling runner set. Waiting for the running and pending runners to finish:", "running", latestRunnerSet.Status.RunningEphemeralRunners, "pending", latestRunnerSet.Status.PendingEphemeralRunners)
 			log.Info("Scaling down the number of desired replicas to 0")
@@ -290,30 +372,106 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 		}
 	}
 
-	// Make sure the AutoscalingListener is up and running in the controller namespace
-	if !listenerFound {
+	if err := r.reconcileContainerHooks(ctx, autoscalingRunnerSet, latestRunnerSet, log); err != nil {
+		log.Error(err, "Failed to reconcile container hooks")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcilePause(ctx, autoscalingRunnerSet, latestRunnerSet, log); err != nil {
+		log.Error(err, "Failed to reconcile pause")
+		return ctrl.Result{}, err
+	}
+
+	// Make sure the AutoscalingListener is up and running in the controller namespace, unless the scale set is paused
+	if !listenerFound && !autoscalingRunnerSet.Paused() {
 		if r.drainingJobs(&latestRunnerSet.Status) {
 			log.Info("Creating a new AutoscalingListener is waiting for the running and pending runners to finish. Waiting for the running and pending runners to finish:", "running", latestRunnerSet.Status.RunningEphemeralRunners, "pending", latestRunnerSet.Status.PendingEphemeralRunners)
 			return ctrl.Result{}, nil
 		}
+
+		// The listener creates the runners, so that no runner is created before the connectivity probe of the runner spec succeeds
+		reachable, probeAfter, err := r.reconcileConnectivityProbe(ctx, autoscalingRunnerSet, latestRunnerSet, log)
+		if err != nil {
+			log.Error(err, "Failed to reconcile connectivity probe")
+			return ctrl.Result{}, err
+		}
+		if !reachable {
+			log.Info("Creating a new AutoscalingListener is waiting for the connectivity probe to succeed")
+			return ctrl.Result{RequeueAfter: probeAfter}, nil
+		}
+
 		log.Info("Creating a new AutoscalingListener for the runner set", "ephemeralRunnerSetName", latestRunnerSet.Name)
 		return r.createAutoScalingListenerForRunnerSet(ctx, autoscalingRunnerSet, latestRunnerSet, log)
 	}
 
 	// Update the status of autoscaling runner set.
-	if latestRunnerSet.Status.CurrentReplicas != autoscalingRunnerSet.Status.CurrentRunners {
+	runnerImage := runnerContainerImage(latestRunnerSet.Spec.EphemeralRunnerSpec.PodTemplateSpec)
+	if latestRunnerSet.Status.CurrentReplicas != autoscalingRunnerSet.Status.CurrentRunners || runnerImage != autoscalingRunnerSet.Status.RunnerImage {
 		if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
 			obj.Status.CurrentRunners = latestRunnerSet.Status.CurrentReplicas
 			obj.Status.PendingEphemeralRunners = latestRunnerSet.Status.PendingEphemeralRunners
 			obj.Status.RunningEphemeralRunners = latestRunnerSet.Status.RunningEphemeralRunners
 			obj.Status.FailedEphemeralRunners = latestRunnerSet.Status.FailedEphemeralRunners
+			obj.Status.RunnerImage = runnerImage
 		}); err != nil {
 			log.Error(err, "Failed to update autoscaling runner set status with current runner count")
 			return ctrl.Result{}, err
 		}
 	}
 
-	return ctrl.Result{}, nil
+	requeueAfter, err := r.reconcileJobQueueLatencySLO(ctx, autoscalingRunnerSet, existingRunnerSets.all(), log)
+	if err != nil {
+		log.Error(err, "Failed to reconcile job queue latency SLO")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileEphemeralRunnerSetMaxReplicas(ctx, autoscalingRunnerSet, latestRunnerSet, log); err != nil {
+		log.Error(err, "Failed to reconcile max replicas of the latest ephemeral runner set")
+		return ctrl.Result{}, err
+	}
+
+	driftCheckAfter, err := r.reconcileScaleSetDrift(ctx, autoscalingRunnerSet, log)
+	if err != nil {
+		log.Error(err, "Failed to reconcile runner scale set drift")
+		return ctrl.Result{}, err
+	}
+	if driftCheckAfter > 0 && (requeueAfter == 0 || driftCheckAfter < requeueAfter) {
+		requeueAfter = driftCheckAfter
+	}
+
+	placeholdersChangeAfter, err := r.reconcilePlaceholders(ctx, autoscalingRunnerSet, latestRunnerSet, log)
+	if err != nil {
+		log.Error(err, "Failed to reconcile placeholders")
+		return ctrl.Result{}, err
+	}
+	if placeholdersChangeAfter > 0 && (requeueAfter == 0 || placeholdersChangeAfter < requeueAfter) {
+		requeueAfter = placeholdersChangeAfter
+	}
+
+	if err := r.reconcileWarmPool(ctx, autoscalingRunnerSet, latestRunnerSet, log); err != nil {
+		log.Error(err, "Failed to reconcile warm pool")
+		return ctrl.Result{}, err
+	}
+
+	admissionChangeAfter, err := r.reconcileAdmissionWindows(ctx, autoscalingRunnerSet, log)
+	if err != nil {
+		log.Error(err, "Failed to reconcile admission windows")
+		return ctrl.Result{}, err
+	}
+	if admissionChangeAfter > 0 && (requeueAfter == 0 || admissionChangeAfter < requeueAfter) {
+		requeueAfter = admissionChangeAfter
+	}
+
+	minRunnersChangeAfter, err := r.reconcileMinRunnersSchedule(ctx, autoscalingRunnerSet, latestRunnerSet, log)
+	if err != nil {
+		log.Error(err, "Failed to reconcile minRunnersSchedule")
+		return ctrl.Result{}, err
+	}
+	if minRunnersChangeAfter > 0 && (requeueAfter == 0 || minRunnersChangeAfter < requeueAfter) {
+		requeueAfter = minRunnersChangeAfter
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // Prevents overprovisioning of runners.
@@ -328,19 +486,18 @@ func (r *AutoscalingRunnerSetReconciler) drainingJobs(latestRunnerSetStatus *v1a
 
 func (r *AutoscalingRunnerSetReconciler) cleanupListener(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) (done bool, err error) {
 	logger.Info("Cleaning up the listener")
-	var listener v1alpha1.AutoscalingListener
-	err = r.Get(ctx, client.ObjectKey{Namespace: r.ControllerNamespace, Name: scaleSetListenerName(autoscalingRunnerSet)}, &listener)
+	listener, err := r.getListener(ctx, autoscalingRunnerSet)
 	switch {
-	case err == nil:
+	case err != nil:
+		return false, fmt.Errorf("failed to get listener: %v", err)
+	case listener != nil:
 		if listener.ObjectMeta.DeletionTimestamp.IsZero() {
 			logger.Info("Deleting the listener")
-			if err := r.Delete(ctx, &listener); err != nil {
+			if err := r.Delete(ctx, listener); err != nil {
 				return false, fmt.Errorf("failed to delete listener: %v", err)
 			}
 		}
 		return false, nil
-	case err != nil && !kerrors.IsNotFound(err):
-		return false, fmt.Errorf("failed to get listener: %v", err)
 	}
 
 	logger.Info("Listener is deleted")
@@ -411,15 +568,13 @@ func (r *AutoscalingRunnerSetReconciler) createRunnerScaleSet(ctx context.Contex
 		return ctrl.Result{}, err
 	}
 
-	runnerGroupId := 1
-	if len(autoscalingRunnerSet.Spec.RunnerGroup) > 0 {
-		runnerGroup, err := actionsClient.GetRunnerGroupByName(ctx, autoscalingRunnerSet.Spec.RunnerGroup)
-		if err != nil {
-			logger.Error(err, "Failed to get runner group by name", "runnerGroup", autoscalingRunnerSet.Spec.RunnerGroup)
-			return ctrl.Result{}, err
-		}
-
-		runnerGroupId = int(runnerGroup.ID)
+	runnerGroupId, err := r.runnerGroupIdFor(ctx, actionsClient, autoscalingRunnerSet, logger)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if runnerGroupId == 0 {
+		// The RunnerGroupPermitted condition tells why the runner scale set can't be created
+		return ctrl.Result{RequeueAfter: runnerGroupPreflightRetryInterval}, nil
 	}
 
 	runnerScaleSet, err := actionsClient.GetRunnerScaleSet(ctx, runnerGroupId, autoscalingRunnerSet.Spec.RunnerScaleSetName)
@@ -438,12 +593,7 @@ func (r *AutoscalingRunnerSetReconciler) createRunnerScaleSet(ctx context.Contex
 			&actions.RunnerScaleSet{
 				Name:          autoscalingRunnerSet.Spec.RunnerScaleSetName,
 				RunnerGroupId: runnerGroupId,
-				Labels: []actions.Label{
-					{
-						Name: autoscalingRunnerSet.Spec.RunnerScaleSetName,
-						Type: "System",
-					},
-				},
+				Labels:        runnerScaleSetLabels(autoscalingRunnerSet.Spec.RunnerScaleSetName, autoscalingRunnerSet.Spec.RunnerScaleSetLabels),
 				RunnerSetting: actions.RunnerSetting{
 					Ephemeral:     true,
 					DisableUpdate: true,
@@ -476,6 +626,7 @@ func (r *AutoscalingRunnerSetReconciler) createRunnerScaleSet(ctx context.Contex
 		obj.Annotations[AnnotationKeyGitHubRunnerScaleSetName] = runnerScaleSet.Name
 		obj.Annotations[runnerScaleSetIdAnnotationKey] = strconv.Itoa(runnerScaleSet.Id)
 		obj.Annotations[AnnotationKeyGitHubRunnerGroupName] = runnerScaleSet.RunnerGroupName
+		obj.Annotations[annotationKeyGitHubRunnerScaleSetLabels] = strings.Join(distinctLabels(obj.Spec.RunnerScaleSetLabels), ",")
 		if err := applyGitHubURLLabels(obj.Spec.GitHubConfigUrl, obj.Labels); err != nil { // should never happen
 			logger.Error(err, "Failed to apply GitHub URL labels")
 		}
@@ -504,15 +655,13 @@ func (r *AutoscalingRunnerSetReconciler) updateRunnerScaleSetRunnerGroup(ctx con
 		return ctrl.Result{}, err
 	}
 
-	runnerGroupId := 1
-	if len(autoscalingRunnerSet.Spec.RunnerGroup) > 0 {
-		runnerGroup, err := actionsClient.GetRunnerGroupByName(ctx, autoscalingRunnerSet.Spec.RunnerGroup)
-		if err != nil {
-			logger.Error(err, "Failed to get runner group by name", "runnerGroup", autoscalingRunnerSet.Spec.RunnerGroup)
-			return ctrl.Result{}, err
-		}
-
-		runnerGroupId = int(runnerGroup.ID)
+	runnerGroupId, err := r.runnerGroupIdFor(ctx, actionsClient, autoscalingRunnerSet, logger)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if runnerGroupId == 0 {
+		// The runner scale set stays in its current runner group until the new one passes the preflight
+		return ctrl.Result{RequeueAfter: runnerGroupPreflightRetryInterval}, nil
 	}
 
 	updatedRunnerScaleSet, err := actionsClient.UpdateRunnerScaleSet(ctx, runnerScaleSetId, &actions.RunnerScaleSet{RunnerGroupId: runnerGroupId})
@@ -739,6 +888,29 @@ func (r *AutoscalingRunnerSetReconciler) actionsClientOptionsFor(ctx context.Con
 		}
 
 		options = append(options, actions.WithRootCAs(pool))
+
+		cert, err := tlsConfig.ToClientCertificate(func(name string) (*corev1.Secret, error) {
+			var secret corev1.Secret
+			err := r.Get(
+				ctx,
+				types.NamespacedName{
+					Namespace: autoscalingRunnerSet.Namespace,
+					Name:      name,
+				},
+				&secret,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+			}
+
+			return &secret, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tls client certificate: %w", err)
+		}
+		if cert != nil {
+			options = append(options, actions.WithClientCertificate(*cert))
+		}
 	}
 
 	return options, nil
@@ -749,6 +921,8 @@ func (r *AutoscalingRunnerSetReconciler) SetupWithManager(mgr ctrl.Manager) erro
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.AutoscalingRunnerSet{}).
 		Owns(&v1alpha1.EphemeralRunnerSet{}).
+		Owns(&v1alpha1.AutoscalingRunnerSet{}).
+		Owns(&batchv1.Job{}).
 		Watches(&v1alpha1.AutoscalingListener{}, handler.EnqueueRequestsFromMapFunc(
 			func(_ context.Context, o client.Object) []reconcile.Request {
 				autoscalingListener := o.(*v1alpha1.AutoscalingListener)
@@ -763,7 +937,7 @@ func (r *AutoscalingRunnerSetReconciler) SetupWithManager(mgr ctrl.Manager) erro
 			},
 		)).
 		WithEventFilter(predicate.ResourceVersionChangedPredicate{}).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap("autoscalingrunnerset", mgr.GetCache(), &v1alpha1.AutoscalingRunnerSet{}, r))
 }
 
 type autoscalingRunnerSetFinalizerDependencyCleaner struct {
@@ -186,7 +186,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			// Check if listener is created
 			Eventually(
 				func() error {
-					return k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, new(v1alpha1.AutoscalingListener))
+					return k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, new(v1alpha1.AutoscalingListener))
 				},
 				autoscalingRunnerSetTestTimeout,
 				autoscalingRunnerSetTestInterval).Should(Succeed(), "Listener should be created")
@@ -204,7 +204,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			// Wait till the listener is created
 			Eventually(
 				func() error {
-					return k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, new(v1alpha1.AutoscalingListener))
+					return k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, new(v1alpha1.AutoscalingListener))
 				},
 				autoscalingRunnerSetTestTimeout,
 				autoscalingRunnerSetTestInterval).Should(Succeed(), "Listener should be created")
@@ -216,7 +216,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			// Check if the listener is deleted
 			Eventually(
 				func() error {
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, new(v1alpha1.AutoscalingListener))
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, new(v1alpha1.AutoscalingListener))
 					if err != nil && errors.IsNotFound(err) {
 						return nil
 					}
@@ -265,7 +265,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			listener := new(v1alpha1.AutoscalingListener)
 			Eventually(
 				func() error {
-					return k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
+					return k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
 				},
 				autoscalingRunnerSetTestTimeout,
 				autoscalingRunnerSetTestInterval).Should(Succeed(), "Listener should be created")
@@ -310,7 +310,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			Eventually(
 				func() (string, error) {
 					listener := new(v1alpha1.AutoscalingListener)
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
 					if err != nil {
 						return "", err
 					}
@@ -329,7 +329,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			runnerSet = runnerSetList.Items[0]
 
 			listener = new(v1alpha1.AutoscalingListener)
-			err = k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
 			Expect(err).NotTo(HaveOccurred(), "failed to get Listener")
 
 			patched = autoscalingRunnerSet.DeepCopy()
@@ -360,7 +360,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			Eventually(
 				func() (string, error) {
 					listener := new(v1alpha1.AutoscalingListener)
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
 					if err != nil {
 						return "", err
 					}
@@ -379,7 +379,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			runnerSet = runnerSetList.Items[0]
 
 			listener = new(v1alpha1.AutoscalingListener)
-			err = k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
 			Expect(err).NotTo(HaveOccurred(), "failed to get Listener")
 
 			patched = autoscalingRunnerSet.DeepCopy()
@@ -409,7 +409,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			Eventually(
 				func() (string, error) {
 					listener := new(v1alpha1.AutoscalingListener)
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
 					if err != nil {
 						return "", err
 					}
@@ -425,7 +425,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			// Wait till the listener is created
 			Eventually(
 				func() error {
-					return k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, new(v1alpha1.AutoscalingListener))
+					return k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, new(v1alpha1.AutoscalingListener))
 				},
 				autoscalingRunnerSetTestTimeout,
 				autoscalingRunnerSetTestInterval).Should(Succeed(), "Listener should be created")
@@ -486,7 +486,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			listener := new(v1alpha1.AutoscalingListener)
 			Eventually(
 				func() error {
-					return k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
+					return k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
 				},
 				autoscalingRunnerSetTestTimeout,
 				autoscalingRunnerSetTestInterval,
@@ -577,7 +577,7 @@ var _ = Describe("Test AutoScalingRunnerSet controller", Ordered, func() {
 			// The listener should not be recreated
 			Consistently(
 				func() error {
-					return k8sClient.Get(ctx, client.ObjectKey{Name: scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
+					return k8sClient.Get(ctx, client.ObjectKey{Name: new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet), Namespace: autoscalingRunnerSet.Namespace}, listener)
 				},
 				autoscalingRunnerSetTestTimeout,
 				autoscalingRunnerSetTestInterval,
@@ -1259,7 +1259,7 @@ var _ = Describe("Test client optional configuration", Ordered, func() {
 					err := k8sClient.Get(
 						ctx,
 						client.ObjectKey{
-							Name:      scaleSetListenerName(autoscalingRunnerSet),
+							Name:      new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet),
 							Namespace: autoscalingRunnerSet.Namespace,
 						},
 						listener,
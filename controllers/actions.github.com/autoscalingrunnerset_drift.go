@@ -0,0 +1,201 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultDriftDetectionInterval = 10 * time.Minute
+
+	// defaultRunnerGroupName is the name of the runner group with the ID 1, which scale sets without spec.runnerGroup belong to.
+	defaultRunnerGroupName = "Default"
+
+	reasonScaleSetInSync   = "InSync"
+	reasonScaleSetDrifted  = "OutOfBandChange"
+	reasonScaleSetReverted = "Reverted"
+)
+
+// reconcileScaleSetDrift compares the runner scale set on GitHub with the spec once per interval of spec.driftDetection,
+// sets the ScaleSetDrifted condition, and reverts the runner scale set to the spec when the action is Reconcile.
+// It returns the delay until the next comparison, or zero when the scale set has no drift detection.
+func (r *AutoscalingRunnerSetReconciler) reconcileScaleSetDrift(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) (time.Duration, error) {
+	driftDetection := autoscalingRunnerSet.Spec.DriftDetection
+	if driftDetection == nil {
+		if autoscalingRunnerSet.Status.LastDriftCheckTime == nil && meta.FindStatusCondition(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionScaleSetDrifted) == nil {
+			return 0, nil
+		}
+
+		logger.Info("Removing the runner scale set drift detection status")
+		return 0, patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			obj.Status.LastDriftCheckTime = nil
+			meta.RemoveStatusCondition(&obj.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionScaleSetDrifted)
+		})
+	}
+
+	interval := defaultDriftDetectionInterval
+	if driftDetection.Interval != nil && driftDetection.Interval.Duration > 0 {
+		interval = driftDetection.Interval.Duration
+	}
+
+	now := time.Now()
+	if last := autoscalingRunnerSet.Status.LastDriftCheckTime; last != nil {
+		if next := last.Add(interval); now.Before(next) {
+			return next.Sub(now), nil
+		}
+	}
+
+	runnerScaleSetId, err := strconv.Atoi(autoscalingRunnerSet.Annotations[runnerScaleSetIdAnnotationKey])
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse runner scale set ID: %w", err)
+	}
+
+	actionsClient, err := r.actionsClientFor(ctx, autoscalingRunnerSet)
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize Actions service client: %w", err)
+	}
+
+	runnerScaleSet, err := actionsClient.GetRunnerScaleSetById(ctx, runnerScaleSetId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get runner scale set %d: %w", runnerScaleSetId, err)
+	}
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.AutoscalingRunnerSetConditionScaleSetDrifted,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonScaleSetInSync,
+		Message:            "The runner scale set matches the spec",
+		ObservedGeneration: autoscalingRunnerSet.Generation,
+	}
+
+	drift := scaleSetDrift(autoscalingRunnerSet, runnerScaleSet)
+	switch {
+	case len(drift) == 0:
+	case driftDetection.Action == v1alpha1.DriftDetectionActionReconcile:
+		logger.Info("Reverting out-of-band changes to the runner scale set", "runnerScaleSetId", runnerScaleSetId, "drift", drift)
+		if err := r.revertScaleSetDrift(ctx, actionsClient, autoscalingRunnerSet, runnerScaleSetId); err != nil {
+			return 0, err
+		}
+		condition.Reason = reasonScaleSetReverted
+		condition.Message = "Reverted out-of-band changes to the runner scale set: " + strings.Join(drift, ", ")
+	default:
+		logger.Info("Runner scale set drifted from the spec", "runnerScaleSetId", runnerScaleSetId, "drift", drift)
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonScaleSetDrifted
+		condition.Message = "The runner scale set drifted from the spec: " + strings.Join(drift, ", ")
+	}
+
+	if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		obj.Status.LastDriftCheckTime = &metav1.Time{Time: now}
+		meta.SetStatusCondition(&obj.Status.Conditions, condition)
+	}); err != nil {
+		return 0, fmt.Errorf("failed to update runner scale set drift status: %w", err)
+	}
+
+	return interval, nil
+}
+
+// scaleSetDrift describes the differences between the runner scale set and the one the controller creates for the spec.
+func scaleSetDrift(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, runnerScaleSet *actions.RunnerScaleSet) []string {
+	var drift []string
+
+	name := scaleSetNameOf(autoscalingRunnerSet)
+	if !strings.EqualFold(runnerScaleSet.Name, name) {
+		drift = append(drift, fmt.Sprintf("name is %q instead of %q", runnerScaleSet.Name, name))
+	}
+
+	runnerGroup := autoscalingRunnerSet.Spec.RunnerGroup
+	if runnerGroup == "" {
+		runnerGroup = defaultRunnerGroupName
+	}
+	if !strings.EqualFold(runnerScaleSet.RunnerGroupName, runnerGroup) {
+		drift = append(drift, fmt.Sprintf("runner group is %q instead of %q", runnerScaleSet.RunnerGroupName, runnerGroup))
+	}
+
+	labels := make([]string, 0, len(runnerScaleSet.Labels))
+	for _, l := range runnerScaleSet.Labels {
+		labels = append(labels, l.Name)
+	}
+	expected := make([]string, 0, len(labels))
+	for _, l := range runnerScaleSetLabels(name, autoscalingRunnerSet.Spec.RunnerScaleSetLabels) {
+		expected = append(expected, l.Name)
+	}
+	if !sameLabels(labels, expected) {
+		drift = append(drift, fmt.Sprintf("labels are [%s] instead of [%s]", strings.Join(labels, " "), strings.Join(expected, " ")))
+	}
+
+	if !runnerScaleSet.RunnerSetting.Ephemeral {
+		drift = append(drift, "runners are not ephemeral")
+	}
+	if !runnerScaleSet.RunnerSetting.DisableUpdate {
+		drift = append(drift, "runner updates are not disabled")
+	}
+
+	return drift
+}
+
+// revertScaleSetDrift updates the runner scale set to the one the controller creates for the spec.
+func (r *AutoscalingRunnerSetReconciler) revertScaleSetDrift(ctx context.Context, actionsClient actions.ActionsService, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, runnerScaleSetId int) error {
+	runnerGroupId := 1
+	if len(autoscalingRunnerSet.Spec.RunnerGroup) > 0 {
+		runnerGroup, err := actionsClient.GetRunnerGroupByName(ctx, autoscalingRunnerSet.Spec.RunnerGroup)
+		if err != nil {
+			return fmt.Errorf("failed to get runner group %q: %w", autoscalingRunnerSet.Spec.RunnerGroup, err)
+		}
+		runnerGroupId = int(runnerGroup.ID)
+	}
+
+	name := scaleSetNameOf(autoscalingRunnerSet)
+	updatedRunnerScaleSet, err := actionsClient.UpdateRunnerScaleSet(ctx, runnerScaleSetId, &actions.RunnerScaleSet{
+		Name:          name,
+		RunnerGroupId: runnerGroupId,
+		Labels:        runnerScaleSetLabels(name, autoscalingRunnerSet.Spec.RunnerScaleSetLabels),
+		RunnerSetting: actions.RunnerSetting{
+			Ephemeral:     true,
+			DisableUpdate: true,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update runner scale set %d: %w", runnerScaleSetId, err)
+	}
+
+	if err := patch(ctx, r.Client, autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		obj.Annotations[AnnotationKeyGitHubRunnerGroupName] = updatedRunnerScaleSet.RunnerGroupName
+		obj.Annotations[AnnotationKeyGitHubRunnerScaleSetName] = updatedRunnerScaleSet.Name
+	}); err != nil {
+		return fmt.Errorf("failed to update runner scale set name and runner group name annotations: %w", err)
+	}
+
+	return nil
+}
+
+// sameLabels returns whether the labels are the same, in any order and case.
+func sameLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, label := range a {
+		if !slices.ContainsFunc(b, func(l string) bool { return strings.EqualFold(l, label) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// scaleSetNameOf returns the name of the runner scale set of the AutoscalingRunnerSet, which defaults to its own name.
+func scaleSetNameOf(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) string {
+	if autoscalingRunnerSet.Spec.RunnerScaleSetName != "" {
+		return autoscalingRunnerSet.Spec.RunnerScaleSetName
+	}
+	return autoscalingRunnerSet.Name
+}
@@ -0,0 +1,214 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/actions/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestScaleSetDrift(t *testing.T) {
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "arc-runners"},
+	}
+
+	inSync := func() *actions.RunnerScaleSet {
+		return &actions.RunnerScaleSet{
+			Name:            "arc-runners",
+			RunnerGroupName: "Default",
+			Labels:          []actions.Label{{Name: "arc-runners", Type: "System"}},
+			RunnerSetting:   actions.RunnerSetting{Ephemeral: true, DisableUpdate: true},
+		}
+	}
+
+	tests := map[string]struct {
+		mutate func(*actions.RunnerScaleSet)
+		want   []string
+	}{
+		"in sync": {
+			mutate: func(*actions.RunnerScaleSet) {},
+		},
+		"names are case insensitive": {
+			mutate: func(s *actions.RunnerScaleSet) {
+				s.Name = "ARC-Runners"
+				s.RunnerGroupName = "default"
+			},
+		},
+		"renamed": {
+			mutate: func(s *actions.RunnerScaleSet) { s.Name = "renamed" },
+			want:   []string{`name is "renamed" instead of "arc-runners"`},
+		},
+		"moved to another runner group": {
+			mutate: func(s *actions.RunnerScaleSet) { s.RunnerGroupName = "other" },
+			want:   []string{`runner group is "other" instead of "Default"`},
+		},
+		"labels added": {
+			mutate: func(s *actions.RunnerScaleSet) {
+				s.Labels = append(s.Labels, actions.Label{Name: "gpu", Type: "User"})
+			},
+			want: []string{"labels are [arc-runners gpu] instead of [arc-runners]"},
+		},
+		"runner settings changed": {
+			mutate: func(s *actions.RunnerScaleSet) { s.RunnerSetting = actions.RunnerSetting{} },
+			want:   []string{"runners are not ephemeral", "runner updates are not disabled"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			scaleSet := inSync()
+			tc.mutate(scaleSet)
+			assert.Equal(t, tc.want, scaleSetDrift(ars, scaleSet))
+		})
+	}
+}
+
+type driftClient struct {
+	actions.ActionsService
+	scaleSet *actions.RunnerScaleSet
+	updates  []*actions.RunnerScaleSet
+}
+
+func (c *driftClient) GetRunnerScaleSetById(_ context.Context, _ int) (*actions.RunnerScaleSet, error) {
+	scaleSet := *c.scaleSet
+	return &scaleSet, nil
+}
+
+func (c *driftClient) GetRunnerGroupByName(_ context.Context, name string) (*actions.RunnerGroup, error) {
+	return &actions.RunnerGroup{ID: 3, Name: name}, nil
+}
+
+func (c *driftClient) UpdateRunnerScaleSet(_ context.Context, _ int, scaleSet *actions.RunnerScaleSet) (*actions.RunnerScaleSet, error) {
+	c.updates = append(c.updates, scaleSet)
+	c.scaleSet = scaleSet
+	c.scaleSet.RunnerGroupName = "linux"
+	return scaleSet, nil
+}
+
+func TestReconcileScaleSetDrift(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newReconciler := func(action string, actionsClient actions.ActionsService) (*AutoscalingRunnerSetReconciler, *v1alpha1.AutoscalingRunnerSet) {
+		ars := &v1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "arc-runners",
+				Namespace: "arc-runners",
+				Annotations: map[string]string{
+					runnerScaleSetIdAnnotationKey:         "1",
+					AnnotationKeyGitHubRunnerGroupName:    "linux",
+					AnnotationKeyGitHubRunnerScaleSetName: "arc-runners",
+				},
+			},
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{
+				GitHubConfigUrl:    "https://github.com/owner/repo",
+				GitHubConfigSecret: "github-config",
+				RunnerGroup:        "linux",
+				DriftDetection: &v1alpha1.DriftDetection{
+					Action:   action,
+					Interval: &metav1.Duration{Duration: 5 * time.Minute},
+				},
+			},
+		}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "github-config", Namespace: "arc-runners"}}
+
+		r := &AutoscalingRunnerSetReconciler{
+			Client:        crfake.NewClientBuilder().WithScheme(scheme).WithObjects(ars, secret).WithStatusSubresource(ars).Build(),
+			Scheme:        scheme,
+			ActionsClient: fake.NewMultiClient(fake.WithDefaultClient(actionsClient, nil)),
+		}
+		return r, ars
+	}
+
+	drifted := func() *actions.RunnerScaleSet {
+		return &actions.RunnerScaleSet{
+			Name:            "renamed",
+			RunnerGroupName: "linux",
+			Labels:          []actions.Label{{Name: "renamed", Type: "System"}},
+			RunnerSetting:   actions.RunnerSetting{Ephemeral: true, DisableUpdate: true},
+		}
+	}
+
+	t.Run("warns about drift", func(t *testing.T) {
+		actionsClient := &driftClient{scaleSet: drifted()}
+		r, ars := newReconciler(v1alpha1.DriftDetectionActionWarn, actionsClient)
+
+		requeueAfter, err := r.reconcileScaleSetDrift(ctx, ars, logr.Discard())
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Minute, requeueAfter)
+		assert.Empty(t, actionsClient.updates)
+
+		var updated v1alpha1.AutoscalingRunnerSet
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), &updated))
+		require.NotNil(t, updated.Status.LastDriftCheckTime)
+		condition := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionScaleSetDrifted)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, reasonScaleSetDrifted, condition.Reason)
+		assert.Contains(t, condition.Message, `name is "renamed" instead of "arc-runners"`)
+
+		// The runner scale set isn't fetched again until the interval elapses
+		actionsClient.scaleSet = nil
+		requeueAfter, err = r.reconcileScaleSetDrift(ctx, &updated, logr.Discard())
+		require.NoError(t, err)
+		assert.InDelta(t, float64(5*time.Minute), float64(requeueAfter), float64(time.Minute))
+	})
+
+	t.Run("reverts drift", func(t *testing.T) {
+		actionsClient := &driftClient{scaleSet: drifted()}
+		r, ars := newReconciler(v1alpha1.DriftDetectionActionReconcile, actionsClient)
+
+		_, err := r.reconcileScaleSetDrift(ctx, ars, logr.Discard())
+		require.NoError(t, err)
+		require.Len(t, actionsClient.updates, 1)
+		assert.Equal(t, &actions.RunnerScaleSet{
+			Name:            "arc-runners",
+			RunnerGroupId:   3,
+			RunnerGroupName: "linux",
+			Labels:          []actions.Label{{Name: "arc-runners", Type: "System"}},
+			RunnerSetting:   actions.RunnerSetting{Ephemeral: true, DisableUpdate: true},
+		}, actionsClient.updates[0])
+
+		var updated v1alpha1.AutoscalingRunnerSet
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), &updated))
+		condition := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionScaleSetDrifted)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonScaleSetReverted, condition.Reason)
+		assert.Equal(t, "arc-runners", updated.Annotations[AnnotationKeyGitHubRunnerScaleSetName])
+	})
+
+	t.Run("clears the status when disabled", func(t *testing.T) {
+		actionsClient := &driftClient{scaleSet: drifted()}
+		r, ars := newReconciler(v1alpha1.DriftDetectionActionWarn, actionsClient)
+
+		_, err := r.reconcileScaleSetDrift(ctx, ars, logr.Discard())
+		require.NoError(t, err)
+
+		ars.Spec.DriftDetection = nil
+		requeueAfter, err := r.reconcileScaleSetDrift(ctx, ars, logr.Discard())
+		require.NoError(t, err)
+		assert.Zero(t, requeueAfter)
+
+		var updated v1alpha1.AutoscalingRunnerSet
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), &updated))
+		assert.Nil(t, updated.Status.LastDriftCheckTime)
+		assert.Empty(t, updated.Status.Conditions)
+	})
+}
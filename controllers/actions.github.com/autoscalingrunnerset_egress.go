@@ -0,0 +1,184 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EgressPolicyKind is a kind of egress policy resource that the scale sets are allowed to create through spec.egressPolicy.
+type EgressPolicyKind struct {
+	schema.GroupKind
+
+	// SelectorPath is the path of the label selector of the pods in the resource, like spec.podSelector.
+	// The controller sets it to the runner pods of the scale set. It's empty for kinds that don't select pods.
+	SelectorPath []string
+}
+
+// ParseEgressPolicyKind parses an egress policy kind in the KIND.GROUP[:SELECTOR_PATH] format,
+// like StaticGatewayConfiguration.egressgateway.kubernetes.azure.com or NetworkPolicy.networking.k8s.io:spec.podSelector.
+func ParseEgressPolicyKind(s string) (EgressPolicyKind, error) {
+	groupKind, selectorPath, _ := strings.Cut(s, ":")
+	kind := EgressPolicyKind{GroupKind: schema.ParseGroupKind(groupKind)}
+	if kind.Kind == "" {
+		return EgressPolicyKind{}, fmt.Errorf("egress policy kind %q has no kind", s)
+	}
+	if selectorPath != "" {
+		kind.SelectorPath = strings.Split(selectorPath, ".")
+		for _, field := range kind.SelectorPath {
+			if field == "" {
+				return EgressPolicyKind{}, fmt.Errorf("egress policy kind %q has an invalid selector path", s)
+			}
+		}
+	}
+	return kind, nil
+}
+
+// allowedEgressPolicyKind returns the allowed egress policy kind of the group and kind, or false when it isn't allowed.
+func (r *AutoscalingRunnerSetReconciler) allowedEgressPolicyKind(groupKind schema.GroupKind) (EgressPolicyKind, bool) {
+	for _, kind := range r.EgressPolicyKinds {
+		if kind.GroupKind == groupKind {
+			return kind, true
+		}
+	}
+	return EgressPolicyKind{}, false
+}
+
+// selectRunnerPods sets the selector of the egress policy to the runner pods of the scale set.
+// The template can't set the selector itself, as it could select the pods of other scale sets or workloads.
+func selectRunnerPods(policy *unstructured.Unstructured, kind EgressPolicyKind, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) error {
+	if len(kind.SelectorPath) == 0 {
+		return nil
+	}
+
+	path := strings.Join(kind.SelectorPath, ".")
+	if _, found, _ := unstructured.NestedFieldNoCopy(policy.Object, kind.SelectorPath...); found {
+		return fmt.Errorf("egress policy template sets %s, which the controller sets to the runner pods of the scale set", path)
+	}
+
+	selector := map[string]interface{}{
+		"matchLabels": map[string]interface{}{
+			LabelKeyKubernetesComponent:     "runner",
+			LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
+			LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
+		},
+	}
+	if err := unstructured.SetNestedField(policy.Object, selector, kind.SelectorPath...); err != nil {
+		return fmt.Errorf("failed to set %s of egress policy: %v", path, err)
+	}
+	return nil
+}
+
+// reconcileEgressPolicy makes sure the egress policy resource of the scale set matches spec.egressPolicy.
+// Only the namespaced kinds of EgressPolicyKinds are created, and their selector is always the runner pods of the scale set.
+// The previously created resource is deleted when spec.egressPolicy is removed or changed to another kind.
+func (r *AutoscalingRunnerSetReconciler) reconcileEgressPolicy(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) error {
+	var desired *unstructured.Unstructured
+	if autoscalingRunnerSet.Spec.EgressPolicy != nil {
+		policy, err := r.ResourceBuilder.newEgressPolicy(autoscalingRunnerSet)
+		if err != nil {
+			return err
+		}
+
+		groupKind := policy.GroupVersionKind().GroupKind()
+		kind, ok := r.allowedEgressPolicyKind(groupKind)
+		if !ok {
+			return fmt.Errorf("egress policy kind %s isn't allowed by the --egress-policy-kind flags of the controller", groupKind)
+		}
+
+		namespaced, err := r.IsObjectNamespaced(policy)
+		if err != nil {
+			return fmt.Errorf("failed to determine the scope of egress policy %s: %v", policy.GroupVersionKind(), err)
+		}
+		if !namespaced {
+			return fmt.Errorf("egress policy kind %s is cluster-scoped, only namespaced kinds can be created", groupKind)
+		}
+
+		if err := selectRunnerPods(policy, kind, autoscalingRunnerSet); err != nil {
+			return err
+		}
+
+		if err := ctrl.SetControllerReference(autoscalingRunnerSet, policy, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set controller reference on egress policy: %v", err)
+		}
+
+		desired = policy
+	}
+
+	var ref *corev1.ObjectReference
+	if desired != nil {
+		ref = &corev1.ObjectReference{
+			APIVersion: desired.GetAPIVersion(),
+			Kind:       desired.GetKind(),
+			Namespace:  desired.GetNamespace(),
+			Name:       desired.GetName(),
+		}
+	}
+
+	if current := autoscalingRunnerSet.Status.EgressPolicyRef; current != nil && !equality.Semantic.DeepEqual(current, ref) {
+		if err := r.deleteEgressPolicy(ctx, current, logger); err != nil {
+			return err
+		}
+	}
+
+	if desired != nil {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(desired.GroupVersionKind())
+
+		err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+		switch {
+		case kerrors.IsNotFound(err):
+			logger.Info("Creating egress policy", "kind", desired.GetKind(), "name", desired.GetName())
+			if err := r.Create(ctx, desired); err != nil {
+				return fmt.Errorf("failed to create egress policy: %v", err)
+			}
+		case err != nil:
+			return fmt.Errorf("failed to get egress policy: %v", err)
+		case existing.GetAnnotations()[annotationKeyValuesHash] != desired.GetAnnotations()[annotationKeyValuesHash]:
+			logger.Info("Updating egress policy", "kind", desired.GetKind(), "name", desired.GetName())
+			desired.SetResourceVersion(existing.GetResourceVersion())
+			if err := r.Update(ctx, desired); err != nil {
+				return fmt.Errorf("failed to update egress policy: %v", err)
+			}
+		}
+	}
+
+	if equality.Semantic.DeepEqual(autoscalingRunnerSet.Status.EgressPolicyRef, ref) {
+		return nil
+	}
+
+	if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		obj.Status.EgressPolicyRef = ref
+	}); err != nil {
+		return fmt.Errorf("failed to update egress policy reference in status: %v", err)
+	}
+
+	return nil
+}
+
+func (r *AutoscalingRunnerSetReconciler) deleteEgressPolicy(ctx context.Context, ref *corev1.ObjectReference, logger logr.Logger) error {
+	policy := &unstructured.Unstructured{}
+	policy.SetAPIVersion(ref.APIVersion)
+	policy.SetKind(ref.Kind)
+	policy.SetNamespace(ref.Namespace)
+	policy.SetName(ref.Name)
+
+	logger.Info("Deleting egress policy", "kind", ref.Kind, "name", ref.Name)
+	// The kind is no longer served when the egress gateway solution has been uninstalled
+	if err := r.Delete(ctx, policy); err != nil && !kerrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return fmt.Errorf("failed to delete egress policy: %v", err)
+	}
+
+	return nil
+}
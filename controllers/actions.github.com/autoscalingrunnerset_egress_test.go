@@ -0,0 +1,241 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var (
+	ciliumEgressGatewayPolicyGVK = schema.GroupVersionKind{Group: "cilium.io", Version: "v2", Kind: "CiliumEgressGatewayPolicy"}
+	staticGatewayConfigGVK       = schema.GroupVersionKind{Group: "egressgateway.kubernetes.azure.com", Version: "v1alpha1", Kind: "StaticGatewayConfiguration"}
+	networkPolicyGVK             = schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"}
+)
+
+const ciliumEgressGatewayPolicyTemplate = `{
+  "apiVersion": "cilium.io/v2",
+  "kind": "CiliumEgressGatewayPolicy",
+  "spec": {
+    "selectors": [{"podSelector": {"matchLabels": {"actions.github.com/scale-set-name": "test-scale-set"}}}],
+    "destinationCIDRs": ["10.0.0.0/8"],
+    "egressGateway": {"nodeSelector": {"matchLabels": {"egress-gateway": "true"}}, "egressIP": "192.0.2.10"}
+  }
+}`
+
+const staticGatewayConfigTemplate = `{
+  "apiVersion": "egressgateway.kubernetes.azure.com/v1alpha1",
+  "kind": "StaticGatewayConfiguration",
+  "spec": {"gatewayNodepoolName": "egress"}
+}`
+
+const networkPolicyTemplate = `{
+  "apiVersion": "networking.k8s.io/v1",
+  "kind": "NetworkPolicy",
+  "spec": {"policyTypes": ["Egress"], "egress": [{"to": [{"ipBlock": {"cidr": "10.0.0.0/8"}}]}]}
+}`
+
+func newEgressPolicyTestReconciler(t *testing.T, objs ...client.Object) *AutoscalingRunnerSetReconciler {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{
+		v1alpha1.GroupVersion,
+		ciliumEgressGatewayPolicyGVK.GroupVersion(),
+		staticGatewayConfigGVK.GroupVersion(),
+		networkPolicyGVK.GroupVersion(),
+	})
+	mapper.Add(v1alpha1.GroupVersion.WithKind("AutoscalingRunnerSet"), meta.RESTScopeNamespace)
+	mapper.Add(ciliumEgressGatewayPolicyGVK, meta.RESTScopeRoot)
+	mapper.Add(staticGatewayConfigGVK, meta.RESTScopeNamespace)
+	mapper.Add(networkPolicyGVK, meta.RESTScopeNamespace)
+
+	allowed := make([]EgressPolicyKind, 0, 3)
+	for _, k := range []string{
+		"CiliumEgressGatewayPolicy.cilium.io:spec.selectors",
+		"StaticGatewayConfiguration.egressgateway.kubernetes.azure.com",
+		"NetworkPolicy.networking.k8s.io:spec.podSelector",
+	} {
+		kind, err := ParseEgressPolicyKind(k)
+		require.NoError(t, err)
+		allowed = append(allowed, kind)
+	}
+
+	return &AutoscalingRunnerSetReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithRESTMapper(mapper).
+			WithObjects(objs...).
+			WithStatusSubresource(objs...).
+			Build(),
+		Scheme:            scheme,
+		EgressPolicyKinds: allowed,
+	}
+}
+
+func newEgressPolicyTestRunnerSet(template string) *v1alpha1.AutoscalingRunnerSet {
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scale-set",
+			Namespace: "test-ns",
+			UID:       "test-uid",
+		},
+	}
+	if template != "" {
+		ars.Spec.EgressPolicy = &v1alpha1.EgressPolicyConfig{Template: runtime.RawExtension{Raw: []byte(template)}}
+	}
+	return ars
+}
+
+func getEgressPolicy(t *testing.T, r *AutoscalingRunnerSetReconciler, gvk schema.GroupVersionKind, key client.ObjectKey) (*unstructured.Unstructured, error) {
+	t.Helper()
+
+	policy := &unstructured.Unstructured{}
+	policy.SetGroupVersionKind(gvk)
+	return policy, r.Get(context.Background(), key, policy)
+}
+
+func TestParseEgressPolicyKind(t *testing.T) {
+	kind, err := ParseEgressPolicyKind("NetworkPolicy.networking.k8s.io:spec.podSelector")
+	require.NoError(t, err)
+	assert.Equal(t, schema.GroupKind{Group: "networking.k8s.io", Kind: "NetworkPolicy"}, kind.GroupKind)
+	assert.Equal(t, []string{"spec", "podSelector"}, kind.SelectorPath)
+
+	kind, err = ParseEgressPolicyKind("StaticGatewayConfiguration.egressgateway.kubernetes.azure.com")
+	require.NoError(t, err)
+	assert.Equal(t, "egressgateway.kubernetes.azure.com", kind.Group)
+	assert.Empty(t, kind.SelectorPath)
+
+	_, err = ParseEgressPolicyKind(".networking.k8s.io")
+	assert.Error(t, err)
+	_, err = ParseEgressPolicyKind("NetworkPolicy.networking.k8s.io:spec..podSelector")
+	assert.Error(t, err)
+}
+
+func TestReconcileEgressPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("namespaced policy", func(t *testing.T) {
+		ars := newEgressPolicyTestRunnerSet(staticGatewayConfigTemplate)
+		r := newEgressPolicyTestReconciler(t, ars)
+
+		require.NoError(t, r.reconcileEgressPolicy(ctx, ars, logr.Discard()))
+
+		name := r.scaleSetEgressPolicyName(ars)
+		policy, err := getEgressPolicy(t, r, staticGatewayConfigGVK, client.ObjectKey{Namespace: ars.Namespace, Name: name})
+		require.NoError(t, err)
+		require.Len(t, policy.GetOwnerReferences(), 1)
+		assert.Equal(t, ars.Name, policy.GetOwnerReferences()[0].Name)
+		assert.Equal(t, "egress-policy", policy.GetLabels()[LabelKeyKubernetesComponent])
+		assert.Equal(t, ars.Name, policy.GetLabels()[LabelKeyGitHubScaleSetName])
+
+		require.NotNil(t, ars.Status.EgressPolicyRef)
+		assert.Equal(t, "StaticGatewayConfiguration", ars.Status.EgressPolicyRef.Kind)
+		assert.Equal(t, ars.Namespace, ars.Status.EgressPolicyRef.Namespace)
+		assert.Equal(t, name, ars.Status.EgressPolicyRef.Name)
+
+		// Updated when the template changes
+		ars.Spec.EgressPolicy.Template.Raw = []byte(`{"apiVersion": "egressgateway.kubernetes.azure.com/v1alpha1", "kind": "StaticGatewayConfiguration", "spec": {"gatewayNodepoolName": "other"}}`)
+		require.NoError(t, r.reconcileEgressPolicy(ctx, ars, logr.Discard()))
+
+		policy, err = getEgressPolicy(t, r, staticGatewayConfigGVK, client.ObjectKey{Namespace: ars.Namespace, Name: name})
+		require.NoError(t, err)
+		nodepool, _, _ := unstructured.NestedString(policy.Object, "spec", "gatewayNodepoolName")
+		assert.Equal(t, "other", nodepool)
+
+		// The previous policy is deleted when the template changes to another kind
+		ars.Spec.EgressPolicy.Template.Raw = []byte(networkPolicyTemplate)
+		require.NoError(t, r.reconcileEgressPolicy(ctx, ars, logr.Discard()))
+
+		_, err = getEgressPolicy(t, r, staticGatewayConfigGVK, client.ObjectKey{Namespace: ars.Namespace, Name: name})
+		assert.True(t, kerrors.IsNotFound(err))
+		_, err = getEgressPolicy(t, r, networkPolicyGVK, client.ObjectKey{Namespace: ars.Namespace, Name: name})
+		require.NoError(t, err)
+
+		// Deleted when the egress policy is removed from the spec
+		ars.Spec.EgressPolicy = nil
+		require.NoError(t, r.reconcileEgressPolicy(ctx, ars, logr.Discard()))
+
+		_, err = getEgressPolicy(t, r, networkPolicyGVK, client.ObjectKey{Namespace: ars.Namespace, Name: name})
+		assert.True(t, kerrors.IsNotFound(err))
+		assert.Nil(t, ars.Status.EgressPolicyRef)
+	})
+
+	t.Run("selects the runner pods of the scale set", func(t *testing.T) {
+		ars := newEgressPolicyTestRunnerSet(networkPolicyTemplate)
+		r := newEgressPolicyTestReconciler(t, ars)
+
+		require.NoError(t, r.reconcileEgressPolicy(ctx, ars, logr.Discard()))
+
+		policy, err := getEgressPolicy(t, r, networkPolicyGVK, client.ObjectKey{Namespace: ars.Namespace, Name: r.scaleSetEgressPolicyName(ars)})
+		require.NoError(t, err)
+		matchLabels, _, _ := unstructured.NestedStringMap(policy.Object, "spec", "podSelector", "matchLabels")
+		assert.Equal(t, map[string]string{
+			LabelKeyKubernetesComponent:     "runner",
+			LabelKeyGitHubScaleSetName:      ars.Name,
+			LabelKeyGitHubScaleSetNamespace: ars.Namespace,
+		}, matchLabels)
+	})
+
+	t.Run("rejects templates setting the selector", func(t *testing.T) {
+		ars := newEgressPolicyTestRunnerSet(`{"apiVersion": "networking.k8s.io/v1", "kind": "NetworkPolicy", "spec": {"podSelector": {}}}`)
+		r := newEgressPolicyTestReconciler(t, ars)
+
+		err := r.reconcileEgressPolicy(ctx, ars, logr.Discard())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "sets spec.podSelector")
+
+		_, err = getEgressPolicy(t, r, networkPolicyGVK, client.ObjectKey{Namespace: ars.Namespace, Name: r.scaleSetEgressPolicyName(ars)})
+		assert.True(t, kerrors.IsNotFound(err))
+	})
+
+	t.Run("rejects cluster-scoped kinds", func(t *testing.T) {
+		ars := newEgressPolicyTestRunnerSet(ciliumEgressGatewayPolicyTemplate)
+		r := newEgressPolicyTestReconciler(t, ars)
+
+		err := r.reconcileEgressPolicy(ctx, ars, logr.Discard())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is cluster-scoped")
+
+		_, err = getEgressPolicy(t, r, ciliumEgressGatewayPolicyGVK, client.ObjectKey{Name: r.scaleSetEgressPolicyName(ars)})
+		assert.True(t, kerrors.IsNotFound(err))
+	})
+
+	t.Run("rejects kinds that aren't allowed", func(t *testing.T) {
+		ars := newEgressPolicyTestRunnerSet(`{"apiVersion": "v1", "kind": "ConfigMap", "data": {"key": "value"}}`)
+		r := newEgressPolicyTestReconciler(t, ars)
+
+		err := r.reconcileEgressPolicy(ctx, ars, logr.Discard())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "isn't allowed")
+	})
+
+	t.Run("deletes a policy created before its kind was disallowed", func(t *testing.T) {
+		ars := newEgressPolicyTestRunnerSet("")
+		ars.Status.EgressPolicyRef = &corev1.ObjectReference{APIVersion: "cilium.io/v2", Kind: "CiliumEgressGatewayPolicy", Name: new(ResourceBuilder).scaleSetEgressPolicyName(ars)}
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(ciliumEgressGatewayPolicyGVK)
+		existing.SetName(new(ResourceBuilder).scaleSetEgressPolicyName(ars))
+		r := newEgressPolicyTestReconciler(t, ars, existing)
+
+		require.NoError(t, r.reconcileEgressPolicy(ctx, ars, logr.Discard()))
+
+		_, err := getEgressPolicy(t, r, ciliumEgressGatewayPolicyGVK, client.ObjectKey{Name: r.scaleSetEgressPolicyName(ars)})
+		assert.True(t, kerrors.IsNotFound(err))
+		assert.Nil(t, ars.Status.EgressPolicyRef)
+	})
+}
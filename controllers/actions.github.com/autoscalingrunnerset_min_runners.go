@@ -0,0 +1,100 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+)
+
+// reconcileMinRunnersSchedule raises the replicas of the latest EphemeralRunnerSet above the ones requested by the listener
+// by the difference between the minRunners of the windows of spec.minRunnersSchedule active now and the minRunners of the listener,
+// so that the listener isn't recreated when a window starts or ends.
+// It returns the delay until the next window starts or ends, or zero when there is no window.
+func (r *AutoscalingRunnerSetReconciler) reconcileMinRunnersSchedule(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, latestRunnerSet *v1alpha1.EphemeralRunnerSet, logger logr.Logger) (time.Duration, error) {
+	now := time.Now()
+	additionalReplicas, nextChange, err := ephemeralRunnerSetAdditionalReplicas(autoscalingRunnerSet, now)
+	if err != nil {
+		logger.Error(err, "Invalid minRunnersSchedule, using minRunners")
+	}
+
+	if additionalReplicas != latestRunnerSet.Spec.AdditionalReplicas {
+		logger.Info("Updating additional replicas of the latest ephemeral runner set for the minRunnersSchedule", "name", latestRunnerSet.Name, "additionalReplicas", additionalReplicas)
+		if err := patch(ctx, r.Client, latestRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+			obj.Spec.AdditionalReplicas = additionalReplicas
+			// The runners are scaled on a patch of the listener, or while there is none
+			obj.Spec.PatchID = 0
+		}); err != nil {
+			return 0, fmt.Errorf("failed to patch additional replicas of ephemeral runner set %s: %w", latestRunnerSet.Name, err)
+		}
+	}
+
+	if nextChange.IsZero() {
+		return 0, nil
+	}
+	return nextChange.Sub(now), nil
+}
+
+// listenerMinRunners returns the minRunners of the listener of the scale set, which is the lowest of spec.minRunners
+// and the minRunners of the windows of spec.minRunnersSchedule, up to the maxRunners of the listener.
+// The EphemeralRunnerSet adds the difference with the minRunners of the windows active at a time to the replicas requested by the listener.
+func listenerMinRunners(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) int {
+	minRunners := 0
+	if autoscalingRunnerSet.Spec.MinRunners != nil {
+		minRunners = *autoscalingRunnerSet.Spec.MinRunners
+	}
+
+	for _, w := range autoscalingRunnerSet.Spec.MinRunnersSchedule {
+		minRunners = min(minRunners, w.MinRunners)
+	}
+
+	return min(minRunners, listenerMaxRunners(autoscalingRunnerSet))
+}
+
+// ephemeralRunnerSetAdditionalReplicas returns the AdditionalReplicas of the EphemeralRunnerSet of the scale set at now,
+// and the next time any of the windows of spec.minRunnersSchedule starts or ends.
+// An invalid schedule is ignored, with spec.minRunners applying at all times.
+func ephemeralRunnerSetAdditionalReplicas(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, now time.Time) (int, time.Time, error) {
+	if len(autoscalingRunnerSet.Spec.MinRunnersSchedule) == 0 || autoscalingRunnerSet.Paused() {
+		return 0, time.Time{}, nil
+	}
+
+	minRunners, nextChange, err := scheduledMinRunners(autoscalingRunnerSet, now)
+
+	return minRunners - listenerMinRunners(autoscalingRunnerSet), nextChange, err
+}
+
+// scheduledMinRunners returns the minRunners of the scale set at now, which is the largest minRunners
+// of the windows of spec.minRunnersSchedule active at now, or spec.minRunners when none is,
+// and the next time any of the windows starts or ends.
+func scheduledMinRunners(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, now time.Time) (int, time.Time, error) {
+	minRunners := 0
+	if autoscalingRunnerSet.Spec.MinRunners != nil {
+		minRunners = *autoscalingRunnerSet.Spec.MinRunners
+	}
+
+	var (
+		active     bool
+		scheduled  int
+		nextChange time.Time
+	)
+	for i, w := range autoscalingRunnerSet.Spec.MinRunnersSchedule {
+		windowActive, next, err := w.Active(now)
+		if err != nil {
+			return minRunners, time.Time{}, fmt.Errorf("invalid minRunners window %d: %v", i, err)
+		}
+
+		if windowActive && (!active || w.MinRunners > scheduled) {
+			scheduled = w.MinRunners
+			active = true
+		}
+		nextChange = v1alpha1.EarliestChange(nextChange, next)
+	}
+
+	if active {
+		minRunners = scheduled
+	}
+	return min(minRunners, listenerMaxRunners(autoscalingRunnerSet)), nextChange, nil
+}
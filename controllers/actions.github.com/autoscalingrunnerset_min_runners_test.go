@@ -0,0 +1,130 @@
+package actionsgithubcom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledMinRunners(t *testing.T) {
+	newARS := func(minRunners, maxRunners *int, windows ...v1alpha1.MinRunnersWindow) *v1alpha1.AutoscalingRunnerSet {
+		return &v1alpha1.AutoscalingRunnerSet{
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{
+				MinRunners:         minRunners,
+				MaxRunners:         maxRunners,
+				MinRunnersSchedule: windows,
+			},
+		}
+	}
+
+	intPtr := func(i int) *int { return &i }
+
+	// 2024-05-06 is a Monday
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 5, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	businessHours := v1alpha1.MinRunnersWindow{
+		DailyWindow: v1alpha1.DailyWindow{
+			Days:  []v1alpha1.WindowDay{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
+			Start: "08:00",
+			End:   "18:00",
+		},
+		MinRunners: 5,
+	}
+
+	t.Run("without a schedule", func(t *testing.T) {
+		minRunners, next, err := scheduledMinRunners(newARS(intPtr(2), nil), at(6, 10))
+		require.NoError(t, err)
+		assert.Equal(t, 2, minRunners)
+		assert.True(t, next.IsZero())
+	})
+
+	t.Run("within a window", func(t *testing.T) {
+		minRunners, next, err := scheduledMinRunners(newARS(nil, nil, businessHours), at(6, 10))
+		require.NoError(t, err)
+		assert.Equal(t, 5, minRunners)
+		assert.WithinDuration(t, at(6, 18), next, 0)
+	})
+
+	t.Run("outside of the windows", func(t *testing.T) {
+		minRunners, next, err := scheduledMinRunners(newARS(intPtr(1), nil, businessHours), at(10, 20))
+		require.NoError(t, err)
+		assert.Equal(t, 1, minRunners)
+		assert.WithinDuration(t, at(13, 8), next, 0, "the next window starts on Monday")
+	})
+
+	t.Run("overrides minRunners with a lower value", func(t *testing.T) {
+		overnight := v1alpha1.MinRunnersWindow{DailyWindow: v1alpha1.DailyWindow{Start: "22:00", End: "06:00"}, MinRunners: 0}
+		minRunners, next, err := scheduledMinRunners(newARS(intPtr(3), nil, overnight), at(7, 2))
+		require.NoError(t, err)
+		assert.Equal(t, 0, minRunners)
+		assert.WithinDuration(t, at(7, 6), next, 0)
+	})
+
+	t.Run("the largest of the active windows up to maxRunners", func(t *testing.T) {
+		peak := v1alpha1.MinRunnersWindow{DailyWindow: v1alpha1.DailyWindow{Start: "09:00", End: "11:00"}, MinRunners: 20}
+		minRunners, next, err := scheduledMinRunners(newARS(nil, intPtr(10), businessHours, peak), at(6, 10))
+		require.NoError(t, err)
+		assert.Equal(t, 10, minRunners)
+		assert.WithinDuration(t, at(6, 11), next, 0)
+	})
+
+	t.Run("in the time zone of the window", func(t *testing.T) {
+		window := businessHours
+		window.TimeZone = "America/New_York"
+		minRunners, _, err := scheduledMinRunners(newARS(nil, nil, window), at(6, 10))
+		require.NoError(t, err)
+		assert.Equal(t, 0, minRunners, "it's 6:00 in New York")
+	})
+
+	t.Run("invalid windows", func(t *testing.T) {
+		window := businessHours
+		window.TimeZone = "Nowhere/Nowhere"
+		minRunners, _, err := scheduledMinRunners(newARS(intPtr(1), nil, window), at(6, 10))
+		assert.Error(t, err)
+		assert.Equal(t, 1, minRunners)
+	})
+}
+
+func TestEphemeralRunnerSetAdditionalReplicas(t *testing.T) {
+	minRunners, maxRunners := 2, 10
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			MinRunners: &minRunners,
+			MaxRunners: &maxRunners,
+			MinRunnersSchedule: []v1alpha1.MinRunnersWindow{
+				{DailyWindow: v1alpha1.DailyWindow{Start: "08:00", End: "18:00"}, MinRunners: 5},
+				{DailyWindow: v1alpha1.DailyWindow{Start: "22:00", End: "06:00"}, MinRunners: 0},
+			},
+		},
+	}
+
+	// The listener keeps the lowest minRunners of the schedule at all times
+	assert.Equal(t, 0, listenerMinRunners(ars))
+
+	additionalReplicas, next, err := ephemeralRunnerSetAdditionalReplicas(ars, time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 5, additionalReplicas)
+	assert.WithinDuration(t, time.Date(2024, 5, 6, 18, 0, 0, 0, time.UTC), next, 0)
+
+	additionalReplicas, _, err = ephemeralRunnerSetAdditionalReplicas(ars, time.Date(2024, 5, 6, 20, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 2, additionalReplicas, "minRunners applies outside of the windows")
+
+	additionalReplicas, _, err = ephemeralRunnerSetAdditionalReplicas(ars, time.Date(2024, 5, 6, 23, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 0, additionalReplicas)
+
+	// The runner set is capped to maxRunners, as the additional replicas are added on top of the ones requested by the listener
+	require.NotNil(t, ephemeralRunnerSetMaxReplicas(ars))
+	assert.Equal(t, 10, *ephemeralRunnerSetMaxReplicas(ars))
+
+	ars.Annotations = map[string]string{v1alpha1.AnnotationKeyPaused: "true"}
+	additionalReplicas, _, err = ephemeralRunnerSetAdditionalReplicas(ars, time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 0, additionalReplicas, "no runner is created while paused")
+}
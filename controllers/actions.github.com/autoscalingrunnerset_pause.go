@@ -0,0 +1,57 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const reasonPausedByAnnotation = "PausedByAnnotation"
+
+// reconcilePause scales the latest runner set down to its runners running jobs while the scale set is paused,
+// as its listener is deleted and doesn't scale it anymore, and sets the Paused condition.
+// The condition is removed once the scale set is resumed, and the listener recreated scales the runner set again.
+func (r *AutoscalingRunnerSetReconciler) reconcilePause(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, latestRunnerSet *v1alpha1.EphemeralRunnerSet, logger logr.Logger) error {
+	if !autoscalingRunnerSet.Paused() {
+		if meta.FindStatusCondition(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionPaused) == nil {
+			return nil
+		}
+
+		logger.Info("Autoscaling runner set was resumed")
+		return patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			meta.RemoveStatusCondition(&obj.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionPaused)
+		})
+	}
+
+	// Scaling down to 0 only deletes the idle runners, the ones running jobs are deleted once they finish
+	if latestRunnerSet.Spec.Replicas != 0 || latestRunnerSet.Spec.PatchID != 0 {
+		logger.Info("Autoscaling runner set is paused. Scaling down the latest runner set to its runners running jobs", "running", latestRunnerSet.Status.RunningEphemeralRunners, "pending", latestRunnerSet.Status.PendingEphemeralRunners)
+		if err := patch(ctx, r.Client, latestRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+			obj.Spec.Replicas = 0
+			obj.Spec.PatchID = 0
+		}); err != nil {
+			return fmt.Errorf("failed to scale down the paused runner set: %w", err)
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.AutoscalingRunnerSetConditionPaused,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: autoscalingRunnerSet.Generation,
+		Reason:             reasonPausedByAnnotation,
+		Message:            fmt.Sprintf("No job is acquired and no runner is created until the %s annotation is removed", v1alpha1.AnnotationKeyPaused),
+	}
+	current := meta.FindStatusCondition(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionPaused)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason && current.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+
+	logger.Info("Autoscaling runner set is paused")
+	return patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		meta.SetStatusCondition(&obj.Status.Conditions, condition)
+	})
+}
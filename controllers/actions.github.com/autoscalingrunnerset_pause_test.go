@@ -0,0 +1,83 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcilePause(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newReconciler := func(annotations map[string]string) (*AutoscalingRunnerSetReconciler, *v1alpha1.AutoscalingRunnerSet, *v1alpha1.EphemeralRunnerSet) {
+		ars := &v1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "arc-runners", Namespace: "arc-runners", Annotations: annotations, Generation: 2},
+		}
+		ers := &v1alpha1.EphemeralRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "arc-runners-x8k2p", Namespace: "arc-runners"},
+			Spec:       v1alpha1.EphemeralRunnerSetSpec{Replicas: 5, PatchID: 7},
+		}
+
+		r := &AutoscalingRunnerSetReconciler{
+			Client: crfake.NewClientBuilder().WithScheme(scheme).WithObjects(ars, ers).WithStatusSubresource(ars).Build(),
+			Scheme: scheme,
+		}
+		return r, ars, ers
+	}
+
+	t.Run("scales the runner set down while paused", func(t *testing.T) {
+		r, ars, ers := newReconciler(map[string]string{v1alpha1.AnnotationKeyPaused: "true"})
+		require.NoError(t, r.reconcilePause(ctx, ars, ers, logr.Discard()))
+
+		updatedERS := new(v1alpha1.EphemeralRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ers), updatedERS))
+		assert.Equal(t, 0, updatedERS.Spec.Replicas)
+		assert.Equal(t, 0, updatedERS.Spec.PatchID)
+
+		updatedARS := new(v1alpha1.AutoscalingRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), updatedARS))
+		condition := meta.FindStatusCondition(updatedARS.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionPaused)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, reasonPausedByAnnotation, condition.Reason)
+		assert.Equal(t, int64(2), condition.ObservedGeneration)
+	})
+
+	t.Run("leaves the runner set to the listener when not paused", func(t *testing.T) {
+		r, ars, ers := newReconciler(map[string]string{v1alpha1.AnnotationKeyPaused: "false"})
+		require.NoError(t, r.reconcilePause(ctx, ars, ers, logr.Discard()))
+
+		updatedERS := new(v1alpha1.EphemeralRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ers), updatedERS))
+		assert.Equal(t, 5, updatedERS.Spec.Replicas)
+	})
+
+	t.Run("removes the condition once resumed", func(t *testing.T) {
+		r, ars, ers := newReconciler(map[string]string{v1alpha1.AnnotationKeyPaused: "true"})
+		require.NoError(t, r.reconcilePause(ctx, ars, ers, logr.Discard()))
+
+		resumed := new(v1alpha1.AutoscalingRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), resumed))
+		require.NotNil(t, meta.FindStatusCondition(resumed.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionPaused))
+		delete(resumed.Annotations, v1alpha1.AnnotationKeyPaused)
+		require.NoError(t, r.reconcilePause(ctx, resumed, ers, logr.Discard()))
+
+		updatedARS := new(v1alpha1.AutoscalingRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), updatedARS))
+		assert.Nil(t, meta.FindStatusCondition(updatedARS.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionPaused))
+	})
+}
@@ -0,0 +1,183 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/hash"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultPlaceholderImage is the image of the placeholder pods when spec.placeholders.image is empty.
+const DefaultPlaceholderImage = "registry.k8s.io/pause:3.9"
+
+// reconcilePlaceholders makes sure the deployment of the placeholder pods of the scale set matches spec.placeholders,
+// and deletes it when spec.placeholders is removed.
+// It returns the delay after which the number of placeholder pods changes with the windows, or zero when it doesn't.
+func (r *AutoscalingRunnerSetReconciler) reconcilePlaceholders(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, latestRunnerSet *v1alpha1.EphemeralRunnerSet, logger logr.Logger) (time.Duration, error) {
+	placeholders := autoscalingRunnerSet.Spec.Placeholders
+	if placeholders == nil {
+		current := autoscalingRunnerSet.Status.Placeholders
+		if current == nil {
+			return 0, nil
+		}
+
+		logger.Info("Deleting placeholder deployment", "name", current.DeploymentName)
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: autoscalingRunnerSet.Namespace, Name: current.DeploymentName}}
+		if err := r.Delete(ctx, deployment); err != nil && !kerrors.IsNotFound(err) {
+			return 0, fmt.Errorf("failed to delete placeholder deployment: %v", err)
+		}
+
+		return 0, patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			obj.Status.Placeholders = nil
+		})
+	}
+
+	now := time.Now()
+	replicas, nextChange, err := desiredPlaceholderReplicas(placeholders, latestRunnerSet.Status, maxRunners(autoscalingRunnerSet), now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute placeholder replicas: %v", err)
+	}
+	// No runner needs the nodes of the placeholders while the scale set is paused
+	if autoscalingRunnerSet.Paused() {
+		replicas = 0
+	}
+
+	desired := r.ResourceBuilder.newPlaceholderDeployment(autoscalingRunnerSet, replicas)
+	desired.Annotations = map[string]string{annotationKeyValuesHash: hash.ComputeTemplateHash(desired.Spec.Template)}
+	if err := ctrl.SetControllerReference(autoscalingRunnerSet, desired, r.Scheme); err != nil {
+		return 0, fmt.Errorf("failed to set controller reference on placeholder deployment: %v", err)
+	}
+
+	existing := new(appsv1.Deployment)
+	err = r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	switch {
+	case kerrors.IsNotFound(err):
+		logger.Info("Creating placeholder deployment", "name", desired.Name, "replicas", replicas)
+		if err := r.Create(ctx, desired); err != nil {
+			return 0, fmt.Errorf("failed to create placeholder deployment: %v", err)
+		}
+	case err != nil:
+		return 0, fmt.Errorf("failed to get placeholder deployment: %v", err)
+	case existing.Annotations[annotationKeyValuesHash] != desired.Annotations[annotationKeyValuesHash]:
+		logger.Info("Updating placeholder deployment", "name", desired.Name, "replicas", replicas)
+		desired.ResourceVersion = existing.ResourceVersion
+		if err := r.Update(ctx, desired); err != nil {
+			return 0, fmt.Errorf("failed to update placeholder deployment: %v", err)
+		}
+	case existing.Spec.Replicas == nil || int(*existing.Spec.Replicas) != replicas:
+		logger.Info("Scaling placeholder deployment", "name", desired.Name, "replicas", replicas)
+		if err := patch(ctx, r.Client, existing, func(obj *appsv1.Deployment) {
+			obj.Spec.Replicas = desired.Spec.Replicas
+		}); err != nil {
+			return 0, fmt.Errorf("failed to scale placeholder deployment: %v", err)
+		}
+	}
+
+	status := &v1alpha1.PlaceholdersStatus{DeploymentName: desired.Name, Replicas: replicas}
+	if current := autoscalingRunnerSet.Status.Placeholders; current == nil || *current != *status {
+		if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			obj.Status.Placeholders = status
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if nextChange.IsZero() {
+		return 0, nil
+	}
+	return nextChange.Sub(now), nil
+}
+
+// desiredPlaceholderReplicas returns the number of placeholder pods of the scale set at now,
+// and the next time a window starts or ends, or the zero time when there is no window.
+// The placeholder pods stand in for the runners yet to be created, so there are never more of them than the runners the scale set can still add.
+func desiredPlaceholderReplicas(placeholders *v1alpha1.Placeholders, runnerSetStatus v1alpha1.EphemeralRunnerSetStatus, maxRunners int, now time.Time) (int, time.Time, error) {
+	replicas := 0
+	if placeholders.Replicas != nil {
+		replicas = *placeholders.Replicas
+	}
+
+	windowReplicas, nextChange, err := activePlaceholderWindowReplicas(placeholders.Windows, now)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	replicas = max(replicas, windowReplicas)
+
+	if placeholders.PerPendingRunner != nil {
+		replicas += *placeholders.PerPendingRunner * runnerSetStatus.PendingEphemeralRunners
+	}
+
+	if maxRunners < math.MaxInt32 {
+		replicas = min(replicas, max(maxRunners-runnerSetStatus.CurrentReplicas, 0))
+	}
+
+	return replicas, nextChange, nil
+}
+
+// activePlaceholderWindowReplicas returns the largest replicas of the windows active at now,
+// and the next time any of the windows starts or ends.
+func activePlaceholderWindowReplicas(windows []v1alpha1.PlaceholderWindow, now time.Time) (int, time.Time, error) {
+	var (
+		replicas   int
+		nextChange time.Time
+	)
+	for i, w := range windows {
+		active, next, err := w.Active(now)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("invalid window %d: %v", i, err)
+		}
+
+		if active {
+			replicas = max(replicas, w.Replicas)
+		}
+		nextChange = v1alpha1.EarliestChange(nextChange, next)
+	}
+
+	return replicas, nextChange, nil
+}
+
+// podResourceRequests returns the resources requested by a pod of the spec:
+// the sum of the requests of its containers, or the largest request of its init containers when it's larger.
+// The limit of a resource is used when its request is omitted, as the request then defaults to the limit.
+func podResourceRequests(spec corev1.PodSpec) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+
+	for _, c := range spec.Containers {
+		for name, quantity := range containerResourceRequests(c) {
+			sum := requests[name]
+			sum.Add(quantity)
+			requests[name] = sum
+		}
+	}
+
+	for _, c := range spec.InitContainers {
+		for name, quantity := range containerResourceRequests(c) {
+			if current, ok := requests[name]; !ok || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+
+	return requests
+}
+
+func containerResourceRequests(c corev1.Container) corev1.ResourceList {
+	requests := make(corev1.ResourceList, len(c.Resources.Limits))
+	for name, quantity := range c.Resources.Limits {
+		requests[name] = quantity.DeepCopy()
+	}
+	for name, quantity := range c.Resources.Requests {
+		requests[name] = quantity.DeepCopy()
+	}
+	return requests
+}
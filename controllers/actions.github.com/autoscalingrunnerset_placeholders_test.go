@@ -0,0 +1,155 @@
+package actionsgithubcom
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDesiredPlaceholderReplicas(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	placeholders := &v1alpha1.Placeholders{
+		PriorityClassName: "arc-placeholder",
+		Replicas:          intPtr(2),
+		PerPendingRunner:  intPtr(1),
+		Windows: []v1alpha1.PlaceholderWindow{
+			{
+				DailyWindow: v1alpha1.DailyWindow{
+					Days:     []v1alpha1.WindowDay{"Monday"},
+					Start:    "08:30",
+					End:      "10:00",
+					TimeZone: "Europe/Berlin",
+				},
+				Replicas: 10,
+			},
+			{
+				DailyWindow: v1alpha1.DailyWindow{Start: "22:00", End: "01:00"},
+				Replicas:    5,
+			},
+		},
+	}
+
+	// 2024-05-06 is a Monday, and Europe/Berlin is UTC+2
+	monday := func(hour, min int) time.Time {
+		return time.Date(2024, 5, 6, hour, min, 0, 0, time.UTC)
+	}
+
+	t.Run("outside the windows", func(t *testing.T) {
+		replicas, next, err := desiredPlaceholderReplicas(placeholders, v1alpha1.EphemeralRunnerSetStatus{}, math.MaxInt32, monday(5, 0))
+		require.NoError(t, err)
+		assert.Equal(t, 2, replicas)
+		assert.WithinDuration(t, monday(6, 30), next, 0)
+	})
+
+	t.Run("in a window of the time zone", func(t *testing.T) {
+		replicas, next, err := desiredPlaceholderReplicas(placeholders, v1alpha1.EphemeralRunnerSetStatus{PendingEphemeralRunners: 3}, math.MaxInt32, monday(7, 0))
+		require.NoError(t, err)
+		assert.Equal(t, 13, replicas)
+		assert.WithinDuration(t, monday(8, 0), next, 0)
+	})
+
+	t.Run("in a window started on the previous day", func(t *testing.T) {
+		replicas, next, err := desiredPlaceholderReplicas(placeholders, v1alpha1.EphemeralRunnerSetStatus{}, math.MaxInt32, monday(0, 30))
+		require.NoError(t, err)
+		assert.Equal(t, 5, replicas)
+		assert.WithinDuration(t, monday(1, 0), next, 0)
+	})
+
+	t.Run("on another day", func(t *testing.T) {
+		tuesday := monday(7, 0).AddDate(0, 0, 1)
+		replicas, _, err := desiredPlaceholderReplicas(placeholders, v1alpha1.EphemeralRunnerSetStatus{}, math.MaxInt32, tuesday)
+		require.NoError(t, err)
+		assert.Equal(t, 2, replicas)
+	})
+
+	t.Run("up to the runners the scale set can still add", func(t *testing.T) {
+		replicas, _, err := desiredPlaceholderReplicas(placeholders, v1alpha1.EphemeralRunnerSetStatus{CurrentReplicas: 8, PendingEphemeralRunners: 3}, 15, monday(7, 0))
+		require.NoError(t, err)
+		assert.Equal(t, 7, replicas)
+
+		replicas, _, err = desiredPlaceholderReplicas(placeholders, v1alpha1.EphemeralRunnerSetStatus{CurrentReplicas: 20}, 15, monday(7, 0))
+		require.NoError(t, err)
+		assert.Equal(t, 0, replicas)
+	})
+
+	t.Run("invalid time zone", func(t *testing.T) {
+		_, _, err := desiredPlaceholderReplicas(&v1alpha1.Placeholders{
+			Windows: []v1alpha1.PlaceholderWindow{{DailyWindow: v1alpha1.DailyWindow{Start: "08:00", End: "09:00", TimeZone: "Nowhere/Nowhere"}}},
+		}, v1alpha1.EphemeralRunnerSetStatus{}, math.MaxInt32, monday(7, 0))
+		assert.ErrorContains(t, err, "invalid window 0: invalid time zone")
+	})
+}
+
+func TestNewPlaceholderDeployment(t *testing.T) {
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "arc-runners",
+			Namespace: "arc-runners",
+			Labels:    map[string]string{LabelKeyKubernetesVersion: "0.10.1"},
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			Placeholders: &v1alpha1.Placeholders{PriorityClassName: "arc-placeholder"},
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					NodeSelector: map[string]string{"pool": "runners"},
+					InitContainers: []corev1.Container{
+						{
+							Name: "init",
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name: EphemeralRunnerContainerName,
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("2"),
+									corev1.ResourceMemory: resource.MustParse("4Gi"),
+								},
+							},
+						},
+						{
+							Name: "dind",
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("500m"),
+									corev1.ResourceMemory: resource.MustParse("1Gi"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var b ResourceBuilder
+	deployment := b.newPlaceholderDeployment(ars, 3)
+
+	assert.Equal(t, "arc-runners-placeholder", deployment.Name)
+	assert.Equal(t, "arc-runners", deployment.Namespace)
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+	assert.Equal(t, "runner-placeholder", deployment.Spec.Selector.MatchLabels[LabelKeyKubernetesComponent])
+	assert.Equal(t, "runner-placeholder", deployment.Spec.Template.Labels[LabelKeyKubernetesComponent])
+
+	spec := deployment.Spec.Template.Spec
+	assert.Equal(t, "arc-placeholder", spec.PriorityClassName)
+	assert.Equal(t, map[string]string{"pool": "runners"}, spec.NodeSelector)
+	require.Len(t, spec.Containers, 1)
+	assert.Equal(t, DefaultPlaceholderImage, spec.Containers[0].Image)
+
+	// The requests of the containers are summed, the limits standing for omitted requests, and the larger init container request wins
+	requests := spec.Containers[0].Resources.Requests
+	assert.True(t, resource.MustParse("2500m").Equal(requests[corev1.ResourceCPU]), requests.Cpu().String())
+	assert.True(t, resource.MustParse("8Gi").Equal(requests[corev1.ResourceMemory]), requests.Memory().String())
+}
@@ -0,0 +1,251 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// runnerGroupPreflightRetryInterval is the interval a runner group that failed the preflight is checked again at.
+	runnerGroupPreflightRetryInterval = 5 * time.Minute
+
+	reasonRunnerGroupPermitted              = "Permitted"
+	reasonRunnerGroupNotFound               = "NotFound"
+	reasonRunnerGroupNotAdministrable       = "NotAdministrable"
+	reasonRunnerGroupRepositoriesNotAllowed = "RepositoriesNotAllowed"
+	reasonRunnerGroupNotRestricted          = "NotRestricted"
+	reasonAllowedRepositoriesInvalid        = "AllowedRepositoriesInvalid"
+)
+
+// runnerGroupIdFor returns the ID of spec.runnerGroup, after checking that the runner scale set can be created in it:
+// the credentials must be able to administer the runner group, and the runner group must allow spec.runnerGroupRepositories.
+// With spec.allowedRepositories, the runner group must also allow none but them, as GitHub assigns the jobs of
+// any repository the runner group allows to the idle runners of the scale set.
+// Otherwise, the RunnerGroupPermitted condition tells why, and the returned ID is 0.
+// The check is skipped for the default runner group without spec.allowedRepositories, as any scale set can be created in it.
+func (r *AutoscalingRunnerSetReconciler) runnerGroupIdFor(ctx context.Context, actionsClient actions.ActionsService, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) (int, error) {
+	restricted := len(autoscalingRunnerSet.Spec.AllowedRepositories) > 0
+	if restricted {
+		if _, err := allowedRepositoryNames(autoscalingRunnerSet); err != nil {
+			logger.Info("Invalid allowed repositories", "error", err.Error())
+			return 0, r.setRunnerGroupPermitted(ctx, autoscalingRunnerSet, metav1.ConditionFalse, reasonAllowedRepositoriesInvalid, err.Error())
+		}
+	}
+
+	name := autoscalingRunnerSet.Spec.RunnerGroup
+	if len(name) == 0 {
+		if !restricted {
+			return 1, nil
+		}
+		name = defaultRunnerGroupName
+	}
+
+	logger = logger.WithValues("runnerGroup", name)
+
+	runnerGroup, err := actionsClient.GetRunnerGroupByName(ctx, name)
+	if err != nil {
+		if !isRunnerGroupNotFound(err) {
+			logger.Error(err, "Failed to get runner group by name")
+			return 0, err
+		}
+
+		logger.Info("Runner group not found")
+		return 0, r.setRunnerGroupPermitted(ctx, autoscalingRunnerSet, metav1.ConditionFalse, reasonRunnerGroupNotFound,
+			fmt.Sprintf("Runner group %q doesn't exist", name))
+	}
+
+	if runnerGroup.IsDefault && !restricted {
+		return int(runnerGroup.ID), r.setRunnerGroupPermitted(ctx, autoscalingRunnerSet, metav1.ConditionTrue, reasonRunnerGroupPermitted, "The runner group is the default one")
+	}
+
+	config, err := actions.ParseGitHubConfigFromURL(autoscalingRunnerSet.Spec.GitHubConfigUrl)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse GitHub config URL: %w", err)
+	}
+
+	if config.Scope == actions.GitHubScopeRepository {
+		// Repositories have no runner groups to check
+		return int(runnerGroup.ID), nil
+	}
+
+	status, reason, message, err := runnerGroupPreflight(ctx, actionsClient, autoscalingRunnerSet, config, runnerGroup.ID, name)
+	if err != nil {
+		logger.Error(err, "Failed to check runner group")
+		return 0, err
+	}
+
+	if err := r.setRunnerGroupPermitted(ctx, autoscalingRunnerSet, status, reason, message); err != nil {
+		return 0, err
+	}
+
+	if status != metav1.ConditionTrue {
+		logger.Info("The runner scale set can't be created in the runner group", "reason", reason, "message", message)
+		return 0, nil
+	}
+
+	return int(runnerGroup.ID), nil
+}
+
+// runnerGroupPreflight returns the status of the RunnerGroupPermitted condition for the runner group of the organization or the enterprise.
+// It returns an error only when the check itself failed and needs to be retried.
+func runnerGroupPreflight(ctx context.Context, actionsClient actions.ActionsService, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, config *actions.GitHubConfig, runnerGroupId int64, name string) (metav1.ConditionStatus, string, string, error) {
+
+	settings, err := actionsClient.GetRunnerGroupSettings(ctx, runnerGroupId)
+	if err != nil {
+		var apiErr *actions.GitHubAPIError
+		if !errors.As(err, &apiErr) || (apiErr.StatusCode != http.StatusForbidden && apiErr.StatusCode != http.StatusNotFound) {
+			return "", "", "", fmt.Errorf("failed to get runner group %q: %w", name, err)
+		}
+
+		required := "a GitHub App needs the read and write access to the Self-hosted runners organization permission, and a personal access token needs the admin:org scope"
+		if config.Scope == actions.GitHubScopeEnterprise {
+			required = "a personal access token needs the manage_runners:enterprise scope"
+		}
+
+		return metav1.ConditionFalse, reasonRunnerGroupNotAdministrable, fmt.Sprintf(
+			"The credentials in secret %s can't administer runner group %q (status %d, request ID %q): %s",
+			autoscalingRunnerSet.Spec.GitHubConfigSecret, name, apiErr.StatusCode, apiErr.RequestID, required,
+		), nil
+	}
+
+	// allowedRepositoryNames was checked by the caller, and requires an organization
+	allowedRepositories, _ := allowedRepositoryNames(autoscalingRunnerSet)
+
+	// The allowed repositories must be able to use the runner group too, or their jobs would never run
+	wanted := append(append([]string{}, autoscalingRunnerSet.Spec.RunnerGroupRepositories...), allowedRepositories...)
+
+	if len(allowedRepositories) > 0 && settings.Visibility != actions.RunnerGroupVisibilitySelected {
+		return metav1.ConditionFalse, reasonRunnerGroupNotRestricted, fmt.Sprintf(
+			"Runner group %q is visible to %s repositories, and GitHub would assign their jobs to the runners. Restrict it to the allowedRepositories",
+			name, settings.Visibility,
+		), nil
+	}
+
+	if config.Scope != actions.GitHubScopeOrganization || settings.Visibility != actions.RunnerGroupVisibilitySelected || len(wanted) == 0 {
+		return metav1.ConditionTrue, reasonRunnerGroupPermitted, fmt.Sprintf("The runner group is visible to %s repositories", settings.Visibility), nil
+	}
+
+	allowed, err := actionsClient.ListRunnerGroupRepositories(ctx, runnerGroupId)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to list repositories of runner group %q: %w", name, err)
+	}
+
+	if missing := missingRepositories(wanted, allowed); len(missing) > 0 {
+		return metav1.ConditionFalse, reasonRunnerGroupRepositoriesNotAllowed, fmt.Sprintf(
+			"Runner group %q doesn't allow repositories %s. Add them to the repository access of the runner group",
+			name, strings.Join(missing, ", "),
+		), nil
+	}
+
+	if len(allowedRepositories) > 0 {
+		if extra := missingRepositories(allowed, allowedRepositories); len(extra) > 0 {
+			return metav1.ConditionFalse, reasonRunnerGroupNotRestricted, fmt.Sprintf(
+				"Runner group %q also allows repositories %s, and GitHub would assign their jobs to the runners. Remove them from the repository access of the runner group, or add them to the allowedRepositories",
+				name, strings.Join(extra, ", "),
+			), nil
+		}
+	}
+
+	return metav1.ConditionTrue, reasonRunnerGroupPermitted, "The runner group allows all the repositories", nil
+}
+
+// allowedRepositoryNames returns the names of spec.allowedRepositories within the organization of the scale set.
+// The repositories can be given as name or owner/name, but must belong to the organization of an organization scale set.
+func allowedRepositoryNames(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) ([]string, error) {
+	config, err := actions.ParseGitHubConfigFromURL(autoscalingRunnerSet.Spec.GitHubConfigUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub config URL: %w", err)
+	}
+
+	if config.Scope != actions.GitHubScopeOrganization {
+		return nil, fmt.Errorf("allowedRepositories can be used only with the scale sets of organizations, as the runner groups of enterprises and repositories can't be restricted to them")
+	}
+
+	names := make([]string, 0, len(autoscalingRunnerSet.Spec.AllowedRepositories))
+	for _, r := range autoscalingRunnerSet.Spec.AllowedRepositories {
+		if owner, name, ok := strings.Cut(r, "/"); ok {
+			if !strings.EqualFold(owner, config.Organization) || name == "" {
+				return nil, fmt.Errorf("allowed repository %q isn't a repository of organization %q", r, config.Organization)
+			}
+			r = name
+		}
+		names = append(names, r)
+	}
+
+	return names, nil
+}
+
+// qualifiedAllowedRepositories returns the full names, owner/name, of spec.allowedRepositories enforced by the listener.
+func qualifiedAllowedRepositories(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) ([]string, error) {
+	if len(autoscalingRunnerSet.Spec.AllowedRepositories) == 0 {
+		return nil, nil
+	}
+
+	names, err := allowedRepositoryNames(autoscalingRunnerSet)
+	if err != nil {
+		return nil, err
+	}
+
+	// allowedRepositoryNames checked the URL already
+	config, _ := actions.ParseGitHubConfigFromURL(autoscalingRunnerSet.Spec.GitHubConfigUrl)
+	for i := range names {
+		names[i] = config.Organization + "/" + names[i]
+	}
+
+	return names, nil
+}
+
+// missingRepositories returns the names of the wanted repositories that aren't allowed.
+func missingRepositories(wanted, allowed []string) []string {
+	var missing []string
+	for _, w := range wanted {
+		found := false
+		for _, a := range allowed {
+			if strings.EqualFold(w, a) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, w)
+		}
+	}
+	return missing
+}
+
+// isRunnerGroupNotFound returns true when GetRunnerGroupByName failed because no runner group has the name.
+func isRunnerGroupNotFound(err error) bool {
+	var actionsErr *actions.ActionsError
+	return errors.As(err, &actionsErr) && actionsErr.Err != nil && strings.Contains(actionsErr.Err.Error(), "no runner group found")
+}
+
+func (r *AutoscalingRunnerSetReconciler) setRunnerGroupPermitted(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, status metav1.ConditionStatus, reason, message string) error {
+	existing := meta.FindStatusCondition(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionRunnerGroupPermitted)
+	if existing != nil && existing.Status == status && existing.Reason == reason && existing.Message == message && existing.ObservedGeneration == autoscalingRunnerSet.Generation {
+		return nil
+	}
+
+	if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		meta.SetStatusCondition(&obj.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.AutoscalingRunnerSetConditionRunnerGroupPermitted,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: autoscalingRunnerSet.Generation,
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to update runner group condition: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/actions/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunnerGroupIdFor(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	linux := &actions.RunnerGroup{ID: 3, Name: "linux"}
+
+	run := func(t *testing.T, configURL string, allowedRepositories []string, options ...fake.Option) (int, *metav1.Condition) {
+		t.Helper()
+
+		ars := &v1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "arc-runners",
+				Namespace:  "arc-runners",
+				Generation: 2,
+			},
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{
+				GitHubConfigUrl:         configURL,
+				GitHubConfigSecret:      "github-config",
+				RunnerGroup:             "linux",
+				RunnerGroupRepositories: []string{"api", "web"},
+				AllowedRepositories:     allowedRepositories,
+			},
+		}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "github-config", Namespace: "arc-runners"}}
+
+		actionsClient := fake.NewFakeClient(options...)
+		r := &AutoscalingRunnerSetReconciler{
+			Client:        crfake.NewClientBuilder().WithScheme(scheme).WithObjects(ars, secret).WithStatusSubresource(ars).Build(),
+			Scheme:        scheme,
+			ActionsClient: fake.NewMultiClient(fake.WithDefaultClient(actionsClient, nil)),
+		}
+
+		id, err := r.runnerGroupIdFor(ctx, actionsClient, ars, logr.Discard())
+		require.NoError(t, err)
+
+		var updated v1alpha1.AutoscalingRunnerSet
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), &updated))
+
+		return id, meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionRunnerGroupPermitted)
+	}
+
+	t.Run("permitted", func(t *testing.T) {
+		id, condition := run(t, "https://github.com/org", nil,
+			fake.WithGetRunnerGroup(linux, nil),
+			fake.WithGetRunnerGroupSettings(&actions.RunnerGroupSettings{ID: 3, Name: "linux", Visibility: actions.RunnerGroupVisibilitySelected}, nil),
+			fake.WithListRunnerGroupRepositories([]string{"API", "web", "docs"}, nil),
+		)
+		assert.Equal(t, 3, id)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, reasonRunnerGroupPermitted, condition.Reason)
+		assert.Equal(t, int64(2), condition.ObservedGeneration)
+	})
+
+	t.Run("repositories not allowed", func(t *testing.T) {
+		id, condition := run(t, "https://github.com/org", nil,
+			fake.WithGetRunnerGroup(linux, nil),
+			fake.WithGetRunnerGroupSettings(&actions.RunnerGroupSettings{ID: 3, Name: "linux", Visibility: actions.RunnerGroupVisibilitySelected}, nil),
+			fake.WithListRunnerGroupRepositories([]string{"api"}, nil),
+		)
+		assert.Equal(t, 0, id)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonRunnerGroupRepositoriesNotAllowed, condition.Reason)
+		assert.Contains(t, condition.Message, "web")
+		assert.NotContains(t, condition.Message, "api")
+	})
+
+	t.Run("not administrable", func(t *testing.T) {
+		id, condition := run(t, "https://github.com/org", nil,
+			fake.WithGetRunnerGroup(linux, nil),
+			fake.WithGetRunnerGroupSettings(nil, &actions.GitHubAPIError{StatusCode: http.StatusForbidden, RequestID: "abc", Err: errors.New("Resource not accessible by integration")}),
+		)
+		assert.Equal(t, 0, id)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonRunnerGroupNotAdministrable, condition.Reason)
+		assert.Contains(t, condition.Message, "github-config")
+		assert.Contains(t, condition.Message, "admin:org")
+	})
+
+	t.Run("runner group not found", func(t *testing.T) {
+		id, condition := run(t, "https://github.com/org", nil,
+			fake.WithGetRunnerGroup(nil, &actions.ActionsError{StatusCode: http.StatusOK, Err: errors.New(`no runner group found with name "linux"`)}),
+		)
+		assert.Equal(t, 0, id)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonRunnerGroupNotFound, condition.Reason)
+	})
+
+	t.Run("repository scope is not checked", func(t *testing.T) {
+		id, condition := run(t, "https://github.com/org/repo", nil,
+			fake.WithGetRunnerGroup(linux, nil),
+			fake.WithGetRunnerGroupSettings(nil, errors.New("unexpected call")),
+		)
+		assert.Equal(t, 3, id)
+		assert.Nil(t, condition)
+	})
+
+	t.Run("runner group restricted to the allowed repositories", func(t *testing.T) {
+		id, condition := run(t, "https://github.com/org", []string{"org/api", "web"},
+			fake.WithGetRunnerGroup(linux, nil),
+			fake.WithGetRunnerGroupSettings(&actions.RunnerGroupSettings{ID: 3, Name: "linux", Visibility: actions.RunnerGroupVisibilitySelected}, nil),
+			fake.WithListRunnerGroupRepositories([]string{"API", "web"}, nil),
+		)
+		assert.Equal(t, 3, id)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	})
+
+	t.Run("runner group allowing other repositories", func(t *testing.T) {
+		id, condition := run(t, "https://github.com/org", []string{"api", "web"},
+			fake.WithGetRunnerGroup(linux, nil),
+			fake.WithGetRunnerGroupSettings(&actions.RunnerGroupSettings{ID: 3, Name: "linux", Visibility: actions.RunnerGroupVisibilitySelected}, nil),
+			fake.WithListRunnerGroupRepositories([]string{"api", "web", "docs"}, nil),
+		)
+		assert.Equal(t, 0, id)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonRunnerGroupNotRestricted, condition.Reason)
+		assert.Contains(t, condition.Message, "docs")
+	})
+
+	t.Run("runner group visible to all repositories", func(t *testing.T) {
+		id, condition := run(t, "https://github.com/org", []string{"api", "web"},
+			fake.WithGetRunnerGroup(linux, nil),
+			fake.WithGetRunnerGroupSettings(&actions.RunnerGroupSettings{ID: 3, Name: "linux", Visibility: actions.RunnerGroupVisibilityAll}, nil),
+		)
+		assert.Equal(t, 0, id)
+		require.NotNil(t, condition)
+		assert.Equal(t, reasonRunnerGroupNotRestricted, condition.Reason)
+	})
+
+	t.Run("allowed repositories of enterprises or other organizations", func(t *testing.T) {
+		for url, allowed := range map[string][]string{
+			"https://github.com/enterprises/ent": {"org/api"},
+			"https://github.com/org":             {"other/api"},
+		} {
+			id, condition := run(t, url, allowed, fake.WithGetRunnerGroup(nil, errors.New("unexpected call")))
+			assert.Equal(t, 0, id)
+			require.NotNil(t, condition)
+			assert.Equal(t, reasonAllowedRepositoriesInvalid, condition.Reason, url)
+		}
+	})
+}
+
+func TestQualifiedAllowedRepositories(t *testing.T) {
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl:     "https://github.com/org",
+			AllowedRepositories: []string{"api", "Org/web"},
+		},
+	}
+	names, err := qualifiedAllowedRepositories(ars)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"org/api", "org/web"}, names)
+}
@@ -0,0 +1,234 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// jobQueueLatencySLOBuckets is the number of intervals the window of a JobQueueLatencySLO is divided into.
+	jobQueueLatencySLOBuckets = 12
+
+	defaultJobQueueLatencySLOWindow   = time.Hour
+	defaultJobQueueLatencySLOBurnRate = 1.0
+
+	// jobQueueLatencySLOSyncPeriod is how often the measurement of a JobQueueLatencySLO is refreshed,
+	// as the listener reporting the jobs to the EphemeralRunners doesn't trigger a reconciliation of the AutoscalingRunnerSet.
+	jobQueueLatencySLOSyncPeriod = time.Minute
+
+	reasonJobQueueLatencySLOBurning = "JobQueueLatencySLOBurning"
+	reasonJobQueueLatencySLOMet     = "JobQueueLatencySLOMet"
+)
+
+// reconcileJobQueueLatencySLO counts the jobs newly assigned to the runners of the scale set into the measurement
+// of its JobQueueLatencySLO, and sets the Degraded condition while the SLO is burning.
+// It returns the delay after which the measurement must be refreshed, or zero when the scale set has no SLO.
+func (r *AutoscalingRunnerSetReconciler) reconcileJobQueueLatencySLO(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, runnerSets []v1alpha1.EphemeralRunnerSet, logger logr.Logger) (time.Duration, error) {
+	slo := autoscalingRunnerSet.Spec.JobQueueLatencySLO
+	if slo == nil {
+		if autoscalingRunnerSet.Status.JobQueueLatencySLO == nil && meta.FindStatusCondition(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionDegraded) == nil {
+			return 0, nil
+		}
+
+		logger.Info("Removing the job queue latency SLO measurement")
+		return 0, patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			obj.Status.JobQueueLatencySLO = nil
+			meta.RemoveStatusCondition(&obj.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionDegraded)
+		})
+	}
+
+	var runners []v1alpha1.EphemeralRunner
+	for _, runnerSet := range runnerSets {
+		list := new(v1alpha1.EphemeralRunnerList)
+		if err := r.List(ctx, list, client.InNamespace(autoscalingRunnerSet.Namespace), client.MatchingFields{resourceOwnerKey: runnerSet.Name}); err != nil {
+			return 0, fmt.Errorf("failed to list ephemeral runners of %s: %w", runnerSet.Name, err)
+		}
+		runners = append(runners, list.Items...)
+	}
+
+	status, burnRate, burning, err := measureJobQueueLatencySLO(slo, autoscalingRunnerSet.Status.JobQueueLatencySLO, runners, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure job queue latency SLO: %w", err)
+	}
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.AutoscalingRunnerSetConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonJobQueueLatencySLOMet,
+		Message:            fmt.Sprintf("The job queue latency SLO burns its error budget at a rate of %.2f", burnRate),
+		ObservedGeneration: autoscalingRunnerSet.Generation,
+	}
+	if burning {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonJobQueueLatencySLOBurning
+	}
+
+	conditions := append([]metav1.Condition(nil), autoscalingRunnerSet.Status.Conditions...)
+	meta.SetStatusCondition(&conditions, condition)
+
+	if parsedURL, err := actions.ParseGitHubConfigFromURL(autoscalingRunnerSet.Spec.GitHubConfigUrl); err == nil {
+		metrics.SetJobQueueLatencySLOBurnRate(
+			metrics.CommonLabels{
+				Name:         autoscalingRunnerSet.Name,
+				Namespace:    autoscalingRunnerSet.Namespace,
+				Repository:   parsedURL.Repository,
+				Organization: parsedURL.Organization,
+				Enterprise:   parsedURL.Enterprise,
+			},
+			burnRate,
+		)
+	}
+
+	if equality.Semantic.DeepEqual(status, autoscalingRunnerSet.Status.JobQueueLatencySLO) && equality.Semantic.DeepEqual(conditions, autoscalingRunnerSet.Status.Conditions) {
+		return jobQueueLatencySLOSyncPeriod, nil
+	}
+
+	if burning && !meta.IsStatusConditionTrue(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionDegraded) {
+		logger.Info("Job queue latency SLO is burning", "burnRate", status.BurnRate, "compliance", status.Compliance)
+	}
+
+	if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		obj.Status.JobQueueLatencySLO = status
+		obj.Status.Conditions = conditions
+	}); err != nil {
+		return 0, fmt.Errorf("failed to update job queue latency SLO status: %w", err)
+	}
+
+	return jobQueueLatencySLOSyncPeriod, nil
+}
+
+// measureJobQueueLatencySLO counts the jobs of the runners assigned since the previous measurement into the buckets of the window,
+// and returns the new measurement along with the burn rate of the error budget and whether it exceeds the burn rate threshold.
+func measureJobQueueLatencySLO(slo *v1alpha1.JobQueueLatencySLO, prev *v1alpha1.JobQueueLatencySLOStatus, runners []v1alpha1.EphemeralRunner, now time.Time) (*v1alpha1.JobQueueLatencySLOStatus, float64, bool, error) {
+	objective, err := strconv.ParseFloat(slo.Objective, 64)
+	if err != nil || objective < 0 || objective >= 100 {
+		return nil, 0, false, fmt.Errorf("invalid objective %q: must be a percentage below 100", slo.Objective)
+	}
+
+	threshold := defaultJobQueueLatencySLOBurnRate
+	if slo.BurnRateThreshold != "" {
+		threshold, err = strconv.ParseFloat(slo.BurnRateThreshold, 64)
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("invalid burn rate threshold %q: %w", slo.BurnRateThreshold, err)
+		}
+	}
+
+	window := defaultJobQueueLatencySLOWindow
+	if slo.Window != nil && slo.Window.Duration > 0 {
+		window = slo.Window.Duration
+	}
+	width := window / jobQueueLatencySLOBuckets
+	current := now.Truncate(width)
+	oldest := current.Add(width - window)
+
+	status := &v1alpha1.JobQueueLatencySLOStatus{}
+	if prev != nil {
+		status.LastRunnerAssignTime = prev.LastRunnerAssignTime
+		for _, b := range prev.Buckets {
+			if !b.StartTime.Time.Before(oldest) {
+				status.Buckets = append(status.Buckets, b)
+			}
+		}
+	}
+
+	// Times are persisted with a precision of a second
+	last := status.LastRunnerAssignTime.Time
+	for _, runner := range runners {
+		scaleSetAssigned, assigned := runner.Status.JobScaleSetAssignTime, runner.Status.JobRunnerAssignTime
+		if scaleSetAssigned == nil || assigned == nil {
+			continue
+		}
+
+		at := assigned.Time.Truncate(time.Second)
+		if !at.After(last) {
+			continue
+		}
+		if at.After(status.LastRunnerAssignTime.Time) {
+			status.LastRunnerAssignTime = metav1.Time{Time: at}
+		}
+
+		start := at.Truncate(width)
+		if start.Before(oldest) {
+			continue
+		}
+		if start.After(current) {
+			start = current
+		}
+
+		i := sort.Search(len(status.Buckets), func(i int) bool { return !status.Buckets[i].StartTime.Time.Before(start) })
+		if i == len(status.Buckets) || !status.Buckets[i].StartTime.Time.Equal(start) {
+			status.Buckets = append(status.Buckets, v1alpha1.JobQueueLatencyBucket{})
+			copy(status.Buckets[i+1:], status.Buckets[i:])
+			status.Buckets[i] = v1alpha1.JobQueueLatencyBucket{StartTime: metav1.Time{Time: start}}
+		}
+
+		status.Buckets[i].Jobs++
+		if assigned.Time.Sub(scaleSetAssigned.Time) > slo.Threshold.Duration {
+			status.Buckets[i].SlowJobs++
+		}
+	}
+
+	var jobs, slowJobs int
+	for _, b := range status.Buckets {
+		jobs += b.Jobs
+		slowJobs += b.SlowJobs
+	}
+
+	if jobs == 0 {
+		return status, 0, false, nil
+	}
+
+	slowRatio := float64(slowJobs) / float64(jobs)
+	burnRate := slowRatio * 100 / (100 - objective)
+
+	status.Compliance = strconv.FormatFloat((1-slowRatio)*100, 'f', 2, 64)
+	status.BurnRate = strconv.FormatFloat(burnRate, 'f', 2, 64)
+
+	return status, burnRate, burnRate > threshold, nil
+}
+
+// ephemeralRunnerSetMaxReplicas returns the MaxReplicas of the EphemeralRunnerSet of the scale set,
+// which caps its replicas to maxRunners while the listener can scale above it, or the minRunnersSchedule can add replicas, or nil otherwise.
+func ephemeralRunnerSetMaxReplicas(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) *int {
+	maxReplicas := maxRunners(autoscalingRunnerSet)
+	if maxReplicas >= listenerMaxRunners(autoscalingRunnerSet) && (maxReplicas == math.MaxInt32 || len(autoscalingRunnerSet.Spec.MinRunnersSchedule) == 0) {
+		return nil
+	}
+	return &maxReplicas
+}
+
+// reconcileEphemeralRunnerSetMaxReplicas raises and lowers the MaxReplicas of the latest EphemeralRunnerSet
+// as the JobQueueLatencySLO of the scale set starts and stops burning.
+func (r *AutoscalingRunnerSetReconciler) reconcileEphemeralRunnerSetMaxReplicas(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, latestRunnerSet *v1alpha1.EphemeralRunnerSet, logger logr.Logger) error {
+	maxReplicas := ephemeralRunnerSetMaxReplicas(autoscalingRunnerSet)
+	if equality.Semantic.DeepEqual(maxReplicas, latestRunnerSet.Spec.MaxReplicas) {
+		return nil
+	}
+
+	logger.Info("Updating max replicas of the latest ephemeral runner set", "name", latestRunnerSet.Name, "burning", jobQueueLatencySLOBurning(autoscalingRunnerSet))
+	if err := patch(ctx, r.Client, latestRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+		obj.Spec.MaxReplicas = maxReplicas
+	}); err != nil {
+		return fmt.Errorf("failed to patch max replicas of ephemeral runner set %s: %w", latestRunnerSet.Name, err)
+	}
+
+	return nil
+}
+
+// jobQueueLatencySLOBurning returns true while the JobQueueLatencySLO of the scale set is burning.
+func jobQueueLatencySLOBurning(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) bool {
+	return autoscalingRunnerSet.Spec.JobQueueLatencySLO != nil && meta.IsStatusConditionTrue(autoscalingRunnerSet.Status.Conditions, v1alpha1.AutoscalingRunnerSetConditionDegraded)
+}
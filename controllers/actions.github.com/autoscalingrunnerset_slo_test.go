@@ -0,0 +1,172 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMeasureJobQueueLatencySLO(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 7, 30, 0, time.UTC)
+
+	slo := &v1alpha1.JobQueueLatencySLO{
+		Threshold: metav1.Duration{Duration: time.Minute},
+		Objective: "90",
+	}
+
+	runner := func(assignedAgo, latency time.Duration) v1alpha1.EphemeralRunner {
+		assigned := now.Add(-assignedAgo)
+		return v1alpha1.EphemeralRunner{
+			Status: v1alpha1.EphemeralRunnerStatus{
+				// The time the job waited before it was assigned to the scale set isn't part of the latency
+				JobQueueTime:          &metav1.Time{Time: assigned.Add(-latency - time.Hour)},
+				JobScaleSetAssignTime: &metav1.Time{Time: assigned.Add(-latency)},
+				JobRunnerAssignTime:   &metav1.Time{Time: assigned},
+			},
+		}
+	}
+
+	bucket := func(start time.Time, jobs, slowJobs int) v1alpha1.JobQueueLatencyBucket {
+		return v1alpha1.JobQueueLatencyBucket{StartTime: metav1.Time{Time: start}, Jobs: jobs, SlowJobs: slowJobs}
+	}
+
+	t.Run("no jobs", func(t *testing.T) {
+		status, burnRate, burning, err := measureJobQueueLatencySLO(slo, nil, []v1alpha1.EphemeralRunner{{}}, now)
+		require.NoError(t, err)
+		assert.Empty(t, status.Buckets)
+		assert.Empty(t, status.BurnRate)
+		assert.Equal(t, 0.0, burnRate)
+		assert.False(t, burning)
+	})
+
+	t.Run("jobs are counted into the buckets of their assignment", func(t *testing.T) {
+		runners := []v1alpha1.EphemeralRunner{
+			runner(time.Minute, 10*time.Second),
+			runner(2*time.Minute, 2*time.Minute),
+			runner(10*time.Minute, 10*time.Second),
+			runner(2*time.Hour, 10*time.Second), // outside the window
+		}
+
+		status, burnRate, burning, err := measureJobQueueLatencySLO(slo, nil, runners, now)
+		require.NoError(t, err)
+
+		assert.Equal(t, []v1alpha1.JobQueueLatencyBucket{
+			bucket(time.Date(2024, 5, 1, 9, 55, 0, 0, time.UTC), 1, 0),
+			bucket(time.Date(2024, 5, 1, 10, 5, 0, 0, time.UTC), 2, 1),
+		}, status.Buckets)
+		assert.Equal(t, now.Add(-time.Minute), status.LastRunnerAssignTime.Time)
+		assert.Equal(t, "66.67", status.Compliance)
+		assert.Equal(t, "3.33", status.BurnRate)
+		assert.InDelta(t, 3.33, burnRate, 0.01)
+		assert.True(t, burning)
+
+		// Jobs already counted are not counted again, and buckets leaving the window are dropped
+		later := now.Add(56 * time.Minute)
+		runners = append(runners, runner(-50*time.Minute, 10*time.Second))
+
+		status, _, burning, err = measureJobQueueLatencySLO(slo, status, runners, later)
+		require.NoError(t, err)
+
+		assert.Equal(t, []v1alpha1.JobQueueLatencyBucket{
+			bucket(time.Date(2024, 5, 1, 10, 5, 0, 0, time.UTC), 2, 1),
+			bucket(time.Date(2024, 5, 1, 10, 55, 0, 0, time.UTC), 1, 0),
+		}, status.Buckets)
+		assert.Equal(t, "3.33", status.BurnRate)
+		assert.True(t, burning)
+	})
+
+	t.Run("burn rate threshold", func(t *testing.T) {
+		slo := slo.DeepCopy()
+		slo.BurnRateThreshold = "5"
+
+		_, _, burning, err := measureJobQueueLatencySLO(slo, nil, []v1alpha1.EphemeralRunner{runner(time.Minute, 10*time.Second), runner(time.Minute, 2*time.Minute)}, now)
+		require.NoError(t, err)
+		assert.False(t, burning)
+	})
+
+	t.Run("invalid objective", func(t *testing.T) {
+		slo := slo.DeepCopy()
+		slo.Objective = "100"
+
+		_, _, _, err := measureJobQueueLatencySLO(slo, nil, nil, now)
+		require.Error(t, err)
+	})
+}
+
+func TestMaxRunners(t *testing.T) {
+	ars := &v1alpha1.AutoscalingRunnerSet{}
+	assert.Equal(t, math.MaxInt32, maxRunners(ars))
+	assert.Equal(t, math.MaxInt32, listenerMaxRunners(ars))
+	assert.Nil(t, ephemeralRunnerSetMaxReplicas(ars))
+
+	maxRunnersOfSpec, maxRunnersWhileBurning := 5, 10
+	ars.Spec.MaxRunners = &maxRunnersOfSpec
+	assert.Equal(t, 5, listenerMaxRunners(ars))
+	assert.Nil(t, ephemeralRunnerSetMaxReplicas(ars))
+
+	// The listener scales up to maxRunnersWhileBurning at all times, and the runner set is capped to maxRunners
+	ars.Spec.JobQueueLatencySLO = &v1alpha1.JobQueueLatencySLO{MaxRunnersWhileBurning: &maxRunnersWhileBurning}
+	assert.Equal(t, 5, maxRunners(ars))
+	assert.Equal(t, 10, listenerMaxRunners(ars))
+	require.NotNil(t, ephemeralRunnerSetMaxReplicas(ars))
+	assert.Equal(t, 5, *ephemeralRunnerSetMaxReplicas(ars))
+
+	ars.Status.Conditions = []metav1.Condition{{Type: v1alpha1.AutoscalingRunnerSetConditionDegraded, Status: metav1.ConditionTrue}}
+	assert.Equal(t, 10, maxRunners(ars))
+	assert.Equal(t, 10, listenerMaxRunners(ars))
+	assert.Nil(t, ephemeralRunnerSetMaxReplicas(ars))
+}
+
+func TestReconcileEphemeralRunnerSetMaxReplicas(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	maxRunnersOfSpec, maxRunnersWhileBurning := 5, 10
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "arc-runners", Namespace: "arc-runners"},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			MaxRunners:         &maxRunnersOfSpec,
+			JobQueueLatencySLO: &v1alpha1.JobQueueLatencySLO{MaxRunnersWhileBurning: &maxRunnersWhileBurning},
+		},
+	}
+	ers := &v1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "arc-runners-x8k2p", Namespace: "arc-runners"},
+		Spec:       v1alpha1.EphemeralRunnerSetSpec{Replicas: 8, PatchID: 7},
+	}
+
+	r := &AutoscalingRunnerSetReconciler{
+		Client: crfake.NewClientBuilder().WithScheme(scheme).WithObjects(ars, ers).Build(),
+		Scheme: scheme,
+	}
+
+	maxReplicas := func() *int {
+		t.Helper()
+		updated := new(v1alpha1.EphemeralRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ers), updated))
+		assert.Equal(t, 8, updated.Spec.Replicas, "the replicas are left to the listener")
+		return updated.Spec.MaxReplicas
+	}
+
+	require.NoError(t, r.reconcileEphemeralRunnerSetMaxReplicas(ctx, ars, ers, logr.Discard()))
+	require.NotNil(t, maxReplicas())
+	assert.Equal(t, 5, *maxReplicas())
+
+	// The cap is lifted while the SLO is burning
+	ars.Status.Conditions = []metav1.Condition{{Type: v1alpha1.AutoscalingRunnerSetConditionDegraded, Status: metav1.ConditionTrue}}
+	require.NoError(t, r.reconcileEphemeralRunnerSetMaxReplicas(ctx, ars, ers, logr.Discard()))
+	assert.Nil(t, maxReplicas())
+}
@@ -0,0 +1,123 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/hash"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Labels of the warm pods of a scale set
+const (
+	// labelValueWarmPodAvailable is the component of the warm pods the deployment of the warm pool selects.
+	labelValueWarmPodAvailable = "runner-warm-pod"
+	// labelValueWarmPodClaimed is the component of a warm pod claimed by a runner,
+	// which takes it out of its deployment before it's deleted.
+	labelValueWarmPodClaimed = "runner-warm-pod-claimed"
+	// labelKeyWarmPoolRunnerSet is the name of the EphemeralRunnerSet whose runners can take the place of a warm pod.
+	labelKeyWarmPoolRunnerSet = "actions.github.com/warm-pool-runner-set"
+)
+
+// reconcileWarmPool makes sure the deployment of the warm pods of the scale set matches spec.warmPool
+// and the latest runner set, and deletes it when spec.warmPool is removed.
+func (r *AutoscalingRunnerSetReconciler) reconcileWarmPool(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, latestRunnerSet *v1alpha1.EphemeralRunnerSet, logger logr.Logger) error {
+	warmPool := autoscalingRunnerSet.Spec.WarmPool
+	if warmPool == nil {
+		current := autoscalingRunnerSet.Status.WarmPool
+		if current == nil {
+			return nil
+		}
+
+		logger.Info("Deleting warm pool deployment", "name", current.DeploymentName)
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: autoscalingRunnerSet.Namespace, Name: current.DeploymentName}}
+		if err := r.Delete(ctx, deployment); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete warm pool deployment: %v", err)
+		}
+
+		return patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			obj.Status.WarmPool = nil
+		})
+	}
+
+	// The warm pods stand in for the runners yet to be created, so there are never more of them than the runners the scale set can still add
+	replicas := warmPool.Replicas
+	if limit := maxRunners(autoscalingRunnerSet); limit < math.MaxInt32 {
+		replicas = min(replicas, max(limit-latestRunnerSet.Status.CurrentReplicas, 0))
+	}
+	// No runner takes the place of a warm pod while the scale set is paused
+	if autoscalingRunnerSet.Paused() {
+		replicas = 0
+	}
+
+	desired := r.ResourceBuilder.newWarmPoolDeployment(autoscalingRunnerSet, latestRunnerSet, replicas)
+	desired.Annotations = map[string]string{annotationKeyValuesHash: hash.ComputeTemplateHash(desired.Spec.Template)}
+	if err := ctrl.SetControllerReference(autoscalingRunnerSet, desired, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set controller reference on warm pool deployment: %v", err)
+	}
+
+	existing := new(appsv1.Deployment)
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	switch {
+	case kerrors.IsNotFound(err):
+		logger.Info("Creating warm pool deployment", "name", desired.Name, "replicas", replicas)
+		if err := r.Create(ctx, desired); err != nil {
+			return fmt.Errorf("failed to create warm pool deployment: %v", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get warm pool deployment: %v", err)
+	case existing.Annotations[annotationKeyValuesHash] != desired.Annotations[annotationKeyValuesHash]:
+		logger.Info("Updating warm pool deployment", "name", desired.Name, "replicas", replicas)
+		desired.ResourceVersion = existing.ResourceVersion
+		if err := r.Update(ctx, desired); err != nil {
+			return fmt.Errorf("failed to update warm pool deployment: %v", err)
+		}
+	case existing.Spec.Replicas == nil || int(*existing.Spec.Replicas) != replicas:
+		logger.Info("Scaling warm pool deployment", "name", desired.Name, "replicas", replicas)
+		if err := patch(ctx, r.Client, existing, func(obj *appsv1.Deployment) {
+			obj.Spec.Replicas = desired.Spec.Replicas
+		}); err != nil {
+			return fmt.Errorf("failed to scale warm pool deployment: %v", err)
+		}
+	}
+
+	// A runner deletes the warm pod it claimed right away, unless it failed to
+	claimed := new(corev1.PodList)
+	if err := r.List(ctx, claimed, client.InNamespace(autoscalingRunnerSet.Namespace), client.MatchingLabels{
+		LabelKeyKubernetesComponent:     labelValueWarmPodClaimed,
+		LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
+		LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
+	}); err != nil {
+		return fmt.Errorf("failed to list claimed warm pods: %v", err)
+	}
+	for i := range claimed.Items {
+		pod := &claimed.Items[i]
+		if !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		logger.Info("Deleting claimed warm pod", "name", pod.Name)
+		if err := r.Delete(ctx, pod, client.GracePeriodSeconds(0)); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete claimed warm pod: %v", err)
+		}
+	}
+
+	status := &v1alpha1.WarmPoolStatus{DeploymentName: desired.Name, Replicas: replicas}
+	if current := autoscalingRunnerSet.Status.WarmPool; current == nil || *current != *status {
+		if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			obj.Status.WarmPool = status
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
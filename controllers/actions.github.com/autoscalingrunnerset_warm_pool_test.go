@@ -0,0 +1,159 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewWarmPoolDeployment(t *testing.T) {
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "arc-runners",
+			Namespace: "arc-runners",
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			WarmPool: &v1alpha1.WarmPool{Replicas: 2},
+		},
+	}
+	ers := &v1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "arc-runners-x8k2p", Namespace: "arc-runners"},
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			EphemeralRunnerSpec: v1alpha1.EphemeralRunnerSpec{
+				PodTemplateSpec: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						PriorityClassName: "runners",
+						NodeSelector:      map[string]string{"pool": "runners"},
+						InitContainers: []corev1.Container{
+							{Name: "init-dind-externals", Image: "ghcr.io/actions/actions-runner:latest"},
+						},
+						Containers: []corev1.Container{
+							{
+								Name:  EphemeralRunnerContainerName,
+								Image: "ghcr.io/actions/actions-runner:latest",
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+								},
+							},
+							{
+								Name:            "dind",
+								Image:           "docker:dind",
+								ImagePullPolicy: corev1.PullAlways,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var b ResourceBuilder
+	deployment := b.newWarmPoolDeployment(ars, ers, 2)
+
+	assert.Equal(t, "arc-runners-warm-pool", deployment.Name)
+	assert.Equal(t, int32(2), *deployment.Spec.Replicas)
+	assert.Equal(t, labelValueWarmPodAvailable, deployment.Spec.Selector.MatchLabels[LabelKeyKubernetesComponent])
+	assert.NotContains(t, deployment.Spec.Selector.MatchLabels, labelKeyWarmPoolRunnerSet, "the selector of a deployment is immutable")
+	assert.Equal(t, "arc-runners-x8k2p", deployment.Spec.Template.Labels[labelKeyWarmPoolRunnerSet])
+
+	spec := deployment.Spec.Template.Spec
+	assert.Equal(t, "runners", spec.PriorityClassName)
+	assert.Equal(t, map[string]string{"pool": "runners"}, spec.NodeSelector)
+
+	// Each image is pulled once
+	require.Len(t, spec.InitContainers, 2)
+	assert.Equal(t, "ghcr.io/actions/actions-runner:latest", spec.InitContainers[0].Image)
+	assert.Equal(t, "docker:dind", spec.InitContainers[1].Image)
+	assert.Equal(t, corev1.PullAlways, spec.InitContainers[1].ImagePullPolicy)
+
+	require.Len(t, spec.Containers, 1)
+	assert.Equal(t, DefaultPlaceholderImage, spec.Containers[0].Image)
+	assert.True(t, resource.MustParse("2").Equal(spec.Containers[0].Resources.Requests[corev1.ResourceCPU]))
+}
+
+func TestReconcileWarmPool(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	maxRunners := 5
+	newReconciler := func(warmPool *v1alpha1.WarmPool, objs ...client.Object) (*AutoscalingRunnerSetReconciler, *v1alpha1.AutoscalingRunnerSet, *v1alpha1.EphemeralRunnerSet) {
+		ars := &v1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "arc-runners", Namespace: "arc-runners", UID: "ars-uid"},
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{
+				MaxRunners: &maxRunners,
+				WarmPool:   warmPool,
+			},
+		}
+		ers := &v1alpha1.EphemeralRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "arc-runners-x8k2p", Namespace: "arc-runners"},
+			Status:     v1alpha1.EphemeralRunnerSetStatus{CurrentReplicas: 3},
+		}
+
+		r := &AutoscalingRunnerSetReconciler{
+			Client: crfake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objs, ars)...).WithStatusSubresource(ars).Build(),
+			Scheme: scheme,
+		}
+		return r, ars, ers
+	}
+
+	t.Run("keeps the warm pods up to maxRunners", func(t *testing.T) {
+		claimed := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "arc-runners-warm-pool-abcde",
+				Namespace: "arc-runners",
+				Labels: map[string]string{
+					LabelKeyKubernetesComponent:     labelValueWarmPodClaimed,
+					LabelKeyGitHubScaleSetName:      "arc-runners",
+					LabelKeyGitHubScaleSetNamespace: "arc-runners",
+				},
+			},
+		}
+		r, ars, ers := newReconciler(&v1alpha1.WarmPool{Replicas: 3}, claimed)
+
+		require.NoError(t, r.reconcileWarmPool(ctx, ars, ers, logr.Discard()))
+
+		deployment := new(appsv1.Deployment)
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: "arc-runners", Name: "arc-runners-warm-pool"}, deployment))
+		assert.Equal(t, int32(2), *deployment.Spec.Replicas, "3 of the 5 runners already exist")
+		assert.Equal(t, "arc-runners", metav1.GetControllerOf(deployment).Name)
+
+		err := r.Get(ctx, client.ObjectKeyFromObject(claimed), new(corev1.Pod))
+		assert.True(t, kerrors.IsNotFound(err), "the claimed warm pod left behind is deleted")
+
+		updated := new(v1alpha1.AutoscalingRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), updated))
+		assert.Equal(t, &v1alpha1.WarmPoolStatus{DeploymentName: "arc-runners-warm-pool", Replicas: 2}, updated.Status.WarmPool)
+	})
+
+	t.Run("deletes the deployment when the warm pool is removed", func(t *testing.T) {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "arc-runners", Name: "arc-runners-warm-pool"}}
+		r, ars, ers := newReconciler(nil, deployment)
+		ars.Status.WarmPool = &v1alpha1.WarmPoolStatus{DeploymentName: "arc-runners-warm-pool", Replicas: 2}
+		require.NoError(t, r.Status().Update(ctx, ars))
+
+		require.NoError(t, r.reconcileWarmPool(ctx, ars, ers, logr.Discard()))
+
+		err := r.Get(ctx, client.ObjectKeyFromObject(deployment), new(appsv1.Deployment))
+		assert.True(t, kerrors.IsNotFound(err))
+
+		updated := new(v1alpha1.AutoscalingRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), updated))
+		assert.Nil(t, updated.Status.WarmPool)
+	})
+}
@@ -0,0 +1,227 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	// LabelKeyCanary is the label of the canary EphemeralRunners and their pods set to the runner spec hash of their canary.
+	LabelKeyCanary = "actions.github.com/canary"
+
+	// The annotations of the ephemeral runner set the listener counts the jobs of the canary runners in.
+	// They must be kept in sync with the ones of the listener worker.
+	annotationKeyCanarySpecHash      = "actions.github.com/canary-spec-hash"
+	annotationKeyCanaryCompletedJobs = "actions.github.com/canary-completed-jobs"
+	annotationKeyCanaryFailedJobs    = "actions.github.com/canary-failed-jobs"
+
+	defaultCanaryJobs                    = 10
+	defaultCanaryMaxFailedJobsPercentage = 10
+)
+
+// reconcileCanary rolls a new runner image out to a percentage of the new runners of the latest runner set,
+// when spec.canary is set and the image is the only change of the runner spec.
+// The image is promoted once enough jobs of the canary runners completed without too many failing,
+// or rolled back when too many of them failed, in which case the runner spec isn't rolled out until it changes again.
+// It returns true while the latest runner set is kept instead of rolling out the runner spec.
+func (r *AutoscalingRunnerSetReconciler) reconcileCanary(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, latestRunnerSet *v1alpha1.EphemeralRunnerSet, log logr.Logger) (bool, error) {
+	specHash := autoscalingRunnerSet.RunnerSetSpecHash()
+	if autoscalingRunnerSet.Spec.Canary == nil || latestRunnerSet.Annotations[annotationKeyRunnerSpecHash] == specHash {
+		if err := r.removeCanary(ctx, latestRunnerSet, log); err != nil {
+			return false, err
+		}
+
+		if status := autoscalingRunnerSet.Status.Canary; status != nil && status.Phase == v1alpha1.CanaryPhaseProgressing {
+			if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+				obj.Status.Canary = nil
+			}); err != nil {
+				return false, fmt.Errorf("failed to clear canary status: %w", err)
+			}
+		}
+		return false, nil
+	}
+
+	if status := autoscalingRunnerSet.Status.Canary; status != nil && status.SpecHash == specHash {
+		switch status.Phase {
+		case v1alpha1.CanaryPhasePromoted:
+			return false, nil
+		case v1alpha1.CanaryPhaseRolledBack:
+			return true, r.removeCanary(ctx, latestRunnerSet, log)
+		}
+	}
+
+	desiredRunnerSet, err := r.newEphemeralRunnerSet(autoscalingRunnerSet)
+	if err != nil {
+		return false, fmt.Errorf("failed to build the runner set of the runner spec: %w", err)
+	}
+	if !onlyRunnerImageChanged(latestRunnerSet.Spec.EphemeralRunnerSpec, desiredRunnerSet.Spec.EphemeralRunnerSpec) {
+		// Only runner image updates are rolled out with a canary
+		return false, nil
+	}
+
+	canary := &v1alpha1.EphemeralRunnerSetCanary{
+		Image:      runnerContainerImage(desiredRunnerSet.Spec.EphemeralRunnerSpec.PodTemplateSpec),
+		Percentage: autoscalingRunnerSet.Spec.Canary.Percentage,
+		SpecHash:   specHash,
+	}
+	if !equality.Semantic.DeepEqual(latestRunnerSet.Spec.Canary, canary) {
+		log.Info("Rolling out the runner image to a canary of the new runners", "ephemeralRunnerSetName", latestRunnerSet.Name, "image", canary.Image, "percentage", canary.Percentage)
+		if err := patch(ctx, r.Client, latestRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+			obj.Spec.Canary = canary
+		}); err != nil {
+			return false, fmt.Errorf("failed to patch runner set with canary: %w", err)
+		}
+	}
+
+	completed, failed := canaryJobs(latestRunnerSet, specHash)
+	status := &v1alpha1.CanaryStatus{
+		Image:         canary.Image,
+		SpecHash:      specHash,
+		Phase:         v1alpha1.CanaryPhaseProgressing,
+		CompletedJobs: completed,
+		FailedJobs:    failed,
+	}
+
+	jobs, maxFailedJobsPercentage := canaryThresholds(autoscalingRunnerSet.Spec.Canary)
+	switch {
+	case failed*100 > maxFailedJobsPercentage*max(completed, jobs):
+		// Too many jobs failed for the canary to pass, even if the remaining ones succeed
+		log.Info("Rolling back the canary runner image", "image", canary.Image, "completedJobs", completed, "failedJobs", failed)
+		if err := r.removeCanary(ctx, latestRunnerSet, log); err != nil {
+			return false, err
+		}
+		status.Phase = v1alpha1.CanaryPhaseRolledBack
+	case completed >= jobs:
+		log.Info("Promoting the canary runner image", "image", canary.Image, "completedJobs", completed, "failedJobs", failed)
+		status.Phase = v1alpha1.CanaryPhasePromoted
+	}
+
+	if !equality.Semantic.DeepEqual(autoscalingRunnerSet.Status.Canary, status) {
+		if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			obj.Status.Canary = status
+		}); err != nil {
+			return false, fmt.Errorf("failed to update canary status: %w", err)
+		}
+	}
+
+	return status.Phase != v1alpha1.CanaryPhasePromoted, nil
+}
+
+// removeCanary stops the runner set from creating canary runners.
+// Its idle canary runners are then deleted by the runner set controller.
+func (r *AutoscalingRunnerSetReconciler) removeCanary(ctx context.Context, runnerSet *v1alpha1.EphemeralRunnerSet, log logr.Logger) error {
+	if runnerSet.Spec.Canary == nil {
+		return nil
+	}
+
+	log.Info("Removing the canary from the runner set", "ephemeralRunnerSetName", runnerSet.Name, "image", runnerSet.Spec.Canary.Image)
+	if err := patch(ctx, r.Client, runnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+		obj.Spec.Canary = nil
+	}); err != nil {
+		return fmt.Errorf("failed to remove canary from runner set: %w", err)
+	}
+	return nil
+}
+
+func canaryThresholds(canary *v1alpha1.CanaryRollout) (jobs, maxFailedJobsPercentage int) {
+	jobs, maxFailedJobsPercentage = defaultCanaryJobs, defaultCanaryMaxFailedJobsPercentage
+	if canary.Jobs != nil {
+		jobs = *canary.Jobs
+	}
+	if canary.MaxFailedJobsPercentage != nil {
+		maxFailedJobsPercentage = *canary.MaxFailedJobsPercentage
+	}
+	return jobs, maxFailedJobsPercentage
+}
+
+// canaryJobs returns the numbers of completed and failed jobs of the canary runners of the spec hash,
+// counted by the listener in the annotations of the runner set.
+func canaryJobs(runnerSet *v1alpha1.EphemeralRunnerSet, specHash string) (completed, failed int) {
+	if runnerSet.Annotations[annotationKeyCanarySpecHash] != specHash {
+		return 0, 0
+	}
+	completed, _ = strconv.Atoi(runnerSet.Annotations[annotationKeyCanaryCompletedJobs])
+	failed, _ = strconv.Atoi(runnerSet.Annotations[annotationKeyCanaryFailedJobs])
+	return completed, failed
+}
+
+// onlyRunnerImageChanged returns whether the desired runner spec only differs from the current one by the image of the runner container.
+func onlyRunnerImageChanged(current, desired v1alpha1.EphemeralRunnerSpec) bool {
+	currentImage := runnerContainerImage(current.PodTemplateSpec)
+	if currentImage == runnerContainerImage(desired.PodTemplateSpec) {
+		return false
+	}
+
+	spec := desired.DeepCopy()
+	setRunnerContainerImage(&spec.PodTemplateSpec, currentImage)
+	return equality.Semantic.DeepEqual(&current, spec)
+}
+
+func setRunnerContainerImage(template *corev1.PodTemplateSpec, image string) {
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == EphemeralRunnerContainerName {
+			template.Spec.Containers[i].Image = image
+		}
+	}
+}
+
+// canaryRunnerNamePrefix is the prefix of the names of the canary runners of the runner set,
+// by which the listener recognizes their jobs. It must be kept in sync with the one of the listener worker.
+func canaryRunnerNamePrefix(runnerSet *v1alpha1.EphemeralRunnerSet) string {
+	return runnerSet.Name + "-canary-"
+}
+
+// ephemeralRunnerSetCanaryStatus returns the counts of the runners created since the canary of the runner set started,
+// or nil when it has no canary.
+func ephemeralRunnerSetCanaryStatus(runnerSet *v1alpha1.EphemeralRunnerSet) *v1alpha1.EphemeralRunnerSetCanaryStatus {
+	canary := runnerSet.Spec.Canary
+	if canary == nil {
+		return nil
+	}
+	if status := runnerSet.Status.Canary; status != nil && status.SpecHash == canary.SpecHash {
+		return status.DeepCopy()
+	}
+	return &v1alpha1.EphemeralRunnerSetCanaryStatus{SpecHash: canary.SpecHash}
+}
+
+// selectCanary returns whether a new runner is a canary runner, which it is while the share of the canary runners
+// among the runners created since the canary started is below the percentage of the canary.
+func selectCanary(canary *v1alpha1.EphemeralRunnerSetCanary, status *v1alpha1.EphemeralRunnerSetCanaryStatus) bool {
+	if canary == nil || status == nil {
+		return false
+	}
+	return status.CanaryRunners*100 < canary.Percentage*(status.CreatedRunners+1)
+}
+
+// applyCanary makes the runner a canary runner of the runner set, using the image of its canary.
+func applyCanary(runner *v1alpha1.EphemeralRunner, runnerSet *v1alpha1.EphemeralRunnerSet) {
+	if runner.Labels == nil {
+		runner.Labels = make(map[string]string)
+	}
+	runner.Labels[LabelKeyCanary] = runnerSet.Spec.Canary.SpecHash
+	runner.GenerateName = canaryRunnerNamePrefix(runnerSet)
+
+	// The pod template is shared with the runner set
+	runner.Spec.PodTemplateSpec = *runner.Spec.PodTemplateSpec.DeepCopy()
+	setRunnerContainerImage(&runner.Spec.PodTemplateSpec, runnerSet.Spec.Canary.Image)
+}
+
+// staleCanaryRunners returns the canary runners that aren't of the canary of the runner set anymore, like after it was rolled back.
+func staleCanaryRunners(canary *v1alpha1.EphemeralRunnerSetCanary, runners ...[]*v1alpha1.EphemeralRunner) []*v1alpha1.EphemeralRunner {
+	var stale []*v1alpha1.EphemeralRunner
+	for _, list := range runners {
+		for _, runner := range list {
+			specHash, ok := runner.Labels[LabelKeyCanary]
+			if ok && (canary == nil || specHash != canary.SpecHash) {
+				stale = append(stale, runner)
+			}
+		}
+	}
+	return stale
+}
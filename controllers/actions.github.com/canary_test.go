@@ -0,0 +1,205 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileCanary(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	jobs, maxFailedJobsPercentage := 4, 25
+	newAutoscalingRunnerSet := func(image string) *v1alpha1.AutoscalingRunnerSet {
+		return &v1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "arc-runners",
+				Namespace:   "arc-runners",
+				Annotations: map[string]string{runnerScaleSetIdAnnotationKey: "1"},
+			},
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{
+				GitHubConfigUrl:    "https://github.com/my-org",
+				GitHubConfigSecret: "github-config",
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: EphemeralRunnerContainerName, Image: image}},
+					},
+				},
+				Canary: &v1alpha1.CanaryRollout{
+					Percentage:              10,
+					Jobs:                    &jobs,
+					MaxFailedJobsPercentage: &maxFailedJobsPercentage,
+				},
+			},
+		}
+	}
+
+	newReconciler := func(t *testing.T, ars *v1alpha1.AutoscalingRunnerSet, annotations map[string]string) (*AutoscalingRunnerSetReconciler, *v1alpha1.EphemeralRunnerSet) {
+		r := &AutoscalingRunnerSetReconciler{Scheme: scheme}
+
+		ers, err := r.newEphemeralRunnerSet(newAutoscalingRunnerSet("ghcr.io/actions/actions-runner:2.319.1"))
+		require.NoError(t, err)
+		ers.Name = "arc-runners-x8k2p"
+		for k, v := range annotations {
+			ers.Annotations[k] = v
+		}
+
+		r.Client = crfake.NewClientBuilder().WithScheme(scheme).WithObjects(ars, ers).WithStatusSubresource(ars).Build()
+		return r, ers
+	}
+
+	reconcile := func(t *testing.T, r *AutoscalingRunnerSetReconciler, ars *v1alpha1.AutoscalingRunnerSet, ers *v1alpha1.EphemeralRunnerSet) (bool, *v1alpha1.AutoscalingRunnerSet, *v1alpha1.EphemeralRunnerSet) {
+		t.Helper()
+
+		canarying, err := r.reconcileCanary(ctx, ars, ers, logr.Discard())
+		require.NoError(t, err)
+
+		updatedARS := new(v1alpha1.AutoscalingRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ars), updatedARS))
+		updatedERS := new(v1alpha1.EphemeralRunnerSet)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ers), updatedERS))
+		return canarying, updatedARS, updatedERS
+	}
+
+	t.Run("rolls the new image out to a canary of the runners", func(t *testing.T) {
+		ars := newAutoscalingRunnerSet("ghcr.io/actions/actions-runner:2.320.0")
+		r, ers := newReconciler(t, ars, nil)
+
+		canarying, ars, ers := reconcile(t, r, ars, ers)
+		assert.True(t, canarying)
+		assert.Equal(t, &v1alpha1.EphemeralRunnerSetCanary{
+			Image:      "ghcr.io/actions/actions-runner:2.320.0",
+			Percentage: 10,
+			SpecHash:   ars.RunnerSetSpecHash(),
+		}, ers.Spec.Canary)
+		require.NotNil(t, ars.Status.Canary)
+		assert.Equal(t, v1alpha1.CanaryPhaseProgressing, ars.Status.Canary.Phase)
+	})
+
+	t.Run("promotes the image once enough jobs completed", func(t *testing.T) {
+		ars := newAutoscalingRunnerSet("ghcr.io/actions/actions-runner:2.320.0")
+		r, ers := newReconciler(t, ars, map[string]string{
+			annotationKeyCanarySpecHash:      ars.RunnerSetSpecHash(),
+			annotationKeyCanaryCompletedJobs: "4",
+			annotationKeyCanaryFailedJobs:    "1",
+		})
+
+		canarying, ars, _ := reconcile(t, r, ars, ers)
+		assert.False(t, canarying, "the runner spec is rolled out")
+		assert.Equal(t, &v1alpha1.CanaryStatus{
+			Image:         "ghcr.io/actions/actions-runner:2.320.0",
+			SpecHash:      ars.RunnerSetSpecHash(),
+			Phase:         v1alpha1.CanaryPhasePromoted,
+			CompletedJobs: 4,
+			FailedJobs:    1,
+		}, ars.Status.Canary)
+	})
+
+	t.Run("rolls the image back once too many jobs failed", func(t *testing.T) {
+		ars := newAutoscalingRunnerSet("ghcr.io/actions/actions-runner:2.320.0")
+		r, ers := newReconciler(t, ars, map[string]string{
+			annotationKeyCanarySpecHash:      ars.RunnerSetSpecHash(),
+			annotationKeyCanaryCompletedJobs: "2",
+			annotationKeyCanaryFailedJobs:    "2",
+		})
+
+		canarying, ars, ers := reconcile(t, r, ars, ers)
+		assert.True(t, canarying)
+		assert.Nil(t, ers.Spec.Canary)
+		assert.Equal(t, v1alpha1.CanaryPhaseRolledBack, ars.Status.Canary.Phase)
+
+		canarying, _, ers = reconcile(t, r, ars, ers)
+		assert.True(t, canarying, "the image isn't rolled out until the runner spec changes again")
+		assert.Nil(t, ers.Spec.Canary)
+	})
+
+	t.Run("ignores the jobs of another canary", func(t *testing.T) {
+		ars := newAutoscalingRunnerSet("ghcr.io/actions/actions-runner:2.320.0")
+		r, ers := newReconciler(t, ars, map[string]string{
+			annotationKeyCanarySpecHash:      "previous",
+			annotationKeyCanaryCompletedJobs: "4",
+			annotationKeyCanaryFailedJobs:    "4",
+		})
+
+		canarying, ars, _ := reconcile(t, r, ars, ers)
+		assert.True(t, canarying)
+		assert.Equal(t, v1alpha1.CanaryPhaseProgressing, ars.Status.Canary.Phase)
+		assert.Zero(t, ars.Status.Canary.CompletedJobs)
+	})
+
+	t.Run("rolls other changes out right away", func(t *testing.T) {
+		ars := newAutoscalingRunnerSet("ghcr.io/actions/actions-runner:2.320.0")
+		ars.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "RUNNER_DEBUG", Value: "1"}}
+		r, ers := newReconciler(t, ars, nil)
+
+		canarying, ars, ers := reconcile(t, r, ars, ers)
+		assert.False(t, canarying)
+		assert.Nil(t, ers.Spec.Canary)
+		assert.Nil(t, ars.Status.Canary)
+	})
+}
+
+func TestSelectCanary(t *testing.T) {
+	canary := &v1alpha1.EphemeralRunnerSetCanary{Image: "runner:new", Percentage: 25, SpecHash: "abc"}
+	status := &v1alpha1.EphemeralRunnerSetCanaryStatus{SpecHash: "abc"}
+
+	var selected []bool
+	for i := 0; i < 8; i++ {
+		isCanary := selectCanary(canary, status)
+		selected = append(selected, isCanary)
+		status.CreatedRunners++
+		if isCanary {
+			status.CanaryRunners++
+		}
+	}
+
+	assert.Equal(t, []bool{true, false, false, false, true, false, false, false}, selected)
+	assert.False(t, selectCanary(nil, nil))
+}
+
+func TestApplyCanary(t *testing.T) {
+	runnerSet := &v1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "arc-runners-x8k2p", Namespace: "arc-runners"},
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			EphemeralRunnerSpec: v1alpha1.EphemeralRunnerSpec{
+				PodTemplateSpec: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: EphemeralRunnerContainerName, Image: "runner:old"},
+							{Name: "dind", Image: "docker:dind"},
+						},
+					},
+				},
+			},
+			Canary: &v1alpha1.EphemeralRunnerSetCanary{Image: "runner:new", Percentage: 10, SpecHash: "abc"},
+		},
+	}
+
+	runner := new(ResourceBuilder).newEphemeralRunner(runnerSet)
+	applyCanary(runner, runnerSet)
+
+	assert.Equal(t, "arc-runners-x8k2p-canary-", runner.GenerateName)
+	assert.Equal(t, "abc", runner.Labels[LabelKeyCanary])
+	assert.Equal(t, "runner:new", runnerContainerImage(runner.Spec.PodTemplateSpec))
+	assert.Equal(t, "docker:dind", runner.Spec.PodTemplateSpec.Spec.Containers[1].Image)
+	assert.Equal(t, "runner:old", runnerContainerImage(runnerSet.Spec.EphemeralRunnerSpec.PodTemplateSpec), "the runner set is left unchanged")
+
+	stale := &v1alpha1.EphemeralRunner{ObjectMeta: metav1.ObjectMeta{Name: "stale", Labels: map[string]string{LabelKeyCanary: "previous"}}}
+	regular := &v1alpha1.EphemeralRunner{ObjectMeta: metav1.ObjectMeta{Name: "regular"}}
+	assert.Equal(t, []*v1alpha1.EphemeralRunner{stale}, staleCanaryRunners(runnerSet.Spec.Canary, []*v1alpha1.EphemeralRunner{runner, regular}, []*v1alpha1.EphemeralRunner{stale}))
+	assert.Len(t, staleCanaryRunners(nil, []*v1alpha1.EphemeralRunner{runner, stale}), 2)
+}
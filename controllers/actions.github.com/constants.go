@@ -43,12 +43,26 @@ const (
 	AnnotationKeyGitHubRunnerGroupName    = "actions.github.com/runner-group-name"
 	AnnotationKeyGitHubRunnerScaleSetName = "actions.github.com/runner-scale-set-name"
 	AnnotationKeyPatchID                  = "actions.github.com/patch-id"
+
+	// AnnotationKeyCreationPriority is the priority of the EphemeralRunner creations of the AutoscalingRunnerSet it's annotated on,
+	// when the creations are rate limited. The creations of a higher priority are admitted first. Defaults to 0.
+	AnnotationKeyCreationPriority = "actions.github.com/creation-priority"
+
+	// AnnotationKeyReplacedEphemeralRunner is the name of the EphemeralRunner that lost its pod
+	// and is replaced by the EphemeralRunner it's annotated on.
+	AnnotationKeyReplacedEphemeralRunner = "actions.github.com/replaced-ephemeral-runner"
 )
 
-// Labels applied to listener roles
+// Labels tracking the listeners and ephemeral runners resources were created for, like the roles of the listeners
 const (
 	labelKeyListenerName      = "auto-scaling-listener-name"
 	labelKeyListenerNamespace = "auto-scaling-listener-namespace"
+
+	// labelKeyEphemeralRunnerName tracks the ephemeral runner a secret was created for.
+	labelKeyEphemeralRunnerName = "ephemeral-runner-name"
+
+	// labelKeyRunnerPod is set by the container hooks to the resources they create for a runner pod.
+	labelKeyRunnerPod = "runner-pod"
 )
 
 // Annotations applied for later cleanup of resources
@@ -76,3 +90,6 @@ const (
 	ReasonTooManyPodFailures = "TooManyPodFailures"
 	ReasonInvalidPodFailure  = "InvalidPod"
 )
+
+// ReasonPodLost is the reason of an EphemeralRunner whose pod was deleted out of band
+const ReasonPodLost = "PodLost"
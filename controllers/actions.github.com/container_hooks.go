@@ -0,0 +1,257 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LabelKeyContainerHooks is the label of EphemeralRunners and their pods set to the name of the container hooks they use.
+	LabelKeyContainerHooks = "actions.github.com/container-hooks"
+
+	envVarRunnerContainerHooks = "ACTIONS_RUNNER_CONTAINER_HOOKS"
+
+	defaultContainerHooksPath = "/home/runner/k8s"
+	containerHooksVolumeName  = "container-hooks"
+	containerHooksMountPath   = "/home/runner/container-hooks"
+	containerHooksInitName    = "init-container-hooks"
+
+	reasonContainerHooksCompatible   = "CompatibleRunnerVersion"
+	reasonContainerHooksIncompatible = "IncompatibleRunnerVersion"
+)
+
+// reconcileContainerHooks rolls out the container hooks compatible with the runner image to the latest runner set,
+// and reports the incompatible ones with the ContainerHooksCompatible condition.
+func (r *AutoscalingRunnerSetReconciler) reconcileContainerHooks(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, latestRunnerSet *v1alpha1.EphemeralRunnerSet, log logr.Logger) error {
+	compatible, incompatible := compatibleContainerHooks(autoscalingRunnerSet.Spec.ContainerHooks, runnerContainerImage(autoscalingRunnerSet.RunnerTemplate()))
+
+	if !equality.Semantic.DeepEqual(latestRunnerSet.Spec.ContainerHooks, compatible) {
+		log.Info("Rolling out container hooks to the runner set", "ephemeralRunnerSetName", latestRunnerSet.Name, "containerHooks", containerHooksNames(compatible))
+		if err := patch(ctx, r.Client, latestRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+			obj.Spec.ContainerHooks = compatible
+		}); err != nil {
+			return fmt.Errorf("failed to patch runner set with container hooks: %w", err)
+		}
+	}
+
+	conditions := append([]metav1.Condition(nil), autoscalingRunnerSet.Status.Conditions...)
+	switch {
+	case len(autoscalingRunnerSet.Spec.ContainerHooks) == 0:
+		meta.RemoveStatusCondition(&conditions, v1alpha1.AutoscalingRunnerSetConditionContainerHooksCompatible)
+	case len(incompatible) > 0:
+		meta.SetStatusCondition(&conditions, metav1.Condition{
+			Type:               v1alpha1.AutoscalingRunnerSetConditionContainerHooksCompatible,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonContainerHooksIncompatible,
+			Message:            fmt.Sprintf("The runner image is older than the minRunnerVersion of the container hooks %s, which are not used", strings.Join(incompatible, ", ")),
+			ObservedGeneration: autoscalingRunnerSet.Generation,
+		})
+	default:
+		meta.SetStatusCondition(&conditions, metav1.Condition{
+			Type:               v1alpha1.AutoscalingRunnerSetConditionContainerHooksCompatible,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonContainerHooksCompatible,
+			Message:            "All the container hooks are compatible with the runner image",
+			ObservedGeneration: autoscalingRunnerSet.Generation,
+		})
+	}
+
+	if equality.Semantic.DeepEqual(conditions, autoscalingRunnerSet.Status.Conditions) {
+		return nil
+	}
+
+	if len(incompatible) > 0 {
+		log.Info("Container hooks are not compatible with the runner image", "containerHooks", incompatible)
+	}
+
+	if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		obj.Status.Conditions = conditions
+	}); err != nil {
+		return fmt.Errorf("failed to update container hooks condition: %w", err)
+	}
+
+	return nil
+}
+
+// compatibleContainerHooks splits the container hooks into the ones compatible with the version of the runner image,
+// and the names of the others. Hooks are assumed to be compatible with an image whose tag isn't a version, like latest.
+func compatibleContainerHooks(hooks []v1alpha1.ContainerHooks, runnerImage string) ([]v1alpha1.ContainerHooks, []string) {
+	var (
+		compatible   []v1alpha1.ContainerHooks
+		incompatible []string
+	)
+
+	runnerVersion, ok := imageVersion(runnerImage)
+	for _, h := range hooks {
+		if ok && h.MinRunnerVersion != "" {
+			if minVersion, valid := parseVersion(h.MinRunnerVersion); valid && compareVersions(runnerVersion, minVersion) < 0 {
+				incompatible = append(incompatible, h.Name)
+				continue
+			}
+		}
+		compatible = append(compatible, h)
+	}
+
+	return compatible, incompatible
+}
+
+// imageVersion returns the version of the tag of the image, like 2.317.0 for ghcr.io/actions/actions-runner:2.317.0-ubuntu-22.04.
+func imageVersion(image string) ([3]int, bool) {
+	if strings.Contains(image, "@") {
+		return [3]int{}, false
+	}
+
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return [3]int{}, false
+	}
+
+	tag, _, _ := strings.Cut(image[i+1:], "-")
+	return parseVersion(tag)
+}
+
+// parseVersion parses versions like 2, 2.317 and v2.317.0.
+func parseVersion(s string) ([3]int, bool) {
+	var version [3]int
+
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) > len(version) {
+		return version, false
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return version, false
+		}
+		version[i] = n
+	}
+
+	return version, true
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func containerHooksNames(hooks []v1alpha1.ContainerHooks) []string {
+	names := make([]string, 0, len(hooks))
+	for _, h := range hooks {
+		names = append(names, h.Name)
+	}
+	return names
+}
+
+func containerHooksWeight(hooks *v1alpha1.ContainerHooks) int {
+	if hooks.Weight == nil {
+		return 1
+	}
+	return *hooks.Weight
+}
+
+// selectContainerHooks returns the container hooks for a new runner, which are the ones whose share of the runners
+// in use is the furthest below their weight, or nil when no hooks have a weight.
+func selectContainerHooks(hooks []v1alpha1.ContainerHooks, inUse map[string]int) *v1alpha1.ContainerHooks {
+	var selected *v1alpha1.ContainerHooks
+	for i := range hooks {
+		h := &hooks[i]
+		weight := containerHooksWeight(h)
+		if weight <= 0 {
+			continue
+		}
+
+		// Compare inUse[h]/weight(h) < inUse[selected]/weight(selected) without dividing
+		if selected == nil || inUse[h.Name]*containerHooksWeight(selected) < inUse[selected.Name]*weight {
+			selected = h
+		}
+	}
+	return selected
+}
+
+// containerHooksInUse counts the runners by the name of the container hooks they use.
+func containerHooksInUse(runners ...[]*v1alpha1.EphemeralRunner) map[string]int {
+	inUse := make(map[string]int)
+	for _, list := range runners {
+		for _, runner := range list {
+			if name, ok := runner.Labels[LabelKeyContainerHooks]; ok {
+				inUse[name]++
+			}
+		}
+	}
+	return inUse
+}
+
+// applyContainerHooks makes the runner use the container hooks, copied from their image by an init container
+// into a volume mounted in the runner container.
+func applyContainerHooks(runner *v1alpha1.EphemeralRunner, hooks *v1alpha1.ContainerHooks) {
+	if runner.Labels == nil {
+		runner.Labels = make(map[string]string)
+	}
+	runner.Labels[LabelKeyContainerHooks] = hooks.Name
+
+	// The pod template is shared with the runner set
+	runner.Spec.PodTemplateSpec = *runner.Spec.PodTemplateSpec.DeepCopy()
+
+	path := hooks.Path
+	if path == "" {
+		path = defaultContainerHooksPath
+	}
+
+	spec := &runner.Spec.PodTemplateSpec.Spec
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: containerHooksVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	})
+
+	mount := corev1.VolumeMount{
+		Name:      containerHooksVolumeName,
+		MountPath: containerHooksMountPath,
+	}
+
+	spec.InitContainers = append(spec.InitContainers, corev1.Container{
+		Name:         containerHooksInitName,
+		Image:        hooks.Image,
+		Command:      []string{"cp", "-r", strings.TrimSuffix(path, "/") + "/.", containerHooksMountPath},
+		VolumeMounts: []corev1.VolumeMount{mount},
+	})
+
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		if c.Name != EphemeralRunnerContainerName {
+			continue
+		}
+
+		c.VolumeMounts = append(c.VolumeMounts, mount)
+
+		env := corev1.EnvVar{Name: envVarRunnerContainerHooks, Value: containerHooksMountPath + "/index.js"}
+		replaced := false
+		for j := range c.Env {
+			if c.Env[j].Name == envVarRunnerContainerHooks {
+				c.Env[j] = env
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.Env = append(c.Env, env)
+		}
+	}
+}
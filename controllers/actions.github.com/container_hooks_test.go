@@ -0,0 +1,105 @@
+package actionsgithubcom
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCompatibleContainerHooks(t *testing.T) {
+	hooks := []v1alpha1.ContainerHooks{
+		{Name: "v0.5.1", Image: "hooks:0.5.1"},
+		{Name: "v0.6.0", Image: "hooks:0.6.0", MinRunnerVersion: "2.317.0"},
+	}
+
+	tests := map[string]struct {
+		runnerImage      string
+		wantCompatible   []string
+		wantIncompatible []string
+	}{
+		"newer runner":           {runnerImage: "ghcr.io/actions/actions-runner:2.319.1", wantCompatible: []string{"v0.5.1", "v0.6.0"}},
+		"same runner version":    {runnerImage: "ghcr.io/actions/actions-runner:v2.317.0-ubuntu-22.04", wantCompatible: []string{"v0.5.1", "v0.6.0"}},
+		"older runner":           {runnerImage: "ghcr.io/actions/actions-runner:2.316.1", wantCompatible: []string{"v0.5.1"}, wantIncompatible: []string{"v0.6.0"}},
+		"tag is not a version":   {runnerImage: "ghcr.io/actions/actions-runner:latest", wantCompatible: []string{"v0.5.1", "v0.6.0"}},
+		"registry with a port":   {runnerImage: "registry:5000/actions-runner", wantCompatible: []string{"v0.5.1", "v0.6.0"}},
+		"image pinned by digest": {runnerImage: "ghcr.io/actions/actions-runner@sha256:0123", wantCompatible: []string{"v0.5.1", "v0.6.0"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compatible, incompatible := compatibleContainerHooks(hooks, tc.runnerImage)
+			assert.Equal(t, tc.wantCompatible, containerHooksNames(compatible))
+			assert.Equal(t, tc.wantIncompatible, incompatible)
+		})
+	}
+}
+
+func TestSelectContainerHooks(t *testing.T) {
+	weight := func(w int) *int { return &w }
+
+	hooks := []v1alpha1.ContainerHooks{
+		{Name: "old", Weight: weight(3)},
+		{Name: "new", Weight: weight(1)},
+		{Name: "disabled", Weight: weight(0)},
+	}
+
+	inUse := map[string]int{"new": 1}
+	selected := map[string]int{}
+	for i := 0; i < 7; i++ {
+		h := selectContainerHooks(hooks, inUse)
+		require.NotNil(t, h)
+		inUse[h.Name]++
+		selected[h.Name]++
+	}
+
+	assert.Equal(t, map[string]int{"old": 6, "new": 1}, selected)
+	assert.Nil(t, selectContainerHooks(hooks[2:], inUse))
+	assert.Nil(t, selectContainerHooks(nil, inUse))
+}
+
+func TestApplyContainerHooks(t *testing.T) {
+	runnerSet := &v1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-runner-set", Namespace: "test-ns"},
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			EphemeralRunnerSpec: v1alpha1.EphemeralRunnerSpec{
+				PodTemplateSpec: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  EphemeralRunnerContainerName,
+								Image: "ghcr.io/actions/actions-runner:2.317.0",
+								Env: []corev1.EnvVar{
+									{Name: envVarRunnerContainerHooks, Value: "/home/runner/k8s/index.js"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	original := runnerSet.DeepCopy()
+
+	var b ResourceBuilder
+	runner := b.newEphemeralRunner(runnerSet)
+	applyContainerHooks(runner, &v1alpha1.ContainerHooks{Name: "v0.6.0", Image: "hooks:0.6.0", Path: "/hooks/"})
+
+	assert.Equal(t, original, runnerSet, "the runner set must not be modified")
+	assert.Equal(t, "v0.6.0", runner.Labels[LabelKeyContainerHooks])
+
+	spec := runner.Spec.PodTemplateSpec.Spec
+	require.Len(t, spec.InitContainers, 1)
+	assert.Equal(t, "hooks:0.6.0", spec.InitContainers[0].Image)
+	assert.Equal(t, []string{"cp", "-r", "/hooks/.", containerHooksMountPath}, spec.InitContainers[0].Command)
+
+	require.Len(t, spec.Volumes, 1)
+	assert.Equal(t, containerHooksVolumeName, spec.Volumes[0].Name)
+
+	runnerContainer := spec.Containers[0]
+	assert.Equal(t, []corev1.EnvVar{{Name: envVarRunnerContainerHooks, Value: containerHooksMountPath + "/index.js"}}, runnerContainer.Env)
+	assert.Equal(t, []corev1.VolumeMount{{Name: containerHooksVolumeName, MountPath: containerHooksMountPath}}, runnerContainer.VolumeMounts)
+}
@@ -24,13 +24,16 @@ import (
 	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
 	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 	"github.com/go-logr/logr"
 	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -52,7 +55,12 @@ type EphemeralRunnerReconciler struct {
 	Log           logr.Logger
 	Scheme        *runtime.Scheme
 	ActionsClient actions.MultiClient
+	// Clientset reads the logs of the failed runner pods captured by failureRetention.logLines.
+	// The logs aren't captured when it's nil.
+	Clientset kubernetes.Interface
 	ResourceBuilder
+
+	PublishMetrics bool
 }
 
 // +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunners,verbs=get;list;watch;create;update;patch;delete
@@ -60,7 +68,7 @@ type EphemeralRunnerReconciler struct {
 // +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunners/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods/status,verbs=get
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=create;get;list;watch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=create;get;list;watch;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -75,6 +83,8 @@ func (r *EphemeralRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	ctx = withScaleSet(ctx, ephemeralRunner)
+
 	if !ephemeralRunner.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(ephemeralRunner, ephemeralRunnerFinalizerName) {
 			return ctrl.Result{}, nil
@@ -133,6 +143,16 @@ func (r *EphemeralRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	}
 
 	if ephemeralRunner.IsDone() {
+		retainedFor, err := r.failedPodRetainedFor(ctx, ephemeralRunner, log)
+		if err != nil {
+			log.Error(err, "Failed to check the retention of the failed pod")
+			return ctrl.Result{}, err
+		}
+		if retainedFor > 0 {
+			log.Info("Keeping the failed pod of the ephemeral runner for post-mortem", "for", retainedFor)
+			return ctrl.Result{RequeueAfter: retainedFor}, nil
+		}
+
 		log.Info("Cleaning up resources after after ephemeral runner termination", "phase", ephemeralRunner.Status.Phase)
 		done, err := r.cleanupResources(ctx, ephemeralRunner, log)
 		if err != nil {
@@ -176,11 +196,13 @@ func (r *EphemeralRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, nil
 	}
 
+	registered := false
 	if ephemeralRunner.Status.RunnerId == 0 {
 		log.Info("Creating new ephemeral runner registration and updating status with runner config")
 		if r, err := r.updateStatusWithRunnerConfig(ctx, ephemeralRunner, log); r != nil {
 			return *r, err
 		}
+		registered = true
 	}
 
 	secret := new(corev1.Secret)
@@ -202,6 +224,12 @@ func (r *EphemeralRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 			log.Error(err, "Failed to fetch secret")
 			return ctrl.Result{}, err
 		}
+	} else if registered || jitConfigSecretOutdated(secret, ephemeralRunner) {
+		// The runner was registered again to replace the JIT config of the secret
+		if err := r.updateSecret(ctx, ephemeralRunner, secret, log); err != nil {
+			log.Error(err, "Failed to update secret")
+			return ctrl.Result{}, err
+		}
 	}
 
 	pod := new(corev1.Pod)
@@ -211,7 +239,25 @@ func (r *EphemeralRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 			log.Error(err, "Failed to fetch the pod")
 			return ctrl.Result{}, err
 
-		case len(ephemeralRunner.Status.Failures) > 5:
+		case podLost(ephemeralRunner) && !ephemeralRunner.Status.LostPods[string(ephemeralRunner.Status.PodUID)]:
+			log.Info("EphemeralRunner pod was deleted out of band", "podId", ephemeralRunner.Status.PodUID, "jobRequestId", ephemeralRunner.Status.JobRequestId)
+			if err := r.markPodAsLost(ctx, ephemeralRunner, log); err != nil {
+				log.Error(err, "Failed to track the lost pod")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+
+		case podLost(ephemeralRunner) && ephemeralRunner.Status.JobRequestId > 0:
+			// The job was running in the lost pod, so it's lost as well.
+			// The runner can't take another job, so it's deleted to be replaced by the EphemeralRunnerSet.
+			log.Info("Deleting EphemeralRunner that lost its pod while running a job", "jobRequestId", ephemeralRunner.Status.JobRequestId)
+			if err := r.Delete(ctx, ephemeralRunner); err != nil && !kerrors.IsNotFound(err) {
+				log.Error(err, "Failed to delete ephemeral runner that lost its pod")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+
+		case len(ephemeralRunner.Status.Failures) > maxPodFailures:
 			log.Info("EphemeralRunner has failed more than 5 times. Marking it as failed")
 			errMessage := fmt.Sprintf("Pod has failed to start more than 5 times: %s", pod.Status.Message)
 			if err := r.markAsFailed(ctx, ephemeralRunner, errMessage, ReasonTooManyPodFailures, log); err != nil {
@@ -222,6 +268,17 @@ func (r *EphemeralRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 
 		default:
 			// Pod was not found. Create if the pod has never been created
+			if reason := jitConfigRefreshReason(secret, time.Now()); reason != "" {
+				refreshed, err := r.refreshJitConfig(ctx, ephemeralRunner, reason, log)
+				if err != nil {
+					log.Error(err, "Failed to refresh the jitconfig")
+					return ctrl.Result{}, err
+				}
+				if refreshed {
+					return ctrl.Result{Requeue: true}, nil
+				}
+			}
+
 			log.Info("Creating new EphemeralRunner pod.")
 			result, err := r.createPod(ctx, ephemeralRunner, secret, log)
 			switch {
@@ -268,6 +325,10 @@ func (r *EphemeralRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 			log.Info("Failed to update ephemeral runner status. Requeue to not miss this event")
 			return ctrl.Result{}, err
 		}
+		if err := r.updatePodJobMetadata(ctx, ephemeralRunner, pod, log); err != nil {
+			log.Error(err, "Failed to update the pod with the job metadata")
+			return ctrl.Result{}, err
+		}
 		return ctrl.Result{}, nil
 
 	case cs.State.Terminated.ExitCode != 0: // failed
@@ -396,7 +457,7 @@ func (r *EphemeralRunnerReconciler) cleanupContainerHooksResources(ctx context.C
 func (r *EphemeralRunnerReconciler) cleanupRunnerLinkedPods(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, log logr.Logger) (done bool, err error) {
 	runnerLinedLabels := client.MatchingLabels(
 		map[string]string{
-			"runner-pod": ephemeralRunner.Name,
+			labelKeyRunnerPod: ephemeralRunner.Name,
 		},
 	)
 	var runnerLinkedPodList corev1.PodList
@@ -431,7 +492,7 @@ func (r *EphemeralRunnerReconciler) cleanupRunnerLinkedPods(ctx context.Context,
 func (r *EphemeralRunnerReconciler) cleanupRunnerLinkedSecrets(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, log logr.Logger) (done bool, err error) {
 	runnerLinkedLabels := client.MatchingLabels(
 		map[string]string{
-			"runner-pod": ephemeralRunner.ObjectMeta.Name,
+			labelKeyRunnerPod: ephemeralRunner.ObjectMeta.Name,
 		},
 	)
 	var runnerLinkedSecretList corev1.SecretList
@@ -495,9 +556,21 @@ func (r *EphemeralRunnerReconciler) markAsFinished(ctx context.Context, ephemera
 }
 
 // deletePodAsFailed is responsible for deleting the pod and updating the .Status.Failures for tracking failure count.
-// It should not be responsible for setting the status to Failed.
+// It should not be responsible for setting the status to Failed, except when the failed pod is retained for post-mortem:
+// the last pod of a runner failing too many times is then kept instead of deleted, see failureRetention.
 func (r *EphemeralRunnerReconciler) deletePodAsFailed(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, pod *corev1.Pod, log logr.Logger) error {
-	if pod.ObjectMeta.DeletionTimestamp.IsZero() {
+	var failure *v1alpha1.EphemeralRunnerFailure
+	if retention := r.failureRetention(ctx, ephemeralRunner, log); retention != nil {
+		failure = r.captureFailure(ctx, pod, retention, log)
+		failure.Retained = retention.KeepFailedPods > 0 && podFailures(ephemeralRunner, pod) > maxPodFailures && pod.DeletionTimestamp.IsZero()
+		if failure.Retained {
+			if err := r.retainFailedPod(ctx, ephemeralRunner, pod, retention, log); err != nil {
+				return err
+			}
+		}
+	}
+
+	if (failure == nil || !failure.Retained) && pod.ObjectMeta.DeletionTimestamp.IsZero() {
 		log.Info("Deleting the ephemeral runner pod", "podId", pod.UID)
 		if err := r.Delete(ctx, pod); err != nil && !kerrors.IsNotFound(err) {
 			return fmt.Errorf("failed to delete pod with status failed: %v", err)
@@ -513,14 +586,71 @@ func (r *EphemeralRunnerReconciler) deletePodAsFailed(ctx context.Context, ephem
 		obj.Status.Ready = false
 		obj.Status.Reason = pod.Status.Reason
 		obj.Status.Message = pod.Status.Message
+		if failure != nil {
+			obj.Status.LastFailure = failure
+		}
 	}); err != nil {
 		return fmt.Errorf("failed to update ephemeral runner status: failed attempts: %v", err)
 	}
 
+	if failure != nil && failure.Retained {
+		// The retained pod isn't gone, so the runner is marked as failed here rather than once its pod is
+		errMessage := fmt.Sprintf("Pod has failed to start more than 5 times: %s", pod.Status.Message)
+		return r.markAsFailed(ctx, ephemeralRunner, errMessage, ReasonTooManyPodFailures, log)
+	}
+
 	log.Info("EphemeralRunner pod is deleted and status is updated with failure count")
 	return nil
 }
 
+// markPodAsLost records the pod of the runner as deleted out of band in .Status.LostPods.
+// A lost pod doesn't count as a failure, so that e.g. draining nodes doesn't make runners fail for too many pod failures.
+func (r *EphemeralRunnerReconciler) markPodAsLost(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, log logr.Logger) error {
+	podUID := string(ephemeralRunner.Status.PodUID)
+	jobLost := ephemeralRunner.Status.JobRequestId > 0
+
+	if err := patchSubResource(ctx, r.Status(), ephemeralRunner, func(obj *v1alpha1.EphemeralRunner) {
+		if obj.Status.LostPods == nil {
+			obj.Status.LostPods = make(map[string]bool)
+		}
+		obj.Status.LostPods[podUID] = true
+		obj.Status.Ready = false
+		obj.Status.Reason = ReasonPodLost
+		obj.Status.Message = fmt.Sprintf("Pod %s was deleted out of band", podUID)
+	}); err != nil {
+		return fmt.Errorf("failed to update ephemeral runner status: lost pods: %v", err)
+	}
+
+	if r.PublishMetrics {
+		parsedURL, err := actions.ParseGitHubConfigFromURL(ephemeralRunner.Spec.GitHubConfigUrl)
+		if err != nil {
+			log.Error(err, "Github Config URL is invalid", "URL", ephemeralRunner.Spec.GitHubConfigUrl)
+			return nil
+		}
+
+		metrics.AddEphemeralRunnerPodLoss(
+			metrics.CommonLabels{
+				Name:         ephemeralRunner.Labels[LabelKeyGitHubScaleSetName],
+				Namespace:    ephemeralRunner.Labels[LabelKeyGitHubScaleSetNamespace],
+				Repository:   parsedURL.Repository,
+				Organization: parsedURL.Organization,
+				Enterprise:   parsedURL.Enterprise,
+			},
+			jobLost,
+		)
+	}
+
+	log.Info("EphemeralRunner status is updated with the lost pod")
+	return nil
+}
+
+// podLost returns true when the last pod created for the runner was deleted out of band,
+// i.e. not by deletePodAsFailed. It must only be called once the pod is known to be gone.
+func podLost(ephemeralRunner *v1alpha1.EphemeralRunner) bool {
+	podUID := string(ephemeralRunner.Status.PodUID)
+	return podUID != "" && !ephemeralRunner.Status.Failures[podUID]
+}
+
 // updateStatusWithRunnerConfig fetches runtime configuration needed by the runner
 // This method should always set .status.runnerId and .status.runnerJITConfig
 func (r *EphemeralRunnerReconciler) updateStatusWithRunnerConfig(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, log logr.Logger) (*ctrl.Result, error) {
@@ -613,58 +743,76 @@ func (r *EphemeralRunnerReconciler) updateStatusWithRunnerConfig(ctx context.Con
 	return nil, nil
 }
 
-func (r *EphemeralRunnerReconciler) createPod(ctx context.Context, runner *v1alpha1.EphemeralRunner, secret *corev1.Secret, log logr.Logger) (ctrl.Result, error) {
+// proxyEnvs returns the proxy environment variables of a runner container, referring to the keys of the proxy secret
+// created from the proxy config by the EphemeralRunnerSet.
+func proxyEnvs(proxy *v1alpha1.ProxyConfig, secretName string) []corev1.EnvVar {
 	var envs []corev1.EnvVar
-	if runner.Spec.ProxySecretRef != "" {
-		http := corev1.EnvVar{
-			Name: "http_proxy",
-			ValueFrom: &corev1.EnvVarSource{
-				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: runner.Spec.ProxySecretRef,
-					},
-					Key: "http_proxy",
+	http := corev1.EnvVar{
+		Name: "http_proxy",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secretName,
 				},
+				Key: "http_proxy",
 			},
-		}
-		if runner.Spec.Proxy.HTTP != nil {
-			envs = append(envs, http)
-		}
+		},
+	}
+	if proxy.HTTP != nil {
+		envs = append(envs, http)
+	}
 
-		https := corev1.EnvVar{
-			Name: "https_proxy",
-			ValueFrom: &corev1.EnvVarSource{
-				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: runner.Spec.ProxySecretRef,
-					},
-					Key: "https_proxy",
+	https := corev1.EnvVar{
+		Name: "https_proxy",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secretName,
 				},
+				Key: "https_proxy",
 			},
-		}
-		if runner.Spec.Proxy.HTTPS != nil {
-			envs = append(envs, https)
-		}
+		},
+	}
+	if proxy.HTTPS != nil {
+		envs = append(envs, https)
+	}
 
-		noProxy := corev1.EnvVar{
-			Name: "no_proxy",
-			ValueFrom: &corev1.EnvVarSource{
-				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: runner.Spec.ProxySecretRef,
-					},
-					Key: "no_proxy",
+	noProxy := corev1.EnvVar{
+		Name: "no_proxy",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secretName,
 				},
+				Key: "no_proxy",
 			},
-		}
-		if len(runner.Spec.Proxy.NoProxy) > 0 {
-			envs = append(envs, noProxy)
-		}
+		},
+	}
+	if len(proxy.NoProxy) > 0 {
+		envs = append(envs, noProxy)
+	}
+
+	return envs
+}
+
+func (r *EphemeralRunnerReconciler) createPod(ctx context.Context, runner *v1alpha1.EphemeralRunner, secret *corev1.Secret, log logr.Logger) (ctrl.Result, error) {
+	var envs []corev1.EnvVar
+	if runner.Spec.ProxySecretRef != "" {
+		envs = proxyEnvs(runner.Spec.Proxy, runner.Spec.ProxySecretRef)
 	}
 
 	log.Info("Creating new pod for ephemeral runner")
 	newPod := r.ResourceBuilder.newEphemeralRunnerPod(ctx, runner, secret, envs...)
 
+	node, err := r.claimWarmPod(ctx, runner, log)
+	if err != nil {
+		// The runner pod is created without a warm pod rather than waiting for one
+		log.Error(err, "Failed to claim a warm pod")
+	}
+	if node != "" {
+		requireNode(&newPod.Spec, node)
+	}
+
 	if err := ctrl.SetControllerReference(runner, newPod, r.Scheme); err != nil {
 		log.Error(err, "Failed to set controller reference to a new pod")
 		return ctrl.Result{}, err
@@ -676,6 +824,19 @@ func (r *EphemeralRunnerReconciler) createPod(ctx context.Context, runner *v1alp
 		return ctrl.Result{}, err
 	}
 
+	if err := r.markSecretUsedByPod(ctx, secret, newPod.UID); err != nil {
+		log.Error(err, "Failed to annotate the secret with the pod UID")
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Updating ephemeral runner status with the pod UID", "podId", newPod.UID)
+	if err := patchSubResource(ctx, r.Status(), runner, func(obj *v1alpha1.EphemeralRunner) {
+		obj.Status.PodUID = newPod.UID
+	}); err != nil {
+		log.Error(err, "Failed to update ephemeral runner status with the pod UID")
+		return ctrl.Result{}, err
+	}
+
 	log.Info("Created ephemeral runner pod",
 		"runnerScaleSetId", runner.Spec.RunnerScaleSetId,
 		"runnerName", runner.Status.RunnerName,
@@ -689,6 +850,7 @@ func (r *EphemeralRunnerReconciler) createPod(ctx context.Context, runner *v1alp
 func (r *EphemeralRunnerReconciler) createSecret(ctx context.Context, runner *v1alpha1.EphemeralRunner, log logr.Logger) (*ctrl.Result, error) {
 	log.Info("Creating new secret for ephemeral runner")
 	jitSecret := r.ResourceBuilder.newEphemeralRunnerJitSecret(runner)
+	setJitConfigSecretData(jitSecret, runner, time.Now())
 
 	if err := ctrl.SetControllerReference(runner, jitSecret, r.Scheme); err != nil {
 		return &ctrl.Result{}, fmt.Errorf("failed to set controller reference: %v", err)
@@ -732,6 +894,10 @@ func (r *EphemeralRunnerReconciler) updateRunStatusFromPod(ctx context.Context,
 }
 
 func (r *EphemeralRunnerReconciler) actionsClientFor(ctx context.Context, runner *v1alpha1.EphemeralRunner) (actions.ActionsService, error) {
+	if isLoadTestSimulation(runner.Annotations) {
+		return loadTestSimulationClient, nil
+	}
+
 	secret := new(corev1.Secret)
 	if err := r.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: runner.Spec.GitHubConfigSecret}, secret); err != nil {
 		return nil, fmt.Errorf("failed to get secret: %w", err)
@@ -793,6 +959,29 @@ func (r *EphemeralRunnerReconciler) actionsClientOptionsFor(ctx context.Context,
 		}
 
 		opts = append(opts, actions.WithRootCAs(pool))
+
+		cert, err := tlsConfig.ToClientCertificate(func(name string) (*corev1.Secret, error) {
+			var secret corev1.Secret
+			err := r.Get(
+				ctx,
+				types.NamespacedName{
+					Namespace: runner.Namespace,
+					Name:      name,
+				},
+				&secret,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+			}
+
+			return &secret, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tls client certificate: %w", err)
+		}
+		if cert != nil {
+			opts = append(opts, actions.WithClientCertificate(*cert))
+		}
 	}
 
 	return opts, nil
@@ -851,7 +1040,7 @@ func (r *EphemeralRunnerReconciler) SetupWithManager(mgr ctrl.Manager, opts ...O
 			Owns(&corev1.Pod{}).
 			WithEventFilter(predicate.ResourceVersionChangedPredicate{}),
 		opts,
-	).Complete(r)
+	).Complete(reconcilemetrics.Wrap("ephemeralrunner", mgr.GetCache(), &v1alpha1.EphemeralRunner{}, r))
 }
 
 func runnerContainerStatus(pod *corev1.Pod) *corev1.ContainerStatus {
@@ -0,0 +1,181 @@
+package actionsgithubcom
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultEphemeralRunnerCreationBurst is the number of EphemeralRunners that can be created at once
+	// when no creation has been made for a while.
+	DefaultEphemeralRunnerCreationBurst = 50
+
+	// creationRequestTTL is how long a queued creation request is kept without being admitted again,
+	// so that the requests of EphemeralRunnerSets that stopped reconciling don't hold back the others forever.
+	creationRequestTTL = time.Minute
+
+	minCreationRetryDelay = time.Second
+	maxCreationRetryDelay = 30 * time.Second
+)
+
+// EphemeralRunnerCreationScheduler admits the creations of EphemeralRunners of all the EphemeralRunnerSets under a global rate limit,
+// so that several scale sets scaling out by hundreds at once don't overload the API server.
+//
+// The pending creations are queued per EphemeralRunnerSet, ordered by the creation priority of the scale set and the age of the request.
+// A set is admitted only the creations that are left once all the sets ahead of it in the queue are served,
+// and is told when to ask again for the rest.
+type EphemeralRunnerCreationScheduler struct {
+	limiter *rate.Limiter
+
+	clock clock.PassiveClock
+
+	mu    sync.Mutex
+	queue map[types.NamespacedName]*creationRequest
+}
+
+type creationRequest struct {
+	key      types.NamespacedName
+	priority int
+	count    int
+	// since is when the set started waiting for its creations, which orders the sets of the same priority.
+	since time.Time
+	// updated is when the request was admitted last.
+	updated time.Time
+
+	labels metrics.CommonLabels
+}
+
+// NewEphemeralRunnerCreationScheduler returns a scheduler that admits up to perSecond creations per second on average,
+// and burst creations at once.
+func NewEphemeralRunnerCreationScheduler(perSecond float64, burst int) *EphemeralRunnerCreationScheduler {
+	if burst <= 0 {
+		burst = DefaultEphemeralRunnerCreationBurst
+	}
+
+	return &EphemeralRunnerCreationScheduler{
+		limiter: rate.NewLimiter(rate.Limit(perSecond), burst),
+		clock:   clock.RealClock{},
+		queue:   map[types.NamespacedName]*creationRequest{},
+	}
+}
+
+// Admit queues the count creations the EphemeralRunnerSet of the key needs, replacing its previous request,
+// and returns how many of them it may create now. When some are left, it also returns how long to wait before asking again.
+func (s *EphemeralRunnerCreationScheduler) Admit(key types.NamespacedName, priority, count int, labels metrics.CommonLabels) (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.expire(now)
+
+	if count <= 0 {
+		s.remove(key)
+		return 0, 0
+	}
+
+	req, ok := s.queue[key]
+	if !ok {
+		req = &creationRequest{key: key, since: now}
+		s.queue[key] = req
+	}
+	req.priority = priority
+	req.count = count
+	req.updated = now
+	req.labels = labels
+
+	var ahead int
+	for _, r := range s.ordered() {
+		if r == req {
+			break
+		}
+		ahead += r.count
+	}
+
+	tokens := int(s.limiter.TokensAt(now))
+
+	admitted := min(count, tokens-ahead)
+	if admitted > 0 && s.limiter.AllowN(now, admitted) {
+		req.count -= admitted
+	} else {
+		admitted = 0
+	}
+
+	if req.count == 0 {
+		s.remove(key)
+		return admitted, 0
+	}
+
+	metrics.SetEphemeralRunnerCreationQueueDepth(labels, req.count)
+
+	// Ask again once the tokens for the sets ahead and the rest of this set are replenished, or as many as the burst
+	deficit := min(ahead+req.count, s.limiter.Burst()) - (tokens - admitted)
+	wait := time.Duration(float64(deficit) / float64(s.limiter.Limit()) * float64(time.Second))
+
+	return admitted, min(max(wait, minCreationRetryDelay), maxCreationRetryDelay)
+}
+
+// Pending returns true when the EphemeralRunnerSet of the key is waiting for some of its creations to be admitted.
+func (s *EphemeralRunnerCreationScheduler) Pending(key types.NamespacedName) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(s.clock.Now())
+
+	_, ok := s.queue[key]
+	return ok
+}
+
+// Forget removes the request of the EphemeralRunnerSet of the key, like when it no longer needs to scale up or is deleted.
+func (s *EphemeralRunnerCreationScheduler) Forget(key types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(key)
+}
+
+// ordered returns the queued requests in the order they are admitted in: the highest priority first, then the oldest first.
+// The caller must hold the lock.
+func (s *EphemeralRunnerCreationScheduler) ordered() []*creationRequest {
+	reqs := make([]*creationRequest, 0, len(s.queue))
+	for _, r := range s.queue {
+		reqs = append(reqs, r)
+	}
+
+	sort.Slice(reqs, func(i, j int) bool {
+		if reqs[i].priority != reqs[j].priority {
+			return reqs[i].priority > reqs[j].priority
+		}
+		if !reqs[i].since.Equal(reqs[j].since) {
+			return reqs[i].since.Before(reqs[j].since)
+		}
+		return reqs[i].key.String() < reqs[j].key.String()
+	})
+
+	return reqs
+}
+
+// expire removes the requests not admitted again for creationRequestTTL. The caller must hold the lock.
+func (s *EphemeralRunnerCreationScheduler) expire(now time.Time) {
+	for key, r := range s.queue {
+		if now.Sub(r.updated) >= creationRequestTTL {
+			s.remove(key)
+		}
+	}
+}
+
+// remove removes the request of the key. The caller must hold the lock.
+func (s *EphemeralRunnerCreationScheduler) remove(key types.NamespacedName) {
+	r, ok := s.queue[key]
+	if !ok {
+		return
+	}
+
+	delete(s.queue, key)
+	metrics.SetEphemeralRunnerCreationQueueDepth(r.labels, 0)
+}
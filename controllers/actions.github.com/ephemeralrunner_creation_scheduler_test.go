@@ -0,0 +1,163 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	testclock "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEphemeralRunnerCreationScheduler(t *testing.T) {
+	clock := testclock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	s := NewEphemeralRunnerCreationScheduler(1, 5)
+	s.clock = clock
+
+	a := types.NamespacedName{Namespace: "arc-runners", Name: "a"}
+	b := types.NamespacedName{Namespace: "arc-runners", Name: "b"}
+	c := types.NamespacedName{Namespace: "arc-runners", Name: "c"}
+
+	// The burst is admitted right away
+	admitted, wait := s.Admit(a, 0, 3, metrics.CommonLabels{})
+	assert.Equal(t, 3, admitted)
+	assert.Zero(t, wait)
+	assert.False(t, s.Pending(a))
+
+	// The rest of the burst is admitted, and the others are queued
+	admitted, wait = s.Admit(b, 0, 10, metrics.CommonLabels{})
+	assert.Equal(t, 2, admitted)
+	assert.Equal(t, 5*time.Second, wait)
+	assert.True(t, s.Pending(b))
+
+	// A higher priority is served ahead of the older requests
+	clock.Step(time.Second)
+	admitted, wait = s.Admit(c, 1, 4, metrics.CommonLabels{})
+	assert.Equal(t, 1, admitted)
+	assert.Equal(t, 3*time.Second, wait)
+
+	clock.Step(2 * time.Second)
+	admitted, wait = s.Admit(b, 0, 8, metrics.CommonLabels{})
+	assert.Equal(t, 0, admitted)
+	assert.Equal(t, 3*time.Second, wait)
+
+	admitted, _ = s.Admit(c, 1, 3, metrics.CommonLabels{})
+	assert.Equal(t, 2, admitted)
+
+	// The requests of the same priority are served oldest first
+	clock.Step(time.Second)
+	admitted, _ = s.Admit(a, 0, 1, metrics.CommonLabels{})
+	assert.Equal(t, 0, admitted)
+	admitted, _ = s.Admit(c, 1, 0, metrics.CommonLabels{})
+	assert.Equal(t, 0, admitted)
+	assert.False(t, s.Pending(c))
+	admitted, _ = s.Admit(b, 0, 8, metrics.CommonLabels{})
+	assert.Equal(t, 1, admitted)
+
+	// The requests that aren't admitted again expire
+	clock.Step(creationRequestTTL)
+	assert.False(t, s.Pending(a))
+	assert.False(t, s.Pending(b))
+
+	s.Admit(a, 0, 100, metrics.CommonLabels{})
+	s.Forget(a)
+	assert.False(t, s.Pending(a))
+}
+
+func TestEphemeralRunnerSetThrottlesCreations(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "arc-runners",
+			Namespace:   "arc-runners",
+			Annotations: map[string]string{AnnotationKeyCreationPriority: "5"},
+		},
+	}
+
+	ers := &v1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "arc-runners-abcde",
+			Namespace: "arc-runners",
+			UID:       "ers-uid",
+			Labels: map[string]string{
+				LabelKeyGitHubScaleSetName:      "arc-runners",
+				LabelKeyGitHubScaleSetNamespace: "arc-runners",
+			},
+			Finalizers: []string{ephemeralRunnerSetFinalizerName},
+		},
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			Replicas: 5,
+			PatchID:  1,
+			EphemeralRunnerSpec: v1alpha1.EphemeralRunnerSpec{
+				GitHubConfigUrl: "https://github.com/owner/repo",
+			},
+		},
+	}
+
+	c := crfake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ars, ers).
+		WithStatusSubresource(ers).
+		WithIndex(&v1alpha1.EphemeralRunner{}, resourceOwnerKey, newGroupVersionOwnerKindIndexer("EphemeralRunnerSet")).
+		Build()
+
+	clock := testclock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	scheduler := NewEphemeralRunnerCreationScheduler(1, 2)
+	scheduler.clock = clock
+
+	r := &EphemeralRunnerSetReconciler{
+		Client:            c,
+		Log:               logr.Discard(),
+		Scheme:            scheme,
+		CreationScheduler: scheduler,
+	}
+
+	count := func(t *testing.T) int {
+		t.Helper()
+
+		var runners v1alpha1.EphemeralRunnerList
+		require.NoError(t, c.List(ctx, &runners, client.InNamespace("arc-runners")))
+		return len(runners.Items)
+	}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ers)}
+
+	res, err := r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count(t))
+	assert.Equal(t, 2*time.Second, res.RequeueAfter)
+	assert.Equal(t, 5, scheduler.queue[req.NamespacedName].priority)
+
+	// The runners of the patch are created until all of them are admitted, although some of them are already annotated with the patch ID
+	clock.Step(2 * time.Second)
+
+	res, err = r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count(t))
+	assert.Equal(t, time.Second, res.RequeueAfter)
+
+	clock.Step(time.Second)
+
+	res, err = r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 5, count(t))
+	assert.Zero(t, res.RequeueAfter)
+	assert.False(t, scheduler.Pending(req.NamespacedName))
+}
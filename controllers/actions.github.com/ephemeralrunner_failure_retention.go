@@ -0,0 +1,186 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// labelKeyRetainedFailedPod is the label of the failed runner pods kept for post-mortem.
+	labelKeyRetainedFailedPod = "actions.github.com/retained-failed-pod"
+
+	// annotationKeyFailedPodRetainedAt is the annotation of the kept failed runner pods set to when they were kept.
+	annotationKeyFailedPodRetainedAt = "actions.github.com/failed-pod-retained-at"
+
+	// maxPodFailures is the number of pod failures a runner tolerates before it's marked as failed.
+	maxPodFailures = 5
+
+	defaultFailedPodTTL = 24 * time.Hour
+
+	// failureLogsLimitBytes caps the logs captured in the status of a runner whose pod failed.
+	failureLogsLimitBytes = 16 * 1024
+)
+
+// failureRetention returns the failure retention of the scale set of the runner, or nil when it has none.
+func (r *EphemeralRunnerReconciler) failureRetention(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, log logr.Logger) *v1alpha1.FailureRetention {
+	key := types.NamespacedName{
+		Namespace: ephemeralRunner.Labels[LabelKeyGitHubScaleSetNamespace],
+		Name:      ephemeralRunner.Labels[LabelKeyGitHubScaleSetName],
+	}
+	if key.Name == "" {
+		return nil
+	}
+
+	autoscalingRunnerSet := new(v1alpha1.AutoscalingRunnerSet)
+	if err := r.Get(ctx, key, autoscalingRunnerSet); err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to get autoscaling runner set for failure retention")
+		}
+		return nil
+	}
+
+	return autoscalingRunnerSet.Spec.FailureRetention
+}
+
+// +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+
+// captureFailure returns the diagnostic of the failed pod, with the last lines of the logs of its runner container
+// when the retention captures them. Logs that can't be read, like the ones of an evicted pod, are left out.
+func (r *EphemeralRunnerReconciler) captureFailure(ctx context.Context, pod *corev1.Pod, retention *v1alpha1.FailureRetention, log logr.Logger) *v1alpha1.EphemeralRunnerFailure {
+	failure := &v1alpha1.EphemeralRunnerFailure{
+		PodUID:  pod.UID,
+		Time:    metav1.Now(),
+		Reason:  pod.Status.Reason,
+		Message: pod.Status.Message,
+	}
+	if cs := runnerContainerStatus(pod); cs != nil && cs.State.Terminated != nil {
+		failure.ExitCode = cs.State.Terminated.ExitCode
+		if failure.Reason == "" {
+			failure.Reason = cs.State.Terminated.Reason
+		}
+		if failure.Message == "" {
+			failure.Message = cs.State.Terminated.Message
+		}
+	}
+
+	if retention.LogLines == 0 || r.Clientset == nil {
+		return failure
+	}
+
+	tailLines, limitBytes := retention.LogLines, int64(failureLogsLimitBytes)
+	logs, err := r.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  EphemeralRunnerContainerName,
+		TailLines:  &tailLines,
+		LimitBytes: &limitBytes,
+	}).DoRaw(ctx)
+	if err != nil {
+		log.Error(err, "Failed to capture the logs of the failed runner pod", "podId", pod.UID)
+		return failure
+	}
+
+	failure.Logs = string(logs)
+	return failure
+}
+
+// retainFailedPod keeps the failed pod for post-mortem, and deletes the oldest failed pods of the scale set
+// kept beyond the keepFailedPods of the retention.
+func (r *EphemeralRunnerReconciler) retainFailedPod(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, pod *corev1.Pod, retention *v1alpha1.FailureRetention, log logr.Logger) error {
+	if _, ok := pod.Labels[labelKeyRetainedFailedPod]; !ok {
+		log.Info("Keeping the failed runner pod for post-mortem", "podId", pod.UID)
+		if err := patch(ctx, r.Client, pod, func(obj *corev1.Pod) {
+			if obj.Labels == nil {
+				obj.Labels = make(map[string]string)
+			}
+			obj.Labels[labelKeyRetainedFailedPod] = "true"
+			if obj.Annotations == nil {
+				obj.Annotations = make(map[string]string)
+			}
+			obj.Annotations[annotationKeyFailedPodRetainedAt] = time.Now().UTC().Format(time.RFC3339)
+		}); err != nil {
+			return fmt.Errorf("failed to label the failed pod as retained: %w", err)
+		}
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(ephemeralRunner.Namespace), client.MatchingLabels{
+		labelKeyRetainedFailedPod:       "true",
+		LabelKeyGitHubScaleSetName:      ephemeralRunner.Labels[LabelKeyGitHubScaleSetName],
+		LabelKeyGitHubScaleSetNamespace: ephemeralRunner.Labels[LabelKeyGitHubScaleSetNamespace],
+	}); err != nil {
+		return fmt.Errorf("failed to list the retained failed pods: %w", err)
+	}
+
+	retained := make([]*corev1.Pod, 0, len(pods.Items))
+	for i := range pods.Items {
+		if p := &pods.Items[i]; p.UID != pod.UID && p.DeletionTimestamp.IsZero() {
+			retained = append(retained, p)
+		}
+	}
+	// The pod just retained is the newest one
+	sort.SliceStable(retained, func(i, j int) bool {
+		return retained[i].Annotations[annotationKeyFailedPodRetainedAt] > retained[j].Annotations[annotationKeyFailedPodRetainedAt]
+	})
+
+	for i := retention.KeepFailedPods - 1; i < len(retained); i++ {
+		log.Info("Deleting a retained failed pod beyond keepFailedPods", "name", retained[i].Name)
+		if err := r.Delete(ctx, retained[i]); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete retained failed pod: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// failedPodRetainedFor returns how long the failed pod of the failed runner is still kept for,
+// or 0 when it isn't kept anymore and can be deleted.
+func (r *EphemeralRunnerReconciler) failedPodRetainedFor(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, log logr.Logger) (time.Duration, error) {
+	if ephemeralRunner.Status.Phase != corev1.PodFailed || ephemeralRunner.Status.LastFailure == nil || !ephemeralRunner.Status.LastFailure.Retained {
+		return 0, nil
+	}
+
+	pod := new(corev1.Pod)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ephemeralRunner.Namespace, Name: ephemeralRunner.Name}, pod); err != nil {
+		if kerrors.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get the retained failed pod: %w", err)
+	}
+	if pod.UID != ephemeralRunner.Status.LastFailure.PodUID || !pod.DeletionTimestamp.IsZero() {
+		return 0, nil
+	}
+
+	retention := r.failureRetention(ctx, ephemeralRunner, log)
+	if retention == nil || retention.KeepFailedPods == 0 {
+		return 0, nil
+	}
+
+	retainedAt, err := time.Parse(time.RFC3339, pod.Annotations[annotationKeyFailedPodRetainedAt])
+	if err != nil {
+		return 0, nil
+	}
+
+	ttl := defaultFailedPodTTL
+	if retention.TTL != nil {
+		ttl = retention.TTL.Duration
+	}
+	return max(0, time.Until(retainedAt.Add(ttl))), nil
+}
+
+// podFailures returns the number of failed pods of the runner, counting the failed pod.
+func podFailures(ephemeralRunner *v1alpha1.EphemeralRunner, pod *corev1.Pod) int {
+	failures := len(ephemeralRunner.Status.Failures)
+	if !ephemeralRunner.Status.Failures[string(pod.UID)] {
+		failures++
+	}
+	return failures
+}
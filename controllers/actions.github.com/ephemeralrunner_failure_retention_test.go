@@ -0,0 +1,164 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEphemeralRunnerFailureRetention(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	labels := map[string]string{
+		LabelKeyGitHubScaleSetName:      "my-scale-set",
+		LabelKeyGitHubScaleSetNamespace: "arc-runners",
+	}
+
+	newPod := func(name string, uid types.UID) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "arc-runners", UID: uid, Labels: labels},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  EphemeralRunnerContainerName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"}},
+				}},
+			},
+		}
+	}
+
+	retainedPod := func(name string, uid types.UID, retainedAt time.Time) *corev1.Pod {
+		pod := newPod(name, uid)
+		pod.Labels = map[string]string{labelKeyRetainedFailedPod: "true"}
+		for k, v := range labels {
+			pod.Labels[k] = v
+		}
+		pod.Annotations = map[string]string{annotationKeyFailedPodRetainedAt: retainedAt.UTC().Format(time.RFC3339)}
+		return pod
+	}
+
+	newReconciler := func(retention *v1alpha1.FailureRetention, runner *v1alpha1.EphemeralRunner, objs ...client.Object) *EphemeralRunnerReconciler {
+		autoscalingRunnerSet := &v1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-scale-set", Namespace: "arc-runners"},
+			Spec:       v1alpha1.AutoscalingRunnerSetSpec{FailureRetention: retention},
+		}
+		return &EphemeralRunnerReconciler{
+			Client:    crfake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objs, runner, autoscalingRunnerSet)...).WithStatusSubresource(runner).Build(),
+			Clientset: k8sfake.NewSimpleClientset(),
+			Log:       logr.Discard(),
+			Scheme:    scheme,
+		}
+	}
+
+	newRunner := func(status v1alpha1.EphemeralRunnerStatus) *v1alpha1.EphemeralRunner {
+		return &v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "arc-runners", Labels: labels},
+			Status:     status,
+		}
+	}
+
+	podExists := func(t *testing.T, r *EphemeralRunnerReconciler, name string) bool {
+		t.Helper()
+
+		err := r.Get(ctx, types.NamespacedName{Namespace: "arc-runners", Name: name}, new(corev1.Pod))
+		if kerrors.IsNotFound(err) {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+
+	t.Run("captures the logs of the failed pod before deleting it", func(t *testing.T) {
+		runner := newRunner(v1alpha1.EphemeralRunnerStatus{})
+		pod := newPod("runner", "pod-1")
+		r := newReconciler(&v1alpha1.FailureRetention{KeepFailedPods: 1, LogLines: 50}, runner, pod)
+
+		require.NoError(t, r.deletePodAsFailed(ctx, runner, pod, logr.Discard()))
+		assert.False(t, podExists(t, r, "runner"), "the pod is retried while the runner tolerates its failures")
+
+		updated := new(v1alpha1.EphemeralRunner)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(runner), updated))
+		require.NotNil(t, updated.Status.LastFailure)
+		assert.Equal(t, types.UID("pod-1"), updated.Status.LastFailure.PodUID)
+		assert.Equal(t, int32(2), updated.Status.LastFailure.ExitCode)
+		assert.Equal(t, "Error", updated.Status.LastFailure.Reason)
+		assert.Equal(t, "fake logs", updated.Status.LastFailure.Logs)
+		assert.False(t, updated.Status.LastFailure.Retained)
+		assert.True(t, updated.Status.Failures["pod-1"])
+	})
+
+	t.Run("captures nothing without failure retention", func(t *testing.T) {
+		runner := newRunner(v1alpha1.EphemeralRunnerStatus{})
+		pod := newPod("runner", "pod-1")
+		r := newReconciler(nil, runner, pod)
+
+		require.NoError(t, r.deletePodAsFailed(ctx, runner, pod, logr.Discard()))
+
+		updated := new(v1alpha1.EphemeralRunner)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(runner), updated))
+		assert.Nil(t, updated.Status.LastFailure)
+	})
+
+	t.Run("keeps the newest failed pods of the scale set", func(t *testing.T) {
+		runner := newRunner(v1alpha1.EphemeralRunnerStatus{})
+		pod := newPod("runner", "pod-new")
+		now := time.Now()
+		r := newReconciler(&v1alpha1.FailureRetention{KeepFailedPods: 2}, runner, pod,
+			retainedPod("older", "pod-older", now.Add(-2*time.Hour)),
+			retainedPod("old", "pod-old", now.Add(-time.Hour)),
+		)
+
+		require.NoError(t, r.retainFailedPod(ctx, runner, pod, &v1alpha1.FailureRetention{KeepFailedPods: 2}, logr.Discard()))
+
+		updated := new(corev1.Pod)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(pod), updated))
+		assert.Equal(t, "true", updated.Labels[labelKeyRetainedFailedPod])
+		assert.NotEmpty(t, updated.Annotations[annotationKeyFailedPodRetainedAt])
+		assert.True(t, podExists(t, r, "old"))
+		assert.False(t, podExists(t, r, "older"), "the oldest pod beyond keepFailedPods is deleted")
+	})
+
+	t.Run("keeps the retained pod until its ttl expires", func(t *testing.T) {
+		status := v1alpha1.EphemeralRunnerStatus{
+			Phase:       corev1.PodFailed,
+			LastFailure: &v1alpha1.EphemeralRunnerFailure{PodUID: "pod-1", Retained: true},
+		}
+		retention := &v1alpha1.FailureRetention{KeepFailedPods: 1, TTL: &metav1.Duration{Duration: 2 * time.Hour}}
+
+		runner := newRunner(status)
+		r := newReconciler(retention, runner, retainedPod("runner", "pod-1", time.Now().Add(-time.Hour)))
+		retainedFor, err := r.failedPodRetainedFor(ctx, runner, logr.Discard())
+		require.NoError(t, err)
+		assert.InDelta(t, time.Hour.Seconds(), retainedFor.Seconds(), 60)
+
+		runner = newRunner(status)
+		r = newReconciler(retention, runner, retainedPod("runner", "pod-1", time.Now().Add(-3*time.Hour)))
+		retainedFor, err = r.failedPodRetainedFor(ctx, runner, logr.Discard())
+		require.NoError(t, err)
+		assert.Zero(t, retainedFor, "the expired pod is deleted")
+
+		runner = newRunner(status)
+		r = newReconciler(retention, runner, retainedPod("runner", "pod-2", time.Now()))
+		retainedFor, err = r.failedPodRetainedFor(ctx, runner, logr.Discard())
+		require.NoError(t, err)
+		assert.Zero(t, retainedFor, "another pod isn't the retained one")
+	})
+}
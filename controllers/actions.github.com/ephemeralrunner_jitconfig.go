@@ -0,0 +1,136 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Annotations of the per-pod secret holding the JIT config of an EphemeralRunner
+const (
+	// annotationKeyJitConfigGeneratedAt is when the JIT config of the secret was stored, in RFC3339.
+	annotationKeyJitConfigGeneratedAt = "actions.github.com/jit-config-generated-at"
+	// annotationKeyJitConfigPodUID is the UID of the pod that was created with the JIT config of the secret.
+	annotationKeyJitConfigPodUID = "actions.github.com/jit-config-pod-uid"
+)
+
+// jitConfigTTL is how long the JIT config of a runner that has never started is used for a new pod.
+// The registrations of runners that never connect are eventually removed by the Actions service,
+// so an older JIT config is replaced with a new registration before creating the pod.
+const jitConfigTTL = time.Hour
+
+// Reasons for replacing the JIT config of an EphemeralRunner before creating its pod
+const (
+	jitConfigRefreshReasonExpired = "expired"
+	jitConfigRefreshReasonRotated = "pod restarted"
+)
+
+// setJitConfigSecretData stores the JIT config of the runner into the secret, along with the annotations tracking it.
+func setJitConfigSecretData(secret *corev1.Secret, ephemeralRunner *v1alpha1.EphemeralRunner, now time.Time) {
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[jitTokenKey] = []byte(ephemeralRunner.Status.RunnerJITConfig)
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[annotationKeyJitConfigGeneratedAt] = now.UTC().Format(time.RFC3339)
+	delete(secret.Annotations, annotationKeyJitConfigPodUID)
+}
+
+// jitConfigSecretOutdated returns true when the secret doesn't hold the current JIT config of the runner.
+func jitConfigSecretOutdated(secret *corev1.Secret, ephemeralRunner *v1alpha1.EphemeralRunner) bool {
+	return ephemeralRunner.Status.RunnerJITConfig != "" && string(secret.Data[jitTokenKey]) != ephemeralRunner.Status.RunnerJITConfig
+}
+
+// jitConfigRefreshReason returns why the JIT config of the secret should be replaced before creating a new pod with it,
+// or an empty string when it can be used as is.
+//
+// A JIT config is rotated once a pod has been created with it, because the runner of that pod may have already
+// configured itself with it. A JIT config that has never been used is replaced once it's older than jitConfigTTL.
+func jitConfigRefreshReason(secret *corev1.Secret, now time.Time) string {
+	if secret.Annotations[annotationKeyJitConfigPodUID] != "" {
+		return jitConfigRefreshReasonRotated
+	}
+
+	generatedAt, err := time.Parse(time.RFC3339, secret.Annotations[annotationKeyJitConfigGeneratedAt])
+	if err != nil {
+		return ""
+	}
+	if now.Sub(generatedAt) > jitConfigTTL {
+		return jitConfigRefreshReasonExpired
+	}
+	return ""
+}
+
+// updateSecret stores the current JIT config of the runner into its existing secret.
+func (r *EphemeralRunnerReconciler) updateSecret(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, secret *corev1.Secret, log logr.Logger) error {
+	log.Info("Updating ephemeral runner secret with the new jitconfig", "runnerId", ephemeralRunner.Status.RunnerId)
+
+	updated := secret.DeepCopy()
+	setJitConfigSecretData(updated, ephemeralRunner, time.Now())
+	if err := r.Patch(ctx, updated, client.MergeFromWithOptions(secret, client.MergeFromWithOptimisticLock{})); err != nil {
+		return fmt.Errorf("failed to update jit secret: %v", err)
+	}
+
+	*secret = *updated
+	log.Info("Updated ephemeral runner secret", "secretName", secret.Name)
+	return nil
+}
+
+// markSecretUsedByPod records the pod created with the JIT config of the secret,
+// so that the JIT config is rotated if the pod has to be created again.
+func (r *EphemeralRunnerReconciler) markSecretUsedByPod(ctx context.Context, secret *corev1.Secret, podUID types.UID) error {
+	updated := secret.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[annotationKeyJitConfigPodUID] = string(podUID)
+
+	if err := r.Patch(ctx, updated, client.MergeFrom(secret)); err != nil {
+		return fmt.Errorf("failed to annotate jit secret with the pod UID: %v", err)
+	}
+
+	*secret = *updated
+	return nil
+}
+
+// refreshJitConfig removes the runner registration of the current JIT config and resets it in the status,
+// so that a new registration and JIT config are generated into the secret before the pod is created.
+//
+// It returns false when the registration couldn't be removed, like on a transient failure of the Actions service.
+// The current JIT config is then used as is, as a pod creation shouldn't wait for the Actions service when it can be avoided.
+func (r *EphemeralRunnerReconciler) refreshJitConfig(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, reason string, log logr.Logger) (bool, error) {
+	log = log.WithValues("runnerId", ephemeralRunner.Status.RunnerId, "reason", reason)
+
+	log.Info("Removing the runner registration to generate a new jitconfig")
+	if err := r.deleteRunnerFromService(ctx, ephemeralRunner, log); err != nil {
+		actionsError := &actions.ActionsError{}
+		if !errors.As(err, &actionsError) || actionsError.StatusCode != http.StatusNotFound {
+			log.Error(err, "Failed to remove the runner registration. Using the current jitconfig")
+			return false, nil
+		}
+	}
+
+	err := patchSubResource(ctx, r.Status(), ephemeralRunner, func(obj *v1alpha1.EphemeralRunner) {
+		obj.Status.RunnerId = 0
+		obj.Status.RunnerName = ""
+		obj.Status.RunnerJITConfig = ""
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to reset the runner registration in the status: %v", err)
+	}
+
+	log.Info("Removed the runner registration to generate a new jitconfig")
+	return true, nil
+}
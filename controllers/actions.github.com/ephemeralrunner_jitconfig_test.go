@@ -0,0 +1,205 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/actions/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestJitConfigRefreshReason(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+	}{
+		{
+			name: "secret created before the annotations",
+		},
+		{
+			name:        "fresh",
+			annotations: map[string]string{annotationKeyJitConfigGeneratedAt: now.Add(-time.Minute).Format(time.RFC3339)},
+		},
+		{
+			name:        "expired",
+			annotations: map[string]string{annotationKeyJitConfigGeneratedAt: now.Add(-2 * time.Hour).Format(time.RFC3339)},
+			want:        jitConfigRefreshReasonExpired,
+		},
+		{
+			name: "used by a pod",
+			annotations: map[string]string{
+				annotationKeyJitConfigGeneratedAt: now.Add(-time.Minute).Format(time.RFC3339),
+				annotationKeyJitConfigPodUID:      "pod-1",
+			},
+			want: jitConfigRefreshReasonRotated,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			assert.Equal(t, tc.want, jitConfigRefreshReason(secret, now))
+		})
+	}
+}
+
+func TestEphemeralRunnerJitConfigSecret(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newJitConfig := &actions.RunnerScaleSetJitRunnerConfig{
+		Runner:           &actions.RunnerReference{Id: 2, Name: "runner", RunnerScaleSetId: 1},
+		EncodedJITConfig: "new",
+	}
+
+	newReconciler := func(annotations map[string]string, opts ...fake.Option) (*EphemeralRunnerReconciler, *v1alpha1.EphemeralRunner) {
+		runner := &v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "runner",
+				Namespace:  "arc-runners",
+				Finalizers: []string{ephemeralRunnerFinalizerName, ephemeralRunnerActionsFinalizerName},
+			},
+			Spec: v1alpha1.EphemeralRunnerSpec{
+				GitHubConfigUrl:    "https://github.com/owner/repo",
+				GitHubConfigSecret: "github-config",
+				RunnerScaleSetId:   1,
+				PodTemplateSpec: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: EphemeralRunnerContainerName, Image: "runner"}},
+					},
+				},
+			},
+			Status: v1alpha1.EphemeralRunnerStatus{
+				RunnerId:        1,
+				RunnerName:      "runner",
+				RunnerJITConfig: "old",
+				PodUID:          "pod-1",
+				Failures:        map[string]bool{"pod-1": true},
+			},
+		}
+		jitSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "arc-runners", Annotations: annotations},
+			Data:       map[string][]byte{jitTokenKey: []byte("old")},
+		}
+		configSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "github-config", Namespace: "arc-runners"},
+			Data:       map[string][]byte{"github_token": []byte("token")},
+		}
+
+		r := &EphemeralRunnerReconciler{
+			Client:        crfake.NewClientBuilder().WithScheme(scheme).WithObjects(runner, jitSecret, configSecret).WithStatusSubresource(runner).Build(),
+			Log:           logr.Discard(),
+			Scheme:        scheme,
+			ActionsClient: fake.NewMultiClient(fake.WithDefaultClient(fake.NewFakeClient(opts...), nil)),
+		}
+		return r, runner
+	}
+
+	reconcile := func(t *testing.T, r *EphemeralRunnerReconciler, runner *v1alpha1.EphemeralRunner) *v1alpha1.EphemeralRunner {
+		t.Helper()
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(runner)})
+		require.NoError(t, err)
+
+		updated := new(v1alpha1.EphemeralRunner)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(runner), updated))
+		return updated
+	}
+
+	getSecret := func(t *testing.T, r *EphemeralRunnerReconciler) *corev1.Secret {
+		t.Helper()
+
+		secret := new(corev1.Secret)
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: "arc-runners", Name: "runner"}, secret))
+		return secret
+	}
+
+	podExists := func(t *testing.T, r *EphemeralRunnerReconciler) bool {
+		t.Helper()
+
+		err := r.Get(ctx, client.ObjectKey{Namespace: "arc-runners", Name: "runner"}, new(corev1.Pod))
+		return err == nil
+	}
+
+	t.Run("rotates the jitconfig of a restarted pod", func(t *testing.T) {
+		r, runner := newReconciler(
+			map[string]string{
+				annotationKeyJitConfigGeneratedAt: time.Now().Format(time.RFC3339),
+				annotationKeyJitConfigPodUID:      "pod-1",
+			},
+			fake.WithGenerateJitRunnerConfig(newJitConfig, nil),
+		)
+
+		updated := reconcile(t, r, runner)
+		assert.Equal(t, 0, updated.Status.RunnerId)
+		assert.Empty(t, updated.Status.RunnerJITConfig)
+		assert.False(t, podExists(t, r), "the pod waits for the new jitconfig")
+
+		updated = reconcile(t, r, updated)
+		assert.Equal(t, 2, updated.Status.RunnerId)
+		assert.Equal(t, "new", updated.Status.RunnerJITConfig)
+		assert.True(t, podExists(t, r))
+
+		secret := getSecret(t, r)
+		assert.Equal(t, "new", string(secret.Data[jitTokenKey]))
+		assert.Contains(t, secret.Annotations, annotationKeyJitConfigPodUID)
+	})
+
+	t.Run("regenerates an expired jitconfig", func(t *testing.T) {
+		r, runner := newReconciler(
+			map[string]string{annotationKeyJitConfigGeneratedAt: time.Now().Add(-2 * jitConfigTTL).Format(time.RFC3339)},
+			fake.WithGenerateJitRunnerConfig(newJitConfig, nil),
+		)
+
+		updated := reconcile(t, r, runner)
+		assert.Equal(t, 0, updated.Status.RunnerId)
+
+		updated = reconcile(t, r, updated)
+		assert.Equal(t, 2, updated.Status.RunnerId)
+		assert.Equal(t, "new", string(getSecret(t, r).Data[jitTokenKey]))
+		assert.True(t, podExists(t, r))
+	})
+
+	t.Run("uses the current jitconfig when the Actions service fails", func(t *testing.T) {
+		r, runner := newReconciler(
+			map[string]string{
+				annotationKeyJitConfigGeneratedAt: time.Now().Format(time.RFC3339),
+				annotationKeyJitConfigPodUID:      "pod-1",
+			},
+			fake.WithRemoveRunner(errors.New("service unavailable")),
+		)
+
+		updated := reconcile(t, r, runner)
+		assert.Equal(t, 1, updated.Status.RunnerId)
+		assert.True(t, podExists(t, r))
+		assert.Equal(t, "old", string(getSecret(t, r).Data[jitTokenKey]))
+	})
+
+	t.Run("keeps the jitconfig of secrets created before the annotations", func(t *testing.T) {
+		r, runner := newReconciler(nil)
+
+		updated := reconcile(t, r, runner)
+		assert.Equal(t, 1, updated.Status.RunnerId)
+		assert.True(t, podExists(t, r))
+		assert.Equal(t, "old", string(getSecret(t, r).Data[jitTokenKey]))
+	})
+}
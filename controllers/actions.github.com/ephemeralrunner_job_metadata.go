@@ -0,0 +1,164 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Labels of the runner pods set to the metadata of the job they run, for cost attribution and dashboards.
+// They're left out when the metadata isn't a valid label value.
+const (
+	LabelKeyJobOwner         = "actions.github.com/job-owner"
+	LabelKeyJobRepository    = "actions.github.com/job-repository-name"
+	LabelKeyJobWorkflow      = "actions.github.com/job-workflow"
+	LabelKeyJobWorkflowRunId = "actions.github.com/job-workflow-run-id"
+)
+
+// Annotations of the runner pods set to the metadata of the job they run, for log correlation.
+const (
+	AnnotationKeyJobRequestId     = "actions.github.com/job-request-id"
+	AnnotationKeyJobRepository    = "actions.github.com/job-repository"
+	AnnotationKeyJobWorkflowRef   = "actions.github.com/job-workflow-ref"
+	AnnotationKeyJobWorkflowRunId = "actions.github.com/job-workflow-run-id"
+	AnnotationKeyJobDisplayName   = "actions.github.com/job-display-name"
+)
+
+const (
+	// jobMetadataVolumeName is the downward API volume exposing the job annotations of the runner pod as files.
+	// Environment variables can't be changed once a container started, while the files are updated by the kubelet
+	// when the runner pod gets a job.
+	jobMetadataVolumeName = "job-metadata"
+	jobMetadataMountPath  = "/etc/actions-runner/job"
+)
+
+// jobMetadataFiles maps the files of the job metadata volume to the annotations they expose.
+var jobMetadataFiles = []struct{ path, annotation string }{
+	{"request-id", AnnotationKeyJobRequestId},
+	{"repository", AnnotationKeyJobRepository},
+	{"workflow-ref", AnnotationKeyJobWorkflowRef},
+	{"workflow-run-id", AnnotationKeyJobWorkflowRunId},
+	{"display-name", AnnotationKeyJobDisplayName},
+}
+
+// jobMetadata returns the labels and annotations of the runner pod for the job the runner runs.
+func jobMetadata(ephemeralRunner *v1alpha1.EphemeralRunner) (labels, annotations map[string]string) {
+	status := ephemeralRunner.Status
+	runId := strconv.FormatInt(status.WorkflowRunId, 10)
+
+	annotations = map[string]string{
+		AnnotationKeyJobRequestId:     strconv.FormatInt(status.JobRequestId, 10),
+		AnnotationKeyJobRepository:    status.JobRepositoryName,
+		AnnotationKeyJobWorkflowRef:   status.JobWorkflowRef,
+		AnnotationKeyJobWorkflowRunId: runId,
+		AnnotationKeyJobDisplayName:   status.JobDisplayName,
+	}
+
+	owner, repository, _ := strings.Cut(status.JobRepositoryName, "/")
+	labels = make(map[string]string)
+	for key, value := range map[string]string{
+		LabelKeyJobOwner:         owner,
+		LabelKeyJobRepository:    repository,
+		LabelKeyJobWorkflow:      workflowName(status.JobWorkflowRef),
+		LabelKeyJobWorkflowRunId: runId,
+	} {
+		if value != "" && len(validation.IsValidLabelValue(value)) == 0 {
+			labels[key] = value
+		}
+	}
+
+	return labels, annotations
+}
+
+// workflowName returns the name of the workflow file of the workflow ref, like ci for octo-org/octo-repo/.github/workflows/ci.yml@refs/heads/main.
+func workflowName(workflowRef string) string {
+	file, _, _ := strings.Cut(workflowRef, "@")
+	name := path.Base(file)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// updatePodJobMetadata labels and annotates the pod of the runner with the metadata of the job the runner started.
+func (r *EphemeralRunnerReconciler) updatePodJobMetadata(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, pod *corev1.Pod, log logr.Logger) error {
+	if ephemeralRunner.Status.JobRequestId == 0 {
+		return nil
+	}
+	if pod.Annotations[AnnotationKeyJobRequestId] == strconv.FormatInt(ephemeralRunner.Status.JobRequestId, 10) {
+		return nil
+	}
+
+	labels, annotations := jobMetadata(ephemeralRunner)
+	log.Info("Updating the runner pod with the metadata of its job", "jobRequestId", ephemeralRunner.Status.JobRequestId)
+	if err := patch(ctx, r.Client, pod, func(obj *corev1.Pod) {
+		if obj.Labels == nil {
+			obj.Labels = make(map[string]string)
+		}
+		for k, v := range labels {
+			obj.Labels[k] = v
+		}
+		if obj.Annotations == nil {
+			obj.Annotations = make(map[string]string)
+		}
+		for k, v := range annotations {
+			obj.Annotations[k] = v
+		}
+	}); err != nil {
+		return fmt.Errorf("failed to update the pod with the job metadata: %w", err)
+	}
+
+	return nil
+}
+
+// addJobMetadataVolume mounts the job metadata volume in each container of the runner pod,
+// unless the pod template already uses its name or its mount path.
+func addJobMetadataVolume(spec *corev1.PodSpec) {
+	for _, v := range spec.Volumes {
+		if v.Name == jobMetadataVolumeName {
+			return
+		}
+	}
+
+	items := make([]corev1.DownwardAPIVolumeFile, 0, len(jobMetadataFiles))
+	for _, file := range jobMetadataFiles {
+		items = append(items, corev1.DownwardAPIVolumeFile{
+			Path:     file.path,
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", file.annotation)},
+		})
+	}
+	// The pod spec shares its slices with the pod template of the runner
+	spec.Volumes = append(slices.Clip(spec.Volumes), corev1.Volume{
+		Name:         jobMetadataVolumeName,
+		VolumeSource: corev1.VolumeSource{DownwardAPI: &corev1.DownwardAPIVolumeSource{Items: items}},
+	})
+
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		if hasMountPath(c, jobMetadataMountPath) {
+			continue
+		}
+		c.VolumeMounts = append(slices.Clip(c.VolumeMounts), corev1.VolumeMount{
+			Name:      jobMetadataVolumeName,
+			MountPath: jobMetadataMountPath,
+			ReadOnly:  true,
+		})
+	}
+}
+
+func hasMountPath(c *corev1.Container, mountPath string) bool {
+	for _, m := range c.VolumeMounts {
+		if m.MountPath == mountPath {
+			return true
+		}
+	}
+	return false
+}
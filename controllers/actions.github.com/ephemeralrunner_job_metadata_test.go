@@ -0,0 +1,118 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWorkflowName(t *testing.T) {
+	tests := map[string]string{
+		"octo-org/octo-repo/.github/workflows/ci.yml@refs/heads/main":    "ci",
+		"octo-org/octo-repo/.github/workflows/release.yaml@refs/tags/v1": "release",
+		"octo-org/octo-repo/.github/workflows/build":                     "build",
+		"": "",
+	}
+	for ref, want := range tests {
+		assert.Equal(t, want, workflowName(ref), ref)
+	}
+}
+
+func TestJobMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	runner := &v1alpha1.EphemeralRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "arc-runners"},
+		Status: v1alpha1.EphemeralRunnerStatus{
+			JobRequestId:      42,
+			JobRepositoryName: "octo-org/octo-repo",
+			JobWorkflowRef:    "octo-org/octo-repo/.github/workflows/ci.yml@refs/heads/main",
+			WorkflowRunId:     1234,
+			JobDisplayName:    "build (ubuntu, 1.22)",
+		},
+	}
+
+	t.Run("labels the valid label values only", func(t *testing.T) {
+		labels, annotations := jobMetadata(runner)
+		assert.Equal(t, map[string]string{
+			LabelKeyJobOwner:         "octo-org",
+			LabelKeyJobRepository:    "octo-repo",
+			LabelKeyJobWorkflow:      "ci",
+			LabelKeyJobWorkflowRunId: "1234",
+		}, labels)
+		assert.Equal(t, map[string]string{
+			AnnotationKeyJobRequestId:     "42",
+			AnnotationKeyJobRepository:    "octo-org/octo-repo",
+			AnnotationKeyJobWorkflowRef:   "octo-org/octo-repo/.github/workflows/ci.yml@refs/heads/main",
+			AnnotationKeyJobWorkflowRunId: "1234",
+			AnnotationKeyJobDisplayName:   "build (ubuntu, 1.22)",
+		}, annotations)
+
+		long := runner.DeepCopy()
+		long.Status.JobRepositoryName = "octo-org/" + strings.Repeat("r", 64)
+		labels, annotations = jobMetadata(long)
+		assert.NotContains(t, labels, LabelKeyJobRepository)
+		assert.Equal(t, long.Status.JobRepositoryName, annotations[AnnotationKeyJobRepository])
+	})
+
+	t.Run("updates the pod once the runner started a job", func(t *testing.T) {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "arc-runners", Labels: map[string]string{"app": "runner"}}}
+		r := &EphemeralRunnerReconciler{
+			Client: crfake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build(),
+			Log:    logr.Discard(),
+			Scheme: scheme,
+		}
+
+		require.NoError(t, r.updatePodJobMetadata(ctx, &v1alpha1.EphemeralRunner{}, pod, logr.Discard()))
+		updated := new(corev1.Pod)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(pod), updated))
+		assert.Empty(t, updated.Annotations, "the runner has no job yet")
+
+		require.NoError(t, r.updatePodJobMetadata(ctx, runner, pod, logr.Discard()))
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(pod), updated))
+		assert.Equal(t, "runner", updated.Labels["app"])
+		assert.Equal(t, "octo-repo", updated.Labels[LabelKeyJobRepository])
+		assert.Equal(t, "42", updated.Annotations[AnnotationKeyJobRequestId])
+		assert.Equal(t, "build (ubuntu, 1.22)", updated.Annotations[AnnotationKeyJobDisplayName])
+	})
+
+	t.Run("mounts the job metadata volume in each container", func(t *testing.T) {
+		template := corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: EphemeralRunnerContainerName},
+				{Name: "sidecar", VolumeMounts: []corev1.VolumeMount{{Name: "own", MountPath: jobMetadataMountPath}}},
+			},
+			Volumes: make([]corev1.Volume, 0, 4),
+		}
+
+		spec := template
+		spec.Containers = []corev1.Container{template.Containers[0], template.Containers[1]}
+		addJobMetadataVolume(&spec)
+
+		require.Len(t, spec.Volumes, 1)
+		assert.Equal(t, jobMetadataVolumeName, spec.Volumes[0].Name)
+		require.Len(t, spec.Volumes[0].DownwardAPI.Items, len(jobMetadataFiles))
+		assert.Equal(t, "metadata.annotations['actions.github.com/job-request-id']", spec.Volumes[0].DownwardAPI.Items[0].FieldRef.FieldPath)
+		assert.Equal(t, []corev1.VolumeMount{{Name: jobMetadataVolumeName, MountPath: jobMetadataMountPath, ReadOnly: true}}, spec.Containers[0].VolumeMounts)
+		assert.Equal(t, "own", spec.Containers[1].VolumeMounts[0].Name, "the mount path is already used")
+		assert.Empty(t, template.Volumes[:cap(template.Volumes)][0].Name, "the pod template is left untouched")
+
+		addJobMetadataVolume(&spec)
+		assert.Len(t, spec.Volumes, 1, "the volume is added once")
+	})
+}
@@ -0,0 +1,258 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEphemeralRunnerPodLoss(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newReconciler := func(status v1alpha1.EphemeralRunnerStatus) (*EphemeralRunnerReconciler, *v1alpha1.EphemeralRunner) {
+		status.RunnerId = 1
+		runner := &v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "runner",
+				Namespace:  "arc-runners",
+				Finalizers: []string{ephemeralRunnerFinalizerName, ephemeralRunnerActionsFinalizerName},
+			},
+			Spec: v1alpha1.EphemeralRunnerSpec{
+				GitHubConfigUrl: "https://github.com/owner/repo",
+				PodTemplateSpec: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: EphemeralRunnerContainerName, Image: "runner"}},
+					},
+				},
+			},
+			Status: status,
+		}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "arc-runners"}}
+
+		r := &EphemeralRunnerReconciler{
+			Client: crfake.NewClientBuilder().WithScheme(scheme).WithObjects(runner, secret).WithStatusSubresource(runner).Build(),
+			Log:    logr.Discard(),
+			Scheme: scheme,
+		}
+		return r, runner
+	}
+
+	reconcile := func(t *testing.T, r *EphemeralRunnerReconciler, runner *v1alpha1.EphemeralRunner) *v1alpha1.EphemeralRunner {
+		t.Helper()
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(runner)})
+		require.NoError(t, err)
+
+		updated := new(v1alpha1.EphemeralRunner)
+		if err := r.Get(ctx, client.ObjectKeyFromObject(runner), updated); err != nil {
+			require.True(t, kerrors.IsNotFound(err), "unexpected error: %v", err)
+			return nil
+		}
+		return updated
+	}
+
+	podExists := func(t *testing.T, r *EphemeralRunnerReconciler) bool {
+		t.Helper()
+
+		err := r.Get(ctx, types.NamespacedName{Namespace: "arc-runners", Name: "runner"}, new(corev1.Pod))
+		if kerrors.IsNotFound(err) {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+
+	t.Run("recreates the pod of an idle runner without counting a failure", func(t *testing.T) {
+		r, runner := newReconciler(v1alpha1.EphemeralRunnerStatus{Phase: corev1.PodRunning, PodUID: "pod-1"})
+
+		updated := reconcile(t, r, runner)
+		require.NotNil(t, updated)
+		assert.Equal(t, map[string]bool{"pod-1": true}, updated.Status.LostPods)
+		assert.Empty(t, updated.Status.Failures)
+		assert.Equal(t, ReasonPodLost, updated.Status.Reason)
+		assert.False(t, podExists(t, r))
+
+		updated = reconcile(t, r, updated)
+		require.NotNil(t, updated)
+		assert.True(t, podExists(t, r))
+		assert.Empty(t, updated.Status.Failures)
+		assert.Nil(t, updated.DeletionTimestamp)
+	})
+
+	t.Run("deletes a runner that lost its job", func(t *testing.T) {
+		r, runner := newReconciler(v1alpha1.EphemeralRunnerStatus{Phase: corev1.PodRunning, PodUID: "pod-1", JobRequestId: 10})
+
+		updated := reconcile(t, r, runner)
+		require.NotNil(t, updated)
+		assert.Equal(t, map[string]bool{"pod-1": true}, updated.Status.LostPods)
+
+		updated = reconcile(t, r, updated)
+		require.NotNil(t, updated, "the finalizers keep the runner until it's removed from the service")
+		assert.NotNil(t, updated.DeletionTimestamp)
+		assert.False(t, podExists(t, r))
+	})
+
+	t.Run("doesn't treat a pod deleted as failed as lost", func(t *testing.T) {
+		r, runner := newReconciler(v1alpha1.EphemeralRunnerStatus{
+			Phase:        corev1.PodRunning,
+			PodUID:       "pod-1",
+			JobRequestId: 10,
+			Failures:     map[string]bool{"pod-1": true},
+		})
+
+		updated := reconcile(t, r, runner)
+		require.NotNil(t, updated)
+		assert.Empty(t, updated.Status.LostPods)
+		assert.Nil(t, updated.DeletionTimestamp)
+		assert.True(t, podExists(t, r))
+	})
+}
+
+func TestEphemeralRunnerSetReplacesLostRunners(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	ers := &v1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "arc-runners",
+			Namespace:  "arc-runners",
+			UID:        "ers-uid",
+			Finalizers: []string{ephemeralRunnerSetFinalizerName},
+		},
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			Replicas: 2,
+			PatchID:  1,
+			EphemeralRunnerSpec: v1alpha1.EphemeralRunnerSpec{
+				GitHubConfigUrl: "https://github.com/owner/repo",
+			},
+		},
+	}
+
+	runner := func(name string, status v1alpha1.EphemeralRunnerStatus) *v1alpha1.EphemeralRunner {
+		r := &v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "arc-runners",
+				Annotations: map[string]string{AnnotationKeyPatchID: "1"},
+			},
+			Status: status,
+		}
+		require.NoError(t, ctrl.SetControllerReference(ers, r, scheme))
+		return r
+	}
+
+	lost := runner("lost", v1alpha1.EphemeralRunnerStatus{
+		Phase:        corev1.PodRunning,
+		PodUID:       "pod-1",
+		LostPods:     map[string]bool{"pod-1": true},
+		JobRequestId: 10,
+	})
+	lost.Finalizers = []string{ephemeralRunnerFinalizerName}
+
+	running := runner("running", v1alpha1.EphemeralRunnerStatus{Phase: corev1.PodRunning, PodUID: "pod-2", JobRequestId: 11})
+
+	c := crfake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ers, lost, running).
+		WithStatusSubresource(ers, lost, running).
+		WithIndex(&v1alpha1.EphemeralRunner{}, resourceOwnerKey, newGroupVersionOwnerKindIndexer("EphemeralRunnerSet")).
+		Build()
+
+	// The runner that lost its job is being deleted until the job times out
+	require.NoError(t, c.Delete(ctx, lost))
+
+	r := &EphemeralRunnerSetReconciler{
+		Client: c,
+		Log:    logr.Discard(),
+		Scheme: scheme,
+	}
+
+	list := func(t *testing.T) []v1alpha1.EphemeralRunner {
+		t.Helper()
+
+		var runners v1alpha1.EphemeralRunnerList
+		require.NoError(t, c.List(ctx, &runners, client.InNamespace("arc-runners")))
+		return runners.Items
+	}
+
+	// The runners for the latest patch were all created, so only the lost runner is replaced
+	for i := 0; i < 2; i++ {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ers)})
+		require.NoError(t, err)
+	}
+
+	runners := list(t)
+	require.Len(t, runners, 3)
+
+	var replacements []v1alpha1.EphemeralRunner
+	for _, runner := range runners {
+		if runner.Annotations[AnnotationKeyReplacedEphemeralRunner] != "" {
+			replacements = append(replacements, runner)
+		}
+	}
+	require.Len(t, replacements, 1)
+	assert.Equal(t, "lost", replacements[0].Annotations[AnnotationKeyReplacedEphemeralRunner])
+	assert.Equal(t, "1", replacements[0].Annotations[AnnotationKeyPatchID])
+
+	var updated v1alpha1.EphemeralRunnerSet
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(ers), &updated))
+	assert.Equal(t, 2, updated.Status.CurrentReplicas)
+}
+
+func TestEphemeralRunnerStateLostRunners(t *testing.T) {
+	deleting := metav1.Now()
+
+	list := &v1alpha1.EphemeralRunnerList{
+		Items: []v1alpha1.EphemeralRunner{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "lost-with-job", DeletionTimestamp: &deleting},
+				Status:     v1alpha1.EphemeralRunnerStatus{PodUID: "pod-1", LostPods: map[string]bool{"pod-1": true}, JobRequestId: 1},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "lost-and-replaced", DeletionTimestamp: &deleting},
+				Status:     v1alpha1.EphemeralRunnerStatus{PodUID: "pod-2", LostPods: map[string]bool{"pod-2": true}, JobRequestId: 2},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "failed-with-job", DeletionTimestamp: &deleting},
+				Status:     v1alpha1.EphemeralRunnerStatus{PodUID: "pod-3", Failures: map[string]bool{"pod-3": true}, JobRequestId: 3},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "scaled-down", DeletionTimestamp: &deleting},
+				Status:     v1alpha1.EphemeralRunnerStatus{PodUID: "pod-4"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "replacement",
+					Annotations: map[string]string{AnnotationKeyReplacedEphemeralRunner: "lost-and-replaced"},
+				},
+				Status: v1alpha1.EphemeralRunnerStatus{Phase: corev1.PodPending},
+			},
+		},
+	}
+
+	state := newEphemeralRunnerState(list)
+	assert.Len(t, state.deleting, 4)
+	assert.Len(t, state.lost, 2)
+	assert.Equal(t, []string{"lost-with-job"}, state.unreplaced())
+	assert.Equal(t, 1, state.scaleTotal())
+}
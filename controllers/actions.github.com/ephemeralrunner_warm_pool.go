@@ -0,0 +1,101 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// claimWarmPod takes a ready warm pod of the runner set of the runner out of the warm pool, and deletes it
+// to make room for the pod of the runner on its node, where the images of the runner pod are already pulled.
+// It returns the node of the claimed warm pod, or an empty string when no warm pod is ready.
+func (r *EphemeralRunnerReconciler) claimWarmPod(ctx context.Context, runner *v1alpha1.EphemeralRunner, log logr.Logger) (string, error) {
+	owner := metav1.GetControllerOf(runner)
+	if owner == nil || owner.Kind != "EphemeralRunnerSet" {
+		return "", nil
+	}
+
+	pods := new(corev1.PodList)
+	if err := r.List(ctx, pods, client.InNamespace(runner.Namespace), client.MatchingLabels{
+		LabelKeyKubernetesComponent: labelValueWarmPodAvailable,
+		labelKeyWarmPoolRunnerSet:   owner.Name,
+	}); err != nil {
+		return "", fmt.Errorf("failed to list warm pods: %v", err)
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !warmPodReady(pod) {
+			continue
+		}
+
+		// Changing the component takes the pod out of the deployment, which replaces it,
+		// and the optimistic lock makes sure that a single runner claims it
+		claimed := pod.DeepCopy()
+		claimed.Labels[LabelKeyKubernetesComponent] = labelValueWarmPodClaimed
+		if err := r.Patch(ctx, claimed, client.MergeFromWithOptions(pod, client.MergeFromWithOptimisticLock{})); err != nil {
+			if kerrors.IsConflict(err) || kerrors.IsNotFound(err) {
+				continue
+			}
+			return "", fmt.Errorf("failed to claim warm pod: %v", err)
+		}
+
+		log.Info("Claimed warm pod", "pod", pod.Name, "node", pod.Spec.NodeName)
+		if err := r.Delete(ctx, claimed, client.GracePeriodSeconds(0)); err != nil && !kerrors.IsNotFound(err) {
+			// The AutoscalingRunnerSet deletes it later, and the runner pod is scheduled once it's gone
+			log.Error(err, "Failed to delete the claimed warm pod", "pod", pod.Name)
+		}
+		return pod.Spec.NodeName, nil
+	}
+
+	return "", nil
+}
+
+func warmPodReady(pod *corev1.Pod) bool {
+	if !pod.DeletionTimestamp.IsZero() || pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// requireNode restricts the pod to the node, along with the node affinity it already has.
+func requireNode(spec *corev1.PodSpec, node string) {
+	requirement := corev1.NodeSelectorRequirement{
+		Key:      metav1.ObjectNameField,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{node},
+	}
+
+	// The affinity may be shared with the template of the runner
+	spec.Affinity = spec.Affinity.DeepCopy()
+	if spec.Affinity == nil {
+		spec.Affinity = &corev1.Affinity{}
+	}
+	if spec.Affinity.NodeAffinity == nil {
+		spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	if spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+
+	selector := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	// The terms are ORed, so the node is required by each of them
+	for i := range selector.NodeSelectorTerms {
+		selector.NodeSelectorTerms[i].MatchFields = append(selector.NodeSelectorTerms[i].MatchFields, requirement)
+	}
+}
@@ -0,0 +1,122 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClaimWarmPod(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	controller := true
+	runner := &v1alpha1.EphemeralRunner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "arc-runners-x8k2p-runner-1",
+			Namespace: "arc-runners",
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: v1alpha1.GroupVersion.String(), Kind: "EphemeralRunnerSet", Name: "arc-runners-x8k2p", UID: "ers-uid", Controller: &controller},
+			},
+		},
+	}
+
+	newWarmPod := func(name, runnerSet string, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "arc-runners",
+				Labels: map[string]string{
+					LabelKeyKubernetesComponent: labelValueWarmPodAvailable,
+					labelKeyWarmPoolRunnerSet:   runnerSet,
+				},
+			},
+			Spec: corev1.PodSpec{NodeName: "node-" + name},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+
+	t.Run("claims a ready warm pod of the runner set", func(t *testing.T) {
+		pending := newWarmPod("pending", "arc-runners-x8k2p", false)
+		outdated := newWarmPod("outdated", "arc-runners-old", true)
+		ready := newWarmPod("ready", "arc-runners-x8k2p", true)
+
+		r := &EphemeralRunnerReconciler{
+			Client: crfake.NewClientBuilder().WithScheme(scheme).WithObjects(pending, outdated, ready).Build(),
+			Scheme: scheme,
+		}
+
+		node, err := r.claimWarmPod(ctx, runner, logr.Discard())
+		require.NoError(t, err)
+		assert.Equal(t, "node-ready", node)
+
+		err = r.Get(ctx, client.ObjectKeyFromObject(ready), new(corev1.Pod))
+		assert.True(t, kerrors.IsNotFound(err), "the claimed warm pod is deleted to make room for the runner pod")
+
+		node, err = r.claimWarmPod(ctx, runner, logr.Discard())
+		require.NoError(t, err)
+		assert.Empty(t, node, "no other warm pod of the runner set is ready")
+	})
+
+	t.Run("without a runner set", func(t *testing.T) {
+		r := &EphemeralRunnerReconciler{
+			Client: crfake.NewClientBuilder().WithScheme(scheme).Build(),
+			Scheme: scheme,
+		}
+
+		node, err := r.claimWarmPod(ctx, &v1alpha1.EphemeralRunner{ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "arc-runners"}}, logr.Discard())
+		require.NoError(t, err)
+		assert.Empty(t, node)
+	})
+}
+
+func TestRequireNode(t *testing.T) {
+	affinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}}},
+					{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}}}},
+				},
+			},
+		},
+	}
+	template := corev1.PodSpec{Affinity: affinity}
+
+	spec := template
+	requireNode(&spec, "node-1")
+
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	require.Len(t, terms, 2)
+	for _, term := range terms {
+		assert.Equal(t, []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-1"}}}, term.MatchFields)
+	}
+	assert.Empty(t, affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchFields, "the affinity of the template is left untouched")
+
+	spec = corev1.PodSpec{}
+	requireNode(&spec, "node-1")
+	terms = spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	require.Len(t, terms, 1)
+	assert.Equal(t, "node-1", terms[0].MatchFields[0].Values[0])
+}
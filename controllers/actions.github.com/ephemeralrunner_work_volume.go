@@ -0,0 +1,61 @@
+package actionsgithubcom
+
+import (
+	"slices"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/workvolume"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	workVolumeName      = "work"
+	workVolumeMountPath = "/home/runner/_work"
+)
+
+// applyWorkVolume sets the source of the "work" volume of the runner pod to spec.workVolume.
+// The dind and kubernetes container modes of the chart already add the volume and mount it in their containers,
+// in which case only its source is replaced. Otherwise the volume is added and mounted at the work directory of the
+// runner container.
+func applyWorkVolume(spec *corev1.PodSpec, workVolume *v1alpha1.WorkVolumeSource) {
+	if workVolume == nil {
+		return
+	}
+
+	var source corev1.VolumeSource
+	switch {
+	case workVolume.EmptyDir != nil:
+		source.EmptyDir = workVolume.EmptyDir.DeepCopy()
+	case workVolume.Ephemeral != nil:
+		source.Ephemeral = workVolume.Ephemeral.DeepCopy()
+	case workVolume.HostPath != nil:
+		source.HostPath = workVolume.HostPath.DeepCopy()
+	default:
+		return
+	}
+
+	// The pod spec shares its slices with the pod template of the runner
+	spec.Volumes = slices.Clone(spec.Volumes)
+	spec.Containers = slices.Clone(spec.Containers)
+
+	if i := slices.IndexFunc(spec.Volumes, func(v corev1.Volume) bool { return v.Name == workVolumeName }); i >= 0 {
+		spec.Volumes[i].VolumeSource = source
+	} else {
+		spec.Volumes = append(spec.Volumes, corev1.Volume{Name: workVolumeName, VolumeSource: source})
+	}
+
+	i := slices.IndexFunc(spec.Containers, func(c corev1.Container) bool { return c.Name == EphemeralRunnerContainerName })
+	if i < 0 {
+		return
+	}
+	c := &spec.Containers[i]
+
+	if !slices.ContainsFunc(c.VolumeMounts, func(m corev1.VolumeMount) bool { return m.Name == workVolumeName }) && !hasMountPath(c, workVolumeMountPath) {
+		c.VolumeMounts = append(slices.Clip(c.VolumeMounts), corev1.VolumeMount{
+			Name:      workVolumeName,
+			MountPath: workVolumeMountPath,
+		})
+	}
+
+	workvolume.RequestEphemeralStorage(&c.Resources, workVolume.EmptyDir)
+}
@@ -0,0 +1,130 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyWorkVolume(t *testing.T) {
+	ctx := context.Background()
+	size := resource.MustParse("20Gi")
+
+	newRunner := func(podSpec corev1.PodSpec, workVolume *v1alpha1.WorkVolumeSource) *v1alpha1.EphemeralRunner {
+		return &v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "arc-runners"},
+			Spec: v1alpha1.EphemeralRunnerSpec{
+				PodTemplateSpec: corev1.PodTemplateSpec{Spec: podSpec},
+				WorkVolume:      workVolume,
+			},
+		}
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "runner"}}
+	b := ResourceBuilder{}
+
+	t.Run("adds and mounts the work volume with the ephemeral storage request of its size limit", func(t *testing.T) {
+		runner := newRunner(corev1.PodSpec{
+			Containers: []corev1.Container{{Name: EphemeralRunnerContainerName}},
+		}, &v1alpha1.WorkVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &size}})
+
+		pod := b.newEphemeralRunnerPod(ctx, runner, secret)
+
+		var work *corev1.Volume
+		for i := range pod.Spec.Volumes {
+			if pod.Spec.Volumes[i].Name == workVolumeName {
+				work = &pod.Spec.Volumes[i]
+			}
+		}
+		require.NotNil(t, work)
+		require.NotNil(t, work.EmptyDir)
+		assert.Equal(t, size, *work.EmptyDir.SizeLimit)
+
+		runnerContainer := pod.Spec.Containers[0]
+		assert.Contains(t, runnerContainer.VolumeMounts, corev1.VolumeMount{Name: workVolumeName, MountPath: workVolumeMountPath})
+		assert.Equal(t, size, runnerContainer.Resources.Requests[corev1.ResourceEphemeralStorage])
+
+		assert.Empty(t, runner.Spec.PodTemplateSpec.Spec.Volumes, "the pod template of the runner must not be modified")
+		assert.Empty(t, runner.Spec.PodTemplateSpec.Spec.Containers[0].VolumeMounts, "the pod template of the runner must not be modified")
+	})
+
+	t.Run("replaces the source of the work volume of the pod template", func(t *testing.T) {
+		runner := newRunner(corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:         EphemeralRunnerContainerName,
+					VolumeMounts: []corev1.VolumeMount{{Name: workVolumeName, MountPath: workVolumeMountPath}},
+				},
+				{
+					Name:         "dind",
+					VolumeMounts: []corev1.VolumeMount{{Name: workVolumeName, MountPath: workVolumeMountPath}},
+				},
+			},
+			Volumes: []corev1.Volume{{Name: workVolumeName, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+		}, &v1alpha1.WorkVolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/lib/runner-work"}})
+
+		pod := b.newEphemeralRunnerPod(ctx, runner, secret)
+
+		var works []corev1.Volume
+		for _, v := range pod.Spec.Volumes {
+			if v.Name == workVolumeName {
+				works = append(works, v)
+			}
+		}
+		require.Len(t, works, 1)
+		assert.Nil(t, works[0].EmptyDir)
+		require.NotNil(t, works[0].HostPath)
+		assert.Equal(t, "/var/lib/runner-work", works[0].HostPath.Path)
+
+		for _, c := range pod.Spec.Containers {
+			var mounts int
+			for _, m := range c.VolumeMounts {
+				if m.Name == workVolumeName {
+					mounts++
+				}
+			}
+			assert.Equal(t, 1, mounts, c.Name)
+		}
+		assert.Empty(t, pod.Spec.Containers[0].Resources.Requests)
+
+		require.NotNil(t, runner.Spec.PodTemplateSpec.Spec.Volumes[0].EmptyDir, "the pod template of the runner must not be modified")
+	})
+
+	t.Run("doesn't override the ephemeral storage request of the runner container", func(t *testing.T) {
+		requested := resource.MustParse("50Gi")
+		runner := newRunner(corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: EphemeralRunnerContainerName,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceEphemeralStorage: requested},
+				},
+			}},
+		}, &v1alpha1.WorkVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &size}})
+
+		pod := b.newEphemeralRunnerPod(ctx, runner, secret)
+
+		assert.Equal(t, requested, pod.Spec.Containers[0].Resources.Requests[corev1.ResourceEphemeralStorage])
+	})
+
+	t.Run("clamps the ephemeral storage request to the limit of the runner container", func(t *testing.T) {
+		limit := resource.MustParse("5Gi")
+		runner := newRunner(corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: EphemeralRunnerContainerName,
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceEphemeralStorage: limit},
+				},
+			}},
+		}, &v1alpha1.WorkVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &size}})
+
+		pod := b.newEphemeralRunnerPod(ctx, runner, secret)
+
+		assert.Equal(t, limit, pod.Spec.Containers[0].Resources.Requests[corev1.ResourceEphemeralStorage])
+		assert.Equal(t, limit, pod.Spec.Containers[0].Resources.Limits[corev1.ResourceEphemeralStorage])
+	})
+}
@@ -27,9 +27,11 @@ import (
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
 	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 	"github.com/go-logr/logr"
 	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -53,6 +55,10 @@ type EphemeralRunnerSetReconciler struct {
 
 	PublishMetrics bool
 
+	// CreationScheduler is optional. When set, the EphemeralRunners created to scale up are admitted by it
+	// under a rate limit shared by all the EphemeralRunnerSets.
+	CreationScheduler *EphemeralRunnerCreationScheduler
+
 	ResourceBuilder
 }
 
@@ -79,15 +85,22 @@ func (r *EphemeralRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.R
 
 	ephemeralRunnerSet := new(v1alpha1.EphemeralRunnerSet)
 	if err := r.Get(ctx, req.NamespacedName, ephemeralRunnerSet); err != nil {
+		if kerrors.IsNotFound(err) {
+			r.forgetCreations(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	ctx = withScaleSet(ctx, ephemeralRunnerSet)
+
 	// Requested deletion does not need reconciled.
 	if !ephemeralRunnerSet.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(ephemeralRunnerSet, ephemeralRunnerSetFinalizerName) {
 			return ctrl.Result{}, nil
 		}
 
+		r.forgetCreations(req.NamespacedName)
+
 		log.Info("Deleting resources")
 		done, err := r.cleanUpEphemeralRunners(ctx, ephemeralRunnerSet, log)
 		if err != nil {
@@ -189,30 +202,63 @@ func (r *EphemeralRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.R
 		)
 	}
 
+	// The idle runners of a canary that was rolled back are replaced by runners of the runner spec
+	if stale := staleCanaryRunners(ephemeralRunnerSet.Spec.Canary, ephemeralRunnerState.pending, ephemeralRunnerState.running); len(stale) > 0 {
+		log.Info("Deleting the idle runners of a removed canary", "count", len(stale))
+		if err := r.deleteIdleEphemeralRunners(ctx, ephemeralRunnerSet, stale, nil, len(stale), log); err != nil {
+			log.Error(err, "failed to delete idle canary runners")
+			return ctrl.Result{}, err
+		}
+	}
+
+	canary := ephemeralRunnerSetCanaryStatus(ephemeralRunnerSet)
 	total := ephemeralRunnerState.scaleTotal()
-	if ephemeralRunnerSet.Spec.PatchID == 0 || ephemeralRunnerSet.Spec.PatchID != ephemeralRunnerState.latestPatchID {
+	// The runners of the latest patch are annotated with its ID as soon as the first of them is created,
+	// so the scale up is continued while some of its creations are still waiting to be admitted.
+	creationsPending := r.CreationScheduler != nil && r.CreationScheduler.Pending(req.NamespacedName)
+	var result ctrl.Result
+	if ephemeralRunnerSet.Spec.PatchID == 0 || ephemeralRunnerSet.Spec.PatchID != ephemeralRunnerState.latestPatchID || creationsPending {
 		defer func() {
 			if err := r.cleanupFinishedEphemeralRunners(ctx, ephemeralRunnerState.finished, log); err != nil {
 				log.Error(err, "failed to cleanup finished ephemeral runners")
 			}
 		}()
-		log.Info("Scaling comparison", "current", total, "desired", ephemeralRunnerSet.Spec.Replicas)
+		replicas := ephemeralRunnerSet.Spec.Replicas + ephemeralRunnerSet.Spec.AdditionalReplicas
+		if ephemeralRunnerSet.Spec.MaxReplicas != nil {
+			replicas = min(replicas, *ephemeralRunnerSet.Spec.MaxReplicas)
+		}
+		log.Info("Scaling comparison", "current", total, "desired", replicas)
 		switch {
-		case total < ephemeralRunnerSet.Spec.Replicas: // Handle scale up
-			count := ephemeralRunnerSet.Spec.Replicas - total
-			log.Info("Creating new ephemeral runners (scale up)", "count", count)
-			if err := r.createEphemeralRunners(ctx, ephemeralRunnerSet, count, log); err != nil {
-				log.Error(err, "failed to make ephemeral runner")
-				return ctrl.Result{}, err
+		case total < replicas: // Handle scale up
+			count := replicas - total
+			if r.CreationScheduler != nil {
+				admitted, retryAfter := r.CreationScheduler.Admit(req.NamespacedName, r.creationPriority(ctx, ephemeralRunnerSet, log), count, ephemeralRunnerSetMetricLabels(ephemeralRunnerSet))
+				if admitted < count {
+					log.Info("Throttled ephemeral runner creations", "admitted", admitted, "queued", count-admitted, "retryAfter", retryAfter)
+					result.RequeueAfter = retryAfter
+				}
+				count = admitted
+			}
+			if count > 0 {
+				log.Info("Creating new ephemeral runners (scale up)", "count", count)
+				// The runners that lost their pod along with their job aren't counted in the total,
+				// so the new runners replace them. Record it, so that they aren't replaced again by the next reconciliations.
+				creation := newEphemeralRunnerCreation(ephemeralRunnerState, count, canary, ephemeralRunnerState.unreplaced())
+				if err := r.createEphemeralRunners(ctx, ephemeralRunnerSet, creation, log); err != nil {
+					log.Error(err, "failed to make ephemeral runner")
+					return ctrl.Result{}, err
+				}
 			}
 
-		case ephemeralRunnerSet.Spec.PatchID > 0 && total >= ephemeralRunnerSet.Spec.Replicas: // Handle scale down scenario.
+		case ephemeralRunnerSet.Spec.PatchID > 0 && total >= replicas: // Handle scale down scenario.
 			// If ephemeral runner did not yet update the phase to succeeded, but the scale down
 			// request is issued, we should ignore the scale down request.
 			// Eventually, the ephemeral runner will be cleaned up on the next patch request, which happens
 			// on the next batch
-		case ephemeralRunnerSet.Spec.PatchID == 0 && total > ephemeralRunnerSet.Spec.Replicas:
-			count := total - ephemeralRunnerSet.Spec.Replicas
+			r.forgetCreations(req.NamespacedName)
+		case ephemeralRunnerSet.Spec.PatchID == 0 && total > replicas:
+			r.forgetCreations(req.NamespacedName)
+			count := total - replicas
 			log.Info("Deleting ephemeral runners (scale down)", "count", count)
 			if err := r.deleteIdleEphemeralRunners(
 				ctx,
@@ -225,6 +271,18 @@ func (r *EphemeralRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.R
 				log.Error(err, "failed to delete idle runners")
 				return ctrl.Result{}, err
 			}
+		default:
+			r.forgetCreations(req.NamespacedName)
+		}
+	} else if unreplaced := ephemeralRunnerState.unreplaced(); len(unreplaced) > 0 {
+		// The runners for the latest patch have already been created, so the scale up above is skipped until the next patch.
+		// The runners that lost their pod along with their job since then are still counted in the desired replicas
+		// by the listener, because their jobs haven't completed yet, so they're replaced right away.
+		log.Info("Creating new ephemeral runners to replace the runners that lost their pod", "count", len(unreplaced), "replaced", unreplaced)
+		creation := newEphemeralRunnerCreation(ephemeralRunnerState, len(unreplaced), canary, unreplaced)
+		if err := r.createEphemeralRunners(ctx, ephemeralRunnerSet, creation, log); err != nil {
+			log.Error(err, "failed to make ephemeral runner to replace the runners that lost their pod")
+			return ctrl.Result{}, err
 		}
 	}
 
@@ -233,10 +291,11 @@ func (r *EphemeralRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.R
 		PendingEphemeralRunners: len(ephemeralRunnerState.pending),
 		RunningEphemeralRunners: len(ephemeralRunnerState.running),
 		FailedEphemeralRunners:  len(ephemeralRunnerState.failed),
+		Canary:                  canary,
 	}
 
 	// Update the status if needed.
-	if ephemeralRunnerSet.Status != desiredStatus {
+	if !equality.Semantic.DeepEqual(ephemeralRunnerSet.Status, desiredStatus) {
 		log.Info("Updating status with current runners count", "count", total)
 		if err := patchSubResource(ctx, r.Status(), ephemeralRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
 			obj.Status = desiredStatus
@@ -246,7 +305,56 @@ func (r *EphemeralRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.R
 		}
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
+}
+
+// creationPriority returns the creation priority annotated on the AutoscalingRunnerSet of the EphemeralRunnerSet, or 0 when there's none.
+func (r *EphemeralRunnerSetReconciler) creationPriority(ctx context.Context, ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet, log logr.Logger) int {
+	autoscalingRunnerSet := new(v1alpha1.AutoscalingRunnerSet)
+	key := types.NamespacedName{
+		Namespace: ephemeralRunnerSet.Labels[LabelKeyGitHubScaleSetNamespace],
+		Name:      ephemeralRunnerSet.Labels[LabelKeyGitHubScaleSetName],
+	}
+	if err := r.Get(ctx, key, autoscalingRunnerSet); err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to get autoscaling runner set for creation priority")
+		}
+		return 0
+	}
+
+	v, ok := autoscalingRunnerSet.Annotations[AnnotationKeyCreationPriority]
+	if !ok {
+		return 0
+	}
+
+	priority, err := strconv.Atoi(v)
+	if err != nil {
+		log.Error(err, "Ignoring invalid creation priority", "annotation", AnnotationKeyCreationPriority, "value", v)
+		return 0
+	}
+
+	return priority
+}
+
+func (r *EphemeralRunnerSetReconciler) forgetCreations(key types.NamespacedName) {
+	if r.CreationScheduler != nil {
+		r.CreationScheduler.Forget(key)
+	}
+}
+
+// ephemeralRunnerSetMetricLabels returns the metric labels of the scale set of the EphemeralRunnerSet.
+func ephemeralRunnerSetMetricLabels(ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet) metrics.CommonLabels {
+	labels := metrics.CommonLabels{
+		Name:      ephemeralRunnerSet.Labels[LabelKeyGitHubScaleSetName],
+		Namespace: ephemeralRunnerSet.Labels[LabelKeyGitHubScaleSetNamespace],
+	}
+	if parsedURL, err := actions.ParseGitHubConfigFromURL(ephemeralRunnerSet.Spec.EphemeralRunnerSpec.GitHubConfigUrl); err == nil {
+		labels.Repository = parsedURL.Repository
+		labels.Organization = parsedURL.Organization
+		labels.Enterprise = parsedURL.Enterprise
+	}
+
+	return labels
 }
 
 func (r *EphemeralRunnerSetReconciler) cleanupFinishedEphemeralRunners(ctx context.Context, finishedEphemeralRunners []*v1alpha1.EphemeralRunner, log logr.Logger) error {
@@ -355,15 +463,53 @@ func (r *EphemeralRunnerSetReconciler) cleanUpEphemeralRunners(ctx context.Conte
 	return false, nil
 }
 
-// createEphemeralRunners provisions `count` number of v1alpha1.EphemeralRunner resources in the cluster.
-func (r *EphemeralRunnerSetReconciler) createEphemeralRunners(ctx context.Context, runnerSet *v1alpha1.EphemeralRunnerSet, count int, log logr.Logger) error {
+// ephemeralRunnerCreation describes the runners createEphemeralRunners creates.
+// The counters are updated as the runners are created.
+type ephemeralRunnerCreation struct {
+	// count is the number of runners to create.
+	count int
+	// inUse is the number of runners using each container hooks version of the runner set.
+	inUse map[string]int
+	// canary is the canary status of the runner set, if any.
+	canary *v1alpha1.EphemeralRunnerSetCanaryStatus
+	// replaced are the names of the runners that the first created runners replace.
+	replaced []string
+}
+
+// newEphemeralRunnerCreation returns the creation of count runners, counting the container hooks versions
+// of the pending and running runners of the state.
+func newEphemeralRunnerCreation(state *ephemeralRunnerState, count int, canary *v1alpha1.EphemeralRunnerSetCanaryStatus, replaced []string) ephemeralRunnerCreation {
+	return ephemeralRunnerCreation{
+		count:    count,
+		inUse:    containerHooksInUse(state.pending, state.running),
+		canary:   canary,
+		replaced: replaced,
+	}
+}
+
+// createEphemeralRunners provisions `creation.count` number of v1alpha1.EphemeralRunner resources in the cluster,
+// spread between the container hooks versions of the runner set according to the number of runners already using
+// each of them. The first of them are annotated as the replacements of the runners in `creation.replaced`.
+func (r *EphemeralRunnerSetReconciler) createEphemeralRunners(ctx context.Context, runnerSet *v1alpha1.EphemeralRunnerSet, creation ephemeralRunnerCreation, log logr.Logger) error {
 	// Track multiple errors at once and return the bundle.
 	errs := make([]error, 0)
-	for i := 0; i < count; i++ {
+	var replacements int
+	for i := 0; i < creation.count; i++ {
 		ephemeralRunner := r.ResourceBuilder.newEphemeralRunner(runnerSet)
+		if i < len(creation.replaced) {
+			ephemeralRunner.Annotations[AnnotationKeyReplacedEphemeralRunner] = creation.replaced[i]
+		}
 		if runnerSet.Spec.EphemeralRunnerSpec.Proxy != nil {
 			ephemeralRunner.Spec.ProxySecretRef = proxyEphemeralRunnerSetSecretName(runnerSet)
 		}
+		if hooks := selectContainerHooks(runnerSet.Spec.ContainerHooks, creation.inUse); hooks != nil {
+			applyContainerHooks(ephemeralRunner, hooks)
+			creation.inUse[hooks.Name]++
+		}
+		isCanary := selectCanary(runnerSet.Spec.Canary, creation.canary)
+		if isCanary {
+			applyCanary(ephemeralRunner, runnerSet)
+		}
 
 		// Make sure that we own the resource we create.
 		if err := ctrl.SetControllerReference(runnerSet, ephemeralRunner, r.Scheme); err != nil {
@@ -372,7 +518,7 @@ func (r *EphemeralRunnerSetReconciler) createEphemeralRunners(ctx context.Contex
 			continue
 		}
 
-		log.Info("Creating new ephemeral runner", "progress", i+1, "total", count)
+		log.Info("Creating new ephemeral runner", "progress", i+1, "total", creation.count)
 		if err := r.Create(ctx, ephemeralRunner); err != nil {
 			log.Error(err, "failed to make ephemeral runner")
 			errs = append(errs, err)
@@ -380,6 +526,30 @@ func (r *EphemeralRunnerSetReconciler) createEphemeralRunners(ctx context.Contex
 		}
 
 		log.Info("Created new ephemeral runner", "runner", ephemeralRunner.Name)
+		if creation.canary != nil {
+			creation.canary.CreatedRunners++
+			if isCanary {
+				creation.canary.CanaryRunners++
+			}
+		}
+		if i < len(creation.replaced) {
+			replacements++
+		}
+	}
+
+	if replacements > 0 && r.PublishMetrics {
+		if parsedURL, err := actions.ParseGitHubConfigFromURL(runnerSet.Spec.EphemeralRunnerSpec.GitHubConfigUrl); err == nil {
+			metrics.AddEphemeralRunnerReplacements(
+				metrics.CommonLabels{
+					Name:         runnerSet.Labels[LabelKeyGitHubScaleSetName],
+					Namespace:    runnerSet.Labels[LabelKeyGitHubScaleSetNamespace],
+					Repository:   parsedURL.Repository,
+					Organization: parsedURL.Organization,
+					Enterprise:   parsedURL.Enterprise,
+				},
+				replacements,
+			)
+		}
 	}
 
 	return multierr.Combine(errs...)
@@ -399,10 +569,10 @@ func (r *EphemeralRunnerSetReconciler) createProxySecret(ctx context.Context, ep
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      proxyEphemeralRunnerSetSecretName(ephemeralRunnerSet),
 			Namespace: ephemeralRunnerSet.Namespace,
-			Labels: map[string]string{
+			Labels: r.NamingPolicy.applyRequiredLabels(map[string]string{
 				LabelKeyGitHubScaleSetName:      ephemeralRunnerSet.Labels[LabelKeyGitHubScaleSetName],
 				LabelKeyGitHubScaleSetNamespace: ephemeralRunnerSet.Labels[LabelKeyGitHubScaleSetNamespace],
-			},
+			}),
 		},
 		Data: proxySecretData,
 	}
@@ -503,6 +673,10 @@ func (r *EphemeralRunnerSetReconciler) deleteEphemeralRunnerWithActionsClient(ct
 }
 
 func (r *EphemeralRunnerSetReconciler) actionsClientFor(ctx context.Context, rs *v1alpha1.EphemeralRunnerSet) (actions.ActionsService, error) {
+	if isLoadTestSimulation(rs.Annotations) {
+		return loadTestSimulationClient, nil
+	}
+
 	secret := new(corev1.Secret)
 	if err := r.Get(ctx, types.NamespacedName{Namespace: rs.Namespace, Name: rs.Spec.EphemeralRunnerSpec.GitHubConfigSecret}, secret); err != nil {
 		return nil, fmt.Errorf("failed to get secret: %w", err)
@@ -564,6 +738,29 @@ func (r *EphemeralRunnerSetReconciler) actionsClientOptionsFor(ctx context.Conte
 		}
 
 		opts = append(opts, actions.WithRootCAs(pool))
+
+		cert, err := tlsConfig.ToClientCertificate(func(name string) (*corev1.Secret, error) {
+			var secret corev1.Secret
+			err := r.Get(
+				ctx,
+				types.NamespacedName{
+					Namespace: rs.Namespace,
+					Name:      name,
+				},
+				&secret,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+			}
+
+			return &secret, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tls client certificate: %w", err)
+		}
+		if cert != nil {
+			opts = append(opts, actions.WithClientCertificate(*cert))
+		}
 	}
 
 	return opts, nil
@@ -575,7 +772,7 @@ func (r *EphemeralRunnerSetReconciler) SetupWithManager(mgr ctrl.Manager) error
 		For(&v1alpha1.EphemeralRunnerSet{}).
 		Owns(&v1alpha1.EphemeralRunner{}).
 		WithEventFilter(predicate.ResourceVersionChangedPredicate{}).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap("ephemeralrunnerset", mgr.GetCache(), &v1alpha1.EphemeralRunnerSet{}, r))
 }
 
 type ephemeralRunnerStepper struct {
@@ -629,6 +826,11 @@ type ephemeralRunnerState struct {
 	failed   []*v1alpha1.EphemeralRunner
 	deleting []*v1alpha1.EphemeralRunner
 
+	// lost are the deleting runners that lost their pod along with their job,
+	// and replaced the names of the runners their replacement has been created for.
+	lost     []*v1alpha1.EphemeralRunner
+	replaced map[string]bool
+
 	latestPatchID int
 }
 
@@ -643,9 +845,19 @@ func newEphemeralRunnerState(ephemeralRunnerList *v1alpha1.EphemeralRunnerList)
 		}
 		if !r.ObjectMeta.DeletionTimestamp.IsZero() {
 			ephemeralRunnerState.deleting = append(ephemeralRunnerState.deleting, r)
+			if podLost(r) && r.Status.JobRequestId > 0 {
+				ephemeralRunnerState.lost = append(ephemeralRunnerState.lost, r)
+			}
 			continue
 		}
 
+		if name, ok := r.Annotations[AnnotationKeyReplacedEphemeralRunner]; ok {
+			if ephemeralRunnerState.replaced == nil {
+				ephemeralRunnerState.replaced = make(map[string]bool)
+			}
+			ephemeralRunnerState.replaced[name] = true
+		}
+
 		switch r.Status.Phase {
 		case corev1.PodRunning:
 			ephemeralRunnerState.running = append(ephemeralRunnerState.running, r)
@@ -667,3 +879,14 @@ func newEphemeralRunnerState(ephemeralRunnerList *v1alpha1.EphemeralRunnerList)
 func (s *ephemeralRunnerState) scaleTotal() int {
 	return len(s.pending) + len(s.running) + len(s.failed)
 }
+
+// unreplaced returns the names of the runners that lost their pod along with their job and have no replacement yet.
+func (s *ephemeralRunnerState) unreplaced() []string {
+	var names []string
+	for _, r := range s.lost {
+		if !s.replaced[r.Name] {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,206 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// LabelKeyJobTemplate is the label of the AutoscalingRunnerSets of job templates set to the name of their job template.
+	LabelKeyJobTemplate = "actions.github.com/job-template"
+
+	// LabelKeyJobTemplateOf is the label of the AutoscalingRunnerSets of job templates set to the name of the AutoscalingRunnerSet
+	// they're a job template of.
+	LabelKeyJobTemplateOf = "actions.github.com/job-template-of"
+
+	// annotationKeyGitHubRunnerScaleSetLabels is the annotation of the AutoscalingRunnerSet holding the labels besides its name
+	// the runner scale set was last updated with.
+	annotationKeyGitHubRunnerScaleSetLabels = "actions.github.com/runner-scale-set-labels"
+)
+
+// runnerScaleSetLabels returns the labels of the runner scale set: its name, which every job of the scale set requests,
+// and the other labels its jobs request.
+func runnerScaleSetLabels(name string, labels []string) []actions.Label {
+	scaleSetLabels := []actions.Label{{Name: name, Type: "System"}}
+	for _, label := range distinctLabels(labels) {
+		scaleSetLabels = append(scaleSetLabels, actions.Label{Name: label, Type: "System"})
+	}
+	return scaleSetLabels
+}
+
+// distinctLabels returns the distinct labels, lowercased and sorted.
+func distinctLabels(labels []string) []string {
+	var distinct []string
+	for _, label := range labels {
+		label = strings.ToLower(label)
+		if !slices.Contains(distinct, label) {
+			distinct = append(distinct, label)
+		}
+	}
+	slices.Sort(distinct)
+	return distinct
+}
+
+// updateRunnerScaleSetLabels updates the labels of the runner scale set with the runnerScaleSetLabels of the spec.
+func (r *AutoscalingRunnerSetReconciler) updateRunnerScaleSetLabels(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) (ctrl.Result, error) {
+	runnerScaleSetId, err := strconv.Atoi(autoscalingRunnerSet.Annotations[runnerScaleSetIdAnnotationKey])
+	if err != nil {
+		logger.Error(err, "Failed to parse runner scale set ID")
+		return ctrl.Result{}, err
+	}
+
+	actionsClient, err := r.actionsClientFor(ctx, autoscalingRunnerSet)
+	if err != nil {
+		logger.Error(err, "Failed to initialize Actions service client for updating a existing runner scale set")
+		return ctrl.Result{}, err
+	}
+
+	labels := runnerScaleSetLabels(scaleSetNameOf(autoscalingRunnerSet), autoscalingRunnerSet.Spec.RunnerScaleSetLabels)
+	if _, err := actionsClient.UpdateRunnerScaleSet(ctx, runnerScaleSetId, &actions.RunnerScaleSet{Labels: labels}); err != nil {
+		logger.Error(err, "Failed to update runner scale set labels", "runnerScaleSetId", runnerScaleSetId)
+		return ctrl.Result{}, err
+	}
+
+	scaleSetLabels := strings.Join(distinctLabels(autoscalingRunnerSet.Spec.RunnerScaleSetLabels), ",")
+	if err := patch(ctx, r.Client, autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		obj.Annotations[annotationKeyGitHubRunnerScaleSetLabels] = scaleSetLabels
+	}); err != nil {
+		logger.Error(err, "Failed to update runner scale set labels annotation")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Updated runner scale set labels", "labels", scaleSetLabels)
+	return ctrl.Result{}, nil
+}
+
+// runnerScaleSetLabelsChanged returns whether the runnerScaleSetLabels of the spec changed since the runner scale set was last updated with them.
+func runnerScaleSetLabelsChanged(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) bool {
+	labels, ok := autoscalingRunnerSet.Annotations[annotationKeyGitHubRunnerScaleSetLabels]
+	return !ok || labels != strings.Join(distinctLabels(autoscalingRunnerSet.Spec.RunnerScaleSetLabels), ",")
+}
+
+// reconcileJobTemplates makes the AutoscalingRunnerSets of the job templates match them, and deletes the ones of the removed job templates.
+// Each of them has its own runner scale set, labeled with the labels of its job template, so that GitHub only ever gives
+// the jobs requesting them to its runners.
+func (r *AutoscalingRunnerSetReconciler) reconcileJobTemplates(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) error {
+	existing, err := r.listJobTemplates(ctx, autoscalingRunnerSet)
+	if err != nil {
+		return err
+	}
+
+	if len(autoscalingRunnerSet.Spec.JobTemplates) > 0 {
+		// The pod templates of the job templates aren't part of the CRD schema, and the admission webhook is optional
+		if err := autoscalingRunnerSet.Validate(); err != nil {
+			return fmt.Errorf("invalid job templates: %w", err)
+		}
+	}
+
+	for i := range autoscalingRunnerSet.Spec.JobTemplates {
+		desired := r.ResourceBuilder.newJobTemplateAutoscalingRunnerSet(autoscalingRunnerSet, &autoscalingRunnerSet.Spec.JobTemplates[i])
+		if err := ctrl.SetControllerReference(autoscalingRunnerSet, desired, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set controller reference to the AutoscalingRunnerSet of job template %q: %w", autoscalingRunnerSet.Spec.JobTemplates[i].Name, err)
+		}
+
+		idx := slices.IndexFunc(existing, func(ars v1alpha1.AutoscalingRunnerSet) bool { return ars.Name == desired.Name })
+		if idx < 0 {
+			logger.Info("Creating the AutoscalingRunnerSet of a job template", "name", desired.Name)
+			if err := r.Create(ctx, desired); err != nil && !kerrors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create the AutoscalingRunnerSet of job template %q: %w", autoscalingRunnerSet.Spec.JobTemplates[i].Name, err)
+			}
+			continue
+		}
+
+		current := &existing[idx]
+		if equality.Semantic.DeepEqual(current.Spec, desired.Spec) && labelsContain(current.Labels, desired.Labels) {
+			continue
+		}
+
+		logger.Info("Updating the AutoscalingRunnerSet of a job template", "name", current.Name)
+		if err := patch(ctx, r.Client, current, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			obj.Spec = desired.Spec
+			for k, v := range desired.Labels {
+				obj.Labels[k] = v
+			}
+		}); err != nil {
+			return fmt.Errorf("failed to update the AutoscalingRunnerSet of job template %q: %w", autoscalingRunnerSet.Spec.JobTemplates[i].Name, err)
+		}
+	}
+
+	for i := range existing {
+		name := existing[i].Labels[LabelKeyJobTemplate]
+		if slices.ContainsFunc(autoscalingRunnerSet.Spec.JobTemplates, func(t v1alpha1.JobTemplate) bool { return t.Name == name }) {
+			continue
+		}
+
+		logger.Info("Deleting the AutoscalingRunnerSet of a removed job template", "name", existing[i].Name)
+		if err := r.Delete(ctx, &existing[i]); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete the AutoscalingRunnerSet of removed job template %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// cleanupJobTemplates deletes the AutoscalingRunnerSets of the job templates, and returns whether they're all gone.
+// They're deleted before the AutoscalingRunnerSet, so that they can still delete their runner scale sets
+// with the GitHub config secret it may clean up.
+func (r *AutoscalingRunnerSetReconciler) cleanupJobTemplates(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) (done bool, err error) {
+	existing, err := r.listJobTemplates(ctx, autoscalingRunnerSet)
+	if err != nil {
+		return false, err
+	}
+	if len(existing) == 0 {
+		return true, nil
+	}
+
+	for i := range existing {
+		if !existing[i].DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		logger.Info("Deleting the AutoscalingRunnerSet of a job template", "name", existing[i].Name)
+		if err := r.Delete(ctx, &existing[i]); err != nil && !kerrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to delete the AutoscalingRunnerSet of job template %q: %w", existing[i].Labels[LabelKeyJobTemplate], err)
+		}
+	}
+
+	return false, nil
+}
+
+// listJobTemplates returns the AutoscalingRunnerSets of the job templates of the AutoscalingRunnerSet.
+func (r *AutoscalingRunnerSetReconciler) listJobTemplates(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) ([]v1alpha1.AutoscalingRunnerSet, error) {
+	list := new(v1alpha1.AutoscalingRunnerSetList)
+	if err := r.List(ctx, list, client.InNamespace(autoscalingRunnerSet.Namespace), client.MatchingLabels{LabelKeyJobTemplateOf: autoscalingRunnerSet.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list the AutoscalingRunnerSets of the job templates: %w", err)
+	}
+
+	owned := make([]v1alpha1.AutoscalingRunnerSet, 0, len(list.Items))
+	for _, ars := range list.Items {
+		if metav1.IsControlledBy(&ars, autoscalingRunnerSet) {
+			owned = append(owned, ars)
+		}
+	}
+	return owned, nil
+}
+
+// labelsContain returns whether the labels contain all of the other labels, with the same values.
+func labelsContain(labels, other map[string]string) bool {
+	for k, v := range other {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/build"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunnerScaleSetLabels(t *testing.T) {
+	assert.Equal(t, []actions.Label{
+		{Name: "arc-runners-gpu", Type: "System"},
+		{Name: "gpu", Type: "System"},
+		{Name: "linux", Type: "System"},
+	}, runnerScaleSetLabels("arc-runners-gpu", []string{"linux", "GPU", "gpu"}))
+	assert.Equal(t, []actions.Label{{Name: "arc-runners", Type: "System"}}, runnerScaleSetLabels("arc-runners", nil))
+
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}},
+	}
+	assert.True(t, runnerScaleSetLabelsChanged(ars), "the scale set wasn't updated with its labels yet")
+
+	ars.Annotations[annotationKeyGitHubRunnerScaleSetLabels] = ""
+	assert.False(t, runnerScaleSetLabelsChanged(ars))
+
+	ars.Spec.RunnerScaleSetLabels = []string{"linux", "GPU"}
+	assert.True(t, runnerScaleSetLabelsChanged(ars))
+
+	ars.Annotations[annotationKeyGitHubRunnerScaleSetLabels] = "gpu,linux"
+	assert.False(t, runnerScaleSetLabelsChanged(ars))
+}
+
+func TestReconcileJobTemplates(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	runnerTemplate := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "runner", Image: image}},
+		}}
+	}
+	minRunners, maxRunners, gpuMaxRunners := 2, 10, 4
+
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "arc-runners",
+			Namespace: "arc-runners",
+			UID:       "arc-runners-uid",
+			Labels:    map[string]string{LabelKeyKubernetesVersion: build.Version},
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl:    "https://github.com/my-org",
+			GitHubConfigSecret: "github-config",
+			RunnerScaleSetName: "arc",
+			Template:           runnerTemplate("runner:default"),
+			MinRunners:         &minRunners,
+			MaxRunners:         &maxRunners,
+			Canary:             &v1alpha1.CanaryRollout{Percentage: 10},
+			JobTemplates: []v1alpha1.JobTemplate{
+				{Name: "gpu", Labels: []string{"gpu"}, MaxRunners: &gpuMaxRunners, Template: runnerTemplate("runner:gpu")},
+				{Name: "large", Labels: []string{"large", "linux"}, Template: runnerTemplate("runner:large")},
+			},
+		},
+	}
+
+	r := &AutoscalingRunnerSetReconciler{
+		Client: crfake.NewClientBuilder().WithScheme(scheme).WithObjects(ars).Build(),
+		Scheme: scheme,
+	}
+	require.NoError(t, r.reconcileJobTemplates(ctx, ars, logr.Discard()))
+
+	gpu := new(v1alpha1.AutoscalingRunnerSet)
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: "arc-runners", Name: "arc-runners-gpu"}, gpu))
+	assert.True(t, metav1.IsControlledBy(gpu, ars))
+	assert.Equal(t, "gpu", gpu.Labels[LabelKeyJobTemplate])
+	assert.Equal(t, "arc-runners", gpu.Labels[LabelKeyJobTemplateOf])
+	assert.Equal(t, "arc-runners-gpu", gpu.Labels[LabelKeyGitHubScaleSetName])
+	assert.Equal(t, build.Version, gpu.Labels[LabelKeyKubernetesVersion])
+	assert.Equal(t, "arc-gpu", gpu.Spec.RunnerScaleSetName, "the job template has its own runner scale set")
+	assert.Equal(t, []string{"gpu"}, gpu.Spec.RunnerScaleSetLabels)
+	assert.Equal(t, "runner:gpu", gpu.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "github-config", gpu.Spec.GitHubConfigSecret)
+	assert.Empty(t, gpu.Spec.JobTemplates)
+	assert.Nil(t, gpu.Spec.MinRunners)
+	assert.Equal(t, 4, *gpu.Spec.MaxRunners)
+	assert.Nil(t, gpu.Spec.Canary, "the canary is an image of the template of the scale set")
+
+	large := new(v1alpha1.AutoscalingRunnerSet)
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: "arc-runners", Name: "arc-runners-large"}, large))
+	assert.Equal(t, 10, *large.Spec.MaxRunners, "maxRunners defaults to the one of the scale set")
+
+	// The pod template of a job template is updated, and the AutoscalingRunnerSet of a removed job template deleted
+	ars.Spec.JobTemplates = ars.Spec.JobTemplates[:1]
+	ars.Spec.JobTemplates[0].Template = runnerTemplate("runner:gpu-2")
+	require.NoError(t, r.reconcileJobTemplates(ctx, ars, logr.Discard()))
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(gpu), gpu))
+	assert.Equal(t, "runner:gpu-2", gpu.Spec.Template.Spec.Containers[0].Image)
+
+	list := new(v1alpha1.AutoscalingRunnerSetList)
+	require.NoError(t, r.List(ctx, list, client.MatchingLabels{LabelKeyJobTemplateOf: "arc-runners"}))
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, "arc-runners-gpu", list.Items[0].Name)
+
+	// A job template without the runner container isn't created
+	ars.Spec.JobTemplates = append(ars.Spec.JobTemplates, v1alpha1.JobTemplate{
+		Name:     "arm",
+		Labels:   []string{"arm64"},
+		Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "build", Image: "runner:arm"}}}},
+	})
+	require.ErrorContains(t, r.reconcileJobTemplates(ctx, ars, logr.Discard()), "spec.jobTemplates[1].template.spec.containers")
+
+	done, err := r.cleanupJobTemplates(ctx, ars, logr.Discard())
+	require.NoError(t, err)
+	assert.False(t, done, "the AutoscalingRunnerSets of the job templates are being deleted")
+
+	done, err = r.cleanupJobTemplates(ctx, ars, logr.Discard())
+	require.NoError(t, err)
+	assert.True(t, done)
+}
@@ -0,0 +1,467 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	loadTestDefaultTimeout = 10 * time.Minute
+	loadTestDefaultRef     = "main"
+
+	// loadTestPollInterval is the interval the scaling of the jobs is checked at once they are all generated,
+	// besides the changes of the ephemeral runners.
+	loadTestPollInterval = 10 * time.Second
+)
+
+// LoadTestReconciler reconciles a LoadTest object
+type LoadTestReconciler struct {
+	client.Client
+	Log           logr.Logger
+	Scheme        *runtime.Scheme
+	ActionsClient actions.MultiClient
+	ResourceBuilder
+}
+
+// +kubebuilder:rbac:groups=actions.github.com,resources=loadtests,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=actions.github.com,resources=loadtests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunnersets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunners,verbs=get;list;watch
+
+// Reconcile a LoadTest resource so that its jobs are generated at the rate of its spec,
+// and the latency of the scaling of the scale set for them is reported in its status.
+func (r *LoadTestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("loadtest", req.NamespacedName)
+
+	loadTest := new(v1alpha1.LoadTest)
+	if err := r.Get(ctx, req.NamespacedName, loadTest); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if loadTest.Status.Phase == v1alpha1.LoadTestPhaseCompleted || loadTest.Status.Phase == v1alpha1.LoadTestPhaseFailed {
+		return ctrl.Result{}, r.cleanupSimulation(ctx, loadTest, log)
+	}
+
+	autoscalingRunnerSet := new(v1alpha1.AutoscalingRunnerSet)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: loadTest.Namespace, Name: loadTest.Spec.AutoscalingRunnerSetName}, autoscalingRunnerSet); err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to get AutoscalingRunnerSet")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.fail(ctx, loadTest, fmt.Sprintf("AutoscalingRunnerSet %q not found", loadTest.Spec.AutoscalingRunnerSetName), log)
+	}
+
+	if loadTest.Status.StartTime == nil {
+		log.Info("Starting load test", "mode", loadTest.LoadTestMode(), "jobs", loadTest.Spec.Jobs, "jobsPerMinute", loadTest.Spec.JobsPerMinute)
+		now := metav1.Now()
+		if err := patchSubResource(ctx, r.Status(), loadTest, func(obj *v1alpha1.LoadTest) {
+			obj.Status.Phase = v1alpha1.LoadTestPhaseRunning
+			obj.Status.StartTime = &now
+		}); err != nil {
+			log.Error(err, "Failed to update load test status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	now := time.Now()
+	start := loadTest.Status.StartTime.Time
+	interval := loadTest.JobInterval()
+	due := min(loadTest.Spec.Jobs, int(now.Sub(start)/interval)+1)
+
+	var samples []v1alpha1.LoadTestSample
+	switch loadTest.LoadTestMode() {
+	case v1alpha1.LoadTestModeSimulation:
+		var err error
+		samples, err = r.simulateJobs(ctx, loadTest, autoscalingRunnerSet, due, log)
+		if err != nil {
+			log.Error(err, "Failed to simulate jobs")
+			return ctrl.Result{}, err
+		}
+	default:
+		owner, repo, err := loadTestRepository(loadTest, autoscalingRunnerSet)
+		if err != nil {
+			return ctrl.Result{}, r.fail(ctx, loadTest, err.Error(), log)
+		}
+
+		samples, err = r.dispatchJobs(ctx, loadTest, autoscalingRunnerSet, owner, repo, due, log)
+		if err != nil {
+			log.Error(err, "Failed to dispatch jobs")
+			return ctrl.Result{}, err
+		}
+	}
+
+	samples = mergeLoadTestSamples(loadTest.Status.Samples, samples, loadTest.Spec.Jobs)
+
+	timeout := loadTestDefaultTimeout
+	if loadTest.Spec.Timeout != nil {
+		timeout = loadTest.Spec.Timeout.Duration
+	}
+	lastJob := start.Add(time.Duration(loadTest.Spec.Jobs-1) * interval)
+
+	phase, message := v1alpha1.LoadTestPhaseRunning, ""
+	switch {
+	case len(samples) >= loadTest.Spec.Jobs:
+		phase = v1alpha1.LoadTestPhaseCompleted
+		message = fmt.Sprintf("The scaling for all the %d jobs was observed", loadTest.Spec.Jobs)
+	case due == loadTest.Spec.Jobs && now.After(lastJob.Add(timeout)):
+		phase = v1alpha1.LoadTestPhaseFailed
+		message = fmt.Sprintf("The scaling for only %d of the %d jobs was observed within %s of the last one", len(samples), loadTest.Spec.Jobs, timeout)
+	}
+
+	if err := patchSubResource(ctx, r.Status(), loadTest, func(obj *v1alpha1.LoadTest) {
+		obj.Status.GeneratedJobs = due
+		obj.Status.Samples = samples
+		obj.Status.ScalingLatency = loadTestLatency(samples)
+		obj.Status.Phase = phase
+		obj.Status.Message = message
+		if phase != v1alpha1.LoadTestPhaseRunning {
+			completionTime := metav1.NewTime(now)
+			obj.Status.CompletionTime = &completionTime
+		}
+	}); err != nil {
+		log.Error(err, "Failed to update load test status")
+		return ctrl.Result{}, err
+	}
+
+	if phase != v1alpha1.LoadTestPhaseRunning {
+		log.Info("Load test finished", "phase", phase, "message", message)
+		return ctrl.Result{}, r.cleanupSimulation(ctx, loadTest, log)
+	}
+
+	if due < loadTest.Spec.Jobs {
+		return ctrl.Result{RequeueAfter: start.Add(time.Duration(due) * interval).Sub(now)}, nil
+	}
+	return ctrl.Result{RequeueAfter: loadTestPollInterval}, nil
+}
+
+// dispatchJobs dispatches the workflow runs due and not dispatched yet,
+// and returns the jobs of the dispatched runs a runner of the scale set was assigned to.
+func (r *LoadTestReconciler) dispatchJobs(ctx context.Context, loadTest *v1alpha1.LoadTest, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, owner, repo string, due int, log logr.Logger) ([]v1alpha1.LoadTestSample, error) {
+	if loadTest.Status.GeneratedJobs < due {
+		actionsClient, err := (&AutoscalingRunnerSetReconciler{Client: r.Client, ActionsClient: r.ActionsClient}).actionsClientFor(ctx, autoscalingRunnerSet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Actions service client: %w", err)
+		}
+
+		workflow := loadTest.Spec.Workflow
+		ref := workflow.Ref
+		if ref == "" {
+			ref = loadTestDefaultRef
+		}
+
+		log.Info("Dispatching workflow runs", "repository", owner+"/"+repo, "workflow", workflow.Workflow, "count", due-loadTest.Status.GeneratedJobs)
+		for generated := loadTest.Status.GeneratedJobs; generated < due; generated++ {
+			if err := actionsClient.DispatchWorkflow(ctx, owner, repo, workflow.Workflow, ref, workflow.Inputs); err != nil {
+				// Record the runs dispatched so far, so that they aren't dispatched again
+				if err := patchSubResource(ctx, r.Status(), loadTest, func(obj *v1alpha1.LoadTest) {
+					obj.Status.GeneratedJobs = generated
+				}); err != nil {
+					log.Error(err, "Failed to update load test status")
+				}
+				return nil, fmt.Errorf("failed to dispatch workflow: %w", err)
+			}
+		}
+	}
+
+	runners := new(v1alpha1.EphemeralRunnerList)
+	if err := r.List(ctx, runners, client.InNamespace(autoscalingRunnerSet.Namespace), client.MatchingLabels{
+		LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
+		LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list ephemeral runners: %w", err)
+	}
+
+	var samples []v1alpha1.LoadTestSample
+	for i := range runners.Items {
+		status := &runners.Items[i].Status
+		if status.JobRequestId == 0 || status.JobQueueTime == nil || status.JobRunnerAssignTime == nil {
+			continue
+		}
+		if status.JobQueueTime.Before(loadTest.Status.StartTime) || !strings.EqualFold(status.JobRepositoryName, owner+"/"+repo) {
+			continue
+		}
+
+		samples = append(samples, v1alpha1.LoadTestSample{
+			Name:          strconv.FormatInt(status.JobRequestId, 10),
+			GeneratedTime: *status.JobQueueTime,
+			ScaledTime:    *status.JobRunnerAssignTime,
+		})
+	}
+
+	return samples, nil
+}
+
+// simulateJobs scales the runner set simulating the jobs of the load test up to the jobs due, creating it when there is none,
+// and returns the simulated jobs whose runner pod started.
+// The jobs are matched to the runners in the order the runners were created.
+// The runners are backed by a fake Actions client, so they never register with the scale set.
+func (r *LoadTestReconciler) simulateJobs(ctx context.Context, loadTest *v1alpha1.LoadTest, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, due int, log logr.Logger) ([]v1alpha1.LoadTestSample, error) {
+	ephemeralRunnerSet, err := r.simulationRunnerSet(ctx, loadTest)
+	if err != nil {
+		return nil, err
+	}
+
+	if ephemeralRunnerSet == nil {
+		ephemeralRunnerSet, err = r.newEphemeralRunnerSet(autoscalingRunnerSet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build simulation runner set: %w", err)
+		}
+		ephemeralRunnerSet.GenerateName = loadTest.Name + "-loadtest-"
+		newSimulationRunnerSet(loadTest, ephemeralRunnerSet)
+		ephemeralRunnerSet.Spec.Replicas = due
+		ephemeralRunnerSet.Spec.PatchID = due
+		if err := ctrl.SetControllerReference(loadTest, ephemeralRunnerSet, r.Scheme); err != nil {
+			return nil, fmt.Errorf("failed to set controller reference: %w", err)
+		}
+
+		log.Info("Creating simulation runner set", "replicas", due)
+		if err := r.Create(ctx, ephemeralRunnerSet); err != nil {
+			return nil, fmt.Errorf("failed to create simulation runner set: %w", err)
+		}
+		return nil, nil
+	}
+
+	if ephemeralRunnerSet.Spec.Replicas < due {
+		log.Info("Scaling up simulation runner set", "name", ephemeralRunnerSet.Name, "replicas", due)
+		if err := patch(ctx, r.Client, ephemeralRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+			obj.Spec.Replicas = due
+			obj.Spec.PatchID = due
+		}); err != nil {
+			return nil, fmt.Errorf("failed to scale simulation runner set: %w", err)
+		}
+	}
+
+	runnerList := new(v1alpha1.EphemeralRunnerList)
+	if err := r.List(ctx, runnerList, client.InNamespace(ephemeralRunnerSet.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ephemeral runners: %w", err)
+	}
+
+	var runners []*v1alpha1.EphemeralRunner
+	for i := range runnerList.Items {
+		if metav1.IsControlledBy(&runnerList.Items[i], ephemeralRunnerSet) {
+			runners = append(runners, &runnerList.Items[i])
+		}
+	}
+	sort.Slice(runners, func(i, j int) bool {
+		if !runners[i].CreationTimestamp.Equal(&runners[j].CreationTimestamp) {
+			return runners[i].CreationTimestamp.Before(&runners[j].CreationTimestamp)
+		}
+		return runners[i].Name < runners[j].Name
+	})
+
+	start := loadTest.Status.StartTime.Time
+	var samples []v1alpha1.LoadTestSample
+	for i, runner := range runners {
+		pod := new(corev1.Pod)
+		if err := r.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: runner.Name}, pod); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get runner pod: %w", err)
+		}
+
+		started := runnerContainerStartTime(pod)
+		if started == nil {
+			continue
+		}
+
+		samples = append(samples, v1alpha1.LoadTestSample{
+			Name:          runner.Name,
+			GeneratedTime: metav1.NewTime(start.Add(time.Duration(i) * loadTest.JobInterval())),
+			ScaledTime:    *started,
+		})
+	}
+
+	return samples, nil
+}
+
+// runnerContainerStartTime returns when the runner container of the pod started, or nil when it didn't yet.
+func runnerContainerStartTime(pod *corev1.Pod) *metav1.Time {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != EphemeralRunnerContainerName {
+			continue
+		}
+		switch {
+		case cs.State.Running != nil:
+			return &cs.State.Running.StartedAt
+		case cs.State.Terminated != nil:
+			return &cs.State.Terminated.StartedAt
+		}
+	}
+	return nil
+}
+
+// simulationRunnerSet returns the runner set simulating the jobs of the load test, or nil when there is none.
+func (r *LoadTestReconciler) simulationRunnerSet(ctx context.Context, loadTest *v1alpha1.LoadTest) (*v1alpha1.EphemeralRunnerSet, error) {
+	list := new(v1alpha1.EphemeralRunnerSetList)
+	if err := r.List(ctx, list, client.InNamespace(loadTest.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ephemeral runner sets: %w", err)
+	}
+
+	for i := range list.Items {
+		if metav1.IsControlledBy(&list.Items[i], loadTest) {
+			return &list.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// cleanupSimulation deletes the runner set simulating the jobs of the load test, which deletes its runners.
+func (r *LoadTestReconciler) cleanupSimulation(ctx context.Context, loadTest *v1alpha1.LoadTest, log logr.Logger) error {
+	ephemeralRunnerSet, err := r.simulationRunnerSet(ctx, loadTest)
+	if err != nil || ephemeralRunnerSet == nil || !ephemeralRunnerSet.DeletionTimestamp.IsZero() {
+		return err
+	}
+
+	log.Info("Deleting simulation runner set", "name", ephemeralRunnerSet.Name)
+	if err := r.Delete(ctx, ephemeralRunnerSet); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete simulation runner set: %w", err)
+	}
+	return nil
+}
+
+func (r *LoadTestReconciler) fail(ctx context.Context, loadTest *v1alpha1.LoadTest, message string, log logr.Logger) error {
+	log.Info("Load test failed", "message", message)
+	now := metav1.Now()
+	if err := patchSubResource(ctx, r.Status(), loadTest, func(obj *v1alpha1.LoadTest) {
+		obj.Status.Phase = v1alpha1.LoadTestPhaseFailed
+		obj.Status.Message = message
+		obj.Status.CompletionTime = &now
+	}); err != nil {
+		return err
+	}
+	return r.cleanupSimulation(ctx, loadTest, log)
+}
+
+// loadTestRepository returns the owner and the name of the repository of the workflow dispatched by the load test.
+func loadTestRepository(loadTest *v1alpha1.LoadTest, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) (string, string, error) {
+	if loadTest.Spec.Workflow == nil || loadTest.Spec.Workflow.Workflow == "" {
+		return "", "", fmt.Errorf("spec.workflow.workflow is required in the %s mode", v1alpha1.LoadTestModeDispatch)
+	}
+
+	if owner, repo, ok := strings.Cut(loadTest.Spec.Workflow.Repository, "/"); ok {
+		return owner, repo, nil
+	}
+
+	config, err := actions.ParseGitHubConfigFromURL(autoscalingRunnerSet.Spec.GitHubConfigUrl)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid githubConfigUrl of AutoscalingRunnerSet %q: %w", autoscalingRunnerSet.Name, err)
+	}
+
+	switch {
+	case loadTest.Spec.Workflow.Repository != "" && config.Organization != "":
+		return config.Organization, loadTest.Spec.Workflow.Repository, nil
+	case loadTest.Spec.Workflow.Repository == "" && config.Scope == actions.GitHubScopeRepository:
+		return config.Organization, config.Repository, nil
+	}
+	return "", "", fmt.Errorf("spec.workflow.repository must be in the owner/name format for the scale sets of enterprises, and is required for the ones of organizations")
+}
+
+// mergeLoadTestSamples adds the samples not observed yet to the observed ones, up to limit.
+// The observed samples are kept, as the runners of the jobs are deleted when the jobs complete.
+func mergeLoadTestSamples(observed, samples []v1alpha1.LoadTestSample, limit int) []v1alpha1.LoadTestSample {
+	names := make(map[string]bool, len(observed))
+	for _, s := range observed {
+		names[s.Name] = true
+	}
+
+	merged := append([]v1alpha1.LoadTestSample(nil), observed...)
+	for _, s := range samples {
+		if len(merged) >= limit {
+			break
+		}
+		if names[s.Name] {
+			continue
+		}
+		names[s.Name] = true
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// loadTestLatency returns the nearest-rank percentiles of the scaling latency of the samples, or nil when there is none.
+func loadTestLatency(samples []v1alpha1.LoadTestSample) *v1alpha1.LoadTestLatency {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	latencies := make([]time.Duration, len(samples))
+	for i := range samples {
+		latencies[i] = samples[i].Latency()
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	percentile := func(p float64) metav1.Duration {
+		rank := int(math.Ceil(p*float64(len(latencies)))) - 1
+		return metav1.Duration{Duration: latencies[max(rank, 0)]}
+	}
+
+	return &v1alpha1.LoadTestLatency{
+		P50: percentile(0.5),
+		P90: percentile(0.9),
+		P99: percentile(0.99),
+		Max: metav1.Duration{Duration: latencies[len(latencies)-1]},
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LoadTestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// The status updates of each reconciliation would otherwise trigger another one
+		For(&v1alpha1.LoadTest{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&v1alpha1.EphemeralRunnerSet{}).
+		// The jobs are observed through the ephemeral runners of the scale set
+		Watches(&v1alpha1.EphemeralRunner{}, handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, o client.Object) []reconcile.Request {
+				loadTests := new(v1alpha1.LoadTestList)
+				if err := mgr.GetClient().List(ctx, loadTests, client.InNamespace(o.GetNamespace())); err != nil {
+					return nil
+				}
+
+				var requests []reconcile.Request
+				for _, loadTest := range loadTests.Items {
+					if loadTest.Status.Phase == v1alpha1.LoadTestPhaseRunning {
+						requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: loadTest.Namespace, Name: loadTest.Name}})
+					}
+				}
+				return requests
+			},
+		)).
+		Complete(r)
+}
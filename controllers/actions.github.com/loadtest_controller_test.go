@@ -0,0 +1,272 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/actions/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// dispatchClient records the workflow runs dispatched.
+type dispatchClient struct {
+	actions.ActionsService
+
+	dispatched []string
+}
+
+func (c *dispatchClient) DispatchWorkflow(ctx context.Context, owner, repo, workflow, ref string, inputs map[string]string) error {
+	c.dispatched = append(c.dispatched, owner+"/"+repo+"/"+workflow+"@"+ref)
+	return nil
+}
+
+func TestLoadTestReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	// The load tests started 65s ago generate a job every 30s, so 3 of their jobs are due
+	startTime := metav1.NewTime(time.Now().Add(-65 * time.Second).Truncate(time.Second))
+
+	newReconciler := func(actionsClient actions.ActionsService, spec v1alpha1.LoadTestSpec, objs ...client.Object) (*LoadTestReconciler, *v1alpha1.LoadTest) {
+		spec.AutoscalingRunnerSetName = "my-scale-set"
+		spec.JobsPerMinute = 2
+
+		loadTest := &v1alpha1.LoadTest{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-load-test", Namespace: "arc-runners", Generation: 1},
+			Spec:       spec,
+			Status: v1alpha1.LoadTestStatus{
+				Phase:     v1alpha1.LoadTestPhaseRunning,
+				StartTime: &startTime,
+			},
+		}
+		autoscalingRunnerSet := &v1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "my-scale-set",
+				Namespace:   "arc-runners",
+				Annotations: map[string]string{runnerScaleSetIdAnnotationKey: "1"},
+			},
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{
+				GitHubConfigUrl:    "https://github.com/my-org",
+				GitHubConfigSecret: "github-config",
+			},
+		}
+		configSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "github-config", Namespace: "arc-runners"},
+			Data:       map[string][]byte{"github_token": []byte("token")},
+		}
+
+		r := &LoadTestReconciler{
+			Client:        crfake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objs, loadTest, autoscalingRunnerSet, configSecret)...).WithStatusSubresource(loadTest).Build(),
+			Log:           logr.Discard(),
+			Scheme:        scheme,
+			ActionsClient: fake.NewMultiClient(fake.WithDefaultClient(actionsClient, nil)),
+		}
+		return r, loadTest
+	}
+
+	reconcile := func(t *testing.T, r *LoadTestReconciler, loadTest *v1alpha1.LoadTest) *v1alpha1.LoadTest {
+		t.Helper()
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(loadTest)})
+		require.NoError(t, err)
+
+		updated := new(v1alpha1.LoadTest)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(loadTest), updated))
+		return updated
+	}
+
+	newRunner := func(name string, status v1alpha1.EphemeralRunnerStatus) *v1alpha1.EphemeralRunner {
+		return &v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "arc-runners",
+				Labels: map[string]string{
+					LabelKeyGitHubScaleSetName:      "my-scale-set",
+					LabelKeyGitHubScaleSetNamespace: "arc-runners",
+				},
+			},
+			Status: status,
+		}
+	}
+
+	t.Run("dispatches the jobs due and reports the latency of their scaling", func(t *testing.T) {
+		queued := metav1.NewTime(startTime.Add(10 * time.Second))
+		assigned := metav1.NewTime(startTime.Add(14 * time.Second))
+		before := metav1.NewTime(startTime.Add(-time.Minute))
+
+		actionsClient := new(dispatchClient)
+		r, loadTest := newReconciler(actionsClient, v1alpha1.LoadTestSpec{
+			Jobs:     5,
+			Workflow: &v1alpha1.LoadTestWorkflow{Repository: "my-repo", Workflow: "load.yaml"},
+		},
+			newRunner("assigned", v1alpha1.EphemeralRunnerStatus{JobRequestId: 1, JobRepositoryName: "my-org/my-repo", JobQueueTime: &queued, JobRunnerAssignTime: &assigned}),
+			newRunner("other-repository", v1alpha1.EphemeralRunnerStatus{JobRequestId: 2, JobRepositoryName: "my-org/other", JobQueueTime: &queued, JobRunnerAssignTime: &assigned}),
+			newRunner("queued-before", v1alpha1.EphemeralRunnerStatus{JobRequestId: 3, JobRepositoryName: "my-org/my-repo", JobQueueTime: &before, JobRunnerAssignTime: &assigned}),
+			newRunner("idle", v1alpha1.EphemeralRunnerStatus{}),
+		)
+
+		updated := reconcile(t, r, loadTest)
+		assert.Equal(t, []string{"my-org/my-repo/load.yaml@main", "my-org/my-repo/load.yaml@main", "my-org/my-repo/load.yaml@main"}, actionsClient.dispatched)
+		assert.Equal(t, 3, updated.Status.GeneratedJobs)
+		assert.Equal(t, v1alpha1.LoadTestPhaseRunning, updated.Status.Phase)
+
+		require.Len(t, updated.Status.Samples, 1)
+		assert.Equal(t, "1", updated.Status.Samples[0].Name)
+		require.NotNil(t, updated.Status.ScalingLatency)
+		assert.Equal(t, 4*time.Second, updated.Status.ScalingLatency.P90.Duration)
+
+		actionsClient.dispatched = nil
+		reconcile(t, r, updated)
+		assert.Empty(t, actionsClient.dispatched, "the jobs already dispatched aren't dispatched again")
+	})
+
+	t.Run("scales a runner set up for the simulated jobs", func(t *testing.T) {
+		r, loadTest := newReconciler(nil, v1alpha1.LoadTestSpec{
+			Mode: v1alpha1.LoadTestModeSimulation,
+			Jobs: 2,
+		})
+
+		updated := reconcile(t, r, loadTest)
+		ephemeralRunnerSet, err := r.simulationRunnerSet(ctx, updated)
+		require.NoError(t, err)
+		require.NotNil(t, ephemeralRunnerSet)
+		assert.Equal(t, 2, ephemeralRunnerSet.Spec.Replicas)
+		assert.Equal(t, "my-scale-set", ephemeralRunnerSet.Labels[LabelKeyGitHubScaleSetName])
+		assert.Equal(t, 2, updated.Status.GeneratedJobs)
+		assert.Equal(t, "my-load-test", ephemeralRunnerSet.Annotations[AnnotationKeyLoadTestSimulation])
+		assert.Zero(t, ephemeralRunnerSet.Spec.EphemeralRunnerSpec.RunnerScaleSetId, "the simulated runners never register with the scale set")
+
+		for i, name := range []string{"runner-a", "runner-b"} {
+			runner := newRunner(name, v1alpha1.EphemeralRunnerStatus{})
+			runner.CreationTimestamp = metav1.NewTime(startTime.Add(time.Duration(i) * time.Second))
+			require.NoError(t, ctrl.SetControllerReference(ephemeralRunnerSet, runner, scheme))
+			require.NoError(t, r.Create(ctx, runner))
+
+			require.NoError(t, r.Create(ctx, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "arc-runners"},
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{{
+						Name:  EphemeralRunnerContainerName,
+						State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(startTime.Add(40 * time.Second))}},
+					}},
+				},
+			}))
+		}
+
+		updated = reconcile(t, r, updated)
+		assert.Equal(t, v1alpha1.LoadTestPhaseCompleted, updated.Status.Phase)
+		assert.NotNil(t, updated.Status.CompletionTime)
+		require.Len(t, updated.Status.Samples, 2)
+		assert.Equal(t, 40*time.Second, updated.Status.ScalingLatency.Max.Duration)
+		assert.Equal(t, 10*time.Second, updated.Status.ScalingLatency.P50.Duration)
+
+		ephemeralRunnerSet, err = r.simulationRunnerSet(ctx, updated)
+		require.NoError(t, err)
+		assert.Nil(t, ephemeralRunnerSet, "the simulation runner set is deleted once the load test completes")
+	})
+
+	t.Run("fails when the scaling isn't observed in time", func(t *testing.T) {
+		r, loadTest := newReconciler(new(dispatchClient), v1alpha1.LoadTestSpec{
+			Jobs:     2,
+			Timeout:  &metav1.Duration{Duration: time.Second},
+			Workflow: &v1alpha1.LoadTestWorkflow{Repository: "my-org/my-repo", Workflow: "load.yaml"},
+		})
+
+		updated := reconcile(t, r, loadTest)
+		assert.Equal(t, v1alpha1.LoadTestPhaseFailed, updated.Status.Phase)
+		assert.Contains(t, updated.Status.Message, "only 0 of the 2 jobs")
+	})
+
+	t.Run("fails without the scale set", func(t *testing.T) {
+		r, loadTest := newReconciler(new(dispatchClient), v1alpha1.LoadTestSpec{Jobs: 1})
+		loadTest.Spec.AutoscalingRunnerSetName = "missing"
+		require.NoError(t, r.Update(ctx, loadTest))
+
+		updated := reconcile(t, r, loadTest)
+		assert.Equal(t, v1alpha1.LoadTestPhaseFailed, updated.Status.Phase)
+		assert.Equal(t, `AutoscalingRunnerSet "missing" not found`, updated.Status.Message)
+	})
+}
+
+func TestLoadTestLatency(t *testing.T) {
+	base := time.Now()
+	var samples []v1alpha1.LoadTestSample
+	for i := 1; i <= 10; i++ {
+		samples = append(samples, v1alpha1.LoadTestSample{
+			GeneratedTime: metav1.NewTime(base),
+			ScaledTime:    metav1.NewTime(base.Add(time.Duration(11-i) * time.Second)),
+		})
+	}
+
+	latency := loadTestLatency(samples)
+	assert.Equal(t, 5*time.Second, latency.P50.Duration)
+	assert.Equal(t, 9*time.Second, latency.P90.Duration)
+	assert.Equal(t, 10*time.Second, latency.P99.Duration)
+	assert.Equal(t, 10*time.Second, latency.Max.Duration)
+
+	assert.Nil(t, loadTestLatency(nil))
+}
+
+func TestNewSimulationRunnerSet(t *testing.T) {
+	autoscalingRunnerSet := &v1alpha1.AutoscalingRunnerSet{
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: EphemeralRunnerContainerName, Command: []string{"/home/runner/run.sh"}},
+						{Name: "sidecar", Command: []string{"sidecar"}},
+					},
+				},
+			},
+		},
+	}
+	ephemeralRunnerSet := &v1alpha1.EphemeralRunnerSet{
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			EphemeralRunnerSpec: v1alpha1.EphemeralRunnerSpec{
+				RunnerScaleSetId: 1,
+				PodTemplateSpec:  autoscalingRunnerSet.Spec.Template,
+			},
+		},
+	}
+
+	newSimulationRunnerSet(&v1alpha1.LoadTest{ObjectMeta: metav1.ObjectMeta{Name: "my-load-test"}}, ephemeralRunnerSet)
+
+	assert.True(t, isLoadTestSimulation(ephemeralRunnerSet.Annotations))
+	assert.Zero(t, ephemeralRunnerSet.Spec.EphemeralRunnerSpec.RunnerScaleSetId)
+	containers := ephemeralRunnerSet.Spec.EphemeralRunnerSpec.PodTemplateSpec.Spec.Containers
+	assert.Equal(t, simulationRunnerCommand, containers[0].Command)
+	assert.Equal(t, []string{"sidecar"}, containers[1].Command)
+	assert.Equal(t, []string{"/home/runner/run.sh"}, autoscalingRunnerSet.Spec.Template.Spec.Containers[0].Command, "the scale set is left untouched")
+}
+
+func TestSimulationActionsClient(t *testing.T) {
+	ctx := context.Background()
+	c := &simulationActionsClient{ActionsService: fake.NewFakeClient()}
+
+	first, err := c.GenerateJitRunnerConfig(ctx, &actions.RunnerScaleSetJitRunnerSetting{Name: "runner-a"}, 0)
+	require.NoError(t, err)
+	second, err := c.GenerateJitRunnerConfig(ctx, &actions.RunnerScaleSetJitRunnerSetting{Name: "runner-b"}, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Runner.Id, second.Runner.Id)
+	assert.Equal(t, "runner-b", second.Runner.Name)
+
+	runner, err := c.GetRunner(ctx, int64(second.Runner.Id))
+	require.NoError(t, err)
+	assert.Equal(t, second.Runner.Id, runner.Id)
+	assert.NoError(t, c.RemoveRunner(ctx, int64(second.Runner.Id)))
+}
@@ -0,0 +1,79 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/actions/fake"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// AnnotationKeyLoadTestSimulation marks the runner sets simulating the jobs of a LoadTest, and their runners.
+// Their runners are backed by simulationActionsClient, so they never register with the scale set, and can't pick up real jobs.
+const AnnotationKeyLoadTestSimulation = "actions.github.com/load-test-simulation"
+
+// simulationRunnerCommand replaces the command of the runner container of the simulated runners,
+// as the runner would fail to start with the fake JIT config. The container runs until its runner is deleted.
+var simulationRunnerCommand = []string{"/bin/sh", "-c", "trap 'exit 0' TERM INT; while true; do sleep 1; done"}
+
+// simulationActionsClient is the fake Actions client of the simulated runners.
+// Each runner gets its own runner ID, which is found as long as it's asked for.
+type simulationActionsClient struct {
+	actions.ActionsService
+
+	lastRunnerId atomic.Int64
+}
+
+var loadTestSimulationClient = &simulationActionsClient{ActionsService: fake.NewFakeClient()}
+
+func (c *simulationActionsClient) GenerateJitRunnerConfig(ctx context.Context, jitRunnerSetting *actions.RunnerScaleSetJitRunnerSetting, scaleSetId int) (*actions.RunnerScaleSetJitRunnerConfig, error) {
+	return &actions.RunnerScaleSetJitRunnerConfig{
+		Runner: &actions.RunnerReference{
+			Id:               int(c.lastRunnerId.Add(1)),
+			Name:             jitRunnerSetting.Name,
+			RunnerScaleSetId: scaleSetId,
+		},
+		EncodedJITConfig: "load-test-simulation",
+	}, nil
+}
+
+func (c *simulationActionsClient) GetRunner(ctx context.Context, runnerId int64) (*actions.RunnerReference, error) {
+	return &actions.RunnerReference{Id: int(runnerId)}, nil
+}
+
+func (c *simulationActionsClient) GetRunnerByName(ctx context.Context, runnerName string) (*actions.RunnerReference, error) {
+	return nil, nil
+}
+
+func (c *simulationActionsClient) RemoveRunner(ctx context.Context, runnerId int64) error {
+	return nil
+}
+
+// isLoadTestSimulation returns true for the runner sets and the runners simulating the jobs of a LoadTest.
+func isLoadTestSimulation(annotations map[string]string) bool {
+	_, ok := annotations[AnnotationKeyLoadTestSimulation]
+	return ok
+}
+
+// newSimulationRunnerSet turns the runner set built from the scale set into one simulating the jobs of the load test,
+// whose runners don't register with the scale set and don't run the runner.
+func newSimulationRunnerSet(loadTest *v1alpha1.LoadTest, ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet) {
+	if ephemeralRunnerSet.Annotations == nil {
+		ephemeralRunnerSet.Annotations = make(map[string]string)
+	}
+	ephemeralRunnerSet.Annotations[AnnotationKeyLoadTestSimulation] = loadTest.Name
+	ephemeralRunnerSet.Spec.EphemeralRunnerSpec.RunnerScaleSetId = 0
+
+	// The containers are shared with the pod template of the scale set
+	spec := &ephemeralRunnerSet.Spec.EphemeralRunnerSpec.PodTemplateSpec.Spec
+	spec.Containers = append([]corev1.Container(nil), spec.Containers...)
+	for i := range spec.Containers {
+		if spec.Containers[i].Name != EphemeralRunnerContainerName {
+			continue
+		}
+		spec.Containers[i].Command = simulationRunnerCommand
+		spec.Containers[i].Args = nil
+	}
+}
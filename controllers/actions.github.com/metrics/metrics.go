@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -66,6 +68,46 @@ var (
 		},
 		labels,
 	)
+	jobQueueLatencySLOBurnRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: githubScaleSetControllerSubsystem,
+			Name:      "job_queue_latency_slo_burn_rate",
+			Help:      "Rate the error budget of the job queue latency SLO of an autoscaling runner set is consumed at.",
+		},
+		labels,
+	)
+	reclaimedOrphanedResources = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: githubScaleSetControllerSubsystem,
+			Name:      "reclaimed_orphaned_resources_total",
+			Help:      "Number of resources deleted because the listener they were created for no longer exists.",
+		},
+		[]string{"kind"},
+	)
+	ephemeralRunnerPodLosses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: githubScaleSetControllerSubsystem,
+			Name:      "ephemeral_runner_pod_losses_total",
+			Help:      "Number of ephemeral runner pods deleted out of band, e.g. by a user or a node drain.",
+		},
+		append(labels, "job_lost"),
+	)
+	ephemeralRunnerCreationQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: githubScaleSetControllerSubsystem,
+			Name:      "ephemeral_runner_creation_queue_depth",
+			Help:      "Number of ephemeral runners waiting to be created under the global creation rate limit.",
+		},
+		labels,
+	)
+	ephemeralRunnerReplacements = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: githubScaleSetControllerSubsystem,
+			Name:      "ephemeral_runner_replacements_total",
+			Help:      "Number of ephemeral runners created to replace the ephemeral runners that lost their pod along with their job.",
+		},
+		labels,
+	)
 )
 
 func RegisterMetrics() {
@@ -74,6 +116,11 @@ func RegisterMetrics() {
 		runningEphemeralRunners,
 		failedEphemeralRunners,
 		runningListeners,
+		jobQueueLatencySLOBurnRate,
+		reclaimedOrphanedResources,
+		ephemeralRunnerPodLosses,
+		ephemeralRunnerReplacements,
+		ephemeralRunnerCreationQueueDepth,
 	)
 }
 
@@ -90,3 +137,25 @@ func AddRunningListener(commonLabels CommonLabels) {
 func SubRunningListener(commonLabels CommonLabels) {
 	runningListeners.With(commonLabels.labels()).Set(0)
 }
+
+func SetJobQueueLatencySLOBurnRate(commonLabels CommonLabels, burnRate float64) {
+	jobQueueLatencySLOBurnRate.With(commonLabels.labels()).Set(burnRate)
+}
+
+func AddReclaimedOrphanedResources(kind string, count int) {
+	reclaimedOrphanedResources.With(prometheus.Labels{"kind": kind}).Add(float64(count))
+}
+
+func AddEphemeralRunnerPodLoss(commonLabels CommonLabels, jobLost bool) {
+	l := commonLabels.labels()
+	l["job_lost"] = strconv.FormatBool(jobLost)
+	ephemeralRunnerPodLosses.With(l).Inc()
+}
+
+func AddEphemeralRunnerReplacements(commonLabels CommonLabels, count int) {
+	ephemeralRunnerReplacements.With(commonLabels.labels()).Add(float64(count))
+}
+
+func SetEphemeralRunnerCreationQueueDepth(commonLabels CommonLabels, depth int) {
+	ephemeralRunnerCreationQueueDepth.With(commonLabels.labels()).Set(float64(depth))
+}
@@ -0,0 +1,320 @@
+package actionsgithubcom
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/hash"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Kinds of the objects whose names can be customized with a name template.
+// Objects not listed here are named after another object, like the pods and the JIT config secrets
+// of ephemeral runners, which are named after their EphemeralRunner, itself named after its EphemeralRunnerSet.
+const (
+	namingKindAutoscalingListener = "AutoscalingListener"
+	namingKindEphemeralRunnerSet  = "EphemeralRunnerSet"
+	namingKindServiceAccount      = "ServiceAccount"
+	namingKindRole                = "Role"
+	namingKindSecret              = "Secret"
+	namingKindEgressPolicy        = "EgressPolicy"
+	namingKindPlaceholder         = "Placeholder"
+	namingKindConnectivityProbe   = "ConnectivityProbe"
+	namingKindWarmPool            = "WarmPool"
+
+	// namingKindDefault is the kind of the template applied to the kinds without a template of their own.
+	namingKindDefault = "*"
+)
+
+var namingKinds = []string{
+	namingKindAutoscalingListener,
+	namingKindEphemeralRunnerSet,
+	namingKindServiceAccount,
+	namingKindRole,
+	namingKindSecret,
+	namingKindEgressPolicy,
+	namingKindPlaceholder,
+	namingKindConnectivityProbe,
+	namingKindWarmPool,
+	namingKindDefault,
+}
+
+// NamingPolicy customizes the names and labels of the objects created for AutoscalingRunnerSets,
+// for clusters enforcing policies on them. The zero value and nil keep the default names and labels.
+type NamingPolicy struct {
+	nameTemplates  map[string]*template.Template
+	requiredLabels map[string]string
+
+	// hash identifies the policy, so that the objects created under another policy can be found and renamed.
+	hash string
+}
+
+// nameTemplateData is the data name templates are rendered with.
+type nameTemplateData struct {
+	// Name is the name the controller would give the object without a template.
+	Name string
+	// Kind is the kind of the object.
+	Kind string
+	// ScaleSetName is the name of the AutoscalingRunnerSet the object belongs to.
+	ScaleSetName string
+	// ScaleSetNamespace is the namespace of the AutoscalingRunnerSet the object belongs to.
+	ScaleSetNamespace string
+}
+
+// NewNamingPolicy parses a naming policy.
+// nameTemplates are in the KIND=TEMPLATE format, where TEMPLATE is a Go template rendering the name of the objects of KIND,
+// or of all kinds without a template of their own when KIND is "*".
+// labels are in the KEY=VALUE format, and are added to all the objects.
+// The templates are rendered with sample data and the labels are validated, so that a policy producing invalid objects is rejected on startup.
+func NewNamingPolicy(templates, labels []string) (*NamingPolicy, error) {
+	parsedTemplates := make(map[string]*template.Template, len(templates))
+	for _, t := range templates {
+		kind, text, ok := strings.Cut(t, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid name template %q: must be in the KIND=TEMPLATE format", t)
+		}
+
+		if !isNamingKind(kind) {
+			return nil, fmt.Errorf("invalid name template %q: unknown kind %q, must be one of %s", t, kind, strings.Join(namingKinds, ", "))
+		}
+
+		tmpl, err := template.New(kind).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name template %q: %w", t, err)
+		}
+
+		name, err := renderName(tmpl, nameTemplateData{
+			Name:              "example-6b5d8f7c-listener",
+			Kind:              kind,
+			ScaleSetName:      "example",
+			ScaleSetNamespace: "arc-runners",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid name template %q: %w", t, err)
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid name template %q: renders the invalid name %q: %s", t, name, strings.Join(errs, ", "))
+		}
+
+		parsedTemplates[kind] = tmpl
+	}
+
+	parsedLabels := make(map[string]string, len(labels))
+	for _, l := range labels {
+		key, value, ok := strings.Cut(l, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q: must be in the KEY=VALUE format", l)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid value of label %q: %s", key, strings.Join(errs, ", "))
+		}
+		for _, k := range commonLabelKeys {
+			if key == k {
+				return nil, fmt.Errorf("invalid label %q: the label is managed by the controller", key)
+			}
+		}
+
+		parsedLabels[key] = value
+	}
+
+	policy := &NamingPolicy{
+		nameTemplates:  parsedTemplates,
+		requiredLabels: parsedLabels,
+	}
+
+	// Only the templates change the names, and the default policy keeps the hash empty,
+	// so that the objects created before naming policies existed aren't renamed
+	if len(templates) > 0 {
+		sorted := slices.Clone(templates)
+		slices.Sort(sorted)
+		policy.hash = hash.FNVHashString(strings.Join(sorted, "\n"))
+	}
+
+	return policy, nil
+}
+
+// Hash identifies the name templates of the policy. It's empty for the default names.
+func (p *NamingPolicy) Hash() string {
+	if p == nil {
+		return ""
+	}
+	return p.hash
+}
+
+func isNamingKind(kind string) bool {
+	for _, k := range namingKinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+func renderName(tmpl *template.Template, data nameTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// generatedName returns the name of an object of the kind, rendered with the name template of the kind when there is one.
+// defaultName is returned when there is no template, or when the template fails to render.
+func (p *NamingPolicy) generatedName(kind, defaultName, scaleSetName, scaleSetNamespace string) string {
+	if p == nil {
+		return defaultName
+	}
+
+	tmpl, ok := p.nameTemplates[kind]
+	if !ok {
+		tmpl, ok = p.nameTemplates[namingKindDefault]
+	}
+	if !ok {
+		return defaultName
+	}
+
+	name, err := renderName(tmpl, nameTemplateData{
+		Name:              defaultName,
+		Kind:              kind,
+		ScaleSetName:      scaleSetName,
+		ScaleSetNamespace: scaleSetNamespace,
+	})
+	if err != nil || name == "" {
+		return defaultName
+	}
+	return name
+}
+
+// applyRequiredLabels adds the labels of the naming policy to labels, and returns them.
+func (p *NamingPolicy) applyRequiredLabels(labels map[string]string) map[string]string {
+	if p == nil || len(p.requiredLabels) == 0 {
+		return labels
+	}
+
+	if labels == nil {
+		labels = make(map[string]string, len(p.requiredLabels))
+	}
+	for k, v := range p.requiredLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// annotationKeyNamingPolicyHash is the annotation of the AutoscalingRunnerSets and AutoscalingListeners holding the hash of
+// the naming policy their objects were last created under. The listeners created under another policy are recreated,
+// and the objects of the scale sets created under another policy are renamed.
+const annotationKeyNamingPolicyHash = "actions.github.com/naming-policy-hash"
+
+// deleteObjectsNotNamed deletes the objects of the list matching the list options and the filter, other than the ones named keep.
+// It returns whether any object was deleted.
+func deleteObjectsNotNamed(ctx context.Context, c client.Client, list client.ObjectList, keep []string, filter func(client.Object) bool, opts ...client.ListOption) (bool, error) {
+	if err := c.List(ctx, list, opts...); err != nil {
+		return false, err
+	}
+
+	deleted := false
+	err := meta.EachListItem(list, func(o runtime.Object) error {
+		obj := o.(client.Object)
+		if slices.Contains(keep, obj.GetName()) || (filter != nil && !filter(obj)) || !obj.GetDeletionTimestamp().IsZero() {
+			return nil
+		}
+
+		if err := c.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+		deleted = true
+		return nil
+	})
+	return deleted, err
+}
+
+// renameObjects deletes the objects the scale set controls that were created under a naming policy whose names differ from the current one,
+// like its placeholder and warm pool deployments, its connectivity probe job and its listener session secret, so that they are recreated
+// under their current names instead of being orphaned. It's a no-op while the scale set is annotated with the hash of the current policy.
+// The listener is recreated when its own annotation differs, and the egress policy is renamed through its status.
+func (r *AutoscalingRunnerSetReconciler) renameObjects(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) error {
+	policyHash := r.NamingPolicy.Hash()
+	if autoscalingRunnerSet.Annotations[annotationKeyNamingPolicyHash] == policyHash {
+		return nil
+	}
+
+	logger.Info("Naming policy changed. Deleting the objects created under the previous names so that they are recreated.")
+
+	controlled := func(obj client.Object) bool {
+		return metav1.IsControlledBy(obj, autoscalingRunnerSet)
+	}
+	inNamespace := client.InNamespace(autoscalingRunnerSet.Namespace)
+	sessionSecretName := r.scaleSetListenerSessionSecretName(&v1alpha1.AutoscalingListener{
+		Spec: v1alpha1.AutoscalingListenerSpec{
+			AutoscalingRunnerSetName:      autoscalingRunnerSet.Name,
+			AutoscalingRunnerSetNamespace: autoscalingRunnerSet.Namespace,
+		},
+	})
+
+	for _, kind := range []struct {
+		list client.ObjectList
+		keep []string
+	}{
+		{&appsv1.DeploymentList{}, []string{r.scaleSetPlaceholderName(autoscalingRunnerSet), r.scaleSetWarmPoolName(autoscalingRunnerSet)}},
+		{&batchv1.JobList{}, []string{r.scaleSetConnectivityProbeName(autoscalingRunnerSet)}},
+		{&corev1.SecretList{}, []string{sessionSecretName}},
+	} {
+		if _, err := deleteObjectsNotNamed(ctx, r.Client, kind.list, kind.keep, controlled, inNamespace); err != nil {
+			return fmt.Errorf("failed to delete objects created under the previous naming policy: %w", err)
+		}
+	}
+
+	return patch(ctx, r.Client, autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		if policyHash == "" {
+			delete(obj.Annotations, annotationKeyNamingPolicyHash)
+			return
+		}
+		if obj.Annotations == nil {
+			obj.Annotations = map[string]string{}
+		}
+		obj.Annotations[annotationKeyNamingPolicyHash] = policyHash
+	})
+}
+
+// getListener returns the listener of the scale set, or nil when there is none.
+// It's looked up by the labels of the scale set when it isn't found by its name,
+// so that a listener created under another naming policy is found and recreated under its current name.
+func (r *AutoscalingRunnerSetReconciler) getListener(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) (*v1alpha1.AutoscalingListener, error) {
+	listener := new(v1alpha1.AutoscalingListener)
+	err := r.Get(ctx, client.ObjectKey{Namespace: r.ControllerNamespace, Name: r.scaleSetListenerName(autoscalingRunnerSet)}, listener)
+	if err == nil {
+		return listener, nil
+	}
+	if !kerrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	var list v1alpha1.AutoscalingListenerList
+	if err := r.List(ctx, &list, client.InNamespace(r.ControllerNamespace), client.MatchingLabels{
+		LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
+		LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
+	}); err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	return &list.Items[0], nil
+}
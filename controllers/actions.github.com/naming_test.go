@@ -0,0 +1,251 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewNamingPolicy(t *testing.T) {
+	tests := map[string]struct {
+		templates []string
+		labels    []string
+		wantErr   bool
+	}{
+		"empty":                {},
+		"valid":                {templates: []string{"*=team-a-{{ .Name }}", "AutoscalingListener={{ .ScaleSetNamespace }}-{{ .ScaleSetName }}"}, labels: []string{"example.com/cost-center=ci"}},
+		"missing separator":    {templates: []string{"team-a-{{ .Name }}"}, wantErr: true},
+		"unknown kind":         {templates: []string{"Pod=team-a-{{ .Name }}"}, wantErr: true},
+		"unparseable template": {templates: []string{"*=team-a-{{ .Name"}, wantErr: true},
+		"unknown field":        {templates: []string{"*=team-a-{{ .Namespace }}"}, wantErr: true},
+		"invalid name":         {templates: []string{"*=Team_A-{{ .Name }}"}, wantErr: true},
+		"invalid label key":    {labels: []string{"cost center=ci"}, wantErr: true},
+		"invalid label value":  {labels: []string{"cost-center=c i"}, wantErr: true},
+		"managed label":        {labels: []string{LabelKeyGitHubScaleSetName + "=test"}, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewNamingPolicy(tc.templates, tc.labels)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNamingPolicy(t *testing.T) {
+	autoscalingRunnerSet := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scale-set",
+			Namespace: "test-ns",
+			Labels: map[string]string{
+				LabelKeyKubernetesPartOf:  labelValueKubernetesPartOf,
+				LabelKeyKubernetesVersion: "0.1.0",
+			},
+			Annotations: map[string]string{
+				runnerScaleSetIdAnnotationKey: "1",
+			},
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl: "https://github.com/org/repo",
+		},
+	}
+	autoscalingListener := &v1alpha1.AutoscalingListener{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-listener", Namespace: "arc-systems"},
+		Spec: v1alpha1.AutoscalingListenerSpec{
+			AutoscalingRunnerSetName:      autoscalingRunnerSet.Name,
+			AutoscalingRunnerSetNamespace: autoscalingRunnerSet.Namespace,
+		},
+	}
+
+	defaultListenerName := new(ResourceBuilder).scaleSetListenerName(autoscalingRunnerSet)
+
+	policy, err := NewNamingPolicy(
+		[]string{
+			"*=team-a-{{ .Name }}",
+			"AutoscalingListener={{ .ScaleSetNamespace }}-{{ .ScaleSetName }}-listener",
+		},
+		[]string{"cost-center=ci"},
+	)
+	require.NoError(t, err)
+
+	t.Run("names", func(t *testing.T) {
+		b := ResourceBuilder{NamingPolicy: policy}
+
+		assert.Equal(t, "test-ns-test-scale-set-listener", b.scaleSetListenerName(autoscalingRunnerSet))
+		assert.Equal(t, "team-a-"+defaultListenerName, b.scaleSetListenerServiceAccountName(autoscalingListener))
+		assert.Equal(t, "team-a-"+defaultListenerName, b.scaleSetListenerRoleName(autoscalingListener))
+		assert.Equal(t, "team-a-"+defaultListenerName, b.scaleSetListenerSecretMirrorName(autoscalingListener))
+		assert.Equal(t, "team-a-"+defaultListenerName+"-proxy", b.proxyListenerSecretName(autoscalingListener))
+
+		// Names derived from other objects are left as is
+		assert.Equal(t, "test-listener-config", scaleSetListenerConfigName(autoscalingListener))
+
+		ephemeralRunnerSet, err := b.newEphemeralRunnerSet(autoscalingRunnerSet)
+		require.NoError(t, err)
+		assert.Equal(t, "team-a-test-scale-set-", ephemeralRunnerSet.GenerateName)
+	})
+
+	t.Run("labels", func(t *testing.T) {
+		b := ResourceBuilder{ExcludeLabelPropagationPrefixes: []string{"cost-center"}, NamingPolicy: policy}
+
+		ephemeralRunnerSet, err := b.newEphemeralRunnerSet(autoscalingRunnerSet)
+		require.NoError(t, err)
+		assert.Equal(t, "ci", ephemeralRunnerSet.Labels["cost-center"], "required labels are not subject to exclusion")
+
+		ephemeralRunnerSet.Name = "test-runner-set"
+		ephemeralRunner := b.newEphemeralRunner(ephemeralRunnerSet)
+		assert.Equal(t, "ci", ephemeralRunner.Labels["cost-center"])
+
+		secret := b.newScaleSetListenerAdminTokenSecret(autoscalingListener, []byte("token"))
+		assert.Equal(t, "ci", secret.Labels["cost-center"])
+	})
+
+	t.Run("default policy", func(t *testing.T) {
+		var b ResourceBuilder
+
+		assert.Equal(t, defaultListenerName, b.scaleSetListenerName(autoscalingRunnerSet))
+		assert.Empty(t, b.NamingPolicy.Hash())
+
+		listener, err := b.newAutoScalingListener(autoscalingRunnerSet, &v1alpha1.EphemeralRunnerSet{}, "arc-systems", "ghcr.io/actions/gha-runner-scale-set-controller:latest", nil)
+		require.NoError(t, err)
+		assert.NotContains(t, listener.Annotations, annotationKeyNamingPolicyHash)
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		reordered, err := NewNamingPolicy(
+			[]string{
+				"AutoscalingListener={{ .ScaleSetNamespace }}-{{ .ScaleSetName }}-listener",
+				"*=team-a-{{ .Name }}",
+			},
+			nil,
+		)
+		require.NoError(t, err)
+		assert.NotEmpty(t, policy.Hash())
+		assert.Equal(t, policy.Hash(), reordered.Hash(), "the order of the templates doesn't change the hash")
+
+		empty, err := NewNamingPolicy(nil, []string{"cost-center=ci"})
+		require.NoError(t, err)
+		assert.Empty(t, empty.Hash())
+
+		b := ResourceBuilder{NamingPolicy: policy}
+		listener, err := b.newAutoScalingListener(autoscalingRunnerSet, &v1alpha1.EphemeralRunnerSet{}, "arc-systems", "ghcr.io/actions/gha-runner-scale-set-controller:latest", nil)
+		require.NoError(t, err)
+		assert.Equal(t, policy.Hash(), listener.Annotations[annotationKeyNamingPolicyHash])
+	})
+}
+
+func TestRenameObjects(t *testing.T) {
+	ctx := context.Background()
+
+	policy, err := NewNamingPolicy([]string{"*=team-a-{{ .Name }}"}, nil)
+	require.NoError(t, err)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	ars := &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-scale-set", Namespace: "test-ns", UID: "ars-uid"},
+	}
+	b := ResourceBuilder{NamingPolicy: policy}
+
+	controlled := func(obj client.Object) client.Object {
+		require.NoError(t, ctrl.SetControllerReference(ars, obj, scheme))
+		return obj
+	}
+	deployment := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ars.Namespace}}
+	}
+
+	r := &AutoscalingRunnerSetReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			ars,
+			controlled(deployment("test-scale-set-placeholder")),
+			controlled(deployment(b.scaleSetPlaceholderName(ars))),
+			deployment("unrelated"),
+			controlled(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "test-scale-set-connectivity-probe", Namespace: ars.Namespace}}),
+			controlled(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-scale-set-listener-session", Namespace: ars.Namespace}}),
+		).Build(),
+		Scheme:          scheme,
+		ResourceBuilder: b,
+	}
+
+	require.NoError(t, r.renameObjects(ctx, ars, logr.Discard()))
+
+	var deployments appsv1.DeploymentList
+	require.NoError(t, r.List(ctx, &deployments))
+	var names []string
+	for _, d := range deployments.Items {
+		names = append(names, d.Name)
+	}
+	assert.ElementsMatch(t, []string{"team-a-test-scale-set-placeholder", "unrelated"}, names)
+
+	var jobs batchv1.JobList
+	require.NoError(t, r.List(ctx, &jobs))
+	assert.Empty(t, jobs.Items)
+
+	var secrets corev1.SecretList
+	require.NoError(t, r.List(ctx, &secrets))
+	assert.Empty(t, secrets.Items)
+
+	assert.Equal(t, policy.Hash(), ars.Annotations[annotationKeyNamingPolicyHash])
+
+	// Nothing is deleted again until the policy changes
+	require.NoError(t, r.Create(ctx, controlled(deployment("test-scale-set-placeholder"))))
+	require.NoError(t, r.renameObjects(ctx, ars, logr.Discard()))
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: ars.Namespace, Name: "test-scale-set-placeholder"}, new(appsv1.Deployment)))
+}
+
+func TestGetListener(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	ars := &v1alpha1.AutoscalingRunnerSet{ObjectMeta: metav1.ObjectMeta{Name: "test-scale-set", Namespace: "test-ns"}}
+
+	// Created under a previous naming policy
+	listener := &v1alpha1.AutoscalingListener{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "previous-name",
+			Namespace: "arc-systems",
+			Labels: map[string]string{
+				LabelKeyGitHubScaleSetNamespace: ars.Namespace,
+				LabelKeyGitHubScaleSetName:      ars.Name,
+			},
+		},
+	}
+
+	r := &AutoscalingRunnerSetReconciler{
+		Client:              fake.NewClientBuilder().WithScheme(scheme).WithObjects(listener).Build(),
+		ControllerNamespace: "arc-systems",
+	}
+
+	found, err := r.getListener(ctx, ars)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "previous-name", found.Name)
+
+	require.NoError(t, r.Delete(ctx, listener))
+
+	found, err = r.getListener(ctx, ars)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
@@ -0,0 +1,279 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
+	"github.com/actions/actions-runner-controller/hash"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultOrphanedResourceCollectionInterval is the default interval between two collections of orphaned listener resources.
+	DefaultOrphanedResourceCollectionInterval = 10 * time.Minute
+
+	// orphanedResourceMinAge is the age below which a resource is never collected,
+	// so that a resource created for a listener the cache doesn't know of yet isn't mistaken for an orphan.
+	orphanedResourceMinAge = 5 * time.Minute
+)
+
+// OrphanedResourceCollector periodically deletes the resources created for AutoscalingListeners and EphemeralRunners
+// that no longer exist.
+//
+// The controllers delete them with finalizers, and most of them are owned by their listener or runner.
+// But the roles and role bindings of the listener live in the namespace of its scale set, out of the reach of owner references,
+// and all of them leak when a finalizer is removed by hand, or owner references are dropped,
+// for example by a backup and restore, while the controller isn't running.
+// Resources are tracked by the listener namespace and name labels, or the ephemeral runner name label, the controller sets on them.
+// The resources created before the labels were set are tracked by their owner references when they have any,
+// and by their default names otherwise.
+type OrphanedResourceCollector struct {
+	client.Client
+	Log logr.Logger
+
+	// ControllerNamespace is the namespace of the listeners, and of their service accounts and secrets.
+	ControllerNamespace string
+
+	// Interval is the interval between two collections. Defaults to DefaultOrphanedResourceCollectionInterval.
+	Interval time.Duration
+}
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=list;delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=list;delete
+
+func (c *OrphanedResourceCollector) Start(ctx context.Context) error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultOrphanedResourceCollectionInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			reclaimed, err := c.collect(ctx, time.Now())
+			if err != nil {
+				c.Log.Error(err, "Failed to collect orphaned resources")
+			}
+			for kind, count := range reclaimed {
+				metrics.AddReclaimedOrphanedResources(kind, count)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection makes only the leader collect orphaned resources.
+func (c *OrphanedResourceCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// collect deletes the orphaned resources, and returns the numbers of deleted resources by kind.
+// It carries on with the other resources when one can't be deleted, and returns the last error.
+func (c *OrphanedResourceCollector) collect(ctx context.Context, now time.Time) (map[string]int, error) {
+	reclaimed := make(map[string]int)
+
+	var roles rbacv1.RoleList
+	if err := c.List(ctx, &roles); err != nil {
+		return reclaimed, fmt.Errorf("failed to list roles: %w", err)
+	}
+	var roleBindings rbacv1.RoleBindingList
+	if err := c.List(ctx, &roleBindings); err != nil {
+		return reclaimed, fmt.Errorf("failed to list role bindings: %w", err)
+	}
+	var serviceAccounts corev1.ServiceAccountList
+	if err := c.List(ctx, &serviceAccounts, client.InNamespace(c.ControllerNamespace)); err != nil {
+		return reclaimed, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+	// The secrets of the ephemeral runners are in the namespaces of their scale sets.
+	var secrets corev1.SecretList
+	if err := c.List(ctx, &secrets); err != nil {
+		return reclaimed, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	var listeners v1alpha1.AutoscalingListenerList
+	if err := c.List(ctx, &listeners, client.InNamespace(c.ControllerNamespace)); err != nil {
+		return reclaimed, fmt.Errorf("failed to list listeners: %w", err)
+	}
+
+	type candidate struct {
+		kind string
+		obj  client.Object
+	}
+	var candidates []candidate
+	for i := range roles.Items {
+		candidates = append(candidates, candidate{"Role", &roles.Items[i]})
+	}
+	for i := range roleBindings.Items {
+		candidates = append(candidates, candidate{"RoleBinding", &roleBindings.Items[i]})
+	}
+	for i := range serviceAccounts.Items {
+		candidates = append(candidates, candidate{"ServiceAccount", &serviceAccounts.Items[i]})
+	}
+	for i := range secrets.Items {
+		candidates = append(candidates, candidate{"Secret", &secrets.Items[i]})
+	}
+
+	var lastErr error
+	for _, cand := range candidates {
+		kind, obj := cand.kind, cand.obj
+		orphaned, err := c.orphaned(ctx, kind, obj, listeners.Items, now)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !orphaned {
+			continue
+		}
+
+		log := c.Log.WithValues("kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+
+		log.Info("Deleting orphaned resource")
+		if err := c.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete orphaned resource")
+			lastErr = fmt.Errorf("failed to delete %s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+			continue
+		}
+		reclaimed[kind]++
+	}
+
+	return reclaimed, lastErr
+}
+
+// orphaned returns true when the listener or the ephemeral runner the object was created for no longer exists.
+// It returns false for the objects not created for any.
+func (c *OrphanedResourceCollector) orphaned(ctx context.Context, kind string, obj client.Object, listeners []v1alpha1.AutoscalingListener, now time.Time) (bool, error) {
+	if !obj.GetDeletionTimestamp().IsZero() || now.Sub(obj.GetCreationTimestamp().Time) < orphanedResourceMinAge {
+		return false, nil
+	}
+
+	// The service accounts and secrets of the listeners are in the namespace of the controller,
+	// and the roles and role bindings in the namespaces of their scale sets.
+	inListenerNamespace := kind == "Role" || kind == "RoleBinding" || obj.GetNamespace() == c.ControllerNamespace
+
+	labels := obj.GetLabels()
+	switch {
+	case labels[labelKeyListenerName] != "":
+		if !inListenerNamespace {
+			return false, nil
+		}
+		key := types.NamespacedName{Namespace: labels[labelKeyListenerNamespace], Name: labels[labelKeyListenerName]}
+		return c.notFound(ctx, key, new(v1alpha1.AutoscalingListener))
+	case labels[labelKeyEphemeralRunnerName] != "":
+		key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: labels[labelKeyEphemeralRunnerName]}
+		return c.notFound(ctx, key, new(v1alpha1.EphemeralRunner))
+	case labels[labelKeyRunnerPod] != "":
+		// The secrets the container hooks create for the runner pods of the kubernetes container mode
+		key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: labels[labelKeyRunnerPod]}
+		return c.notFound(ctx, key, new(v1alpha1.EphemeralRunner))
+	}
+
+	if ref := metav1.GetControllerOf(obj); ref != nil {
+		return c.ownerNotFound(ctx, obj.GetNamespace(), ref)
+	}
+
+	if !inListenerNamespace {
+		return false, nil
+	}
+
+	return c.unlabeledListenerResourceOrphaned(ctx, kind, obj, listeners)
+}
+
+// ownerNotFound returns true when the controller owner of an object is an AutoscalingRunnerSet, an EphemeralRunnerSet,
+// an EphemeralRunner or an AutoscalingListener that no longer exists, or that was replaced by another one of the same name.
+func (c *OrphanedResourceCollector) ownerNotFound(ctx context.Context, namespace string, ref *metav1.OwnerReference) (bool, error) {
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	if gvk.GroupVersion() != v1alpha1.GroupVersion {
+		return false, nil
+	}
+
+	var owner client.Object
+	switch ref.Kind {
+	case "AutoscalingRunnerSet":
+		owner = new(v1alpha1.AutoscalingRunnerSet)
+	case "EphemeralRunnerSet":
+		owner = new(v1alpha1.EphemeralRunnerSet)
+	case "EphemeralRunner":
+		owner = new(v1alpha1.EphemeralRunner)
+	case "AutoscalingListener":
+		owner = new(v1alpha1.AutoscalingListener)
+	default:
+		return false, nil
+	}
+
+	notFound, err := c.notFound(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, owner)
+	if err != nil || notFound {
+		return notFound, err
+	}
+
+	return owner.GetUID() != ref.UID, nil
+}
+
+// unlabeledListenerResourceOrphaned matches the unlabeled listener resources by the default names the controller gives them,
+// which embed the name of the scale set and a hash of its namespace.
+// A role or a role binding is orphaned when its scale set no longer exists,
+// and a service account or a secret when no listener is left for its scale set.
+func (c *OrphanedResourceCollector) unlabeledListenerResourceOrphaned(ctx context.Context, kind string, obj client.Object, listeners []v1alpha1.AutoscalingListener) (bool, error) {
+	switch kind {
+	case "Role", "RoleBinding":
+		scaleSetName, ok := strings.CutSuffix(obj.GetName(), fmt.Sprintf("-%s-listener", shortNamespaceHash(obj.GetNamespace())))
+		if !ok || scaleSetName == "" {
+			return false, nil
+		}
+		key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: scaleSetName}
+		return c.notFound(ctx, key, new(v1alpha1.AutoscalingRunnerSet))
+	default:
+		name, ok := strings.CutSuffix(obj.GetName(), "-listener")
+		if !ok {
+			if name, ok = strings.CutSuffix(obj.GetName(), "-listener-proxy"); !ok {
+				return false, nil
+			}
+		}
+		i := strings.LastIndex(name, "-")
+		if i <= 0 {
+			return false, nil
+		}
+		scaleSetName, namespaceHash := name[:i], name[i+1:]
+
+		for _, l := range listeners {
+			if l.Spec.AutoscalingRunnerSetName == scaleSetName && shortNamespaceHash(l.Spec.AutoscalingRunnerSetNamespace) == namespaceHash {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// notFound gets the object by key into obj, and returns true when it doesn't exist.
+func (c *OrphanedResourceCollector) notFound(ctx context.Context, key types.NamespacedName, obj client.Object) (bool, error) {
+	if err := c.Get(ctx, key, obj); err != nil {
+		if kerrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get %T %s: %w", obj, key, err)
+	}
+
+	return false, nil
+}
+
+// shortNamespaceHash is the hash of the namespace embedded into the default names of the listener resources.
+func shortNamespaceHash(namespace string) string {
+	namespaceHash := hash.FNVHashString(namespace)
+	if len(namespaceHash) > 8 {
+		namespaceHash = namespaceHash[:8]
+	}
+	return namespaceHash
+}
@@ -0,0 +1,134 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestOrphanedResourceCollector(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	live := &v1alpha1.AutoscalingListener{
+		ObjectMeta: metav1.ObjectMeta{Name: "live-listener", Namespace: "arc-system", UID: "live-listener-uid"},
+		Spec:       v1alpha1.AutoscalingListenerSpec{AutoscalingRunnerSetName: "live-set", AutoscalingRunnerSetNamespace: "test-ns"},
+	}
+	deleted := &v1alpha1.AutoscalingListener{ObjectMeta: metav1.ObjectMeta{Name: "deleted-listener", Namespace: "arc-system"}}
+
+	meta := func(name, namespace string, listener *v1alpha1.AutoscalingListener, age time.Duration) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			Labels:            listenerOwnershipLabels(listener),
+			CreationTimestamp: metav1.NewTime(now.Add(-age)),
+		}
+	}
+
+	liveSet := &v1alpha1.AutoscalingRunnerSet{ObjectMeta: metav1.ObjectMeta{Name: "live-set", Namespace: "test-ns"}}
+	liveRunner := &v1alpha1.EphemeralRunner{ObjectMeta: metav1.ObjectMeta{Name: "live-runner", Namespace: "test-ns"}}
+
+	labeled := func(m metav1.ObjectMeta, key, value string) metav1.ObjectMeta {
+		m.Labels = map[string]string{key: value}
+		return m
+	}
+	unlabeled := func(name, namespace string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}
+	}
+	ownedBy := func(m metav1.ObjectMeta, kind, name string, uid types.UID) metav1.ObjectMeta {
+		controller := true
+		m.OwnerReferences = []metav1.OwnerReference{{APIVersion: v1alpha1.GroupVersion.String(), Kind: kind, Name: name, UID: uid, Controller: &controller}}
+		return m
+	}
+	testNSHash := shortNamespaceHash("test-ns")
+	otherNSHash := shortNamespaceHash("other-ns")
+
+	objects := []client.Object{
+		live,
+		liveSet,
+		liveRunner,
+		&rbacv1.Role{ObjectMeta: meta("live-role", "test-ns", live, time.Hour)},
+		&rbacv1.RoleBinding{ObjectMeta: meta("live-role", "test-ns", live, time.Hour)},
+		&corev1.ServiceAccount{ObjectMeta: meta("live-sa", "arc-system", live, time.Hour)},
+		&corev1.Secret{ObjectMeta: meta("live-config", "arc-system", live, time.Hour)},
+
+		&rbacv1.Role{ObjectMeta: meta("orphaned-role", "test-ns", deleted, time.Hour)},
+		&rbacv1.RoleBinding{ObjectMeta: meta("orphaned-role", "test-ns", deleted, time.Hour)},
+		&corev1.ServiceAccount{ObjectMeta: meta("orphaned-sa", "arc-system", deleted, time.Hour)},
+		&corev1.Secret{ObjectMeta: meta("orphaned-config", "arc-system", deleted, time.Hour)},
+		&corev1.Secret{ObjectMeta: meta("orphaned-proxy", "arc-system", deleted, time.Hour)},
+
+		// Secrets of ephemeral runners
+		&corev1.Secret{ObjectMeta: labeled(unlabeled("live-runner", "test-ns"), labelKeyEphemeralRunnerName, "live-runner")},
+		&corev1.Secret{ObjectMeta: labeled(unlabeled("orphaned-runner", "test-ns"), labelKeyEphemeralRunnerName, "deleted-runner")},
+		&corev1.Secret{ObjectMeta: labeled(unlabeled("live-runner-hook", "test-ns"), labelKeyRunnerPod, "live-runner")},
+		&corev1.Secret{ObjectMeta: labeled(unlabeled("orphaned-runner-hook", "test-ns"), labelKeyRunnerPod, "deleted-runner")},
+
+		// Unlabeled resources tracked by their owner references
+		&corev1.Secret{ObjectMeta: ownedBy(unlabeled("live-listener-config", "arc-system"), "AutoscalingListener", "live-listener", "live-listener-uid")},
+		&corev1.Secret{ObjectMeta: ownedBy(unlabeled("orphaned-listener-config", "arc-system"), "AutoscalingListener", "deleted-listener", "deleted-listener-uid")},
+		// Owned by a listener replaced by another one of the same name
+		&corev1.Secret{ObjectMeta: ownedBy(unlabeled("orphaned-replaced-listener-config", "arc-system"), "AutoscalingListener", "live-listener", "replaced-listener-uid")},
+		&corev1.Secret{ObjectMeta: ownedBy(unlabeled("orphaned-jit-config", "test-ns"), "EphemeralRunner", "deleted-runner", "deleted-runner-uid")},
+
+		// Unlabeled resources tracked by their default names
+		&rbacv1.Role{ObjectMeta: unlabeled("live-set-"+testNSHash+"-listener", "test-ns")},
+		&rbacv1.RoleBinding{ObjectMeta: unlabeled("live-set-"+testNSHash+"-listener", "test-ns")},
+		&rbacv1.Role{ObjectMeta: unlabeled("orphaned-set-"+testNSHash+"-listener", "test-ns")},
+		&rbacv1.RoleBinding{ObjectMeta: unlabeled("orphaned-set-"+testNSHash+"-listener", "test-ns")},
+		&corev1.ServiceAccount{ObjectMeta: unlabeled("live-set-"+testNSHash+"-listener", "arc-system")},
+		&corev1.Secret{ObjectMeta: unlabeled("live-set-"+testNSHash+"-listener-proxy", "arc-system")},
+		&corev1.ServiceAccount{ObjectMeta: unlabeled("orphaned-set-"+testNSHash+"-listener", "arc-system")},
+		// The scale set of the same name in another namespace has no listener
+		&corev1.Secret{ObjectMeta: unlabeled("orphaned-live-set-"+otherNSHash+"-listener", "arc-system")},
+		// Not named like a listener resource
+		&rbacv1.Role{ObjectMeta: unlabeled("runner-role", "test-ns")},
+		// Out of the namespace of the listeners
+		&corev1.ServiceAccount{ObjectMeta: unlabeled("other-set-"+otherNSHash+"-listener", "other-ns")},
+
+		// Too young to tell whether its listener is just not in the cache yet
+		&corev1.Secret{ObjectMeta: meta("new-config", "arc-system", deleted, time.Minute)},
+		// Not created for a listener
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "github-config", Namespace: "arc-system", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}},
+		// Out of the namespace of the listeners
+		&corev1.Secret{ObjectMeta: meta("other-config", "other-ns", deleted, time.Hour)},
+	}
+
+	c := &OrphanedResourceCollector{
+		Client:              crfake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		Log:                 logr.Discard(),
+		ControllerNamespace: "arc-system",
+	}
+
+	reclaimed, err := c.collect(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"Role": 2, "RoleBinding": 2, "ServiceAccount": 2, "Secret": 8}, reclaimed)
+
+	for _, obj := range objects {
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if strings.HasPrefix(obj.GetName(), "orphaned-") {
+			assert.True(t, kerrors.IsNotFound(err), "%s must be deleted", obj.GetName())
+			continue
+		}
+		assert.NoError(t, err, "%s must be kept", obj.GetName())
+	}
+}
@@ -0,0 +1,55 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+)
+
+// RequestCoordinatorServer serves the request coordinator of the controller to the listeners,
+// so that their requests count towards the same per-credentials rate limit as the requests of the controller.
+type RequestCoordinatorServer struct {
+	Addr        string
+	Coordinator *actions.RequestCoordinator
+	Log         logr.Logger
+}
+
+func (s *RequestCoordinatorServer) Start(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.Addr,
+		Handler:           s.Coordinator,
+		ReadHeaderTimeout: 10 * time.Second,
+		// The waiting requests are released on shutdown rather than holding it up
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		<-ctx.Done()
+
+		srv.Shutdown(context.Background())
+	}()
+
+	s.Log.Info("Starting request coordinator server", "addr", s.Addr)
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	<-done
+
+	return nil
+}
+
+// NeedLeaderElection makes only the leader serve its coordinator, as it is the one making the requests of the controller.
+// The listeners make their requests without waiting while they can't reach it.
+func (s *RequestCoordinatorServer) NeedLeaderElection() bool {
+	return true
+}
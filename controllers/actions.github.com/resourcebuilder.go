@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"math"
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/build"
@@ -16,9 +18,12 @@ import (
 	"github.com/actions/actions-runner-controller/github/actions"
 	"github.com/actions/actions-runner-controller/hash"
 	"github.com/actions/actions-runner-controller/logging"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 // secret constants
@@ -71,6 +76,49 @@ func SetListenerEntrypoint(entrypoint string) {
 
 type ResourceBuilder struct {
 	ExcludeLabelPropagationPrefixes []string
+
+	// GitHubAPIProxyURL is the URL of the GitHub API proxy the listeners send their GitHub API requests through, if any.
+	GitHubAPIProxyURL string
+
+	// GitHubAPIProxyCACert is the PEM bundle of the CAs the listeners pin the certificate of the GitHub API proxy to.
+	GitHubAPIProxyCACert string
+
+	// NamingPolicy customizes the names and labels of the objects, if any.
+	NamingPolicy *NamingPolicy
+
+	// RequestCoordinatorURL is the URL of the request coordinator of the controller the listeners pace their requests with, if any.
+	RequestCoordinatorURL string
+}
+
+// maxRunners returns the maxRunners of the scale set, which is raised by the JobQueueLatencySLO while it is burning.
+func maxRunners(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) int {
+	maxRunners := math.MaxInt32
+	if autoscalingRunnerSet.Spec.MaxRunners != nil {
+		maxRunners = *autoscalingRunnerSet.Spec.MaxRunners
+	}
+
+	if slo := autoscalingRunnerSet.Spec.JobQueueLatencySLO; slo != nil && slo.MaxRunnersWhileBurning != nil && jobQueueLatencySLOBurning(autoscalingRunnerSet) {
+		maxRunners = max(maxRunners, *slo.MaxRunnersWhileBurning)
+	}
+
+	return maxRunners
+}
+
+// listenerMaxRunners returns the maxRunners of the listener of the scale set.
+// When the JobQueueLatencySLO can raise maxRunners, the listener scales up to the raised maxRunners at all times,
+// and the EphemeralRunnerSet caps its replicas to maxRunners instead, so that the listener isn't recreated
+// whenever the SLO starts or stops burning.
+func listenerMaxRunners(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) int {
+	maxRunners := math.MaxInt32
+	if autoscalingRunnerSet.Spec.MaxRunners != nil {
+		maxRunners = *autoscalingRunnerSet.Spec.MaxRunners
+	}
+
+	if slo := autoscalingRunnerSet.Spec.JobQueueLatencySLO; slo != nil && slo.MaxRunnersWhileBurning != nil {
+		maxRunners = max(maxRunners, *slo.MaxRunnersWhileBurning)
+	}
+
+	return maxRunners
 }
 
 func (b *ResourceBuilder) newAutoScalingListener(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet, namespace, image string, imagePullSecrets []corev1.LocalObjectReference) (*v1alpha1.AutoscalingListener, error) {
@@ -79,13 +127,12 @@ func (b *ResourceBuilder) newAutoScalingListener(autoscalingRunnerSet *v1alpha1.
 		return nil, err
 	}
 
-	effectiveMinRunners := 0
-	effectiveMaxRunners := math.MaxInt32
-	if autoscalingRunnerSet.Spec.MaxRunners != nil {
-		effectiveMaxRunners = *autoscalingRunnerSet.Spec.MaxRunners
-	}
-	if autoscalingRunnerSet.Spec.MinRunners != nil {
-		effectiveMinRunners = *autoscalingRunnerSet.Spec.MinRunners
+	effectiveMinRunners := listenerMinRunners(autoscalingRunnerSet)
+	effectiveMaxRunners := listenerMaxRunners(autoscalingRunnerSet)
+
+	shards := 1
+	if autoscalingRunnerSet.Spec.ListenerShards != nil {
+		shards = *autoscalingRunnerSet.Spec.ListenerShards
 	}
 
 	labels := b.mergeLabels(autoscalingRunnerSet.Labels, map[string]string{
@@ -99,15 +146,25 @@ func (b *ResourceBuilder) newAutoScalingListener(autoscalingRunnerSet *v1alpha1.
 	annotations := map[string]string{
 		annotationKeyRunnerSpecHash: autoscalingRunnerSet.ListenerSpecHash(),
 		annotationKeyValuesHash:     autoscalingRunnerSet.Annotations[annotationKeyValuesHash],
+
+		AnnotationKeyGitHubRunnerGroupName: autoscalingRunnerSet.Annotations[AnnotationKeyGitHubRunnerGroupName],
+	}
+	if policyHash := b.NamingPolicy.Hash(); policyHash != "" {
+		annotations[annotationKeyNamingPolicyHash] = policyHash
 	}
 
 	if err := applyGitHubURLLabels(autoscalingRunnerSet.Spec.GitHubConfigUrl, labels); err != nil {
 		return nil, fmt.Errorf("failed to apply GitHub URL labels: %v", err)
 	}
 
+	allowedRepositories, err := qualifiedAllowedRepositories(autoscalingRunnerSet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allowed repositories: %w", err)
+	}
+
 	autoscalingListener := &v1alpha1.AutoscalingListener{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        scaleSetListenerName(autoscalingRunnerSet),
+			Name:        b.scaleSetListenerName(autoscalingRunnerSet),
 			Namespace:   namespace,
 			Labels:      labels,
 			Annotations: annotations,
@@ -126,6 +183,10 @@ func (b *ResourceBuilder) newAutoScalingListener(autoscalingRunnerSet *v1alpha1.
 			Proxy:                         autoscalingRunnerSet.Spec.Proxy,
 			GitHubServerTLS:               autoscalingRunnerSet.Spec.GitHubServerTLS,
 			Template:                      autoscalingRunnerSet.Spec.ListenerTemplate,
+			AllowedRepositories:           allowedRepositories,
+			AdmissionWindows:              autoscalingRunnerSet.Spec.AdmissionWindows,
+			OutsideAdmissionWindows:       autoscalingRunnerSet.Spec.OutsideAdmissionWindows,
+			Shards:                        shards,
 		},
 	}
 
@@ -153,7 +214,14 @@ func (lm *listenerMetricsServerConfig) containerPort() (corev1.ContainerPort, er
 	}, nil
 }
 
-func (b *ResourceBuilder) newScaleSetListenerConfig(autoscalingListener *v1alpha1.AutoscalingListener, secret *corev1.Secret, metricsConfig *listenerMetricsServerConfig, cert string) (*corev1.Secret, error) {
+// listenerServerTLS is the TLS configuration of the connections of a listener to GitHub, in PEM.
+type listenerServerTLS struct {
+	rootCA     string
+	clientCert string
+	clientKey  string
+}
+
+func (b *ResourceBuilder) newScaleSetListenerConfig(autoscalingListener *v1alpha1.AutoscalingListener, shard int, secret *corev1.Secret, metricsConfig *listenerMetricsServerConfig, serverTLS listenerServerTLS, shareAdminToken bool) (*corev1.Secret, error) {
 	var (
 		metricsAddr     = ""
 		metricsEndpoint = ""
@@ -193,11 +261,30 @@ func (b *ResourceBuilder) newScaleSetListenerConfig(autoscalingListener *v1alpha
 		MinRunners:                  autoscalingListener.Spec.MinRunners,
 		RunnerScaleSetId:            autoscalingListener.Spec.RunnerScaleSetId,
 		RunnerScaleSetName:          autoscalingListener.Spec.AutoscalingRunnerSetName,
-		ServerRootCA:                cert,
+		RunnerGroup:                 autoscalingListener.Annotations[AnnotationKeyGitHubRunnerGroupName],
+		ServerRootCA:                serverTLS.rootCA,
+		ServerClientCertificate:     serverTLS.clientCert,
+		ServerClientKey:             serverTLS.clientKey,
 		LogLevel:                    scaleSetListenerLogLevel,
 		LogFormat:                   scaleSetListenerLogFormat,
 		MetricsAddr:                 metricsAddr,
 		MetricsEndpoint:             metricsEndpoint,
+		GitHubAPIProxyURL:           b.GitHubAPIProxyURL,
+		GitHubAPIProxyCACert:        b.GitHubAPIProxyCACert,
+		RequestCoordinatorURL:       b.RequestCoordinatorURL,
+		AllowedRepositories:         autoscalingListener.Spec.AllowedRepositories,
+		AdmissionWindows:            autoscalingListener.Spec.AdmissionWindows,
+		OutsideAdmissionWindows:     autoscalingListener.Spec.OutsideAdmissionWindows,
+		SessionSecretName:           b.scaleSetListenerSessionSecretName(autoscalingListener),
+	}
+
+	if autoscalingListener.Spec.Shards > 1 {
+		config.ShardIndex = shard
+		config.ShardCount = autoscalingListener.Spec.Shards
+	}
+
+	if shareAdminToken {
+		config.AdminTokenPath = listenerAdminTokenMountPath + "/" + listenerAdminTokenKey
 	}
 
 	var buf bytes.Buffer
@@ -207,8 +294,9 @@ func (b *ResourceBuilder) newScaleSetListenerConfig(autoscalingListener *v1alpha
 
 	return &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      scaleSetListenerConfigName(autoscalingListener),
+			Name:      scaleSetListenerShardConfigName(autoscalingListener, shard),
 			Namespace: autoscalingListener.Namespace,
+			Labels:    b.NamingPolicy.applyRequiredLabels(listenerOwnershipLabels(autoscalingListener)),
 		},
 		Data: map[string][]byte{
 			"config.json": buf.Bytes(),
@@ -216,7 +304,34 @@ func (b *ResourceBuilder) newScaleSetListenerConfig(autoscalingListener *v1alpha
 	}, nil
 }
 
-func (b *ResourceBuilder) newScaleSetListenerPod(autoscalingListener *v1alpha1.AutoscalingListener, podConfig *corev1.Secret, serviceAccount *corev1.ServiceAccount, secret *corev1.Secret, metricsConfig *listenerMetricsServerConfig, envs ...corev1.EnvVar) (*corev1.Pod, error) {
+func (b *ResourceBuilder) newScaleSetListenerAdminTokenSecret(autoscalingListener *v1alpha1.AutoscalingListener, token []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      scaleSetListenerAdminTokenName(autoscalingListener),
+			Namespace: autoscalingListener.Namespace,
+			Labels:    b.NamingPolicy.applyRequiredLabels(listenerOwnershipLabels(autoscalingListener)),
+		},
+		Data: map[string][]byte{
+			listenerAdminTokenKey: token,
+		},
+	}
+}
+
+// newScaleSetListenerSessionSecret builds the secret the listener hands its message session off through.
+func (b *ResourceBuilder) newScaleSetListenerSessionSecret(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, autoscalingListener *v1alpha1.AutoscalingListener) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.scaleSetListenerSessionSecretName(autoscalingListener),
+			Namespace: autoscalingRunnerSet.Namespace,
+			Labels: b.NamingPolicy.applyRequiredLabels(map[string]string{
+				LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
+				LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
+			}),
+		},
+	}
+}
+
+func (b *ResourceBuilder) newScaleSetListenerPod(autoscalingListener *v1alpha1.AutoscalingListener, shard int, podConfig *corev1.Secret, serviceAccount *corev1.ServiceAccount, secret *corev1.Secret, metricsConfig *listenerMetricsServerConfig, shareAdminToken bool, envs ...corev1.EnvVar) (*corev1.Pod, error) {
 	listenerEnv := []corev1.EnvVar{
 		{
 			Name:  "LISTENER_CONFIG_PATH",
@@ -270,6 +385,26 @@ func (b *ResourceBuilder) newScaleSetListenerPod(autoscalingListener *v1alpha1.A
 		TerminationGracePeriodSeconds: &terminationGracePeriodSeconds,
 	}
 
+	if shareAdminToken {
+		// The secret is optional so that the listener can start, minting its own token,
+		// before the controller managed to share one.
+		optional := true
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: listenerAdminTokenVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: scaleSetListenerAdminTokenName(autoscalingListener),
+					Optional:   &optional,
+				},
+			},
+		})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      listenerAdminTokenVolumeName,
+			MountPath: listenerAdminTokenMountPath,
+			ReadOnly:  true,
+		})
+	}
+
 	labels := make(map[string]string, len(autoscalingListener.Labels))
 	for key, val := range autoscalingListener.Labels {
 		labels[key] = val
@@ -281,7 +416,7 @@ func (b *ResourceBuilder) newScaleSetListenerPod(autoscalingListener *v1alpha1.A
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      autoscalingListener.Name,
+			Name:      scaleSetListenerShardPodName(autoscalingListener, shard),
 			Namespace: autoscalingListener.Namespace,
 			Labels:    labels,
 		},
@@ -412,22 +547,24 @@ func mergeListenerContainer(base, from *corev1.Container) {
 func (b *ResourceBuilder) newScaleSetListenerServiceAccount(autoscalingListener *v1alpha1.AutoscalingListener) *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      scaleSetListenerServiceAccountName(autoscalingListener),
+			Name:      b.scaleSetListenerServiceAccountName(autoscalingListener),
 			Namespace: autoscalingListener.Namespace,
 			Labels: b.mergeLabels(autoscalingListener.Labels, map[string]string{
 				LabelKeyGitHubScaleSetNamespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace,
 				LabelKeyGitHubScaleSetName:      autoscalingListener.Spec.AutoscalingRunnerSetName,
+				labelKeyListenerNamespace:       autoscalingListener.Namespace,
+				labelKeyListenerName:            autoscalingListener.Name,
 			}),
 		},
 	}
 }
 
 func (b *ResourceBuilder) newScaleSetListenerRole(autoscalingListener *v1alpha1.AutoscalingListener) *rbacv1.Role {
-	rules := rulesForListenerRole([]string{autoscalingListener.Spec.EphemeralRunnerSetName})
+	rules := rulesForListenerRole([]string{autoscalingListener.Spec.EphemeralRunnerSetName}, b.scaleSetListenerSessionSecretName(autoscalingListener))
 	rulesHash := hash.ComputeTemplateHash(&rules)
 	newRole := &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      scaleSetListenerRoleName(autoscalingListener),
+			Name:      b.scaleSetListenerRoleName(autoscalingListener),
 			Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace,
 			Labels: b.mergeLabels(autoscalingListener.Labels, map[string]string{
 				LabelKeyGitHubScaleSetNamespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace,
@@ -461,7 +598,7 @@ func (b *ResourceBuilder) newScaleSetListenerRoleBinding(autoscalingListener *v1
 
 	newRoleBinding := &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      scaleSetListenerRoleName(autoscalingListener),
+			Name:      b.scaleSetListenerRoleName(autoscalingListener),
 			Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace,
 			Labels: b.mergeLabels(autoscalingListener.Labels, map[string]string{
 				LabelKeyGitHubScaleSetNamespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace,
@@ -484,11 +621,13 @@ func (b *ResourceBuilder) newScaleSetListenerSecretMirror(autoscalingListener *v
 
 	newListenerSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      scaleSetListenerSecretMirrorName(autoscalingListener),
+			Name:      b.scaleSetListenerSecretMirrorName(autoscalingListener),
 			Namespace: autoscalingListener.Namespace,
 			Labels: b.mergeLabels(autoscalingListener.Labels, map[string]string{
 				LabelKeyGitHubScaleSetNamespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace,
 				LabelKeyGitHubScaleSetName:      autoscalingListener.Spec.AutoscalingRunnerSetName,
+				labelKeyListenerNamespace:       autoscalingListener.Namespace,
+				labelKeyListenerName:            autoscalingListener.Name,
 				"secret-data-hash":              dataHash,
 			}),
 		},
@@ -526,7 +665,7 @@ func (b *ResourceBuilder) newEphemeralRunnerSet(autoscalingRunnerSet *v1alpha1.A
 	newEphemeralRunnerSet := &v1alpha1.EphemeralRunnerSet{
 		TypeMeta: metav1.TypeMeta{},
 		ObjectMeta: metav1.ObjectMeta{
-			GenerateName: autoscalingRunnerSet.ObjectMeta.Name + "-",
+			GenerateName: b.NamingPolicy.generatedName(namingKindEphemeralRunnerSet, autoscalingRunnerSet.ObjectMeta.Name, autoscalingRunnerSet.Name, autoscalingRunnerSet.Namespace) + "-",
 			Namespace:    autoscalingRunnerSet.ObjectMeta.Namespace,
 			Labels:       labels,
 			Annotations:  newAnnotations,
@@ -536,17 +675,341 @@ func (b *ResourceBuilder) newEphemeralRunnerSet(autoscalingRunnerSet *v1alpha1.A
 			EphemeralRunnerSpec: v1alpha1.EphemeralRunnerSpec{
 				RunnerScaleSetId:   runnerScaleSetId,
 				GitHubConfigUrl:    autoscalingRunnerSet.Spec.GitHubConfigUrl,
-				GitHubConfigSecret: autoscalingRunnerSet.Spec.GitHubConfigSecret,
+				GitHubConfigSecret: autoscalingRunnerSet.RunnerGitHubConfigSecret(),
 				Proxy:              autoscalingRunnerSet.Spec.Proxy,
 				GitHubServerTLS:    autoscalingRunnerSet.Spec.GitHubServerTLS,
-				PodTemplateSpec:    autoscalingRunnerSet.Spec.Template,
+				PodTemplateSpec:    autoscalingRunnerSet.RunnerTemplate(),
+				WorkVolume:         autoscalingRunnerSet.Spec.WorkVolume,
 			},
+			MaxReplicas: ephemeralRunnerSetMaxReplicas(autoscalingRunnerSet),
 		},
 	}
+	// An invalid minRunnersSchedule is reported by the AutoscalingRunnerSet controller
+	newEphemeralRunnerSet.Spec.AdditionalReplicas, _, _ = ephemeralRunnerSetAdditionalReplicas(autoscalingRunnerSet, time.Now())
+	newEphemeralRunnerSet.Spec.ContainerHooks, _ = compatibleContainerHooks(autoscalingRunnerSet.Spec.ContainerHooks, runnerContainerImage(autoscalingRunnerSet.RunnerTemplate()))
 
 	return newEphemeralRunnerSet, nil
 }
 
+// newJobTemplateAutoscalingRunnerSet builds the AutoscalingRunnerSet of the job template, with its own runner scale set
+// labeled with the labels of the job template. It shares the settings of the AutoscalingRunnerSet, except the ones
+// sizing its runners, which apply to the pod template of the AutoscalingRunnerSet only.
+func (b *ResourceBuilder) newJobTemplateAutoscalingRunnerSet(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, template *v1alpha1.JobTemplate) *v1alpha1.AutoscalingRunnerSet {
+	// The labels aren't filtered by ExcludeLabelPropagationPrefixes, as the AutoscalingRunnerSet must keep the version label
+	labels := maps.Clone(autoscalingRunnerSet.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[LabelKeyGitHubScaleSetName] = autoscalingRunnerSet.JobTemplateName(template)
+	labels[LabelKeyJobTemplate] = template.Name
+	labels[LabelKeyJobTemplateOf] = autoscalingRunnerSet.Name
+
+	spec := autoscalingRunnerSet.Spec.DeepCopy()
+	spec.RunnerScaleSetName = scaleSetNameOf(autoscalingRunnerSet) + "-" + template.Name
+	spec.RunnerScaleSetLabels = template.Labels
+	spec.Template = *template.Template.DeepCopy()
+	spec.JobTemplates = nil
+	spec.MinRunners = template.MinRunners
+	if template.MaxRunners != nil {
+		spec.MaxRunners = template.MaxRunners
+	}
+	spec.MinRunnersSchedule = nil
+	spec.JobQueueLatencySLO = nil
+	spec.Canary = nil
+	spec.Placeholders = nil
+	spec.WarmPool = nil
+	spec.ListenerShards = nil
+
+	return &v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      autoscalingRunnerSet.JobTemplateName(template),
+			Namespace: autoscalingRunnerSet.Namespace,
+			Labels:    labels,
+		},
+		Spec: *spec,
+	}
+}
+
+// newEgressPolicy builds the egress policy resource of the scale set from spec.egressPolicy.template.
+// The namespace is left for the caller to clear when the resource turns out to be cluster-scoped.
+func (b *ResourceBuilder) newEgressPolicy(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) (*unstructured.Unstructured, error) {
+	policy := &unstructured.Unstructured{}
+	if err := policy.UnmarshalJSON(autoscalingRunnerSet.Spec.EgressPolicy.Template.Raw); err != nil {
+		return nil, fmt.Errorf("failed to parse egress policy template: %v", err)
+	}
+	templateHash := hash.ComputeTemplateHash(policy.Object)
+
+	labels := b.mergeLabels(autoscalingRunnerSet.Labels, map[string]string{
+		LabelKeyKubernetesPartOf:        labelValueKubernetesPartOf,
+		LabelKeyKubernetesComponent:     "egress-policy",
+		LabelKeyKubernetesVersion:       autoscalingRunnerSet.Labels[LabelKeyKubernetesVersion],
+		LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
+		LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
+	})
+	for k, v := range policy.GetLabels() {
+		labels[k] = v
+	}
+
+	annotations := policy.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[annotationKeyValuesHash] = templateHash
+
+	policy.SetName(b.scaleSetEgressPolicyName(autoscalingRunnerSet))
+	policy.SetNamespace(autoscalingRunnerSet.Namespace)
+	policy.SetLabels(labels)
+	policy.SetAnnotations(annotations)
+
+	return policy, nil
+}
+
+// newPlaceholderDeployment builds the deployment of the placeholder pods of the scale set from spec.placeholders.
+// The placeholder pods request the resources of a runner pod and are scheduled like it,
+// so that a node fitting a placeholder pod fits the runner pod preempting it.
+func (b *ResourceBuilder) newPlaceholderDeployment(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, replicas int) *appsv1.Deployment {
+	placeholders := autoscalingRunnerSet.Spec.Placeholders
+	template := autoscalingRunnerSet.RunnerTemplate()
+
+	image := placeholders.Image
+	if image == "" {
+		image = DefaultPlaceholderImage
+	}
+
+	selector := map[string]string{
+		LabelKeyKubernetesComponent:     "runner-placeholder",
+		LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
+		LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
+	}
+
+	labels := b.mergeLabels(autoscalingRunnerSet.Labels, map[string]string{
+		LabelKeyKubernetesPartOf:  labelValueKubernetesPartOf,
+		LabelKeyKubernetesVersion: autoscalingRunnerSet.Labels[LabelKeyKubernetesVersion],
+	})
+	for k, v := range selector {
+		labels[k] = v
+	}
+
+	podLabels := make(map[string]string, len(labels))
+	for k, v := range labels {
+		podLabels[k] = v
+	}
+
+	deploymentReplicas := int32(replicas)
+	automountServiceAccountToken := false
+	terminationGracePeriodSeconds := int64(0)
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.scaleSetPlaceholderName(autoscalingRunnerSet),
+			Namespace: autoscalingRunnerSet.Namespace,
+			Labels:    b.NamingPolicy.applyRequiredLabels(labels),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &deploymentReplicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: b.NamingPolicy.applyRequiredLabels(podLabels),
+				},
+				Spec: corev1.PodSpec{
+					PriorityClassName:             placeholders.PriorityClassName,
+					NodeSelector:                  template.Spec.NodeSelector,
+					Affinity:                      template.Spec.Affinity,
+					Tolerations:                   template.Spec.Tolerations,
+					RuntimeClassName:              template.Spec.RuntimeClassName,
+					ImagePullSecrets:              template.Spec.ImagePullSecrets,
+					AutomountServiceAccountToken:  &automountServiceAccountToken,
+					TerminationGracePeriodSeconds: &terminationGracePeriodSeconds,
+					Containers: []corev1.Container{
+						{
+							Name:  "placeholder",
+							Image: image,
+							Resources: corev1.ResourceRequirements{
+								Requests: podResourceRequests(template.Spec),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// newWarmPoolDeployment builds the deployment of the warm pods of the scale set, for the runner pods of the ephemeral runner set.
+// A warm pod pulls each image of the runner pod template with an init container, and then holds the resources requested
+// by a runner pod on its node with a pause container, until a runner of the ephemeral runner set takes its place.
+func (b *ResourceBuilder) newWarmPoolDeployment(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet, replicas int) *appsv1.Deployment {
+	template := ephemeralRunnerSet.Spec.EphemeralRunnerSpec.PodTemplateSpec
+
+	selector := map[string]string{
+		LabelKeyKubernetesComponent:     labelValueWarmPodAvailable,
+		LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
+		LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
+	}
+
+	labels := b.mergeLabels(autoscalingRunnerSet.Labels, map[string]string{
+		LabelKeyKubernetesPartOf:  labelValueKubernetesPartOf,
+		LabelKeyKubernetesVersion: autoscalingRunnerSet.Labels[LabelKeyKubernetesVersion],
+	})
+	for k, v := range selector {
+		labels[k] = v
+	}
+
+	podLabels := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		podLabels[k] = v
+	}
+	podLabels[labelKeyWarmPoolRunnerSet] = ephemeralRunnerSet.Name
+
+	var pullContainers []corev1.Container
+	pulled := map[string]bool{}
+	for _, c := range append(append([]corev1.Container{}, template.Spec.InitContainers...), template.Spec.Containers...) {
+		if c.Image == "" || pulled[c.Image] {
+			continue
+		}
+		pulled[c.Image] = true
+
+		pullContainers = append(pullContainers, corev1.Container{
+			Name:            fmt.Sprintf("pull-%d", len(pullContainers)),
+			Image:           c.Image,
+			ImagePullPolicy: c.ImagePullPolicy,
+			Command:         []string{"sleep", "0"},
+			SecurityContext: c.SecurityContext,
+		})
+	}
+
+	deploymentReplicas := int32(replicas)
+	automountServiceAccountToken := false
+	terminationGracePeriodSeconds := int64(0)
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.scaleSetWarmPoolName(autoscalingRunnerSet),
+			Namespace: autoscalingRunnerSet.Namespace,
+			Labels:    b.NamingPolicy.applyRequiredLabels(labels),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &deploymentReplicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: b.NamingPolicy.applyRequiredLabels(podLabels),
+				},
+				Spec: corev1.PodSpec{
+					PriorityClassName:             template.Spec.PriorityClassName,
+					NodeSelector:                  template.Spec.NodeSelector,
+					Affinity:                      template.Spec.Affinity,
+					Tolerations:                   template.Spec.Tolerations,
+					RuntimeClassName:              template.Spec.RuntimeClassName,
+					ImagePullSecrets:              template.Spec.ImagePullSecrets,
+					SecurityContext:               template.Spec.SecurityContext,
+					AutomountServiceAccountToken:  &automountServiceAccountToken,
+					TerminationGracePeriodSeconds: &terminationGracePeriodSeconds,
+					InitContainers:                pullContainers,
+					Containers: []corev1.Container{
+						{
+							Name:  "warm",
+							Image: DefaultPlaceholderImage,
+							Resources: corev1.ResourceRequirements{
+								Requests: podResourceRequests(template.Spec),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// newConnectivityProbeJob builds the Job of the connectivity probe of the runner spec of the ephemeral runner set.
+// Its pod is a runner pod of the ephemeral runner set, with the same labels, proxy and mounts,
+// so that it leaves the cluster the way the runners do, but with the probe in place of the runner.
+func (b *ResourceBuilder) newConnectivityProbeJob(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet) *batchv1.Job {
+	probe := autoscalingRunnerSet.Spec.ConnectivityProbe
+	runnerSpec := ephemeralRunnerSet.Spec.EphemeralRunnerSpec
+
+	timeout := int32(defaultConnectivityProbeTimeoutSeconds)
+	if probe.TimeoutSeconds != nil {
+		timeout = *probe.TimeoutSeconds
+	}
+
+	endpoints := probe.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = connectivityProbeEndpoints(runnerSpec.GitHubConfigUrl)
+	}
+
+	command := probe.Command
+	if len(command) == 0 {
+		command = []string{"sh", "-c", strings.ReplaceAll(connectivityProbeScript, "CONNECTIVITY_PROBE_TIMEOUT_SECONDS", strconv.Itoa(int(timeout)))}
+	}
+
+	envs := []corev1.EnvVar{
+		{
+			Name:  envVarConnectivityProbeEndpoints,
+			Value: strings.Join(endpoints, " "),
+		},
+	}
+	if runnerSpec.Proxy != nil {
+		envs = append(envs, proxyEnvs(runnerSpec.Proxy, proxyEphemeralRunnerSetSecretName(ephemeralRunnerSet))...)
+	}
+
+	labels := b.newEphemeralRunner(ephemeralRunnerSet).Labels
+	for k, v := range runnerSpec.PodTemplateSpec.Labels {
+		labels[k] = v
+	}
+	labels[labelKeyConnectivityProbe] = "true"
+
+	podLabels := make(map[string]string, len(labels))
+	for k, v := range labels {
+		podLabels[k] = v
+	}
+
+	podSpec := *runnerSpec.PodTemplateSpec.Spec.DeepCopy()
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	// The sidecars of the runner, like dind, would keep the pod running after the probe exits
+	podSpec.Containers = nil
+	for _, c := range runnerSpec.PodTemplateSpec.Spec.Containers {
+		if c.Name != EphemeralRunnerContainerName {
+			continue
+		}
+
+		c.Command = command
+		c.Args = nil
+		c.Env = append(c.Env, envs...)
+		c.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+		podSpec.Containers = append(podSpec.Containers, c)
+	}
+
+	backoffLimit := int32(0)
+	activeDeadlineSeconds := int64(timeout) + connectivityProbeStartupSeconds
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.scaleSetConnectivityProbeName(autoscalingRunnerSet),
+			Namespace: autoscalingRunnerSet.Namespace,
+			Labels:    b.NamingPolicy.applyRequiredLabels(labels),
+			Annotations: map[string]string{
+				annotationKeyValuesHash: hash.ComputeTemplateHash(&struct {
+					EphemeralRunnerSet string
+					Template           corev1.PodSpec
+				}{ephemeralRunnerSet.Name, podSpec}),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &activeDeadlineSeconds,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      b.NamingPolicy.applyRequiredLabels(podLabels),
+					Annotations: runnerSpec.PodTemplateSpec.Annotations,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
 func (b *ResourceBuilder) newEphemeralRunner(ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet) *v1alpha1.EphemeralRunner {
 	labels := make(map[string]string)
 	for k, v := range ephemeralRunnerSet.Labels {
@@ -639,6 +1102,9 @@ func (b *ResourceBuilder) newEphemeralRunnerPod(ctx context.Context, runner *v1a
 		newPod.Spec.Containers = append(newPod.Spec.Containers, c)
 	}
 
+	applyWorkVolume(&newPod.Spec, runner.Spec.WorkVolume)
+	addJobMetadataVolume(&newPod.Spec)
+
 	return &newPod
 }
 
@@ -647,6 +1113,9 @@ func (b *ResourceBuilder) newEphemeralRunnerJitSecret(ephemeralRunner *v1alpha1.
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      ephemeralRunner.Name,
 			Namespace: ephemeralRunner.Namespace,
+			Labels: b.NamingPolicy.applyRequiredLabels(map[string]string{
+				labelKeyEphemeralRunnerName: ephemeralRunner.Name,
+			}),
 		},
 		Data: map[string][]byte{
 			jitTokenKey: []byte(ephemeralRunner.Status.RunnerJITConfig),
@@ -654,48 +1123,103 @@ func (b *ResourceBuilder) newEphemeralRunnerJitSecret(ephemeralRunner *v1alpha1.
 	}
 }
 
+// listenerOwnershipLabels returns the labels tracking the listener a resource was created for,
+// which let the OrphanedResourceCollector find the resources of deleted listeners.
+func listenerOwnershipLabels(autoscalingListener *v1alpha1.AutoscalingListener) map[string]string {
+	return map[string]string{
+		labelKeyListenerNamespace: autoscalingListener.Namespace,
+		labelKeyListenerName:      autoscalingListener.Name,
+	}
+}
+
 func scaleSetListenerConfigName(autoscalingListener *v1alpha1.AutoscalingListener) string {
 	return fmt.Sprintf("%s-config", autoscalingListener.Name)
 }
 
-func scaleSetListenerName(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) string {
+// scaleSetListenerShardPodName returns the name of the pod of the shard of the listener.
+// The first shard is the listener pod itself.
+func scaleSetListenerShardPodName(autoscalingListener *v1alpha1.AutoscalingListener, shard int) string {
+	if shard == 0 {
+		return autoscalingListener.Name
+	}
+	return fmt.Sprintf("%s-shard-%d", autoscalingListener.Name, shard)
+}
+
+func scaleSetListenerShardConfigName(autoscalingListener *v1alpha1.AutoscalingListener, shard int) string {
+	if shard == 0 {
+		return scaleSetListenerConfigName(autoscalingListener)
+	}
+	return fmt.Sprintf("%s-config", scaleSetListenerShardPodName(autoscalingListener, shard))
+}
+
+func scaleSetListenerAdminTokenName(autoscalingListener *v1alpha1.AutoscalingListener) string {
+	return fmt.Sprintf("%s-admin-token", autoscalingListener.Name)
+}
+
+func (b *ResourceBuilder) scaleSetListenerName(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) string {
+	namespaceHash := hash.FNVHashString(autoscalingRunnerSet.Namespace)
+	if len(namespaceHash) > 8 {
+		namespaceHash = namespaceHash[:8]
+	}
+	return b.NamingPolicy.generatedName(namingKindAutoscalingListener, fmt.Sprintf("%v-%v-listener", autoscalingRunnerSet.Name, namespaceHash), autoscalingRunnerSet.Name, autoscalingRunnerSet.Namespace)
+}
+
+func (b *ResourceBuilder) scaleSetPlaceholderName(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) string {
+	return b.NamingPolicy.generatedName(namingKindPlaceholder, fmt.Sprintf("%v-placeholder", autoscalingRunnerSet.Name), autoscalingRunnerSet.Name, autoscalingRunnerSet.Namespace)
+}
+
+func (b *ResourceBuilder) scaleSetWarmPoolName(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) string {
+	return b.NamingPolicy.generatedName(namingKindWarmPool, fmt.Sprintf("%v-warm-pool", autoscalingRunnerSet.Name), autoscalingRunnerSet.Name, autoscalingRunnerSet.Namespace)
+}
+
+func (b *ResourceBuilder) scaleSetConnectivityProbeName(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) string {
+	return b.NamingPolicy.generatedName(namingKindConnectivityProbe, fmt.Sprintf("%v-connectivity-probe", autoscalingRunnerSet.Name), autoscalingRunnerSet.Name, autoscalingRunnerSet.Namespace)
+}
+
+// scaleSetEgressPolicyName is unique across namespaces, as the egress policy resource can be cluster-scoped.
+func (b *ResourceBuilder) scaleSetEgressPolicyName(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) string {
 	namespaceHash := hash.FNVHashString(autoscalingRunnerSet.Namespace)
 	if len(namespaceHash) > 8 {
 		namespaceHash = namespaceHash[:8]
 	}
-	return fmt.Sprintf("%v-%v-listener", autoscalingRunnerSet.Name, namespaceHash)
+	return b.NamingPolicy.generatedName(namingKindEgressPolicy, fmt.Sprintf("%v-%v-egress", autoscalingRunnerSet.Name, namespaceHash), autoscalingRunnerSet.Name, autoscalingRunnerSet.Namespace)
 }
 
-func scaleSetListenerServiceAccountName(autoscalingListener *v1alpha1.AutoscalingListener) string {
+func (b *ResourceBuilder) scaleSetListenerServiceAccountName(autoscalingListener *v1alpha1.AutoscalingListener) string {
 	namespaceHash := hash.FNVHashString(autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 	if len(namespaceHash) > 8 {
 		namespaceHash = namespaceHash[:8]
 	}
-	return fmt.Sprintf("%v-%v-listener", autoscalingListener.Spec.AutoscalingRunnerSetName, namespaceHash)
+	return b.NamingPolicy.generatedName(namingKindServiceAccount, fmt.Sprintf("%v-%v-listener", autoscalingListener.Spec.AutoscalingRunnerSetName, namespaceHash), autoscalingListener.Spec.AutoscalingRunnerSetName, autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 }
 
-func scaleSetListenerRoleName(autoscalingListener *v1alpha1.AutoscalingListener) string {
+func (b *ResourceBuilder) scaleSetListenerRoleName(autoscalingListener *v1alpha1.AutoscalingListener) string {
 	namespaceHash := hash.FNVHashString(autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 	if len(namespaceHash) > 8 {
 		namespaceHash = namespaceHash[:8]
 	}
-	return fmt.Sprintf("%v-%v-listener", autoscalingListener.Spec.AutoscalingRunnerSetName, namespaceHash)
+	return b.NamingPolicy.generatedName(namingKindRole, fmt.Sprintf("%v-%v-listener", autoscalingListener.Spec.AutoscalingRunnerSetName, namespaceHash), autoscalingListener.Spec.AutoscalingRunnerSetName, autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 }
 
-func scaleSetListenerSecretMirrorName(autoscalingListener *v1alpha1.AutoscalingListener) string {
+func (b *ResourceBuilder) scaleSetListenerSecretMirrorName(autoscalingListener *v1alpha1.AutoscalingListener) string {
 	namespaceHash := hash.FNVHashString(autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 	if len(namespaceHash) > 8 {
 		namespaceHash = namespaceHash[:8]
 	}
-	return fmt.Sprintf("%v-%v-listener", autoscalingListener.Spec.AutoscalingRunnerSetName, namespaceHash)
+	return b.NamingPolicy.generatedName(namingKindSecret, fmt.Sprintf("%v-%v-listener", autoscalingListener.Spec.AutoscalingRunnerSetName, namespaceHash), autoscalingListener.Spec.AutoscalingRunnerSetName, autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
+}
+
+// scaleSetListenerSessionSecretName is the secret in the namespace of the scale set the listener hands its message session off through.
+func (b *ResourceBuilder) scaleSetListenerSessionSecretName(autoscalingListener *v1alpha1.AutoscalingListener) string {
+	return b.NamingPolicy.generatedName(namingKindSecret, fmt.Sprintf("%v-listener-session", autoscalingListener.Spec.AutoscalingRunnerSetName), autoscalingListener.Spec.AutoscalingRunnerSetName, autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 }
 
-func proxyListenerSecretName(autoscalingListener *v1alpha1.AutoscalingListener) string {
+func (b *ResourceBuilder) proxyListenerSecretName(autoscalingListener *v1alpha1.AutoscalingListener) string {
 	namespaceHash := hash.FNVHashString(autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 	if len(namespaceHash) > 8 {
 		namespaceHash = namespaceHash[:8]
 	}
-	return fmt.Sprintf("%v-%v-listener-proxy", autoscalingListener.Spec.AutoscalingRunnerSetName, namespaceHash)
+	return b.NamingPolicy.generatedName(namingKindSecret, fmt.Sprintf("%v-%v-listener-proxy", autoscalingListener.Spec.AutoscalingRunnerSetName, namespaceHash), autoscalingListener.Spec.AutoscalingRunnerSetName, autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 }
 
 func proxyEphemeralRunnerSetSecretName(ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet) string {
@@ -706,19 +1230,25 @@ func proxyEphemeralRunnerSetSecretName(ephemeralRunnerSet *v1alpha1.EphemeralRun
 	return fmt.Sprintf("%v-%v-runner-proxy", ephemeralRunnerSet.Name, namespaceHash)
 }
 
-func rulesForListenerRole(resourceNames []string) []rbacv1.PolicyRule {
+func rulesForListenerRole(resourceNames []string, sessionSecretName string) []rbacv1.PolicyRule {
 	return []rbacv1.PolicyRule{
 		{
 			APIGroups:     []string{"actions.github.com"},
 			Resources:     []string{"ephemeralrunnersets"},
 			ResourceNames: resourceNames,
-			Verbs:         []string{"patch"},
+			Verbs:         []string{"get", "patch"},
 		},
 		{
 			APIGroups: []string{"actions.github.com"},
 			Resources: []string{"ephemeralrunners", "ephemeralrunners/status"},
 			Verbs:     []string{"patch"},
 		},
+		{
+			APIGroups:     []string{""},
+			Resources:     []string{"secrets"},
+			ResourceNames: []string{sessionSecretName},
+			Verbs:         []string{"get", "update"},
+		},
 	}
 }
 
@@ -773,5 +1303,5 @@ overwrite:
 		mergedLabels[k] = v
 	}
 
-	return mergedLabels
+	return b.NamingPolicy.applyRequiredLabels(mergedLabels)
 }
@@ -2,14 +2,17 @@ package actionsgithubcom
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	listenerconfig "github.com/actions/actions-runner-controller/cmd/githubrunnerscalesetlistener/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -87,7 +90,7 @@ func TestLabelPropagation(t *testing.T) {
 			Name: "test",
 		},
 	}
-	listenerPod, err := b.newScaleSetListenerPod(listener, &corev1.Secret{}, listenerServiceAccount, listenerSecret, nil)
+	listenerPod, err := b.newScaleSetListenerPod(listener, 0, &corev1.Secret{}, listenerServiceAccount, listenerSecret, nil, false)
 	require.NoError(t, err)
 	assert.Equal(t, listenerPod.Labels, listener.Labels)
 
@@ -182,3 +185,157 @@ func TestGitHubURLTrimLabelValues(t *testing.T) {
 		assert.Len(t, listener.Labels[LabelKeyGitHubRepository], 0)
 	})
 }
+
+func TestRunnerImageAnnotation(t *testing.T) {
+	autoscalingRunnerSet := v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scale-set",
+			Namespace: "test-ns",
+			Annotations: map[string]string{
+				runnerScaleSetIdAnnotationKey: "1",
+			},
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl: "https://github.com/org/repo",
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: EphemeralRunnerContainerName, Image: "ghcr.io/actions/actions-runner:2.311.0"},
+						{Name: "sidecar", Image: "busybox"},
+					},
+				},
+			},
+		},
+	}
+
+	var b ResourceBuilder
+
+	before, err := b.newEphemeralRunnerSet(&autoscalingRunnerSet)
+	require.NoError(t, err)
+	assert.Equal(t, "ghcr.io/actions/actions-runner:2.311.0", runnerContainerImage(before.Spec.EphemeralRunnerSpec.PodTemplateSpec))
+
+	autoscalingRunnerSet.Annotations[v1alpha1.AnnotationKeyRunnerImage] = "registry.example.com/runner@sha256:abc"
+
+	after, err := b.newEphemeralRunnerSet(&autoscalingRunnerSet)
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/runner@sha256:abc", runnerContainerImage(after.Spec.EphemeralRunnerSpec.PodTemplateSpec))
+	assert.Equal(t, "busybox", after.Spec.EphemeralRunnerSpec.PodTemplateSpec.Spec.Containers[1].Image)
+	assert.NotEqual(t, before.Annotations[annotationKeyRunnerSpecHash], after.Annotations[annotationKeyRunnerSpecHash], "changing the image must roll out a new runner set")
+
+	// The spec itself is left untouched
+	assert.Equal(t, "ghcr.io/actions/actions-runner:2.311.0", autoscalingRunnerSet.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestRunnerGitHubConfigSecret(t *testing.T) {
+	autoscalingRunnerSet := v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scale-set",
+			Namespace: "test-ns",
+			Annotations: map[string]string{
+				runnerScaleSetIdAnnotationKey: "1",
+			},
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl:    "https://github.com/org/repo",
+			GitHubConfigSecret: "admin-secret",
+		},
+	}
+
+	var b ResourceBuilder
+
+	ephemeralRunnerSet, err := b.newEphemeralRunnerSet(&autoscalingRunnerSet)
+	require.NoError(t, err)
+	assert.Equal(t, "admin-secret", ephemeralRunnerSet.Spec.EphemeralRunnerSpec.GitHubConfigSecret)
+	defaultHash := ephemeralRunnerSet.Annotations[annotationKeyRunnerSpecHash]
+
+	autoscalingRunnerSet.Spec.RunnerGitHubConfigSecret = "runner-secret"
+
+	ephemeralRunnerSet, err = b.newEphemeralRunnerSet(&autoscalingRunnerSet)
+	require.NoError(t, err)
+	assert.Equal(t, "runner-secret", ephemeralRunnerSet.Spec.EphemeralRunnerSpec.GitHubConfigSecret)
+	assert.NotEqual(t, defaultHash, ephemeralRunnerSet.Annotations[annotationKeyRunnerSpecHash])
+
+	listener, err := b.newAutoScalingListener(&autoscalingRunnerSet, ephemeralRunnerSet, autoscalingRunnerSet.Namespace, "test:latest", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "admin-secret", listener.Spec.GitHubConfigSecret, "the listener acquires jobs with githubConfigSecret")
+}
+
+func TestListenerSessionSecret(t *testing.T) {
+	autoscalingRunnerSet := v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scale-set",
+			Namespace: "test-ns",
+			Annotations: map[string]string{
+				runnerScaleSetIdAnnotationKey: "1",
+			},
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl: "https://github.com/org/repo",
+		},
+	}
+
+	var b ResourceBuilder
+
+	ephemeralRunnerSet, err := b.newEphemeralRunnerSet(&autoscalingRunnerSet)
+	require.NoError(t, err)
+	ephemeralRunnerSet.Name = "test-runner-set"
+
+	listener, err := b.newAutoScalingListener(&autoscalingRunnerSet, ephemeralRunnerSet, "arc-systems", "test:latest", nil)
+	require.NoError(t, err)
+
+	sessionSecret := b.newScaleSetListenerSessionSecret(&autoscalingRunnerSet, listener)
+	assert.Equal(t, "test-scale-set-listener-session", sessionSecret.Name)
+	assert.Equal(t, "test-ns", sessionSecret.Namespace, "the session secret is in the namespace of the scale set, where the listener role applies")
+	assert.Empty(t, sessionSecret.Data)
+
+	podConfig, err := b.newScaleSetListenerConfig(listener, 0, &corev1.Secret{}, nil, listenerServerTLS{}, false)
+	require.NoError(t, err)
+	var config listenerconfig.Config
+	require.NoError(t, json.Unmarshal(podConfig.Data["config.json"], &config))
+	assert.Equal(t, sessionSecret.Name, config.SessionSecretName)
+
+	role := b.newScaleSetListenerRole(listener)
+	assert.Contains(t, role.Rules, rbacv1.PolicyRule{
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		ResourceNames: []string{sessionSecret.Name},
+		Verbs:         []string{"get", "update"},
+	})
+}
+
+func TestListenerGitHubAPIProxy(t *testing.T) {
+	autoscalingRunnerSet := v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scale-set",
+			Namespace: "test-ns",
+			Annotations: map[string]string{
+				runnerScaleSetIdAnnotationKey: "1",
+			},
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl: "https://github.com/org",
+		},
+	}
+
+	b := ResourceBuilder{
+		GitHubAPIProxyURL:    "https://arc-gha-rs-controller-github-api-proxy.arc-systems.svc:8443",
+		GitHubAPIProxyCACert: "ca",
+	}
+
+	ephemeralRunnerSet, err := b.newEphemeralRunnerSet(&autoscalingRunnerSet)
+	require.NoError(t, err)
+
+	listener, err := b.newAutoScalingListener(&autoscalingRunnerSet, ephemeralRunnerSet, "arc-systems", "test:latest", nil)
+	require.NoError(t, err)
+
+	podConfig, err := b.newScaleSetListenerConfig(listener, 0, &corev1.Secret{}, nil, listenerServerTLS{}, false)
+	require.NoError(t, err)
+	var config listenerconfig.Config
+	require.NoError(t, json.Unmarshal(podConfig.Data["config.json"], &config))
+	assert.Equal(t, "https://arc-gha-rs-controller-github-api-proxy.arc-systems.svc:8443", config.GitHubAPIProxyURL)
+	assert.Equal(t, "ca", config.GitHubAPIProxyCACert)
+
+	pod, err := b.newScaleSetListenerPod(listener, 0, podConfig, &corev1.ServiceAccount{}, &corev1.Secret{}, nil, false)
+	require.NoError(t, err)
+	assert.Len(t, pod.Spec.Containers, 1, "the listener shares the proxy of the controller")
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsgithubcom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	runnerGroupFinalizerName = "runnergroup.actions.github.com/finalizer"
+
+	// runnerGroupResyncInterval is the interval the runner group on GitHub is checked for drift at,
+	// as changes made on GitHub don't trigger reconciliations.
+	runnerGroupResyncInterval = 10 * time.Minute
+
+	reasonRunnerGroupSynced      = "Synced"
+	reasonRunnerGroupSyncFailed  = "SyncFailed"
+	reasonRunnerGroupInvalidSpec = "InvalidSpec"
+	reasonRunnerGroupExists      = "AlreadyExists"
+)
+
+// errRunnerGroupExists is returned when a runner group with the name of the RunnerGroup exists,
+// but was neither created by it nor adopted.
+var errRunnerGroupExists = errors.New("runner group exists")
+
+// RunnerGroupReconciler reconciles a RunnerGroup object
+type RunnerGroupReconciler struct {
+	client.Client
+	Log           logr.Logger
+	Scheme        *runtime.Scheme
+	ActionsClient actions.MultiClient
+}
+
+// +kubebuilder:rbac:groups=actions.github.com,resources=runnergroups,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=actions.github.com,resources=runnergroups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=actions.github.com,resources=runnergroups/finalizers,verbs=update
+
+// Reconcile a RunnerGroup resource so that the runner group on GitHub matches its spec.
+func (r *RunnerGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("runnergroup", req.NamespacedName)
+
+	runnerGroup := new(v1alpha1.RunnerGroup)
+	if err := r.Get(ctx, req.NamespacedName, runnerGroup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !runnerGroup.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(runnerGroup, runnerGroupFinalizerName) {
+			return ctrl.Result{}, nil
+		}
+
+		if err := r.deleteRunnerGroup(ctx, runnerGroup, log); err != nil {
+			log.Error(err, "Failed to delete runner group")
+			return ctrl.Result{}, err
+		}
+
+		log.Info("Removing finalizer")
+		err := patch(ctx, r.Client, runnerGroup, func(obj *v1alpha1.RunnerGroup) {
+			controllerutil.RemoveFinalizer(obj, runnerGroupFinalizerName)
+		})
+		if err != nil && !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to update runner group without finalizer")
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(runnerGroup, runnerGroupFinalizerName) {
+		log.Info("Adding finalizer")
+		if err := patch(ctx, r.Client, runnerGroup, func(obj *v1alpha1.RunnerGroup) {
+			controllerutil.AddFinalizer(obj, runnerGroupFinalizerName)
+		}); err != nil {
+			log.Error(err, "Failed to update runner group with finalizer added")
+			return ctrl.Result{}, err
+		}
+	}
+
+	config, err := actions.ParseGitHubConfigFromURL(runnerGroup.Spec.GitHubConfigUrl)
+	if err != nil || config.Scope == actions.GitHubScopeRepository {
+		log.Info("Runner groups can only be managed for organizations and enterprises", "githubConfigUrl", runnerGroup.Spec.GitHubConfigUrl)
+		return ctrl.Result{}, r.setSynced(ctx, runnerGroup, metav1.ConditionFalse, reasonRunnerGroupInvalidSpec,
+			fmt.Sprintf("%q isn't the URL of an organization or an enterprise", runnerGroup.Spec.GitHubConfigUrl))
+	}
+
+	// The organizations of enterprise runner groups aren't managed, so the runner group would be usable by none
+	if config.Scope == actions.GitHubScopeEnterprise && runnerGroup.RunnerGroupVisibility() == actions.RunnerGroupVisibilitySelected {
+		log.Info("The runner groups of enterprises can't be restricted to selected organizations")
+		return ctrl.Result{}, r.setSynced(ctx, runnerGroup, metav1.ConditionFalse, reasonRunnerGroupInvalidSpec,
+			"The visibility of the runner groups of enterprises can't be selected")
+	}
+
+	actionsClient, err := r.actionsClientFor(ctx, runnerGroup)
+	if err != nil {
+		log.Error(err, "Failed to initialize Actions service client")
+		return ctrl.Result{}, err
+	}
+
+	settings, err := r.syncRunnerGroup(ctx, actionsClient, runnerGroup, config, log)
+	if errors.Is(err, errRunnerGroupExists) {
+		log.Info("Runner group exists and isn't adopted", "name", runnerGroup.RunnerGroupName())
+		return ctrl.Result{}, r.setSynced(ctx, runnerGroup, metav1.ConditionFalse, reasonRunnerGroupExists,
+			fmt.Sprintf("Runner group %q exists, and wasn't created by the RunnerGroup. Set adopt to manage it", runnerGroup.RunnerGroupName()))
+	}
+	if err != nil {
+		log.Error(err, "Failed to sync runner group")
+		if err := r.setSynced(ctx, runnerGroup, metav1.ConditionFalse, reasonRunnerGroupSyncFailed, err.Error()); err != nil {
+			log.Error(err, "Failed to update runner group status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	if settings == nil {
+		return ctrl.Result{}, r.setSynced(ctx, runnerGroup, metav1.ConditionFalse, reasonRunnerGroupInvalidSpec,
+			fmt.Sprintf("%q is the default runner group, which can't be managed", runnerGroup.RunnerGroupName()))
+	}
+
+	now := metav1.Now()
+	if err := patchSubResource(ctx, r.Status(), runnerGroup, func(obj *v1alpha1.RunnerGroup) {
+		obj.Status.ID = settings.ID
+		obj.Status.LastSyncTime = &now
+		meta.SetStatusCondition(&obj.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.RunnerGroupConditionSynced,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonRunnerGroupSynced,
+			Message:            fmt.Sprintf("Runner group %q matches the spec", settings.Name),
+			ObservedGeneration: obj.Generation,
+		})
+	}); err != nil {
+		log.Error(err, "Failed to update runner group status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: runnerGroupResyncInterval}, nil
+}
+
+// syncRunnerGroup finds the runner group on GitHub, by the ID in the status or else by name, creates it when there is none,
+// and updates its settings and repositories when they drifted from the spec.
+// A runner group found by name is only adopted with spec.adopt, and errRunnerGroupExists is returned otherwise.
+// The ID of the runner group is recorded in the status as soon as it's created or adopted, so that it's deleted with the RunnerGroup.
+// It returns nil when the runner group is the default one, which isn't managed.
+func (r *RunnerGroupReconciler) syncRunnerGroup(ctx context.Context, actionsClient actions.ActionsService, runnerGroup *v1alpha1.RunnerGroup, config *actions.GitHubConfig, log logr.Logger) (*actions.RunnerGroupSettings, error) {
+	desired := &actions.RunnerGroupSettings{
+		Name:                     runnerGroup.RunnerGroupName(),
+		Visibility:               runnerGroup.RunnerGroupVisibility(),
+		AllowsPublicRepositories: runnerGroup.Spec.AllowsPublicRepositories,
+	}
+
+	current, owned, err := findRunnerGroup(ctx, actionsClient, runnerGroup.Status.ID, desired.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case current == nil:
+		log.Info("Creating runner group", "name", desired.Name)
+		current, err = actionsClient.CreateRunnerGroup(ctx, desired)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create runner group: %w", err)
+		}
+		if err := r.recordRunnerGroup(ctx, runnerGroup, current.ID, false); err != nil {
+			return nil, err
+		}
+	case current.Default:
+		return nil, nil
+	case !owned && !runnerGroup.Spec.Adopt:
+		return nil, errRunnerGroupExists
+	case !owned:
+		log.Info("Adopting runner group", "id", current.ID, "name", current.Name)
+		if err := r.recordRunnerGroup(ctx, runnerGroup, current.ID, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if current.Name != desired.Name || current.Visibility != desired.Visibility || current.AllowsPublicRepositories != desired.AllowsPublicRepositories {
+		log.Info("Updating drifted runner group", "id", current.ID, "name", desired.Name, "visibility", desired.Visibility, "allowsPublicRepositories", desired.AllowsPublicRepositories)
+		current, err = actionsClient.UpdateRunnerGroup(ctx, current.ID, desired)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update runner group: %w", err)
+		}
+	}
+
+	// The runner groups of enterprises are restricted to organizations, not repositories
+	if desired.Visibility != actions.RunnerGroupVisibilitySelected || config.Scope != actions.GitHubScopeOrganization {
+		return current, nil
+	}
+
+	repositories, err := actionsClient.ListRunnerGroupRepositories(ctx, current.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runner group repositories: %w", err)
+	}
+
+	if !sameRepositories(repositories, runnerGroup.Spec.SelectedRepositories) {
+		log.Info("Updating drifted runner group repositories", "id", current.ID, "repositories", runnerGroup.Spec.SelectedRepositories)
+		if err := actionsClient.SetRunnerGroupRepositories(ctx, current.ID, runnerGroup.Spec.SelectedRepositories); err != nil {
+			return nil, fmt.Errorf("failed to set runner group repositories: %w", err)
+		}
+	}
+
+	return current, nil
+}
+
+// findRunnerGroup returns the runner group with the ID, or else the one with the name, or nil when there is none.
+// The ID is tried first so that renaming the runner group doesn't create another one.
+// owned tells whether the runner group was found by the ID, which is only recorded for the created or adopted runner groups.
+func findRunnerGroup(ctx context.Context, actionsClient actions.ActionsService, id int64, name string) (settings *actions.RunnerGroupSettings, owned bool, err error) {
+	if id != 0 {
+		settings, err := actionsClient.GetRunnerGroupSettings(ctx, id)
+		switch {
+		case err == nil:
+			return settings, true, nil
+		case !isGitHubAPINotFound(err):
+			return nil, false, fmt.Errorf("failed to get runner group: %w", err)
+		}
+	}
+
+	runnerGroups, err := actionsClient.ListRunnerGroupSettings(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list runner groups: %w", err)
+	}
+
+	for i := range runnerGroups {
+		if strings.EqualFold(runnerGroups[i].Name, name) {
+			return &runnerGroups[i], false, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// recordRunnerGroup records the ID of the runner group the RunnerGroup created or adopted.
+func (r *RunnerGroupReconciler) recordRunnerGroup(ctx context.Context, runnerGroup *v1alpha1.RunnerGroup, id int64, adopted bool) error {
+	if err := patchSubResource(ctx, r.Status(), runnerGroup, func(obj *v1alpha1.RunnerGroup) {
+		obj.Status.ID = id
+		obj.Status.Adopted = adopted
+	}); err != nil {
+		return fmt.Errorf("failed to record runner group %d: %w", id, err)
+	}
+	return nil
+}
+
+// deleteRunnerGroup deletes the runner group from GitHub, unless the deletion policy orphans it.
+func (r *RunnerGroupReconciler) deleteRunnerGroup(ctx context.Context, runnerGroup *v1alpha1.RunnerGroup, log logr.Logger) error {
+	if runnerGroup.Status.ID == 0 || runnerGroup.RunnerGroupDeletionPolicy() == v1alpha1.RunnerGroupDeletionPolicyOrphan {
+		return nil
+	}
+
+	actionsClient, err := r.actionsClientFor(ctx, runnerGroup)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Deleting runner group", "id", runnerGroup.Status.ID)
+	if err := actionsClient.DeleteRunnerGroup(ctx, runnerGroup.Status.ID); err != nil && !isGitHubAPINotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RunnerGroupReconciler) setSynced(ctx context.Context, runnerGroup *v1alpha1.RunnerGroup, status metav1.ConditionStatus, reason, message string) error {
+	existing := meta.FindStatusCondition(runnerGroup.Status.Conditions, v1alpha1.RunnerGroupConditionSynced)
+	if existing != nil && existing.Status == status && existing.Reason == reason && existing.Message == message && existing.ObservedGeneration == runnerGroup.Generation {
+		return nil
+	}
+
+	return patchSubResource(ctx, r.Status(), runnerGroup, func(obj *v1alpha1.RunnerGroup) {
+		meta.SetStatusCondition(&obj.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.RunnerGroupConditionSynced,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: obj.Generation,
+		})
+	})
+}
+
+// sameRepositories returns true when a and b have the same repositories, regardless of their order and case.
+func sameRepositories(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	normalize := func(repositories []string) []string {
+		normalized := make([]string, len(repositories))
+		for i, r := range repositories {
+			normalized[i] = strings.ToLower(r)
+		}
+		sort.Strings(normalized)
+		return normalized
+	}
+
+	na, nb := normalize(a), normalize(b)
+	for i := range na {
+		if na[i] != nb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isGitHubAPINotFound(err error) bool {
+	var apiErr *actions.GitHubAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+func (r *RunnerGroupReconciler) actionsClientFor(ctx context.Context, runnerGroup *v1alpha1.RunnerGroup) (actions.ActionsService, error) {
+	var configSecret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: runnerGroup.Namespace, Name: runnerGroup.Spec.GitHubConfigSecret}, &configSecret); err != nil {
+		return nil, fmt.Errorf("failed to find GitHub config secret: %w", err)
+	}
+
+	opts, err := r.actionsClientOptionsFor(ctx, runnerGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get actions client options: %w", err)
+	}
+
+	return r.ActionsClient.GetClientFromSecret(
+		ctx,
+		runnerGroup.Spec.GitHubConfigUrl,
+		runnerGroup.Namespace,
+		configSecret.Data,
+		opts...,
+	)
+}
+
+func (r *RunnerGroupReconciler) actionsClientOptionsFor(ctx context.Context, runnerGroup *v1alpha1.RunnerGroup) ([]actions.ClientOption, error) {
+	var options []actions.ClientOption
+
+	if runnerGroup.Spec.Proxy != nil {
+		proxyFunc, err := runnerGroup.Spec.Proxy.ProxyFunc(func(s string) (*corev1.Secret, error) {
+			var secret corev1.Secret
+			err := r.Get(ctx, types.NamespacedName{Namespace: runnerGroup.Namespace, Name: s}, &secret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get proxy secret %s: %w", s, err)
+			}
+
+			return &secret, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get proxy func: %w", err)
+		}
+
+		options = append(options, actions.WithProxy(proxyFunc))
+	}
+
+	tlsConfig := runnerGroup.Spec.GitHubServerTLS
+	if tlsConfig != nil {
+		pool, err := tlsConfig.ToCertPool(func(name, key string) ([]byte, error) {
+			var configmap corev1.ConfigMap
+			err := r.Get(ctx, types.NamespacedName{Namespace: runnerGroup.Namespace, Name: name}, &configmap)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get configmap %s: %w", name, err)
+			}
+
+			return []byte(configmap.Data[key]), nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tls config: %w", err)
+		}
+
+		options = append(options, actions.WithRootCAs(pool))
+
+		cert, err := tlsConfig.ToClientCertificate(func(name string) (*corev1.Secret, error) {
+			var secret corev1.Secret
+			err := r.Get(ctx, types.NamespacedName{Namespace: runnerGroup.Namespace, Name: name}, &secret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+			}
+
+			return &secret, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tls client certificate: %w", err)
+		}
+		if cert != nil {
+			options = append(options, actions.WithClientCertificate(*cert))
+		}
+	}
+
+	return options, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RunnerGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// The status updates of each sync would otherwise trigger another sync
+		For(&v1alpha1.RunnerGroup{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
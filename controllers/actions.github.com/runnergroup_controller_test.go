@@ -0,0 +1,278 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/actions/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// runnerGroupsClient keeps the runner groups of an organization in memory.
+type runnerGroupsClient struct {
+	actions.ActionsService
+
+	nextID       int64
+	groups       map[int64]*actions.RunnerGroupSettings
+	repositories map[int64][]string
+	calls        []string
+}
+
+func newRunnerGroupsClient(groups ...actions.RunnerGroupSettings) *runnerGroupsClient {
+	c := &runnerGroupsClient{
+		nextID:       10,
+		groups:       map[int64]*actions.RunnerGroupSettings{},
+		repositories: map[int64][]string{},
+	}
+	for i := range groups {
+		c.groups[groups[i].ID] = &groups[i]
+	}
+	return c
+}
+
+func (c *runnerGroupsClient) GetRunnerGroupSettings(ctx context.Context, runnerGroupId int64) (*actions.RunnerGroupSettings, error) {
+	group, ok := c.groups[runnerGroupId]
+	if !ok {
+		return nil, &actions.GitHubAPIError{StatusCode: http.StatusNotFound}
+	}
+	settings := *group
+	return &settings, nil
+}
+
+func (c *runnerGroupsClient) ListRunnerGroupSettings(ctx context.Context) ([]actions.RunnerGroupSettings, error) {
+	var groups []actions.RunnerGroupSettings
+	for _, group := range c.groups {
+		groups = append(groups, *group)
+	}
+	return groups, nil
+}
+
+func (c *runnerGroupsClient) CreateRunnerGroup(ctx context.Context, runnerGroup *actions.RunnerGroupSettings) (*actions.RunnerGroupSettings, error) {
+	c.calls = append(c.calls, "create")
+	created := *runnerGroup
+	created.ID = c.nextID
+	c.nextID++
+	c.groups[created.ID] = &created
+	return c.GetRunnerGroupSettings(ctx, created.ID)
+}
+
+func (c *runnerGroupsClient) UpdateRunnerGroup(ctx context.Context, runnerGroupId int64, runnerGroup *actions.RunnerGroupSettings) (*actions.RunnerGroupSettings, error) {
+	c.calls = append(c.calls, "update")
+	updated := *runnerGroup
+	updated.ID = runnerGroupId
+	c.groups[runnerGroupId] = &updated
+	return c.GetRunnerGroupSettings(ctx, runnerGroupId)
+}
+
+func (c *runnerGroupsClient) ListRunnerGroupRepositories(ctx context.Context, runnerGroupId int64) ([]string, error) {
+	return c.repositories[runnerGroupId], nil
+}
+
+func (c *runnerGroupsClient) SetRunnerGroupRepositories(ctx context.Context, runnerGroupId int64, repositories []string) error {
+	c.calls = append(c.calls, "set repositories")
+	c.repositories[runnerGroupId] = repositories
+	return nil
+}
+
+func (c *runnerGroupsClient) DeleteRunnerGroup(ctx context.Context, runnerGroupId int64) error {
+	c.calls = append(c.calls, "delete")
+	delete(c.groups, runnerGroupId)
+	return nil
+}
+
+func TestRunnerGroupReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newReconciler := func(actionsClient *runnerGroupsClient, spec v1alpha1.RunnerGroupSpec) (*RunnerGroupReconciler, *v1alpha1.RunnerGroup) {
+		spec.GitHubConfigUrl = "https://github.com/my-org"
+		spec.GitHubConfigSecret = "github-config"
+
+		runnerGroup := &v1alpha1.RunnerGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-group", Namespace: "arc-systems", Generation: 1},
+			Spec:       spec,
+		}
+		configSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "github-config", Namespace: "arc-systems"},
+			Data:       map[string][]byte{"github_token": []byte("token")},
+		}
+
+		r := &RunnerGroupReconciler{
+			Client:        crfake.NewClientBuilder().WithScheme(scheme).WithObjects(runnerGroup, configSecret).WithStatusSubresource(runnerGroup).Build(),
+			Log:           logr.Discard(),
+			Scheme:        scheme,
+			ActionsClient: fake.NewMultiClient(fake.WithDefaultClient(actionsClient, nil)),
+		}
+		return r, runnerGroup
+	}
+
+	reconcile := func(t *testing.T, r *RunnerGroupReconciler, runnerGroup *v1alpha1.RunnerGroup) *v1alpha1.RunnerGroup {
+		t.Helper()
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(runnerGroup)})
+		require.NoError(t, err)
+
+		updated := new(v1alpha1.RunnerGroup)
+		if err := r.Get(ctx, client.ObjectKeyFromObject(runnerGroup), updated); err != nil {
+			return nil
+		}
+		return updated
+	}
+
+	t.Run("creates the runner group and its repositories", func(t *testing.T) {
+		actionsClient := newRunnerGroupsClient()
+		r, runnerGroup := newReconciler(actionsClient, v1alpha1.RunnerGroupSpec{
+			Visibility:           actions.RunnerGroupVisibilitySelected,
+			SelectedRepositories: []string{"repo-a", "repo-b"},
+		})
+
+		updated := reconcile(t, r, runnerGroup)
+		assert.Contains(t, updated.Finalizers, runnerGroupFinalizerName)
+		assert.Equal(t, int64(10), updated.Status.ID)
+		assert.NotNil(t, updated.Status.LastSyncTime)
+		assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, v1alpha1.RunnerGroupConditionSynced))
+
+		assert.Equal(t, &actions.RunnerGroupSettings{ID: 10, Name: "my-group", Visibility: actions.RunnerGroupVisibilitySelected}, actionsClient.groups[10])
+		assert.Equal(t, []string{"repo-a", "repo-b"}, actionsClient.repositories[10])
+
+		actionsClient.calls = nil
+		reconcile(t, r, updated)
+		assert.Empty(t, actionsClient.calls, "a runner group in sync isn't updated")
+	})
+
+	t.Run("reverts the drift of an adopted runner group", func(t *testing.T) {
+		actionsClient := newRunnerGroupsClient(
+			actions.RunnerGroupSettings{ID: 1, Name: "Default", Visibility: actions.RunnerGroupVisibilityAll, Default: true},
+			actions.RunnerGroupSettings{ID: 2, Name: "My-Group", Visibility: actions.RunnerGroupVisibilityAll, AllowsPublicRepositories: true},
+		)
+		actionsClient.repositories[2] = []string{"repo-b", "repo-c"}
+
+		r, runnerGroup := newReconciler(actionsClient, v1alpha1.RunnerGroupSpec{
+			Adopt:                true,
+			Visibility:           actions.RunnerGroupVisibilitySelected,
+			SelectedRepositories: []string{"REPO-c", "repo-b"},
+		})
+
+		updated := reconcile(t, r, runnerGroup)
+		assert.Equal(t, int64(2), updated.Status.ID)
+		assert.True(t, updated.Status.Adopted)
+		assert.Equal(t, []string{"update"}, actionsClient.calls)
+		assert.Equal(t, &actions.RunnerGroupSettings{ID: 2, Name: "my-group", Visibility: actions.RunnerGroupVisibilitySelected}, actionsClient.groups[2])
+
+		require.NoError(t, r.Delete(ctx, updated))
+		assert.Nil(t, reconcile(t, r, updated), "the finalizer is removed")
+		assert.Contains(t, actionsClient.groups, int64(2), "the adopted runner group is orphaned by default")
+	})
+
+	t.Run("doesn't adopt an existing runner group without adopt", func(t *testing.T) {
+		actionsClient := newRunnerGroupsClient(actions.RunnerGroupSettings{ID: 2, Name: "my-group", Visibility: actions.RunnerGroupVisibilityAll})
+		r, runnerGroup := newReconciler(actionsClient, v1alpha1.RunnerGroupSpec{Visibility: actions.RunnerGroupVisibilityPrivate})
+
+		updated := reconcile(t, r, runnerGroup)
+		assert.Empty(t, actionsClient.calls)
+		assert.Zero(t, updated.Status.ID)
+
+		synced := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.RunnerGroupConditionSynced)
+		require.NotNil(t, synced)
+		assert.Equal(t, metav1.ConditionFalse, synced.Status)
+		assert.Equal(t, reasonRunnerGroupExists, synced.Reason)
+
+		require.NoError(t, r.Delete(ctx, updated))
+		assert.Nil(t, reconcile(t, r, updated), "the finalizer is removed")
+		assert.Contains(t, actionsClient.groups, int64(2), "the runner group isn't deleted")
+	})
+
+	t.Run("follows the runner group when it's renamed", func(t *testing.T) {
+		actionsClient := newRunnerGroupsClient()
+		r, runnerGroup := newReconciler(actionsClient, v1alpha1.RunnerGroupSpec{})
+
+		updated := reconcile(t, r, runnerGroup)
+		actionsClient.calls = nil
+		updated.Spec.Name = "renamed"
+		require.NoError(t, r.Update(ctx, updated))
+
+		updated = reconcile(t, r, updated)
+		assert.Equal(t, int64(10), updated.Status.ID)
+		assert.Equal(t, []string{"update"}, actionsClient.calls)
+		assert.Equal(t, "renamed", actionsClient.groups[10].Name)
+	})
+
+	t.Run("doesn't manage the default runner group", func(t *testing.T) {
+		actionsClient := newRunnerGroupsClient(actions.RunnerGroupSettings{ID: 1, Name: "Default", Visibility: actions.RunnerGroupVisibilityAll, Default: true})
+		r, runnerGroup := newReconciler(actionsClient, v1alpha1.RunnerGroupSpec{Name: "default", Visibility: actions.RunnerGroupVisibilityPrivate})
+
+		updated := reconcile(t, r, runnerGroup)
+		assert.Empty(t, actionsClient.calls)
+
+		synced := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.RunnerGroupConditionSynced)
+		require.NotNil(t, synced)
+		assert.Equal(t, metav1.ConditionFalse, synced.Status)
+		assert.Equal(t, reasonRunnerGroupInvalidSpec, synced.Reason)
+	})
+
+	t.Run("rejects repository URLs", func(t *testing.T) {
+		actionsClient := newRunnerGroupsClient()
+		r, runnerGroup := newReconciler(actionsClient, v1alpha1.RunnerGroupSpec{})
+		runnerGroup.Spec.GitHubConfigUrl = "https://github.com/my-org/my-repo"
+		require.NoError(t, r.Update(ctx, runnerGroup))
+
+		updated := reconcile(t, r, runnerGroup)
+		assert.Empty(t, actionsClient.calls)
+
+		synced := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.RunnerGroupConditionSynced)
+		require.NotNil(t, synced)
+		assert.Equal(t, reasonRunnerGroupInvalidSpec, synced.Reason)
+	})
+
+	t.Run("rejects selected enterprise runner groups", func(t *testing.T) {
+		actionsClient := newRunnerGroupsClient()
+		r, runnerGroup := newReconciler(actionsClient, v1alpha1.RunnerGroupSpec{Visibility: actions.RunnerGroupVisibilitySelected})
+		runnerGroup.Spec.GitHubConfigUrl = "https://github.com/enterprises/my-enterprise"
+		require.NoError(t, r.Update(ctx, runnerGroup))
+
+		updated := reconcile(t, r, runnerGroup)
+		assert.Empty(t, actionsClient.calls)
+
+		synced := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.RunnerGroupConditionSynced)
+		require.NotNil(t, synced)
+		assert.Equal(t, reasonRunnerGroupInvalidSpec, synced.Reason)
+	})
+
+	for _, tc := range []struct {
+		deletionPolicy string
+		wantDeleted    bool
+	}{
+		{deletionPolicy: "", wantDeleted: true},
+		{deletionPolicy: v1alpha1.RunnerGroupDeletionPolicyOrphan, wantDeleted: false},
+	} {
+		t.Run("deletion policy "+tc.deletionPolicy, func(t *testing.T) {
+			actionsClient := newRunnerGroupsClient()
+			r, runnerGroup := newReconciler(actionsClient, v1alpha1.RunnerGroupSpec{DeletionPolicy: tc.deletionPolicy})
+
+			updated := reconcile(t, r, runnerGroup)
+			require.Contains(t, actionsClient.groups, int64(10))
+
+			require.NoError(t, r.Delete(ctx, updated))
+			assert.Nil(t, reconcile(t, r, updated), "the finalizer is removed")
+
+			_, exists := actionsClient.groups[10]
+			assert.Equal(t, !tc.wantDeleted, exists)
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package actionsgithubcom
 
 import (
+	"context"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
 )
 
@@ -25,3 +30,26 @@ func RandStringRunes(n int) string {
 	}
 	return string(b)
 }
+
+// runnerContainerImage returns the image of the runner container in the pod template,
+// or an empty string if there is no runner container.
+func runnerContainerImage(template corev1.PodTemplateSpec) string {
+	for _, c := range template.Spec.Containers {
+		if c.Name == EphemeralRunnerContainerName {
+			return c.Image
+		}
+	}
+	return ""
+}
+
+// withScaleSet returns a copy of ctx identifying the scale set that obj belongs to,
+// so that the Actions API requests made for it get their fair share of the rate limit of its credentials.
+// Objects without the scale set labels are identified by their own name.
+func withScaleSet(ctx context.Context, obj metav1.Object) context.Context {
+	namespace, name := obj.GetLabels()[LabelKeyGitHubScaleSetNamespace], obj.GetLabels()[LabelKeyGitHubScaleSetName]
+	if namespace == "" || name == "" {
+		namespace, name = obj.GetNamespace(), obj.GetName()
+	}
+
+	return actions.ContextWithScaleSet(ctx, namespace+"/"+name)
+}
@@ -7,12 +7,14 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	prometheus_metrics "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
 	arcgithub "github.com/actions/actions-runner-controller/github"
 	"github.com/google/go-github/v52/github"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -23,11 +25,16 @@ const (
 	defaultScaleDownFactor    = 0.7
 )
 
-func (r *HorizontalRunnerAutoscalerReconciler) suggestDesiredReplicas(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
+// desiredReplicasSourceFallbackReplicas is the source of the desired replicas when they come from spec.fallbackReplicas.
+const desiredReplicasSourceFallbackReplicas = "FallbackReplicas"
+
+// suggestDesiredReplicas returns the desired replicas suggested by the metrics of the HRA, along with the type of the metric
+// they were computed from, or desiredReplicasSourceFallbackReplicas when all the metrics failed and spec.fallbackReplicas is set.
+func (r *HorizontalRunnerAutoscalerReconciler) suggestDesiredReplicas(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, string, error) {
 	if hra.Spec.MinReplicas == nil {
-		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing minReplicas", hra.Namespace, hra.Name)
+		return nil, "", fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing minReplicas", hra.Namespace, hra.Name)
 	} else if hra.Spec.MaxReplicas == nil {
-		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing maxReplicas", hra.Namespace, hra.Name)
+		return nil, "", fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing maxReplicas", hra.Namespace, hra.Name)
 	}
 
 	metrics := hra.Spec.Metrics
@@ -35,9 +42,9 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestDesiredReplicas(ghc *arcgi
 	if numMetrics == 0 {
 		// We don't default to anything since ARC 0.23.0
 		// See https://github.com/actions/actions-runner-controller/issues/728
-		return nil, nil
+		return nil, "", nil
 	} else if numMetrics > 2 {
-		return nil, fmt.Errorf("too many autoscaling metrics configured: It must be 0 to 2, but got %d", numMetrics)
+		return nil, "", fmt.Errorf("too many autoscaling metrics configured: It must be 0 to 2, but got %d", numMetrics)
 	}
 
 	primaryMetric := metrics[0]
@@ -51,44 +58,75 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestDesiredReplicas(ghc *arcgi
 	switch primaryMetricType {
 	case v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns:
 		suggested, err = r.suggestReplicasByQueuedAndInProgressWorkflowRuns(ghc, st, hra, &primaryMetric)
+	case v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowJobs:
+		suggested, err = r.suggestReplicasByQueuedAndInProgressWorkflowJobs(ghc, st, hra, &primaryMetric)
 	case v1alpha1.AutoscalingMetricTypePercentageRunnersBusy:
 		suggested, err = r.suggestReplicasByPercentageRunnersBusy(ghc, st, hra, primaryMetric)
+	case v1alpha1.AutoscalingMetricTypeOldestQueuedWorkflowJobAge:
+		suggested, err = r.suggestReplicasByOldestQueuedWorkflowJobAge(ghc, st, hra, &primaryMetric)
 	default:
-		return nil, fmt.Errorf("validating autoscaling metrics: unsupported metric type %q", primaryMetric)
+		return nil, "", fmt.Errorf("validating autoscaling metrics: unsupported metric type %q", primaryMetric)
 	}
 
 	if err != nil {
-		return nil, err
-	}
+		r.ScaleExplanations.addMetric(hra, MetricExplanation{Type: primaryMetricType, Error: err.Error()})
 
-	if suggested != nil && *suggested > 0 {
-		return suggested, nil
-	}
+		if len(metrics) == 1 && hra.Spec.FallbackReplicas == nil {
+			return nil, "", err
+		}
 
-	if len(metrics) == 1 {
+		r.Log.Error(err, "Could not compute the primary metric. Falling back", "namespace", hra.Namespace, "horizontal_runner_autoscaler", hra.Name, "metric", primaryMetricType)
+	} else if suggested != nil && *suggested > 0 {
+		return suggested, primaryMetricType, nil
+	} else if len(metrics) == 1 {
 		// This is never supposed to happen but anyway-
 		// Fall-back to `minReplicas + capacityReservedThroughWebhook`.
-		return nil, nil
+		return nil, primaryMetricType, nil
 	}
 
-	// At this point, we are sure that there are exactly 2 Metrics entries.
+	if len(metrics) == 2 {
+		fallbackMetric := metrics[1]
+		fallbackMetricType := fallbackMetric.Type
+
+		if primaryMetricType != v1alpha1.AutoscalingMetricTypePercentageRunnersBusy ||
+			(fallbackMetricType != v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns &&
+				fallbackMetricType != v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowJobs) {
+			return nil, "", fmt.Errorf(
+				"invalid HRA Spec: Metrics[0] of %s cannot be combined with Metrics[1] of %s: The only allowed combinations are 0=PercentageRunnersBusy and 1=TotalNumberOfQueuedAndInProgressWorkflowRuns or TotalNumberOfQueuedAndInProgressWorkflowJobs",
+				primaryMetricType, fallbackMetricType,
+			)
+		}
+
+		var fallbackErr error
 
-	fallbackMetric := metrics[1]
-	fallbackMetricType := fallbackMetric.Type
+		switch fallbackMetricType {
+		case v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns:
+			suggested, fallbackErr = r.suggestReplicasByQueuedAndInProgressWorkflowRuns(ghc, st, hra, &fallbackMetric)
+		case v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowJobs:
+			suggested, fallbackErr = r.suggestReplicasByQueuedAndInProgressWorkflowJobs(ghc, st, hra, &fallbackMetric)
+		}
+
+		if fallbackErr == nil {
+			return suggested, fallbackMetricType, nil
+		}
 
-	if primaryMetricType != v1alpha1.AutoscalingMetricTypePercentageRunnersBusy ||
-		fallbackMetricType != v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns {
+		r.ScaleExplanations.addMetric(hra, MetricExplanation{Type: fallbackMetricType, Error: fallbackErr.Error()})
 
-		return nil, fmt.Errorf(
-			"invalid HRA Spec: Metrics[0] of %s cannot be combined with Metrics[1] of %s: The only allowed combination is 0=PercentageRunnersBusy and 1=TotalNumberOfQueuedAndInProgressWorkflowRuns",
-			primaryMetricType, fallbackMetricType,
-		)
+		r.Log.Error(fallbackErr, "Could not compute the secondary metric", "namespace", hra.Namespace, "horizontal_runner_autoscaler", hra.Name, "metric", fallbackMetricType)
+
+		err = fallbackErr
+	}
+
+	if hra.Spec.FallbackReplicas != nil {
+		return hra.Spec.FallbackReplicas, desiredReplicasSourceFallbackReplicas, nil
 	}
 
-	return r.suggestReplicasByQueuedAndInProgressWorkflowRuns(ghc, st, hra, &fallbackMetric)
+	return nil, "", err
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgressWorkflowRuns(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec) (*int, error) {
+// metricRepositories returns the list of [owner, repo] pairs whose workflow runs are polled for the metric.
+// It returns nil without an error when the scale target is organizational and no metric is configured.
+func metricRepositories(st scaleTarget, metrics *v1alpha1.MetricSpec) ([][]string, error) {
 	var repos [][]string
 	repoID := st.repo
 	if repoID == "" {
@@ -117,6 +155,51 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 		repos = append(repos, repo)
 	}
 
+	return repos, nil
+}
+
+// listWorkflowRuns returns the queued and in-progress workflow runs of each of the repos, in the same order.
+// With GraphQLMetrics, the runs of the repositories of an organization are fetched with the bulk GraphQL queries.
+func (r *HorizontalRunnerAutoscalerReconciler) listWorkflowRuns(ghc *arcgithub.Client, repos [][]string) ([][]*github.WorkflowRun, error) {
+	workflowRuns := make([][]*github.WorkflowRun, 0, len(repos))
+
+	if r.GraphQLMetrics && len(repos) > 1 {
+		owner := repos[0][0]
+		names := make([]string, 0, len(repos))
+		for _, repo := range repos {
+			names = append(names, repo[1])
+		}
+
+		runs, err := ghc.ListRepositoriesWorkflowRuns(context.TODO(), owner, names)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			workflowRuns = append(workflowRuns, runs[name])
+		}
+
+		return workflowRuns, nil
+	}
+
+	for _, repo := range repos {
+		runs, err := ghc.ListRepositoryWorkflowRuns(context.TODO(), repo[0], repo[1])
+		if err != nil {
+			return nil, err
+		}
+
+		workflowRuns = append(workflowRuns, runs)
+	}
+
+	return workflowRuns, nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgressWorkflowRuns(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec) (*int, error) {
+	repos, err := metricRepositories(st, metrics)
+	if err != nil || repos == nil {
+		return nil, err
+	}
+
 	var total, inProgress, queued, completed, unknown int
 	listWorkflowJobs := func(user string, repoName string, runID int64) {
 		if runID == 0 {
@@ -184,12 +267,14 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 		}
 	}
 
-	for _, repo := range repos {
+	workflowRunsOfRepos, err := r.listWorkflowRuns(ghc, repos)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, repo := range repos {
 		user, repoName := repo[0], repo[1]
-		workflowRuns, err := ghc.ListRepositoryWorkflowRuns(context.TODO(), user, repoName)
-		if err != nil {
-			return nil, err
-		}
+		workflowRuns := workflowRunsOfRepos[i]
 
 		for _, run := range workflowRuns {
 			total++
@@ -211,7 +296,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 		}
 	}
 
-	necessaryReplicas := queued + inProgress
+	// Each runner pod takes as many jobs as it has slots
+	necessaryReplicas := podsForRunners(queued+inProgress, st.slotsPerPod)
 
 	prometheus_metrics.SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowRuns(
 		hra.ObjectMeta,
@@ -227,6 +313,18 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 		unknown,
 	)
 
+	r.ScaleExplanations.addMetric(hra, MetricExplanation{
+		Type:              v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		SuggestedReplicas: &necessaryReplicas,
+		Inputs: map[string]any{
+			"workflowRunsCompleted":  completed,
+			"workflowRunsInProgress": inProgress,
+			"workflowRunsQueued":     queued,
+			"workflowRunsUnknown":    unknown,
+			"slotsPerPod":            max(st.slotsPerPod, 1),
+		},
+	})
+
 	r.Log.V(1).Info(
 		fmt.Sprintf("Suggested desired replicas of %d by TotalNumberOfQueuedAndInProgressWorkflowRuns", necessaryReplicas),
 		"workflow_runs_completed", completed,
@@ -242,6 +340,343 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 	return &necessaryReplicas, nil
 }
 
+// suggestReplicasByQueuedAndInProgressWorkflowJobs is similar to suggestReplicasByQueuedAndInProgressWorkflowRuns,
+// but it counts only the workflow jobs whose runs-on labels can be satisfied by the scale target's runner labels.
+// Unlike TotalNumberOfQueuedAndInProgressWorkflowRuns, the labels are compared case-insensitively as GitHub does,
+// and the default labels that GitHub associates with every ARC runner are taken into account,
+// so that a matrix job routed to another runner pool is never counted toward this pool's desired replicas,
+// and a job requesting e.g. `[self-hosted, linux, custom]` is counted for a pool labeled `custom`.
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgressWorkflowJobs(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec) (*int, error) {
+	repos, err := metricRepositories(st, metrics)
+	if err != nil || repos == nil {
+		return nil, err
+	}
+
+	runnerLabels := runnerLabelSet(st)
+
+	var inProgress, queued, unmatched, unknown int
+
+	workflowRunsOfRepos, err := r.listWorkflowRuns(ghc, repos)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, repo := range repos {
+		user, repoName := repo[0], repo[1]
+		workflowRuns := workflowRunsOfRepos[i]
+
+		for _, run := range workflowRuns {
+			if run.GetID() == 0 {
+				// should not happen in reality
+				r.Log.Info("Detected run with no runID of 0, ignoring the case and not scaling.", "repo_name", repoName, "run_id", run.GetID())
+				continue
+			}
+
+			jobs, err := listAllWorkflowJobs(ghc, user, repoName, run.GetID())
+			if err != nil {
+				return nil, fmt.Errorf("listing workflow jobs for run %d in %s/%s: %w", run.GetID(), user, repoName, err)
+			}
+
+			for _, job := range jobs {
+				status := job.GetStatus()
+				if status == "completed" {
+					continue
+				}
+
+				if !jobLabelsSatisfiedBy(job.Labels, runnerLabels) {
+					unmatched++
+					continue
+				}
+
+				switch status {
+				case "in_progress":
+					inProgress++
+				case "queued":
+					queued++
+				default:
+					unknown++
+				}
+			}
+		}
+	}
+
+	// Each runner pod takes as many jobs as it has slots
+	necessaryReplicas := podsForRunners(queued+inProgress, st.slotsPerPod)
+
+	prometheus_metrics.SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowJobs(
+		hra.ObjectMeta,
+		st.enterprise,
+		st.org,
+		st.repo,
+		st.kind,
+		st.st,
+		necessaryReplicas,
+		inProgress,
+		queued,
+		unmatched,
+		unknown,
+	)
+
+	r.ScaleExplanations.addMetric(hra, MetricExplanation{
+		Type:              v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowJobs,
+		SuggestedReplicas: &necessaryReplicas,
+		Inputs: map[string]any{
+			"workflowJobsInProgress": inProgress,
+			"workflowJobsQueued":     queued,
+			"workflowJobsUnmatched":  unmatched,
+			"workflowJobsUnknown":    unknown,
+			"slotsPerPod":            max(st.slotsPerPod, 1),
+		},
+	})
+
+	r.Log.V(1).Info(
+		fmt.Sprintf("Suggested desired replicas of %d by TotalNumberOfQueuedAndInProgressWorkflowJobs", necessaryReplicas),
+		"workflow_jobs_in_progress", inProgress,
+		"workflow_jobs_queued", queued,
+		"workflow_jobs_unmatched", unmatched,
+		"workflow_jobs_unknown", unknown,
+		"namespace", hra.Namespace,
+		"kind", st.kind,
+		"name", st.st,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &necessaryReplicas, nil
+}
+
+// defaultQueuedJobAgeThreshold is how long a queued workflow job can wait before OldestQueuedWorkflowJobAge scales up,
+// when the metric doesn't specify queuedJobAgeThreshold.
+const defaultQueuedJobAgeThreshold = 2 * time.Minute
+
+// suggestReplicasByOldestQueuedWorkflowJobAge scales up when queued workflow jobs have been waiting longer than the threshold,
+// regardless of how many jobs are queued, so that repositories with few jobs get runners as quickly as busy ones.
+// The target is absolute so that it doesn't grow on every sync while the same jobs are queued: one runner per in-progress job
+// plus one per job queued over the threshold, or plus scaleUpAdjustment runners. It never scales down while jobs are queued,
+// and scales down to the in-progress jobs, or by scaleDownAdjustment, once no job is queued.
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByOldestQueuedWorkflowJobAge(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec) (*int, error) {
+	if metrics.ScaleUpAdjustment < 0 {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].scaleUpAdjustment cannot be lower than 0")
+	}
+	if metrics.ScaleDownAdjustment < 0 {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].scaleDownAdjustment cannot be lower than 0")
+	}
+
+	threshold := defaultQueuedJobAgeThreshold
+	if metrics.QueuedJobAgeThreshold != nil {
+		threshold = metrics.QueuedJobAgeThreshold.Duration
+	}
+
+	repos, err := metricRepositories(st, metrics)
+	if err != nil || repos == nil {
+		return nil, err
+	}
+
+	runnerLabels := runnerLabelSet(st)
+	now := nowFrom(r.Clock)
+
+	var (
+		inProgress, queued, queuedOverThreshold int
+		oldestAge                               time.Duration
+	)
+
+	workflowRunsOfRepos, err := r.listWorkflowRuns(ghc, repos)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, repo := range repos {
+		user, repoName := repo[0], repo[1]
+		workflowRuns := workflowRunsOfRepos[i]
+
+		for _, run := range workflowRuns {
+			if run.GetID() == 0 {
+				continue
+			}
+
+			jobs, err := listAllWorkflowJobs(ghc, user, repoName, run.GetID())
+			if err != nil {
+				return nil, fmt.Errorf("listing workflow jobs for run %d in %s/%s: %w", run.GetID(), user, repoName, err)
+			}
+
+			for _, job := range jobs {
+				if !jobLabelsSatisfiedBy(job.Labels, runnerLabels) {
+					continue
+				}
+
+				switch job.GetStatus() {
+				case "in_progress":
+					inProgress++
+				case "queued":
+					queued++
+
+					// GitHub sets started_at to the time the job was queued until a runner picks it up
+					queuedAt := job.GetCreatedAt().Time
+					if queuedAt.IsZero() {
+						queuedAt = job.GetStartedAt().Time
+					}
+					if queuedAt.IsZero() {
+						continue
+					}
+
+					age := now.Sub(queuedAt)
+					if age > oldestAge {
+						oldestAge = age
+					}
+					if age >= threshold {
+						queuedOverThreshold++
+					}
+				}
+			}
+		}
+	}
+
+	desiredReplicasBefore := 1
+	if st.replicas != nil {
+		desiredReplicasBefore = *st.replicas
+	}
+
+	var desiredReplicas int
+
+	switch {
+	case queuedOverThreshold > 0:
+		if metrics.ScaleUpAdjustment > 0 {
+			desiredReplicas = inProgress + metrics.ScaleUpAdjustment
+		} else {
+			desiredReplicas = inProgress + queuedOverThreshold
+		}
+		desiredReplicas = max(desiredReplicas, desiredReplicasBefore)
+	case queued > 0:
+		desiredReplicas = desiredReplicasBefore
+	case metrics.ScaleDownAdjustment > 0:
+		desiredReplicas = desiredReplicasBefore - metrics.ScaleDownAdjustment
+		if desiredReplicas < inProgress {
+			desiredReplicas = inProgress
+		}
+	default:
+		desiredReplicas = inProgress
+	}
+
+	prometheus_metrics.SetHorizontalRunnerAutoscalerOldestQueuedWorkflowJobAge(
+		hra.ObjectMeta,
+		st.enterprise,
+		st.org,
+		st.repo,
+		st.kind,
+		st.st,
+		desiredReplicas,
+		inProgress,
+		queued,
+		queuedOverThreshold,
+		oldestAge,
+	)
+
+	r.ScaleExplanations.addMetric(hra, MetricExplanation{
+		Type:              v1alpha1.AutoscalingMetricTypeOldestQueuedWorkflowJobAge,
+		SuggestedReplicas: &desiredReplicas,
+		Inputs: map[string]any{
+			"replicasDesiredBefore":           desiredReplicasBefore,
+			"workflowJobsInProgress":          inProgress,
+			"workflowJobsQueued":              queued,
+			"workflowJobsQueuedOverThreshold": queuedOverThreshold,
+			"oldestQueuedWorkflowJobAge":      oldestAge.String(),
+			"queuedJobAgeThreshold":           threshold.String(),
+			"scaleUpAdjustment":               metrics.ScaleUpAdjustment,
+			"scaleDownAdjustment":             metrics.ScaleDownAdjustment,
+		},
+	})
+
+	r.Log.V(1).Info(
+		fmt.Sprintf("Suggested desired replicas of %d by OldestQueuedWorkflowJobAge", desiredReplicas),
+		"replicas_desired_before", desiredReplicasBefore,
+		"workflow_jobs_in_progress", inProgress,
+		"workflow_jobs_queued", queued,
+		"workflow_jobs_queued_over_threshold", queuedOverThreshold,
+		"oldest_queued_workflow_job_age", oldestAge,
+		"queued_job_age_threshold", threshold,
+		"namespace", hra.Namespace,
+		"kind", st.kind,
+		"name", st.st,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &desiredReplicas, nil
+}
+
+// runnerOSLabels and runnerArchLabels map the values of the kubernetes.io/os and kubernetes.io/arch node labels
+// to the OS and architecture labels GitHub associates with the runners running on such nodes.
+var (
+	runnerOSLabels = map[string]string{
+		"linux":   "linux",
+		"windows": "windows",
+	}
+	runnerArchLabels = map[string]string{
+		"amd64": "x64",
+		"arm64": "arm64",
+		"arm":   "arm",
+	}
+)
+
+// runnerLabelSet returns the lower-cased labels of the runners of the scale target, including the labels GitHub associates
+// with every runner ARC deploys: self-hosted, and the OS and the architecture of the runner.
+// The OS and the architecture are derived from the node selector of the runner pods. The OS defaults to linux,
+// and all the architecture labels are included when the architecture isn't selected, as the runners may run on any.
+func runnerLabelSet(st scaleTarget) map[string]struct{} {
+	runnerLabels := make(map[string]struct{}, len(st.labels)+2+len(runnerArchLabels))
+	runnerLabels["self-hosted"] = struct{}{}
+
+	if os, ok := runnerOSLabels[st.nodeSelector[corev1.LabelOSStable]]; ok {
+		runnerLabels[os] = struct{}{}
+	} else {
+		runnerLabels["linux"] = struct{}{}
+	}
+
+	if arch, ok := runnerArchLabels[st.nodeSelector[corev1.LabelArchStable]]; ok {
+		runnerLabels[arch] = struct{}{}
+	} else {
+		for _, arch := range runnerArchLabels {
+			runnerLabels[arch] = struct{}{}
+		}
+	}
+
+	for _, l := range st.labels {
+		runnerLabels[strings.ToLower(l)] = struct{}{}
+	}
+	return runnerLabels
+}
+
+// jobLabelsSatisfiedBy returns true when every runs-on label of the job is one of the runner labels.
+// runnerLabels must be lower-cased.
+func jobLabelsSatisfiedBy(jobLabels []string, runnerLabels map[string]struct{}) bool {
+	if len(jobLabels) == 0 {
+		return false
+	}
+
+	for _, l := range jobLabels {
+		if _, ok := runnerLabels[strings.ToLower(l)]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func listAllWorkflowJobs(ghc *arcgithub.Client, user, repoName string, runID int64) ([]*github.WorkflowJob, error) {
+	opt := github.ListWorkflowJobsOptions{ListOptions: github.ListOptions{PerPage: 50}}
+	var allJobs []*github.WorkflowJob
+	for {
+		jobs, resp, err := ghc.Actions.ListWorkflowJobs(context.TODO(), user, repoName, runID, &opt)
+		if err != nil {
+			return nil, err
+		}
+		allJobs = append(allJobs, jobs.Jobs...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return allJobs, nil
+}
+
 func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunnersBusy(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*int, error) {
 	ctx := context.Background()
 	scaleUpThreshold := defaultScaleUpThreshold
@@ -377,14 +812,27 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 	}
 
 	var desiredReplicas int
-	fractionBusy := float64(numRunnersBusy+numTerminatingBusy) / float64(desiredReplicasBefore)
-	if fractionBusy >= scaleUpThreshold {
+	// The busy runners are compared to the runners hosted by the desired runner pods
+	fractionBusy := float64(numRunnersBusy+numTerminatingBusy) / float64(desiredReplicasBefore*max(st.slotsPerPod, 1))
+
+	// The thresholds are compared to the smoothed fraction, while the metrics keep reporting the latest sample
+	thresholdFraction := fractionBusy
+	if h := metrics.SmoothingHalfLife; h != nil && h.Duration > 0 {
+		thresholdFraction = r.busyFractionAverages.observe(
+			types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name},
+			h.Duration,
+			fractionBusy,
+			nowFrom(r.Clock),
+		)
+	}
+
+	if thresholdFraction >= scaleUpThreshold {
 		if scaleUpAdjustment > 0 {
 			desiredReplicas = desiredReplicasBefore + scaleUpAdjustment
 		} else {
 			desiredReplicas = int(math.Ceil(float64(desiredReplicasBefore) * scaleUpFactor))
 		}
-	} else if fractionBusy < scaleDownThreshold {
+	} else if thresholdFraction < scaleDownThreshold {
 		if scaleDownAdjustment > 0 {
 			desiredReplicas = desiredReplicasBefore - scaleDownAdjustment
 		} else {
@@ -410,8 +858,30 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 		numRunnersRegistered,
 		numRunnersBusy,
 		numTerminatingBusy,
+		fractionBusy,
 	)
 
+	r.ScaleExplanations.addMetric(hra, MetricExplanation{
+		Type:              v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+		SuggestedReplicas: &desiredReplicas,
+		Inputs: map[string]any{
+			"replicasDesiredBefore": desiredReplicasBefore,
+			"numRunners":            numRunners,
+			"numRunnersRegistered":  numRunnersRegistered,
+			"numRunnersBusy":        numRunnersBusy,
+			"numTerminatingBusy":    numTerminatingBusy,
+			"fractionBusy":          fractionBusy,
+			"fractionBusySmoothed":  thresholdFraction,
+			"scaleUpThreshold":      scaleUpThreshold,
+			"scaleDownThreshold":    scaleDownThreshold,
+			"scaleUpFactor":         scaleUpFactor,
+			"scaleDownFactor":       scaleDownFactor,
+			"scaleUpAdjustment":     scaleUpAdjustment,
+			"scaleDownAdjustment":   scaleDownAdjustment,
+			"slotsPerPod":           max(st.slotsPerPod, 1),
+		},
+	})
+
 	r.Log.V(1).Info(
 		fmt.Sprintf("Suggested desired replicas of %d by PercentageRunnersBusy", desiredReplicas),
 		"replicas_desired_before", desiredReplicasBefore,
@@ -420,6 +890,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 		"num_runners_registered", numRunnersRegistered,
 		"num_runners_busy", numRunnersBusy,
 		"num_terminating_busy", numTerminatingBusy,
+		"fraction_busy", fractionBusy,
+		"fraction_busy_smoothed", thresholdFraction,
 		"namespace", hra.Namespace,
 		"kind", st.kind,
 		"name", st.st,
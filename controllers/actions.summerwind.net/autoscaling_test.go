@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/github"
@@ -413,14 +414,14 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 				},
 			}
 
-			minReplicas, _, _, err := h.getMinReplicas(log, metav1Now.Time, hra)
+			minReplicas, _, _, err := h.getMinReplicas(context.Background(), log, metav1Now.Time, hra)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
 
 			st := h.scaleTargetFromRD(context.Background(), rd)
 
-			got, err := h.computeReplicasWithCache(client, log, metav1Now.Time, st, hra, minReplicas)
+			got, _, err := h.computeReplicasWithCache(client, log, metav1Now.Time, st, hra, minReplicas)
 			if err != nil {
 				if tc.err == "" {
 					t.Fatalf("unexpected error: expected none, got %v", err)
@@ -823,14 +824,14 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 				},
 			}
 
-			minReplicas, _, _, err := h.getMinReplicas(log, metav1Now.Time, hra)
+			minReplicas, _, _, err := h.getMinReplicas(context.Background(), log, metav1Now.Time, hra)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
 
 			st := h.scaleTargetFromRD(context.Background(), rd)
 
-			got, err := h.computeReplicasWithCache(client, log, metav1Now.Time, st, hra, minReplicas)
+			got, _, err := h.computeReplicasWithCache(client, log, metav1Now.Time, st, hra, minReplicas)
 			if err != nil {
 				if tc.err == "" {
 					t.Fatalf("unexpected error: expected none, got %v", err)
@@ -846,3 +847,401 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 		})
 	}
 }
+
+func TestDetermineDesiredReplicas_QueuedAndInProgressWorkflowJobs(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	metav1Now := metav1.Now()
+	testcases := []struct {
+		description string
+
+		labels       []string
+		nodeSelector map[string]string
+
+		workflowRuns_queued      string
+		workflowRuns_in_progress string
+		workflowJobs             map[int]string
+
+		want int
+	}{
+		{
+			description:              "only jobs whose runs-on labels are satisfied by the runner labels are counted",
+			labels:                   []string{"gpu"},
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"id": 2, "status":"in_progress"}]}"`,
+			workflowJobs: map[int]string{
+				// A matrix whose legs are routed to different runner pools
+				1: `{"jobs": [{"status":"queued", "labels":["self-hosted", "gpu"]}, {"status":"queued", "labels":["self-hosted", "cpu"]}, {"status":"queued", "labels":["self-hosted", "cpu"]}]}`,
+				2: `{"jobs": [{"status":"in_progress", "labels":["self-hosted", "gpu"]}, {"status":"completed", "labels":["self-hosted", "gpu"]}]}`,
+			},
+			want: 2,
+		},
+		{
+			description:              "labels are matched case-insensitively and default runner labels are implied",
+			labels:                   []string{"GPU"},
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 0, "workflow_runs":[]}"`,
+			workflowJobs: map[int]string{
+				1: `{"jobs": [{"status":"queued", "labels":["self-hosted", "Linux", "gpu"]}, {"status":"queued", "labels":["self-hosted", "windows", "gpu"]}, {"status":"queued", "labels":[]}]}`,
+			},
+			want: 1,
+		},
+		{
+			description:              "the architecture label is derived from the node selector",
+			labels:                   []string{"gpu"},
+			nodeSelector:             map[string]string{"kubernetes.io/arch": "arm64"},
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 0, "workflow_runs":[]}"`,
+			workflowJobs: map[int]string{
+				1: `{"jobs": [{"status":"queued", "labels":["self-hosted", "linux", "ARM64", "gpu"]}, {"status":"queued", "labels":["self-hosted", "linux", "x64", "gpu"]}]}`,
+			},
+			want: 1,
+		},
+		{
+			description:              "any architecture label is satisfied when the architecture isn't selected",
+			labels:                   []string{"gpu"},
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 0, "workflow_runs":[]}"`,
+			workflowJobs: map[int]string{
+				1: `{"jobs": [{"status":"queued", "labels":["self-hosted", "linux", "ARM64", "gpu"]}, {"status":"queued", "labels":["self-hosted", "linux", "x64", "gpu"]}]}`,
+			},
+			want: 2,
+		},
+		{
+			description:              "the OS label is derived from the node selector",
+			nodeSelector:             map[string]string{"kubernetes.io/os": "windows", "kubernetes.io/arch": "amd64"},
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 0, "workflow_runs":[]}"`,
+			workflowJobs: map[int]string{
+				1: `{"jobs": [{"status":"queued", "labels":["self-hosted", "Windows", "X64"]}, {"status":"queued", "labels":["self-hosted", "linux", "x64"]}]}`,
+			},
+			want: 1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(tc.description, func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200, "", tc.workflowRuns_queued, tc.workflowRuns_in_progress),
+				fake.WithListWorkflowJobsResponse(200, tc.workflowJobs),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log:                   log,
+				Scheme:                scheme,
+				DefaultScaleDownDelay: DefaultScaleDownDelay,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "testrd",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							RunnerConfig: v1alpha1.RunnerConfig{
+								Repository: "test/valid",
+								Labels:     tc.labels,
+							},
+							RunnerPodSpec: v1alpha1.RunnerPodSpec{
+								NodeSelector: tc.nodeSelector,
+							},
+						},
+					},
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: intPtr(10),
+					MinReplicas: intPtr(0),
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowJobs,
+						},
+					},
+				},
+			}
+
+			minReplicas, _, _, err := h.getMinReplicas(context.Background(), log, metav1Now.Time, hra)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			st := h.scaleTargetFromRD(context.Background(), rd)
+
+			got, _, err := h.computeReplicasWithCache(client, log, metav1Now.Time, st, hra, minReplicas)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSuggestDesiredReplicas_Fallback(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	workflowRuns := `{"total_count": 3, "workflow_runs":[{"id": 1, "status":"queued"}, {"id": 2, "status":"in_progress"}, {"id": 3, "status":"in_progress"}]}"`
+	workflowRunsQueued := `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`
+	workflowRunsInProgress := `{"total_count": 2, "workflow_runs":[{"id": 2, "status":"in_progress"}, {"id": 3, "status":"in_progress"}]}"`
+	workflowJobs := map[int]string{
+		1: `{"jobs": [{"status":"queued", "labels":["self-hosted"]}]}`,
+		2: `{"jobs": [{"status":"in_progress", "labels":["self-hosted"]}]}`,
+		3: `{"jobs": [{"status":"in_progress", "labels":["self-hosted"]}]}`,
+	}
+
+	testcases := []struct {
+		description        string
+		workflowRunsStatus int
+		fallbackReplicas   *int
+		secondary          bool
+		want               *int
+		wantSource         string
+		wantErr            bool
+	}{
+		{
+			description:        "secondary metric on primary error",
+			workflowRunsStatus: 200,
+			secondary:          true,
+			want:               intPtr(3),
+			wantSource:         v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		{
+			description:        "fallbackReplicas on secondary error",
+			workflowRunsStatus: 500,
+			secondary:          true,
+			fallbackReplicas:   intPtr(4),
+			want:               intPtr(4),
+			wantSource:         desiredReplicasSourceFallbackReplicas,
+		},
+		{
+			description:      "fallbackReplicas on primary error",
+			fallbackReplicas: intPtr(4),
+			want:             intPtr(4),
+			wantSource:       desiredReplicasSourceFallbackReplicas,
+		},
+		{
+			description: "error without fallback",
+			wantErr:     true,
+		},
+		{
+			description:        "error when the secondary metric also fails",
+			workflowRunsStatus: 500,
+			secondary:          true,
+			wantErr:            true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			opts := []fake.Option{
+				fake.WithListRunnersResponse(500, ""),
+			}
+			if tc.workflowRunsStatus != 0 {
+				opts = append(opts,
+					fake.WithListRepositoryWorkflowRunsResponse(tc.workflowRunsStatus, workflowRuns, workflowRunsQueued, workflowRunsInProgress),
+					fake.WithListWorkflowJobsResponse(200, workflowJobs),
+				)
+			}
+
+			server := fake.NewServer(opts...)
+			defer server.Close()
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log: zap.New(func(o *zap.Options) { o.Development = true }),
+			}
+
+			metrics := []v1alpha1.MetricSpec{
+				{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy},
+			}
+			if tc.secondary {
+				metrics = append(metrics, v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns})
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas:      intPtr(1),
+					MaxReplicas:      intPtr(10),
+					FallbackReplicas: tc.fallbackReplicas,
+					Metrics:          metrics,
+				},
+			}
+
+			st := scaleTarget{
+				st:       "testrd",
+				kind:     "runnerdeployment",
+				repo:     "test/valid",
+				labels:   []string{"self-hosted"},
+				replicas: intPtr(2),
+				getRunnerMap: func() (map[string]struct{}, error) {
+					return map[string]struct{}{}, nil
+				},
+			}
+
+			got, source, err := h.suggestDesiredReplicas(newGithubClient(server), st, hra)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got == nil || *got != *tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %v", *tc.want, *got)
+			}
+
+			if source != tc.wantSource {
+				t.Errorf("incorrect source: want %q, got %q", tc.wantSource, source)
+			}
+		})
+	}
+}
+
+func TestSuggestDesiredReplicas_OldestQueuedWorkflowJobAge(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	queuedAgo := func(d time.Duration) string {
+		return now.Add(-d).Format(time.RFC3339)
+	}
+
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"id": 1, "status":"queued"}, {"id": 2, "status":"in_progress"}]}"`
+	workflowRunsQueued := `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`
+	workflowRunsInProgress := `{"total_count": 1, "workflow_runs":[{"id": 2, "status":"in_progress"}]}"`
+
+	testcases := []struct {
+		description         string
+		queuedJobs          string
+		scaleUpAdjustment   int
+		scaleDownAdjustment int
+		threshold           *metav1.Duration
+		replicas            int
+		want                int
+	}{
+		{
+			description: "scale up by the jobs queued over the threshold",
+			queuedJobs:  fmt.Sprintf(`{"jobs": [{"status":"queued", "labels":["self-hosted"], "created_at":%q}, {"status":"queued", "labels":["self-hosted"], "created_at":%q}, {"status":"queued", "labels":["self-hosted"], "created_at":%q}]}`, queuedAgo(5*time.Minute), queuedAgo(3*time.Minute), queuedAgo(time.Minute)),
+			replicas:    1,
+			want:        3,
+		},
+		{
+			description: "don't add the jobs queued over the threshold again on every sync",
+			queuedJobs:  fmt.Sprintf(`{"jobs": [{"status":"queued", "labels":["self-hosted"], "created_at":%q}, {"status":"queued", "labels":["self-hosted"], "created_at":%q}, {"status":"queued", "labels":["self-hosted"], "created_at":%q}]}`, queuedAgo(5*time.Minute), queuedAgo(3*time.Minute), queuedAgo(time.Minute)),
+			replicas:    3,
+			want:        3,
+		},
+		{
+			description:       "scale up by scaleUpAdjustment",
+			queuedJobs:        fmt.Sprintf(`{"jobs": [{"status":"queued", "labels":["self-hosted"], "created_at":%q}]}`, queuedAgo(5*time.Minute)),
+			scaleUpAdjustment: 4,
+			replicas:          1,
+			want:              5,
+		},
+		{
+			description: "hold while jobs are queued within the threshold",
+			queuedJobs:  fmt.Sprintf(`{"jobs": [{"status":"queued", "labels":["self-hosted"], "created_at":%q}]}`, queuedAgo(time.Minute)),
+			replicas:    3,
+			want:        3,
+		},
+		{
+			description: "custom threshold",
+			queuedJobs:  fmt.Sprintf(`{"jobs": [{"status":"queued", "labels":["self-hosted"], "created_at":%q}]}`, queuedAgo(time.Minute)),
+			threshold:   &metav1.Duration{Duration: 30 * time.Second},
+			replicas:    1,
+			want:        2,
+		},
+		{
+			description: "jobs of other runners are ignored",
+			queuedJobs:  fmt.Sprintf(`{"jobs": [{"status":"queued", "labels":["self-hosted", "gpu"], "created_at":%q}]}`, queuedAgo(5*time.Minute)),
+			replicas:    3,
+			want:        1,
+		},
+		{
+			description:         "scale down by scaleDownAdjustment once nothing is queued",
+			queuedJobs:          `{"jobs": [{"status":"completed", "labels":["self-hosted"]}]}`,
+			scaleDownAdjustment: 1,
+			replicas:            3,
+			want:                2,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRunnersResponse(200, `{"total_count": 0, "runners": []}`),
+				fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRunsQueued, workflowRunsInProgress),
+				fake.WithListWorkflowJobsResponse(200, map[int]string{
+					1: tc.queuedJobs,
+					2: `{"jobs": [{"status":"in_progress", "labels":["self-hosted"]}]}`,
+				}),
+			)
+			defer server.Close()
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log:   zap.New(func(o *zap.Options) { o.Development = true }),
+				Clock: NewSimulatedClock(now),
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas: intPtr(1),
+					MaxReplicas: intPtr(10),
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type:                  v1alpha1.AutoscalingMetricTypeOldestQueuedWorkflowJobAge,
+							ScaleUpAdjustment:     tc.scaleUpAdjustment,
+							ScaleDownAdjustment:   tc.scaleDownAdjustment,
+							QueuedJobAgeThreshold: tc.threshold,
+						},
+					},
+				},
+			}
+
+			st := scaleTarget{
+				st:       "testrd",
+				kind:     "runnerdeployment",
+				repo:     "test/valid",
+				replicas: intPtr(tc.replicas),
+			}
+
+			got, source, err := h.suggestDesiredReplicas(newGithubClient(server), st, hra)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got == nil || *got != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %v", tc.want, got)
+			}
+
+			if source != v1alpha1.AutoscalingMetricTypeOldestQueuedWorkflowJobAge {
+				t.Errorf("incorrect source: got %q", source)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/hash"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	CapacityReservationLockTypeLease = "lease"
+
+	DefaultCapacityReservationLockDuration = 15 * time.Second
+
+	capacityReservationLockRetryInterval = 500 * time.Millisecond
+	capacityReservationLockNamePrefix    = "arc-capacity-reservations-"
+)
+
+// CapacityReservationLock serializes the capacity reservation updates of each HorizontalRunnerAutoscaler
+// across the replicas of the github webhook server.
+//
+// Every replica batches the webhook events it receives and applies them to the HRA by reading the current reservations,
+// computing the new ones, and writing them back. Without a lock, two replicas doing so for the same HRA at the same time
+// overwrite each other's reservations, which drops scale triggers.
+type CapacityReservationLock interface {
+	// Lock blocks until the lock of the HRA is acquired or ctx is done.
+	Lock(ctx context.Context, hra types.NamespacedName) error
+	// Unlock releases the lock of the HRA acquired by Lock.
+	Unlock(ctx context.Context, hra types.NamespacedName) error
+}
+
+// NewCapacityReservationLock returns the lock of the given type.
+// It returns nil without an error when lockType is empty, which disables the coordination between replicas.
+// The clock is optional. When set, it is used instead of the wall clock to acquire, renew and expire the leases.
+func NewCapacityReservationLock(lockType string, c client.Client, namespace, identity string, duration time.Duration, clock clock.PassiveClock) (CapacityReservationLock, error) {
+	switch lockType {
+	case "":
+		return nil, nil
+	case CapacityReservationLockTypeLease:
+		if namespace == "" {
+			return nil, fmt.Errorf("namespace is required for the %s capacity reservation lock", lockType)
+		}
+		if identity == "" {
+			return nil, fmt.Errorf("identity is required for the %s capacity reservation lock", lockType)
+		}
+		if duration <= 0 {
+			duration = DefaultCapacityReservationLockDuration
+		}
+		return &LeaseCapacityReservationLock{Client: c, Namespace: namespace, Identity: identity, LeaseDuration: duration, Clock: clock}, nil
+	default:
+		return nil, fmt.Errorf("unsupported capacity reservation lock type %q", lockType)
+	}
+}
+
+// LeaseCapacityReservationLock locks each HRA with a coordination.k8s.io Lease held by the replica updating the HRA.
+//
+// A lease that isn't renewed within LeaseDuration is considered abandoned, so that a replica that crashed while
+// holding it doesn't block the others forever.
+// LeaseDuration must therefore be longer than a batch update of an HRA takes.
+type LeaseCapacityReservationLock struct {
+	client.Client
+
+	Namespace string
+	// Identity distinguishes this replica from the others, usually the pod name.
+	Identity      string
+	LeaseDuration time.Duration
+
+	// Clock is optional. When set, it is used instead of the wall clock.
+	Clock clock.PassiveClock
+}
+
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;update
+
+// capacityReservationLockName returns the name of the Lease of the HRA.
+// The namespace and the name of an HRA don't always fit in a Lease name, so they're hashed.
+// A hash collision only makes the two HRAs share a lock, which is harmless.
+func capacityReservationLockName(hra types.NamespacedName) string {
+	return capacityReservationLockNamePrefix + hash.FNVHashString(hra.String())
+}
+
+func (l *LeaseCapacityReservationLock) Lock(ctx context.Context, hra types.NamespacedName) error {
+	for {
+		acquired, err := l.tryLock(ctx, hra)
+		if err != nil {
+			return err
+		}
+
+		if acquired {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for the capacity reservation lock of %s: %w", hra, ctx.Err())
+		case <-time.After(capacityReservationLockRetryInterval):
+		}
+	}
+}
+
+// tryLock acquires the lease of the HRA unless another replica holds it.
+func (l *LeaseCapacityReservationLock) tryLock(ctx context.Context, hra types.NamespacedName) (bool, error) {
+	key := types.NamespacedName{Namespace: l.Namespace, Name: capacityReservationLockName(hra)}
+	now := metav1.NewMicroTime(nowFrom(l.Clock))
+	durationSeconds := int32(l.LeaseDuration / time.Second)
+	if durationSeconds < 1 {
+		durationSeconds = 1
+	}
+
+	var lease coordinationv1.Lease
+	if err := l.Client.Get(ctx, key, &lease); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return false, fmt.Errorf("getting lease %s: %w", key, err)
+		}
+
+		lease = coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: key.Namespace,
+				Name:      key.Name,
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &l.Identity,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+
+		if err := l.Client.Create(ctx, &lease); err != nil {
+			if kerrors.IsAlreadyExists(err) {
+				// Another replica created it in the meantime
+				return false, nil
+			}
+			return false, fmt.Errorf("creating lease %s: %w", key, err)
+		}
+
+		return true, nil
+	}
+
+	if holder := lease.Spec.HolderIdentity; holder != nil && *holder != "" && *holder != l.Identity && !leaseExpired(lease, now.Time) {
+		return false, nil
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.Identity {
+		var transitions int32
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions
+		}
+		transitions++
+		lease.Spec.LeaseTransitions = &transitions
+	}
+
+	lease.Spec.HolderIdentity = &l.Identity
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+
+	// The update fails on a conflict when another replica acquired or released the lease after we read it
+	if err := l.Client.Update(ctx, &lease); err != nil {
+		if kerrors.IsConflict(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("updating lease %s: %w", key, err)
+	}
+
+	return true, nil
+}
+
+func (l *LeaseCapacityReservationLock) Unlock(ctx context.Context, hra types.NamespacedName) error {
+	key := types.NamespacedName{Namespace: l.Namespace, Name: capacityReservationLockName(hra)}
+
+	var lease coordinationv1.Lease
+	if err := l.Client.Get(ctx, key, &lease); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("getting lease %s: %w", key, err)
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.Identity {
+		// The lease expired and has been taken over by another replica
+		return nil
+	}
+
+	lease.Spec.HolderIdentity = nil
+	lease.Spec.AcquireTime = nil
+	lease.Spec.RenewTime = nil
+
+	if err := l.Client.Update(ctx, &lease); err != nil {
+		if kerrors.IsConflict(err) || kerrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("updating lease %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func leaseExpired(lease coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	return !now.Before(lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second))
+}
@@ -0,0 +1,121 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLeaseCapacityReservationLock(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	a, err := NewCapacityReservationLock(CapacityReservationLockTypeLease, c, "arc-system", "webhook-a", time.Minute, nil)
+	require.NoError(t, err)
+	b, err := NewCapacityReservationLock(CapacityReservationLockTypeLease, c, "arc-system", "webhook-b", time.Minute, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	hra := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	require.NoError(t, a.Lock(ctx, hra))
+
+	// Another replica waits until the holder releases the lock
+	waitCtx, cancel := context.WithTimeout(ctx, 2*capacityReservationLockRetryInterval)
+	defer cancel()
+	require.ErrorIs(t, b.Lock(waitCtx, hra), context.DeadlineExceeded)
+
+	// The lock of another HRA is independent
+	require.NoError(t, b.Lock(ctx, types.NamespacedName{Namespace: "default", Name: "other"}))
+
+	require.NoError(t, a.Unlock(ctx, hra))
+	require.NoError(t, b.Lock(ctx, hra))
+
+	// Unlocking what's held by another replica is a no-op
+	require.NoError(t, a.Unlock(ctx, hra))
+
+	var lease coordinationv1.Lease
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "arc-system", Name: capacityReservationLockName(hra)}, &lease))
+	require.Equal(t, "webhook-b", *lease.Spec.HolderIdentity)
+	require.Equal(t, int32(1), *lease.Spec.LeaseTransitions)
+
+	// A lease not renewed within its duration can be taken over
+	expired := metav1.NewMicroTime(time.Now().Add(-2 * time.Minute))
+	lease.Spec.RenewTime = &expired
+	require.NoError(t, c.Update(ctx, &lease))
+	require.NoError(t, a.Lock(ctx, hra))
+
+	_, err = NewCapacityReservationLock("redis", c, "arc-system", "webhook-a", time.Minute, nil)
+	require.Error(t, err)
+
+	_, err = NewCapacityReservationLock(CapacityReservationLockTypeLease, c, "arc-system", "", time.Minute, nil)
+	require.Error(t, err)
+}
+
+func TestBatchScale_CapacityReservationLock(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			MaxReplicas: intPtr(10),
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hra).Build()
+
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	// Simulate two webhook server replicas receiving different events for the same HRA
+	var replicas []*batchScaler
+	for _, identity := range []string{"webhook-a", "webhook-b"} {
+		lock, err := NewCapacityReservationLock(CapacityReservationLockTypeLease, c, "arc-system", identity, time.Minute, nil)
+		require.NoError(t, err)
+
+		s := newBatchScaler(ctx, c, logr.Discard(), nil)
+		s.lock = lock
+		replicas = append(replicas, s)
+	}
+
+	errs := make(chan error, len(replicas))
+	for i, s := range replicas {
+		s, jobID := s, int64(i+1)
+		go func() {
+			errs <- s.batchScale(ctx, batchScaleOperation{
+				namespacedName: key,
+				scaleOps: []scaleOperation{
+					{
+						log:   logr.Discard(),
+						jobID: jobID,
+						trigger: v1alpha1.ScaleUpTrigger{
+							Amount:   1,
+							Duration: metav1.Duration{Duration: time.Hour},
+						},
+					},
+				},
+			})
+		}()
+	}
+	for range replicas {
+		require.NoError(t, <-errs)
+	}
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(ctx, key, &updated))
+	require.Len(t, updated.Spec.CapacityReservations, 2)
+}
@@ -0,0 +1,269 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConfigMapCapacityReservationStore(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	store, err := NewCapacityReservationStore(CapacityReservationStoreTypeConfigMap, c, "arc-system", "", "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	hra := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	_, found, err := store.Get(ctx, hra)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	reservations := []v1alpha1.CapacityReservation{
+		{EffectiveTime: now, ExpirationTime: metav1.NewTime(now.Add(time.Minute)), Replicas: 1},
+	}
+	require.NoError(t, store.Put(ctx, hra, reservations))
+
+	got, found, err := store.Get(ctx, hra)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Len(t, got, 1)
+	require.True(t, got[0].ExpirationTime.Equal(&reservations[0].ExpirationTime))
+
+	// An empty list is stored as-is, so that it is distinguishable from nothing being stored
+	require.NoError(t, store.Put(ctx, hra, nil))
+	got, found, err = store.Get(ctx, hra)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Empty(t, got)
+
+	require.NoError(t, store.Delete(ctx, hra))
+	_, found, err = store.Get(ctx, hra)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	// Deleting what's already gone is a no-op
+	require.NoError(t, store.Delete(ctx, hra))
+
+	_, err = NewCapacityReservationStore("etcd", c, "arc-system", "", "")
+	require.Error(t, err)
+}
+
+func TestConfigMapCapacityReservationStore_Shards(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	ctx := context.Background()
+	hra := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	store, err := NewCapacityReservationStore(CapacityReservationStoreTypeConfigMap, c, "arc-system", "", "")
+	require.NoError(t, err)
+	s := store.(*ConfigMapCapacityReservationStore)
+
+	require.NoError(t, store.Put(ctx, hra, nil))
+
+	var shard corev1.ConfigMap
+	require.NoError(t, c.Get(ctx, s.shard(hra), &shard))
+	require.Equal(t, map[string]string{"default.example": "[]"}, shard.Data)
+	require.Equal(t, "16", shard.Annotations[annotationKeyCapacityReservationStoreShards])
+	require.Regexp(t, `^actions-runner-controller-capacity-reservations-\d+$`, shard.Name)
+
+	// The reservations that would make the shard exceed its size limit are rejected
+	huge := make([]v1alpha1.CapacityReservation, capacityReservationStoreMaxBytes/20)
+	err = store.Put(ctx, hra, huge)
+	require.ErrorIs(t, err, errCapacityReservationStoreFull)
+
+	got, found, err := store.Get(ctx, hra)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Empty(t, got)
+
+	// The shards written with another number of shards are refused, rather than losing the reservations stored in the other shards
+	shard.Annotations[annotationKeyCapacityReservationStoreShards] = "8"
+	require.NoError(t, c.Update(ctx, &shard))
+
+	_, _, err = store.Get(ctx, hra)
+	require.ErrorContains(t, err, "written with \"8\" shards")
+	require.ErrorContains(t, store.Put(ctx, hra, nil), "written with \"8\" shards")
+	require.ErrorContains(t, store.Delete(ctx, hra), "written with \"8\" shards")
+
+	delete(shard.Annotations, annotationKeyCapacityReservationStoreShards)
+	require.NoError(t, c.Update(ctx, &shard))
+
+	_, _, err = store.Get(ctx, hra)
+	require.ErrorContains(t, err, "doesn't record its number of shards")
+
+	require.NoError(t, c.Get(ctx, s.shard(hra), &shard))
+	require.Equal(t, map[string]string{"default.example": "[]"}, shard.Data)
+}
+
+func TestRedisCapacityReservationStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	store, err := NewCapacityReservationStore(CapacityReservationStoreTypeRedis, nil, "", "", "redis://"+mr.Addr()+"/0")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	hra := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	_, found, err := store.Get(ctx, hra)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	reservations := []v1alpha1.CapacityReservation{
+		{EffectiveTime: now, ExpirationTime: metav1.NewTime(now.Add(time.Minute)), Replicas: 1},
+	}
+	require.NoError(t, store.Put(ctx, hra, reservations))
+
+	got, found, err := store.Get(ctx, hra)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Len(t, got, 1)
+	require.True(t, got[0].ExpirationTime.Equal(&reservations[0].ExpirationTime))
+	require.True(t, mr.Exists(DefaultCapacityReservationStoreConfigMapName))
+
+	require.NoError(t, store.Put(ctx, hra, nil))
+	got, found, err = store.Get(ctx, hra)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Empty(t, got)
+
+	require.NoError(t, store.Delete(ctx, hra))
+	_, found, err = store.Get(ctx, hra)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	require.NoError(t, store.Delete(ctx, hra))
+
+	_, err = NewCapacityReservationStore(CapacityReservationStoreTypeRedis, nil, "", "", "")
+	require.Error(t, err)
+}
+
+func TestBatchScale_CapacityReservationStore(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			MaxReplicas: intPtr(10),
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hra).Build()
+
+	store, err := NewCapacityReservationStore(CapacityReservationStoreTypeConfigMap, c, "arc-system", "", "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	// Simulate reservations that were lost from the HRA spec, e.g. due to the HRA being re-applied
+	now := time.Now()
+	require.NoError(t, store.Put(ctx, key, []v1alpha1.CapacityReservation{
+		{EffectiveTime: metav1.NewTime(now), ExpirationTime: metav1.NewTime(now.Add(time.Hour)), Replicas: 1},
+	}))
+
+	s := newBatchScaler(ctx, c, logr.Discard(), store)
+
+	require.NoError(t, s.batchScale(ctx, batchScaleOperation{
+		namespacedName: key,
+		scaleOps: []scaleOperation{
+			{
+				log: logr.Discard(),
+				trigger: v1alpha1.ScaleUpTrigger{
+					Amount:   1,
+					Duration: metav1.Duration{Duration: time.Hour},
+				},
+			},
+		},
+	}))
+
+	stored, found, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Len(t, stored, 2)
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(ctx, key, &updated))
+	require.Len(t, updated.Spec.CapacityReservations, 2)
+}
+
+// fullCapacityReservationStore is a store whose reservations never fit within its size limit.
+type fullCapacityReservationStore struct {
+	CapacityReservationStore
+}
+
+func (s fullCapacityReservationStore) Put(context.Context, types.NamespacedName, []v1alpha1.CapacityReservation) error {
+	return fmt.Errorf("%w: too large", errCapacityReservationStoreFull)
+}
+
+func TestBatchScale_CapacityReservationStoreFull(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			MaxReplicas: intPtr(10),
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hra).Build()
+
+	store, err := NewCapacityReservationStore(CapacityReservationStoreTypeConfigMap, c, "arc-system", "", "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	now := time.Now()
+	require.NoError(t, store.Put(ctx, key, []v1alpha1.CapacityReservation{
+		{EffectiveTime: metav1.NewTime(now), ExpirationTime: metav1.NewTime(now.Add(time.Hour)), Replicas: 1},
+	}))
+
+	s := newBatchScaler(ctx, c, logr.Discard(), fullCapacityReservationStore{store})
+
+	require.NoError(t, s.batchScale(ctx, batchScaleOperation{
+		namespacedName: key,
+		scaleOps: []scaleOperation{
+			{
+				log: logr.Discard(),
+				trigger: v1alpha1.ScaleUpTrigger{
+					Amount:   1,
+					Duration: metav1.Duration{Duration: time.Hour},
+				},
+			},
+		},
+	}))
+
+	// The outdated reservations are removed from the store, which leaves the HRA as their record
+	_, found, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(ctx, key, &updated))
+	require.Len(t, updated.Spec.CapacityReservations, 2)
+}
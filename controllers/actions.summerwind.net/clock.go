@@ -0,0 +1,178 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ clock.PassiveClock = &SimulatedClock{}
+
+const (
+	// SimulatedClockPath is the path of the endpoint to read and advance the simulated clock.
+	SimulatedClockPath = "/simulated-clock"
+
+	// DefaultSimulatedClockBindAddress is the address the simulated clock is served on by default.
+	// It's bound to the loopback interface, so that only those who can port-forward to or exec into the pod can time-travel it.
+	DefaultSimulatedClockBindAddress = "127.0.0.1:8089"
+)
+
+// nowFrom returns the current time of the clock, or the wall clock time when the clock is nil.
+//
+// The clock provides the current time to the scaling policies, that is, scheduled overrides, scale-down delays,
+// capacity reservation expirations, and the lifecycles of warm standby runners.
+func nowFrom(c clock.PassiveClock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+
+	return c.Now()
+}
+
+// SimulatedClock is a clock.PassiveClock that stands still until it is explicitly set or advanced.
+//
+// It lets users test complex scheduled override and scale-down delay configurations deterministically,
+// by time-travelling the controller and the webhook-based autoscaler via the HTTP endpoint served by ServeHTTP.
+// It must never be used in production, as GitHub and Kubernetes still use the wall clock.
+type SimulatedClock struct {
+	mu          sync.Mutex
+	now         time.Time
+	subscribers []chan event.GenericEvent
+}
+
+func NewSimulatedClock(now time.Time) *SimulatedClock {
+	return &SimulatedClock{now: now}
+}
+
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *SimulatedClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Set moves the clock to t, which can be in the past.
+func (c *SimulatedClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = t
+
+	for _, s := range c.subscribers {
+		// The subscriber re-evaluates everything on any change, so coalescing changes is fine.
+		select {
+		case s <- event.GenericEvent{Object: &v1alpha1.HorizontalRunnerAutoscaler{}}:
+		default:
+		}
+	}
+}
+
+// Step advances the clock by d.
+func (c *SimulatedClock) Step(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// changes returns a channel that receives an event whenever the clock is set or advanced,
+// so that the controllers can re-evaluate the scaling policies immediately.
+func (c *SimulatedClock) changes() <-chan event.GenericEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan event.GenericEvent, 1)
+	c.subscribers = append(c.subscribers, ch)
+
+	return ch
+}
+
+// ServeHTTP responds with the current time of the clock in RFC 3339 format.
+// A POST request with either the "time" parameter in RFC 3339 format or the "advance" parameter
+// in Go's duration format, like "90m", sets or advances the clock before responding.
+func (c *SimulatedClock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if v := r.Form.Get("time"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parsing time: %v", err), http.StatusBadRequest)
+				return
+			}
+			c.Set(t)
+		} else if v := r.Form.Get("advance"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parsing advance: %v", err), http.StatusBadRequest)
+				return
+			}
+			c.Step(d)
+		} else {
+			http.Error(w, `either "time" or "advance" is required`, http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fmt.Fprintln(w, c.Now().Format(time.RFC3339))
+}
+
+// SimulatedClockServer serves the simulated clock on its own listener, apart from the unauthenticated metrics server,
+// as anyone who can reach it can change the scaling of all the runners.
+type SimulatedClockServer struct {
+	Addr  string
+	Clock *SimulatedClock
+	Log   logr.Logger
+}
+
+func (s *SimulatedClockServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(SimulatedClockPath, s.Clock)
+
+	srv := &http.Server{
+		Addr:              s.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		<-ctx.Done()
+
+		srv.Shutdown(context.Background())
+	}()
+
+	s.Log.Info("Starting simulated clock server", "addr", s.Addr)
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	<-done
+
+	return nil
+}
+
+// NeedLeaderElection makes every replica serve its clock, as each replica has a clock of its own.
+func (s *SimulatedClockServer) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,100 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSimulatedClock_ServeHTTP(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	c := NewSimulatedClock(start)
+	changes := c.changes()
+
+	do := func(method string, form url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, SimulatedClockPath, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "2024-01-01T09:00:00Z\n", rec.Body.String())
+	require.Len(t, changes, 0)
+
+	rec = do(http.MethodPost, url.Values{"advance": {"90m"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, start.Add(90*time.Minute), c.Now())
+	require.Len(t, changes, 1)
+
+	// Changes are coalesced while the subscriber is busy
+	rec = do(http.MethodPost, url.Values{"time": {"2024-12-25T00:00:00Z"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC), c.Now())
+	require.Len(t, changes, 1)
+
+	for _, form := range []url.Values{{}, {"advance": {"1 hour"}}, {"time": {"tomorrow"}}} {
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, form).Code, "%v", form)
+	}
+	require.Equal(t, http.StatusMethodNotAllowed, do(http.MethodDelete, nil).Code)
+}
+
+func TestBatchScale_SimulatedClock(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				{EffectiveTime: metav1.NewTime(start), ExpirationTime: metav1.NewTime(start.Add(time.Hour)), Replicas: 1},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hra).Build()
+
+	clock := NewSimulatedClock(start.Add(2 * time.Hour))
+	s := newBatchScaler(context.Background(), c, logr.Discard(), nil)
+	s.clock = clock
+
+	key := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	require.NoError(t, s.batchScale(context.Background(), batchScaleOperation{
+		namespacedName: key,
+		scaleOps: []scaleOperation{
+			{
+				log: logr.Discard(),
+				trigger: v1alpha1.ScaleUpTrigger{
+					Amount:   1,
+					Duration: metav1.Duration{Duration: 30 * time.Minute},
+				},
+			},
+		},
+	}))
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(context.Background(), key, &updated))
+
+	// The existing reservation expired in the simulated time, and the new one is based on the simulated time
+	require.Len(t, updated.Spec.CapacityReservations, 1)
+	require.True(t, updated.Spec.CapacityReservations[0].ExpirationTime.Time.Equal(clock.Now().Add(30*time.Minute)))
+}
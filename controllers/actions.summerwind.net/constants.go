@@ -39,6 +39,65 @@ const (
 
 	AnnotationKeyRunnerID = annotationKeyPrefix + "id"
 
+	// AnnotationKeyRunnerImage is the annotation that a runner image build pipeline can set on a RunnerDeployment
+	// to roll out a new runner image, without modifying the spec that is usually owned by Helm or a GitOps tool.
+	// Its value takes precedence over spec.template.spec.image.
+	AnnotationKeyRunnerImage = annotationKeyPrefix + "runner-image"
+
+	// AnnotationKeyRestartedAt is the annotation that can be set on a RunnerDeployment or a RunnerSet to recycle all its runners,
+	// like `kubectl rollout restart` does for Deployments. Changing its value rolls out new runners the same way as a template change does,
+	// so that busy runners are replaced only once their jobs completed.
+	AnnotationKeyRestartedAt = annotationKeyPrefix + "restarted-at"
+
+	// AnnotationKeyWarmStandby is the annotation that is set to "true" on a warm standby runner and its pod,
+	// which pauses the runner before registration, and is set to "false" once the runner is promoted.
+	AnnotationKeyWarmStandby = annotationKeyPrefix + "warm-standby"
+
+	// AnnotationKeyWarmStandbyPromotionTimestamp is the annotation that contains the time a warm standby runner was promoted.
+	// The registration timeout of a promoted runner starts at this time rather than when the pod became ready.
+	AnnotationKeyWarmStandbyPromotionTimestamp = annotationKeyPrefix + "warm-standby-promotion-timestamp"
+
+	// AnnotationKeyCheckpointOnInterruption is the annotation that opts a runner pod into being checkpointed and restored
+	// on another node when its node is about to be interrupted, like a spot instance. It's set to "true" on the runner template.
+	AnnotationKeyCheckpointOnInterruption = annotationKeyPrefix + "checkpoint-on-interruption"
+
+	// AnnotationKeyCheckpointStartTimestamp is the annotation that is added onto the runner pod when ARC started checkpointing it.
+	// The checkpoint of a pod is attempted only once.
+	AnnotationKeyCheckpointStartTimestamp = annotationKeyPrefix + "checkpoint-start-timestamp"
+
+	// AnnotationKeyCheckpointImage is the annotation that is added onto the runner once the image of the checkpoint of its pod is pushed.
+	// The runner pod is then recreated from the image, and the annotation is removed.
+	AnnotationKeyCheckpointImage = annotationKeyPrefix + "checkpoint-image"
+
+	// AnnotationKeyRestoredCheckpointImage is the annotation that contains the checkpoint image a runner pod was restored from.
+	AnnotationKeyRestoredCheckpointImage = annotationKeyPrefix + "restored-checkpoint-image"
+
+	// AnnotationKeyNodeInterruptionTimestamp is the annotation that is added onto the runner pod and its owner when ARC noticed that
+	// the node of the pod is about to be interrupted. The pod is then unregistered so that it takes no new job, and replaced ahead of time.
+	AnnotationKeyNodeInterruptionTimestamp = annotationKeyPrefix + "node-interruption-timestamp"
+
+	// AnnotationKeyIdleTimeout is the annotation that contains the idleTimeout of the runner on its pod.
+	AnnotationKeyIdleTimeout = annotationKeyPrefix + "idle-timeout"
+
+	// AnnotationKeyIdleSinceTimestamp is the annotation that contains the time ARC first saw the runner of the pod idle.
+	// It's removed whenever the runner is seen busy.
+	AnnotationKeyIdleSinceTimestamp = annotationKeyPrefix + "idle-since-timestamp"
+
+	// AnnotationKeyIdleTimeoutTimestamp is the annotation that is added onto the runner pod once it has been idle for its idleTimeout,
+	// and onto its owner once ARC started unregistering it. The pool doesn't replace the owners unregistered this way.
+	AnnotationKeyIdleTimeoutTimestamp = annotationKeyPrefix + "idle-timeout-timestamp"
+
+	// AnnotationKeyIdleScaleIn is the annotation of a RunnerReplicaSet or a RunnerSet that records the number of its runners
+	// deleted on idle timeout that aren't replaced, and the desired replicas they were deleted at, like "2/5".
+	AnnotationKeyIdleScaleIn = annotationKeyPrefix + "idle-scale-in"
+
+	// AnnotationKeySlotsPerPod is the annotation that contains the number of runners hosted by the runner pod, when it's more than one.
+	AnnotationKeySlotsPerPod = annotationKeyPrefix + "slots-per-pod"
+
+	// AnnotationKeyJITConfig is the annotation that is set to "true" on a runner pod that registers its runner with a just-in-time configuration
+	// instead of a registration token.
+	AnnotationKeyJITConfig = annotationKeyPrefix + "jit-config"
+
 	// This can be any value but a larger value can make an unregistration timeout longer than configured in practice.
 	DefaultUnregistrationRetryDelay = time.Minute
 
@@ -0,0 +1,207 @@
+package actionssummerwindnet
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const exclusionCalendarDateLayout = "2006-01-02"
+
+// ExclusionCalendar is a set of dates on which recurrences of a scheduled override are skipped.
+type ExclusionCalendar struct {
+	// Location is the time zone used to determine the date of a recurrence.
+	Location *time.Location
+
+	dates map[string]struct{}
+}
+
+// NewExclusionCalendar builds an ExclusionCalendar out of the dates and date ranges in the format of
+// YYYY-MM-DD and YYYY-MM-DD/YYYY-MM-DD respectively, and the optional iCalendar document.
+// The time zone defaults to the X-WR-TIMEZONE of the document, if any, and to UTC otherwise.
+func NewExclusionCalendar(timeZone string, dates []string, ical string) (*ExclusionCalendar, error) {
+	c := &ExclusionCalendar{dates: map[string]struct{}{}}
+
+	var icalTimeZone string
+	if ical != "" {
+		var err error
+		icalTimeZone, err = c.addICal(ical)
+		if err != nil {
+			return nil, fmt.Errorf("parsing icalendar: %w", err)
+		}
+	}
+
+	if timeZone == "" {
+		timeZone = icalTimeZone
+	}
+
+	c.Location = time.UTC
+	if timeZone != "" {
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("loading time zone %q: %w", timeZone, err)
+		}
+		c.Location = loc
+	}
+
+	for _, d := range dates {
+		start, end, isRange := strings.Cut(d, "/")
+		if !isRange {
+			end = start
+		}
+
+		from, err := time.Parse(exclusionCalendarDateLayout, start)
+		if err != nil {
+			return nil, fmt.Errorf("parsing excluded date %q: %w", d, err)
+		}
+
+		to, err := time.Parse(exclusionCalendarDateLayout, end)
+		if err != nil {
+			return nil, fmt.Errorf("parsing excluded date %q: %w", d, err)
+		}
+
+		if err := c.addRange(from, to); err != nil {
+			return nil, fmt.Errorf("excluded date range %q: %w", d, err)
+		}
+	}
+
+	return c, nil
+}
+
+// Excludes returns true when the date of t is excluded.
+func (c *ExclusionCalendar) Excludes(t time.Time) bool {
+	if c == nil {
+		return false
+	}
+
+	if c.Location != nil {
+		t = t.In(c.Location)
+	}
+
+	_, ok := c.dates[t.Format(exclusionCalendarDateLayout)]
+
+	return ok
+}
+
+// maxExclusionRangeDays prevents a typo in a range, like 2024-12-24/2042-12-24, from allocating a huge set.
+const maxExclusionRangeDays = 366
+
+func (c *ExclusionCalendar) addRange(from, to time.Time) error {
+	if to.Before(from) {
+		return fmt.Errorf("end date %s is before start date %s", to.Format(exclusionCalendarDateLayout), from.Format(exclusionCalendarDateLayout))
+	}
+
+	for i, d := 0, from; !d.After(to); i, d = i+1, d.AddDate(0, 0, 1) {
+		if i >= maxExclusionRangeDays {
+			return fmt.Errorf("ranges longer than %d days are not supported", maxExclusionRangeDays)
+		}
+		c.dates[d.Format(exclusionCalendarDateLayout)] = struct{}{}
+	}
+
+	return nil
+}
+
+// addICal adds the dates covered by every VEVENT in the iCalendar document, and returns the X-WR-TIMEZONE of the document, if any.
+// Only DTSTART and DTEND are read. As in RFC 5545, DTEND is exclusive, and an event without DTEND covers the date of DTSTART only.
+// Recurring events are rejected rather than excluding their first occurrence only, as their occurrences aren't expanded.
+func (c *ExclusionCalendar) addICal(doc string) (string, error) {
+	var (
+		inEvent    bool
+		start, end *time.Time
+		timeZone   string
+	)
+
+	lines, err := unfoldICalLines(doc)
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		// Drop parameters like ;VALUE=DATE or ;TZID=Europe/Berlin
+		name, _, _ = strings.Cut(name, ";")
+		name = strings.ToUpper(name)
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			inEvent, start, end = true, nil, nil
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if !inEvent {
+				return "", fmt.Errorf("unexpected END:VEVENT")
+			}
+			inEvent = false
+
+			if start == nil {
+				return "", fmt.Errorf("VEVENT without DTSTART")
+			}
+
+			last := *start
+			if end != nil && end.After(*start) {
+				last = end.AddDate(0, 0, -1)
+			}
+
+			if err := c.addRange(*start, last); err != nil {
+				return "", err
+			}
+		case inEvent && (name == "RRULE" || name == "RDATE"):
+			return "", fmt.Errorf("recurring VEVENT with %s is not supported, list each occurrence as an event or in dates instead", name)
+		case !inEvent && name == "X-WR-TIMEZONE":
+			timeZone = value
+		case inEvent && (name == "DTSTART" || name == "DTEND"):
+			d, err := parseICalDate(value)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", name, err)
+			}
+
+			if name == "DTSTART" {
+				start = &d
+			} else {
+				end = &d
+			}
+		}
+	}
+
+	if inEvent {
+		return "", fmt.Errorf("VEVENT is not terminated")
+	}
+
+	return timeZone, nil
+}
+
+// parseICalDate parses the date part of an iCalendar DATE or DATE-TIME value, like 20241225 or 20241225T090000Z.
+func parseICalDate(v string) (time.Time, error) {
+	if len(v) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", v)
+	}
+
+	return time.Parse("20060102", v[:8])
+}
+
+// unfoldICalLines splits the document into lines, joining folded lines as described in RFC 5545 section 3.1.
+func unfoldICalLines(doc string) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(strings.NewReader(doc))
+	// Lines are usually folded at 75 octets, but nothing prevents a producer from writing a long description on a single line
+	scanner.Buffer(nil, max(len(doc)+1, bufio.MaxScanTokenSize))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading iCalendar lines: %w", err)
+	}
+
+	return lines, nil
+}
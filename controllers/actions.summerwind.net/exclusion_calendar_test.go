@@ -0,0 +1,154 @@
+package actionssummerwindnet
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExclusionCalendar(t *testing.T) {
+	ical := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Independence\r\n" +
+		"  Day\r\n" +
+		"DTSTART;VALUE=DATE:20240704\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Shutdown\r\n" +
+		"DTSTART;VALUE=DATE:20241223\r\n" +
+		"DTEND;VALUE=DATE:20241228\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	c, err := NewExclusionCalendar("", []string{"2024-05-27", "2024-11-28/2024-11-29"}, ical)
+	require.NoError(t, err)
+
+	date := func(s string) time.Time {
+		t.Helper()
+		d, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return d
+	}
+
+	for _, excluded := range []string{
+		"2024-05-27T09:00:00Z",
+		"2024-11-28T09:00:00Z",
+		"2024-11-29T09:00:00Z",
+		"2024-07-04T09:00:00Z",
+		"2024-12-23T09:00:00Z",
+		"2024-12-27T09:00:00Z",
+	} {
+		require.True(t, c.Excludes(date(excluded)), excluded)
+	}
+
+	for _, included := range []string{
+		"2024-05-28T09:00:00Z",
+		"2024-11-30T09:00:00Z",
+		"2024-07-05T09:00:00Z",
+		// DTEND is exclusive
+		"2024-12-28T09:00:00Z",
+	} {
+		require.False(t, c.Excludes(date(included)), included)
+	}
+
+	var nilCalendar *ExclusionCalendar
+	require.False(t, nilCalendar.Excludes(date("2024-05-27T09:00:00Z")))
+}
+
+func TestNewExclusionCalendar_LongLine(t *testing.T) {
+	ical := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DESCRIPTION:" + strings.Repeat("x", 128*1024) + "\r\n" +
+		"DTSTART;VALUE=DATE:20240704\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	c, err := NewExclusionCalendar("", nil, ical)
+	require.NoError(t, err)
+	require.True(t, c.Excludes(time.Date(2024, 7, 4, 9, 0, 0, 0, time.UTC)))
+}
+
+func TestNewExclusionCalendar_TimeZone(t *testing.T) {
+	c, err := NewExclusionCalendar("Asia/Tokyo", []string{"2024-01-01"}, "")
+	require.NoError(t, err)
+
+	// 2023-12-31T16:00:00Z is 2024-01-01 in Tokyo
+	require.True(t, c.Excludes(time.Date(2023, 12, 31, 16, 0, 0, 0, time.UTC)))
+	require.False(t, c.Excludes(time.Date(2023, 12, 31, 14, 0, 0, 0, time.UTC)))
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// Defaults to UTC rather than the time zone of the recurrence
+	c, err = NewExclusionCalendar("", []string{"2024-01-01"}, "")
+	require.NoError(t, err)
+	require.Equal(t, time.UTC, c.Location)
+	require.False(t, c.Excludes(time.Date(2024, 1, 1, 8, 0, 0, 0, tokyo)))
+	require.True(t, c.Excludes(time.Date(2024, 1, 1, 10, 0, 0, 0, tokyo)))
+
+	ical := "BEGIN:VCALENDAR\r\n" +
+		"X-WR-TIMEZONE:Asia/Tokyo\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20240101\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	// Defaults to the time zone of the iCalendar document
+	c, err = NewExclusionCalendar("", nil, ical)
+	require.NoError(t, err)
+	require.Equal(t, tokyo.String(), c.Location.String())
+	require.True(t, c.Excludes(time.Date(2023, 12, 31, 16, 0, 0, 0, time.UTC)))
+
+	// timeZone takes precedence over the time zone of the document
+	c, err = NewExclusionCalendar("UTC", nil, ical)
+	require.NoError(t, err)
+	require.False(t, c.Excludes(time.Date(2023, 12, 31, 16, 0, 0, 0, time.UTC)))
+}
+
+func TestNewExclusionCalendar_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		timeZone string
+		dates    []string
+		ical     string
+	}{
+		{dates: []string{"2024/01/01"}},
+		{dates: []string{"2024-01-05/2024-01-01"}},
+		{dates: []string{"2024-01-01/2026-01-01"}},
+		{timeZone: "Nowhere/Nothing"},
+		{ical: "BEGIN:VEVENT\nSUMMARY:no dtstart\nEND:VEVENT\n"},
+		{ical: "BEGIN:VEVENT\nDTSTART:2024\nEND:VEVENT\n"},
+		{ical: "BEGIN:VEVENT\nDTSTART:20240101\n"},
+		{ical: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20241225\nRRULE:FREQ=YEARLY\nEND:VEVENT\n"},
+		{ical: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20241225\nRDATE;VALUE=DATE:20251225\nEND:VEVENT\n"},
+		{ical: "X-WR-TIMEZONE:Nowhere/Nothing\nBEGIN:VEVENT\nDTSTART:20240101\nEND:VEVENT\n"},
+	} {
+		_, err := NewExclusionCalendar(tc.timeZone, tc.dates, tc.ical)
+		require.Error(t, err, "%+v", tc)
+	}
+}
+
+func TestMatchSchedule_Exclusions(t *testing.T) {
+	exclusions, err := NewExclusionCalendar("", []string{"2024-12-25", "2024-12-26"}, "")
+	require.NoError(t, err)
+
+	// Business hours on every weekday starting Monday, 2024-12-23
+	start := time.Date(2024, 12, 23, 9, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 12, 23, 17, 0, 0, 0, time.UTC)
+	rule := RecurrenceRule{Frequency: "Daily", Exclusions: exclusions}
+
+	// On an excluded date, the override isn't active and the upcoming one skips the following excluded date
+	active, upcoming, err := MatchSchedule(time.Date(2024, 12, 25, 10, 0, 0, 0, time.UTC), start, end, rule)
+	require.NoError(t, err)
+	require.Nil(t, active)
+	require.NotNil(t, upcoming)
+	require.Equal(t, time.Date(2024, 12, 27, 9, 0, 0, 0, time.UTC), upcoming.StartTime)
+
+	// On a date that isn't excluded, the override is active as usual
+	active, _, err = MatchSchedule(time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC), start, end, rule)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	require.Equal(t, time.Date(2024, 12, 24, 9, 0, 0, 0, time.UTC), active.StartTime)
+}
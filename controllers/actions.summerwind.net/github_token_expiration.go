@@ -0,0 +1,39 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// githubTokenExpirationWarning is how long before the expiration of the personal access token the resources using it are warned.
+	githubTokenExpirationWarning = 7 * 24 * time.Hour
+
+	// EventReasonGitHubTokenExpiring is the reason of the warning events of the resources whose personal access token expires soon.
+	EventReasonGitHubTokenExpiring = "GitHubTokenExpiring"
+)
+
+// warnIfGitHubTokenExpiring records a warning event on the object when the personal access token it uses expires soon,
+// so that the credentials are rotated before the runners stop registering.
+// It returns true when the warning is recorded.
+func warnIfGitHubTokenExpiring(recorder record.EventRecorder, obj runtime.Object, expiresAt time.Time, now time.Time) bool {
+	remaining := expiresAt.Sub(now)
+	if remaining > githubTokenExpirationWarning {
+		return false
+	}
+
+	var msg string
+	if remaining <= 0 {
+		msg = fmt.Sprintf("The GitHub personal access token expired at %s. Runners can no longer be registered until it's rotated", expiresAt.Format(time.RFC3339))
+	} else {
+		msg = fmt.Sprintf("The GitHub personal access token expires at %s, in %s. Rotate it before runners stop registering", expiresAt.Format(time.RFC3339), remaining.Round(time.Minute))
+	}
+
+	recorder.Event(obj, corev1.EventTypeWarning, EventReasonGitHubTokenExpiring, msg)
+
+	return true
+}
@@ -0,0 +1,26 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestWarnIfGitHubTokenExpiring(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	runner := &v1alpha1.Runner{}
+
+	recorder := record.NewFakeRecorder(10)
+
+	require.False(t, warnIfGitHubTokenExpiring(recorder, runner, now.Add(30*24*time.Hour), now))
+	require.Empty(t, recorder.Events)
+
+	require.True(t, warnIfGitHubTokenExpiring(recorder, runner, now.Add(3*24*time.Hour), now))
+	require.Equal(t, "Warning GitHubTokenExpiring The GitHub personal access token expires at 2024-06-04T12:00:00Z, in 72h0m0s. Rotate it before runners stop registering", <-recorder.Events)
+
+	require.True(t, warnIfGitHubTokenExpiring(recorder, runner, now.Add(-time.Hour), now))
+	require.Contains(t, <-recorder.Events, "expired at 2024-06-01T11:00:00Z")
+}
@@ -2,14 +2,17 @@ package actionssummerwindnet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
 	"github.com/go-logr/logr"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -19,27 +22,79 @@ type batchScaler struct {
 	Log      logr.Logger
 	interval time.Duration
 
+	// store is optional. When set, capacity reservations are read from and written to it
+	// in addition to HRA.Spec.CapacityReservations.
+	store CapacityReservationStore
+
+	// lock is optional. When set, it is held while the capacity reservations of an HRA are updated,
+	// so that multiple webhook server replicas can update the same HRA without overwriting each other's reservations.
+	lock CapacityReservationLock
+
+	// reader is optional. When set, the HRA is read with it instead of Client.
+	// It must be an uncached reader when lock is set, as the cache may not have seen the update made by
+	// the replica that held the lock before.
+	reader client.Reader
+
+	// clock is optional. When set, it is used instead of the wall clock.
+	clock clock.PassiveClock
+
+	// maxInProgressDuration is how long the reservations of a started job are held at most.
+	// Defaults to DefaultMaxInProgressCapacityReservationDuration.
+	maxInProgressDuration time.Duration
+
+	// events is optional. When set, the capacity reservations added or removed by the webhook deliveries are emitted to it.
+	events *ScalingEventPublisher
+
 	queue       chan *ScaleTarget
 	workerStart sync.Once
+	// workerDone is closed once the batch worker stopped due to Ctx being done.
+	workerDone chan struct{}
+
+	// delayed counts the scale targets waiting in addAfter.
+	delayed sync.WaitGroup
+
+	// unapplied is the operations that were left unapplied when Ctx was done.
+	// It's guarded by mu.
+	unapplied []batchScaleOperation
+	mu        sync.Mutex
 }
 
-func newBatchScaler(ctx context.Context, client client.Client, log logr.Logger) *batchScaler {
+func newBatchScaler(ctx context.Context, client client.Client, log logr.Logger, store CapacityReservationStore) *batchScaler {
 	return &batchScaler{
-		Ctx:      ctx,
-		Client:   client,
-		Log:      log,
-		interval: 3 * time.Second,
+		Ctx:        ctx,
+		Client:     client,
+		Log:        log,
+		interval:   3 * time.Second,
+		store:      store,
+		workerDone: make(chan struct{}),
 	}
 }
 
 type batchScaleOperation struct {
 	namespacedName types.NamespacedName
 	scaleOps       []scaleOperation
+
+	// retryAfter is when the operation was going to be retried after failing to be applied.
+	// It's set only for the operations left unapplied when the batch scaler stopped.
+	retryAfter time.Time
 }
 
 type scaleOperation struct {
-	trigger v1alpha1.ScaleUpTrigger
-	log     logr.Logger
+	trigger    v1alpha1.ScaleUpTrigger
+	repository string
+	jobID      int64
+	renew      bool
+	log        logr.Logger
+	done       func()
+
+	// event and receivedAt are the type and the receipt time of the webhook event, recorded in the metrics.
+	event      string
+	receivedAt time.Time
+	// deliveryID is the X-GitHub-Delivery of the webhook delivery, attached to the scaling events.
+	deliveryID string
+	// added and removed are the numbers of capacity reservations the operation added and removed, set by planBatchScale.
+	added   int
+	removed int
 }
 
 // Add the scale target to the unbounded queue, blocking until the target is successfully added to the queue.
@@ -61,14 +116,9 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 		go func() {
 			log.Info("Starting batch worker")
 			defer log.Info("Stopped batch worker")
+			defer close(s.workerDone)
 
 			for {
-				select {
-				case <-s.Ctx.Done():
-					return
-				default:
-				}
-
 				log.V(2).Info("Batch worker is dequeueing operations")
 
 				batches := map[types.NamespacedName]batchScaleOperation{}
@@ -78,24 +128,13 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 			batch:
 				for {
 					select {
+					case <-s.Ctx.Done():
+						s.setAside(batches, time.Time{})
+						return
 					case <-after:
 						break batch
 					case st := <-s.queue:
-						nsName := types.NamespacedName{
-							Namespace: st.HorizontalRunnerAutoscaler.Namespace,
-							Name:      st.HorizontalRunnerAutoscaler.Name,
-						}
-						b, ok := batches[nsName]
-						if !ok {
-							b = batchScaleOperation{
-								namespacedName: nsName,
-							}
-						}
-						b.scaleOps = append(b.scaleOps, scaleOperation{
-							log:     *st.log,
-							trigger: st.ScaleUpTrigger,
-						})
-						batches[nsName] = b
+						addToBatches(batches, st)
 						ops++
 					}
 				}
@@ -113,6 +152,21 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 							failed[nsName] = b
 						} else {
 							log.V(2).Info("Successfully ran batch scale", "hra", b.namespacedName)
+
+							for _, op := range b.scaleOps {
+								if op.event != "" {
+									metrics.AddGitHubWebhookCapacityReservationsCreated(op.event, nsName.Name, nsName.Namespace, op.added)
+									if !op.receivedAt.IsZero() {
+										metrics.ObserveGitHubWebhookScaleLatency(op.event, nsName.Name, nsName.Namespace, nowFrom(s.clock).Sub(op.receivedAt))
+									}
+								}
+
+								s.publishScalingEvent(nsName, op)
+
+								if op.done != nil {
+									op.done()
+								}
+							}
 						}
 					}
 
@@ -126,30 +180,173 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 					if i < len(expBackoff) {
 						delay = expBackoff[i]
 					}
-					time.Sleep(delay)
+
+					select {
+					case <-s.Ctx.Done():
+						s.setAside(batches, nowFrom(s.clock).Add(delay))
+						return
+					case <-time.After(delay):
+					}
 				}
 			}
 		}()
 	})
 
-	s.queue <- st
+	select {
+	case s.queue <- st:
+	case <-s.Ctx.Done():
+		batches := map[types.NamespacedName]batchScaleOperation{}
+		addToBatches(batches, st)
+		s.setAside(batches, time.Time{})
+	}
+}
+
+// addAfter adds the scale target once the time t has come.
+// The scale target is left unapplied when Ctx is done before that.
+func (s *batchScaler) addAfter(st *ScaleTarget, t time.Time) {
+	d := time.Until(t)
+	if d <= 0 {
+		s.Add(st)
+		return
+	}
+
+	s.delayed.Add(1)
+
+	go func() {
+		defer s.delayed.Done()
+
+		select {
+		case <-s.Ctx.Done():
+			batches := map[types.NamespacedName]batchScaleOperation{}
+			addToBatches(batches, st)
+			s.setAside(batches, t)
+		case <-time.After(d):
+			s.Add(st)
+		}
+	}()
+}
+
+func addToBatches(batches map[types.NamespacedName]batchScaleOperation, st *ScaleTarget) {
+	nsName := types.NamespacedName{
+		Namespace: st.HorizontalRunnerAutoscaler.Namespace,
+		Name:      st.HorizontalRunnerAutoscaler.Name,
+	}
+	b, ok := batches[nsName]
+	if !ok {
+		b = batchScaleOperation{
+			namespacedName: nsName,
+		}
+	}
+	b.scaleOps = append(b.scaleOps, scaleOperation{
+		log:        *st.log,
+		trigger:    st.ScaleUpTrigger,
+		repository: st.Repository,
+		jobID:      st.JobID,
+		renew:      st.Renew,
+		done:       st.done,
+		event:      st.event,
+		receivedAt: st.receivedAt,
+		deliveryID: st.deliveryID,
+	})
+	batches[nsName] = b
+}
+
+// setAside records the batches left unapplied due to Ctx being done.
+func (s *batchScaler) setAside(batches map[types.NamespacedName]batchScaleOperation, retryAfter time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range batches {
+		b.retryAfter = retryAfter
+		s.unapplied = append(s.unapplied, b)
+	}
+}
+
+// unappliedOperations waits for the batch scaler to stop after Ctx is done,
+// and returns the operations it left unapplied.
+func (s *batchScaler) unappliedOperations() []batchScaleOperation {
+	// Prevent the worker from starting afterwards, so that there's nothing to wait for when it never started.
+	// Any scale target added afterwards is set aside right away, as Ctx is done.
+	s.workerStart.Do(func() {
+		close(s.workerDone)
+	})
+
+	<-s.workerDone
+	s.delayed.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.unapplied
 }
 
 func (s *batchScaler) batchScale(ctx context.Context, batch batchScaleOperation) error {
+	if s.lock != nil {
+		if err := s.lock.Lock(ctx, batch.namespacedName); err != nil {
+			return fmt.Errorf("acquiring capacity reservation lock: %w", err)
+		}
+
+		defer func() {
+			if err := s.lock.Unlock(ctx, batch.namespacedName); err != nil {
+				s.Log.Error(err, "Failed to release capacity reservation lock", "hra", batch.namespacedName)
+			}
+		}()
+	}
+
+	var reader client.Reader = s.Client
+	if s.reader != nil {
+		reader = s.reader
+	}
+
 	var hra v1alpha1.HorizontalRunnerAutoscaler
 
-	if err := s.Client.Get(ctx, batch.namespacedName, &hra); err != nil {
+	if err := reader.Get(ctx, batch.namespacedName, &hra); err != nil {
 		return err
 	}
 
-	now := time.Now()
+	now := nowFrom(s.clock)
+
+	base := &hra
+	if s.store != nil {
+		reservations, found, err := s.store.Get(ctx, batch.namespacedName)
+		if err != nil {
+			return fmt.Errorf("getting stored capacity reservations: %w", err)
+		}
+
+		if found {
+			base = hra.DeepCopy()
+			base.Spec.CapacityReservations = reservations
+		}
+	}
 
-	copy, err := s.planBatchScale(ctx, batch, &hra, now)
+	copy, err := s.planBatchScale(ctx, batch, base, now)
 	if err != nil {
 		return err
 	}
 
-	if err := s.Client.Patch(ctx, copy, client.MergeFrom(&hra)); err != nil {
+	if s.store != nil {
+		// Persist the reservations before patching the HRA, so that they survive
+		// even if the patch below fails or the HRA is re-applied afterwards.
+		if err := s.store.Put(ctx, batch.namespacedName, copy.Spec.CapacityReservations); errors.Is(err, errCapacityReservationStoreFull) {
+			// Fall back to the HRA as the record of the reservations, rather than leaving outdated ones in the store.
+			s.Log.Error(err, "Capacity reservations don't fit in the store. Keeping them in the HRA only", "hra", batch.namespacedName)
+			if err := s.store.Delete(ctx, batch.namespacedName); err != nil {
+				return fmt.Errorf("deleting capacity reservations from the store: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("storing capacity reservations: %w", err)
+		}
+	}
+
+	patch := client.MergeFrom(&hra)
+	if s.lock != nil && s.store == nil {
+		// The HRA is the only record of the reservations, so the patch must fail rather than overwrite
+		// the reservations written by another replica in case our lease expired in the meantime.
+		// With a store, the store is the source of truth and a retry would add the stored reservations twice.
+		patch = client.MergeFromWithOptions(&hra, client.MergeFromWithOptimisticLock{})
+	}
+
+	if err := s.Client.Patch(ctx, copy, patch); err != nil {
 		return fmt.Errorf("patching horizontalrunnerautoscaler to add capacity reservation: %w", err)
 	}
 
@@ -214,15 +411,52 @@ func (s *batchScaler) planBatchScale(ctx context.Context, batch batchScaleOperat
 		}
 	}
 
+	if len(hra.Spec.RepositoryBudgets) > 0 {
+		// Reservations beyond the budget of their repository are starved in the same way as the ones beyond maxReplicas,
+		// so their expiration time is extended as well until they start adding replicas.
+		admitted, _ := budgetCapacityReservations(*copy, copy.Spec.CapacityReservations, now)
+
+		for i, ok := range admitted {
+			r := &copy.Spec.CapacityReservations[i]
+			if ok || !r.ExpirationTime.Time.After(now) {
+				continue
+			}
+
+			duration := r.ExpirationTime.Time.Sub(r.EffectiveTime.Time)
+			r.EffectiveTime = metav1.Time{Time: now}
+			r.ExpirationTime = metav1.Time{Time: now.Add(duration)}
+		}
+	}
+
+	// Hold the reservations of the jobs that started before filtering out the expired ones,
+	// so that a reservation expiring while its job start event was in the batch isn't lost.
+	maxInProgressDuration := s.maxInProgressDuration
+	if maxInProgressDuration <= 0 {
+		maxInProgressDuration = DefaultMaxInProgressCapacityReservationDuration
+	}
+
+	for _, scale := range batch.scaleOps {
+		if !scale.renew {
+			continue
+		}
+
+		held := holdCapacityReservationsOfJob(copy.Spec.CapacityReservations, scale.jobID, now, maxInProgressDuration)
+		scale.log.V(2).Info("Holding capacity reservations of started job until it completes", "jobID", scale.jobID, "held", held)
+	}
+
 	// Now we can filter out any expired reservations from consideration.
 	// This could leave us with 0 reservations left.
-	copy.Spec.CapacityReservations = getValidCapacityReservations(copy)
+	copy.Spec.CapacityReservations = getValidCapacityReservations(copy, now)
 	before := len(hra.Spec.CapacityReservations)
 	expired := before - len(copy.Spec.CapacityReservations)
 
 	var added, completed int
 
-	for _, scale := range batch.scaleOps {
+	for i := range batch.scaleOps {
+		scale := &batch.scaleOps[i]
+		scale.added = 0
+		scale.removed = 0
+
 		amount := scale.trigger.Amount
 
 		// We do not track if a webhook-based scale-down event matches an expired capacity reservation
@@ -237,6 +471,14 @@ func (s *batchScaler) planBatchScale(ctx context.Context, batch batchScaleOperat
 		// but that would be a lot of work. So for now we allow for some slop, and hope that
 		// GitHub provides a better autoscaling solution soon.
 		if amount > 0 {
+			// GitHub redelivers an event on request, and the redelivery may reach another webhook server replica.
+			// A queued job holds its reservations until it completes, so a queued event for a job that already
+			// holds some is a duplicate.
+			if hasCapacityReservationsOfJob(copy.Spec.CapacityReservations, scale.jobID) {
+				scale.log.V(1).Info("Ignoring duplicate scale up of job that already has capacity reservations", "jobID", scale.jobID)
+				continue
+			}
+
 			scale.log.V(2).Info("Adding capacity reservation", "amount", amount)
 
 			// Parts of this function require that Spec.CapacityReservations.Replicas always equals 1.
@@ -246,15 +488,45 @@ func (s *batchScaler) planBatchScale(ctx context.Context, batch batchScaleOperat
 					EffectiveTime:  metav1.Time{Time: now},
 					ExpirationTime: metav1.Time{Time: now.Add(scale.trigger.Duration.Duration)},
 					Replicas:       1,
+					Repository:     scale.repository,
+					JobID:          scale.jobID,
 				})
 			}
 			added += amount
+			scale.added = amount
 		} else if amount < 0 {
+			// The amount of a job computed by an amountExpression is only known to its queued event,
+			// so its completion releases exactly the reservations the job added, if any.
+			if scale.trigger.AmountExpression != "" {
+				amount = -countCapacityReservationsOfJob(copy.Spec.CapacityReservations, scale.jobID)
+				if amount == 0 {
+					scale.log.V(1).Info("Ignoring completion of job that has no capacity reservations", "jobID", scale.jobID)
+					continue
+				}
+			}
+
 			scale.log.V(2).Info("Removing capacity reservation", "amount", -amount)
 
+			remove := -amount
+			reserved := len(copy.Spec.CapacityReservations)
+
+			// A completed job releases its own reservations first, which may not be the oldest ones
+			// when the jobs of the HRA complete in a different order than they were queued.
+			var removed int
+			copy.Spec.CapacityReservations, removed = removeCapacityReservationsOfJob(copy.Spec.CapacityReservations, scale.jobID, remove)
+			remove -= removed
+
+			// With repository budgets, a completed job releases a reservation of its own repository first,
+			// so that the reservations pending for the same repository can start adding replicas.
+			if len(hra.Spec.RepositoryBudgets) > 0 {
+				var removed int
+				copy.Spec.CapacityReservations, removed = removeCapacityReservationsOfRepository(copy.Spec.CapacityReservations, scale.repository, remove)
+				remove -= removed
+			}
+
 			// Remove the requested number of reservations unless there are not that many left
-			if len(copy.Spec.CapacityReservations) > -amount {
-				copy.Spec.CapacityReservations = copy.Spec.CapacityReservations[-amount:]
+			if len(copy.Spec.CapacityReservations) > remove {
+				copy.Spec.CapacityReservations = copy.Spec.CapacityReservations[remove:]
 			} else {
 				copy.Spec.CapacityReservations = nil
 			}
@@ -263,6 +535,7 @@ func (s *batchScaler) planBatchScale(ctx context.Context, batch batchScaleOperat
 			// As the amount is negative for a scale-down trigger, we make the "completed" amount positive by negating the amount.
 			// That way, the user can see the number of removed runners(like 3), rather than the delta (like -3) in the number of runners.
 			completed -= amount
+			scale.removed = reserved - len(copy.Spec.CapacityReservations)
 		}
 	}
 
@@ -279,3 +552,110 @@ func (s *batchScaler) planBatchScale(ctx context.Context, batch batchScaleOperat
 
 	return copy, nil
 }
+
+// publishScalingEvent emits the capacity reservations added or removed by the operation applied to the HRA.
+func (s *batchScaler) publishScalingEvent(hra types.NamespacedName, op scaleOperation) {
+	ev := ScalingEvent{
+		Namespace:                  hra.Namespace,
+		HorizontalRunnerAutoscaler: hra.Name,
+		DeliveryID:                 op.deliveryID,
+		Event:                      op.event,
+		Repository:                 op.repository,
+		JobID:                      op.jobID,
+		Time:                       nowFrom(s.clock),
+	}
+
+	switch {
+	case op.added > 0:
+		ev.Reason = ScalingEventReasonCapacityReservationAdded
+		ev.Delta = op.added
+		ev.Message = fmt.Sprintf("added %d capacity reservations for a %s event", op.added, op.event)
+	case op.removed > 0:
+		ev.Reason = ScalingEventReasonCapacityReservationRemoved
+		ev.Delta = -op.removed
+		ev.Message = fmt.Sprintf("removed %d capacity reservations for a %s event", op.removed, op.event)
+	default:
+		return
+	}
+
+	s.events.PublishCapacityReservations(ev)
+}
+
+// holdCapacityReservationsOfJob makes the reservations added by the job expire maxDuration after now,
+// so that they are held until the job completes and removes them rather than for the duration of the scale up trigger,
+// and returns the number of held reservations.
+// maxDuration is only a safety cap against a completed event that is never received, so it should be longer than any job.
+func holdCapacityReservationsOfJob(reservations []v1alpha1.CapacityReservation, jobID int64, now time.Time, maxDuration time.Duration) int {
+	if jobID == 0 {
+		return 0
+	}
+
+	var held int
+	for i := range reservations {
+		r := &reservations[i]
+		if r.JobID != jobID {
+			continue
+		}
+
+		r.EffectiveTime = metav1.Time{Time: now}
+		r.ExpirationTime = metav1.Time{Time: now.Add(maxDuration)}
+		held++
+	}
+
+	return held
+}
+
+// hasCapacityReservationsOfJob returns true when any of the reservations was added by the job.
+func hasCapacityReservationsOfJob(reservations []v1alpha1.CapacityReservation, jobID int64) bool {
+	if jobID == 0 {
+		return false
+	}
+
+	for _, r := range reservations {
+		if r.JobID == jobID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countCapacityReservationsOfJob returns the number of reservations added by the job.
+func countCapacityReservationsOfJob(reservations []v1alpha1.CapacityReservation, jobID int64) int {
+	if jobID == 0 {
+		return 0
+	}
+
+	var n int
+	for _, r := range reservations {
+		if r.JobID == jobID {
+			n++
+		}
+	}
+
+	return n
+}
+
+// removeCapacityReservationsOfJob removes up to n reservations added by the job,
+// and returns the remaining reservations along with the number of removed ones.
+func removeCapacityReservationsOfJob(reservations []v1alpha1.CapacityReservation, jobID int64, n int) ([]v1alpha1.CapacityReservation, int) {
+	if jobID == 0 {
+		return reservations, 0
+	}
+
+	var (
+		remaining []v1alpha1.CapacityReservation
+		removed   int
+	)
+
+	for _, r := range reservations {
+		if removed < n && r.JobID == jobID {
+			removed++
+			continue
+		}
+
+		remaining = append(remaining, r)
+	}
+
+	return remaining, removed
+}
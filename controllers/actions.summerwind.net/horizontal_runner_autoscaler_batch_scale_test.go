@@ -164,3 +164,122 @@ func TestPlanBatchScale(t *testing.T) {
 		})
 	})
 }
+
+func TestPlanBatchScale_JobReservations(t *testing.T) {
+	s := &batchScaler{Log: logr.Discard()}
+
+	var (
+		duration = 10 * time.Minute
+
+		t0 = time.Now()
+		t1 = t0.Add(time.Minute)
+		t2 = t0.Add(duration)
+	)
+
+	reservation := func(jobID int64, effective time.Time) v1alpha1.CapacityReservation {
+		return v1alpha1.CapacityReservation{
+			EffectiveTime:  metav1.NewTime(effective),
+			ExpirationTime: metav1.NewTime(effective.Add(duration)),
+			Replicas:       1,
+			JobID:          jobID,
+		}
+	}
+
+	plan := func(t *testing.T, now time.Time, op scaleOperation, reservations ...v1alpha1.CapacityReservation) []v1alpha1.CapacityReservation {
+		t.Helper()
+
+		op.log = logr.Discard()
+		op.trigger.Duration = metav1.Duration{Duration: duration}
+
+		hra := &v1alpha1.HorizontalRunnerAutoscaler{
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				CapacityReservations: reservations,
+			},
+		}
+
+		got, err := s.planBatchScale(context.Background(), batchScaleOperation{scaleOps: []scaleOperation{op}}, hra, now)
+		require.NoError(t, err)
+
+		return got.Spec.CapacityReservations
+	}
+
+	t.Run("queued job adds a reservation of the job", func(t *testing.T) {
+		got := plan(t, t0, scaleOperation{trigger: v1alpha1.ScaleUpTrigger{Amount: 1}, jobID: 1})
+		require.Equal(t, []v1alpha1.CapacityReservation{reservation(1, t0)}, got)
+	})
+
+	t.Run("redelivered queued event of a job does not add another reservation", func(t *testing.T) {
+		got := plan(t, t1, scaleOperation{trigger: v1alpha1.ScaleUpTrigger{Amount: 1}, jobID: 1}, reservation(1, t0))
+		require.Equal(t, []v1alpha1.CapacityReservation{reservation(1, t0)}, got)
+	})
+
+	held := func(jobID int64, effective time.Time) v1alpha1.CapacityReservation {
+		r := reservation(jobID, effective)
+		r.ExpirationTime = metav1.NewTime(effective.Add(DefaultMaxInProgressCapacityReservationDuration))
+		return r
+	}
+
+	t.Run("started job holds its reservations even when they expire now", func(t *testing.T) {
+		got := plan(t, t2, scaleOperation{renew: true, jobID: 2}, reservation(1, t0), reservation(2, t0))
+		require.Equal(t, []v1alpha1.CapacityReservation{held(2, t2)}, got)
+	})
+
+	t.Run("reservation of a started job outlives the duration of its trigger", func(t *testing.T) {
+		got := plan(t, t2.Add(3*duration), scaleOperation{trigger: v1alpha1.ScaleUpTrigger{Amount: 1}, jobID: 3}, held(2, t1))
+		require.Equal(t, []v1alpha1.CapacityReservation{held(2, t1), reservation(3, t2.Add(3*duration))}, got)
+	})
+
+	t.Run("reservation of a started job is released after the safety cap", func(t *testing.T) {
+		got := plan(t, t1.Add(DefaultMaxInProgressCapacityReservationDuration), scaleOperation{}, held(2, t1))
+		require.Empty(t, got)
+	})
+
+	t.Run("completed job removes its own reservation", func(t *testing.T) {
+		got := plan(t, t1, scaleOperation{trigger: v1alpha1.ScaleUpTrigger{Amount: -1}, jobID: 2}, reservation(1, t0), reservation(2, t0))
+		require.Equal(t, []v1alpha1.CapacityReservation{reservation(1, t0)}, got)
+	})
+
+	t.Run("completed job without a reservation removes the oldest one", func(t *testing.T) {
+		got := plan(t, t1, scaleOperation{trigger: v1alpha1.ScaleUpTrigger{Amount: -1}, jobID: 3}, reservation(1, t0), reservation(2, t1))
+		require.Equal(t, []v1alpha1.CapacityReservation{reservation(2, t1)}, got)
+	})
+
+	t.Run("completed job of an amountExpression releases all its reservations", func(t *testing.T) {
+		trigger := v1alpha1.ScaleUpTrigger{Amount: -1, AmountExpression: "size(workflow_job.labels)"}
+		got := plan(t, t1, scaleOperation{trigger: trigger, jobID: 2}, reservation(1, t0), reservation(2, t0), reservation(2, t0), reservation(2, t0))
+		require.Equal(t, []v1alpha1.CapacityReservation{reservation(1, t0)}, got)
+	})
+
+	t.Run("completed job of an amountExpression without a reservation removes none", func(t *testing.T) {
+		trigger := v1alpha1.ScaleUpTrigger{Amount: -1, AmountExpression: "size(workflow_job.labels)"}
+		got := plan(t, t1, scaleOperation{trigger: trigger, jobID: 3}, reservation(1, t0), reservation(2, t1))
+		require.Equal(t, []v1alpha1.CapacityReservation{reservation(1, t0), reservation(2, t1)}, got)
+	})
+}
+
+func TestPlanBatchScale_AddedReservations(t *testing.T) {
+	s := &batchScaler{Log: logr.Discard()}
+
+	now := time.Now()
+	duration := metav1.Duration{Duration: 10 * time.Minute}
+
+	batch := batchScaleOperation{
+		scaleOps: []scaleOperation{
+			{log: logr.Discard(), trigger: v1alpha1.ScaleUpTrigger{Amount: 2, Duration: duration}, jobID: 1},
+			{log: logr.Discard(), trigger: v1alpha1.ScaleUpTrigger{Amount: 1, Duration: duration}, jobID: 1},
+			{log: logr.Discard(), trigger: v1alpha1.ScaleUpTrigger{Amount: -1, Duration: duration}, jobID: 2},
+			// A retried batch recomputes the number of added reservations
+			{log: logr.Discard(), trigger: v1alpha1.ScaleUpTrigger{Amount: 1, Duration: duration}, jobID: 3, added: 5},
+		},
+	}
+
+	_, err := s.planBatchScale(context.Background(), batch, &v1alpha1.HorizontalRunnerAutoscaler{}, now)
+	require.NoError(t, err)
+
+	var added []int
+	for _, op := range batch.scaleOps {
+		added = append(added, op.added)
+	}
+
+	require.Equal(t, []int{2, 0, 0, 1}, added)
+}
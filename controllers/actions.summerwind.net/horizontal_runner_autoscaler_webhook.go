@@ -20,13 +20,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
@@ -35,9 +36,12 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
 	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 	"github.com/actions/actions-runner-controller/simulator"
 )
 
@@ -48,6 +52,10 @@ const (
 	keyRunnerGroup      = "/group/"
 
 	DefaultQueueLimit = 100
+
+	// DefaultMaxInProgressCapacityReservationDuration is how long the capacity reservation of a started job is held
+	// at most when its completed event is never received.
+	DefaultMaxInProgressCapacityReservationDuration = 24 * time.Hour
 )
 
 // HorizontalRunnerAutoscalerGitHubWebhook autoscales a HorizontalRunnerAutoscaler and the RunnerDeployment on each
@@ -62,6 +70,10 @@ type HorizontalRunnerAutoscalerGitHubWebhook struct {
 	// the administrator is generated and specified in GitHub Web UI.
 	SecretKeyBytes []byte
 
+	// NextSecretKeyBytes is optional. When set, deliveries signed with it are accepted too,
+	// so that the secret token can be rotated without dropping deliveries.
+	NextSecretKeyBytes []byte
+
 	// GitHub Client to discover runner groups assigned to a repository
 	GitHubClient *github.Client
 
@@ -75,8 +87,74 @@ type HorizontalRunnerAutoscalerGitHubWebhook struct {
 	// A scale target is enqueued on each retrieval of each eligible webhook event, so that it is processed asynchronously.
 	QueueLimit int
 
-	worker     *worker
-	workerInit sync.Once
+	// CapacityReservationStore is optional. When set, capacity reservations are persisted to it
+	// so that they survive the HRA being re-applied or failing to be patched.
+	CapacityReservationStore CapacityReservationStore
+
+	// CapacityReservationLock is optional. When set, it serializes the capacity reservation updates of each HRA
+	// across the webhook server replicas, so that the webhook server can run with more than one replica.
+	CapacityReservationLock CapacityReservationLock
+
+	// APIReader is optional. When set, HRAs are read with it instead of the cached client before
+	// their capacity reservations are updated. It must be set along with CapacityReservationLock.
+	APIReader client.Reader
+
+	// DeliveryQueue is optional. When set, webhook deliveries are persisted to it and acknowledged right away,
+	// and applied asynchronously until they succeed, so that they survive restarts and Kubernetes API outages.
+	DeliveryQueue WebhookDeliveryQueue
+
+	// Clock is optional. When set, it is used instead of the wall clock to compute capacity reservation expirations.
+	Clock clock.PassiveClock
+
+	// DefaultScaleUpTriggerDuration is the duration of the capacity reservation added by a scale up trigger that omits it.
+	// Defaults to v1alpha1.DefaultScaleUpTriggerDuration.
+	DefaultScaleUpTriggerDuration time.Duration
+
+	// MaxInProgressCapacityReservationDuration is how long the capacity reservation of a started job is held at most,
+	// in case its completed event is lost. Defaults to DefaultMaxInProgressCapacityReservationDuration.
+	MaxInProgressCapacityReservationDuration time.Duration
+
+	// ConfigsOnly makes the server reject the deliveries to paths not served by any WebhookAutoscalerConfig,
+	// instead of handling them with the settings above.
+	ConfigsOnly bool
+
+	// UnmatchedLabels is optional. When set, the labels of the queued workflow jobs that match no HRA are recorded into it.
+	UnmatchedLabels *UnmatchedLabelCatalog
+
+	// SourceIPAllowlist is optional. When set, the deliveries not sent from the allowed IP ranges are rejected.
+	SourceIPAllowlist *SourceIPAllowlist
+
+	// RateLimiter is optional. When set, the deliveries from a source exceeding its rate limit are rejected with 429.
+	RateLimiter *WebhookRateLimiter
+
+	// MaxPayloadBytes is the maximum size of the payload of a delivery. The larger deliveries are rejected with 413.
+	// Set to 0 for no limit.
+	MaxPayloadBytes int64
+
+	// ScaleHandoff is optional. When set, the scale operations not yet applied when the server stops are handed off to it,
+	// and the ones handed off by other servers are restored from it, so that upgrading the server doesn't lose them.
+	ScaleHandoff ScaleHandoff
+	// ScaleHandoffIdentity identifies the operations handed off by this server. It should be unique per replica.
+	ScaleHandoffIdentity string
+
+	// ScalingEvents is optional. When set, the capacity reservations added or removed by the deliveries are emitted to it.
+	ScalingEvents *ScalingEventPublisher
+
+	// amountPrograms are the compiled amountExpressions of the scale up triggers, keyed by the namespace/name of their HRA.
+	amountPrograms   map[types.NamespacedName]*amountPrograms
+	amountProgramsMu sync.Mutex
+
+	// configs are the WebhookAutoscalerConfigs loaded by the WebhookAutoscalerConfigReconciler, keyed by their namespace/name.
+	configs   map[string]*webhookConfig
+	configsMu sync.RWMutex
+
+	worker      *worker
+	batchScaler *batchScaler
+	stopWorker  context.CancelFunc
+	workerInit  sync.Once
+
+	deliveryQueued     chan struct{}
+	deliveryQueuedInit sync.Once
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
@@ -121,45 +199,240 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		return
 	}
 
-	var payload []byte
+	webhookType := gogithub.WebHookType(r)
+	receivedAt := nowFrom(autoscaler.Clock)
 
-	if len(autoscaler.SecretKeyBytes) > 0 {
-		payload, err = gogithub.ValidatePayload(r, autoscaler.SecretKeyBytes)
-		if err != nil {
-			autoscaler.Log.Error(err, "error validating request body")
+	metrics.AddGitHubWebhookDeliveryReceived(webhookType)
+
+	defer func() {
+		result := "success"
+		if !ok {
+			result = "failure"
+		}
+		metrics.ObserveGitHubWebhookDeliveryHandlingDuration(webhookType, result, nowFrom(autoscaler.Clock).Sub(receivedAt))
+	}()
 
+	if allowlist := autoscaler.SourceIPAllowlist; allowlist != nil {
+		if !allowlist.Ready() {
+			// Fail closed, but in a way GitHub shows as a failed delivery that can be redelivered
+			ok = true
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonSourceIP)
+			autoscaler.Log.Info("Rejected webhook delivery as the IP ranges of GitHub haven't been fetched yet")
+			http.Error(w, "the IP allowlist is not ready yet", http.StatusServiceUnavailable)
 			return
 		}
-	} else {
-		payload, err = io.ReadAll(r.Body)
-		if err != nil {
-			autoscaler.Log.Error(err, "error reading request body")
 
+		if addr, allowed := allowlist.Allowed(r); !allowed {
+			ok = true
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonSourceIP)
+			autoscaler.Log.Info("Rejected webhook delivery from a source not in the IP allowlist", "source", addr.String(), "remoteAddr", r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
 	}
 
-	webhookType := gogithub.WebHookType(r)
-	event, err := gogithub.ParseWebHook(webhookType, payload)
+	if limiter := autoscaler.RateLimiter; limiter != nil {
+		if addr, retryAfter, allowed := limiter.Allow(r); !allowed {
+			ok = true
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonRateLimited)
+			autoscaler.Log.V(1).Info("Rejected webhook delivery from a source exceeding the rate limit", "source", addr.String(), "remoteAddr", r.RemoteAddr, "retryAfter", retryAfter)
+			setRetryAfter(w, retryAfter)
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	if limit := autoscaler.MaxPayloadBytes; limit > 0 {
+		if r.ContentLength > limit {
+			ok = true
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonPayloadTooLarge)
+			autoscaler.Log.Info("Rejected webhook delivery exceeding the maximum payload size", "contentLength", r.ContentLength, "maxPayloadBytes", limit)
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		// The body without Content-Length, e.g. chunked, is cut off at the limit while it's read
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+
+	cfg := autoscaler.configForPath(r.URL.Path)
+	if cfg == nil {
+		ok = true
+		metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonUnknownPath)
+		http.NotFound(w, r)
+		return
+	}
+
+	payload, secret, err := validateWebhookPayload(r, cfg.secretKeyBytes, cfg.nextSecretKeyBytes)
+
+	metrics.AddGitHubWebhookDeliverySignature(cfg.key, secret)
+
 	if err != nil {
-		var s string
-		if payload != nil {
-			s = string(payload)
+		if reason := webhookRequestBodyErrorReason(err); reason != "" {
+			ok = true
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, reason)
+			autoscaler.Log.Info("Rejected webhook delivery whose body couldn't be read within the limits", "reason", reason, "error", err.Error())
+
+			status := http.StatusRequestEntityTooLarge
+			if reason == webhookDropReasonTimeout {
+				status = http.StatusRequestTimeout
+			}
+			http.Error(w, reason, status)
+			return
 		}
 
-		autoscaler.Log.Error(err, "could not parse webhook", "webhookType", webhookType, "payload", s)
+		metrics.AddGitHubWebhookSignatureFailure(webhookType)
+
+		autoscaler.Log.Error(err, "error validating request body")
 
 		return
 	}
 
-	var target *ScaleTarget
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
 
 	log := autoscaler.Log.WithValues(
 		"event", webhookType,
 		"hookID", r.Header.Get("X-GitHub-Hook-ID"),
-		"delivery", r.Header.Get("X-GitHub-Delivery"),
+		"delivery", deliveryID,
+		"secret", secret,
 	)
 
+	if cfg.key != "" {
+		log = log.WithValues("webhookAutoscalerConfig", cfg.key)
+	}
+
+	var msg string
+
+	if dedupKey := cfg.dedup.key(deliveryID, webhookType, payload); dedupKey != "" {
+		if !cfg.dedup.claim(dedupKey, nowFrom(autoscaler.Clock)) {
+			ok = true
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonDuplicate)
+			msg = fmt.Sprintf("ignored duplicate delivery %s", deliveryID)
+			log.V(1).Info(msg, "deduplicationKey", dedupKey)
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, msg)
+			return
+		}
+
+		// Release the key of a failed delivery, so that its redelivery isn't ignored
+		defer func() {
+			if !ok {
+				cfg.dedup.release(dedupKey)
+			}
+		}()
+	}
+
+	if autoscaler.DeliveryQueue != nil && webhookType == "workflow_job" {
+		// The delivery is applied asynchronously by processWebhookDeliveries,
+		// which keeps retrying it until the HRA is updated, even across restarts.
+		delivery := WebhookDelivery{
+			ID:         deliveryID,
+			EventType:  webhookType,
+			Payload:    payload,
+			ReceivedAt: receivedAt,
+			Config:     cfg.key,
+		}
+
+		if _, err = gogithub.ParseWebHook(webhookType, payload); err != nil {
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonInvalid)
+
+			log.Error(err, "could not parse webhook", "webhookType", webhookType)
+
+			return
+		}
+
+		if err = autoscaler.DeliveryQueue.Enqueue(delivery); err != nil {
+			log.Error(err, "Could not persist webhook delivery")
+
+			return
+		}
+
+		autoscaler.notifyDeliveryQueued()
+
+		msg = fmt.Sprintf("queued delivery %s", delivery.ID)
+
+		log.V(1).Info(msg)
+	} else {
+		msg, err = autoscaler.handleEvent(context.TODO(), log, cfg, webhookType, deliveryID, payload, receivedAt, nil)
+		if err != nil {
+			return
+		}
+	}
+
+	ok = true
+
+	w.WriteHeader(http.StatusOK)
+
+	if msg == "" {
+		return
+	}
+
+	if written, err := w.Write([]byte(msg)); err != nil {
+		log.Error(err, "failed writing http response", "msg", msg, "written", written)
+	}
+}
+
+// The reasons a webhook delivery is dropped for without scaling any HRA,
+// which label the github_webhook_deliveries_dropped_total metric.
+const (
+	webhookDropReasonUnknownPath      = "unknown_path"
+	webhookDropReasonDuplicate        = "duplicate"
+	webhookDropReasonInvalid          = "invalid"
+	webhookDropReasonUnsupportedEvent = "unsupported_event"
+	webhookDropReasonIgnoredAction    = "ignored_action"
+	webhookDropReasonOtherApp         = "other_app"
+	webhookDropReasonNoScaleTarget    = "no_scale_target"
+	webhookDropReasonZeroAmount       = "zero_amount"
+	webhookDropReasonQueueFull        = "queue_full"
+	webhookDropReasonConfigDeleted    = "config_deleted"
+	webhookDropReasonSourceIP         = "source_ip"
+	webhookDropReasonRateLimited      = "rate_limited"
+	webhookDropReasonPayloadTooLarge  = "payload_too_large"
+	webhookDropReasonTimeout          = "timeout"
+)
+
+// invalidWebhookDeliveryError is returned by handleEvent when retrying the delivery would never succeed.
+type invalidWebhookDeliveryError struct {
+	error
+}
+
+func (e invalidWebhookDeliveryError) Unwrap() error {
+	return e.error
+}
+
+// handleEvent enqueues the scale target of the webhook event handled with cfg, and returns the message to respond with.
+// It returns an error when the event could not be handled and the delivery needs to be retried.
+//
+// deliveryID is the X-GitHub-Delivery of the delivery, which is attached to the scaling events.
+// receivedAt is the time the delivery was received at, from which the latency of the scale is measured.
+// done is optional. When set, it's called once the event has been applied to the HRA, or right away when the event
+// doesn't scale any HRA.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) handleEvent(ctx context.Context, log logr.Logger, cfg *webhookConfig, webhookType, deliveryID string, payload []byte, receivedAt time.Time, done func()) (string, error) {
+	event, err := parseWebHook(webhookType, payload)
+	if err != nil {
+		metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonInvalid)
+
+		var s string
+		if payload != nil {
+			s = string(payload)
+		}
+
+		autoscaler.Log.Error(err, "could not parse webhook", "webhookType", webhookType, "payload", s)
+
+		return "", invalidWebhookDeliveryError{err}
+	}
+
+	var (
+		target   *ScaleTarget
+		enqueued bool
+	)
+
+	defer func() {
+		if done != nil && err == nil && !enqueued {
+			done()
+		}
+	}()
+
 	var enterpriseEvent struct {
 		Enterprise struct {
 			Slug string `json:"slug,omitempty"`
@@ -191,25 +464,48 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		}
 
 		labels := e.WorkflowJob.Labels
+		workflow := &jobWorkflow{
+			owner: e.Repo.Owner.GetLogin(),
+			repo:  e.Repo.GetName(),
+			runID: e.GetWorkflowJob().GetRunID(),
+			name:  e.GetWorkflowJob().GetWorkflowName(),
+		}
 
 		switch action := e.GetAction(); action {
-		case "queued", "completed":
-			target, err = autoscaler.getJobScaleUpTargetForRepoOrOrg(
-				context.TODO(),
-				log,
-				e.Repo.GetName(),
-				e.Repo.Owner.GetLogin(),
-				e.Repo.Owner.GetType(),
-				enterpriseSlug,
-				labels,
-			)
+		case "queued", "in_progress", "completed":
+			var routed bool
+
+			target, routed, err = autoscaler.getRoutedJobScaleTarget(ctx, log, cfg, e.Repo.GetFullName(), labels, workflow)
+			if !routed {
+				target, err = autoscaler.getJobScaleUpTargetForRepoOrOrg(
+					ctx,
+					log,
+					cfg,
+					e.Repo.GetName(),
+					e.Repo.Owner.GetLogin(),
+					e.Repo.Owner.GetType(),
+					enterpriseSlug,
+					labels,
+					workflow,
+				)
+			}
 			if target == nil {
+				if err == nil && action == "queued" {
+					autoscaler.UnmatchedLabels.Record(labels, e.Repo.GetFullName(), time.Now())
+				}
 				break
 			}
 
+			target.Repository = e.Repo.GetFullName()
+			target.JobID = e.GetWorkflowJob().GetID()
+
 			if e.GetAction() == "queued" {
 				target.Amount = 1
 				break
+			} else if e.GetAction() == "in_progress" {
+				target.Amount = 0
+				target.Renew = true
+				break
 			} else if e.GetAction() == "completed" && e.GetWorkflowJob().GetConclusion() != "skipped" {
 				// We want to filter out "completed" events sent by check runs.
 				// See https://github.com/actions/actions-runner-controller/issues/2118
@@ -231,38 +527,137 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 			// If the conclusion is "skipped", we will ignore it and fallthrough to the default case.
 			fallthrough
 		default:
-			ok = true
+			log.V(2).Info("Received and ignored a workflow_job event as it triggers neither scale-up nor scale-down", "action", action)
 
-			w.WriteHeader(http.StatusOK)
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonIgnoredAction)
 
-			log.V(2).Info("Received and ignored a workflow_job event as it triggers neither scale-up nor scale-down", "action", action)
+			return "", nil
+		}
+	case *gogithub.CheckSuiteEvent:
+		suite := e.GetCheckSuite()
+		log = log.WithValues(
+			"checkSuite.ID", suite.GetID(),
+			"checkSuite.headBranch", suite.GetHeadBranch(),
+			"checkSuite.app", suite.GetApp().GetSlug(),
+			"repository.name", e.Repo.GetName(),
+			"repository.owner.login", e.Repo.GetOwner().GetLogin(),
+			"repository.owner.type", e.Repo.GetOwner().GetType(),
+			"enterprise.slug", enterpriseSlug,
+			"action", e.GetAction(),
+		)
 
-			return
+		// Every GitHub App installed in the repository has its own check suites, which don't run on self-hosted runners
+		if suite.GetApp().GetSlug() != githubActionsAppSlug {
+			log.V(2).Info("Received and ignored a check_suite event of a GitHub App other than GitHub Actions")
+
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonOtherApp)
+
+			return "", nil
 		}
-	case *gogithub.PingEvent:
-		ok = true
 
-		w.WriteHeader(http.StatusOK)
+		switch action := e.GetAction(); action {
+		case "requested", "rerequested", "completed":
+			target, err = autoscaler.getRunEventScaleUpTarget(ctx, log, cfg, webhookType, e.Repo, enterpriseSlug, runEvent{id: suite.GetID(), action: action, branch: suite.GetHeadBranch()})
+		default:
+			log.V(2).Info("Received and ignored a check_suite event as it triggers neither scale-up nor scale-down", "action", action)
 
-		msg := "pong"
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonIgnoredAction)
 
-		if written, err := w.Write([]byte(msg)); err != nil {
-			log.Error(err, "failed writing http response", "msg", msg, "written", written)
+			return "", nil
 		}
+	case *gogithub.WorkflowRunEvent:
+		run := e.GetWorkflowRun()
+		log = log.WithValues(
+			"workflowRun.ID", run.GetID(),
+			"workflowRun.name", run.GetName(),
+			"workflowRun.headBranch", run.GetHeadBranch(),
+			"repository.name", e.Repo.GetName(),
+			"repository.owner.login", e.Repo.GetOwner().GetLogin(),
+			"repository.owner.type", e.Repo.GetOwner().GetType(),
+			"enterprise.slug", enterpriseSlug,
+			"action", e.GetAction(),
+		)
 
+		switch action := e.GetAction(); action {
+		case "requested", "in_progress", "completed":
+			target, err = autoscaler.getRunEventScaleUpTarget(ctx, log, cfg, webhookType, e.Repo, enterpriseSlug, runEvent{id: run.GetID(), action: action, branch: run.GetHeadBranch()})
+		default:
+			log.V(2).Info("Received and ignored a workflow_run event as it triggers neither scale-up nor scale-down", "action", action)
+
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonIgnoredAction)
+
+			return "", nil
+		}
+	case *deploymentProtectionRuleEvent:
+		log = log.WithValues(
+			"deployment.ID", e.Deployment.GetID(),
+			"environment", e.Environment,
+			"repository.name", e.Repo.GetName(),
+			"repository.owner.login", e.Repo.GetOwner().GetLogin(),
+			"repository.owner.type", e.Repo.GetOwner().GetType(),
+			"enterprise.slug", enterpriseSlug,
+			"action", e.Action,
+		)
+
+		if e.Action != "requested" {
+			log.V(2).Info("Received and ignored a deployment_protection_rule event as it triggers neither scale-up nor scale-down", "action", e.Action)
+
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonIgnoredAction)
+
+			return "", nil
+		}
+
+		target, err = autoscaler.getDeploymentScaleUpTarget(ctx, log, cfg, webhookType, e.Repo, enterpriseSlug, deploymentEvent{id: e.Deployment.GetID(), environment: e.Environment})
+	case *gogithub.DeploymentEvent:
+		deployment := e.GetDeployment()
+		log = log.WithValues(
+			"deployment.ID", deployment.GetID(),
+			"environment", deployment.GetEnvironment(),
+			"repository.name", e.Repo.GetName(),
+			"repository.owner.login", e.Repo.GetOwner().GetLogin(),
+			"repository.owner.type", e.Repo.GetOwner().GetType(),
+			"enterprise.slug", enterpriseSlug,
+		)
+
+		target, err = autoscaler.getDeploymentScaleUpTarget(ctx, log, cfg, webhookType, e.Repo, enterpriseSlug, deploymentEvent{id: deployment.GetID(), environment: deployment.GetEnvironment()})
+	case *gogithub.DeploymentStatusEvent:
+		deployment := e.GetDeployment()
+		state := e.GetDeploymentStatus().GetState()
+		log = log.WithValues(
+			"deployment.ID", deployment.GetID(),
+			"environment", deployment.GetEnvironment(),
+			"deploymentStatus.state", state,
+			"repository.name", e.Repo.GetName(),
+			"repository.owner.login", e.Repo.GetOwner().GetLogin(),
+			"repository.owner.type", e.Repo.GetOwner().GetType(),
+			"enterprise.slug", enterpriseSlug,
+		)
+
+		if state != "in_progress" && !deploymentStatusFinal(state) {
+			log.V(2).Info("Received and ignored a deployment_status event as it triggers neither scale-up nor scale-down", "state", state)
+
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonIgnoredAction)
+
+			return "", nil
+		}
+
+		target, err = autoscaler.getDeploymentScaleUpTarget(ctx, log, cfg, webhookType, e.Repo, enterpriseSlug, deploymentEvent{id: deployment.GetID(), environment: deployment.GetEnvironment(), state: state})
+	case *gogithub.PingEvent:
 		log.Info("received ping event")
 
-		return
+		return "pong", nil
 	default:
 		log.Info("unknown event type", "eventType", webhookType)
 
-		return
+		metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonUnsupportedEvent)
+
+		return "", invalidWebhookDeliveryError{fmt.Errorf("unknown event type %q", webhookType)}
 	}
 
 	if err != nil {
 		log.Error(err, "handling check_run event")
 
-		return
+		return "", err
 	}
 
 	if target == nil {
@@ -270,56 +665,92 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 			"Scale target not found. If this is unexpected, ensure that there is exactly one repository-wide or organizational runner deployment that matches this webhook event. If --watch-namespace is set ensure this is configured correctly.",
 		)
 
-		msg := "no horizontalrunnerautoscaler to scale for this github event"
+		metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonNoScaleTarget)
 
-		ok = true
+		return "no horizontalrunnerautoscaler to scale for this github event", nil
+	}
+
+	metrics.AddGitHubWebhookDeliveryMatched(webhookType, target.Name, target.Namespace)
 
-		w.WriteHeader(http.StatusOK)
+	// The completion of a job releases the capacity reservations its queued event added instead of evaluating the
+	// amountExpression again, as the expression may not evaluate to the same amount against both events.
+	if target.AmountExpression != "" && !target.Renew && target.Amount > 0 {
+		var amount int
+
+		amount, err = autoscaler.evalScaleUpTriggerAmount(&target.HorizontalRunnerAutoscaler, target.AmountExpression, webhookType, payload)
+		if err != nil {
+			log.Error(err, "evaluating amountExpression of the scale up trigger", "hra", target.Name, "amountExpression", target.AmountExpression)
 
-		if written, err := w.Write([]byte(msg)); err != nil {
-			log.Error(err, "failed writing http response", "msg", msg, "written", written)
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonInvalid)
+
+			return "", invalidWebhookDeliveryError{err}
 		}
 
-		return
-	}
+		if amount == 0 {
+			log.V(1).Info("Received and ignored an event as the amountExpression of the scale up trigger evaluated to zero", "eventType", webhookType, "hra", target.Name)
 
-	autoscaler.workerInit.Do(func() {
-		batchScaler := newBatchScaler(context.Background(), autoscaler.Client, autoscaler.Log)
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonZeroAmount)
 
-		queueLimit := autoscaler.QueueLimit
-		if queueLimit == 0 {
-			queueLimit = DefaultQueueLimit
+			return "", nil
 		}
-		autoscaler.worker = newWorker(context.Background(), queueLimit, batchScaler.Add)
-	})
+
+		target.Amount = amount
+	}
+
+	autoscaler.initWorker()
 
 	target.log = &log
+	target.done = done
+	target.event = webhookType
+	target.deliveryID = deliveryID
+	target.receivedAt = receivedAt
 	if ok := autoscaler.worker.Add(target); !ok {
+		err = fmt.Errorf("could not scale up due to queue full")
 		log.Error(err, "Could not scale up due to queue full")
-		return
+		// A queued delivery is retried instead, so only the deliveries handled synchronously are dropped
+		if done == nil {
+			metrics.AddGitHubWebhookDeliveryDropped(webhookType, webhookDropReasonQueueFull)
+		}
+		return "", err
 	}
-
-	ok = true
-
-	w.WriteHeader(http.StatusOK)
+	enqueued = true
 
 	msg := fmt.Sprintf("scaled %s by %d", target.Name, target.Amount)
+	if target.Renew {
+		msg = fmt.Sprintf("held capacity reservations of job %d for %s", target.JobID, target.Name)
+	} else if target.AmountExpression != "" && target.Amount < 0 {
+		msg = fmt.Sprintf("released capacity reservations of job %d for %s", target.JobID, target.Name)
+	}
 
 	log.Info(msg)
 
-	if written, err := w.Write([]byte(msg)); err != nil {
-		log.Error(err, "failed writing http response", "msg", msg, "written", written)
-	}
+	return msg, nil
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) findHRAsByKey(ctx context.Context, value string) ([]v1alpha1.HorizontalRunnerAutoscaler, error) {
-	ns := autoscaler.Namespace
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) initWorker() {
+	autoscaler.workerInit.Do(func() {
+		// The worker is stopped only to hand off the scale operations it hasn't applied yet
+		ctx, cancel := context.WithCancel(context.Background())
+		autoscaler.stopWorker = cancel
 
-	var defaultListOpts []client.ListOption
+		batchScaler := newBatchScaler(ctx, autoscaler.Client, autoscaler.Log, autoscaler.CapacityReservationStore)
+		batchScaler.clock = autoscaler.Clock
+		batchScaler.lock = autoscaler.CapacityReservationLock
+		batchScaler.reader = autoscaler.APIReader
+		batchScaler.events = autoscaler.ScalingEvents
+		batchScaler.maxInProgressDuration = autoscaler.MaxInProgressCapacityReservationDuration
 
-	if ns != "" {
-		defaultListOpts = append(defaultListOpts, client.InNamespace(ns))
-	}
+		queueLimit := autoscaler.QueueLimit
+		if queueLimit == 0 {
+			queueLimit = DefaultQueueLimit
+		}
+		autoscaler.worker = newWorker(ctx, queueLimit, batchScaler.Add)
+		autoscaler.batchScaler = batchScaler
+	})
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) findHRAsByKey(ctx context.Context, cfg *webhookConfig, value string) ([]v1alpha1.HorizontalRunnerAutoscaler, error) {
+	defaultListOpts := cfg.hraListOptions()
 
 	var hras []v1alpha1.HorizontalRunnerAutoscaler
 
@@ -327,10 +758,6 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) findHRAsByKey(ctx con
 		opts := append([]client.ListOption{}, defaultListOpts...)
 		opts = append(opts, client.MatchingFields{scaleTargetKey: value})
 
-		if autoscaler.Namespace != "" {
-			opts = append(opts, client.InNamespace(autoscaler.Namespace))
-		}
-
 		var hraList v1alpha1.HorizontalRunnerAutoscalerList
 
 		if err := autoscaler.List(ctx, &hraList, opts...); err != nil {
@@ -347,24 +774,48 @@ type ScaleTarget struct {
 	v1alpha1.HorizontalRunnerAutoscaler
 	v1alpha1.ScaleUpTrigger
 
+	// Repository is the full name of the repository the webhook event originates from.
+	// It's used to attribute capacity reservations to repositories for repositoryBudgets.
+	Repository string
+
+	// JobID is the ID of the workflow job the webhook event originates from.
+	JobID int64
+
+	// Renew is true when the webhook event reports the start of the job,
+	// which holds the capacity reservations of the job until it completes instead of adding or removing any.
+	Renew bool
+
 	log *logr.Logger
+
+	// done is optional. When set, it's called once the scale target has been applied to the HRA.
+	done func()
+
+	// event and receivedAt are the type and the receipt time of the webhook event, which label and measure
+	// the metrics recorded once the scale target has been applied to the HRA.
+	event      string
+	receivedAt time.Time
+
+	// deliveryID is the X-GitHub-Delivery of the webhook delivery, attached to the scaling events.
+	deliveryID string
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleUpTargetForRepoOrOrg(
-	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, labels []string,
+	ctx context.Context, log logr.Logger, cfg *webhookConfig, repo, owner, ownerType, enterprise string, labels []string, workflow *jobWorkflow,
 ) (*ScaleTarget, error) {
 
 	scaleTarget := func(value string) (*ScaleTarget, error) {
-		return autoscaler.getJobScaleTarget(ctx, value, labels)
+		return autoscaler.getJobScaleTarget(ctx, log, cfg, value, labels, workflow)
 	}
-	return autoscaler.getScaleUpTargetWithFunction(ctx, log, repo, owner, ownerType, enterprise, scaleTarget)
+	return autoscaler.getScaleUpTargetWithFunction(ctx, log, cfg, repo, owner, ownerType, enterprise, scaleTarget)
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithFunction(
-	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, scaleTarget func(value string) (*ScaleTarget, error)) (*ScaleTarget, error) {
+	ctx context.Context, log logr.Logger, cfg *webhookConfig, repo, owner, ownerType, enterprise string, scaleTarget func(value string) (*ScaleTarget, error)) (*ScaleTarget, error) {
 
 	repositoryRunnerKey := owner + "/" + repo
 
+	scaleTarget = autoscaler.withAllowedRepository(ctx, log, repositoryRunnerKey, scaleTarget)
+
 	// Search for repository HRAs
 	if target, err := scaleTarget(repositoryRunnerKey); err != nil {
 		log.Error(err, "finding repository-wide runner", "repository", repositoryRunnerKey)
@@ -381,7 +832,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithF
 
 	// Find the potential runner groups first to avoid spending API queries needless. Once/if GitHub improves an
 	// API to find related/linked runner groups from a specific repository this logic could be removed
-	managedRunnerGroups, err := autoscaler.getManagedRunnerGroupsFromHRAs(ctx, enterprise, owner)
+	managedRunnerGroups, err := autoscaler.getManagedRunnerGroupsFromHRAs(ctx, cfg, enterprise, owner)
 	if err != nil {
 		log.Error(err, "finding potential organization/enterprise runner groups from HRAs", "organization", owner)
 		return nil, err
@@ -397,9 +848,9 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithF
 	}
 
 	var visibleGroups *simulator.VisibleRunnerGroups
-	if autoscaler.GitHubClient != nil {
+	if cfg.githubClient != nil {
 		simu := &simulator.Simulator{
-			Client: autoscaler.GitHubClient,
+			Client: cfg.githubClient,
 			Log:    log,
 		}
 		// Get available organization runner groups and enterprise runner groups for a repository
@@ -477,19 +928,10 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithF
 	return t, nil
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getManagedRunnerGroupsFromHRAs(ctx context.Context, enterprise, org string) (*simulator.VisibleRunnerGroups, error) {
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getManagedRunnerGroupsFromHRAs(ctx context.Context, cfg *webhookConfig, enterprise, org string) (*simulator.VisibleRunnerGroups, error) {
 	groups := simulator.NewVisibleRunnerGroups()
-	ns := autoscaler.Namespace
-
-	var defaultListOpts []client.ListOption
-	if ns != "" {
-		defaultListOpts = append(defaultListOpts, client.InNamespace(ns))
-	}
 
-	opts := append([]client.ListOption{}, defaultListOpts...)
-	if autoscaler.Namespace != "" {
-		opts = append(opts, client.InNamespace(autoscaler.Namespace))
-	}
+	opts := cfg.hraListOptions()
 
 	var hraList v1alpha1.HorizontalRunnerAutoscalerList
 	if err := autoscaler.List(ctx, &hraList, opts...); err != nil {
@@ -552,8 +994,8 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getManagedRunnerGroup
 	return groups, nil
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx context.Context, name string, labels []string) (*ScaleTarget, error) {
-	hras, err := autoscaler.findHRAsByKey(ctx, name)
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx context.Context, log logr.Logger, cfg *webhookConfig, name string, labels []string, workflow *jobWorkflow) (*ScaleTarget, error) {
+	hras, err := autoscaler.findHRAsByKey(ctx, cfg, name)
 	if err != nil {
 		return nil, err
 	}
@@ -566,39 +1008,13 @@ HRA:
 			continue
 		}
 
-		if len(hra.Spec.ScaleUpTriggers) > 1 {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has too many ScaleUpTriggers to be used in workflow_job based scaling", "hra", hra.Name)
-			continue
-		}
-
-		if len(hra.Spec.ScaleUpTriggers) == 0 {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has no ScaleUpTriggers configured", "hra", hra.Name)
-			continue
-		}
-
-		scaleUpTrigger := hra.Spec.ScaleUpTriggers[0]
-
-		if scaleUpTrigger.GitHubEvent == nil {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has no `githubEvent` scale trigger configured", "hra", hra.Name)
-
-			continue
-		}
-
-		if scaleUpTrigger.GitHubEvent.WorkflowJob == nil {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has no `githubEvent.workflowJob` scale trigger configured", "hra", hra.Name)
-
+		trigger, err := autoscaler.workflowJobScaleUpTrigger(ctx, log, cfg, &hra, workflow)
+		if err != nil {
+			return nil, err
+		} else if trigger == nil {
 			continue
 		}
 
-		duration := scaleUpTrigger.Duration
-		if duration.Duration <= 0 {
-			// Try to release the reserved capacity after at least 10 minutes by default,
-			// we won't end up in the reserved capacity remained forever in case GitHub somehow stopped sending us "completed" workflow_job events.
-			// GitHub usually send us those but nothing is 100% guaranteed, e.g. in case of something went wrong on GitHub :)
-			// Probably we'd better make this configurable via custom resources in the future?
-			duration.Duration = 10 * time.Minute
-		}
-
 		switch hra.Spec.ScaleTargetRef.Kind {
 		case "RunnerSet":
 			var rs v1alpha1.RunnerSet
@@ -630,7 +1046,7 @@ HRA:
 				}
 			}
 
-			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}}, nil
+			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: *trigger}, nil
 		case "RunnerDeployment", "":
 			var rd v1alpha1.RunnerDeployment
 
@@ -661,7 +1077,7 @@ HRA:
 				}
 			}
 
-			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}}, nil
+			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: *trigger}, nil
 		default:
 			return nil, fmt.Errorf("unsupported scaleTargetRef.kind: %v", hra.Spec.ScaleTargetRef.Kind)
 		}
@@ -670,10 +1086,116 @@ HRA:
 	return nil, nil
 }
 
-func getValidCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscaler) []v1alpha1.CapacityReservation {
-	var capacityReservations []v1alpha1.CapacityReservation
+// workflowJobScaleUpTrigger returns the scale up trigger of the HRA for the job of a workflow_job event,
+// or nil when the HRA doesn't scale on the job.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) workflowJobScaleUpTrigger(ctx context.Context, log logr.Logger, cfg *webhookConfig, hra *v1alpha1.HorizontalRunnerAutoscaler, workflow *jobWorkflow) (*v1alpha1.ScaleUpTrigger, error) {
+	if len(hra.Spec.ScaleUpTriggers) > 1 {
+		autoscaler.Log.V(1).Info("Skipping this HRA as it has too many ScaleUpTriggers to be used in workflow_job based scaling", "hra", hra.Name)
+		return nil, nil
+	}
+
+	if len(hra.Spec.ScaleUpTriggers) == 0 {
+		autoscaler.Log.V(1).Info("Skipping this HRA as it has no ScaleUpTriggers configured", "hra", hra.Name)
+		return nil, nil
+	}
+
+	scaleUpTrigger := hra.Spec.ScaleUpTriggers[0]
+
+	if scaleUpTrigger.GitHubEvent == nil {
+		autoscaler.Log.V(1).Info("Skipping this HRA as it has no `githubEvent` scale trigger configured", "hra", hra.Name)
+
+		return nil, nil
+	}
+
+	if scaleUpTrigger.GitHubEvent.WorkflowJob == nil {
+		autoscaler.Log.V(1).Info("Skipping this HRA as it has no `githubEvent.workflowJob` scale trigger configured", "hra", hra.Name)
+
+		return nil, nil
+	}
+
+	if matched, err := autoscaler.matchWorkflowJobFilters(ctx, log, cfg, scaleUpTrigger.GitHubEvent.WorkflowJob, workflow); err != nil {
+		return nil, err
+	} else if !matched {
+		autoscaler.Log.V(1).Info("Skipping this HRA as the workflow of the job doesn't match its `githubEvent.workflowJob` filters", "hra", hra.Name, "workflow", workflow.name, "workflowPath", workflow.path)
+
+		return nil, nil
+	}
+
+	return &v1alpha1.ScaleUpTrigger{AmountExpression: scaleUpTrigger.AmountExpression, Duration: scaleUpTriggerDuration(cfg, scaleUpTrigger)}, nil
+}
+
+// scaleUpTriggerDuration returns the duration of the capacity reservations added by the scale up trigger.
+func scaleUpTriggerDuration(cfg *webhookConfig, scaleUpTrigger v1alpha1.ScaleUpTrigger) metav1.Duration {
+	duration := scaleUpTrigger.Duration
+	if duration.Duration <= 0 {
+		// Try to release the reserved capacity after at least 10 minutes by default,
+		// we won't end up in the reserved capacity remained forever in case GitHub somehow stopped sending us "completed" workflow_job events.
+		// GitHub usually send us those but nothing is 100% guaranteed, e.g. in case of something went wrong on GitHub :)
+		// The admission webhook usually sets the duration, but it can be disabled.
+		duration.Duration = cfg.defaultScaleUpTriggerDuration
+		if duration.Duration <= 0 {
+			duration.Duration = v1alpha1.DefaultScaleUpTriggerDuration
+		}
+	}
+
+	return duration
+}
 
-	now := time.Now()
+// evalScaleUpTriggerAmount evaluates the amountExpression of a scale up trigger of the HRA against the payload of a webhook event.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) evalScaleUpTriggerAmount(hra *v1alpha1.HorizontalRunnerAutoscaler, expr, event string, payload []byte) (int, error) {
+	program, err := autoscaler.scaleUpTriggerAmountProgram(hra, expr)
+	if err != nil {
+		return 0, fmt.Errorf("compiling amountExpression: %w", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return 0, fmt.Errorf("decoding webhook payload: %w", err)
+	}
+
+	return program.Eval(event, fields)
+}
+
+// amountPrograms are the compiled amountExpressions of the scale up triggers of a generation of an HRA.
+type amountPrograms struct {
+	generation int64
+	programs   map[string]*v1alpha1.ScaleUpTriggerAmountProgram
+}
+
+// scaleUpTriggerAmountProgram returns the compiled amountExpression of a scale up trigger of the HRA.
+// Expressions are compiled once per generation of the HRA rather than on every webhook event.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) scaleUpTriggerAmountProgram(hra *v1alpha1.HorizontalRunnerAutoscaler, expr string) (*v1alpha1.ScaleUpTriggerAmountProgram, error) {
+	autoscaler.amountProgramsMu.Lock()
+	defer autoscaler.amountProgramsMu.Unlock()
+
+	key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+	if autoscaler.amountPrograms == nil {
+		autoscaler.amountPrograms = map[types.NamespacedName]*amountPrograms{}
+	}
+
+	cached, ok := autoscaler.amountPrograms[key]
+	if !ok || cached.generation != hra.Generation {
+		cached = &amountPrograms{generation: hra.Generation, programs: map[string]*v1alpha1.ScaleUpTriggerAmountProgram{}}
+		autoscaler.amountPrograms[key] = cached
+	}
+
+	if program, ok := cached.programs[expr]; ok {
+		return program, nil
+	}
+
+	program, err := v1alpha1.CompileScaleUpTriggerAmountExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	cached.programs[expr] = program
+
+	return program, nil
+}
+
+func getValidCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscaler, now time.Time) []v1alpha1.CapacityReservation {
+	var capacityReservations []v1alpha1.CapacityReservation
 
 	for _, reservation := range autoscaler.Spec.CapacityReservations {
 		if reservation.ExpirationTime.Time.After(now) {
@@ -692,6 +1214,35 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) SetupWithManager(mgr
 
 	autoscaler.Recorder = mgr.GetEventRecorderFor(name)
 
+	if autoscaler.DeliveryQueue != nil {
+		// The manager starts the runnable once the cache is synced, so that the deliveries
+		// queued while the server was down are applied as soon as HRAs can be looked up.
+		if err := mgr.Add(manager.RunnableFunc(autoscaler.processWebhookDeliveries)); err != nil {
+			return err
+		}
+	}
+
+	if autoscaler.SourceIPAllowlist != nil {
+		log := autoscaler.Log.WithName("ipallowlist")
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return autoscaler.SourceIPAllowlist.Run(ctx, log)
+		})); err != nil {
+			return err
+		}
+	}
+
+	if autoscaler.ScaleHandoff != nil {
+		if err := mgr.Add(manager.RunnableFunc(autoscaler.runScaleHandoff)); err != nil {
+			return err
+		}
+	}
+
+	if autoscaler.ScalingEvents != nil {
+		if err := mgr.Add(manager.RunnableFunc(autoscaler.ScalingEvents.Run)); err != nil {
+			return err
+		}
+	}
+
 	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &v1alpha1.HorizontalRunnerAutoscaler{}, scaleTargetKey, autoscaler.indexer); err != nil {
 		return err
 	}
@@ -699,7 +1250,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) SetupWithManager(mgr
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
 		Named(name).
-		Complete(autoscaler)
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &v1alpha1.HorizontalRunnerAutoscaler{}, autoscaler))
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) indexer(rawObj client.Object) []string {
@@ -0,0 +1,74 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+// scaleTargetAllowsRepository returns false when the runners of the scale target of the HRA are restricted
+// to allowedRepositories that don't include the repository, which is the full name of the repository of the event.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) scaleTargetAllowsRepository(ctx context.Context, hra *v1alpha1.HorizontalRunnerAutoscaler, repository string) (bool, error) {
+	rc, err := autoscaler.scaleTargetRunnerConfig(ctx, hra)
+	if kerrors.IsNotFound(err) {
+		// The HRA of the missing scale target is left to the caller, as it was before the allowlist was enforced
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return repositoryAllowed(rc, repository), nil
+}
+
+// repositoryAllowed returns true when the runners aren't restricted to allowedRepositories, or the repository is one of them.
+func repositoryAllowed(rc *v1alpha1.RunnerConfig, repository string) bool {
+	if len(rc.AllowedRepositories) == 0 {
+		return true
+	}
+
+	owner, name, found := strings.Cut(repository, "/")
+	if !found {
+		owner, name = rc.Organization, repository
+	}
+
+	if !strings.EqualFold(owner, rc.Organization) {
+		return false
+	}
+
+	for _, r := range rc.AllowedRepositories {
+		if _, n, ok := strings.Cut(r, "/"); ok {
+			r = n
+		}
+
+		if strings.EqualFold(r, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withAllowedRepository wraps the function returning the scale target of the event in the repository,
+// so that the scale targets whose runners aren't allowed to run the jobs of the repository are never scaled.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) withAllowedRepository(ctx context.Context, log logr.Logger, repository string, scaleTarget func(value string) (*ScaleTarget, error)) func(value string) (*ScaleTarget, error) {
+	return func(value string) (*ScaleTarget, error) {
+		target, err := scaleTarget(value)
+		if target == nil || err != nil {
+			return target, err
+		}
+
+		if allowed, err := autoscaler.scaleTargetAllowsRepository(ctx, &target.HorizontalRunnerAutoscaler, repository); err != nil {
+			return nil, err
+		} else if !allowed {
+			log.Info("Refusing to scale the HRA as the repository isn't one of the allowed repositories of its runners", "hra", target.HorizontalRunnerAutoscaler.Name, "repository", repository)
+
+			return nil, nil
+		}
+
+		return target, nil
+	}
+}
@@ -0,0 +1,72 @@
+package actionssummerwindnet
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-github/v52/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestWebhookWorkflowJob_AllowedRepositories(t *testing.T) {
+	f, err := os.Open("testdata/org_webhook_workflow_job_payload.json")
+	if err != nil {
+		t.Fatalf("could not open the fixture: %s", err)
+	}
+	defer f.Close()
+	var e github.WorkflowJobEvent
+	if err := json.NewDecoder(f).Decode(&e); err != nil {
+		t.Fatalf("invalid json: %s", err)
+	}
+
+	newObjs := func(allowedRepositories []string) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+						},
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization:        "MYORG",
+							Group:               "linux",
+							AllowedRepositories: allowedRepositories,
+							Labels:              []string{"label1"},
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+
+	t.Run("Allowed", func(t *testing.T) {
+		testServerWithInitObjs(t, "workflow_job", &e, 200, "scaled test-name by 1", newObjs([]string{"other", "myrepo"}))
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		testServerWithInitObjs(t, "workflow_job", &e, 200, "no horizontalrunnerautoscaler to scale for this github event", newObjs([]string{"other"}))
+	})
+}
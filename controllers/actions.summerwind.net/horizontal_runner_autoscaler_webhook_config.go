@@ -0,0 +1,249 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultWebhookDeduplicationWindow is how long an applied delivery is remembered when the deduplication window is omitted.
+	DefaultWebhookDeduplicationWindow = 10 * time.Minute
+)
+
+// webhookConfig is the set of settings a webhook delivery is handled with.
+// It's either loaded from a WebhookAutoscalerConfig, or made of the settings given to the server via flags.
+type webhookConfig struct {
+	// key is the namespace/name of the WebhookAutoscalerConfig, or empty for the settings given via flags.
+	key string
+	// path is the HTTP path the config is served on. Unused for the settings given via flags, which are served on all the other paths.
+	path string
+	// created is the creation time of the WebhookAutoscalerConfig, used to let the oldest config win a path.
+	created time.Time
+
+	secretKeyBytes     []byte
+	nextSecretKeyBytes []byte
+
+	githubClient *github.Client
+	// githubCredsHash is the hash of the secret githubClient was created from, used to reuse the client until the secret changes.
+	githubCredsHash string
+
+	// namespace is the namespace of the HRAs to scale. Empty for all the namespaces.
+	namespace string
+	// selector is optional. When set, only the HRAs matching it are scaled.
+	selector labels.Selector
+
+	defaultScaleUpTriggerDuration time.Duration
+
+	// dedup is nil when deliveries aren't deduplicated.
+	dedup *webhookDeduplicator
+
+	// routes route the workflow_job events to the HRAs selected by the first matching route.
+	routes []*webhookRoute
+}
+
+func (c *webhookConfig) hraListOptions() []client.ListOption {
+	var opts []client.ListOption
+
+	if c.namespace != "" {
+		opts = append(opts, client.InNamespace(c.namespace))
+	}
+
+	if c.selector != nil {
+		opts = append(opts, client.MatchingLabelsSelector{Selector: c.selector})
+	}
+
+	return opts
+}
+
+// defaultConfig returns the settings given to the server via flags.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) defaultConfig() *webhookConfig {
+	return &webhookConfig{
+		secretKeyBytes:                autoscaler.SecretKeyBytes,
+		nextSecretKeyBytes:            autoscaler.NextSecretKeyBytes,
+		githubClient:                  autoscaler.GitHubClient,
+		namespace:                     autoscaler.Namespace,
+		defaultScaleUpTriggerDuration: autoscaler.DefaultScaleUpTriggerDuration,
+	}
+}
+
+// configForPath returns the config to handle a delivery to the path with.
+// It returns nil when no WebhookAutoscalerConfig serves the path and ConfigsOnly is set.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) configForPath(path string) *webhookConfig {
+	autoscaler.configsMu.RLock()
+	defer autoscaler.configsMu.RUnlock()
+
+	for _, c := range autoscaler.configs {
+		if c.path == path {
+			return c
+		}
+	}
+
+	if autoscaler.ConfigsOnly {
+		return nil
+	}
+
+	return autoscaler.defaultConfig()
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getConfig(key string) *webhookConfig {
+	autoscaler.configsMu.RLock()
+	defer autoscaler.configsMu.RUnlock()
+
+	return autoscaler.configs[key]
+}
+
+// setConfig starts serving the config on its path, replacing the previous version of the config.
+// When another config is served on the same path, the older of the two wins.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) setConfig(c *webhookConfig) error {
+	autoscaler.configsMu.Lock()
+	defer autoscaler.configsMu.Unlock()
+
+	if autoscaler.configs == nil {
+		autoscaler.configs = map[string]*webhookConfig{}
+	}
+
+	for key, other := range autoscaler.configs {
+		if key == c.key || other.path != c.path {
+			continue
+		}
+
+		if other.created.Before(c.created) || (other.created.Equal(c.created) && other.key < c.key) {
+			return fmt.Errorf("path %s is already served by WebhookAutoscalerConfig %s", c.path, other.key)
+		}
+
+		// The other config notices the conflict and reports it on its next resync
+		delete(autoscaler.configs, key)
+	}
+
+	autoscaler.configs[c.key] = c
+
+	return nil
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) deleteConfig(key string) {
+	autoscaler.configsMu.Lock()
+	defer autoscaler.configsMu.Unlock()
+
+	delete(autoscaler.configs, key)
+}
+
+// configForDelivery returns the config to apply the queued delivery with.
+//
+// A delivery for a WebhookAutoscalerConfig that isn't loaded yet, e.g. right after a restart, fails to be retried later,
+// whereas a delivery for a deleted one can never be applied.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) configForDelivery(ctx context.Context, d WebhookDelivery) (*webhookConfig, error) {
+	if d.Config == "" {
+		return autoscaler.defaultConfig(), nil
+	}
+
+	if c := autoscaler.getConfig(d.Config); c != nil {
+		return c, nil
+	}
+
+	ns, name, _ := strings.Cut(d.Config, "/")
+
+	var config v1alpha1.WebhookAutoscalerConfig
+	if err := autoscaler.Client.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, &config); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, invalidWebhookDeliveryError{fmt.Errorf("WebhookAutoscalerConfig %s no longer exists", d.Config)}
+		}
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("WebhookAutoscalerConfig %s is not loaded yet: %s", d.Config, config.Status.Message)
+}
+
+// webhookDeduplicator remembers the deliveries applied within the window, so that their duplicates are ignored.
+type webhookDeduplicator struct {
+	policy string
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newWebhookDeduplicator(spec *v1alpha1.WebhookDeduplication) *webhookDeduplicator {
+	if spec == nil || spec.Policy == "" || spec.Policy == v1alpha1.WebhookDeduplicationPolicyNone {
+		return nil
+	}
+
+	window := DefaultWebhookDeduplicationWindow
+	if spec.Window != nil && spec.Window.Duration > 0 {
+		window = spec.Window.Duration
+	}
+
+	return &webhookDeduplicator{
+		policy: spec.Policy,
+		window: window,
+		seen:   map[string]time.Time{},
+	}
+}
+
+// key returns the key the delivery is deduplicated by, or an empty string when it isn't deduplicated.
+func (d *webhookDeduplicator) key(deliveryID, webhookType string, payload []byte) string {
+	if d == nil {
+		return ""
+	}
+
+	switch d.policy {
+	case v1alpha1.WebhookDeduplicationPolicyDeliveryID:
+		return deliveryID
+	case v1alpha1.WebhookDeduplicationPolicyWorkflowJob:
+		if webhookType != "workflow_job" {
+			return ""
+		}
+
+		var e struct {
+			Action      string `json:"action"`
+			WorkflowJob struct {
+				ID int64 `json:"id"`
+			} `json:"workflow_job"`
+		}
+		if err := json.Unmarshal(payload, &e); err != nil || e.WorkflowJob.ID == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("%d/%s", e.WorkflowJob.ID, e.Action)
+	}
+
+	return ""
+}
+
+// claim returns false when the key was claimed within the window.
+// The key of a delivery that fails must be released, so that its redelivery is applied.
+func (d *webhookDeduplicator) claim(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for k, t := range d.seen {
+		if now.Sub(t) >= d.window {
+			delete(d.seen, k)
+		}
+	}
+
+	if _, ok := d.seen[key]; ok {
+		return false
+	}
+
+	d.seen[key] = now
+
+	return true
+}
+
+func (d *webhookDeduplicator) release(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.seen, key)
+}
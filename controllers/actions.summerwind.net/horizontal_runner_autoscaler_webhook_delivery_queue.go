@@ -0,0 +1,315 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+)
+
+const (
+	WebhookDeliveryQueueTypeFile = "file"
+
+	// webhookDeliveryRetryInterval is the interval between the attempts to apply the pending deliveries
+	// when no new delivery arrives in the meantime.
+	webhookDeliveryRetryInterval = 10 * time.Second
+
+	webhookDeliveryFileExt = ".json"
+)
+
+// WebhookDelivery is a GitHub webhook delivery accepted by the webhook server but not yet applied to any HRA.
+type WebhookDelivery struct {
+	// ID is the value of the X-GitHub-Delivery header.
+	ID         string    `json:"id"`
+	EventType  string    `json:"eventType"`
+	Payload    []byte    `json:"payload"`
+	ReceivedAt time.Time `json:"receivedAt"`
+
+	// Config is the namespace/name of the WebhookAutoscalerConfig the delivery was received for,
+	// or empty when it was received for the settings given via flags.
+	Config string `json:"config,omitempty"`
+
+	// key identifies the delivery within the queue.
+	key string
+}
+
+// WebhookDeliveryQueue persists the webhook deliveries accepted by the webhook server until they're applied.
+//
+// Without it, a delivery received while the HRAs can't be looked up or updated, for example during an upgrade or
+// a Kubernetes API outage, fails or is lost along with the in-memory queue on a restart.
+// GitHub doesn't redeliver failed deliveries automatically, so the jobs queued meanwhile never got capacity reservations.
+type WebhookDeliveryQueue interface {
+	// Enqueue persists the delivery. The delivery is safe once Enqueue returns without an error.
+	Enqueue(d WebhookDelivery) error
+	// Pending returns the persisted deliveries in the order they were enqueued.
+	Pending() ([]WebhookDelivery, error)
+	// Remove deletes the delivery returned by Pending, once it has been applied.
+	Remove(d WebhookDelivery) error
+}
+
+// NewWebhookDeliveryQueue returns the queue of the given type.
+// It returns nil without an error when queueType is empty, which makes the webhook server apply deliveries synchronously.
+func NewWebhookDeliveryQueue(queueType, dir string) (WebhookDeliveryQueue, error) {
+	switch queueType {
+	case "":
+		return nil, nil
+	case WebhookDeliveryQueueTypeFile:
+		if dir == "" {
+			return nil, fmt.Errorf("directory is required for the %s webhook delivery queue", queueType)
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating webhook delivery queue directory %s: %w", dir, err)
+		}
+		return &FileWebhookDeliveryQueue{Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unsupported webhook delivery queue type %q", queueType)
+	}
+}
+
+// FileWebhookDeliveryQueue stores each delivery in a file of its own in Dir, which is usually a persistent volume.
+//
+// A file is written to a temporary name and renamed, so that a crash never leaves a partially written delivery behind.
+type FileWebhookDeliveryQueue struct {
+	Dir string
+
+	mu   sync.Mutex
+	last int64
+}
+
+func (q *FileWebhookDeliveryQueue) Enqueue(d WebhookDelivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("marshaling webhook delivery %s: %w", d.ID, err)
+	}
+
+	name := q.nextName()
+
+	tmp, err := os.CreateTemp(q.Dir, "."+name+"-*")
+	if err != nil {
+		return fmt.Errorf("creating webhook delivery file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing webhook delivery file: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing webhook delivery file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing webhook delivery file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(q.Dir, name)); err != nil {
+		return fmt.Errorf("renaming webhook delivery file: %w", err)
+	}
+
+	return nil
+}
+
+// nextName returns a file name that sorts after the names of all the deliveries enqueued before,
+// even when the wall clock doesn't advance between two deliveries.
+func (q *FileWebhookDeliveryQueue) nextName() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := time.Now().UnixNano()
+	if n <= q.last {
+		n = q.last + 1
+	}
+	q.last = n
+
+	return fmt.Sprintf("%020d%s", n, webhookDeliveryFileExt)
+}
+
+func (q *FileWebhookDeliveryQueue) Pending() ([]WebhookDelivery, error) {
+	entries, err := os.ReadDir(q.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading webhook delivery queue directory %s: %w", q.Dir, err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		// Skip the temporary files of the deliveries being enqueued
+		if e.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != webhookDeliveryFileExt {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	deliveries := make([]WebhookDelivery, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(q.Dir, name))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("reading webhook delivery file %s: %w", name, err)
+		}
+
+		var d WebhookDelivery
+		if err := json.Unmarshal(data, &d); err != nil {
+			return nil, fmt.Errorf("unmarshaling webhook delivery file %s: %w", name, err)
+		}
+		d.key = name
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
+
+func (q *FileWebhookDeliveryQueue) Remove(d WebhookDelivery) error {
+	if d.key == "" {
+		return fmt.Errorf("webhook delivery %s was not returned by the queue", d.ID)
+	}
+
+	if err := os.Remove(filepath.Join(q.Dir, d.key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing webhook delivery file %s: %w", d.key, err)
+	}
+
+	return nil
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) deliveryQueuedChan() chan struct{} {
+	autoscaler.deliveryQueuedInit.Do(func() {
+		autoscaler.deliveryQueued = make(chan struct{}, 1)
+	})
+
+	return autoscaler.deliveryQueued
+}
+
+// notifyDeliveryQueued wakes up processWebhookDeliveries without blocking.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) notifyDeliveryQueued() {
+	select {
+	case autoscaler.deliveryQueuedChan() <- struct{}{}:
+	default:
+	}
+}
+
+// processWebhookDeliveries applies the deliveries persisted to the DeliveryQueue in order, until ctx is done.
+//
+// A delivery is removed from the queue only after its scale target is applied to the HRA.
+// A delivery that fails is retried along with all the deliveries after it, so that e.g. the completion of a job
+// is never applied before its queueing. A delivery that can never be applied is dropped instead.
+// As a result, a delivery is applied at least once, and a redelivered queued event is ignored by the batch scaler
+// when its job already has capacity reservations.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) processWebhookDeliveries(ctx context.Context) error {
+	log := autoscaler.Log.WithName("deliveryqueue")
+
+	log.Info("Starting webhook delivery queue processor")
+	defer log.Info("Stopped webhook delivery queue processor")
+
+	var (
+		mu sync.Mutex
+		// handled is the set of the deliveries handed to the worker. The value is false once the delivery is applied,
+		// and the entry is kept until a listing no longer contains it, so that a listing taken before the removal
+		// doesn't make us apply it twice.
+		handled = map[string]bool{}
+	)
+
+	for {
+		pending, err := autoscaler.DeliveryQueue.Pending()
+		if err != nil {
+			log.Error(err, "Failed to read pending webhook deliveries")
+		}
+
+		listed := make(map[string]bool, len(pending))
+		for _, d := range pending {
+			listed[d.key] = true
+		}
+
+		mu.Lock()
+		for key, inflight := range handled {
+			if !inflight && !listed[key] {
+				delete(handled, key)
+			}
+		}
+		mu.Unlock()
+
+		for _, d := range pending {
+			d := d
+
+			mu.Lock()
+			_, busy := handled[d.key]
+			if !busy {
+				handled[d.key] = true
+			}
+			mu.Unlock()
+
+			if busy {
+				continue
+			}
+
+			dlog := log.WithValues(
+				"event", d.EventType,
+				"delivery", d.ID,
+				"receivedAt", d.ReceivedAt,
+			)
+
+			if d.Config != "" {
+				dlog = dlog.WithValues("webhookAutoscalerConfig", d.Config)
+			}
+
+			done := func() {
+				if err := autoscaler.DeliveryQueue.Remove(d); err != nil {
+					dlog.Error(err, "Failed to remove applied webhook delivery")
+				}
+
+				mu.Lock()
+				handled[d.key] = false
+				mu.Unlock()
+			}
+
+			cfg, err := autoscaler.configForDelivery(ctx, d)
+			if err == nil {
+				_, err = autoscaler.handleEvent(ctx, dlog, cfg, d.EventType, d.ID, d.Payload, d.ReceivedAt, done)
+			}
+
+			if err != nil {
+				var invalid invalidWebhookDeliveryError
+				if errors.As(err, &invalid) {
+					dlog.Error(err, "Dropping queued webhook delivery that can never be applied")
+
+					// handleEvent counts the deliveries it drops on its own
+					if cfg == nil {
+						metrics.AddGitHubWebhookDeliveryDropped(d.EventType, webhookDropReasonConfigDeleted)
+					}
+
+					done()
+
+					continue
+				}
+
+				dlog.Error(err, "Failed to handle queued webhook delivery. It will be retried")
+
+				mu.Lock()
+				delete(handled, d.key)
+				mu.Unlock()
+
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-autoscaler.deliveryQueuedChan():
+		case <-time.After(webhookDeliveryRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFileWebhookDeliveryQueue(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := NewWebhookDeliveryQueue(WebhookDeliveryQueueTypeFile, dir)
+	require.NoError(t, err)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Enqueue(WebhookDelivery{ID: id, EventType: "workflow_job", Payload: []byte(`{"action":"queued"}`)}))
+	}
+
+	pending, err := q.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+	for i, id := range []string{"a", "b", "c"} {
+		require.Equal(t, id, pending[i].ID)
+	}
+	require.Equal(t, []byte(`{"action":"queued"}`), pending[0].Payload)
+
+	require.NoError(t, q.Remove(pending[1]))
+	// Removing what's already gone is a no-op
+	require.NoError(t, q.Remove(pending[1]))
+
+	// A queue reopened after a restart sees the same deliveries
+	q, err = NewWebhookDeliveryQueue(WebhookDeliveryQueueTypeFile, dir)
+	require.NoError(t, err)
+
+	pending, err = q.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, "a", pending[0].ID)
+	require.Equal(t, "c", pending[1].ID)
+
+	require.Error(t, q.Remove(WebhookDelivery{ID: "d"}))
+
+	_, err = NewWebhookDeliveryQueue(WebhookDeliveryQueueTypeFile, "")
+	require.Error(t, err)
+
+	_, err = NewWebhookDeliveryQueue("nats", dir)
+	require.Error(t, err)
+}
+
+func TestProcessWebhookDeliveries(t *testing.T) {
+	payload, err := os.ReadFile("testdata/org_webhook_workflow_job_payload.json")
+	require.NoError(t, err)
+
+	hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-name",
+		},
+		Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+				Name: "test-name",
+			},
+			ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+				{
+					GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+						WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+					},
+				},
+			},
+		},
+	}
+
+	rd := &actionsv1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-name",
+		},
+		Spec: actionsv1alpha1.RunnerDeploymentSpec{
+			Template: actionsv1alpha1.RunnerTemplate{
+				Spec: actionsv1alpha1.RunnerSpec{
+					RunnerConfig: actionsv1alpha1.RunnerConfig{
+						Organization: "MYORG",
+						Labels:       []string{"label1"},
+					},
+				},
+			},
+		},
+	}
+
+	queue, err := NewWebhookDeliveryQueue(WebhookDeliveryQueueTypeFile, t.TempDir())
+	require.NoError(t, err)
+
+	// Deliveries accepted before the server restarted, one of which can never be applied
+	require.NoError(t, queue.Enqueue(WebhookDelivery{ID: "invalid", EventType: "workflow_job", Payload: []byte("{")}))
+	require.NoError(t, queue.Enqueue(WebhookDelivery{ID: "queued", EventType: "workflow_job", Payload: payload}))
+
+	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Log:           logr.Discard(),
+		DeliveryQueue: queue,
+	}
+	hraWebhook.Client = fake.NewClientBuilder().
+		WithScheme(sc).
+		WithObjects(hra, rd).
+		WithIndex(&actionsv1alpha1.HorizontalRunnerAutoscaler{}, scaleTargetKey, hraWebhook.indexer).
+		Build()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = hraWebhook.processWebhookDeliveries(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		pending, err := queue.Pending()
+		return err == nil && len(pending) == 0
+	}, 30*time.Second, 100*time.Millisecond)
+
+	var updated actionsv1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, hraWebhook.Client.Get(ctx, types.NamespacedName{Name: "test-name"}, &updated))
+	require.Len(t, updated.Spec.CapacityReservations, 1)
+}
+
+func TestWebhookDeliveryQueued(t *testing.T) {
+	queue, err := NewWebhookDeliveryQueue(WebhookDeliveryQueueTypeFile, t.TempDir())
+	require.NoError(t, err)
+
+	// No HRA can be looked up, as if the Kubernetes API was unreachable
+	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Log:           logr.Discard(),
+		DeliveryQueue: queue,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(hraWebhook.Handle))
+	defer server.Close()
+
+	f, err := os.Open("testdata/org_webhook_workflow_job_payload.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	var e github.WorkflowJobEvent
+	require.NoError(t, json.NewDecoder(f).Decode(&e))
+
+	resp, err := sendWebhook(server, "workflow_job", &e)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "queued delivery ", string(body))
+
+	pending, err := queue.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, "workflow_job", pending[0].EventType)
+
+	// Ping events are still answered synchronously
+	resp, err = sendWebhook(server, "ping", &github.PingEvent{Zen: github.String("zen")})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "pong", string(body))
+}
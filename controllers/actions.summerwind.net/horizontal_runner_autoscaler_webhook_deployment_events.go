@@ -0,0 +1,140 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+const (
+	webhookTypeDeployment               = "deployment"
+	webhookTypeDeploymentProtectionRule = "deployment_protection_rule"
+	webhookTypeDeploymentStatus         = "deployment_status"
+)
+
+var defaultDeploymentEvents = []string{webhookTypeDeploymentProtectionRule, webhookTypeDeployment}
+
+// deploymentProtectionRuleEvent is the payload of a deployment_protection_rule event, which go-github doesn't parse.
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#deployment_protection_rule
+type deploymentProtectionRuleEvent struct {
+	Action      string               `json:"action,omitempty"`
+	Environment string               `json:"environment,omitempty"`
+	Event       string               `json:"event,omitempty"`
+	Deployment  *gogithub.Deployment `json:"deployment,omitempty"`
+	Repo        *gogithub.Repository `json:"repository,omitempty"`
+}
+
+// parseWebHook parses the payload of the webhook event like gogithub.ParseWebHook,
+// along with the events go-github doesn't parse.
+func parseWebHook(webhookType string, payload []byte) (interface{}, error) {
+	if webhookType == webhookTypeDeploymentProtectionRule {
+		var e deploymentProtectionRuleEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	}
+
+	return gogithub.ParseWebHook(webhookType, payload)
+}
+
+// deploymentEvent is a deployment_protection_rule, deployment or deployment_status event.
+// Like check_suite and workflow_run events, they don't include the labels of the jobs,
+// so they scale the HRAs of the repository, the organization or the enterprise regardless of the labels of their runners.
+type deploymentEvent struct {
+	// id is the ID of the deployment, which the capacity reservations added for it are attributed to,
+	// so that they are removed once it has a final status.
+	id          int64
+	environment string
+	// state is the state of the deployment_status event. It's empty for the other events.
+	state string
+}
+
+// getDeploymentScaleUpTarget returns the scale target of the deployment event, in the same order of precedence
+// as the scale targets of workflow_job events.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getDeploymentScaleUpTarget(
+	ctx context.Context, log logr.Logger, cfg *webhookConfig, webhookType string, repo *gogithub.Repository, enterprise string, e deploymentEvent,
+) (*ScaleTarget, error) {
+	scaleTarget := func(value string) (*ScaleTarget, error) {
+		return autoscaler.getDeploymentScaleTarget(ctx, cfg, value, webhookType, e)
+	}
+
+	target, err := autoscaler.getScaleUpTargetWithFunction(ctx, log, cfg, repo.GetName(), repo.GetOwner().GetLogin(), repo.GetOwner().GetType(), enterprise, scaleTarget)
+	if target == nil || err != nil {
+		return nil, err
+	}
+
+	target.Repository = repo.GetFullName()
+	target.JobID = e.id
+
+	return target, nil
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getDeploymentScaleTarget(ctx context.Context, cfg *webhookConfig, name, webhookType string, e deploymentEvent) (*ScaleTarget, error) {
+	hras, err := autoscaler.findHRAsByKey(ctx, cfg, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hra := range hras {
+		if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if len(hra.Spec.ScaleUpTriggers) != 1 || hra.Spec.ScaleUpTriggers[0].GitHubEvent == nil || hra.Spec.ScaleUpTriggers[0].GitHubEvent.Deployment == nil {
+			continue
+		}
+
+		scaleUpTrigger := hra.Spec.ScaleUpTriggers[0]
+		spec := scaleUpTrigger.GitHubEvent.Deployment
+
+		if len(spec.Environments) > 0 && !matchAnyGlob(spec.Environments, e.environment) {
+			autoscaler.Log.V(1).Info("Skipping this HRA as the environment doesn't match its environments", "hra", hra.Name, "event", webhookType, "environment", e.environment)
+
+			continue
+		}
+
+		events := spec.Events
+		if len(events) == 0 {
+			events = defaultDeploymentEvents
+		}
+
+		target := &ScaleTarget{
+			HorizontalRunnerAutoscaler: hra,
+			ScaleUpTrigger:             v1alpha1.ScaleUpTrigger{AmountExpression: scaleUpTrigger.AmountExpression, Duration: scaleUpTriggerDuration(cfg, scaleUpTrigger)},
+		}
+
+		switch {
+		case webhookType == webhookTypeDeploymentStatus && e.state == "in_progress":
+			// An approved deployment holds the reservations added while it was waiting until it has a final status
+			target.Renew = true
+		case webhookType == webhookTypeDeploymentStatus:
+			target.Amount = -1
+		case slices.Contains(events, webhookType):
+			target.Amount = 1
+		default:
+			autoscaler.Log.V(1).Info("Skipping this HRA as the event isn't one of its events", "hra", hra.Name, "event", webhookType)
+
+			continue
+		}
+
+		return target, nil
+	}
+
+	return nil, nil
+}
+
+// deploymentStatusFinal returns true when the deployment_status state is final, so that the deployment no longer needs runners.
+func deploymentStatusFinal(state string) bool {
+	switch state {
+	case "success", "failure", "error", "inactive":
+		return true
+	}
+
+	return false
+}
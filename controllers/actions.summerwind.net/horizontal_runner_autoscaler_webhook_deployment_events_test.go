@@ -0,0 +1,166 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-github/v52/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestWebhookDeployment(t *testing.T) {
+	newObjs := func(spec actionsv1alpha1.GitHubEventScaleUpTriggerSpec) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &spec,
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+
+	repo := &github.Repository{
+		Name:     github.String("myrepo"),
+		FullName: github.String("MYORG/myrepo"),
+		Owner: &github.User{
+			Login: github.String("MYORG"),
+			Type:  github.String("Organization"),
+		},
+	}
+
+	protectionRule := func(action, environment string) *deploymentProtectionRuleEvent {
+		return &deploymentProtectionRuleEvent{
+			Action:      action,
+			Environment: environment,
+			Event:       "push",
+			Deployment:  &github.Deployment{ID: github.Int64(3), Environment: github.String(environment)},
+			Repo:        repo,
+		}
+	}
+
+	deployment := func(environment string) *github.DeploymentEvent {
+		return &github.DeploymentEvent{
+			Deployment: &github.Deployment{ID: github.Int64(3), Environment: github.String(environment)},
+			Repo:       repo,
+		}
+	}
+
+	deploymentStatus := func(state string) *github.DeploymentStatusEvent {
+		return &github.DeploymentStatusEvent{
+			Deployment:       &github.Deployment{ID: github.Int64(3), Environment: github.String("production")},
+			DeploymentStatus: &github.DeploymentStatus{State: github.String(state)},
+			Repo:             repo,
+		}
+	}
+
+	noTarget := "no horizontalrunnerautoscaler to scale for this github event"
+
+	tests := []struct {
+		name      string
+		spec      actionsv1alpha1.GitHubEventScaleUpTriggerSpec
+		eventType string
+		event     interface{}
+		want      string
+	}{
+		{
+			name:      "protection rule requested",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{Deployment: &actionsv1alpha1.DeploymentSpec{}},
+			eventType: "deployment_protection_rule",
+			event:     protectionRule("requested", "production"),
+			want:      "scaled test-name by 1",
+		},
+		{
+			name:      "protection rule requested for matching environment",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{Deployment: &actionsv1alpha1.DeploymentSpec{Environments: []string{"prod*"}}},
+			eventType: "deployment_protection_rule",
+			event:     protectionRule("requested", "production"),
+			want:      "scaled test-name by 1",
+		},
+		{
+			name:      "protection rule requested for other environment",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{Deployment: &actionsv1alpha1.DeploymentSpec{Environments: []string{"prod*"}}},
+			eventType: "deployment_protection_rule",
+			event:     protectionRule("requested", "staging"),
+			want:      noTarget,
+		},
+		{
+			name:      "protection rule of unknown action",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{Deployment: &actionsv1alpha1.DeploymentSpec{}},
+			eventType: "deployment_protection_rule",
+			event:     protectionRule("approved", "production"),
+		},
+		{
+			name:      "deployment created",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{Deployment: &actionsv1alpha1.DeploymentSpec{}},
+			eventType: "deployment",
+			event:     deployment("production"),
+			want:      "scaled test-name by 1",
+		},
+		{
+			name:      "deployment created but not in events",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{Deployment: &actionsv1alpha1.DeploymentSpec{Events: []string{"deployment_protection_rule"}}},
+			eventType: "deployment",
+			event:     deployment("production"),
+			want:      noTarget,
+		},
+		{
+			name:      "deployment without trigger",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{}},
+			eventType: "deployment",
+			event:     deployment("production"),
+			want:      noTarget,
+		},
+		{
+			name:      "deployment in progress",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{Deployment: &actionsv1alpha1.DeploymentSpec{}},
+			eventType: "deployment_status",
+			event:     deploymentStatus("in_progress"),
+			want:      "held capacity reservations of job 3 for test-name",
+		},
+		{
+			name:      "deployment succeeded",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{Deployment: &actionsv1alpha1.DeploymentSpec{}},
+			eventType: "deployment_status",
+			event:     deploymentStatus("success"),
+			want:      "scaled test-name by -1",
+		},
+		{
+			name:      "deployment waiting",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{Deployment: &actionsv1alpha1.DeploymentSpec{}},
+			eventType: "deployment_status",
+			event:     deploymentStatus("waiting"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testServerWithInitObjs(t, tt.eventType, tt.event, 200, tt.want, newObjs(tt.spec))
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ScaleHandoffTypeConfigMap = "configmap"
+
+	DefaultScaleHandoffConfigMapName = "actions-runner-controller-scale-handoff"
+
+	// scaleHandoffPollInterval is how often a running webhook server looks for the scale operations handed off by others.
+	// A server started by a rolling update is running before the old one hands off its operations,
+	// so it can't just restore them on start.
+	scaleHandoffPollInterval = 10 * time.Second
+
+	// scaleHandoffTimeout is the time the webhook server has to hand off its operations once it's stopping.
+	scaleHandoffTimeout = 10 * time.Second
+)
+
+// ScaleHandoffOperation is a scale operation not yet applied to its HRA by a webhook server that stopped,
+// like the capacity reservation of a job queued within the last batch interval.
+type ScaleHandoffOperation struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	Trigger    v1alpha1.ScaleUpTrigger `json:"trigger"`
+	Repository string                  `json:"repository,omitempty"`
+	JobID      int64                   `json:"jobID,omitempty"`
+	Renew      bool                    `json:"renew,omitempty"`
+
+	// Event, DeliveryID and ReceivedAt are the type, the delivery and the receipt time of the webhook event the operation originates from.
+	Event      string      `json:"event,omitempty"`
+	DeliveryID string      `json:"deliveryID,omitempty"`
+	ReceivedAt metav1.Time `json:"receivedAt,omitempty"`
+
+	// RetryAfter is when the operation was going to be retried after failing to be applied.
+	// It's empty for an operation that was never tried.
+	RetryAfter metav1.Time `json:"retryAfter,omitempty"`
+}
+
+// ScaleHandoff carries the scale operations left unapplied by a stopping webhook server over to the running ones.
+//
+// The webhook server batches the scale operations in memory for a few seconds before applying them to HRAs,
+// and retries the failed ones with a backoff. Without a handoff, the operations still in memory are lost when the
+// server is stopped, e.g. during an upgrade, so the jobs queued meanwhile never get capacity reservations.
+type ScaleHandoff interface {
+	// Save records the operations left unapplied by the webhook server identified by identity.
+	Save(ctx context.Context, identity string, ops []ScaleHandoffOperation) error
+	// Take removes the operations recorded by all the webhook servers, and returns them.
+	Take(ctx context.Context) ([]ScaleHandoffOperation, error)
+}
+
+// NewScaleHandoff returns the handoff of the given type.
+// It returns nil without an error when handoffType is empty, which disables the handoff.
+func NewScaleHandoff(handoffType string, c client.Client, namespace, name string) (ScaleHandoff, error) {
+	switch handoffType {
+	case "":
+		return nil, nil
+	case ScaleHandoffTypeConfigMap:
+		if namespace == "" {
+			return nil, fmt.Errorf("namespace is required for the %s scale handoff", handoffType)
+		}
+		if name == "" {
+			name = DefaultScaleHandoffConfigMapName
+		}
+		return &ConfigMapScaleHandoff{Client: c, Namespace: namespace, Name: name}, nil
+	default:
+		return nil, fmt.Errorf("unsupported scale handoff type %q", handoffType)
+	}
+}
+
+// ConfigMapScaleHandoff records the operations in a single ConfigMap, keyed by the identity of the webhook server.
+type ConfigMapScaleHandoff struct {
+	client.Client
+
+	Namespace string
+	Name      string
+}
+
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;create;update
+
+func (h *ConfigMapScaleHandoff) Save(ctx context.Context, identity string, ops []ScaleHandoffOperation) error {
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return fmt.Errorf("marshaling scale operations of %s: %w", identity, err)
+	}
+
+	return updateConfigMap(ctx, h.Client, types.NamespacedName{Namespace: h.Namespace, Name: h.Name}, func(cm *corev1.ConfigMap) bool {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[identity] = string(data)
+		return true
+	})
+}
+
+func (h *ConfigMapScaleHandoff) Take(ctx context.Context) ([]ScaleHandoffOperation, error) {
+	var (
+		taken []ScaleHandoffOperation
+		errs  []error
+	)
+
+	err := updateConfigMap(ctx, h.Client, types.NamespacedName{Namespace: h.Namespace, Name: h.Name}, func(cm *corev1.ConfigMap) bool {
+		// The update may be retried on conflict, in which case the ConfigMap is read again
+		taken, errs = nil, nil
+
+		if len(cm.Data) == 0 {
+			return false
+		}
+
+		identities := make([]string, 0, len(cm.Data))
+		for identity := range cm.Data {
+			identities = append(identities, identity)
+		}
+		sort.Strings(identities)
+
+		for _, identity := range identities {
+			var ops []ScaleHandoffOperation
+			if err := json.Unmarshal([]byte(cm.Data[identity]), &ops); err != nil {
+				// The entry is removed anyway, as it would never be readable
+				errs = append(errs, fmt.Errorf("unmarshaling scale operations of %s: %w", identity, err))
+				continue
+			}
+			taken = append(taken, ops...)
+		}
+
+		cm.Data = nil
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return taken, errors.Join(errs...)
+}
+
+// runScaleHandoff restores the scale operations handed off by other webhook servers until ctx is done,
+// and then hands off the ones this server hasn't applied yet.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) runScaleHandoff(ctx context.Context) error {
+	log := autoscaler.Log.WithName("scalehandoff")
+
+	log.Info("Starting scale handoff", "identity", autoscaler.ScaleHandoffIdentity)
+	defer log.Info("Stopped scale handoff")
+
+	autoscaler.initWorker()
+
+	ticker := time.NewTicker(scaleHandoffPollInterval)
+	defer ticker.Stop()
+
+	for {
+		autoscaler.restoreScaleOperations(ctx, log)
+
+		select {
+		case <-ctx.Done():
+			return autoscaler.handOffScaleOperations(log)
+		case <-ticker.C:
+		}
+	}
+}
+
+// restoreScaleOperations takes the scale operations handed off by other webhook servers, and applies them.
+// An operation that was waiting for a retry is applied once the retry was due.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) restoreScaleOperations(ctx context.Context, log logr.Logger) {
+	ops, err := autoscaler.ScaleHandoff.Take(ctx)
+	if err != nil {
+		log.Error(err, "Failed to take handed off scale operations")
+	}
+
+	if len(ops) == 0 {
+		return
+	}
+
+	log.Info("Restoring handed off scale operations", "operations", len(ops))
+
+	for _, op := range ops {
+		opLog := log.WithValues(
+			"hra", types.NamespacedName{Namespace: op.Namespace, Name: op.Name},
+			"event", op.Event,
+			"jobID", op.JobID,
+		)
+
+		st := &ScaleTarget{
+			HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: op.Namespace, Name: op.Name},
+			},
+			ScaleUpTrigger: op.Trigger,
+			Repository:     op.Repository,
+			JobID:          op.JobID,
+			Renew:          op.Renew,
+			log:            &opLog,
+			event:          op.Event,
+			deliveryID:     op.DeliveryID,
+			receivedAt:     op.ReceivedAt.Time,
+		}
+
+		autoscaler.batchScaler.addAfter(st, op.RetryAfter.Time)
+	}
+}
+
+// handOffScaleOperations stops the worker, and saves the scale operations it hasn't applied yet to the ScaleHandoff.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) handOffScaleOperations(log logr.Logger) error {
+	autoscaler.stopWorker()
+
+	// The scale targets still in the queue of the worker were enqueued after the ones the batch scaler holds
+	queued := map[types.NamespacedName]batchScaleOperation{}
+	for _, st := range autoscaler.worker.Drain() {
+		addToBatches(queued, st)
+	}
+
+	batches := autoscaler.batchScaler.unappliedOperations()
+	for _, b := range queued {
+		batches = append(batches, b)
+	}
+
+	var ops []ScaleHandoffOperation
+	for _, b := range batches {
+		for _, op := range b.scaleOps {
+			// The operations of the deliveries persisted to the DeliveryQueue are applied again from the queue
+			if op.done != nil {
+				continue
+			}
+
+			ops = append(ops, ScaleHandoffOperation{
+				Namespace:  b.namespacedName.Namespace,
+				Name:       b.namespacedName.Name,
+				Trigger:    op.trigger,
+				Repository: op.repository,
+				JobID:      op.jobID,
+				Renew:      op.renew,
+				Event:      op.event,
+				DeliveryID: op.deliveryID,
+				ReceivedAt: metav1.NewTime(op.receivedAt),
+				RetryAfter: metav1.NewTime(b.retryAfter),
+			})
+		}
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	// ctx of the manager is already done
+	ctx, cancel := context.WithTimeout(context.Background(), scaleHandoffTimeout)
+	defer cancel()
+
+	if err := autoscaler.ScaleHandoff.Save(ctx, autoscaler.ScaleHandoffIdentity, ops); err != nil {
+		return fmt.Errorf("handing off %d scale operations: %w", len(ops), err)
+	}
+
+	log.Info("Handed off unapplied scale operations", "operations", len(ops))
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConfigMapScaleHandoff(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	handoff, err := NewScaleHandoff(ScaleHandoffTypeConfigMap, c, "arc-system", "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// Taking from a missing ConfigMap doesn't create it
+	ops, err := handoff.Take(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ops)
+
+	retryAfter := metav1.NewTime(time.Now().Add(time.Minute).Truncate(time.Second))
+
+	require.NoError(t, handoff.Save(ctx, "server-b", []ScaleHandoffOperation{
+		{Namespace: "default", Name: "example", Trigger: v1alpha1.ScaleUpTrigger{Amount: -1}, JobID: 2},
+	}))
+	require.NoError(t, handoff.Save(ctx, "server-a", []ScaleHandoffOperation{
+		{Namespace: "default", Name: "example", Trigger: v1alpha1.ScaleUpTrigger{Amount: 1}, JobID: 1, RetryAfter: retryAfter},
+	}))
+
+	ops, err = handoff.Take(ctx)
+	require.NoError(t, err)
+	require.Len(t, ops, 2)
+	require.Equal(t, int64(1), ops[0].JobID)
+	require.True(t, ops[0].RetryAfter.Equal(&retryAfter))
+	require.Equal(t, int64(2), ops[1].JobID)
+	require.True(t, ops[1].RetryAfter.IsZero())
+
+	// The operations are taken only once
+	ops, err = handoff.Take(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ops)
+
+	_, err = NewScaleHandoff(ScaleHandoffTypeConfigMap, c, "", "")
+	require.Error(t, err)
+
+	_, err = NewScaleHandoff("redis", c, "arc-system", "")
+	require.Error(t, err)
+}
+
+func TestScaleHandoff_BetweenWebhookServers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	key := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+	}).Build()
+
+	handoff, err := NewScaleHandoff(ScaleHandoffTypeConfigMap, c, "arc-system", "")
+	require.NoError(t, err)
+
+	newServer := func(identity string) *HorizontalRunnerAutoscalerGitHubWebhook {
+		s := &HorizontalRunnerAutoscalerGitHubWebhook{
+			Client:               c,
+			Log:                  logr.Discard(),
+			ScaleHandoff:         handoff,
+			ScaleHandoffIdentity: identity,
+		}
+		s.initWorker()
+		s.batchScaler.interval = 100 * time.Millisecond
+		return s
+	}
+
+	log := logr.Discard()
+	target := func(jobID int64, done func()) *ScaleTarget {
+		return &ScaleTarget{
+			HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			},
+			ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Amount: 1, Duration: metav1.Duration{Duration: time.Hour}},
+			JobID:          jobID,
+			log:            &log,
+			done:           done,
+			event:          "workflow_job",
+			receivedAt:     time.Now(),
+		}
+	}
+
+	outgoing := newServer("outgoing")
+	outgoing.batchScaler.interval = time.Hour
+
+	require.True(t, outgoing.worker.Add(target(1, nil)))
+	// The delivery of a DeliveryQueue is applied again from the queue instead
+	require.True(t, outgoing.worker.Add(target(2, func() {})))
+
+	require.NoError(t, outgoing.handOffScaleOperations(log))
+
+	// A stopped server accepts no more scale targets
+	require.False(t, outgoing.worker.Add(target(3, nil)))
+
+	var hra v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(context.Background(), key, &hra))
+	require.Empty(t, hra.Spec.CapacityReservations, "the outgoing server must not have applied the operations")
+
+	incoming := newServer("incoming")
+	incoming.restoreScaleOperations(context.Background(), log)
+
+	require.Eventually(t, func() bool {
+		var hra v1alpha1.HorizontalRunnerAutoscaler
+		if err := c.Get(context.Background(), key, &hra); err != nil {
+			return false
+		}
+		return len(hra.Spec.CapacityReservations) == 1 && hra.Spec.CapacityReservations[0].JobID == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	ops, err := handoff.Take(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, ops, "the handed off operations must be restored only once")
+}
+
+func TestBatchScaler_UnappliedOperations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := newBatchScaler(ctx, nil, logr.Discard(), nil)
+
+	log := logr.Discard()
+	retryAfter := time.Now().Add(time.Hour)
+
+	s.addAfter(&ScaleTarget{
+		HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+		},
+		JobID: 1,
+		log:   &log,
+	}, retryAfter)
+
+	cancel()
+
+	unapplied := s.unappliedOperations()
+	require.Len(t, unapplied, 1)
+	require.Equal(t, types.NamespacedName{Namespace: "default", Name: "example"}, unapplied[0].namespacedName)
+	require.Equal(t, retryAfter, unapplied[0].retryAfter)
+	require.Len(t, unapplied[0].scaleOps, 1)
+}
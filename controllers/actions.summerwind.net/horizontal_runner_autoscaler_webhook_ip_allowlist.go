@@ -0,0 +1,276 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// DefaultGitHubMetaURL is the URL of the GitHub API that lists the IP ranges GitHub sends webhook deliveries from.
+	DefaultGitHubMetaURL = "https://api.github.com/meta"
+
+	// DefaultSourceIPAllowlistRefreshInterval is the default interval of fetching the IP ranges of GitHub.
+	DefaultSourceIPAllowlistRefreshInterval = time.Hour
+
+	// sourceIPAllowlistRetryInterval is the interval of retrying a failed fetch of the IP ranges of GitHub.
+	sourceIPAllowlistRetryInterval = time.Minute
+)
+
+// SourceIPAllowlist rejects the webhook deliveries not sent from the IP ranges GitHub sends them from,
+// as a defense in depth for the webhook servers whose port is broadly exposed.
+//
+// The ranges are the "hooks" ranges of the GitHub meta API, refreshed on an interval, and the CIDRs given
+// by the user, like the addresses of a GitHub Enterprise Server.
+type SourceIPAllowlist struct {
+	// MetaURL is the URL of the GitHub meta API. The ranges of the API aren't fetched when it's empty.
+	MetaURL string
+	// CIDRs are always allowed in addition to the ranges of the GitHub meta API.
+	CIDRs []netip.Prefix
+	// TrustedProxies are the ranges of the load balancers and the reverse proxies in front of the webhook server.
+	// The source of a request from a trusted proxy is the rightmost untrusted address of its X-Forwarded-For header.
+	TrustedProxies []netip.Prefix
+	// RefreshInterval is the interval of fetching the ranges of the GitHub meta API.
+	RefreshInterval time.Duration
+	// HTTPClient is the client used to call the GitHub meta API. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	mu sync.RWMutex
+	// hooks are the ranges of the GitHub meta API, and hooksLoaded is true once they were fetched.
+	hooks       []netip.Prefix
+	hooksLoaded bool
+}
+
+// NewSourceIPAllowlist returns an allowlist of the ranges of the GitHub meta API at metaURL and the CIDRs,
+// which trusts the X-Forwarded-For header of the requests from the trustedProxies.
+func NewSourceIPAllowlist(metaURL string, cidrs, trustedProxies []string, refreshInterval time.Duration) (*SourceIPAllowlist, error) {
+	if metaURL == "" && len(cidrs) == 0 {
+		return nil, fmt.Errorf("either the URL of the GitHub meta API or CIDRs are required for the IP allowlist")
+	}
+
+	allowed, err := parsePrefixes(cidrs)
+	if err != nil {
+		return nil, err
+	}
+
+	proxies, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultSourceIPAllowlistRefreshInterval
+	}
+
+	return &SourceIPAllowlist{
+		MetaURL:         metaURL,
+		CIDRs:           allowed,
+		TrustedProxies:  proxies,
+		RefreshInterval: refreshInterval,
+	}, nil
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+
+		// Accept single addresses as well, as it's common to allowlist a single GHES instance
+		if !strings.Contains(c, "/") {
+			addr, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %q as a CIDR: %w", c, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q as a CIDR: %w", c, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+
+	return prefixes, nil
+}
+
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Ready returns false until the ranges of the GitHub meta API are fetched for the first time.
+// The allowlist is always ready when it doesn't fetch the ranges.
+func (a *SourceIPAllowlist) Ready() bool {
+	if a.MetaURL == "" {
+		return true
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.hooksLoaded
+}
+
+// Allowed returns the source address of the request, and whether the address is allowed.
+func (a *SourceIPAllowlist) Allowed(r *http.Request) (netip.Addr, bool) {
+	addr, ok := requestSourceAddr(r, a.TrustedProxies)
+	if !ok {
+		return addr, false
+	}
+
+	if prefixesContain(a.CIDRs, addr) {
+		return addr, true
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return addr, prefixesContain(a.hooks, addr)
+}
+
+// requestSourceAddr returns the address the request originates from, following the X-Forwarded-For header
+// as long as the request comes from the trustedProxies.
+func requestSourceAddr(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap()
+
+	if !prefixesContain(trustedProxies, addr) {
+		return addr, true
+	}
+
+	var forwarded []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(h, ",")...)
+	}
+
+	// The rightmost addresses are appended by the trusted proxies, while the leftmost ones can be forged by the client
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		hop = hop.Unmap()
+
+		addr = hop
+
+		if !prefixesContain(trustedProxies, hop) {
+			break
+		}
+	}
+
+	return addr, true
+}
+
+type githubMeta struct {
+	Hooks []string `json:"hooks"`
+}
+
+// Refresh fetches the ranges of the GitHub meta API.
+// The ranges fetched before are kept when it fails.
+func (a *SourceIPAllowlist) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.MetaURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	httpClient := a.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", a.MetaURL, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: unexpected status %s", a.MetaURL, res.Status)
+	}
+
+	var meta githubMeta
+	if err := json.NewDecoder(res.Body).Decode(&meta); err != nil {
+		return fmt.Errorf("decoding %s: %w", a.MetaURL, err)
+	}
+
+	if len(meta.Hooks) == 0 {
+		return fmt.Errorf("%s lists no hooks IP ranges", a.MetaURL)
+	}
+
+	hooks, err := parsePrefixes(meta.Hooks)
+	if err != nil {
+		return fmt.Errorf("parsing hooks IP ranges of %s: %w", a.MetaURL, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.hooks = hooks
+	a.hooksLoaded = true
+
+	return nil
+}
+
+// Run refreshes the ranges of the GitHub meta API every RefreshInterval until ctx is done.
+// A failed refresh is retried sooner.
+func (a *SourceIPAllowlist) Run(ctx context.Context, log logr.Logger) error {
+	if a.MetaURL == "" {
+		return nil
+	}
+
+	log.Info("Starting IP allowlist refresh", "metaURL", a.MetaURL, "interval", a.RefreshInterval)
+	defer log.Info("Stopped IP allowlist refresh")
+
+	for {
+		interval := a.RefreshInterval
+
+		if err := a.Refresh(ctx); err != nil {
+			log.Error(err, "Failed to refresh the IP ranges of GitHub webhook deliveries. The last ones are used meanwhile")
+
+			if interval > sourceIPAllowlistRetryInterval {
+				interval = sourceIPAllowlistRetryInterval
+			}
+		} else {
+			a.mu.RLock()
+			n := len(a.hooks)
+			a.mu.RUnlock()
+
+			log.V(1).Info("Refreshed the IP ranges of GitHub webhook deliveries", "ranges", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceIPAllowlist(t *testing.T) {
+	hooks := `"192.30.252.0/22", "2a0a:a440::/29"`
+
+	meta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"verifiable_password_authentication":false,"hooks":[%s],"web":["140.82.112.0/20"]}`, hooks)
+	}))
+	defer meta.Close()
+
+	a, err := NewSourceIPAllowlist(meta.URL, []string{"10.1.0.0/16", "10.2.0.1"}, []string{"10.0.0.0/24"}, 0)
+	require.NoError(t, err)
+
+	require.False(t, a.Ready())
+	require.NoError(t, a.Refresh(context.Background()))
+	require.True(t, a.Ready())
+
+	allowed := func(remoteAddr string, forwardedFor ...string) bool {
+		t.Helper()
+
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = remoteAddr
+		for _, f := range forwardedFor {
+			r.Header.Add("X-Forwarded-For", f)
+		}
+
+		_, ok := a.Allowed(r)
+		return ok
+	}
+
+	assert.True(t, allowed("192.30.252.1:12345"), "hooks range")
+	assert.True(t, allowed("[2a0a:a440::1]:12345"), "IPv6 hooks range")
+	assert.True(t, allowed("[::ffff:192.30.252.1]:12345"), "IPv4-mapped hooks range")
+	assert.False(t, allowed("140.82.112.1:12345"), "web range")
+	assert.True(t, allowed("10.1.2.3:12345"), "custom CIDR")
+	assert.True(t, allowed("10.2.0.1:12345"), "custom address")
+	assert.False(t, allowed("10.2.0.2:12345"))
+
+	// X-Forwarded-For is followed only for the requests from the trusted proxies
+	assert.True(t, allowed("10.0.0.1:12345", "192.30.252.1"))
+	assert.True(t, allowed("10.0.0.1:12345", "203.0.113.1, 192.30.252.1, 10.0.0.2"))
+	assert.False(t, allowed("10.0.0.1:12345", "192.30.252.1, 203.0.113.1"), "forged leftmost address")
+	assert.False(t, allowed("10.0.0.1:12345", "192.30.252.1", "203.0.113.1"), "forged leftmost header")
+	assert.False(t, allowed("203.0.113.1:12345", "192.30.252.1"), "untrusted proxy")
+	assert.False(t, allowed("10.0.0.1:12345", "not-an-ip"))
+
+	// A failed refresh keeps the ranges fetched before
+	hooks = `"not-a-cidr"`
+	require.Error(t, a.Refresh(context.Background()))
+	assert.True(t, allowed("192.30.252.1:12345"))
+
+	_, err = NewSourceIPAllowlist("", nil, nil, 0)
+	require.Error(t, err)
+
+	_, err = NewSourceIPAllowlist("", []string{"10.0.0.0/33"}, nil, 0)
+	require.Error(t, err)
+
+	custom, err := NewSourceIPAllowlist("", []string{"10.1.0.0/16"}, nil, 0)
+	require.NoError(t, err)
+	require.True(t, custom.Ready(), "the allowlist without the meta API is ready right away")
+}
+
+func TestWebhookSourceIPAllowlist(t *testing.T) {
+	meta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"hooks":["192.30.252.0/22"]}`)
+	}))
+	defer meta.Close()
+
+	allowlist, err := NewSourceIPAllowlist(meta.URL, nil, nil, 0)
+	require.NoError(t, err)
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{SourceIPAllowlist: allowlist}
+	installTestLogger(webhook)
+
+	handle := func(remoteAddr string) (int, string) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"zen":"Keep it logically awesome."}`))
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-GitHub-Event", "ping")
+
+		w := httptest.NewRecorder()
+		webhook.Handle(w, r)
+
+		return w.Code, w.Body.String()
+	}
+
+	code, _ := handle("192.30.252.1:12345")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "the deliveries are rejected until the ranges are fetched")
+
+	require.NoError(t, allowlist.Refresh(context.Background()))
+
+	code, _ = handle("203.0.113.1:12345")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, body := handle("192.30.252.1:12345")
+	assert.Equal(t, http.StatusOK, code, body)
+
+	// The health check is served regardless of the source
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.1:12345"
+	w := httptest.NewRecorder()
+	webhook.Handle(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
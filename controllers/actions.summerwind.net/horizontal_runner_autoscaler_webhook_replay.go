@@ -0,0 +1,205 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/github"
+	gogithub "github.com/google/go-github/v52/github"
+)
+
+const (
+	// ReplayPath is the path of the metrics server the WebhookReplayer is served on.
+	ReplayPath = "/replay"
+
+	// maxReplayRequestSize is the maximum size of a replay request, which is about the maximum size of a webhook payload.
+	maxReplayRequestSize = 25 << 20
+)
+
+// WebhookReplayRequest is the body of a request to the WebhookReplayer.
+// Either DeliveryID or Event and Payload are required.
+type WebhookReplayRequest struct {
+	// Path is the path of the webhook server the delivery was sent to, which selects the WebhookAutoscalerConfig
+	// it's handled with. Defaults to "/".
+	Path string `json:"path,omitempty"`
+
+	// DeliveryID is the ID of the delivery to fetch from GitHub, as listed in the "Recent Deliveries" of the webhook.
+	// It requires HookID, and either Repository or Organization to tell where the webhook is.
+	DeliveryID int64 `json:"deliveryID,omitempty"`
+	// HookID is the ID of the webhook the delivery was sent by.
+	HookID int64 `json:"hookID,omitempty"`
+	// Repository is the owner/name of the repository of the webhook.
+	Repository string `json:"repository,omitempty"`
+	// Organization is the organization of the webhook.
+	Organization string `json:"organization,omitempty"`
+
+	// Event is the type of the event of the payload, like "workflow_job".
+	Event string `json:"event,omitempty"`
+	// Payload is the payload of the delivery to replay, like the one saved from the GitHub UI.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// WebhookReplayResponse is the body of a response of the WebhookReplayer.
+type WebhookReplayResponse struct {
+	// GUID is the X-GitHub-Delivery of the delivery fetched from GitHub.
+	GUID string `json:"guid,omitempty"`
+	// Event is the type of the event of the replayed delivery.
+	Event string `json:"event"`
+	// Message is what the webhook server would have responded to the delivery, like "scaled example-hra by 1".
+	Message string `json:"message,omitempty"`
+}
+
+// WebhookReplayer re-processes webhook deliveries, so that the deliveries missed by the webhook server can be recovered,
+// and the scale triggers can be tested with recorded payloads.
+//
+// Replayed deliveries are handled like the ones sent by GitHub, except that they are authenticated with Token instead
+// of the webhook secret, and that they are never ignored as duplicates.
+type WebhookReplayer struct {
+	Webhook *HorizontalRunnerAutoscalerGitHubWebhook
+
+	// Token is the bearer token the requests must be authenticated with.
+	// All the requests are rejected when it's empty.
+	Token string
+}
+
+func (r *WebhookReplayer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if r.Token == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(r.Token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var replay WebhookReplayRequest
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxReplayRequestSize)).Decode(&replay); err != nil {
+		http.Error(w, fmt.Sprintf("decoding replay request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	res, err := r.Webhook.replay(req.Context(), replay)
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		var invalid invalidWebhookDeliveryError
+		if errors.As(err, &invalid) {
+			status = http.StatusBadRequest
+		}
+
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		r.Webhook.Log.Error(err, "failed writing replay response")
+	}
+}
+
+// replay handles the delivery of the replay request like the webhook server handles the deliveries sent by GitHub.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) replay(ctx context.Context, replay WebhookReplayRequest) (*WebhookReplayResponse, error) {
+	path := replay.Path
+	if path == "" {
+		path = "/"
+	}
+
+	cfg := autoscaler.configForPath(path)
+	if cfg == nil {
+		return nil, invalidWebhookDeliveryError{fmt.Errorf("no webhook autoscaler config is served on path %q", path)}
+	}
+
+	res := &WebhookReplayResponse{Event: replay.Event}
+
+	payload := []byte(replay.Payload)
+
+	switch {
+	case replay.DeliveryID != 0:
+		delivery, err := getHookDelivery(ctx, cfg.githubClient, replay)
+		if err != nil {
+			return nil, err
+		}
+
+		res.GUID = delivery.GetGUID()
+		res.Event = delivery.GetEvent()
+
+		if delivery.Request == nil || delivery.Request.RawPayload == nil {
+			return nil, fmt.Errorf("delivery %d has no payload", replay.DeliveryID)
+		}
+		payload = *delivery.Request.RawPayload
+	case replay.Event == "" || len(payload) == 0:
+		return nil, invalidWebhookDeliveryError{errors.New("either deliveryID or event and payload are required")}
+	}
+
+	log := autoscaler.Log.WithValues(
+		"event", res.Event,
+		"delivery", res.GUID,
+		"replay", true,
+	)
+
+	if cfg.key != "" {
+		log = log.WithValues("webhookAutoscalerConfig", cfg.key)
+	}
+
+	// The replayed delivery is applied even when it was already applied, but a redelivery of it by GitHub is ignored afterwards
+	if dedupKey := cfg.dedup.key(res.GUID, res.Event, payload); dedupKey != "" {
+		cfg.dedup.claim(dedupKey, nowFrom(autoscaler.Clock))
+	}
+
+	log.Info("Replaying webhook delivery")
+
+	msg, err := autoscaler.handleEvent(ctx, log, cfg, res.Event, res.GUID, payload, nowFrom(autoscaler.Clock), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Message = msg
+
+	return res, nil
+}
+
+// getHookDelivery fetches the delivery of the replay request from the webhook of the repository or the organization.
+func getHookDelivery(ctx context.Context, client *github.Client, replay WebhookReplayRequest) (*gogithub.HookDelivery, error) {
+	if client == nil {
+		return nil, invalidWebhookDeliveryError{errors.New("replaying a delivery by its ID requires GitHub API credentials")}
+	}
+
+	if replay.HookID == 0 {
+		return nil, invalidWebhookDeliveryError{errors.New("hookID is required to replay a delivery by its ID")}
+	}
+
+	var (
+		delivery *gogithub.HookDelivery
+		err      error
+	)
+
+	switch {
+	case replay.Repository != "":
+		owner, repo, ok := strings.Cut(replay.Repository, "/")
+		if !ok {
+			return nil, invalidWebhookDeliveryError{fmt.Errorf("repository %q must be in the owner/name format", replay.Repository)}
+		}
+
+		delivery, _, err = client.Repositories.GetHookDelivery(ctx, owner, repo, replay.HookID, replay.DeliveryID)
+	case replay.Organization != "":
+		delivery, _, err = client.Organizations.GetHookDelivery(ctx, replay.Organization, replay.HookID, replay.DeliveryID)
+	default:
+		return nil, invalidWebhookDeliveryError{errors.New("either repository or organization is required to replay a delivery by its ID")}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("getting delivery %d of hook %d: %w", replay.DeliveryID, replay.HookID, err)
+	}
+
+	return delivery, nil
+}
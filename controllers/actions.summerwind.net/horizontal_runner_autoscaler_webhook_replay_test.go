@@ -0,0 +1,134 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWebhookReplayer(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/org_webhook_workflow_job_payload.json")
+	require.NoError(t, err)
+
+	// Serves the delivery of the fixture as delivery 2 of the hook 1 of the repository
+	githubServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/MYORG/MYREPO/hooks/1/deliveries/2":
+			fmt.Fprintf(w, `{"id":2,"guid":"0b989ba4-242f-11e5-81e1-c7b6966d2516","event":"workflow_job","request":{"headers":{},"payload":%s}}`, fixture)
+		case "/orgs/MYORG/actions/runner-groups":
+			fmt.Fprint(w, `{"total_count":1,"runner_groups":[{"id":1,"name":"Default","default":true,"visibility":"all"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer githubServer.Close()
+
+	githubClient, err := (&github.Config{URL: githubServer.URL + "/", Token: "token"}).NewClient()
+	require.NoError(t, err)
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{GitHubClient: githubClient}
+	logs := installTestLogger(webhook)
+	defer func() {
+		if t.Failed() {
+			t.Logf("diagnostics: %s", logs.String())
+		}
+	}()
+
+	webhook.Client = fake.NewClientBuilder().
+		WithScheme(sc).
+		WithObjects(
+			&actionsv1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-name"},
+				Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{Name: "test-name"},
+					ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+						{GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{}}},
+					},
+				},
+			},
+			&actionsv1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-name"},
+				Spec: actionsv1alpha1.RunnerDeploymentSpec{
+					Template: actionsv1alpha1.RunnerTemplate{
+						Spec: actionsv1alpha1.RunnerSpec{
+							RunnerConfig: actionsv1alpha1.RunnerConfig{Organization: "MYORG", Labels: []string{"label1"}},
+						},
+					},
+				},
+			},
+		).
+		WithIndex(&actionsv1alpha1.HorizontalRunnerAutoscaler{}, scaleTargetKey, webhook.indexer).
+		Build()
+
+	replayer := &WebhookReplayer{Webhook: webhook, Token: "replay-token"}
+
+	send := func(token string, req WebhookReplayRequest) (int, string) {
+		t.Helper()
+
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, ReplayPath, bytes.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		replayer.ServeHTTP(w, r)
+
+		return w.Code, w.Body.String()
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		code, _ := send("", WebhookReplayRequest{Event: "workflow_job", Payload: fixture})
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		code, _ = send("wrong", WebhookReplayRequest{Event: "workflow_job", Payload: fixture})
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("payload", func(t *testing.T) {
+		code, body := send("replay-token", WebhookReplayRequest{Event: "workflow_job", Payload: fixture})
+		require.Equal(t, http.StatusOK, code, body)
+
+		var res WebhookReplayResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &res))
+		assert.Equal(t, WebhookReplayResponse{Event: "workflow_job", Message: "scaled test-name by 1"}, res)
+	})
+
+	t.Run("delivery ID", func(t *testing.T) {
+		code, body := send("replay-token", WebhookReplayRequest{DeliveryID: 2, HookID: 1, Repository: "MYORG/MYREPO"})
+		require.Equal(t, http.StatusOK, code, body)
+
+		var res WebhookReplayResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &res))
+		assert.Equal(t, WebhookReplayResponse{GUID: "0b989ba4-242f-11e5-81e1-c7b6966d2516", Event: "workflow_job", Message: "scaled test-name by 1"}, res)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		code, body := send("replay-token", WebhookReplayRequest{DeliveryID: 2, Repository: "MYORG/MYREPO"})
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, body, "hookID is required")
+
+		code, body = send("replay-token", WebhookReplayRequest{Payload: fixture})
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, body, "either deliveryID or event and payload are required")
+
+		code, _ = send("replay-token", WebhookReplayRequest{Event: "unknown", Payload: fixture})
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		code, _ = send("replay-token", WebhookReplayRequest{DeliveryID: 3, HookID: 1, Repository: "MYORG/MYREPO"})
+		assert.Equal(t, http.StatusInternalServerError, code, "the delivery isn't found on GitHub")
+	})
+}
@@ -0,0 +1,145 @@
+package actionssummerwindnet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultWebhookMaxPayloadBytes is the default maximum size of a webhook delivery, which is the size GitHub caps the payloads at.
+	DefaultWebhookMaxPayloadBytes = 25 << 20
+
+	// DefaultWebhookReadHeaderTimeout, DefaultWebhookReadTimeout, DefaultWebhookWriteTimeout and DefaultWebhookIdleTimeout
+	// are the default timeouts of the webhook HTTP server, so that a slow sender can't hold its connections forever.
+	DefaultWebhookReadHeaderTimeout = 10 * time.Second
+	DefaultWebhookReadTimeout       = 30 * time.Second
+	DefaultWebhookWriteTimeout      = 30 * time.Second
+	DefaultWebhookIdleTimeout       = 2 * time.Minute
+
+	// webhookRateLimiterIdleTimeout is the duration after which the rate limiter of a source that sent no delivery is forgotten.
+	webhookRateLimiterIdleTimeout = 10 * time.Minute
+)
+
+// WebhookRateLimiter limits the rate of the webhook deliveries per source IP address with a token bucket,
+// so that a misbehaving sender can't starve the autoscaler of the deliveries of the others.
+type WebhookRateLimiter struct {
+	// Rate is the number of deliveries per second allowed from a source on average.
+	Rate rate.Limit
+	// Burst is the number of deliveries a source can send at once.
+	Burst int
+	// TrustedProxies are the ranges of the load balancers and the reverse proxies in front of the webhook server.
+	// The source of a request from a trusted proxy is the rightmost untrusted address of its X-Forwarded-For header.
+	TrustedProxies []netip.Prefix
+
+	mu        sync.Mutex
+	limiters  map[netip.Addr]*sourceRateLimiter
+	lastSweep time.Time
+}
+
+type sourceRateLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewWebhookRateLimiter returns a rate limiter allowing perSecond deliveries per source on average and burst ones at once,
+// which trusts the X-Forwarded-For header of the requests from the trustedProxies.
+func NewWebhookRateLimiter(perSecond float64, burst int, trustedProxies []string) (*WebhookRateLimiter, error) {
+	if perSecond <= 0 {
+		return nil, fmt.Errorf("the rate limit of webhook deliveries must be greater than 0, but was %v", perSecond)
+	}
+
+	if burst <= 0 {
+		burst = int(math.Ceil(perSecond))
+	}
+
+	proxies, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookRateLimiter{
+		Rate:           rate.Limit(perSecond),
+		Burst:          burst,
+		TrustedProxies: proxies,
+	}, nil
+}
+
+// Allow returns the source address of the request, and whether a delivery from the source is allowed now.
+// When it's not allowed, it also returns the duration after which the source can retry.
+func (l *WebhookRateLimiter) Allow(r *http.Request) (netip.Addr, time.Duration, bool) {
+	addr, ok := requestSourceAddr(r, l.TrustedProxies)
+	if !ok {
+		// The requests of unknown sources share a single bucket
+		addr = netip.Addr{}
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limiters == nil {
+		l.limiters = map[netip.Addr]*sourceRateLimiter{}
+	}
+
+	if now.Sub(l.lastSweep) > webhookRateLimiterIdleTimeout {
+		for a, s := range l.limiters {
+			if now.Sub(s.lastSeen) > webhookRateLimiterIdleTimeout {
+				delete(l.limiters, a)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	s, ok := l.limiters[addr]
+	if !ok {
+		s = &sourceRateLimiter{limiter: rate.NewLimiter(l.Rate, l.Burst)}
+		l.limiters[addr] = s
+	}
+	s.lastSeen = now
+
+	if s.limiter.AllowN(now, 1) {
+		return addr, 0, true
+	}
+
+	res := s.limiter.ReserveN(now, 1)
+	retryAfter := res.DelayFrom(now)
+	res.CancelAt(now)
+
+	return addr, retryAfter, false
+}
+
+// setRetryAfter sets the Retry-After header in seconds, rounded up so that the sender doesn't retry too early.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	secs := int(math.Ceil(d.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+}
+
+// webhookRequestBodyErrorReason returns the drop reason of the error of reading the body of a delivery,
+// or an empty string when the error isn't due to the request limits.
+func webhookRequestBodyErrorReason(err error) string {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return webhookDropReasonPayloadTooLarge
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return webhookDropReasonTimeout
+	}
+
+	return ""
+}
@@ -0,0 +1,97 @@
+package actionssummerwindnet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRateLimiter(t *testing.T) {
+	limiter, err := NewWebhookRateLimiter(0.01, 2, []string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{RateLimiter: limiter}
+	installTestLogger(webhook)
+
+	handle := func(remoteAddr string, forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"zen":"Keep it logically awesome."}`))
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-GitHub-Event", "ping")
+
+		w := httptest.NewRecorder()
+		webhook.Handle(w, r)
+
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		w := handle("192.30.252.1:12345", "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w := handle("192.30.252.1:12345", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "100", w.Header().Get("Retry-After"))
+
+	w = handle("192.30.252.2:12345", "")
+	assert.Equal(t, http.StatusOK, w.Code, "the other sources aren't limited")
+
+	// The deliveries through a trusted proxy are limited per their source
+	for i := 0; i < 2; i++ {
+		w = handle("10.0.0.1:12345", "192.30.252.3")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = handle("10.0.0.1:12345", "192.30.252.3")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	w = handle("10.0.0.1:12345", "192.30.252.4")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestNewWebhookRateLimiter(t *testing.T) {
+	_, err := NewWebhookRateLimiter(0, 0, nil)
+	assert.Error(t, err)
+
+	limiter, err := NewWebhookRateLimiter(2.5, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, limiter.Burst, "the burst defaults to the rate rounded up")
+
+	_, err = NewWebhookRateLimiter(1, 1, []string{"not-a-cidr"})
+	assert.Error(t, err)
+}
+
+func TestWebhookMaxPayloadBytes(t *testing.T) {
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{MaxPayloadBytes: 64}
+	installTestLogger(webhook)
+
+	handle := func(payload string, chunked bool) int {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+		if chunked {
+			r.ContentLength = -1
+		}
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-GitHub-Event", "ping")
+
+		w := httptest.NewRecorder()
+		webhook.Handle(w, r)
+
+		return w.Code
+	}
+
+	small := `{"zen":"Keep it logically awesome."}`
+	large := `{"zen":"` + strings.Repeat("a", 64) + `"}`
+
+	assert.Equal(t, http.StatusOK, handle(small, false))
+	assert.Equal(t, http.StatusOK, handle(small, true))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, handle(large, false))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, handle(large, true), "the payload without Content-Length is cut off while it's read")
+}
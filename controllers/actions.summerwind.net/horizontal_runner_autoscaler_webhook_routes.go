@@ -0,0 +1,203 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/actionsglob"
+)
+
+// webhookRoute is a route of a WebhookAutoscalerConfig.
+type webhookRoute struct {
+	// index is the index of the route in the config, used to tell which route an event was routed by.
+	index int
+
+	repositories  []string
+	labelPatterns []string
+	runnerGroup   string
+
+	// selector is the selector of the route combined with the selector of the config.
+	selector labels.Selector
+}
+
+// newWebhookRoute validates the route, and combines its selector with the selector of the config, which is optional.
+func newWebhookRoute(index int, route v1alpha1.WebhookRoute, configSelector labels.Selector) (*webhookRoute, error) {
+	if route.HorizontalRunnerAutoscalerSelector == nil {
+		return nil, fmt.Errorf("routes[%d]: horizontalRunnerAutoscalerSelector must be set", index)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(route.HorizontalRunnerAutoscalerSelector)
+	if err != nil {
+		return nil, fmt.Errorf("routes[%d]: invalid horizontalRunnerAutoscalerSelector: %w", index, err)
+	}
+
+	if configSelector != nil {
+		requirements, _ := configSelector.Requirements()
+		selector = selector.Add(requirements...)
+	}
+
+	for _, p := range append(append([]string{}, route.Repositories...), route.Labels...) {
+		if p == "" {
+			return nil, fmt.Errorf("routes[%d]: repositories and labels must not include empty patterns", index)
+		}
+	}
+
+	r := &webhookRoute{
+		index:        index,
+		repositories: route.Repositories,
+		runnerGroup:  route.RunnerGroup,
+		selector:     selector,
+	}
+
+	for _, l := range route.Labels {
+		r.labelPatterns = append(r.labelPatterns, strings.ToLower(l))
+	}
+
+	return r, nil
+}
+
+// matches returns true when the repository, the full name of the repository of the job, and the labels of the job
+// match the route.
+func (r *webhookRoute) matches(repository string, jobLabels []string) bool {
+	if len(r.repositories) > 0 && !matchAnyGlob(r.repositories, repository) {
+		return false
+	}
+
+	for _, p := range r.labelPatterns {
+		matched := false
+		for _, l := range jobLabels {
+			if actionsglob.Match(p, strings.ToLower(l)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// route returns the first route of the config that matches the job, or nil.
+func (c *webhookConfig) route(repository string, jobLabels []string) *webhookRoute {
+	for _, r := range c.routes {
+		if r.matches(repository, jobLabels) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// getRoutedJobScaleTarget returns the scale target of the workflow_job event routed by the routes of the config.
+// It returns false when no route matches the job, in which case the scale target is looked up by the repository,
+// the organization or the enterprise of the job instead.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getRoutedJobScaleTarget(ctx context.Context, log logr.Logger, cfg *webhookConfig, repository string, jobLabels []string, workflow *jobWorkflow) (*ScaleTarget, bool, error) {
+	route := cfg.route(repository, jobLabels)
+	if route == nil {
+		return nil, false, nil
+	}
+
+	log = log.WithValues("route", route.index)
+
+	opts := []client.ListOption{client.MatchingLabelsSelector{Selector: route.selector}}
+	if cfg.namespace != "" {
+		opts = append(opts, client.InNamespace(cfg.namespace))
+	}
+
+	var hraList v1alpha1.HorizontalRunnerAutoscalerList
+	if err := autoscaler.List(ctx, &hraList, opts...); err != nil {
+		return nil, true, err
+	}
+
+	hras := hraList.Items
+	sort.Slice(hras, func(i, j int) bool {
+		return hras[i].Name < hras[j].Name
+	})
+
+	for i := range hras {
+		hra := &hras[i]
+
+		if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		trigger, err := autoscaler.workflowJobScaleUpTrigger(ctx, log, cfg, hra, workflow)
+		if err != nil {
+			return nil, true, err
+		} else if trigger == nil {
+			continue
+		}
+
+		if route.runnerGroup != "" {
+			group, err := autoscaler.scaleTargetRunnerGroup(ctx, hra)
+			if err != nil {
+				return nil, true, err
+			}
+
+			if !strings.EqualFold(group, route.runnerGroup) {
+				log.V(1).Info("Skipping this HRA as its runners aren't registered to the runner group of the route", "hra", hra.Name, "group", group)
+
+				continue
+			}
+		}
+
+		if allowed, err := autoscaler.scaleTargetAllowsRepository(ctx, hra, repository); err != nil {
+			return nil, true, err
+		} else if !allowed {
+			log.V(1).Info("Skipping this HRA as the repository isn't one of the allowed repositories of its runners", "hra", hra.Name)
+
+			continue
+		}
+
+		log.Info("job scale up target is routed", "hra", hra.Name)
+
+		return &ScaleTarget{HorizontalRunnerAutoscaler: *hra, ScaleUpTrigger: *trigger}, true, nil
+	}
+
+	log.V(1).Info("No HRA selected by the route scales on the job")
+
+	return nil, true, nil
+}
+
+// scaleTargetRunnerGroup returns the runner group the runners of the scale target of the HRA are registered to.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) scaleTargetRunnerGroup(ctx context.Context, hra *v1alpha1.HorizontalRunnerAutoscaler) (string, error) {
+	rc, err := autoscaler.scaleTargetRunnerConfig(ctx, hra)
+	if err != nil {
+		return "", err
+	}
+
+	return rc.Group, nil
+}
+
+// scaleTargetRunnerConfig returns the runner config of the scale target of the HRA.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) scaleTargetRunnerConfig(ctx context.Context, hra *v1alpha1.HorizontalRunnerAutoscaler) (*v1alpha1.RunnerConfig, error) {
+	key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.ScaleTargetRef.Name}
+
+	switch kind := hra.Spec.ScaleTargetRef.Kind; kind {
+	case "RunnerSet":
+		var rs v1alpha1.RunnerSet
+		if err := autoscaler.Client.Get(ctx, key, &rs); err != nil {
+			return nil, err
+		}
+		return &rs.Spec.RunnerConfig, nil
+	case "RunnerDeployment", "":
+		var rd v1alpha1.RunnerDeployment
+		if err := autoscaler.Client.Get(ctx, key, &rd); err != nil {
+			return nil, err
+		}
+		return &rd.Spec.Template.Spec.RunnerConfig, nil
+	default:
+		return nil, fmt.Errorf("unsupported scaleTargetRef.kind: %v", kind)
+	}
+}
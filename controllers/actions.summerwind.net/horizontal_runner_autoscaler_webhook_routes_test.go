@@ -0,0 +1,138 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWebhookRoute_Matches(t *testing.T) {
+	route, err := newWebhookRoute(0, actionsv1alpha1.WebhookRoute{
+		Repositories:                       []string{"my-org/frontend-*", "my-org/web"},
+		Labels:                             []string{"GPU-*", "linux"},
+		HorizontalRunnerAutoscalerSelector: &metav1.LabelSelector{},
+	}, nil)
+	require.NoError(t, err)
+
+	assert.True(t, route.matches("my-org/frontend-app", []string{"self-hosted", "Linux", "gpu-a100"}))
+	assert.True(t, route.matches("my-org/web", []string{"linux", "gpu-t4"}))
+	assert.False(t, route.matches("my-org/backend", []string{"linux", "gpu-t4"}), "the repository doesn't match")
+	assert.False(t, route.matches("my-org/web", []string{"linux"}), "a label pattern matches no label")
+
+	_, err = newWebhookRoute(1, actionsv1alpha1.WebhookRoute{}, nil)
+	assert.ErrorContains(t, err, "routes[1]")
+
+	_, err = newWebhookRoute(2, actionsv1alpha1.WebhookRoute{Labels: []string{""}, HorizontalRunnerAutoscalerSelector: &metav1.LabelSelector{}}, nil)
+	assert.ErrorContains(t, err, "routes[2]")
+}
+
+func TestWebhookWithRoutes(t *testing.T) {
+	ctx := context.Background()
+
+	hra := func(name string, labels map[string]string) client.Object {
+		return &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ci", Labels: labels},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{Name: name},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{}}},
+				},
+			},
+		}
+	}
+
+	// The runners are registered to another organization with other labels,
+	// so that the events would scale none of them without the routes.
+	rd := func(name, group string) client.Object {
+		return &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ci"},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{Organization: "OTHER", Group: group, Labels: []string{"other"}},
+					},
+				},
+			},
+		}
+	}
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{}
+	logs := installTestLogger(webhook)
+	defer func() {
+		if t.Failed() {
+			t.Logf("diagnostics: %s", logs.String())
+		}
+	}()
+
+	webhook.Client = fake.NewClientBuilder().
+		WithScheme(sc).
+		WithObjects(
+			// Sorts before the other HRAs of the pool, but isn't selected by the config
+			hra("a-unselected", map[string]string{"pool": "gpu", "unselected": "true"}), rd("a-unselected", "group-b"),
+			hra("gpu-a", map[string]string{"pool": "gpu"}), rd("gpu-a", "group-a"),
+			hra("gpu-b", map[string]string{"pool": "gpu"}), rd("gpu-b", "group-b"),
+			hra("default", map[string]string{"pool": "default"}), rd("default", ""),
+		).
+		WithIndex(&actionsv1alpha1.HorizontalRunnerAutoscaler{}, scaleTargetKey, webhook.indexer).
+		Build()
+
+	configSelector, err := labels.Parse("!unselected")
+	require.NoError(t, err)
+
+	cfg := &webhookConfig{key: "ci/webhook", namespace: "ci", selector: configSelector}
+	for i, route := range []actionsv1alpha1.WebhookRoute{
+		{
+			Repositories:                       []string{"MYORG/ml-*"},
+			Labels:                             []string{"gpu"},
+			RunnerGroup:                        "group-b",
+			HorizontalRunnerAutoscalerSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+		},
+		{
+			Labels:                             []string{"gpu"},
+			HorizontalRunnerAutoscalerSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+		},
+		{
+			Repositories:                       []string{"MYORG/*"},
+			HorizontalRunnerAutoscalerSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "default"}},
+		},
+	} {
+		r, err := newWebhookRoute(i, route, cfg.selector)
+		require.NoError(t, err)
+		cfg.routes = append(cfg.routes, r)
+	}
+
+	fixture, err := os.ReadFile("testdata/org_webhook_workflow_job_payload.json")
+	require.NoError(t, err)
+
+	send := func(repository string, jobLabels ...string) string {
+		t.Helper()
+
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal(fixture, &e))
+		e["repository"].(map[string]interface{})["full_name"] = repository
+		e["workflow_job"].(map[string]interface{})["labels"] = jobLabels
+
+		payload, err := json.Marshal(e)
+		require.NoError(t, err)
+
+		msg, err := webhook.handleEvent(ctx, webhook.Log, cfg, "workflow_job", "", payload, time.Now(), nil)
+		require.NoError(t, err)
+
+		return msg
+	}
+
+	assert.Equal(t, "scaled gpu-b by 1", send("MYORG/ml-training", "self-hosted", "GPU"), "the first route restricts the HRAs to the runner group")
+	assert.Equal(t, "scaled gpu-a by 1", send("MYORG/web", "self-hosted", "gpu"), "the second route selects the first HRA by name")
+	assert.Equal(t, "scaled default by 1", send("MYORG/web", "self-hosted", "linux"))
+	assert.Equal(t, "no horizontalrunnerautoscaler to scale for this github event", send("OTHERORG/web", "self-hosted", "linux"), "no route matches")
+}
@@ -0,0 +1,135 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"slices"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+const (
+	// githubActionsAppSlug is the slug of the GitHub App that the check suites of GitHub Actions workflows belong to.
+	githubActionsAppSlug = "github-actions"
+)
+
+var (
+	defaultCheckSuiteTypes  = []string{"requested", "rerequested"}
+	defaultWorkflowRunTypes = []string{"requested"}
+)
+
+// runEvent is a check_suite or workflow_run event. Unlike workflow_job events, they don't include the labels of the jobs,
+// so they scale the HRAs of the repository, the organization or the enterprise regardless of the labels of their runners.
+type runEvent struct {
+	// id is the ID of the check suite or the workflow run, which the capacity reservations added for it are attributed to,
+	// so that they are removed once it's completed.
+	id     int64
+	action string
+	branch string
+}
+
+// getRunEventScaleUpTarget returns the scale target of the check_suite or workflow_run event, in the same order of precedence
+// as the scale targets of workflow_job events.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getRunEventScaleUpTarget(
+	ctx context.Context, log logr.Logger, cfg *webhookConfig, webhookType string, repo *gogithub.Repository, enterprise string, e runEvent,
+) (*ScaleTarget, error) {
+	scaleTarget := func(value string) (*ScaleTarget, error) {
+		return autoscaler.getRunEventScaleTarget(ctx, cfg, value, webhookType, e)
+	}
+
+	target, err := autoscaler.getScaleUpTargetWithFunction(ctx, log, cfg, repo.GetName(), repo.GetOwner().GetLogin(), repo.GetOwner().GetType(), enterprise, scaleTarget)
+	if target == nil || err != nil {
+		return nil, err
+	}
+
+	target.Repository = repo.GetFullName()
+	target.JobID = e.id
+
+	return target, nil
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getRunEventScaleTarget(ctx context.Context, cfg *webhookConfig, name, webhookType string, e runEvent) (*ScaleTarget, error) {
+	hras, err := autoscaler.findHRAsByKey(ctx, cfg, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hra := range hras {
+		if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if len(hra.Spec.ScaleUpTriggers) != 1 || hra.Spec.ScaleUpTriggers[0].GitHubEvent == nil {
+			continue
+		}
+
+		scaleUpTrigger := hra.Spec.ScaleUpTriggers[0]
+
+		types, branches, ok := runEventFilters(scaleUpTrigger.GitHubEvent, webhookType)
+		if !ok {
+			continue
+		}
+
+		if len(branches) > 0 && !matchAnyGlob(branches, e.branch) {
+			autoscaler.Log.V(1).Info("Skipping this HRA as the head branch doesn't match its branches", "hra", hra.Name, "event", webhookType, "branch", e.branch)
+
+			continue
+		}
+
+		target := &ScaleTarget{
+			HorizontalRunnerAutoscaler: hra,
+			ScaleUpTrigger:             v1alpha1.ScaleUpTrigger{AmountExpression: scaleUpTrigger.AmountExpression, Duration: scaleUpTriggerDuration(cfg, scaleUpTrigger)},
+		}
+
+		switch {
+		case slices.Contains(types, e.action):
+			target.Amount = 1
+		case e.action == "completed":
+			target.Amount = -1
+		case e.action == "in_progress":
+			// A started workflow run holds the reservations added when it was requested until it's completed
+			target.Renew = true
+		default:
+			autoscaler.Log.V(1).Info("Skipping this HRA as the action isn't one of its types", "hra", hra.Name, "event", webhookType, "action", e.action)
+
+			continue
+		}
+
+		return target, nil
+	}
+
+	return nil, nil
+}
+
+// runEventFilters returns the types, defaulted, and the branches of the scale up trigger for the event type.
+// It returns false when the trigger doesn't scale on the event type.
+func runEventFilters(spec *v1alpha1.GitHubEventScaleUpTriggerSpec, webhookType string) ([]string, []string, bool) {
+	switch webhookType {
+	case "check_suite":
+		if spec.CheckSuite == nil {
+			return nil, nil, false
+		}
+
+		types := spec.CheckSuite.Types
+		if len(types) == 0 {
+			types = defaultCheckSuiteTypes
+		}
+
+		return types, spec.CheckSuite.Branches, true
+	case "workflow_run":
+		if spec.WorkflowRun == nil {
+			return nil, nil, false
+		}
+
+		types := spec.WorkflowRun.Types
+		if len(types) == 0 {
+			types = defaultWorkflowRunTypes
+		}
+
+		return types, spec.WorkflowRun.Branches, true
+	}
+
+	return nil, nil, false
+}
@@ -0,0 +1,165 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-github/v52/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestWebhookCheckSuiteAndWorkflowRun(t *testing.T) {
+	newObjs := func(spec actionsv1alpha1.GitHubEventScaleUpTriggerSpec) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &spec,
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+
+	repo := &github.Repository{
+		Name:     github.String("myrepo"),
+		FullName: github.String("MYORG/myrepo"),
+		Owner: &github.User{
+			Login: github.String("MYORG"),
+			Type:  github.String("Organization"),
+		},
+	}
+
+	checkSuite := func(action, app, branch string) *github.CheckSuiteEvent {
+		return &github.CheckSuiteEvent{
+			Action: github.String(action),
+			CheckSuite: &github.CheckSuite{
+				ID:         github.Int64(1),
+				HeadBranch: github.String(branch),
+				App:        &github.App{Slug: github.String(app)},
+			},
+			Repo: repo,
+		}
+	}
+
+	workflowRun := func(action, branch string) *github.WorkflowRunEvent {
+		return &github.WorkflowRunEvent{
+			Action: github.String(action),
+			WorkflowRun: &github.WorkflowRun{
+				ID:         github.Int64(2),
+				HeadBranch: github.String(branch),
+			},
+			Repo: repo,
+		}
+	}
+
+	noTarget := "no horizontalrunnerautoscaler to scale for this github event"
+
+	tests := []struct {
+		name      string
+		spec      actionsv1alpha1.GitHubEventScaleUpTriggerSpec
+		eventType string
+		event     interface{}
+		want      string
+	}{
+		{
+			name:      "check suite requested",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{CheckSuite: &actionsv1alpha1.CheckSuiteSpec{}},
+			eventType: "check_suite",
+			event:     checkSuite("requested", "github-actions", "main"),
+			want:      "scaled test-name by 1",
+		},
+		{
+			name:      "check suite completed",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{CheckSuite: &actionsv1alpha1.CheckSuiteSpec{Types: []string{"requested"}}},
+			eventType: "check_suite",
+			event:     checkSuite("completed", "github-actions", "main"),
+			want:      "scaled test-name by -1",
+		},
+		{
+			name:      "check suite of type not in types",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{CheckSuite: &actionsv1alpha1.CheckSuiteSpec{Types: []string{"requested"}}},
+			eventType: "check_suite",
+			event:     checkSuite("rerequested", "github-actions", "main"),
+			want:      noTarget,
+		},
+		{
+			name:      "check suite of another app",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{CheckSuite: &actionsv1alpha1.CheckSuiteSpec{}},
+			eventType: "check_suite",
+			event:     checkSuite("requested", "other-ci", "main"),
+		},
+		{
+			name:      "check suite without trigger",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{}},
+			eventType: "check_suite",
+			event:     checkSuite("requested", "github-actions", "main"),
+			want:      noTarget,
+		},
+		{
+			name:      "workflow run requested on matching branch",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowRun: &actionsv1alpha1.WorkflowRunSpec{Branches: []string{"release/*"}}},
+			eventType: "workflow_run",
+			event:     workflowRun("requested", "release/v1"),
+			want:      "scaled test-name by 1",
+		},
+		{
+			name:      "workflow run requested on other branch",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowRun: &actionsv1alpha1.WorkflowRunSpec{Branches: []string{"release/*"}}},
+			eventType: "workflow_run",
+			event:     workflowRun("requested", "main"),
+			want:      noTarget,
+		},
+		{
+			name:      "workflow run in progress",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowRun: &actionsv1alpha1.WorkflowRunSpec{}},
+			eventType: "workflow_run",
+			event:     workflowRun("in_progress", "main"),
+			want:      "held capacity reservations of job 2 for test-name",
+		},
+		{
+			name:      "workflow run in progress in types",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowRun: &actionsv1alpha1.WorkflowRunSpec{Types: []string{"in_progress"}}},
+			eventType: "workflow_run",
+			event:     workflowRun("in_progress", "main"),
+			want:      "scaled test-name by 1",
+		},
+		{
+			name:      "workflow run completed",
+			spec:      actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowRun: &actionsv1alpha1.WorkflowRunSpec{}},
+			eventType: "workflow_run",
+			event:     workflowRun("completed", "main"),
+			want:      "scaled test-name by -1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testServerWithInitObjs(t, tt.eventType, tt.event, 200, tt.want, newObjs(tt.spec))
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// GitHubWebhookServer serves the GitHub webhook deliveries of the webhook-based autoscaler as a runnable of a manager.
+//
+// It's what the github-webhook-server runs, and it can be added to the controller manager instead,
+// so that small installations run the webhook-based autoscaler without a separate deployment,
+// sharing the cache of the controller manager and patching HRAs with no cross-deployment latency.
+//
+// The server only listens while the manager is the leader, so that a single replica updates the capacity reservations
+// of the HorizontalRunnerAutoscalers like a single replica of the github-webhook-server does.
+type GitHubWebhookServer struct {
+	// Addr is the address the server listens on, like ":8000".
+	Addr string
+
+	Webhook *HorizontalRunnerAutoscalerGitHubWebhook
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// listener is optional. It's used instead of listening on Addr in tests.
+	listener net.Listener
+}
+
+var _ manager.LeaderElectionRunnable = &GitHubWebhookServer{}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (s *GitHubWebhookServer) NeedLeaderElection() bool {
+	return true
+}
+
+// Start serves the webhook deliveries until the context is done.
+func (s *GitHubWebhookServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.Webhook.Handle)
+
+	srv := &http.Server{
+		Addr:              s.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		ReadTimeout:       s.ReadTimeout,
+		WriteTimeout:      s.WriteTimeout,
+		IdleTimeout:       s.IdleTimeout,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		<-ctx.Done()
+
+		srv.Shutdown(context.Background())
+	}()
+
+	s.Webhook.Log.Info("Starting GitHub webhook server", "addr", s.Addr)
+
+	var err error
+	if s.listener != nil {
+		err = srv.Serve(s.listener)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	<-done
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubWebhookServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &GitHubWebhookServer{
+		Webhook:  &HorizontalRunnerAutoscalerGitHubWebhook{Log: logr.Discard()},
+		listener: l,
+	}
+
+	require.True(t, s.NeedLeaderElection())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Start(ctx)
+	}()
+
+	resp, err := sendWebhookTo("http://"+l.Addr().String(), "ping", &github.PingEvent{Zen: github.String("zen")})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "pong", string(body))
+
+	// The server stops gracefully with the manager
+	cancel()
+	require.NoError(t, <-errs)
+}
@@ -0,0 +1,85 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	gogithub "github.com/google/go-github/v52/github"
+)
+
+const (
+	// webhookSecretCurrent and webhookSecretNext tell which of the two secret tokens validated a delivery.
+	webhookSecretCurrent = "current"
+	webhookSecretNext    = "next"
+	// webhookSecretNone means that no secret token is configured and the delivery isn't validated.
+	webhookSecretNone = "none"
+	// webhookSecretInvalid means that the delivery was rejected.
+	webhookSecretInvalid = "invalid"
+)
+
+// validateWebhookPayload reads the payload of the delivery and validates its signature with the current secret token,
+// and then with the next one. It returns which of them validated the payload.
+//
+// Accepting both lets the secret token of the GitHub webhook be rotated without dropping deliveries:
+// the new token is configured as the next one, the GitHub webhook is updated, and then the new token becomes the current one.
+func validateWebhookPayload(r *http.Request, current, next []byte) ([]byte, string, error) {
+	if len(current) == 0 && len(next) == 0 {
+		payload, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, webhookSecretInvalid, fmt.Errorf("reading request body: %w", err)
+		}
+
+		return payload, webhookSecretNone, nil
+	}
+
+	if len(next) == 0 {
+		payload, err := gogithub.ValidatePayload(r, current)
+		if err != nil {
+			return nil, webhookSecretInvalid, err
+		}
+
+		return payload, webhookSecretCurrent, nil
+	}
+
+	signature := r.Header.Get(gogithub.SHA256SignatureHeader)
+	if signature == "" {
+		signature = r.Header.Get(gogithub.SHA1SignatureHeader)
+	}
+
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, webhookSecretInvalid, err
+	}
+
+	// The body is read once and validated against each of the secret tokens
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, webhookSecretInvalid, fmt.Errorf("reading request body: %w", err)
+	}
+
+	secrets := []struct {
+		name  string
+		token []byte
+	}{
+		{webhookSecretCurrent, current},
+		{webhookSecretNext, next},
+	}
+
+	for _, s := range secrets {
+		if len(s.token) == 0 {
+			continue
+		}
+
+		payload, verr := gogithub.ValidatePayloadFromBody(contentType, bytes.NewReader(body), signature, s.token)
+		if verr == nil {
+			return payload, s.name, nil
+		}
+
+		err = verr
+	}
+
+	return nil, webhookSecretInvalid, err
+}
@@ -0,0 +1,65 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWebhookPayload(t *testing.T) {
+	payload := []byte(`{"zen": "zen"}`)
+
+	newRequest := func(t *testing.T, token string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		if token != "" {
+			mac := hmac.New(sha256.New, []byte(token))
+			mac.Write(payload)
+			req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+
+		return req
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		current, next string
+		want          string
+		wantErr       bool
+	}{
+		{name: "no secret", want: webhookSecretNone},
+		{name: "current", token: "current", current: "current", want: webhookSecretCurrent},
+		{name: "current with next", token: "current", current: "current", next: "next", want: webhookSecretCurrent},
+		{name: "next", token: "next", current: "current", next: "next", want: webhookSecretNext},
+		{name: "next only", token: "next", next: "next", want: webhookSecretNext},
+		{name: "other", token: "other", current: "current", next: "next", want: webhookSecretInvalid, wantErr: true},
+		{name: "unsigned", current: "current", next: "next", want: webhookSecretInvalid, wantErr: true},
+		{name: "next without rotation", token: "next", current: "current", want: webhookSecretInvalid, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, secret, err := validateWebhookPayload(newRequest(t, tt.token), []byte(tt.current), []byte(tt.next))
+			assert.Equal(t, tt.want, secret)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, payload, got)
+		})
+	}
+}
@@ -101,6 +101,105 @@ func TestWebhookWorkflowJob(t *testing.T) {
 			initObjs,
 		)
 	})
+	t.Run("AmountExpression", func(t *testing.T) {
+		newObjs := func(expr string) []runtime.Object {
+			hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-name",
+				},
+				Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+						Name: "test-name",
+					},
+					ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+						{
+							GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+								WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+							},
+							AmountExpression: expr,
+						},
+					},
+				},
+			}
+
+			rd := &actionsv1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-name",
+				},
+				Spec: actionsv1alpha1.RunnerDeploymentSpec{
+					Template: actionsv1alpha1.RunnerTemplate{
+						Spec: actionsv1alpha1.RunnerSpec{
+							RunnerConfig: actionsv1alpha1.RunnerConfig{
+								Organization: "MYORG",
+								Labels:       []string{"label1"},
+							},
+						},
+					},
+				},
+			}
+
+			return []runtime.Object{hra, rd}
+		}
+
+		e := setupTest()
+		testServerWithInitObjs(t, "workflow_job", &e, 200, "scaled test-name by 3", newObjs("size(workflow_job.labels) + 2"))
+
+		e = setupTest()
+		testServerWithInitObjs(t, "workflow_job", &e, 200, "", newObjs("workflow_job.labels.exists(l, l == 'xlarge')"))
+
+		e = setupTest()
+		e.Action = github.String("completed")
+		e.WorkflowJob.Conclusion = github.String("failure")
+		e.WorkflowJob.Labels = nil
+		testServerWithInitObjs(t, "workflow_job", &e, 200, fmt.Sprintf("released capacity reservations of job %d for test-name", e.WorkflowJob.GetID()), newObjs("size(workflow_job.labels) + 2"))
+	})
+	t.Run("InProgress", func(t *testing.T) {
+		e := setupTest()
+		e.Action = github.String("in_progress")
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+						},
+						// The amount of a started job is never evaluated
+						AmountExpression: "size(workflow_job.labels) + 2",
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+							Labels:       []string{"label1"},
+						},
+					},
+				},
+			},
+		}
+
+		testServerWithInitObjs(t,
+			"workflow_job",
+			&e,
+			200,
+			"held capacity reservations of job 1234567890 for test-name",
+			[]runtime.Object{hra, rd},
+		)
+	})
 	t.Run("WrongLabels", func(t *testing.T) {
 		e := setupTest()
 		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
@@ -374,6 +473,40 @@ func TestGetRequest(t *testing.T) {
 	}
 }
 
+func TestScaleUpTriggerAmountProgram(t *testing.T) {
+	autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{}
+
+	hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-name", Generation: 1},
+	}
+
+	p1, err := autoscaler.scaleUpTriggerAmountProgram(hra, "size(workflow_job.labels)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p2, err := autoscaler.scaleUpTriggerAmountProgram(hra, "size(workflow_job.labels)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1 != p2 {
+		t.Errorf("expected the expression to be compiled once per generation of the HRA")
+	}
+
+	hra.Generation = 2
+	p3, err := autoscaler.scaleUpTriggerAmountProgram(hra, "size(workflow_job.labels)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p3 == p1 {
+		t.Errorf("expected the expression to be compiled again for a new generation of the HRA")
+	}
+
+	if _, err := autoscaler.scaleUpTriggerAmountProgram(hra, "size("); err == nil {
+		t.Errorf("expected an invalid expression to fail to compile")
+	}
+}
+
 func TestGetValidCapacityReservations(t *testing.T) {
 	now := time.Now()
 	duration, _ := time.ParseDuration("10m")
@@ -401,7 +534,7 @@ func TestGetValidCapacityReservations(t *testing.T) {
 		},
 	}
 
-	revs := getValidCapacityReservations(hra)
+	revs := getValidCapacityReservations(hra, time.Now())
 
 	var count int
 
@@ -489,6 +622,10 @@ func testServer(t *testing.T, eventType string, event interface{}, wantCode int,
 }
 
 func sendWebhook(server *httptest.Server, eventType string, event interface{}) (*http.Response, error) {
+	return sendWebhookTo(server.URL, eventType, event)
+}
+
+func sendWebhookTo(serverURL string, eventType string, event interface{}) (*http.Response, error) {
 	jsonBuf := &bytes.Buffer{}
 	enc := json.NewEncoder(jsonBuf)
 	enc.SetIndent("  ", "")
@@ -499,7 +636,7 @@ func sendWebhook(server *httptest.Server, eventType string, event interface{}) (
 
 	reqBody := jsonBuf.Bytes()
 
-	u, err := url.Parse(server.URL)
+	u, err := url.Parse(serverURL)
 	if err != nil {
 		return nil, fmt.Errorf("parsing server url: %v", err)
 	}
@@ -0,0 +1,190 @@
+package actionssummerwindnet
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+)
+
+const (
+	// UnmatchedLabelsPath is the path of the metrics server that the catalog of unmatched workflow job labels is served on.
+	UnmatchedLabelsPath = "/unmatched-workflow-job-labels"
+
+	// DefaultUnmatchedLabelsLimit is the default maximum number of label sets in the catalog.
+	DefaultUnmatchedLabelsLimit = 100
+
+	// unmatchedLabelSetRepositoriesLimit is the maximum number of repositories recorded per label set.
+	unmatchedLabelSetRepositoriesLimit = 10
+)
+
+// DefaultUnmatchedLabelsIgnore are the patterns of the labels of GitHub-hosted runners,
+// whose jobs never match any HRA and aren't demand for self-hosted runners.
+var DefaultUnmatchedLabelsIgnore = []string{"ubuntu-*", "windows-*", "macos-*"}
+
+// UnmatchedLabelSet is a set of runs-on labels of the queued workflow jobs that no HRA scaled for.
+type UnmatchedLabelSet struct {
+	// Labels are the lowercased labels of the jobs, sorted.
+	Labels []string `json:"labels"`
+	// Jobs is the number of queued jobs that requested the labels.
+	Jobs int64 `json:"jobs"`
+	// Repositories are the full names of the repositories the jobs most recently came from.
+	Repositories []string  `json:"repositories"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
+
+// UnmatchedLabelCatalog records the distinct label sets of the queued workflow jobs that no HRA scaled for,
+// so that platform teams can discover the jobs waiting for labels nobody serves.
+//
+// The catalog is kept in memory by each replica of the webhook server, and exposed via the
+// github_webhook_unmatched_workflow_jobs_total metric and the UnmatchedLabelsPath of the metrics server.
+type UnmatchedLabelCatalog struct {
+	limit  int
+	ignore []string
+
+	mu   sync.Mutex
+	sets map[string]*UnmatchedLabelSet
+}
+
+// NewUnmatchedLabelCatalog returns a catalog of up to limit label sets, forgetting the least recently seen one
+// to record a new one. The label sets including a label that matches any of the ignore patterns aren't recorded.
+// It returns nil when limit is 0, which disables the catalog.
+func NewUnmatchedLabelCatalog(limit int, ignore []string) *UnmatchedLabelCatalog {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &UnmatchedLabelCatalog{
+		limit:  limit,
+		ignore: ignore,
+		sets:   map[string]*UnmatchedLabelSet{},
+	}
+}
+
+// Record counts a queued job of the repository that requested the labels and matched no HRA.
+func (c *UnmatchedLabelCatalog) Record(labels []string, repository string, now time.Time) {
+	if c == nil || len(labels) == 0 {
+		return
+	}
+
+	normalized := normalizeLabels(labels)
+	for _, l := range normalized {
+		for _, pattern := range c.ignore {
+			if ok, _ := path.Match(strings.ToLower(pattern), l); ok {
+				return
+			}
+		}
+	}
+
+	key := strings.Join(normalized, ",")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.sets[key]
+	if !ok {
+		if len(c.sets) >= c.limit {
+			c.evictLocked()
+		}
+
+		s = &UnmatchedLabelSet{
+			Labels:    normalized,
+			FirstSeen: now,
+		}
+		c.sets[key] = s
+	}
+
+	s.Jobs++
+	s.LastSeen = now
+
+	if repository != "" {
+		repos := []string{repository}
+		for _, r := range s.Repositories {
+			if r != repository && len(repos) < unmatchedLabelSetRepositoriesLimit {
+				repos = append(repos, r)
+			}
+		}
+		s.Repositories = repos
+	}
+
+	metrics.AddGitHubWebhookUnmatchedWorkflowJob(key)
+}
+
+// evictLocked forgets the least recently seen label set.
+func (c *UnmatchedLabelCatalog) evictLocked() {
+	var oldest string
+	for key, s := range c.sets {
+		if oldest == "" || s.LastSeen.Before(c.sets[oldest].LastSeen) {
+			oldest = key
+		}
+	}
+
+	delete(c.sets, oldest)
+	metrics.DeleteGitHubWebhookUnmatchedWorkflowJobs(oldest)
+}
+
+// List returns the recorded label sets, the ones requested by the most jobs first.
+func (c *UnmatchedLabelCatalog) List() []UnmatchedLabelSet {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sets := make([]UnmatchedLabelSet, 0, len(c.sets))
+	for _, s := range c.sets {
+		set := *s
+		set.Labels = append([]string(nil), s.Labels...)
+		set.Repositories = append([]string(nil), s.Repositories...)
+		sets = append(sets, set)
+	}
+
+	sort.Slice(sets, func(i, j int) bool {
+		if sets[i].Jobs != sets[j].Jobs {
+			return sets[i].Jobs > sets[j].Jobs
+		}
+		return strings.Join(sets[i].Labels, ",") < strings.Join(sets[j].Labels, ",")
+	})
+
+	return sets
+}
+
+// ServeHTTP responds with the recorded label sets in JSON.
+func (c *UnmatchedLabelCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(c.List()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// normalizeLabels returns the labels lowercased, deduplicated and sorted, as GitHub compares them case-insensitively.
+func normalizeLabels(labels []string) []string {
+	seen := map[string]struct{}{}
+
+	var normalized []string
+	for _, l := range labels {
+		l = strings.ToLower(l)
+		if _, ok := seen[l]; ok {
+			continue
+		}
+		seen[l] = struct{}{}
+		normalized = append(normalized, l)
+	}
+
+	sort.Strings(normalized)
+
+	return normalized
+}
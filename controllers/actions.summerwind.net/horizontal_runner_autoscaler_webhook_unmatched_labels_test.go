@@ -0,0 +1,110 @@
+package actionssummerwindnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmatchedLabelCatalog(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("normalizes and counts", func(t *testing.T) {
+		c := NewUnmatchedLabelCatalog(10, nil)
+
+		c.Record([]string{"self-hosted", "GPU", "gpu"}, "org/a", now)
+		c.Record([]string{"gpu", "Self-Hosted"}, "org/b", now.Add(time.Minute))
+		c.Record([]string{"self-hosted", "arm64"}, "org/a", now)
+
+		sets := c.List()
+		require.Len(t, sets, 2)
+
+		assert.Equal(t, []string{"gpu", "self-hosted"}, sets[0].Labels)
+		assert.Equal(t, int64(2), sets[0].Jobs)
+		assert.Equal(t, []string{"org/b", "org/a"}, sets[0].Repositories)
+		assert.Equal(t, now, sets[0].FirstSeen)
+		assert.Equal(t, now.Add(time.Minute), sets[0].LastSeen)
+
+		assert.Equal(t, []string{"arm64", "self-hosted"}, sets[1].Labels)
+		assert.Equal(t, int64(1), sets[1].Jobs)
+	})
+
+	t.Run("ignores", func(t *testing.T) {
+		c := NewUnmatchedLabelCatalog(10, DefaultUnmatchedLabelsIgnore)
+
+		c.Record([]string{"ubuntu-latest"}, "org/a", now)
+		c.Record([]string{"Windows-2022"}, "org/a", now)
+		c.Record([]string{"self-hosted", "linux"}, "org/a", now)
+		c.Record(nil, "org/a", now)
+
+		sets := c.List()
+		require.Len(t, sets, 1)
+		assert.Equal(t, []string{"linux", "self-hosted"}, sets[0].Labels)
+	})
+
+	t.Run("evicts the least recently seen", func(t *testing.T) {
+		c := NewUnmatchedLabelCatalog(2, nil)
+
+		c.Record([]string{"a"}, "", now)
+		c.Record([]string{"b"}, "", now.Add(2*time.Minute))
+		c.Record([]string{"a"}, "", now.Add(3*time.Minute))
+		c.Record([]string{"c"}, "", now.Add(4*time.Minute))
+
+		sets := c.List()
+		require.Len(t, sets, 2)
+		assert.Equal(t, []string{"a"}, sets[0].Labels)
+		assert.Equal(t, int64(2), sets[0].Jobs)
+		assert.Equal(t, []string{"c"}, sets[1].Labels)
+		assert.Empty(t, sets[1].Repositories)
+	})
+
+	t.Run("limits repositories", func(t *testing.T) {
+		c := NewUnmatchedLabelCatalog(10, nil)
+
+		for i := 0; i < unmatchedLabelSetRepositoriesLimit+5; i++ {
+			c.Record([]string{"gpu"}, fmt.Sprintf("org/%d", i), now)
+		}
+		c.Record([]string{"gpu"}, "org/3", now)
+
+		sets := c.List()
+		require.Len(t, sets, 1)
+		require.Len(t, sets[0].Repositories, unmatchedLabelSetRepositoriesLimit)
+		assert.Equal(t, "org/3", sets[0].Repositories[0])
+		assert.Equal(t, "org/14", sets[0].Repositories[1])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		c := NewUnmatchedLabelCatalog(0, nil)
+		require.Nil(t, c)
+
+		c.Record([]string{"gpu"}, "org/a", now)
+		assert.Empty(t, c.List())
+	})
+}
+
+func TestUnmatchedLabelCatalog_ServeHTTP(t *testing.T) {
+	c := NewUnmatchedLabelCatalog(10, nil)
+	c.Record([]string{"gpu"}, "org/a", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, UnmatchedLabelsPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var sets []UnmatchedLabelSet
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sets))
+	require.Len(t, sets, 1)
+	assert.Equal(t, []string{"gpu"}, sets[0].Labels)
+	assert.Equal(t, []string{"org/a"}, sets[0].Repositories)
+
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, UnmatchedLabelsPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -2,6 +2,7 @@ package actionssummerwindnet
 
 import (
 	"context"
+	"sync"
 )
 
 // worker is a worker that has a non-blocking bounded queue of scale targets, dequeues scale target and executes the scale operation one by one.
@@ -9,6 +10,10 @@ type worker struct {
 	scaleTargetQueue chan *ScaleTarget
 	work             func(*ScaleTarget)
 	done             chan struct{}
+
+	// mu guards stopped, so that no scale target is added once the queue is drained.
+	mu      sync.Mutex
+	stopped bool
 }
 
 func newWorker(ctx context.Context, queueLimit int, work func(*ScaleTarget)) *worker {
@@ -41,7 +46,15 @@ func newWorker(ctx context.Context, queueLimit int, work func(*ScaleTarget)) *wo
 // In case you're building a webhook server around this worker, this means that you must return a http error to the webhook server,
 // so that (hopefully) the sender can resend the webhook event later, or at least the human operator can notice or be notified about the
 // webhook develiery failure so that a manual retry can be done later.
+// It also returns false once the worker has been drained.
 func (w *worker) Add(st *ScaleTarget) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopped {
+		return false
+	}
+
 	select {
 	case w.scaleTargetQueue <- st:
 		return true
@@ -53,3 +66,24 @@ func (w *worker) Add(st *ScaleTarget) bool {
 func (w *worker) Done() chan struct{} {
 	return w.done
 }
+
+// Drain waits for the worker to stop after its context is done, and returns the scale targets left in the queue.
+// The worker accepts no scale target afterwards.
+func (w *worker) Drain() []*ScaleTarget {
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stopped = true
+
+	var remaining []*ScaleTarget
+	for {
+		select {
+		case st := <-w.scaleTargetQueue:
+			remaining = append(remaining, st)
+		default:
+			return remaining
+		}
+	}
+}
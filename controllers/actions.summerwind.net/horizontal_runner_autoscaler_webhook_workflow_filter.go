@@ -0,0 +1,63 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/actionsglob"
+	"github.com/go-logr/logr"
+)
+
+// jobWorkflow is the workflow of the job of a workflow_job event, matched against the workflow filters of the scale up triggers.
+type jobWorkflow struct {
+	owner, repo string
+	runID       int64
+	name        string
+
+	// path is the path of the workflow file, looked up from the workflow run on the first match against WorkflowPaths,
+	// as workflow_job events don't include it.
+	path       string
+	pathLoaded bool
+}
+
+// matchWorkflowJobFilters returns true when the workflow of the job matches the workflow filters of the scale up trigger.
+// A trigger without filters matches all the workflows.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) matchWorkflowJobFilters(ctx context.Context, log logr.Logger, cfg *webhookConfig, spec *v1alpha1.WorkflowJobSpec, workflow *jobWorkflow) (bool, error) {
+	if len(spec.Workflows) > 0 && !matchAnyGlob(spec.Workflows, workflow.name) {
+		return false, nil
+	}
+
+	if len(spec.WorkflowPaths) == 0 {
+		return true, nil
+	}
+
+	if !workflow.pathLoaded {
+		if cfg.githubClient == nil {
+			log.Info("Unable to match the workflow file path of the job without GitHub credentials. Configure the github webhook server with GitHub credentials to use workflowPaths")
+			return false, nil
+		}
+
+		path, err := cfg.githubClient.GetWorkflowRunPath(ctx, workflow.owner, workflow.repo, workflow.runID)
+		if err != nil {
+			return false, fmt.Errorf("getting workflow run %d of %s/%s: %w", workflow.runID, workflow.owner, workflow.repo, err)
+		}
+
+		workflow.path, _, _ = strings.Cut(path, "@")
+		workflow.pathLoaded = true
+	}
+
+	return matchAnyGlob(spec.WorkflowPaths, workflow.path), nil
+}
+
+func matchAnyGlob(patterns []string, s string) bool {
+	for _, p := range patterns {
+		// Empty patterns are rejected by the validating webhook, but it can be disabled
+		if p != "" && actionsglob.Match(p, s) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestWebhookWorkflowJobWorkflowFilter(t *testing.T) {
+	newObjs := func(spec *actionsv1alpha1.WorkflowJobSpec) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: spec,
+						},
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+							Labels:       []string{"label1"},
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+
+	newEvent := func() *github.WorkflowJobEvent {
+		f, err := os.Open("testdata/org_webhook_workflow_job_payload.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		var e github.WorkflowJobEvent
+		require.NoError(t, json.NewDecoder(f).Decode(&e))
+		e.WorkflowJob.WorkflowName = github.String("Build backend")
+
+		return &e
+	}
+
+	t.Run("MatchingWorkflow", func(t *testing.T) {
+		testServerWithInitObjs(t, "workflow_job", newEvent(), 200, "scaled test-name by 1",
+			newObjs(&actionsv1alpha1.WorkflowJobSpec{Workflows: []string{"Deploy", "Build *"}}))
+	})
+
+	t.Run("OtherWorkflow", func(t *testing.T) {
+		testServerWithInitObjs(t, "workflow_job", newEvent(), 200, "no horizontalrunnerautoscaler to scale for this github event",
+			newObjs(&actionsv1alpha1.WorkflowJobSpec{Workflows: []string{"Deploy"}}))
+	})
+
+	t.Run("WorkflowPathsWithoutGitHubClient", func(t *testing.T) {
+		testServerWithInitObjs(t, "workflow_job", newEvent(), 200, "no horizontalrunnerautoscaler to scale for this github event",
+			newObjs(&actionsv1alpha1.WorkflowJobSpec{WorkflowPaths: []string{".github/workflows/build-*.yml"}}))
+	})
+}
+
+func TestMatchWorkflowJobFilters(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.URL.Path != "/repos/MYORG/myrepo/actions/runs/1234567890" {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprint(w, `{"id": 1234567890, "path": ".github/workflows/build-backend.yml@refs/heads/main"}`)
+	}))
+	defer server.Close()
+
+	autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{GitHubClient: newGithubClient(server)}
+
+	newWorkflow := func() *jobWorkflow {
+		return &jobWorkflow{owner: "MYORG", repo: "myrepo", runID: 1234567890, name: "Build backend"}
+	}
+
+	tests := []struct {
+		name string
+		spec actionsv1alpha1.WorkflowJobSpec
+		want bool
+	}{
+		{name: "no filters", want: true},
+		{name: "matching path", spec: actionsv1alpha1.WorkflowJobSpec{WorkflowPaths: []string{".github/workflows/build-*.yml"}}, want: true},
+		{name: "other path", spec: actionsv1alpha1.WorkflowJobSpec{WorkflowPaths: []string{".github/workflows/deploy-*.yml"}}},
+		{name: "excluded path", spec: actionsv1alpha1.WorkflowJobSpec{WorkflowPaths: []string{"!.github/workflows/build-*.yml"}}},
+		{
+			name: "matching path of other workflow",
+			spec: actionsv1alpha1.WorkflowJobSpec{Workflows: []string{"Deploy"}, WorkflowPaths: []string{".github/workflows/build-*.yml"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := autoscaler.matchWorkflowJobFilters(context.Background(), logr.Discard(), autoscaler.defaultConfig(), &tt.spec, newWorkflow())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, matched)
+		})
+	}
+
+	t.Run("path is looked up once", func(t *testing.T) {
+		requests = 0
+
+		workflow := newWorkflow()
+		spec := &actionsv1alpha1.WorkflowJobSpec{WorkflowPaths: []string{".github/workflows/build-*.yml"}}
+
+		for i := 0; i < 2; i++ {
+			matched, err := autoscaler.matchWorkflowJobFilters(context.Background(), logr.Discard(), autoscaler.defaultConfig(), spec, workflow)
+			require.NoError(t, err)
+			assert.True(t, matched)
+		}
+		assert.Equal(t, 1, requests)
+		assert.Equal(t, ".github/workflows/build-backend.yml", workflow.path)
+	})
+
+	t.Run("lookup failure", func(t *testing.T) {
+		workflow := newWorkflow()
+		workflow.runID = 1
+
+		_, err := autoscaler.matchWorkflowJobFilters(context.Background(), logr.Discard(), autoscaler.defaultConfig(), &actionsv1alpha1.WorkflowJobSpec{WorkflowPaths: []string{"*"}}, workflow)
+		require.Error(t, err)
+	})
+}
@@ -20,28 +20,37 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/go-logr/logr"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
 	arcgithub "github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 )
 
 const (
 	DefaultScaleDownDelay = 10 * time.Minute
+	DefaultSyncPeriod     = 1 * time.Minute
 )
 
 // HorizontalRunnerAutoscalerReconciler reconciles a HorizontalRunnerAutoscaler object
@@ -53,6 +62,50 @@ type HorizontalRunnerAutoscalerReconciler struct {
 	Scheme                *runtime.Scheme
 	DefaultScaleDownDelay time.Duration
 	Name                  string
+
+	// CapacityReservationStore is optional. When set, the capacity reservations stored in it
+	// take precedence over HRA.Spec.CapacityReservations.
+	CapacityReservationStore CapacityReservationStore
+
+	// FederationStore is optional. It is required to limit the replicas of HRAs with spec.federation.
+	FederationStore FederationStore
+
+	// SecretReader is optional. When set, the iCalendar secrets of exclusion calendars are read from it,
+	// like the cache of the manager, rather than from the API server on every sync of every HRA.
+	SecretReader client.Reader
+
+	// Clock is optional. When set, it is used instead of the wall clock to evaluate scheduled overrides,
+	// scale-down delays, and capacity reservation expirations.
+	Clock clock.PassiveClock
+
+	// ScalingEvents is optional. When set, the changes in the replicas of the scale targets are emitted to it.
+	ScalingEvents *ScalingEventPublisher
+
+	// DemandSnapshots emits the demand and the desired replicas of every HRA to ScalingEvents on every sync,
+	// in addition to the changes in the replicas.
+	DemandSnapshots bool
+
+	// SyncPeriod is the interval between the steps of an HRA with maxScaleUpReplicasPerSync or maxScaleDownReplicasPerSync.
+	// Defaults to DefaultSyncPeriod.
+	SyncPeriod time.Duration
+
+	// GraphQLMetrics makes the metrics based on the workflow runs of the repositoryNames of an organization fetch the runs
+	// of up to 50 repositories with a single call to the GitHub GraphQL API, instead of two or more REST API calls per repository.
+	GraphQLMetrics bool
+
+	// MetricCacheDuration is the duration the replicas suggested by the metrics of an HRA are reused for,
+	// until the spec of the HRA other than its capacity reservations changes. Zero disables the cache.
+	MetricCacheDuration time.Duration
+
+	// MetricCacheSize is the maximum number of HRAs whose suggested replicas are cached at once.
+	// Defaults to DefaultMetricCacheSize.
+	MetricCacheSize int
+
+	// ScaleExplanations is optional. When set, the trace of the latest computation of the desired replicas of every HRA is recorded to it.
+	ScaleExplanations *ScaleExplanationStore
+
+	busyFractionAverages busyFractionAverages
+	metricCache          metricCache
 }
 
 const defaultReplicas = 1
@@ -68,6 +121,16 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 
 	var hra v1alpha1.HorizontalRunnerAutoscaler
 	if err := r.Get(ctx, req.NamespacedName, &hra); err != nil {
+		if kerrors.IsNotFound(err) {
+			r.busyFractionAverages.forget(req.NamespacedName)
+			r.metricCache.forget(req.NamespacedName)
+			r.ScaleExplanations.forget(req.NamespacedName)
+		}
+		if kerrors.IsNotFound(err) && r.CapacityReservationStore != nil {
+			if err := r.CapacityReservationStore.Delete(ctx, req.NamespacedName); err != nil {
+				return ctrl.Result{}, fmt.Errorf("deleting stored capacity reservations: %w", err)
+			}
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
@@ -77,12 +140,28 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 		return ctrl.Result{}, nil
 	}
 
+	if r.CapacityReservationStore != nil {
+		reservations, found, err := r.CapacityReservationStore.Get(ctx, req.NamespacedName)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("getting stored capacity reservations: %w", err)
+		}
+
+		if found {
+			// We never write the spec back, so it is safe to override it only in memory
+			hra.Spec.CapacityReservations = reservations
+		}
+	}
+
 	metrics.SetHorizontalRunnerAutoscalerSpec(hra.ObjectMeta, hra.Spec)
 
 	kind := hra.Spec.ScaleTargetRef.Kind
 
 	switch kind {
 	case "", "RunnerDeployment":
+		if len(hra.Spec.ScaleTargets) > 0 {
+			return r.reconcileWeightedScaleTargets(ctx, req, log, hra)
+		}
+
 		var rd v1alpha1.RunnerDeployment
 		if err := r.Get(ctx, types.NamespacedName{
 			Namespace: req.Namespace,
@@ -98,40 +177,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 		st := r.scaleTargetFromRD(ctx, rd)
 
 		return r.reconcile(ctx, req, log, hra, st, func(newDesiredReplicas int) error {
-			currentDesiredReplicas := getIntOrDefault(rd.Spec.Replicas, defaultReplicas)
-
-			ephemeral := rd.Spec.Template.Spec.Ephemeral == nil || *rd.Spec.Template.Spec.Ephemeral
-
-			var effectiveTime *time.Time
-
-			for _, r := range hra.Spec.CapacityReservations {
-				t := r.EffectiveTime
-				if effectiveTime == nil || effectiveTime.Before(t.Time) {
-					effectiveTime = &t.Time
-				}
-			}
-
-			// Please add more conditions that we can in-place update the newest runnerreplicaset without disruption
-			if currentDesiredReplicas != newDesiredReplicas {
-				copy := rd.DeepCopy()
-				copy.Spec.Replicas = &newDesiredReplicas
-
-				if ephemeral && effectiveTime != nil {
-					copy.Spec.EffectiveTime = &metav1.Time{Time: *effectiveTime}
-				}
-
-				if err := r.Client.Patch(ctx, copy, client.MergeFrom(&rd)); err != nil {
-					return fmt.Errorf("patching runnerdeployment to have %d replicas: %w", newDesiredReplicas, err)
-				}
-			} else if ephemeral && effectiveTime != nil {
-				copy := rd.DeepCopy()
-				copy.Spec.EffectiveTime = &metav1.Time{Time: *effectiveTime}
-
-				if err := r.Client.Patch(ctx, copy, client.MergeFrom(&rd)); err != nil {
-					return fmt.Errorf("patching runnerdeployment to have %d replicas: %w", newDesiredReplicas, err)
-				}
-			}
-			return nil
+			return r.scaleRunnerDeployment(ctx, hra, rd, newDesiredReplicas)
 		})
 	case "RunnerSet":
 		var rs v1alpha1.RunnerSet
@@ -161,6 +207,10 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 			repo:       rs.Spec.Repository,
 			replicas:   replicas,
 			labels:     rs.Spec.RunnerConfig.Labels,
+
+			nodeSelector: rs.Spec.Template.Spec.NodeSelector,
+
+			slotsPerPod: runnerConfigSlots(rs.Spec.RunnerConfig),
 			getRunnerMap: func() (map[string]struct{}, error) {
 				// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
 				var runnerPodList corev1.PodList
@@ -189,7 +239,9 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 				}
 				runnerMap := make(map[string]struct{})
 				for _, items := range runnerPodList.Items {
-					runnerMap[items.Name] = struct{}{}
+					for slot := 0; slot < podRunnerSlots(&items); slot++ {
+						runnerMap[runnerSlotName(items.Name, slot)] = struct{}{}
+					}
 				}
 
 				return runnerMap, nil
@@ -245,6 +297,45 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 	return ctrl.Result{}, nil
 }
 
+// scaleRunnerDeployment patches the RunnerDeployment to have the desired replicas.
+func (r *HorizontalRunnerAutoscalerReconciler) scaleRunnerDeployment(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, rd v1alpha1.RunnerDeployment, newDesiredReplicas int) error {
+	currentDesiredReplicas := getIntOrDefault(rd.Spec.Replicas, defaultReplicas)
+
+	ephemeral := rd.Spec.Template.Spec.Ephemeral == nil || *rd.Spec.Template.Spec.Ephemeral
+
+	var effectiveTime *time.Time
+
+	for _, r := range hra.Spec.CapacityReservations {
+		t := r.EffectiveTime
+		if effectiveTime == nil || effectiveTime.Before(t.Time) {
+			effectiveTime = &t.Time
+		}
+	}
+
+	// Please add more conditions that we can in-place update the newest runnerreplicaset without disruption
+	if currentDesiredReplicas != newDesiredReplicas {
+		copy := rd.DeepCopy()
+		copy.Spec.Replicas = &newDesiredReplicas
+
+		if ephemeral && effectiveTime != nil {
+			copy.Spec.EffectiveTime = &metav1.Time{Time: *effectiveTime}
+		}
+
+		if err := r.Client.Patch(ctx, copy, client.MergeFrom(&rd)); err != nil {
+			return fmt.Errorf("patching runnerdeployment to have %d replicas: %w", newDesiredReplicas, err)
+		}
+	} else if ephemeral && effectiveTime != nil {
+		copy := rd.DeepCopy()
+		copy.Spec.EffectiveTime = &metav1.Time{Time: *effectiveTime}
+
+		if err := r.Client.Patch(ctx, copy, client.MergeFrom(&rd)); err != nil {
+			return fmt.Errorf("patching runnerdeployment to have %d replicas: %w", newDesiredReplicas, err)
+		}
+	}
+
+	return nil
+}
+
 func (r *HorizontalRunnerAutoscalerReconciler) scaleTargetFromRD(ctx context.Context, rd v1alpha1.RunnerDeployment) scaleTarget {
 	st := scaleTarget{
 		st:         rd.Name,
@@ -254,6 +345,10 @@ func (r *HorizontalRunnerAutoscalerReconciler) scaleTargetFromRD(ctx context.Con
 		repo:       rd.Spec.Template.Spec.Repository,
 		replicas:   rd.Spec.Replicas,
 		labels:     rd.Spec.Template.Spec.RunnerConfig.Labels,
+
+		nodeSelector: rd.Spec.Template.Spec.NodeSelector,
+
+		slotsPerPod: runnerConfigSlots(rd.Spec.Template.Spec.RunnerConfig),
 		getRunnerMap: func() (map[string]struct{}, error) {
 			// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
 			var runnerList v1alpha1.RunnerList
@@ -280,9 +375,13 @@ func (r *HorizontalRunnerAutoscalerReconciler) scaleTargetFromRD(ctx context.Con
 					return nil, err
 				}
 			}
+			slots := runnerConfigSlots(rd.Spec.Template.Spec.RunnerConfig)
+
 			runnerMap := make(map[string]struct{})
 			for _, items := range runnerList.Items {
-				runnerMap[items.Name] = struct{}{}
+				for slot := 0; slot < slots; slot++ {
+					runnerMap[runnerSlotName(items.Name, slot)] = struct{}{}
+				}
 			}
 
 			return runnerMap, nil
@@ -297,36 +396,179 @@ type scaleTarget struct {
 	enterprise, repo, org string
 	replicas              *int
 	labels                []string
+	// nodeSelector is the node selector of the runner pods, which tells the OS and the architecture of the runners.
+	nodeSelector map[string]string
+	// slotsPerPod is the number of runners hosted by each runner pod of the scale target
+	slotsPerPod int
 
 	getRunnerMap func() (map[string]struct{}, error)
 }
 
 func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, req ctrl.Request, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, updatedDesiredReplicas func(int) error) (ctrl.Result, error) {
-	now := time.Now()
+	now := nowFrom(r.Clock)
 
-	minReplicas, active, upcoming, err := r.getMinReplicas(log, now, hra)
+	minReplicas, active, upcoming, err := r.getMinReplicas(ctx, log, now, hra)
 	if err != nil {
 		log.Error(err, "Could not compute min replicas")
 
 		return ctrl.Result{}, err
 	}
 
+	explanation := &ScaleExplanation{
+		Namespace:       hra.Namespace,
+		Name:            hra.Name,
+		Time:            now,
+		ScaleTargetKind: st.kind,
+		ScaleTargetName: st.st,
+		CurrentReplicas: getIntOrDefault(st.replicas, defaultReplicas),
+		MinReplicas:     minReplicas,
+		MaxReplicas:     hra.Spec.MaxReplicas,
+	}
+	if active != nil && active.ScheduledOverride.MinReplicas != nil {
+		explanation.ScheduledOverride = fmt.Sprintf("minReplicas=%d until %s", *active.ScheduledOverride.MinReplicas, active.Period.EndTime.Format(time.RFC3339))
+	}
+
+	r.ScaleExplanations.begin(hra, explanation)
+	defer r.ScaleExplanations.commit(hra)
+
 	ghc, err := r.GitHubClient.InitForHRA(context.Background(), &hra)
 	if err != nil {
+		r.ScaleExplanations.update(hra, func(e *ScaleExplanation) { e.Error = err.Error() })
+
 		return ctrl.Result{}, err
 	}
 
-	newDesiredReplicas, err := r.computeReplicasWithCache(ghc, log, now, st, hra, minReplicas)
+	newDesiredReplicas, uncappedReplicas, source, err := r.computeReplicasAndDemandWithCache(ghc, log, now, st, hra, minReplicas)
 	if err != nil {
+		r.ScaleExplanations.update(hra, func(e *ScaleExplanation) { e.Error = err.Error() })
+
 		r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
 
 		log.Error(err, "Could not compute replicas")
 
+		if retryAfter, ok := arcgithub.RetryAfterRateLimit(err); ok {
+			// Wait for the rate limit instead of retrying with the backoff of controller-runtime,
+			// which would keep every HRA calling the GitHub API while it's rate limited.
+			return ctrl.Result{RequeueAfter: max(retryAfter, retryDelayOnGitHubAPIRateLimitError)}, nil
+		}
+
+		if retryAfter, ok := arcgithub.RetryAfterCircuitOpen(err); ok {
+			// Likewise, wait for GitHub to recover instead of flooding the logs and the work queue with failures
+			return ctrl.Result{RequeueAfter: retryAfter}, nil
+		}
+
 		return ctrl.Result{}, err
 	}
 
-	if err := updatedDesiredReplicas(newDesiredReplicas); err != nil {
+	if expiresAt, ok, err := ghc.CheckTokenExpiration(ctx); err != nil {
+		log.V(1).Info("Could not check the expiration of the GitHub token", "error", err.Error())
+	} else if ok {
+		warnIfGitHubTokenExpiring(r.Recorder, &hra, expiresAt, now)
+	}
+
+	demandReplicas := newDesiredReplicas
+
+	reasons := []string{fmt.Sprintf("computed %d replicas from %s", newDesiredReplicas, sourceOrDefault(source))}
+
+	stabilizedReplicas, history := stabilizeScaleDown(hra, newDesiredReplicas, now)
+	if stabilizedReplicas != newDesiredReplicas {
+		reasons = append(reasons, fmt.Sprintf("kept %d replicas within the scale down stabilization window", stabilizedReplicas))
+
+		r.ScaleExplanations.addStep(hra, "stabilization", stabilizedReplicas, fmt.Sprintf("kept the highest replicas computed within the scale down stabilization window of %ds", *hra.Spec.ScaleDownStabilizationSeconds))
+
+		log.V(1).Info(
+			fmt.Sprintf("Keeping desired replicas of %d within the scale down stabilization window", stabilizedReplicas),
+			"computed", newDesiredReplicas,
+			"window_seconds", *hra.Spec.ScaleDownStabilizationSeconds,
+		)
+
+		newDesiredReplicas = stabilizedReplicas
+	}
+
+	syncPeriod := r.SyncPeriod
+	if syncPeriod <= 0 {
+		syncPeriod = DefaultSyncPeriod
+	}
+
+	steppedReplicas, nextStepAfter := limitScaleStep(hra, newDesiredReplicas, syncPeriod, now)
+	if steppedReplicas != newDesiredReplicas {
+		reasons = append(reasons, fmt.Sprintf("limited to %d replicas in this sync", steppedReplicas))
+
+		r.ScaleExplanations.addStep(hra, "stepLimit", steppedReplicas, fmt.Sprintf("limited by the max replicas scaled per sync, the next step in %s", nextStepAfter))
+
+		log.V(1).Info(
+			fmt.Sprintf("Limiting desired replicas to %d in this sync", steppedReplicas),
+			"computed", newDesiredReplicas,
+			"next_step_after", nextStepAfter,
+		)
+
+		newDesiredReplicas = steppedReplicas
+	}
+
+	budgetedReplicas, budgetStatus, throttled, err := limitByCostBudget(hra, newDesiredReplicas, minReplicas, syncPeriod, now)
+	if err != nil {
+		log.Error(err, "Could not apply the cost budget")
+	} else if throttled {
+		msg := fmt.Sprintf("Limiting desired replicas to %d out of %d to stay within the monthly cost budget of %s", budgetedReplicas, newDesiredReplicas, hra.Spec.CostBudget.MonthlyCap)
+
+		log.V(1).Info(msg, "replica_hours", budgetStatus.ReplicaHours)
+
+		r.Recorder.Event(&hra, corev1.EventTypeWarning, "CostBudgetExceeded", msg)
+
+		reasons = append(reasons, fmt.Sprintf("limited to %d replicas by the cost budget", budgetedReplicas))
+
+		r.ScaleExplanations.addStep(hra, "costBudget", budgetedReplicas, fmt.Sprintf("limited by the monthly cost budget of %s, %s replica hours used", hra.Spec.CostBudget.MonthlyCap, budgetStatus.ReplicaHours))
+
+		newDesiredReplicas = budgetedReplicas
+	}
+
+	federatedReplicas, federationStatus, err := r.limitByFederation(ctx, hra, newDesiredReplicas, minReplicas, now)
+	if err != nil {
+		log.Error(err, "Could not exchange the demand with the federation", "federation", hra.Spec.Federation.Name)
+
+		r.Recorder.Event(&hra, corev1.EventTypeWarning, "FederationUnavailable", err.Error())
+	}
+	if federatedReplicas != newDesiredReplicas {
+		log.V(1).Info(
+			fmt.Sprintf("Limiting desired replicas to %d out of %d to the share of the federation", federatedReplicas, newDesiredReplicas),
+			"federation", hra.Spec.Federation.Name,
+			"total_demand", federationStatus.TotalDemand,
+			"members", federationStatus.Members,
+		)
+
+		reasons = append(reasons, fmt.Sprintf("limited to %d replicas by the federation", federatedReplicas))
+
+		r.ScaleExplanations.addStep(hra, "federation", federatedReplicas, fmt.Sprintf("limited to the share of federation %s, whose total demand is %d replicas", hra.Spec.Federation.Name, federationStatus.TotalDemand))
+
+		newDesiredReplicas = federatedReplicas
+	}
+
+	saturation, saturated := maxReplicasSaturation(hra, uncappedReplicas, now)
+	if saturated != nil && saturated.Status == metav1.ConditionTrue && !meta.IsStatusConditionTrue(hra.Status.Conditions, saturated.Type) {
+		log.Info("The metrics have demanded more replicas than maxReplicas for too long", "message", saturated.Message)
+
+		r.Recorder.Event(&hra, corev1.EventTypeWarning, "MaxReplicasSaturated", saturated.Message)
+	}
+
+	r.ScaleExplanations.update(hra, func(e *ScaleExplanation) {
+		e.DesiredReplicas = newDesiredReplicas
+		e.DryRun = hra.Spec.DryRun
+	})
+
+	if hra.Spec.DryRun {
+		log.V(1).Info(
+			fmt.Sprintf("Dry run: not scaling the scale target to %d replicas", newDesiredReplicas),
+			"current", getIntOrDefault(st.replicas, defaultReplicas),
+			"reasons", reasons,
+		)
+	} else if err := updatedDesiredReplicas(newDesiredReplicas); err != nil {
 		return ctrl.Result{}, err
+	} else if currentReplicas := getIntOrDefault(st.replicas, defaultReplicas); currentReplicas != newDesiredReplicas {
+		r.publishScalingEvent(hra, currentReplicas, newDesiredReplicas, source, reasons, now)
+	}
+
+	if r.DemandSnapshots {
+		r.publishDemandSnapshot(hra, getIntOrDefault(st.replicas, defaultReplicas), demandReplicas, newDesiredReplicas, source, now)
 	}
 
 	updated := hra.DeepCopy()
@@ -335,10 +577,11 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 		if (hra.Status.DesiredReplicas == nil && newDesiredReplicas > 1) ||
 			(hra.Status.DesiredReplicas != nil && newDesiredReplicas > *hra.Status.DesiredReplicas) {
 
-			updated.Status.LastSuccessfulScaleOutTime = &metav1.Time{Time: time.Now()}
+			updated.Status.LastSuccessfulScaleOutTime = &metav1.Time{Time: now}
 		}
 
 		updated.Status.DesiredReplicas = &newDesiredReplicas
+		updated.Status.LastScaleTime = &metav1.Time{Time: now}
 	}
 
 	var overridesSummary string
@@ -364,6 +607,27 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 		updated.Status.ScheduledOverridesSummary = nil
 	}
 
+	_, updated.Status.RepositoryUsage = budgetCapacityReservations(hra, hra.Spec.CapacityReservations, now)
+	updated.Status.DesiredReplicasHistory = history
+	updated.Status.DesiredReplicasSource = source
+	updated.Status.CostBudget = budgetStatus
+	updated.Status.Federation = federationStatus
+	updated.Status.MaxReplicasSaturation = saturation
+	if saturated != nil {
+		meta.SetStatusCondition(&updated.Status.Conditions, *saturated)
+	} else {
+		meta.RemoveStatusCondition(&updated.Status.Conditions, v1alpha1.HorizontalRunnerAutoscalerConditionMaxReplicasSaturated)
+	}
+	updated.Status.LastScaleReason = strings.Join(reasons, "; ")
+	updated.Status.LastEvaluationTime = &metav1.Time{Time: now}
+	updated.Status.NextEvaluationTime = &metav1.Time{Time: now.Add(nextEvaluationAfter(nextStepAfter, syncPeriod))}
+	// The metric cache is kept in memory now. Clear the entries older versions left in the status.
+	updated.Status.CacheEntries = nil
+	updated.Status.DryRun = nil
+	if hra.Spec.DryRun {
+		updated.Status.DryRun = dryRunStatus(hra.Status.DryRun, getIntOrDefault(st.replicas, defaultReplicas), newDesiredReplicas, reasons, now)
+	}
+
 	if !reflect.DeepEqual(hra.Status, updated.Status) {
 		metrics.SetHorizontalRunnerAutoscalerStatus(updated.ObjectMeta, updated.Status)
 
@@ -372,7 +636,55 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 		}
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: nextStepAfter}, nil
+}
+
+// publishScalingEvent emits the change in the replicas of the scale target of the HRA.
+func (r *HorizontalRunnerAutoscalerReconciler) publishScalingEvent(hra v1alpha1.HorizontalRunnerAutoscaler, previous, desired int, source string, reasons []string, now time.Time) {
+	reason := ScalingEventReasonScaledOut
+	if desired < previous {
+		reason = ScalingEventReasonScaledIn
+	}
+
+	r.ScalingEvents.PublishDesiredReplicas(ScalingEvent{
+		Namespace:                  hra.Namespace,
+		HorizontalRunnerAutoscaler: hra.Name,
+		Reason:                     reason,
+		Message:                    strings.Join(reasons, "; "),
+		Delta:                      desired - previous,
+		PreviousReplicas:           &previous,
+		DesiredReplicas:            &desired,
+		Source:                     sourceOrDefault(source),
+		Time:                       now,
+	})
+}
+
+// publishDemandSnapshot emits the demand and the desired replicas of the HRA as of this sync.
+func (r *HorizontalRunnerAutoscalerReconciler) publishDemandSnapshot(hra v1alpha1.HorizontalRunnerAutoscaler, previous, demand, desired int, source string, now time.Time) {
+	reserved := admittedCapacityReservationReplicas(hra, now)
+
+	r.ScalingEvents.PublishDemandSnapshot(ScalingEvent{
+		Namespace:                  hra.Namespace,
+		HorizontalRunnerAutoscaler: hra.Name,
+		Reason:                     ScalingEventReasonDemandSnapshot,
+		Delta:                      desired - previous,
+		PreviousReplicas:           &previous,
+		DesiredReplicas:            &desired,
+		DemandReplicas:             &demand,
+		CapacityReservations:       &reserved,
+		Source:                     sourceOrDefault(source),
+		Time:                       now,
+	})
+}
+
+// nextEvaluationAfter returns the delay until the next evaluation of an HRA, which is the next step of a limited scale step,
+// or the sync period after which the manager resyncs all the HRAs.
+func nextEvaluationAfter(nextStepAfter, syncPeriod time.Duration) time.Duration {
+	if nextStepAfter > 0 && nextStepAfter < syncPeriod {
+		return nextStepAfter
+	}
+
+	return syncPeriod
 }
 
 func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -383,10 +695,37 @@ func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager
 
 	r.Recorder = mgr.GetEventRecorderFor(name)
 
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
-		Named(name).
-		Complete(r)
+		Named(name)
+
+	if r.ScalingEvents != nil {
+		if err := mgr.Add(manager.RunnableFunc(r.ScalingEvents.Run)); err != nil {
+			return err
+		}
+	}
+
+	if c, ok := r.Clock.(*SimulatedClock); ok {
+		// Re-evaluate all the HRAs as soon as the clock is set or advanced, rather than on the next sync.
+		b = b.WatchesRawSource(&source.Channel{Source: c.changes()}, handler.EnqueueRequestsFromMapFunc(r.allHorizontalRunnerAutoscalers))
+	}
+
+	return b.Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &v1alpha1.HorizontalRunnerAutoscaler{}, r))
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) allHorizontalRunnerAutoscalers(ctx context.Context, _ client.Object) []reconcile.Request {
+	var hraList v1alpha1.HorizontalRunnerAutoscalerList
+	if err := r.List(ctx, &hraList); err != nil {
+		r.Log.Error(err, "Could not list horizontalrunnerautoscalers")
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, hra := range hraList.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}})
+	}
+
+	return reqs
 }
 
 type Override struct {
@@ -394,7 +733,7 @@ type Override struct {
 	Period            Period
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, *Override, *Override, error) {
+func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(ctx context.Context, log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, *Override, *Override, error) {
 	var minReplicas *int
 	var active, upcoming *Override
 
@@ -408,11 +747,17 @@ func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.
 			"untilTime", o.RecurrenceRule.UntilTime,
 		)
 
+		exclusions, err := r.exclusionCalendarFor(ctx, hra.Namespace, o.ExclusionCalendar)
+		if err != nil {
+			return minReplicas, nil, nil, err
+		}
+
 		a, u, err := MatchSchedule(
 			now, o.StartTime.Time, o.EndTime.Time,
 			RecurrenceRule{
-				Frequency: o.RecurrenceRule.Frequency,
-				UntilTime: o.RecurrenceRule.UntilTime.Time,
+				Frequency:  o.RecurrenceRule.Frequency,
+				UntilTime:  o.RecurrenceRule.UntilTime.Time,
+				Exclusions: exclusions,
 			},
 		)
 		if err != nil {
@@ -451,13 +796,47 @@ func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.
 	return minReplicas, active, upcoming, nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) getMinReplicas(log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (int, *Override, *Override, error) {
+func (r *HorizontalRunnerAutoscalerReconciler) exclusionCalendarFor(ctx context.Context, namespace string, c *v1alpha1.ExclusionCalendar) (*ExclusionCalendar, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	var ical string
+
+	if ref := c.ICalSecretKeyRef; ref != nil {
+		var reader client.Reader = r.Client
+		if r.SecretReader != nil {
+			reader = r.SecretReader
+		}
+
+		var secret corev1.Secret
+		if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+			return nil, fmt.Errorf("getting secret %s/%s for exclusion calendar: %w", namespace, ref.Name, err)
+		}
+
+		data, ok := secret.Data[ref.Key]
+		if !ok {
+			return nil, fmt.Errorf("secret %s/%s for exclusion calendar has no key %q", namespace, ref.Name, ref.Key)
+		}
+
+		ical = string(data)
+	}
+
+	exclusions, err := NewExclusionCalendar(c.TimeZone, c.Dates, ical)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclusion calendar: %w", err)
+	}
+
+	return exclusions, nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) getMinReplicas(ctx context.Context, log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (int, *Override, *Override, error) {
 	minReplicas := defaultReplicas
 	if hra.Spec.MinReplicas != nil && *hra.Spec.MinReplicas >= 0 {
 		minReplicas = *hra.Spec.MinReplicas
 	}
 
-	m, active, upcoming, err := r.matchScheduledOverrides(log, now, hra)
+	m, active, upcoming, err := r.matchScheduledOverrides(ctx, log, now, hra)
 	if err != nil {
 		return 0, nil, nil, err
 	} else if m != nil {
@@ -467,34 +846,78 @@ func (r *HorizontalRunnerAutoscalerReconciler) getMinReplicas(log logr.Logger, n
 	return minReplicas, active, upcoming, nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arcgithub.Client, log logr.Logger, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, minReplicas int) (int, error) {
+// admittedCapacityReservationReplicas returns the number of replicas reserved by the capacity reservations of the HRA
+// that are admitted by its repository budgets.
+func admittedCapacityReservationReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) int {
+	var reserved int
+
+	admitted, _ := budgetCapacityReservations(hra, hra.Spec.CapacityReservations, now)
+
+	for i, reservation := range hra.Spec.CapacityReservations {
+		if admitted[i] {
+			reserved += reservation.Replicas
+		}
+	}
+
+	return reserved
+}
+
+// computeReplicasWithCache returns the desired replicas, along with the source of the replicas suggested by the metrics.
+func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arcgithub.Client, log logr.Logger, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, minReplicas int) (int, string, error) {
+	replicas, _, source, err := r.computeReplicasAndDemandWithCache(ghc, log, now, st, hra, minReplicas)
+	return replicas, source, err
+}
+
+// computeReplicasAndDemandWithCache is computeReplicasWithCache that also returns the demand,
+// which is the replicas suggested by the metrics plus the capacity reservations before they are limited to maxReplicas.
+func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasAndDemandWithCache(ghc *arcgithub.Client, log logr.Logger, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, minReplicas int) (int, int, string, error) {
 	var suggestedReplicas int
 
-	v, err := r.suggestDesiredReplicas(ghc, st, hra)
-	if err != nil {
-		return 0, err
+	v, source, cached := r.getCachedSuggestedReplicas(hra, now)
+	if !cached {
+		var err error
+
+		v, source, err = r.suggestDesiredReplicas(ghc, st, hra)
+		if err != nil {
+			return 0, 0, "", err
+		}
+
+		r.cacheSuggestedReplicas(hra, v, source, now)
 	}
 
 	if v == nil {
 		suggestedReplicas = minReplicas
+
+		r.ScaleExplanations.addStep(hra, "metrics", suggestedReplicas, "no replicas suggested by the metrics, starting from minReplicas")
 	} else {
 		suggestedReplicas = *v
-	}
 
-	var reserved int
-
-	for _, reservation := range hra.Spec.CapacityReservations {
-		if reservation.ExpirationTime.Time.After(now) {
-			reserved += reservation.Replicas
+		detail := "suggested by " + source
+		if cached {
+			detail += ", reused from the metric cache"
 		}
+		r.ScaleExplanations.addStep(hra, "metrics", suggestedReplicas, detail)
 	}
 
+	reserved := admittedCapacityReservationReplicas(hra, now)
+
+	metrics.SetHorizontalRunnerAutoscalerCapacityReservations(hra.ObjectMeta, hra.Spec.CapacityReservations, now)
+
 	newDesiredReplicas := suggestedReplicas + reserved
+	demandReplicas := newDesiredReplicas
+
+	if reserved > 0 {
+		r.ScaleExplanations.addStep(hra, "capacityReservations", newDesiredReplicas, fmt.Sprintf("added %d replicas reserved by the active capacity reservations", reserved))
+	}
 
 	if newDesiredReplicas < minReplicas {
 		newDesiredReplicas = minReplicas
+
+		r.ScaleExplanations.addStep(hra, "minReplicas", newDesiredReplicas, "raised to minReplicas")
 	} else if hra.Spec.MaxReplicas != nil && newDesiredReplicas > *hra.Spec.MaxReplicas {
 		newDesiredReplicas = *hra.Spec.MaxReplicas
+
+		r.ScaleExplanations.addStep(hra, "maxReplicas", newDesiredReplicas, "lowered to maxReplicas")
 	}
 
 	//
@@ -522,6 +945,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arc
 		if t.After(now) {
 			scaleDownDelayUntil = &t
 			newDesiredReplicas = *hra.Status.DesiredReplicas
+
+			r.ScaleExplanations.addStep(hra, "scaleDownDelay", newDesiredReplicas, fmt.Sprintf("kept the current desired replicas until %s, %s after the last scale out", t.Format(time.RFC3339), scaleDownDelay))
 		}
 	} else {
 		newDesiredReplicas = *hra.Status.DesiredReplicas
@@ -533,6 +958,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arc
 
 	kvs := []interface{}{
 		"suggested", suggestedReplicas,
+		"source", source,
+		"cached", cached,
 		"reserved", reserved,
 		"min", minReplicas,
 	}
@@ -550,5 +977,27 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arc
 		kvs...,
 	)
 
-	return newDesiredReplicas, nil
+	return newDesiredReplicas, demandReplicas, source, nil
+}
+
+// getCachedSuggestedReplicas returns the replicas and the source suggested by the metrics of the HRA within the last MetricCacheDuration, if any.
+func (r *HorizontalRunnerAutoscalerReconciler) getCachedSuggestedReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) (*int, string, bool) {
+	if r.MetricCacheDuration <= 0 {
+		return nil, "", false
+	}
+
+	return r.metricCache.get(hra, now)
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) cacheSuggestedReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, replicas *int, source string, now time.Time) {
+	if r.MetricCacheDuration <= 0 {
+		return
+	}
+
+	size := r.MetricCacheSize
+	if size <= 0 {
+		size = DefaultMetricCacheSize
+	}
+
+	r.metricCache.add(hra, replicas, source, now, r.MetricCacheDuration, size)
 }
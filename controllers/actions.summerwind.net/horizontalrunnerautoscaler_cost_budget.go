@@ -0,0 +1,92 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// costBudgetAccountingInterval is the minimum interval between two accountings of the replica-hours.
+// Every accounting updates the status, which triggers another reconciliation right away, which must not account again.
+const costBudgetAccountingInterval = time.Minute
+
+// limitByCostBudget accounts the desired replicas of the HRA since the last accounting into the replica-hours of the month,
+// and limits the desired replicas to those the rest of the monthly budget can pay for until the next sync, but not below minReplicas.
+// It returns the replicas to scale to, the updated budget status, and whether the budget limited the replicas.
+func limitByCostBudget(hra v1alpha1.HorizontalRunnerAutoscaler, desired, minReplicas int, syncPeriod time.Duration, now time.Time) (int, *v1alpha1.CostBudgetStatus, bool, error) {
+	budget := hra.Spec.CostBudget
+	if budget == nil {
+		return desired, nil, false, nil
+	}
+
+	hourlyCost, err := strconv.ParseFloat(budget.ReplicaHourlyCost, 64)
+	if err != nil || hourlyCost <= 0 {
+		return desired, hra.Status.CostBudget, false, fmt.Errorf("invalid replicaHourlyCost %q", budget.ReplicaHourlyCost)
+	}
+
+	monthlyCap, err := strconv.ParseFloat(budget.MonthlyCap, 64)
+	if err != nil {
+		return desired, hra.Status.CostBudget, false, fmt.Errorf("invalid monthlyCap %q", budget.MonthlyCap)
+	}
+
+	now = now.UTC()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var status *v1alpha1.CostBudgetStatus
+	switch prev := hra.Status.CostBudget; {
+	case prev == nil:
+		// Nothing was accounted yet, so the replicas until now are unknown
+		status = &v1alpha1.CostBudgetStatus{
+			PeriodStart:       metav1.NewTime(periodStart),
+			LastAccountedTime: metav1.NewTime(now),
+			ReplicaHours:      "0",
+		}
+	case prev.PeriodStart.Time.Before(periodStart):
+		// A new month started. The replicas since its start count towards it.
+		lastAccounted := prev.LastAccountedTime.Time
+		if lastAccounted.Before(periodStart) {
+			lastAccounted = periodStart
+		}
+
+		status = &v1alpha1.CostBudgetStatus{
+			PeriodStart:       metav1.NewTime(periodStart),
+			LastAccountedTime: metav1.NewTime(lastAccounted),
+			ReplicaHours:      "0",
+		}
+	default:
+		status = prev.DeepCopy()
+	}
+
+	replicaHours, err := strconv.ParseFloat(status.ReplicaHours, 64)
+	if err != nil {
+		replicaHours = 0
+	}
+
+	if elapsed := now.Sub(status.LastAccountedTime.Time); elapsed >= costBudgetAccountingInterval {
+		var current int
+		if hra.Status.DesiredReplicas != nil {
+			current = *hra.Status.DesiredReplicas
+		}
+
+		replicaHours += float64(current) * elapsed.Hours()
+
+		status.ReplicaHours = strconv.FormatFloat(replicaHours, 'f', 4, 64)
+		status.LastAccountedTime = metav1.NewTime(now)
+	}
+
+	remaining := monthlyCap - replicaHours*hourlyCost
+	affordable := remaining / (hourlyCost * syncPeriod.Hours())
+
+	status.Throttled = desired > minReplicas && float64(desired) > affordable
+	if status.Throttled {
+		desired = minReplicas
+		if affordable > float64(minReplicas) {
+			desired = int(affordable)
+		}
+	}
+
+	return desired, status, status.Throttled, nil
+}
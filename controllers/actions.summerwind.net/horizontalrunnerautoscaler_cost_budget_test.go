@@ -0,0 +1,111 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLimitByCostBudget(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	march := metav1.NewTime(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC))
+	syncPeriod := 10 * time.Minute
+
+	budget := &v1alpha1.CostBudget{ReplicaHourlyCost: "0.5", MonthlyCap: "100"}
+
+	newHRA := func(budget *v1alpha1.CostBudget, current int, status *v1alpha1.CostBudgetStatus) v1alpha1.HorizontalRunnerAutoscaler {
+		return v1alpha1.HorizontalRunnerAutoscaler{
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{CostBudget: budget},
+			Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(current),
+				CostBudget:      status,
+			},
+		}
+	}
+
+	accounted := func(at time.Time, replicaHours string) *v1alpha1.CostBudgetStatus {
+		return &v1alpha1.CostBudgetStatus{PeriodStart: march, LastAccountedTime: metav1.NewTime(at), ReplicaHours: replicaHours}
+	}
+
+	tests := []struct {
+		name          string
+		hra           v1alpha1.HorizontalRunnerAutoscaler
+		desired       int
+		minReplicas   int
+		want          int
+		wantThrottled bool
+		wantStatus    *v1alpha1.CostBudgetStatus
+	}{
+		{
+			name:    "disabled",
+			hra:     newHRA(nil, 3, nil),
+			desired: 10,
+			want:    10,
+		},
+		{
+			name:       "accounting starts at the first sync",
+			hra:        newHRA(budget, 3, nil),
+			desired:    10,
+			want:       10,
+			wantStatus: accounted(now, "0"),
+		},
+		{
+			name:       "replica-hours accumulate the desired replicas since the last accounting",
+			hra:        newHRA(budget, 4, accounted(now.Add(-30*time.Minute), "10.0000")),
+			desired:    5,
+			want:       5,
+			wantStatus: accounted(now, "12.0000"),
+		},
+		{
+			name:       "replica-hours aren't accounted again right after the last accounting",
+			hra:        newHRA(budget, 4, accounted(now.Add(-10*time.Second), "10.0000")),
+			desired:    5,
+			want:       5,
+			wantStatus: accounted(now.Add(-10*time.Second), "10.0000"),
+		},
+		{
+			// 1 left to spend, for 10 minutes of 0.5 an hour per replica
+			name:          "scale up is limited to the replicas the rest of the budget pays for",
+			hra:           newHRA(budget, 4, accounted(now, "198.0000")),
+			desired:       20,
+			minReplicas:   1,
+			want:          12,
+			wantThrottled: true,
+			wantStatus:    &v1alpha1.CostBudgetStatus{PeriodStart: march, LastAccountedTime: metav1.NewTime(now), ReplicaHours: "198.0000", Throttled: true},
+		},
+		{
+			name:          "an exhausted budget keeps minReplicas",
+			hra:           newHRA(budget, 4, accounted(now, "250.0000")),
+			desired:       6,
+			minReplicas:   2,
+			want:          2,
+			wantThrottled: true,
+			wantStatus:    &v1alpha1.CostBudgetStatus{PeriodStart: march, LastAccountedTime: metav1.NewTime(now), ReplicaHours: "250.0000", Throttled: true},
+		},
+		{
+			name: "a new month starts from zero",
+			hra: newHRA(&v1alpha1.CostBudget{ReplicaHourlyCost: "0.5", MonthlyCap: "1000"}, 2, &v1alpha1.CostBudgetStatus{
+				PeriodStart:       metav1.NewTime(time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)),
+				LastAccountedTime: metav1.NewTime(time.Date(2024, time.February, 29, 23, 0, 0, 0, time.UTC)),
+				ReplicaHours:      "250.0000",
+				Throttled:         true,
+			}),
+			desired:    6,
+			want:       6,
+			wantStatus: accounted(now, "696.0000"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, status, throttled, err := limitByCostBudget(tt.hra, tt.desired, tt.minReplicas, syncPeriod, now)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantThrottled, throttled)
+			require.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package actionssummerwindnet
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// dryRunStatus returns the scaling operation a dry-run HRA would have made at now.
+// The previous operation is kept as is while it doesn't change, so that the status isn't updated on every reconciliation.
+func dryRunStatus(prev *v1alpha1.DryRunStatus, current, desired int, reasons []string, now time.Time) *v1alpha1.DryRunStatus {
+	if prev != nil && prev.CurrentReplicas == current && prev.DesiredReplicas == desired && reflect.DeepEqual(prev.Reasons, reasons) {
+		return prev
+	}
+
+	return &v1alpha1.DryRunStatus{
+		Time:            metav1.NewTime(now),
+		CurrentReplicas: current,
+		DesiredReplicas: desired,
+		Reasons:         reasons,
+	}
+}
+
+// sourceOrDefault returns the source of the desired replicas for display.
+// No source means that no metric suggested replicas, leaving minReplicas and the capacity reservations.
+func sourceOrDefault(source string) string {
+	if source == "" {
+		return "minReplicas and capacity reservations"
+	}
+
+	return source
+}
@@ -0,0 +1,58 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDryRunStatus(t *testing.T) {
+	now := time.Now()
+	earlier := metav1.NewTime(now.Add(-time.Minute))
+
+	reasons := []string{"computed 5 replicas from PercentageRunnersBusy", "limited to 3 replicas in this sync"}
+
+	prev := &v1alpha1.DryRunStatus{Time: earlier, CurrentReplicas: 1, DesiredReplicas: 3, Reasons: reasons}
+
+	tests := []struct {
+		name    string
+		prev    *v1alpha1.DryRunStatus
+		current int
+		desired int
+		reasons []string
+		want    *v1alpha1.DryRunStatus
+	}{
+		{
+			name:    "first operation",
+			current: 1,
+			desired: 3,
+			reasons: reasons,
+			want:    &v1alpha1.DryRunStatus{Time: metav1.NewTime(now), CurrentReplicas: 1, DesiredReplicas: 3, Reasons: reasons},
+		},
+		{
+			name:    "unchanged operation keeps its time",
+			prev:    prev,
+			current: 1,
+			desired: 3,
+			reasons: []string{"computed 5 replicas from PercentageRunnersBusy", "limited to 3 replicas in this sync"},
+			want:    prev,
+		},
+		{
+			name:    "changed operation",
+			prev:    prev,
+			current: 1,
+			desired: 5,
+			reasons: reasons[:1],
+			want:    &v1alpha1.DryRunStatus{Time: metav1.NewTime(now), CurrentReplicas: 1, DesiredReplicas: 5, Reasons: reasons[:1]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, dryRunStatus(tt.prev, tt.current, tt.desired, tt.reasons, now))
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package actionssummerwindnet
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ScaleExplanationPath is the path of the metrics server that ScaleExplanationStore serves the explanations at.
+const ScaleExplanationPath = "/explain-scale"
+
+// ScaleExplanation is the trace of the latest computation of the desired replicas of an HRA,
+// from the inputs of its metrics to every adjustment made to the replicas they suggested.
+type ScaleExplanation struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Time      time.Time `json:"time"`
+
+	ScaleTargetKind string `json:"scaleTargetKind"`
+	ScaleTargetName string `json:"scaleTargetName"`
+	CurrentReplicas int    `json:"currentReplicas"`
+
+	MinReplicas       int    `json:"minReplicas"`
+	MaxReplicas       *int   `json:"maxReplicas,omitempty"`
+	ScheduledOverride string `json:"scheduledOverride,omitempty"`
+
+	// Metrics are the metrics computed in order. The secondary metric is computed only when the primary one fails or suggests no replicas.
+	Metrics []MetricExplanation `json:"metrics,omitempty"`
+	// Steps are the adjustments made to the replicas in order, starting from the replicas suggested by the metrics.
+	Steps []ScaleStep `json:"steps"`
+
+	DesiredReplicas int    `json:"desiredReplicas"`
+	DryRun          bool   `json:"dryRun,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// MetricExplanation is the inputs and the result of a metric.
+type MetricExplanation struct {
+	Type              string         `json:"type"`
+	SuggestedReplicas *int           `json:"suggestedReplicas,omitempty"`
+	Inputs            map[string]any `json:"inputs,omitempty"`
+	Error             string         `json:"error,omitempty"`
+}
+
+// ScaleStep is an adjustment made to the replicas, with the replicas after it.
+type ScaleStep struct {
+	Name     string `json:"name"`
+	Replicas int    `json:"replicas"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ScaleExplanationStore keeps the latest ScaleExplanation of every HRA in memory, and serves them over HTTP
+// so that `kubectl arc explain-scale` can show why an HRA was scaled the way it was.
+// A nil store records nothing.
+type ScaleExplanationStore struct {
+	mu      sync.Mutex
+	latest  map[types.NamespacedName]*ScaleExplanation
+	pending map[types.NamespacedName]*ScaleExplanation
+}
+
+func NewScaleExplanationStore() *ScaleExplanationStore {
+	return &ScaleExplanationStore{
+		latest:  map[types.NamespacedName]*ScaleExplanation{},
+		pending: map[types.NamespacedName]*ScaleExplanation{},
+	}
+}
+
+// begin starts recording the explanation of the ongoing computation of the HRA.
+// An HRA is never reconciled concurrently, so there's at most one pending explanation per HRA.
+func (s *ScaleExplanationStore) begin(hra v1alpha1.HorizontalRunnerAutoscaler, e *ScaleExplanation) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending[types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}] = e
+}
+
+// update applies f to the pending explanation of the HRA, if any.
+func (s *ScaleExplanationStore) update(hra v1alpha1.HorizontalRunnerAutoscaler, f func(*ScaleExplanation)) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.pending[types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}]; ok {
+		f(e)
+	}
+}
+
+func (s *ScaleExplanationStore) addMetric(hra v1alpha1.HorizontalRunnerAutoscaler, m MetricExplanation) {
+	s.update(hra, func(e *ScaleExplanation) {
+		e.Metrics = append(e.Metrics, m)
+	})
+}
+
+func (s *ScaleExplanationStore) addStep(hra v1alpha1.HorizontalRunnerAutoscaler, name string, replicas int, detail string) {
+	s.update(hra, func(e *ScaleExplanation) {
+		e.Steps = append(e.Steps, ScaleStep{Name: name, Replicas: replicas, Detail: detail})
+	})
+}
+
+// commit makes the pending explanation of the HRA the latest one.
+func (s *ScaleExplanationStore) commit(hra v1alpha1.HorizontalRunnerAutoscaler) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+	if e, ok := s.pending[key]; ok {
+		s.latest[key] = e
+		delete(s.pending, key)
+	}
+}
+
+func (s *ScaleExplanationStore) forget(key types.NamespacedName) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.latest, key)
+	delete(s.pending, key)
+}
+
+// Get returns the latest explanation of the HRA.
+func (s *ScaleExplanationStore) Get(key types.NamespacedName) (*ScaleExplanation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.latest[key]
+
+	return e, ok
+}
+
+// ServeHTTP serves the latest explanation of the HRA named by the namespace and name query parameters as JSON.
+func (s *ScaleExplanationStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := types.NamespacedName{Namespace: r.URL.Query().Get("namespace"), Name: r.URL.Query().Get("name")}
+	if key.Namespace == "" || key.Name == "" {
+		http.Error(w, "namespace and name query parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	e, ok := s.Get(key)
+	if !ok {
+		http.Error(w, "no scaling decision has been made for horizontalrunnerautoscaler "+key.String()+" since the controller started", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,70 @@
+package actionssummerwindnet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestScaleExplanation(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			MinReplicas: intPtr(1),
+			MaxReplicas: intPtr(3),
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				{Replicas: 5, ExpirationTime: metav1.Time{Time: now.Add(time.Hour)}},
+			},
+		},
+	}
+
+	store := NewScaleExplanationStore()
+
+	r := &HorizontalRunnerAutoscalerReconciler{Log: logr.Discard(), ScaleExplanations: store}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ScaleExplanationPath+query, nil))
+		return rec
+	}
+
+	require.Equal(t, http.StatusBadRequest, get("?namespace=default").Code)
+	require.Equal(t, http.StatusNotFound, get("?namespace=default&name=example").Code)
+
+	store.begin(hra, &ScaleExplanation{Namespace: hra.Namespace, Name: hra.Name, Time: now, MinReplicas: 1, MaxReplicas: hra.Spec.MaxReplicas})
+
+	replicas, _, err := r.computeReplicasWithCache(nil, r.Log, now, scaleTarget{}, hra, 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, replicas)
+
+	// Not served until the computation completes
+	require.Equal(t, http.StatusNotFound, get("?namespace=default&name=example").Code)
+
+	store.commit(hra)
+
+	rec := get("?namespace=default&name=example")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var e ScaleExplanation
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &e))
+	require.Equal(t, []ScaleStep{
+		{Name: "metrics", Replicas: 1, Detail: "no replicas suggested by the metrics, starting from minReplicas"},
+		{Name: "capacityReservations", Replicas: 6, Detail: "added 5 replicas reserved by the active capacity reservations"},
+		{Name: "maxReplicas", Replicas: 3, Detail: "lowered to maxReplicas"},
+	}, e.Steps)
+
+	store.forget(types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name})
+	require.Equal(t, http.StatusNotFound, get("?namespace=default&name=example").Code)
+}
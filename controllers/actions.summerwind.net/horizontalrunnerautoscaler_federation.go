@@ -0,0 +1,269 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	FederationStoreTypeConfigMap = "configmap"
+
+	DefaultFederationStoreConfigMapName = "actions-runner-controller-federation"
+	DefaultFederationMemberTTL          = 5 * time.Minute
+)
+
+// FederationDemand is the number of replicas a member of a federation demands.
+type FederationDemand struct {
+	// Member identifies the HRA within the federation. It isn't stored, as it is the key of the demand in the store.
+	Member string `json:"-"`
+	// Self is true for the demand of the HRA exchanging its demand.
+	Self bool `json:"-"`
+
+	Replicas    int       `json:"replicas"`
+	MinReplicas int       `json:"minReplicas"`
+	UpdateTime  time.Time `json:"updateTime"`
+}
+
+// FederationStore is shared by the ARC installations whose HRAs share a maximum number of replicas
+// through spec.federation. Each installation publishes the demands of its HRAs to the store,
+// and divides the maximum between the demands of all the live members of the federation.
+type FederationStore interface {
+	// Exchange publishes the demand of the HRA for the federation, and returns the demands of all the live members
+	// of the federation, including the one of the HRA, which has Self set.
+	Exchange(ctx context.Context, federation string, hra types.NamespacedName, demand FederationDemand) ([]FederationDemand, error)
+}
+
+// NewFederationStore returns the store of the given type.
+// It returns nil without an error when storeType is empty, which disables federations.
+// cluster identifies this ARC installation among the members of the federations,
+// and ttl is the duration after which a member that stopped publishing its demand is ignored.
+func NewFederationStore(storeType string, c client.Client, namespace, name, cluster string, ttl time.Duration) (FederationStore, error) {
+	switch storeType {
+	case "":
+		return nil, nil
+	case FederationStoreTypeConfigMap:
+		if namespace == "" {
+			return nil, fmt.Errorf("namespace is required for the %s federation store", storeType)
+		}
+		if cluster == "" || strings.ContainsAny(cluster, ".") {
+			return nil, fmt.Errorf("a cluster name without dots is required for the %s federation store", storeType)
+		}
+		if name == "" {
+			name = DefaultFederationStoreConfigMapName
+		}
+		if ttl <= 0 {
+			ttl = DefaultFederationMemberTTL
+		}
+		return &ConfigMapFederationStore{Client: c, Namespace: namespace, Name: name, Cluster: cluster, TTL: ttl}, nil
+	default:
+		return nil, fmt.Errorf("unsupported federation store type %q", storeType)
+	}
+}
+
+// ConfigMapFederationStore stores the demands of the members of all the federations in a single ConfigMap,
+// usually in a hub cluster all the ARC installations have access to.
+type ConfigMapFederationStore struct {
+	client.Client
+
+	Namespace string
+	Name      string
+	Cluster   string
+	TTL       time.Duration
+
+	// mu serializes updates made within this process to save round-trips on conflicts.
+	// Updates made by other processes are handled by retrying on conflict.
+	mu sync.Mutex
+}
+
+// federationStoreKey returns the key of the demand of the HRA of the cluster in the federation.
+// Neither the federation, the cluster nor the namespace of the HRA can contain ".", so it is safe to use as the separator.
+func federationStoreKey(federation, cluster string, hra types.NamespacedName) string {
+	return federation + "." + cluster + "." + hra.Namespace + "." + hra.Name
+}
+
+func (s *ConfigMapFederationStore) Exchange(ctx context.Context, federation string, hra types.NamespacedName, demand FederationDemand) ([]FederationDemand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	self := federationStoreKey(federation, s.Cluster, hra)
+	prefix := federation + "."
+	now := demand.UpdateTime
+
+	data, err := json.Marshal(demand)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling federation demand of %s: %w", hra, err)
+	}
+
+	var demands []FederationDemand
+	err = updateConfigMap(ctx, s.Client, types.NamespacedName{Namespace: s.Namespace, Name: s.Name}, func(cm *corev1.ConfigMap) bool {
+		demands = nil
+		changed := false
+
+		for key, value := range cm.Data {
+			var d FederationDemand
+			if err := json.Unmarshal([]byte(value), &d); err != nil || now.Sub(d.UpdateTime) > s.TTL {
+				// Members that stopped publishing their demands, like the HRAs of an uninstalled controller, are pruned
+				delete(cm.Data, key)
+				changed = true
+				continue
+			}
+
+			if key == self {
+				// Republishing an unchanged demand only refreshes it before it expires, which saves writes
+				if d.Replicas != demand.Replicas || d.MinReplicas != demand.MinReplicas || now.Sub(d.UpdateTime) > s.TTL/3 {
+					changed = true
+				}
+				continue
+			}
+
+			if strings.HasPrefix(key, prefix) {
+				d.Member = strings.TrimPrefix(key, prefix)
+				demands = append(demands, d)
+			}
+		}
+
+		if _, ok := cm.Data[self]; !ok {
+			changed = true
+		}
+
+		if changed {
+			if cm.Data == nil {
+				cm.Data = map[string]string{}
+			}
+			cm.Data[self] = string(data)
+		}
+
+		return changed
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	demand.Member = strings.TrimPrefix(self, prefix)
+	demand.Self = true
+	demands = append(demands, demand)
+
+	sort.Slice(demands, func(i, j int) bool { return demands[i].Member < demands[j].Member })
+
+	return demands, nil
+}
+
+// federationShare divides maxReplicas between the demands, and returns the share of the demand with Self set
+// along with the total demand.
+// Every member first gets its demand up to its minReplicas, and the rest of maxReplicas is divided
+// in proportion to the remaining demands, using the largest remainder method ordered by member
+// so that all the members compute the same shares, whose sum never exceeds maxReplicas.
+func federationShare(demands []FederationDemand, maxReplicas int) (share, total int) {
+	guaranteed := make([]int, len(demands))
+	var totalGuaranteed, totalExtra int
+	for i, d := range demands {
+		total += d.Replicas
+
+		guaranteed[i] = d.Replicas
+		if guaranteed[i] > d.MinReplicas {
+			guaranteed[i] = d.MinReplicas
+		}
+		totalGuaranteed += guaranteed[i]
+		totalExtra += d.Replicas - guaranteed[i]
+	}
+
+	shares := make([]int, len(demands))
+	rest := maxReplicas - totalGuaranteed
+	switch {
+	case totalExtra <= rest:
+		for i, d := range demands {
+			shares[i] = d.Replicas
+		}
+	case rest <= 0:
+		copy(shares, guaranteed)
+	default:
+		remainders := make([]int, len(demands))
+		distributed := 0
+		for i, d := range demands {
+			extra := d.Replicas - guaranteed[i]
+			shares[i] = guaranteed[i] + rest*extra/totalExtra
+			remainders[i] = rest * extra % totalExtra
+			distributed += rest * extra / totalExtra
+		}
+
+		order := make([]int, len(demands))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			i, j := order[a], order[b]
+			if remainders[i] != remainders[j] {
+				return remainders[i] > remainders[j]
+			}
+			return demands[i].Member < demands[j].Member
+		})
+
+		for _, i := range order[:rest-distributed] {
+			shares[i]++
+		}
+	}
+
+	for i, d := range demands {
+		if d.Self {
+			return shares[i], total
+		}
+	}
+
+	return maxReplicas, total
+}
+
+// limitByFederation limits the desired replicas of the HRA to its share of the maximum replicas of its federation.
+// When the demands can't be exchanged, the HRA keeps the share of its last sync, and the error is returned.
+func (r *HorizontalRunnerAutoscalerReconciler) limitByFederation(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, desiredReplicas, minReplicas int, now time.Time) (int, *v1alpha1.FederationStatus, error) {
+	federation := hra.Spec.Federation
+	if federation == nil {
+		return desiredReplicas, nil, nil
+	}
+
+	status := hra.Status.Federation
+
+	var err error
+	if r.FederationStore == nil {
+		err = fmt.Errorf("no federation store is configured")
+	} else {
+		var demands []FederationDemand
+		demands, err = r.FederationStore.Exchange(ctx, federation.Name, types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}, FederationDemand{
+			Replicas:    desiredReplicas,
+			MinReplicas: minReplicas,
+			UpdateTime:  now,
+		})
+		if err == nil {
+			share, total := federationShare(demands, federation.MaxReplicas)
+			status = &v1alpha1.FederationStatus{
+				Demand:      desiredReplicas,
+				TotalDemand: total,
+				Members:     len(demands),
+				Share:       share,
+			}
+		}
+	}
+
+	if status == nil {
+		return desiredReplicas, nil, err
+	}
+
+	limited := desiredReplicas
+	if limited > status.Share {
+		limited = status.Share
+	}
+	if limited < minReplicas {
+		limited = minReplicas
+	}
+
+	return limited, status, err
+}
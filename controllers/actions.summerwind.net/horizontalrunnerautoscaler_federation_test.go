@@ -0,0 +1,180 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFederationShare(t *testing.T) {
+	demand := func(member string, replicas, minReplicas int) FederationDemand {
+		return FederationDemand{Member: member, Replicas: replicas, MinReplicas: minReplicas}
+	}
+
+	tests := map[string]struct {
+		demands     []FederationDemand
+		maxReplicas int
+		want        []int
+	}{
+		"within the maximum": {
+			demands:     []FederationDemand{demand("a", 10, 0), demand("b", 20, 0)},
+			maxReplicas: 30,
+			want:        []int{10, 20},
+		},
+		"proportional": {
+			demands:     []FederationDemand{demand("a", 10, 0), demand("b", 30, 0)},
+			maxReplicas: 20,
+			want:        []int{5, 15},
+		},
+		"largest remainder": {
+			demands:     []FederationDemand{demand("a", 10, 0), demand("b", 10, 0), demand("c", 10, 0)},
+			maxReplicas: 10,
+			want:        []int{4, 3, 3},
+		},
+		"min replicas first": {
+			demands:     []FederationDemand{demand("a", 10, 4), demand("b", 10, 0)},
+			maxReplicas: 10,
+			want:        []int{6, 4},
+		},
+		"min replicas above the maximum": {
+			demands:     []FederationDemand{demand("a", 10, 8), demand("b", 10, 8)},
+			maxReplicas: 10,
+			want:        []int{8, 8},
+		},
+		"no demand": {
+			demands:     []FederationDemand{demand("a", 0, 0), demand("b", 0, 0)},
+			maxReplicas: 10,
+			want:        []int{0, 0},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []int
+			for i := range tc.demands {
+				demands := append([]FederationDemand(nil), tc.demands...)
+				demands[i].Self = true
+
+				share, _ := federationShare(demands, tc.maxReplicas)
+				got = append(got, share)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestConfigMapFederationStore(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	east, err := NewFederationStore(FederationStoreTypeConfigMap, c, "arc-federation", "", "east", time.Minute)
+	require.NoError(t, err)
+	west, err := NewFederationStore(FederationStoreTypeConfigMap, c, "arc-federation", "", "west", time.Minute)
+	require.NoError(t, err)
+
+	_, err = NewFederationStore(FederationStoreTypeConfigMap, c, "arc-federation", "", "us.east", time.Minute)
+	require.Error(t, err)
+
+	ctx := context.Background()
+	hra := types.NamespacedName{Namespace: "default", Name: "example.runners"}
+	now := time.Now().Truncate(time.Second)
+
+	demands, err := east.Exchange(ctx, "org", hra, FederationDemand{Replicas: 10, UpdateTime: now})
+	require.NoError(t, err)
+	require.Len(t, demands, 1)
+	assert.True(t, demands[0].Self)
+	assert.Equal(t, "east.default.example.runners", demands[0].Member)
+
+	// Another federation isn't returned
+	_, err = west.Exchange(ctx, "other", hra, FederationDemand{Replicas: 1, UpdateTime: now})
+	require.NoError(t, err)
+
+	demands, err = west.Exchange(ctx, "org", hra, FederationDemand{Replicas: 30, UpdateTime: now.Add(10 * time.Second)})
+	require.NoError(t, err)
+	require.Len(t, demands, 2)
+	assert.Equal(t, "east.default.example.runners", demands[0].Member)
+	assert.Equal(t, 10, demands[0].Replicas)
+	assert.True(t, demands[0].UpdateTime.Equal(now))
+	assert.False(t, demands[0].Self)
+	assert.Equal(t, 30, demands[1].Replicas)
+	assert.True(t, demands[1].Self)
+
+	share, total := federationShare(demands, 20)
+	assert.Equal(t, 15, share)
+	assert.Equal(t, 40, total)
+
+	// An unchanged demand isn't rewritten until it is about to expire
+	var before corev1.ConfigMap
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "arc-federation", Name: DefaultFederationStoreConfigMapName}, &before))
+	_, err = east.Exchange(ctx, "org", hra, FederationDemand{Replicas: 10, UpdateTime: now.Add(10 * time.Second)})
+	require.NoError(t, err)
+	var after corev1.ConfigMap
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "arc-federation", Name: DefaultFederationStoreConfigMapName}, &after))
+	assert.Equal(t, before.ResourceVersion, after.ResourceVersion)
+
+	// The members that stopped publishing their demands are pruned
+	demands, err = west.Exchange(ctx, "org", hra, FederationDemand{Replicas: 30, UpdateTime: now.Add(2 * time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, demands, 1)
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "arc-federation", Name: DefaultFederationStoreConfigMapName}, &after))
+	assert.Len(t, after.Data, 1)
+	assert.Contains(t, after.Data, "org.west.default.example.runners")
+}
+
+type fakeFederationStore struct {
+	demands []FederationDemand
+	err     error
+}
+
+func (s *fakeFederationStore) Exchange(_ context.Context, _ string, _ types.NamespacedName, demand FederationDemand) ([]FederationDemand, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	demand.Member = "self"
+	demand.Self = true
+	return append(append([]FederationDemand(nil), s.demands...), demand), nil
+}
+
+func TestLimitByFederation(t *testing.T) {
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			Federation: &v1alpha1.Federation{Name: "org", MaxReplicas: 10},
+		},
+	}
+
+	store := &fakeFederationStore{demands: []FederationDemand{{Member: "other", Replicas: 15}}}
+	r := &HorizontalRunnerAutoscalerReconciler{FederationStore: store}
+
+	replicas, status, err := r.limitByFederation(context.Background(), hra, 5, 1, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 3, replicas)
+	assert.Equal(t, &v1alpha1.FederationStatus{Demand: 5, TotalDemand: 20, Members: 2, Share: 3}, status)
+
+	// The share of the last sync is kept while the store is unavailable
+	hra.Status.Federation = status
+	store.err = assert.AnError
+	replicas, status, err = r.limitByFederation(context.Background(), hra, 8, 1, time.Now())
+	require.Error(t, err)
+	assert.Equal(t, 3, replicas)
+	assert.Equal(t, hra.Status.Federation, status)
+
+	// Without a federation, the replicas are left as is
+	hra.Spec.Federation = nil
+	replicas, status, err = r.limitByFederation(context.Background(), hra, 8, 1, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 8, replicas)
+	assert.Nil(t, status)
+}
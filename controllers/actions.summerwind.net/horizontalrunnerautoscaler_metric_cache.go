@@ -0,0 +1,127 @@
+package actionssummerwindnet
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultMetricCacheSize is the default number of HRAs whose suggested replicas are cached at once.
+const DefaultMetricCacheSize = 1000
+
+// metricCache caches the replicas suggested by the metrics of each HRA for a while, so that the reconciliations
+// triggered by changes to an HRA, like the capacity reservations added by the webhook server, don't call the GitHub API every time.
+//
+// It's kept in memory rather than in the HRA status, like the cacheEntries of the status used to be,
+// as the cache is of no use to the users and every status update triggers another reconciliation.
+// An entry is valid until it expires or the spec of the HRA changes other than its capacity reservations,
+// and the least recently used entries are evicted beyond the size of the cache.
+type metricCache struct {
+	mu      sync.Mutex
+	entries map[types.NamespacedName]*list.Element
+	order   *list.List
+}
+
+type metricCacheEntry struct {
+	key            types.NamespacedName
+	specHash       string
+	replicas       *int
+	source         string
+	expirationTime time.Time
+}
+
+// get returns the replicas and the source suggested by the metrics of the HRA, when they were cached for the current spec of the HRA
+// and haven't expired at now.
+func (c *metricCache) get(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) (*int, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+	e, ok := c.entries[key]
+	if !ok {
+		metrics.IncHorizontalRunnerAutoscalerMetricCacheMisses(hra.ObjectMeta)
+		return nil, "", false
+	}
+
+	entry := e.Value.(*metricCacheEntry)
+
+	if entry.specHash != metricCacheSpecHash(hra) || !now.Before(entry.expirationTime) {
+		c.remove(e)
+		metrics.IncHorizontalRunnerAutoscalerMetricCacheMisses(hra.ObjectMeta)
+		return nil, "", false
+	}
+
+	c.order.MoveToFront(e)
+
+	metrics.IncHorizontalRunnerAutoscalerMetricCacheHits(hra.ObjectMeta)
+
+	return entry.replicas, entry.source, true
+}
+
+// add caches the replicas and the source suggested by the metrics of the HRA until now+ttl,
+// evicting the least recently used entries beyond size.
+func (c *metricCache) add(hra v1alpha1.HorizontalRunnerAutoscaler, replicas *int, source string, now time.Time, ttl time.Duration, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[types.NamespacedName]*list.Element{}
+		c.order = list.New()
+	}
+
+	key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+	entry := &metricCacheEntry{
+		key:            key,
+		specHash:       metricCacheSpecHash(hra),
+		replicas:       replicas,
+		source:         source,
+		expirationTime: now.Add(ttl),
+	}
+
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+	} else {
+		c.entries[key] = c.order.PushFront(entry)
+	}
+
+	for c.order.Len() > size {
+		c.remove(c.order.Back())
+		metrics.IncHorizontalRunnerAutoscalerMetricCacheEvictions()
+	}
+
+	metrics.SetHorizontalRunnerAutoscalerMetricCacheEntries(c.order.Len())
+}
+
+// metricCacheSpecHash returns the hash of the spec of the HRA the metrics are computed from.
+// The capacity reservations are left out, as they are added to the suggested replicas on every sync,
+// and change far more often than the rest of the spec.
+func metricCacheSpecHash(hra v1alpha1.HorizontalRunnerAutoscaler) string {
+	spec := hra.Spec.DeepCopy()
+	spec.CapacityReservations = nil
+
+	return ComputeHash(spec)
+}
+
+func (c *metricCache) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+}
+
+// remove removes the entry. The caller must hold mu.
+func (c *metricCache) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*metricCacheEntry).key)
+
+	metrics.SetHorizontalRunnerAutoscalerMetricCacheEntries(c.order.Len())
+}
@@ -0,0 +1,111 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestMetricCache(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	ttl := time.Minute
+
+	newHRA := func(name string) v1alpha1.HorizontalRunnerAutoscaler {
+		return v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+				Metrics: []v1alpha1.MetricSpec{
+					{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns, RepositoryNames: []string{"test/valid"}},
+				},
+			},
+		}
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		var c metricCache
+
+		hra := newHRA("example")
+
+		_, _, ok := c.get(hra, now)
+		require.False(t, ok)
+
+		c.add(hra, intPtr(3), v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns, now, ttl, DefaultMetricCacheSize)
+
+		replicas, source, ok := c.get(hra, now.Add(ttl-time.Second))
+		require.True(t, ok)
+		require.Equal(t, 3, *replicas)
+		require.Equal(t, v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns, source)
+
+		// Capacity reservations are added on top of the cached replicas on every sync
+		hra.Spec.CapacityReservations = []v1alpha1.CapacityReservation{{Replicas: 1, ExpirationTime: metav1.Time{Time: now.Add(ttl)}}}
+
+		_, _, ok = c.get(hra, now)
+		require.True(t, ok)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		var c metricCache
+
+		hra := newHRA("example")
+
+		c.add(hra, intPtr(3), "", now, ttl, DefaultMetricCacheSize)
+
+		_, _, ok := c.get(hra, now.Add(ttl))
+		require.False(t, ok)
+		require.Empty(t, c.entries)
+	})
+
+	t.Run("spec changed", func(t *testing.T) {
+		var c metricCache
+
+		hra := newHRA("example")
+
+		c.add(hra, intPtr(3), "", now, ttl, DefaultMetricCacheSize)
+
+		hra.Spec.Metrics[0].RepositoryNames = append(hra.Spec.Metrics[0].RepositoryNames, "test/other")
+
+		_, _, ok := c.get(hra, now)
+		require.False(t, ok)
+	})
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		var c metricCache
+
+		first, second, third := newHRA("first"), newHRA("second"), newHRA("third")
+
+		c.add(first, intPtr(1), "", now, ttl, 2)
+		c.add(second, intPtr(2), "", now, ttl, 2)
+
+		_, _, ok := c.get(first, now)
+		require.True(t, ok)
+
+		c.add(third, intPtr(3), "", now, ttl, 2)
+
+		_, _, ok = c.get(second, now)
+		require.False(t, ok)
+
+		_, _, ok = c.get(first, now)
+		require.True(t, ok)
+
+		_, _, ok = c.get(third, now)
+		require.True(t, ok)
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		var c metricCache
+
+		hra := newHRA("example")
+
+		c.add(hra, intPtr(3), "", now, ttl, DefaultMetricCacheSize)
+		c.forget(types.NamespacedName{Namespace: "default", Name: "example"})
+
+		_, _, ok := c.get(hra, now)
+		require.False(t, ok)
+	})
+}
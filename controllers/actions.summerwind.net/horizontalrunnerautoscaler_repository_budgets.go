@@ -0,0 +1,100 @@
+package actionssummerwindnet
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+const repositoryBudgetWildcard = "*"
+
+// repositoryBudget returns the budget that applies to the repository, preferring OWNER/REPO over REPO over the wildcard.
+func repositoryBudget(budgets []v1alpha1.RepositoryBudget, repository string) (int, bool) {
+	if repository == "" {
+		return 0, false
+	}
+
+	name := repository
+	if i := strings.LastIndex(repository, "/"); i >= 0 {
+		name = repository[i+1:]
+	}
+
+	for _, key := range []string{repository, name, repositoryBudgetWildcard} {
+		for _, b := range budgets {
+			if b.Repository == key {
+				return b.MaxReplicas, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// budgetCapacityReservations tells which of the capacity reservations active at now add replicas.
+// Reservations are admitted in order, up to the budget of the repository they are attributed to.
+// Reservations without a repository or a budget are always admitted.
+// Expired reservations are never admitted.
+func budgetCapacityReservations(hra v1alpha1.HorizontalRunnerAutoscaler, reservations []v1alpha1.CapacityReservation, now time.Time) ([]bool, []v1alpha1.RepositoryUsage) {
+	admitted := make([]bool, len(reservations))
+
+	usage := map[string]*v1alpha1.RepositoryUsage{}
+
+	for i, r := range reservations {
+		if !r.ExpirationTime.Time.After(now) {
+			continue
+		}
+
+		max, ok := repositoryBudget(hra.Spec.RepositoryBudgets, r.Repository)
+		if !ok {
+			admitted[i] = true
+			continue
+		}
+
+		u, ok := usage[r.Repository]
+		if !ok {
+			u = &v1alpha1.RepositoryUsage{Repository: r.Repository, MaxReplicas: max}
+			usage[r.Repository] = u
+		}
+
+		if u.Reserved+r.Replicas > max {
+			u.Pending += r.Replicas
+			continue
+		}
+
+		u.Reserved += r.Replicas
+		admitted[i] = true
+	}
+
+	var usages []v1alpha1.RepositoryUsage
+	for _, u := range usage {
+		usages = append(usages, *u)
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Repository < usages[j].Repository
+	})
+
+	return admitted, usages
+}
+
+// removeCapacityReservationsOfRepository removes up to n of the oldest reservations attributed to the repository,
+// and returns the remaining reservations along with the number of reservations actually removed.
+func removeCapacityReservationsOfRepository(reservations []v1alpha1.CapacityReservation, repository string, n int) ([]v1alpha1.CapacityReservation, int) {
+	var (
+		remaining []v1alpha1.CapacityReservation
+		removed   int
+	)
+
+	for _, r := range reservations {
+		if removed < n && repository != "" && r.Repository == repository {
+			removed++
+			continue
+		}
+
+		remaining = append(remaining, r)
+	}
+
+	return remaining, removed
+}
@@ -0,0 +1,141 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRepositoryBudget(t *testing.T) {
+	budgets := []v1alpha1.RepositoryBudget{
+		{Repository: "*", MaxReplicas: 2},
+		{Repository: "monorepo", MaxReplicas: 5},
+		{Repository: "other-org/monorepo", MaxReplicas: 1},
+	}
+
+	tests := []struct {
+		repository string
+		want       int
+		wantOK     bool
+	}{
+		{repository: "my-org/monorepo", want: 5, wantOK: true},
+		{repository: "other-org/monorepo", want: 1, wantOK: true},
+		{repository: "my-org/small", want: 2, wantOK: true},
+		{repository: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := repositoryBudget(budgets, tt.repository)
+		require.Equal(t, tt.wantOK, ok, tt.repository)
+		require.Equal(t, tt.want, got, tt.repository)
+	}
+
+	_, ok := repositoryBudget([]v1alpha1.RepositoryBudget{{Repository: "monorepo", MaxReplicas: 1}}, "my-org/small")
+	require.False(t, ok)
+}
+
+func TestBudgetCapacityReservations(t *testing.T) {
+	now := time.Now()
+
+	reservation := func(repo string, expired bool) v1alpha1.CapacityReservation {
+		exp := now.Add(time.Minute)
+		if expired {
+			exp = now.Add(-time.Minute)
+		}
+		return v1alpha1.CapacityReservation{Repository: repo, Replicas: 1, ExpirationTime: metav1.NewTime(exp)}
+	}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			RepositoryBudgets: []v1alpha1.RepositoryBudget{{Repository: "noisy", MaxReplicas: 2}},
+		},
+	}
+
+	admitted, usage := budgetCapacityReservations(hra, []v1alpha1.CapacityReservation{
+		reservation("org/noisy", true),
+		reservation("org/noisy", false),
+		reservation("org/quiet", false),
+		reservation("org/noisy", false),
+		reservation("org/noisy", false),
+		reservation("", false),
+	}, now)
+
+	require.Equal(t, []bool{false, true, true, true, false, true}, admitted)
+	require.Equal(t, []v1alpha1.RepositoryUsage{{Repository: "org/noisy", Reserved: 2, Pending: 1, MaxReplicas: 2}}, usage)
+}
+
+func TestPlanBatchScale_RepositoryBudgets(t *testing.T) {
+	s := &batchScaler{Log: logr.Discard()}
+
+	var (
+		expiry = 10 * time.Second
+		t0     = time.Now()
+		t1     = t0.Add(3 * time.Second)
+	)
+
+	reservation := func(repo string, at time.Time) v1alpha1.CapacityReservation {
+		return v1alpha1.CapacityReservation{
+			EffectiveTime:  metav1.NewTime(at),
+			ExpirationTime: metav1.NewTime(at.Add(expiry)),
+			Replicas:       1,
+			Repository:     repo,
+		}
+	}
+
+	op := func(repo string, amount int) batchScaleOperation {
+		return batchScaleOperation{
+			scaleOps: []scaleOperation{
+				{
+					log:        logr.Discard(),
+					repository: repo,
+					trigger:    v1alpha1.ScaleUpTrigger{Amount: amount, Duration: metav1.Duration{Duration: expiry}},
+				},
+			},
+		}
+	}
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			RepositoryBudgets: []v1alpha1.RepositoryBudget{{Repository: "noisy", MaxReplicas: 1}},
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				reservation("org/quiet", t0),
+				reservation("org/noisy", t0),
+				reservation("org/noisy", t0),
+			},
+		},
+	}
+
+	t.Run("scale up extends reservations beyond the budget", func(t *testing.T) {
+		got, err := s.planBatchScale(context.Background(), op("org/noisy", 1), hra, t1)
+		require.NoError(t, err)
+		require.Equal(t, []v1alpha1.CapacityReservation{
+			reservation("org/quiet", t0),
+			reservation("org/noisy", t0),
+			reservation("org/noisy", t1),
+			reservation("org/noisy", t1),
+		}, got.Spec.CapacityReservations)
+	})
+
+	t.Run("scale down releases a reservation of the same repository", func(t *testing.T) {
+		got, err := s.planBatchScale(context.Background(), op("org/noisy", -1), hra, t1)
+		require.NoError(t, err)
+		require.Equal(t, []v1alpha1.CapacityReservation{
+			reservation("org/quiet", t0),
+			reservation("org/noisy", t1),
+		}, got.Spec.CapacityReservations)
+	})
+
+	t.Run("scale down falls back to the oldest reservation", func(t *testing.T) {
+		got, err := s.planBatchScale(context.Background(), op("org/unknown", -1), hra, t1)
+		require.NoError(t, err)
+		require.Equal(t, []v1alpha1.CapacityReservation{
+			reservation("org/noisy", t0),
+			reservation("org/noisy", t1),
+		}, got.Spec.CapacityReservations)
+	})
+}
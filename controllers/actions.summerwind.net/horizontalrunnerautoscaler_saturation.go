@@ -0,0 +1,77 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultMaxReplicasSaturationDuration is how long the metrics must demand more replicas than maxReplicas
+// for the HRA to be saturated, unless spec.maxReplicasSaturation.duration says otherwise.
+const defaultMaxReplicasSaturationDuration = 15 * time.Minute
+
+const (
+	reasonMaxReplicasSaturated = "DemandExceedsMaxReplicas"
+	reasonMaxReplicasCapped    = "Capped"
+	reasonWithinMaxReplicas    = "WithinMaxReplicas"
+)
+
+// maxReplicasSaturation tracks how long the metrics of the HRA have demanded more replicas than maxReplicas.
+// demand is the number of replicas suggested by the metrics plus the capacity reservations, before maxReplicas applies.
+// It returns the updated saturation status, which is nil while the demand fits within maxReplicas,
+// and the MaxReplicasSaturated condition, which is nil when the HRA has no maxReplicas.
+func maxReplicasSaturation(hra v1alpha1.HorizontalRunnerAutoscaler, demand int, now time.Time) (*v1alpha1.MaxReplicasSaturationStatus, *metav1.Condition) {
+	if hra.Spec.MaxReplicas == nil {
+		return nil, nil
+	}
+
+	maxReplicas := *hra.Spec.MaxReplicas
+
+	condition := &metav1.Condition{
+		Type:               v1alpha1.HorizontalRunnerAutoscalerConditionMaxReplicasSaturated,
+		ObservedGeneration: hra.Generation,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+
+	if demand <= maxReplicas {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonWithinMaxReplicas
+		condition.Message = fmt.Sprintf("The metrics demand no more than maxReplicas of %d", maxReplicas)
+		return nil, condition
+	}
+
+	status := &v1alpha1.MaxReplicasSaturationStatus{
+		Since:              metav1.NewTime(now),
+		PeakDemandReplicas: demand,
+	}
+	if prev := hra.Status.MaxReplicasSaturation; prev != nil {
+		status.Since = prev.Since
+		status.PeakDemandReplicas = max(prev.PeakDemandReplicas, demand)
+	}
+
+	duration := defaultMaxReplicasSaturationDuration
+	if s := hra.Spec.MaxReplicasSaturation; s != nil && s.Duration != nil {
+		duration = s.Duration.Duration
+	}
+
+	if now.Sub(status.Since.Time) < duration {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonMaxReplicasCapped
+		condition.Message = fmt.Sprintf("The metrics have demanded more than maxReplicas of %d since %s, for less than %s", maxReplicas, status.Since.UTC().Format(time.RFC3339), duration)
+		return status, condition
+	}
+
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = reasonMaxReplicasSaturated
+	condition.Message = fmt.Sprintf("The metrics have demanded more than maxReplicas of %d since %s, up to %d replicas", maxReplicas, status.Since.UTC().Format(time.RFC3339), status.PeakDemandReplicas)
+
+	if s := hra.Spec.MaxReplicasSaturation; s != nil && s.SuggestMaxReplicas {
+		suggested := status.PeakDemandReplicas
+		status.SuggestedMaxReplicas = &suggested
+		condition.Message += fmt.Sprintf(". Consider raising maxReplicas to %d", suggested)
+	}
+
+	return status, condition
+}
@@ -0,0 +1,117 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMaxReplicasSaturation(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+	newHRA := func(maxReplicas *int, saturation *v1alpha1.MaxReplicasSaturation, status *v1alpha1.MaxReplicasSaturationStatus) v1alpha1.HorizontalRunnerAutoscaler {
+		return v1alpha1.HorizontalRunnerAutoscaler{
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MaxReplicas:           maxReplicas,
+				MaxReplicasSaturation: saturation,
+			},
+			Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				MaxReplicasSaturation: status,
+			},
+		}
+	}
+
+	since := func(ago time.Duration, peak int) *v1alpha1.MaxReplicasSaturationStatus {
+		return &v1alpha1.MaxReplicasSaturationStatus{Since: metav1.NewTime(now.Add(-ago)), PeakDemandReplicas: peak}
+	}
+
+	tests := []struct {
+		name             string
+		hra              v1alpha1.HorizontalRunnerAutoscaler
+		demand           int
+		wantStatus       *v1alpha1.MaxReplicasSaturationStatus
+		wantCondition    metav1.ConditionStatus
+		wantReason       string
+		wantSuggestedMax *int
+		wantNoCondition  bool
+	}{
+		{
+			name:            "without maxReplicas",
+			hra:             newHRA(nil, nil, nil),
+			demand:          100,
+			wantNoCondition: true,
+		},
+		{
+			name:          "within maxReplicas",
+			hra:           newHRA(intPtr(10), nil, since(time.Hour, 12)),
+			demand:        10,
+			wantCondition: metav1.ConditionFalse,
+			wantReason:    reasonWithinMaxReplicas,
+		},
+		{
+			name:          "capped for the first time",
+			hra:           newHRA(intPtr(10), nil, nil),
+			demand:        12,
+			wantStatus:    since(0, 12),
+			wantCondition: metav1.ConditionFalse,
+			wantReason:    reasonMaxReplicasCapped,
+		},
+		{
+			name:          "capped for less than the default duration",
+			hra:           newHRA(intPtr(10), nil, since(10*time.Minute, 15)),
+			demand:        12,
+			wantStatus:    since(10*time.Minute, 15),
+			wantCondition: metav1.ConditionFalse,
+			wantReason:    reasonMaxReplicasCapped,
+		},
+		{
+			name:          "saturated after the default duration",
+			hra:           newHRA(intPtr(10), nil, since(15*time.Minute, 11)),
+			demand:        14,
+			wantStatus:    since(15*time.Minute, 14),
+			wantCondition: metav1.ConditionTrue,
+			wantReason:    reasonMaxReplicasSaturated,
+		},
+		{
+			name:          "saturated after a custom duration",
+			hra:           newHRA(intPtr(10), &v1alpha1.MaxReplicasSaturation{Duration: &metav1.Duration{Duration: 5 * time.Minute}}, since(5*time.Minute, 11)),
+			demand:        11,
+			wantStatus:    since(5*time.Minute, 11),
+			wantCondition: metav1.ConditionTrue,
+			wantReason:    reasonMaxReplicasSaturated,
+		},
+		{
+			name:             "suggests the peak demand",
+			hra:              newHRA(intPtr(10), &v1alpha1.MaxReplicasSaturation{SuggestMaxReplicas: true}, since(time.Hour, 18)),
+			demand:           12,
+			wantStatus:       &v1alpha1.MaxReplicasSaturationStatus{Since: metav1.NewTime(now.Add(-time.Hour)), PeakDemandReplicas: 18, SuggestedMaxReplicas: intPtr(18)},
+			wantCondition:    metav1.ConditionTrue,
+			wantReason:       reasonMaxReplicasSaturated,
+			wantSuggestedMax: intPtr(18),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			status, condition := maxReplicasSaturation(tc.hra, tc.demand, now)
+
+			require.Equal(t, tc.wantStatus, status)
+
+			if tc.wantNoCondition {
+				require.Nil(t, condition)
+				return
+			}
+
+			require.NotNil(t, condition)
+			require.Equal(t, v1alpha1.HorizontalRunnerAutoscalerConditionMaxReplicasSaturated, condition.Type)
+			require.Equal(t, tc.wantCondition, condition.Status)
+			require.Equal(t, tc.wantReason, condition.Reason)
+			if tc.wantSuggestedMax != nil {
+				require.Contains(t, condition.Message, "Consider raising maxReplicas to 18")
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileWeightedScaleTargets computes the desired replicas of the HRA as a whole,
+// and splits them across the RunnerDeployments listed in spec.scaleTargets.
+// The runner configuration of the RunnerDeployment referred by spec.scaleTargetRef is used to compute the metrics,
+// while the current replicas and the runners are summed up across all the targets.
+func (r *HorizontalRunnerAutoscalerReconciler) reconcileWeightedScaleTargets(ctx context.Context, req ctrl.Request, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler) (ctrl.Result, error) {
+	var (
+		targets []v1alpha1.WeightedScaleTargetRef
+		rds     []v1alpha1.RunnerDeployment
+		primary = -1
+	)
+
+	for _, t := range hra.Spec.ScaleTargets {
+		if t.Kind != "" && t.Kind != "RunnerDeployment" {
+			log.Info(fmt.Sprintf("Skipping unsupported scale target %s %s: only RunnerDeployment is supported in scaleTargets", t.Kind, t.Name))
+			continue
+		}
+
+		var rd v1alpha1.RunnerDeployment
+		if err := r.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: t.Name}, &rd); err != nil {
+			if kerrors.IsNotFound(err) {
+				log.V(1).Info("Skipping scale target as the RunnerDeployment is not found", "runnerdeployment", t.Name)
+				continue
+			}
+
+			return ctrl.Result{}, err
+		}
+
+		if !rd.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if rd.Name == hra.Spec.ScaleTargetRef.Name {
+			primary = len(rds)
+		}
+
+		targets = append(targets, t)
+		rds = append(rds, rd)
+	}
+
+	// As with a single scale target, nothing is scaled without the RunnerDeployment of scaleTargetRef,
+	// as the metrics are computed with its runner configuration.
+	if primary < 0 {
+		if !slices.ContainsFunc(hra.Spec.ScaleTargets, func(t v1alpha1.WeightedScaleTargetRef) bool { return t.Name == hra.Spec.ScaleTargetRef.Name }) {
+			log.Error(nil, "Skipping reconciliation as scaleTargetRef doesn't refer to one of scaleTargets", "scaleTargetRef", hra.Spec.ScaleTargetRef.Name)
+		} else {
+			log.V(1).Info("Skipping reconciliation as the RunnerDeployment of scaleTargetRef is not found or being deleted", "scaleTargetRef", hra.Spec.ScaleTargetRef.Name)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	st := r.scaleTargetFromRD(ctx, rds[primary])
+
+	var replicas int
+
+	getRunnerMaps := make([]func() (map[string]struct{}, error), len(rds))
+
+	for i, rd := range rds {
+		replicas += getIntOrDefault(rd.Spec.Replicas, defaultReplicas)
+		getRunnerMaps[i] = r.scaleTargetFromRD(ctx, rd).getRunnerMap
+	}
+
+	st.replicas = &replicas
+	st.getRunnerMap = func() (map[string]struct{}, error) {
+		runnerMap := make(map[string]struct{})
+
+		for _, get := range getRunnerMaps {
+			m, err := get()
+			if err != nil {
+				return nil, err
+			}
+
+			for name := range m {
+				runnerMap[name] = struct{}{}
+			}
+		}
+
+		return runnerMap, nil
+	}
+
+	return r.reconcile(ctx, req, log, hra, st, func(newDesiredReplicas int) error {
+		distributed := distributeReplicas(newDesiredReplicas, targets)
+
+		var kvs []interface{}
+
+		for i := range rds {
+			if err := r.scaleRunnerDeployment(ctx, hra, rds[i], distributed[i]); err != nil {
+				return err
+			}
+
+			kvs = append(kvs, rds[i].Name, distributed[i])
+		}
+
+		log.V(1).Info(fmt.Sprintf("Distributed desired replicas of %d across scale targets", newDesiredReplicas), kvs...)
+
+		return nil
+	})
+}
+
+// distributeReplicas splits the desired replicas across the weighted scale targets.
+// The targets are filled in ascending order of priority, each up to its maxReplicas.
+// Targets of the same priority split the replicas in proportion to their weights.
+// The replicas that exceed the total maxReplicas of all the targets are not assigned to any target.
+func distributeReplicas(desired int, targets []v1alpha1.WeightedScaleTargetRef) []int {
+	assigned := make([]int, len(targets))
+
+	order := make([]int, len(targets))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return targets[order[i]].Priority < targets[order[j]].Priority
+	})
+
+	remaining := desired
+
+	for start := 0; start < len(order) && remaining > 0; {
+		end := start + 1
+		for end < len(order) && targets[order[end]].Priority == targets[order[start]].Priority {
+			end++
+		}
+
+		remaining -= splitByWeight(remaining, targets, order[start:end], assigned)
+
+		start = end
+	}
+
+	return assigned
+}
+
+// splitByWeight assigns up to n replicas to the targets at the indices in proportion to their weights,
+// capping each target at its maxReplicas and handing the excess to the others.
+// It returns the number of replicas actually assigned.
+func splitByWeight(n int, targets []v1alpha1.WeightedScaleTargetRef, indices []int, assigned []int) int {
+	var total int
+
+	for n > 0 {
+		var (
+			open        []int
+			totalWeight int
+		)
+
+		for _, i := range indices {
+			w := scaleTargetWeight(targets[i])
+			if w <= 0 {
+				continue
+			}
+
+			if max := targets[i].MaxReplicas; max != nil && assigned[i] >= *max {
+				continue
+			}
+
+			open = append(open, i)
+			totalWeight += w
+		}
+
+		if len(open) == 0 {
+			break
+		}
+
+		// Largest remainder method, so that the shares always sum up to n
+		shares := make([]int, len(open))
+		remainders := make([]int, len(open))
+		given := 0
+
+		for k, i := range open {
+			w := scaleTargetWeight(targets[i])
+			shares[k] = n * w / totalWeight
+			remainders[k] = n * w % totalWeight
+			given += shares[k]
+		}
+
+		byRemainder := make([]int, len(open))
+		for k := range byRemainder {
+			byRemainder[k] = k
+		}
+
+		sort.SliceStable(byRemainder, func(a, b int) bool {
+			return remainders[byRemainder[a]] > remainders[byRemainder[b]]
+		})
+
+		for k := 0; given < n; k++ {
+			shares[byRemainder[k]]++
+			given++
+		}
+
+		added := 0
+
+		for k, i := range open {
+			share := shares[k]
+
+			if max := targets[i].MaxReplicas; max != nil && assigned[i]+share > *max {
+				share = *max - assigned[i]
+			}
+
+			assigned[i] += share
+			added += share
+		}
+
+		if added == 0 {
+			break
+		}
+
+		n -= added
+		total += added
+	}
+
+	return total
+}
+
+func scaleTargetWeight(t v1alpha1.WeightedScaleTargetRef) int {
+	if t.Weight == nil {
+		return 1
+	}
+
+	return *t.Weight
+}
@@ -0,0 +1,140 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDistributeReplicas(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	target := func(name string, priority int, weight, max *int) v1alpha1.WeightedScaleTargetRef {
+		return v1alpha1.WeightedScaleTargetRef{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: name},
+			Priority:       priority,
+			Weight:         weight,
+			MaxReplicas:    max,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		desired int
+		targets []v1alpha1.WeightedScaleTargetRef
+		want    []int
+	}{
+		{
+			name:    "single target",
+			desired: 3,
+			targets: []v1alpha1.WeightedScaleTargetRef{target("a", 0, nil, nil)},
+			want:    []int{3},
+		},
+		{
+			name:    "cheaper pool is filled first",
+			desired: 7,
+			targets: []v1alpha1.WeightedScaleTargetRef{target("on-demand", 1, nil, nil), target("spot", 0, nil, intPtr(5))},
+			want:    []int{2, 5},
+		},
+		{
+			name:    "cheaper pool has capacity left",
+			desired: 4,
+			targets: []v1alpha1.WeightedScaleTargetRef{target("spot", 0, nil, intPtr(5)), target("on-demand", 1, nil, nil)},
+			want:    []int{4, 0},
+		},
+		{
+			name:    "weights with largest remainder",
+			desired: 10,
+			targets: []v1alpha1.WeightedScaleTargetRef{target("a", 0, intPtr(2), nil), target("b", 0, intPtr(1), nil)},
+			want:    []int{7, 3},
+		},
+		{
+			name:    "capped target overflows to the others of the same priority",
+			desired: 10,
+			targets: []v1alpha1.WeightedScaleTargetRef{target("a", 0, intPtr(1), intPtr(2)), target("b", 0, intPtr(1), nil)},
+			want:    []int{2, 8},
+		},
+		{
+			name:    "zero weight",
+			desired: 3,
+			targets: []v1alpha1.WeightedScaleTargetRef{target("a", 0, intPtr(0), nil), target("b", 0, nil, nil)},
+			want:    []int{0, 3},
+		},
+		{
+			name:    "exceeding the total capacity",
+			desired: 10,
+			targets: []v1alpha1.WeightedScaleTargetRef{target("a", 0, nil, intPtr(3)), target("b", 1, nil, intPtr(4))},
+			want:    []int{3, 4},
+		},
+		{
+			name:    "zero",
+			desired: 0,
+			targets: []v1alpha1.WeightedScaleTargetRef{target("a", 0, nil, nil), target("b", 1, nil, nil)},
+			want:    []int{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, distributeReplicas(tt.desired, tt.targets))
+		})
+	}
+}
+
+func TestReconcileWeightedScaleTargets_RequiresPrimary(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	intPtr := func(v int) *int { return &v }
+
+	rd := func(name string) *v1alpha1.RunnerDeployment {
+		return &v1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       v1alpha1.RunnerDeploymentSpec{Replicas: intPtr(1)},
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		primary string
+	}{
+		{name: "scaleTargetRef is not one of scaleTargets", primary: "other"},
+		{name: "the RunnerDeployment of scaleTargetRef is not found", primary: "missing"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(rd("spot"), rd("on-demand")).Build()
+			r := &HorizontalRunnerAutoscalerReconciler{Client: c, Scheme: scheme}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: tc.primary},
+					ScaleTargets: []v1alpha1.WeightedScaleTargetRef{
+						{ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "spot"}},
+						{ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "on-demand"}},
+						{ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "missing"}},
+					},
+				},
+			}
+
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "hra"}}
+			_, err := r.reconcileWeightedScaleTargets(context.Background(), req, logr.Discard(), hra)
+			require.NoError(t, err)
+
+			// The other scale targets are left as is rather than scaled with the runner configuration of the first one
+			for _, name := range []string{"spot", "on-demand"} {
+				var got v1alpha1.RunnerDeployment
+				require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &got))
+				require.Equal(t, 1, *got.Spec.Replicas, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// DefaultPubSubEndpoint is the endpoint of the Google Cloud Pub/Sub REST API.
+	DefaultPubSubEndpoint = "https://pubsub.googleapis.com/v1/"
+
+	pubSubScope = "https://www.googleapis.com/auth/pubsub"
+)
+
+var (
+	// sqsQueueHostPattern matches the host of an SQS queue URL like sqs.us-east-1.amazonaws.com, capturing the region.
+	sqsQueueHostPattern = regexp.MustCompile(`^sqs\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+
+	pubSubTopicPattern = regexp.MustCompile(`^projects/[^/]+/topics/[^/]+$`)
+)
+
+// sqsScalingEventSink sends the events to an Amazon SQS queue in the structured content mode of CloudEvents.
+// The events of an HRA are kept in order when the queue is a FIFO queue, as the subject is the message group of the event.
+type sqsScalingEventSink struct {
+	QueueURL string
+	Client   sqsiface.SQSAPI
+}
+
+// newSQSScalingEventSink returns the sink sending to the queue with the credentials from the default chain of the AWS SDK,
+// like the environment variables or IRSA.
+func newSQSScalingEventSink(queueURL string) (*sqsScalingEventSink, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sqs queue url %q: %w", queueURL, err)
+	}
+
+	config := aws.NewConfig()
+	if m := sqsQueueHostPattern.FindStringSubmatch(u.Host); m != nil {
+		config = config.WithRegion(m[1])
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating aws session for sqs: %w", err)
+	}
+
+	return &sqsScalingEventSink{QueueURL: queueURL, Client: sqs.New(sess)}, nil
+}
+
+func (s *sqsScalingEventSink) Send(ctx context.Context, ev CloudEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshaling scaling event: %w", err)
+	}
+
+	in := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(s.QueueURL),
+		MessageBody: aws.String(string(body)),
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			"ce-type": {DataType: aws.String("String"), StringValue: aws.String(ev.Type)},
+		},
+	}
+
+	if strings.HasSuffix(s.QueueURL, ".fifo") {
+		in.MessageGroupId = aws.String(ev.Subject)
+		in.MessageDeduplicationId = aws.String(ev.ID)
+	}
+
+	if _, err := s.Client.SendMessageWithContext(ctx, in); err != nil {
+		return fmt.Errorf("sending scaling event to %s: %w", s.QueueURL, err)
+	}
+
+	return nil
+}
+
+// pubSubScalingEventSink publishes the events to a Google Cloud Pub/Sub topic in the binary content mode
+// of the CloudEvents Pub/Sub protocol binding.
+// The subject is the ordering key of the event, so that the events of an HRA are kept in order by the subscriptions with message ordering.
+type pubSubScalingEventSink struct {
+	// Topic is the name of the topic, like projects/my-project/topics/arc-scaling-events.
+	Topic string
+	// Endpoint defaults to DefaultPubSubEndpoint.
+	Endpoint string
+
+	HTTPClient *http.Client
+}
+
+// newPubSubScalingEventSink returns the sink publishing to the topic with the application default credentials,
+// like the ones of the Workload Identity.
+func newPubSubScalingEventSink(topic string) (*pubSubScalingEventSink, error) {
+	if !pubSubTopicPattern.MatchString(topic) {
+		return nil, fmt.Errorf("pubsub topic %q must be in the form of projects/PROJECT/topics/TOPIC", topic)
+	}
+
+	httpClient, err := google.DefaultClient(context.Background(), pubSubScope)
+	if err != nil {
+		return nil, fmt.Errorf("finding google application default credentials for pubsub: %w", err)
+	}
+
+	return &pubSubScalingEventSink{Topic: topic, HTTPClient: httpClient}, nil
+}
+
+func (s *pubSubScalingEventSink) Send(ctx context.Context, ev CloudEvent) error {
+	attributes := map[string]string{
+		"ce-specversion": ev.SpecVersion,
+		"ce-id":          ev.ID,
+		"ce-source":      ev.Source,
+		"ce-type":        ev.Type,
+		"ce-time":        ev.Time.Format(time.RFC3339Nano),
+		"content-type":   ev.DataContentType,
+	}
+	if ev.Subject != "" {
+		attributes["ce-subject"] = ev.Subject
+	}
+
+	// encoding/json encodes []byte in base64, as required for the data of a Pub/Sub message
+	body, err := json.Marshal(map[string]any{
+		"messages": []map[string]any{
+			{"data": []byte(ev.Data), "attributes": attributes, "orderingKey": ev.Subject},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling scaling event: %w", err)
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultPubSubEndpoint
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/"+s.Topic+":publish", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("publishing scaling event to %s: %w", s.Topic, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("publishing scaling event to %s: unexpected status %s: %s", s.Topic, res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	return nil
+}
@@ -0,0 +1,329 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+)
+
+const (
+	// ScalingEventSinkTypeHTTP sends the scaling events to an HTTP endpoint, in the binary content mode of the
+	// CloudEvents HTTP protocol binding. Knative brokers and most CloudEvents receivers accept it.
+	ScalingEventSinkTypeHTTP = "http"
+
+	// ScalingEventSinkTypeKafka produces the scaling events to a Kafka topic via the REST API of a Kafka bridge,
+	// like the Confluent REST Proxy or the Strimzi Kafka Bridge, in the structured content mode of CloudEvents.
+	// The URL of the sink is the URL of the topic, like http://kafka-bridge:8080/topics/arc-scaling-events.
+	ScalingEventSinkTypeKafka = "kafka"
+
+	// ScalingEventSinkTypeSQS sends the scaling events to an Amazon SQS queue in the structured content mode of CloudEvents,
+	// with the credentials from the default chain of the AWS SDK. The URL of the sink is the URL of the queue.
+	ScalingEventSinkTypeSQS = "sqs"
+
+	// ScalingEventSinkTypePubSub publishes the scaling events to a Google Cloud Pub/Sub topic with the application default credentials.
+	// The URL of the sink is the name of the topic, like projects/my-project/topics/arc-scaling-events.
+	ScalingEventSinkTypePubSub = "pubsub"
+
+	// ScalingEventTypeCapacityReservations is the type of the events emitted by the github webhook server
+	// when a webhook delivery adds or removes capacity reservations of an HRA.
+	ScalingEventTypeCapacityReservations = "dev.summerwind.actions.horizontalrunnerautoscaler.capacityreservations.changed"
+
+	// ScalingEventTypeDesiredReplicas is the type of the events emitted by the controller
+	// when an HRA changes the replicas of its scale target.
+	ScalingEventTypeDesiredReplicas = "dev.summerwind.actions.horizontalrunnerautoscaler.desiredreplicas.changed"
+
+	// ScalingEventTypeDemandSnapshot is the type of the events emitted by the controller on every sync of an HRA,
+	// so that capacity outside of Kubernetes can be scaled off the same demand.
+	ScalingEventTypeDemandSnapshot = "dev.summerwind.actions.horizontalrunnerautoscaler.demand.snapshot"
+
+	// The reasons of the scaling events.
+	ScalingEventReasonCapacityReservationAdded   = "CapacityReservationAdded"
+	ScalingEventReasonCapacityReservationRemoved = "CapacityReservationRemoved"
+	ScalingEventReasonScaledOut                  = "ScaledOut"
+	ScalingEventReasonScaledIn                   = "ScaledIn"
+	ScalingEventReasonDemandSnapshot             = "DemandSnapshot"
+
+	// DefaultScalingEventQueueLimit is the number of scaling events buffered while the sink is slow or unavailable.
+	// Events are dropped once the buffer is full, so that scaling is never blocked by the sink.
+	DefaultScalingEventQueueLimit = 1000
+
+	scalingEventSendTimeout = 10 * time.Second
+)
+
+// scalingEventRetryDelays are the delays between the attempts to send a scaling event.
+var scalingEventRetryDelays = []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+
+// ScalingEvent is the data of a CloudEvent emitted on a scaling decision, for audit and chargeback pipelines.
+type ScalingEvent struct {
+	// Namespace and HorizontalRunnerAutoscaler identify the HRA that scaled.
+	Namespace                  string `json:"namespace"`
+	HorizontalRunnerAutoscaler string `json:"horizontalRunnerAutoscaler"`
+
+	// Reason is one of the ScalingEventReason constants.
+	Reason string `json:"reason"`
+	// Message explains the decision in a human readable form.
+	Message string `json:"message,omitempty"`
+	// Delta is the change in the number of capacity reservations or desired replicas.
+	Delta int `json:"delta"`
+
+	// PreviousReplicas and DesiredReplicas are the replicas of the scale target before and after the change.
+	// They are set only for ScalingEventTypeDesiredReplicas and ScalingEventTypeDemandSnapshot.
+	PreviousReplicas *int `json:"previousReplicas,omitempty"`
+	DesiredReplicas  *int `json:"desiredReplicas,omitempty"`
+	// DemandReplicas is the number of replicas the metrics and the capacity reservations ask for within the min and max replicas,
+	// before the scale down stabilization, the step limits, the cost budget, and the federation are applied.
+	// CapacityReservations is the number of replicas reserved by the capacity reservations of the HRA.
+	// They are set only for ScalingEventTypeDemandSnapshot.
+	DemandReplicas       *int `json:"demandReplicas,omitempty"`
+	CapacityReservations *int `json:"capacityReservations,omitempty"`
+	// Source is the source of the desired replicas, like a metric type, as in the status of the HRA.
+	Source string `json:"source,omitempty"`
+
+	// DeliveryID is the X-GitHub-Delivery of the webhook delivery that triggered the change, if any.
+	DeliveryID string `json:"deliveryID,omitempty"`
+	// Event is the type of the webhook event that triggered the change, if any.
+	Event string `json:"event,omitempty"`
+	// Repository and JobID are the repository and the workflow job the webhook event originates from, if any.
+	Repository string `json:"repository,omitempty"`
+	JobID      int64  `json:"jobID,omitempty"`
+
+	// Time is when the change was made.
+	Time time.Time `json:"-"`
+
+	// eventType is the CloudEvents type of the event.
+	eventType string
+}
+
+// ScalingEventSink delivers the CloudEvents to a destination.
+type ScalingEventSink interface {
+	Send(ctx context.Context, event CloudEvent) error
+}
+
+// NewScalingEventSink returns the sink of the given type sending to url.
+// It returns nil without an error when sinkType is empty, which disables the scaling events.
+func NewScalingEventSink(sinkType, url string) (ScalingEventSink, error) {
+	switch sinkType {
+	case "":
+		return nil, nil
+	case ScalingEventSinkTypeHTTP, ScalingEventSinkTypeKafka, ScalingEventSinkTypeSQS, ScalingEventSinkTypePubSub:
+		if url == "" {
+			return nil, fmt.Errorf("url is required for the %s scaling event sink", sinkType)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scaling event sink type %q", sinkType)
+	}
+
+	switch sinkType {
+	case ScalingEventSinkTypeSQS:
+		return newSQSScalingEventSink(url)
+	case ScalingEventSinkTypePubSub:
+		return newPubSubScalingEventSink(url)
+	default:
+		return &httpScalingEventSink{URL: url, Kafka: sinkType == ScalingEventSinkTypeKafka}, nil
+	}
+}
+
+// CloudEvent is a CloudEvents 1.0 event with a JSON data.
+// See https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// httpScalingEventSink posts the events to an HTTP endpoint, or to a topic of the REST API of a Kafka bridge.
+type httpScalingEventSink struct {
+	URL   string
+	Kafka bool
+
+	// HTTPClient is optional. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+func (s *httpScalingEventSink) Send(ctx context.Context, ev CloudEvent) error {
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+
+	header := http.Header{}
+
+	if s.Kafka {
+		// The subject is the key of the record, so that the events of an HRA are kept in order in a partition
+		body, err = json.Marshal(map[string]any{
+			"records": []map[string]any{
+				{"key": ev.Subject, "value": ev},
+			},
+		})
+		contentType = "application/vnd.kafka.json.v2+json"
+	} else {
+		body = ev.Data
+		contentType = ev.DataContentType
+
+		header.Set("ce-specversion", ev.SpecVersion)
+		header.Set("ce-id", ev.ID)
+		header.Set("ce-source", ev.Source)
+		header.Set("ce-type", ev.Type)
+		header.Set("ce-time", ev.Time.Format(time.RFC3339Nano))
+		if ev.Subject != "" {
+			header.Set("ce-subject", ev.Subject)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("marshaling scaling event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", contentType)
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending scaling event to %s: %w", s.URL, err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("sending scaling event to %s: unexpected status %s", s.URL, res.Status)
+	}
+
+	return nil
+}
+
+// ScalingEventPublisher emits the scaling decisions as CloudEvents to its Sink in the background.
+// A nil publisher emits nothing.
+type ScalingEventPublisher struct {
+	Sink ScalingEventSink
+	Log  logr.Logger
+
+	// Source is the CloudEvents source of the events, identifying the emitting component.
+	Source string
+
+	queue chan CloudEvent
+}
+
+// NewScalingEventPublisher returns the publisher emitting to sink, or nil when sink is nil.
+func NewScalingEventPublisher(sink ScalingEventSink, source string, log logr.Logger) *ScalingEventPublisher {
+	if sink == nil {
+		return nil
+	}
+
+	return &ScalingEventPublisher{
+		Sink:   sink,
+		Source: source,
+		Log:    log,
+		queue:  make(chan CloudEvent, DefaultScalingEventQueueLimit),
+	}
+}
+
+// PublishCapacityReservations emits the change in the capacity reservations of an HRA made by a webhook delivery.
+func (p *ScalingEventPublisher) PublishCapacityReservations(ev ScalingEvent) {
+	ev.eventType = ScalingEventTypeCapacityReservations
+	p.publish(ev)
+}
+
+// PublishDesiredReplicas emits the change in the replicas of the scale target of an HRA.
+func (p *ScalingEventPublisher) PublishDesiredReplicas(ev ScalingEvent) {
+	ev.eventType = ScalingEventTypeDesiredReplicas
+	p.publish(ev)
+}
+
+// PublishDemandSnapshot emits the demand and the desired replicas of an HRA as of a sync.
+func (p *ScalingEventPublisher) PublishDemandSnapshot(ev ScalingEvent) {
+	ev.eventType = ScalingEventTypeDemandSnapshot
+	p.publish(ev)
+}
+
+func (p *ScalingEventPublisher) publish(ev ScalingEvent) {
+	if p == nil {
+		return
+	}
+
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		p.Log.Error(err, "Failed to marshal scaling event")
+		return
+	}
+
+	ce := CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              uuid.NewString(),
+		Source:          p.Source,
+		Type:            ev.eventType,
+		Subject:         fmt.Sprintf("namespaces/%s/horizontalrunnerautoscalers/%s", ev.Namespace, ev.HorizontalRunnerAutoscaler),
+		Time:            ev.Time.UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+
+	select {
+	case p.queue <- ce:
+	default:
+		p.Log.Info("Dropped scaling event as the queue is full. The scaling event sink might be unavailable", "type", ce.Type, "subject", ce.Subject, "reason", ev.Reason)
+	}
+}
+
+// Run sends the published events to the Sink until ctx is done.
+// An event that fails to be sent is retried a few times, and then dropped.
+func (p *ScalingEventPublisher) Run(ctx context.Context) error {
+	p.Log.Info("Starting scaling event publisher", "source", p.Source)
+	defer p.Log.Info("Stopped scaling event publisher")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-p.queue:
+			p.send(ctx, ev)
+		}
+	}
+}
+
+func (p *ScalingEventPublisher) send(ctx context.Context, ev CloudEvent) {
+	for i := 0; ; i++ {
+		sendCtx, cancel := context.WithTimeout(ctx, scalingEventSendTimeout)
+		err := p.Sink.Send(sendCtx, ev)
+		cancel()
+
+		if err == nil {
+			return
+		}
+
+		if i >= len(scalingEventRetryDelays) {
+			p.Log.Error(err, "Dropped scaling event that failed to be sent", "id", ev.ID, "type", ev.Type, "subject", ev.Subject)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(scalingEventRetryDelays[i]):
+		}
+	}
+}
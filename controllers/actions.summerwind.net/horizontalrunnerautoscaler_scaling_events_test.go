@@ -0,0 +1,330 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHTTPScalingEventSink(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	ev := CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              "abc",
+		Source:          "actions-runner-controller/github-webhook-server",
+		Type:            ScalingEventTypeCapacityReservations,
+		Subject:         "namespaces/default/horizontalrunnerautoscalers/example",
+		Time:            time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"delta":1}`),
+	}
+
+	sink, err := NewScalingEventSink(ScalingEventSinkTypeHTTP, srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Equal(t, `{"delta":1}`, string(body))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "1.0", header.Get("ce-specversion"))
+	assert.Equal(t, "abc", header.Get("ce-id"))
+	assert.Equal(t, ScalingEventTypeCapacityReservations, header.Get("ce-type"))
+	assert.Equal(t, "namespaces/default/horizontalrunnerautoscalers/example", header.Get("ce-subject"))
+	assert.Equal(t, "2024-01-02T03:04:05Z", header.Get("ce-time"))
+
+	sink, err = NewScalingEventSink(ScalingEventSinkTypeKafka, srv.URL+"/topics/arc")
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Equal(t, "application/vnd.kafka.json.v2+json", header.Get("Content-Type"))
+	assert.Empty(t, header.Get("ce-id"), "the attributes are in the value of the record in the structured mode")
+
+	var records struct {
+		Records []struct {
+			Key   string     `json:"key"`
+			Value CloudEvent `json:"value"`
+		} `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal(body, &records))
+	require.Len(t, records.Records, 1)
+	assert.Equal(t, ev.Subject, records.Records[0].Key)
+	assert.Equal(t, ev, records.Records[0].Value)
+
+	_, err = NewScalingEventSink(ScalingEventSinkTypeHTTP, "")
+	require.Error(t, err)
+
+	_, err = NewScalingEventSink("nats", srv.URL)
+	require.Error(t, err)
+
+	sink, err = NewScalingEventSink("", "")
+	require.NoError(t, err)
+	require.Nil(t, sink)
+	require.Nil(t, NewScalingEventPublisher(sink, "", logr.Discard()))
+}
+
+type recordingScalingEventSink struct {
+	events chan CloudEvent
+}
+
+func (s *recordingScalingEventSink) Send(ctx context.Context, ev CloudEvent) error {
+	s.events <- ev
+	return nil
+}
+
+func TestScalingEventPublisher(t *testing.T) {
+	sink := &recordingScalingEventSink{events: make(chan CloudEvent, 1)}
+	p := NewScalingEventPublisher(sink, "actions-runner-controller/controller-manager", logr.Discard())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go p.Run(ctx)
+
+	previous, desired := 2, 5
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	p.PublishDesiredReplicas(ScalingEvent{
+		Namespace:                  "default",
+		HorizontalRunnerAutoscaler: "example",
+		Reason:                     ScalingEventReasonScaledOut,
+		Delta:                      3,
+		PreviousReplicas:           &previous,
+		DesiredReplicas:            &desired,
+		Time:                       now,
+	})
+
+	var ev CloudEvent
+	select {
+	case ev = <-sink.events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the scaling event")
+	}
+
+	assert.Equal(t, "1.0", ev.SpecVersion)
+	assert.NotEmpty(t, ev.ID)
+	assert.Equal(t, "actions-runner-controller/controller-manager", ev.Source)
+	assert.Equal(t, ScalingEventTypeDesiredReplicas, ev.Type)
+	assert.Equal(t, "namespaces/default/horizontalrunnerautoscalers/example", ev.Subject)
+	assert.Equal(t, now, ev.Time)
+	assert.JSONEq(t, `{"namespace":"default","horizontalRunnerAutoscaler":"example","reason":"ScaledOut","delta":3,"previousReplicas":2,"desiredReplicas":5}`, string(ev.Data))
+
+	// A nil publisher is a no-op
+	var nilPublisher *ScalingEventPublisher
+	nilPublisher.PublishDesiredReplicas(ScalingEvent{})
+}
+
+func TestBatchScaler_PublishesScalingEvents(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	key := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+	}).Build()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The publisher isn't run, so that the published events are read from its queue
+	events := NewScalingEventPublisher(&recordingScalingEventSink{}, "test", logr.Discard())
+
+	s := newBatchScaler(ctx, c, logr.Discard(), nil)
+	s.interval = 100 * time.Millisecond
+	s.events = events
+
+	log := logr.Discard()
+	add := func(amount int, deliveryID string) ScalingEvent {
+		t.Helper()
+
+		s.Add(&ScaleTarget{
+			HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			},
+			ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Amount: amount, Duration: metav1.Duration{Duration: time.Hour}},
+			Repository:     "example/myrepo",
+			JobID:          1,
+			log:            &log,
+			event:          "workflow_job",
+			deliveryID:     deliveryID,
+		})
+
+		var ce CloudEvent
+		select {
+		case ce = <-events.queue:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the scaling event")
+		}
+
+		require.Equal(t, ScalingEventTypeCapacityReservations, ce.Type)
+
+		var ev ScalingEvent
+		require.NoError(t, json.Unmarshal(ce.Data, &ev))
+		return ev
+	}
+
+	ev := add(1, "delivery-1")
+	assert.Equal(t, ScalingEventReasonCapacityReservationAdded, ev.Reason)
+	assert.Equal(t, 1, ev.Delta)
+	assert.Equal(t, "delivery-1", ev.DeliveryID)
+	assert.Equal(t, "example/myrepo", ev.Repository)
+	assert.Equal(t, int64(1), ev.JobID)
+
+	ev = add(-1, "delivery-2")
+	assert.Equal(t, ScalingEventReasonCapacityReservationRemoved, ev.Reason)
+	assert.Equal(t, -1, ev.Delta)
+	assert.Equal(t, "delivery-2", ev.DeliveryID)
+}
+
+type fakeSQS struct {
+	sqsiface.SQSAPI
+
+	inputs []*sqs.SendMessageInput
+}
+
+func (f *fakeSQS) SendMessageWithContext(ctx aws.Context, in *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	f.inputs = append(f.inputs, in)
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func TestSQSScalingEventSink(t *testing.T) {
+	ev := CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              "abc",
+		Source:          "actions-runner-controller/controller-manager",
+		Type:            ScalingEventTypeDemandSnapshot,
+		Subject:         "namespaces/default/horizontalrunnerautoscalers/example",
+		Time:            time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"delta":1}`),
+	}
+
+	client := &fakeSQS{}
+
+	sink := &sqsScalingEventSink{QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/arc", Client: client}
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	sink.QueueURL += ".fifo"
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	require.Len(t, client.inputs, 2)
+
+	var got CloudEvent
+	require.NoError(t, json.Unmarshal([]byte(aws.StringValue(client.inputs[0].MessageBody)), &got))
+	assert.Equal(t, ev, got)
+	assert.Equal(t, ScalingEventTypeDemandSnapshot, aws.StringValue(client.inputs[0].MessageAttributes["ce-type"].StringValue))
+	assert.Nil(t, client.inputs[0].MessageGroupId, "a standard queue has no message groups")
+
+	assert.Equal(t, ev.Subject, aws.StringValue(client.inputs[1].MessageGroupId))
+	assert.Equal(t, ev.ID, aws.StringValue(client.inputs[1].MessageDeduplicationId))
+
+	s, err := NewScalingEventSink(ScalingEventSinkTypeSQS, "https://sqs.eu-west-1.amazonaws.com/123456789012/arc")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", aws.StringValue(s.(*sqsScalingEventSink).Client.(*sqs.SQS).Config.Region))
+}
+
+func TestPubSubScalingEventSink(t *testing.T) {
+	var (
+		path string
+		body struct {
+			Messages []struct {
+				Data        []byte            `json:"data"`
+				Attributes  map[string]string `json:"attributes"`
+				OrderingKey string            `json:"orderingKey"`
+			} `json:"messages"`
+		}
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"messageIds":["1"]}`))
+	}))
+	defer srv.Close()
+
+	sink := &pubSubScalingEventSink{Topic: "projects/my-project/topics/arc", Endpoint: srv.URL + "/v1/"}
+
+	ev := CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              "abc",
+		Source:          "actions-runner-controller/controller-manager",
+		Type:            ScalingEventTypeDesiredReplicas,
+		Subject:         "namespaces/default/horizontalrunnerautoscalers/example",
+		Time:            time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"delta":1}`),
+	}
+
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Equal(t, "/v1/projects/my-project/topics/arc:publish", path)
+	require.Len(t, body.Messages, 1)
+	assert.Equal(t, `{"delta":1}`, string(body.Messages[0].Data))
+	assert.Equal(t, ev.Subject, body.Messages[0].OrderingKey)
+	assert.Equal(t, map[string]string{
+		"ce-specversion": "1.0",
+		"ce-id":          "abc",
+		"ce-source":      "actions-runner-controller/controller-manager",
+		"ce-type":        ScalingEventTypeDesiredReplicas,
+		"ce-subject":     "namespaces/default/horizontalrunnerautoscalers/example",
+		"ce-time":        "2024-01-02T03:04:05Z",
+		"content-type":   "application/json",
+	}, body.Messages[0].Attributes)
+
+	_, err := NewScalingEventSink(ScalingEventSinkTypePubSub, "arc")
+	require.Error(t, err)
+}
+
+func TestPublishDemandSnapshot(t *testing.T) {
+	now := time.Now()
+
+	events := NewScalingEventPublisher(&recordingScalingEventSink{}, "test", logr.Discard())
+
+	r := &HorizontalRunnerAutoscalerReconciler{ScalingEvents: events, DemandSnapshots: true}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				{Replicas: 2, ExpirationTime: metav1.NewTime(now.Add(time.Minute))},
+				{Replicas: 1, ExpirationTime: metav1.NewTime(now.Add(-time.Minute))},
+			},
+		},
+	}
+
+	r.publishDemandSnapshot(hra, 3, 6, 5, "", now)
+
+	ce := <-events.queue
+	assert.Equal(t, ScalingEventTypeDemandSnapshot, ce.Type)
+	assert.Equal(t, "namespaces/default/horizontalrunnerautoscalers/example", ce.Subject)
+	assert.JSONEq(t, `{"namespace":"default","horizontalRunnerAutoscaler":"example","reason":"DemandSnapshot","delta":2,"previousReplicas":3,"desiredReplicas":5,"demandReplicas":6,"capacityReservations":2,"source":"`+sourceOrDefault("")+`"}`, string(ce.Data))
+}
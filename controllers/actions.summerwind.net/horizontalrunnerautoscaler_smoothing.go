@@ -0,0 +1,56 @@
+package actionssummerwindnet
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// busyFractionAverages keeps the exponentially-weighted moving average of the fraction of busy runners per HRA.
+// It's kept in memory rather than in the HRA status, as every status update triggers another reconciliation,
+// and starts over from the latest sample after the controller restarts.
+type busyFractionAverages struct {
+	mu       sync.Mutex
+	averages map[types.NamespacedName]busyFractionAverage
+}
+
+type busyFractionAverage struct {
+	value float64
+	time  time.Time
+}
+
+// observe adds the sample taken at now to the average of the HRA, and returns the updated average.
+// The weight of the previous average halves every halfLife, regardless of how often samples are taken.
+func (a *busyFractionAverages) observe(key types.NamespacedName, halfLife time.Duration, sample float64, now time.Time) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.averages == nil {
+		a.averages = map[types.NamespacedName]busyFractionAverage{}
+	}
+
+	avg := sample
+
+	if prev, ok := a.averages[key]; ok && halfLife > 0 {
+		elapsed := now.Sub(prev.time)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+
+		weight := math.Exp2(-float64(elapsed) / float64(halfLife))
+		avg = weight*prev.value + (1-weight)*sample
+	}
+
+	a.averages[key] = busyFractionAverage{value: avg, time: now}
+
+	return avg
+}
+
+func (a *busyFractionAverages) forget(key types.NamespacedName) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.averages, key)
+}
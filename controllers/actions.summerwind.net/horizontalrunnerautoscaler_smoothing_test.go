@@ -0,0 +1,33 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestBusyFractionAverages(t *testing.T) {
+	var (
+		a        busyFractionAverages
+		key      = types.NamespacedName{Namespace: "default", Name: "hra"}
+		other    = types.NamespacedName{Namespace: "default", Name: "other"}
+		halfLife = time.Minute
+		t0       = time.Now()
+	)
+
+	require.Equal(t, 1.0, a.observe(key, halfLife, 1.0, t0), "the first sample is taken as is")
+
+	require.InDelta(t, 0.5, a.observe(key, halfLife, 0, t0.Add(time.Minute)), 1e-9, "the previous average weighs half after a half-life")
+
+	require.InDelta(t, 0.5, a.observe(key, halfLife, 0, t0.Add(time.Minute)), 1e-9, "a sample taken at the same time doesn't move the average")
+
+	require.InDelta(t, 0.125, a.observe(key, halfLife, 0, t0.Add(3*time.Minute)), 1e-9)
+
+	require.Equal(t, 0.8, a.observe(other, halfLife, 0.8, t0), "averages are kept per HRA")
+
+	a.forget(key)
+
+	require.Equal(t, 0.9, a.observe(key, halfLife, 0.9, t0.Add(4*time.Minute)), "the average starts over once forgotten")
+}
@@ -0,0 +1,61 @@
+package actionssummerwindnet
+
+import (
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxDesiredReplicasHistory bounds the size of status.desiredReplicasHistory,
+// in case the HRA is reconciled very frequently with a long stabilization window.
+const maxDesiredReplicasHistory = 100
+
+// stabilizeScaleDown records the desired replicas computed at now, and returns the highest desired replicas
+// recorded within the scale down stabilization window, along with the updated history.
+// It returns the desired replicas as is and no history when the window isn't configured.
+func stabilizeScaleDown(hra v1alpha1.HorizontalRunnerAutoscaler, desired int, now time.Time) (int, []v1alpha1.DesiredReplicasRecord) {
+	if hra.Spec.ScaleDownStabilizationSeconds == nil || *hra.Spec.ScaleDownStabilizationSeconds <= 0 {
+		return desired, nil
+	}
+
+	window := time.Duration(*hra.Spec.ScaleDownStabilizationSeconds) * time.Second
+	windowStart := now.Add(-window)
+
+	var history []v1alpha1.DesiredReplicasRecord
+
+	for _, r := range hra.Status.DesiredReplicasHistory {
+		if r.Time.Time.After(windowStart) && !r.Time.Time.After(now) {
+			history = append(history, r)
+		}
+	}
+
+	if last := len(history) - 1; last >= 0 && history[last].Replicas == desired {
+		// Only the latest time a number of replicas was computed matters for the window.
+		// It's refreshed at a fraction of the window, rather than on every reconciliation,
+		// as every status update triggers another reconciliation.
+		if now.Sub(history[last].Time.Time) >= window/10 {
+			history[last].Time = metav1.Time{Time: now}
+		}
+	} else {
+		history = append(history, v1alpha1.DesiredReplicasRecord{Time: metav1.Time{Time: now}, Replicas: desired})
+	}
+
+	if len(history) > maxDesiredReplicasHistory {
+		history = history[len(history)-maxDesiredReplicasHistory:]
+	}
+
+	stabilized := desired
+	for _, r := range history {
+		if r.Replicas > stabilized {
+			stabilized = r.Replicas
+		}
+	}
+
+	// The window must not keep more replicas than currently allowed, like after maxReplicas is lowered
+	if hra.Spec.MaxReplicas != nil && stabilized > *hra.Spec.MaxReplicas {
+		stabilized = *hra.Spec.MaxReplicas
+	}
+
+	return stabilized, history
+}
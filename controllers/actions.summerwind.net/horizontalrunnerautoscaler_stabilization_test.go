@@ -0,0 +1,95 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStabilizeScaleDown(t *testing.T) {
+	now := time.Now()
+
+	record := func(ago time.Duration, replicas int) v1alpha1.DesiredReplicasRecord {
+		return v1alpha1.DesiredReplicasRecord{Time: metav1.NewTime(now.Add(-ago)), Replicas: replicas}
+	}
+
+	newHRA := func(window *int, max *int, history ...v1alpha1.DesiredReplicasRecord) v1alpha1.HorizontalRunnerAutoscaler {
+		return v1alpha1.HorizontalRunnerAutoscaler{
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleDownStabilizationSeconds: window,
+				MaxReplicas:                   max,
+			},
+			Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicasHistory: history,
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		hra         v1alpha1.HorizontalRunnerAutoscaler
+		desired     int
+		want        int
+		wantHistory []v1alpha1.DesiredReplicasRecord
+	}{
+		{
+			name:    "disabled",
+			hra:     newHRA(nil, nil, record(time.Minute, 10)),
+			desired: 2,
+			want:    2,
+		},
+		{
+			name:        "scale down is held by the highest replicas within the window",
+			hra:         newHRA(intPtr(300), nil, record(4*time.Minute, 5), record(2*time.Minute, 8), record(time.Minute, 3)),
+			desired:     2,
+			want:        8,
+			wantHistory: []v1alpha1.DesiredReplicasRecord{record(4*time.Minute, 5), record(2*time.Minute, 8), record(time.Minute, 3), record(0, 2)},
+		},
+		{
+			name:        "records older than the window are dropped",
+			hra:         newHRA(intPtr(300), nil, record(10*time.Minute, 8), record(time.Minute, 3)),
+			desired:     2,
+			want:        3,
+			wantHistory: []v1alpha1.DesiredReplicasRecord{record(time.Minute, 3), record(0, 2)},
+		},
+		{
+			name:        "scale up is immediate",
+			hra:         newHRA(intPtr(300), nil, record(time.Minute, 3)),
+			desired:     6,
+			want:        6,
+			wantHistory: []v1alpha1.DesiredReplicasRecord{record(time.Minute, 3), record(0, 6)},
+		},
+		{
+			name:        "repeated replicas are refreshed only after a fraction of the window",
+			hra:         newHRA(intPtr(300), nil, record(10*time.Second, 3)),
+			desired:     3,
+			want:        3,
+			wantHistory: []v1alpha1.DesiredReplicasRecord{record(10*time.Second, 3)},
+		},
+		{
+			name:        "repeated replicas are refreshed",
+			hra:         newHRA(intPtr(300), nil, record(time.Minute, 3)),
+			desired:     3,
+			want:        3,
+			wantHistory: []v1alpha1.DesiredReplicasRecord{record(0, 3)},
+		},
+		{
+			name:        "capped by maxReplicas",
+			hra:         newHRA(intPtr(300), intPtr(4), record(time.Minute, 8)),
+			desired:     2,
+			want:        4,
+			wantHistory: []v1alpha1.DesiredReplicasRecord{record(time.Minute, 8), record(0, 2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, history := stabilizeScaleDown(tt.hra, tt.desired, now)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantHistory, history)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package actionssummerwindnet
+
+import (
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+// limitScaleStep limits the change from the current desired replicas of the HRA to the desired replicas computed at now,
+// to maxScaleUpReplicasPerSync or maxScaleDownReplicasPerSync, once per sync period.
+// It returns the replicas to scale to, and how long to wait before taking the next step, or zero when there's no further step.
+func limitScaleStep(hra v1alpha1.HorizontalRunnerAutoscaler, desired int, syncPeriod time.Duration, now time.Time) (int, time.Duration) {
+	if hra.Status.DesiredReplicas == nil {
+		return desired, 0
+	}
+
+	current := *hra.Status.DesiredReplicas
+
+	var max *int
+	switch {
+	case desired > current:
+		max = hra.Spec.MaxScaleUpReplicasPerSync
+	case desired < current:
+		max = hra.Spec.MaxScaleDownReplicasPerSync
+	}
+
+	if max == nil || *max <= 0 {
+		return desired, 0
+	}
+
+	// Every change updates the status, which triggers another reconciliation right away.
+	// The next step waits for the rest of the sync period instead.
+	if last := hra.Status.LastScaleTime; last != nil {
+		if next := last.Add(syncPeriod); now.Before(next) {
+			return current, next.Sub(now)
+		}
+	}
+
+	switch {
+	case desired > current+*max:
+		return current + *max, syncPeriod
+	case desired < current-*max:
+		return current - *max, syncPeriod
+	}
+
+	return desired, 0
+}
@@ -0,0 +1,101 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLimitScaleStep(t *testing.T) {
+	now := time.Now()
+	syncPeriod := time.Minute
+
+	newHRA := func(current *int, lastScaleAgo *time.Duration, up, down *int) v1alpha1.HorizontalRunnerAutoscaler {
+		hra := v1alpha1.HorizontalRunnerAutoscaler{
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MaxScaleUpReplicasPerSync:   up,
+				MaxScaleDownReplicasPerSync: down,
+			},
+			Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: current,
+			},
+		}
+		if lastScaleAgo != nil {
+			hra.Status.LastScaleTime = &metav1.Time{Time: now.Add(-*lastScaleAgo)}
+		}
+		return hra
+	}
+
+	durationPtr := func(d time.Duration) *time.Duration { return &d }
+
+	tests := []struct {
+		name          string
+		hra           v1alpha1.HorizontalRunnerAutoscaler
+		desired       int
+		want          int
+		wantNextAfter time.Duration
+	}{
+		{
+			name:    "unlimited",
+			hra:     newHRA(intPtr(2), nil, nil, nil),
+			desired: 50,
+			want:    50,
+		},
+		{
+			name:    "first sync",
+			hra:     newHRA(nil, nil, intPtr(5), nil),
+			desired: 50,
+			want:    50,
+		},
+		{
+			name:          "scale up is limited",
+			hra:           newHRA(intPtr(2), durationPtr(2*time.Minute), intPtr(5), nil),
+			desired:       50,
+			want:          7,
+			wantNextAfter: syncPeriod,
+		},
+		{
+			name:          "scale up within the sync period is held",
+			hra:           newHRA(intPtr(7), durationPtr(10*time.Second), intPtr(5), nil),
+			desired:       50,
+			want:          7,
+			wantNextAfter: 50 * time.Second,
+		},
+		{
+			name:    "last step",
+			hra:     newHRA(intPtr(47), durationPtr(time.Minute), intPtr(5), nil),
+			desired: 50,
+			want:    50,
+		},
+		{
+			name:          "scale down is limited",
+			hra:           newHRA(intPtr(50), nil, intPtr(5), intPtr(10)),
+			desired:       2,
+			want:          40,
+			wantNextAfter: syncPeriod,
+		},
+		{
+			name:    "scale down limit doesn't apply to scale up",
+			hra:     newHRA(intPtr(2), durationPtr(10*time.Second), nil, intPtr(10)),
+			desired: 50,
+			want:    50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, nextAfter := limitScaleStep(tt.hra, tt.desired, syncPeriod, now)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantNextAfter, nextAfter)
+		})
+	}
+}
+
+func TestNextEvaluationAfter(t *testing.T) {
+	require.Equal(t, time.Minute, nextEvaluationAfter(0, time.Minute))
+	require.Equal(t, 10*time.Second, nextEvaluationAfter(10*time.Second, time.Minute))
+	require.Equal(t, time.Minute, nextEvaluationAfter(2*time.Minute, time.Minute))
+}
@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// ExternalMetricsAPIPath is the path of the external.metrics.k8s.io/v1beta1 API served by ExternalMetricsHandler.
+	// Register the handler for both this path and the path with the trailing slash.
+	ExternalMetricsAPIPath = "/apis/external.metrics.k8s.io/v1beta1"
+
+	externalMetricsGroupVersion = "external.metrics.k8s.io/v1beta1"
+
+	// externalMetricPrefix is the prefix of the metrics exposed via the External Metrics API.
+	// Only the values computed for HRAs are exposed, not the metrics of the controller itself.
+	externalMetricPrefix = "horizontalrunnerautoscaler_"
+)
+
+// ExternalMetricValueList mirrors k8s.io/metrics/pkg/apis/external_metrics/v1beta1.ExternalMetricValueList.
+type ExternalMetricValueList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ExternalMetricValue `json:"items"`
+}
+
+// ExternalMetricValue mirrors k8s.io/metrics/pkg/apis/external_metrics/v1beta1.ExternalMetricValue.
+type ExternalMetricValue struct {
+	metav1.TypeMeta `json:",inline"`
+
+	MetricName   string            `json:"metricName"`
+	MetricLabels map[string]string `json:"metricLabels"`
+	Timestamp    metav1.Time       `json:"timestamp"`
+	Value        resource.Quantity `json:"value"`
+}
+
+// ExternalMetricsHandler serves the HRA metrics, like the number of queued workflow jobs and the desired replicas,
+// via the Kubernetes External Metrics API, so that a standard HPA or KEDA can scale on the same data as HRA.
+//
+// The metric name is the name of the Prometheus metric, like horizontalrunnerautoscaler_workflow_jobs_queued,
+// and the label selector of the metric selects the Prometheus labels, like horizontalrunnerautoscaler=myhra.
+// A metric is only visible from the namespace of the HRA it is computed for.
+//
+// Only the requests proxied by the API aggregation layer are served, and the user of each request is authorized with
+// a SubjectAccessReview for the get verb on the metric in the external.metrics.k8s.io group, like any other external metrics adapter.
+// Only the leader computes the values, so the other replicas respond with 503 Service Unavailable.
+// LeaderPodLabeler labels the pod of the leader, so that the Service the APIService points to only selects the leader.
+type ExternalMetricsHandler struct {
+	// Gatherer defaults to the controller-runtime metrics registry.
+	Gatherer prometheus.Gatherer
+
+	Authenticator Authenticator
+
+	// Client creates the SubjectAccessReviews.
+	Client client.Client
+
+	// Elected is closed once this replica becomes the leader, like manager.Manager.Elected.
+	Elected <-chan struct{}
+}
+
+func (h *ExternalMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	spec, err := h.Authenticator.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	select {
+	case <-h.Elected:
+	default:
+		http.Error(w, "not the leader", http.StatusServiceUnavailable)
+		return
+	}
+
+	gatherer := h.Gatherer
+	if gatherer == nil {
+		gatherer = metrics.Registry
+	}
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, ExternalMetricsAPIPath), "/")
+
+	// namespaces/<namespace>/<metric>
+	parts := strings.Split(path, "/")
+	if path != "" && (len(parts) != 3 || parts[0] != "namespaces") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var namespace, metric string
+	if path != "" {
+		namespace, metric = parts[1], parts[2]
+	}
+
+	allowed, err := authorize(r.Context(), h.Client, spec, namespace, metric)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !allowed {
+		http.Error(w, fmt.Sprintf("user %q cannot get %s in namespace %q", spec.User, metric, namespace), http.StatusForbidden)
+		return
+	}
+
+	if path == "" {
+		writeJSON(w, externalMetricsResourceList(families))
+		return
+	}
+
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, externalMetricValues(families, namespace, metric, selector, time.Now()))
+}
+
+func externalMetricsResourceList(families []*dto.MetricFamily) *metav1.APIResourceList {
+	list := &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: externalMetricsGroupVersion,
+		APIResources: []metav1.APIResource{},
+	}
+
+	for _, f := range families {
+		if !strings.HasPrefix(f.GetName(), externalMetricPrefix) {
+			continue
+		}
+
+		list.APIResources = append(list.APIResources, metav1.APIResource{
+			Name:       f.GetName(),
+			Namespaced: true,
+			Kind:       "ExternalMetricValueList",
+			Verbs:      []string{"get"},
+		})
+	}
+
+	sort.Slice(list.APIResources, func(i, j int) bool {
+		return list.APIResources[i].Name < list.APIResources[j].Name
+	})
+
+	return list
+}
+
+func externalMetricValues(families []*dto.MetricFamily, namespace, name string, selector labels.Selector, now time.Time) *ExternalMetricValueList {
+	list := &ExternalMetricValueList{
+		TypeMeta: metav1.TypeMeta{Kind: "ExternalMetricValueList", APIVersion: externalMetricsGroupVersion},
+		Items:    []ExternalMetricValue{},
+	}
+
+	if !strings.HasPrefix(name, externalMetricPrefix) {
+		return list
+	}
+
+	for _, f := range families {
+		if f.GetName() != name || f.GetType() != dto.MetricType_GAUGE {
+			continue
+		}
+
+		for _, m := range f.GetMetric() {
+			metricLabels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				metricLabels[l.GetName()] = l.GetValue()
+			}
+
+			if metricLabels[hraNamespace] != namespace || !selector.Matches(labels.Set(metricLabels)) {
+				continue
+			}
+
+			v := m.GetGauge().GetValue()
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+
+			list.Items = append(list.Items, ExternalMetricValue{
+				MetricName:   name,
+				MetricLabels: metricLabels,
+				Timestamp:    metav1.NewTime(now),
+				Value:        *resource.NewMilliQuantity(int64(math.Round(v*1000)), resource.DecimalSI),
+			})
+		}
+	}
+
+	return list
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,251 @@
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// extensionAPIServerAuthentication is the ConfigMap in kube-system where the API server publishes how
+	// it authenticates itself when it proxies the requests of aggregated APIs, like the External Metrics API.
+	extensionAPIServerAuthentication = "extension-apiserver-authentication"
+
+	externalMetricsGroup   = "external.metrics.k8s.io"
+	externalMetricsVersion = "v1beta1"
+
+	// DefaultRequestHeaderReloadInterval is how often ReloadingRequestHeaderAuthenticator re-reads the request header authentication settings.
+	DefaultRequestHeaderReloadInterval = time.Minute
+)
+
+// Authenticator returns the user a request is made for.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*authorizationv1.SubjectAccessReviewSpec, error)
+}
+
+// RequestClientCert makes a TLS server ask for, but not require, a client certificate, so that
+// RequestHeaderAuthenticator can verify the API server proxying a request without affecting the admission webhooks
+// served on the same port.
+func RequestClientCert(c *tls.Config) {
+	c.ClientAuth = tls.RequestClientCert
+}
+
+// RequestHeaderAuthenticator authenticates the requests proxied by the API aggregation layer.
+// The API server presents a client certificate signed by the request header CA and passes the authenticated user
+// in the request headers, the same way as for any other aggregated API server.
+type RequestHeaderAuthenticator struct {
+	ClientCAs *x509.CertPool
+	// AllowedNames are the allowed common names of the client certificate. Any name is allowed if empty.
+	AllowedNames        []string
+	UsernameHeaders     []string
+	GroupHeaders        []string
+	ExtraHeaderPrefixes []string
+}
+
+// NewRequestHeaderAuthenticator reads the request header authentication settings the API server publishes
+// in the kube-system/extension-apiserver-authentication ConfigMap.
+func NewRequestHeaderAuthenticator(ctx context.Context, c client.Reader) (*RequestHeaderAuthenticator, error) {
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "kube-system", Name: extensionAPIServerAuthentication}, &cm); err != nil {
+		return nil, fmt.Errorf("getting configmap kube-system/%s: %w", extensionAPIServerAuthentication, err)
+	}
+
+	ca := cm.Data["requestheader-client-ca-file"]
+	if ca == "" {
+		return nil, fmt.Errorf("configmap kube-system/%s has no requestheader-client-ca-file", extensionAPIServerAuthentication)
+	}
+
+	a := &RequestHeaderAuthenticator{ClientCAs: x509.NewCertPool()}
+	if !a.ClientCAs.AppendCertsFromPEM([]byte(ca)) {
+		return nil, fmt.Errorf("configmap kube-system/%s has no valid certificate in requestheader-client-ca-file", extensionAPIServerAuthentication)
+	}
+
+	for key, v := range map[string]*[]string{
+		"requestheader-allowed-names":        &a.AllowedNames,
+		"requestheader-username-headers":     &a.UsernameHeaders,
+		"requestheader-group-headers":        &a.GroupHeaders,
+		"requestheader-extra-headers-prefix": &a.ExtraHeaderPrefixes,
+	} {
+		if s := cm.Data[key]; s != "" {
+			if err := json.Unmarshal([]byte(s), v); err != nil {
+				return nil, fmt.Errorf("parsing %s of configmap kube-system/%s: %w", key, extensionAPIServerAuthentication, err)
+			}
+		}
+	}
+
+	if len(a.UsernameHeaders) == 0 {
+		return nil, fmt.Errorf("configmap kube-system/%s has no requestheader-username-headers", extensionAPIServerAuthentication)
+	}
+
+	return a, nil
+}
+
+// Authenticate returns the user the API server authenticated the proxied request for.
+func (a *RequestHeaderAuthenticator) Authenticate(r *http.Request) (*authorizationv1.SubjectAccessReviewSpec, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, errors.New("no client certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+
+	cert := r.TLS.PeerCertificates[0]
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         a.ClientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return nil, fmt.Errorf("verifying client certificate: %w", err)
+	}
+
+	if len(a.AllowedNames) > 0 && !slices.Contains(a.AllowedNames, cert.Subject.CommonName) {
+		return nil, fmt.Errorf("client certificate common name %q is not allowed", cert.Subject.CommonName)
+	}
+
+	spec := &authorizationv1.SubjectAccessReviewSpec{}
+
+	for _, h := range a.UsernameHeaders {
+		if spec.User = r.Header.Get(h); spec.User != "" {
+			break
+		}
+	}
+
+	if spec.User == "" {
+		return nil, errors.New("no user in the request headers")
+	}
+
+	for _, h := range a.GroupHeaders {
+		spec.Groups = append(spec.Groups, r.Header.Values(h)...)
+	}
+
+	for name, values := range r.Header {
+		for _, prefix := range a.ExtraHeaderPrefixes {
+			if len(name) <= len(prefix) || !strings.EqualFold(name[:len(prefix)], prefix) {
+				continue
+			}
+
+			key, err := url.PathUnescape(strings.ToLower(name[len(prefix):]))
+			if err != nil {
+				key = strings.ToLower(name[len(prefix):])
+			}
+
+			if spec.Extra == nil {
+				spec.Extra = map[string]authorizationv1.ExtraValue{}
+			}
+			spec.Extra[key] = append(spec.Extra[key], values...)
+		}
+	}
+
+	return spec, nil
+}
+
+// ReloadingRequestHeaderAuthenticator is a RequestHeaderAuthenticator that re-reads the settings the API server publishes
+// in the kube-system/extension-apiserver-authentication ConfigMap every Interval, so that a rotated request header client CA
+// is trusted without restarting the controller.
+//
+// It implements manager.Runnable and runs on every replica, as every replica serves the admission webhooks on the same port.
+type ReloadingRequestHeaderAuthenticator struct {
+	Reader   client.Reader
+	Interval time.Duration
+	Log      logr.Logger
+
+	mu      sync.RWMutex
+	current *RequestHeaderAuthenticator
+}
+
+// NewReloadingRequestHeaderAuthenticator reads the request header authentication settings once,
+// so that the controller fails to start when the API server doesn't publish them.
+func NewReloadingRequestHeaderAuthenticator(ctx context.Context, c client.Reader, log logr.Logger) (*ReloadingRequestHeaderAuthenticator, error) {
+	current, err := NewRequestHeaderAuthenticator(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReloadingRequestHeaderAuthenticator{
+		Reader:  c,
+		Log:     log,
+		current: current,
+	}, nil
+}
+
+func (a *ReloadingRequestHeaderAuthenticator) Authenticate(r *http.Request) (*authorizationv1.SubjectAccessReviewSpec, error) {
+	a.mu.RLock()
+	current := a.current
+	a.mu.RUnlock()
+
+	return current.Authenticate(r)
+}
+
+func (a *ReloadingRequestHeaderAuthenticator) NeedLeaderElection() bool {
+	return false
+}
+
+func (a *ReloadingRequestHeaderAuthenticator) Start(ctx context.Context) error {
+	interval := a.Interval
+	if interval == 0 {
+		interval = DefaultRequestHeaderReloadInterval
+	}
+
+	wait.UntilWithContext(ctx, a.reload, interval)
+
+	return nil
+}
+
+// reload replaces the settings with the ones currently published by the API server.
+// The previous settings are kept when they can't be read, so that a transient error doesn't reject every request.
+func (a *ReloadingRequestHeaderAuthenticator) reload(ctx context.Context) {
+	next, err := NewRequestHeaderAuthenticator(ctx, a.Reader)
+	if err != nil {
+		a.Log.Error(err, "Failed to reload the request header authentication settings. Keeping the previous ones")
+		return
+	}
+
+	a.mu.Lock()
+	a.current = next
+	a.mu.Unlock()
+}
+
+// authorize checks that the user can get the metric in the namespace via the External Metrics API with a SubjectAccessReview,
+// so that the metrics are subject to the same RBAC as if they were served by any other external metrics adapter.
+func authorize(ctx context.Context, c client.Client, spec *authorizationv1.SubjectAccessReviewSpec, namespace, metric string) (bool, error) {
+	sar := &authorizationv1.SubjectAccessReview{Spec: *spec}
+
+	if metric == "" {
+		sar.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+			Path: ExternalMetricsAPIPath,
+			Verb: "get",
+		}
+	} else {
+		sar.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace: namespace,
+			Verb:      "get",
+			Group:     externalMetricsGroup,
+			Version:   externalMetricsVersion,
+			Resource:  metric,
+		}
+	}
+
+	if err := c.Create(ctx, sar); err != nil {
+		return false, fmt.Errorf("creating subjectaccessreview: %w", err)
+	}
+
+	return sar.Status.Allowed, nil
+}
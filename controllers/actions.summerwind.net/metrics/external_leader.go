@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// LabelKeyExternalMetricsLeader is set to "true" on the pod of the leader by LeaderPodLabeler,
+	// so that the Service of the External Metrics API selects the only replica that computes the values.
+	LabelKeyExternalMetricsLeader = "actions-runner-controller/external-metrics-leader"
+
+	leaderPodLabelRetryInterval = 5 * time.Second
+)
+
+// LeaderPodLabeler labels the pod of this replica with LabelKeyExternalMetricsLeader once it becomes the leader.
+// The label is removed on start, in case the container of a former leader was restarted in the same pod.
+// The label doesn't need to be removed when the leadership is lost, because the controller exits then.
+//
+// It implements manager.Runnable and runs on every replica.
+type LeaderPodLabeler struct {
+	Client    client.Client
+	Log       logr.Logger
+	Namespace string
+	Name      string
+
+	// Elected is closed once this replica becomes the leader, like manager.Manager.Elected.
+	Elected <-chan struct{}
+}
+
+func (l *LeaderPodLabeler) NeedLeaderElection() bool {
+	return false
+}
+
+func (l *LeaderPodLabeler) Start(ctx context.Context) error {
+	// label only fails once the context is done
+	if err := l.label(ctx, nil); err != nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-l.Elected:
+	}
+
+	leader := "true"
+	if err := l.label(ctx, &leader); err != nil {
+		return nil
+	}
+
+	l.Log.Info("Labeled the pod of the leader for the external metrics API", "pod", types.NamespacedName{Namespace: l.Namespace, Name: l.Name})
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// label sets the label to the value, or removes it when the value is nil. It retries until it succeeds or the context is done.
+func (l *LeaderPodLabeler) label(ctx context.Context, value *string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]*string{LabelKeyExternalMetricsLeader: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: l.Namespace, Name: l.Name}}
+
+	return wait.PollUntilContextCancel(ctx, leaderPodLabelRetryInterval, true, func(ctx context.Context) (bool, error) {
+		if err := l.Client.Patch(ctx, pod, client.RawPatch(types.MergePatchType, patch)); err != nil {
+			l.Log.Error(err, "Failed to patch the leader label of the pod. Retrying", "pod", types.NamespacedName{Namespace: l.Namespace, Name: l.Name})
+			return false, nil
+		}
+		return true, nil
+	})
+}
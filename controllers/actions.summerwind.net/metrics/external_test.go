@@ -0,0 +1,337 @@
+package metrics
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestExternalMetricsHandler(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	queued := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "horizontalrunnerautoscaler_workflow_jobs_queued"}, []string{hraName, hraNamespace})
+	other := prometheus.NewGauge(prometheus.GaugeOpts{Name: "controller_runtime_other"})
+	reg.MustRegister(queued, other)
+
+	queued.With(prometheus.Labels{hraName: "a", hraNamespace: "default"}).Set(3)
+	queued.With(prometheus.Labels{hraName: "b", hraNamespace: "default"}).Set(0.5)
+	queued.With(prometheus.Labels{hraName: "a", hraNamespace: "team-a"}).Set(7)
+
+	ca, clientCert := newTestClientCert(t)
+
+	elected := make(chan struct{})
+	close(elected)
+
+	h := &ExternalMetricsHandler{
+		Gatherer: reg,
+		Authenticator: &RequestHeaderAuthenticator{
+			ClientCAs:       ca,
+			AllowedNames:    []string{"front-proxy-client"},
+			UsernameHeaders: []string{"X-Remote-User"},
+			GroupHeaders:    []string{"X-Remote-Group"},
+		},
+		Client:  newTestSubjectAccessReviewClient("system:serviceaccount:kube-system:horizontal-pod-autoscaler"),
+		Elected: elected,
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}}
+		req.Header.Set("X-Remote-User", "system:serviceaccount:kube-system:horizontal-pod-autoscaler")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(ExternalMetricsAPIPath)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resources metav1.APIResourceList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resources))
+	require.Len(t, resources.APIResources, 1)
+	require.Equal(t, "horizontalrunnerautoscaler_workflow_jobs_queued", resources.APIResources[0].Name)
+
+	rec = get(ExternalMetricsAPIPath + "/namespaces/default/horizontalrunnerautoscaler_workflow_jobs_queued?labelSelector=horizontalrunnerautoscaler%3Da")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var values ExternalMetricValueList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &values))
+	require.Len(t, values.Items, 1)
+	require.Equal(t, "3", values.Items[0].Value.String())
+	require.Equal(t, "a", values.Items[0].MetricLabels[hraName])
+
+	// Without a selector, only the metrics of the requested namespace are returned
+	rec = get(ExternalMetricsAPIPath + "/namespaces/default/horizontalrunnerautoscaler_workflow_jobs_queued")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &values))
+	require.Len(t, values.Items, 2)
+
+	// Metrics other than the HRA ones are never exposed
+	rec = get(ExternalMetricsAPIPath + "/namespaces/default/controller_runtime_other")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &values))
+	require.Empty(t, values.Items)
+
+	require.Equal(t, http.StatusBadRequest, get(ExternalMetricsAPIPath+"/namespaces/default/horizontalrunnerautoscaler_workflow_jobs_queued?labelSelector=%3D%3D").Code)
+	require.Equal(t, http.StatusNotFound, get(ExternalMetricsAPIPath+"/foo").Code)
+}
+
+func TestExternalMetricsHandler_Auth(t *testing.T) {
+	ca, clientCert := newTestClientCert(t)
+	_, untrustedCert := newTestClientCert(t)
+
+	elected := make(chan struct{})
+
+	authenticator := &RequestHeaderAuthenticator{
+		ClientCAs:       ca,
+		UsernameHeaders: []string{"X-Remote-User"},
+	}
+
+	h := &ExternalMetricsHandler{
+		Gatherer:      prometheus.NewRegistry(),
+		Authenticator: authenticator,
+		Client:        newTestSubjectAccessReviewClient("allowed"),
+		Elected:       elected,
+	}
+
+	get := func(cert *x509.Certificate, user string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, ExternalMetricsAPIPath+"/namespaces/default/horizontalrunnerautoscaler_workflow_jobs_queued", nil)
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		}
+		if user != "" {
+			req.Header.Set("X-Remote-User", user)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, get(nil, "allowed"))
+	require.Equal(t, http.StatusUnauthorized, get(untrustedCert, "allowed"))
+	require.Equal(t, http.StatusUnauthorized, get(clientCert, ""))
+
+	// Only the leader has the values
+	require.Equal(t, http.StatusServiceUnavailable, get(clientCert, "allowed"))
+
+	close(elected)
+
+	require.Equal(t, http.StatusForbidden, get(clientCert, "denied"))
+	require.Equal(t, http.StatusOK, get(clientCert, "allowed"))
+
+	authenticator.AllowedNames = []string{"other"}
+	require.Equal(t, http.StatusUnauthorized, get(clientCert, "allowed"))
+}
+
+func TestNewRequestHeaderAuthenticator(t *testing.T) {
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: newTestCA(t).cert.Raw})
+
+	c := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "extension-apiserver-authentication"},
+		Data: map[string]string{
+			"requestheader-client-ca-file":       string(caPEM),
+			"requestheader-allowed-names":        `["front-proxy-client"]`,
+			"requestheader-username-headers":     `["X-Remote-User"]`,
+			"requestheader-group-headers":        `["X-Remote-Group"]`,
+			"requestheader-extra-headers-prefix": `["X-Remote-Extra-"]`,
+		},
+	}).Build()
+
+	a, err := NewRequestHeaderAuthenticator(context.Background(), c)
+	require.NoError(t, err)
+	require.Equal(t, []string{"front-proxy-client"}, a.AllowedNames)
+	require.Equal(t, []string{"X-Remote-User"}, a.UsernameHeaders)
+	require.Equal(t, []string{"X-Remote-Group"}, a.GroupHeaders)
+	require.Equal(t, []string{"X-Remote-Extra-"}, a.ExtraHeaderPrefixes)
+
+	_, err = NewRequestHeaderAuthenticator(context.Background(), fake.NewClientBuilder().Build())
+	require.Error(t, err)
+}
+
+func TestReloadingRequestHeaderAuthenticator(t *testing.T) {
+	ctx := context.Background()
+
+	oldCA, newCA := newTestCA(t), newTestCA(t)
+	clientCert := newTestClientCertSignedBy(t, newCA)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "extension-apiserver-authentication"},
+		Data: map[string]string{
+			"requestheader-client-ca-file":   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: oldCA.cert.Raw})),
+			"requestheader-username-headers": `["X-Remote-User"]`,
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(cm).Build()
+
+	a, err := NewReloadingRequestHeaderAuthenticator(ctx, c, logr.Discard())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, ExternalMetricsAPIPath, nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}}
+	req.Header.Set("X-Remote-User", "user")
+
+	_, err = a.Authenticate(req)
+	require.Error(t, err)
+
+	// The API server rotated the request header client CA
+	cm.Data["requestheader-client-ca-file"] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: newCA.cert.Raw}))
+	require.NoError(t, c.Update(ctx, cm))
+	a.reload(ctx)
+
+	spec, err := a.Authenticate(req)
+	require.NoError(t, err)
+	require.Equal(t, "user", spec.User)
+
+	// The previous settings are kept when the new ones are invalid
+	cm.Data["requestheader-client-ca-file"] = ""
+	require.NoError(t, c.Update(ctx, cm))
+	a.reload(ctx)
+
+	_, err = a.Authenticate(req)
+	require.NoError(t, err)
+}
+
+func TestLeaderPodLabeler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "arc-system",
+			Name:      "controller-0",
+			Labels:    map[string]string{"app": "arc", LabelKeyExternalMetricsLeader: "true"},
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(pod).Build()
+
+	elected := make(chan struct{})
+	l := &LeaderPodLabeler{Client: c, Log: logr.Discard(), Namespace: pod.Namespace, Name: pod.Name, Elected: elected}
+
+	done := make(chan error)
+	go func() { done <- l.Start(ctx) }()
+
+	labels := func() map[string]string {
+		var p corev1.Pod
+		require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(pod), &p))
+		return p.Labels
+	}
+
+	// The label left by a former leader in the same pod is removed until this replica is elected
+	require.Eventually(t, func() bool {
+		_, ok := labels()[LabelKeyExternalMetricsLeader]
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "arc", labels()["app"])
+
+	close(elected)
+
+	require.Eventually(t, func() bool {
+		return labels()[LabelKeyExternalMetricsLeader] == "true"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "front-proxy-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key}
+}
+
+// newTestClientCert returns a pool of a new CA and a client certificate for the API server signed by the CA.
+func newTestClientCert(t *testing.T) (*x509.CertPool, *x509.Certificate) {
+	t.Helper()
+
+	ca := newTestCA(t)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	return pool, newTestClientCertSignedBy(t, ca)
+}
+
+// newTestClientCertSignedBy returns a client certificate for the API server signed by the CA.
+func newTestClientCertSignedBy(t *testing.T, ca *testCA) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "front-proxy-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// newTestSubjectAccessReviewClient returns a client whose SubjectAccessReviews only allow the user.
+func newTestSubjectAccessReviewClient(user string) client.Client {
+	return fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			sar, ok := obj.(*authorizationv1.SubjectAccessReview)
+			if !ok {
+				return c.Create(ctx, obj, opts...)
+			}
+
+			sar.Status.Allowed = sar.Spec.User == user
+			return nil
+		},
+	}).Build()
+}
@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	webhookAutoscalerConfig = "webhook_autoscaler_config"
+	webhookSecret           = "secret"
+	webhookEvent            = "event"
+	webhookDropReason       = "reason"
+	webhookResult           = "result"
+	webhookLabels           = "labels"
+)
+
+var (
+	githubWebhookMetrics = []prometheus.Collector{
+		githubWebhookDeliverySignatures,
+		githubWebhookDeliveriesReceived,
+		githubWebhookSignatureFailures,
+		githubWebhookDeliveriesMatched,
+		githubWebhookDeliveriesDropped,
+		githubWebhookCapacityReservationsCreated,
+		githubWebhookDeliveryHandlingDuration,
+		githubWebhookScaleLatency,
+		githubWebhookUnmatchedWorkflowJobs,
+	}
+)
+
+var (
+	githubWebhookDeliverySignatures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_delivery_signatures_total",
+			Help: `Number of webhook deliveries received by the github webhook server, by the secret token that validated their signature. The secret is "current", "next", "none" when no secret token is configured, or "invalid" when the delivery was rejected.`,
+		},
+		[]string{webhookAutoscalerConfig, webhookSecret},
+	)
+	githubWebhookDeliveriesReceived = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_deliveries_received_total",
+			Help: "Number of webhook deliveries received by the github webhook server",
+		},
+		[]string{webhookEvent},
+	)
+	githubWebhookSignatureFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_signature_failures_total",
+			Help: "Number of webhook deliveries rejected by the github webhook server as their signature could not be validated",
+		},
+		[]string{webhookEvent},
+	)
+	githubWebhookDeliveriesMatched = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_deliveries_matched_total",
+			Help: "Number of webhook deliveries that matched a scale trigger of the HorizontalRunnerAutoscaler",
+		},
+		[]string{webhookEvent, hraName, hraNamespace},
+	)
+	githubWebhookDeliveriesDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_deliveries_dropped_total",
+			Help: "Number of webhook deliveries that didn't scale any HorizontalRunnerAutoscaler, by the reason they were dropped for",
+		},
+		[]string{webhookEvent, webhookDropReason},
+	)
+	githubWebhookCapacityReservationsCreated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_capacity_reservations_created_total",
+			Help: "Number of capacity reservations added to the HorizontalRunnerAutoscaler by webhook deliveries",
+		},
+		[]string{webhookEvent, hraName, hraNamespace},
+	)
+	githubWebhookDeliveryHandlingDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "github_webhook_delivery_handling_duration_seconds",
+			Help:    "Time taken by the github webhook server to respond to a webhook delivery, by whether the delivery succeeded",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{webhookEvent, webhookResult},
+	)
+	githubWebhookScaleLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "github_webhook_scale_latency_seconds",
+			Help:    "Time from the receipt of a webhook delivery until its scale target was applied to the HorizontalRunnerAutoscaler",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		},
+		[]string{webhookEvent, hraName, hraNamespace},
+	)
+	githubWebhookUnmatchedWorkflowJobs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_unmatched_workflow_jobs_total",
+			Help: "Number of queued workflow jobs that matched no HorizontalRunnerAutoscaler, by their comma-separated runs-on labels",
+		},
+		[]string{webhookLabels},
+	)
+)
+
+// AddGitHubWebhookDeliverySignature counts a delivery validated with the secret token for the WebhookAutoscalerConfig,
+// which is empty for the settings given to the github webhook server via flags.
+func AddGitHubWebhookDeliverySignature(config, secret string) {
+	githubWebhookDeliverySignatures.With(prometheus.Labels{
+		webhookAutoscalerConfig: config,
+		webhookSecret:           secret,
+	}).Inc()
+}
+
+// AddGitHubWebhookDeliveryReceived counts a webhook delivery of the event type received by the github webhook server.
+func AddGitHubWebhookDeliveryReceived(event string) {
+	githubWebhookDeliveriesReceived.With(prometheus.Labels{
+		webhookEvent: event,
+	}).Inc()
+}
+
+// AddGitHubWebhookSignatureFailure counts a webhook delivery rejected due to an invalid signature.
+func AddGitHubWebhookSignatureFailure(event string) {
+	githubWebhookSignatureFailures.With(prometheus.Labels{
+		webhookEvent: event,
+	}).Inc()
+}
+
+// AddGitHubWebhookDeliveryMatched counts a webhook delivery that matched a scale trigger of the HRA.
+func AddGitHubWebhookDeliveryMatched(event, name, namespace string) {
+	githubWebhookDeliveriesMatched.With(prometheus.Labels{
+		webhookEvent: event,
+		hraName:      name,
+		hraNamespace: namespace,
+	}).Inc()
+}
+
+// AddGitHubWebhookDeliveryDropped counts a webhook delivery that was dropped for the reason without scaling any HRA.
+func AddGitHubWebhookDeliveryDropped(event, reason string) {
+	githubWebhookDeliveriesDropped.With(prometheus.Labels{
+		webhookEvent:      event,
+		webhookDropReason: reason,
+	}).Inc()
+}
+
+// AddGitHubWebhookCapacityReservationsCreated counts the capacity reservations a webhook delivery added to the HRA.
+func AddGitHubWebhookCapacityReservationsCreated(event, name, namespace string, n int) {
+	githubWebhookCapacityReservationsCreated.With(prometheus.Labels{
+		webhookEvent: event,
+		hraName:      name,
+		hraNamespace: namespace,
+	}).Add(float64(n))
+}
+
+// ObserveGitHubWebhookDeliveryHandlingDuration records the time taken to respond to a webhook delivery.
+// The result is either "success" or "failure".
+func ObserveGitHubWebhookDeliveryHandlingDuration(event, result string, d time.Duration) {
+	githubWebhookDeliveryHandlingDuration.With(prometheus.Labels{
+		webhookEvent:  event,
+		webhookResult: result,
+	}).Observe(d.Seconds())
+}
+
+// ObserveGitHubWebhookScaleLatency records the time from the receipt of a webhook delivery until it was applied to the HRA.
+func ObserveGitHubWebhookScaleLatency(event, name, namespace string, d time.Duration) {
+	githubWebhookScaleLatency.With(prometheus.Labels{
+		webhookEvent: event,
+		hraName:      name,
+		hraNamespace: namespace,
+	}).Observe(d.Seconds())
+}
+
+// AddGitHubWebhookUnmatchedWorkflowJob counts a queued workflow job of the labels that matched no HRA.
+func AddGitHubWebhookUnmatchedWorkflowJob(labels string) {
+	githubWebhookUnmatchedWorkflowJobs.With(prometheus.Labels{
+		webhookLabels: labels,
+	}).Inc()
+}
+
+// DeleteGitHubWebhookUnmatchedWorkflowJobs removes the count of the labels forgotten by the catalog of unmatched labels,
+// so that the number of series stays bounded.
+func DeleteGitHubWebhookUnmatchedWorkflowJobs(labels string) {
+	githubWebhookUnmatchedWorkflowJobs.Delete(prometheus.Labels{
+		webhookLabels: labels,
+	})
+}
@@ -1,8 +1,12 @@
 package metrics
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -14,23 +18,45 @@ const (
 	stRepository   = "repository"
 	stKind         = "kind"
 	stName         = "name"
+	expiresWithin  = "le"
 )
 
+// capacityReservationExpiryBuckets are the upper bounds of the time until expiration
+// that the active capacity reservations are counted into, in seconds.
+var capacityReservationExpiryBuckets = []float64{60, 300, 900, 1800, 3600}
+
 var (
 	horizontalRunnerAutoscalerMetrics = []prometheus.Collector{
 		horizontalRunnerAutoscalerMinReplicas,
 		horizontalRunnerAutoscalerMaxReplicas,
+		horizontalRunnerAutoscalerDryRun,
 		horizontalRunnerAutoscalerDesiredReplicas,
+		horizontalRunnerAutoscalerMaxReplicasSaturated,
+		horizontalRunnerAutoscalerSuggestedMaxReplicas,
 		horizontalRunnerAutoscalerReplicasDesired,
 		horizontalRunnerAutoscalerRunners,
 		horizontalRunnerAutoscalerRunnersRegistered,
 		horizontalRunnerAutoscalerRunnersBusy,
 		horizontalRunnerAutoscalerTerminatingBusy,
+		horizontalRunnerAutoscalerRunnersBusyRatio,
 		horizontalRunnerAutoscalerNecessaryReplicas,
 		horizontalRunnerAutoscalerWorkflowRunsCompleted,
 		horizontalRunnerAutoscalerWorkflowRunsInProgress,
 		horizontalRunnerAutoscalerWorkflowRunsQueued,
 		horizontalRunnerAutoscalerWorkflowRunsUnknown,
+		horizontalRunnerAutoscalerWorkflowJobsInProgress,
+		horizontalRunnerAutoscalerWorkflowJobsQueued,
+		horizontalRunnerAutoscalerWorkflowJobsUnmatched,
+		horizontalRunnerAutoscalerWorkflowJobsUnknown,
+		horizontalRunnerAutoscalerOldestQueuedWorkflowJobAge,
+		horizontalRunnerAutoscalerWorkflowJobsQueuedOverThreshold,
+		horizontalRunnerAutoscalerCapacityReservations,
+		horizontalRunnerAutoscalerCapacityReservedReplicas,
+		horizontalRunnerAutoscalerCapacityReservationsExpiring,
+		horizontalRunnerAutoscalerMetricCacheHits,
+		horizontalRunnerAutoscalerMetricCacheMisses,
+		horizontalRunnerAutoscalerMetricCacheEvictions,
+		horizontalRunnerAutoscalerMetricCacheEntries,
 	}
 )
 
@@ -49,6 +75,13 @@ var (
 		},
 		[]string{hraName, hraNamespace},
 	)
+	horizontalRunnerAutoscalerDryRun = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_spec_dry_run",
+			Help: "1 if the HorizontalRunnerAutoscaler is in dry-run mode, in which desired_replicas isn't applied to the scale target",
+		},
+		[]string{hraName, hraNamespace},
+	)
 	horizontalRunnerAutoscalerDesiredReplicas = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "horizontalrunnerautoscaler_status_desired_replicas",
@@ -56,6 +89,20 @@ var (
 		},
 		[]string{hraName, hraNamespace},
 	)
+	horizontalRunnerAutoscalerMaxReplicasSaturated = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_max_replicas_saturated",
+			Help: "1 if the metrics of HorizontalRunnerAutoscaler have demanded more replicas than maxReplicas for longer than maxReplicasSaturation.duration",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerSuggestedMaxReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_suggested_max_replicas",
+			Help: "maxReplicas suggested for a saturated HorizontalRunnerAutoscaler, which is the highest number of replicas its metrics demanded",
+		},
+		[]string{hraName, hraNamespace},
+	)
 	// PercentageRunnersBusy
 	horizontalRunnerAutoscalerReplicasDesired = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -92,6 +139,13 @@ var (
 		},
 		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
 	)
+	horizontalRunnerAutoscalerRunnersBusyRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_runners_busy_ratio",
+			Help: "fraction_busy of PercentageRunnersBusy, compared against scaleUpThreshold and scaleDownThreshold",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
 	// QueuedAndInProgressWorkflowRuns
 	horizontalRunnerAutoscalerNecessaryReplicas = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -128,6 +182,98 @@ var (
 		},
 		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
 	)
+	// QueuedAndInProgressWorkflowJobs
+	horizontalRunnerAutoscalerWorkflowJobsInProgress = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_workflow_jobs_in_progress",
+			Help: "workflow_jobs_in_progress of QueuedAndInProgressWorkflowJobs",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
+	horizontalRunnerAutoscalerWorkflowJobsQueued = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_workflow_jobs_queued",
+			Help: "workflow_jobs_queued of QueuedAndInProgressWorkflowJobs",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
+	horizontalRunnerAutoscalerWorkflowJobsUnmatched = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_workflow_jobs_unmatched",
+			Help: "workflow_jobs_unmatched of QueuedAndInProgressWorkflowJobs",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
+	horizontalRunnerAutoscalerWorkflowJobsUnknown = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_workflow_jobs_unknown",
+			Help: "workflow_jobs_unknown of QueuedAndInProgressWorkflowJobs",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
+	// OldestQueuedWorkflowJobAge
+	horizontalRunnerAutoscalerOldestQueuedWorkflowJobAge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_oldest_queued_workflow_job_age_seconds",
+			Help: "Age of the oldest queued workflow job of OldestQueuedWorkflowJobAge, in seconds",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
+	horizontalRunnerAutoscalerWorkflowJobsQueuedOverThreshold = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_workflow_jobs_queued_over_threshold",
+			Help: "workflow_jobs_queued_over_threshold of OldestQueuedWorkflowJobAge",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
+	// CapacityReservations
+	horizontalRunnerAutoscalerCapacityReservations = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_capacity_reservations",
+			Help: "Number of active capacity reservations of HorizontalRunnerAutoscaler",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerCapacityReservedReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_capacity_reserved_replicas",
+			Help: "Sum of replicas of active capacity reservations of HorizontalRunnerAutoscaler",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerCapacityReservationsExpiring = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_capacity_reservations_expiring",
+			Help: "Cumulative number of active capacity reservations of HorizontalRunnerAutoscaler expiring within le seconds",
+		},
+		[]string{hraName, hraNamespace, expiresWithin},
+	)
+	horizontalRunnerAutoscalerMetricCacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "horizontalrunnerautoscaler_metric_cache_hits_total",
+			Help: "Total number of syncs of HorizontalRunnerAutoscaler that reused the replicas suggested by its metrics from the cache",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerMetricCacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "horizontalrunnerautoscaler_metric_cache_misses_total",
+			Help: "Total number of syncs of HorizontalRunnerAutoscaler that computed the replicas suggested by its metrics as they weren't cached",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerMetricCacheEvictions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "horizontalrunnerautoscaler_metric_cache_evictions_total",
+			Help: "Total number of entries evicted from the HorizontalRunnerAutoscaler metric cache as it was full",
+		},
+	)
+	horizontalRunnerAutoscalerMetricCacheEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_metric_cache_entries",
+			Help: "Number of HorizontalRunnerAutoscalers whose suggested replicas are in the metric cache",
+		},
+	)
 )
 
 func SetHorizontalRunnerAutoscalerSpec(o metav1.ObjectMeta, spec v1alpha1.HorizontalRunnerAutoscalerSpec) {
@@ -141,6 +287,11 @@ func SetHorizontalRunnerAutoscalerSpec(o metav1.ObjectMeta, spec v1alpha1.Horizo
 	if spec.MinReplicas != nil {
 		horizontalRunnerAutoscalerMinReplicas.With(labels).Set(float64(*spec.MinReplicas))
 	}
+	if spec.DryRun {
+		horizontalRunnerAutoscalerDryRun.With(labels).Set(1)
+	} else {
+		horizontalRunnerAutoscalerDryRun.With(labels).Set(0)
+	}
 }
 
 func SetHorizontalRunnerAutoscalerStatus(o metav1.ObjectMeta, status v1alpha1.HorizontalRunnerAutoscalerStatus) {
@@ -151,6 +302,16 @@ func SetHorizontalRunnerAutoscalerStatus(o metav1.ObjectMeta, status v1alpha1.Ho
 	if status.DesiredReplicas != nil {
 		horizontalRunnerAutoscalerDesiredReplicas.With(labels).Set(float64(*status.DesiredReplicas))
 	}
+	if meta.IsStatusConditionTrue(status.Conditions, v1alpha1.HorizontalRunnerAutoscalerConditionMaxReplicasSaturated) {
+		horizontalRunnerAutoscalerMaxReplicasSaturated.With(labels).Set(1)
+	} else {
+		horizontalRunnerAutoscalerMaxReplicasSaturated.With(labels).Set(0)
+	}
+	if s := status.MaxReplicasSaturation; s != nil && s.SuggestedMaxReplicas != nil {
+		horizontalRunnerAutoscalerSuggestedMaxReplicas.With(labels).Set(float64(*s.SuggestedMaxReplicas))
+	} else {
+		horizontalRunnerAutoscalerSuggestedMaxReplicas.Delete(labels)
+	}
 }
 
 func SetHorizontalRunnerAutoscalerPercentageRunnersBusy(
@@ -165,6 +326,7 @@ func SetHorizontalRunnerAutoscalerPercentageRunnersBusy(
 	numRunnersRegistered int,
 	numRunnersBusy int,
 	numTerminatingBusy int,
+	fractionBusy float64,
 ) {
 	labels := prometheus.Labels{
 		hraName:        o.Name,
@@ -180,6 +342,7 @@ func SetHorizontalRunnerAutoscalerPercentageRunnersBusy(
 	horizontalRunnerAutoscalerRunnersRegistered.With(labels).Set(float64(numRunnersRegistered))
 	horizontalRunnerAutoscalerRunnersBusy.With(labels).Set(float64(numRunnersBusy))
 	horizontalRunnerAutoscalerTerminatingBusy.With(labels).Set(float64(numTerminatingBusy))
+	horizontalRunnerAutoscalerRunnersBusyRatio.With(labels).Set(fractionBusy)
 }
 
 func SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowRuns(
@@ -210,3 +373,124 @@ func SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowRuns(
 	horizontalRunnerAutoscalerWorkflowRunsQueued.With(labels).Set(float64(workflowRunsQueued))
 	horizontalRunnerAutoscalerWorkflowRunsUnknown.With(labels).Set(float64(workflowRunsUnknown))
 }
+
+func SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowJobs(
+	o metav1.ObjectMeta,
+	enterprise string,
+	organization string,
+	repository string,
+	kind string,
+	name string,
+	necessaryReplicas int,
+	workflowJobsInProgress int,
+	workflowJobsQueued int,
+	workflowJobsUnmatched int,
+	workflowJobsUnknown int,
+) {
+	labels := prometheus.Labels{
+		hraName:        o.Name,
+		hraNamespace:   o.Namespace,
+		stEnterprise:   enterprise,
+		stOrganization: organization,
+		stRepository:   repository,
+		stKind:         kind,
+		stName:         name,
+	}
+	horizontalRunnerAutoscalerNecessaryReplicas.With(labels).Set(float64(necessaryReplicas))
+	horizontalRunnerAutoscalerWorkflowJobsInProgress.With(labels).Set(float64(workflowJobsInProgress))
+	horizontalRunnerAutoscalerWorkflowJobsQueued.With(labels).Set(float64(workflowJobsQueued))
+	horizontalRunnerAutoscalerWorkflowJobsUnmatched.With(labels).Set(float64(workflowJobsUnmatched))
+	horizontalRunnerAutoscalerWorkflowJobsUnknown.With(labels).Set(float64(workflowJobsUnknown))
+}
+
+func SetHorizontalRunnerAutoscalerOldestQueuedWorkflowJobAge(
+	o metav1.ObjectMeta,
+	enterprise string,
+	organization string,
+	repository string,
+	kind string,
+	name string,
+	necessaryReplicas int,
+	workflowJobsInProgress int,
+	workflowJobsQueued int,
+	workflowJobsQueuedOverThreshold int,
+	oldestQueuedWorkflowJobAge time.Duration,
+) {
+	labels := prometheus.Labels{
+		hraName:        o.Name,
+		hraNamespace:   o.Namespace,
+		stEnterprise:   enterprise,
+		stOrganization: organization,
+		stRepository:   repository,
+		stKind:         kind,
+		stName:         name,
+	}
+	horizontalRunnerAutoscalerNecessaryReplicas.With(labels).Set(float64(necessaryReplicas))
+	horizontalRunnerAutoscalerWorkflowJobsInProgress.With(labels).Set(float64(workflowJobsInProgress))
+	horizontalRunnerAutoscalerWorkflowJobsQueued.With(labels).Set(float64(workflowJobsQueued))
+	horizontalRunnerAutoscalerWorkflowJobsQueuedOverThreshold.With(labels).Set(float64(workflowJobsQueuedOverThreshold))
+	horizontalRunnerAutoscalerOldestQueuedWorkflowJobAge.With(labels).Set(oldestQueuedWorkflowJobAge.Seconds())
+}
+
+// SetHorizontalRunnerAutoscalerCapacityReservations sets the number of the capacity reservations active at now,
+// the sum of their replicas, and the histogram of the time until they expire.
+func SetHorizontalRunnerAutoscalerCapacityReservations(o metav1.ObjectMeta, reservations []v1alpha1.CapacityReservation, now time.Time) {
+	labels := prometheus.Labels{
+		hraName:      o.Name,
+		hraNamespace: o.Namespace,
+	}
+
+	var (
+		active   int
+		replicas int
+		expiring = make([]int, len(capacityReservationExpiryBuckets))
+	)
+
+	for _, r := range reservations {
+		if !r.ExpirationTime.Time.After(now) {
+			continue
+		}
+
+		active++
+		replicas += r.Replicas
+
+		ttl := r.ExpirationTime.Time.Sub(now).Seconds()
+		for i, le := range capacityReservationExpiryBuckets {
+			if ttl <= le {
+				expiring[i]++
+			}
+		}
+	}
+
+	horizontalRunnerAutoscalerCapacityReservations.With(labels).Set(float64(active))
+	horizontalRunnerAutoscalerCapacityReservedReplicas.With(labels).Set(float64(replicas))
+
+	for i, le := range capacityReservationExpiryBuckets {
+		horizontalRunnerAutoscalerCapacityReservationsExpiring.With(bucketLabels(labels, strconv.FormatFloat(le, 'f', -1, 64))).Set(float64(expiring[i]))
+	}
+	horizontalRunnerAutoscalerCapacityReservationsExpiring.With(bucketLabels(labels, "+Inf")).Set(float64(active))
+}
+
+func bucketLabels(labels prometheus.Labels, le string) prometheus.Labels {
+	l := prometheus.Labels{expiresWithin: le}
+	for k, v := range labels {
+		l[k] = v
+	}
+	return l
+}
+
+func IncHorizontalRunnerAutoscalerMetricCacheHits(o metav1.ObjectMeta) {
+	horizontalRunnerAutoscalerMetricCacheHits.With(prometheus.Labels{hraName: o.Name, hraNamespace: o.Namespace}).Inc()
+}
+
+func IncHorizontalRunnerAutoscalerMetricCacheMisses(o metav1.ObjectMeta) {
+	horizontalRunnerAutoscalerMetricCacheMisses.With(prometheus.Labels{hraName: o.Name, hraNamespace: o.Namespace}).Inc()
+}
+
+func IncHorizontalRunnerAutoscalerMetricCacheEvictions() {
+	horizontalRunnerAutoscalerMetricCacheEvictions.Inc()
+}
+
+func SetHorizontalRunnerAutoscalerMetricCacheEntries(n int) {
+	horizontalRunnerAutoscalerMetricCacheEntries.Set(float64(n))
+}
@@ -168,19 +168,7 @@ func (c *MultiGitHubClient) initClientForSecret(secret *corev1.Secret, dependent
 
 	cliRef := c.clients[secRef]
 
-	var ks []string
-
-	for k := range secret.Data {
-		ks = append(ks, k)
-	}
-
-	sort.SliceStable(ks, func(i, j int) bool { return ks[i] < ks[j] })
-
-	hash := sha1.New()
-	for _, k := range ks {
-		hash.Write(secret.Data[k])
-	}
-	hashStr := hex.EncodeToString(hash.Sum(nil))
+	hashStr := secretDataHash(secret.Data)
 
 	if cliRef.hash != hashStr {
 		delete(c.clients, secRef)
@@ -195,6 +183,14 @@ func (c *MultiGitHubClient) initClientForSecret(secret *corev1.Secret, dependent
 			conf.EnterpriseURL = c.githubClient.GithubBaseURL
 		}
 
+		// The API calls made with the credentials of the secret share the controller-wide GitHub API proxy, if any.
+		conf.APIProxyURL = c.githubClient.APIProxyURL
+		conf.APIProxyCACert = c.githubClient.APIProxyCACert
+		conf.CircuitBreaker = c.githubClient.CircuitBreaker
+		conf.TLS = c.githubClient.TLS
+		conf.RunnerCacheTTL = c.githubClient.RunnerCacheTTL
+		conf.CredentialsName = secret.Namespace + "/" + secret.Name
+
 		cli, err := conf.NewClient()
 		if err != nil {
 			return nil, err
@@ -266,6 +262,24 @@ func (c *MultiGitHubClient) derefClient(ns, secretName string, dependent *runner
 	}
 }
 
+// secretDataHash returns the hash of the contents of a secret, used to tell if the secret has changed.
+func secretDataHash(data map[string][]byte) string {
+	var ks []string
+
+	for k := range data {
+		ks = append(ks, k)
+	}
+
+	sort.SliceStable(ks, func(i, j int) bool { return ks[i] < ks[j] })
+
+	hash := sha1.New()
+	for _, k := range ks {
+		hash.Write(data[k])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
 func secretDataToGitHubClientConfig(data map[string][]byte) (*github.Config, error) {
 	var (
 		conf github.Config
@@ -303,6 +317,8 @@ func secretDataToGitHubClientConfig(data map[string][]byte) (*github.Config, err
 
 	conf.AppPrivateKey = string(data["github_app_private_key"])
 
+	conf.AppCredentials = string(data["github_app_credentials"])
+
 	return &conf, nil
 }
 
@@ -27,6 +27,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 )
 
 // RunnerPersistentVolumeClaimReconciler reconciles a PersistentVolume object
@@ -70,5 +72,5 @@ func (r *RunnerPersistentVolumeClaimReconciler) SetupWithManager(mgr ctrl.Manage
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.PersistentVolumeClaim{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &corev1.PersistentVolumeClaim{}, r))
 }
@@ -27,6 +27,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 )
 
 // RunnerPersistentVolumeReconciler reconciles a PersistentVolume object
@@ -68,5 +70,5 @@ func (r *RunnerPersistentVolumeReconciler) SetupWithManager(mgr ctrl.Manager) er
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.PersistentVolume{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &corev1.PersistentVolume{}, r))
 }
@@ -70,17 +70,25 @@ func (t *PodRunnerTokenInjector) Handle(ctx context.Context, req admission.Reque
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	rt, err := ghc.GetRegistrationToken(context.Background(), enterprise, org, repo, pod.Name)
-	if err != nil {
-		t.Log.Error(err, "Failed to get new registration token")
-		return admission.Errored(http.StatusInternalServerError, err)
-	}
+	var updated *corev1.Pod
 
-	ts := rt.GetExpiresAt().Format(time.RFC3339)
+	if podUsesJITConfig(&pod) {
+		// The runner pod controller registers the runner and populates its jit config once the pod is created
+		updated = mutatePod(&pod, "")
+	} else if rt, err := ghc.GetRegistrationToken(context.Background(), enterprise, org, repo, pod.Name); err != nil {
+		// Admitting the pod without the token would let it crash-loop until it's recreated.
+		// Instead, hold it until the runner pod controller populates the token.
+		t.Log.Error(err, "Failed to get new registration token. Gating the pod until the token is populated", "pod", pod.Name)
 
-	updated := mutatePod(&pod, *rt.Token)
+		updated = pod.DeepCopy()
+		gatePodOnRegistrationToken(updated)
+	} else {
+		ts := rt.GetExpiresAt().Format(time.RFC3339)
 
-	updated.Annotations[AnnotationKeyTokenExpirationDate] = ts
+		updated = mutatePod(&pod, *rt.Token)
+
+		updated.Annotations[AnnotationKeyTokenExpirationDate] = ts
+	}
 
 	forceRunnerPodRestartPolicyNever(updated)
 
@@ -0,0 +1,243 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultRegistrationTokenPoolSecretName     = "actions-runner-controller-registration-tokens"
+	DefaultRegistrationTokenPoolRefillInterval = 5 * time.Minute
+)
+
+// RegistrationTokenPool keeps a pool of valid registration tokens per enterprise, organization and repository of the RunnerDeployments and RunnerSets
+// in a Secret, and seeds the GitHub API clients of the controller with them, so that a burst of new runner pods at scale-up time
+// gets its registration tokens without serializing behind the Create Registration Token API calls.
+//
+// The tokens of a pool expire one refill interval apart, so that the pool always has a token that outlasts the runner startup timeout.
+// The pool is shared via the Secret across the replicas of the controller and its restarts, so that the tokens are available right after a failover.
+//
+// RegistrationTokenPool implements manager.Runnable so that it can be added to the controller manager.
+// It runs on every replica, as each replica hands out tokens from its own GitHub API clients.
+type RegistrationTokenPool struct {
+	// Client lists the RunnerDeployments and RunnerSets whose tokens are pooled.
+	client.Client
+	// SecretClient reads and writes the Secret of the pool, which usually lives outside of the watched namespaces.
+	SecretClient client.Client
+	Log          logr.Logger
+	GitHubClient *MultiGitHubClient
+
+	Namespace      string
+	Name           string
+	Size           int
+	RefillInterval time.Duration
+}
+
+// registrationTokenPoolScope is the enterprise, organization or repository the tokens of a pool are created for,
+// along with the GitHub API credentials they are created with.
+type registrationTokenPoolScope struct {
+	namespace, credentialsSecret string
+
+	enterprise, org, repo string
+}
+
+// key returns the key of the pool of the scope in the Secret, which may only contain alphanumerics, '-', '_' and '.'.
+func (s registrationTokenPoolScope) key() string {
+	creds := "default"
+	if s.credentialsSecret != "" {
+		creds = s.namespace + "." + s.credentialsSecret
+	}
+
+	var target string
+	switch {
+	case s.repo != "":
+		target = "repo_" + s.repo
+	case s.org != "":
+		target = "org_" + s.org
+	default:
+		target = "enterprise_" + s.enterprise
+	}
+
+	return strings.ToLower(creds + "_" + strings.ReplaceAll(target, "/", "."))
+}
+
+func (p *RegistrationTokenPool) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return DefaultRegistrationTokenPoolSecretName
+}
+
+func (p *RegistrationTokenPool) refillInterval() time.Duration {
+	if p.RefillInterval > 0 {
+		return p.RefillInterval
+	}
+	return DefaultRegistrationTokenPoolRefillInterval
+}
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create;update
+
+func (p *RegistrationTokenPool) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.refillInterval())
+	defer ticker.Stop()
+
+	for {
+		if err := p.refill(ctx); err != nil {
+			p.Log.Error(err, "Failed to refill registration token pool", "secret", p.name())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *RegistrationTokenPool) NeedLeaderElection() bool {
+	return false
+}
+
+// refill tops up the pools of all the scopes in the Secret, drops the pools of the scopes no longer used, and seeds the GitHub API clients with the pooled tokens.
+func (p *RegistrationTokenPool) refill(ctx context.Context) error {
+	scopes, err := p.scopes(ctx)
+	if err != nil {
+		return err
+	}
+
+	var secret corev1.Secret
+	exists := true
+	if err := p.SecretClient.Get(ctx, types.NamespacedName{Namespace: p.Namespace, Name: p.name()}, &secret); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return fmt.Errorf("getting registration token pool secret: %w", err)
+		}
+
+		exists = false
+		secret = corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: p.Namespace, Name: p.name()}}
+	}
+
+	data := map[string][]byte{}
+
+	for _, s := range scopes {
+		key := s.key()
+		log := p.Log.WithValues("pool", key)
+
+		var pool []*github.RegistrationToken
+		if v, ok := secret.Data[key]; ok {
+			if err := json.Unmarshal(v, &pool); err != nil {
+				log.Error(err, "Ignoring malformed registration token pool")
+				pool = nil
+			}
+		}
+
+		ghc, err := p.GitHubClient.initClientWithSecretName(ctx, s.namespace, s.credentialsSecret, nil)
+		if err != nil {
+			log.Error(err, "Failed to initialize GitHub API client for registration token pool")
+			if v, ok := secret.Data[key]; ok {
+				data[key] = v
+			}
+			continue
+		}
+
+		pool, err = ghc.RefillRegistrationTokens(ctx, s.enterprise, s.org, s.repo, pool, p.Size, p.refillInterval())
+		if err != nil {
+			log.Error(err, "Failed to create registration token for pool")
+		}
+
+		if len(pool) == 0 {
+			continue
+		}
+
+		v, err := json.Marshal(pool)
+		if err != nil {
+			return err
+		}
+		data[key] = v
+	}
+
+	if len(secret.Data) == len(data) && (len(data) == 0 || reflect.DeepEqual(secret.Data, data)) {
+		return nil
+	}
+
+	secret.Data = data
+
+	if !exists {
+		if err := p.SecretClient.Create(ctx, &secret); err != nil {
+			return fmt.Errorf("creating registration token pool secret: %w", err)
+		}
+	} else if err := p.SecretClient.Update(ctx, &secret); err != nil {
+		if kerrors.IsConflict(err) {
+			// Another replica refilled the pools in the meantime. Ours are refilled from its tokens on the next refill.
+			p.Log.V(1).Info("Registration token pool secret was updated concurrently. Retrying on the next refill")
+			return nil
+		}
+		return fmt.Errorf("updating registration token pool secret: %w", err)
+	}
+
+	p.Log.V(1).Info("Refilled registration token pools", "secret", p.name(), "pools", len(data))
+
+	return nil
+}
+
+// scopes returns the distinct scopes of the RunnerDeployments and RunnerSets whose runners register with registration tokens.
+func (p *RegistrationTokenPool) scopes(ctx context.Context) ([]registrationTokenPoolScope, error) {
+	seen := map[string]struct{}{}
+	var scopes []registrationTokenPoolScope
+
+	add := func(ns string, rc v1alpha1.RunnerConfig) {
+		if runnerConfigUsesJIT(rc) {
+			return
+		}
+
+		s := registrationTokenPoolScope{
+			enterprise: rc.Enterprise,
+			org:        rc.Organization,
+			repo:       rc.Repository,
+		}
+		if rc.GitHubAPICredentialsFrom != nil && rc.GitHubAPICredentialsFrom.SecretRef.Name != "" {
+			s.namespace = ns
+			s.credentialsSecret = rc.GitHubAPICredentialsFrom.SecretRef.Name
+		}
+
+		if _, ok := seen[s.key()]; ok {
+			return
+		}
+		seen[s.key()] = struct{}{}
+
+		scopes = append(scopes, s)
+	}
+
+	var rds v1alpha1.RunnerDeploymentList
+	if err := p.List(ctx, &rds); err != nil {
+		return nil, fmt.Errorf("listing runnerdeployments: %w", err)
+	}
+	for _, rd := range rds.Items {
+		add(rd.Namespace, rd.Spec.Template.Spec.RunnerConfig)
+	}
+
+	var rss v1alpha1.RunnerSetList
+	if err := p.List(ctx, &rss); err != nil {
+		return nil, fmt.Errorf("listing runnersets: %w", err)
+	}
+	for _, rs := range rss.Items {
+		add(rs.Namespace, rs.Spec.RunnerConfig)
+	}
+
+	sort.SliceStable(scopes, func(i, j int) bool { return scopes[i].key() < scopes[j].key() })
+
+	return scopes, nil
+}
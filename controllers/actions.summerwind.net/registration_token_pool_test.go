@@ -0,0 +1,88 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRegistrationTokenPoolScopeKey(t *testing.T) {
+	require.Equal(t, "default_org_my-org", registrationTokenPoolScope{org: "My-Org"}.key())
+	require.Equal(t, "default_repo_owner.my-repo", registrationTokenPoolScope{repo: "owner/my-repo"}.key())
+	require.Equal(t, "default_enterprise_my-enterprise", registrationTokenPoolScope{enterprise: "my-enterprise"}.key())
+	require.Equal(t, "runners.creds_org_my-org", registrationTokenPoolScope{namespace: "runners", credentialsSecret: "creds", org: "my-org"}.key())
+}
+
+func TestRegistrationTokenPoolRefill(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	server := fake.NewServer(fake.WithListRunnersResponse(200, fake.RunnersListBody))
+	defer server.Close()
+
+	enabled := true
+
+	rd := func(name string, rc v1alpha1.RunnerConfig) *v1alpha1.RunnerDeployment {
+		return &v1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1alpha1.RunnerDeploymentSpec{
+				Template: v1alpha1.RunnerTemplate{Spec: v1alpha1.RunnerSpec{RunnerConfig: rc}},
+			},
+		}
+	}
+
+	stale, err := json.Marshal([]*github.RegistrationToken{{Token: github.String("stale")}})
+	require.NoError(t, err)
+
+	c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+		rd("org-runners", v1alpha1.RunnerConfig{Organization: "test"}),
+		rd("more-org-runners", v1alpha1.RunnerConfig{Organization: "test"}),
+		rd("jit-runners", v1alpha1.RunnerConfig{Organization: "other", JITConfig: &enabled}),
+		&v1alpha1.RunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "repo-runners"},
+			Spec:       v1alpha1.RunnerSetSpec{RunnerConfig: v1alpha1.RunnerConfig{Repository: "test/valid"}},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "arc-system", Name: DefaultRegistrationTokenPoolSecretName},
+			Data:       map[string][]byte{"default_org_gone": stale},
+		},
+	).Build()
+
+	p := &RegistrationTokenPool{
+		Client:       c,
+		SecretClient: c,
+		Log:          logr.Discard(),
+		GitHubClient: NewMultiGitHubClient(c, newGithubClient(server)),
+		Namespace:    "arc-system",
+		Size:         3,
+	}
+
+	ctx := context.Background()
+	require.NoError(t, p.refill(ctx))
+
+	var secret corev1.Secret
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "arc-system", Name: DefaultRegistrationTokenPoolSecretName}, &secret))
+
+	// The pools of the scopes no longer used and of the runners registered with jit configs are dropped
+	require.Len(t, secret.Data, 2)
+
+	for _, key := range []string{"default_org_test", "default_repo_test.valid"} {
+		var pool []*github.RegistrationToken
+		require.NoError(t, json.Unmarshal(secret.Data[key], &pool), key)
+		require.Len(t, pool, 1, key)
+		require.Equal(t, fake.RegistrationToken, pool[0].GetToken(), key)
+	}
+}
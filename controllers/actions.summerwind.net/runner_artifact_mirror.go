@@ -0,0 +1,330 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	DefaultRunnerArtifactMirrorName  = "actions-runner-artifact-mirror"
+	DefaultRunnerArtifactMirrorImage = "nginxinc/nginx-unprivileged:stable-alpine"
+	DefaultRunnerArtifactMirrorPort  = 8080
+
+	// EnvVarRunnerArtifactMirrorURL is the environment variable injected into the runner container
+	// so that the entrypoint can download runner releases and container hooks from the in-cluster mirror
+	// instead of github.com.
+	EnvVarRunnerArtifactMirrorURL = "RUNNER_ARTIFACT_MIRROR_URL"
+
+	// EnvVarRunnerDownloadVersion and EnvVarRunnerContainerHooksDownloadVersion tell the entrypoint
+	// which artifacts to fetch from the mirror.
+	EnvVarRunnerDownloadVersion               = "RUNNER_DOWNLOAD_VERSION"
+	EnvVarRunnerContainerHooksDownloadVersion = "RUNNER_CONTAINER_HOOKS_DOWNLOAD_VERSION"
+
+	runnerArtifactMirrorVolumeName = "artifacts"
+	runnerArtifactMirrorDocRoot    = "/usr/share/nginx/html"
+)
+
+// RunnerArtifactMirrorReconciler manages an in-cluster HTTP server that serves actions/runner release tarballs
+// and runner-container-hooks archives out of a PersistentVolumeClaim.
+//
+// It is intended for air-gapped clusters, where runner pods cannot reach github.com to download
+// a runner version other than the one bundled in the runner image.
+// Only the runner pods of RunnerDeployments and RunnerSets are pointed at the mirror, as only the entrypoint of
+// the summerwind runner images downloads from it. The scale set controller deploys it too, for custom runner images.
+// The operator is responsible for populating the volume, using the same file names as the GitHub releases, e.g.
+// actions-runner-linux-x64-2.311.0.tar.gz and actions-runner-hooks-k8s-0.5.0.zip.
+//
+// The reconciler watches the deployment, service and persistent volume claim of the mirror,
+// so that any drift, like a deleted service or an edited deployment, is repaired on the elected leader.
+type RunnerArtifactMirrorReconciler struct {
+	client.Client
+	Log  logr.Logger
+	Name string
+
+	Namespace        string
+	DeploymentName   string
+	Image            string
+	StorageClassName string
+	StorageSize      resource.Quantity
+	Port             int32
+
+	// AccessMode is the access mode of the persistent volume claim. Defaults to ReadWriteMany,
+	// so that the volume can be populated while the mirror serves it.
+	AccessMode corev1.PersistentVolumeAccessMode
+}
+
+func (m *RunnerArtifactMirrorReconciler) name() string {
+	if m.DeploymentName != "" {
+		return m.DeploymentName
+	}
+	return DefaultRunnerArtifactMirrorName
+}
+
+func (m *RunnerArtifactMirrorReconciler) port() int32 {
+	if m.Port != 0 {
+		return m.Port
+	}
+	return DefaultRunnerArtifactMirrorPort
+}
+
+// URL returns the in-cluster base URL of the mirror that is injected into runner pods.
+func (m *RunnerArtifactMirrorReconciler) URL() string {
+	return fmt.Sprintf("http://%s.%s.svc:%d", m.name(), m.Namespace, m.port())
+}
+
+func (m *RunnerArtifactMirrorReconciler) accessMode() corev1.PersistentVolumeAccessMode {
+	if m.AccessMode != "" {
+		return m.AccessMode
+	}
+	return corev1.ReadWriteMany
+}
+
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create
+
+func (m *RunnerArtifactMirrorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := m.Log.WithValues("runnerartifactmirror", req.NamespacedName)
+
+	if err := m.ensure(ctx); err != nil {
+		log.Error(err, "Failed to ensure runner artifact mirror. Retrying")
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("Runner artifact mirror is up to date", "url", m.URL())
+
+	return ctrl.Result{}, nil
+}
+
+func (m *RunnerArtifactMirrorReconciler) ensure(ctx context.Context) error {
+	// Most of a PVC's spec is immutable after creation, so we only create it when missing.
+	pvc := m.newPersistentVolumeClaim()
+	if err := m.Create(ctx, pvc); err != nil {
+		if !kerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("creating persistentvolumeclaim %s: %w", pvc.Name, err)
+		}
+	} else {
+		m.Log.Info("Created runner artifact mirror volume claim", "name", pvc.Name)
+	}
+
+	desiredDeployment := m.newDeployment()
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: desiredDeployment.Namespace, Name: desiredDeployment.Name}}
+	op, err := controllerutil.CreateOrPatch(ctx, m.Client, deployment, func() error {
+		deployment.Labels = desiredDeployment.Labels
+		deployment.Spec.Replicas = desiredDeployment.Spec.Replicas
+		deployment.Spec.Strategy = desiredDeployment.Spec.Strategy
+		if deployment.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType {
+			deployment.Spec.Strategy.RollingUpdate = nil
+		}
+		deployment.Spec.Template = desiredDeployment.Spec.Template
+		if deployment.Spec.Selector == nil {
+			deployment.Spec.Selector = desiredDeployment.Spec.Selector
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ensuring deployment %s: %w", deployment.Name, err)
+	}
+	m.Log.V(1).Info("Ensured runner artifact mirror deployment", "name", deployment.Name, "operation", op)
+
+	desiredService := m.newService()
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: desiredService.Namespace, Name: desiredService.Name}}
+	op, err = controllerutil.CreateOrPatch(ctx, m.Client, service, func() error {
+		service.Labels = desiredService.Labels
+		service.Spec.Selector = desiredService.Spec.Selector
+		service.Spec.Ports = desiredService.Spec.Ports
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ensuring service %s: %w", service.Name, err)
+	}
+	m.Log.V(1).Info("Ensured runner artifact mirror service", "name", service.Name, "operation", op)
+
+	return nil
+}
+
+func (m *RunnerArtifactMirrorReconciler) labels() map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       m.name(),
+		"app.kubernetes.io/component":  "runner-artifact-mirror",
+		"app.kubernetes.io/managed-by": "actions-runner-controller",
+	}
+}
+
+func (m *RunnerArtifactMirrorReconciler) newPersistentVolumeClaim() *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.name(),
+			Namespace: m.Namespace,
+			Labels:    m.labels(),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{m.accessMode()},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: m.StorageSize,
+				},
+			},
+		},
+	}
+
+	if m.StorageClassName != "" {
+		pvc.Spec.StorageClassName = &m.StorageClassName
+	}
+
+	return pvc
+}
+
+func (m *RunnerArtifactMirrorReconciler) newDeployment() *appsv1.Deployment {
+	image := m.Image
+	if image == "" {
+		image = DefaultRunnerArtifactMirrorImage
+	}
+
+	replicas := int32(1)
+
+	// A ReadWriteOnce volume can't be mounted by the old and the new pods at once when they land on different nodes.
+	strategy := appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+	}
+	if m.accessMode() == corev1.ReadWriteOnce || m.accessMode() == corev1.ReadWriteOncePod {
+		strategy.Type = appsv1.RecreateDeploymentStrategyType
+	}
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.name(),
+			Namespace: m.Namespace,
+			Labels:    m.labels(),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: m.labels(),
+			},
+			Strategy: strategy,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: m.labels(),
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "mirror",
+							Image: image,
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "http",
+									ContainerPort: m.port(),
+									Protocol:      corev1.ProtocolTCP,
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      runnerArtifactMirrorVolumeName,
+									MountPath: runnerArtifactMirrorDocRoot,
+								},
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									TCPSocket: &corev1.TCPSocketAction{
+										Port: intstr.FromString("http"),
+									},
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: runnerArtifactMirrorVolumeName,
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: m.name(),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (m *RunnerArtifactMirrorReconciler) newService() *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.name(),
+			Namespace: m.Namespace,
+			Labels:    m.labels(),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: m.labels(),
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       m.port(),
+					TargetPort: intstr.FromString("http"),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// SetupWithManager watches the resources of the mirror through a cache of their own,
+// because the mirror lives in the namespace of the controller, which the manager's cache may not cover.
+func (m *RunnerArtifactMirrorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	name := "runnerartifactmirror-controller"
+	if m.Name != "" {
+		name = m.Name
+	}
+
+	mirrorCache, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:               mgr.GetScheme(),
+		Mapper:               mgr.GetRESTMapper(),
+		DefaultNamespaces:    map[string]cache.Config{m.Namespace: {}},
+		DefaultLabelSelector: labels.SelectorFromSet(m.labels()),
+	})
+	if err != nil {
+		return fmt.Errorf("creating cache for runner artifact mirror: %w", err)
+	}
+
+	if err := mgr.Add(mirrorCache); err != nil {
+		return err
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: m.Namespace, Name: m.name()}}
+	enqueueMirror := handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{req}
+	})
+
+	// Nothing is watched until the resources are created, so the mirror is reconciled once on start.
+	initial := make(chan event.GenericEvent, 1)
+	initial <- event.GenericEvent{Object: &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: req.Namespace, Name: req.Name}}}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WatchesRawSource(&source.Channel{Source: initial}, enqueueMirror).
+		WatchesRawSource(source.Kind(mirrorCache, &appsv1.Deployment{}), enqueueMirror).
+		WatchesRawSource(source.Kind(mirrorCache, &corev1.Service{}), enqueueMirror).
+		WatchesRawSource(source.Kind(mirrorCache, &corev1.PersistentVolumeClaim{}), enqueueMirror).
+		Complete(m)
+}
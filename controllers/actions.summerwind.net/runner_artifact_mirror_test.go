@@ -0,0 +1,140 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	arcv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRunnerArtifactMirror_Ensure(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	m := &RunnerArtifactMirrorReconciler{
+		Client:           c,
+		Log:              logr.Discard(),
+		Namespace:        "arc-system",
+		StorageClassName: "standard",
+		StorageSize:      resource.MustParse("5Gi"),
+	}
+
+	require.Equal(t, "http://actions-runner-artifact-mirror.arc-system.svc:8080", m.URL())
+
+	ctx := context.Background()
+
+	// Ensuring twice must be idempotent
+	require.NoError(t, m.ensure(ctx))
+	require.NoError(t, m.ensure(ctx))
+
+	key := types.NamespacedName{Namespace: "arc-system", Name: DefaultRunnerArtifactMirrorName}
+
+	var pvc corev1.PersistentVolumeClaim
+	require.NoError(t, c.Get(ctx, key, &pvc))
+	require.Equal(t, "standard", *pvc.Spec.StorageClassName)
+	storage := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	require.Equal(t, "5Gi", storage.String())
+	require.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}, pvc.Spec.AccessModes)
+
+	var deployment appsv1.Deployment
+	require.NoError(t, c.Get(ctx, key, &deployment))
+	require.Equal(t, DefaultRunnerArtifactMirrorImage, deployment.Spec.Template.Spec.Containers[0].Image)
+	require.Equal(t, DefaultRunnerArtifactMirrorName, deployment.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+
+	var svc corev1.Service
+	require.NoError(t, c.Get(ctx, key, &svc))
+	require.Equal(t, int32(8080), svc.Spec.Ports[0].Port)
+	require.Equal(t, deployment.Spec.Template.Labels, svc.Spec.Selector)
+
+	// Changing the image is propagated to the existing deployment
+	m.Image = "registry.internal/nginx:1"
+	require.NoError(t, m.ensure(ctx))
+	require.NoError(t, c.Get(ctx, key, &deployment))
+	require.Equal(t, "registry.internal/nginx:1", deployment.Spec.Template.Spec.Containers[0].Image)
+
+	// Drift, like a deleted service or a scaled down deployment, is repaired on the next reconciliation
+	require.NoError(t, c.Delete(ctx, &svc))
+	zero := int32(0)
+	deployment.Spec.Replicas = &zero
+	require.NoError(t, c.Update(ctx, &deployment))
+
+	_, err := m.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.NoError(t, c.Get(ctx, key, &svc))
+	require.NoError(t, c.Get(ctx, key, &deployment))
+	require.Equal(t, int32(1), *deployment.Spec.Replicas)
+}
+
+func TestRunnerArtifactMirror_ReadWriteOnce(t *testing.T) {
+	m := &RunnerArtifactMirrorReconciler{
+		Namespace:  "arc-system",
+		AccessMode: corev1.ReadWriteOnce,
+	}
+
+	require.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, m.newPersistentVolumeClaim().Spec.AccessModes)
+	require.Equal(t, appsv1.RecreateDeploymentStrategyType, m.newDeployment().Spec.Strategy.Type, "the old and the new pods can't mount a ReadWriteOnce volume on different nodes")
+
+	m.AccessMode = ""
+	require.Equal(t, appsv1.RollingUpdateDeploymentStrategyType, m.newDeployment().Spec.Strategy.Type)
+}
+
+func TestNewRunnerPod_ArtifactMirror(t *testing.T) {
+	envOf := func(pod corev1.Pod) map[string]string {
+		env := map[string]string{}
+		for _, e := range pod.Spec.Containers[0].Env {
+			env[e.Name] = e.Value
+		}
+		return env
+	}
+
+	defaults := RunnerPodDefaults{
+		RunnerImage:       "default-runner-image",
+		DockerImage:       "default-docker-image",
+		ArtifactMirrorURL: "http://mirror.arc-system.svc:8080",
+	}
+
+	config := arcv1alpha1.RunnerConfig{
+		RunnerVersion:         "2.311.0",
+		ContainerHooksVersion: "0.5.0",
+	}
+
+	pod, err := newRunnerPod(corev1.Pod{}, config, "api.github.com", defaults)
+	require.NoError(t, err)
+
+	env := envOf(pod)
+	require.Equal(t, "http://mirror.arc-system.svc:8080", env[EnvVarRunnerArtifactMirrorURL])
+	require.Equal(t, "2.311.0", env[EnvVarRunnerDownloadVersion])
+	require.NotContains(t, env, EnvVarRunnerContainerHooksDownloadVersion, "container hooks are only relevant to the kubernetes container mode")
+
+	kubernetesModeTemplate := corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:         "runner",
+					VolumeMounts: []corev1.VolumeMount{{Name: "work", MountPath: "/runner/_work"}},
+				},
+			},
+			Volumes: []corev1.Volume{newWorkGenericEphemeralVolume(t, "10Gi")},
+		},
+	}
+
+	pod, err = newRunnerPodWithContainerMode("kubernetes", kubernetesModeTemplate, config, "api.github.com", defaults)
+	require.NoError(t, err)
+	require.Equal(t, "0.5.0", envOf(pod)[EnvVarRunnerContainerHooksDownloadVersion])
+
+	pod, err = newRunnerPod(corev1.Pod{}, arcv1alpha1.RunnerConfig{}, "api.github.com", RunnerPodDefaults{RunnerImage: "default-runner-image"})
+	require.NoError(t, err)
+	require.NotContains(t, envOf(pod), EnvVarRunnerArtifactMirrorURL)
+}
@@ -0,0 +1,246 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultRunnerUnregistrationWorkers is the number of RemoveRunner calls made concurrently while unregistering runners in bulk.
+	DefaultRunnerUnregistrationWorkers = 10
+
+	// DefaultRunnerUnregistrationsPerSecond is the rate of RemoveRunner calls made while unregistering runners in bulk.
+	DefaultRunnerUnregistrationsPerSecond = 10
+)
+
+// RunnerBulkUnregistrar unregisters the runners of a scaled-down RunnerReplicaSet from GitHub
+// through a pool of workers sharing a rate limit, instead of one RemoveRunner call per runner pod reconcilation.
+//
+// A runner pod is annotated as unregistered once its runner is removed, so that the runner pod controller
+// deletes it without calling the GitHub API again.
+// Runners that can't be unregistered in bulk, like busy ones or ones without a known ID yet,
+// are left to the graceful stop of the runner pod controller.
+type RunnerBulkUnregistrar struct {
+	Workers int
+
+	limiter *rate.Limiter
+}
+
+// NewRunnerBulkUnregistrar returns an unregistrar that calls RemoveRunner at most perSecond times per second
+// from up to workers goroutines at once.
+func NewRunnerBulkUnregistrar(perSecond float64, workers int) *RunnerBulkUnregistrar {
+	if workers <= 0 {
+		workers = DefaultRunnerUnregistrationWorkers
+	}
+
+	limit := rate.Limit(perSecond)
+	if perSecond <= 0 {
+		limit = rate.Inf
+	}
+
+	return &RunnerBulkUnregistrar{
+		Workers: workers,
+		limiter: rate.NewLimiter(limit, workers),
+	}
+}
+
+type bulkUnregistration struct {
+	runner v1alpha1.Runner
+	pod    *corev1.Pod
+	ghc    *github.Client
+	id     int64
+}
+
+// Unregister removes the runners that were requested for unregistration from GitHub.
+//
+// The errors of all the runners are aggregated into the returned error.
+// When GitHub rate-limited the calls, the remaining runners are skipped and the returned duration tells how long to wait before retrying.
+func (u *RunnerBulkUnregistrar) Unregister(ctx context.Context, c client.Client, log logr.Logger, ghClient *MultiGitHubClient, runners []v1alpha1.Runner) (time.Duration, error) {
+	var errs []error
+
+	var pending []bulkUnregistration
+	for _, r := range runners {
+		item, ok, err := u.prepare(ctx, c, ghClient, r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("runner %s: %w", r.Name, err))
+			continue
+		}
+		if ok {
+			pending = append(pending, item)
+		}
+	}
+
+	if len(pending) == 0 {
+		return 0, errors.Join(errs...)
+	}
+
+	log.V(1).Info("Unregistering runners in bulk", "count", len(pending), "workers", u.Workers)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu         sync.Mutex
+		retryAfter time.Duration
+		wg         sync.WaitGroup
+	)
+
+	items := make(chan bulkUnregistration)
+
+	for i := 0; i < min(u.Workers, len(pending)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for item := range items {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				d, err := u.unregister(ctx, c, log, item)
+				if err == nil {
+					continue
+				}
+
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("runner %s: %w", item.runner.Name, err))
+				if d > 0 {
+					retryAfter = max(retryAfter, d)
+					// The other calls would be rate-limited too
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, item := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		items <- item
+	}
+	close(items)
+
+	wg.Wait()
+
+	return retryAfter, errors.Join(errs...)
+}
+
+// prepare returns the runner to unregister in bulk along with its pod and ID.
+// It returns false when the runner isn't requested for unregistration, or is left to the runner pod controller.
+func (u *RunnerBulkUnregistrar) prepare(ctx context.Context, c client.Client, ghClient *MultiGitHubClient, r v1alpha1.Runner) (bulkUnregistration, bool, error) {
+	item := bulkUnregistration{runner: r}
+
+	if _, ok := getAnnotation(&r, AnnotationKeyUnregistrationRequestTimestamp); !ok {
+		return item, false, nil
+	}
+
+	var pod corev1.Pod
+	if err := c.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: r.Name}, &pod); err != nil {
+		return item, false, client.IgnoreNotFound(err)
+	}
+
+	if _, ok := getAnnotation(&pod, AnnotationKeyUnregistrationCompleteTimestamp); ok {
+		return item, false, nil
+	}
+
+	// A runner that is waiting for its job to complete, or has already stopped, doesn't need to be removed by ARC
+	if _, ok := getAnnotation(&pod, AnnotationKeyRunnerCompletionWaitStartTimestamp); ok {
+		return item, false, nil
+	}
+	if isWarmStandby(&pod) || runnerPodOrContainerIsStopped(&pod) {
+		return item, false, nil
+	}
+
+	ghc, err := ghClient.InitForRunner(ctx, &r)
+	if err != nil {
+		return item, false, err
+	}
+
+	item.pod = &pod
+	item.ghc = ghc
+
+	if id, ok := getAnnotation(&pod, AnnotationKeyRunnerID); ok {
+		v, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return item, false, err
+		}
+
+		item.id = v
+
+		return item, true, nil
+	}
+
+	// The runners are listed once for all the runners of the scope and cached
+	runner, err := getRunner(ctx, ghc, r.Spec.Enterprise, r.Spec.Organization, r.Spec.Repository, r.Name)
+	if err != nil {
+		return item, false, err
+	}
+
+	// A runner busy running a job would make RemoveRunner fail with 422 anyway
+	if runner == nil || runner.ID == nil || runner.GetBusy() {
+		return item, false, nil
+	}
+
+	item.id = runner.GetID()
+
+	return item, true, nil
+}
+
+// unregister removes the runner and annotates its pod as unregistered.
+// It returns a non-zero duration along with the error when the call was rate-limited.
+func (u *RunnerBulkUnregistrar) unregister(ctx context.Context, c client.Client, log logr.Logger, item bulkUnregistration) (time.Duration, error) {
+	if err := u.limiter.Wait(ctx); err != nil {
+		return 0, err
+	}
+
+	log = log.WithValues("runner", item.runner.Name, "runnerID", item.id)
+
+	spec := item.runner.Spec
+	if _, err := unregisterRunner(ctx, item.ghc, spec.Enterprise, spec.Organization, spec.Repository, item.id); err != nil {
+		if retryAfter, ok := github.RetryAfterRateLimit(err); ok {
+			return max(retryAfter, retryDelayOnGitHubAPIRateLimitError), err
+		}
+
+		errRes := &gogithub.ErrorResponse{}
+		if !errors.As(err, &errRes) {
+			return 0, err
+		}
+
+		switch errRes.Response.StatusCode {
+		case http.StatusNotFound:
+			// The runner has already unregistered itself
+		case http.StatusUnprocessableEntity, http.StatusForbidden:
+			// The runner is still busy, or can't be unregistered with the credentials.
+			// The runner pod controller handles both on its graceful stop.
+			log.V(1).Info("Leaving runner unregistration to the runner pod controller", "error", err.Error())
+			return 0, nil
+		default:
+			return 0, err
+		}
+	} else {
+		log.Info("Runner has just been unregistered in bulk.")
+	}
+
+	if _, err := annotatePodOnce(ctx, c, log, item.pod, AnnotationKeyUnregistrationCompleteTimestamp, time.Now().Format(time.RFC3339)); err != nil && !kerrors.IsNotFound(err) {
+		return 0, err
+	}
+
+	return 0, nil
+}
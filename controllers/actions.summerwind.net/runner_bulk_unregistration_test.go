@@ -0,0 +1,78 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunnerBulkUnregistrar(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	server := fake.NewServer(fake.WithListRunnersResponse(200, fake.RunnersListBody))
+	defer server.Close()
+
+	runner := func(name string, rc v1alpha1.RunnerConfig, requested bool) v1alpha1.Runner {
+		r := v1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       v1alpha1.RunnerSpec{RunnerConfig: rc},
+		}
+		if requested {
+			r.Annotations = map[string]string{AnnotationKeyUnregistrationRequestTimestamp: "2024-01-01T00:00:00Z"}
+		}
+		return r
+	}
+
+	pod := func(name, id string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        name,
+				Annotations: map[string]string{AnnotationKeyRunnerID: id},
+			},
+		}
+	}
+
+	runners := []v1alpha1.Runner{
+		runner("removed-0", v1alpha1.RunnerConfig{Repository: "test/valid"}, true),
+		runner("removed-1", v1alpha1.RunnerConfig{Repository: "test/valid"}, true),
+		runner("failed", v1alpha1.RunnerConfig{Organization: "error"}, true),
+		runner("kept", v1alpha1.RunnerConfig{Repository: "test/valid"}, false),
+	}
+
+	c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pod("removed-0", "0"),
+		pod("removed-1", "1"),
+		pod("failed", "1"),
+		pod("kept", "0"),
+	).Build()
+
+	u := NewRunnerBulkUnregistrar(0, 2)
+
+	ctx := context.Background()
+	retryAfter, err := u.Unregister(ctx, c, logr.Discard(), NewMultiGitHubClient(c, newGithubClient(server)), runners)
+	require.Zero(t, retryAfter)
+
+	// The errors of the runners that failed are aggregated, without stopping the others
+	require.ErrorContains(t, err, "runner failed")
+
+	for name, unregistered := range map[string]bool{"removed-0": true, "removed-1": true, "failed": false, "kept": false} {
+		var p corev1.Pod
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &p))
+
+		_, ok := getAnnotation(&p, AnnotationKeyUnregistrationCompleteTimestamp)
+		require.Equal(t, unregistered, ok, name)
+	}
+}
@@ -42,6 +42,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 )
 
 const (
@@ -86,6 +87,11 @@ type RunnerPodDefaults struct {
 	DockerGID string
 
 	UseRunnerStatusUpdateHook bool
+
+	// ArtifactMirrorURL is the base URL of the in-cluster runner artifact mirror.
+	// When set, runners download the runner release and container hooks requested via
+	// spec.runnerVersion and spec.containerHooksVersion from it instead of github.com.
+	ArtifactMirrorURL string
 }
 
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners,verbs=get;list;watch;create;update;patch;delete
@@ -152,6 +158,15 @@ func (r *RunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		phase = "Created"
 	}
 
+	if image, ok := getAnnotation(&runner, AnnotationKeyCheckpointImage); ok {
+		if restored, _ := getAnnotation(&pod, AnnotationKeyRestoredCheckpointImage); restored == image {
+			if err := clearRunnerCheckpointImage(ctx, r.Client, &runner); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+	}
+
 	ready := runnerPodReady(&pod)
 
 	if (runner.Status.Phase != phase || runner.Status.Ready != ready) && !r.RunnerPodDefaults.UseRunnerStatusUpdateHook || runner.Status.Phase == "" && r.RunnerPodDefaults.UseRunnerStatusUpdateHook {
@@ -289,10 +304,13 @@ func (r *RunnerReconciler) processRunnerDeletion(runner v1alpha1.Runner, ctx con
 }
 
 func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1alpha1.Runner, log logr.Logger) (reconcile.Result, error) {
-	if updated, err := r.updateRegistrationToken(ctx, runner); err != nil {
-		return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
-	} else if updated {
-		return ctrl.Result{Requeue: true}, nil
+	// A runner with a jit config is registered by the runner pod controller once its pod is created
+	if !runnerConfigUsesJIT(runner.Spec.RunnerConfig) {
+		if updated, err := r.updateRegistrationToken(ctx, runner); err != nil {
+			return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
+		} else if updated {
+			return ctrl.Result{Requeue: true}, nil
+		}
 	}
 
 	newPod, err := r.newPod(runner)
@@ -301,6 +319,11 @@ func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1a
 		return ctrl.Result{}, err
 	}
 
+	checkpointImage, restoring := runnerCheckpointImage(&runner, nil)
+	if restoring {
+		restoreRunnerPodFromCheckpoint(&newPod, checkpointImage)
+	}
+
 	needsServiceAccount := runner.Spec.ServiceAccountName == "" && (r.RunnerPodDefaults.UseRunnerStatusUpdateHook || runner.Spec.ContainerMode == "kubernetes")
 	if needsServiceAccount {
 		serviceAccount := &corev1.ServiceAccount{
@@ -410,6 +433,15 @@ func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1a
 	r.Recorder.Event(&runner, corev1.EventTypeNormal, "PodCreated", fmt.Sprintf("Created pod '%s'", newPod.Name))
 	log.Info("Created runner pod", "repository", runner.Spec.Repository)
 
+	if restoring {
+		log.Info("Restored runner pod from checkpoint", "image", checkpointImage)
+
+		if err := clearRunnerCheckpointImage(ctx, r.Client, &runner); err != nil {
+			// Retried on the next reconciliation, which sees the restored pod
+			log.Error(err, "Failed to clear checkpoint image from runner")
+		}
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -446,6 +478,16 @@ func (r *RunnerReconciler) updateRegistrationToken(ctx context.Context, runner v
 		return false, err
 	}
 
+	if len(runner.Spec.AllowedRepositories) > 0 {
+		// The runner group is restricted before the runner gets the registration token,
+		// so that the runner never runs a job of the repositories that aren't allowed
+		if err := ghc.EnsureRunnerGroupRepositoryAccess(ctx, runner.Spec.Organization, runner.Spec.Group, runner.Spec.AllowedRepositories); err != nil {
+			r.Recorder.Event(&runner, corev1.EventTypeWarning, "FailedRestrictRunnerGroup", fmt.Sprintf("Restricting runner group %s to the allowed repositories failed: %v", runner.Spec.Group, err))
+			log.Error(err, "Failed to restrict runner group to the allowed repositories", "group", runner.Spec.Group)
+			return false, err
+		}
+	}
+
 	rt, err := ghc.GetRegistrationToken(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
 	if err != nil {
 		// An error can be a permanent, permission issue like the below:
@@ -472,6 +514,10 @@ func (r *RunnerReconciler) updateRegistrationToken(ctx context.Context, runner v
 	}
 
 	r.Recorder.Event(&runner, corev1.EventTypeNormal, "RegistrationTokenUpdated", "Successfully update registration token")
+
+	if expiresAt, ok := ghc.TokenExpiration(); ok {
+		warnIfGitHubTokenExpiring(r.Recorder, &runner, expiresAt, time.Now())
+	}
 	log.Info("Updated registration token", "repository", runner.Spec.Repository)
 
 	return true, nil
@@ -597,6 +643,12 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 		return pod, err
 	}
 
+	// The gate is added to promoted runners too, so that the pod spec doesn't depend on
+	// whether the pod was created before or after the promotion.
+	if _, ok := runner.Annotations[AnnotationKeyWarmStandby]; ok {
+		addWarmStandbyGate(&pod)
+	}
+
 	// Customize the pod spec according to the runner spec
 	runnerSpec := runner.Spec
 
@@ -700,6 +752,15 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 		pod.Spec.DNSConfig = runnerSpec.DnsConfig
 	}
 
+	if runnerSpec.HostNetwork {
+		pod.Spec.HostNetwork = true
+
+		// Cluster services wouldn't be resolvable with the default ClusterFirst policy, which falls back to the DNS of the node
+		if runnerSpec.DnsPolicy == "" {
+			pod.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+		}
+	}
+
 	if runnerSpec.RuntimeClassName != nil {
 		pod.Spec.RuntimeClassName = runnerSpec.RuntimeClassName
 	}
@@ -723,10 +784,14 @@ func mutatePod(pod *corev1.Pod, token string) *corev1.Pod {
 		setRunnerEnv(updated, EnvVarRunnerName, pod.ObjectMeta.Name)
 	}
 
-	if getRunnerEnv(pod, EnvVarRunnerToken) == "" {
+	if podUsesJITConfig(pod) {
+		gatePodOnJITConfig(updated)
+	} else if getRunnerEnv(pod, EnvVarRunnerToken) == "" {
 		setRunnerEnv(updated, EnvVarRunnerToken, token)
 	}
 
+	addRunnerSlotContainers(updated)
+
 	return updated
 }
 
@@ -802,6 +867,17 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 	if runnerSpec.GitHubAPICredentialsFrom != nil {
 		template.ObjectMeta.Annotations = CloneAndAddLabel(template.ObjectMeta.Annotations, annotationKeyGitHubAPICredsSecret, runnerSpec.GitHubAPICredentialsFrom.SecretRef.Name)
 	}
+	if runnerSpec.IdleTimeout != nil && !ephemeral {
+		template.ObjectMeta.Annotations = CloneAndAddLabel(template.ObjectMeta.Annotations, AnnotationKeyIdleTimeout, runnerSpec.IdleTimeout.Duration.String())
+	}
+	if runnerConfigUsesJIT(runnerSpec) {
+		// The runner container is gated on the jit config along with the runner name, once the pod name is known
+		template.ObjectMeta.Annotations = CloneAndAddLabel(template.ObjectMeta.Annotations, AnnotationKeyJITConfig, "true")
+	}
+	if slots := runnerConfigSlots(runnerSpec); slots > 1 && containerMode != "kubernetes" {
+		// The slot containers are added along with the registration token, once the pod name is known
+		template.ObjectMeta.Annotations = CloneAndAddLabel(template.ObjectMeta.Annotations, AnnotationKeySlotsPerPod, strconv.Itoa(slots))
+	}
 
 	workDir := runnerSpec.WorkDir
 	if workDir == "" {
@@ -873,6 +949,27 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 		},
 	}
 
+	if d.ArtifactMirrorURL != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  EnvVarRunnerArtifactMirrorURL,
+			Value: d.ArtifactMirrorURL,
+		})
+	}
+
+	if runnerSpec.RunnerVersion != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  EnvVarRunnerDownloadVersion,
+			Value: runnerSpec.RunnerVersion,
+		})
+	}
+
+	if runnerSpec.ContainerHooksVersion != "" && containerMode == "kubernetes" {
+		env = append(env, corev1.EnvVar{
+			Name:  EnvVarRunnerContainerHooksDownloadVersion,
+			Value: runnerSpec.ContainerHooksVersion,
+		})
+	}
+
 	var seLinuxOptions *corev1.SELinuxOptions
 	if template.Spec.SecurityContext != nil {
 		seLinuxOptions = template.Spec.SecurityContext.SELinuxOptions
@@ -983,6 +1080,14 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 		}...)
 	}
 
+	if err := applyWorkVolume(pod, runnerContainer, runnerSpec.WorkVolume, containerMode, workDir); err != nil {
+		return *pod, err
+	}
+
+	if err := applyShmSize(pod, runnerContainer, runnerSpec.ShmSize); err != nil {
+		return *pod, err
+	}
+
 	//
 	// /runner must be generated on runtime from /runnertmp embedded in the container image.
 	//
@@ -1279,6 +1384,9 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 		}
 	}
 
+	applySpread(pod, runnerSpec.Spread)
+	applyNetworkCheck(pod, runnerSpec.NetworkCheck, githubBaseURL)
+
 	return *pod, nil
 }
 
@@ -1294,7 +1402,7 @@ func (r *RunnerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&v1alpha1.Runner{}).
 		Owns(&corev1.Pod{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &v1alpha1.Runner{}, r))
 }
 
 func addFinalizer(finalizers []string, finalizerName string) ([]string, bool) {
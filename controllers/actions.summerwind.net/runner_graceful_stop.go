@@ -26,13 +26,17 @@ import (
 // This function is designed to complete a lengthy graceful stop process in a unblocking way.
 // When it wants to be retried later, the function returns a non-nil *ctrl.Result as the second return value, may or may not populating the error in the second return value.
 // The caller is expected to return the returned ctrl.Result and error to postpone the current reconcilation loop and trigger a scheduled retry.
-func tickRunnerGracefulStop(ctx context.Context, retryDelay time.Duration, log logr.Logger, ghClient *github.Client, c client.Client, enterprise, organization, repository, runner string, pod *corev1.Pod) (*corev1.Pod, *ctrl.Result, error) {
+func tickRunnerGracefulStop(ctx context.Context, retryDelay time.Duration, now time.Time, log logr.Logger, ghClient *github.Client, c client.Client, enterprise, organization, repository, runner string, pod *corev1.Pod) (*corev1.Pod, *ctrl.Result, error) {
 	pod, err := annotatePodOnce(ctx, c, log, pod, AnnotationKeyUnregistrationStartTimestamp, time.Now().Format(time.RFC3339))
 	if err != nil {
 		return nil, &ctrl.Result{}, err
 	}
 
-	if res, err := ensureRunnerUnregistration(ctx, retryDelay, log, ghClient, c, enterprise, organization, repository, runner, pod); res != nil {
+	if res, err := ensureRunnerSlotsUnregistration(ctx, retryDelay, log, ghClient, c, enterprise, organization, repository, runner, pod); res != nil {
+		return nil, res, err
+	}
+
+	if res, err := ensureRunnerUnregistration(ctx, retryDelay, now, log, ghClient, c, enterprise, organization, repository, runner, pod); res != nil {
 		return nil, res, err
 	}
 
@@ -69,7 +73,7 @@ func annotatePodOnce(ctx context.Context, c client.Client, log logr.Logger, pod
 }
 
 // If the first return value is nil, it's safe to delete the runner pod.
-func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, log logr.Logger, ghClient *github.Client, c client.Client, enterprise, organization, repository, runner string, pod *corev1.Pod) (*ctrl.Result, error) {
+func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, now time.Time, log logr.Logger, ghClient *github.Client, c client.Client, enterprise, organization, repository, runner string, pod *corev1.Pod) (*ctrl.Result, error) {
 	var runnerID *int64
 
 	if id, ok := getAnnotation(pod, AnnotationKeyRunnerID); ok {
@@ -81,7 +85,8 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 		runnerID = &v
 	}
 
-	if runnerID == nil {
+	// A standby runner has never registered itself, so there's no need to look it up.
+	if runnerID == nil && (pod == nil || !isWarmStandby(pod)) {
 		runner, err := getRunner(ctx, ghClient, enterprise, organization, repository, runner)
 		if err != nil {
 			return &ctrl.Result{}, err
@@ -98,7 +103,9 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 		// If it's already unregistered in the previous reconcilation loop,
 		// you can safely assume that it won't get registered again so it's safe to delete the runner pod.
 		log.Info("Runner pod is marked as already unregistered.")
-	} else if runnerID == nil && !runnerPodOrContainerIsStopped(pod) && !podConditionTransitionTimeAfter(pod, corev1.PodReady, registrationTimeout) &&
+	} else if runnerID == nil && pod != nil && isWarmStandby(pod) {
+		log.Info("Runner pod is in warm standby and has never registered itself. Marking unregistration as completed.")
+	} else if runnerID == nil && !runnerPodOrContainerIsStopped(pod) && !podRegistrationTimedOut(pod, now) &&
 		!podIsPending(pod) {
 
 		log.Info(
@@ -141,7 +148,7 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 				"Marking unregistration as completed anyway because there's nothing ARC can do.",
 			"registrationTimeout", registrationTimeout,
 		)
-	} else if runnerID == nil && podConditionTransitionTimeAfter(pod, corev1.PodReady, registrationTimeout) {
+	} else if runnerID == nil && podRegistrationTimedOut(pod, now) {
 		log.Info(
 			"Unregistration started before runner ID is assigned and the runner was unable to obtain ID within registration timeout. "+
 				"Perhaps the runner has communication issue, or a firewall egress rule is dropping traffic to GitHub API, or GitHub API is unavailable? "+
@@ -180,18 +187,21 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 			"pod.phase", pod.Status.Phase,
 		)
 	} else if ok, err := unregisterRunner(ctx, ghClient, enterprise, organization, repository, *runnerID); err != nil {
-		if errors.Is(err, &gogithub.RateLimitError{}) {
+		if retryAfter, ok := github.RetryAfterRateLimit(err); ok {
+			retryAfter = max(retryAfter, retryDelayOnGitHubAPIRateLimitError)
+
 			// We log the underlying error when we failed calling GitHub API to list or unregisters,
 			// or the runner is still busy.
 			log.Error(
 				err,
 				fmt.Sprintf(
 					"Failed to unregister runner due to GitHub API rate limits. Delaying retry for %s to avoid excessive GitHub API calls",
-					retryDelayOnGitHubAPIRateLimitError,
+					retryAfter,
 				),
 			)
 
-			return &ctrl.Result{RequeueAfter: retryDelayOnGitHubAPIRateLimitError}, err
+			// The error isn't returned, as controller-runtime would then retry with its own backoff instead of retryAfter
+			return &ctrl.Result{RequeueAfter: retryAfter}, nil
 		}
 
 		log.V(1).Info("Failed to unregister runner before deleting the pod.", "error", err)
@@ -296,7 +306,7 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 
 func ensureRunnerPodRegistered(ctx context.Context, log logr.Logger, ghClient *github.Client, c client.Client, enterprise, organization, repository, runner string, pod *corev1.Pod) (*corev1.Pod, *ctrl.Result, error) {
 	_, hasRunnerID := getAnnotation(pod, AnnotationKeyRunnerID)
-	if runnerPodOrContainerIsStopped(pod) || hasRunnerID {
+	if runnerPodOrContainerIsStopped(pod) || hasRunnerID || isWarmStandby(pod) {
 		return pod, nil, nil
 	}
 
@@ -458,16 +468,5 @@ func unregisterRunner(ctx context.Context, client *github.Client, enterprise, or
 }
 
 func getRunner(ctx context.Context, client *github.Client, enterprise, org, repo, name string) (*gogithub.Runner, error) {
-	runners, err := client.ListRunners(ctx, enterprise, org, repo)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, runner := range runners {
-		if runner.GetName() == name {
-			return runner, nil
-		}
-	}
-
-	return nil, nil
+	return client.GetRunner(ctx, enterprise, org, repo, name)
 }
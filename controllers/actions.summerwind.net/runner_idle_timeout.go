@@ -0,0 +1,266 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions/actions-runner-controller/github"
+)
+
+// idleTimeoutCheckInterval is the interval between two checks of whether the runner of a pod with an idle timeout is busy.
+// It matches the duration the ListRunners responses are cached for, so more frequent checks would see no change.
+const idleTimeoutCheckInterval = time.Minute
+
+// syncRunnerPodIdleTimeout tracks since when the runner of the pod has been idle, and marks the pod with
+// AnnotationKeyIdleTimeoutTimestamp once it has been idle for its idle timeout, so that its pool unregisters and deletes it.
+func (r *RunnerPodReconciler) syncRunnerPodIdleTimeout(ctx context.Context, log logr.Logger, ghc *github.Client, enterprise, org, repo string, pod *corev1.Pod) (ctrl.Result, error) {
+	v, _ := getAnnotation(pod, AnnotationKeyIdleTimeout)
+	timeout, err := time.ParseDuration(v)
+	if err != nil || timeout <= 0 {
+		log.Info("Ignoring invalid idle timeout of runner pod", "idleTimeout", v)
+		return ctrl.Result{}, nil
+	}
+
+	if _, ok := getAnnotation(pod, AnnotationKeyIdleTimeoutTimestamp); ok {
+		// The pool takes it from here
+		return ctrl.Result{}, nil
+	}
+
+	if podRunnerID(pod) == "" {
+		return ctrl.Result{RequeueAfter: idleTimeoutCheckInterval}, nil
+	}
+
+	runner, err := getRunner(ctx, ghc, enterprise, org, repo, pod.Name)
+	if retryAfter, ok := github.RetryAfterRateLimit(err); ok {
+		return ctrl.Result{RequeueAfter: max(retryAfter, idleTimeoutCheckInterval)}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if runner == nil {
+		return ctrl.Result{RequeueAfter: idleTimeoutCheckInterval}, nil
+	}
+
+	busy := runner.GetBusy()
+	if slots := podRunnerSlots(pod); slots > 1 {
+		// The runner pod is idle only when all of its slots are
+		runners, err := ghc.ListRunners(ctx, enterprise, org, repo)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		busy = len(busyRunnerSlots(runners, pod.Name, slots)) > 0
+	}
+
+	if busy {
+		if _, ok := getAnnotation(pod, AnnotationKeyIdleSinceTimestamp); ok {
+			updated := pod.DeepCopy()
+			delete(updated.Annotations, AnnotationKeyIdleSinceTimestamp)
+
+			if err := r.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+				log.Error(err, "Failed to patch runner pod to remove the idle-since timestamp")
+				return ctrl.Result{}, err
+			}
+		}
+
+		return ctrl.Result{RequeueAfter: idleTimeoutCheckInterval}, nil
+	}
+
+	now := time.Now()
+
+	v, ok := getAnnotation(pod, AnnotationKeyIdleSinceTimestamp)
+	if !ok {
+		if _, err := annotatePodOnce(ctx, r.Client, log, pod, AnnotationKeyIdleSinceTimestamp, now.Format(time.RFC3339)); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: min(timeout, idleTimeoutCheckInterval)}, nil
+	}
+
+	idleSince, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		idleSince = now
+	}
+
+	if idle := now.Sub(idleSince); idle < timeout {
+		return ctrl.Result{RequeueAfter: min(timeout-idle, idleTimeoutCheckInterval)}, nil
+	}
+
+	if _, err := annotatePodOnce(ctx, r.Client, log, pod, AnnotationKeyIdleTimeoutTimestamp, now.Format(time.RFC3339)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.Recorder.Event(pod, corev1.EventTypeNormal, "IdleTimeout", fmt.Sprintf("Runner has been idle since %s, longer than its idle timeout of %s", v, timeout))
+	log.Info("Runner has been idle longer than its idle timeout", "idleSince", v, "idleTimeout", timeout)
+
+	return ctrl.Result{}, nil
+}
+
+// idleScaleIn is the number of runners of a pool deleted on idle timeout that aren't replaced,
+// and the desired replicas of the pool when they were deleted.
+type idleScaleIn struct {
+	runners  int
+	replicas int
+}
+
+func parseIdleScaleIn(v string) (idleScaleIn, bool) {
+	runners, replicas, ok := strings.Cut(v, "/")
+	if !ok {
+		return idleScaleIn{}, false
+	}
+
+	n, err := strconv.Atoi(runners)
+	if err != nil || n < 0 {
+		return idleScaleIn{}, false
+	}
+
+	d, err := strconv.Atoi(replicas)
+	if err != nil || d < 0 {
+		return idleScaleIn{}, false
+	}
+
+	return idleScaleIn{runners: n, replicas: d}, true
+}
+
+func (s idleScaleIn) String() string {
+	return fmt.Sprintf("%d/%d", s.runners, s.replicas)
+}
+
+// syncIdleScaleIn starts unregistering the runners of the pool that have been idle for their idle timeout,
+// and returns the desired replicas of the pool less the runners deleted this way.
+//
+// The deleted runners aren't replaced while the desired replicas stay the same or increase,
+// as the replicas added on demand are served by new runners on top of the remaining ones.
+// When the desired replicas decrease, the decrease is counted against the deleted runners first.
+func syncIdleScaleIn(ctx context.Context, c client.Client, log logr.Logger, now time.Time, pool client.Object, idleTimeout *metav1.Duration, replicas int, owners []client.Object) (int, error) {
+	v, recorded := getAnnotation(pool, AnnotationKeyIdleScaleIn)
+
+	if idleTimeout == nil {
+		if recorded {
+			updated := pool.DeepCopyObject().(client.Object)
+			annotations := updated.GetAnnotations()
+			delete(annotations, AnnotationKeyIdleScaleIn)
+			updated.SetAnnotations(annotations)
+
+			if err := c.Patch(ctx, updated, client.MergeFrom(pool)); err != nil {
+				return 0, err
+			}
+		}
+
+		return replicas, nil
+	}
+
+	s, ok := parseIdleScaleIn(v)
+	if !ok {
+		s = idleScaleIn{replicas: replicas}
+	}
+
+	if replicas < s.replicas {
+		s.runners = max(s.runners-(s.replicas-replicas), 0)
+	}
+	s.replicas = replicas
+
+	terminated, err := terminateIdleRunnerOwners(ctx, c, log, now, owners)
+	if err != nil {
+		return 0, err
+	}
+
+	s.runners = min(s.runners+terminated, replicas)
+
+	if s.String() != v && (recorded || s.runners > 0) {
+		updated := pool.DeepCopyObject().(client.Object)
+		annotations := updated.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[AnnotationKeyIdleScaleIn] = s.String()
+		updated.SetAnnotations(annotations)
+
+		if err := c.Patch(ctx, updated, client.MergeFrom(pool)); err != nil {
+			log.Error(err, fmt.Sprintf("Failed to patch pool to have %s annotation", AnnotationKeyIdleScaleIn))
+			return 0, err
+		}
+	}
+
+	if s.runners > 0 {
+		log.V(1).Info("Not replacing runners deleted on idle timeout", "idleScaleIn", s.runners, "desired", replicas)
+	}
+
+	return replicas - s.runners, nil
+}
+
+// terminateIdleRunnerOwners starts the unregistration of the owners whose runner pods have all been idle for their idle timeout,
+// and returns the number of such owners.
+func terminateIdleRunnerOwners(ctx context.Context, c client.Client, log logr.Logger, now time.Time, owners []client.Object) (int, error) {
+	var terminated int
+
+	for _, o := range owners {
+		if !o.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		if _, ok := getAnnotation(o, AnnotationKeyUnregistrationRequestTimestamp); ok {
+			continue
+		}
+
+		log := log.WithValues("owner", client.ObjectKeyFromObject(o))
+
+		res, err := getPodsForOwner(ctx, c, log, o, now)
+		if err != nil {
+			return 0, err
+		}
+
+		if res == nil || len(res.pods) == 0 {
+			continue
+		}
+
+		idle := true
+		for _, pod := range res.pods {
+			if _, ok := getAnnotation(&pod, AnnotationKeyIdleTimeoutTimestamp); !ok {
+				idle = false
+				break
+			}
+		}
+
+		if !idle {
+			continue
+		}
+
+		timestamp := time.Now().Format(time.RFC3339)
+
+		for _, pod := range res.pods {
+			if _, err := annotatePodOnce(ctx, c, log, &pod, AnnotationKeyUnregistrationRequestTimestamp, timestamp); err != nil {
+				return 0, err
+			}
+		}
+
+		updated := o.DeepCopyObject().(client.Object)
+		annotations := updated.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[AnnotationKeyIdleTimeoutTimestamp] = timestamp
+		annotations[AnnotationKeyUnregistrationRequestTimestamp] = timestamp
+		updated.SetAnnotations(annotations)
+
+		if err := c.Patch(ctx, updated, client.MergeFrom(o)); err != nil {
+			log.Error(err, "Failed to patch owner to start the unregistration on idle timeout")
+			return 0, err
+		}
+
+		log.Info("Started unregistering runner on idle timeout")
+
+		terminated++
+	}
+
+	return terminated, nil
+}
@@ -0,0 +1,141 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncIdleScaleIn(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	idleTimeout := &metav1.Duration{Duration: 30 * time.Minute}
+
+	newRunner := func(name string) *v1alpha1.Runner {
+		return &v1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels:    map[string]string{LabelKeyRunnerTemplateHash: "abc"},
+			},
+		}
+	}
+
+	newPod := func(name string, idle bool) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        name,
+				Annotations: map[string]string{AnnotationKeyIdleTimeout: idleTimeout.Duration.String()},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		if idle {
+			pod.Annotations[AnnotationKeyIdleTimeoutTimestamp] = time.Now().Format(time.RFC3339)
+		}
+		return pod
+	}
+
+	tests := []struct {
+		name        string
+		idleTimeout *metav1.Duration
+		recorded    string
+		replicas    int
+		idle        bool
+		want        int
+		wantRecord  string
+	}{
+		{
+			name:        "idle runner",
+			idleTimeout: idleTimeout,
+			replicas:    2,
+			idle:        true,
+			want:        1,
+			wantRecord:  "1/2",
+		},
+		{
+			name:        "busy runner",
+			idleTimeout: idleTimeout,
+			replicas:    2,
+			want:        2,
+		},
+		{
+			name:        "more replicas after idle scale in",
+			idleTimeout: idleTimeout,
+			recorded:    "1/2",
+			replicas:    3,
+			want:        2,
+			wantRecord:  "1/3",
+		},
+		{
+			name:        "less replicas after idle scale in",
+			idleTimeout: idleTimeout,
+			recorded:    "2/5",
+			replicas:    4,
+			want:        3,
+			wantRecord:  "1/4",
+		},
+		{
+			name:        "less replicas than idle scale in",
+			idleTimeout: idleTimeout,
+			recorded:    "2/5",
+			replicas:    1,
+			want:        1,
+			wantRecord:  "0/1",
+		},
+		{
+			name:     "idle timeout removed",
+			recorded: "2/5",
+			replicas: 5,
+			want:     5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &v1alpha1.RunnerReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"}}
+			if tt.recorded != "" {
+				rs.Annotations = map[string]string{AnnotationKeyIdleScaleIn: tt.recorded}
+			}
+
+			runner := newRunner("example-1")
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(rs, runner, newPod("example-1", tt.idle)).Build()
+
+			ctx := context.Background()
+
+			got, err := syncIdleScaleIn(ctx, c, logr.Discard(), time.Now(), rs, tt.idleTimeout, tt.replicas, []client.Object{runner})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+
+			var updated v1alpha1.RunnerReplicaSet
+			require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &updated))
+			require.Equal(t, tt.wantRecord, updated.Annotations[AnnotationKeyIdleScaleIn])
+
+			var updatedRunner v1alpha1.Runner
+			require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-1"}, &updatedRunner))
+			_, unregistering := getAnnotation(&updatedRunner, AnnotationKeyUnregistrationRequestTimestamp)
+			require.Equal(t, tt.idle, unregistering)
+
+			if tt.idle {
+				res, err := getPodsForOwner(ctx, c, logr.Discard(), &updatedRunner, time.Now())
+				require.NoError(t, err)
+				require.Zero(t, res.running, "the runner pod unregistering on idle timeout must not be counted as running")
+				require.Equal(t, 1, res.terminating)
+			}
+		})
+	}
+}
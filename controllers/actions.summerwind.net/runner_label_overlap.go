@@ -0,0 +1,103 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// EventReasonOverlappingRunnerLabels is the reason of the warning event emitted when
+	// a RunnerDeployment or a RunnerSet advertises the same labels to the same scope as another one.
+	EventReasonOverlappingRunnerLabels = "OverlappingRunnerLabels"
+)
+
+// runnerPoolLabelSetKey returns a key that is identical for any two runner pools that
+// GitHub cannot tell apart when routing a job, that is, pools registering runners
+// to the same enterprise, organization or repository, within the same runner group, with the same set of labels.
+//
+// Labels are compared case-insensitively and regardless of the order, as GitHub does when matching runs-on.
+func runnerPoolLabelSetKey(config v1alpha1.RunnerConfig) string {
+	labels := make([]string, 0, len(config.Labels))
+	seen := map[string]struct{}{}
+
+	for _, l := range config.Labels {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if l == "" {
+			continue
+		}
+
+		if _, ok := seen[l]; ok {
+			continue
+		}
+		seen[l] = struct{}{}
+
+		labels = append(labels, l)
+	}
+
+	sort.Strings(labels)
+
+	return strings.ToLower(fmt.Sprintf("enterprise=%s,organization=%s,repository=%s,group=%s,labels=%s",
+		config.Enterprise, config.Organization, config.Repository, config.Group, strings.Join(labels, ",")))
+}
+
+// findOverlappingRunnerPools returns the RunnerDeployments and RunnerSets other than the one identified by kind, namespace and name,
+// whose runners are indistinguishable from the runners of the given config in the eyes of GitHub.
+// Jobs targeting such runners are assigned to whichever pool has an idle runner first,
+// which makes job routing unpredictable and confuses the autoscaling of every overlapping pool.
+//
+// Each item is formatted as "<kind>/<namespace>/<name>" and the result is sorted.
+func findOverlappingRunnerPools(ctx context.Context, c client.Reader, kind, namespace, name string, config v1alpha1.RunnerConfig) ([]string, error) {
+	key := runnerPoolLabelSetKey(config)
+
+	var overlapping []string
+
+	add := func(k, ns, n string, other v1alpha1.RunnerConfig) {
+		if k == kind && ns == namespace && n == name {
+			return
+		}
+
+		if runnerPoolLabelSetKey(other) == key {
+			overlapping = append(overlapping, fmt.Sprintf("%s/%s/%s", k, ns, n))
+		}
+	}
+
+	var rds v1alpha1.RunnerDeploymentList
+	if err := c.List(ctx, &rds); err != nil {
+		return nil, fmt.Errorf("listing runnerdeployments: %w", err)
+	}
+
+	for _, rd := range rds.Items {
+		if !rd.DeletionTimestamp.IsZero() {
+			continue
+		}
+		add("RunnerDeployment", rd.Namespace, rd.Name, rd.Spec.Template.Spec.RunnerConfig)
+	}
+
+	var runnerSets v1alpha1.RunnerSetList
+	if err := c.List(ctx, &runnerSets); err != nil {
+		return nil, fmt.Errorf("listing runnersets: %w", err)
+	}
+
+	for _, rs := range runnerSets.Items {
+		if !rs.DeletionTimestamp.IsZero() {
+			continue
+		}
+		add("RunnerSet", rs.Namespace, rs.Name, rs.Spec.RunnerConfig)
+	}
+
+	sort.Strings(overlapping)
+
+	return overlapping, nil
+}
+
+// overlappingRunnerPoolsMessage returns the message of the warning event emitted for the overlapping pools.
+func overlappingRunnerPoolsMessage(overlapping []string) string {
+	return fmt.Sprintf("Runners are registered with the same labels to the same scope and runner group as %s. "+
+		"Jobs may be routed to any of them, and autoscaling may be inaccurate. Add a distinguishing label to each of them",
+		strings.Join(overlapping, ", "))
+}
@@ -0,0 +1,58 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFindOverlappingRunnerPools(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	rd := func(ns, name string, config v1alpha1.RunnerConfig) *v1alpha1.RunnerDeployment {
+		return &v1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+			Spec: v1alpha1.RunnerDeploymentSpec{
+				Template: v1alpha1.RunnerTemplate{
+					Spec: v1alpha1.RunnerSpec{RunnerConfig: config},
+				},
+			},
+		}
+	}
+
+	rs := func(ns, name string, config v1alpha1.RunnerConfig) *v1alpha1.RunnerSet {
+		return &v1alpha1.RunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+			Spec:       v1alpha1.RunnerSetSpec{RunnerConfig: config},
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		rd("default", "linux", v1alpha1.RunnerConfig{Organization: "myorg", Labels: []string{"linux", "x64"}}),
+		// Same labels in a different order and case, in another namespace
+		rd("team-a", "linux", v1alpha1.RunnerConfig{Organization: "MyOrg", Labels: []string{"X64", "linux"}}),
+		rs("default", "linux-set", v1alpha1.RunnerConfig{Organization: "myorg", Labels: []string{"linux", "x64", "linux"}}),
+		// A distinguishing label
+		rd("default", "gpu", v1alpha1.RunnerConfig{Organization: "myorg", Labels: []string{"linux", "x64", "gpu"}}),
+		// A different runner group
+		rd("default", "restricted", v1alpha1.RunnerConfig{Organization: "myorg", Group: "restricted", Labels: []string{"linux", "x64"}}),
+		// A different scope
+		rd("default", "repo", v1alpha1.RunnerConfig{Repository: "myorg/myrepo", Labels: []string{"linux", "x64"}}),
+	).Build()
+
+	ctx := context.Background()
+
+	overlapping, err := findOverlappingRunnerPools(ctx, c, "RunnerDeployment", "default", "linux", v1alpha1.RunnerConfig{Organization: "myorg", Labels: []string{"linux", "x64"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"RunnerDeployment/team-a/linux", "RunnerSet/default/linux-set"}, overlapping)
+
+	overlapping, err = findOverlappingRunnerPools(ctx, c, "RunnerDeployment", "default", "gpu", v1alpha1.RunnerConfig{Organization: "myorg", Labels: []string{"linux", "x64", "gpu"}})
+	require.NoError(t, err)
+	require.Empty(t, overlapping)
+}
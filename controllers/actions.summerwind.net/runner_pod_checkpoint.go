@@ -0,0 +1,447 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+)
+
+const (
+	// DefaultCheckpointBuilderImage is the default image of the pod building an image from a checkpoint archive.
+	DefaultCheckpointBuilderImage = "quay.io/buildah/stable:latest"
+
+	// runnerPodNodeNameKey is the index of the runner pods by their node.
+	runnerPodNodeNameKey = "spec.nodeName"
+
+	// checkpointBuilderContainerName is the name of the container of the checkpoint builder pod.
+	checkpointBuilderContainerName = "builder"
+
+	// checkpointRegistryAuthDir is where the registry secret is mounted in the checkpoint builder pod.
+	checkpointRegistryAuthDir = "/run/containers/auth"
+
+	// checkpointPollInterval is the interval of checking the checkpoint builder pods.
+	checkpointPollInterval = 5 * time.Second
+
+	// checkpointBuilderScript builds an image restorable by CRI-O from the checkpoint archive, and pushes it.
+	// See https://kubernetes.io/blog/2022/12/05/forensic-container-checkpointing-alpha/
+	checkpointBuilderScript = `set -eu
+ctr=$(buildah from scratch)
+buildah add "$ctr" "$CHECKPOINT_ARCHIVE" /
+buildah config --annotation=io.kubernetes.cri-o.annotations.checkpoint.name="$CHECKPOINT_CONTAINER" "$ctr"
+buildah commit "$ctr" "$CHECKPOINT_IMAGE"
+buildah push "$CHECKPOINT_IMAGE"
+`
+)
+
+// ContainerCheckpointer checkpoints running containers.
+type ContainerCheckpointer interface {
+	// Checkpoint checkpoints the container of the pod on the node, leaving the container running,
+	// and returns the path of the checkpoint archive on the node.
+	Checkpoint(ctx context.Context, node, namespace, pod, container string) (string, error)
+}
+
+// KubeletContainerCheckpointer checkpoints containers with the checkpoint API of the kubelet, via the node proxy of the API server.
+// It requires the ContainerCheckpoint feature gate, and a container runtime supporting it, like CRI-O with CRIU.
+type KubeletContainerCheckpointer struct {
+	// RESTClient is the client of the core API group.
+	RESTClient rest.Interface
+	// Timeout is the timeout of the checkpoint. The kubelet defaults it to 4 minutes when it's zero.
+	Timeout time.Duration
+}
+
+// +kubebuilder:rbac:groups=core,resources=nodes/proxy,verbs=create
+
+func (c *KubeletContainerCheckpointer) Checkpoint(ctx context.Context, node, namespace, pod, container string) (string, error) {
+	req := c.RESTClient.Post().AbsPath("/api/v1/nodes", node, "proxy", "checkpoint", namespace, pod, container)
+	if c.Timeout > 0 {
+		req = req.Param("timeout", strconv.Itoa(int(c.Timeout.Seconds())))
+	}
+
+	body, err := req.DoRaw(ctx)
+	if err != nil {
+		return "", fmt.Errorf("checkpointing container %s of pod %s/%s on node %s: %w", container, namespace, pod, node, err)
+	}
+
+	var res struct {
+		Items []string `json:"items"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", fmt.Errorf("decoding checkpoint response of node %s: %w", node, err)
+	}
+
+	if len(res.Items) == 0 {
+		return "", fmt.Errorf("node %s returned no checkpoint archive", node)
+	}
+
+	return res.Items[0], nil
+}
+
+// RunnerCheckpointReconciler checkpoints the runner pods opted in with AnnotationKeyCheckpointOnInterruption
+// when their node is tainted with any of InterruptionTaints, like a spot instance about to be reclaimed,
+// so that the runner controller restores them on another node instead of losing their jobs.
+//
+// A checkpoint goes as follows:
+//  1. The runner container is checkpointed via the Checkpointer, which leaves an archive on the node.
+//  2. A builder pod in BuilderNamespace on the node builds an image from the archive and pushes it to ImageRepository.
+//  3. The image is recorded onto the runner, and the runner pod is deleted without unregistering the runner.
+//  4. The runner controller recreates the runner pod with the runner container started from the image.
+//
+// This is experimental. Only the runner container is restored, so the state of sidecars like dockerd is lost.
+type RunnerCheckpointReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Scheme   *runtime.Scheme
+	Name     string
+
+	Checkpointer ContainerCheckpointer
+
+	// InterruptionTaints are the keys of the taints on a node that is about to be interrupted.
+	InterruptionTaints []string
+	// ImageRepository is the repository the checkpoint images are pushed to, like registry.example.com/arc/checkpoints.
+	// The images contain the memory of the runner container, including the secrets of the job it runs,
+	// so the repository must be as private as the secrets.
+	ImageRepository string
+	// BuilderImage is the image of the pod building the checkpoint image. It must provide buildah.
+	// Defaults to DefaultCheckpointBuilderImage.
+	BuilderImage string
+	// BuilderNamespace is the namespace the builder pods are created in, usually the one of the controller.
+	// The builder pods are privileged, so they're kept out of the namespaces of the runners.
+	BuilderNamespace string
+	// RegistrySecretName is optional. When set, the kubernetes.io/dockerconfigjson secret of the name
+	// in BuilderNamespace is used to push the checkpoint image.
+	RegistrySecretName string
+}
+
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners,verbs=get;list;watch;update;patch
+
+func (r *RunnerCheckpointReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("node", req.Name)
+
+	var node corev1.Node
+	if err := r.Get(ctx, req.NamespacedName, &node); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !nodeInterrupted(&node, r.InterruptionTaints) {
+		return ctrl.Result{}, nil
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.MatchingFields{runnerPodNodeNameKey: node.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var pending bool
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		if _, isRunnerPod := pod.Labels[LabelKeyRunner]; !isRunnerPod {
+			continue
+		}
+
+		if v, _ := getAnnotation(pod, AnnotationKeyCheckpointOnInterruption); v != "true" {
+			continue
+		}
+
+		if !pod.DeletionTimestamp.IsZero() || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		p, err := r.checkpointRunnerPod(ctx, log.WithValues("runnerpod", types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}), &node, pod)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		pending = pending || p
+	}
+
+	if pending {
+		return ctrl.Result{RequeueAfter: checkpointPollInterval}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// checkpointRunnerPod progresses the checkpoint of the runner pod, and returns true while it's in progress.
+func (r *RunnerCheckpointReconciler) checkpointRunnerPod(ctx context.Context, log logr.Logger, node *corev1.Node, pod *corev1.Pod) (bool, error) {
+	var runner v1alpha1.Runner
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &runner); err != nil {
+		// A RunnerSet pod has no runner to restore it
+		return false, client.IgnoreNotFound(err)
+	}
+
+	var builder corev1.Pod
+	if err := r.Get(ctx, types.NamespacedName{Namespace: r.BuilderNamespace, Name: checkpointBuilderPodName(pod)}, &builder); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return false, err
+		}
+
+		if _, started := getAnnotation(pod, AnnotationKeyCheckpointStartTimestamp); started {
+			// The checkpoint has failed or completed. Either way, there's nothing left to do.
+			return false, nil
+		}
+
+		return r.startCheckpoint(ctx, log, node, pod, &runner)
+	}
+
+	switch builder.Status.Phase {
+	case corev1.PodSucceeded:
+	case corev1.PodFailed:
+		log.Info("Failed to build the checkpoint image. The runner pod is left as is", "builder", builder.Name)
+		r.Recorder.Event(&runner, corev1.EventTypeWarning, "CheckpointFailed", fmt.Sprintf("Checkpoint builder pod '%s' failed", builder.Name))
+
+		return false, client.IgnoreNotFound(r.Delete(ctx, &builder))
+	default:
+		return true, nil
+	}
+
+	image := builder.Annotations[AnnotationKeyCheckpointImage]
+
+	updated := runner.DeepCopy()
+	setAnnotation(&updated.ObjectMeta, AnnotationKeyCheckpointImage, image)
+
+	if err := r.Patch(ctx, updated, client.MergeFrom(&runner)); err != nil {
+		return false, fmt.Errorf("recording checkpoint image onto runner: %w", err)
+	}
+
+	// Kill the runner right away, as the runner would report the job as canceled on a graceful stop,
+	// and the job would diverge from the checkpoint if it kept running.
+	var force int64 = 0
+	if err := r.Delete(ctx, pod, &client.DeleteOptions{GracePeriodSeconds: &force}); client.IgnoreNotFound(err) != nil {
+		return false, fmt.Errorf("deleting checkpointed runner pod: %w", err)
+	}
+
+	log.Info("Checkpointed runner pod. It will be restored from the checkpoint image", "image", image)
+	r.Recorder.Event(&runner, corev1.EventTypeNormal, "Checkpointed", fmt.Sprintf("Checkpointed pod '%s' to image '%s'", pod.Name, image))
+
+	return false, client.IgnoreNotFound(r.Delete(ctx, &builder))
+}
+
+// startCheckpoint checkpoints the runner container, and creates the pod building the checkpoint image.
+func (r *RunnerCheckpointReconciler) startCheckpoint(ctx context.Context, log logr.Logger, node *corev1.Node, pod *corev1.Pod, runner *v1alpha1.Runner) (bool, error) {
+	now := time.Now()
+
+	// Mark the start before checkpointing, so that a failed checkpoint isn't retried
+	updated := pod.DeepCopy()
+	setAnnotation(&updated.ObjectMeta, AnnotationKeyCheckpointStartTimestamp, now.Format(time.RFC3339))
+	if err := r.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+		return false, err
+	}
+
+	log.Info("Checkpointing runner pod as its node is about to be interrupted")
+
+	archive, err := r.Checkpointer.Checkpoint(ctx, node.Name, pod.Namespace, pod.Name, containerName)
+	if err != nil {
+		log.Error(err, "Failed to checkpoint runner pod. The runner pod is left as is")
+		r.Recorder.Event(runner, corev1.EventTypeWarning, "CheckpointFailed", err.Error())
+		return false, nil
+	}
+
+	image := fmt.Sprintf("%s:%s-%d", r.ImageRepository, pod.Name, now.Unix())
+
+	// The builder pod can't be owned by the runner in another namespace. It's deleted once it completes.
+	builder := r.newCheckpointBuilderPod(pod, node, archive, image)
+	if err := r.Create(ctx, builder); err != nil {
+		if kerrors.IsForbidden(err) || kerrors.IsInvalid(err) {
+			// Like when PodSecurity forbids privileged pods in the namespace. Retrying wouldn't help.
+			log.Error(err, "Failed to create checkpoint builder pod. The runner pod is left as is", "namespace", r.BuilderNamespace)
+			r.Recorder.Event(runner, corev1.EventTypeWarning, "CheckpointFailed", fmt.Sprintf("Checkpoint builder pod was rejected: %v", err))
+			return false, nil
+		}
+
+		return false, fmt.Errorf("creating checkpoint builder pod: %w", err)
+	}
+
+	log.Info("Building checkpoint image", "archive", archive, "image", image, "builder", builder.Name)
+
+	return true, nil
+}
+
+// checkpointBuilderPodName returns the name of the builder pod of the runner pod,
+// unique across the namespaces of the runners as all the builder pods are in the same namespace.
+func checkpointBuilderPodName(pod *corev1.Pod) string {
+	return pod.Namespace + "-" + pod.Name + "-checkpoint"
+}
+
+func (r *RunnerCheckpointReconciler) newCheckpointBuilderPod(pod *corev1.Pod, node *corev1.Node, archive, image string) *corev1.Pod {
+	builderImage := r.BuilderImage
+	if builderImage == "" {
+		builderImage = DefaultCheckpointBuilderImage
+	}
+
+	privileged := true
+	automountServiceAccountToken := false
+	hostPathFile := corev1.HostPathFile
+
+	// The node is tainted for the interruption, and may already be cordoned
+	tolerations := []corev1.Toleration{
+		{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	}
+	for _, t := range node.Spec.Taints {
+		if slices.Contains(r.InterruptionTaints, t.Key) {
+			tolerations = append(tolerations, corev1.Toleration{Key: t.Key, Operator: corev1.TolerationOpExists, Effect: t.Effect})
+		}
+	}
+
+	builder := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: r.BuilderNamespace,
+			Name:      checkpointBuilderPodName(pod),
+			Annotations: map[string]string{
+				AnnotationKeyCheckpointImage: image,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:                     node.Name,
+			RestartPolicy:                corev1.RestartPolicyNever,
+			Tolerations:                  tolerations,
+			AutomountServiceAccountToken: &automountServiceAccountToken,
+			Containers: []corev1.Container{
+				{
+					Name:    checkpointBuilderContainerName,
+					Image:   builderImage,
+					Command: []string{"/bin/sh", "-c", checkpointBuilderScript},
+					Env: []corev1.EnvVar{
+						{Name: "CHECKPOINT_ARCHIVE", Value: archive},
+						{Name: "CHECKPOINT_CONTAINER", Value: containerName},
+						{Name: "CHECKPOINT_IMAGE", Value: image},
+					},
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: &privileged,
+					},
+					// Only the archive of the runner is mounted, not the checkpoints of the other pods of the node
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "checkpoint", MountPath: archive, ReadOnly: true},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "checkpoint",
+					VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{Path: archive, Type: &hostPathFile},
+					},
+				},
+			},
+		},
+	}
+
+	if r.RegistrySecretName != "" {
+		c := &builder.Spec.Containers[0]
+		c.Env = append(c.Env, corev1.EnvVar{Name: "REGISTRY_AUTH_FILE", Value: filepath.Join(checkpointRegistryAuthDir, corev1.DockerConfigJsonKey)})
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: "registry-auth", MountPath: checkpointRegistryAuthDir, ReadOnly: true})
+
+		builder.Spec.Volumes = append(builder.Spec.Volumes, corev1.Volume{
+			Name: "registry-auth",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: r.RegistrySecretName},
+			},
+		})
+	}
+
+	return builder
+}
+
+func nodeInterrupted(node *corev1.Node, taints []string) bool {
+	for _, t := range node.Spec.Taints {
+		for _, key := range taints {
+			if t.Key == key {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// runnerCheckpointImage returns the checkpoint image the runner pod is going to be restored from, if any.
+// It returns false once the pod has been restored from the image.
+func runnerCheckpointImage(runner *v1alpha1.Runner, pod *corev1.Pod) (string, bool) {
+	image, ok := getAnnotation(runner, AnnotationKeyCheckpointImage)
+	if !ok || image == "" {
+		return "", false
+	}
+
+	if pod != nil {
+		if restored, _ := getAnnotation(pod, AnnotationKeyRestoredCheckpointImage); restored == image {
+			return "", false
+		}
+	}
+
+	return image, true
+}
+
+// restoreRunnerPodFromCheckpoint makes the runner container of the pod start from the checkpoint image.
+func restoreRunnerPodFromCheckpoint(pod *corev1.Pod, image string) {
+	annotations := make(map[string]string, len(pod.Annotations))
+	for k, v := range pod.Annotations {
+		annotations[k] = v
+	}
+	delete(annotations, AnnotationKeyCheckpointImage)
+	delete(annotations, AnnotationKeyCheckpointStartTimestamp)
+	annotations[AnnotationKeyRestoredCheckpointImage] = image
+	pod.Annotations = annotations
+
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == containerName {
+			pod.Spec.Containers[i].Image = image
+		}
+	}
+}
+
+// clearRunnerCheckpointImage removes the checkpoint image from the runner once its pod is restored from it.
+func clearRunnerCheckpointImage(ctx context.Context, c client.Client, runner *v1alpha1.Runner) error {
+	updated := runner.DeepCopy()
+	delete(updated.Annotations, AnnotationKeyCheckpointImage)
+
+	return c.Patch(ctx, updated, client.MergeFrom(runner))
+}
+
+func runnerPodNodeNameIndexer(rawObj client.Object) []string {
+	pod := rawObj.(*corev1.Pod)
+	if _, isRunnerPod := pod.Labels[LabelKeyRunner]; !isRunnerPod || pod.Spec.NodeName == "" {
+		return nil
+	}
+
+	return []string{pod.Spec.NodeName}
+}
+
+func (r *RunnerCheckpointReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	name := "runnercheckpoint-controller"
+	if r.Name != "" {
+		name = r.Name
+	}
+
+	r.Recorder = mgr.GetEventRecorderFor(name)
+
+	if err := indexRunnerPodsByNodeName(mgr); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Node{}).
+		Named(name).
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &corev1.Node{}, r))
+}
@@ -0,0 +1,253 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+type fakeContainerCheckpointer struct {
+	archive string
+	calls   int
+}
+
+func (c *fakeContainerCheckpointer) Checkpoint(ctx context.Context, node, namespace, pod, container string) (string, error) {
+	c.calls++
+	return c.archive, nil
+}
+
+func TestRunnerCheckpointReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newRunnerPod := func(name string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        name,
+				Labels:      map[string]string{LabelKeyRunner: ""},
+				Annotations: annotations,
+			},
+			Spec: corev1.PodSpec{
+				NodeName:   "spot-1",
+				Containers: []corev1.Container{{Name: containerName, Image: "runner:latest"}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithIndex(&corev1.Pod{}, runnerPodNodeNameKey, runnerPodNodeNameIndexer).
+		WithObjects(
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "spot-1"},
+				Spec: corev1.NodeSpec{
+					Taints: []corev1.Taint{{Key: "aws-node-termination-handler/spot-itn", Effect: corev1.TaintEffectNoSchedule}},
+				},
+			},
+			&v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"}},
+			&v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "not-opted-in"}},
+			newRunnerPod("example", map[string]string{AnnotationKeyCheckpointOnInterruption: "true"}),
+			newRunnerPod("not-opted-in", nil),
+		).
+		Build()
+
+	checkpointer := &fakeContainerCheckpointer{archive: "/var/lib/kubelet/checkpoints/checkpoint-example_default-runner.tar"}
+
+	r := &RunnerCheckpointReconciler{
+		Client:             c,
+		Log:                logr.Discard(),
+		Recorder:           record.NewFakeRecorder(10),
+		Scheme:             scheme,
+		Checkpointer:       checkpointer,
+		InterruptionTaints: []string{"aws-node-termination-handler/spot-itn"},
+		ImageRepository:    "registry.example.com/arc/checkpoints",
+		BuilderNamespace:   "actions-runner-system",
+		RegistrySecretName: "checkpoint-registry",
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "spot-1"}}
+
+	res, err := r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, checkpointPollInterval, res.RequeueAfter)
+	require.Equal(t, 1, checkpointer.calls, "only the opted in runner pod is checkpointed")
+
+	builderKey := types.NamespacedName{Namespace: "actions-runner-system", Name: "default-example-checkpoint"}
+
+	var builder corev1.Pod
+	require.NoError(t, c.Get(ctx, builderKey, &builder))
+	require.Equal(t, "spot-1", builder.Spec.NodeName)
+	require.Equal(t, checkpointer.archive, builder.Spec.Volumes[0].HostPath.Path, "only the archive of the runner is mounted")
+	require.Equal(t, corev1.HostPathFile, *builder.Spec.Volumes[0].HostPath.Type)
+	require.Equal(t, checkpointer.archive, builder.Spec.Containers[0].VolumeMounts[0].MountPath)
+	require.Equal(t, "checkpoint-registry", builder.Spec.Volumes[1].Secret.SecretName)
+	require.Equal(t, []corev1.Toleration{
+		{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: "aws-node-termination-handler/spot-itn", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	}, builder.Spec.Tolerations)
+	require.Equal(t, DefaultCheckpointBuilderImage, builder.Spec.Containers[0].Image)
+
+	image := builder.Annotations[AnnotationKeyCheckpointImage]
+	require.Regexp(t, `^registry\.example\.com/arc/checkpoints:example-\d+$`, image)
+
+	// The checkpoint isn't taken again while the image is being built
+	res, err = r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, checkpointPollInterval, res.RequeueAfter)
+	require.Equal(t, 1, checkpointer.calls)
+
+	builder.Status.Phase = corev1.PodSucceeded
+	require.NoError(t, c.Status().Update(ctx, &builder))
+
+	res, err = r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	require.Zero(t, res.RequeueAfter)
+
+	var runner v1alpha1.Runner
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &runner))
+	require.Equal(t, image, runner.Annotations[AnnotationKeyCheckpointImage])
+
+	err = c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &corev1.Pod{})
+	require.True(t, kerrors.IsNotFound(err), "the checkpointed runner pod must be deleted")
+
+	err = c.Get(ctx, builderKey, &corev1.Pod{})
+	require.True(t, kerrors.IsNotFound(err), "the builder pod must be deleted")
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "not-opted-in"}, &corev1.Pod{}))
+}
+
+func TestRunnerCheckpointReconciler_BuilderForbidden(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "example",
+			Labels:      map[string]string{LabelKeyRunner: ""},
+			Annotations: map[string]string{AnnotationKeyCheckpointOnInterruption: "true"},
+		},
+		Spec:   corev1.PodSpec{NodeName: "spot-1"},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithIndex(&corev1.Pod{}, runnerPodNodeNameKey, runnerPodNodeNameIndexer).
+		WithObjects(
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "spot-1"},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "karpenter.sh/disruption", Effect: corev1.TaintEffectNoSchedule}}},
+			},
+			&v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"}},
+			pod,
+		).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				return kerrors.NewForbidden(corev1.Resource("pods"), obj.GetName(), errors.New(`violates PodSecurity "baseline:latest": privileged`))
+			},
+		}).
+		Build()
+
+	recorder := record.NewFakeRecorder(10)
+	r := &RunnerCheckpointReconciler{
+		Client:             c,
+		Log:                logr.Discard(),
+		Recorder:           recorder,
+		Scheme:             scheme,
+		Checkpointer:       &fakeContainerCheckpointer{archive: "/var/lib/kubelet/checkpoints/checkpoint-example_default-runner.tar"},
+		InterruptionTaints: []string{"karpenter.sh/disruption"},
+		ImageRepository:    "registry.example.com/arc/checkpoints",
+		BuilderNamespace:   "actions-runner-system",
+	}
+
+	res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "spot-1"}})
+	require.NoError(t, err, "a rejected builder pod isn't retried")
+	require.Zero(t, res.RequeueAfter)
+	require.Contains(t, <-recorder.Events, "CheckpointFailed")
+
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "example"}, &corev1.Pod{}), "the runner pod is left as is")
+}
+
+func TestRestoreRunnerPodFromCheckpoint(t *testing.T) {
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{AnnotationKeyCheckpointImage: "registry.example.com/arc/checkpoints:example-1"},
+		},
+	}
+
+	image, ok := runnerCheckpointImage(runner, nil)
+	require.True(t, ok)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: runner.Annotations,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: containerName, Image: "runner:latest"}, {Name: "docker", Image: "docker:dind"}},
+		},
+	}
+
+	restoreRunnerPodFromCheckpoint(pod, image)
+
+	require.Equal(t, image, pod.Spec.Containers[0].Image)
+	require.Equal(t, "docker:dind", pod.Spec.Containers[1].Image)
+	require.Equal(t, map[string]string{AnnotationKeyRestoredCheckpointImage: image}, pod.Annotations)
+	require.Contains(t, runner.Annotations, AnnotationKeyCheckpointImage, "the annotations of the runner must be left intact")
+
+	// The restored pod isn't checkpointed
+	_, ok = runnerCheckpointImage(runner, pod)
+	require.False(t, ok)
+
+	_, ok = runnerCheckpointImage(&v1alpha1.Runner{}, nil)
+	require.False(t, ok)
+}
+
+func TestKubeletContainerCheckpointer(t *testing.T) {
+	var path, timeout string
+
+	kubelet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		timeout = r.URL.Query().Get("timeout")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":["/var/lib/kubelet/checkpoints/checkpoint-example_default-runner.tar"]}`))
+	}))
+	defer kubelet.Close()
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: kubelet.URL})
+	require.NoError(t, err)
+
+	c := &KubeletContainerCheckpointer{RESTClient: clientset.CoreV1().RESTClient(), Timeout: time.Minute}
+
+	archive, err := c.Checkpoint(context.Background(), "spot-1", "default", "example", containerName)
+	require.NoError(t, err)
+	require.Equal(t, "/var/lib/kubelet/checkpoints/checkpoint-example_default-runner.tar", archive)
+	require.Equal(t, "/api/v1/nodes/spot-1/proxy/checkpoint/default/example/runner", path)
+	require.Equal(t, "60", timeout)
+}
@@ -29,10 +29,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	arcv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 
 	corev1 "k8s.io/api/core/v1"
 )
@@ -49,10 +51,14 @@ type RunnerPodReconciler struct {
 	RegistrationRecheckJitter   time.Duration
 
 	UnregistrationRetryDelay time.Duration
+
+	// Clock is optional. When set, it is used instead of the wall clock to time out the registration of promoted warm standby runners.
+	Clock clock.PassiveClock
 }
 
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch;delete
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -133,8 +139,12 @@ func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			key    = types.NamespacedName{Namespace: runnerPod.Namespace, Name: runnerPod.Name}
 			runner arcv1alpha1.Runner
 		)
+		var checkpointed bool
 		if err := r.Get(ctx, key, &runner); err == nil {
-			if runner.Name != "" && runner.DeletionTimestamp == nil {
+			// A checkpointed runner pod is restored by the runner controller, so neither the runner nor its registration must go away
+			_, checkpointed = runnerCheckpointImage(&runner, &runnerPod)
+
+			if runner.Name != "" && runner.DeletionTimestamp == nil && !checkpointed {
 				log.Info("This runner pod seems to have been deleted directly, bypassing the parent Runner resource. Marking the runner for deletion to not let it recreate this pod.")
 				if err := r.Delete(ctx, &runner); err != nil {
 					return ctrl.Result{}, err
@@ -169,9 +179,18 @@ func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			// In a standard scenario, the upstream controller, like runnerset-controller, ensures this runner to be gracefully stopped before the deletion timestamp is set.
 			// But for the case that the user manually deleted it for whatever reason,
 			// we have to ensure it to gracefully stop now.
-			updatedPod, res, err := tickRunnerGracefulStop(ctx, r.unregistrationRetryDelay(), log, ghc, r.Client, enterprise, org, repo, runnerPod.Name, &runnerPod)
-			if res != nil {
-				return *res, err
+			updatedPod := &runnerPod
+			if checkpointed {
+				log.Info("Runner pod has been checkpointed. Skipped unregistration to restore it with the same registration")
+			} else {
+				var (
+					res *ctrl.Result
+					err error
+				)
+				updatedPod, res, err = tickRunnerGracefulStop(ctx, r.unregistrationRetryDelay(), nowFrom(r.Clock), log, ghc, r.Client, enterprise, org, repo, runnerPod.Name, &runnerPod)
+				if res != nil {
+					return *res, err
+				}
 			}
 
 			patchedPod := updatedPod.DeepCopy()
@@ -225,7 +244,32 @@ func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
-	po, res, err := ensureRunnerPodRegistered(ctx, log, ghc, r.Client, enterprise, org, repo, runnerPod.Name, &runnerPod)
+	po, res, err := syncRegistrationTokenGate(ctx, r.Client, r.Scheme, r.Recorder, log, ghc, enterprise, org, repo, &runnerPod)
+	if res != nil {
+		return *res, err
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	runnerPod = *po
+
+	po, res, err = syncJITConfigGate(ctx, r.Client, r.Scheme, r.Recorder, log, ghc, enterprise, org, repo, &runnerPod)
+	if res != nil {
+		return *res, err
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	runnerPod = *po
+
+	po, err = syncNetworkReadyCondition(ctx, r.Client, r.Recorder, log, &runnerPod)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	runnerPod = *po
+
+	po, res, err = ensureRunnerPodRegistered(ctx, log, ghc, r.Client, enterprise, org, repo, runnerPod.Name, &runnerPod)
 	if res != nil {
 		return *res, err
 	}
@@ -239,7 +283,7 @@ func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		//
 		// In a standard scenario, ARC starts the unregistration process before marking the pod for deletion at all,
 		// so that it isn't subject to terminationGracePeriod and can safely take hours to finish it's work.
-		_, res, err := tickRunnerGracefulStop(ctx, r.unregistrationRetryDelay(), log, ghc, r.Client, enterprise, org, repo, runnerPod.Name, &runnerPod)
+		_, res, err := tickRunnerGracefulStop(ctx, r.unregistrationRetryDelay(), nowFrom(r.Clock), log, ghc, r.Client, enterprise, org, repo, runnerPod.Name, &runnerPod)
 		if res != nil {
 			return *res, err
 		}
@@ -254,6 +298,10 @@ func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
+	if _, ok := getAnnotation(&runnerPod, AnnotationKeyIdleTimeout); ok {
+		return r.syncRunnerPodIdleTimeout(ctx, log, ghc, enterprise, org, repo, &runnerPod)
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -277,7 +325,7 @@ func (r *RunnerPodReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Pod{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &corev1.Pod{}, r))
 }
 
 func (r *RunnerPodReconciler) cleanupRunnerLinkedPods(ctx context.Context, pod *corev1.Pod, log logr.Logger) error {
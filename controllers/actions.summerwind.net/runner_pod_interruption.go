@@ -0,0 +1,269 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+)
+
+// nodeInterruptionEventNodeNameKey is the index of the interruption events by the node they are about.
+const nodeInterruptionEventNodeNameKey = "involvedObject.name"
+
+// RunnerInterruptionReconciler reacts on the interruption notices of the nodes, like the taints and the events
+// recorded by the spot instance termination handlers, before the nodes disappear.
+//
+// The runner pods on an interrupted node are annotated with AnnotationKeyNodeInterruptionTimestamp and get unregistered
+// as soon as they finish their current jobs, if any, so that GitHub assigns them no new job.
+// The runnerreplicaset and runnerset controllers don't count those pods as running, so that they create replacements
+// on other nodes while the interrupted ones are still draining.
+//
+// The runner pods opted in with AnnotationKeyCheckpointOnInterruption are left to RunnerCheckpointReconciler.
+type RunnerInterruptionReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Scheme   *runtime.Scheme
+	Name     string
+
+	// InterruptionTaints are the keys of the taints on a node that is about to be interrupted.
+	InterruptionTaints []string
+	// InterruptionEventReasons are the reasons of the events on a node that is about to be interrupted,
+	// like SpotInterruption and RebalanceRecommendation recorded by aws-node-termination-handler.
+	InterruptionEventReasons []string
+}
+
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;update;patch
+
+func (r *RunnerInterruptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("node", req.Name)
+
+	var node corev1.Node
+	if err := r.Get(ctx, req.NamespacedName, &node); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	interrupted, reason, err := r.nodeInterrupted(ctx, &node)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !interrupted {
+		return ctrl.Result{}, nil
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.MatchingFields{runnerPodNodeNameKey: node.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		if v, _ := getAnnotation(pod, AnnotationKeyCheckpointOnInterruption); v == "true" {
+			continue
+		}
+
+		if !pod.DeletionTimestamp.IsZero() || runnerPodOrContainerIsStopped(pod) {
+			continue
+		}
+
+		if _, ok := getAnnotation(pod, AnnotationKeyNodeInterruptionTimestamp); ok {
+			continue
+		}
+
+		if err := r.drainRunnerPod(ctx, log.WithValues("runnerpod", types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}), pod, reason); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// drainRunnerPod requests the unregistration of the runner pod, and marks its owner so that the pod gets replaced.
+func (r *RunnerInterruptionReconciler) drainRunnerPod(ctx context.Context, log logr.Logger, pod *corev1.Pod, reason string) error {
+	now := time.Now().Format(time.RFC3339)
+
+	// The owner is marked first, so that the interruption of the pod isn't missed by the owner's controller
+	// even if the update of the pod below failed.
+	if err := r.markOwnerInterrupted(ctx, pod, now); err != nil {
+		return err
+	}
+
+	updated := pod.DeepCopy()
+	setAnnotation(&updated.ObjectMeta, AnnotationKeyNodeInterruptionTimestamp, now)
+	if _, ok := getAnnotation(pod, AnnotationKeyUnregistrationRequestTimestamp); !ok {
+		setAnnotation(&updated.ObjectMeta, AnnotationKeyUnregistrationRequestTimestamp, now)
+	}
+
+	if err := r.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+		log.Error(err, "Failed to patch runner pod on interrupted node")
+		return err
+	}
+
+	r.Recorder.Event(pod, corev1.EventTypeNormal, "NodeInterrupted", fmt.Sprintf("Draining runner pod as node %s is about to be interrupted: %s", pod.Spec.NodeName, reason))
+	log.Info("Started draining runner pod on interrupted node", "reason", reason)
+
+	return nil
+}
+
+func (r *RunnerInterruptionReconciler) markOwnerInterrupted(ctx context.Context, pod *corev1.Pod, timestamp string) error {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil
+	}
+
+	var owner client.Object
+
+	switch ref.Kind {
+	case "Runner":
+		owner = &v1alpha1.Runner{}
+	case "StatefulSet":
+		owner = &appsv1.StatefulSet{}
+	default:
+		return nil
+	}
+
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}, owner); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if _, ok := getAnnotation(owner, AnnotationKeyNodeInterruptionTimestamp); ok {
+		return nil
+	}
+
+	updated := owner.DeepCopyObject().(client.Object)
+	annotations := updated.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationKeyNodeInterruptionTimestamp] = timestamp
+	updated.SetAnnotations(annotations)
+
+	return r.Patch(ctx, updated, client.MergeFrom(owner))
+}
+
+// nodeInterrupted returns true along with the reason when the node has any of the interruption taints or events.
+func (r *RunnerInterruptionReconciler) nodeInterrupted(ctx context.Context, node *corev1.Node) (bool, string, error) {
+	for _, t := range node.Spec.Taints {
+		for _, key := range r.InterruptionTaints {
+			if t.Key == key {
+				return true, fmt.Sprintf("the node is tainted with %s", t.Key), nil
+			}
+		}
+	}
+
+	if len(r.InterruptionEventReasons) == 0 {
+		return false, "", nil
+	}
+
+	var events corev1.EventList
+	if err := r.List(ctx, &events, client.MatchingFields{nodeInterruptionEventNodeNameKey: node.Name}); err != nil {
+		return false, "", err
+	}
+
+	for _, ev := range events.Items {
+		// An event that is older than the node is about a previous node of the same name
+		if eventTime(&ev).Before(node.CreationTimestamp.Time) {
+			continue
+		}
+
+		return true, fmt.Sprintf("%s: %s", ev.Reason, ev.Message), nil
+	}
+
+	return false, "", nil
+}
+
+func eventTime(ev *corev1.Event) time.Time {
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp.Time
+	}
+
+	if !ev.EventTime.IsZero() {
+		return ev.EventTime.Time
+	}
+
+	return ev.CreationTimestamp.Time
+}
+
+func (r *RunnerInterruptionReconciler) nodeInterruptionEventIndexer(rawObj client.Object) []string {
+	ev := rawObj.(*corev1.Event)
+	if ev.InvolvedObject.Kind != "Node" || ev.InvolvedObject.Name == "" {
+		return nil
+	}
+
+	for _, reason := range r.InterruptionEventReasons {
+		if ev.Reason == reason {
+			return []string{ev.InvolvedObject.Name}
+		}
+	}
+
+	return nil
+}
+
+func (r *RunnerInterruptionReconciler) nodeOfInterruptionEvent(_ context.Context, obj client.Object) []reconcile.Request {
+	names := r.nodeInterruptionEventIndexer(obj)
+	if len(names) == 0 {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: names[0]}}}
+}
+
+// runnerPodsIndexedByNodeName records the managers the runner pods are indexed by node for,
+// as the index is shared by RunnerCheckpointReconciler and RunnerInterruptionReconciler.
+var runnerPodsIndexedByNodeName sync.Map
+
+func indexRunnerPodsByNodeName(mgr ctrl.Manager) error {
+	if _, indexed := runnerPodsIndexedByNodeName.LoadOrStore(mgr, struct{}{}); indexed {
+		return nil
+	}
+
+	return mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Pod{}, runnerPodNodeNameKey, runnerPodNodeNameIndexer)
+}
+
+func (r *RunnerInterruptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	name := "runnerinterruption-controller"
+	if r.Name != "" {
+		name = r.Name
+	}
+
+	r.Recorder = mgr.GetEventRecorderFor(name)
+
+	if err := indexRunnerPodsByNodeName(mgr); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Node{}).
+		Named(name)
+
+	if len(r.InterruptionEventReasons) > 0 {
+		if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Event{}, nodeInterruptionEventNodeNameKey, r.nodeInterruptionEventIndexer); err != nil {
+			return err
+		}
+
+		b = b.Watches(&corev1.Event{}, handler.EnqueueRequestsFromMapFunc(r.nodeOfInterruptionEvent))
+	}
+
+	return b.Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &corev1.Node{}, r))
+}
@@ -0,0 +1,155 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunnerInterruptionReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	nodeCreated := metav1.NewTime(time.Now().Add(-time.Hour))
+	controller := true
+
+	newRunnerPod := func(name, node string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        name,
+				Labels:      map[string]string{LabelKeyRunner: ""},
+				Annotations: annotations,
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: v1alpha1.GroupVersion.String(), Kind: "Runner", Name: name, Controller: &controller},
+				},
+			},
+			Spec: corev1.PodSpec{
+				NodeName:   node,
+				Containers: []corev1.Container{{Name: containerName, Image: "runner:latest"}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	r := &RunnerInterruptionReconciler{
+		Log:                      logr.Discard(),
+		Recorder:                 record.NewFakeRecorder(10),
+		Scheme:                   scheme,
+		InterruptionTaints:       []string{"aws-node-termination-handler/spot-itn"},
+		InterruptionEventReasons: []string{"SpotInterruption"},
+	}
+
+	r.Client = fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithIndex(&corev1.Pod{}, runnerPodNodeNameKey, runnerPodNodeNameIndexer).
+		WithIndex(&corev1.Event{}, nodeInterruptionEventNodeNameKey, r.nodeInterruptionEventIndexer).
+		WithObjects(
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "tainted", CreationTimestamp: nodeCreated},
+				Spec: corev1.NodeSpec{
+					Taints: []corev1.Taint{{Key: "aws-node-termination-handler/spot-itn", Effect: corev1.TaintEffectNoSchedule}},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "noticed", CreationTimestamp: nodeCreated}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "healthy", CreationTimestamp: nodeCreated}},
+			&corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: "noticed.1"},
+				InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "noticed"},
+				Reason:         "SpotInterruption",
+				Message:        "Spot ITN received. Instance will be interrupted at 2024-01-02T03:06:05Z",
+				LastTimestamp:  metav1.Now(),
+			},
+			&corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: "healthy.1"},
+				InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "healthy"},
+				Reason:         "SpotInterruption",
+				LastTimestamp:  metav1.NewTime(nodeCreated.Add(-time.Minute)),
+			},
+			&v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "on-tainted"}},
+			&v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "on-noticed"}},
+			&v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "on-healthy"}},
+			&v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "checkpointed"}},
+			newRunnerPod("on-tainted", "tainted", nil),
+			newRunnerPod("on-noticed", "noticed", nil),
+			newRunnerPod("on-healthy", "healthy", nil),
+			newRunnerPod("checkpointed", "tainted", map[string]string{AnnotationKeyCheckpointOnInterruption: "true"}),
+		).
+		Build()
+
+	ctx := context.Background()
+
+	for _, node := range []string{"tainted", "noticed", "healthy"} {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: node}})
+		require.NoError(t, err)
+	}
+
+	assertDrained := func(name string, drained bool) {
+		t.Helper()
+
+		var pod corev1.Pod
+		require.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &pod))
+
+		var runner v1alpha1.Runner
+		require.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &runner))
+
+		for _, obj := range []client.Object{&pod, &runner} {
+			_, ok := getAnnotation(obj, AnnotationKeyNodeInterruptionTimestamp)
+			require.Equal(t, drained, ok, "%s/%s", obj.GetObjectKind().GroupVersionKind().Kind, name)
+		}
+
+		_, ok := getAnnotation(&pod, AnnotationKeyUnregistrationRequestTimestamp)
+		require.Equal(t, drained, ok)
+	}
+
+	assertDrained("on-tainted", true)
+	assertDrained("on-noticed", true)
+	assertDrained("on-healthy", false)
+	assertDrained("checkpointed", false)
+}
+
+func TestGetPodsForOwner_Interrupted(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "example",
+			Labels:    map[string]string{LabelKeyRunnerTemplateHash: "abc"},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		runner,
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "example",
+				Annotations: map[string]string{AnnotationKeyNodeInterruptionTimestamp: time.Now().Format(time.RFC3339)},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	).Build()
+
+	res, err := getPodsForOwner(context.Background(), c, logr.Discard(), runner, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, res.total)
+	require.Equal(t, 1, res.interrupted)
+	require.Zero(t, res.running, "the runner pod on an interrupted node must not be counted as running so that it's replaced")
+}
@@ -0,0 +1,240 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	arcgithub "github.com/actions/actions-runner-controller/github"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// SchedulingGateJITConfig holds a runner pod off the nodes until the just-in-time configuration of its runner is generated.
+	SchedulingGateJITConfig = "actions.summerwind.dev/jit-config"
+
+	// EnvVarRunnerJITConfigFile is the path to the just-in-time configuration the runner starts with, instead of registering itself with RUNNER_TOKEN.
+	EnvVarRunnerJITConfigFile = "RUNNER_JITCONFIG_FILE"
+
+	jitConfigPendingReasonFailed = "JITConfigRequestFailed"
+	jitConfigPendingReasonIssued = "JITConfigIssued"
+
+	jitConfigSecretKey       = "jitconfig"
+	jitConfigVolumeName      = "runner-jitconfig"
+	jitConfigVolumeMountPath = "/runner-jitconfig"
+)
+
+// runnerConfigUsesJIT returns true when the runners of the config are registered with just-in-time configurations.
+// jitConfig is ignored for non-ephemeral runners, as validated by the webhooks.
+func runnerConfigUsesJIT(cfg v1alpha1.RunnerConfig) bool {
+	if cfg.JITConfig == nil || !*cfg.JITConfig {
+		return false
+	}
+
+	return cfg.Ephemeral == nil || *cfg.Ephemeral
+}
+
+// podUsesJITConfig returns true when the runner pod registers its runner with a just-in-time configuration.
+func podUsesJITConfig(pod *corev1.Pod) bool {
+	v, _ := getAnnotation(pod, AnnotationKeyJITConfig)
+
+	return v == "true"
+}
+
+// jitConfigSecretName returns the name of the secret the just-in-time configuration of the runner pod is read from.
+func jitConfigSecretName(pod *corev1.Pod) string {
+	return pod.Name + "-jit-config"
+}
+
+// gatePodOnJITConfig makes the runner container start with the just-in-time configuration from a secret that the runner pod controller populates later,
+// and holds the pod off the nodes with a scheduling gate until then.
+func gatePodOnJITConfig(pod *corev1.Pod) {
+	for _, g := range pod.Spec.SchedulingGates {
+		if g.Name == SchedulingGateJITConfig {
+			return
+		}
+	}
+
+	if getRunnerEnv(pod, EnvVarRunnerName) == "" {
+		setRunnerEnv(pod, EnvVarRunnerName, pod.Name)
+	}
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if c.Name != containerName {
+			continue
+		}
+
+		// The runner is registered by the controller, so the runner container never needs a registration token
+		var env []corev1.EnvVar
+		for _, e := range c.Env {
+			if e.Name != EnvVarRunnerToken && e.Name != EnvVarRunnerJITConfigFile {
+				env = append(env, e)
+			}
+		}
+		c.Env = append(env, corev1.EnvVar{Name: EnvVarRunnerJITConfigFile, Value: jitConfigVolumeMountPath + "/" + jitConfigSecretKey})
+
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      jitConfigVolumeName,
+			MountPath: jitConfigVolumeMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: jitConfigVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: jitConfigSecretName(pod)},
+							Items:                []corev1.KeyToPath{{Key: jitConfigSecretKey, Path: jitConfigSecretKey}},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	pod.Spec.SchedulingGates = append(pod.Spec.SchedulingGates, corev1.PodSchedulingGate{Name: SchedulingGateJITConfig})
+}
+
+func hasJITConfigGate(pod *corev1.Pod) bool {
+	for _, g := range pod.Spec.SchedulingGates {
+		if g.Name == SchedulingGateJITConfig {
+			return true
+		}
+	}
+
+	return false
+}
+
+// syncJITConfigGate generates the just-in-time configuration of the runner of the gated runner pod, populates its secret,
+// and removes the scheduling gate once it's populated. The pod is annotated with the ID of the registered runner,
+// so that it can be unregistered without waiting for the runner to come online.
+// It returns a non-nil result while the pod is still waiting for its configuration.
+func syncJITConfigGate(ctx context.Context, c client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, log logr.Logger, ghc *arcgithub.Client, enterprise, org, repo string, pod *corev1.Pod) (*corev1.Pod, *ctrl.Result, error) {
+	if !hasJITConfigGate(pod) {
+		return pod, nil, nil
+	}
+
+	name := getRunnerEnv(pod, EnvVarRunnerName)
+	if name == "" {
+		name = pod.Name
+	}
+
+	var labels []string
+	if v := getRunnerEnv(pod, EnvVarLabels); v != "" {
+		labels = strings.Split(v, ",")
+	}
+
+	jitConfig, err := ghc.GenerateJITConfig(ctx, enterprise, org, repo, &arcgithub.JITConfigRequest{
+		Name:       name,
+		Group:      getRunnerEnv(pod, EnvVarGroup),
+		Labels:     labels,
+		WorkFolder: getRunnerEnv(pod, "RUNNER_WORKDIR"),
+	})
+	if errors.Is(err, arcgithub.ErrRunnerAlreadyExists) {
+		// The configuration was generated by a previous reconciliation that failed to populate the secret,
+		// or the runner of a previous pod of the same name is left registered.
+		// Neither can be started anymore, so the runner is removed unless it's running a job.
+		err = removeStaleJITRunner(ctx, log, ghc, enterprise, org, repo, name)
+	}
+	if err != nil {
+		log.Error(err, "Failed to generate jit config for gated runner pod")
+
+		if _, err := setTokenPendingCondition(ctx, c, pod, corev1.ConditionTrue, jitConfigPendingReasonFailed, err.Error()); err != nil {
+			return nil, nil, err
+		}
+
+		recorder.Event(pod, corev1.EventTypeWarning, jitConfigPendingReasonFailed, err.Error())
+
+		retryDelay := registrationTokenRetryDelay
+		if retryAfter, ok := arcgithub.RetryAfterRateLimit(err); ok {
+			retryDelay = max(retryAfter, retryDelay)
+		}
+
+		return nil, &ctrl.Result{RequeueAfter: retryDelay}, nil
+	}
+	if jitConfig == nil {
+		// Generate the configuration again now that the stale runner is removed
+		return nil, &ctrl.Result{Requeue: true}, nil
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: pod.Namespace,
+			Name:      jitConfigSecretName(pod),
+		},
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		secret.Data = map[string][]byte{jitConfigSecretKey: []byte(jitConfig.EncodedJITConfig)}
+		// The secret is garbage-collected along with the pod
+		return controllerutil.SetOwnerReference(pod, secret, scheme)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("populating jit config secret %s: %w", secret.Name, err)
+	}
+
+	updated := pod.DeepCopy()
+	updated.Spec.SchedulingGates = nil
+	for _, g := range pod.Spec.SchedulingGates {
+		if g.Name != SchedulingGateJITConfig {
+			updated.Spec.SchedulingGates = append(updated.Spec.SchedulingGates, g)
+		}
+	}
+	setAnnotation(&updated.ObjectMeta, AnnotationKeyRunnerID, strconv.FormatInt(jitConfig.Runner.GetID(), 10))
+
+	if err := c.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, &ctrl.Result{}, nil
+		}
+		return nil, nil, fmt.Errorf("removing jit config scheduling gate of runner pod: %w", err)
+	}
+
+	log.Info("Populated jit config of gated runner pod. Released it for scheduling", "secret", secret.Name, "runnerId", jitConfig.Runner.GetID())
+
+	updated, err = setTokenPendingCondition(ctx, c, updated, corev1.ConditionFalse, jitConfigPendingReasonIssued, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return updated, nil, nil
+}
+
+// removeStaleJITRunner removes the registered runner of the name, so that a just-in-time configuration can be generated for the name again.
+func removeStaleJITRunner(ctx context.Context, log logr.Logger, ghc *arcgithub.Client, enterprise, org, repo, name string) error {
+	runner, err := ghc.GetRunner(ctx, enterprise, org, repo, name)
+	if err != nil {
+		return err
+	}
+
+	if runner == nil {
+		// Removed in the meantime
+		return nil
+	}
+
+	if runner.GetBusy() {
+		return fmt.Errorf("runner %s is already registered and running a job", name)
+	}
+
+	if err := ghc.RemoveRunner(ctx, enterprise, org, repo, runner.GetID()); err != nil {
+		return err
+	}
+
+	log.Info("Removed stale runner registered with the same name", "runnerId", runner.GetID())
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunnerConfigUsesJIT(t *testing.T) {
+	enabled, disabled := true, false
+
+	require.False(t, runnerConfigUsesJIT(v1alpha1.RunnerConfig{}))
+	require.False(t, runnerConfigUsesJIT(v1alpha1.RunnerConfig{JITConfig: &disabled}))
+	require.True(t, runnerConfigUsesJIT(v1alpha1.RunnerConfig{JITConfig: &enabled}))
+	require.True(t, runnerConfigUsesJIT(v1alpha1.RunnerConfig{JITConfig: &enabled, Ephemeral: &enabled}))
+	require.False(t, runnerConfigUsesJIT(v1alpha1.RunnerConfig{JITConfig: &enabled, Ephemeral: &disabled}))
+}
+
+func TestSyncJITConfigGate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	server := fake.NewServer(fake.WithListRunnersResponse(200, fake.RunnersListBody))
+	defer server.Close()
+
+	ghc := newGithubClient(server)
+
+	newPod := func(org, repo string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "example-runnerset-0",
+				Annotations: map[string]string{AnnotationKeyJITConfig: "true"},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: containerName,
+						Env: []corev1.EnvVar{
+							{Name: EnvVarEnterprise},
+							{Name: EnvVarOrg, Value: org},
+							{Name: EnvVarRepo, Value: repo},
+							{Name: EnvVarLabels, Value: "linux,gpu"},
+							{Name: "RUNNER_WORKDIR", Value: "/runner/_work"},
+						},
+					},
+				},
+			},
+		}
+
+		return mutatePod(pod, "")
+	}
+
+	condition := func(pod *corev1.Pod) *corev1.PodCondition {
+		for i := range pod.Status.Conditions {
+			if pod.Status.Conditions[i].Type == PodConditionTokenPending {
+				return &pod.Status.Conditions[i]
+			}
+		}
+		return nil
+	}
+
+	t.Run("gated pod", func(t *testing.T) {
+		pod := newPod("", "test/valid")
+
+		require.Equal(t, []corev1.PodSchedulingGate{{Name: SchedulingGateJITConfig}}, pod.Spec.SchedulingGates)
+		require.Equal(t, pod.Name, getRunnerEnv(pod, EnvVarRunnerName))
+		require.Empty(t, getRunnerEnv(pod, EnvVarRunnerToken))
+		require.Equal(t, "/runner-jitconfig/jitconfig", getRunnerEnv(pod, EnvVarRunnerJITConfigFile))
+		require.Contains(t, pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: jitConfigVolumeName, MountPath: jitConfigVolumeMountPath, ReadOnly: true})
+		require.Equal(t, "example-runnerset-0-jit-config", pod.Spec.Volumes[0].Projected.Sources[0].Secret.Name)
+
+		// Gating is idempotent
+		gatePodOnJITConfig(pod)
+		require.Len(t, pod.Spec.SchedulingGates, 1)
+		require.Len(t, pod.Spec.Volumes, 1)
+	})
+
+	t.Run("jit config issued", func(t *testing.T) {
+		ctx := context.Background()
+		pod := newPod("", "test/valid")
+		c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(&corev1.Pod{}).Build()
+
+		updated, res, err := syncJITConfigGate(ctx, c, scheme, record.NewFakeRecorder(10), logr.Discard(), ghc, "", "", "test/valid", pod)
+		require.NoError(t, err)
+		require.Nil(t, res)
+
+		var secret corev1.Secret
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-runnerset-0-jit-config"}, &secret))
+		require.Equal(t, fake.JITConfig, string(secret.Data[jitConfigSecretKey]))
+		require.Equal(t, pod.Name, secret.OwnerReferences[0].Name)
+
+		var got corev1.Pod
+		require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(pod), &got))
+		require.Empty(t, got.Spec.SchedulingGates)
+		require.Equal(t, "3", got.Annotations[AnnotationKeyRunnerID])
+		require.Equal(t, corev1.ConditionFalse, condition(&got).Status)
+		require.Equal(t, jitConfigPendingReasonIssued, condition(updated).Reason)
+	})
+
+	t.Run("jit config request failed", func(t *testing.T) {
+		ctx := context.Background()
+		pod := newPod("error", "")
+		c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(&corev1.Pod{}).Build()
+
+		_, res, err := syncJITConfigGate(ctx, c, scheme, record.NewFakeRecorder(10), logr.Discard(), ghc, "", "error", "", pod)
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		require.Equal(t, registrationTokenRetryDelay, res.RequeueAfter)
+
+		var got corev1.Pod
+		require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(pod), &got))
+		require.Len(t, got.Spec.SchedulingGates, 1)
+		require.Empty(t, got.Annotations[AnnotationKeyRunnerID])
+		require.Equal(t, corev1.ConditionTrue, condition(&got).Status)
+		require.Equal(t, jitConfigPendingReasonFailed, condition(&got).Reason)
+	})
+}
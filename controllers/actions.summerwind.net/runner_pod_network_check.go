@@ -0,0 +1,205 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/githuburl"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PodConditionNetworkReady is the readiness gate of runner pods with the network check enabled.
+	// It's true once the runner pod has verified that the GitHub and Actions endpoints are reachable.
+	PodConditionNetworkReady corev1.PodConditionType = "actions.summerwind.dev/network-ready"
+
+	networkCheckContainerName = "network-check"
+
+	networkReadyReasonChecking    = "NetworkCheckInProgress"
+	networkReadyReasonReachable   = "GitHubReachable"
+	networkReadyReasonUnreachable = "GitHubUnreachable"
+
+	defaultNetworkCheckTimeoutSeconds = 120
+)
+
+// networkCheckScript resolves and connects to each endpoint until all of them succeed or the timeout elapses.
+// The failure is written to the termination log so that it ends up in the message of the pod condition.
+const networkCheckScript = `deadline=$(( $(date +%s) + NETWORK_CHECK_TIMEOUT_SECONDS ))
+for endpoint in $NETWORK_CHECK_ENDPOINTS; do
+  host=$(echo "$endpoint" | sed -E 's#^[a-z]+://([^/:]+).*#\1#')
+  until err=$(getent hosts "$host" >/dev/null || echo "DNS resolution of $host failed") && [ -z "$err" ] \
+    && err=$(curl -sS -o /dev/null --connect-timeout 5 --max-time 10 "$endpoint" 2>&1) ; do
+    if [ "$(date +%s)" -ge "$deadline" ]; then
+      echo "$endpoint is unreachable: $err" | tee /dev/termination-log
+      exit 1
+    fi
+    sleep 5
+  done
+  echo "$endpoint is reachable"
+done
+`
+
+// networkCheckEndpoints returns the endpoints checked by default for the GitHub URL.
+func networkCheckEndpoints(githubBaseURL string) []string {
+	endpoints := []string{githubBaseURL}
+
+	u, err := url.Parse(githubBaseURL)
+	if err != nil {
+		return endpoints
+	}
+
+	if u.Host == "github.com" {
+		endpoints = append(endpoints, "https://api.github.com/", "https://pipelines.actions.githubusercontent.com/")
+	} else if host, ok := githuburl.DataResidencyHost(u.Host); ok {
+		endpoints = append(endpoints, fmt.Sprintf("%s://api.%s/", u.Scheme, host))
+	}
+
+	return endpoints
+}
+
+// applyNetworkCheck injects the network check init container and the readiness gate into the runner pod.
+// The init container runs before any other init container, using the runner image so that no additional image is pulled.
+func applyNetworkCheck(pod *corev1.Pod, spec *v1alpha1.NetworkCheckSpec, githubBaseURL string) {
+	if spec == nil {
+		return
+	}
+
+	for _, c := range pod.Spec.InitContainers {
+		if c.Name == networkCheckContainerName {
+			return
+		}
+	}
+
+	var runner *corev1.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == containerName {
+			runner = &pod.Spec.Containers[i]
+		}
+	}
+
+	if runner == nil {
+		return
+	}
+
+	endpoints := spec.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = networkCheckEndpoints(githubBaseURL)
+	}
+
+	timeout := int32(defaultNetworkCheckTimeoutSeconds)
+	if spec.TimeoutSeconds != nil {
+		timeout = *spec.TimeoutSeconds
+	}
+
+	check := corev1.Container{
+		Name:            networkCheckContainerName,
+		Image:           runner.Image,
+		ImagePullPolicy: runner.ImagePullPolicy,
+		Command:         []string{"sh", "-c", strings.ReplaceAll(networkCheckScript, "NETWORK_CHECK_TIMEOUT_SECONDS", fmt.Sprintf("%d", timeout))},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "NETWORK_CHECK_ENDPOINTS",
+				Value: strings.Join(endpoints, " "),
+			},
+		},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	}
+
+	pod.Spec.InitContainers = append([]corev1.Container{check}, pod.Spec.InitContainers...)
+	pod.Spec.ReadinessGates = append(pod.Spec.ReadinessGates, corev1.PodReadinessGate{ConditionType: PodConditionNetworkReady})
+}
+
+// networkReadyCondition computes the network-ready condition of the runner pod from the status of the network check init container.
+// It returns false when the pod doesn't have the network check enabled.
+func networkReadyCondition(pod *corev1.Pod) (corev1.PodCondition, bool) {
+	var gated bool
+	for _, g := range pod.Spec.ReadinessGates {
+		if g.ConditionType == PodConditionNetworkReady {
+			gated = true
+		}
+	}
+
+	if !gated {
+		return corev1.PodCondition{}, false
+	}
+
+	cond := corev1.PodCondition{
+		Type:   PodConditionNetworkReady,
+		Status: corev1.ConditionFalse,
+		Reason: networkReadyReasonChecking,
+	}
+
+	for _, s := range pod.Status.InitContainerStatuses {
+		if s.Name != networkCheckContainerName {
+			continue
+		}
+
+		terminated := s.State.Terminated
+		if terminated == nil {
+			// The check is retried when the pod's restart policy isn't Never, as in RunnerSet pods
+			terminated = s.LastTerminationState.Terminated
+		}
+
+		switch {
+		case s.State.Terminated != nil && s.State.Terminated.ExitCode == 0:
+			cond.Status = corev1.ConditionTrue
+			cond.Reason = networkReadyReasonReachable
+		case terminated != nil && terminated.ExitCode != 0:
+			cond.Reason = networkReadyReasonUnreachable
+			cond.Message = strings.TrimSpace(terminated.Message)
+		}
+	}
+
+	return cond, true
+}
+
+// syncNetworkReadyCondition reflects the result of the network check onto the runner pod's readiness gate,
+// so that a broken node egress shows up as a clear pod condition instead of the registration silently timing out.
+func syncNetworkReadyCondition(ctx context.Context, c client.Client, recorder record.EventRecorder, log logr.Logger, pod *corev1.Pod) (*corev1.Pod, error) {
+	cond, ok := networkReadyCondition(pod)
+	if !ok {
+		return pod, nil
+	}
+
+	for _, existing := range pod.Status.Conditions {
+		if existing.Type == cond.Type && existing.Status == cond.Status && existing.Reason == cond.Reason && existing.Message == cond.Message {
+			return pod, nil
+		}
+	}
+
+	cond.LastTransitionTime = metav1.Now()
+
+	updated := pod.DeepCopy()
+
+	var replaced bool
+	for i := range updated.Status.Conditions {
+		if updated.Status.Conditions[i].Type == cond.Type {
+			updated.Status.Conditions[i] = cond
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		updated.Status.Conditions = append(updated.Status.Conditions, cond)
+	}
+
+	if err := c.Status().Patch(ctx, updated, client.StrategicMergeFrom(pod)); err != nil {
+		return nil, fmt.Errorf("updating %s condition of runner pod: %w", cond.Type, err)
+	}
+
+	if cond.Reason == networkReadyReasonUnreachable {
+		log.Info("Runner pod failed to reach GitHub. Check the egress of the node", "node", pod.Spec.NodeName, "message", cond.Message)
+		recorder.Event(pod, corev1.EventTypeWarning, networkReadyReasonUnreachable, cond.Message)
+	} else {
+		log.V(1).Info("Updated network readiness of runner pod", "status", cond.Status, "reason", cond.Reason)
+	}
+
+	return updated, nil
+}
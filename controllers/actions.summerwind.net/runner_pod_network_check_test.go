@@ -0,0 +1,157 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplyNetworkCheck(t *testing.T) {
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "user-init"}},
+				Containers:     []corev1.Container{{Name: containerName, Image: "runner:latest", ImagePullPolicy: corev1.PullIfNotPresent}},
+			},
+		}
+	}
+
+	pod := newPod()
+	applyNetworkCheck(pod, nil, "https://github.com/")
+	require.Len(t, pod.Spec.InitContainers, 1)
+	require.Empty(t, pod.Spec.ReadinessGates)
+
+	pod = newPod()
+	applyNetworkCheck(pod, &v1alpha1.NetworkCheckSpec{}, "https://github.com/")
+	require.Len(t, pod.Spec.InitContainers, 2)
+
+	check := pod.Spec.InitContainers[0]
+	require.Equal(t, networkCheckContainerName, check.Name)
+	require.Equal(t, "runner:latest", check.Image)
+	require.Equal(t, corev1.PullIfNotPresent, check.ImagePullPolicy)
+	require.Contains(t, check.Command[2], "deadline=$(( $(date +%s) + 120 ))")
+	require.Equal(t, "https://github.com/ https://api.github.com/ https://pipelines.actions.githubusercontent.com/", check.Env[0].Value)
+	require.Equal(t, []corev1.PodReadinessGate{{ConditionType: PodConditionNetworkReady}}, pod.Spec.ReadinessGates)
+
+	// Applying twice, as when the pod template of a RunnerSet is rebuilt, doesn't duplicate the check
+	applyNetworkCheck(pod, &v1alpha1.NetworkCheckSpec{}, "https://github.com/")
+	require.Len(t, pod.Spec.InitContainers, 2)
+	require.Len(t, pod.Spec.ReadinessGates, 1)
+
+	timeout := int32(30)
+	pod = newPod()
+	applyNetworkCheck(pod, &v1alpha1.NetworkCheckSpec{Endpoints: []string{"https://ghes.example.com/"}, TimeoutSeconds: &timeout}, "https://ghes.example.com/")
+	require.Contains(t, pod.Spec.InitContainers[0].Command[2], "+ 30 ))")
+	require.Equal(t, "https://ghes.example.com/", pod.Spec.InitContainers[0].Env[0].Value)
+}
+
+func TestNetworkCheckEndpoints(t *testing.T) {
+	require.Equal(t, []string{"https://ghes.example.com/"}, networkCheckEndpoints("https://ghes.example.com/"))
+	require.Len(t, networkCheckEndpoints("https://github.com/"), 3)
+	require.Equal(t, []string{"https://my-tenant.ghe.com/", "https://api.my-tenant.ghe.com/"}, networkCheckEndpoints("https://my-tenant.ghe.com/"))
+}
+
+func TestSyncNetworkReadyCondition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	newPod := func(statuses ...corev1.ContainerStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				ReadinessGates: []corev1.PodReadinessGate{{ConditionType: PodConditionNetworkReady}},
+			},
+			Status: corev1.PodStatus{InitContainerStatuses: statuses},
+		}
+	}
+
+	terminated := func(code int32, msg string) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: code, Message: msg}}
+	}
+
+	tests := []struct {
+		name       string
+		pod        *corev1.Pod
+		wantStatus corev1.ConditionStatus
+		wantReason string
+		wantEvent  bool
+	}{
+		{
+			name:       "checking",
+			pod:        newPod(corev1.ContainerStatus{Name: networkCheckContainerName, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}),
+			wantStatus: corev1.ConditionFalse,
+			wantReason: networkReadyReasonChecking,
+		},
+		{
+			name:       "reachable",
+			pod:        newPod(corev1.ContainerStatus{Name: networkCheckContainerName, State: terminated(0, "")}),
+			wantStatus: corev1.ConditionTrue,
+			wantReason: networkReadyReasonReachable,
+		},
+		{
+			name:       "unreachable",
+			pod:        newPod(corev1.ContainerStatus{Name: networkCheckContainerName, State: terminated(1, "https://github.com/ is unreachable: timeout\n")}),
+			wantStatus: corev1.ConditionFalse,
+			wantReason: networkReadyReasonUnreachable,
+			wantEvent:  true,
+		},
+		{
+			name: "retrying after failure",
+			pod: newPod(corev1.ContainerStatus{
+				Name:                 networkCheckContainerName,
+				State:                corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				LastTerminationState: terminated(1, "https://github.com/ is unreachable: timeout"),
+			}),
+			wantStatus: corev1.ConditionFalse,
+			wantReason: networkReadyReasonUnreachable,
+			wantEvent:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.pod).WithStatusSubresource(tt.pod).Build()
+			recorder := record.NewFakeRecorder(1)
+
+			var pod corev1.Pod
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(tt.pod), &pod))
+
+			_, err := syncNetworkReadyCondition(context.Background(), c, recorder, logr.Discard(), &pod)
+			require.NoError(t, err)
+
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(tt.pod), &pod))
+			require.Len(t, pod.Status.Conditions, 1)
+			require.Equal(t, PodConditionNetworkReady, pod.Status.Conditions[0].Type)
+			require.Equal(t, tt.wantStatus, pod.Status.Conditions[0].Status)
+			require.Equal(t, tt.wantReason, pod.Status.Conditions[0].Reason)
+
+			if tt.wantEvent {
+				require.Equal(t, "https://github.com/ is unreachable: timeout", pod.Status.Conditions[0].Message)
+				require.Len(t, recorder.Events, 1)
+			} else {
+				require.Empty(t, recorder.Events)
+			}
+
+			// The condition is only patched when it changes
+			updated, err := syncNetworkReadyCondition(context.Background(), c, recorder, logr.Discard(), &pod)
+			require.NoError(t, err)
+			require.Equal(t, &pod, updated)
+		})
+	}
+
+	t.Run("not gated", func(t *testing.T) {
+		pod := &corev1.Pod{}
+		updated, err := syncNetworkReadyCondition(context.Background(), nil, nil, logr.Discard(), pod)
+		require.NoError(t, err)
+		require.Equal(t, pod, updated)
+	})
+}
@@ -23,6 +23,7 @@ type podsForOwner struct {
 	terminating  int
 	regTimeout   int
 	pending      int
+	interrupted  int
 	templateHash string
 	runner       *v1alpha1.Runner
 	statefulSet  *appsv1.StatefulSet
@@ -32,6 +33,21 @@ type podsForOwner struct {
 	pods         []corev1.Pod
 }
 
+// scaleDownOrder returns the owners in the order they're deleted on scale down, first to last.
+// Warm standby runners go first, as they have never registered themselves and are only waiting to be promoted,
+// while registered runners need to be unregistered and may be about to be assigned a job.
+// The owners are otherwise deleted oldest first.
+func scaleDownOrder(owners []*podsForOwner) []*podsForOwner {
+	ordered := make([]*podsForOwner, len(owners))
+	copy(ordered, owners)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return isWarmStandby(ordered[i].object) && !isWarmStandby(ordered[j].object)
+	})
+
+	return ordered
+}
+
 type owner interface {
 	client.Object
 
@@ -132,7 +148,7 @@ func (s *ownerStatefulSet) synced() bool {
 	return true
 }
 
-func getPodsForOwner(ctx context.Context, c client.Client, log logr.Logger, o client.Object) (*podsForOwner, error) {
+func getPodsForOwner(ctx context.Context, c client.Client, log logr.Logger, o client.Object, now time.Time) (*podsForOwner, error) {
 	var (
 		owner       owner
 		runner      *v1alpha1.Runner
@@ -166,15 +182,23 @@ func getPodsForOwner(ctx context.Context, c client.Client, log logr.Logger, o cl
 		return nil, err
 	}
 
-	var completed, running, terminating, regTimeout, pending, total int
+	var completed, running, terminating, regTimeout, pending, interrupted, total int
+
+	// The runner pods of an owner unregistering on idle timeout are going away without being replaced
+	_, idleTimedOut := getAnnotation(object, AnnotationKeyIdleTimeoutTimestamp)
 
 	for _, pod := range pods {
 		total++
 
 		if runnerPodOrContainerIsStopped(&pod) {
 			completed++
+		} else if idleTimedOut {
+			terminating++
+		} else if _, ok := getAnnotation(&pod, AnnotationKeyNodeInterruptionTimestamp); ok && pod.DeletionTimestamp.IsZero() {
+			// The pod is going to be lost along with its node, so it's replaced while it's still draining
+			interrupted++
 		} else if pod.Status.Phase == corev1.PodRunning {
-			if podRunnerID(&pod) == "" && podConditionTransitionTimeAfter(&pod, corev1.PodReady, registrationTimeout) {
+			if podRunnerID(&pod) == "" && !isWarmStandby(&pod) && podRegistrationTimedOut(&pod, now) {
 				log.Info(
 					"Runner failed to register itself to GitHub in timely manner. "+
 						"Recreating the pod to see if it resolves the issue. "+
@@ -213,6 +237,7 @@ func getPodsForOwner(ctx context.Context, c client.Client, log logr.Logger, o cl
 		terminating:  terminating,
 		regTimeout:   regTimeout,
 		pending:      pending,
+		interrupted:  interrupted,
 		templateHash: templateHash,
 		runner:       runner,
 		statefulSet:  statefulSet,
@@ -249,8 +274,8 @@ type result struct {
 // The second call fails due to the first call mutated the client.Object to have .Revision.
 // Passing a factory function of client.Object and creating a brand-new client.Object per a client.Create call resolves this issue,
 // allowing us to create two or more replicas in one reconcilation loop without being rejected by K8s.
-func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger, effectiveTime *metav1.Time, newDesiredReplicas int, create func() client.Object, ephemeral bool, owners []client.Object) (*result, error) {
-	state, err := collectPodsForOwners(ctx, c, log, owners)
+func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger, now time.Time, effectiveTime *metav1.Time, newDesiredReplicas int, create func() client.Object, ephemeral bool, owners []client.Object) (*result, error) {
+	state, err := collectPodsForOwners(ctx, c, log, owners, now)
 	if err != nil || state == nil {
 		return nil, err
 	}
@@ -302,7 +327,7 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 		log.V(2).Info("Detected some current object(s)", "creationTimestampFirst", timestampFirst, "creationTimestampLast", timestampLast, "names", names)
 	}
 
-	var total, terminating, pending, running, regTimeout int
+	var total, terminating, pending, running, regTimeout, interrupted int
 
 	for _, ss := range currentObjects {
 		total += ss.total
@@ -310,6 +335,7 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 		pending += ss.pending
 		running += ss.running
 		regTimeout += ss.regTimeout
+		interrupted += ss.interrupted
 	}
 
 	numOwners := len(owners)
@@ -326,6 +352,7 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 		"pending", pending,
 		"running", running,
 		"regTimeout", regTimeout,
+		"interrupted", interrupted,
 		"desired", newDesiredReplicas,
 		"owners", numOwners,
 	)
@@ -334,7 +361,7 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 
 	wantMoreRunners := newDesiredReplicas > maybeRunning
 	alreadySyncedAfterEffectiveTime := ephemeral && lastSyncTime != nil && effectiveTime != nil && lastSyncTime.After(effectiveTime.Time)
-	runnerPodRecreationDelayAfterWebhookScale := lastSyncTime != nil && time.Now().Before(lastSyncTime.Add(DefaultRunnerPodRecreationDelayAfterWebhookScale))
+	runnerPodRecreationDelayAfterWebhookScale := lastSyncTime != nil && now.Before(lastSyncTime.Add(DefaultRunnerPodRecreationDelayAfterWebhookScale))
 
 	log = log.WithValues(
 		"lastSyncTime", lastSyncTime,
@@ -347,7 +374,7 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 		"templateHashObserved", hashes,
 	)
 
-	if wantMoreRunners && alreadySyncedAfterEffectiveTime && runnerPodRecreationDelayAfterWebhookScale {
+	if wantMoreRunners && alreadySyncedAfterEffectiveTime && runnerPodRecreationDelayAfterWebhookScale && interrupted == 0 {
 		// This is our special handling of the situation for ephemeral runners only.
 		//
 		// Handling static runners this way results in scale-up to not work at all,
@@ -369,6 +396,12 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 
 		num := newDesiredReplicas - maybeRunning
 
+		if alreadySyncedAfterEffectiveTime && runnerPodRecreationDelayAfterWebhookScale && num > interrupted {
+			// Replace only the runners on the interrupted nodes, not the ephemeral runners that have disappeared
+			// due to their completions, for the same reason as the above.
+			num = interrupted
+		}
+
 		for i := 0; i < num; i++ {
 			// Add more replicas
 			if err := c.Create(ctx, create()); err != nil {
@@ -402,9 +435,11 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 
 		var retained int
 
+		candidates := scaleDownOrder(currentObjects)
+
 		var delete []*podsForOwner
-		for i := len(currentObjects) - 1; i >= 0; i-- {
-			ss := currentObjects[i]
+		for i := len(candidates) - 1; i >= 0; i-- {
+			ss := candidates[i]
 
 			if ss.running == 0 || retained >= newDesiredReplicas {
 				// In case the desired replicas is satisfied until i-1, or this owner has no running pods,
@@ -482,7 +517,7 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 	}, nil
 }
 
-func collectPodsForOwners(ctx context.Context, c client.Client, log logr.Logger, owners []client.Object) (*state, error) {
+func collectPodsForOwners(ctx context.Context, c client.Client, log logr.Logger, owners []client.Object, now time.Time) (*state, error) {
 	podsForOwnerPerTemplateHash := map[string][]*podsForOwner{}
 
 	// lastSyncTime becomes non-nil only when there are one or more owner(s) hence there are same number of runner pods.
@@ -497,7 +532,7 @@ func collectPodsForOwners(ctx context.Context, c client.Client, log logr.Logger,
 	for _, ss := range owners {
 		log := log.WithValues("owner", types.NamespacedName{Namespace: ss.GetNamespace(), Name: ss.GetName()})
 
-		res, err := getPodsForOwner(ctx, c, log, ss)
+		res, err := getPodsForOwner(ctx, c, log, ss, now)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,47 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	shmVolumeName      = "dshm"
+	shmVolumeMountPath = "/dev/shm"
+)
+
+// applyShmSize mounts a memory-backed emptyDir volume of the given size at /dev/shm of the runner container,
+// which replaces the fixed-size /dev/shm provided by the container runtime.
+func applyShmSize(pod *corev1.Pod, runnerContainer *corev1.Container, size *resource.Quantity) error {
+	if size == nil {
+		return nil
+	}
+
+	for _, m := range runnerContainer.VolumeMounts {
+		if path.Clean(m.MountPath) == shmVolumeMountPath {
+			return fmt.Errorf("shmSize can't be used along with the volume mount %q at %s", m.Name, shmVolumeMountPath)
+		}
+	}
+
+	sizeLimit := size.DeepCopy()
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: shmVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				Medium:    corev1.StorageMediumMemory,
+				SizeLimit: &sizeLimit,
+			},
+		},
+	})
+
+	runnerContainer.VolumeMounts = append(runnerContainer.VolumeMounts, corev1.VolumeMount{
+		Name:      shmVolumeName,
+		MountPath: shmVolumeMountPath,
+	})
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestNewRunnerPod_ShmSize(t *testing.T) {
+	size := resource.MustParse("2Gi")
+
+	pod, err := newRunnerPod(corev1.Pod{}, v1alpha1.RunnerConfig{ShmSize: &size}, "api.github.com", RunnerPodDefaults{})
+	require.NoError(t, err)
+
+	var shm []corev1.Volume
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == shmVolumeName {
+			shm = append(shm, v)
+		}
+	}
+	require.Len(t, shm, 1)
+	require.Equal(t, corev1.StorageMediumMemory, shm[0].EmptyDir.Medium)
+	require.Equal(t, &size, shm[0].EmptyDir.SizeLimit)
+
+	var runner corev1.Container
+	for _, c := range pod.Spec.Containers {
+		if c.Name == containerName {
+			runner = c
+		}
+	}
+	require.Contains(t, runner.VolumeMounts, corev1.VolumeMount{Name: shmVolumeName, MountPath: "/dev/shm"})
+
+	_, err = newRunnerPod(corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:         containerName,
+					VolumeMounts: []corev1.VolumeMount{{Name: "shm", MountPath: "/dev/shm/"}},
+				},
+			},
+		},
+	}, v1alpha1.RunnerConfig{ShmSize: &size}, "api.github.com", RunnerPodDefaults{})
+	require.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package actionssummerwindnet
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	SpreadNode = "node"
+	SpreadZone = "zone"
+	SpreadNone = "none"
+)
+
+// applySpread expands the spread preset into topology spread constraints on the runner pod.
+// The constraints are best-effort, so that runners are never left pending due to the lack of nodes or zones.
+// Explicitly configured constraints always take precedence over the preset.
+func applySpread(pod *corev1.Pod, spread string) {
+	if len(pod.Spec.TopologySpreadConstraints) != 0 {
+		return
+	}
+
+	var topologyKeys []string
+
+	switch spread {
+	case SpreadNode:
+		topologyKeys = []string{corev1.LabelHostname}
+	case SpreadZone:
+		topologyKeys = []string{corev1.LabelTopologyZone, corev1.LabelHostname}
+	default:
+		return
+	}
+
+	selector := spreadSelector(pod.Labels)
+
+	for _, key := range topologyKeys {
+		pod.Spec.TopologySpreadConstraints = append(pod.Spec.TopologySpreadConstraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       key,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     selector,
+		})
+	}
+}
+
+// spreadSelector selects the runner pods of the same pool, regardless of the template hash,
+// so that the pods are spread across rolling updates too.
+func spreadSelector(labels map[string]string) *metav1.LabelSelector {
+	for _, key := range []string{LabelKeyRunnerDeploymentName, LabelKeyRunnerSetName} {
+		if v, ok := labels[key]; ok {
+			return &metav1.LabelSelector{MatchLabels: map[string]string{key: v}}
+		}
+	}
+
+	// Standalone runners are spread across all the runners in the namespace
+	return &metav1.LabelSelector{MatchLabels: map[string]string{LabelKeyRunner: ""}}
+}
@@ -0,0 +1,48 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplySpread(t *testing.T) {
+	rdPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					LabelKeyRunnerDeploymentName: "example",
+					LabelKeyRunnerTemplateHash:   "abc",
+				},
+			},
+		}
+	}
+
+	pod := rdPod()
+	applySpread(pod, SpreadZone)
+	require.Len(t, pod.Spec.TopologySpreadConstraints, 2)
+	require.Equal(t, corev1.LabelTopologyZone, pod.Spec.TopologySpreadConstraints[0].TopologyKey)
+	require.Equal(t, corev1.LabelHostname, pod.Spec.TopologySpreadConstraints[1].TopologyKey)
+	require.Equal(t, corev1.ScheduleAnyway, pod.Spec.TopologySpreadConstraints[0].WhenUnsatisfiable)
+	require.Equal(t, map[string]string{LabelKeyRunnerDeploymentName: "example"}, pod.Spec.TopologySpreadConstraints[0].LabelSelector.MatchLabels)
+
+	pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{LabelKeyRunnerSetName: "example-set"}}}
+	applySpread(pod, SpreadNode)
+	require.Len(t, pod.Spec.TopologySpreadConstraints, 1)
+	require.Equal(t, map[string]string{LabelKeyRunnerSetName: "example-set"}, pod.Spec.TopologySpreadConstraints[0].LabelSelector.MatchLabels)
+
+	for _, spread := range []string{"", SpreadNone} {
+		pod = rdPod()
+		applySpread(pod, spread)
+		require.Empty(t, pod.Spec.TopologySpreadConstraints)
+	}
+
+	// Explicit constraints take precedence
+	pod = rdPod()
+	pod.Spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{{MaxSkew: 2, TopologyKey: "custom"}}
+	applySpread(pod, SpreadNode)
+	require.Len(t, pod.Spec.TopologySpreadConstraints, 1)
+	require.Equal(t, "custom", pod.Spec.TopologySpreadConstraints[0].TopologyKey)
+}
@@ -0,0 +1,201 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	arcgithub "github.com/actions/actions-runner-controller/github"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// SchedulingGateRegistrationToken holds a runner pod off the nodes until its registration token secret is populated.
+	// The runner pod webhook adds it when it fails to obtain the token at admission, instead of admitting a pod without the token
+	// that crash-loops until it's recreated.
+	SchedulingGateRegistrationToken = "actions.summerwind.dev/registration-token"
+
+	// PodConditionTokenPending is true while the runner pod waits for its registration token.
+	PodConditionTokenPending corev1.PodConditionType = "actions.summerwind.dev/token-pending"
+
+	tokenPendingReasonFailed = "TokenRequestFailed"
+	tokenPendingReasonIssued = "TokenIssued"
+
+	registrationTokenSecretKey = "token"
+
+	// registrationTokenRetryDelay is how long the controller waits before requesting the registration token of a gated pod again.
+	registrationTokenRetryDelay = 10 * time.Second
+)
+
+// registrationTokenSecretName returns the name of the secret the registration token of the gated runner pod is read from.
+func registrationTokenSecretName(pod *corev1.Pod) string {
+	return pod.Name + "-registration-token"
+}
+
+// gatePodOnRegistrationToken makes the runner container read the registration token from a secret that the runner pod controller populates later,
+// and holds the pod off the nodes with a scheduling gate until then.
+func gatePodOnRegistrationToken(pod *corev1.Pod) {
+	for _, g := range pod.Spec.SchedulingGates {
+		if g.Name == SchedulingGateRegistrationToken {
+			return
+		}
+	}
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if c.Name != containerName {
+			continue
+		}
+
+		if getRunnerEnv(pod, EnvVarRunnerName) == "" {
+			setRunnerEnv(pod, EnvVarRunnerName, pod.Name)
+		}
+
+		env := corev1.EnvVar{
+			Name: EnvVarRunnerToken,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: registrationTokenSecretName(pod)},
+					Key:                  registrationTokenSecretKey,
+				},
+			},
+		}
+
+		var replaced bool
+		for j := range c.Env {
+			if c.Env[j].Name == EnvVarRunnerToken {
+				c.Env[j] = env
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			c.Env = append(c.Env, env)
+		}
+	}
+
+	pod.Spec.SchedulingGates = append(pod.Spec.SchedulingGates, corev1.PodSchedulingGate{Name: SchedulingGateRegistrationToken})
+
+	addRunnerSlotContainers(pod)
+}
+
+func hasRegistrationTokenGate(pod *corev1.Pod) bool {
+	for _, g := range pod.Spec.SchedulingGates {
+		if g.Name == SchedulingGateRegistrationToken {
+			return true
+		}
+	}
+
+	return false
+}
+
+// syncRegistrationTokenGate populates the registration token secret of the gated runner pod and removes the scheduling gate once it's populated.
+// It returns a non-nil result while the pod is still waiting for its token.
+func syncRegistrationTokenGate(ctx context.Context, c client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, log logr.Logger, ghc *arcgithub.Client, enterprise, org, repo string, pod *corev1.Pod) (*corev1.Pod, *ctrl.Result, error) {
+	if !hasRegistrationTokenGate(pod) {
+		return pod, nil, nil
+	}
+
+	rt, err := ghc.GetRegistrationToken(ctx, enterprise, org, repo, pod.Name)
+	if err != nil {
+		log.Error(err, "Failed to get registration token for gated runner pod")
+
+		if _, err := setTokenPendingCondition(ctx, c, pod, corev1.ConditionTrue, tokenPendingReasonFailed, err.Error()); err != nil {
+			return nil, nil, err
+		}
+
+		recorder.Event(pod, corev1.EventTypeWarning, tokenPendingReasonFailed, err.Error())
+
+		retryDelay := registrationTokenRetryDelay
+		if retryAfter, ok := arcgithub.RetryAfterRateLimit(err); ok {
+			retryDelay = max(retryAfter, retryDelay)
+		}
+
+		return nil, &ctrl.Result{RequeueAfter: retryDelay}, nil
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: pod.Namespace,
+			Name:      registrationTokenSecretName(pod),
+		},
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		secret.Data = map[string][]byte{registrationTokenSecretKey: []byte(rt.GetToken())}
+		// The secret is garbage-collected along with the pod
+		return controllerutil.SetOwnerReference(pod, secret, scheme)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("populating registration token secret %s: %w", secret.Name, err)
+	}
+
+	updated := pod.DeepCopy()
+	updated.Spec.SchedulingGates = nil
+	for _, g := range pod.Spec.SchedulingGates {
+		if g.Name != SchedulingGateRegistrationToken {
+			updated.Spec.SchedulingGates = append(updated.Spec.SchedulingGates, g)
+		}
+	}
+	setAnnotation(&updated.ObjectMeta, AnnotationKeyTokenExpirationDate, rt.GetExpiresAt().Format(time.RFC3339))
+
+	if err := c.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, &ctrl.Result{}, nil
+		}
+		return nil, nil, fmt.Errorf("removing registration token scheduling gate of runner pod: %w", err)
+	}
+
+	log.Info("Populated registration token of gated runner pod. Released it for scheduling", "secret", secret.Name)
+
+	updated, err = setTokenPendingCondition(ctx, c, updated, corev1.ConditionFalse, tokenPendingReasonIssued, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return updated, nil, nil
+}
+
+// setTokenPendingCondition updates the TokenPending condition of the runner pod when it's changed.
+func setTokenPendingCondition(ctx context.Context, c client.Client, pod *corev1.Pod, status corev1.ConditionStatus, reason, message string) (*corev1.Pod, error) {
+	for _, existing := range pod.Status.Conditions {
+		if existing.Type == PodConditionTokenPending && existing.Status == status && existing.Reason == reason && existing.Message == message {
+			return pod, nil
+		}
+	}
+
+	cond := corev1.PodCondition{
+		Type:               PodConditionTokenPending,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	}
+
+	updated := pod.DeepCopy()
+
+	var replaced bool
+	for i := range updated.Status.Conditions {
+		if updated.Status.Conditions[i].Type == cond.Type {
+			updated.Status.Conditions[i] = cond
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		updated.Status.Conditions = append(updated.Status.Conditions, cond)
+	}
+
+	if err := c.Status().Patch(ctx, updated, client.StrategicMergeFrom(pod)); err != nil {
+		return nil, fmt.Errorf("updating %s condition of runner pod: %w", cond.Type, err)
+	}
+
+	return updated, nil
+}
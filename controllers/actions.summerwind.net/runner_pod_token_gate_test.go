@@ -0,0 +1,118 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncRegistrationTokenGate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	server := fake.NewServer(fake.WithListRunnersResponse(200, fake.RunnersListBody))
+	defer server.Close()
+
+	ghc := newGithubClient(server)
+
+	newGatedPod := func(repo string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example-runnerset-0"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: containerName,
+						Env: []corev1.EnvVar{
+							{Name: EnvVarEnterprise},
+							{Name: EnvVarOrg},
+							{Name: EnvVarRepo, Value: repo},
+						},
+					},
+				},
+			},
+		}
+
+		gatePodOnRegistrationToken(pod)
+
+		return pod
+	}
+
+	condition := func(pod *corev1.Pod) *corev1.PodCondition {
+		for i := range pod.Status.Conditions {
+			if pod.Status.Conditions[i].Type == PodConditionTokenPending {
+				return &pod.Status.Conditions[i]
+			}
+		}
+		return nil
+	}
+
+	t.Run("gated pod", func(t *testing.T) {
+		pod := newGatedPod("test/valid")
+
+		require.Equal(t, []corev1.PodSchedulingGate{{Name: SchedulingGateRegistrationToken}}, pod.Spec.SchedulingGates)
+		require.Equal(t, pod.Name, getRunnerEnv(pod, EnvVarRunnerName))
+		require.Contains(t, pod.Spec.Containers[0].Env, corev1.EnvVar{
+			Name: EnvVarRunnerToken,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "example-runnerset-0-registration-token"},
+					Key:                  registrationTokenSecretKey,
+				},
+			},
+		})
+
+		// Gating is idempotent
+		gatePodOnRegistrationToken(pod)
+		require.Len(t, pod.Spec.SchedulingGates, 1)
+	})
+
+	t.Run("token issued", func(t *testing.T) {
+		ctx := context.Background()
+		pod := newGatedPod("test/valid")
+		c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(&corev1.Pod{}).Build()
+
+		updated, res, err := syncRegistrationTokenGate(ctx, c, scheme, record.NewFakeRecorder(10), logr.Discard(), ghc, "", "", "test/valid", pod)
+		require.NoError(t, err)
+		require.Nil(t, res)
+
+		var secret corev1.Secret
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-runnerset-0-registration-token"}, &secret))
+		require.Equal(t, fake.RegistrationToken, string(secret.Data[registrationTokenSecretKey]))
+		require.Equal(t, pod.Name, secret.OwnerReferences[0].Name)
+
+		var got corev1.Pod
+		require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(pod), &got))
+		require.Empty(t, got.Spec.SchedulingGates)
+		require.NotEmpty(t, got.Annotations[AnnotationKeyTokenExpirationDate])
+		require.Equal(t, corev1.ConditionFalse, condition(&got).Status)
+		require.Equal(t, tokenPendingReasonIssued, condition(updated).Reason)
+	})
+
+	t.Run("token request failed", func(t *testing.T) {
+		ctx := context.Background()
+		pod := newGatedPod("test/error")
+		c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(&corev1.Pod{}).Build()
+
+		_, res, err := syncRegistrationTokenGate(ctx, c, scheme, record.NewFakeRecorder(10), logr.Discard(), ghc, "", "", "test/error", pod)
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		require.Equal(t, registrationTokenRetryDelay, res.RequeueAfter)
+
+		var got corev1.Pod
+		require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(pod), &got))
+		require.Len(t, got.Spec.SchedulingGates, 1)
+		require.Equal(t, corev1.ConditionTrue, condition(&got).Status)
+		require.Equal(t, tokenPendingReasonFailed, condition(&got).Reason)
+	})
+}
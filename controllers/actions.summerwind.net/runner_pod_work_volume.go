@@ -0,0 +1,50 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/workvolume"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// applyWorkVolume adds the "work" volume from spec.workVolume to the runner pod, mounted at the work directory of the runner container.
+// The dockerd sidecar, if any, mounts it at the same path afterwards, as it does with the default "work" volume.
+func applyWorkVolume(pod *corev1.Pod, runnerContainer *corev1.Container, spec *v1alpha1.WorkVolumeSource, containerMode, workDir string) error {
+	if spec == nil {
+		return nil
+	}
+
+	if containerMode == "kubernetes" {
+		return fmt.Errorf("workVolume can't be used with containerMode: kubernetes, use workVolumeClaimTemplate instead")
+	}
+
+	if ok, _ := workVolumePresent(pod.Spec.Volumes); ok {
+		return fmt.Errorf("work volume should not be specified along with workVolume")
+	}
+
+	volume := corev1.Volume{Name: "work"}
+
+	switch {
+	case spec.EmptyDir != nil:
+		volume.EmptyDir = spec.EmptyDir.DeepCopy()
+		workvolume.RequestEphemeralStorage(&runnerContainer.Resources, spec.EmptyDir)
+	case spec.Ephemeral != nil:
+		volume.Ephemeral = spec.Ephemeral.DeepCopy()
+	case spec.HostPath != nil:
+		volume.HostPath = spec.HostPath.DeepCopy()
+	default:
+		return fmt.Errorf("workVolume must have one of emptyDir, ephemeral, and hostPath")
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+
+	if ok, _ := workVolumeMountPresent(runnerContainer.VolumeMounts); !ok {
+		runnerContainer.VolumeMounts = append(runnerContainer.VolumeMounts, corev1.VolumeMount{
+			Name:      "work",
+			MountPath: workDir,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestNewRunnerPod_WorkVolume(t *testing.T) {
+	sizeLimit := resource.MustParse("20Gi")
+
+	findContainer := func(pod corev1.Pod, name string) corev1.Container {
+		for _, c := range pod.Spec.Containers {
+			if c.Name == name {
+				return c
+			}
+		}
+		t.Fatalf("container %q not found", name)
+		return corev1.Container{}
+	}
+
+	findVolume := func(pod corev1.Pod, name string) []corev1.Volume {
+		var vs []corev1.Volume
+		for _, v := range pod.Spec.Volumes {
+			if v.Name == name {
+				vs = append(vs, v)
+			}
+		}
+		return vs
+	}
+
+	findMount := func(c corev1.Container, name string) []corev1.VolumeMount {
+		var ms []corev1.VolumeMount
+		for _, m := range c.VolumeMounts {
+			if m.Name == name {
+				ms = append(ms, m)
+			}
+		}
+		return ms
+	}
+
+	t.Run("size-limited emptyDir with dockerd sidecar", func(t *testing.T) {
+		pod, err := newRunnerPod(corev1.Pod{}, v1alpha1.RunnerConfig{
+			WorkVolume: &v1alpha1.WorkVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &sizeLimit}},
+		}, "api.github.com", RunnerPodDefaults{})
+		require.NoError(t, err)
+
+		work := findVolume(pod, "work")
+		require.Len(t, work, 1)
+		require.Equal(t, &sizeLimit, work[0].EmptyDir.SizeLimit)
+
+		runner := findContainer(pod, containerName)
+		require.Equal(t, []corev1.VolumeMount{{Name: "work", MountPath: "/runner/_work"}}, findMount(runner, "work"))
+		require.Equal(t, sizeLimit, runner.Resources.Requests[corev1.ResourceEphemeralStorage])
+
+		docker := findContainer(pod, "docker")
+		require.Equal(t, []corev1.VolumeMount{{Name: "work", MountPath: "/runner/_work"}}, findMount(docker, "work"))
+	})
+
+	t.Run("hostPath at a relocated work directory without docker", func(t *testing.T) {
+		dockerEnabled := false
+
+		pod, err := newRunnerPod(corev1.Pod{}, v1alpha1.RunnerConfig{
+			DockerEnabled: &dockerEnabled,
+			WorkDir:       "/work",
+			WorkVolume:    &v1alpha1.WorkVolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/mnt/runner-work"}},
+		}, "api.github.com", RunnerPodDefaults{})
+		require.NoError(t, err)
+
+		work := findVolume(pod, "work")
+		require.Len(t, work, 1)
+		require.Equal(t, "/mnt/runner-work", work[0].HostPath.Path)
+
+		runner := findContainer(pod, containerName)
+		require.Equal(t, []corev1.VolumeMount{{Name: "work", MountPath: "/work"}}, findMount(runner, "work"))
+		require.NotContains(t, runner.Resources.Requests, corev1.ResourceEphemeralStorage)
+	})
+
+	t.Run("explicit ephemeral-storage request is kept", func(t *testing.T) {
+		pod, err := newRunnerPod(corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: containerName,
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("30Gi")},
+						},
+					},
+				},
+			},
+		}, v1alpha1.RunnerConfig{
+			WorkVolume: &v1alpha1.WorkVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &sizeLimit}},
+		}, "api.github.com", RunnerPodDefaults{})
+		require.NoError(t, err)
+
+		runner := findContainer(pod, containerName)
+		require.Equal(t, resource.MustParse("30Gi"), runner.Resources.Requests[corev1.ResourceEphemeralStorage])
+	})
+
+	t.Run("request is clamped to the ephemeral-storage limit", func(t *testing.T) {
+		pod, err := newRunnerPod(corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: containerName,
+						Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("10Gi")},
+						},
+					},
+				},
+			},
+		}, v1alpha1.RunnerConfig{
+			WorkVolume: &v1alpha1.WorkVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &sizeLimit}},
+		}, "api.github.com", RunnerPodDefaults{})
+		require.NoError(t, err)
+
+		runner := findContainer(pod, containerName)
+		require.Equal(t, resource.MustParse("10Gi"), runner.Resources.Requests[corev1.ResourceEphemeralStorage])
+	})
+
+	t.Run("conflicts with a work volume in the template", func(t *testing.T) {
+		_, err := newRunnerPod(corev1.Pod{
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{Name: "work"}},
+			},
+		}, v1alpha1.RunnerConfig{
+			WorkVolume: &v1alpha1.WorkVolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}, "api.github.com", RunnerPodDefaults{})
+		require.Error(t, err)
+	})
+}
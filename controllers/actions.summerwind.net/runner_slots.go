@@ -0,0 +1,250 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runnerConfigSlots returns the number of runners hosted by each runner pod of the config.
+// slotsPerPod is ignored unless the runners are non-ephemeral and share a dockerd sidecar, as validated by the webhooks.
+func runnerConfigSlots(cfg v1alpha1.RunnerConfig) int {
+	if cfg.SlotsPerPod == nil || *cfg.SlotsPerPod <= 1 {
+		return 1
+	}
+
+	if cfg.Ephemeral == nil || *cfg.Ephemeral {
+		return 1
+	}
+
+	if cfg.ContainerMode == "kubernetes" || (cfg.DockerdWithinRunnerContainer != nil && *cfg.DockerdWithinRunnerContainer) {
+		return 1
+	}
+
+	return *cfg.SlotsPerPod
+}
+
+// podsForRunners returns the number of runner pods needed to host the runners.
+func podsForRunners(runners, slots int) int {
+	if slots <= 1 {
+		return runners
+	}
+
+	return (runners + slots - 1) / slots
+}
+
+// runnerSlotName returns the name of the runner registered by the slot of the runner pod.
+// The first slot is the runner container itself, which registers the runner named after the pod.
+func runnerSlotName(runner string, slot int) string {
+	if slot == 0 {
+		return runner
+	}
+
+	return fmt.Sprintf("%s-slot-%d", runner, slot)
+}
+
+func runnerSlotContainerName(slot int) string {
+	return fmt.Sprintf("%s-slot-%d", containerName, slot)
+}
+
+// podRunnerSlots returns the number of runners hosted by the runner pod.
+func podRunnerSlots(pod *corev1.Pod) int {
+	if pod == nil {
+		return 1
+	}
+
+	v, ok := getAnnotation(pod, AnnotationKeySlotsPerPod)
+	if !ok {
+		return 1
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 1
+	}
+
+	return n
+}
+
+// addRunnerSlotContainers adds a copy of the runner container per additional slot of the runner pod.
+// It must be called once the runner name and the registration token are set to the runner container,
+// so that every slot registers itself with the same token under its own name.
+//
+// Each slot has its own /runner volume, as the runner keeps its configuration there,
+// while it works in a subdirectory of the work volume shared with the dockerd sidecar, so that docker steps can bind-mount it.
+func addRunnerSlotContainers(pod *corev1.Pod) {
+	slots := podRunnerSlots(pod)
+	if slots <= 1 {
+		return
+	}
+
+	var runnerContainer *corev1.Container
+
+	for i := range pod.Spec.Containers {
+		switch pod.Spec.Containers[i].Name {
+		case containerName:
+			runnerContainer = &pod.Spec.Containers[i]
+		case runnerSlotContainerName(1):
+			// Already added
+			return
+		}
+	}
+
+	if runnerContainer == nil {
+		return
+	}
+
+	runnerName := getRunnerEnv(pod, EnvVarRunnerName)
+	if runnerName == "" {
+		runnerName = pod.Name
+	}
+
+	var runnerVolume *corev1.Volume
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == "runner" {
+			runnerVolume = &pod.Spec.Volumes[i]
+		}
+	}
+
+	var (
+		containers []corev1.Container
+		volumes    []corev1.Volume
+	)
+
+	for slot := 1; slot < slots; slot++ {
+		c := runnerContainer.DeepCopy()
+		c.Name = runnerSlotContainerName(slot)
+		// Only a single container can expose a port
+		c.Ports = nil
+
+		for i := range c.Env {
+			switch c.Env[i].Name {
+			case EnvVarRunnerName:
+				c.Env[i] = corev1.EnvVar{Name: EnvVarRunnerName, Value: runnerSlotName(runnerName, slot)}
+			case "RUNNER_WORKDIR":
+				c.Env[i].Value = fmt.Sprintf("%s/slot-%d", strings.TrimSuffix(c.Env[i].Value, "/"), slot)
+			case "RUNNER_STATUS_UPDATE_HOOK":
+				// The status of the Runner resource is reported by the first slot
+				c.Env[i].Value = "false"
+			}
+		}
+
+		if runnerVolume != nil {
+			v := runnerVolume.DeepCopy()
+			v.Name = fmt.Sprintf("%s-slot-%d", runnerVolume.Name, slot)
+			volumes = append(volumes, *v)
+
+			for i := range c.VolumeMounts {
+				if c.VolumeMounts[i].Name == runnerVolume.Name {
+					c.VolumeMounts[i].Name = v.Name
+				}
+			}
+		}
+
+		containers = append(containers, *c)
+	}
+
+	pod.Spec.Containers = append(pod.Spec.Containers, containers...)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, volumes...)
+}
+
+// busyRunnerSlots returns the names of the runners of the pod's slots that are running jobs.
+func busyRunnerSlots(runners []*gogithub.Runner, runner string, slots int) []string {
+	byName := make(map[string]*gogithub.Runner, len(runners))
+	for _, r := range runners {
+		byName[r.GetName()] = r
+	}
+
+	var busy []string
+
+	for slot := 0; slot < slots; slot++ {
+		name := runnerSlotName(runner, slot)
+		if byName[name].GetBusy() {
+			busy = append(busy, name)
+		}
+	}
+
+	return busy
+}
+
+// ensureRunnerSlotsUnregistration unregisters the runners of the additional slots of the runner pod,
+// once none of its slots is running a job, so that an idle slot doesn't take a new job while the pod waits for a busy one.
+// The runner of the first slot is left to ensureRunnerUnregistration.
+//
+// If the first return value is nil, the additional slots are unregistered.
+func ensureRunnerSlotsUnregistration(ctx context.Context, retryDelay time.Duration, log logr.Logger, ghClient *github.Client, c client.Client, enterprise, organization, repository, runner string, pod *corev1.Pod) (*ctrl.Result, error) {
+	slots := podRunnerSlots(pod)
+	if slots <= 1 || pod.Annotations[AnnotationKeyUnregistrationCompleteTimestamp] != "" {
+		return nil, nil
+	}
+
+	runners, err := ghClient.ListRunners(ctx, enterprise, organization, repository)
+	if err != nil {
+		if retryAfter, ok := github.RetryAfterRateLimit(err); ok {
+			return &ctrl.Result{RequeueAfter: max(retryAfter, retryDelayOnGitHubAPIRateLimitError)}, nil
+		}
+
+		return &ctrl.Result{}, err
+	}
+
+	if !runnerPodOrContainerIsStopped(pod) {
+		if busy := busyRunnerSlots(runners, runner, slots); len(busy) > 0 {
+			msg := fmt.Sprintf("Runner slots %s are still running jobs", strings.Join(busy, ", "))
+			if _, err := annotatePodOnce(ctx, c, log, pod, AnnotationKeyUnregistrationFailureMessage, msg); err != nil {
+				return &ctrl.Result{}, err
+			}
+
+			log.V(2).Info("Retrying runner unregistration because some of the runner slots are still busy", "busy", busy)
+
+			return &ctrl.Result{RequeueAfter: retryDelay}, nil
+		}
+	}
+
+	for slot := 1; slot < slots; slot++ {
+		name := runnerSlotName(runner, slot)
+
+		var id *int64
+		for _, r := range runners {
+			if r.GetName() == name {
+				id = r.ID
+			}
+		}
+
+		if id == nil {
+			continue
+		}
+
+		if _, err := unregisterRunner(ctx, ghClient, enterprise, organization, repository, *id); err != nil {
+			if retryAfter, ok := github.RetryAfterRateLimit(err); ok {
+				return &ctrl.Result{RequeueAfter: max(retryAfter, retryDelayOnGitHubAPIRateLimitError)}, nil
+			}
+
+			errRes := &gogithub.ErrorResponse{}
+			if errors.As(err, &errRes) && errRes.Response.StatusCode == http.StatusNotFound {
+				// Unregistered in a previous reconciliation loop, but the cached ListRunners response still contains it
+				continue
+			}
+
+			// The slot may have taken a job since ListRunners
+			log.V(1).Info("Failed to unregister runner slot before deleting the pod.", "slot", name, "error", err)
+
+			return &ctrl.Result{RequeueAfter: retryDelay}, nil
+		}
+
+		log.Info("Runner slot has just been unregistered.", "slot", name)
+	}
+
+	return nil, nil
+}
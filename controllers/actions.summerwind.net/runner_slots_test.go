@@ -0,0 +1,138 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAddRunnerSlotContainers(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "example-runnerset-0",
+			Annotations: map[string]string{AnnotationKeySlotsPerPod: "3"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  containerName,
+					Ports: []corev1.ContainerPort{{ContainerPort: 8080}},
+					Env: []corev1.EnvVar{
+						{Name: "RUNNER_WORKDIR", Value: "/runner/_work"},
+						{Name: "RUNNER_STATUS_UPDATE_HOOK", Value: "true"},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "runner", MountPath: "/runner"},
+						{Name: "work", MountPath: "/runner/_work"},
+					},
+				},
+				{Name: "docker"},
+			},
+			Volumes: []corev1.Volume{
+				{Name: "runner", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				{Name: "work", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			},
+		},
+	}
+
+	updated := mutatePod(pod, "token")
+
+	require.Len(t, updated.Spec.Containers, 4)
+	require.Equal(t, "example-runnerset-0", getRunnerEnv(updated, EnvVarRunnerName))
+
+	slot := updated.Spec.Containers[3]
+	require.Equal(t, "runner-slot-2", slot.Name)
+	require.Empty(t, slot.Ports)
+	require.ElementsMatch(t, []corev1.EnvVar{
+		{Name: "RUNNER_WORKDIR", Value: "/runner/_work/slot-2"},
+		{Name: "RUNNER_STATUS_UPDATE_HOOK", Value: "false"},
+		{Name: EnvVarRunnerName, Value: "example-runnerset-0-slot-2"},
+		{Name: EnvVarRunnerToken, Value: "token"},
+	}, slot.Env)
+	require.Equal(t, []corev1.VolumeMount{
+		{Name: "runner-slot-2", MountPath: "/runner"},
+		{Name: "work", MountPath: "/runner/_work"},
+	}, slot.VolumeMounts)
+	require.Equal(t, "runner-slot-2", updated.Spec.Volumes[3].Name)
+
+	// Adding the slots is idempotent
+	addRunnerSlotContainers(updated)
+	require.Len(t, updated.Spec.Containers, 4)
+	require.Len(t, updated.Spec.Volumes, 4)
+}
+
+func TestEnsureRunnerSlotsUnregistration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "example-runner",
+				Annotations: map[string]string{AnnotationKeySlotsPerPod: "2"},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	t.Run("busy slot", func(t *testing.T) {
+		server := fake.NewServer(fake.WithListRunnersResponse(200, `{"total_count": 2, "runners": [
+			{"id": 0, "name": "example-runner", "os": "linux", "status": "online", "busy": false},
+			{"id": 1, "name": "example-runner-slot-1", "os": "linux", "status": "online", "busy": true}
+		]}`))
+		defer server.Close()
+
+		pod := newPod()
+		c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+
+		res, err := ensureRunnerSlotsUnregistration(context.Background(), time.Minute, logr.Discard(), newGithubClient(server), c, "", "", "test/valid", pod.Name, pod)
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, res.RequeueAfter)
+
+		var updated corev1.Pod
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(pod), &updated))
+		require.Equal(t, "Runner slots example-runner-slot-1 are still running jobs", updated.Annotations[AnnotationKeyUnregistrationFailureMessage])
+	})
+
+	t.Run("idle slots", func(t *testing.T) {
+		server := fake.NewServer(fake.WithListRunnersResponse(200, `{"total_count": 2, "runners": [
+			{"id": 0, "name": "example-runner", "os": "linux", "status": "online", "busy": false},
+			{"id": 1, "name": "example-runner-slot-1", "os": "linux", "status": "online", "busy": false}
+		]}`))
+		defer server.Close()
+
+		pod := newPod()
+		c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+
+		res, err := ensureRunnerSlotsUnregistration(context.Background(), time.Minute, logr.Discard(), newGithubClient(server), c, "", "", "test/valid", pod.Name, pod)
+		require.NoError(t, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("single slot", func(t *testing.T) {
+		pod := newPod()
+		delete(pod.Annotations, AnnotationKeySlotsPerPod)
+
+		res, err := ensureRunnerSlotsUnregistration(context.Background(), time.Minute, logr.Discard(), nil, nil, "", "", "test/valid", pod.Name, pod)
+		require.NoError(t, err)
+		require.Nil(t, res)
+	})
+}
+
+func TestPodsForRunners(t *testing.T) {
+	require.Equal(t, 5, podsForRunners(5, 1))
+	require.Equal(t, 5, podsForRunners(5, 0))
+	require.Equal(t, 2, podsForRunners(5, 4))
+	require.Equal(t, 0, podsForRunners(0, 4))
+}
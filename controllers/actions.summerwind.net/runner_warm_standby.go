@@ -0,0 +1,260 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// EnvVarRunnerWarmStandbyFile is the environment variable injected into the runner container of a warm standby runner.
+	// The entrypoint waits before registering the runner as long as the file contains "true",
+	// and the file of EnvVarRunnerWarmStandbyPromotionFile doesn't exist.
+	EnvVarRunnerWarmStandbyFile = "RUNNER_WARM_STANDBY_FILE"
+
+	// EnvVarRunnerWarmStandbyPromotionFile is the environment variable injected into the runner container of a warm standby runner.
+	// The file is created by the controller on promotion, so that the runner registers itself right away.
+	EnvVarRunnerWarmStandbyPromotionFile = "RUNNER_WARM_STANDBY_PROMOTION_FILE"
+
+	warmStandbyVolumeName          = "warm-standby"
+	warmStandbyMountPath           = "/run/actions-runner/warm-standby"
+	warmStandbyFileName            = "standby"
+	warmStandbyPromotionVolumeName = "warm-standby-promotion"
+	warmStandbyPromotionMountPath  = "/run/actions-runner/warm-standby-promotion"
+	warmStandbyPromotionFileName   = "promoted"
+
+	// warmStandbyTokenExpiryMargin is how long before the expiration of the registration token a standby runner is replaced.
+	// A standby runner cannot register itself with an expired registration token, which is injected on pod creation.
+	// This must be shorter than the 30 minutes the GitHub client guarantees a cached registration token to be valid for,
+	// so that a newly created standby runner is not replaced immediately.
+	warmStandbyTokenExpiryMargin = 15 * time.Minute
+)
+
+// isWarmStandby returns true when the runner or its pod is paused before registration, waiting to be promoted.
+func isWarmStandby(obj client.Object) bool {
+	return obj.GetAnnotations()[AnnotationKeyWarmStandby] == "true"
+}
+
+// addWarmStandbyGate exposes the warm standby annotation of the pod to the runner container via a downward API volume,
+// so that the entrypoint can wait for the promotion before registering the runner.
+//
+// The kubelet updates the file in place when the annotation changes, without restarting the container,
+// but only on its next sync of the pod, which can take up to a minute.
+// So a running pod is also signaled by creating the promotion file in an emptyDir volume, see WarmStandbyPromoter.
+// The downward API volume still tells the pods created after the promotion of their runners not to wait at all.
+func addWarmStandbyGate(pod *corev1.Pod) {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: warmStandbyVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{
+				Items: []corev1.DownwardAPIVolumeFile{
+					{
+						Path: warmStandbyFileName,
+						FieldRef: &corev1.ObjectFieldSelector{
+							FieldPath: fmt.Sprintf("metadata.annotations['%s']", AnnotationKeyWarmStandby),
+						},
+					},
+				},
+			},
+		},
+	}, corev1.Volume{
+		Name: warmStandbyPromotionVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+		},
+	})
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+
+		if c.Name != containerName {
+			continue
+		}
+
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      warmStandbyVolumeName,
+			MountPath: warmStandbyMountPath,
+			ReadOnly:  true,
+		}, corev1.VolumeMount{
+			Name:      warmStandbyPromotionVolumeName,
+			MountPath: warmStandbyPromotionMountPath,
+		})
+
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  EnvVarRunnerWarmStandbyFile,
+			Value: warmStandbyMountPath + "/" + warmStandbyFileName,
+		}, corev1.EnvVar{
+			Name:  EnvVarRunnerWarmStandbyPromotionFile,
+			Value: warmStandbyPromotionMountPath + "/" + warmStandbyPromotionFileName,
+		})
+	}
+}
+
+// WarmStandbyPromoter signals the runner container of a running warm standby pod that it's promoted.
+type WarmStandbyPromoter interface {
+	Promote(ctx context.Context, namespace, pod string) error
+}
+
+// ExecWarmStandbyPromoter creates the promotion file in the runner container via the exec subresource of the pod.
+type ExecWarmStandbyPromoter struct {
+	// Config is the config of the client the exec stream is opened with.
+	Config *rest.Config
+	// RESTClient is the client of the core API group.
+	RESTClient rest.Interface
+}
+
+// +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create
+
+func (p *ExecWarmStandbyPromoter) Promote(ctx context.Context, namespace, pod string) error {
+	req := p.RESTClient.Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   []string{"touch", warmStandbyPromotionMountPath + "/" + warmStandbyPromotionFileName},
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(p.Config, http.MethodPost, req.URL())
+	if err != nil {
+		return fmt.Errorf("creating exec stream to pod %s/%s: %w", namespace, pod, err)
+	}
+
+	var stderr bytes.Buffer
+	if err := exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stderr: &stderr}); err != nil {
+		return fmt.Errorf("creating promotion file in pod %s/%s: %w: %s", namespace, pod, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// podRegistrationTimedOut returns true when the runner failed to register itself within the registration timeout
+// since the pod became ready, or since the pod was promoted from warm standby.
+func podRegistrationTimedOut(pod *corev1.Pod, now time.Time) bool {
+	if pod != nil {
+		if v, ok := getAnnotation(pod, AnnotationKeyWarmStandbyPromotionTimestamp); ok {
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t.Add(registrationTimeout).Before(now)
+			}
+		}
+	}
+
+	return podConditionTransitionTimeAfter(pod, corev1.PodReady, registrationTimeout)
+}
+
+// syncWarmStandbyRunners promotes standby runners, oldest first, until there are as many non-standby runners as replicas.
+// It also deletes the remaining standby runners whose registration token is about to expire,
+// so that they are replaced by fresh ones when the runners are synced with replicas plus warm standby.
+//
+// This must run before syncing runners, so that the promotion happens immediately on scale up,
+// without waiting for newly created runners to get synced.
+func syncWarmStandbyRunners(ctx context.Context, c client.Client, promoter WarmStandbyPromoter, log logr.Logger, now time.Time, replicas int, runners []v1alpha1.Runner) error {
+	var (
+		active  int
+		standby []*v1alpha1.Runner
+	)
+
+	for i := range runners {
+		r := &runners[i]
+
+		if !r.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if _, ok := getAnnotation(r, AnnotationKeyUnregistrationRequestTimestamp); ok {
+			continue
+		}
+
+		if isWarmStandby(r) {
+			standby = append(standby, r)
+		} else {
+			active++
+		}
+	}
+
+	sort.SliceStable(standby, func(i, j int) bool {
+		return standby[i].CreationTimestamp.Before(&standby[j].CreationTimestamp)
+	})
+
+	for _, r := range standby {
+		log := log.WithValues("runner", r.Name)
+
+		if active < replicas {
+			if err := promoteWarmStandbyRunner(ctx, c, promoter, log, r, now); err != nil {
+				return err
+			}
+
+			active++
+
+			continue
+		}
+
+		expiresAt := r.Status.Registration.ExpiresAt
+		if !expiresAt.IsZero() && expiresAt.Time.Before(now.Add(warmStandbyTokenExpiryMargin)) {
+			if err := c.Delete(ctx, r); err != nil && !kerrors.IsNotFound(err) {
+				return fmt.Errorf("deleting standby runner %s whose registration token is about to expire: %w", r.Name, err)
+			}
+
+			log.V(1).Info("Deleted standby runner whose registration token is about to expire", "expiresAt", expiresAt)
+		}
+	}
+
+	return nil
+}
+
+func promoteWarmStandbyRunner(ctx context.Context, c client.Client, promoter WarmStandbyPromoter, log logr.Logger, runner *v1alpha1.Runner, now time.Time) error {
+	promotedAt := now.Format(time.RFC3339)
+
+	// Promote the pod first, as that's what lets the runner start registering itself.
+	var pod corev1.Pod
+	if err := c.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: runner.Name}, &pod); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return fmt.Errorf("getting pod of standby runner %s: %w", runner.Name, err)
+		}
+	} else {
+		updated := pod.DeepCopy()
+		setAnnotation(&updated.ObjectMeta, AnnotationKeyWarmStandby, "false")
+		setAnnotation(&updated.ObjectMeta, AnnotationKeyWarmStandbyPromotionTimestamp, promotedAt)
+
+		if err := c.Patch(ctx, updated, client.MergeFrom(&pod)); err != nil {
+			return fmt.Errorf("promoting pod of standby runner %s: %w", runner.Name, err)
+		}
+
+		// A pod that isn't running yet sees the promotion in its downward API volume once its containers start
+		if promoter != nil && pod.Status.Phase == corev1.PodRunning {
+			if err := promoter.Promote(ctx, pod.Namespace, pod.Name); err != nil {
+				log.Error(err, "Failed to signal the promotion to the runner container. It registers itself once the kubelet updates its downward API volume instead")
+			}
+		}
+	}
+
+	// The runner is promoted as well, so that the pod is created without the gate being closed in case it's not created yet,
+	// and so that the runner is never promoted twice.
+	updated := runner.DeepCopy()
+	setAnnotation(&updated.ObjectMeta, AnnotationKeyWarmStandby, "false")
+	setAnnotation(&updated.ObjectMeta, AnnotationKeyWarmStandbyPromotionTimestamp, promotedAt)
+
+	if err := c.Patch(ctx, updated, client.MergeFrom(runner)); err != nil {
+		return fmt.Errorf("promoting standby runner %s: %w", runner.Name, err)
+	}
+
+	log.Info("Promoted standby runner")
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeWarmStandbyPromoter struct {
+	promoted []string
+}
+
+func (p *fakeWarmStandbyPromoter) Promote(_ context.Context, namespace, pod string) error {
+	p.promoted = append(p.promoted, namespace+"/"+pod)
+	return nil
+}
+
+func TestSyncWarmStandbyRunners(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	now := time.Now()
+
+	runner := func(name string, created time.Time, standby bool, tokenExpiresAt time.Time) v1alpha1.Runner {
+		r := v1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Status: v1alpha1.RunnerStatus{
+				Registration: v1alpha1.RunnerStatusRegistration{
+					Token:     "token",
+					ExpiresAt: metav1.NewTime(tokenExpiresAt),
+				},
+			},
+		}
+		if standby {
+			r.Annotations = map[string]string{AnnotationKeyWarmStandby: "true"}
+		}
+		return r
+	}
+
+	runners := []v1alpha1.Runner{
+		runner("active", now.Add(-3*time.Minute), false, now.Add(time.Hour)),
+		runner("standby-old", now.Add(-2*time.Minute), true, now.Add(time.Hour)),
+		runner("standby-expiring", now.Add(-time.Minute), true, now.Add(5*time.Minute)),
+		runner("standby-new", now, true, now.Add(time.Hour)),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "standby-old",
+			Annotations: map[string]string{AnnotationKeyWarmStandby: "true"},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+
+	builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod)
+	for i := range runners {
+		builder = builder.WithObjects(runners[i].DeepCopy())
+	}
+	c := builder.Build()
+
+	ctx := context.Background()
+	promoter := &fakeWarmStandbyPromoter{}
+
+	require.NoError(t, syncWarmStandbyRunners(ctx, c, promoter, logr.Discard(), time.Now(), 2, runners))
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Namespace: "default", Name: name}
+	}
+
+	// The oldest standby runner and its pod are promoted
+	var promoted v1alpha1.Runner
+	require.NoError(t, c.Get(ctx, key("standby-old"), &promoted))
+	require.False(t, isWarmStandby(&promoted))
+	require.NotEmpty(t, promoted.Annotations[AnnotationKeyWarmStandbyPromotionTimestamp])
+
+	var promotedPod corev1.Pod
+	require.NoError(t, c.Get(ctx, key("standby-old"), &promotedPod))
+	require.Equal(t, "false", promotedPod.Annotations[AnnotationKeyWarmStandby])
+	require.False(t, podRegistrationTimedOut(&promotedPod, time.Now()))
+
+	// The running pod is signaled right away
+	require.Equal(t, []string{"default/standby-old"}, promoter.promoted)
+
+	// The standby runner whose token is about to expire is replaced
+	var expiring v1alpha1.Runner
+	err := c.Get(ctx, key("standby-expiring"), &expiring)
+	require.True(t, kerrors.IsNotFound(err), "%v", err)
+
+	// The other standby runner stays in standby
+	var remaining v1alpha1.Runner
+	require.NoError(t, c.Get(ctx, key("standby-new"), &remaining))
+	require.True(t, isWarmStandby(&remaining))
+}
+
+func TestAddWarmStandbyGate(t *testing.T) {
+	pod := corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "runner"}, {Name: "docker"}},
+		},
+	}
+
+	addWarmStandbyGate(&pod)
+
+	require.Len(t, pod.Spec.Volumes, 2)
+	require.Equal(t, "metadata.annotations['actions-runner/warm-standby']", pod.Spec.Volumes[0].DownwardAPI.Items[0].FieldRef.FieldPath)
+	require.NotNil(t, pod.Spec.Volumes[1].EmptyDir)
+
+	require.Equal(t, []corev1.EnvVar{
+		{Name: EnvVarRunnerWarmStandbyFile, Value: "/run/actions-runner/warm-standby/standby"},
+		{Name: EnvVarRunnerWarmStandbyPromotionFile, Value: "/run/actions-runner/warm-standby-promotion/promoted"},
+	}, pod.Spec.Containers[0].Env)
+	require.Len(t, pod.Spec.Containers[0].VolumeMounts, 2)
+	require.Empty(t, pod.Spec.Containers[1].Env)
+}
+
+func TestScaleDownOrder(t *testing.T) {
+	owner := func(name string, standby bool) *podsForOwner {
+		r := &v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if standby {
+			r.Annotations = map[string]string{AnnotationKeyWarmStandby: "true"}
+		}
+		return &podsForOwner{object: r}
+	}
+
+	// Sorted oldest first, as the current owners are
+	owners := []*podsForOwner{
+		owner("registered-old", false),
+		owner("standby-old", true),
+		owner("registered-new", false),
+		owner("standby-new", true),
+	}
+
+	var names []string
+	for _, o := range scaleDownOrder(owners) {
+		names = append(names, o.object.GetName())
+	}
+
+	require.Equal(t, []string{"standby-old", "standby-new", "registered-old", "registered-new"}, names)
+	require.Equal(t, "registered-old", owners[0].object.GetName(), "the owners must not be reordered in place")
+}
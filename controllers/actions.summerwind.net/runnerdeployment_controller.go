@@ -39,6 +39,7 @@ import (
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 )
 
 const (
@@ -191,9 +192,13 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	if rd.Spec.EffectiveTime != nil {
 		et2 = rd.Spec.EffectiveTime.Time
 	}
-	if currentDesiredReplicas != newDesiredReplicas || et1 != et2 {
+	currentWarmStandby := getIntOrDefault(newestSet.Spec.WarmStandby, 0)
+	newWarmStandby := getIntOrDefault(desiredRS.Spec.WarmStandby, 0)
+
+	if currentDesiredReplicas != newDesiredReplicas || et1 != et2 || currentWarmStandby != newWarmStandby {
 		newestSet.Spec.Replicas = &newDesiredReplicas
 		newestSet.Spec.EffectiveTime = rd.Spec.EffectiveTime
+		newestSet.Spec.WarmStandby = desiredRS.Spec.WarmStandby
 
 		if err := r.Client.Update(ctx, newestSet); err != nil {
 			log.Error(err, "Failed to update runnerreplicaset resource")
@@ -206,6 +211,8 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 			"newDesiredReplicas", newDesiredReplicas,
 			"currentEffectiveTime", newestSet.Spec.EffectiveTime,
 			"newEffectiveTime", rd.Spec.EffectiveTime,
+			"currentWarmStandby", currentWarmStandby,
+			"newWarmStandby", newWarmStandby,
 		)
 
 		return ctrl.Result{}, err
@@ -248,7 +255,7 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 			rslog := log.WithValues("runnerreplicaset", rs.Name)
 
 			if rs.Status.Replicas != nil && *rs.Status.Replicas > 0 {
-				if rs.Spec.Replicas != nil && *rs.Spec.Replicas == 0 {
+				if rs.Spec.Replicas != nil && *rs.Spec.Replicas == 0 && getIntOrDefault(rs.Spec.WarmStandby, 0) == 0 {
 					rslog.V(2).Info("Waiting for runnerreplicaset to scale to zero")
 
 					continue
@@ -257,6 +264,7 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 				updated := rs.DeepCopy()
 				zero := 0
 				updated.Spec.Replicas = &zero
+				updated.Spec.WarmStandby = nil
 				if err := r.Client.Update(ctx, updated); err != nil {
 					rslog.Error(err, "Failed to scale runnerreplicaset to zero")
 
@@ -313,6 +321,20 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	status.DesiredReplicas = &newDesiredReplicas
 	status.Replicas = &totalCurrentReplicas
 	status.UpdatedReplicas = &updatedReplicas
+	status.RunnerImage = newestSet.Spec.Template.Spec.Image
+
+	overlapping, err := findOverlappingRunnerPools(ctx, r.Client, "RunnerDeployment", rd.Namespace, rd.Name, rd.Spec.Template.Spec.RunnerConfig)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(overlapping) > 0 && !reflect.DeepEqual(rd.Status.OverlappingRunnerPools, overlapping) {
+		r.Recorder.Event(&rd, corev1.EventTypeWarning, EventReasonOverlappingRunnerLabels, overlappingRunnerPoolsMessage(overlapping))
+
+		log.Info("Detected runner pools with overlapping labels", "overlapping", overlapping)
+	}
+
+	status.OverlappingRunnerPools = overlapping
 
 	if !reflect.DeepEqual(rd.Status, status) {
 		updated := rd.DeepCopy()
@@ -442,6 +464,15 @@ func newRunnerReplicaSet(rd *v1alpha1.RunnerDeployment, commonRunnerLabels []str
 
 	newRSTemplate.Spec.Labels = append(newRSTemplate.Spec.Labels, commonRunnerLabels...)
 
+	if image := rd.Annotations[AnnotationKeyRunnerImage]; image != "" {
+		newRSTemplate.Spec.Image = image
+	}
+
+	if restartedAt := rd.Annotations[AnnotationKeyRestartedAt]; restartedAt != "" {
+		// Part of the template, so that changing it results in a new template hash
+		newRSTemplate.ObjectMeta.Annotations = CloneAndAddLabel(newRSTemplate.ObjectMeta.Annotations, AnnotationKeyRestartedAt, restartedAt)
+	}
+
 	templateHash := ComputeHash(&newRSTemplate)
 
 	// Add template hash label to selector.
@@ -466,6 +497,7 @@ func newRunnerReplicaSet(rd *v1alpha1.RunnerDeployment, commonRunnerLabels []str
 			Selector:      newRSSelector,
 			Template:      newRSTemplate,
 			EffectiveTime: rd.Spec.EffectiveTime,
+			WarmStandby:   rd.Spec.WarmStandby,
 		},
 	}
 
@@ -504,5 +536,5 @@ func (r *RunnerDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&v1alpha1.RunnerDeployment{}).
 		Owns(&v1alpha1.RunnerReplicaSet{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &v1alpha1.RunnerDeployment{}, r))
 }
@@ -122,6 +122,45 @@ func TestNewRunnerReplicaSet(t *testing.T) {
 			hash1, hash3,
 		)
 	}
+
+	rd4 := rd.DeepCopy()
+	rd4.Annotations = map[string]string{AnnotationKeyRunnerImage: "example.com/runner:v2"}
+
+	rs4, err := r.newRunnerReplicaSet(*rd4)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if rs4.Spec.Template.Spec.Image != "example.com/runner:v2" {
+		t.Errorf("runner image annotation must override the runner image, but got %q", rs4.Spec.Template.Spec.Image)
+	}
+
+	if hash1 == rs4.Labels[LabelKeyRunnerTemplateHash] {
+		t.Errorf("runner replica sets from runner deployments with varying runner image annotations must have different template hash")
+	}
+
+	rd5 := rd.DeepCopy()
+	rd5.Annotations = map[string]string{AnnotationKeyRestartedAt: "2024-03-15T12:00:00Z"}
+
+	rs5, err := r.newRunnerReplicaSet(*rd5)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if hash1 == rs5.Labels[LabelKeyRunnerTemplateHash] {
+		t.Errorf("restarting a runner deployment must change the template hash")
+	}
+
+	rd5.Annotations[AnnotationKeyRestartedAt] = "2024-03-16T12:00:00Z"
+
+	rs6, err := r.newRunnerReplicaSet(*rd5)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if rs5.Labels[LabelKeyRunnerTemplateHash] == rs6.Labels[LabelKeyRunnerTemplateHash] {
+		t.Errorf("restarting a runner deployment again must change the template hash")
+	}
 }
 
 // SetupDeploymentTest will set up a testing environment.
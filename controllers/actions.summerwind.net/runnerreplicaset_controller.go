@@ -18,6 +18,7 @@ package actionssummerwindnet
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"time"
 
@@ -26,12 +27,14 @@ import (
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 )
 
 // RunnerReplicaSetReconciler reconciles a Runner object
@@ -41,6 +44,19 @@ type RunnerReplicaSetReconciler struct {
 	Recorder record.EventRecorder
 	Scheme   *runtime.Scheme
 	Name     string
+
+	// GitHubClient and BulkUnregistrar are optional.
+	// When both are set, the runners of a scaled-down RunnerReplicaSet are unregistered from GitHub in bulk.
+	GitHubClient    *MultiGitHubClient
+	BulkUnregistrar *RunnerBulkUnregistrar
+
+	// WarmStandbyPromoter is optional. When set, the running pods of promoted warm standby runners are signaled with it,
+	// so that they register their runners without waiting for the kubelet to update their downward API volumes.
+	WarmStandbyPromoter WarmStandbyPromoter
+
+	// Clock is optional. When set, it is used instead of the wall clock to promote warm standby runners
+	// and to delay the recreation of runner pods after a webhook-based scale.
+	Clock clock.PassiveClock
 }
 
 const (
@@ -53,6 +69,7 @@ const (
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch
 
 func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("runnerreplicaset", req.NamespacedName)
@@ -81,6 +98,7 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		template := rs.Spec.DeepCopy()
 		template.Replicas = nil
 		template.EffectiveTime = nil
+		template.WarmStandby = nil
 		templateHash := ComputeHash(template)
 
 		log.Info("Using auto-generated template hash", "value", templateHash)
@@ -112,6 +130,19 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		replicas = *rs.Spec.Replicas
 	}
 
+	warmStandby := 0
+	if rs.Spec.WarmStandby != nil {
+		warmStandby = *rs.Spec.WarmStandby
+	}
+
+	if warmStandby > 0 && runnerConfigUsesJIT(rs.Spec.Template.Spec.RunnerConfig) {
+		// A just-in-time configuration registers the runner as soon as its pod is created, which defeats the standby.
+		// The webhook rejects the combination, but we still guard against runner replica sets created before it did.
+		log.Info("Ignoring warmStandby as it can't be used along with jitConfig", "warmStandby", warmStandby)
+
+		warmStandby = 0
+	}
+
 	effectiveTime := rs.Spec.EffectiveTime
 	ephemeral := rs.Spec.Template.Spec.Ephemeral == nil || *rs.Spec.Template.Spec.Ephemeral
 
@@ -122,17 +153,44 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, err
 	}
 
+	if warmStandby > 0 {
+		// Every new runner starts in warm standby, and is promoted as soon as it's needed to satisfy the replicas.
+		setAnnotation(&desired.ObjectMeta, AnnotationKeyWarmStandby, "true")
+	}
+
 	var live []client.Object
 	for _, r := range runnerList.Items {
 		r := r
 		live = append(live, &r)
 	}
 
-	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, replicas, func() client.Object { return desired.DeepCopy() }, ephemeral, live)
+	replicas, err = syncIdleScaleIn(ctx, r.Client, log, nowFrom(r.Clock), &rs, rs.Spec.Template.Spec.IdleTimeout, replicas, live)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Standby runners are promoted even after warm standby is disabled, so that they don't remain paused forever.
+	if err := syncWarmStandbyRunners(ctx, r.Client, r.WarmStandbyPromoter, log, nowFrom(r.Clock), replicas, runnerList.Items); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	res, err := syncRunnerPodsOwners(ctx, r.Client, log, nowFrom(r.Clock), effectiveTime, replicas+warmStandby, func() client.Object { return desired.DeepCopy() }, ephemeral, live)
 	if err != nil || res == nil {
 		return ctrl.Result{}, err
 	}
 
+	if r.GitHubClient != nil && r.BulkUnregistrar != nil {
+		retryAfter, err := r.BulkUnregistrar.Unregister(ctx, r.Client, log, r.GitHubClient, runnerList.Items)
+		if retryAfter > 0 {
+			log.Error(err, fmt.Sprintf("Failed to unregister runners in bulk due to GitHub API rate limits. Delaying retry for %s", retryAfter))
+
+			return ctrl.Result{RequeueAfter: retryAfter}, nil
+		} else if err != nil {
+			// The runner pod controller retries the unregistration of each runner on its own
+			log.Error(err, "Failed to unregister some runners in bulk")
+		}
+	}
+
 	var (
 		status v1alpha1.RunnerReplicaSetStatus
 
@@ -141,6 +199,12 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 	for _, o := range res.currentObjects {
 		current += o.total
+
+		// Standby runners aren't registered to GitHub, so they aren't available for jobs yet.
+		if isWarmStandby(o.object) {
+			continue
+		}
+
 		available += o.running
 		ready += o.running
 	}
@@ -201,5 +265,5 @@ func (r *RunnerReplicaSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&v1alpha1.RunnerReplicaSet{}).
 		Owns(&v1alpha1.Runner{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &v1alpha1.RunnerReplicaSet{}, r))
 }
@@ -18,6 +18,7 @@ package actionssummerwindnet
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -33,6 +35,7 @@ import (
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
 	"github.com/go-logr/logr"
 )
 
@@ -49,6 +52,9 @@ type RunnerSetReconciler struct {
 	GitHubClient       *MultiGitHubClient
 
 	RunnerPodDefaults RunnerPodDefaults
+
+	// Clock is optional. When set, it is used instead of the wall clock to delay the recreation of runner pods after a webhook-based scale.
+	Clock clock.PassiveClock
 }
 
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnersets,verbs=get;list;watch;create;update;patch;delete
@@ -136,7 +142,12 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return *res, nil
 	}
 
-	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, newDesiredReplicas, func() client.Object { return create.DeepCopy() }, ephemeral, owners)
+	newDesiredReplicas, err = syncIdleScaleIn(ctx, r.Client, log, nowFrom(r.Clock), runnerSet, runnerSet.Spec.IdleTimeout, newDesiredReplicas, owners)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	res, err := syncRunnerPodsOwners(ctx, r.Client, log, nowFrom(r.Clock), effectiveTime, newDesiredReplicas, func() client.Object { return create.DeepCopy() }, ephemeral, owners)
 	if err != nil || res == nil {
 		return ctrl.Result{}, err
 	}
@@ -158,6 +169,19 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	status.Replicas = &statusReplicas
 	status.UpdatedReplicas = &updatedReplicas
 
+	overlapping, err := findOverlappingRunnerPools(ctx, r.Client, "RunnerSet", runnerSet.Namespace, runnerSet.Name, runnerSet.Spec.RunnerConfig)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(overlapping) > 0 && !reflect.DeepEqual(runnerSet.Status.OverlappingRunnerPools, overlapping) {
+		r.Recorder.Event(runnerSet, corev1.EventTypeWarning, EventReasonOverlappingRunnerLabels, overlappingRunnerPoolsMessage(overlapping))
+
+		log.Info("Detected runner pools with overlapping labels", "overlapping", overlapping)
+	}
+
+	status.OverlappingRunnerPools = overlapping
+
 	if !reflect.DeepEqual(runnerSet.Status, status) {
 		updated := runnerSet.DeepCopy()
 		updated.Status = *status
@@ -226,6 +250,13 @@ func (r *RunnerSetReconciler) newStatefulSet(ctx context.Context, runnerSet *v1a
 		return nil, err
 	}
 
+	if len(runnerSet.Spec.AllowedRepositories) > 0 {
+		// The runner pods get their registration tokens from the pod webhook, so the runner group is restricted before they're created
+		if err := ghc.EnsureRunnerGroupRepositoryAccess(ctx, runnerSet.Spec.Organization, runnerSet.Spec.Group, runnerSet.Spec.AllowedRepositories); err != nil {
+			return nil, fmt.Errorf("restricting runner group %s to the allowed repositories: %w", runnerSet.Spec.Group, err)
+		}
+	}
+
 	githubBaseURL := ghc.GithubBaseURL
 
 	pod, err := newRunnerPodWithContainerMode(runnerSet.Spec.RunnerConfig.ContainerMode, template, runnerSet.Spec.RunnerConfig, githubBaseURL, r.RunnerPodDefaults)
@@ -244,6 +275,14 @@ func (r *RunnerSetReconciler) newStatefulSet(ctx context.Context, runnerSet *v1a
 
 	templateHash := ComputeHash(pod.Spec)
 
+	if restartedAt := runnerSet.Annotations[AnnotationKeyRestartedAt]; restartedAt != "" {
+		runnerSetWithOverrides.StatefulSetSpec.Template.ObjectMeta.Annotations = CloneAndAddLabel(runnerSetWithOverrides.StatefulSetSpec.Template.ObjectMeta.Annotations, AnnotationKeyRestartedAt, restartedAt)
+
+		// The restartedAt value is hashed on purpose: the new hash is what triggers the rolling recycle of the runners.
+		// Runner sets without the annotation keep hashing only the pod spec, so their existing hash doesn't change.
+		templateHash = ComputeHash([]interface{}{pod.Spec, restartedAt})
+	}
+
 	// Add template hash label to selector.
 	runnerSetWithOverrides.Template.ObjectMeta.Labels = CloneAndAddLabel(runnerSetWithOverrides.Template.ObjectMeta.Labels, LabelKeyRunnerTemplateHash, templateHash)
 
@@ -302,5 +341,5 @@ func (r *RunnerSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&v1alpha1.RunnerSet{}).
 		Owns(&appsv1.StatefulSet{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &v1alpha1.RunnerSet{}, r))
 }
@@ -10,8 +10,15 @@ import (
 type RecurrenceRule struct {
 	Frequency string
 	UntilTime time.Time
+
+	// Exclusions is optional. A recurrence that starts on an excluded date is skipped.
+	Exclusions *ExclusionCalendar
 }
 
+// maxSkippedRecurrences bounds the search for the upcoming recurrence that isn't excluded,
+// so that a calendar excluding every recurrence never results in an infinite loop.
+const maxSkippedRecurrences = 1000
+
 type Period struct {
 	StartTime time.Time
 	EndTime   time.Time
@@ -26,13 +33,41 @@ func (r *Period) String() string {
 }
 
 func MatchSchedule(now time.Time, startTime, endTime time.Time, recurrenceRule RecurrenceRule) (*Period, *Period, error) {
-	return calculateActiveAndUpcomingRecurringPeriods(
+	active, upcoming, err := calculateActiveAndUpcomingRecurringPeriods(
 		now,
 		startTime,
 		endTime,
 		recurrenceRule.Frequency,
 		recurrenceRule.UntilTime,
 	)
+	if err != nil || recurrenceRule.Exclusions == nil {
+		return active, upcoming, err
+	}
+
+	exclusions := recurrenceRule.Exclusions
+
+	if active != nil && exclusions.Excludes(active.StartTime) {
+		active = nil
+	}
+
+	for i := 0; upcoming != nil && exclusions.Excludes(upcoming.StartTime); i++ {
+		if i >= maxSkippedRecurrences {
+			return active, nil, nil
+		}
+
+		_, upcoming, err = calculateActiveAndUpcomingRecurringPeriods(
+			upcoming.StartTime,
+			startTime,
+			endTime,
+			recurrenceRule.Frequency,
+			recurrenceRule.UntilTime,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return active, upcoming, nil
 }
 
 func calculateActiveAndUpcomingRecurringPeriods(now, startTime, endTime time.Time, frequency string, untilTime time.Time) (*Period, *Period, error) {
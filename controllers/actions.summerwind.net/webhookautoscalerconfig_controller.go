@@ -0,0 +1,273 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+)
+
+const (
+	// webhookAutoscalerConfigResyncInterval is the interval the referenced secrets are reloaded at,
+	// as the secrets aren't watched.
+	webhookAutoscalerConfigResyncInterval = 5 * time.Minute
+
+	// webhookAutoscalerConfigRetryInterval is the interval a config that failed to load is retried at.
+	webhookAutoscalerConfigRetryInterval = 30 * time.Second
+)
+
+// WebhookAutoscalerConfigReconciler loads the WebhookAutoscalerConfigs into the github webhook server,
+// so that a config is served as soon as it's created or updated without redeploying the server.
+//
+// Every replica of the server runs the reconciler, as each of them serves the configs on its own.
+type WebhookAutoscalerConfigReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	Name   string
+
+	// SecretReader reads the secrets referenced by the configs. It's usually an uncached client,
+	// so that the server doesn't need to watch all the secrets. Defaults to Client.
+	SecretReader client.Reader
+
+	Webhook *HorizontalRunnerAutoscalerGitHubWebhook
+}
+
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=webhookautoscalerconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=webhookautoscalerconfigs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+
+func (r *WebhookAutoscalerConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("webhookautoscalerconfig", req.NamespacedName)
+
+	key := req.NamespacedName.String()
+
+	var config v1alpha1.WebhookAutoscalerConfig
+	if err := r.Get(ctx, req.NamespacedName, &config); err != nil {
+		if kerrors.IsNotFound(err) {
+			r.Webhook.deleteConfig(key)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !config.ObjectMeta.DeletionTimestamp.IsZero() {
+		r.Webhook.deleteConfig(key)
+
+		return ctrl.Result{}, nil
+	}
+
+	status := v1alpha1.WebhookAutoscalerConfigStatus{
+		ObservedGeneration: config.Generation,
+		Path:               webhookAutoscalerConfigPath(&config),
+	}
+
+	result := ctrl.Result{RequeueAfter: webhookAutoscalerConfigResyncInterval}
+
+	c, err := r.load(ctx, log, &config)
+	if err == nil {
+		err = r.Webhook.setConfig(c)
+	}
+
+	if err != nil {
+		// Stop serving the previous version of the config too, so that e.g. a removed secret token
+		// doesn't leave the path accepting deliveries validated with the old one.
+		r.Webhook.deleteConfig(key)
+
+		log.Error(err, "Unable to load WebhookAutoscalerConfig")
+
+		status.Message = err.Error()
+		result.RequeueAfter = webhookAutoscalerConfigRetryInterval
+	} else {
+		status.Ready = true
+	}
+
+	if config.Status != status {
+		updated := config.DeepCopy()
+		updated.Status = status
+
+		if err := r.Status().Patch(ctx, updated, client.MergeFrom(&config)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("patching WebhookAutoscalerConfig status: %w", err)
+		}
+
+		log.V(1).Info("Updated WebhookAutoscalerConfig status", "path", status.Path, "ready", status.Ready)
+	}
+
+	return result, nil
+}
+
+// load builds the settings of the config. The GitHub client and the deduplication state of the previously loaded version
+// of the config are reused as long as their settings don't change.
+func (r *WebhookAutoscalerConfigReconciler) load(ctx context.Context, log logr.Logger, config *v1alpha1.WebhookAutoscalerConfig) (*webhookConfig, error) {
+	key := types.NamespacedName{Namespace: config.Namespace, Name: config.Name}.String()
+	prev := r.Webhook.getConfig(key)
+
+	c := &webhookConfig{
+		key:                           key,
+		path:                          webhookAutoscalerConfigPath(config),
+		created:                       config.CreationTimestamp.Time,
+		githubClient:                  r.Webhook.GitHubClient,
+		namespace:                     config.Namespace,
+		defaultScaleUpTriggerDuration: r.Webhook.DefaultScaleUpTriggerDuration,
+	}
+
+	// A config can't take over the deliveries to the paths of other namespaces, or to the default path of the server
+	if prefix := "/" + config.Namespace + "/"; !strings.HasPrefix(c.path, prefix) || c.path == prefix || path.Clean(c.path) != c.path {
+		return nil, fmt.Errorf("path must be a clean path under %s: %q", prefix, c.path)
+	}
+
+	// Deliveries to the path would otherwise be accepted from anyone
+	if config.Spec.SecretTokenSecretRef == nil {
+		return nil, fmt.Errorf("secretTokenSecretRef is required")
+	}
+
+	var err error
+
+	c.secretKeyBytes, err = r.getSecretToken(ctx, config.Namespace, config.Spec.SecretTokenSecretRef, v1alpha1.DefaultWebhookSecretTokenKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.nextSecretKeyBytes, err = r.getSecretToken(ctx, config.Namespace, config.Spec.NextSecretTokenSecretRef, v1alpha1.DefaultWebhookNextSecretTokenKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if creds := config.Spec.GitHubAPICredentialsFrom; creds != nil {
+		secret, err := r.getSecret(ctx, config.Namespace, creds.SecretRef.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		c.githubCredsHash = secretDataHash(secret.Data)
+
+		if prev != nil && prev.githubCredsHash == c.githubCredsHash {
+			c.githubClient = prev.githubClient
+		} else {
+			conf, err := secretDataToGitHubClientConfig(secret.Data)
+			if err != nil {
+				return nil, fmt.Errorf("reading GitHub API credentials from secret %s: %w", creds.SecretRef.Name, err)
+			}
+
+			// Fallback to the server-wide setting if EnterpriseURL is not set and the original client is an enterprise client.
+			if conf.EnterpriseURL == "" && r.Webhook.GitHubClient != nil && r.Webhook.GitHubClient.IsEnterprise {
+				conf.EnterpriseURL = r.Webhook.GitHubClient.GithubBaseURL
+			}
+
+			// The API calls made with the credentials of the secret share the server-wide GitHub API proxy and TLS settings, if any.
+			if r.Webhook.GitHubClient != nil {
+				conf.APIProxyURL = r.Webhook.GitHubClient.APIProxyURL
+				conf.APIProxyCACert = r.Webhook.GitHubClient.APIProxyCACert
+				conf.TLS = r.Webhook.GitHubClient.TLS
+			}
+
+			conf.Log = &log
+			conf.CredentialsName = secret.Namespace + "/" + secret.Name
+
+			c.githubClient, err = conf.NewClient()
+			if err != nil {
+				return nil, fmt.Errorf("creating GitHub client from secret %s: %w", creds.SecretRef.Name, err)
+			}
+		}
+	}
+
+	if config.Spec.HorizontalRunnerAutoscalerSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(config.Spec.HorizontalRunnerAutoscalerSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid horizontalRunnerAutoscalerSelector: %w", err)
+		}
+
+		c.selector = selector
+	}
+
+	for i, route := range config.Spec.Routes {
+		r, err := newWebhookRoute(i, route, c.selector)
+		if err != nil {
+			return nil, err
+		}
+
+		c.routes = append(c.routes, r)
+	}
+
+	if d := config.Spec.DefaultScaleUpTriggerDuration; d != nil && d.Duration > 0 {
+		c.defaultScaleUpTriggerDuration = d.Duration
+	}
+
+	c.dedup = newWebhookDeduplicator(config.Spec.Deduplication)
+	if c.dedup != nil && prev != nil && prev.dedup != nil && prev.dedup.policy == c.dedup.policy && prev.dedup.window == c.dedup.window {
+		c.dedup = prev.dedup
+	}
+
+	return c, nil
+}
+
+// getSecretToken returns the webhook secret token referenced by ref, or nil when ref is nil.
+func (r *WebhookAutoscalerConfigReconciler) getSecretToken(ctx context.Context, ns string, ref *v1alpha1.SecretKeyReference, defaultKey string) ([]byte, error) {
+	if ref == nil {
+		return nil, nil
+	}
+
+	secret, err := r.getSecret(ctx, ns, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ref.Key
+	if key == "" {
+		key = defaultKey
+	}
+
+	token := secret.Data[key]
+	if len(token) == 0 {
+		return nil, fmt.Errorf("secret %s has no webhook secret token at key %s", ref.Name, key)
+	}
+
+	return token, nil
+}
+
+func (r *WebhookAutoscalerConfigReconciler) getSecret(ctx context.Context, ns, name string) (*corev1.Secret, error) {
+	reader := r.SecretReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	var secret corev1.Secret
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, &secret); err != nil {
+		return nil, fmt.Errorf("getting secret %s: %w", name, err)
+	}
+
+	return &secret, nil
+}
+
+// webhookAutoscalerConfigPath returns the HTTP path the config is served on.
+func webhookAutoscalerConfigPath(config *v1alpha1.WebhookAutoscalerConfig) string {
+	if config.Spec.Path != "" {
+		return config.Spec.Path
+	}
+
+	return "/" + config.Namespace + "/" + config.Name
+}
+
+func (r *WebhookAutoscalerConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	name := "webhookautoscalerconfig-controller"
+	if r.Name != "" {
+		name = r.Name
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.WebhookAutoscalerConfig{}).
+		Named(name).
+		Complete(reconcilemetrics.Wrap(name, mgr.GetCache(), &v1alpha1.WebhookAutoscalerConfig{}, r))
+}
@@ -0,0 +1,320 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWebhookAutoscalerConfigReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	newConfig := func(name string, created time.Time, spec actionsv1alpha1.WebhookAutoscalerConfigSpec) *actionsv1alpha1.WebhookAutoscalerConfig {
+		return &actionsv1alpha1.WebhookAutoscalerConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "team-a",
+				Generation:        1,
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: spec,
+		}
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "team-a"},
+		Data:       map[string][]byte{"github_webhook_secret_token": []byte("secret")},
+	}
+
+	setup := func(t *testing.T, objs ...client.Object) (*WebhookAutoscalerConfigReconciler, func(name string) actionsv1alpha1.WebhookAutoscalerConfigStatus) {
+		t.Helper()
+
+		c := fake.NewClientBuilder().
+			WithScheme(sc).
+			WithObjects(objs...).
+			WithStatusSubresource(&actionsv1alpha1.WebhookAutoscalerConfig{}).
+			Build()
+
+		r := &WebhookAutoscalerConfigReconciler{
+			Client:  c,
+			Log:     logr.Discard(),
+			Scheme:  sc,
+			Webhook: &HorizontalRunnerAutoscalerGitHubWebhook{Client: c, DefaultScaleUpTriggerDuration: 5 * time.Minute},
+		}
+
+		reconcile := func(name string) actionsv1alpha1.WebhookAutoscalerConfigStatus {
+			t.Helper()
+
+			key := types.NamespacedName{Namespace: "team-a", Name: name}
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			require.NoError(t, err)
+
+			var config actionsv1alpha1.WebhookAutoscalerConfig
+			require.NoError(t, c.Get(ctx, key, &config))
+			return config.Status
+		}
+
+		return r, reconcile
+	}
+
+	t.Run("loads the config", func(t *testing.T) {
+		config := newConfig("webhook", time.Now(), actionsv1alpha1.WebhookAutoscalerConfigSpec{
+			SecretTokenSecretRef:               &actionsv1alpha1.SecretKeyReference{Name: "webhook"},
+			HorizontalRunnerAutoscalerSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			DefaultScaleUpTriggerDuration:      &metav1.Duration{Duration: 30 * time.Minute},
+			Deduplication:                      &actionsv1alpha1.WebhookDeduplication{Policy: actionsv1alpha1.WebhookDeduplicationPolicyDeliveryID},
+		})
+
+		r, reconcile := setup(t, config, secret)
+
+		status := reconcile("webhook")
+		assert.Equal(t, actionsv1alpha1.WebhookAutoscalerConfigStatus{ObservedGeneration: 1, Path: "/team-a/webhook", Ready: true}, status)
+
+		c := r.Webhook.configForPath("/team-a/webhook")
+		require.NotNil(t, c)
+		assert.Equal(t, "team-a/webhook", c.key)
+		assert.Equal(t, []byte("secret"), c.secretKeyBytes)
+		assert.Equal(t, "team-a", c.namespace)
+		assert.Equal(t, "team=a", c.selector.String())
+		assert.Equal(t, 30*time.Minute, c.defaultScaleUpTriggerDuration)
+		require.NotNil(t, c.dedup)
+		assert.Equal(t, DefaultWebhookDeduplicationWindow, c.dedup.window)
+
+		// The deduplication state survives a resync
+		c.dedup.claim("delivery", time.Now())
+		reconcile("webhook")
+		assert.False(t, r.Webhook.configForPath("/team-a/webhook").dedup.claim("delivery", time.Now()))
+
+		// Other paths are served with the settings given via flags
+		assert.Equal(t, "", r.Webhook.configForPath("/").key)
+		r.Webhook.ConfigsOnly = true
+		assert.Nil(t, r.Webhook.configForPath("/"))
+
+		require.NoError(t, r.Delete(ctx, config))
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+		require.NoError(t, err)
+		assert.Nil(t, r.Webhook.configForPath("/team-a/webhook"))
+	})
+
+	t.Run("missing secret", func(t *testing.T) {
+		config := newConfig("webhook", time.Now(), actionsv1alpha1.WebhookAutoscalerConfigSpec{
+			Path:                 "/team-a/hook",
+			SecretTokenSecretRef: &actionsv1alpha1.SecretKeyReference{Name: "webhook", Key: "other"},
+		})
+
+		r, reconcile := setup(t, config, secret)
+
+		status := reconcile("webhook")
+		assert.False(t, status.Ready)
+		assert.Equal(t, "/team-a/hook", status.Path)
+		assert.Contains(t, status.Message, "no webhook secret token at key other")
+		assert.Equal(t, "", r.Webhook.configForPath("/team-a/hook").key)
+	})
+
+	t.Run("secret token required", func(t *testing.T) {
+		config := newConfig("webhook", time.Now(), actionsv1alpha1.WebhookAutoscalerConfigSpec{})
+
+		r, reconcile := setup(t, config)
+
+		status := reconcile("webhook")
+		assert.False(t, status.Ready)
+		assert.Equal(t, "secretTokenSecretRef is required", status.Message)
+		assert.Equal(t, "", r.Webhook.configForPath("/team-a/webhook").key)
+	})
+
+	t.Run("paths outside of the namespace", func(t *testing.T) {
+		for _, path := range []string{"/webhook", "/team-b/webhook", "/team-a/", "/team-a/../team-b/webhook", "team-a/webhook"} {
+			config := newConfig("webhook", time.Now(), actionsv1alpha1.WebhookAutoscalerConfigSpec{
+				Path:                 path,
+				SecretTokenSecretRef: &actionsv1alpha1.SecretKeyReference{Name: "webhook"},
+			})
+
+			r, reconcile := setup(t, config, secret)
+
+			status := reconcile("webhook")
+			assert.False(t, status.Ready, path)
+			assert.Contains(t, status.Message, "path must be a clean path under /team-a/", path)
+			assert.Equal(t, "", r.Webhook.configForPath(path).key, path)
+		}
+	})
+
+	t.Run("conflicting paths", func(t *testing.T) {
+		now := time.Now()
+		spec := actionsv1alpha1.WebhookAutoscalerConfigSpec{
+			Path:                 "/team-a/webhook",
+			SecretTokenSecretRef: &actionsv1alpha1.SecretKeyReference{Name: "webhook"},
+		}
+		older := newConfig("older", now.Add(-time.Hour), spec)
+		newer := newConfig("newer", now, spec)
+
+		r, reconcile := setup(t, older, newer, secret)
+
+		assert.True(t, reconcile("newer").Ready)
+		assert.True(t, reconcile("older").Ready)
+		assert.Equal(t, "team-a/older", r.Webhook.configForPath("/team-a/webhook").key)
+
+		status := reconcile("newer")
+		assert.False(t, status.Ready)
+		assert.Equal(t, "path /team-a/webhook is already served by WebhookAutoscalerConfig team-a/older", status.Message)
+	})
+}
+
+func TestWebhookWithWebhookAutoscalerConfig(t *testing.T) {
+	ctx := context.Background()
+
+	hra := func(name string, labels map[string]string) client.Object {
+		return &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a", Labels: labels},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{Name: name},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{}}},
+				},
+			},
+		}
+	}
+
+	rd := func(name string) client.Object {
+		return &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{Organization: "MYORG", Labels: []string{"label1"}},
+					},
+				},
+			},
+		}
+	}
+
+	config := &actionsv1alpha1.WebhookAutoscalerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "team-a"},
+		Spec: actionsv1alpha1.WebhookAutoscalerConfigSpec{
+			SecretTokenSecretRef:               &actionsv1alpha1.SecretKeyReference{Name: "webhook"},
+			HorizontalRunnerAutoscalerSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"webhook": "true"}},
+			Deduplication:                      &actionsv1alpha1.WebhookDeduplication{Policy: actionsv1alpha1.WebhookDeduplicationPolicyWorkflowJob},
+		},
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "team-a"},
+		Data:       map[string][]byte{"github_webhook_secret_token": []byte("secret")},
+	}
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{ConfigsOnly: true}
+	logs := installTestLogger(webhook)
+	defer func() {
+		if t.Failed() {
+			t.Logf("diagnostics: %s", logs.String())
+		}
+	}()
+
+	c := fake.NewClientBuilder().
+		WithScheme(sc).
+		WithObjects(
+			// The unselected HRA sorts first, so that it would be picked if the selector were ignored
+			hra("a-unselected", nil), rd("a-unselected"),
+			hra("b-selected", map[string]string{"webhook": "true"}), rd("b-selected"),
+			config, secret,
+		).
+		WithStatusSubresource(config).
+		WithIndex(&actionsv1alpha1.HorizontalRunnerAutoscaler{}, scaleTargetKey, webhook.indexer).
+		Build()
+	webhook.Client = c
+
+	r := &WebhookAutoscalerConfigReconciler{Client: c, Log: logr.Discard(), Scheme: sc, Webhook: webhook}
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", webhook.Handle)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	payload, err := os.ReadFile("testdata/org_webhook_workflow_job_payload.json")
+	require.NoError(t, err)
+
+	send := func(path, delivery, token string) (int, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPost, server.URL+path, bytes.NewReader(payload))
+		require.NoError(t, err)
+		req.Header.Set("X-GitHub-Event", "workflow_job")
+		req.Header.Set("X-GitHub-Delivery", delivery)
+		req.Header.Set("Content-Type", "application/json")
+
+		mac := hmac.New(sha256.New, []byte(token))
+		mac.Write(payload)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp.StatusCode, string(body)
+	}
+
+	code, _ := send("/", "1", "secret")
+	assert.Equal(t, http.StatusNotFound, code, "paths not served by any config are rejected")
+
+	code, _ = send("/team-a/webhook", "1", "wrong")
+	assert.Equal(t, http.StatusInternalServerError, code, "deliveries are validated with the secret token of the config")
+
+	code, body := send("/team-a/webhook", "1", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "scaled b-selected by 1", body)
+
+	// The same event sent by another webhook, e.g. the repository's and the organization's
+	code, body = send("/team-a/webhook", "2", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ignored duplicate delivery 2", body)
+}
+
+func TestWebhookDeduplicator(t *testing.T) {
+	now := time.Now()
+
+	assert.Nil(t, newWebhookDeduplicator(nil))
+	assert.Nil(t, newWebhookDeduplicator(&actionsv1alpha1.WebhookDeduplication{Policy: actionsv1alpha1.WebhookDeduplicationPolicyNone}))
+
+	d := newWebhookDeduplicator(&actionsv1alpha1.WebhookDeduplication{
+		Policy: actionsv1alpha1.WebhookDeduplicationPolicyWorkflowJob,
+		Window: &metav1.Duration{Duration: time.Minute},
+	})
+
+	payload := []byte(`{"action": "queued", "workflow_job": {"id": 10}}`)
+	assert.Equal(t, "10/queued", d.key("1", "workflow_job", payload))
+	assert.Equal(t, "", d.key("1", "ping", payload))
+
+	assert.True(t, d.claim("10/queued", now))
+	assert.False(t, d.claim("10/queued", now.Add(30*time.Second)))
+	assert.True(t, d.claim("10/queued", now.Add(time.Minute)), "the key expires after the window")
+
+	d.release("10/queued")
+	assert.True(t, d.claim("10/queued", now.Add(time.Minute)), "a released key can be claimed again")
+
+	byID := newWebhookDeduplicator(&actionsv1alpha1.WebhookDeduplication{Policy: actionsv1alpha1.WebhookDeduplicationPolicyDeliveryID})
+	assert.Equal(t, "1", byID.key("1", "workflow_job", payload))
+}
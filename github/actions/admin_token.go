@@ -0,0 +1,74 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ActionsServiceAdminToken is an admin token of the Actions service, along with the URL of the service
+// it is valid for. It is what the controller shares with listener pods so that they don't mint their own.
+type ActionsServiceAdminToken struct {
+	ActionsServiceURL string    `json:"actionsServiceUrl"`
+	Token             string    `json:"token"`
+	ExpiresAt         time.Time `json:"expiresAt"`
+}
+
+// AdminTokenSource returns an admin token acquired elsewhere, like by the controller.
+// The client mints its own token when the source fails, or returns a token about to expire.
+type AdminTokenSource func() (*ActionsServiceAdminToken, error)
+
+// WithAdminTokenSource makes the client use the admin tokens of source before minting its own.
+func WithAdminTokenSource(source AdminTokenSource) ClientOption {
+	return func(c *Client) {
+		c.adminTokenSource = source
+	}
+}
+
+// AdminTokenFromFile returns an AdminTokenSource reading the JSON-encoded ActionsServiceAdminToken at path,
+// like a Secret key mounted in the pod.
+func AdminTokenFromFile(path string) AdminTokenSource {
+	return func() (*ActionsServiceAdminToken, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read admin token file: %w", err)
+		}
+
+		var token ActionsServiceAdminToken
+		if err := json.Unmarshal(data, &token); err != nil {
+			return nil, fmt.Errorf("failed to decode admin token file: %w", err)
+		}
+
+		if token.Token == "" || token.ActionsServiceURL == "" {
+			return nil, fmt.Errorf("admin token file %s is incomplete", path)
+		}
+
+		return &token, nil
+	}
+}
+
+// AdminTokenProvider is implemented by the clients able to hand out their admin token.
+type AdminTokenProvider interface {
+	AdminToken(ctx context.Context, minValidity time.Duration) (*ActionsServiceAdminToken, error)
+}
+
+var _ AdminTokenProvider = &Client{}
+
+// AdminToken returns the admin token of the client, refreshed first when it expires within minValidity.
+// Refreshing ahead of the expiry lets the token be shared before the ones handed out previously expire.
+func (c *Client) AdminToken(ctx context.Context, minValidity time.Duration) (*ActionsServiceAdminToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refreshTokenIfExpiresWithin(ctx, minValidity); err != nil {
+		return nil, err
+	}
+
+	return &ActionsServiceAdminToken{
+		ActionsServiceURL: c.ActionsServiceURL,
+		Token:             c.ActionsServiceAdminToken,
+		ExpiresAt:         c.ActionsServiceAdminTokenExpiresAt,
+	}, nil
+}
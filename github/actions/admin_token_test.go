@@ -0,0 +1,97 @@
+package actions_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/actions/testserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_AdminToken(t *testing.T) {
+	ctx := context.Background()
+	defaultCreds := &actions.ActionsAuth{Token: "token"}
+	newToken := defaultActionsToken(t)
+	server := testserver.New(t, nil, testserver.WithActionsToken(newToken))
+
+	client, err := actions.NewClient(server.ConfigURLForOrg("my-org"), defaultCreds)
+	require.NoError(t, err)
+	client.ActionsServiceURL = "http://actions.example.com"
+	client.ActionsServiceAdminToken = "current-token"
+	client.ActionsServiceAdminTokenExpiresAt = time.Now().Add(5 * time.Minute)
+
+	token, err := client.AdminToken(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "current-token", token.Token, "a token valid long enough is not refreshed")
+
+	token, err = client.AdminToken(ctx, 6*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, newToken, token.Token, "a token expiring within minValidity is refreshed ahead of time")
+	assert.Equal(t, client.ActionsServiceAdminTokenExpiresAt, token.ExpiresAt)
+	assert.True(t, token.ExpiresAt.After(time.Now().Add(6*time.Minute)))
+}
+
+func TestClient_AdminTokenSource(t *testing.T) {
+	ctx := context.Background()
+	defaultCreds := &actions.ActionsAuth{Token: "token"}
+
+	writeToken := func(t *testing.T, token actions.ActionsServiceAdminToken) string {
+		path := filepath.Join(t.TempDir(), "token.json")
+		data, err := json.Marshal(token)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		return path
+	}
+
+	t.Run("uses the shared token", func(t *testing.T) {
+		server := testserver.New(t, nil, testserver.WithActionsToken(defaultActionsToken(t)))
+		path := writeToken(t, actions.ActionsServiceAdminToken{
+			ActionsServiceURL: server.URL,
+			Token:             "shared-token",
+			ExpiresAt:         time.Now().Add(time.Hour),
+		})
+
+		client, err := actions.NewClient(server.ConfigURLForOrg("my-org"), defaultCreds, actions.WithAdminTokenSource(actions.AdminTokenFromFile(path)))
+		require.NoError(t, err)
+
+		req, err := client.NewActionsServiceRequest(ctx, http.MethodGet, "my-path", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer shared-token", req.Header.Get("Authorization"))
+	})
+
+	t.Run("mints a token when the shared one is about to expire", func(t *testing.T) {
+		newToken := defaultActionsToken(t)
+		server := testserver.New(t, nil, testserver.WithActionsToken(newToken))
+		path := writeToken(t, actions.ActionsServiceAdminToken{
+			ActionsServiceURL: server.URL,
+			Token:             "expiring-token",
+			ExpiresAt:         time.Now().Add(30 * time.Second),
+		})
+
+		client, err := actions.NewClient(server.ConfigURLForOrg("my-org"), defaultCreds, actions.WithAdminTokenSource(actions.AdminTokenFromFile(path)))
+		require.NoError(t, err)
+
+		req, err := client.NewActionsServiceRequest(ctx, http.MethodGet, "my-path", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer "+newToken, req.Header.Get("Authorization"))
+	})
+
+	t.Run("mints a token when the shared one is missing", func(t *testing.T) {
+		newToken := defaultActionsToken(t)
+		server := testserver.New(t, nil, testserver.WithActionsToken(newToken))
+
+		client, err := actions.NewClient(server.ConfigURLForOrg("my-org"), defaultCreds, actions.WithAdminTokenSource(actions.AdminTokenFromFile(filepath.Join(t.TempDir(), "missing.json"))))
+		require.NoError(t, err)
+
+		req, err := client.NewActionsServiceRequest(ctx, http.MethodGet, "my-path", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer "+newToken, req.Header.Get("Authorization"))
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/actions/actions-runner-controller/build"
+	"github.com/actions/actions-runner-controller/pkg/githubapiproxy"
 	"github.com/go-logr/logr"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
@@ -38,6 +39,14 @@ type ActionsService interface {
 	GetRunnerScaleSet(ctx context.Context, runnerGroupId int, runnerScaleSetName string) (*RunnerScaleSet, error)
 	GetRunnerScaleSetById(ctx context.Context, runnerScaleSetId int) (*RunnerScaleSet, error)
 	GetRunnerGroupByName(ctx context.Context, runnerGroup string) (*RunnerGroup, error)
+	GetRunnerGroupSettings(ctx context.Context, runnerGroupId int64) (*RunnerGroupSettings, error)
+	ListRunnerGroupRepositories(ctx context.Context, runnerGroupId int64) ([]string, error)
+	ListRunnerGroupSettings(ctx context.Context) ([]RunnerGroupSettings, error)
+	CreateRunnerGroup(ctx context.Context, runnerGroup *RunnerGroupSettings) (*RunnerGroupSettings, error)
+	UpdateRunnerGroup(ctx context.Context, runnerGroupId int64, runnerGroup *RunnerGroupSettings) (*RunnerGroupSettings, error)
+	SetRunnerGroupRepositories(ctx context.Context, runnerGroupId int64, repositories []string) error
+	DeleteRunnerGroup(ctx context.Context, runnerGroupId int64) error
+	DispatchWorkflow(ctx context.Context, owner, repo, workflow, ref string, inputs map[string]string) error
 	CreateRunnerScaleSet(ctx context.Context, runnerScaleSet *RunnerScaleSet) (*RunnerScaleSet, error)
 	UpdateRunnerScaleSet(ctx context.Context, runnerScaleSetId int, runnerScaleSet *RunnerScaleSet) (*RunnerScaleSet, error)
 	DeleteRunnerScaleSet(ctx context.Context, runnerScaleSetId int) error
@@ -103,9 +112,17 @@ type Client struct {
 	userAgent UserAgentInfo
 
 	rootCAs               *x509.CertPool
+	clientCertificate     *tls.Certificate
 	tlsInsecureSkipVerify bool
 
 	proxyFunc ProxyFunc
+
+	coordinator RequestPacer
+
+	gitHubAPIProxyURL *url.URL
+	gitHubAPIProxyCAs *x509.CertPool
+
+	adminTokenSource AdminTokenSource
 }
 
 var _ ActionsService = &Client{}
@@ -166,6 +183,14 @@ func WithRootCAs(rootCAs *x509.CertPool) ClientOption {
 	}
 }
 
+// WithClientCertificate presents the certificate to GitHub and the Actions service,
+// like when GHES is fronted by a proxy that enforces mTLS.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(c *Client) {
+		c.clientCertificate = &cert
+	}
+}
+
 func WithoutTLSVerify() ClientOption {
 	return func(c *Client) {
 		c.tlsInsecureSkipVerify = true
@@ -178,6 +203,26 @@ func WithProxy(proxyFunc ProxyFunc) ClientOption {
 	}
 }
 
+// WithRequestCoordinator paces the requests of the client with the coordinator,
+// which shares the rate limit of its credentials between the scale sets using them.
+// Each attempt of a retried request is paced, as the retries count towards the rate limit too.
+func WithRequestCoordinator(coordinator RequestPacer) ClientOption {
+	return func(c *Client) {
+		c.coordinator = coordinator
+	}
+}
+
+// WithGitHubAPIProxy sends the GitHub API requests of the client through the GitHub API proxy at proxyURL,
+// which shares its caching and rate limit accounting with the other ARC components.
+// The proxy must be served over HTTPS with a certificate issued by one of caCerts, as it's sent the credentials.
+// The requests to the Actions service aren't affected.
+func WithGitHubAPIProxy(proxyURL *url.URL, caCerts *x509.CertPool) ClientOption {
+	return func(c *Client) {
+		c.gitHubAPIProxyURL = proxyURL
+		c.gitHubAPIProxyCAs = caCerts
+	}
+}
+
 func NewClient(githubConfigURL string, creds *ActionsAuth, options ...ClientOption) (*Client, error) {
 	config, err := ParseGitHubConfigFromURL(githubConfigURL)
 	if err != nil {
@@ -203,6 +248,19 @@ func NewClient(githubConfigURL string, creds *ActionsAuth, options ...ClientOpti
 		option(ac)
 	}
 
+	if ac.gitHubAPIProxyURL != nil {
+		// The proxy calls the GitHub API with TLS settings of its own
+		if ac.rootCAs != nil || ac.clientCertificate != nil || ac.tlsInsecureSkipVerify {
+			return nil, fmt.Errorf("the TLS settings of the GitHub server can't be used with the GitHub API proxy, which doesn't apply them to its requests to GitHub")
+		}
+		if err := githubapiproxy.ValidateURL(ac.gitHubAPIProxyURL); err != nil {
+			return nil, err
+		}
+		if ac.gitHubAPIProxyCAs == nil {
+			return nil, fmt.Errorf("the CA of the GitHub API proxy is required to verify its certificate")
+		}
+	}
+
 	retryClient := retryablehttp.NewClient()
 	retryClient.Logger = &clientLogger{Logger: ac.logger}
 
@@ -225,13 +283,35 @@ func NewClient(githubConfigURL string, creds *ActionsAuth, options ...ClientOpti
 		transport.TLSClientConfig.RootCAs = ac.rootCAs
 	}
 
+	if ac.clientCertificate != nil {
+		transport.TLSClientConfig.Certificates = []tls.Certificate{*ac.clientCertificate}
+	}
+
 	if ac.tlsInsecureSkipVerify {
 		transport.TLSClientConfig.InsecureSkipVerify = true
 	}
 
 	transport.Proxy = ac.proxyFunc
 
-	retryClient.HTTPClient.Transport = transport
+	var base http.RoundTripper = transport
+	if ac.gitHubAPIProxyURL != nil {
+		proxyTransport := transport.Clone()
+		proxyTransport.TLSClientConfig = &tls.Config{RootCAs: ac.gitHubAPIProxyCAs}
+		base = &gitHubAPIProxyTransport{
+			proxyHost: ac.gitHubAPIProxyURL.Host,
+			proxy:     proxyTransport,
+			base:      transport,
+		}
+	}
+
+	retryClient.HTTPClient.Transport = base
+	if ac.coordinator != nil {
+		retryClient.HTTPClient.Transport = &pacedTransport{
+			base:        base,
+			coordinator: ac.coordinator,
+			key:         ac.rateLimitIdentifier(),
+		}
+	}
 	ac.Client = retryClient.StandardClient()
 
 	return ac, nil
@@ -267,9 +347,59 @@ func (c *Client) Identifier() string {
 		identifier += fmt.Sprintf("rootCAs:%q", c.rootCAs.Subjects())
 	}
 
+	if c.clientCertificate != nil && len(c.clientCertificate.Certificate) > 0 {
+		identifier += fmt.Sprintf("clientCert:%x", sha256.Sum256(c.clientCertificate.Certificate[0]))
+	}
+
+	if c.gitHubAPIProxyURL != nil {
+		identifier += fmt.Sprintf("gitHubAPIProxy:%q", c.gitHubAPIProxyURL.String())
+	}
+
 	return uuid.NewHash(sha256.New(), uuid.NameSpaceOID, []byte(identifier), 6).String()
 }
 
+// rateLimitIdentifier returns a string identifying the rate limit the requests of the client count towards.
+// Unlike Identifier, it doesn't depend on the TLS configuration, as clients of the same GitHub App installation
+// share its rate limit regardless of it.
+func (c *Client) rateLimitIdentifier() string {
+	if c.creds.AppCreds != nil {
+		return fmt.Sprintf("%s/app:%d/installation:%d", c.config.ConfigURL.Host, c.creds.AppCreds.AppID, c.creds.AppCreds.AppInstallationID)
+	}
+
+	return uuid.NewHash(sha256.New(), uuid.NameSpaceOID, []byte(c.config.ConfigURL.Host+"/"+c.creds.Token), 6).String()
+}
+
+// gitHubAPIProxyTransport sends the requests to the GitHub API proxy with proxy, which trusts the CAs of the proxy only,
+// and the requests to the Actions service with base.
+type gitHubAPIProxyTransport struct {
+	proxyHost string
+	proxy     http.RoundTripper
+	base      http.RoundTripper
+}
+
+func (t *gitHubAPIProxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == t.proxyHost {
+		return t.proxy.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// pacedTransport waits for the coordinator before each request it sends.
+// It wraps the transport of the retrying client, so that every attempt is paced rather than only the first one.
+type pacedTransport struct {
+	base        http.RoundTripper
+	coordinator RequestPacer
+	key         string
+}
+
+func (t *pacedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.coordinator.Wait(req.Context(), t.key); err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(req)
+}
+
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	resp, err := c.Client.Do(req)
 	if err != nil {
@@ -292,6 +422,9 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 
 func (c *Client) NewGitHubAPIRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
 	u := c.config.GitHubAPIURL(path)
+	if c.gitHubAPIProxyURL != nil {
+		u = githubapiproxy.URL(c.gitHubAPIProxyURL, u)
+	}
 	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
 		return nil, err
@@ -466,6 +599,236 @@ func (c *Client) GetRunnerGroupByName(ctx context.Context, runnerGroup string) (
 	return &runnerGroupList.RunnerGroups[0], nil
 }
 
+// GetRunnerGroupSettings returns the runner group of the organization or the enterprise from the GitHub REST API.
+// Unlike GetRunnerGroupByName, it fails with a GitHubAPIError of status 403 or 404 when the credentials of the client
+// can't administer the runner groups.
+func (c *Client) GetRunnerGroupSettings(ctx context.Context, runnerGroupId int64) (*RunnerGroupSettings, error) {
+	path, err := runnerGroupPath(c.config, runnerGroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	var runnerGroup RunnerGroupSettings
+	if err := c.getGitHubAPI(ctx, path, nil, &runnerGroup); err != nil {
+		return nil, err
+	}
+
+	return &runnerGroup, nil
+}
+
+// ListRunnerGroupRepositories returns the names of the repositories that can use the runner group of the organization,
+// when the runner group is restricted to selected repositories.
+func (c *Client) ListRunnerGroupRepositories(ctx context.Context, runnerGroupId int64) ([]string, error) {
+	if c.config.Scope != GitHubScopeOrganization {
+		return nil, fmt.Errorf("runner groups are only restricted to repositories in organizations: %s", c.config.ConfigURL)
+	}
+
+	groupPath, err := runnerGroupPath(c.config, runnerGroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	var repositories []string
+	for page := 1; ; page++ {
+		query := url.Values{
+			"per_page": {"100"},
+			"page":     {strconv.Itoa(page)},
+		}
+
+		var list runnerGroupRepositoryList
+		if err := c.getGitHubAPI(ctx, groupPath+"/repositories", query, &list); err != nil {
+			return nil, err
+		}
+
+		for _, r := range list.Repositories {
+			repositories = append(repositories, r.Name)
+		}
+
+		if len(list.Repositories) == 0 || len(repositories) >= list.TotalCount {
+			return repositories, nil
+		}
+	}
+}
+
+// ListRunnerGroupSettings returns the runner groups of the organization or the enterprise from the GitHub REST API.
+func (c *Client) ListRunnerGroupSettings(ctx context.Context) ([]RunnerGroupSettings, error) {
+	groupsPath, err := runnerGroupsPath(c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var runnerGroups []RunnerGroupSettings
+	for page := 1; ; page++ {
+		query := url.Values{
+			"per_page": {"100"},
+			"page":     {strconv.Itoa(page)},
+		}
+
+		var list runnerGroupSettingsList
+		if err := c.getGitHubAPI(ctx, groupsPath, query, &list); err != nil {
+			return nil, err
+		}
+
+		runnerGroups = append(runnerGroups, list.RunnerGroups...)
+
+		if len(list.RunnerGroups) == 0 || len(runnerGroups) >= list.TotalCount {
+			return runnerGroups, nil
+		}
+	}
+}
+
+// CreateRunnerGroup creates a runner group of the organization or the enterprise with the GitHub REST API.
+// Only its name, visibility and allows_public_repositories are set.
+func (c *Client) CreateRunnerGroup(ctx context.Context, runnerGroup *RunnerGroupSettings) (*RunnerGroupSettings, error) {
+	groupsPath, err := runnerGroupsPath(c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var created RunnerGroupSettings
+	if err := c.doGitHubAPI(ctx, http.MethodPost, groupsPath, nil, newRunnerGroupRequest(runnerGroup), &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// UpdateRunnerGroup updates the name, visibility and allows_public_repositories of the runner group
+// of the organization or the enterprise with the GitHub REST API.
+func (c *Client) UpdateRunnerGroup(ctx context.Context, runnerGroupId int64, runnerGroup *RunnerGroupSettings) (*RunnerGroupSettings, error) {
+	groupPath, err := runnerGroupPath(c.config, runnerGroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated RunnerGroupSettings
+	if err := c.doGitHubAPI(ctx, http.MethodPatch, groupPath, nil, newRunnerGroupRequest(runnerGroup), &updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// SetRunnerGroupRepositories replaces the repositories of the organization that can use the runner group
+// when it's restricted to selected repositories. The repositories are given by name.
+func (c *Client) SetRunnerGroupRepositories(ctx context.Context, runnerGroupId int64, repositories []string) error {
+	if c.config.Scope != GitHubScopeOrganization {
+		return fmt.Errorf("runner groups are only restricted to repositories in organizations: %s", c.config.ConfigURL)
+	}
+
+	groupPath, err := runnerGroupPath(c.config, runnerGroupId)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(repositories))
+	for _, name := range repositories {
+		var repository struct {
+			ID int64 `json:"id"`
+		}
+		if err := c.getGitHubAPI(ctx, fmt.Sprintf("/repos/%s/%s", c.config.Organization, name), nil, &repository); err != nil {
+			return fmt.Errorf("failed to get repository %q: %w", name, err)
+		}
+		ids = append(ids, repository.ID)
+	}
+
+	body := map[string][]int64{"selected_repository_ids": ids}
+	return c.doGitHubAPI(ctx, http.MethodPut, groupPath+"/repositories", nil, body, nil)
+}
+
+// DeleteRunnerGroup deletes the runner group of the organization or the enterprise with the GitHub REST API.
+// Its runners are moved to the default runner group.
+func (c *Client) DeleteRunnerGroup(ctx context.Context, runnerGroupId int64) error {
+	groupPath, err := runnerGroupPath(c.config, runnerGroupId)
+	if err != nil {
+		return err
+	}
+
+	return c.doGitHubAPI(ctx, http.MethodDelete, groupPath, nil, nil, nil)
+}
+
+// DispatchWorkflow creates a workflow_dispatch event of the workflow, given by file name or ID, on the ref of the repository
+// with the GitHub REST API.
+func (c *Client) DispatchWorkflow(ctx context.Context, owner, repo, workflow, ref string, inputs map[string]string) error {
+	body := struct {
+		Ref    string            `json:"ref"`
+		Inputs map[string]string `json:"inputs,omitempty"`
+	}{
+		Ref:    ref,
+		Inputs: inputs,
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/actions/workflows/%s/dispatches", owner, repo, url.PathEscape(workflow))
+	return c.doGitHubAPI(ctx, http.MethodPost, path, nil, body, nil)
+}
+
+// getGitHubAPI calls the GitHub REST API with the credentials of the client, and decodes the response into v.
+func (c *Client) getGitHubAPI(ctx context.Context, path string, query url.Values, v any) error {
+	return c.doGitHubAPI(ctx, http.MethodGet, path, query, nil, v)
+}
+
+// doGitHubAPI calls the GitHub REST API with the credentials of the client, sending body as JSON unless it's nil,
+// and decodes the response into v unless it's nil.
+func (c *Client) doGitHubAPI(ctx context.Context, method, path string, query url.Values, body, v any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := c.NewGitHubAPIRequest(ctx, method, path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.URL.RawQuery = query.Encode()
+
+	bearerToken, err := c.gitHubAPIBearerToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", bearerToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return &GitHubAPIError{
+			StatusCode: resp.StatusCode,
+			RequestID:  resp.Header.Get(HeaderGitHubRequestID),
+			Err:        errors.New(string(body)),
+		}
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &GitHubAPIError{
+			StatusCode: resp.StatusCode,
+			RequestID:  resp.Header.Get(HeaderGitHubRequestID),
+			Err:        err,
+		}
+	}
+
+	return nil
+}
+
 func (c *Client) CreateRunnerScaleSet(ctx context.Context, runnerScaleSet *RunnerScaleSet) (*RunnerScaleSet, error) {
 	body, err := json.Marshal(runnerScaleSet)
 	if err != nil {
@@ -978,17 +1341,9 @@ func (c *Client) getRunnerRegistrationToken(ctx context.Context) (*registrationT
 		return nil, err
 	}
 
-	bearerToken := ""
-
-	if c.creds.Token != "" {
-		bearerToken = fmt.Sprintf("Bearer %v", c.creds.Token)
-	} else {
-		accessToken, err := c.fetchAccessToken(ctx, c.config.ConfigURL.String(), c.creds.AppCreds)
-		if err != nil {
-			return nil, err
-		}
-
-		bearerToken = fmt.Sprintf("Bearer %v", accessToken.Token)
+	bearerToken, err := c.gitHubAPIBearerToken(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/vnd.github.v3+json")
@@ -1026,6 +1381,20 @@ func (c *Client) getRunnerRegistrationToken(ctx context.Context) (*registrationT
 	return registrationToken, nil
 }
 
+// gitHubAPIBearerToken returns the Authorization header to call the GitHub REST API with the credentials of the client.
+func (c *Client) gitHubAPIBearerToken(ctx context.Context) (string, error) {
+	if c.creds.Token != "" {
+		return fmt.Sprintf("Bearer %v", c.creds.Token), nil
+	}
+
+	accessToken, err := c.fetchAccessToken(ctx, c.config.ConfigURL.String(), c.creds.AppCreds)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Bearer %v", accessToken.Token), nil
+}
+
 // Format: https://docs.github.com/en/rest/apps/apps#create-an-installation-access-token-for-an-app
 type accessToken struct {
 	Token     string    `json:"token"`
@@ -1197,6 +1566,27 @@ func createRegistrationTokenPath(config *GitHubConfig) (string, error) {
 	}
 }
 
+func runnerGroupPath(config *GitHubConfig, runnerGroupId int64) (string, error) {
+	groupsPath, err := runnerGroupsPath(config)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%d", groupsPath, runnerGroupId), nil
+}
+
+func runnerGroupsPath(config *GitHubConfig) (string, error) {
+	switch config.Scope {
+	case GitHubScopeOrganization:
+		return fmt.Sprintf("/orgs/%s/actions/runner-groups", config.Organization), nil
+
+	case GitHubScopeEnterprise:
+		return fmt.Sprintf("/enterprises/%s/actions/runner-groups", config.Enterprise), nil
+
+	default:
+		return "", fmt.Errorf("runner groups are only available to organizations and enterprises: %s", config.ConfigURL)
+	}
+}
+
 func createJWTForGitHubApp(appAuth *GitHubAppAuth) (string, error) {
 	// Encode as JWT
 	// See https://docs.github.com/en/developers/apps/building-github-apps/authenticating-with-github-apps#authenticating-as-a-github-app
@@ -1247,11 +1637,30 @@ func (c *Client) updateTokenIfNeeded(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	aboutToExpire := time.Now().Add(60 * time.Second).After(c.ActionsServiceAdminTokenExpiresAt)
+	return c.refreshTokenIfExpiresWithin(ctx, 60*time.Second)
+}
+
+// refreshTokenIfExpiresWithin refreshes the admin token unless it is valid for more than margin.
+// c.mu must be held.
+func (c *Client) refreshTokenIfExpiresWithin(ctx context.Context, margin time.Duration) error {
+	aboutToExpire := time.Now().Add(margin).After(c.ActionsServiceAdminTokenExpiresAt)
 	if !aboutToExpire && !c.ActionsServiceAdminTokenExpiresAt.IsZero() {
 		return nil
 	}
 
+	if c.adminTokenSource != nil {
+		token, err := c.adminTokenSource()
+		if err != nil {
+			c.logger.Info("failed to get shared admin token, minting one", "error", err.Error())
+		}
+		if token != nil && time.Now().Add(margin).Before(token.ExpiresAt) {
+			c.ActionsServiceURL = token.ActionsServiceURL
+			c.ActionsServiceAdminToken = token.Token
+			c.ActionsServiceAdminTokenExpiresAt = token.ExpiresAt
+			return nil
+		}
+	}
+
 	c.logger.Info("refreshing token", "githubConfigUrl", c.config.ConfigURL.String())
 	rt, err := c.getRunnerRegistrationToken(ctx)
 	if err != nil {
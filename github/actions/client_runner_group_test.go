@@ -0,0 +1,234 @@
+package actions_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRunnerGroupSettings(t *testing.T) {
+	ctx := context.Background()
+	auth := &actions.ActionsAuth{
+		Token: "token",
+	}
+
+	t.Run("Get runner group settings", func(t *testing.T) {
+		want := &actions.RunnerGroupSettings{
+			ID:         2,
+			Name:       "my-group",
+			Visibility: actions.RunnerGroupVisibilitySelected,
+		}
+
+		server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v3/orgs/my-org/actions/runner-groups/2", r.URL.Path)
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"id": 2, "name": "my-group", "visibility": "selected", "default": false, "allows_public_repositories": false}`))
+		}))
+
+		client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+		require.NoError(t, err)
+
+		got, err := client.GetRunnerGroupSettings(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+		}))
+
+		client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+		require.NoError(t, err)
+
+		_, err = client.GetRunnerGroupSettings(ctx, 2)
+		require.Error(t, err)
+
+		var apiErr *actions.GitHubAPIError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	})
+
+	t.Run("Repository scope has no runner groups", func(t *testing.T) {
+		server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}))
+
+		client, err := actions.NewClient(server.configURLForOrg("my-org")+"/my-repo", auth)
+		require.NoError(t, err)
+
+		_, err = client.GetRunnerGroupSettings(ctx, 2)
+		require.Error(t, err)
+	})
+}
+
+func TestListRunnerGroupRepositories(t *testing.T) {
+	ctx := context.Background()
+	auth := &actions.ActionsAuth{
+		Token: "token",
+	}
+
+	server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/orgs/my-org/actions/runner-groups/2/repositories", r.URL.Path)
+		assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Write([]byte(`{"total_count": 3, "repositories": [{"name": "repo-a"}, {"name": "repo-b"}]}`))
+		case "2":
+			w.Write([]byte(`{"total_count": 3, "repositories": [{"name": "repo-c"}]}`))
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+
+	client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+	require.NoError(t, err)
+
+	got, err := client.ListRunnerGroupRepositories(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"repo-a", "repo-b", "repo-c"}, got)
+}
+
+func TestListRunnerGroupSettings(t *testing.T) {
+	ctx := context.Background()
+	auth := &actions.ActionsAuth{
+		Token: "token",
+	}
+
+	server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/api/v3/enterprises/my-enterprise/actions/runner-groups", r.URL.Path)
+
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Write([]byte(`{"total_count": 2, "runner_groups": [{"id": 1, "name": "Default", "visibility": "all", "default": true}]}`))
+		case "2":
+			w.Write([]byte(`{"total_count": 2, "runner_groups": [{"id": 2, "name": "my-group", "visibility": "selected"}]}`))
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+
+	client, err := actions.NewClient(server.URL+"/enterprises/my-enterprise", auth)
+	require.NoError(t, err)
+
+	got, err := client.ListRunnerGroupSettings(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []actions.RunnerGroupSettings{
+		{ID: 1, Name: "Default", Visibility: actions.RunnerGroupVisibilityAll, Default: true},
+		{ID: 2, Name: "my-group", Visibility: actions.RunnerGroupVisibilitySelected},
+	}, got)
+}
+
+func TestCreateAndUpdateRunnerGroup(t *testing.T) {
+	ctx := context.Background()
+	auth := &actions.ActionsAuth{
+		Token: "token",
+	}
+
+	settings := &actions.RunnerGroupSettings{
+		Name:                     "my-group",
+		Visibility:               actions.RunnerGroupVisibilityPrivate,
+		AllowsPublicRepositories: true,
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/api/v3/orgs/my-org/actions/runner-groups", r.URL.Path)
+
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"name": "my-group", "visibility": "private", "allows_public_repositories": true}`, string(body))
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 3, "name": "my-group", "visibility": "private", "allows_public_repositories": true}`))
+		}))
+
+		client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+		require.NoError(t, err)
+
+		got, err := client.CreateRunnerGroup(ctx, settings)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), got.ID)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.Equal(t, "/api/v3/orgs/my-org/actions/runner-groups/3", r.URL.Path)
+			w.Write([]byte(`{"id": 3, "name": "my-group", "visibility": "private", "allows_public_repositories": true}`))
+		}))
+
+		client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+		require.NoError(t, err)
+
+		got, err := client.UpdateRunnerGroup(ctx, 3, settings)
+		require.NoError(t, err)
+		assert.True(t, got.AllowsPublicRepositories)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodDelete, r.Method)
+			assert.Equal(t, "/api/v3/orgs/my-org/actions/runner-groups/3", r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+		require.NoError(t, err)
+
+		require.NoError(t, client.DeleteRunnerGroup(ctx, 3))
+	})
+}
+
+func TestSetRunnerGroupRepositories(t *testing.T) {
+	ctx := context.Background()
+	auth := &actions.ActionsAuth{
+		Token: "token",
+	}
+
+	ids := map[string]int{"repo-a": 10, "repo-b": 11}
+
+	server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v3/repos/my-org/"):
+			name := strings.TrimPrefix(r.URL.Path, "/api/v3/repos/my-org/")
+			id, ok := ids[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"message": "Not Found"}`))
+				return
+			}
+			fmt.Fprintf(w, `{"id": %d, "name": %q}`, id, name)
+		case r.Method == http.MethodPut && r.URL.Path == "/api/v3/orgs/my-org/actions/runner-groups/3/repositories":
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"selected_repository_ids": [10, 11]}`, string(body))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+
+	client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+	require.NoError(t, err)
+
+	require.NoError(t, client.SetRunnerGroupRepositories(ctx, 3, []string{"repo-a", "repo-b"}))
+
+	err = client.SetRunnerGroupRepositories(ctx, 3, []string{"missing"})
+	require.Error(t, err)
+	var apiErr *actions.GitHubAPIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
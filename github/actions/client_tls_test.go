@@ -134,6 +134,47 @@ func TestServerWithSelfSignedCertificates(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("client with client certificate", func(t *testing.T) {
+		rootCA, err := os.ReadFile(filepath.Join("testdata", "rootCA.crt"))
+		require.NoError(t, err)
+
+		pool := x509.NewCertPool()
+		require.True(t, pool.AppendCertsFromPEM(rootCA))
+
+		server := httptest.NewUnstartedServer(http.HandlerFunc(h))
+		t.Cleanup(server.Close)
+
+		serverCert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		require.NoError(t, err)
+
+		server.TLS = &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+		}
+		server.StartTLS()
+		u = server.URL
+		configURL := server.URL + "/my-org"
+
+		auth := &actions.ActionsAuth{
+			Token: "token",
+		}
+		client, err := actions.NewClient(configURL, auth, actions.WithRootCAs(pool), actions.WithRetryMax(0))
+		require.NoError(t, err)
+
+		err = client.RemoveRunner(ctx, 1)
+		assert.Error(t, err, "the server requires a client certificate")
+
+		clientCert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		require.NoError(t, err)
+
+		client, err = actions.NewClient(configURL, auth, actions.WithRootCAs(pool), actions.WithClientCertificate(clientCert), actions.WithRetryMax(0))
+		require.NoError(t, err)
+
+		err = client.RemoveRunner(ctx, 1)
+		assert.NoError(t, err)
+	})
+
 	t.Run("client skipping tls verification", func(t *testing.T) {
 		server := startNewTLSTestServer(t, certPath, keyPath, http.HandlerFunc(h))
 		configURL := server.URL + "/my-org"
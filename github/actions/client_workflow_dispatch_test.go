@@ -0,0 +1,41 @@
+package actions_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatchWorkflow(t *testing.T) {
+	ctx := context.Background()
+	auth := &actions.ActionsAuth{
+		Token: "token",
+	}
+
+	server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v3/repos/my-org/my-repo/actions/workflows/load.yaml/dispatches", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var body struct {
+			Ref    string            `json:"ref"`
+			Inputs map[string]string `json:"inputs"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "main", body.Ref)
+		assert.Equal(t, map[string]string{"duration": "60"}, body.Inputs)
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+	require.NoError(t, err)
+
+	err = client.DispatchWorkflow(ctx, "my-org", "my-repo", "load.yaml", "main", map[string]string{"duration": "60"})
+	require.NoError(t, err)
+}
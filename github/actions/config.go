@@ -5,6 +5,8 @@ import (
 	"net/url"
 	"os"
 	"strings"
+
+	"github.com/actions/actions-runner-controller/pkg/githuburl"
 )
 
 var ErrInvalidGitHubConfigURL = fmt.Errorf("invalid config URL, should point to an enterprise, org, or repository")
@@ -89,6 +91,12 @@ func (c *GitHubConfig) GitHubAPIURL(path string) *url.URL {
 			// re-routing www.github.com to api.github.com
 			result.Host = "api.github.com"
 		}
+
+		// GitHub Enterprise Cloud with data residency serves its API at api.<tenant>.ghe.com,
+		// regardless of the case of the host or of the config URL pointing to the API host already
+		if host, ok := githuburl.DataResidencyHost(c.ConfigURL.Host); ok {
+			result.Host = "api." + host
+		}
 	}
 
 	result.Path += path
@@ -105,5 +113,11 @@ func isHostedGitHubURL(u *url.URL) bool {
 	return strings.EqualFold(u.Host, "github.com") ||
 		strings.EqualFold(u.Host, "www.github.com") ||
 		strings.EqualFold(u.Host, "github.localhost") ||
-		strings.HasSuffix(u.Host, ".ghe.com")
+		isDataResidencyGitHubURL(u)
+}
+
+// isDataResidencyGitHubURL returns whether the URL is of GitHub Enterprise Cloud with data residency, like https://my-tenant.ghe.com.
+func isDataResidencyGitHubURL(u *url.URL) bool {
+	_, ok := githuburl.DataResidencyHost(u.Host)
+	return ok
 }
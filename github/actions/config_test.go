@@ -175,6 +175,14 @@ func TestGitHubConfig_GitHubAPIURL(t *testing.T) {
 		result := config.GitHubAPIURL("/some/path")
 		assert.Equal(t, "https://api.github.ghe.com/some/path", result.String())
 	})
+	t.Run("when hosted with ghe.com in upper case", func(t *testing.T) {
+		config, err := actions.ParseGitHubConfigFromURL("https://My-Tenant.GHE.com/org/repo")
+		require.NoError(t, err)
+		assert.True(t, config.IsHosted)
+
+		result := config.GitHubAPIURL("/some/path")
+		assert.Equal(t, "https://api.my-tenant.ghe.com/some/path", result.String())
+	})
 	t.Run("when not hosted", func(t *testing.T) {
 		config, err := actions.ParseGitHubConfigFromURL("https://ghes.com/org/repo")
 		require.NoError(t, err)
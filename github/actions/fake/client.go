@@ -24,6 +24,59 @@ func WithGetRunnerGroup(runnerGroup *actions.RunnerGroup, err error) Option {
 	}
 }
 
+func WithGetRunnerGroupSettings(runnerGroup *actions.RunnerGroupSettings, err error) Option {
+	return func(f *FakeClient) {
+		f.getRunnerGroupSettingsResult.RunnerGroupSettings = runnerGroup
+		f.getRunnerGroupSettingsResult.err = err
+	}
+}
+
+func WithListRunnerGroupRepositories(repositories []string, err error) Option {
+	return func(f *FakeClient) {
+		f.listRunnerGroupRepositoriesResult.repositories = repositories
+		f.listRunnerGroupRepositoriesResult.err = err
+	}
+}
+
+func WithListRunnerGroupSettings(runnerGroups []actions.RunnerGroupSettings, err error) Option {
+	return func(f *FakeClient) {
+		f.listRunnerGroupSettingsResult.runnerGroups = runnerGroups
+		f.listRunnerGroupSettingsResult.err = err
+	}
+}
+
+func WithCreateRunnerGroup(runnerGroup *actions.RunnerGroupSettings, err error) Option {
+	return func(f *FakeClient) {
+		f.createRunnerGroupResult.RunnerGroupSettings = runnerGroup
+		f.createRunnerGroupResult.err = err
+	}
+}
+
+func WithUpdateRunnerGroup(runnerGroup *actions.RunnerGroupSettings, err error) Option {
+	return func(f *FakeClient) {
+		f.updateRunnerGroupResult.RunnerGroupSettings = runnerGroup
+		f.updateRunnerGroupResult.err = err
+	}
+}
+
+func WithSetRunnerGroupRepositories(err error) Option {
+	return func(f *FakeClient) {
+		f.setRunnerGroupRepositoriesResult.err = err
+	}
+}
+
+func WithDeleteRunnerGroup(err error) Option {
+	return func(f *FakeClient) {
+		f.deleteRunnerGroupResult.err = err
+	}
+}
+
+func WithDispatchWorkflow(err error) Option {
+	return func(f *FakeClient) {
+		f.dispatchWorkflowResult.err = err
+	}
+}
+
 func WithGetRunner(runner *actions.RunnerReference, err error) Option {
 	return func(f *FakeClient) {
 		f.getRunnerResult.RunnerReference = runner
@@ -45,6 +98,19 @@ func WithUpdateRunnerScaleSet(scaleSet *actions.RunnerScaleSet, err error) Optio
 	}
 }
 
+func WithGenerateJitRunnerConfig(jitConfig *actions.RunnerScaleSetJitRunnerConfig, err error) Option {
+	return func(f *FakeClient) {
+		f.generateJitRunnerConfigResult.RunnerScaleSetJitRunnerConfig = jitConfig
+		f.generateJitRunnerConfigResult.err = err
+	}
+}
+
+func WithRemoveRunner(err error) Option {
+	return func(f *FakeClient) {
+		f.removeRunnerResult.err = err
+	}
+}
+
 var defaultRunnerScaleSet = &actions.RunnerScaleSet{
 	Id:                 1,
 	Name:               "testset",
@@ -76,6 +142,13 @@ var defaultRunnerGroup = &actions.RunnerGroup{
 	IsDefault: true,
 }
 
+var defaultRunnerGroupSettings = &actions.RunnerGroupSettings{
+	ID:         1,
+	Name:       "testgroup",
+	Visibility: actions.RunnerGroupVisibilityAll,
+	Default:    true,
+}
+
 var sessionID = uuid.New()
 
 var defaultRunnerScaleSetSession = &actions.RunnerScaleSetSession{
@@ -135,6 +208,36 @@ type FakeClient struct {
 		*actions.RunnerGroup
 		err error
 	}
+	getRunnerGroupSettingsResult struct {
+		*actions.RunnerGroupSettings
+		err error
+	}
+	listRunnerGroupRepositoriesResult struct {
+		repositories []string
+		err          error
+	}
+	listRunnerGroupSettingsResult struct {
+		runnerGroups []actions.RunnerGroupSettings
+		err          error
+	}
+	createRunnerGroupResult struct {
+		*actions.RunnerGroupSettings
+		err error
+	}
+	updateRunnerGroupResult struct {
+		*actions.RunnerGroupSettings
+		err error
+	}
+	setRunnerGroupRepositoriesResult struct {
+		err error
+	}
+	deleteRunnerGroupResult struct {
+		err error
+	}
+
+	dispatchWorkflowResult struct {
+		err error
+	}
 
 	createRunnerScaleSetResult struct {
 		*actions.RunnerScaleSet
@@ -203,6 +306,7 @@ func (f *FakeClient) applyDefaults() {
 	f.getRunnerScaleSetResult.RunnerScaleSet = defaultRunnerScaleSet
 	f.getRunnerScaleSetByIdResult.RunnerScaleSet = defaultRunnerScaleSet
 	f.getRunnerGroupByNameResult.RunnerGroup = defaultRunnerGroup
+	f.getRunnerGroupSettingsResult.RunnerGroupSettings = defaultRunnerGroupSettings
 	f.createRunnerScaleSetResult.RunnerScaleSet = defaultRunnerScaleSet
 	f.updateRunnerScaleSetResult.RunnerScaleSet = defaultUpdatedRunnerScaleSet
 	f.createMessageSessionResult.RunnerScaleSetSession = defaultRunnerScaleSetSession
@@ -227,6 +331,38 @@ func (f *FakeClient) GetRunnerGroupByName(ctx context.Context, runnerGroup strin
 	return f.getRunnerGroupByNameResult.RunnerGroup, f.getRunnerGroupByNameResult.err
 }
 
+func (f *FakeClient) GetRunnerGroupSettings(ctx context.Context, runnerGroupId int64) (*actions.RunnerGroupSettings, error) {
+	return f.getRunnerGroupSettingsResult.RunnerGroupSettings, f.getRunnerGroupSettingsResult.err
+}
+
+func (f *FakeClient) ListRunnerGroupRepositories(ctx context.Context, runnerGroupId int64) ([]string, error) {
+	return f.listRunnerGroupRepositoriesResult.repositories, f.listRunnerGroupRepositoriesResult.err
+}
+
+func (f *FakeClient) ListRunnerGroupSettings(ctx context.Context) ([]actions.RunnerGroupSettings, error) {
+	return f.listRunnerGroupSettingsResult.runnerGroups, f.listRunnerGroupSettingsResult.err
+}
+
+func (f *FakeClient) CreateRunnerGroup(ctx context.Context, runnerGroup *actions.RunnerGroupSettings) (*actions.RunnerGroupSettings, error) {
+	return f.createRunnerGroupResult.RunnerGroupSettings, f.createRunnerGroupResult.err
+}
+
+func (f *FakeClient) UpdateRunnerGroup(ctx context.Context, runnerGroupId int64, runnerGroup *actions.RunnerGroupSettings) (*actions.RunnerGroupSettings, error) {
+	return f.updateRunnerGroupResult.RunnerGroupSettings, f.updateRunnerGroupResult.err
+}
+
+func (f *FakeClient) SetRunnerGroupRepositories(ctx context.Context, runnerGroupId int64, repositories []string) error {
+	return f.setRunnerGroupRepositoriesResult.err
+}
+
+func (f *FakeClient) DeleteRunnerGroup(ctx context.Context, runnerGroupId int64) error {
+	return f.deleteRunnerGroupResult.err
+}
+
+func (f *FakeClient) DispatchWorkflow(ctx context.Context, owner, repo, workflow, ref string, inputs map[string]string) error {
+	return f.dispatchWorkflowResult.err
+}
+
 func (f *FakeClient) CreateRunnerScaleSet(ctx context.Context, runnerScaleSet *actions.RunnerScaleSet) (*actions.RunnerScaleSet, error) {
 	return f.createRunnerScaleSetResult.RunnerScaleSet, f.createRunnerScaleSetResult.err
 }
@@ -2,9 +2,11 @@ package actions_test
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
@@ -68,6 +70,63 @@ func TestNewGitHubAPIRequest(t *testing.T) {
 		}
 	})
 
+	t.Run("sends the request through the GitHub API proxy", func(t *testing.T) {
+		proxyURL, err := url.Parse("https://arc-github-api-proxy.arc-systems.svc:8443")
+		require.NoError(t, err)
+
+		client, err := actions.NewClient("https://my-instance.com/org/repo", nil, actions.WithGitHubAPIProxy(proxyURL, x509.NewCertPool()))
+		require.NoError(t, err)
+
+		req, err := client.NewGitHubAPIRequest(ctx, http.MethodGet, "/app/installations/123/access_tokens", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "https://arc-github-api-proxy.arc-systems.svc:8443/my-instance.com/api/v3/app/installations/123/access_tokens", req.URL.String())
+
+		_, err = actions.NewClient("https://my-instance.com/org/repo", nil, actions.WithGitHubAPIProxy(proxyURL, x509.NewCertPool()), actions.WithRootCAs(x509.NewCertPool()))
+		assert.Error(t, err, "the proxy doesn't apply the TLS settings of the GitHub server")
+
+		_, err = actions.NewClient("https://my-instance.com/org/repo", nil, actions.WithGitHubAPIProxy(proxyURL, nil))
+		assert.Error(t, err, "the certificate of the proxy is pinned to its CA")
+
+		plaintextURL, err := url.Parse("http://arc-github-api-proxy.arc-systems.svc:8080")
+		require.NoError(t, err)
+		_, err = actions.NewClient("https://my-instance.com/org/repo", nil, actions.WithGitHubAPIProxy(plaintextURL, x509.NewCertPool()))
+		assert.Error(t, err, "the proxy is sent the credentials")
+	})
+
+	t.Run("trusts only the CA of the GitHub API proxy for it", func(t *testing.T) {
+		proxy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/my-instance.com/api/v3/orgs/org", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer proxy.Close()
+
+		proxyURL, err := url.Parse(proxy.URL)
+		require.NoError(t, err)
+
+		proxyCAs := x509.NewCertPool()
+		proxyCAs.AddCert(proxy.Certificate())
+
+		client, err := actions.NewClient("https://my-instance.com/org/repo", nil, actions.WithGitHubAPIProxy(proxyURL, proxyCAs), actions.WithRetryMax(0))
+		require.NoError(t, err)
+
+		req, err := client.NewGitHubAPIRequest(ctx, http.MethodGet, "/orgs/org", nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		client, err = actions.NewClient("https://my-instance.com/org/repo", nil, actions.WithGitHubAPIProxy(proxyURL, x509.NewCertPool()), actions.WithRetryMax(0))
+		require.NoError(t, err)
+
+		req, err = client.NewGitHubAPIRequest(ctx, http.MethodGet, "/orgs/org", nil)
+		require.NoError(t, err)
+
+		_, err = client.Do(req)
+		assert.Error(t, err, "the certificate of the proxy isn't issued by the pinned CA")
+	})
+
 	t.Run("sets user agent header if present", func(t *testing.T) {
 		client, err := actions.NewClient("http://localhost/my-org", nil)
 		require.NoError(t, err)
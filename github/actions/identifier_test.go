@@ -1,6 +1,7 @@
 package actions_test
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"os"
 	"path/filepath"
@@ -144,10 +145,22 @@ func TestClient_Identifier(t *testing.T) {
 		)
 		require.NoError(t, err)
 
+		cert, err := tls.LoadX509KeyPair(filepath.Join("testdata", "server.crt"), filepath.Join("testdata", "server.key"))
+		require.NoError(t, err)
+
+		clientCert, err := actions.NewClient(
+			configURL,
+			defaultCreds,
+			actions.WithRootCAs(poolFromCert(t, filepath.Join("testdata", "rootCA.crt"))),
+			actions.WithClientCertificate(cert),
+		)
+		require.NoError(t, err)
+
 		clients := []*actions.Client{
 			noTlS,
 			root,
 			chain,
+			clientCert,
 		}
 		identifiers := map[string]struct{}{}
 		for _, client := range clients {
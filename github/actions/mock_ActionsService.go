@@ -66,6 +66,32 @@ func (_m *MockActionsService) CreateMessageSession(ctx context.Context, runnerSc
 	return r0, r1
 }
 
+// CreateRunnerGroup provides a mock function with given fields: ctx, runnerGroup
+func (_m *MockActionsService) CreateRunnerGroup(ctx context.Context, runnerGroup *RunnerGroupSettings) (*RunnerGroupSettings, error) {
+	ret := _m.Called(ctx, runnerGroup)
+
+	var r0 *RunnerGroupSettings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *RunnerGroupSettings) (*RunnerGroupSettings, error)); ok {
+		return rf(ctx, runnerGroup)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *RunnerGroupSettings) *RunnerGroupSettings); ok {
+		r0 = rf(ctx, runnerGroup)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*RunnerGroupSettings)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *RunnerGroupSettings) error); ok {
+		r1 = rf(ctx, runnerGroup)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateRunnerScaleSet provides a mock function with given fields: ctx, runnerScaleSet
 func (_m *MockActionsService) CreateRunnerScaleSet(ctx context.Context, runnerScaleSet *RunnerScaleSet) (*RunnerScaleSet, error) {
 	ret := _m.Called(ctx, runnerScaleSet)
@@ -120,6 +146,20 @@ func (_m *MockActionsService) DeleteMessageSession(ctx context.Context, runnerSc
 	return r0
 }
 
+// DeleteRunnerGroup provides a mock function with given fields: ctx, runnerGroupId
+func (_m *MockActionsService) DeleteRunnerGroup(ctx context.Context, runnerGroupId int64) error {
+	ret := _m.Called(ctx, runnerGroupId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, runnerGroupId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteRunnerScaleSet provides a mock function with given fields: ctx, runnerScaleSetId
 func (_m *MockActionsService) DeleteRunnerScaleSet(ctx context.Context, runnerScaleSetId int) error {
 	ret := _m.Called(ctx, runnerScaleSetId)
@@ -134,6 +174,20 @@ func (_m *MockActionsService) DeleteRunnerScaleSet(ctx context.Context, runnerSc
 	return r0
 }
 
+// DispatchWorkflow provides a mock function with given fields: ctx, owner, repo, workflow, ref, inputs
+func (_m *MockActionsService) DispatchWorkflow(ctx context.Context, owner string, repo string, workflow string, ref string, inputs map[string]string) error {
+	ret := _m.Called(ctx, owner, repo, workflow, ref, inputs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, map[string]string) error); ok {
+		r0 = rf(ctx, owner, repo, workflow, ref, inputs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GenerateJitRunnerConfig provides a mock function with given fields: ctx, jitRunnerSetting, scaleSetId
 func (_m *MockActionsService) GenerateJitRunnerConfig(ctx context.Context, jitRunnerSetting *RunnerScaleSetJitRunnerSetting, scaleSetId int) (*RunnerScaleSetJitRunnerConfig, error) {
 	ret := _m.Called(ctx, jitRunnerSetting, scaleSetId)
@@ -290,6 +344,32 @@ func (_m *MockActionsService) GetRunnerGroupByName(ctx context.Context, runnerGr
 	return r0, r1
 }
 
+// GetRunnerGroupSettings provides a mock function with given fields: ctx, runnerGroupId
+func (_m *MockActionsService) GetRunnerGroupSettings(ctx context.Context, runnerGroupId int64) (*RunnerGroupSettings, error) {
+	ret := _m.Called(ctx, runnerGroupId)
+
+	var r0 *RunnerGroupSettings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*RunnerGroupSettings, error)); ok {
+		return rf(ctx, runnerGroupId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *RunnerGroupSettings); ok {
+		r0 = rf(ctx, runnerGroupId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*RunnerGroupSettings)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, runnerGroupId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRunnerScaleSet provides a mock function with given fields: ctx, runnerGroupId, runnerScaleSetName
 func (_m *MockActionsService) GetRunnerScaleSet(ctx context.Context, runnerGroupId int, runnerScaleSetName string) (*RunnerScaleSet, error) {
 	ret := _m.Called(ctx, runnerGroupId, runnerScaleSetName)
@@ -342,6 +422,58 @@ func (_m *MockActionsService) GetRunnerScaleSetById(ctx context.Context, runnerS
 	return r0, r1
 }
 
+// ListRunnerGroupRepositories provides a mock function with given fields: ctx, runnerGroupId
+func (_m *MockActionsService) ListRunnerGroupRepositories(ctx context.Context, runnerGroupId int64) ([]string, error) {
+	ret := _m.Called(ctx, runnerGroupId)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]string, error)); ok {
+		return rf(ctx, runnerGroupId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []string); ok {
+		r0 = rf(ctx, runnerGroupId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, runnerGroupId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRunnerGroupSettings provides a mock function with given fields: ctx
+func (_m *MockActionsService) ListRunnerGroupSettings(ctx context.Context) ([]RunnerGroupSettings, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []RunnerGroupSettings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]RunnerGroupSettings, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []RunnerGroupSettings); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]RunnerGroupSettings)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RefreshMessageSession provides a mock function with given fields: ctx, runnerScaleSetId, sessionId
 func (_m *MockActionsService) RefreshMessageSession(ctx context.Context, runnerScaleSetId int, sessionId *uuid.UUID) (*RunnerScaleSetSession, error) {
 	ret := _m.Called(ctx, runnerScaleSetId, sessionId)
@@ -382,11 +514,51 @@ func (_m *MockActionsService) RemoveRunner(ctx context.Context, runnerId int64)
 	return r0
 }
 
+// SetRunnerGroupRepositories provides a mock function with given fields: ctx, runnerGroupId, repositories
+func (_m *MockActionsService) SetRunnerGroupRepositories(ctx context.Context, runnerGroupId int64, repositories []string) error {
+	ret := _m.Called(ctx, runnerGroupId, repositories)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []string) error); ok {
+		r0 = rf(ctx, runnerGroupId, repositories)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetUserAgent provides a mock function with given fields: info
 func (_m *MockActionsService) SetUserAgent(info UserAgentInfo) {
 	_m.Called(info)
 }
 
+// UpdateRunnerGroup provides a mock function with given fields: ctx, runnerGroupId, runnerGroup
+func (_m *MockActionsService) UpdateRunnerGroup(ctx context.Context, runnerGroupId int64, runnerGroup *RunnerGroupSettings) (*RunnerGroupSettings, error) {
+	ret := _m.Called(ctx, runnerGroupId, runnerGroup)
+
+	var r0 *RunnerGroupSettings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *RunnerGroupSettings) (*RunnerGroupSettings, error)); ok {
+		return rf(ctx, runnerGroupId, runnerGroup)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *RunnerGroupSettings) *RunnerGroupSettings); ok {
+		r0 = rf(ctx, runnerGroupId, runnerGroup)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*RunnerGroupSettings)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, *RunnerGroupSettings) error); ok {
+		r1 = rf(ctx, runnerGroupId, runnerGroup)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateRunnerScaleSet provides a mock function with given fields: ctx, runnerScaleSetId, runnerScaleSet
 func (_m *MockActionsService) UpdateRunnerScaleSet(ctx context.Context, runnerScaleSetId int, runnerScaleSet *RunnerScaleSet) (*RunnerScaleSet, error) {
 	ret := _m.Called(ctx, runnerScaleSetId, runnerScaleSet)
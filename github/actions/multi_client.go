@@ -20,6 +20,9 @@ type multiClient struct {
 	clients map[ActionsClientKey]*Client
 
 	logger logr.Logger
+
+	// options are applied to every client, before the options of GetClientFor
+	options []ClientOption
 }
 
 type GitHubAppAuth struct {
@@ -41,11 +44,12 @@ type ActionsClientKey struct {
 	Namespace  string
 }
 
-func NewMultiClient(logger logr.Logger) MultiClient {
+func NewMultiClient(logger logr.Logger, options ...ClientOption) MultiClient {
 	return &multiClient{
 		mu:      sync.Mutex{},
 		clients: make(map[ActionsClientKey]*Client),
 		logger:  logger,
+		options: options,
 	}
 }
 
@@ -63,9 +67,9 @@ func (m *multiClient) GetClientFor(ctx context.Context, githubConfigURL string,
 	client, err := NewClient(
 		githubConfigURL,
 		&creds,
-		append([]ClientOption{
+		append(append([]ClientOption{
 			WithLogger(m.logger),
-		}, options...)...,
+		}, m.options...), options...)...,
 	)
 	if err != nil {
 		return nil, err
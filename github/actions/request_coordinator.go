@@ -0,0 +1,294 @@
+package actions
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+type scaleSetContextKey struct{}
+
+// ContextWithScaleSet returns a copy of ctx identifying the scale set on whose behalf requests are made,
+// so that a RequestCoordinator can share the rate limit of the credentials fairly between scale sets.
+func ContextWithScaleSet(ctx context.Context, scaleSet string) context.Context {
+	return context.WithValue(ctx, scaleSetContextKey{}, scaleSet)
+}
+
+func scaleSetFromContext(ctx context.Context) string {
+	scaleSet, _ := ctx.Value(scaleSetContextKey{}).(string)
+	return scaleSet
+}
+
+// RequestPacer paces the requests made with the credentials identified by key.
+type RequestPacer interface {
+	// Wait blocks until a request can be made with the credentials identified by key on behalf of the scale set of ctx,
+	// or until ctx is done.
+	Wait(ctx context.Context, key string) error
+}
+
+// RequestCoordinator paces the requests made with the same credentials, like a GitHub App installation,
+// and time-slices them between the scale sets sharing the credentials.
+//
+// Each credentials get a token bucket refilled at the configured rate. While requests are waiting for a token,
+// tokens are granted round-robin between the scale sets that have waiting requests, so that a scale set
+// making many requests can't starve the token refreshes and runner registrations of the others.
+type RequestCoordinator struct {
+	interval time.Duration
+	burst    float64
+
+	mu      sync.Mutex
+	buckets map[string]*requestBucket
+}
+
+// NewRequestCoordinator returns a RequestCoordinator allowing requestsPerHour requests per credentials,
+// with bursts of up to burst requests when no request is waiting.
+func NewRequestCoordinator(requestsPerHour, burst int) *RequestCoordinator {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RequestCoordinator{
+		interval: time.Hour / time.Duration(requestsPerHour),
+		burst:    float64(burst),
+		buckets:  make(map[string]*requestBucket),
+	}
+}
+
+type requestBucket struct {
+	tokens     float64
+	refilledAt time.Time
+
+	// queues holds the waiting requests of each scale set, and order the scale sets with waiting requests
+	// in the order their next request is granted.
+	queues map[string][]*requestWaiter
+	order  []string
+
+	timer *time.Timer
+}
+
+type requestWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// Wait blocks until a request can be made with the credentials identified by key on behalf of the scale set of ctx,
+// or until ctx is done.
+func (c *RequestCoordinator) Wait(ctx context.Context, key string) error {
+	scaleSet := scaleSetFromContext(ctx)
+
+	c.mu.Lock()
+
+	b, ok := c.buckets[key]
+	if !ok {
+		b = &requestBucket{
+			tokens:     c.burst,
+			refilledAt: time.Now(),
+			queues:     make(map[string][]*requestWaiter),
+		}
+		c.buckets[key] = b
+	}
+
+	c.refill(b)
+
+	if len(b.order) == 0 && b.tokens >= 1 {
+		b.tokens--
+		c.mu.Unlock()
+		return nil
+	}
+
+	w := &requestWaiter{ready: make(chan struct{})}
+	if len(b.queues[scaleSet]) == 0 {
+		b.order = append(b.order, scaleSet)
+	}
+	b.queues[scaleSet] = append(b.queues[scaleSet], w)
+
+	c.schedule(b)
+
+	c.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if w.granted {
+			return nil
+		}
+
+		c.cancel(b, scaleSet, w)
+
+		return ctx.Err()
+	}
+}
+
+func (c *RequestCoordinator) refill(b *requestBucket) {
+	now := time.Now()
+
+	b.tokens += float64(now.Sub(b.refilledAt)) / float64(c.interval)
+	if b.tokens > c.burst {
+		b.tokens = c.burst
+	}
+	b.refilledAt = now
+}
+
+// schedule grants the available tokens to the waiting requests, and arranges for the next grant
+// once another token is available. c.mu must be held.
+func (c *RequestCoordinator) schedule(b *requestBucket) {
+	for len(b.order) > 0 && b.tokens >= 1 {
+		scaleSet := b.order[0]
+		queue := b.queues[scaleSet]
+
+		w := queue[0]
+		w.granted = true
+		close(w.ready)
+		b.tokens--
+
+		b.order = b.order[1:]
+		if len(queue) > 1 {
+			b.queues[scaleSet] = queue[1:]
+			// The scale set waits for its next turn behind the others
+			b.order = append(b.order, scaleSet)
+		} else {
+			delete(b.queues, scaleSet)
+		}
+	}
+
+	if len(b.order) == 0 || b.timer != nil {
+		return
+	}
+
+	wait := time.Duration((1 - b.tokens) * float64(c.interval))
+	b.timer = time.AfterFunc(wait, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		b.timer = nil
+		c.refill(b)
+		c.schedule(b)
+	})
+}
+
+// cancel removes the waiter from the queue of the scale set. c.mu must be held.
+func (c *RequestCoordinator) cancel(b *requestBucket, scaleSet string, w *requestWaiter) {
+	queue := b.queues[scaleSet]
+	for i := range queue {
+		if queue[i] == w {
+			queue = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+
+	if len(queue) > 0 {
+		b.queues[scaleSet] = queue
+		return
+	}
+
+	delete(b.queues, scaleSet)
+	for i, s := range b.order {
+		if s == scaleSet {
+			b.order = append(b.order[:i:i], b.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// ServeHTTP lets other processes, like the listeners, pace their requests with the coordinator. See RemoteRequestCoordinator.
+// It responds once a request can be made with the credentials identified by the key query parameter on behalf of
+// the scale set of the scaleSet query parameter.
+func (c *RequestCoordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return
+	}
+
+	ctx := ContextWithScaleSet(r.Context(), r.URL.Query().Get("scaleSet"))
+	if err := c.Wait(ctx, key); err != nil {
+		// The client has gone away
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoteRequestCoordinator paces requests with the RequestCoordinator served by another process,
+// so that the listeners share the rate limit of their credentials with the controller and the other scale sets.
+//
+// It fails open: when the coordinator can't be reached, like while the controller restarts, requests are made
+// without waiting rather than stopping the listener from acquiring jobs.
+type RemoteRequestCoordinator struct {
+	url      *url.URL
+	scaleSet string
+	client   *http.Client
+	logger   logr.Logger
+}
+
+// NewRemoteRequestCoordinator returns a RemoteRequestCoordinator for the coordinator served at rawURL.
+// scaleSet identifies the scale set of the requests whose context doesn't.
+func NewRemoteRequestCoordinator(rawURL, scaleSet string, logger logr.Logger) (*RemoteRequestCoordinator, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteRequestCoordinator{
+		url:      u,
+		scaleSet: scaleSet,
+		client: &http.Client{
+			// The coordinator runs in the cluster, so the proxy settings for GitHub don't apply to it
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+			},
+		},
+		logger: logger,
+	}, nil
+}
+
+func (c *RemoteRequestCoordinator) Wait(ctx context.Context, key string) error {
+	scaleSet := scaleSetFromContext(ctx)
+	if scaleSet == "" {
+		scaleSet = c.scaleSet
+	}
+
+	u := *c.url
+	q := u.Query()
+	q.Set("key", key)
+	q.Set("scaleSet", scaleSet)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.logger.Info("Request coordinator is unavailable, making the request without waiting for it", "error", err.Error())
+		return nil
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusNoContent {
+		c.logger.Info("Request coordinator failed, making the request without waiting for it", "status", resp.Status)
+	}
+
+	return nil
+}
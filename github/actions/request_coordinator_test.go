@@ -0,0 +1,164 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestCoordinator_Burst(t *testing.T) {
+	c := NewRequestCoordinator(3600, 3)
+	ctx := ContextWithScaleSet(context.Background(), "ns/a")
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Wait(ctx, "key"))
+	}
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// Other credentials have their own bucket
+	require.NoError(t, c.Wait(ctx, "other"))
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	err := c.Wait(ctx, "key")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Empty(t, c.buckets["key"].order, "cancelled requests must leave the queue")
+}
+
+func TestRequestCoordinator_RoundRobin(t *testing.T) {
+	// A token every 100ms
+	c := NewRequestCoordinator(36000, 1)
+	require.NoError(t, c.Wait(context.Background(), "key"))
+
+	var (
+		mu      sync.Mutex
+		granted []string
+		wg      sync.WaitGroup
+	)
+
+	wait := func(scaleSet string) {
+		defer wg.Done()
+		require.NoError(t, c.Wait(ContextWithScaleSet(context.Background(), scaleSet), "key"))
+		mu.Lock()
+		granted = append(granted, scaleSet)
+		mu.Unlock()
+	}
+
+	queued := func(n int) func() bool {
+		return func() bool {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			var total int
+			for _, q := range c.buckets["key"].queues {
+				total += len(q)
+			}
+			return total == n
+		}
+	}
+
+	// The large scale set queues its requests first
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go wait("ns/large")
+		require.Eventually(t, queued(i+1), time.Second, time.Millisecond)
+	}
+
+	wg.Add(1)
+	go wait("ns/small")
+	require.Eventually(t, queued(5), time.Second, time.Millisecond)
+
+	wg.Wait()
+
+	assert.Equal(t, []string{"ns/large", "ns/small", "ns/large", "ns/large", "ns/large"}, granted)
+}
+
+type countingPacer struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (p *countingPacer) Wait(ctx context.Context, key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, key)
+	return nil
+}
+
+func TestClient_PacesEachAttempt(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	pacer := &countingPacer{}
+	client, err := NewClient(
+		"https://localhost/org/repo",
+		&ActionsAuth{Token: "token"},
+		WithRequestCoordinator(pacer),
+		WithRetryMax(2),
+		WithRetryWaitMax(time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	require.Error(t, err)
+
+	assert.Equal(t, 3, attempts)
+	require.Len(t, pacer.keys, attempts, "each attempt must be paced")
+	assert.Equal(t, client.rateLimitIdentifier(), pacer.keys[0])
+}
+
+func TestRemoteRequestCoordinator(t *testing.T) {
+	t.Run("waits for the coordinator on behalf of the scale set", func(t *testing.T) {
+		coordinator := NewRequestCoordinator(3600, 1)
+		server := httptest.NewServer(coordinator)
+		defer server.Close()
+
+		remote, err := NewRemoteRequestCoordinator(server.URL, "ns/a", logr.Discard())
+		require.NoError(t, err)
+
+		require.NoError(t, remote.Wait(context.Background(), "key"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- remote.Wait(ctx, "key")
+		}()
+
+		require.Eventually(t, func() bool {
+			coordinator.mu.Lock()
+			defer coordinator.mu.Unlock()
+			return len(coordinator.buckets["key"].queues["ns/a"]) == 1
+		}, time.Second, time.Millisecond)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("doesn't wait when the coordinator is unavailable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		remote, err := NewRemoteRequestCoordinator(server.URL, "ns/a", logr.Discard())
+		require.NoError(t, err)
+
+		assert.NoError(t, remote.Wait(context.Background(), "key"))
+	})
+}
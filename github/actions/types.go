@@ -86,6 +86,49 @@ type RunnerGroupList struct {
 	RunnerGroups []RunnerGroup `json:"value"`
 }
 
+// RunnerGroupSettings is a runner group of an organization or an enterprise as returned by the GitHub REST API.
+type RunnerGroupSettings struct {
+	ID                       int64  `json:"id"`
+	Name                     string `json:"name"`
+	Visibility               string `json:"visibility"`
+	Default                  bool   `json:"default"`
+	AllowsPublicRepositories bool   `json:"allows_public_repositories"`
+}
+
+// The visibilities of a runner group, which tell the repositories or the organizations that can use it.
+const (
+	RunnerGroupVisibilityAll      = "all"
+	RunnerGroupVisibilitySelected = "selected"
+	RunnerGroupVisibilityPrivate  = "private"
+)
+
+type runnerGroupSettingsList struct {
+	TotalCount   int                   `json:"total_count"`
+	RunnerGroups []RunnerGroupSettings `json:"runner_groups"`
+}
+
+// runnerGroupRequest is the body of the requests creating and updating runner groups.
+type runnerGroupRequest struct {
+	Name                     string `json:"name"`
+	Visibility               string `json:"visibility,omitempty"`
+	AllowsPublicRepositories bool   `json:"allows_public_repositories"`
+}
+
+func newRunnerGroupRequest(runnerGroup *RunnerGroupSettings) *runnerGroupRequest {
+	return &runnerGroupRequest{
+		Name:                     runnerGroup.Name,
+		Visibility:               runnerGroup.Visibility,
+		AllowsPublicRepositories: runnerGroup.AllowsPublicRepositories,
+	}
+}
+
+type runnerGroupRepositoryList struct {
+	TotalCount   int `json:"total_count"`
+	Repositories []struct {
+		Name string `json:"name"`
+	} `json:"repositories"`
+}
+
 type RunnerScaleSet struct {
 	Id                 int                      `json:"id,omitempty"`
 	Name               string                   `json:"name,omitempty"`
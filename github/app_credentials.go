@@ -0,0 +1,183 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/metrics"
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"k8s.io/utils/clock"
+)
+
+// AppCredentials is the credentials of one of the GitHub Apps the API calls are spread across.
+// All the apps need to be installed on the same organizations or enterprise, with the same permissions.
+type AppCredentials struct {
+	AppID             int64 `json:"appID"`
+	AppInstallationID int64 `json:"appInstallationID"`
+	// AppPrivateKey is the path of the private key file, or the private key itself.
+	AppPrivateKey string `json:"appPrivateKey"`
+}
+
+// parseAppCredentials parses the JSON array of AppCredentials in v, or in the file at the path v.
+func parseAppCredentials(v string) ([]AppCredentials, error) {
+	data := []byte(v)
+	if !strings.HasPrefix(strings.TrimSpace(v), "[") {
+		var err error
+		data, err = os.ReadFile(v)
+		if err != nil {
+			return nil, fmt.Errorf("reading github app credentials: %w", err)
+		}
+	}
+
+	var creds []AppCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("parsing github app credentials: %w", err)
+	}
+
+	if len(creds) == 0 {
+		return nil, fmt.Errorf("github app credentials must have at least one app")
+	}
+
+	for i, c := range creds {
+		if c.AppID <= 0 || c.AppInstallationID <= 0 || c.AppPrivateKey == "" {
+			return nil, fmt.Errorf("github app credentials at index %d must have appID, appInstallationID and appPrivateKey", i)
+		}
+	}
+
+	return creds, nil
+}
+
+// newAppTransport returns the transport authenticating as the installation of the GitHub App.
+func newAppTransport(base http.RoundTripper, creds AppCredentials, enterpriseURL, url string) (*ghinstallation.Transport, error) {
+	var tr *ghinstallation.Transport
+
+	if _, err := os.Stat(creds.AppPrivateKey); err == nil {
+		tr, err = ghinstallation.NewKeyFromFile(base, creds.AppID, creds.AppInstallationID, creds.AppPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: using private key at %s: %v", creds.AppPrivateKey, err)
+		}
+	} else {
+		tr, err = ghinstallation.New(base, creds.AppID, creds.AppInstallationID, []byte(creds.AppPrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: using private key of size %d (%s...): %v", len(creds.AppPrivateKey), strings.Split(creds.AppPrivateKey, "\n")[0], err)
+		}
+	}
+
+	if len(enterpriseURL) > 0 {
+		githubAPIURL, err := getEnterpriseApiUrl(enterpriseURL)
+		if err != nil {
+			return nil, fmt.Errorf("enterprise url incorrect: %v", err)
+		}
+		tr.BaseURL = githubAPIURL
+	} else if url != "" && tr.BaseURL != url {
+		tr.BaseURL = url
+	}
+
+	return tr, nil
+}
+
+// appRotatingTransport sends each request as the GitHub App with the most requests remaining in its current rate limit window,
+// so that the API calls of a large fleet don't stall once the rate limit of a single app is exhausted.
+// An app whose remaining requests are unknown yet, or whose window has been reset since, is preferred over the others.
+type appRotatingTransport struct {
+	apps []*rotatedApp
+
+	clock clock.PassiveClock
+
+	mu sync.Mutex
+}
+
+type rotatedApp struct {
+	appID          int64
+	installationID int64
+	transport      http.RoundTripper
+
+	// remaining is the number of requests remaining until reset, or -1 when it's unknown.
+	remaining int
+	reset     time.Time
+}
+
+func newAppRotatingTransport(apps []AppCredentials, transports []http.RoundTripper) *appRotatingTransport {
+	t := &appRotatingTransport{clock: clock.RealClock{}}
+
+	for i, app := range apps {
+		t.apps = append(t.apps, &rotatedApp{
+			appID:          app.AppID,
+			installationID: app.AppInstallationID,
+			transport:      transports[i],
+			remaining:      -1,
+		})
+	}
+
+	return t
+}
+
+func (t *appRotatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	app := t.pick()
+
+	res, err := app.transport.RoundTrip(req)
+	if res != nil {
+		t.observe(app, res)
+	}
+
+	return res, err
+}
+
+// pick returns the app with the most requests remaining, and counts the request against it
+// so that concurrent requests are spread across the apps before their responses arrive.
+// When every app is exhausted, it returns the one whose window is reset first.
+func (t *appRotatingTransport) pick() *rotatedApp {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+
+	var picked *rotatedApp
+	var pickedRemaining int
+
+	for _, app := range t.apps {
+		remaining := app.remaining
+		if remaining < 0 || !now.Before(app.reset) {
+			remaining = math.MaxInt
+		}
+
+		if picked == nil ||
+			remaining > pickedRemaining ||
+			(remaining == 0 && pickedRemaining == 0 && app.reset.Before(picked.reset)) {
+			picked = app
+			pickedRemaining = remaining
+		}
+	}
+
+	if picked.remaining > 0 && now.Before(picked.reset) {
+		picked.remaining--
+	}
+
+	return picked
+}
+
+func (t *appRotatingTransport) observe(app *rotatedApp, res *http.Response) {
+	remaining, err := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	app.remaining = remaining
+	app.reset = time.Unix(reset, 0)
+	t.mu.Unlock()
+
+	metrics.SetAppRateLimitRemaining(app.appID, app.installationID, remaining)
+}
@@ -0,0 +1,134 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	testclock "k8s.io/utils/clock/testing"
+)
+
+type rateLimitedTransport struct {
+	remaining int
+	reset     time.Time
+	calls     int
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	if t.remaining > 0 {
+		t.remaining--
+	}
+
+	header := http.Header{}
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(t.remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(t.reset.Unix(), 10))
+
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+}
+
+func TestAppRotatingTransport(t *testing.T) {
+	clock := testclock.NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	now := clock.Now()
+
+	first := &rateLimitedTransport{remaining: 3, reset: now.Add(time.Hour)}
+	second := &rateLimitedTransport{remaining: 10, reset: now.Add(30 * time.Minute)}
+
+	tr := newAppRotatingTransport(
+		[]AppCredentials{{AppID: 1, AppInstallationID: 1}, {AppID: 2, AppInstallationID: 2}},
+		[]http.RoundTripper{first, second},
+	)
+	tr.clock = clock
+
+	roundTrip := func() {
+		t.Helper()
+
+		req, _ := http.NewRequest("GET", "https://api.github.com/", nil)
+		if _, err := tr.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Both are unknown at first, then the second has more remaining
+	for i := 0; i < 8; i++ {
+		roundTrip()
+	}
+
+	if first.calls != 1 || second.calls != 7 {
+		t.Errorf("unexpected calls: first=%d second=%d", first.calls, second.calls)
+	}
+
+	// The second now has 3 remaining and the first 2
+	roundTrip()
+
+	if second.calls != 8 {
+		t.Errorf("expected the app with the most remaining to be used: first=%d second=%d", first.calls, second.calls)
+	}
+
+	// Exhaust both
+	for first.remaining > 0 || second.remaining > 0 {
+		roundTrip()
+	}
+
+	calls := second.calls
+	roundTrip()
+
+	if second.calls != calls+1 {
+		t.Errorf("expected the app whose window is reset first to be used when every app is exhausted: first=%d second=%d", first.calls, second.calls)
+	}
+
+	// The window of the first app is reset
+	clock.Step(time.Hour)
+
+	calls = first.calls
+	roundTrip()
+
+	if first.calls != calls+1 {
+		t.Errorf("expected the app whose window was reset to be used: first=%d second=%d", first.calls, second.calls)
+	}
+}
+
+func TestParseAppCredentials(t *testing.T) {
+	const creds = `[{"appID": 1, "appInstallationID": 2, "appPrivateKey": "/path/to/key.pem"}]`
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "json", value: creds},
+		{name: "file", value: path},
+		{name: "missing file", value: filepath.Join(t.TempDir(), "missing.json"), wantErr: true},
+		{name: "empty", value: `[]`, wantErr: true},
+		{name: "missing private key", value: `[{"appID": 1, "appInstallationID": 2}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAppCredentials(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if fmt.Sprint(got) != "[{1 2 /path/to/key.pem}]" {
+				t.Errorf("unexpected credentials: %v", got)
+			}
+		})
+	}
+}
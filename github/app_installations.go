@@ -0,0 +1,264 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// appInstallationsTTL is how long the installations of a GitHub App are reused for, once listed.
+	appInstallationsTTL = 10 * time.Minute
+
+	// appInstallationsMissRefreshInterval is the minimum interval between the refreshes of the listed installations
+	// made to find the installation on an owner missing from them, like an organization the app was just installed on.
+	appInstallationsMissRefreshInterval = time.Minute
+)
+
+type installationOwnerContextKey struct{}
+
+// withInstallationOwner returns the context of the API calls on the resources of the owner that can't be told from their paths, like GraphQL queries,
+// so that they are sent as the installation of the GitHub App on the owner.
+func withInstallationOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, installationOwnerContextKey{}, owner)
+}
+
+// appInstallationTransport sends each request as the installation of the GitHub App on the enterprise, organization or user
+// that owns the resource of the request, so that an app installed on multiple organizations doesn't need an installation ID
+// per organization. The installation ID is resolved from the installations of the app, which are listed once and cached.
+type appInstallationTransport struct {
+	apps *ghinstallation.AppsTransport
+
+	// apiURL is the base URL of the REST API, without the trailing slash.
+	apiURL string
+	// apiPath is the path prefix of the REST API, like /api/v3/ on GitHub Enterprise Server.
+	apiPath string
+
+	clock clock.PassiveClock
+
+	// mu is held while listing the installations, so that concurrent requests wait for a single list
+	mu            sync.Mutex
+	fetchedAt     time.Time
+	installations map[string]int64
+	transports    map[int64]*ghinstallation.Transport
+}
+
+func newAppInstallationTransport(base http.RoundTripper, creds AppCredentials, enterpriseURL, apiURL string) (*appInstallationTransport, error) {
+	var (
+		apps *ghinstallation.AppsTransport
+		err  error
+	)
+
+	if _, statErr := os.Stat(creds.AppPrivateKey); statErr == nil {
+		apps, err = ghinstallation.NewAppsTransportKeyFromFile(base, creds.AppID, creds.AppPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: using private key at %s: %v", creds.AppPrivateKey, err)
+		}
+	} else {
+		apps, err = ghinstallation.NewAppsTransport(base, creds.AppID, []byte(creds.AppPrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: using private key of size %d (%s...): %v", len(creds.AppPrivateKey), strings.Split(creds.AppPrivateKey, "\n")[0], err)
+		}
+	}
+
+	if len(enterpriseURL) > 0 {
+		apiURL, err = getEnterpriseApiUrl(enterpriseURL)
+		if err != nil {
+			return nil, fmt.Errorf("enterprise url incorrect: %v", err)
+		}
+	} else if apiURL == "" {
+		apiURL = apps.BaseURL
+	}
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	apps.BaseURL = apiURL
+
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("github api url incorrect: %v", err)
+	}
+
+	return &appInstallationTransport{
+		apps:          apps,
+		apiURL:        apiURL,
+		apiPath:       strings.TrimSuffix(u.Path, "/") + "/",
+		clock:         clock.RealClock{},
+		installations: map[string]int64{},
+		transports:    map[int64]*ghinstallation.Transport{},
+	}, nil
+}
+
+func (t *appInstallationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	owner, ok := req.Context().Value(installationOwnerContextKey{}).(string)
+	if !ok {
+		owner = installationOwnerFromPath(strings.TrimPrefix(req.URL.Path, t.apiPath))
+	}
+
+	tr, err := t.transport(req.Context(), owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.RoundTrip(req)
+}
+
+// installationOwnerFromPath returns the key of the owner of the resource at the path of the REST API,
+// which is the login of the organization or the user, or the enterprise prefixed with "enterprises/".
+// It returns an empty string when the path isn't under an owner, like /rate_limit.
+func installationOwnerFromPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 || segments[1] == "" {
+		return ""
+	}
+
+	switch segments[0] {
+	case "repos", "orgs", "users":
+		return strings.ToLower(segments[1])
+	case "enterprises":
+		return "enterprises/" + strings.ToLower(segments[1])
+	default:
+		return ""
+	}
+}
+
+// transport returns the transport of the installation of the app on the owner.
+// A request that isn't under an owner is sent as the only installation of the app, if any.
+func (t *appInstallationTransport) transport(ctx context.Context, owner string) (*ghinstallation.Transport, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.refresh(ctx, appInstallationsTTL); err != nil {
+		return nil, err
+	}
+
+	id, err := t.installationID(owner)
+	if err != nil {
+		if refreshErr := t.refresh(ctx, appInstallationsMissRefreshInterval); refreshErr != nil {
+			return nil, refreshErr
+		}
+
+		id, err = t.installationID(owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tr, ok := t.transports[id]
+	if !ok {
+		tr = ghinstallation.NewFromAppsTransport(t.apps, id)
+		t.transports[id] = tr
+	}
+
+	return tr, nil
+}
+
+// installationID returns the ID of the installation of the app on the owner from the listed installations.
+// The caller must hold the lock.
+func (t *appInstallationTransport) installationID(owner string) (int64, error) {
+	if owner != "" {
+		id, ok := t.installations[owner]
+		if !ok {
+			return 0, fmt.Errorf("github app %d is not installed on %s. Install the app on it, or set the installation ID of the app to the credentials", t.apps.AppID(), owner)
+		}
+
+		return id, nil
+	}
+
+	ids := map[int64]struct{}{}
+	for _, id := range t.installations {
+		ids[id] = struct{}{}
+	}
+
+	if len(ids) != 1 {
+		return 0, fmt.Errorf("github app %d has %d installations, and the installation to use can't be determined for a request that isn't about an enterprise, organization or repository. Set the installation ID of the app to the credentials", t.apps.AppID(), len(ids))
+	}
+
+	for id := range ids {
+		return id, nil
+	}
+
+	return 0, nil
+}
+
+// refresh lists the installations of the app when they were listed before maxAge, or never.
+// The caller must hold the lock.
+func (t *appInstallationTransport) refresh(ctx context.Context, maxAge time.Duration) error {
+	now := t.clock.Now()
+	if !t.fetchedAt.IsZero() && now.Sub(t.fetchedAt) < maxAge {
+		return nil
+	}
+
+	installations, err := t.listInstallations(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.fetchedAt = now
+	t.installations = installations
+
+	return nil
+}
+
+type appInstallation struct {
+	ID      int64 `json:"id"`
+	Account struct {
+		// Login is the login of the organization or the user the app is installed on
+		Login string `json:"login"`
+		// Slug is the slug of the enterprise the app is installed on
+		Slug string `json:"slug"`
+	} `json:"account"`
+	TargetType string `json:"target_type"`
+}
+
+// listInstallations lists all the installations of the app, keyed like installationOwnerFromPath.
+func (t *appInstallationTransport) listInstallations(ctx context.Context) (map[string]int64, error) {
+	client := &http.Client{Transport: t.apps}
+	installations := map[string]int64{}
+
+	for page := 1; ; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/app/installations?per_page=100&page=%d", t.apiURL, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("listing installations of github app %d: %w", t.apps.AppID(), err)
+		}
+
+		var list []appInstallation
+		err = json.NewDecoder(res.Body).Decode(&list)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("listing installations of github app %d: unexpected status: %d", t.apps.AppID(), res.StatusCode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing installations of github app %d: %w", t.apps.AppID(), err)
+		}
+
+		for _, inst := range list {
+			if inst.TargetType == "Enterprise" {
+				slug := inst.Account.Slug
+				if slug == "" {
+					slug = inst.Account.Login
+				}
+				installations["enterprises/"+strings.ToLower(slug)] = inst.ID
+			} else {
+				installations[strings.ToLower(inst.Account.Login)] = inst.ID
+			}
+		}
+
+		if len(list) < 100 {
+			return installations, nil
+		}
+	}
+}
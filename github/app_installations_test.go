@@ -0,0 +1,150 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	testclock "k8s.io/utils/clock/testing"
+)
+
+func TestInstallationOwnerFromPath(t *testing.T) {
+	tests := map[string]string{
+		"repos/My-Org/my-repo/actions/runners":       "my-org",
+		"orgs/my-org/actions/runners":                "my-org",
+		"users/my-user":                              "my-user",
+		"enterprises/My-Enterprise/actions/runners":  "enterprises/my-enterprise",
+		"/orgs/my-org/actions/runner-groups?page=2/": "my-org",
+		"rate_limit":  "",
+		"orgs":        "",
+		"app/hook":    "",
+		"graphql":     "",
+		"repos//test": "",
+	}
+
+	for path, want := range tests {
+		if got := installationOwnerFromPath(path); got != want {
+			t.Errorf("installationOwnerFromPath(%q): got %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestAppInstallationTransport(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	installations := `[
+		{"id": 11, "account": {"login": "Org-A"}, "target_type": "Organization"},
+		{"id": 12, "account": {"login": "org-b"}, "target_type": "Organization"}
+	]`
+	var listed int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/app/installations", func(w http.ResponseWriter, r *http.Request) {
+		listed++
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Errorf("unexpected authorization to list installations: %q", r.Header.Get("Authorization"))
+		}
+		fmt.Fprint(w, installations)
+	})
+	for _, id := range []int{11, 12, 13} {
+		id := id
+		mux.HandleFunc(fmt.Sprintf("/api/v3/app/installations/%d/access_tokens", id), func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token": "token-%d", "expires_at": %q}`, id, time.Now().Add(time.Hour).Format(time.RFC3339))
+		})
+	}
+	mux.HandleFunc("/api/v3/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tr, err := newAppInstallationTransport(http.DefaultTransport, AppCredentials{AppID: 1, AppPrivateKey: privateKey}, server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := testclock.NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	tr.clock = clock
+
+	client := &http.Client{Transport: tr}
+
+	get := func(ctx context.Context, path string) (string, error) {
+		t.Helper()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v3/"+path, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+
+		// The installation token is sent as "token <token>"
+		var scheme, token string
+		if _, err := fmt.Fscan(res.Body, &scheme, &token); err != nil {
+			return "", err
+		}
+		return token, nil
+	}
+
+	auth := func(path string) string {
+		t.Helper()
+
+		got, err := get(context.Background(), path)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", path, err)
+		}
+		return got
+	}
+
+	// Each owner is sent as its own installation, from a single list of the installations
+	if got := auth("repos/org-a/my-repo/actions/runners"); got != "token-11" {
+		t.Errorf("unexpected token: %q", got)
+	}
+	if got := auth("orgs/org-b/actions/runners"); got != "token-12" {
+		t.Errorf("unexpected token: %q", got)
+	}
+	if got, err := get(withInstallationOwner(context.Background(), "org-b"), "graphql"); err != nil || got != "token-12" {
+		t.Errorf("unexpected token: %q, %v", got, err)
+	}
+	if listed != 1 {
+		t.Errorf("unexpected number of lists: %d, want 1", listed)
+	}
+
+	// A request that isn't about an owner is ambiguous with multiple installations
+	if _, err := get(context.Background(), "rate_limit"); err == nil || !strings.Contains(err.Error(), "has 2 installations") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A missing installation is listed again, but not more often than appInstallationsMissRefreshInterval
+	installations = `[{"id": 13, "account": {"login": "org-c"}, "target_type": "Organization"}]`
+
+	if _, err := get(context.Background(), "orgs/org-c/actions/runners"); err == nil || !strings.Contains(err.Error(), "github app 1 is not installed on org-c") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	clock.Step(appInstallationsMissRefreshInterval)
+
+	if got := auth("orgs/org-c/actions/runners"); got != "token-13" {
+		t.Errorf("unexpected token: %q", got)
+	}
+	if got := auth("rate_limit"); got != "token-13" {
+		t.Errorf("unexpected token: %q", got)
+	}
+	if listed != 2 {
+		t.Errorf("unexpected number of lists: %d, want 2", listed)
+	}
+}
@@ -0,0 +1,264 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/metrics"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultCircuitBreakerOpenDuration is how long the circuit breaker stays open by default before it lets a probe request through.
+	DefaultCircuitBreakerOpenDuration = 30 * time.Second
+	// DefaultRetryBaseDelay is the base of the exponential backoff between the retries of a failed API call by default.
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+
+	// retryMaxDelay caps the backoff between the retries, so that a retried call doesn't block a reconciler for long.
+	retryMaxDelay = 10 * time.Second
+)
+
+// CircuitBreakerConfig configures the retries of the API calls failed with 5xx responses or network errors,
+// and the circuit breaker that stops sending the API calls after consecutive failures.
+type CircuitBreakerConfig struct {
+	// Threshold is the number of consecutive failures that opens the circuit breaker. The breaker is disabled when it's 0.
+	Threshold int `split_words:"true"`
+	// OpenDuration is how long the breaker stays open before letting a probe request through. Defaults to DefaultCircuitBreakerOpenDuration.
+	OpenDuration time.Duration `split_words:"true"`
+	// MaxRetries is the number of times a failed GET or HEAD request is retried. The requests aren't retried when it's 0.
+	MaxRetries int `split_words:"true"`
+	// RetryBaseDelay is the base of the jittered exponential backoff between the retries. Defaults to DefaultRetryBaseDelay.
+	RetryBaseDelay time.Duration `split_words:"true"`
+}
+
+// CircuitOpenError is the error of the API calls that the client didn't send, as its circuit breaker is open after consecutive failures of GitHub.
+type CircuitOpenError struct {
+	// Until is the time the circuit breaker lets a probe request through.
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for the GitHub API is open until %s after consecutive server errors or timeouts, not making remote request", e.Until.Format(time.RFC3339))
+}
+
+// RetryAfterCircuitOpen returns the duration to wait for before retrying, when the error is due to the open circuit breaker.
+func RetryAfterCircuitOpen(err error) (time.Duration, bool) {
+	var openErr *CircuitOpenError
+	if errors.As(err, &openErr) {
+		return max(time.Until(openErr.Until), 0), true
+	}
+
+	return 0, false
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreakerTransport retries the safe API calls that failed with 5xx responses or network errors, with jittered exponential backoff,
+// and stops sending requests once the number of consecutive failures reaches the threshold,
+// so that a GitHub outage makes the reconcilers fail fast instead of piling up retries.
+//
+// The open breaker lets a single probe request through after OpenDuration. It's closed when the probe succeeds, and opened again otherwise.
+type circuitBreakerTransport struct {
+	Transport http.RoundTripper
+
+	// FailureThreshold is the number of consecutive failures that opens the breaker. The breaker is disabled when it's 0.
+	FailureThreshold int
+	// OpenDuration is how long the breaker stays open before letting a probe through.
+	OpenDuration time.Duration
+	// MaxRetries is the number of times a failed GET or HEAD request is retried.
+	MaxRetries int
+	// RetryBaseDelay is the base of the exponential backoff between the retries.
+	RetryBaseDelay time.Duration
+
+	// clock is optional. It defaults to the wall clock.
+	clock clock.Clock
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	until    time.Time
+	probing  bool
+}
+
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.allow(); err != nil {
+		return nil, err
+	}
+
+	retryable := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	for attempt := 0; ; attempt++ {
+		res, err := t.Transport.RoundTrip(req)
+
+		var notSent *SecondaryRateLimitError
+		if errors.As(err, &notSent) {
+			// The request didn't reach GitHub, so it tells nothing about its health
+			t.release()
+			return res, err
+		}
+
+		failed := isServerFailure(res, err)
+		t.record(failed)
+
+		if !failed || !retryable || attempt >= t.MaxRetries || req.Context().Err() != nil {
+			return res, err
+		}
+
+		if res != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			res.Body.Close()
+		}
+
+		if err := t.wait(req.Context(), t.retryDelay(attempt)); err != nil {
+			return nil, err
+		}
+
+		metrics.IncGitHubAPIRetries()
+
+		if err := t.allow(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// allow returns an error when the breaker is open, or when it's half-open with the probe in flight.
+func (t *circuitBreakerTransport) allow() error {
+	if t.FailureThreshold <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch t.state {
+	case circuitOpen:
+		if t.getClock().Now().Before(t.until) {
+			return &CircuitOpenError{Until: t.until}
+		}
+
+		t.transition(circuitHalfOpen)
+		t.probing = true
+	case circuitHalfOpen:
+		if t.probing {
+			return &CircuitOpenError{Until: t.until}
+		}
+
+		t.probing = true
+	}
+
+	return nil
+}
+
+func (t *circuitBreakerTransport) record(failed bool) {
+	if t.FailureThreshold <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.probing = false
+
+	if !failed {
+		t.failures = 0
+		t.transition(circuitClosed)
+		return
+	}
+
+	t.failures++
+
+	if t.state == circuitHalfOpen || t.failures >= t.FailureThreshold {
+		openDuration := t.OpenDuration
+		if openDuration <= 0 {
+			openDuration = DefaultCircuitBreakerOpenDuration
+		}
+
+		t.until = t.getClock().Now().Add(openDuration)
+		t.transition(circuitOpen)
+	}
+}
+
+// release lets another probe through when the probe wasn't sent.
+func (t *circuitBreakerTransport) release() {
+	t.mu.Lock()
+	t.probing = false
+	t.mu.Unlock()
+}
+
+func (t *circuitBreakerTransport) transition(to circuitState) {
+	if t.state == to {
+		return
+	}
+
+	metrics.SetGitHubCircuitBreakerState(t.state.String(), to.String())
+
+	t.state = to
+}
+
+// retryDelay returns the delay before the retry after the attempt, picked randomly up to the exponential backoff.
+func (t *circuitBreakerTransport) retryDelay(attempt int) time.Duration {
+	base := t.RetryBaseDelay
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+
+	backoff := retryMaxDelay
+	if attempt < 16 {
+		backoff = min(base<<attempt, retryMaxDelay)
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+func (t *circuitBreakerTransport) wait(ctx context.Context, d time.Duration) error {
+	timer := t.getClock().NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+func (t *circuitBreakerTransport) getClock() clock.Clock {
+	if t.clock != nil {
+		return t.clock
+	}
+	return clock.RealClock{}
+}
+
+// isServerFailure returns whether the API call failed due to GitHub, rather than the request or the client.
+func isServerFailure(res *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+	}
+
+	return res.StatusCode >= 500
+}
@@ -0,0 +1,172 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"k8s.io/utils/clock"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+func TestCircuitBreakerTransport(t *testing.T) {
+	clock := testclock.NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	stub := &stubTransport{
+		responses: []*http.Response{
+			newStubResponse(http.StatusInternalServerError, nil, ""),
+			newStubResponse(http.StatusNotFound, nil, ""),
+			newStubResponse(http.StatusBadGateway, nil, ""),
+			newStubResponse(http.StatusServiceUnavailable, nil, ""),
+			newStubResponse(http.StatusServiceUnavailable, nil, ""),
+			newStubResponse(http.StatusOK, nil, "{}"),
+			newStubResponse(http.StatusOK, nil, "{}"),
+		},
+	}
+
+	tr := &circuitBreakerTransport{
+		Transport:        stub,
+		FailureThreshold: 2,
+		OpenDuration:     time.Minute,
+		clock:            clock,
+	}
+
+	roundTrip := func(wantStatus int, wantOpenUntil time.Time) {
+		t.Helper()
+
+		req, _ := http.NewRequest("GET", "https://api.github.com/", nil)
+		res, err := tr.RoundTrip(req)
+		if wantOpenUntil.IsZero() {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.StatusCode != wantStatus {
+				t.Errorf("unexpected status: %d, want %d", res.StatusCode, wantStatus)
+			}
+			res.Body.Close()
+			return
+		}
+
+		var openErr *CircuitOpenError
+		if !errors.As(err, &openErr) {
+			t.Fatalf("expected a circuit open error, got %v", err)
+		}
+
+		if !openErr.Until.Equal(wantOpenUntil) {
+			t.Errorf("unexpected open circuit: until %s, want %s", openErr.Until, wantOpenUntil)
+		}
+	}
+
+	// A response from GitHub other than 5xx resets the consecutive failures
+	roundTrip(http.StatusInternalServerError, time.Time{})
+	roundTrip(http.StatusNotFound, time.Time{})
+
+	// Opens after the consecutive failures reach the threshold
+	roundTrip(http.StatusBadGateway, time.Time{})
+	roundTrip(http.StatusServiceUnavailable, time.Time{})
+	roundTrip(0, clock.Now().Add(time.Minute))
+
+	// The failed probe opens it again
+	clock.Step(time.Minute)
+	roundTrip(http.StatusServiceUnavailable, time.Time{})
+	roundTrip(0, clock.Now().Add(time.Minute))
+
+	// The successful probe closes it
+	clock.Step(time.Minute)
+	roundTrip(http.StatusOK, time.Time{})
+	roundTrip(http.StatusOK, time.Time{})
+
+	if stub.calls != len(stub.responses) {
+		t.Errorf("unexpected number of requests sent: %d, want %d", stub.calls, len(stub.responses))
+	}
+}
+
+// firingClock fires its timers right away, recording their durations.
+type firingClock struct {
+	*testclock.FakeClock
+
+	delays []time.Duration
+}
+
+func (c *firingClock) NewTimer(d time.Duration) clock.Timer {
+	c.delays = append(c.delays, d)
+
+	timer := c.FakeClock.NewTimer(d)
+	c.FakeClock.Step(d)
+
+	return timer
+}
+
+func TestCircuitBreakerTransport_Retries(t *testing.T) {
+	var clock *firingClock
+
+	newTransport := func(responses ...*http.Response) (*circuitBreakerTransport, *stubTransport) {
+		clock = &firingClock{FakeClock: testclock.NewFakeClock(time.Now())}
+
+		stub := &stubTransport{responses: responses}
+
+		return &circuitBreakerTransport{
+			Transport:      stub,
+			MaxRetries:     2,
+			RetryBaseDelay: time.Second,
+			clock:          clock,
+		}, stub
+	}
+
+	t.Run("retried until success", func(t *testing.T) {
+		tr, stub := newTransport(
+			newStubResponse(http.StatusBadGateway, nil, ""),
+			newStubResponse(http.StatusServiceUnavailable, nil, ""),
+			newStubResponse(http.StatusOK, nil, "{}"),
+		)
+
+		req, _ := http.NewRequest("GET", "https://api.github.com/", nil)
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if res.StatusCode != http.StatusOK || stub.calls != 3 {
+			t.Errorf("unexpected result: status %d after %d calls", res.StatusCode, stub.calls)
+		}
+
+		// The delays are jittered up to the exponential backoff
+		if len(clock.delays) != 2 || clock.delays[0] > time.Second || clock.delays[1] > 2*time.Second {
+			t.Errorf("unexpected delays: %v", clock.delays)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		tr, stub := newTransport(
+			newStubResponse(http.StatusBadGateway, nil, ""),
+			newStubResponse(http.StatusBadGateway, nil, ""),
+			newStubResponse(http.StatusBadGateway, nil, ""),
+		)
+
+		req, _ := http.NewRequest("GET", "https://api.github.com/", nil)
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if res.StatusCode != http.StatusBadGateway || stub.calls != 3 {
+			t.Errorf("unexpected result: status %d after %d calls", res.StatusCode, stub.calls)
+		}
+	})
+
+	t.Run("unsafe method", func(t *testing.T) {
+		tr, stub := newTransport(
+			newStubResponse(http.StatusBadGateway, nil, ""),
+		)
+
+		req, _ := http.NewRequest("POST", "https://api.github.com/", nil)
+		if _, err := tr.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if stub.calls != 1 {
+			t.Errorf("expected no retries, got %d calls", stub.calls)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/pkg/githuburl"
+	"github.com/google/go-github/v52/github"
+)
+
+// newEnterpriseClient returns the client of the GitHub Enterprise at enterpriseURL,
+// along with the URL of its web UI that the runners are registered to.
+//
+// GitHub Enterprise Cloud with data residency, like https://my-tenant.ghe.com, serves its REST API at https://api.my-tenant.ghe.com/
+// and its uploads at https://uploads.my-tenant.ghe.com/ like github.com does.
+// Any other enterprise URL is of GitHub Enterprise Server, which serves them below /api/v3/ and /api/uploads/ of the web host.
+func newEnterpriseClient(enterpriseURL string, httpClient *http.Client) (*github.Client, string, error) {
+	u, err := url.Parse(enterpriseURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if host, ok := githuburl.DataResidencyHost(u.Host); ok {
+		scheme := u.Scheme
+		if scheme == "" {
+			scheme = "https"
+		}
+
+		client := github.NewClient(httpClient)
+		client.BaseURL = &url.URL{Scheme: scheme, Host: "api." + host, Path: "/"}
+		client.UploadURL = &url.URL{Scheme: scheme, Host: "uploads." + host, Path: "/"}
+
+		return client, fmt.Sprintf("%s://%s/", scheme, host), nil
+	}
+
+	client, err := github.NewEnterpriseClient(enterpriseURL, enterpriseURL, httpClient)
+	if err != nil {
+		return nil, "", err
+	}
+
+	githubBaseURL := fmt.Sprintf("%s://%s%s", client.BaseURL.Scheme, client.BaseURL.Host, strings.TrimSuffix(client.BaseURL.Path, "api/v3/"))
+
+	return client, githubBaseURL, nil
+}
@@ -0,0 +1,66 @@
+package github
+
+import "testing"
+
+func TestNewClient_Enterprise(t *testing.T) {
+	tests := []struct {
+		enterpriseURL string
+		baseURL       string
+		uploadURL     string
+		githubBaseURL string
+		apiURL        string
+	}{
+		{
+			enterpriseURL: "https://ghes.example.com",
+			baseURL:       "https://ghes.example.com/api/v3/",
+			uploadURL:     "https://ghes.example.com/api/uploads/",
+			githubBaseURL: "https://ghes.example.com/",
+			apiURL:        "https://ghes.example.com/api/v3",
+		},
+		{
+			enterpriseURL: "https://my-tenant.ghe.com",
+			baseURL:       "https://api.my-tenant.ghe.com/",
+			uploadURL:     "https://uploads.my-tenant.ghe.com/",
+			githubBaseURL: "https://my-tenant.ghe.com/",
+			apiURL:        "https://api.my-tenant.ghe.com",
+		},
+		{
+			enterpriseURL: "https://API.My-Tenant.ghe.com/",
+			baseURL:       "https://api.my-tenant.ghe.com/",
+			uploadURL:     "https://uploads.my-tenant.ghe.com/",
+			githubBaseURL: "https://my-tenant.ghe.com/",
+			apiURL:        "https://api.my-tenant.ghe.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.enterpriseURL, func(t *testing.T) {
+			c := Config{EnterpriseURL: tt.enterpriseURL, Token: "token"}
+			client, err := c.NewClient()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := client.Client.BaseURL.String(); got != tt.baseURL {
+				t.Errorf("unexpected base URL: got %q, want %q", got, tt.baseURL)
+			}
+			if got := client.Client.UploadURL.String(); got != tt.uploadURL {
+				t.Errorf("unexpected upload URL: got %q, want %q", got, tt.uploadURL)
+			}
+			if client.GithubBaseURL != tt.githubBaseURL {
+				t.Errorf("unexpected GitHub base URL: got %q, want %q", client.GithubBaseURL, tt.githubBaseURL)
+			}
+			if !client.IsEnterprise {
+				t.Error("expected an enterprise client")
+			}
+
+			apiURL, err := getEnterpriseApiUrl(tt.enterpriseURL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if apiURL != tt.apiURL {
+				t.Errorf("unexpected API URL: got %q, want %q", apiURL, tt.apiURL)
+			}
+		})
+	}
+}
@@ -13,6 +13,11 @@ import (
 const (
 	RegistrationToken = "fake-registration-token"
 
+	// JITConfig is the encoded just-in-time configuration of the runner registered by the generate-jitconfig routes.
+	JITConfig = "fake-jit-config"
+
+	jitConfigBody = `{"runner": {"id": 3, "name": "test3", "os": "unknown", "status": "offline", "busy": false}, "encoded_jit_config": "` + JITConfig + `"}`
+
 	RunnersListBody = `
 {
   "total_count": 2,
@@ -126,6 +131,24 @@ func NewServer(opts ...Option) *httptest.Server {
 			Body:   "",
 		},
 
+		// For GenerateJITConfig
+		"/repos/test/valid/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusCreated,
+			Body:   jitConfigBody,
+		},
+		"/orgs/test/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusCreated,
+			Body:   jitConfigBody,
+		},
+		"/enterprises/test/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusCreated,
+			Body:   jitConfigBody,
+		},
+		"/orgs/error/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusBadRequest,
+			Body:   "",
+		},
+
 		// For ListRunners
 		"/repos/test/valid/actions/runners": config.FixedResponses.ListRunners,
 		"/repos/test/invalid/actions/runners": &Handler{
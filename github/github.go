@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,7 +13,8 @@ import (
 	"github.com/actions/actions-runner-controller/build"
 	"github.com/actions/actions-runner-controller/github/metrics"
 	"github.com/actions/actions-runner-controller/logging"
-	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/actions/actions-runner-controller/pkg/githubapiproxy"
+	"github.com/actions/actions-runner-controller/pkg/githuburl"
 	"github.com/go-logr/logr"
 	"github.com/google/go-github/v52/github"
 	"github.com/gregjones/httpcache"
@@ -22,16 +23,35 @@ import (
 
 // Config contains configuration for Github client
 type Config struct {
-	EnterpriseURL     string `split_words:"true"`
-	AppID             int64  `split_words:"true"`
+	EnterpriseURL string `split_words:"true"`
+	AppID         int64  `split_words:"true"`
+	// AppInstallationID is the ID of the installation of the GitHub App. When it's omitted, the installation is resolved per API call
+	// from the enterprise, organization or repository of the call, so that an app installed on multiple organizations can be used for all of them.
 	AppInstallationID int64  `split_words:"true"`
 	AppPrivateKey     string `split_words:"true"`
+	// AppCredentials is the JSON array of the credentials of more GitHub Apps, or the path of the file containing it.
+	// The API calls are spread across all the apps, based on the remaining rate limit of each app.
+	AppCredentials    string `split_words:"true"`
 	Token             string
 	URL               string `split_words:"true"`
 	UploadURL         string `split_words:"true"`
 	BasicauthUsername string `split_words:"true"`
 	BasicauthPassword string `split_words:"true"`
 	RunnerGitHubURL   string `split_words:"true"`
+	// APIProxyURL is the https URL of the GitHub API proxy the API calls are sent through, if any.
+	APIProxyURL string `split_words:"true"`
+	// APIProxyCACert is the bundle of the CAs the certificate of the GitHub API proxy is pinned to, or the path of its PEM file.
+	APIProxyCACert string `split_words:"true"`
+	// CircuitBreaker configures the retries of the API calls failed due to GitHub, and the circuit breaker that stops sending them during an outage.
+	CircuitBreaker CircuitBreakerConfig `envconfig:"circuit_breaker"`
+	// TLS configures the CAs trusted for the GitHub API, and the client certificate presented to it.
+	TLS TLSConfig `envconfig:"tls"`
+	// RunnerCacheTTL is how long the runners listed by the client are reused for by ListRunners, GetRunner and IsRunnerBusy.
+	// The runners aren't cached when it's 0.
+	RunnerCacheTTL time.Duration `split_words:"true"`
+	// CredentialsName identifies the credentials in the metrics, like the namespace and the name of the secret they're read from.
+	// Defaults to DefaultCredentialsName.
+	CredentialsName string `ignored:"true"`
 
 	Log *logr.Logger
 }
@@ -40,60 +60,139 @@ type Config struct {
 type Client struct {
 	*github.Client
 	regTokens map[string]*github.RegistrationToken
-	mu        sync.Mutex
+	// restrictedRunnerGroups records when the repository access of each runner group was last ensured,
+	// keyed by the organization, the group and the repositories.
+	restrictedRunnerGroups map[string]time.Time
+	mu                     sync.Mutex
 	// GithubBaseURL to Github without API suffix.
 	GithubBaseURL string
 	IsEnterprise  bool
+	// APIProxyURL is the URL of the GitHub API proxy the client sends the API calls through, if any.
+	APIProxyURL string
+	// APIProxyCACert is the bundle of the CAs the certificate of the GitHub API proxy is pinned to.
+	APIProxyCACert string
+	// CircuitBreaker is the circuit breaker configuration the client was created with.
+	CircuitBreaker CircuitBreakerConfig
+	// TLS is the TLS configuration the client was created with.
+	TLS TLSConfig
+	// RunnerCacheTTL is how long the listed runners are reused for. The runners aren't cached when it's 0.
+	RunnerCacheTTL time.Duration
+	runners        *runnerCache
+	// tokenExpiration is the expiration of the personal access token of the client. It's nil unless the client authenticates with a token.
+	tokenExpiration *tokenExpiration
 }
 
 type BasicAuthTransport struct {
 	Username string
 	Password string
+
+	// Transport sends the requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
 }
 
 func (p BasicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.SetBasicAuth(p.Username, p.Password)
-	return http.DefaultTransport.RoundTrip(req)
+
+	transport := p.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return transport.RoundTrip(req)
 }
 
 // NewClient creates a Github Client
 func (c *Config) NewClient() (*Client, error) {
+	base, err := c.TLS.transport()
+	if err != nil {
+		return nil, fmt.Errorf("github tls config incorrect: %v", err)
+	}
+	if len(c.APIProxyURL) > 0 {
+		// The proxy calls the GitHub API with TLS settings of its own
+		if !c.TLS.IsZero() {
+			return nil, fmt.Errorf("the github tls config can't be used with the github api proxy, which doesn't apply it to its requests to github")
+		}
+		proxyURL, err := url.Parse(c.APIProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("github api proxy url incorrect: %v", err)
+		}
+		caCert, err := c.ReadAPIProxyCACert()
+		if err != nil {
+			return nil, err
+		}
+		caCerts, err := githubapiproxy.CertPool(caCert)
+		if err != nil {
+			return nil, err
+		}
+		base, err = githubapiproxy.NewTransport(proxyURL, caCerts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	credentials := c.CredentialsName
+	if credentials == "" {
+		credentials = DefaultCredentialsName
+	}
+
 	var transport http.RoundTripper
+	var expiration *tokenExpiration
 	if len(c.BasicauthUsername) > 0 && len(c.BasicauthPassword) > 0 {
-		transport = BasicAuthTransport{Username: c.BasicauthUsername, Password: c.BasicauthPassword}
+		transport = BasicAuthTransport{Username: c.BasicauthUsername, Password: c.BasicauthPassword, Transport: base}
 	} else if len(c.Token) > 0 {
-		transport = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.Token})).Transport
+		expiration = &tokenExpiration{}
+		transport = &tokenExpirationTransport{
+			Transport:  &oauth2.Transport{Source: oauth2.ReuseTokenSource(nil, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.Token})), Base: base},
+			name:       credentials,
+			expiration: expiration,
+		}
+	} else if c.AppID > 0 && c.AppInstallationID <= 0 && len(c.AppCredentials) == 0 {
+		// The installation is resolved per request from the enterprise, organization or repository the request is about
+		tr, err := newAppInstallationTransport(base, AppCredentials{AppID: c.AppID, AppPrivateKey: c.AppPrivateKey}, c.EnterpriseURL, c.URL)
+		if err != nil {
+			return nil, err
+		}
+		transport = tr
 	} else {
-		var tr *ghinstallation.Transport
+		apps := []AppCredentials{{AppID: c.AppID, AppInstallationID: c.AppInstallationID, AppPrivateKey: c.AppPrivateKey}}
 
-		if _, err := os.Stat(c.AppPrivateKey); err == nil {
-			tr, err = ghinstallation.NewKeyFromFile(http.DefaultTransport, c.AppID, c.AppInstallationID, c.AppPrivateKey)
+		if len(c.AppCredentials) > 0 {
+			additional, err := parseAppCredentials(c.AppCredentials)
 			if err != nil {
-				return nil, fmt.Errorf("authentication failed: using private key at %s: %v", c.AppPrivateKey, err)
+				return nil, err
 			}
-		} else {
-			tr, err = ghinstallation.New(http.DefaultTransport, c.AppID, c.AppInstallationID, []byte(c.AppPrivateKey))
-			if err != nil {
-				return nil, fmt.Errorf("authentication failed: using private key of size %d (%s...): %v", len(c.AppPrivateKey), strings.Split(c.AppPrivateKey, "\n")[0], err)
+
+			if c.AppID <= 0 && c.AppPrivateKey == "" {
+				apps = nil
 			}
+			apps = append(apps, additional...)
 		}
 
-		if len(c.EnterpriseURL) > 0 {
-			githubAPIURL, err := getEnterpriseApiUrl(c.EnterpriseURL)
+		var transports []http.RoundTripper
+		for _, app := range apps {
+			tr, err := newAppTransport(base, app, c.EnterpriseURL, c.URL)
 			if err != nil {
-				return nil, fmt.Errorf("enterprise url incorrect: %v", err)
+				return nil, err
 			}
-			tr.BaseURL = githubAPIURL
-		} else if c.URL != "" && tr.BaseURL != c.URL {
-			tr.BaseURL = c.URL
+			transports = append(transports, tr)
+		}
+
+		if len(transports) == 1 {
+			transport = transports[0]
+		} else {
+			transport = newAppRotatingTransport(apps, transports)
 		}
-		transport = tr
 	}
 
 	cached := httpcache.NewTransport(httpcache.NewMemoryCache())
-	cached.Transport = transport
+	cached.Transport = &circuitBreakerTransport{
+		Transport:        &secondaryRateLimitTransport{Transport: transport},
+		FailureThreshold: c.CircuitBreaker.Threshold,
+		OpenDuration:     c.CircuitBreaker.OpenDuration,
+		MaxRetries:       c.CircuitBreaker.MaxRetries,
+		RetryBaseDelay:   c.CircuitBreaker.RetryBaseDelay,
+	}
 	loggingTransport := logging.Transport{Transport: cached, Log: c.Log}
-	metricsTransport := metrics.Transport{Transport: loggingTransport}
+	metricsTransport := metrics.Transport{Transport: loggingTransport, Credentials: credentials}
 	httpClient := &http.Client{Transport: metricsTransport}
 
 	metrics.Register()
@@ -104,11 +203,10 @@ func (c *Config) NewClient() (*Client, error) {
 	if len(c.EnterpriseURL) > 0 {
 		var err error
 		isEnterprise = true
-		client, err = github.NewEnterpriseClient(c.EnterpriseURL, c.EnterpriseURL, httpClient)
+		client, githubBaseURL, err = newEnterpriseClient(c.EnterpriseURL, httpClient)
 		if err != nil {
 			return nil, fmt.Errorf("enterprise client creation failed: %v", err)
 		}
-		githubBaseURL = fmt.Sprintf("%s://%s%s", client.BaseURL.Scheme, client.BaseURL.Host, strings.TrimSuffix(client.BaseURL.Path, "api/v3/"))
 	} else {
 		client = github.NewClient(httpClient)
 		githubBaseURL = "https://github.com/"
@@ -143,15 +241,44 @@ func (c *Config) NewClient() (*Client, error) {
 		}
 	}
 	client.UserAgent = "actions-runner-controller/" + build.Version
+
+	var runners *runnerCache
+	if c.RunnerCacheTTL > 0 {
+		runners = newRunnerCache(c.RunnerCacheTTL)
+	}
+
 	return &Client{
-		Client:        client,
-		regTokens:     map[string]*github.RegistrationToken{},
-		mu:            sync.Mutex{},
-		GithubBaseURL: githubBaseURL,
-		IsEnterprise:  isEnterprise,
+		Client:                 client,
+		regTokens:              map[string]*github.RegistrationToken{},
+		restrictedRunnerGroups: map[string]time.Time{},
+		mu:                     sync.Mutex{},
+		GithubBaseURL:          githubBaseURL,
+		IsEnterprise:           isEnterprise,
+		APIProxyURL:            c.APIProxyURL,
+		APIProxyCACert:         c.APIProxyCACert,
+		CircuitBreaker:         c.CircuitBreaker,
+		TLS:                    c.TLS,
+		RunnerCacheTTL:         c.RunnerCacheTTL,
+		runners:                runners,
+		tokenExpiration:        expiration,
 	}, nil
 }
 
+// runnerStartupTimeout is how long a registration token must remain valid for to be handed to a runner.
+//
+// We'd like to allow the runner just starting up to miss the expiration date by a bit.
+// Note that this means that we're going to cache Creation Registraion Token API response longer than the
+// recommended cache duration.
+//
+// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-a-repository
+// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-an-organization
+// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-an-enterprise
+// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#conditional-requests
+//
+// This is currently set to 30 minutes as the result of the discussion took place at the following issue:
+// https://github.com/actions/actions-runner-controller/issues/1295
+const runnerStartupTimeout = 30 * time.Minute
+
 // GetRegistrationToken returns a registration token tied with the name of repository and runner.
 func (c *Client) GetRegistrationToken(ctx context.Context, enterprise, org, repo, name string) (*github.RegistrationToken, error) {
 	c.mu.Lock()
@@ -160,19 +287,6 @@ func (c *Client) GetRegistrationToken(ctx context.Context, enterprise, org, repo
 	key := getRegistrationKey(org, repo, enterprise)
 	rt, ok := c.regTokens[key]
 
-	// We'd like to allow the runner just starting up to miss the expiration date by a bit.
-	// Note that this means that we're going to cache Creation Registraion Token API response longer than the
-	// recommended cache duration.
-	//
-	// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-a-repository
-	// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-an-organization
-	// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-an-enterprise
-	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#conditional-requests
-	//
-	// This is currently set to 30 minutes as the result of the discussion took place at the following issue:
-	// https://github.com/actions/actions-runner-controller/issues/1295
-	runnerStartupTimeout := 30 * time.Minute
-
 	if ok && rt.GetExpiresAt().After(time.Now().Add(runnerStartupTimeout)) {
 		return rt, nil
 	}
@@ -219,10 +333,15 @@ func (c *Client) RemoveRunner(ctx context.Context, enterprise, org, repo string,
 		return fmt.Errorf("unexpected status: %d", res.StatusCode)
 	}
 
+	if c.runners != nil {
+		c.runners.forget(getRegistrationKey(owner, repo, enterprise), runnerID)
+	}
+
 	return nil
 }
 
 // ListRunners returns a list of runners of specified owner/repository name.
+// The runners are reused for RunnerCacheTTL, if any.
 func (c *Client) ListRunners(ctx context.Context, enterprise, org, repo string) ([]*github.Runner, error) {
 	enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
 
@@ -230,6 +349,48 @@ func (c *Client) ListRunners(ctx context.Context, enterprise, org, repo string)
 		return nil, err
 	}
 
+	if c.runners == nil {
+		return c.listAllRunners(ctx, enterprise, owner, repo)
+	}
+
+	return c.runners.list(ctx, getRegistrationKey(owner, repo, enterprise), func(ctx context.Context) ([]*github.Runner, error) {
+		return c.listAllRunners(ctx, enterprise, owner, repo)
+	})
+}
+
+// GetRunner returns the runner of specified owner/repository name with the name, or nil when it's not found.
+// With RunnerCacheTTL, it's looked up in the runners cached for the owner/repository name,
+// which are listed again to find a missing runner at most once per 15 seconds.
+func (c *Client) GetRunner(ctx context.Context, enterprise, org, repo, name string) (*github.Runner, error) {
+	enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
+
+	if err != nil {
+		return nil, err
+	}
+
+	list := func(ctx context.Context) ([]*github.Runner, error) {
+		return c.listAllRunners(ctx, enterprise, owner, repo)
+	}
+
+	if c.runners != nil {
+		return c.runners.get(ctx, getRegistrationKey(owner, repo, enterprise), name, list)
+	}
+
+	runners, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, runner := range runners {
+		if runner.GetName() == name {
+			return runner, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (c *Client) listAllRunners(ctx context.Context, enterprise, owner, repo string) ([]*github.Runner, error) {
 	var runners []*github.Runner
 
 	opts := github.ListOptions{PerPage: 100}
@@ -306,6 +467,134 @@ func (c *Client) ListRunnerGroupRepositoryAccesses(ctx context.Context, org stri
 	return repos, nil
 }
 
+// runnerGroupRestrictionTTL is the duration the repository access of a runner group is trusted to be unchanged
+// after it was ensured, so that the runner group isn't read on every runner registration.
+const runnerGroupRestrictionTTL = 10 * time.Minute
+
+// EnsureRunnerGroupRepositoryAccess restricts the organization runner group to the repositories,
+// creating the group when it's missing, so that the runners registered to it never run the jobs of the other repositories.
+// The repositories are the names of the repositories of the organization, with or without the organization part.
+func (c *Client) EnsureRunnerGroupRepositoryAccess(ctx context.Context, org, group string, repos []string) error {
+	if org == "" || group == "" || len(repos) == 0 {
+		return fmt.Errorf("an organization, a runner group and repositories are required to restrict the runner group")
+	}
+
+	names := make([]string, 0, len(repos))
+	for _, r := range repos {
+		if _, name, ok := strings.Cut(r, "/"); ok {
+			r = name
+		}
+		names = append(names, strings.ToLower(r))
+	}
+	sort.Strings(names)
+
+	key := strings.ToLower(org) + "/" + strings.ToLower(group) + ":" + strings.Join(names, ",")
+
+	c.mu.Lock()
+	ensuredAt, ok := c.restrictedRunnerGroups[key]
+	c.mu.Unlock()
+
+	if ok && time.Since(ensuredAt) < runnerGroupRestrictionTTL {
+		return nil
+	}
+
+	repoIDs := make([]int64, 0, len(names))
+	for _, name := range names {
+		repo, _, err := c.Repositories.Get(ctx, org, name)
+		if err != nil {
+			return fmt.Errorf("failed to get repository %s/%s: %w", org, name, err)
+		}
+		repoIDs = append(repoIDs, repo.GetID())
+	}
+
+	rg, err := c.getOrganizationRunnerGroup(ctx, org, group)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case rg == nil:
+		_, _, err := c.Actions.CreateOrganizationRunnerGroup(ctx, org, github.CreateRunnerGroupRequest{
+			Name:                     github.String(group),
+			Visibility:               github.String("selected"),
+			SelectedRepositoryIDs:    repoIDs,
+			AllowsPublicRepositories: github.Bool(false),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create runner group %s: %w", group, err)
+		}
+	case rg.GetDefault():
+		return fmt.Errorf("the default runner group %s can't be restricted to repositories", group)
+	case rg.GetInherited():
+		return fmt.Errorf("runner group %s is inherited from the enterprise and can't be restricted to repositories by the organization", group)
+	default:
+		if rg.GetVisibility() != "selected" {
+			if _, _, err := c.Actions.UpdateOrganizationRunnerGroup(ctx, org, rg.GetID(), github.UpdateRunnerGroupRequest{Visibility: github.String("selected")}); err != nil {
+				return fmt.Errorf("failed to update visibility of runner group %s: %w", group, err)
+			}
+		}
+
+		current, err := c.ListRunnerGroupRepositoryAccesses(ctx, org, rg.GetID())
+		if err != nil {
+			return err
+		}
+
+		if !sameRepositoryIDs(current, repoIDs) {
+			if _, err := c.Actions.SetRepositoryAccessRunnerGroup(ctx, org, rg.GetID(), github.SetRepoAccessRunnerGroupRequest{SelectedRepositoryIDs: repoIDs}); err != nil {
+				return fmt.Errorf("failed to set repository access of runner group %s: %w", group, err)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.restrictedRunnerGroups[key] = time.Now()
+	c.mu.Unlock()
+
+	return nil
+}
+
+// getOrganizationRunnerGroup returns the runner group of the organization by name, or nil when there's none.
+func (c *Client) getOrganizationRunnerGroup(ctx context.Context, org, group string) (*github.RunnerGroup, error) {
+	opts := github.ListOrgRunnerGroupOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	for {
+		list, res, err := c.Actions.ListOrganizationRunnerGroups(ctx, org, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization runner groups: %w", err)
+		}
+
+		for _, rg := range list.RunnerGroups {
+			if strings.EqualFold(rg.GetName(), group) {
+				return rg, nil
+			}
+		}
+
+		if res.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = res.NextPage
+	}
+}
+
+func sameRepositoryIDs(repos []*github.Repository, ids []int64) bool {
+	if len(repos) != len(ids) {
+		return false
+	}
+
+	want := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+
+	for _, r := range repos {
+		if !want[r.GetID()] {
+			return false
+		}
+	}
+
+	return true
+}
+
 // cleanup removes expired registration tokens.
 func (c *Client) cleanup() {
 	c.mu.Lock()
@@ -397,6 +686,25 @@ func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, re
 	return workflowRuns, nil
 }
 
+// GetWorkflowRunPath returns the path of the workflow file of the workflow run, like `.github/workflows/build.yml`.
+// The path of a run triggered from another ref, like a reusable workflow, is suffixed with the ref, like `@refs/heads/main`.
+func (c *Client) GetWorkflowRunPath(ctx context.Context, owner, repo string, runID int64) (string, error) {
+	req, err := c.Client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/actions/runs/%v", owner, repo, runID), nil)
+	if err != nil {
+		return "", err
+	}
+
+	// The WorkflowRun of go-github doesn't have the path
+	var run struct {
+		Path string `json:"path"`
+	}
+	if _, err := c.Client.Do(ctx, req, &run); err != nil {
+		return "", fmt.Errorf("failed to get workflow run: %w", err)
+	}
+
+	return run.Path, nil
+}
+
 // Validates enterprise, organization and repo arguments. Both are optional, but at least one should be specified
 func getEnterpriseOrganizationAndRepo(enterprise, org, repo string) (string, string, string, error) {
 	if len(repo) > 0 {
@@ -428,6 +736,9 @@ func getEnterpriseApiUrl(baseURL string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if host, ok := githuburl.DataResidencyHost(baseEndpoint.Host); ok {
+		return fmt.Sprintf("%s://api.%s", baseEndpoint.Scheme, host), nil
+	}
 	if !strings.HasSuffix(baseEndpoint.Path, "/") {
 		baseEndpoint.Path += "/"
 	}
@@ -458,19 +769,18 @@ func (e *RunnerOffline) Error() string {
 }
 
 func (r *Client) IsRunnerBusy(ctx context.Context, enterprise, org, repo, name string) (bool, error) {
-	runners, err := r.ListRunners(ctx, enterprise, org, repo)
+	runner, err := r.GetRunner(ctx, enterprise, org, repo, name)
 	if err != nil {
 		return false, err
 	}
 
-	for _, runner := range runners {
-		if runner.GetName() == name {
-			if runner.GetStatus() == "offline" {
-				return runner.GetBusy(), &RunnerOffline{runnerName: name}
-			}
-			return runner.GetBusy(), nil
-		}
+	if runner == nil {
+		return false, &RunnerNotFound{runnerName: name}
+	}
+
+	if runner.GetStatus() == "offline" {
+		return runner.GetBusy(), &RunnerOffline{runnerName: name}
 	}
 
-	return false, &RunnerNotFound{runnerName: name}
+	return runner.GetBusy(), nil
 }
@@ -2,6 +2,10 @@ package github
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
@@ -159,3 +163,194 @@ func TestUserAgent(t *testing.T) {
 		t.Errorf("UserAgent should be set to actions-runner-controller/NA")
 	}
 }
+
+func TestEnsureRunnerGroupRepositoryAccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		groups     string
+		access     string
+		wantCalls  []string
+		wantSelect string
+		err        bool
+	}{
+		{
+			name:       "missing group",
+			groups:     `{"total_count": 0, "runner_groups": []}`,
+			wantCalls:  []string{"POST /orgs/example/actions/runner-groups"},
+			wantSelect: "[1 2]",
+		},
+		{
+			name:       "group of all repositories",
+			groups:     `{"total_count": 1, "runner_groups": [{"id": 3, "name": "linux", "visibility": "all"}]}`,
+			access:     `{"total_count": 0, "repositories": []}`,
+			wantCalls:  []string{"PATCH /orgs/example/actions/runner-groups/3", "PUT /orgs/example/actions/runner-groups/3/repositories"},
+			wantSelect: "[1 2]",
+		},
+		{
+			name:   "restricted group",
+			groups: `{"total_count": 1, "runner_groups": [{"id": 3, "name": "Linux", "visibility": "selected"}]}`,
+			access: `{"total_count": 2, "repositories": [{"id": 2}, {"id": 1}]}`,
+		},
+		{
+			name:   "default group",
+			groups: `{"total_count": 1, "runner_groups": [{"id": 1, "name": "linux", "default": true, "visibility": "all"}]}`,
+			err:    true,
+		},
+		{
+			name:   "inherited group",
+			groups: `{"total_count": 1, "runner_groups": [{"id": 3, "name": "linux", "inherited": true, "visibility": "selected"}]}`,
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			var selected string
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/example/", func(w http.ResponseWriter, r *http.Request) {
+				id := map[string]int{"/repos/example/api": 1, "/repos/example/web": 2}[r.URL.Path]
+				fmt.Fprintf(w, `{"id": %d}`, id)
+			})
+			mux.HandleFunc("/orgs/example/actions/runner-groups", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					fmt.Fprint(w, tt.groups)
+					return
+				}
+				calls = append(calls, r.Method+" "+r.URL.Path)
+				var req github.CreateRunnerGroupRequest
+				_ = json.NewDecoder(r.Body).Decode(&req)
+				selected = fmt.Sprint(req.SelectedRepositoryIDs)
+				fmt.Fprint(w, `{"id": 3}`)
+			})
+			mux.HandleFunc("/orgs/example/actions/runner-groups/3", func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.Method+" "+r.URL.Path)
+				fmt.Fprint(w, `{"id": 3}`)
+			})
+			mux.HandleFunc("/orgs/example/actions/runner-groups/3/repositories", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					fmt.Fprint(w, tt.access)
+					return
+				}
+				calls = append(calls, r.Method+" "+r.URL.Path)
+				var req github.SetRepoAccessRunnerGroupRequest
+				_ = json.NewDecoder(r.Body).Decode(&req)
+				selected = fmt.Sprint(req.SelectedRepositoryIDs)
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			s := httptest.NewServer(mux)
+			defer s.Close()
+
+			client := newTestClient()
+			client.Client.BaseURL, _ = url.Parse(s.URL + "/")
+
+			err := client.EnsureRunnerGroupRepositoryAccess(context.Background(), "example", "linux", []string{"web", "example/API"})
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if fmt.Sprint(calls) != fmt.Sprint(tt.wantCalls) {
+				t.Errorf("unexpected calls: %v, want %v", calls, tt.wantCalls)
+			}
+			if selected != tt.wantSelect {
+				t.Errorf("unexpected selected repositories: %s, want %s", selected, tt.wantSelect)
+			}
+
+			// The restriction is cached
+			calls = nil
+			if err := client.EnsureRunnerGroupRepositoryAccess(context.Background(), "example", "linux", []string{"api", "web"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(calls) != 0 {
+				t.Errorf("unexpected calls after the restriction was ensured: %v", calls)
+			}
+		})
+	}
+}
+
+func TestGenerateJITConfig(t *testing.T) {
+	tests := []struct {
+		enterprise string
+		org        string
+		repo       string
+		err        bool
+	}{
+		{enterprise: "", org: "", repo: "test/valid", err: false},
+		{enterprise: "", org: "test", repo: "", err: false},
+		{enterprise: "test", org: "", repo: "", err: false},
+		{enterprise: "", org: "error", repo: "", err: true},
+	}
+
+	client := newTestClient()
+	for i, tt := range tests {
+		cfg, err := client.GenerateJITConfig(context.Background(), tt.enterprise, tt.org, tt.repo, &JITConfigRequest{Name: "test3"})
+		if tt.err {
+			if err == nil {
+				t.Errorf("[%d] expected an error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+			continue
+		}
+		if cfg.EncodedJITConfig != fake.JITConfig || cfg.Runner.GetID() != 3 {
+			t.Errorf("[%d] unexpected jit config: %+v", i, cfg)
+		}
+	}
+}
+
+func TestGenerateJITConfig_Request(t *testing.T) {
+	var body map[string]any
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/example/actions/runner-groups", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count": 1, "runner_groups": [{"id": 3, "name": "linux"}]}`)
+	})
+	mux.HandleFunc("/orgs/example/actions/runners/generate-jitconfig", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		if body["name"] == "existing" {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"message": "Already exists - A runner with the name existing already exists."}`)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"runner": {"id": 4, "name": "example"}, "encoded_jit_config": "config"}`)
+	})
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	client := newTestClient()
+	client.Client.BaseURL, _ = url.Parse(s.URL + "/")
+
+	_, err := client.GenerateJITConfig(context.Background(), "", "example", "", &JITConfigRequest{
+		Name:       "example",
+		Group:      "Linux",
+		Labels:     []string{"self-hosted", "gpu"},
+		WorkFolder: "/runner/_work",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `map[labels:[self-hosted gpu] name:example runner_group_id:3 work_folder:/runner/_work]`
+	if got := fmt.Sprint(body); got != want {
+		t.Errorf("unexpected request: %s, want %s", got, want)
+	}
+
+	_, err = client.GenerateJITConfig(context.Background(), "", "example", "", &JITConfigRequest{Name: "existing"})
+	if !errors.Is(err, ErrRunnerAlreadyExists) {
+		t.Errorf("unexpected error: %v, want ErrRunnerAlreadyExists", err)
+	}
+}
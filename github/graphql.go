@@ -0,0 +1,250 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/github/metrics"
+	"github.com/google/go-github/v52/github"
+)
+
+const (
+	// graphQLRepositoriesPerQuery is the number of repositories fetched by a single GraphQL query,
+	// which keeps the nodes of a query well below the limit of the GitHub GraphQL API.
+	graphQLRepositoriesPerQuery = 50
+
+	// workflowRunsQueryFragment fetches the check suites of the heads of the branches and the open pull requests
+	// that were updated last, along with the workflow runs they belong to.
+	// The GraphQL API has no connection listing the workflow runs of a repository,
+	// so the total counts are fetched too, to tell the repositories whose runs may be missed.
+	workflowRunsQueryFragment = `
+fragment checkSuites on Commit {
+  checkSuites(last: 20) {
+    totalCount
+    nodes {
+      status
+      workflowRun { databaseId }
+    }
+  }
+}
+
+fragment workflowRuns on Repository {
+  refs(refPrefix: "refs/heads/", first: 25, orderBy: {field: TAG_COMMIT_DATE, direction: DESC}) {
+    totalCount
+    nodes {
+      target { ...checkSuites }
+    }
+  }
+  pullRequests(states: OPEN, first: 25, orderBy: {field: UPDATED_AT, direction: DESC}) {
+    totalCount
+    nodes {
+      commits(last: 1) {
+        nodes {
+          commit { ...checkSuites }
+        }
+      }
+    }
+  }
+}
+`
+)
+
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+type graphQLError struct {
+	Message string   `json:"message"`
+	Path    []string `json:"path"`
+}
+
+type graphQLCheckSuites struct {
+	CheckSuites struct {
+		TotalCount int `json:"totalCount"`
+		Nodes      []struct {
+			Status      string `json:"status"`
+			WorkflowRun *struct {
+				DatabaseID int64 `json:"databaseId"`
+			} `json:"workflowRun"`
+		} `json:"nodes"`
+	} `json:"checkSuites"`
+}
+
+type graphQLWorkflowRunsRepository struct {
+	Refs struct {
+		TotalCount int `json:"totalCount"`
+		Nodes      []struct {
+			Target graphQLCheckSuites `json:"target"`
+		} `json:"nodes"`
+	} `json:"refs"`
+	PullRequests struct {
+		TotalCount int `json:"totalCount"`
+		Nodes      []struct {
+			Commits struct {
+				Nodes []struct {
+					Commit graphQLCheckSuites `json:"commit"`
+				} `json:"nodes"`
+			} `json:"commits"`
+		} `json:"nodes"`
+	} `json:"pullRequests"`
+}
+
+// ListRepositoriesWorkflowRuns returns the queued and in-progress workflow runs of the repositories of the owner,
+// keyed by the names of the repositories, like ListRepositoryWorkflowRuns does for a single repository.
+// It fetches the runs of up to 50 repositories with a single call to the GraphQL API, instead of two or more calls
+// to the REST API per repository.
+// Only the runs of the heads of the 25 branches and the 25 open pull requests that were updated last in each repository
+// can be seen with GraphQL, so the runs of the repositories with more branches, open pull requests or check suites per commit
+// are listed with ListRepositoryWorkflowRuns instead, and counted by the github_graphql_workflow_runs_fallbacks_total metric.
+// Only the IDs and the statuses of the runs found with GraphQL are set.
+func (c *Client) ListRepositoriesWorkflowRuns(ctx context.Context, owner string, repoNames []string) (map[string][]*github.WorkflowRun, error) {
+	workflowRuns := make(map[string][]*github.WorkflowRun, len(repoNames))
+
+	var truncated []string
+
+	for start := 0; start < len(repoNames); start += graphQLRepositoriesPerQuery {
+		end := min(start+graphQLRepositoriesPerQuery, len(repoNames))
+
+		// The owner of a GraphQL query can't be told from its path
+		t, err := c.listRepositoriesWorkflowRuns(withInstallationOwner(ctx, strings.ToLower(owner)), owner, repoNames[start:end], workflowRuns)
+		if err != nil {
+			return nil, err
+		}
+		truncated = append(truncated, t...)
+	}
+
+	for _, name := range truncated {
+		metrics.IncGraphQLWorkflowRunsFallbacks()
+
+		runs, err := c.ListRepositoryWorkflowRuns(ctx, owner, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflow runs of %s/%s, which has too many branches, pull requests or check suites for GraphQL: %w", owner, name, err)
+		}
+		workflowRuns[name] = runs
+	}
+
+	return workflowRuns, nil
+}
+
+func (c *Client) listRepositoriesWorkflowRuns(ctx context.Context, owner string, repoNames []string, workflowRuns map[string][]*github.WorkflowRun) ([]string, error) {
+	var truncated []string
+
+	var params, fields strings.Builder
+
+	vars := map[string]any{"owner": owner}
+
+	params.WriteString("$owner: String!")
+	for i, name := range repoNames {
+		fmt.Fprintf(&params, ", $r%d: String!", i)
+		fmt.Fprintf(&fields, "  r%d: repository(owner: $owner, name: $r%d) { ...workflowRuns }\n", i, i)
+		vars[fmt.Sprintf("r%d", i)] = name
+	}
+
+	query := fmt.Sprintf("query(%s) {\n%s}\n%s", params.String(), fields.String(), workflowRunsQueryFragment)
+
+	var res struct {
+		Data   map[string]*graphQLWorkflowRunsRepository `json:"data"`
+		Errors []graphQLError                            `json:"errors"`
+	}
+
+	if err := c.graphQL(ctx, graphQLRequest{Query: query, Variables: vars}, &res); err != nil {
+		return nil, fmt.Errorf("failed to query workflow runs of %d repositories of %s: %w", len(repoNames), owner, err)
+	}
+
+	if len(res.Errors) > 0 {
+		msgs := make([]string, 0, len(res.Errors))
+		for _, e := range res.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return nil, fmt.Errorf("failed to query workflow runs of %d repositories of %s: %s", len(repoNames), owner, strings.Join(msgs, "; "))
+	}
+
+	for i, name := range repoNames {
+		repo := res.Data[fmt.Sprintf("r%d", i)]
+		if repo == nil {
+			return nil, fmt.Errorf("failed to query workflow runs: repository %s/%s not found", owner, name)
+		}
+
+		var suites []graphQLCheckSuites
+		for _, ref := range repo.Refs.Nodes {
+			suites = append(suites, ref.Target)
+		}
+		for _, pr := range repo.PullRequests.Nodes {
+			for _, commit := range pr.Commits.Nodes {
+				suites = append(suites, commit.Commit)
+			}
+		}
+
+		if repo.Refs.TotalCount > len(repo.Refs.Nodes) || repo.PullRequests.TotalCount > len(repo.PullRequests.Nodes) || checkSuitesTruncated(suites) {
+			truncated = append(truncated, name)
+			continue
+		}
+
+		workflowRuns[name] = workflowRunsOfCheckSuites(suites)
+	}
+
+	return truncated, nil
+}
+
+// checkSuitesTruncated returns true when any of the commits has more check suites than fetched.
+func checkSuitesTruncated(suites []graphQLCheckSuites) bool {
+	for _, s := range suites {
+		if s.CheckSuites.TotalCount > len(s.CheckSuites.Nodes) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// workflowRunsOfCheckSuites returns the queued and in-progress workflow runs of the check suites,
+// with the statuses of the REST API. A run is returned once even when its commit is the head of more than one ref.
+func workflowRunsOfCheckSuites(suites []graphQLCheckSuites) []*github.WorkflowRun {
+	var runs []*github.WorkflowRun
+
+	seen := map[int64]bool{}
+
+	for _, s := range suites {
+		for _, suite := range s.CheckSuites.Nodes {
+			if suite.WorkflowRun == nil || seen[suite.WorkflowRun.DatabaseID] {
+				continue
+			}
+
+			var status string
+			switch suite.Status {
+			case "QUEUED":
+				status = "queued"
+			case "IN_PROGRESS":
+				status = "in_progress"
+			default:
+				continue
+			}
+
+			seen[suite.WorkflowRun.DatabaseID] = true
+			runs = append(runs, &github.WorkflowRun{ID: github.Int64(suite.WorkflowRun.DatabaseID), Status: github.String(status)})
+		}
+	}
+
+	return runs
+}
+
+// graphQL sends the query to the GraphQL API of GitHub, which is at /api/graphql on GitHub Enterprise Server
+// instead of below the /api/v3 prefix of the REST API.
+// GitHub Enterprise Cloud with data residency serves both at the root of its API host like github.com.
+func (c *Client) graphQL(ctx context.Context, query graphQLRequest, res any) error {
+	path := "graphql"
+	if c.IsEnterprise && strings.HasSuffix(c.Client.BaseURL.Path, "/api/v3/") {
+		path = "../graphql"
+	}
+
+	req, err := c.Client.NewRequest("POST", path, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Client.Do(ctx, req, res)
+
+	return err
+}
@@ -0,0 +1,205 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestListRepositoriesWorkflowRuns(t *testing.T) {
+	const repository = `{
+  "refs": {"nodes": [
+    {"target": {"checkSuites": {"nodes": [
+      {"status": "QUEUED", "workflowRun": {"databaseId": %[1]d1}},
+      {"status": "COMPLETED", "workflowRun": {"databaseId": %[1]d2}},
+      {"status": "IN_PROGRESS", "workflowRun": null}
+    ]}}}
+  ]},
+  "pullRequests": {"nodes": [
+    {"commits": {"nodes": [{"commit": {"checkSuites": {"nodes": [
+      {"status": "IN_PROGRESS", "workflowRun": {"databaseId": %[1]d3}},
+      {"status": "QUEUED", "workflowRun": {"databaseId": %[1]d1}}
+    ]}}}]}}
+  ]}
+}`
+
+	tests := []struct {
+		name       string
+		enterprise bool
+		path       string
+		repos      int
+		wantCalls  int
+	}{
+		{name: "github.com", path: "/graphql", repos: 2, wantCalls: 1},
+		{name: "enterprise", enterprise: true, path: "/api/graphql", repos: 2, wantCalls: 1},
+		{name: "many repositories", path: "/graphql", repos: 120, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != tt.path {
+					t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				calls++
+
+				var req graphQLRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatalf("invalid request: %v", err)
+				}
+
+				if req.Variables["owner"] != "example" {
+					t.Errorf("unexpected owner: %v", req.Variables["owner"])
+				}
+
+				var data []string
+				for k, v := range req.Variables {
+					if !strings.HasPrefix(k, "r") {
+						continue
+					}
+					var id int
+					fmt.Sscanf(v.(string), "repo%d", &id)
+					data = append(data, fmt.Sprintf("%q: %s", k, fmt.Sprintf(repository, id+1)))
+				}
+
+				fmt.Fprintf(w, `{"data": {%s}}`, strings.Join(data, ","))
+			}))
+			defer s.Close()
+
+			client := newTestClient()
+			client.Client.BaseURL, _ = url.Parse(s.URL + "/")
+			if tt.enterprise {
+				client.IsEnterprise = true
+				client.Client.BaseURL, _ = url.Parse(s.URL + "/api/v3/")
+			}
+
+			var names []string
+			for i := 0; i < tt.repos; i++ {
+				names = append(names, fmt.Sprintf("repo%d", i))
+			}
+
+			runs, err := client.ListRepositoriesWorkflowRuns(context.Background(), "example", names)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("unexpected number of calls: %d, want %d", calls, tt.wantCalls)
+			}
+
+			for i, name := range names {
+				var got []string
+				for _, run := range runs[name] {
+					got = append(got, fmt.Sprintf("%d:%s", run.GetID(), run.GetStatus()))
+				}
+
+				want := fmt.Sprintf("[%[1]d1:queued %[1]d3:in_progress]", i+1)
+				if fmt.Sprint(got) != want {
+					t.Errorf("unexpected runs of %s: %v, want %s", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestListRepositoriesWorkflowRuns_Errors(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {"r0": null}, "errors": [{"type": "NOT_FOUND", "path": ["r0"], "message": "Could not resolve to a Repository with the name 'example/missing'."}]}`)
+	}))
+	defer s.Close()
+
+	client := newTestClient()
+	client.Client.BaseURL, _ = url.Parse(s.URL + "/")
+
+	_, err := client.ListRepositoriesWorkflowRuns(context.Background(), "example", []string{"missing"})
+	if err == nil || !strings.Contains(err.Error(), "example/missing") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestListRepositoriesWorkflowRuns_Fallback(t *testing.T) {
+	const (
+		complete = `{
+  "refs": {"totalCount": 1, "nodes": [
+    {"target": {"checkSuites": {"totalCount": 1, "nodes": [{"status": "QUEUED", "workflowRun": {"databaseId": 1}}]}}}
+  ]},
+  "pullRequests": {"totalCount": 0, "nodes": []}
+}`
+		tooManyBranches = `{
+  "refs": {"totalCount": 30, "nodes": [
+    {"target": {"checkSuites": {"totalCount": 1, "nodes": [{"status": "QUEUED", "workflowRun": {"databaseId": 2}}]}}}
+  ]},
+  "pullRequests": {"totalCount": 0, "nodes": []}
+}`
+		tooManyCheckSuites = `{
+  "refs": {"totalCount": 1, "nodes": [
+    {"target": {"checkSuites": {"totalCount": 21, "nodes": [{"status": "QUEUED", "workflowRun": {"databaseId": 3}}]}}}
+  ]},
+  "pullRequests": {"totalCount": 0, "nodes": []}
+}`
+	)
+
+	var restCalls []string
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/graphql" {
+			fmt.Fprintf(w, `{"data": {"r0": %s, "r1": %s, "r2": %s}}`, complete, tooManyBranches, tooManyCheckSuites)
+			return
+		}
+
+		restCalls = append(restCalls, r.URL.Path+"?"+r.URL.Query().Get("status"))
+
+		switch r.URL.Query().Get("status") {
+		case "queued":
+			fmt.Fprint(w, `{"total_count": 1, "workflow_runs": [{"id": 10, "status": "queued"}]}`)
+		default:
+			fmt.Fprint(w, `{"total_count": 1, "workflow_runs": [{"id": 11, "status": "in_progress"}]}`)
+		}
+	}))
+	defer s.Close()
+
+	client := newTestClient()
+	client.Client.BaseURL, _ = url.Parse(s.URL + "/")
+
+	runs, err := client.ListRepositoriesWorkflowRuns(context.Background(), "example", []string{"complete", "branches", "suites"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := map[string]string{}
+	for name, rs := range runs {
+		var ids []string
+		for _, run := range rs {
+			ids = append(ids, fmt.Sprintf("%d:%s", run.GetID(), run.GetStatus()))
+		}
+		got[name] = fmt.Sprint(ids)
+	}
+
+	want := map[string]string{
+		"complete": "[1:queued]",
+		"branches": "[10:queued 11:in_progress]",
+		"suites":   "[10:queued 11:in_progress]",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("unexpected runs: %v, want %v", got, want)
+	}
+
+	wantRESTCalls := []string{
+		"/repos/example/branches/actions/runs?queued",
+		"/repos/example/branches/actions/runs?in_progress",
+		"/repos/example/suites/actions/runs?queued",
+		"/repos/example/suites/actions/runs?in_progress",
+	}
+	if fmt.Sprint(restCalls) != fmt.Sprint(wantRESTCalls) {
+		t.Errorf("unexpected REST API calls: %v, want %v", restCalls, wantRESTCalls)
+	}
+}
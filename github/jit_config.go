@@ -0,0 +1,160 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v52/github"
+)
+
+// defaultRunnerGroupID is the ID of the Default runner group of every repository, organization and enterprise.
+const defaultRunnerGroupID = 1
+
+// JITConfigRequest is the configuration of the runner to generate a just-in-time configuration for.
+type JITConfigRequest struct {
+	// Name is the name of the runner.
+	Name string
+	// Group is the name of the runner group of the organization or enterprise the runner joins.
+	// The runner joins the Default group when it's empty.
+	Group string
+	// Labels are the labels of the runner, in addition to self-hosted, which is always added.
+	Labels []string
+	// WorkFolder is the working directory of the runner, relative to its root directory or absolute.
+	WorkFolder string
+}
+
+// JITRunnerConfig is the just-in-time configuration of a runner, with which the runner registers itself once, without a registration token.
+type JITRunnerConfig struct {
+	Runner           *github.Runner `json:"runner"`
+	EncodedJITConfig string         `json:"encoded_jit_config"`
+}
+
+// ErrRunnerAlreadyExists is returned by GenerateJITConfig when a runner with the same name already exists.
+var ErrRunnerAlreadyExists = errors.New("a runner with the same name already exists")
+
+// GenerateJITConfig registers an ephemeral runner and returns the just-in-time configuration the runner starts with.
+// Unlike a registration token, the configuration is specific to the runner, and the runner ID is known before the runner starts.
+func (c *Client) GenerateJITConfig(ctx context.Context, enterprise, org, repo string, req *JITConfigRequest) (*JITRunnerConfig, error) {
+	enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	groupID, err := c.runnerGroupID(ctx, enterprise, owner, repo, req.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := []string{"self-hosted"}
+	for _, l := range req.Labels {
+		if l != "" && !strings.EqualFold(l, "self-hosted") {
+			labels = append(labels, l)
+		}
+	}
+
+	body := struct {
+		Name          string   `json:"name"`
+		RunnerGroupID int64    `json:"runner_group_id"`
+		Labels        []string `json:"labels"`
+		WorkFolder    string   `json:"work_folder,omitempty"`
+	}{
+		Name:          req.Name,
+		RunnerGroupID: groupID,
+		Labels:        labels,
+		WorkFolder:    req.WorkFolder,
+	}
+
+	var path string
+	if len(repo) > 0 {
+		path = fmt.Sprintf("repos/%s/%s/actions/runners/generate-jitconfig", owner, repo)
+	} else if len(owner) > 0 {
+		path = fmt.Sprintf("orgs/%s/actions/runners/generate-jitconfig", owner)
+	} else {
+		path = fmt.Sprintf("enterprises/%s/actions/runners/generate-jitconfig", enterprise)
+	}
+
+	r, err := c.Client.NewRequest(http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var jitConfig JITRunnerConfig
+
+	res, err := c.Client.Do(ctx, r, &jitConfig)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusConflict {
+			return nil, fmt.Errorf("failed to generate jit config for runner %s: %w", req.Name, ErrRunnerAlreadyExists)
+		}
+
+		return nil, fmt.Errorf("failed to generate jit config: %w", err)
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
+	}
+
+	return &jitConfig, nil
+}
+
+// runnerGroupID returns the ID of the runner group of the organization or enterprise by name.
+func (c *Client) runnerGroupID(ctx context.Context, enterprise, org, repo, group string) (int64, error) {
+	if group == "" || strings.EqualFold(group, "Default") || len(repo) > 0 {
+		// Repository runners can only join the Default group
+		return defaultRunnerGroupID, nil
+	}
+
+	var (
+		rg  *github.RunnerGroup
+		err error
+	)
+
+	if len(org) > 0 {
+		rg, err = c.getOrganizationRunnerGroup(ctx, org, group)
+	} else {
+		rg, err = c.getEnterpriseRunnerGroup(ctx, enterprise, group)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if rg == nil {
+		return 0, fmt.Errorf("runner group %s not found", group)
+	}
+
+	return rg.GetID(), nil
+}
+
+// getEnterpriseRunnerGroup returns the runner group of the enterprise by name, or nil when there's none.
+func (c *Client) getEnterpriseRunnerGroup(ctx context.Context, enterprise, group string) (*github.RunnerGroup, error) {
+	opts := github.ListOptions{PerPage: 100}
+
+	for {
+		u := fmt.Sprintf("enterprises/%s/actions/runner-groups?per_page=%d&page=%d", enterprise, opts.PerPage, opts.Page)
+
+		r, err := c.Client.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var list github.RunnerGroups
+
+		res, err := c.Client.Do(ctx, r, &list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list enterprise runner groups: %w", err)
+		}
+
+		for _, rg := range list.RunnerGroups {
+			if strings.EqualFold(rg.GetName(), group) {
+				return rg, nil
+			}
+		}
+
+		if res.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = res.NextPage
+	}
+}
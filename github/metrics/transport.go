@@ -7,7 +7,9 @@ package metrics
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -17,7 +19,7 @@ var onceRegister sync.Once
 
 func Register() {
 	onceRegister.Do(func() {
-		metrics.Registry.MustRegister(metricRateLimit, metricRateLimitRemaining)
+		metrics.Registry.MustRegister(metricRateLimit, metricRateLimitRemaining, metricAppRateLimitRemaining, metricSecondaryRateLimits, metricCircuitBreakers, metricRetries, metricTokenExpiresIn, metricRequests, metricRequestDuration, metricGraphQLWorkflowRunsFallbacks)
 	})
 }
 
@@ -35,27 +37,147 @@ var (
 			Help: "The number of requests remaining in the current rate limit window",
 		},
 	)
+	metricAppRateLimitRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_app_rate_limit_remaining",
+			Help: "The number of requests remaining in the current rate limit window of each GitHub App the API calls are spread across",
+		},
+		[]string{"app_id", "installation_id"},
+	)
+	metricSecondaryRateLimits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "github_secondary_rate_limits_total",
+			Help: "The number of responses of secondary rate limits, after which the API calls are backed off",
+		},
+	)
+	metricCircuitBreakers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_circuit_breakers",
+			Help: "The number of GitHub API clients whose circuit breaker is open or half-open, by state",
+		},
+		[]string{"state"},
+	)
+	metricRetries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "github_api_retries_total",
+			Help: "The number of retries of the GitHub API calls that failed with server errors or timeouts",
+		},
+	)
+	metricTokenExpiresIn = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_token_expires_in_seconds",
+			Help: "The seconds until the personal access token expires, as of the last GitHub API response, by the credentials the token is read from",
+		},
+		[]string{"credentials"},
+	)
+	metricRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_api_requests_total",
+			Help: "The number of GitHub API requests, by endpoint template, method, HTTP status and the credentials they're authenticated with",
+		},
+		[]string{"endpoint", "method", "status", "credentials"},
+	)
+	metricGraphQLWorkflowRunsFallbacks = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "github_graphql_workflow_runs_fallbacks_total",
+			Help: "The number of repositories whose workflow runs were listed with the REST API, as the bulk GraphQL query could only see some of their branches, pull requests or check suites",
+		},
+	)
+	metricRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "github_api_request_duration_seconds",
+			Help:    "The latency of the GitHub API requests, by endpoint template, method, HTTP status and the credentials they're authenticated with",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"endpoint", "method", "status", "credentials"},
+	)
 )
 
 const (
 	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
 	headerRateLimit          = "X-RateLimit-Limit"
 	headerRateLimitRemaining = "X-RateLimit-Remaining"
+
+	// headerFromCache is set by httpcache on the responses served from the cache.
+	headerFromCache = "X-From-Cache"
 )
 
 // Transport wraps a transport with metrics monitoring
 type Transport struct {
 	Transport http.RoundTripper
+
+	// Credentials identifies the credentials the requests are authenticated with in the metrics,
+	// so that the requests made with each GitHub App or personal access token can be told apart.
+	Credentials string
 }
 
 func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
 	resp, err := t.Transport.RoundTrip(req)
 	if resp != nil {
 		parseResponse(resp)
 	}
+	t.observe(req, resp, err, time.Since(start))
 	return resp, err
 }
 
+// observe records the request in the request counter and latencies.
+// The responses served from the cache without reaching GitHub aren't recorded, as they don't use up any rate limit.
+func (t Transport) observe(req *http.Request, resp *http.Response, err error, d time.Duration) {
+	status := "error"
+	if err == nil && resp != nil {
+		if resp.Header.Get(headerFromCache) != "" {
+			return
+		}
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	labels := prometheus.Labels{
+		"endpoint":    endpointTemplate(req.URL.Path),
+		"method":      req.Method,
+		"status":      status,
+		"credentials": t.Credentials,
+	}
+	metricRequests.With(labels).Inc()
+	metricRequestDuration.With(labels).Observe(d.Seconds())
+}
+
+// pathParameters maps the path segments followed by the names of an owner to the placeholders of the names.
+// The repositories are named by their owner and name, both of which are replaced.
+var pathParameters = map[string][]string{
+	"repos":       {"{owner}", "{repo}"},
+	"orgs":        {"{org}"},
+	"enterprises": {"{enterprise}"},
+	"users":       {"{user}"},
+	"labels":      {"{name}"},
+}
+
+// endpointTemplate returns the template of the GitHub API endpoint the path is of,
+// with the names of the owners, the repositories and the labels and the numeric IDs replaced by placeholders,
+// like /repos/{owner}/{repo}/actions/runners/{id}, to keep the cardinality of the metrics bounded.
+// The /api/v3 prefix of the GitHub Enterprise Server API is removed so the endpoints of both read the same.
+func endpointTemplate(path string) string {
+	path = strings.TrimPrefix(path, "/api/v3")
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i < len(segments); i++ {
+		if placeholders, ok := pathParameters[segments[i]]; ok {
+			for j := range placeholders {
+				if i+1+j < len(segments) {
+					segments[i+1+j] = placeholders[j]
+				}
+			}
+			i += len(placeholders)
+			continue
+		}
+		if _, err := strconv.ParseInt(segments[i], 10, 64); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
 func parseResponse(resp *http.Response) {
 	rateLimit, err := strconv.Atoi(resp.Header.Get(headerRateLimit))
 	if err == nil {
@@ -66,3 +188,43 @@ func parseResponse(resp *http.Response) {
 		metricRateLimitRemaining.Set(float64(rateLimitRemaining))
 	}
 }
+
+// SetAppRateLimitRemaining records the requests remaining in the current rate limit window of one of the GitHub Apps
+// the API calls are spread across.
+func SetAppRateLimitRemaining(appID, installationID int64, remaining int) {
+	metricAppRateLimitRemaining.With(prometheus.Labels{
+		"app_id":          strconv.FormatInt(appID, 10),
+		"installation_id": strconv.FormatInt(installationID, 10),
+	}).Set(float64(remaining))
+}
+
+// IncSecondaryRateLimits counts a response of a secondary rate limit.
+func IncSecondaryRateLimits() {
+	metricSecondaryRateLimits.Inc()
+}
+
+// SetGitHubCircuitBreakerState records the transition of a circuit breaker of a GitHub API client.
+// Closed breakers aren't counted.
+func SetGitHubCircuitBreakerState(from, to string) {
+	if from != "closed" {
+		metricCircuitBreakers.WithLabelValues(from).Dec()
+	}
+	if to != "closed" {
+		metricCircuitBreakers.WithLabelValues(to).Inc()
+	}
+}
+
+// IncGraphQLWorkflowRunsFallbacks counts a repository whose workflow runs were listed with the REST API instead of GraphQL.
+func IncGraphQLWorkflowRunsFallbacks() {
+	metricGraphQLWorkflowRunsFallbacks.Inc()
+}
+
+// IncGitHubAPIRetries counts a retry of a failed API call.
+func IncGitHubAPIRetries() {
+	metricRetries.Inc()
+}
+
+// SetTokenExpiresIn records how long the personal access token of the credentials remains valid for.
+func SetTokenExpiresIn(credentials string, d time.Duration) {
+	metricTokenExpiresIn.WithLabelValues(credentials).Set(d.Seconds())
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointTemplate(t *testing.T) {
+	tests := map[string]string{
+		"/repos/my-org/my-repo/actions/runners":             "/repos/{owner}/{repo}/actions/runners",
+		"/repos/my-org/my-repo/actions/runners/123":         "/repos/{owner}/{repo}/actions/runners/{id}",
+		"/orgs/my-org/actions/runners/registration-token":   "/orgs/{org}/actions/runners/registration-token",
+		"/orgs/my-org/actions/runner-groups/4/repositories": "/orgs/{org}/actions/runner-groups/{id}/repositories",
+		"/orgs/my-org/actions/runners/5/labels/gpu":         "/orgs/{org}/actions/runners/{id}/labels/{name}",
+		"/enterprises/my-enterprise/actions/runners":        "/enterprises/{enterprise}/actions/runners",
+		"/api/v3/repos/my-org/my-repo/actions/runs/42/jobs": "/repos/{owner}/{repo}/actions/runs/{id}/jobs",
+		"/app/installations/7/access_tokens":                "/app/installations/{id}/access_tokens",
+		"/repos/my-org":                                     "/repos/{owner}",
+		"/":                                                 "/",
+	}
+
+	for path, want := range tests {
+		assert.Equal(t, want, endpointTemplate(path), path)
+	}
+}
@@ -0,0 +1,81 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+)
+
+// RefillRegistrationTokens tops up the pool of registration tokens of the repository, organization or enterprise,
+// and makes GetRegistrationToken return the pooled token that expires last, so that a burst of new runners
+// doesn't wait for the Create Registration Token API.
+//
+// The tokens that won't outlast runnerStartupTimeout after the next refill, which is refillInterval later, are dropped.
+// At most one token is created per call, so that the tokens of a full pool expire one refill interval apart
+// rather than all at once. The returned pool is sorted by the expiration of the tokens.
+func (c *Client) RefillRegistrationTokens(ctx context.Context, enterprise, org, repo string, pool []*github.RegistrationToken, size int, refillInterval time.Duration) ([]*github.RegistrationToken, error) {
+	key := getRegistrationKey(org, repo, enterprise)
+	minExpiry := time.Now().Add(runnerStartupTimeout + refillInterval)
+
+	var tokens []*github.RegistrationToken
+	for _, rt := range pool {
+		if rt.GetToken() != "" && rt.GetExpiresAt().After(minExpiry) {
+			tokens = append(tokens, rt)
+		}
+	}
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].GetExpiresAt().Before(tokens[j].GetExpiresAt().Time)
+	})
+
+	if len(tokens) >= size {
+		c.seedRegistrationToken(key, tokens)
+
+		return tokens[len(tokens)-size:], nil
+	}
+
+	enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
+	if err != nil {
+		return tokens, err
+	}
+
+	rt, res, err := c.createRegistrationToken(ctx, enterprise, owner, repo)
+	if err != nil {
+		c.seedRegistrationToken(key, tokens)
+
+		return tokens, fmt.Errorf("failed to create registration token: %v", err)
+	}
+
+	if res.StatusCode != 201 {
+		c.seedRegistrationToken(key, tokens)
+
+		return tokens, fmt.Errorf("unexpected status: %d", res.StatusCode)
+	}
+
+	tokens = append(tokens, rt)
+
+	c.seedRegistrationToken(key, tokens)
+
+	return tokens, nil
+}
+
+// seedRegistrationToken caches the last token of the sorted tokens for GetRegistrationToken, unless the cached token expires later.
+func (c *Client) seedRegistrationToken(key string, tokens []*github.RegistrationToken) {
+	if len(tokens) == 0 {
+		return
+	}
+
+	rt := tokens[len(tokens)-1]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.regTokens[key]; ok && cached.GetExpiresAt().After(rt.GetExpiresAt().Time) {
+		return
+	}
+
+	c.regTokens[key] = rt
+}
@@ -0,0 +1,101 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+)
+
+func TestRefillRegistrationTokens(t *testing.T) {
+	var created int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/test/actions/runners/registration-token", func(w http.ResponseWriter, r *http.Request) {
+		created++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "token-%d", "expires_at": %q}`, created, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+	mux.HandleFunc("/orgs/error/actions/runners/registration-token", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := Config{Token: "token"}
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	ctx := context.Background()
+	token := func(name string, expiresIn time.Duration) *github.RegistrationToken {
+		return &github.RegistrationToken{
+			Token:     github.String(name),
+			ExpiresAt: &github.Timestamp{Time: time.Now().Add(expiresIn)},
+		}
+	}
+
+	// One token is created per refill until the pool is full
+	pool, err := client.RefillRegistrationTokens(ctx, "", "test", "", nil, 2, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pool) != 1 || pool[0].GetToken() != "token-1" {
+		t.Fatalf("unexpected pool: %v", pool)
+	}
+
+	pool = append(pool, token("pooled", 50*time.Minute))
+
+	pool, err = client.RefillRegistrationTokens(ctx, "", "test", "", pool, 2, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pool) != 2 || pool[0].GetToken() != "pooled" || pool[1].GetToken() != "token-1" || created != 1 {
+		t.Fatalf("unexpected pool: %v, created %d", pool, created)
+	}
+
+	// Tokens that expire before the startup timeout after the next refill are dropped
+	pool = []*github.RegistrationToken{token("expiring", 34*time.Minute), pool[1]}
+
+	pool, err = client.RefillRegistrationTokens(ctx, "", "test", "", pool, 2, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pool) != 2 || pool[0].GetToken() != "token-1" || pool[1].GetToken() != "token-2" {
+		t.Fatalf("unexpected pool: %v", pool)
+	}
+
+	// The pooled token that expires last is returned without calling the API
+	rt, err := client.GetRegistrationToken(ctx, "", "test", "", "runner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt.GetToken() != "token-2" || created != 2 {
+		t.Errorf("unexpected token: %s, created %d", rt.GetToken(), created)
+	}
+
+	// The valid tokens are kept and seeded when the API fails
+	pool, err = client.RefillRegistrationTokens(ctx, "", "error", "", []*github.RegistrationToken{token("pooled", 50*time.Minute)}, 2, 5*time.Minute)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(pool) != 1 || pool[0].GetToken() != "pooled" {
+		t.Fatalf("unexpected pool: %v", pool)
+	}
+
+	rt, err = client.GetRegistrationToken(ctx, "", "error", "", "runner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt.GetToken() != "pooled" {
+		t.Errorf("unexpected token: %s", rt.GetToken())
+	}
+}
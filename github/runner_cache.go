@@ -0,0 +1,141 @@
+package github
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"k8s.io/utils/clock"
+)
+
+// runnerCacheMissRefreshInterval is the minimum interval between the refreshes of the cached runners
+// made to find a runner missing from them, like the one that just registered itself.
+const runnerCacheMissRefreshInterval = 15 * time.Second
+
+// runnerCache keeps the runners of every enterprise, organization and repository listed within its TTL,
+// indexed by name and ID, so that the reconcilers looking up runners one by one share a single ListRunners call per sync period
+// instead of listing all the runners of the scope per lookup.
+type runnerCache struct {
+	ttl time.Duration
+
+	clock clock.PassiveClock
+
+	mu      sync.Mutex
+	entries map[string]*runnerCacheEntry
+}
+
+type runnerCacheEntry struct {
+	// mu is held while listing the runners, so that concurrent lookups of the same scope wait for a single ListRunners call
+	mu        sync.Mutex
+	fetchedAt time.Time
+	runners   []*github.Runner
+	byName    map[string]*github.Runner
+	byID      map[int64]*github.Runner
+}
+
+func newRunnerCache(ttl time.Duration) *runnerCache {
+	return &runnerCache{
+		ttl:     ttl,
+		clock:   clock.RealClock{},
+		entries: map[string]*runnerCacheEntry{},
+	}
+}
+
+func (c *runnerCache) entry(key string) *runnerCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		e = &runnerCacheEntry{}
+		c.entries[key] = e
+	}
+
+	return e
+}
+
+// refresh lists the runners of the entry when they were listed before maxAge, or never.
+// The caller must hold the lock of the entry.
+func (c *runnerCache) refresh(ctx context.Context, e *runnerCacheEntry, maxAge time.Duration, list func(context.Context) ([]*github.Runner, error)) error {
+	now := c.clock.Now()
+	if !e.fetchedAt.IsZero() && now.Sub(e.fetchedAt) < maxAge {
+		return nil
+	}
+
+	runners, err := list(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.fetchedAt = now
+	e.runners = runners
+	e.byName = make(map[string]*github.Runner, len(runners))
+	e.byID = make(map[int64]*github.Runner, len(runners))
+	for _, r := range runners {
+		e.byName[r.GetName()] = r
+		e.byID[r.GetID()] = r
+	}
+
+	return nil
+}
+
+func (c *runnerCache) list(ctx context.Context, key string, list func(context.Context) ([]*github.Runner, error)) ([]*github.Runner, error) {
+	e := c.entry(key)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := c.refresh(ctx, e, c.ttl, list); err != nil {
+		return nil, err
+	}
+
+	return e.runners, nil
+}
+
+// get returns the runner named name, or nil when it's not found.
+// A runner missing from the cached runners is looked up again in a fresh list, unless the cache was refreshed recently.
+func (c *runnerCache) get(ctx context.Context, key, name string, list func(context.Context) ([]*github.Runner, error)) (*github.Runner, error) {
+	e := c.entry(key)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := c.refresh(ctx, e, c.ttl, list); err != nil {
+		return nil, err
+	}
+
+	if r, ok := e.byName[name]; ok {
+		return r, nil
+	}
+
+	if err := c.refresh(ctx, e, min(c.ttl, runnerCacheMissRefreshInterval), list); err != nil {
+		return nil, err
+	}
+
+	return e.byName[name], nil
+}
+
+// forget removes the unregistered runner from the cached runners, so that it isn't found until it's listed again.
+func (c *runnerCache) forget(key string, id int64) {
+	e := c.entry(key)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, ok := e.byID[id]
+	if !ok {
+		return
+	}
+
+	delete(e.byID, id)
+	delete(e.byName, r.GetName())
+
+	runners := make([]*github.Runner, 0, len(e.runners))
+	for _, cached := range e.runners {
+		if cached.GetID() != id {
+			runners = append(runners, cached)
+		}
+	}
+	e.runners = runners
+}
@@ -0,0 +1,90 @@
+package github
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+func TestRunnerCache(t *testing.T) {
+	clock := testclock.NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	c := newRunnerCache(time.Minute)
+	c.clock = clock
+
+	runners := []*github.Runner{
+		{ID: github.Int64(1), Name: github.String("runner-1"), Busy: github.Bool(true)},
+		{ID: github.Int64(2), Name: github.String("runner-2")},
+	}
+
+	var calls int
+	list := func(context.Context) ([]*github.Runner, error) {
+		calls++
+		return runners, nil
+	}
+
+	const key = "org=test,repo=,enterprise="
+
+	get := func(name string) *github.Runner {
+		t.Helper()
+
+		r, err := c.get(context.Background(), key, name, list)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return r
+	}
+
+	// The lookups share a single list within the TTL
+	if r := get("runner-1"); !r.GetBusy() {
+		t.Errorf("unexpected runner: %v", r)
+	}
+	if r := get("runner-2"); r.GetID() != 2 {
+		t.Errorf("unexpected runner: %v", r)
+	}
+	if got, err := c.list(context.Background(), key, list); err != nil || len(got) != 2 {
+		t.Errorf("unexpected runners: %v, %v", got, err)
+	}
+	if calls != 1 {
+		t.Errorf("unexpected number of lists: %d, want 1", calls)
+	}
+
+	// A missing runner is listed again, but not more often than runnerCacheMissRefreshInterval
+	runners = append(runners, &github.Runner{ID: github.Int64(3), Name: github.String("runner-3")})
+
+	if r := get("runner-3"); r != nil {
+		t.Errorf("unexpected runner: %v", r)
+	}
+
+	clock.Step(runnerCacheMissRefreshInterval)
+
+	if r := get("runner-3"); r.GetID() != 3 {
+		t.Errorf("unexpected runner: %v", r)
+	}
+	if calls != 2 {
+		t.Errorf("unexpected number of lists: %d, want 2", calls)
+	}
+
+	// An unregistered runner is forgotten until it's listed again
+	c.forget(key, 1)
+
+	if r := get("runner-1"); r != nil {
+		t.Errorf("unexpected runner: %v", r)
+	}
+	if got, _ := c.list(context.Background(), key, list); len(got) != 2 {
+		t.Errorf("unexpected runners: %v", got)
+	}
+
+	// The runners are listed again after the TTL
+	clock.Step(time.Minute)
+
+	if r := get("runner-1"); r.GetID() != 1 {
+		t.Errorf("unexpected runner: %v", r)
+	}
+	if calls != 3 {
+		t.Errorf("unexpected number of lists: %d, want 3", calls)
+	}
+}
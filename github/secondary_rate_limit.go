@@ -0,0 +1,161 @@
+package github
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/metrics"
+	"github.com/google/go-github/v52/github"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// secondaryRateLimitMinBackoff is the duration the client backs off for on the first secondary rate limit without Retry-After,
+	// which is the minimum GitHub asks for.
+	// https://docs.github.com/en/rest/using-the-rest-api/best-practices-for-using-the-rest-api#handle-rate-limit-errors-appropriately
+	secondaryRateLimitMinBackoff = time.Minute
+	// secondaryRateLimitMaxBackoff caps the exponential backoff on the consecutive secondary rate limits.
+	secondaryRateLimitMaxBackoff = 15 * time.Minute
+
+	// secondaryRateLimitMaxBodyBytes is the maximum size of the body of an error response that is looked for the secondary rate limit message.
+	secondaryRateLimitMaxBodyBytes = 64 << 10
+)
+
+// SecondaryRateLimitError is the error of the API calls that the client didn't send, as it's backing off from a secondary rate limit.
+type SecondaryRateLimitError struct {
+	// Until is the time the client resumes sending the API calls.
+	Until time.Time
+}
+
+func (e *SecondaryRateLimitError) Error() string {
+	return fmt.Sprintf("backing off from the GitHub API secondary rate limit until %s, not making remote request", e.Until.Format(time.RFC3339))
+}
+
+// secondaryRateLimitTransport stops sending requests for a while once GitHub responds with a secondary rate limit,
+// so that the reconcilers sharing the client don't get its token temporarily banned by retrying.
+//
+// It waits for the Retry-After of the response when it's set. Otherwise it waits for a minute,
+// doubling the wait on every consecutive secondary rate limit, until a request succeeds.
+type secondaryRateLimitTransport struct {
+	Transport http.RoundTripper
+
+	// clock is optional. It defaults to the wall clock.
+	clock clock.PassiveClock
+
+	mu      sync.Mutex
+	until   time.Time
+	backoff time.Duration
+}
+
+func (t *secondaryRateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	until := t.until
+	t.mu.Unlock()
+
+	if t.getClock().Now().Before(until) {
+		return nil, &SecondaryRateLimitError{Until: until}
+	}
+
+	res, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+
+	limited, retryAfter := isSecondaryRateLimited(res)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !limited {
+		if res.StatusCode < 400 {
+			t.backoff = 0
+		}
+		return res, nil
+	}
+
+	if retryAfter <= 0 {
+		t.backoff = min(max(2*t.backoff, secondaryRateLimitMinBackoff), secondaryRateLimitMaxBackoff)
+		retryAfter = t.backoff
+	}
+
+	if until := t.getClock().Now().Add(retryAfter); until.After(t.until) {
+		t.until = until
+	}
+
+	metrics.IncSecondaryRateLimits()
+
+	return res, nil
+}
+
+func (t *secondaryRateLimitTransport) getClock() clock.PassiveClock {
+	if t.clock != nil {
+		return t.clock
+	}
+	return clock.RealClock{}
+}
+
+// isSecondaryRateLimited returns whether the response is of a secondary rate limit, and the Retry-After of it if any.
+// The body of the response is restored after looking into it.
+func isSecondaryRateLimited(res *http.Response) (bool, time.Duration) {
+	if res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests {
+		return false, 0
+	}
+
+	if res.Header.Get("X-RateLimit-Remaining") == "0" {
+		// It's the primary rate limit, which go-github handles on its own
+		return false, 0
+	}
+
+	var retryAfter time.Duration
+	if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && secs > 0 {
+		retryAfter = time.Duration(secs) * time.Second
+	}
+
+	if retryAfter > 0 || res.StatusCode == http.StatusTooManyRequests {
+		return true, retryAfter
+	}
+
+	if res.Body == nil {
+		return false, 0
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, secondaryRateLimitMaxBodyBytes))
+	res.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), res.Body))
+	if err != nil {
+		return false, 0
+	}
+
+	msg := strings.ToLower(string(body))
+
+	return strings.Contains(msg, "secondary rate limit") || strings.Contains(msg, "abuse"), 0
+}
+
+// RetryAfterRateLimit returns the duration to wait for before retrying, when the error is due to a primary or secondary rate limit of the GitHub API.
+func RetryAfterRateLimit(err error) (time.Duration, bool) {
+	var secondaryErr *SecondaryRateLimitError
+	if errors.As(err, &secondaryErr) {
+		return max(time.Until(secondaryErr.Until), 0), true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if abuseErr.RetryAfter != nil {
+			return *abuseErr.RetryAfter, true
+		}
+		return secondaryRateLimitMinBackoff, true
+	}
+
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return max(time.Until(rateLimitErr.Rate.Reset.Time), 0), true
+	}
+
+	return 0, false
+}
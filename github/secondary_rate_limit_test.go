@@ -0,0 +1,129 @@
+package github
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+type stubTransport struct {
+	responses []*http.Response
+	calls     int
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res := t.responses[t.calls]
+	t.calls++
+	return res, nil
+}
+
+func newStubResponse(status int, header map[string]string, body string) *http.Response {
+	res := &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+	for k, v := range header {
+		res.Header.Set(k, v)
+	}
+	return res
+}
+
+func TestSecondaryRateLimitTransport(t *testing.T) {
+	const secondaryRateLimitBody = `{"message": "You have exceeded a secondary rate limit. Please wait a few minutes before you try again.", "documentation_url": "https://docs.github.com/rest/overview/resources-in-the-rest-api#secondary-rate-limits"}`
+
+	clock := testclock.NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	stub := &stubTransport{
+		responses: []*http.Response{
+			newStubResponse(http.StatusForbidden, map[string]string{"Retry-After": "30"}, secondaryRateLimitBody),
+			newStubResponse(http.StatusForbidden, nil, secondaryRateLimitBody),
+			newStubResponse(http.StatusTooManyRequests, nil, ""),
+			newStubResponse(http.StatusOK, nil, "{}"),
+			newStubResponse(http.StatusForbidden, nil, secondaryRateLimitBody),
+			newStubResponse(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0"}, `{"message": "API rate limit exceeded"}`),
+			newStubResponse(http.StatusOK, nil, "{}"),
+		},
+	}
+
+	tr := &secondaryRateLimitTransport{Transport: stub, clock: clock}
+
+	roundTrip := func(wantBackoffUntil time.Time) {
+		t.Helper()
+
+		req, _ := http.NewRequest("GET", "https://api.github.com/", nil)
+		res, err := tr.RoundTrip(req)
+		if wantBackoffUntil.IsZero() {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			res.Body.Close()
+			return
+		}
+
+		var secondaryErr *SecondaryRateLimitError
+		if !errors.As(err, &secondaryErr) {
+			t.Fatalf("expected a secondary rate limit error, got %v", err)
+		}
+
+		if !secondaryErr.Until.Equal(wantBackoffUntil) {
+			t.Errorf("unexpected backoff: until %s, want %s", secondaryErr.Until, wantBackoffUntil)
+		}
+	}
+
+	// Retry-After is honored
+	roundTrip(time.Time{})
+	roundTrip(clock.Now().Add(30 * time.Second))
+
+	// Backs off for a minute, doubling on every consecutive secondary rate limit
+	clock.Step(30 * time.Second)
+	roundTrip(time.Time{})
+	roundTrip(clock.Now().Add(time.Minute))
+
+	clock.Step(time.Minute)
+	roundTrip(time.Time{})
+	roundTrip(clock.Now().Add(2 * time.Minute))
+
+	// A success resets the backoff
+	clock.Step(2 * time.Minute)
+	roundTrip(time.Time{})
+	roundTrip(time.Time{})
+	roundTrip(clock.Now().Add(time.Minute))
+
+	// The primary rate limit is left to go-github
+	clock.Step(time.Minute)
+	roundTrip(time.Time{})
+	roundTrip(time.Time{})
+
+	if stub.calls != len(stub.responses) {
+		t.Errorf("unexpected number of requests sent: %d, want %d", stub.calls, len(stub.responses))
+	}
+}
+
+func TestRetryAfterRateLimit(t *testing.T) {
+	retryAfter := 30 * time.Second
+
+	tests := []struct {
+		name   string
+		err    error
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "abuse rate limit with retry-after", err: &github.AbuseRateLimitError{RetryAfter: &retryAfter}, want: retryAfter, wantOK: true},
+		{name: "abuse rate limit", err: &github.AbuseRateLimitError{}, want: secondaryRateLimitMinBackoff, wantOK: true},
+		{name: "reset rate limit", err: &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(-time.Minute)}}}, want: 0, wantOK: true},
+		{name: "other error", err: errors.New("not found")},
+		{name: "no error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RetryAfterRateLimit(tt.err)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("unexpected result: %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
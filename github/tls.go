@@ -0,0 +1,112 @@
+package github
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSConfig configures the TLS connections to the GitHub API, like to a GHES instance fronted by a proxy that enforces mTLS.
+// Each of the fields is the path of a PEM file, or the PEM itself.
+type TLSConfig struct {
+	// CACert is the bundle of the CAs trusted for the GitHub API in addition to the system ones.
+	CACert string `split_words:"true"`
+	// ClientCert is the certificate presented to the GitHub API. It requires ClientKey.
+	ClientCert string `split_words:"true"`
+	// ClientKey is the private key of ClientCert.
+	ClientKey string `split_words:"true"`
+}
+
+// IsZero returns true when the TLS connections to the GitHub API use the defaults.
+func (c TLSConfig) IsZero() bool {
+	return c == TLSConfig{}
+}
+
+// clientConfig returns the TLS configuration of the connections to the GitHub API, or nil to use the defaults.
+func (c TLSConfig) clientConfig() (*tls.Config, error) {
+	if c.IsZero() {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+
+	if c.CACert != "" {
+		bundle, err := readPEM(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca cert: %w", err)
+		}
+
+		systemPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get system cert pool: %w", err)
+		}
+
+		pool := systemPool.Clone()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("failed to parse ca cert: no certificate found")
+		}
+		config.RootCAs = pool
+	}
+
+	if c.ClientCert != "" || c.ClientKey != "" {
+		if c.ClientCert == "" || c.ClientKey == "" {
+			return nil, fmt.Errorf("both the client cert and the client key must be provided")
+		}
+
+		certPEM, err := readPEM(c.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client cert: %w", err)
+		}
+
+		keyPEM, err := readPEM(c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client cert: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// transport returns the transport of the connections to the GitHub API, which is http.DefaultTransport unless TLS is configured.
+func (c TLSConfig) transport() (http.RoundTripper, error) {
+	config, err := c.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return http.DefaultTransport, nil
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = config
+	return tr, nil
+}
+
+// ReadAPIProxyCACert returns the bundle of the CAs the certificate of the GitHub API proxy is pinned to.
+func (c *Config) ReadAPIProxyCACert() ([]byte, error) {
+	if c.APIProxyCACert == "" {
+		return nil, fmt.Errorf("the ca cert of the github api proxy is required to verify its certificate")
+	}
+	bundle, err := readPEM(c.APIProxyCACert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the ca cert of the github api proxy: %w", err)
+	}
+	return bundle, nil
+}
+
+// readPEM reads the PEM file at v, or returns v itself when it isn't the path of a file,
+// the same as the private keys of GitHub Apps are read.
+func readPEM(v string) ([]byte, error) {
+	if _, err := os.Stat(v); err == nil {
+		return os.ReadFile(v)
+	}
+	return []byte(v), nil
+}
@@ -0,0 +1,217 @@
+package github
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newClientCertificate(t *testing.T) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "arc"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestNewClient_TLS(t *testing.T) {
+	certPEM, keyPEM := newClientCertificate(t)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(certPEM)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"login":"arc"}`))
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certPath, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		wantErr bool
+	}{
+		{
+			name: "pem",
+			tls:  TLSConfig{CACert: string(caPEM), ClientCert: string(certPEM), ClientKey: string(keyPEM)},
+		},
+		{
+			name: "files",
+			tls:  TLSConfig{CACert: string(caPEM), ClientCert: certPath, ClientKey: keyPath},
+		},
+		{
+			name:    "without client certificate",
+			tls:     TLSConfig{CACert: string(caPEM)},
+			wantErr: true,
+		},
+		{
+			name:    "without ca",
+			tls:     TLSConfig{ClientCert: string(certPEM), ClientKey: string(keyPEM)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{URL: server.URL, Token: "token", TLS: tt.tls}
+			client, err := c.NewClient()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			user, _, err := client.Users.Get(context.Background(), "")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if user.GetLogin() != "arc" {
+				t.Errorf("unexpected login: %q", user.GetLogin())
+			}
+		})
+	}
+}
+
+func TestTLSConfig_Invalid(t *testing.T) {
+	certPEM, _ := newClientCertificate(t)
+
+	tests := []struct {
+		name string
+		tls  TLSConfig
+	}{
+		{
+			name: "client cert without key",
+			tls:  TLSConfig{ClientCert: string(certPEM)},
+		},
+		{
+			name: "ca without certificate",
+			tls:  TLSConfig{CACert: "not a certificate"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{Token: "token", TLS: tt.tls}
+			if _, err := c.NewClient(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestTLSConfig_APIProxy(t *testing.T) {
+	certPEM, keyPEM := newClientCertificate(t)
+
+	c := Config{
+		Token:       "token",
+		TLS:         TLSConfig{ClientCert: string(certPEM), ClientKey: string(keyPEM)},
+		APIProxyURL: "https://arc-github-api-proxy.arc-systems.svc:8443",
+	}
+	if _, err := c.NewClient(); err == nil {
+		t.Fatal("expected an error, as the proxy doesn't apply the TLS config to its requests to GitHub")
+	}
+}
+
+func TestNewClient_APIProxy(t *testing.T) {
+	proxy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api.github.com/user" {
+			t.Errorf("unexpected path: %q", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"login":"arc"}`))
+	}))
+	t.Cleanup(proxy.Close)
+
+	proxyCAPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: proxy.Certificate().Raw})
+	otherCAPEM, _ := newClientCertificate(t)
+
+	c := Config{Token: "token", APIProxyURL: proxy.URL, APIProxyCACert: string(proxyCAPEM)}
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.GetLogin() != "arc" {
+		t.Errorf("unexpected login: %q", user.GetLogin())
+	}
+
+	c.APIProxyCACert = string(otherCAPEM)
+	client, err = c.NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := client.Users.Get(context.Background(), ""); err == nil {
+		t.Fatal("expected an error, as the certificate of the proxy isn't issued by the pinned CA")
+	}
+
+	c.APIProxyCACert = ""
+	if _, err := c.NewClient(); err == nil {
+		t.Fatal("expected an error, as the certificate of the proxy is pinned to its CA")
+	}
+
+	c.APIProxyCACert = string(proxyCAPEM)
+	c.APIProxyURL = "http://arc-github-api-proxy.arc-systems.svc:8080"
+	if _, err := c.NewClient(); err == nil {
+		t.Fatal("expected an error, as the proxy is sent the credentials")
+	}
+}
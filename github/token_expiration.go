@@ -0,0 +1,123 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/metrics"
+)
+
+// headerTokenExpiration is the response header telling when the personal access token the request was authenticated with expires.
+// GitHub sets it on the responses to the requests made with fine-grained tokens, and with classic tokens that have an expiration.
+//
+// https://docs.github.com/en/rest/overview/troubleshooting-the-rest-api#token-expiration
+const headerTokenExpiration = "GitHub-Authentication-Token-Expiration"
+
+// tokenExpirationLayouts are the formats GitHub has used for the token expiration header.
+var tokenExpirationLayouts = []string{
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05 -0700",
+	time.RFC3339,
+}
+
+// DefaultCredentialsName is the name of the credentials of the controller-wide client in the metrics.
+const DefaultCredentialsName = "default"
+
+// tokenExpiration is the expiration of a personal access token as reported by the responses of GitHub.
+type tokenExpiration struct {
+	mu sync.Mutex
+	// known is true once GitHub has responded to an authenticated request, telling whether the token expires or not.
+	known bool
+	at    time.Time
+}
+
+func (e *tokenExpiration) get() (time.Time, bool, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.at, !e.at.IsZero(), e.known
+}
+
+// tokenExpirationTransport records the expiration of the personal access token the requests are authenticated with,
+// so that expiring tokens are caught before the runners stop registering.
+type tokenExpirationTransport struct {
+	Transport http.RoundTripper
+
+	// name identifies the credentials in the metrics.
+	name       string
+	expiration *tokenExpiration
+}
+
+func (t *tokenExpirationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Transport.RoundTrip(req)
+	// GitHub tells the expiration on any response to an authenticated request, including the ones of client errors like 404
+	if err != nil || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode >= 500 {
+		return resp, err
+	}
+
+	at, ok := parseTokenExpiration(resp.Header.Get(headerTokenExpiration))
+
+	t.expiration.mu.Lock()
+	t.expiration.known = true
+	if ok {
+		t.expiration.at = at
+	}
+	t.expiration.mu.Unlock()
+
+	if ok {
+		metrics.SetTokenExpiresIn(t.name, time.Until(at))
+	}
+
+	return resp, err
+}
+
+func parseTokenExpiration(v string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range tokenExpirationLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// TokenExpiration returns when the personal access token of the client expires, as reported by GitHub on the API calls made so far.
+// It returns false when the token doesn't expire, the client authenticates as a GitHub App, or GitHub hasn't told it yet.
+func (c *Client) TokenExpiration() (time.Time, bool) {
+	if c.tokenExpiration == nil {
+		return time.Time{}, false
+	}
+
+	at, ok, _ := c.tokenExpiration.get()
+	return at, ok
+}
+
+// CheckTokenExpiration returns when the personal access token of the client expires.
+// Unless an API call has already told it, it gets the authenticated user to learn it from the response.
+// It returns false when the token doesn't expire, or the client authenticates as a GitHub App.
+func (c *Client) CheckTokenExpiration(ctx context.Context) (time.Time, bool, error) {
+	if c.tokenExpiration == nil {
+		return time.Time{}, false, nil
+	}
+
+	if at, ok, known := c.tokenExpiration.get(); known {
+		return at, ok, nil
+	}
+
+	if _, _, err := c.Users.Get(ctx, ""); err != nil {
+		if at, ok, known := c.tokenExpiration.get(); known {
+			// The token is allowed to authenticate but not to get the user, like a fine-grained token
+			return at, ok, nil
+		}
+		return time.Time{}, false, err
+	}
+
+	at, ok, _ := c.tokenExpiration.get()
+	return at, ok, nil
+}
@@ -0,0 +1,84 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCheckTokenExpiration(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		status  int
+		want    time.Time
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "fine-grained token", header: "2024-06-01 12:00:00 UTC", status: http.StatusOK, want: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), wantOK: true},
+		{name: "offset", header: "2024-06-01 05:00:00 -0700", status: http.StatusOK, want: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), wantOK: true},
+		{name: "not allowed to get the user", header: "2024-06-01 12:00:00 UTC", status: http.StatusForbidden, want: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), wantOK: true},
+		{name: "no expiration", status: http.StatusOK},
+		{name: "bad credentials", status: http.StatusUnauthorized, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if r.URL.Path != "/user" {
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+				if tt.header != "" {
+					w.Header().Set(headerTokenExpiration, tt.header)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{}`))
+			}))
+			defer s.Close()
+
+			c := Config{Token: "token"}
+			client, err := c.NewClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.Client.BaseURL, _ = url.Parse(s.URL + "/")
+
+			if _, ok := client.TokenExpiration(); ok {
+				t.Fatal("expected the expiration to be unknown before any API call")
+			}
+
+			for i := 0; i < 2; i++ {
+				got, ok, err := client.CheckTokenExpiration(context.Background())
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if ok != tt.wantOK || !got.Equal(tt.want) {
+					t.Errorf("unexpected expiration: got %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+				}
+			}
+
+			// The user is got only until GitHub tells whether the token expires
+			wantCalls := 1
+			if tt.wantErr {
+				wantCalls = 2
+			}
+			if calls != wantCalls {
+				t.Errorf("unexpected number of calls: %d, want %d", calls, wantCalls)
+			}
+		})
+	}
+}
+
+func TestCheckTokenExpiration_App(t *testing.T) {
+	client := &Client{}
+
+	if _, ok, err := client.CheckTokenExpiration(context.Background()); ok || err != nil {
+		t.Errorf("unexpected expiration of a client without a token: %v, %v", ok, err)
+	}
+}
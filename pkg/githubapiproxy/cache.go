@@ -0,0 +1,211 @@
+package githubapiproxy
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCachedBodySize is the maximum size of the body of a cached response.
+const maxCachedBodySize = 1 << 20
+
+// varyHeaders are the request headers that the responses of the GitHub API vary by,
+// so that a response is only shared with the requests of the same credentials and media type.
+var varyHeaders = []string{"Authorization", "Accept", "X-GitHub-Api-Version"}
+
+// cacheKey returns the key of the GET request to the upstream URL in the cache.
+func cacheKey(upstream *url.URL, header http.Header) string {
+	h := sha256.New()
+	h.Write([]byte(upstream.String()))
+	for _, name := range varyHeaders {
+		h.Write([]byte{0})
+		h.Write([]byte(header.Get(name)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// credentialsKey identifies the credentials of the Authorization header, without revealing them in logs and metrics.
+func credentialsKey(authorization string) string {
+	if authorization == "" {
+		return "anonymous"
+	}
+
+	sum := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(sum[:6])
+}
+
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+
+	// expires is the time the response needs to be revalidated after, from its max-age.
+	expires time.Time
+}
+
+func newCachedResponse(status int, header http.Header, body []byte, now time.Time) *cachedResponse {
+	res := &cachedResponse{
+		status: status,
+		header: header.Clone(),
+		body:   body,
+	}
+
+	res.expires = now.Add(maxAge(header))
+
+	return res
+}
+
+// cacheable returns true when the response can be revalidated with the upstream.
+func (r *cachedResponse) cacheable() bool {
+	if r.status != http.StatusOK || len(r.body) > maxCachedBodySize {
+		return false
+	}
+
+	if cc := strings.ToLower(r.header.Get("Cache-Control")); strings.Contains(cc, "no-store") {
+		return false
+	}
+
+	return r.header.Get("ETag") != "" || r.header.Get("Last-Modified") != ""
+}
+
+func (r *cachedResponse) fresh(now time.Time) bool {
+	return now.Before(r.expires)
+}
+
+// revalidated returns the response updated with the headers of the 304 Not Modified response of the upstream,
+// like the rate limit and the max-age.
+func (r *cachedResponse) revalidated(header http.Header, now time.Time) *cachedResponse {
+	updated := &cachedResponse{
+		status: r.status,
+		header: r.header.Clone(),
+		body:   r.body,
+	}
+
+	for k, vs := range header {
+		if k == "Content-Length" || k == "Content-Type" {
+			continue
+		}
+		updated.header[k] = append([]string(nil), vs...)
+	}
+
+	updated.expires = now.Add(maxAge(updated.header))
+
+	return updated
+}
+
+// notModified returns true when the conditional request was made with the validator of the response.
+func (r *cachedResponse) notModified(header http.Header) bool {
+	if inm := header.Get("If-None-Match"); inm != "" {
+		etag := r.header.Get("ETag")
+		if etag == "" {
+			return false
+		}
+
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimSpace(t)
+			if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ims := header.Get("If-Modified-Since"); ims != "" {
+		return ims == r.header.Get("Last-Modified")
+	}
+
+	return false
+}
+
+// maxAge returns the max-age of the Cache-Control header, or 0 when the response must be revalidated.
+func maxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(strings.ToLower(directive))
+
+		if directive == "no-cache" {
+			return 0
+		}
+
+		if v, ok := strings.CutPrefix(directive, "max-age="); ok {
+			seconds, err := strconv.Atoi(v)
+			if err != nil || seconds < 0 {
+				return 0
+			}
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	return 0
+}
+
+// responseCache is an LRU cache of responses. A nil cache caches nothing.
+type responseCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key      string
+	response *cachedResponse
+}
+
+func newResponseCache(size int) *responseCache {
+	return &responseCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *responseCache) get(key string) *cachedResponse {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	c.order.MoveToFront(e)
+
+	return e.Value.(*cacheEntry).response
+}
+
+func (c *responseCache) add(key string, res *cachedResponse) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*cacheEntry).response = res
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, response: res})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+
+	setCacheEntries(c.order.Len())
+}
@@ -0,0 +1,72 @@
+package githubapiproxy
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	proxyRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_api_proxy_requests_total",
+			Help: "Number of requests received by the GitHub API proxy, by how they were served.",
+		},
+		[]string{"method", "result"},
+	)
+	proxyUpstreamRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_api_proxy_upstream_requests_total",
+			Help: "Number of requests the GitHub API proxy sent to the upstreams, by status code.",
+		},
+		[]string{"method", "code"},
+	)
+	proxyRateLimit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_api_proxy_rate_limit",
+			Help: "The maximum number of requests the credentials are permitted to make per hour.",
+		},
+		[]string{"credentials", "resource"},
+	)
+	proxyRateLimitRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_api_proxy_rate_limit_remaining",
+			Help: "The number of requests remaining in the current rate limit window of the credentials.",
+		},
+		[]string{"credentials", "resource"},
+	)
+	proxyCacheEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "github_api_proxy_cache_entries",
+			Help: "Number of responses cached by the GitHub API proxy.",
+		},
+	)
+)
+
+// Collectors returns the metrics of the proxy, to be registered by the server running it.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		proxyRequests,
+		proxyUpstreamRequests,
+		proxyRateLimit,
+		proxyRateLimitRemaining,
+		proxyCacheEntries,
+	}
+}
+
+func observeRequest(method, result string) {
+	proxyRequests.WithLabelValues(method, result).Inc()
+}
+
+func observeUpstreamRequest(method string, code int) {
+	proxyUpstreamRequests.WithLabelValues(method, strconv.Itoa(code)).Inc()
+}
+
+func setRateLimit(credentials, resource string, limit, remaining int) {
+	proxyRateLimit.WithLabelValues(credentials, resource).Set(float64(limit))
+	proxyRateLimitRemaining.WithLabelValues(credentials, resource).Set(float64(remaining))
+}
+
+func setCacheEntries(n int) {
+	proxyCacheEntries.Set(float64(n))
+}
@@ -0,0 +1,391 @@
+// Package githubapiproxy implements a proxy for the GitHub REST API shared by the ARC components,
+// so that they share conditional-request caching, rate-limit accounting and request coalescing
+// instead of each spending the rate limit of the same credentials on its own.
+//
+// A request to https://api.github.com/orgs/my-org is sent to the proxy as <proxy>/api.github.com/orgs/my-org,
+// with the credentials of the client. The proxy forwards it to one of the allowed upstreams only.
+//
+// The proxy only serves HTTPS, as it's sent the credentials of its clients, and the clients pin its certificate to its CA.
+package githubapiproxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sync/singleflight"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultUpstream is the upstream allowed when none is configured.
+	DefaultUpstream = "https://api.github.com"
+
+	// DefaultAddr is the address the proxy listens on by default.
+	DefaultAddr = ":8443"
+
+	// DefaultCacheSize is the default maximum number of cached responses.
+	DefaultCacheSize = 10000
+
+	// HeaderCacheResult is the response header telling how the proxy served the request.
+	HeaderCacheResult = "X-Arc-Proxy-Cache"
+
+	// upstreamTimeout is the timeout of a request to the upstream, which isn't canceled by the client
+	// as the response may be shared by coalesced requests.
+	upstreamTimeout = time.Minute
+)
+
+const (
+	// resultHit is a GET request served from a fresh cached response without a request to the upstream.
+	resultHit = "hit"
+	// resultRevalidated is a GET request served from a cached response the upstream responded 304 Not Modified for.
+	resultRevalidated = "revalidated"
+	// resultMiss is a GET request served from a response of the upstream.
+	resultMiss = "miss"
+	// resultCoalesced is a GET request served from the response to an identical request in flight.
+	resultCoalesced = "coalesced"
+	// resultStale is a GET request served from a stale cached response while the rate limit of the credentials is exhausted.
+	resultStale = "stale"
+	// resultRateLimited is a request rejected while the rate limit of the credentials is exhausted.
+	resultRateLimited = "rate_limited"
+	// resultPassthrough is a request other than GET forwarded to the upstream.
+	resultPassthrough = "passthrough"
+	// resultRejected is a request to an upstream that isn't allowed.
+	resultRejected = "rejected"
+	// resultError is a request the upstream couldn't be reached for.
+	resultError = "error"
+)
+
+// Options configures a Proxy.
+type Options struct {
+	// Upstreams are the base URLs of the GitHub APIs the proxy forwards to, like https://api.github.com
+	// or https://ghes.example.com. Defaults to DefaultUpstream.
+	Upstreams []string
+
+	// CacheSize is the maximum number of cached responses. Responses aren't cached when it's negative.
+	// Defaults to DefaultCacheSize.
+	CacheSize int
+
+	// Transport sends the requests to the upstreams. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	Log logr.Logger
+}
+
+// Proxy is an http.Handler forwarding the requests to the GitHub APIs.
+type Proxy struct {
+	upstreams map[string]*url.URL
+	transport http.RoundTripper
+	log       logr.Logger
+
+	cache  *responseCache
+	limits *rateLimits
+	group  singleflight.Group
+
+	clock clock.PassiveClock
+}
+
+// New returns a Proxy configured with opts.
+func New(opts Options) (*Proxy, error) {
+	upstreams := opts.Upstreams
+	if len(upstreams) == 0 {
+		upstreams = []string{DefaultUpstream}
+	}
+
+	p := &Proxy{
+		upstreams: map[string]*url.URL{},
+		transport: opts.Transport,
+		log:       opts.Log,
+		limits:    newRateLimits(),
+		clock:     clock.RealClock{},
+	}
+
+	if p.transport == nil {
+		p.transport = http.DefaultTransport
+	}
+
+	for _, u := range upstreams {
+		parsed, err := url.Parse(strings.TrimSpace(u))
+		if err != nil {
+			return nil, fmt.Errorf("parsing upstream %q: %w", u, err)
+		}
+
+		if (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return nil, fmt.Errorf("upstream %q must be an absolute http or https URL", u)
+		}
+
+		p.upstreams[strings.ToLower(parsed.Host)] = parsed
+	}
+
+	cacheSize := opts.CacheSize
+	if cacheSize == 0 {
+		cacheSize = DefaultCacheSize
+	}
+
+	if cacheSize > 0 {
+		p.cache = newResponseCache(cacheSize)
+	}
+
+	return p, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upstream, ok := p.upstreamURL(r)
+	if !ok {
+		observeRequest(r.Method, resultRejected)
+
+		http.Error(w, "the upstream isn't allowed by the proxy", http.StatusForbidden)
+		return
+	}
+
+	credentials := credentialsKey(r.Header.Get("Authorization"))
+	resource := rateLimitResource(upstream.Path)
+
+	log := p.log.WithValues("method", r.Method, "url", upstream.String(), "credentials", credentials)
+
+	if r.Method != http.MethodGet {
+		p.forward(w, r, log, upstream, credentials, resource)
+		return
+	}
+
+	key := cacheKey(upstream, r.Header)
+
+	cached := p.cache.get(key)
+	if cached != nil && cached.fresh(p.clock.Now()) {
+		p.serve(w, r, cached, resultHit)
+		return
+	}
+
+	if reset, exhausted := p.limits.exhausted(credentials, resource, p.clock.Now()); exhausted {
+		if cached != nil {
+			p.serve(w, r, cached, resultStale)
+			return
+		}
+
+		p.rejectRateLimited(w, r, resource, reset)
+		return
+	}
+
+	v, err, shared := p.group.Do(key, func() (interface{}, error) {
+		return p.fetch(r, upstream, key, credentials)
+	})
+	if err != nil {
+		log.Error(err, "Unable to get the response of the upstream")
+		observeRequest(r.Method, resultError)
+
+		http.Error(w, "unable to get the response of the upstream", http.StatusBadGateway)
+		return
+	}
+
+	res := v.(*fetchResult)
+
+	result := res.result
+	if shared {
+		result = resultCoalesced
+	}
+
+	p.serve(w, r, res.response, result)
+}
+
+// upstreamURL returns the URL of the upstream the request is sent to, from the first segment of its path.
+func (p *Proxy) upstreamURL(r *http.Request) (*url.URL, bool) {
+	host, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+
+	base, ok := p.upstreams[strings.ToLower(host)]
+	if !ok {
+		return nil, false
+	}
+
+	u, err := url.Parse(base.Scheme + "://" + base.Host + "/" + rest)
+	if err != nil {
+		return nil, false
+	}
+
+	u.RawQuery = r.URL.RawQuery
+
+	return u, true
+}
+
+type fetchResult struct {
+	response *cachedResponse
+	result   string
+}
+
+// fetch gets the response of the upstream to the GET request, revalidating the cached response if any.
+func (p *Proxy) fetch(r *http.Request, upstream *url.URL, key, credentials string) (*fetchResult, error) {
+	// The response may be shared with the requests coalesced with this one, so it isn't canceled along with this one
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), upstreamTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	copyRequestHeader(req.Header, r.Header)
+
+	// The client's own validators are replaced by the ones of the shared cache
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+
+	cached := p.cache.get(key)
+	if cached != nil {
+		if etag := cached.header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := p.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	observeUpstreamRequest(req.Method, resp.StatusCode)
+	p.limits.record(credentials, resp.Header)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.clock.Now()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		revalidated := cached.revalidated(resp.Header, now)
+		p.cache.add(key, revalidated)
+
+		return &fetchResult{response: revalidated, result: resultRevalidated}, nil
+	}
+
+	res := newCachedResponse(resp.StatusCode, resp.Header, body, now)
+	if res.cacheable() {
+		p.cache.add(key, res)
+	}
+
+	return &fetchResult{response: res, result: resultMiss}, nil
+}
+
+// forward sends the request other than GET to the upstream as is, and streams the response back.
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request, log logr.Logger, upstream *url.URL, credentials, resource string) {
+	if reset, exhausted := p.limits.exhausted(credentials, resource, p.clock.Now()); exhausted {
+		p.rejectRateLimited(w, r, resource, reset)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, upstream.String(), r.Body)
+	if err != nil {
+		observeRequest(r.Method, resultError)
+
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.ContentLength = r.ContentLength
+	copyRequestHeader(req.Header, r.Header)
+
+	resp, err := p.transport.RoundTrip(req)
+	if err != nil {
+		log.Error(err, "Unable to forward the request to the upstream")
+		observeRequest(r.Method, resultError)
+
+		http.Error(w, "unable to forward the request to the upstream", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	observeUpstreamRequest(req.Method, resp.StatusCode)
+	observeRequest(r.Method, resultPassthrough)
+	p.limits.record(credentials, resp.Header)
+
+	copyResponseHeader(w.Header(), resp.Header)
+	w.Header().Set(HeaderCacheResult, resultPassthrough)
+	w.WriteHeader(resp.StatusCode)
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		log.Error(err, "Unable to copy the response of the upstream")
+	}
+}
+
+// serve writes the response, or 304 Not Modified when the client already has it.
+func (p *Proxy) serve(w http.ResponseWriter, r *http.Request, res *cachedResponse, result string) {
+	observeRequest(r.Method, result)
+
+	copyResponseHeader(w.Header(), res.header)
+	w.Header().Set(HeaderCacheResult, result)
+
+	if res.status == http.StatusOK && res.notModified(r.Header) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Length", fmt.Sprint(len(res.body)))
+	w.WriteHeader(res.status)
+
+	_, _ = io.Copy(w, bytes.NewReader(res.body))
+}
+
+// rejectRateLimited responds like GitHub does to a request whose rate limit is exhausted, without sending it to the upstream.
+func (p *Proxy) rejectRateLimited(w http.ResponseWriter, r *http.Request, resource string, reset time.Time) {
+	observeRequest(r.Method, resultRateLimited)
+
+	retryAfter := int(reset.Sub(p.clock.Now()).Seconds()) + 1
+
+	w.Header().Set(HeaderCacheResult, resultRateLimited)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Retry-After", fmt.Sprint(retryAfter))
+	w.Header().Set(headerRateLimitRemaining, "0")
+	w.Header().Set(headerRateLimitReset, fmt.Sprint(reset.Unix()))
+	w.Header().Set(headerRateLimitResource, resource)
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	fmt.Fprintf(w, `{"message":"API rate limit exceeded for the credentials until %s, according to the GitHub API proxy of actions-runner-controller"}`, reset.UTC().Format(time.RFC3339))
+}
+
+// hopByHopHeaders are the headers that aren't forwarded by proxies.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func copyRequestHeader(dst, src http.Header) {
+	for k, vs := range src {
+		for _, v := range vs {
+			dst.Add(k, v)
+		}
+	}
+
+	for _, h := range hopByHopHeaders {
+		dst.Del(h)
+	}
+
+	// The Go transport negotiates the compression of the upstream response on its own
+	dst.Del("Accept-Encoding")
+}
+
+func copyResponseHeader(dst, src http.Header) {
+	for k, vs := range src {
+		dst[k] = append([]string(nil), vs...)
+	}
+
+	for _, h := range hopByHopHeaders {
+		dst.Del(h)
+	}
+
+	dst.Del("Content-Length")
+}
@@ -0,0 +1,367 @@
+package githubapiproxy
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+func newTestProxy(t *testing.T, upstream http.Handler) (*Proxy, *url.URL) {
+	t.Helper()
+
+	upstreamSrv := httptest.NewServer(upstream)
+	t.Cleanup(upstreamSrv.Close)
+
+	p, err := New(Options{
+		Upstreams: []string{upstreamSrv.URL},
+		Log:       logr.Discard(),
+	})
+	require.NoError(t, err)
+
+	proxySrv := httptest.NewServer(p)
+	t.Cleanup(proxySrv.Close)
+
+	proxyURL, err := url.Parse(proxySrv.URL)
+	require.NoError(t, err)
+
+	upstreamURL, err := url.Parse(upstreamSrv.URL)
+	require.NoError(t, err)
+
+	return p, URL(proxyURL, upstreamURL)
+}
+
+func get(t *testing.T, u string, header http.Header) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	require.NoError(t, err)
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(body)
+}
+
+func TestProxy_RevalidatesCachedResponses(t *testing.T) {
+	var requests, conditionalRequests atomic.Int32
+
+	_, base := newTestProxy(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		assert.Equal(t, "/repos/owner/repo/actions/runners", r.URL.Path)
+		assert.Equal(t, "per_page=100", r.URL.RawQuery)
+		assert.Equal(t, "token abc", r.Header.Get("Authorization"))
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set(headerRateLimitLimit, "5000")
+		w.Header().Set(headerRateLimitRemaining, "4999")
+		w.Header().Set(headerRateLimitReset, fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditionalRequests.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		fmt.Fprint(w, `{"total_count":1}`)
+	}))
+
+	header := http.Header{"Authorization": []string{"token abc"}}
+
+	resp, body := get(t, base.String()+"/repos/owner/repo/actions/runners?per_page=100", header)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, resultMiss, resp.Header.Get(HeaderCacheResult))
+	assert.Equal(t, `{"total_count":1}`, body)
+
+	resp, body = get(t, base.String()+"/repos/owner/repo/actions/runners?per_page=100", header)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, resultRevalidated, resp.Header.Get(HeaderCacheResult))
+	assert.Equal(t, `{"total_count":1}`, body)
+
+	assert.EqualValues(t, 2, requests.Load())
+	assert.EqualValues(t, 1, conditionalRequests.Load())
+
+	// The client's own conditional request is answered from the cache
+	resp, _ = get(t, base.String()+"/repos/owner/repo/actions/runners?per_page=100", http.Header{
+		"Authorization": []string{"token abc"},
+		"If-None-Match": []string{`"v1"`},
+	})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+}
+
+func TestProxy_DoesNotShareResponsesBetweenCredentials(t *testing.T) {
+	var requests atomic.Int32
+
+	_, base := newTestProxy(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.Header.Get("If-None-Match") != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "private, max-age=60")
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+
+	resp, body := get(t, base.String()+"/orgs/org", http.Header{"Authorization": []string{"token a"}})
+	assert.Equal(t, resultMiss, resp.Header.Get(HeaderCacheResult))
+	assert.Equal(t, "token a", body)
+
+	resp, body = get(t, base.String()+"/orgs/org", http.Header{"Authorization": []string{"token a"}})
+	assert.Equal(t, resultHit, resp.Header.Get(HeaderCacheResult))
+	assert.Equal(t, "token a", body)
+
+	resp, body = get(t, base.String()+"/orgs/org", http.Header{"Authorization": []string{"token b"}})
+	assert.Equal(t, resultMiss, resp.Header.Get(HeaderCacheResult))
+	assert.Equal(t, "token b", body)
+
+	assert.EqualValues(t, 2, requests.Load())
+}
+
+func TestProxy_CoalescesIdenticalRequests(t *testing.T) {
+	var requests atomic.Int32
+
+	release := make(chan struct{})
+
+	_, base := newTestProxy(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		<-release
+
+		fmt.Fprint(w, "ok")
+	}))
+
+	const n = 5
+
+	var wg sync.WaitGroup
+	results := make(chan string, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, body := get(t, base.String()+"/orgs/org/actions/runners", nil)
+			assert.Equal(t, "ok", body)
+
+			results <- resp.Header.Get(HeaderCacheResult)
+		}()
+	}
+
+	// Wait for the requests to reach the proxy before the upstream responds
+	require.Eventually(t, func() bool { return requests.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	wg.Wait()
+	close(results)
+
+	counts := map[string]int{}
+	for r := range results {
+		counts[r]++
+	}
+
+	assert.EqualValues(t, counts[resultCoalesced]+counts[resultMiss], n)
+	assert.Less(t, int(requests.Load()), n)
+}
+
+func TestProxy_RateLimitExhausted(t *testing.T) {
+	var requests atomic.Int32
+
+	reset := time.Now().Add(30 * time.Minute)
+
+	p, base := newTestProxy(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set(headerRateLimitLimit, "5000")
+		w.Header().Set(headerRateLimitRemaining, "0")
+		w.Header().Set(headerRateLimitReset, fmt.Sprint(reset.Unix()))
+		w.Header().Set(headerRateLimitResource, "core")
+
+		fmt.Fprint(w, "ok")
+	}))
+
+	header := http.Header{"Authorization": []string{"token abc"}}
+
+	resp, _ := get(t, base.String()+"/orgs/org", header)
+	assert.Equal(t, resultMiss, resp.Header.Get(HeaderCacheResult))
+
+	// The cached response is served as is without spending the exhausted rate limit
+	resp, body := get(t, base.String()+"/orgs/org", header)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, resultStale, resp.Header.Get(HeaderCacheResult))
+	assert.Equal(t, "ok", body)
+
+	resp, _ = get(t, base.String()+"/orgs/org/actions/runners", header)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, resultRateLimited, resp.Header.Get(HeaderCacheResult))
+	assert.Equal(t, "0", resp.Header.Get(headerRateLimitRemaining))
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+	// The other credentials and the other resources have their own rate limits
+	resp, _ = get(t, base.String()+"/orgs/org/actions/runners", http.Header{"Authorization": []string{"token other"}})
+	assert.Equal(t, resultMiss, resp.Header.Get(HeaderCacheResult))
+
+	resp, _ = get(t, base.String()+"/search/issues", header)
+	assert.Equal(t, resultMiss, resp.Header.Get(HeaderCacheResult))
+
+	assert.EqualValues(t, 3, requests.Load())
+
+	// The rate limit is spent again once it's reset
+	p.clock = testclock.NewFakePassiveClock(reset.Add(time.Second))
+
+	resp, _ = get(t, base.String()+"/orgs/org/actions/runners", header)
+	assert.Equal(t, resultMiss, resp.Header.Get(HeaderCacheResult))
+}
+
+func TestProxy_ForwardsOtherMethods(t *testing.T) {
+	_, base := newTestProxy(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/orgs/org/actions/runners/registration-token", r.URL.Path)
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "{}", string(body))
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"token":"t"}`)
+	}))
+
+	req, err := http.NewRequest(http.MethodPost, base.String()+"/orgs/org/actions/runners/registration-token", strings.NewReader("{}"))
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, resultPassthrough, resp.Header.Get(HeaderCacheResult))
+	assert.Equal(t, `{"token":"t"}`, string(body))
+}
+
+func TestProxy_RejectsUnknownUpstreams(t *testing.T) {
+	_, base := newTestProxy(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request to the upstream")
+	}))
+
+	other := *base
+	other.Path = "/evil.example.com/orgs/org"
+
+	resp, _ := get(t, other.String(), nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestTransport(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/orgs/org", r.URL.Path)
+		assert.Equal(t, "page=2", r.URL.RawQuery)
+		fmt.Fprint(w, "ok")
+	}))
+	defer upstream.Close()
+
+	p, err := New(Options{Upstreams: []string{upstream.URL}, Log: logr.Discard()})
+	require.NoError(t, err)
+
+	proxySrv := httptest.NewTLSServer(p)
+	defer proxySrv.Close()
+
+	proxyURL, err := url.Parse(proxySrv.URL)
+	require.NoError(t, err)
+
+	caCerts, err := CertPool(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: proxySrv.Certificate().Raw}))
+	require.NoError(t, err)
+
+	transport, err := NewTransport(proxyURL, caCerts)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(upstream.URL + "/api/v3/orgs/org?page=2")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", string(body))
+}
+
+func TestURL(t *testing.T) {
+	proxy, err := url.Parse("https://arc-github-api-proxy:8443/")
+	require.NoError(t, err)
+
+	u, err := url.Parse("https://api.github.com/repos/owner/repo/actions/runs?status=queued")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://arc-github-api-proxy:8443/api.github.com/repos/owner/repo/actions/runs?status=queued", URL(proxy, u).String())
+}
+
+func TestValidateURL(t *testing.T) {
+	for u, valid := range map[string]bool{
+		"https://arc-github-api-proxy:8443": true,
+		"http://arc-github-api-proxy:8080":  false,
+		"http://127.0.0.1:8090":             false,
+		"socks5://127.0.0.1:8090":           false,
+	} {
+		parsed, err := url.Parse(u)
+		require.NoError(t, err)
+		assert.Equal(t, valid, ValidateURL(parsed) == nil, u)
+	}
+}
+
+func TestTransport_PinsTheCA(t *testing.T) {
+	proxySrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxySrv.Close()
+
+	proxyURL, err := url.Parse(proxySrv.URL)
+	require.NoError(t, err)
+
+	// The certificate of the proxy isn't issued by the pinned CA
+	transport, err := NewTransport(proxyURL, x509.NewCertPool())
+	require.NoError(t, err)
+
+	_, err = (&http.Client{Transport: transport}).Get("https://api.github.com/orgs/org")
+	assert.Error(t, err)
+
+	_, err = CertPool([]byte("not a certificate"))
+	assert.Error(t, err)
+}
+
+func TestRateLimitResource(t *testing.T) {
+	assert.Equal(t, "core", rateLimitResource("/repos/owner/repo"))
+	assert.Equal(t, "core", rateLimitResource("/api/v3/repos/owner/repo"))
+	assert.Equal(t, "search", rateLimitResource("/search/issues"))
+	assert.Equal(t, "search", rateLimitResource("/api/v3/search/code"))
+	assert.Equal(t, "graphql", rateLimitResource("/graphql"))
+	assert.Equal(t, "graphql", rateLimitResource("/api/graphql"))
+}
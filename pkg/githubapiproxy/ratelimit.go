@@ -0,0 +1,94 @@
+package githubapiproxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api#checking-the-status-of-your-rate-limit
+const (
+	headerRateLimitLimit     = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+	headerRateLimitResource  = "X-RateLimit-Resource"
+
+	defaultRateLimitResource = "core"
+)
+
+type rateLimitKey struct {
+	credentials string
+	resource    string
+}
+
+type rateLimit struct {
+	remaining int
+	reset     time.Time
+}
+
+// rateLimits accounts the rate limits of the credentials from the responses of the upstreams,
+// so that the requests made once a rate limit is exhausted aren't sent until it's reset.
+type rateLimits struct {
+	mu     sync.Mutex
+	limits map[rateLimitKey]rateLimit
+}
+
+func newRateLimits() *rateLimits {
+	return &rateLimits{limits: map[rateLimitKey]rateLimit{}}
+}
+
+// record updates the rate limit of the credentials from the headers of a response of the upstream.
+func (l *rateLimits) record(credentials string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get(headerRateLimitRemaining))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(header.Get(headerRateLimitReset), 10, 64)
+	if err != nil {
+		return
+	}
+
+	resource := header.Get(headerRateLimitResource)
+	if resource == "" {
+		resource = defaultRateLimitResource
+	}
+
+	l.mu.Lock()
+	l.limits[rateLimitKey{credentials: credentials, resource: resource}] = rateLimit{remaining: remaining, reset: time.Unix(reset, 0)}
+	l.mu.Unlock()
+
+	if limit, err := strconv.Atoi(header.Get(headerRateLimitLimit)); err == nil {
+		setRateLimit(credentials, resource, limit, remaining)
+	}
+}
+
+// exhausted returns true along with the reset time when the rate limit of the credentials is exhausted.
+func (l *rateLimits) exhausted(credentials, resource string, now time.Time) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.limits[rateLimitKey{credentials: credentials, resource: resource}]
+	if !ok || limit.remaining > 0 || !now.Before(limit.reset) {
+		return time.Time{}, false
+	}
+
+	return limit.reset, true
+}
+
+// rateLimitResource returns the rate limit resource the request to the path counts towards.
+func rateLimitResource(path string) string {
+	// Enterprise Server APIs are served under /api/v3
+	path = strings.TrimPrefix(path, "/api/v3")
+
+	switch {
+	case strings.HasPrefix(path, "/search/"):
+		return "search"
+	case path == "/graphql" || path == "/api/graphql":
+		return "graphql"
+	default:
+		return defaultRateLimitResource
+	}
+}
@@ -0,0 +1,76 @@
+package githubapiproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ValidateURL returns an error unless the proxy at u is served over HTTPS, as it's sent the credentials of the client.
+func ValidateURL(u *url.URL) error {
+	if u.Scheme != "https" {
+		return fmt.Errorf("the GitHub API proxy %q must be an https URL, as it's sent the credentials of the client", u.Redacted())
+	}
+	return nil
+}
+
+// CertPool returns the pool of the PEM CAs in caCert that the certificate of the proxy is pinned to.
+// The system CAs aren't trusted for the proxy, so that the credentials are only sent to the proxy deployed with ARC.
+func CertPool(caCert []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse the ca cert of the GitHub API proxy: no certificate found")
+	}
+	return pool, nil
+}
+
+// URL returns the URL of the proxy that the request to the GitHub API URL u is sent to.
+func URL(proxy, u *url.URL) *url.URL {
+	proxied := *proxy
+	proxied.Path = strings.TrimSuffix(proxy.Path, "/") + "/" + u.Host + u.Path
+	proxied.RawPath = ""
+	if u.RawPath != "" {
+		proxied.RawPath = strings.TrimSuffix(proxy.EscapedPath(), "/") + "/" + u.Host + u.EscapedPath()
+	}
+	proxied.RawQuery = u.RawQuery
+	proxied.Fragment = ""
+
+	return &proxied
+}
+
+// NewTransport returns a Transport sending the requests to the GitHub API through the proxy at proxyURL,
+// which must be served with a certificate issued by one of caCerts.
+func NewTransport(proxyURL *url.URL, caCerts *x509.CertPool) (*Transport, error) {
+	if err := ValidateURL(proxyURL); err != nil {
+		return nil, err
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = &tls.Config{RootCAs: caCerts}
+
+	return &Transport{ProxyURL: proxyURL, Transport: tr}, nil
+}
+
+// Transport sends the requests to the GitHub API through the proxy at ProxyURL.
+type Transport struct {
+	ProxyURL *url.URL
+
+	// Transport sends the requests to the proxy. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	proxied := req.Clone(req.Context())
+	proxied.URL = URL(t.ProxyURL, req.URL)
+	proxied.Host = ""
+
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return transport.RoundTrip(proxied)
+}
@@ -0,0 +1,371 @@
+// Package githuburl parses and normalizes the URLs and names of the GitHub enterprises, organizations and repositories
+// that runners are registered to, so that the admission webhooks of the custom resources reject malformed configurations
+// with actionable errors, instead of the controllers failing with 404s from the GitHub API.
+//
+// It's kept free of dependencies other than the standard library so that the API packages can import it.
+package githuburl
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalid is wrapped by all the errors returned by the package.
+var ErrInvalid = errors.New("invalid GitHub URL")
+
+// Scope is the kind of the GitHub entity the runners are registered to.
+type Scope int
+
+const (
+	ScopeUnknown Scope = iota
+	ScopeEnterprise
+	ScopeOrganization
+	ScopeRepository
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeEnterprise:
+		return "enterprise"
+	case ScopeOrganization:
+		return "organization"
+	case ScopeRepository:
+		return "repository"
+	default:
+		return "unknown"
+	}
+}
+
+// URL is the normalized URL of a GitHub enterprise, organization or repository.
+type URL struct {
+	// Scheme is either http or https.
+	Scheme string
+	// Host is the lowercased host of GitHub, GHEC with data residency or GHES, with the port if any.
+	// www.github.com is normalized to github.com.
+	Host string
+
+	Scope Scope
+
+	Enterprise   string
+	Organization string
+	Repository   string
+}
+
+// String returns the canonical form of the URL, e.g. https://github.com/enterprises/my-enterprise,
+// https://github.com/my-org or https://ghes.example.com/my-org/my-repo.
+func (u *URL) String() string {
+	var path string
+	switch u.Scope {
+	case ScopeEnterprise:
+		path = "enterprises/" + u.Enterprise
+	case ScopeOrganization:
+		path = u.Organization
+	case ScopeRepository:
+		path = u.Organization + "/" + u.Repository
+	}
+
+	return fmt.Sprintf("%s://%s/%s", u.Scheme, u.Host, path)
+}
+
+// Name returns the name of the entity as set to the enterprise, organization and repository fields of the runner specs,
+// e.g. my-enterprise, my-org or my-org/my-repo.
+func (u *URL) Name() string {
+	switch u.Scope {
+	case ScopeEnterprise:
+		return u.Enterprise
+	case ScopeOrganization:
+		return u.Organization
+	case ScopeRepository:
+		return u.Organization + "/" + u.Repository
+	default:
+		return ""
+	}
+}
+
+func invalid(in, format string, args ...any) error {
+	return fmt.Errorf("%w %q: %s", ErrInvalid, in, fmt.Sprintf(format, args...))
+}
+
+// Parse parses the URL of a GitHub enterprise, organization or repository.
+//
+// Only the differences that don't change the meaning of the URL are normalized:
+// surrounding spaces, trailing and duplicate slashes, and the case of the scheme and the host.
+// Anything else that the controllers would misinterpret, like a missing scheme, an API URL, a clone URL
+// or the URL of a page of the repository, is rejected with an error suggesting the URL to use instead.
+func Parse(in string) (*URL, error) {
+	s := strings.TrimSpace(in)
+	if s == "" {
+		return nil, invalid(in, "it must not be empty")
+	}
+
+	if !strings.Contains(s, "://") {
+		if suggested, err := Parse("https://" + s); err == nil {
+			return nil, invalid(in, "it must start with http:// or https://. Did you mean %q?", suggested.String())
+		}
+
+		return nil, invalid(in, "it must be a URL like https://github.com/<org>, https://github.com/<org>/<repo> or https://github.com/enterprises/<enterprise>")
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, invalid(in, "%v", err)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return nil, invalid(in, "the scheme must be http or https, but it was %q", u.Scheme)
+	}
+
+	if u.User != nil {
+		return nil, invalid(in, "it must not contain credentials, which are read from the GitHub secret")
+	}
+
+	host := strings.ToLower(u.Host)
+	if host == "" {
+		return nil, invalid(in, "it must contain the host of GitHub, e.g. https://github.com/<org>")
+	}
+	if host == "www.github.com" {
+		host = "github.com"
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" || u.ForceQuery {
+		return nil, invalid(in, "it must not contain a query or a fragment")
+	}
+
+	var segments []string
+	for _, seg := range strings.Split(u.Path, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+
+	r := &URL{Scheme: scheme, Host: host}
+
+	suggest := func(format string, args ...any) error {
+		if err := validateURL(r); err != nil {
+			return invalid(in, "%v", err)
+		}
+		return invalid(in, format+". Did you mean %q?", append(args, r.String())...)
+	}
+
+	if host == "api.github.com" || (strings.HasPrefix(host, "api.") && strings.HasSuffix(host, DataResidencySuffix)) {
+		r.Host = strings.TrimPrefix(host, "api.")
+		if len(segments) == 0 {
+			return nil, invalid(in, "it must be the URL of an enterprise, organization or repository on %s, not the URL of the API", r.Host)
+		}
+
+		if parseSegments(r, trimAPIResource(segments)) != nil {
+			return nil, invalid(in, "it must be the URL of an enterprise, organization or repository on %s, not the URL of the API", r.Host)
+		}
+
+		return nil, suggest("it must not point to the API")
+	}
+
+	if len(segments) >= 2 && strings.EqualFold(segments[0], "api") && strings.EqualFold(segments[1], "v3") {
+		if parseSegments(r, trimAPIResource(segments[2:])) != nil {
+			return nil, invalid(in, "it must be the URL of an enterprise, organization or repository on %s, not the URL of the API", host)
+		}
+
+		return nil, suggest("it must not point to the API under /api/v3")
+	}
+
+	if len(segments) == 0 {
+		return nil, invalid(in, "it must contain the enterprise, organization or repository, e.g. %s://%s/<org>", scheme, host)
+	}
+
+	if len(segments) >= 2 && (strings.EqualFold(segments[0], "orgs") || strings.EqualFold(segments[0], "organizations")) {
+		if parseSegments(r, segments[1:2]) != nil {
+			return nil, invalid(in, "it must be the URL of the organization")
+		}
+
+		return nil, suggest("it must be the URL of the organization rather than its settings page")
+	}
+
+	if err := parseSegments(r, segments); err != nil {
+		if r.Scope == ScopeUnknown {
+			return nil, invalid(in, "%v", err)
+		}
+
+		return nil, suggest("%v", err)
+	}
+
+	if err := validateURL(r); err != nil {
+		return nil, invalid(in, "%v", err)
+	}
+
+	if strings.HasSuffix(r.Repository, ".git") {
+		r.Repository = strings.TrimSuffix(r.Repository, ".git")
+		return nil, suggest("it must be the URL of the repository, not its clone URL")
+	}
+
+	return r, nil
+}
+
+// DataResidencySuffix is the domain of the hosts of GitHub Enterprise Cloud with data residency, like my-tenant.ghe.com.
+const DataResidencySuffix = ".ghe.com"
+
+// DataResidencyHost returns the lowercased web host of GitHub Enterprise Cloud with data residency, like my-tenant.ghe.com,
+// when host is its web, API or uploads host.
+//
+// Unlike GitHub Enterprise Server, GHEC with data residency serves its REST API at api.<host>/ like github.com,
+// rather than below the /api/v3 path of the web host.
+func DataResidencyHost(host string) (string, bool) {
+	h := strings.ToLower(host)
+	if !strings.HasSuffix(h, DataResidencySuffix) {
+		return "", false
+	}
+
+	for _, prefix := range []string{"api.", "uploads."} {
+		if trimmed := strings.TrimPrefix(h, prefix); trimmed != h && strings.Count(trimmed, ".") >= 2 {
+			h = trimmed
+			break
+		}
+	}
+
+	// The tenant is the only label below ghe.com
+	if strings.Count(h, ".") != 2 || strings.HasPrefix(h, ".") {
+		return "", false
+	}
+
+	return h, true
+}
+
+// trimAPIResource removes the kind of the resource from the path of an API URL, like /orgs/<org> and /repos/<owner>/<repo>.
+func trimAPIResource(segments []string) []string {
+	if len(segments) >= 2 && (segments[0] == "orgs" || segments[0] == "repos") {
+		return segments[1:]
+	}
+
+	return segments
+}
+
+// parseSegments sets the scope and the names of the URL from its path segments.
+// When there are too many segments but a prefix of them points to an enterprise or a repository,
+// the URL is set from the prefix along with the error, so that it can be suggested.
+func parseSegments(u *URL, segments []string) error {
+	switch {
+	case len(segments) == 0:
+		return errors.New("it must contain the enterprise, organization or repository")
+	case strings.EqualFold(segments[0], "enterprises"):
+		if len(segments) < 2 {
+			return errors.New("it must contain the name of the enterprise after /enterprises")
+		}
+
+		u.Scope = ScopeEnterprise
+		u.Enterprise = segments[1]
+
+		if len(segments) > 2 {
+			return errors.New("it must be the URL of the enterprise rather than one of its pages")
+		}
+	case len(segments) == 1:
+		u.Scope = ScopeOrganization
+		u.Organization = segments[0]
+	default:
+		u.Scope = ScopeRepository
+		u.Organization = segments[0]
+		u.Repository = segments[1]
+
+		if len(segments) > 2 {
+			return errors.New("it must be the URL of the repository rather than one of its pages")
+		}
+	}
+
+	return nil
+}
+
+func validateURL(u *URL) error {
+	switch u.Scope {
+	case ScopeEnterprise:
+		return ValidateName(ScopeEnterprise, u.Enterprise)
+	case ScopeOrganization:
+		return ValidateName(ScopeOrganization, u.Organization)
+	case ScopeRepository:
+		return ValidateName(ScopeRepository, u.Organization+"/"+u.Repository)
+	default:
+		return errors.New("it must point to an enterprise, organization or repository")
+	}
+}
+
+// ValidateName validates the name of an enterprise, organization or repository as set to the fields of the runner specs.
+// The name of a repository is in the OWNER/REPO format.
+func ValidateName(scope Scope, name string) error {
+	if scope != ScopeRepository {
+		return validateSegment(scope, name)
+	}
+
+	owner, repo, found := strings.Cut(name, "/")
+	if !found {
+		return fmt.Errorf("the repository %q must be in the OWNER/REPO format", name)
+	}
+
+	if err := validateSegment(ScopeOrganization, owner); err != nil {
+		return fmt.Errorf("the owner of the repository %q is invalid: %w", name, err)
+	}
+
+	if err := validateSegment(ScopeRepository, repo); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateSegment(scope Scope, name string) error {
+	if name == "" {
+		return fmt.Errorf("the name of the %s must not be empty", scope)
+	}
+
+	if name == "." || name == ".." {
+		return fmt.Errorf("%q isn't a valid name of a %s", name, scope)
+	}
+
+	for _, c := range name {
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' {
+			continue
+		}
+
+		return fmt.Errorf("the name of the %s %q must only contain alphanumeric characters, hyphens, underscores and periods", scope, name)
+	}
+
+	return nil
+}
+
+// NormalizeName normalizes the value of the enterprise, organization or repository field of a runner spec.
+//
+// Surrounding spaces and slashes are removed from the value. A URL pasted into the field is replaced by the name it points to,
+// like https://github.com/my-org/my-repo by my-org/my-repo for the repository field.
+// The value is returned as is when it can't be normalized, so that the validation reports why.
+func NormalizeName(scope Scope, value string) string {
+	v := strings.Trim(strings.TrimSpace(value), "/")
+
+	if !strings.Contains(v, "://") {
+		return v
+	}
+
+	u, err := Parse(v)
+	if err != nil || u.Scope != scope {
+		return value
+	}
+
+	return u.Name()
+}
+
+// ValidateNameField validates the value of the enterprise, organization or repository field of a runner spec,
+// explaining how to fix a URL or a name of the wrong scope that NormalizeName couldn't normalize.
+func ValidateNameField(scope Scope, value string) error {
+	if !strings.Contains(value, "://") {
+		return ValidateName(scope, value)
+	}
+
+	u, err := Parse(value)
+	if err != nil {
+		return fmt.Errorf("%s must be the name of the %s, rather than a URL: %w", scope, scope, err)
+	}
+
+	if u.Scope != scope {
+		return fmt.Errorf("%s must be the name of the %s, but %q points to the %s %q. Set %s to %q instead", scope, scope, value, u.Scope, u.Name(), u.Scope, u.Name())
+	}
+
+	return fmt.Errorf("%s must be the name of the %s. Set it to %q instead of the URL", scope, scope, u.Name())
+}
@@ -0,0 +1,223 @@
+// Package reconcilemetrics instruments the reconcilers of both the legacy and the scale set controllers with the same set of metrics,
+// so that operators can tell which reconciler is slow, failing or requeueing the most at scale, and for which namespaces.
+//
+// This depends on the metrics exporter of kubebuilder.
+// See https://book.kubebuilder.io/reference/metrics.html for details.
+package reconcilemetrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/github/actions"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/prometheus/client_golang/prometheus"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// The results of a reconciliation, as the result label of the duration histogram.
+const (
+	ResultSuccess      = "success"
+	ResultError        = "error"
+	ResultRequeue      = "requeue"
+	ResultRequeueAfter = "requeue_after"
+)
+
+// The categories of the errors returned by the reconcilers, as the category label of the error counter.
+const (
+	CategoryConflict        = "conflict"
+	CategoryNotFound        = "not_found"
+	CategoryForbidden       = "forbidden"
+	CategoryInvalid         = "invalid"
+	CategoryThrottled       = "throttled"
+	CategoryTimeout         = "timeout"
+	CategoryGitHubRateLimit = "github_rate_limit"
+	CategoryGitHubAPI       = "github_api"
+	CategoryActionsService  = "actions_service"
+	CategoryCircuitOpen     = "circuit_open"
+	CategoryOther           = "other"
+)
+
+const subsystem = "arc_reconciler"
+
+var onceRegister sync.Once
+
+// Register registers the metrics of the reconcilers to the registry of controller-runtime.
+// It's called by Wrap, so it only needs to be called directly to export the metrics before any reconciler is set up.
+func Register() {
+	onceRegister.Do(func() {
+		metrics.Registry.MustRegister(metricReconcileDuration, metricReconcileErrors, metricRequeues, metricObjects)
+	})
+}
+
+var (
+	metricReconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "reconcile_duration_seconds",
+			Help:      "The time each reconciliation took, by its result",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"controller", "namespace", "result"},
+	)
+	metricReconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "errors_total",
+			Help:      "The number of reconciliations that returned an error, by the category of the error",
+		},
+		[]string{"controller", "namespace", "category"},
+	)
+	metricRequeues = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "requeues_total",
+			Help:      "The number of reconciliations that requeued the object without an error, either immediately or after a delay",
+		},
+		[]string{"controller", "namespace", "kind"},
+	)
+	metricObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "objects",
+			Help:      "The number of objects the reconciler has reconciled and that still exist",
+		},
+		[]string{"controller", "namespace"},
+	)
+)
+
+// Wrap returns a reconciler that calls r and records its metrics with the controller label set to controller.
+//
+// obj is a prototype of the object the reconciler is for. After each reconciliation, the object is looked up with reader,
+// which should be backed by the cache of the manager, to stop counting the objects that no longer exist.
+func Wrap(controller string, reader client.Reader, obj client.Object, r reconcile.Reconciler) reconcile.Reconciler {
+	Register()
+
+	return &reconciler{
+		controller: controller,
+		reader:     reader,
+		obj:        obj,
+		inner:      r,
+		objects:    map[types.NamespacedName]struct{}{},
+		namespaces: map[string]int{},
+	}
+}
+
+type reconciler struct {
+	controller string
+	reader     client.Reader
+	obj        client.Object
+	inner      reconcile.Reconciler
+
+	mu         sync.Mutex
+	objects    map[types.NamespacedName]struct{}
+	namespaces map[string]int
+}
+
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	start := time.Now()
+	res, err := r.inner.Reconcile(ctx, req)
+	elapsed := time.Since(start)
+
+	ns := req.Namespace
+
+	result := ResultSuccess
+	switch {
+	case err != nil:
+		result = ResultError
+		metricReconcileErrors.WithLabelValues(r.controller, ns, Categorize(err)).Inc()
+	case res.RequeueAfter > 0:
+		result = ResultRequeueAfter
+		metricRequeues.WithLabelValues(r.controller, ns, "after").Inc()
+	case res.Requeue:
+		result = ResultRequeue
+		metricRequeues.WithLabelValues(r.controller, ns, "immediate").Inc()
+	}
+	metricReconcileDuration.WithLabelValues(r.controller, ns, result).Observe(elapsed.Seconds())
+
+	r.track(ctx, req.NamespacedName)
+
+	return res, err
+}
+
+// track counts the object while it exists, and stops counting it once it's gone.
+func (r *reconciler) track(ctx context.Context, key types.NamespacedName) {
+	obj := r.obj.DeepCopyObject().(client.Object)
+	err := r.reader.Get(ctx, key, obj)
+	if err != nil && !kerrors.IsNotFound(err) {
+		// Leave the count as is, as it's unknown whether the object exists
+		return
+	}
+	exists := err == nil
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, tracked := r.objects[key]
+	switch {
+	case exists && !tracked:
+		r.objects[key] = struct{}{}
+		r.namespaces[key.Namespace]++
+	case !exists && tracked:
+		delete(r.objects, key)
+		r.namespaces[key.Namespace]--
+	default:
+		return
+	}
+
+	if n := r.namespaces[key.Namespace]; n > 0 {
+		metricObjects.WithLabelValues(r.controller, key.Namespace).Set(float64(n))
+	} else {
+		delete(r.namespaces, key.Namespace)
+		metricObjects.DeleteLabelValues(r.controller, key.Namespace)
+	}
+}
+
+// Categorize returns the category of an error returned by a reconciler.
+// The categories that depend on the upstream being called, like the GitHub rate limits and the open circuit breaker,
+// are checked before the generic Kubernetes API ones.
+func Categorize(err error) string {
+	if _, ok := github.RetryAfterCircuitOpen(err); ok {
+		return CategoryCircuitOpen
+	}
+	if _, ok := github.RetryAfterRateLimit(err); ok {
+		return CategoryGitHubRateLimit
+	}
+
+	var (
+		ghErr      *gogithub.ErrorResponse
+		apiErr     *actions.GitHubAPIError
+		actionsErr *actions.ActionsError
+		exErr      *actions.ActionsExceptionError
+	)
+	switch {
+	case errors.As(err, &ghErr):
+		return CategoryGitHubAPI
+	case errors.As(err, &apiErr), errors.As(err, &actionsErr), errors.As(err, &exErr):
+		return CategoryActionsService
+	}
+
+	switch {
+	case kerrors.IsConflict(err), kerrors.IsAlreadyExists(err):
+		return CategoryConflict
+	case kerrors.IsNotFound(err):
+		return CategoryNotFound
+	case kerrors.IsForbidden(err), kerrors.IsUnauthorized(err):
+		return CategoryForbidden
+	case kerrors.IsInvalid(err), kerrors.IsBadRequest(err):
+		return CategoryInvalid
+	case kerrors.IsTooManyRequests(err):
+		return CategoryThrottled
+	case kerrors.IsTimeout(err), kerrors.IsServerTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	}
+
+	return CategoryOther
+}
@@ -0,0 +1,97 @@
+package reconcilemetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/github/actions"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCategorize(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: kerrors.NewConflict(gr, "p", errors.New("stale")), want: CategoryConflict},
+		{err: fmt.Errorf("wrapped: %w", kerrors.NewNotFound(gr, "p")), want: CategoryNotFound},
+		{err: kerrors.NewForbidden(gr, "p", errors.New("denied")), want: CategoryForbidden},
+		{err: kerrors.NewBadRequest("bad"), want: CategoryInvalid},
+		{err: kerrors.NewTooManyRequests("slow down", 1), want: CategoryThrottled},
+		{err: fmt.Errorf("wrapped: %w", context.DeadlineExceeded), want: CategoryTimeout},
+		{err: &github.SecondaryRateLimitError{Until: time.Now().Add(time.Minute)}, want: CategoryGitHubRateLimit},
+		{err: &github.CircuitOpenError{Until: time.Now().Add(time.Minute)}, want: CategoryCircuitOpen},
+		{err: &gogithub.ErrorResponse{Message: "not found"}, want: CategoryGitHubAPI},
+		{err: fmt.Errorf("wrapped: %w", &actions.ActionsError{StatusCode: 500, Err: errors.New("boom")}), want: CategoryActionsService},
+		{err: errors.New("boom"), want: CategoryOther},
+	}
+
+	for _, tt := range tests {
+		if got := Categorize(tt.err); got != tt.want {
+			t.Errorf("Categorize(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+type reconcilerFunc func(context.Context, reconcile.Request) (reconcile.Result, error)
+
+func (f reconcilerFunc) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	return f(ctx, req)
+}
+
+func TestWrap(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "p"}}
+	c := fake.NewClientBuilder().WithObjects(pod).Build()
+
+	results := map[string]struct {
+		res reconcile.Result
+		err error
+	}{
+		"p":       {res: reconcile.Result{RequeueAfter: time.Second}},
+		"missing": {err: kerrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "missing")},
+	}
+
+	r := Wrap("test-pod", c, &corev1.Pod{}, reconcilerFunc(func(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+		res := results[req.Name]
+		return res.res, res.err
+	}))
+
+	ctx := context.Background()
+	for _, name := range []string{"p", "p", "missing"} {
+		_, _ = r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: name}})
+	}
+
+	if got := testutil.ToFloat64(metricRequeues.WithLabelValues("test-pod", "ns", "after")); got != 2 {
+		t.Errorf("requeues = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metricReconcileErrors.WithLabelValues("test-pod", "ns", CategoryNotFound)); got != 1 {
+		t.Errorf("errors = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metricObjects.WithLabelValues("test-pod", "ns")); got != 1 {
+		t.Errorf("objects = %v, want 1", got)
+	}
+
+	// The object is no longer counted once it's deleted
+	if err := c.Delete(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "p"}})
+
+	if got := testutil.CollectAndCount(metricObjects, "arc_reconciler_objects"); got != 0 {
+		t.Errorf("objects series = %v, want 0", got)
+	}
+}
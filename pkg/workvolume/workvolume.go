@@ -0,0 +1,37 @@
+// Package workvolume sizes the work volume of the runner pods of both the legacy and the scale set controllers the same way.
+package workvolume
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// RequestEphemeralStorage requests the size limit of a disk-backed emptyDir work volume as the ephemeral storage of the container,
+// so that the scheduler accounts for it. An existing ephemeral-storage request of the container is kept.
+//
+// Limits are left as is, as the kubelet already evicts the pod when the volume exceeds its size limit,
+// and an ephemeral-storage limit would also count logs and the writable layers of the containers.
+// The request is clamped to an existing limit, as a request above the limit is rejected by the API server.
+//
+// The requests are copied before they are modified, as they may be shared with the pod template of the runner.
+func RequestEphemeralStorage(resources *corev1.ResourceRequirements, emptyDir *corev1.EmptyDirVolumeSource) {
+	if emptyDir == nil || emptyDir.SizeLimit == nil || emptyDir.SizeLimit.IsZero() || emptyDir.Medium == corev1.StorageMediumMemory {
+		return
+	}
+
+	if _, ok := resources.Requests[corev1.ResourceEphemeralStorage]; ok {
+		return
+	}
+
+	requests := make(corev1.ResourceList, len(resources.Requests)+1)
+	for k, v := range resources.Requests {
+		requests[k] = v
+	}
+
+	request := emptyDir.SizeLimit.DeepCopy()
+	if limit, ok := resources.Limits[corev1.ResourceEphemeralStorage]; ok && limit.Cmp(request) < 0 {
+		request = limit.DeepCopy()
+	}
+	requests[corev1.ResourceEphemeralStorage] = request
+
+	resources.Requests = requests
+}
@@ -0,0 +1,55 @@
+package workvolume
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRequestEphemeralStorage(t *testing.T) {
+	sizeLimit := resource.MustParse("20Gi")
+	emptyDir := &corev1.EmptyDirVolumeSource{SizeLimit: &sizeLimit}
+
+	t.Run("requests the size limit", func(t *testing.T) {
+		shared := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}
+		resources := corev1.ResourceRequirements{Requests: shared}
+
+		RequestEphemeralStorage(&resources, emptyDir)
+
+		require.Equal(t, sizeLimit, resources.Requests[corev1.ResourceEphemeralStorage])
+		require.Equal(t, resource.MustParse("1"), resources.Requests[corev1.ResourceCPU])
+		require.NotContains(t, shared, corev1.ResourceEphemeralStorage, "the requests of the pod template must not be modified")
+	})
+
+	t.Run("clamps the request to the limit", func(t *testing.T) {
+		resources := corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("10Gi")},
+		}
+
+		RequestEphemeralStorage(&resources, emptyDir)
+
+		require.Equal(t, resource.MustParse("10Gi"), resources.Requests[corev1.ResourceEphemeralStorage])
+	})
+
+	t.Run("keeps an existing request", func(t *testing.T) {
+		resources := corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("30Gi")},
+		}
+
+		RequestEphemeralStorage(&resources, emptyDir)
+
+		require.Equal(t, resource.MustParse("30Gi"), resources.Requests[corev1.ResourceEphemeralStorage])
+	})
+
+	t.Run("ignores memory-backed and unlimited volumes", func(t *testing.T) {
+		resources := corev1.ResourceRequirements{}
+
+		RequestEphemeralStorage(&resources, &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: &sizeLimit})
+		RequestEphemeralStorage(&resources, &corev1.EmptyDirVolumeSource{})
+		RequestEphemeralStorage(&resources, nil)
+
+		require.Nil(t, resources.Requests)
+	})
+}
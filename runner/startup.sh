@@ -45,7 +45,14 @@ else
   exit 1
 fi
 
-if [ -z "${RUNNER_TOKEN}" ]; then
+# RUNNER_JITCONFIG_FILE is set by ARC when the runner has been registered with a just-in-time configuration.
+# The runner starts with the configuration instead of registering itself with RUNNER_TOKEN.
+if [ -n "${RUNNER_JITCONFIG_FILE:-}" ]; then
+  if [ ! -s "${RUNNER_JITCONFIG_FILE}" ]; then
+    log.error "RUNNER_JITCONFIG_FILE ${RUNNER_JITCONFIG_FILE} must not be empty"
+    exit 1
+  fi
+elif [ -z "${RUNNER_TOKEN}" ]; then
   log.error 'RUNNER_TOKEN must be set'
   exit 1
 fi
@@ -76,6 +83,40 @@ if ! cd "${RUNNER_HOME}"; then
   exit 1
 fi
 
+# RUNNER_DOWNLOAD_VERSION and RUNNER_CONTAINER_HOOKS_DOWNLOAD_VERSION are set by ARC when
+# spec.runnerVersion and spec.containerHooksVersion are specified.
+# RUNNER_ARTIFACT_MIRROR_URL is set when ARC runs the in-cluster artifact mirror, so that
+# air-gapped clusters don't need a runner image per runner version.
+if [ -n "${RUNNER_DOWNLOAD_VERSION:-}" ]; then
+  runner_arch=$(uname -m | sed -e 's/x86_64/x64/' -e 's/aarch64/arm64/')
+  runner_tarball="actions-runner-linux-${runner_arch}-${RUNNER_DOWNLOAD_VERSION}.tar.gz"
+  if [ -n "${RUNNER_ARTIFACT_MIRROR_URL:-}" ]; then
+    runner_tarball_url="${RUNNER_ARTIFACT_MIRROR_URL%/}/${runner_tarball}"
+  else
+    runner_tarball_url="https://github.com/actions/runner/releases/download/v${RUNNER_DOWNLOAD_VERSION}/${runner_tarball}"
+  fi
+  log.notice "Downloading runner ${RUNNER_DOWNLOAD_VERSION} from ${runner_tarball_url}"
+  if ! curl -fsSL "${runner_tarball_url}" | tar xz -C "${RUNNER_HOME}"; then
+    log.error "Failed to download runner ${RUNNER_DOWNLOAD_VERSION}"
+    exit 1
+  fi
+fi
+
+if [ -n "${RUNNER_CONTAINER_HOOKS_DOWNLOAD_VERSION:-}" ]; then
+  hooks_zip="actions-runner-hooks-k8s-${RUNNER_CONTAINER_HOOKS_DOWNLOAD_VERSION}.zip"
+  if [ -n "${RUNNER_ARTIFACT_MIRROR_URL:-}" ]; then
+    hooks_zip_url="${RUNNER_ARTIFACT_MIRROR_URL%/}/${hooks_zip}"
+  else
+    hooks_zip_url="https://github.com/actions/runner-container-hooks/releases/download/v${RUNNER_CONTAINER_HOOKS_DOWNLOAD_VERSION}/${hooks_zip}"
+  fi
+  log.notice "Downloading container hooks ${RUNNER_CONTAINER_HOOKS_DOWNLOAD_VERSION} from ${hooks_zip_url}"
+  if ! curl -fsSLo "/tmp/${hooks_zip}" "${hooks_zip_url}" || ! unzip -oq "/tmp/${hooks_zip}" -d "${RUNNER_HOME}/k8s"; then
+    log.error "Failed to download container hooks ${RUNNER_CONTAINER_HOOKS_DOWNLOAD_VERSION}"
+    exit 1
+  fi
+  rm -f "/tmp/${hooks_zip}"
+fi
+
 # past that point, it's all relative pathes from /runner
 
 config_args=()
@@ -88,57 +129,84 @@ if [ "${DISABLE_RUNNER_UPDATE:-}" == "true" ]; then
   log.debug 'Passing --disableupdate to config.sh to disable automatic runner updates.'
 fi
 
-update-status "Registering"
+# RUNNER_WARM_STANDBY_FILE is set by ARC when the runner is created as a warm standby runner.
+# The pod is kept running with its images pulled, but the runner doesn't register itself
+# until ARC promotes it by creating RUNNER_WARM_STANDBY_PROMOTION_FILE,
+# or the kubelet flips the content of RUNNER_WARM_STANDBY_FILE from "true", whichever comes first.
+warm_standby() {
+  [ -n "${RUNNER_WARM_STANDBY_FILE:-}" ] &&
+    [ "$(cat "${RUNNER_WARM_STANDBY_FILE}" 2>/dev/null)" == "true" ] &&
+    [ ! -e "${RUNNER_WARM_STANDBY_PROMOTION_FILE:-/nonexistent}" ]
+}
+if warm_standby; then
+  update-status "Standby"
+  log.notice 'Waiting in warm standby until this runner is promoted'
+  while warm_standby; do
+    sleep 1
+  done
+  log.notice 'Promoted from warm standby'
+fi
 
-retries_left=10
-while [[ ${retries_left} -gt 0 ]]; do
-  log.debug 'Configuring the runner.'
-  ./config.sh --unattended --replace \
-    --name "${RUNNER_NAME}" \
-    --url "${GITHUB_URL}${ATTACH}" \
-    --token "${RUNNER_TOKEN}" \
-    --runnergroup "${RUNNER_GROUPS}" \
-    --labels "${RUNNER_LABELS}" \
-    --work "${RUNNER_WORKDIR}" "${config_args[@]}"
+run_args=()
+if [ -n "${RUNNER_JITCONFIG_FILE:-}" ]; then
+  log.debug 'Skipping the configuration of the runner, which starts with its just-in-time configuration.'
+  # The runner reads the configuration from the environment rather than from its arguments,
+  # which are visible in the process list of the pod
+  ACTIONS_RUNNER_INPUT_JITCONFIG="$(<"${RUNNER_JITCONFIG_FILE}")"
+  export ACTIONS_RUNNER_INPUT_JITCONFIG
+else
+  update-status "Registering"
+
+  retries_left=10
+  while [[ ${retries_left} -gt 0 ]]; do
+    log.debug 'Configuring the runner.'
+    ./config.sh --unattended --replace \
+      --name "${RUNNER_NAME}" \
+      --url "${GITHUB_URL}${ATTACH}" \
+      --token "${RUNNER_TOKEN}" \
+      --runnergroup "${RUNNER_GROUPS}" \
+      --labels "${RUNNER_LABELS}" \
+      --work "${RUNNER_WORKDIR}" "${config_args[@]}"
+
+    if [ -f .runner ]; then
+      log.debug 'Runner successfully configured.'
+      break
+    fi
 
-  if [ -f .runner ]; then
-    log.debug 'Runner successfully configured.'
-    break
-  fi
+    log.debug 'Configuration failed. Retrying'
+    retries_left=$((retries_left - 1))
+    sleep 1
+  done
 
-  log.debug 'Configuration failed. Retrying'
-  retries_left=$((retries_left - 1))
-  sleep 1
-done
+  if [ ! -f .runner ]; then
+    # we couldn't configure and register the runner; no point continuing
+    log.error 'Configuration failed!'
+    exit 2
+  fi
 
-if [ ! -f .runner ]; then
-  # we couldn't configure and register the runner; no point continuing
-  log.error 'Configuration failed!'
-  exit 2
+  cat .runner
+  # Note: the `.runner` file's content should be something like the below:
+  #
+  # $ cat /runner/.runner
+  # {
+  # "agentId": 117, #=> corresponds to the ID of the runner
+  # "agentName": "THE_RUNNER_POD_NAME",
+  # "poolId": 1,
+  # "poolName": "Default",
+  # "serverUrl": "https://pipelines.actions.githubusercontent.com/SOME_RANDOM_ID",
+  # "gitHubUrl": "https://github.com/USER/REPO",
+  # "workFolder": "/some/work/dir" #=> corresponds to Runner.Spec.WorkDir
+  # }
+  #
+  # Especially `agentId` is important, as other than listing all the runners in the repo,
+  # this is the only change we could get the exact runnner ID which can be useful for further
+  # GitHub API call like the below. Note that 171 is the agentId seen above.
+  #   curl \
+  #     -H "Accept: application/vnd.github.v3+json" \
+  #     -H "Authorization: bearer ${GITHUB_TOKEN}"
+  #     https://api.github.com/repos/USER/REPO/actions/runners/171
 fi
 
-cat .runner
-# Note: the `.runner` file's content should be something like the below:
-#
-# $ cat /runner/.runner
-# {
-# "agentId": 117, #=> corresponds to the ID of the runner
-# "agentName": "THE_RUNNER_POD_NAME",
-# "poolId": 1,
-# "poolName": "Default",
-# "serverUrl": "https://pipelines.actions.githubusercontent.com/SOME_RANDOM_ID",
-# "gitHubUrl": "https://github.com/USER/REPO",
-# "workFolder": "/some/work/dir" #=> corresponds to Runner.Spec.WorkDir
-# }
-#
-# Especially `agentId` is important, as other than listing all the runners in the repo,
-# this is the only change we could get the exact runnner ID which can be useful for further
-# GitHub API call like the below. Note that 171 is the agentId seen above.
-#   curl \
-#     -H "Accept: application/vnd.github.v3+json" \
-#     -H "Authorization: bearer ${GITHUB_TOKEN}"
-#     https://api.github.com/repos/USER/REPO/actions/runners/171
-
 # Hack due to the DinD volumes
 if [ -z "${UNITTEST:-}" ] && [ -e ./externalstmp ]; then
   mkdir -p ./externals
@@ -158,7 +226,7 @@ else
 fi
 
 # Unset entrypoint environment variables so they don't leak into the runner environment
-unset RUNNER_NAME RUNNER_REPO RUNNER_TOKEN STARTUP_DELAY_IN_SECONDS DISABLE_WAIT_FOR_DOCKER
+unset RUNNER_NAME RUNNER_REPO RUNNER_TOKEN RUNNER_JITCONFIG_FILE RUNNER_WARM_STANDBY_FILE RUNNER_WARM_STANDBY_PROMOTION_FILE STARTUP_DELAY_IN_SECONDS DISABLE_WAIT_FOR_DOCKER
 
 # Docker ignores PAM and thus never loads the system environment variables that
 # are meant to be set in every environment of every user. We emulate the PAM
@@ -177,4 +245,4 @@ log.notice "WARNING LATEST TAG HAS BEEN DEPRECATED. SEE GITHUB ISSUE FOR DETAILS
 log.notice "https://github.com/actions/actions-runner-controller/issues/2056"
 
 update-status "Idle"
-exec env -- "${env[@]}" ./run.sh
+exec env -- "${env[@]}" ./run.sh "${run_args[@]}"